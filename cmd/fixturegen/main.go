@@ -0,0 +1,157 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Command fixturegen records identity provider responses from a dev tenant as
+// sanitized fixtures for the adapter contract tests. Each argument is a
+// name=target pair: an Auth0 user_id, or an Authelia access token, whose
+// response is written to <out>/<name>.json. Run the adapter tests with
+// UPDATE_GOLDEN=1 afterwards to refresh the golden files, and review the diff.
+//
+// Auth0 is reached with the M2M credentials the service uses (AUTH0_DOMAIN,
+// AUTH0_M2M_CLIENT_ID, AUTH0_M2M_PRIVATE_BASE64_KEY, AUTH0_AUDIENCE) and
+// Authelia through AUTHELIA_OIDC_USERINFO_URL.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/auth0"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/auth0/client"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/golden"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/httpclient"
+)
+
+// fetcher returns the raw provider response for a target
+type fetcher func(ctx context.Context, target string) ([]byte, error)
+
+func main() {
+	var (
+		provider = flag.String("provider", "", "identity provider to record from: auth0 or authelia")
+		out      = flag.String("out", "", "directory the fixtures are written to")
+		timeout  = flag.Duration("timeout", 30*time.Second, "timeout of the whole recording")
+	)
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: fixturegen -provider auth0|authelia -out dir name=target...\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *out == "" || flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	if err := run(ctx, *provider, *out, flag.Args()); err != nil {
+		fmt.Fprintln(os.Stderr, "fixturegen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, provider, out string, targets []string) error {
+	var (
+		fetch fetcher
+		err   error
+	)
+	switch provider {
+	case constants.UserRepositoryTypeAuth0:
+		fetch, err = auth0Fetcher(ctx)
+	case constants.UserRepositoryTypeAuthelia:
+		fetch, err = autheliaFetcher()
+	default:
+		return fmt.Errorf("unsupported provider %q", provider)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(out, 0o755); err != nil {
+		return err
+	}
+	for _, arg := range targets {
+		name, target, ok := strings.Cut(arg, "=")
+		if !ok || name == "" || target == "" || strings.ContainsAny(name, `/\`) {
+			return fmt.Errorf("argument %q is not a name=target pair", arg)
+		}
+		raw, err := fetch(ctx, target)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		fixture, err := golden.Sanitize(raw)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		path := filepath.Join(out, name+".json")
+		if err := os.WriteFile(path, fixture, 0o600); err != nil {
+			return err
+		}
+		fmt.Println("wrote", path)
+	}
+	return nil
+}
+
+// auth0Fetcher reads users from the Management API
+func auth0Fetcher(ctx context.Context) (fetcher, error) {
+	domain := os.Getenv(constants.Auth0DomainEnvKey)
+	if domain == "" {
+		return nil, fmt.Errorf("%s is required", constants.Auth0DomainEnvKey)
+	}
+	tokens, err := auth0.NewM2MTokenManager(ctx, auth0.Config{Domain: domain}, http.DefaultClient)
+	if err != nil {
+		return nil, err
+	}
+	api := client.New(domain, httpclient.NewClient(httpclient.DefaultConfig()))
+
+	return func(ctx context.Context, userID string) ([]byte, error) {
+		token, err := tokens.GetToken(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var raw json.RawMessage
+		if err := api.GetUser(ctx, token, userID, &raw); err != nil {
+			return nil, err
+		}
+		return raw, nil
+	}, nil
+}
+
+// autheliaFetcher reads the userinfo document of access tokens
+func autheliaFetcher() (fetcher, error) {
+	userInfoURL := os.Getenv(constants.AutheliaOIDCUserInfoURLEnvKey)
+	if userInfoURL == "" {
+		return nil, fmt.Errorf("%s is required", constants.AutheliaOIDCUserInfoURLEnvKey)
+	}
+
+	return func(ctx context.Context, token string) ([]byte, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, userInfoURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("userinfo returned %s", resp.Status)
+		}
+		return body, nil
+	}, nil
+}
@@ -0,0 +1,293 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Command loadgen drives a mix of read, update and email lookup requests
+// against a running auth service over NATS and reports throughput, latency
+// percentiles and a breakdown of the errors per operation.
+//
+// Point it at a dev or staging deployment: updates write the given metadata
+// to the users of the update tokens.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/compression"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/deadline"
+)
+
+// operation is one kind of request of the mix
+type operation struct {
+	name    string
+	subject string
+	weight  int
+	// payloads are sent round robin
+	payloads [][]byte
+}
+
+func main() {
+	var (
+		natsURL     = flag.String("nats-url", envOr("NATS_URL", nats.DefaultURL), "NATS server URL")
+		duration    = flag.Duration("duration", 30*time.Second, "how long to send requests")
+		rate        = flag.Int("rate", 100, "requests per second across all workers, 0 for as fast as possible")
+		concurrency = flag.Int("concurrency", 10, "requests in flight at most")
+		timeout     = flag.Duration("timeout", 5*time.Second, "timeout of a request")
+		mix         = flag.String("mix", "read=70,email=25,update=5", "weights of the operations: read, email and update")
+		reads       = flag.String("reads", "", "user_metadata.read inputs (usernames, subs or tokens): comma separated, or @file with one per line")
+		emails      = flag.String("emails", "", "email_to_username inputs: comma separated, or @file with one per line")
+		updates     = flag.String("update-tokens", "", "tokens of the users to update: comma separated, or @file with one per line")
+		metadata    = flag.String("update-metadata", `{"t_shirt_size":"M"}`, "user_metadata sent by updates")
+		format      = flag.String("format", "text", "report format: text or json")
+		encodings   = flag.String("accept-encoding", "", "Accept-Encoding sent with requests, e.g. \"zstd, gzip\"; empty asks for plain replies")
+	)
+	flag.Parse()
+
+	operations, err := buildMix(*mix, *reads, *emails, *updates, *metadata)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "loadgen:", err)
+		os.Exit(2)
+	}
+	if *concurrency < 1 || *rate < 0 {
+		fmt.Fprintln(os.Stderr, "loadgen: -concurrency must be positive and -rate not negative")
+		os.Exit(2)
+	}
+
+	conn, err := nats.Connect(*natsURL, nats.Name("lfx-auth-service-loadgen"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "loadgen: failed to connect to NATS:", err)
+		os.Exit(1)
+	}
+	defer conn.Close()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	ctx, cancelRun := context.WithTimeout(ctx, *duration)
+	defer cancelRun()
+
+	report := run(ctx, conn, operations, *rate, *concurrency, *timeout, *encodings)
+
+	switch *format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		_ = encoder.Encode(report)
+	default:
+		report.print(os.Stdout)
+	}
+	if report.Errors > 0 {
+		os.Exit(1)
+	}
+}
+
+// run sends requests until ctx is done and returns what was measured. With
+// acceptEncoding set, large replies come back compressed and are decoded.
+func run(ctx context.Context, conn *nats.Conn, operations []*operation, rate, concurrency int, timeout time.Duration, acceptEncoding string) *report {
+	stats := make(map[string]*opStats, len(operations))
+	for _, op := range operations {
+		stats[op.name] = newOpStats()
+	}
+
+	var (
+		wg      sync.WaitGroup
+		next    sync.Mutex
+		indexes = make(map[string]int, len(operations))
+		start   = time.Now()
+	)
+	pick := func() (*operation, []byte) {
+		next.Lock()
+		defer next.Unlock()
+		op := weighted(operations)
+		payload := op.payloads[indexes[op.name]%len(op.payloads)]
+		indexes[op.name]++
+		return op, payload
+	}
+
+	var ticks <-chan time.Time
+	if rate > 0 {
+		ticker := time.NewTicker(time.Second / time.Duration(rate))
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				if ticks != nil {
+					select {
+					case <-ctx.Done():
+						return
+					case <-ticks:
+					}
+				} else if ctx.Err() != nil {
+					return
+				}
+
+				op, payload := pick()
+				began := time.Now()
+				request := nats.NewMsg(op.subject)
+				request.Data = payload
+				if acceptEncoding != "" {
+					request.Header.Set(compression.AcceptEncodingHeader, acceptEncoding)
+				}
+				request.Header.Set(deadline.TimeoutHeader, timeout.String())
+				msg, err := conn.RequestMsg(request, timeout)
+				stats[op.name].record(time.Since(began), outcome(msg, err))
+			}
+		}()
+	}
+	wg.Wait()
+
+	return newReport(time.Since(start), operations, stats)
+}
+
+// outcome classifies a reply: empty for a success, otherwise the reason of
+// the failure
+func outcome(msg *nats.Msg, err error) string {
+	switch {
+	case err == nats.ErrTimeout:
+		return "timeout"
+	case err == nats.ErrNoResponders:
+		return "no responders"
+	case err != nil:
+		return err.Error()
+	}
+
+	data, err := compression.Decode(msg.Header.Get(compression.ContentEncodingHeader), msg.Data)
+	if err != nil {
+		return "undecodable reply"
+	}
+	if len(data) == 0 || data[0] != '{' {
+		// plain text replies of the lookups
+		return ""
+	}
+	var reply struct {
+		Success *bool  `json:"success"`
+		Error   string `json:"error"`
+	}
+	if json.Unmarshal(data, &reply) != nil || reply.Success == nil || *reply.Success {
+		return ""
+	}
+	if reply.Error == "" {
+		return "unsuccessful reply"
+	}
+	return reply.Error
+}
+
+// weighted picks an operation with a probability proportional to its weight
+func weighted(operations []*operation) *operation {
+	total := 0
+	for _, op := range operations {
+		total += op.weight
+	}
+	n := rand.IntN(total)
+	for _, op := range operations {
+		if n < op.weight {
+			return op
+		}
+		n -= op.weight
+	}
+	return operations[len(operations)-1]
+}
+
+// buildMix turns the flags into the operations to send, leaving out those
+// with a zero weight
+func buildMix(mix, reads, emails, updates, metadata string) ([]*operation, error) {
+	weights := map[string]int{}
+	for _, part := range strings.Split(mix, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		weight, err := strconv.Atoi(value)
+		if !ok || err != nil || weight < 0 {
+			return nil, fmt.Errorf("invalid -mix entry %q", part)
+		}
+		weights[name] = weight
+	}
+
+	var operations []*operation
+	add := func(name, subject, inputs string, payload func(string) ([]byte, error)) error {
+		weight := weights[name]
+		delete(weights, name)
+		if weight == 0 {
+			return nil
+		}
+		values, err := readInputs(inputs)
+		if err != nil {
+			return err
+		}
+		if len(values) == 0 {
+			return fmt.Errorf("%s has a weight but no inputs", name)
+		}
+		op := &operation{name: name, subject: subject, weight: weight}
+		for _, value := range values {
+			data, err := payload(value)
+			if err != nil {
+				return err
+			}
+			op.payloads = append(op.payloads, data)
+		}
+		operations = append(operations, op)
+		return nil
+	}
+	raw := func(value string) ([]byte, error) { return []byte(value), nil }
+
+	if err := add("read", constants.UserMetadataReadSubject, reads, raw); err != nil {
+		return nil, err
+	}
+	if err := add("email", constants.UserEmailToUserSubject, emails, raw); err != nil {
+		return nil, err
+	}
+	err := add("update", constants.UserMetadataUpdateSubject, updates, func(token string) ([]byte, error) {
+		return json.Marshal(map[string]any{"token": token, "user_metadata": json.RawMessage(metadata)})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid update payload: %w", err)
+	}
+	for name := range weights {
+		return nil, fmt.Errorf("unknown operation %q in -mix", name)
+	}
+	if len(operations) == 0 {
+		return nil, fmt.Errorf("-mix selects no operation")
+	}
+	return operations, nil
+}
+
+// readInputs splits a comma separated list, or reads the lines of @file
+func readInputs(value string) ([]string, error) {
+	separator := ","
+	if path, ok := strings.CutPrefix(value, "@"); ok {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		value, separator = string(data), "\n"
+	}
+	var inputs []string
+	for _, input := range strings.Split(value, separator) {
+		if input = strings.TrimSpace(input); input != "" {
+			inputs = append(inputs, input)
+		}
+	}
+	return inputs, nil
+}
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,190 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// histogramBounds are the upper bounds of the latency histogram buckets; the
+// last bucket holds everything slower
+var histogramBounds = []time.Duration{
+	time.Millisecond,
+	2 * time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+}
+
+// opStats collects the measurements of one operation
+type opStats struct {
+	mu        sync.Mutex
+	latencies []time.Duration
+	errors    map[string]int
+}
+
+func newOpStats() *opStats {
+	return &opStats{errors: make(map[string]int)}
+}
+
+func (s *opStats) record(latency time.Duration, failure string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latencies = append(s.latencies, latency)
+	if failure != "" {
+		s.errors[failure]++
+	}
+}
+
+// bucket is one bar of the latency histogram
+type bucket struct {
+	// UpperBound is empty for the last bucket
+	UpperBound string `json:"le,omitempty"`
+	Count      int    `json:"count"`
+}
+
+// opReport summarizes one operation
+type opReport struct {
+	Name      string         `json:"name"`
+	Subject   string         `json:"subject"`
+	Requests  int            `json:"requests"`
+	Errors    int            `json:"errors"`
+	P50       time.Duration  `json:"p50_ns"`
+	P90       time.Duration  `json:"p90_ns"`
+	P99       time.Duration  `json:"p99_ns"`
+	Max       time.Duration  `json:"max_ns"`
+	Histogram []bucket       `json:"histogram"`
+	Failures  map[string]int `json:"failures,omitempty"`
+}
+
+// report is the outcome of a run
+type report struct {
+	Elapsed    time.Duration `json:"elapsed_ns"`
+	Requests   int           `json:"requests"`
+	Errors     int           `json:"errors"`
+	Throughput float64       `json:"requests_per_second"`
+	Operations []opReport    `json:"operations"`
+}
+
+func newReport(elapsed time.Duration, operations []*operation, stats map[string]*opStats) *report {
+	r := &report{Elapsed: elapsed}
+	for _, op := range operations {
+		s := stats[op.name]
+		s.mu.Lock()
+		latencies := slices.Clone(s.latencies)
+		failures := s.errors
+		s.mu.Unlock()
+		slices.Sort(latencies)
+
+		summary := opReport{
+			Name:      op.name,
+			Subject:   op.subject,
+			Requests:  len(latencies),
+			P50:       percentile(latencies, 0.50),
+			P90:       percentile(latencies, 0.90),
+			P99:       percentile(latencies, 0.99),
+			Histogram: histogram(latencies),
+			Failures:  failures,
+		}
+		if len(latencies) > 0 {
+			summary.Max = latencies[len(latencies)-1]
+		}
+		for _, count := range failures {
+			summary.Errors += count
+		}
+		r.Requests += summary.Requests
+		r.Errors += summary.Errors
+		r.Operations = append(r.Operations, summary)
+	}
+	if elapsed > 0 {
+		r.Throughput = float64(r.Requests) / elapsed.Seconds()
+	}
+	return r
+}
+
+// percentile returns the latency below which a fraction p of the sorted
+// latencies fall
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	index := int(float64(len(sorted))*p+0.5) - 1
+	return sorted[min(max(index, 0), len(sorted)-1)]
+}
+
+func histogram(sorted []time.Duration) []bucket {
+	buckets := make([]bucket, len(histogramBounds)+1)
+	for i, bound := range histogramBounds {
+		buckets[i].UpperBound = bound.String()
+	}
+	for _, latency := range sorted {
+		i, _ := slices.BinarySearch(histogramBounds, latency)
+		buckets[i].Count++
+	}
+	return buckets
+}
+
+// print writes the report for a terminal
+func (r *report) print(w io.Writer) {
+	fmt.Fprintf(w, "%d requests in %s (%.1f/s), %d errors\n\n", r.Requests, r.Elapsed.Round(time.Millisecond), r.Throughput, r.Errors)
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "OPERATION\tREQUESTS\tERRORS\tP50\tP90\tP99\tMAX")
+	for _, op := range r.Operations {
+		fmt.Fprintf(table, "%s\t%d\t%d\t%s\t%s\t%s\t%s\n", op.Name, op.Requests, op.Errors,
+			round(op.P50), round(op.P90), round(op.P99), round(op.Max))
+	}
+	_ = table.Flush()
+
+	for _, op := range r.Operations {
+		fmt.Fprintf(w, "\n%s latency:\n", op.Name)
+		peak := 0
+		for _, b := range op.Histogram {
+			peak = max(peak, b.Count)
+		}
+		for _, b := range op.Histogram {
+			label := "<= " + b.UpperBound
+			if b.UpperBound == "" {
+				label = "slower"
+			}
+			bar := 0
+			if peak > 0 {
+				bar = b.Count * 40 / peak
+			}
+			fmt.Fprintf(w, "  %-10s %7d %s\n", label, b.Count, strings.Repeat("#", bar))
+		}
+
+		if len(op.Failures) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "%s errors:\n", op.Name)
+		reasons := make([]string, 0, len(op.Failures))
+		for reason := range op.Failures {
+			reasons = append(reasons, reason)
+		}
+		slices.SortFunc(reasons, func(a, b string) int {
+			return cmp.Or(cmp.Compare(op.Failures[b], op.Failures[a]), cmp.Compare(a, b))
+		})
+		for _, reason := range reasons {
+			fmt.Fprintf(w, "  %7d %s\n", op.Failures[reason], reason)
+		}
+	}
+}
+
+func round(d time.Duration) time.Duration {
+	return d.Round(10 * time.Microsecond)
+}
@@ -17,12 +17,19 @@ import (
 	"goa.design/clue/debug"
 	goahttp "goa.design/goa/v3/http"
 
+	"github.com/linuxfoundation/lfx-v2-auth-service/cmd/server/service"
 	authservice "github.com/linuxfoundation/lfx-v2-auth-service/gen/auth_service"
 	authserver "github.com/linuxfoundation/lfx-v2-auth-service/gen/http/auth_service/server"
+	internalservice "github.com/linuxfoundation/lfx-v2-auth-service/internal/service"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
 )
 
 // handleHTTPServer starts the HTTP server for health check endpoints
-func handleHTTPServer(ctx context.Context, host string, authEndpoints *authservice.Endpoints, wg *sync.WaitGroup, errc chan<- error, dbg bool) {
+func handleHTTPServer(ctx context.Context, host string, authEndpoints *authservice.Endpoints, wg *sync.WaitGroup, errc chan<- error, dbg bool) error {
+	tlsConfig, exceptProbes, err := serverTLSConfig()
+	if err != nil {
+		return err
+	}
 
 	// Provide the transport specific request decoder and response encoder.
 	// The goa http package has built-in support for JSON, XML and gob.
@@ -82,6 +89,28 @@ func handleHTTPServer(ctx context.Context, host string, authEndpoints *authservi
 	// Configure the mux.
 	authserver.Mount(mux, authServer)
 
+	// routes are documented in the OpenAPI document
+	routes := make([]internalservice.HTTPRoute, 0, len(authServer.Mounts)+2)
+	for _, m := range authServer.Mounts {
+		routes = append(routes, internalservice.HTTPRoute{Method: m.Verb, Path: m.Pattern, OperationID: m.Method, ContentType: "text/plain"})
+	}
+
+	// Auth0 Log Streaming webhook, only mounted when a token is configured
+	if logStreamHandler := service.Auth0LogStreamHandler(ctx); logStreamHandler != nil {
+		mux.Handle(http.MethodPost, constants.Auth0LogStreamPath, logStreamHandler.ServeHTTP)
+		slog.InfoContext(ctx, "HTTP endpoint mounted", "method", http.MethodPost, "pattern", constants.Auth0LogStreamPath)
+		routes = append(routes, internalservice.HTTPRoute{Method: http.MethodPost, Path: constants.Auth0LogStreamPath,
+			OperationID: "auth0LogStream", Summary: "Receive Auth0 Log Streaming deliveries"})
+	}
+
+	// API documents, generated from the subject registrations and the routes
+	specRoute := internalservice.HTTPRoute{Method: http.MethodGet, Path: constants.APISpecPath,
+		OperationID: "spec", Summary: "AsyncAPI and OpenAPI documents of the service"}
+	if specHandler := service.APISpecHandler(Version, append(routes, specRoute)); specHandler != nil {
+		mux.Handle(http.MethodGet, constants.APISpecPath, specHandler.ServeHTTP)
+		slog.InfoContext(ctx, "HTTP endpoint mounted", "method", http.MethodGet, "pattern", constants.APISpecPath)
+	}
+
 	// Wrap the multiplexer with additional middlewares. Middlewares mounted
 	// here apply to all the service endpoints.
 	var handler http.Handler = mux
@@ -96,10 +125,13 @@ func handleHTTPServer(ctx context.Context, host string, authEndpoints *authservi
 			return p != authserver.LivezAuthServicePath() && p != authserver.ReadyzAuthServicePath()
 		}),
 	)
+	if exceptProbes {
+		handler = requireClientCert(handler, authserver.LivezAuthServicePath(), authserver.ReadyzAuthServicePath())
+	}
 
 	// Start HTTP server using default configuration, change the code to
 	// configure the server as required by your service.
-	srv := &http.Server{Addr: host, Handler: handler, ReadHeaderTimeout: time.Second * 60}
+	srv := &http.Server{Addr: host, Handler: handler, ReadHeaderTimeout: time.Second * 60, TLSConfig: tlsConfig}
 	for _, m := range authServer.Mounts {
 		slog.InfoContext(ctx, "HTTP endpoint mounted",
 			"method", m.Method,
@@ -125,11 +157,19 @@ func handleHTTPServer(ctx context.Context, host string, authEndpoints *authservi
 			}
 		}()
 
-		slog.InfoContext(ctx, "HTTP server listening", "host", host)
-		if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		slog.InfoContext(ctx, "HTTP server listening", "host", host, "tls", tlsConfig != nil)
+		var err error
+		if tlsConfig != nil {
+			// The certificate comes from TLSConfig.GetCertificate
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != http.ErrServerClosed {
 			errc <- err
 		}
 	}()
+	return nil
 }
 
 // errorHandler returns a function that writes and logs the given error.
@@ -5,6 +5,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"log/slog"
@@ -18,6 +19,8 @@ import (
 
 	authservice "github.com/linuxfoundation/lfx-v2-auth-service/gen/auth_service"
 	logging "github.com/linuxfoundation/lfx-v2-auth-service/pkg/log"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/normalize"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/redaction"
 	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/utils"
 )
 
@@ -39,6 +42,16 @@ const (
 func init() {
 	// slog is the standard library logger, we use it to log errors and
 	logging.InitStructureLogConfig()
+
+	if err := redaction.ConfigureFromEnv(); err != nil {
+		slog.Error("invalid redaction configuration", "error", err)
+		os.Exit(1)
+	}
+
+	if err := normalize.ConfigureFromEnv(); err != nil {
+		slog.Error("invalid normalization configuration", "error", err)
+		os.Exit(1)
+	}
 }
 
 func main() {
@@ -47,6 +60,10 @@ func main() {
 		dbgF = flag.Bool("d", false, "enable debug logging")
 		port = flag.String("p", defaultPort, "listen port")
 		bind = flag.String("bind", "*", "interface to bind on")
+
+		reindexLookups = flag.Bool("reindex-lookups", false, "rebuild the Authelia KV lookup keys with the current normalization and exit")
+		repairLookups  = flag.Bool("repair-lookups", false, "repair the Authelia KV lookup keys, print the keys claimed by several users and exit")
+		validateConfig = flag.Bool("validate-config", false, "check the configuration against NATS and the identity provider, print a report and exit")
 	)
 	flag.Usage = func() {
 		flag.PrintDefaults()
@@ -77,6 +94,65 @@ func main() {
 		}
 	}()
 
+	// Secrets may be delivered as KMS ciphertext; decrypt them before anything reads them
+	if err := service.DecryptSecrets(ctx); err != nil {
+		slog.ErrorContext(ctx, "failed to decrypt secrets", "error", err)
+		os.Exit(1)
+	}
+
+	if *validateConfig {
+		report := service.ValidateConfig(ctx)
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if errEncode := encoder.Encode(report); errEncode != nil {
+			slog.ErrorContext(ctx, "failed to print the configuration report", "error", errEncode)
+			os.Exit(1)
+		}
+		if !report.OK {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *reindexLookups {
+		result, errReindex := service.ReindexLookups(ctx)
+		if errReindex != nil {
+			slog.ErrorContext(ctx, "failed to re-index lookup keys", "error", errReindex)
+			os.Exit(1)
+		}
+		slog.InfoContext(ctx, "lookup keys re-indexed",
+			"scanned", result.Scanned,
+			"reindexed", result.Reindexed,
+			"removed", result.Removed,
+			"orphaned", result.Orphaned,
+			"failed", result.Failed,
+		)
+		return
+	}
+
+	if *repairLookups {
+		result, errRepair := service.RepairLookups(ctx)
+		if errRepair != nil {
+			slog.ErrorContext(ctx, "failed to repair lookup keys", "error", errRepair)
+			os.Exit(1)
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if errEncode := encoder.Encode(result); errEncode != nil {
+			slog.ErrorContext(ctx, "failed to print the lookup key report", "error", errEncode)
+			os.Exit(1)
+		}
+		if len(result.Conflicts) > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if !service.ValidateConfigOnStartup(ctx) {
+		slog.ErrorContext(ctx, "invalid configuration, run with -validate-config for a report")
+		os.Exit(1)
+	}
+
 	slog.InfoContext(ctx, "Starting auth service",
 		"bind", *bind,
 		"http-port", *port,
@@ -108,10 +184,13 @@ func main() {
 		addr = *bind + ":" + *port
 	}
 
-	handleHTTPServer(ctx, addr, authEndpoints, &wg, errc, *dbgF)
+	if err := handleHTTPServer(ctx, addr, authEndpoints, &wg, errc, *dbgF); err != nil {
+		slog.ErrorContext(ctx, "failed to start HTTP server", "error", err)
+		os.Exit(1)
+	}
 
 	// Start NATS subscriptions
-	if err := service.QueueSubscriptions(ctx); err != nil {
+	if err := service.QueueSubscriptions(ctx, Version); err != nil {
 		slog.ErrorContext(ctx, "failed to start NATS subscriptions", "error", err)
 		errc <- fmt.Errorf("failed to start NATS subscriptions: %w", err)
 	}
@@ -132,6 +211,8 @@ func main() {
 	done := make(chan struct{})
 	go func() {
 		wg.Wait()
+		service.StopRequestWorkers()
+		service.CloseEventPublishers(shutdownCtx)
 		close(done)
 	}()
 
@@ -0,0 +1,79 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/nats"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/service"
+)
+
+// processStartedAt approximates the replica start time for uptime reporting
+var processStartedAt = time.Now().UTC()
+
+// instanceStats assembles model.InstanceStats from the replica's components
+type instanceStats struct {
+	// instanceID is the NATS micro instance ID, set once the service is registered
+	instanceID atomic.Pointer[string]
+	hostname   string
+	version    string
+	natsClient *nats.NATSClient
+	cache      port.CacheStatsReporter
+	provider   port.ProviderStatsReporter
+	// maintenance is the read-only switch of the process
+	maintenance *service.MaintenanceMode
+}
+
+// InstanceStats returns the current stats of this replica
+func (s *instanceStats) InstanceStats(ctx context.Context) model.InstanceStats {
+	stats := model.InstanceStats{
+		Hostname:      s.hostname,
+		Version:       s.version,
+		StartedAt:     processStartedAt,
+		UptimeSeconds: int64(time.Since(processStartedAt).Seconds()),
+	}
+	if id := s.instanceID.Load(); id != nil {
+		stats.InstanceID = *id
+	}
+	if s.natsClient != nil {
+		stats.Requests.InFlight, stats.Requests.Total = s.natsClient.RequestStats()
+	}
+	if s.cache != nil {
+		cacheStats := s.cache.CacheStats()
+		stats.Cache = &cacheStats
+	}
+	if s.provider != nil {
+		providerStats := s.provider.ProviderStats()
+		stats.Provider = &providerStats
+	}
+	if s.maintenance != nil {
+		stats.Maintenance = s.maintenance.State()
+	}
+	return stats
+}
+
+// newInstanceStats picks up whichever stats the user repository and its
+// cache wrapper expose
+func newInstanceStats(version string, natsClient *nats.NATSClient, userReaderWriter, userRepository port.UserReaderWriter) *instanceStats {
+	hostname, _ := os.Hostname()
+	s := &instanceStats{
+		hostname:    hostname,
+		version:     version,
+		natsClient:  natsClient,
+		maintenance: maintenanceMode(),
+	}
+	if cache, ok := userRepository.(port.CacheStatsReporter); ok {
+		s.cache = cache
+	}
+	if provider, ok := userReaderWriter.(port.ProviderStatsReporter); ok {
+		s.provider = provider
+	}
+	return s
+}
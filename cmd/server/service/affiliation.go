@@ -0,0 +1,32 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"log"
+	"log/slog"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/nats"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/service"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+)
+
+// affiliationOptions wires the employment history when enabled; the NATS
+// client opens its bucket on connect
+func affiliationOptions(ctx context.Context, natsClient *nats.NATSClient) []service.MessageHandlerOrchestratorOption {
+	if !envBool(constants.AffiliationsEnabledEnvKey, false) {
+		return nil
+	}
+
+	kv, ok := natsClient.GetKVStore(constants.KVBucketNameUserAffiliations)
+	if !ok {
+		log.Fatalf("affiliations enabled but the %s KV bucket is not available", constants.KVBucketNameUserAffiliations)
+	}
+	slog.InfoContext(ctx, "employment history enabled")
+
+	return []service.MessageHandlerOrchestratorOption{
+		service.WithAffiliationStoreForMessageHandler(nats.NewAffiliationStore(kv)),
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/scheduler"
+)
+
+const (
+	defaultUserArchivalInterval = 24 * time.Hour
+	defaultUserArchivalMaxUsers = 1000
+	// userArchivalInitialDelay keeps the first run off the startup path
+	userArchivalInitialDelay = 10 * time.Minute
+)
+
+// startUserArchivalJob schedules the archival of inactive users when
+// AUTHELIA_ARCHIVE_INACTIVE_AFTER is set. Every replica runs it unless
+// JOB_LOCK_ENABLED is set; the record revisions keep two runs from archiving
+// a user written in between.
+func startUserArchivalJob(ctx context.Context, userReaderWriter port.UserReaderWriter) {
+	inactiveAfter := envDuration(constants.AutheliaArchiveInactiveAfterEnvKey, 0)
+	if inactiveAfter == 0 {
+		return
+	}
+	archiver, ok := userReaderWriter.(port.InactiveUserArchiver)
+	if !ok {
+		slog.WarnContext(ctx, "user archival configured but the user repository does not support it")
+		return
+	}
+
+	interval := envDuration(constants.AutheliaArchiveIntervalEnvKey, defaultUserArchivalInterval)
+	if interval == 0 {
+		interval = defaultUserArchivalInterval
+	}
+	maxUsers := envPositiveInt(constants.AutheliaArchiveMaxUsersEnvKey, defaultUserArchivalMaxUsers)
+
+	slog.InfoContext(ctx, "scheduling inactive user archival",
+		"inactive_after", inactiveAfter,
+		"interval", interval,
+		"max_users", maxUsers,
+	)
+
+	scheduler.Every(ctx, "user-archival", interval, userArchivalInitialDelay, oneReplica("user-archival", interval, func(ctx context.Context) error {
+		if maintenanceMode().ReadOnly() {
+			slog.InfoContext(ctx, "skipping inactive user archival in read-only mode")
+			return nil
+		}
+		_, err := archiver.ArchiveInactiveUsers(ctx, time.Now().Add(-inactiveAfter), maxUsers)
+		return err
+	}))
+}
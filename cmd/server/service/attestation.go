@@ -0,0 +1,42 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"slices"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/nats"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/service"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+)
+
+// attestationOptions wires verified profile fields when enabled; the NATS
+// client opens their bucket on connect
+func attestationOptions(ctx context.Context, natsClient *nats.NATSClient) []service.MessageHandlerOrchestratorOption {
+	if !envBool(constants.AttestationEnabledEnvKey, false) {
+		return nil
+	}
+
+	fields := envList(constants.AttestationFieldsEnvKey)
+	known := model.MetadataFieldNames()
+	for _, field := range fields {
+		if !slices.Contains(known, field) {
+			log.Fatalf("invalid %s entry %q: not a metadata field", constants.AttestationFieldsEnvKey, field)
+		}
+	}
+
+	kv, ok := natsClient.GetKVStore(constants.KVBucketNameUserAttestations)
+	if !ok {
+		log.Fatalf("attestation enabled but the %s KV bucket is not available", constants.KVBucketNameUserAttestations)
+	}
+	slog.InfoContext(ctx, "verified profile fields enabled", "fields", fields)
+
+	return []service.MessageHandlerOrchestratorOption{
+		service.WithAttestationStoreForMessageHandler(nats.NewAttestationStore(kv), fields),
+	}
+}
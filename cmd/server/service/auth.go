@@ -5,6 +5,7 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	authservice "github.com/linuxfoundation/lfx-v2-auth-service/gen/auth_service"
@@ -30,6 +31,15 @@ func (s *authService) Readyz(ctx context.Context) ([]byte, error) {
 		}
 	}
 
+	// the first requests would wait on the provider token and cold caches
+	if !warmedUp() {
+		return nil, errors.New("warming up")
+	}
+
+	// reads are still served, so the replica stays ready
+	if maintenanceMode().ReadOnly() {
+		return []byte("OK (read-only mode)"), nil
+	}
 	return []byte("OK"), nil
 }
 
@@ -0,0 +1,80 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/service"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/redaction"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/scheduler"
+)
+
+const (
+	defaultCanaryProbeInterval = time.Minute
+	defaultCanaryProbeTimeout  = 10 * time.Second
+)
+
+// startCanaryProber schedules the canary user probe when enabled.
+// userReaderWriter must be the raw repository, so probes reach the provider.
+func startCanaryProber(ctx context.Context, userReaderWriter port.UserReaderWriter) {
+	if !envBool(constants.CanaryProbeEnabledEnvKey, false) {
+		return
+	}
+
+	user := strings.TrimSpace(os.Getenv(constants.CanaryProbeUserEnvKey))
+	if user == "" {
+		user = strings.TrimSpace(os.Getenv(constants.SelfTestCanaryUserEnvKey))
+	}
+	if user == "" {
+		log.Fatalf("%s requires %s or %s", constants.CanaryProbeEnabledEnvKey, constants.CanaryProbeUserEnvKey, constants.SelfTestCanaryUserEnvKey)
+	}
+	interval := envDuration(constants.CanaryProbeIntervalEnvKey, defaultCanaryProbeInterval)
+	if interval == 0 {
+		log.Fatalf("invalid %s value: must be a positive duration", constants.CanaryProbeIntervalEnvKey)
+	}
+
+	config := service.CanaryProbeConfig{
+		User:    user,
+		Timeout: envDuration(constants.CanaryProbeTimeoutEnvKey, defaultCanaryProbeTimeout),
+	}
+	tokenFile := os.Getenv(constants.CanaryProbeTokenFileEnvKey)
+	if tokenFile != "" {
+		config.Token = canaryTokenFile(tokenFile)
+	}
+
+	slog.InfoContext(ctx, "scheduling canary probe",
+		"canary_user", redaction.Redact(user),
+		"interval", interval,
+		"timeout", config.Timeout,
+		"writes", tokenFile != "",
+	)
+
+	prober := service.NewCanaryProber(userReaderWriter, userReaderWriter, config)
+	scheduler.Every(ctx, "canary-probe", interval, 0, oneReplica("canary-probe", interval, prober.Run))
+}
+
+// canaryTokenFile reads the canary's access token from path on every probe,
+// so whatever renews the token can rewrite the file in place
+func canaryTokenFile(path string) func(context.Context) (string, error) {
+	return func(context.Context) (string, error) {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		token := strings.TrimSpace(string(raw))
+		if token == "" {
+			return "", errors.New("canary token file is empty")
+		}
+		return token, nil
+	}
+}
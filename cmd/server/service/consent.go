@@ -0,0 +1,46 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"strings"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/nats"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/service"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+)
+
+// consentOptions wires policy acceptance tracking when enabled; the NATS
+// client opens its bucket on connect
+func consentOptions(ctx context.Context, natsClient *nats.NATSClient) []service.MessageHandlerOrchestratorOption {
+	if !envBool(constants.ConsentEnabledEnvKey, false) {
+		return nil
+	}
+
+	policies := make(map[string]string)
+	for _, pair := range envList(constants.ConsentPoliciesEnvKey) {
+		policy, version, ok := strings.Cut(pair, "=")
+		policy, version = strings.TrimSpace(policy), strings.TrimSpace(version)
+		if !ok || policy == "" || version == "" {
+			log.Fatalf("invalid %s entry %q: must be policy=version", constants.ConsentPoliciesEnvKey, pair)
+		}
+		policies[policy] = version
+	}
+	if len(policies) == 0 {
+		log.Fatalf("consent enabled but %s names no policies", constants.ConsentPoliciesEnvKey)
+	}
+
+	kv, ok := natsClient.GetKVStore(constants.KVBucketNameUserConsents)
+	if !ok {
+		log.Fatalf("consent enabled but the %s KV bucket is not available", constants.KVBucketNameUserConsents)
+	}
+	slog.InfoContext(ctx, "policy acceptance tracking enabled", "policies", policies)
+
+	return []service.MessageHandlerOrchestratorOption{
+		service.WithConsentStoreForMessageHandler(nats.NewConsentStore(kv), policies),
+	}
+}
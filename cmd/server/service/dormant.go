@@ -0,0 +1,62 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/service"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/scheduler"
+)
+
+const (
+	defaultDormantThresholdDays = 365
+	defaultDormantScanInterval  = 24 * time.Hour
+	defaultDormantMaxUsers      = 1000
+	// dormantScanInitialDelay keeps the first scan off the startup path
+	dormantScanInitialDelay = 5 * time.Minute
+)
+
+// startDormantAccountsJob schedules the dormant account scan when enabled and
+// supported by the configured user repository.
+func startDormantAccountsJob(ctx context.Context, userReaderWriter port.UserReaderWriter, eventPublisher port.EventPublisher) {
+	if !envBool(constants.DormantAccountsJobEnabledEnvKey, false) {
+		return
+	}
+
+	scanner, ok := userReaderWriter.(port.DormantUserScanner)
+	if !ok {
+		slog.WarnContext(ctx, "dormant account job enabled but the user repository does not expose last-login data",
+			"repository_type", os.Getenv(constants.UserRepositoryTypeEnvKey),
+		)
+		return
+	}
+
+	interval := envDuration(constants.DormantAccountsScanIntervalEnvKey, defaultDormantScanInterval)
+	if interval == 0 {
+		log.Fatalf("invalid %s value: must be a positive duration", constants.DormantAccountsScanIntervalEnvKey)
+	}
+
+	config := service.DormantAccountsConfig{
+		Threshold: time.Duration(envPositiveInt(constants.DormantAccountsThresholdDaysEnvKey, defaultDormantThresholdDays)) * 24 * time.Hour,
+		MaxUsers:  envPositiveInt(constants.DormantAccountsMaxUsersEnvKey, defaultDormantMaxUsers),
+		TagUsers:  envBool(constants.DormantAccountsTagUsersEnvKey, false),
+	}
+
+	slog.InfoContext(ctx, "scheduling dormant account job",
+		"interval", interval,
+		"threshold", config.Threshold,
+		"max_users", config.MaxUsers,
+		"tag_users", config.TagUsers,
+	)
+
+	job := service.NewDormantAccountsJob(scanner, eventPublisher, config)
+	scheduler.Every(ctx, "dormant-accounts", interval, dormantScanInitialDelay, oneReplica("dormant-accounts", interval, job.Run))
+}
@@ -0,0 +1,23 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/jwt"
+)
+
+const defaultDPoPReplayCacheMaxEntries = 100000
+
+// dpopVerifier returns the DPoP proof verifier when DPoP is enabled, or nil
+func dpopVerifier() *jwt.DPoPVerifier {
+	if !envBool(constants.DPoPEnabledEnvKey, false) {
+		return nil
+	}
+	return jwt.NewDPoPVerifier(
+		envDuration(constants.DPoPProofWindowEnvKey, jwt.DefaultDPoPWindow),
+		envPositiveInt(constants.DPoPReplayCacheMaxEntriesEnvKey, defaultDPoPReplayCacheMaxEntries),
+		envBool(constants.DPoPRequiredEnvKey, false),
+	)
+}
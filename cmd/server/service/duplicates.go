@@ -0,0 +1,65 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/service"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/scheduler"
+)
+
+const (
+	defaultDuplicateScanInterval      = 24 * time.Hour
+	defaultDuplicateMaxCandidates     = 1000
+	defaultDuplicateNameSimilarity    = 0.9
+	defaultDuplicateMinMatchingFields = 1
+	// duplicateScanInitialDelay runs the first scan after the dormant one
+	duplicateScanInitialDelay = 10 * time.Minute
+)
+
+// startDuplicateAccountsJob schedules the duplicate account scan when enabled
+// and the configured user repository can list every user.
+func startDuplicateAccountsJob(ctx context.Context, userReaderWriter port.UserReaderWriter, eventPublisher port.EventPublisher) {
+	if !envBool(constants.DuplicateAccountsJobEnabledEnvKey, false) {
+		return
+	}
+
+	exporter, ok := userReaderWriter.(port.UserExporter)
+	if !ok {
+		slog.WarnContext(ctx, "duplicate account job enabled but the user repository does not support exporting users",
+			"repository_type", os.Getenv(constants.UserRepositoryTypeEnvKey),
+		)
+		return
+	}
+
+	interval := envDuration(constants.DuplicateAccountsScanIntervalEnvKey, defaultDuplicateScanInterval)
+	if interval == 0 {
+		log.Fatalf("invalid %s value: must be a positive duration", constants.DuplicateAccountsScanIntervalEnvKey)
+	}
+
+	config := service.DuplicateAccountsConfig{
+		NameSimilarity:    envFraction(constants.DuplicateAccountsNameSimilarityEnvKey, defaultDuplicateNameSimilarity),
+		MinMatchingFields: envPositiveInt(constants.DuplicateAccountsMinMatchingFieldsEnvKey, defaultDuplicateMinMatchingFields),
+		MaxCandidates:     envPositiveInt(constants.DuplicateAccountsMaxCandidatesEnvKey, defaultDuplicateMaxCandidates),
+		AllowList:         envList(constants.DuplicateAccountsAllowListEnvKey),
+	}
+
+	slog.InfoContext(ctx, "scheduling duplicate account job",
+		"interval", interval,
+		"name_similarity", config.NameSimilarity,
+		"min_matching_fields", config.MinMatchingFields,
+		"max_candidates", config.MaxCandidates,
+		"allow_list", len(config.AllowList),
+	)
+
+	job := service.NewDuplicateAccountsJob(exporter, eventPublisher, config)
+	scheduler.Every(ctx, "duplicate-accounts", interval, duplicateScanInitialDelay, oneReplica("duplicate-accounts", interval, job.Run))
+}
@@ -0,0 +1,33 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"log"
+	"log/slog"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/nats"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/service"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+)
+
+// emailOrganizationOptions wires the email domain to organization mappings
+// when enabled; the NATS client opens their bucket on connect
+func emailOrganizationOptions(ctx context.Context, natsClient *nats.NATSClient) []service.MessageHandlerOrchestratorOption {
+	if !envBool(constants.EmailOrganizationsEnabledEnvKey, false) {
+		return nil
+	}
+
+	kv, ok := natsClient.GetKVStore(constants.KVBucketNameEmailOrganizations)
+	if !ok {
+		log.Fatalf("email organizations enabled but the %s KV bucket is not available", constants.KVBucketNameEmailOrganizations)
+	}
+	publicDomains := envList(constants.EmailOrganizationsPublicDomainsEnvKey)
+	slog.InfoContext(ctx, "email organizations enabled", "extra_public_domains", publicDomains)
+
+	return []service.MessageHandlerOrchestratorOption{
+		service.WithEmailOrganizationsForMessageHandler(nats.NewEmailOrganizationStore(kv), publicDomains),
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/encryption"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/scheduler"
+)
+
+const (
+	defaultKVReencryptInterval = time.Hour
+	defaultKVDataKeyTTL        = 5 * time.Minute
+	defaultKVDataKeyMaxUses    = 1000
+	// kvReencryptInitialDelay lets the initial Authelia sync finish first
+	kvReencryptInitialDelay = time.Minute
+)
+
+// newKVEnvelope builds the envelope used to encrypt Authelia KV values, or
+// returns nil when KV encryption is not configured. With the kms key source,
+// keys from KV_ENCRYPTION_KEYS remain usable for reading, which allows
+// migrating from static keys to KMS.
+func newKVEnvelope(ctx context.Context) *encryption.Envelope {
+	rawKeys := strings.TrimSpace(os.Getenv(constants.KVEncryptionKeysEnvKey))
+
+	source := os.Getenv(constants.KVEncryptionKeySourceEnvKey)
+	if source == "" && rawKeys != "" {
+		source = constants.KVEncryptionKeySourceStatic
+	}
+
+	switch source {
+	case "":
+		return nil
+	case constants.KVEncryptionKeySourceStatic:
+		activeKeyID := os.Getenv(constants.KVEncryptionActiveKeyIDEnvKey)
+		if activeKeyID == "" {
+			log.Fatalf("%s is required when %s is set", constants.KVEncryptionActiveKeyIDEnvKey, constants.KVEncryptionKeysEnvKey)
+		}
+		keyring := staticKeyring(activeKeyID, rawKeys)
+
+		slog.InfoContext(ctx, "KV encryption enabled", "key_source", source, "active_key_id", activeKeyID)
+		return encryption.NewEnvelope(keyring)
+	case constants.KVEncryptionKeySourceKMS:
+		provider := keyManagementService(ctx)
+		if provider == nil {
+			log.Fatalf("%s=%s requires %s", constants.KVEncryptionKeySourceEnvKey, source, constants.KMSProviderEnvKey)
+		}
+
+		var wrapper encryption.KeyWrapper = provider
+		if rawKeys != "" {
+			activeKeyID := os.Getenv(constants.KVEncryptionActiveKeyIDEnvKey)
+			if activeKeyID == "" {
+				// The static ring only unwraps here, any of its keys can be nominally active
+				activeKeyID, _, _ = strings.Cut(strings.TrimSpace(strings.Split(rawKeys, ",")[0]), ":")
+			}
+			wrapper = encryption.Chain(provider, staticKeyring(activeKeyID, rawKeys))
+		}
+
+		ttl := envDuration(constants.KVEncryptionDataKeyTTLEnvKey, defaultKVDataKeyTTL)
+		maxUses := envPositiveInt(constants.KVEncryptionDataKeyMaxUsesEnvKey, defaultKVDataKeyMaxUses)
+
+		slog.InfoContext(ctx, "KV encryption enabled",
+			"key_source", source,
+			"active_key_id", wrapper.ActiveKeyID(),
+			"data_key_ttl", ttl,
+			"data_key_max_uses", maxUses,
+			"static_fallback", rawKeys != "",
+		)
+		return encryption.NewEnvelope(wrapper, encryption.WithDataKeyCache(ttl, maxUses))
+	default:
+		log.Fatalf("unsupported %s value: %s", constants.KVEncryptionKeySourceEnvKey, source)
+		return nil
+	}
+}
+
+func staticKeyring(activeKeyID, rawKeys string) *encryption.StaticKeyring {
+	keyring, err := encryption.ParseStaticKeyring(activeKeyID, rawKeys)
+	if err != nil {
+		log.Fatalf("invalid %s value: %v", constants.KVEncryptionKeysEnvKey, err)
+	}
+	return keyring
+}
+
+// startKVReencryptionJob schedules the background pass that rewrites KV
+// values with the active key, so retired keys can be dropped after a rotation.
+func startKVReencryptionJob(ctx context.Context, userReaderWriter port.UserReaderWriter) {
+	reencrypter, ok := userReaderWriter.(port.StorageReencrypter)
+	if !ok {
+		return
+	}
+
+	interval := envDuration(constants.KVEncryptionReencryptIntervalEnvKey, defaultKVReencryptInterval)
+	if interval == 0 {
+		slog.InfoContext(ctx, "KV re-encryption job disabled")
+		return
+	}
+
+	scheduler.Every(ctx, "kv-reencrypt", interval, kvReencryptInitialDelay, func(ctx context.Context) error {
+		_, err := reencrypter.ReencryptStorage(ctx)
+		return err
+	})
+}
@@ -0,0 +1,468 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/nats"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+)
+
+const (
+	// serviceDescription is advertised by `nats micro info`
+	serviceDescription = "LFX v2 authentication and user profile service"
+
+	// request formats advertised in endpoint metadata
+	requestFormatText = "text/plain"
+	requestFormatJSON = "application/json"
+)
+
+// endpointSpec describes a request/reply subject exposed as a NATS micro endpoint
+type endpointSpec struct {
+	subject     string
+	description string
+	// request is the payload format; the schema is served on the schema
+	// subject and documented in docs
+	request string
+	docs    string
+	// write marks subjects that change state or have side effects; they are
+	// always request-logged, while successful reads are sampled
+	write bool
+	// perInstance endpoints are answered by every replica instead of one queue member
+	perInstance bool
+	// batch endpoints carry bulk traffic and are processed by the batch
+	// worker pool, so they can't starve interactive reads
+	batch bool
+}
+
+// serviceEndpoints lists every request/reply subject the service answers on
+var serviceEndpoints = []endpointSpec{
+	{
+		subject:     constants.UserEmailToUserSubject,
+		description: "Resolve a username from an email address",
+		request:     requestFormatText + "; email",
+		docs:        "docs/subjects/email_lookups.md",
+	},
+	{
+		subject:     constants.UserEmailToUserBatchSubject,
+		description: "Resolve usernames for a batch of email addresses",
+		request:     requestFormatJSON,
+		docs:        "docs/subjects/email_lookups.md",
+		batch:       true,
+	},
+	{
+		subject:     constants.UserEmailToSubSubject,
+		description: "Resolve a subject identifier from an email address",
+		request:     requestFormatText + "; email",
+		docs:        "docs/subjects/email_lookups.md",
+	},
+	{
+		subject:     constants.UserUsernameToSubSubject,
+		description: "Resolve a subject identifier from a username",
+		request:     requestFormatText + "; username",
+		docs:        "docs/subjects/username_lookups.md",
+	},
+	{
+		subject:     constants.UserSubToUsernameSubject,
+		description: "Resolve a username from a subject identifier",
+		request:     requestFormatText + "; sub",
+		docs:        "docs/subjects/username_lookups.md",
+	},
+	{
+		subject:     constants.UserSearchSubject,
+		description: "Search users by a username, email or name fragment (privileged)",
+		request:     requestFormatJSON,
+		docs:        "docs/subjects/user_search.md",
+	},
+	{
+		subject:     constants.EmailOrganizationSubject,
+		description: "Organization of an email address or domain, from the domain mappings",
+		request:     requestFormatText + "; email or domain",
+		docs:        "docs/subjects/email_organizations.md",
+	},
+	{
+		subject:     constants.UserHasPermissionSubject,
+		description: "Check whether a subject identifier holds a permission or role",
+		request:     requestFormatJSON,
+		docs:        "docs/subjects/permissions.md",
+	},
+	{
+		subject:     constants.UserHasPermissionBatchSubject,
+		description: "Evaluate a batch of permission and role checks",
+		request:     requestFormatJSON,
+		docs:        "docs/subjects/permissions.md",
+		batch:       true,
+	},
+	{
+		subject:     constants.TokenCheckScopeSubject,
+		description: "Verify an access token and report the scopes and permissions it grants",
+		request:     requestFormatJSON,
+		docs:        "docs/subjects/token_check_scope.md",
+	},
+	{
+		subject:     constants.TokenCheckStepUpSubject,
+		description: "Report whether a token's sign-in is too old or lacks MFA for a sensitive operation",
+		request:     requestFormatJSON,
+		docs:        "docs/subjects/step_up.md",
+	},
+	{
+		subject:     constants.ServiceAccountCreateSubject,
+		description: "Create a service account with scoped grants (privileged)",
+		request:     requestFormatJSON,
+		docs:        "docs/subjects/service_accounts.md",
+		write:       true,
+	},
+	{
+		subject:     constants.ServiceAccountListSubject,
+		description: "List the service accounts managed by the service (privileged)",
+		request:     requestFormatJSON,
+		docs:        "docs/subjects/service_accounts.md",
+	},
+	{
+		subject:     constants.ServiceAccountRotateSubject,
+		description: "Rotate the client secret of a service account (privileged)",
+		request:     requestFormatJSON,
+		docs:        "docs/subjects/service_accounts.md",
+		write:       true,
+	},
+	{
+		subject:     constants.UserSessionsListSubject,
+		description: "List the login sessions and refresh tokens of a user",
+		request:     requestFormatJSON,
+		docs:        "docs/subjects/sessions.md",
+	},
+	{
+		subject:     constants.UserSessionsRevokeSubject,
+		description: "Revoke one or every session of a user",
+		request:     requestFormatJSON,
+		docs:        "docs/subjects/sessions.md",
+		write:       true,
+	},
+	{
+		subject:     constants.PersonalAccessTokenCreateSubject,
+		description: "Mint a personal access token for the caller",
+		request:     requestFormatJSON,
+		docs:        "docs/subjects/personal_access_tokens.md",
+		write:       true,
+	},
+	{
+		subject:     constants.PersonalAccessTokenListSubject,
+		description: "List the caller's personal access tokens",
+		request:     requestFormatJSON,
+		docs:        "docs/subjects/personal_access_tokens.md",
+	},
+	{
+		subject:     constants.PersonalAccessTokenRevokeSubject,
+		description: "Revoke one of the caller's personal access tokens",
+		request:     requestFormatJSON,
+		docs:        "docs/subjects/personal_access_tokens.md",
+		write:       true,
+	},
+	{
+		subject:     constants.PersonalAccessTokenValidateSubject,
+		description: "Exchange a personal access token for the identity of its user",
+		request:     requestFormatJSON,
+		docs:        "docs/subjects/personal_access_tokens.md",
+	},
+	{
+		subject:     constants.UserMetadataReadSubject,
+		description: "Read a user's profile metadata",
+		request:     requestFormatText + "; jwt, username or sub",
+		docs:        "docs/subjects/user_metadata.md",
+	},
+	{
+		subject:     constants.UserMetadataUpdateSubject,
+		description: "Update a user's profile metadata",
+		request:     requestFormatJSON,
+		docs:        "docs/subjects/user_metadata.md",
+		write:       true,
+	},
+	{
+		subject:     constants.UserPreferencesReadSubject,
+		description: "Read a user's notification preferences, defaults applied",
+		request:     requestFormatText + "; jwt, username or sub",
+		docs:        "docs/subjects/user_preferences.md",
+	},
+	{
+		subject:     constants.UserPreferencesUpdateSubject,
+		description: "Update a user's notification preferences, leaving the rest of the metadata alone",
+		request:     requestFormatJSON,
+		docs:        "docs/subjects/user_preferences.md",
+		write:       true,
+	},
+	{
+		subject:     constants.UserEmailReadSubject,
+		description: "List a user's primary and alternate emails",
+		request:     requestFormatJSON,
+		docs:        "docs/subjects/user_emails.md",
+	},
+	{
+		subject:     constants.UserEmailSetPrimarySubject,
+		description: "Promote a verified email to primary",
+		request:     requestFormatJSON,
+		docs:        "docs/subjects/user_emails.md",
+		write:       true,
+	},
+	{
+		subject:     constants.EmailLinkingSendVerificationSubject,
+		description: "Send a one-time code to an alternate email",
+		request:     requestFormatText + "; email",
+		docs:        "docs/subjects/email_verification.md",
+		write:       true,
+	},
+	{
+		subject:     constants.EmailLinkingVerifySubject,
+		description: "Verify an alternate email one-time code",
+		request:     requestFormatJSON,
+		docs:        "docs/subjects/email_verification.md",
+		write:       true,
+	},
+	{
+		subject:     constants.UserIdentityLinkSubject,
+		description: "Link a verified identity to a user",
+		request:     requestFormatJSON,
+		docs:        "docs/subjects/identity_linking.md",
+		write:       true,
+	},
+	{
+		subject:     constants.UserIdentityUnlinkSubject,
+		description: "Unlink a secondary identity from a user",
+		request:     requestFormatJSON,
+		docs:        "docs/subjects/identity_linking.md",
+		write:       true,
+	},
+	{
+		subject:     constants.UserIdentityListSubject,
+		description: "List a user's linked identities",
+		request:     requestFormatJSON,
+		docs:        "docs/subjects/identity_linking.md",
+	},
+	{
+		subject:     constants.UserAddAliasSubject,
+		description: "Claim a system-managed alias email",
+		request:     requestFormatJSON,
+		docs:        "docs/subjects/alias.md",
+		write:       true,
+	},
+	{
+		subject:     constants.PasswordUpdateSubject,
+		description: "Change a user's password",
+		request:     requestFormatJSON,
+		docs:        "docs/subjects/password_management.md",
+		write:       true,
+	},
+	{
+		subject:     constants.PasswordResetLinkSubject,
+		description: "Send a password reset link",
+		request:     requestFormatJSON,
+		docs:        "docs/subjects/password_management.md",
+		write:       true,
+	},
+	{
+		subject:     constants.ImpersonationTokenExchangeSubject,
+		description: "Exchange a token to act as another user",
+		request:     requestFormatJSON,
+		docs:        "docs/subjects/impersonation.md",
+		write:       true,
+	},
+	{
+		subject:     constants.AdminStatsSubject,
+		description: "Per-instance request, cache and provider counters",
+		request:     requestFormatText + "; empty",
+		docs:        "docs/subjects/admin.md",
+		perInstance: true,
+	},
+	{
+		subject:     constants.AdminSelfTestSubject,
+		description: "Run provider, JWKS, KV and cache checks and report pass/fail (privileged)",
+		request:     requestFormatJSON,
+		docs:        "docs/subjects/admin.md",
+		// the canary read warms the caches and can record its access time
+		write: true,
+	},
+	{
+		subject:     constants.AdminRevokeTokenSubject,
+		description: "Revoke an access token or every token of a user (privileged)",
+		request:     requestFormatJSON,
+		docs:        "docs/subjects/admin.md",
+		write:       true,
+	},
+	{
+		subject:     constants.AdminConfigSubject,
+		description: "Effective runtime configuration of the replica, secrets masked (privileged)",
+		request:     requestFormatJSON,
+		docs:        "docs/subjects/admin.md",
+		perInstance: true,
+	},
+	{
+		subject:     constants.AdminUsersImportSubject,
+		description: "Import the users of a manifest into an identity provider connection (privileged)",
+		request:     requestFormatJSON,
+		docs:        "docs/subjects/admin.md",
+		write:       true,
+		batch:       true,
+	},
+	{
+		subject:     constants.AdminUsersImportStatusSubject,
+		description: "Progress and rejected records of a bulk user import (privileged)",
+		request:     requestFormatJSON,
+		docs:        "docs/subjects/admin.md",
+	},
+	{
+		subject:     constants.AdminUsersExportSubject,
+		description: "Export chosen attributes of every user for reporting (privileged)",
+		request:     requestFormatJSON,
+		docs:        "docs/subjects/admin.md",
+		batch:       true,
+	},
+	{
+		subject:     constants.AdminUsersMergeSubject,
+		description: "Merge a duplicate account into another one (privileged)",
+		request:     requestFormatJSON,
+		docs:        "docs/subjects/admin.md",
+		write:       true,
+	},
+	{
+		subject:     constants.AdminEmailOrganizationSetSubject,
+		description: "Map an email domain to an organization (privileged)",
+		request:     requestFormatJSON,
+		docs:        "docs/subjects/email_organizations.md",
+		write:       true,
+	},
+	{
+		subject:     constants.AdminEmailOrganizationDeleteSubject,
+		description: "Remove the organization mapping of an email domain (privileged)",
+		request:     requestFormatJSON,
+		docs:        "docs/subjects/email_organizations.md",
+		write:       true,
+	},
+	{
+		subject:     constants.AdminEmailOrganizationListSubject,
+		description: "List the email domain to organization mappings (privileged)",
+		request:     requestFormatJSON,
+		docs:        "docs/subjects/email_organizations.md",
+	},
+	{
+		subject:     constants.UserConsentRecordSubject,
+		description: "Record that the token's user accepted the current version of a policy",
+		request:     requestFormatJSON,
+		docs:        "docs/subjects/consent.md",
+		write:       true,
+	},
+	{
+		subject:     constants.UserConsentStatusSubject,
+		description: "Whether a user accepted the current versions of the policies",
+		request:     requestFormatJSON,
+		docs:        "docs/subjects/consent.md",
+	},
+	{
+		subject:     constants.UserAffiliationsAddSubject,
+		description: "Add an employer affiliation to the history of a user",
+		request:     requestFormatJSON,
+		docs:        "docs/subjects/affiliations.md",
+		write:       true,
+	},
+	{
+		subject:     constants.UserAffiliationsEndSubject,
+		description: "Set the end date of a current affiliation",
+		request:     requestFormatJSON,
+		docs:        "docs/subjects/affiliations.md",
+		write:       true,
+	},
+	{
+		subject:     constants.UserAffiliationsListSubject,
+		description: "Current and past employer affiliations of a user",
+		request:     requestFormatJSON,
+		docs:        "docs/subjects/affiliations.md",
+	},
+	{
+		subject:     constants.AdminAttestationRecordSubject,
+		description: "Mark the current value of a profile field of a user as verified (privileged)",
+		request:     requestFormatJSON,
+		docs:        "docs/subjects/attestation.md",
+		write:       true,
+	},
+	{
+		subject:     constants.AdminAttestationRevokeSubject,
+		description: "Remove the verification of a profile field of a user (privileged)",
+		request:     requestFormatJSON,
+		docs:        "docs/subjects/attestation.md",
+		write:       true,
+	},
+	{
+		subject:     constants.AdminStaticCacheFlushSubject,
+		description: "Flush the cached roles, connections and organizations of the provider (privileged)",
+		request:     requestFormatJSON,
+		docs:        "docs/subjects/admin.md",
+		write:       true,
+		perInstance: true,
+	},
+	{
+		subject:     constants.AdminReindexSubject,
+		description: "Rebuild the lookup keys of the Authelia users, reporting progress as events (privileged)",
+		request:     requestFormatJSON,
+		docs:        "docs/subjects/admin.md",
+		write:       true,
+	},
+	{
+		subject:     constants.AdminMaintenanceSubject,
+		description: "Switch read-only maintenance mode of every replica on or off (privileged)",
+		request:     requestFormatJSON,
+		docs:        "docs/subjects/admin.md",
+		write:       true,
+		perInstance: true,
+	},
+	{
+		subject:     constants.SchemaSubject,
+		description: "JSON Schemas of the requests and replies of every subject",
+		request:     requestFormatText + "; subject or empty",
+		docs:        "docs/subjects/schema.md",
+	},
+}
+
+func (spec endpointSpec) kind() string {
+	if spec.write {
+		return "write"
+	}
+	return "read"
+}
+
+func (spec endpointSpec) priority() nats.Priority {
+	if spec.batch {
+		return nats.PriorityBatch
+	}
+	return nats.PriorityInteractive
+}
+
+// endpointName derives the micro endpoint name from its subject,
+// e.g. lfx.auth-service.user_metadata.read -> user_metadata_read
+func endpointName(subject string) string {
+	name := strings.TrimPrefix(subject, constants.SubjectPrefix)
+	return strings.ReplaceAll(name, ".", "_")
+}
+
+// buildServiceEndpoints binds every endpoint spec to handler
+func buildServiceEndpoints(handler func(context.Context, port.TransportMessenger)) []nats.ServiceEndpoint {
+	endpoints := make([]nats.ServiceEndpoint, 0, len(serviceEndpoints))
+	for _, spec := range serviceEndpoints {
+		endpoints = append(endpoints, nats.ServiceEndpoint{
+			Name:    endpointName(spec.subject),
+			Subject: spec.subject,
+			Metadata: map[string]string{
+				"description": spec.description,
+				"kind":        spec.kind(),
+				"priority":    string(spec.priority()),
+				"request":     spec.request,
+				"docs":        spec.docs,
+			},
+			Handler:  handler,
+			NoQueue:  spec.perInstance,
+			Priority: spec.priority(),
+		})
+	}
+	return endpoints
+}
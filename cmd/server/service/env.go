@@ -0,0 +1,88 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// envList splits a comma-separated environment variable, dropping blank entries
+func envList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// envBool parses a boolean environment variable, exiting on malformed values
+func envBool(key string, fallback bool) bool {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+	parsed, err := strconv.ParseBool(raw)
+	if err != nil {
+		log.Fatalf("invalid %s value %s: %v", key, raw, err)
+	}
+	return parsed
+}
+
+// envPositiveInt parses a positive integer environment variable, exiting on malformed values
+func envPositiveInt(key string, fallback int) int {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+	parsed, err := strconv.Atoi(raw)
+	if err != nil || parsed <= 0 {
+		log.Fatalf("invalid %s value %s: must be a positive integer", key, raw)
+	}
+	return parsed
+}
+
+// envNonNegativeInt parses a non-negative integer environment variable, exiting on malformed values
+func envNonNegativeInt(key string, fallback int) int {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+	parsed, err := strconv.Atoi(raw)
+	if err != nil || parsed < 0 {
+		log.Fatalf("invalid %s value %s: must be a non-negative integer", key, raw)
+	}
+	return parsed
+}
+
+// envDuration parses a duration environment variable, exiting on malformed or negative values
+func envDuration(key string, fallback time.Duration) time.Duration {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+	parsed, err := time.ParseDuration(raw)
+	if err != nil || parsed < 0 {
+		log.Fatalf("invalid %s value %s: must be a non-negative duration", key, raw)
+	}
+	return parsed
+}
+
+// envFraction parses a fraction in [0, 1], exiting on malformed or out-of-range values
+func envFraction(key string, fallback float64) float64 {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+	parsed, err := strconv.ParseFloat(raw, 64)
+	if err != nil || parsed < 0 || parsed > 1 {
+		log.Fatalf("invalid %s value %s: must be in [0, 1]", key, raw)
+	}
+	return parsed
+}
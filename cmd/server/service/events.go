@@ -0,0 +1,121 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/kafka"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+)
+
+var (
+	// kafkaPublishers are the Kafka publishers created, closed on shutdown
+	kafkaPublishers   []*kafka.Publisher
+	kafkaPublishersMu sync.Mutex
+)
+
+// fanoutEventPublisher publishes every event to all configured sinks. A
+// failing sink does not prevent delivery to the others; the errors are joined.
+type fanoutEventPublisher []port.EventPublisher
+
+func (f fanoutEventPublisher) Publish(ctx context.Context, subject string, data []byte) error {
+	var errs []error
+	for _, publisher := range f {
+		if err := publisher.Publish(ctx, subject, data); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// splitCSV splits a comma-separated env value, trimming blanks.
+func splitCSV(value string) []string {
+	var out []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+func newKafkaPublisher(ctx context.Context) *kafka.Publisher {
+	topic := os.Getenv(constants.KafkaTopicEnvKey)
+	if topic == "" {
+		topic = "lfx.auth-service.user-events"
+	}
+
+	config := kafka.Config{
+		Brokers:       splitCSV(os.Getenv(constants.KafkaBrokersEnvKey)),
+		Topic:         topic,
+		SASLMechanism: os.Getenv(constants.KafkaSASLMechanismEnvKey),
+		SASLUsername:  os.Getenv(constants.KafkaSASLUsernameEnvKey),
+		SASLPassword:  os.Getenv(constants.KafkaSASLPasswordEnvKey),
+		TLSEnabled:    envBool(constants.KafkaTLSEnabledEnvKey, false),
+		TLSCAFile:     os.Getenv(constants.KafkaTLSCAFileEnvKey),
+		WriteTimeout:  10 * time.Second,
+		BatchTimeout:  envDuration(constants.KafkaBatchTimeoutEnvKey, kafka.DefaultBatchTimeout),
+	}
+
+	publisher, err := kafka.NewPublisher(ctx, config)
+	if err != nil {
+		log.Fatalf("failed to create Kafka event publisher: %v", err)
+	}
+
+	kafkaPublishersMu.Lock()
+	defer kafkaPublishersMu.Unlock()
+	kafkaPublishers = append(kafkaPublishers, publisher)
+	return publisher
+}
+
+// CloseEventPublishers closes the Kafka publishers, flushing the events
+// being written. It is called on shutdown, after the subscriptions are
+// cancelled.
+func CloseEventPublishers(ctx context.Context) {
+	kafkaPublishersMu.Lock()
+	defer kafkaPublishersMu.Unlock()
+	for _, publisher := range kafkaPublishers {
+		if err := publisher.Close(); err != nil {
+			slog.WarnContext(ctx, "failed to close Kafka event publisher", "error", err)
+		}
+	}
+	kafkaPublishers = nil
+}
+
+// newEventPublisher builds the event publisher from EVENT_SINKS. NATS is the
+// default sink; Kafka can be used instead of, or alongside, NATS.
+func newEventPublisher(ctx context.Context) port.EventPublisher {
+	sinks := splitCSV(os.Getenv(constants.EventSinksEnvKey))
+	if len(sinks) == 0 {
+		sinks = []string{constants.EventSinkNATS}
+	}
+
+	var publishers fanoutEventPublisher
+	for _, sink := range sinks {
+		switch strings.ToLower(sink) {
+		case constants.EventSinkNATS:
+			publishers = append(publishers, natsClient)
+		case constants.EventSinkKafka:
+			publishers = append(publishers, newKafkaPublisher(ctx))
+		default:
+			log.Fatalf("unsupported event sink: %s", sink)
+		}
+	}
+
+	slog.DebugContext(ctx, "event sinks configured", "sinks", sinks)
+
+	if len(publishers) == 1 {
+		return publishers[0]
+	}
+	return publishers
+}
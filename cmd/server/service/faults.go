@@ -0,0 +1,33 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"os"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/faults"
+)
+
+// newFaultInjector creates the fault injector configured by
+// FAULT_INJECTION_RULES. Production builds refuse to start with rules set.
+func newFaultInjector(ctx context.Context) *faults.Injector {
+	raw := os.Getenv(constants.FaultInjectionRulesEnvKey)
+	if raw == "" {
+		return nil
+	}
+	rules, err := faults.ParseRules(raw)
+	if err != nil {
+		log.Fatalf("invalid %s: %v", constants.FaultInjectionRulesEnvKey, err)
+	}
+	injector, err := faults.New(rules)
+	if err != nil {
+		log.Fatalf("invalid %s: %v", constants.FaultInjectionRulesEnvKey, err)
+	}
+	slog.WarnContext(ctx, "fault injection enabled", "rules", len(rules))
+	return injector
+}
@@ -0,0 +1,46 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"os"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/nats"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/featureflag"
+)
+
+// newFeatureFlags returns the feature flags of the tenant being built: the
+// ones of FEATURE_FLAGS_FILE, overridden by the feature flag bucket when it
+// is followed. Without either every flag is off.
+func newFeatureFlags(ctx context.Context, natsClient *nats.NATSClient) *featureflag.Set {
+	flags := map[string]featureflag.Flag{}
+	if path := os.Getenv(constants.FeatureFlagsFileEnvKey); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Fatalf("failed to read %s: %v", constants.FeatureFlagsFileEnvKey, err)
+		}
+		if flags, err = featureflag.Parse(data); err != nil {
+			log.Fatalf("invalid %s: %v", constants.FeatureFlagsFileEnvKey, err)
+		}
+	}
+	set := featureflag.NewSet(flags)
+
+	if envBool(constants.FeatureFlagsKVEnabledEnvKey, false) {
+		// the NATS client opens the bucket on connect when it is followed
+		kv, ok := natsClient.GetKVStore(constants.KVBucketNameFeatureFlags)
+		if !ok {
+			log.Fatalf("feature flags KV enabled but the %s KV bucket is not available", constants.KVBucketNameFeatureFlags)
+		}
+		if err := nats.WatchFeatureFlags(ctx, kv, set); err != nil {
+			log.Fatalf("failed to load feature flags: %v", err)
+		}
+	} else if len(flags) > 0 {
+		slog.InfoContext(ctx, "feature flags loaded", "flags", set.Names())
+	}
+	return set
+}
@@ -0,0 +1,59 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/service"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+)
+
+const (
+	defaultHedgedReadsPercentile   = 0.95
+	defaultHedgedReadsMinDelay     = 50 * time.Millisecond
+	defaultHedgedReadsMaxPerSecond = 10
+)
+
+// newHedgedReads wraps userReaderWriter with hedged GetUser and SearchUser
+// reads when HEDGED_READS_ENABLED is set, and returns it unchanged otherwise
+func newHedgedReads(ctx context.Context, userReaderWriter port.UserReaderWriter) port.UserReaderWriter {
+	if !envBool(constants.HedgedReadsEnabledEnvKey, false) {
+		return userReaderWriter
+	}
+
+	percentile := envFraction(constants.HedgedReadsPercentileEnvKey, defaultHedgedReadsPercentile)
+	if percentile == 0 || percentile == 1 {
+		log.Fatalf("invalid %s value %v: must be in (0, 1)", constants.HedgedReadsPercentileEnvKey, percentile)
+	}
+
+	maxPerSecond := float64(defaultHedgedReadsMaxPerSecond)
+	if raw := os.Getenv(constants.HedgedReadsMaxPerSecondEnvKey); raw != "" {
+		parsed, err := strconv.ParseFloat(raw, 64)
+		if err != nil || parsed <= 0 {
+			log.Fatalf("invalid %s value %s: must be a positive number", constants.HedgedReadsMaxPerSecondEnvKey, raw)
+		}
+		maxPerSecond = parsed
+	}
+
+	config := service.HedgingConfig{
+		Percentile:   percentile,
+		MinDelay:     envDuration(constants.HedgedReadsMinDelayEnvKey, defaultHedgedReadsMinDelay),
+		MaxPerSecond: maxPerSecond,
+	}
+
+	slog.InfoContext(ctx, "hedged provider reads enabled",
+		"percentile", config.Percentile,
+		"min_delay", config.MinDelay,
+		"max_per_second", config.MaxPerSecond,
+	)
+
+	return service.NewHedgedUserReaderWriter(userReaderWriter, config)
+}
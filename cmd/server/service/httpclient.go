@@ -0,0 +1,39 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/httpclient"
+)
+
+// outboundHTTPConfig returns the default HTTP client configuration with the
+// CA bundles, proxy, per-host overrides and pool settings from HTTP_CLIENT_*.
+func outboundHTTPConfig() httpclient.Config {
+	config := httpclient.DefaultConfig()
+
+	config.CAFiles = envList(constants.HTTPClientCAFilesEnvKey)
+	config.InsecureSkipVerify = envBool(constants.HTTPClientInsecureSkipVerifyEnvKey, false)
+	config.ProxyURL = os.Getenv(constants.HTTPClientProxyURLEnvKey)
+
+	if raw := os.Getenv(constants.HTTPClientHostOverridesEnvKey); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &config.HostOverrides); err != nil {
+			log.Fatalf("invalid %s value: %v", constants.HTTPClientHostOverridesEnvKey, err)
+		}
+	}
+
+	config.MaxIdleConnsPerHost = envPositiveInt(constants.HTTPClientMaxIdleConnsPerHostEnvKey, config.MaxIdleConnsPerHost)
+	config.MaxConnsPerHost = envPositiveInt(constants.HTTPClientMaxConnsPerHostEnvKey, config.MaxConnsPerHost)
+	config.IdleConnTimeout = envDuration(constants.HTTPClientIdleConnTimeoutEnvKey, config.IdleConnTimeout)
+	config.DisableHTTP2 = envBool(constants.HTTPClientDisableHTTP2EnvKey, false)
+
+	if err := config.Validate(); err != nil {
+		log.Fatalf("invalid outbound HTTP client configuration: %v", err)
+	}
+	return config
+}
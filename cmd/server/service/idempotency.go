@@ -0,0 +1,40 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"log"
+	"log/slog"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/nats"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/service"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+)
+
+// newIdempotency returns the idempotency middleware of the write endpoints
+// when enabled, and nil otherwise. The NATS client opens the bucket on
+// connect, so a missing bucket is a deployment error. Per-instance endpoints
+// are left out: every replica handles their request, so a key reserved in
+// the shared bucket by the first one would keep the others from applying it.
+func newIdempotency(ctx context.Context, natsClient *nats.NATSClient, endpoints []endpointSpec) service.Middleware {
+	if !envBool(constants.IdempotencyEnabledEnvKey, false) {
+		return nil
+	}
+
+	kv, ok := natsClient.GetKVStore(constants.KVBucketNameIdempotency)
+	if !ok {
+		log.Fatalf("idempotency enabled but the %s KV bucket is not available", constants.KVBucketNameIdempotency)
+	}
+
+	writes := make(map[string]bool, len(endpoints))
+	for _, spec := range endpoints {
+		if spec.write && !spec.perInstance {
+			writes[spec.subject] = true
+		}
+	}
+
+	slog.InfoContext(ctx, "idempotency keys enabled", "subjects", len(writes))
+	return service.Idempotency(nats.NewIdempotencyStore(kv), func(subject string) bool { return writes[subject] })
+}
@@ -0,0 +1,38 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/nats"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/scheduler"
+)
+
+// jobLockOwner tells the process apart from the other replicas in the leases
+var jobLockOwner = sync.OnceValue(func() string {
+	hostname, _ := os.Hostname()
+	return hostname + "-" + uuid.NewString()
+})
+
+// oneReplica returns job running on a single replica when JOB_LOCK_ENABLED
+// is set, and job itself, run by every replica, otherwise. The lease lasts
+// two intervals: the replica holding it renews it at its next run, and a
+// replica that stops is taken over within two intervals.
+func oneReplica(name string, interval time.Duration, job scheduler.Job) scheduler.Job {
+	if !envBool(constants.JobLockEnabledEnvKey, false) {
+		return job
+	}
+	kv, ok := natsClient.GetKVStore(constants.KVBucketNameJobLocks)
+	if !ok {
+		log.Fatalf("job lock enabled but the %s KV bucket is not available", constants.KVBucketNameJobLocks)
+	}
+	return scheduler.Exclusive(nats.NewJobLock(kv, jobLockOwner()), name, 2*interval, job)
+}
@@ -0,0 +1,101 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/kms"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/encryption"
+)
+
+// kmsSecretPrefix marks environment values holding KMS ciphertext
+const kmsSecretPrefix = "kms:"
+
+// kmsSecretEnvKeys are the environment variables that may be supplied as KMS ciphertext
+var kmsSecretEnvKeys = []string{
+	constants.Auth0M2MPrivateBase64KeyEnvKey,
+	constants.Auth0LFXProfileClientSecretEnvKey,
+	constants.Auth0LogStreamTokenEnvKey,
+	constants.EmailSMTPPasswordEnvKey,
+	constants.KafkaSASLPasswordEnvKey,
+	constants.KVEncryptionKeysEnvKey,
+}
+
+// kmsProvider is a cloud KMS key usable for both data keys and secrets
+type kmsProvider interface {
+	encryption.KeyWrapper
+	port.SecretDecrypter
+}
+
+var (
+	kmsOnce     sync.Once
+	kmsInstance kmsProvider
+)
+
+// keyManagementService returns the KMS key selected by KMS_PROVIDER, or nil
+// when none is configured. The client is created once.
+func keyManagementService(ctx context.Context) kmsProvider {
+	kmsOnce.Do(func() {
+		provider := os.Getenv(constants.KMSProviderEnvKey)
+		if provider == "" {
+			return
+		}
+
+		keyID := os.Getenv(constants.KMSKeyIDEnvKey)
+		var err error
+		switch provider {
+		case constants.KMSProviderAWS:
+			kmsInstance, err = kms.NewAWSKeyWrapper(ctx, keyID)
+		case constants.KMSProviderGCP:
+			kmsInstance, err = kms.NewGCPKeyWrapper(ctx, outboundHTTPConfig(), keyID)
+		default:
+			log.Fatalf("unsupported %s value: %s", constants.KMSProviderEnvKey, provider)
+		}
+		if err != nil {
+			log.Fatalf("failed to initialize %s KMS key %s: %v", provider, keyID, err)
+		}
+		slog.InfoContext(ctx, "KMS initialized", "provider", provider, "active_key_id", kmsInstance.ActiveKeyID())
+	})
+	return kmsInstance
+}
+
+// DecryptSecrets replaces secret environment values of the form
+// "kms:<base64 ciphertext>" with their plaintext, so the rest of the startup
+// code reads secrets the same way regardless of how they were delivered.
+func DecryptSecrets(ctx context.Context) error {
+	for _, key := range kmsSecretEnvKeys {
+		encoded, ok := strings.CutPrefix(os.Getenv(key), kmsSecretPrefix)
+		if !ok {
+			continue
+		}
+
+		decrypter := keyManagementService(ctx)
+		if decrypter == nil {
+			return fmt.Errorf("%s is KMS-encrypted but %s is not set", key, constants.KMSProviderEnvKey)
+		}
+		ciphertext, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return fmt.Errorf("%s: invalid base64 ciphertext: %w", key, err)
+		}
+		plaintext, err := decrypter.DecryptSecret(ctx, ciphertext)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		if err := os.Setenv(key, string(plaintext)); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		slog.DebugContext(ctx, "decrypted KMS secret", "env", key)
+	}
+	return nil
+}
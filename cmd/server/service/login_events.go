@@ -0,0 +1,61 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/auth0"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/memory"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/service"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+)
+
+var (
+	// loginEventIngester is set once the event publisher is available; the
+	// webhook handler is mounted earlier, together with the health endpoints.
+	loginEventIngester atomic.Pointer[port.LoginEventIngester]
+
+	// userActivityStore holds last-login data observed from the log stream
+	userActivityStore = memory.NewActivityStore(30*24*time.Hour, 100_000)
+)
+
+// logStreamEnabled reports whether the Auth0 Log Streaming webhook is configured
+func logStreamEnabled() bool {
+	return os.Getenv(constants.Auth0LogStreamTokenEnvKey) != ""
+}
+
+// initLoginEventIngester wires the ingester used by the log stream webhook
+func initLoginEventIngester(eventPublisher port.EventPublisher) {
+	if !logStreamEnabled() {
+		return
+	}
+	ingester := service.NewLoginEventIngester(userActivityStore, eventPublisher)
+	loginEventIngester.Store(&ingester)
+}
+
+// Auth0LogStreamHandler returns the HTTP handler for Auth0 Log Streaming
+// webhooks, or nil when AUTH0_LOG_STREAM_TOKEN is not set.
+func Auth0LogStreamHandler(ctx context.Context) http.Handler {
+	if !logStreamEnabled() {
+		return nil
+	}
+
+	handler, err := auth0.NewLogStreamHandler(ctx, os.Getenv(constants.Auth0LogStreamTokenEnvKey), func() port.LoginEventIngester {
+		if ingester := loginEventIngester.Load(); ingester != nil {
+			return *ingester
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("failed to create Auth0 log stream handler: %v", err)
+	}
+	return handler
+}
@@ -0,0 +1,102 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/disk"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/nats"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/service"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/scheduler"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/tenant"
+)
+
+const (
+	defaultIdentifierCacheSnapshotPath       = "/var/cache/lfx-auth-service/identifier-cache.json"
+	defaultIdentifierCacheSnapshotInterval   = time.Minute
+	defaultIdentifierCacheSnapshotMaxEntries = 5_000
+
+	// identifierCacheSnapshotKey is the key of the snapshot in the cache
+	// warm-up KV bucket
+	identifierCacheSnapshotKey = "identifier-cache"
+	// lookupSnapshotRestoreTimeout bounds the restore, which delays the
+	// subscriptions of the tenant
+	lookupSnapshotRestoreTimeout = 5 * time.Second
+)
+
+// newLookupSnapshot returns the snapshot of the identifier cache kept where
+// IDENTIFIER_CACHE_SNAPSHOT says, nil when it is unset or the cache is
+// disabled
+func newLookupSnapshot(ctx context.Context, natsClient *nats.NATSClient) *service.LookupSnapshot {
+	var store port.SnapshotStore
+	switch kind := strings.ToLower(strings.TrimSpace(os.Getenv(constants.IdentifierCacheSnapshotEnvKey))); kind {
+	case "":
+		return nil
+	case constants.IdentifierCacheSnapshotKV:
+		kv, ok := natsClient.GetKVStore(constants.KVBucketNameCacheWarmup)
+		if !ok {
+			log.Fatalf("identifier cache snapshot set but the %s KV bucket is not available", constants.KVBucketNameCacheWarmup)
+		}
+		store = nats.NewSnapshotStore(kv, identifierCacheSnapshotKey)
+	case constants.IdentifierCacheSnapshotFile:
+		store = disk.NewSnapshotStore(lookupSnapshotPath(ctx))
+	default:
+		log.Fatalf("invalid %s value %s: must be %s or %s", constants.IdentifierCacheSnapshotEnvKey, kind,
+			constants.IdentifierCacheSnapshotKV, constants.IdentifierCacheSnapshotFile)
+	}
+
+	if envDuration(constants.IdentifierCacheTTLEnvKey, defaultIdentifierCacheTTL) == 0 {
+		slog.WarnContext(ctx, "identifier cache snapshot set but the identifier cache is disabled, nothing is snapshotted")
+		return nil
+	}
+	return service.NewLookupSnapshot(store,
+		envPositiveInt(constants.IdentifierCacheSnapshotMaxEntriesEnvKey, defaultIdentifierCacheSnapshotMaxEntries))
+}
+
+// lookupSnapshotPath returns the file of the snapshot of the tenant of ctx:
+// tenants sharing a volume each get their own, e.g. identifier-cache.acme.json
+func lookupSnapshotPath(ctx context.Context) string {
+	path := os.Getenv(constants.IdentifierCacheSnapshotPathEnvKey)
+	if path == "" {
+		path = defaultIdentifierCacheSnapshotPath
+	}
+	if name := tenant.FromContext(ctx); name != "" {
+		ext := filepath.Ext(path)
+		path = strings.TrimSuffix(path, ext) + "." + name + ext
+	}
+	return path
+}
+
+// startLookupSnapshot restores snapshot, before the tenant subscribes so its
+// first lookups are answered from it, then saves it every
+// IDENTIFIER_CACHE_SNAPSHOT_INTERVAL. A snapshot that can't be restored is
+// logged and the cache starts empty.
+func startLookupSnapshot(ctx context.Context, snapshot *service.LookupSnapshot) {
+	if snapshot == nil {
+		return
+	}
+	interval := envDuration(constants.IdentifierCacheSnapshotIntervalEnvKey, defaultIdentifierCacheSnapshotInterval)
+	if interval == 0 {
+		log.Fatalf("invalid %s value: must be a positive duration", constants.IdentifierCacheSnapshotIntervalEnvKey)
+	}
+
+	restoreCtx, cancel := context.WithTimeout(ctx, lookupSnapshotRestoreTimeout)
+	restored, err := snapshot.Restore(restoreCtx)
+	cancel()
+	if err != nil {
+		slog.WarnContext(ctx, "failed to restore the identifier cache snapshot, starting empty", "error", err)
+	} else {
+		slog.InfoContext(ctx, "identifier cache snapshot restored", "restored", restored, "interval", interval)
+	}
+	scheduler.Every(ctx, "identifier-cache-snapshot", interval, interval, snapshot.Save)
+}
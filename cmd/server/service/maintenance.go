@@ -0,0 +1,51 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"log"
+	"os"
+	"sync"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/nats"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/service"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+)
+
+// maintenanceMode is the read-only switch of the process, shared by the
+// endpoints of every tenant, the readiness check and the stats
+var maintenanceMode = sync.OnceValue(func() *service.MaintenanceMode {
+	return service.NewMaintenanceMode(envBool(constants.MaintenanceReadOnlyEnvKey, false), os.Getenv(constants.MaintenanceReasonEnvKey))
+})
+
+// restoreMaintenanceMode switches the process to the mode kept in the
+// maintenance bucket when MAINTENANCE_KV_ENABLED is set, so a replica started
+// after admin.maintenance comes up in it. The mode is process-wide, so the
+// bucket is the shared one, not a tenant's.
+func restoreMaintenanceMode(ctx context.Context, client *nats.NATSClient) {
+	if !envBool(constants.MaintenanceKVEnabledEnvKey, false) {
+		return
+	}
+	kv, ok := client.GetKVStore(constants.KVBucketNameMaintenance)
+	if !ok {
+		log.Fatalf("maintenance KV enabled but the %s KV bucket is not available", constants.KVBucketNameMaintenance)
+	}
+	if err := maintenanceMode().Restore(ctx, nats.NewMaintenanceStore(kv)); err != nil {
+		log.Fatalf("failed to restore the maintenance mode: %v", err)
+	}
+}
+
+// newReadOnlyGuard returns the middleware refusing the write endpoints in
+// read-only mode. admin.maintenance is left out, so the mode can be
+// switched off again.
+func newReadOnlyGuard(endpoints []endpointSpec) service.Middleware {
+	writes := make(map[string]bool, len(endpoints))
+	for _, spec := range endpoints {
+		if spec.write && spec.subject != constants.AdminMaintenanceSubject {
+			writes[spec.subject] = true
+		}
+	}
+	return service.RefuseWritesWhenReadOnly(maintenanceMode(), func(subject string) bool { return writes[subject] })
+}
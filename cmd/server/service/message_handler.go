@@ -6,16 +6,21 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log/slog"
 
 	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
 	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/deadline"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/faults"
 	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/log"
 )
 
 // MessageHandlerService handles NATS messages using the service layer
 type MessageHandlerService struct {
 	messageHandler port.MessageHandler
+	// faults injects failures for chaos testing; nil in production builds
+	faults *faults.Injector
 }
 
 // HandleMessage routes NATS messages to appropriate handlers
@@ -27,14 +32,36 @@ func (mhs *MessageHandlerService) HandleMessage(ctx context.Context, msg port.Tr
 
 	handlers := map[string]func(ctx context.Context, msg port.TransportMessenger) ([]byte, error){
 		// user read/write operations
-		constants.UserMetadataUpdateSubject:  mhs.messageHandler.UpdateUser,
-		constants.UserMetadataReadSubject:    mhs.messageHandler.GetUserMetadata,
-		constants.UserEmailReadSubject:       mhs.messageHandler.GetUserEmails,
-		constants.UserEmailSetPrimarySubject: mhs.messageHandler.SetPrimaryEmail,
+		constants.UserMetadataUpdateSubject:    mhs.messageHandler.UpdateUser,
+		constants.UserMetadataReadSubject:      mhs.messageHandler.GetUserMetadata,
+		constants.UserPreferencesReadSubject:   mhs.messageHandler.GetUserPreferences,
+		constants.UserPreferencesUpdateSubject: mhs.messageHandler.UpdateUserPreferences,
+		constants.UserEmailReadSubject:         mhs.messageHandler.GetUserEmails,
+		constants.UserEmailSetPrimarySubject:   mhs.messageHandler.SetPrimaryEmail,
 		// lookup operations
-		constants.UserEmailToUserSubject:   mhs.messageHandler.EmailToUsername,
-		constants.UserEmailToSubSubject:    mhs.messageHandler.EmailToSub,
-		constants.UserUsernameToSubSubject: mhs.messageHandler.UsernameToSub,
+		constants.UserEmailToUserSubject:      mhs.messageHandler.EmailToUsername,
+		constants.UserEmailToUserBatchSubject: mhs.messageHandler.EmailToUsernameBatch,
+		constants.UserEmailToSubSubject:       mhs.messageHandler.EmailToSub,
+		constants.UserUsernameToSubSubject:    mhs.messageHandler.UsernameToSub,
+		constants.UserSubToUsernameSubject:    mhs.messageHandler.SubToUsername,
+		constants.UserSearchSubject:           mhs.messageHandler.SearchUsers,
+		// permission and token scope checks
+		constants.UserHasPermissionSubject:      mhs.messageHandler.HasPermission,
+		constants.UserHasPermissionBatchSubject: mhs.messageHandler.HasPermissionBatch,
+		constants.TokenCheckScopeSubject:        mhs.messageHandler.CheckTokenScope,
+		constants.TokenCheckStepUpSubject:       mhs.messageHandler.CheckStepUp,
+		// service account management
+		constants.ServiceAccountCreateSubject: mhs.messageHandler.CreateServiceAccount,
+		constants.ServiceAccountListSubject:   mhs.messageHandler.ListServiceAccounts,
+		constants.ServiceAccountRotateSubject: mhs.messageHandler.RotateServiceAccount,
+		// sessions
+		constants.UserSessionsListSubject:   mhs.messageHandler.ListSessions,
+		constants.UserSessionsRevokeSubject: mhs.messageHandler.RevokeSession,
+		// personal access tokens
+		constants.PersonalAccessTokenCreateSubject:   mhs.messageHandler.CreatePersonalAccessToken,
+		constants.PersonalAccessTokenListSubject:     mhs.messageHandler.ListPersonalAccessTokens,
+		constants.PersonalAccessTokenRevokeSubject:   mhs.messageHandler.RevokePersonalAccessToken,
+		constants.PersonalAccessTokenValidateSubject: mhs.messageHandler.ValidatePersonalAccessToken,
 		// email linking operations
 		constants.EmailLinkingSendVerificationSubject: mhs.messageHandler.StartEmailLinking,
 		constants.EmailLinkingVerifySubject:           mhs.messageHandler.VerifyEmailLinking,
@@ -49,6 +76,41 @@ func (mhs *MessageHandlerService) HandleMessage(ctx context.Context, msg port.Tr
 		constants.PasswordResetLinkSubject: mhs.messageHandler.SendResetPasswordLink,
 		// impersonation
 		constants.ImpersonationTokenExchangeSubject: mhs.messageHandler.ImpersonateUser,
+
+		// admin operations
+		constants.AdminStatsSubject:             mhs.messageHandler.AdminStats,
+		constants.AdminSelfTestSubject:          mhs.messageHandler.AdminSelfTest,
+		constants.AdminRevokeTokenSubject:       mhs.messageHandler.RevokeToken,
+		constants.AdminUsersImportSubject:       mhs.messageHandler.ImportUsers,
+		constants.AdminUsersImportStatusSubject: mhs.messageHandler.UserImportStatus,
+		constants.AdminUsersExportSubject:       mhs.messageHandler.ExportUsers,
+		constants.AdminUsersMergeSubject:        mhs.messageHandler.MergeUsers,
+		constants.AdminConfigSubject:            mhs.messageHandler.AdminConfig,
+
+		// email domain to organization mappings
+		constants.EmailOrganizationSubject:            mhs.messageHandler.EmailOrganization,
+		constants.AdminEmailOrganizationSetSubject:    mhs.messageHandler.SetEmailOrganization,
+		constants.AdminEmailOrganizationDeleteSubject: mhs.messageHandler.DeleteEmailOrganization,
+		constants.AdminEmailOrganizationListSubject:   mhs.messageHandler.ListEmailOrganizations,
+
+		// policy acceptance
+		constants.UserConsentRecordSubject: mhs.messageHandler.RecordConsent,
+		constants.UserConsentStatusSubject: mhs.messageHandler.ConsentStatus,
+
+		// employment history
+		constants.UserAffiliationsAddSubject:  mhs.messageHandler.AddAffiliation,
+		constants.UserAffiliationsEndSubject:  mhs.messageHandler.EndAffiliation,
+		constants.UserAffiliationsListSubject: mhs.messageHandler.ListAffiliations,
+
+		// verified profile fields
+		constants.AdminAttestationRecordSubject: mhs.messageHandler.RecordAttestation,
+		constants.AdminAttestationRevokeSubject: mhs.messageHandler.RevokeAttestation,
+		constants.AdminStaticCacheFlushSubject:  mhs.messageHandler.AdminStaticCacheFlush,
+		constants.AdminReindexSubject:           mhs.messageHandler.AdminReindex,
+		constants.AdminMaintenanceSubject:       mhs.messageHandler.AdminMaintenance,
+
+		// schema discovery
+		constants.SchemaSubject: mhs.messageHandler.Schemas,
 	}
 
 	handler, ok := handlers[subject]
@@ -58,7 +120,21 @@ func (mhs *MessageHandlerService) HandleMessage(ctx context.Context, msg port.Tr
 		return
 	}
 
-	response, errHandler := handler(ctx, msg)
+	// the caller's deadline (see pkg/deadline) may have passed in transit
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		mhs.respondDeadlineExceeded(ctx, msg)
+		return
+	}
+
+	response, errHandler := mhs.faults.Apply(ctx, subject, func() ([]byte, error) {
+		return handler(ctx, msg)
+	})
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		// whatever the handler made of its cancelled provider calls, the
+		// caller gets a reply it can tell apart from a provider failure
+		mhs.respondDeadlineExceeded(ctx, msg)
+		return
+	}
 	if errHandler != nil {
 		slog.ErrorContext(ctx, "error handling message",
 			"error", errHandler,
@@ -77,6 +153,12 @@ func (mhs *MessageHandlerService) HandleMessage(ctx context.Context, msg port.Tr
 	slog.DebugContext(ctx, "responded to NATS message", "response", string(response))
 }
 
+// respondDeadlineExceeded replies to a request whose caller's deadline passed
+func (mhs *MessageHandlerService) respondDeadlineExceeded(ctx context.Context, msg port.TransportMessenger) {
+	slog.WarnContext(ctx, "request deadline exceeded")
+	mhs.respondWithError(ctx, msg, deadline.ExceededError)
+}
+
 func (mhs *MessageHandlerService) respondWithError(ctx context.Context, msg port.TransportMessenger, errorMsg string) {
 	payload, _ := json.Marshal(map[string]string{"error": errorMsg})
 	if err := msg.Respond(payload); err != nil {
@@ -0,0 +1,130 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"log"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/service"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/envelope"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/i18n"
+	logging "github.com/linuxfoundation/lfx-v2-auth-service/pkg/log"
+)
+
+// requestMiddleware is the middleware every request of tenant name goes
+// through before its handler, outermost first. New cross-cutting behavior is
+// added here rather than in the handlers.
+//
+//   - the typed envelope converts the replies of the callers asking for
+//     envelope version 2, outermost so it converts them as sent, scrubbed
+//     and localized
+//   - error scrubbing removes credentials and emails from every unsuccessful
+//     reply, so it sees the replies of all the others
+//   - the tenant guard rejects tokens of other tenants before anything else
+//     runs, when the process serves several tenants (router is not nil)
+//   - request logging sees the reply of every request, including the
+//     internal error recovery sends for a panic
+//   - security detection, when it is enabled (security is not nil), counts
+//     the requests and replies matching its rules, those refused by the
+//     middleware after it included
+//   - error localization adds the caller's language message to every
+//     unsuccessful reply, those of the middleware after it included
+//   - the overload refusal replies service busy to the requests the worker
+//     pools had no capacity for, after localization and logging so the
+//     refusal is like any other
+//   - the enumeration throttle, when it is enabled (enumeration is not nil),
+//     delays and refuses the account lookups of callers looking up many
+//     unknown accounts, after localization so its refusals are localized
+//   - the provider override pins the reads of the requests hinting a
+//     provider to it, or refuses the hint, after localization and logging
+//     so its refusals are like any other
+//   - the payload limit refuses oversize requests before anything after it
+//     decodes them
+//   - recovery keeps a panicking handler from leaving its caller waiting, and
+//     refuses the payloads quarantine holds, when it is enabled
+//   - schema validation rejects malformed payloads, after logging and
+//     recovery so its replies are logged and a validator panic is recovered
+//   - idempotency runs last, when it is enabled (idempotent is not nil), so
+//     only valid mutations reserve a key and only handler replies are stored
+func requestMiddleware(router *service.TenantRouter, name string, envelope service.Middleware, sampler *logging.Sampler,
+	security, enumeration, providerOverride, payloadLimit service.Middleware, quarantine *service.PanicQuarantine,
+	readOnly, idempotent service.Middleware) []service.Middleware {
+	middleware := []service.Middleware{envelope, service.ScrubErrors()}
+	if router != nil {
+		middleware = append(middleware, tenantGuard(router, name))
+	}
+	middleware = append(middleware, requestLogging(sampler))
+	if security != nil {
+		middleware = append(middleware, security)
+	}
+	if envBool(constants.ErrorLocalizationEnabledEnvKey, true) {
+		middleware = append(middleware, service.LocalizeErrors(errorCatalog()))
+	}
+	middleware = append(middleware, service.RefuseWhenOverloaded())
+	if enumeration != nil {
+		middleware = append(middleware, enumeration)
+	}
+	middleware = append(middleware, providerOverride, payloadLimit)
+	middleware = append(middleware, service.Recover(quarantine))
+	if envBool(constants.RequestSchemaValidationEnabledEnvKey, true) {
+		middleware = append(middleware, service.ValidateRequests())
+	}
+	middleware = append(middleware, readOnly)
+	if idempotent != nil {
+		middleware = append(middleware, idempotent)
+	}
+	return middleware
+}
+
+// tenantGuard adapts the tenant guard of router to a middleware
+func tenantGuard(router *service.TenantRouter, name string) service.Middleware {
+	return func(next port.Handler) port.Handler {
+		return port.HandlerFunc(router.Guard(name, next.Handle))
+	}
+}
+
+// errorCatalog loads the error message catalogs; they are embedded, so a
+// failure is a broken build
+func errorCatalog() *i18n.Catalog {
+	catalog, err := i18n.Load()
+	if err != nil {
+		log.Fatalf("failed to load error message catalogs: %v", err)
+	}
+	return catalog
+}
+
+// newPayloadLimit returns the middleware refusing oversize payloads, with
+// the batch endpoints allowed the larger batch limit
+func newPayloadLimit(endpoints []endpointSpec) service.Middleware {
+	limit := envNonNegativeInt(constants.RequestMaxPayloadBytesEnvKey, constants.DefaultRequestMaxPayloadBytes)
+	batchLimit := envNonNegativeInt(constants.RequestMaxBatchPayloadBytesEnvKey, constants.DefaultRequestMaxBatchPayloadBytes)
+
+	batch := make(map[string]bool)
+	for _, spec := range endpoints {
+		if spec.batch {
+			batch[spec.subject] = true
+		}
+	}
+	return service.LimitPayloadSize(func(subject string) int {
+		if batch[subject] {
+			return batchLimit
+		}
+		return limit
+	})
+}
+
+// newResponseEnvelope builds the typed envelope middleware of the replies of
+// the requests served by provider, the user repository type
+func newResponseEnvelope(provider string) service.Middleware {
+	if provider == "" {
+		provider = constants.UserRepositoryTypeMock
+	}
+	version := envPositiveInt(constants.ResponseEnvelopeDefaultVersionEnvKey, envelope.Legacy)
+	if version > envelope.Version {
+		log.Fatalf("invalid %s: %d, the latest envelope version is %d", constants.ResponseEnvelopeDefaultVersionEnvKey, version, envelope.Version)
+	}
+	return service.TypedEnvelope(provider, version)
+}
@@ -0,0 +1,46 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/mock"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+)
+
+// newMockUserReaderWriter creates the mock repository with the fixtures,
+// simulated provider behavior and dev token secret from the environment
+func newMockUserReaderWriter(ctx context.Context) (port.UserReaderWriter, error) {
+	var opts []mock.Option
+	if path := os.Getenv(constants.MockUsersFileEnvKey); path != "" {
+		users, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", constants.MockUsersFileEnvKey, err)
+		}
+		opts = append(opts, mock.WithUsers(users))
+	}
+
+	latency := envDuration(constants.MockLatencyEnvKey, 0)
+	jitter := envDuration(constants.MockLatencyJitterEnvKey, 0)
+	errorRate := envFraction(constants.MockErrorRateEnvKey, 0)
+	opts = append(opts, mock.WithLatency(latency, jitter), mock.WithErrorRate(errorRate))
+
+	secret := os.Getenv(constants.MockJWTSecretEnvKey)
+	if secret != "" {
+		opts = append(opts, mock.WithJWTSecret([]byte(secret)))
+	}
+
+	slog.DebugContext(ctx, "using mock user repository implementation",
+		"latency", latency,
+		"latency_jitter", jitter,
+		"error_rate", errorRate,
+		"verify_jwt", secret != "",
+	)
+	return mock.NewUserReaderWriter(ctx, opts...), nil
+}
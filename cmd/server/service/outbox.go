@@ -0,0 +1,45 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/nats"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/service"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/scheduler"
+)
+
+// defaultOutboxDispatchInterval retries failed publications quickly without
+// listing the bucket on every request
+const defaultOutboxDispatchInterval = 5 * time.Second
+
+// newOutboxPublisher returns publisher behind the event outbox when enabled,
+// with its dispatcher scheduled, and publisher itself otherwise. The NATS
+// client opens the bucket on connect, so a missing bucket is a deployment
+// error.
+func newOutboxPublisher(ctx context.Context, natsClient *nats.NATSClient, publisher port.EventPublisher) port.EventPublisher {
+	if !envBool(constants.OutboxEnabledEnvKey, false) {
+		return publisher
+	}
+
+	kv, ok := natsClient.GetKVStore(constants.KVBucketNameOutbox)
+	if !ok {
+		log.Fatalf("event outbox enabled but the %s KV bucket is not available", constants.KVBucketNameOutbox)
+	}
+	interval := envDuration(constants.OutboxDispatchIntervalEnvKey, defaultOutboxDispatchInterval)
+	if interval == 0 {
+		log.Fatalf("invalid %s value: must be a positive duration", constants.OutboxDispatchIntervalEnvKey)
+	}
+
+	outbox := service.NewOutboxPublisher(nats.NewOutbox(kv), publisher)
+	slog.InfoContext(ctx, "event outbox enabled", "dispatch_interval", interval)
+	scheduler.Every(ctx, "outbox-dispatch", interval, interval, outbox.Dispatch)
+	return outbox
+}
@@ -0,0 +1,48 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/nats"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/service"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+)
+
+// personalAccessTokenOptions wires the personal access token store when
+// enabled. The NATS client opens the bucket on connect, so a missing bucket
+// is a deployment error.
+func personalAccessTokenOptions(ctx context.Context, natsClient *nats.NATSClient) []service.MessageHandlerOrchestratorOption {
+	if !envBool(constants.PersonalAccessTokensEnabledEnvKey, false) {
+		return nil
+	}
+
+	kv, ok := natsClient.GetKVStore(constants.KVBucketNamePersonalAccessTokens)
+	if !ok {
+		log.Fatalf("personal access tokens enabled but the %s KV bucket is not available", constants.KVBucketNamePersonalAccessTokens)
+	}
+
+	maxLifetime := envDuration(constants.PersonalAccessTokenMaxLifetimeEnvKey, constants.DefaultPersonalAccessTokenMaxLifetime)
+	if maxLifetime < 24*time.Hour {
+		log.Fatalf("invalid %s value: must be at least 24h", constants.PersonalAccessTokenMaxLifetimeEnvKey)
+	}
+	scopes := envList(constants.PersonalAccessTokenAllowedScopesEnvKey)
+	if len(scopes) == 0 {
+		slog.WarnContext(ctx, "personal access tokens enabled without allowed scopes, none can be created")
+	}
+
+	slog.InfoContext(ctx, "personal access tokens enabled",
+		"max_lifetime", maxLifetime,
+		"allowed_scopes", scopes,
+	)
+
+	return []service.MessageHandlerOrchestratorOption{
+		service.WithPersonalAccessTokenStoreForMessageHandler(nats.NewPersonalAccessTokenStore(kv)),
+		service.WithPersonalAccessTokenPolicyForMessageHandler(maxLifetime, scopes),
+	}
+}
@@ -5,6 +5,7 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"log/slog"
@@ -13,14 +14,43 @@ import (
 	"sync"
 	"time"
 
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
 	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
 	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/auth0"
 	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/authelia"
-	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/mock"
 	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/nats"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/provider"
 	"github.com/linuxfoundation/lfx-v2-auth-service/internal/service"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/compression"
 	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
-	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/httpclient"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/deadline"
+	logging "github.com/linuxfoundation/lfx-v2-auth-service/pkg/log"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/tenant"
+)
+
+// defaultAutheliaOIDCDiscoveryRefreshInterval picks up endpoint and key
+// changes without restarting
+const defaultAutheliaOIDCDiscoveryRefreshInterval = time.Hour
+
+// defaults of the Postgres user store pool; connections are recycled so a
+// failover or a credential rotation is picked up
+const (
+	defaultAutheliaPostgresMaxConns        = 10
+	defaultAutheliaPostgresMaxConnLifetime = time.Hour
+	defaultAutheliaPostgresMaxConnIdleTime = 30 * time.Minute
+)
+
+// default lifetimes of the cached Auth0 static resources; connections are
+// the ones changing least
+const (
+	defaultAuth0StaticCacheRolesTTL         = 10 * time.Minute
+	defaultAuth0StaticCacheConnectionsTTL   = time.Hour
+	defaultAuth0StaticCacheOrganizationsTTL = 10 * time.Minute
+)
+
+const (
+	defaultNATSWorkers          = 64
+	defaultNATSBatchWorkerShare = 0.25
 )
 
 var (
@@ -30,49 +60,71 @@ var (
 	natsDoOnce sync.Once
 )
 
-func natsInit(ctx context.Context) {
+// natsConfig reads the NATS connection settings from the environment
+func natsConfig() nats.Config {
+	natsURL := os.Getenv("NATS_URL")
+	if natsURL == "" {
+		natsURL = "nats://localhost:4222"
+	}
 
-	natsDoOnce.Do(func() {
-		natsURL := os.Getenv("NATS_URL")
-		if natsURL == "" {
-			natsURL = "nats://localhost:4222"
-		}
+	natsTimeout := os.Getenv("NATS_TIMEOUT")
+	if natsTimeout == "" {
+		natsTimeout = "10s"
+	}
+	natsTimeoutDuration, err := time.ParseDuration(natsTimeout)
+	if err != nil {
+		log.Fatalf("invalid NATS timeout duration: %v", err)
+	}
 
-		natsTimeout := os.Getenv("NATS_TIMEOUT")
-		if natsTimeout == "" {
-			natsTimeout = "10s"
-		}
-		natsTimeoutDuration, err := time.ParseDuration(natsTimeout)
-		if err != nil {
-			log.Fatalf("invalid NATS timeout duration: %v", err)
-		}
+	natsMaxReconnect := os.Getenv("NATS_MAX_RECONNECT")
+	if natsMaxReconnect == "" {
+		natsMaxReconnect = "3"
+	}
+	natsMaxReconnectInt, err := strconv.Atoi(natsMaxReconnect)
+	if err != nil {
+		log.Fatalf("invalid NATS max reconnect value %s: %v", natsMaxReconnect, err)
+	}
 
-		natsMaxReconnect := os.Getenv("NATS_MAX_RECONNECT")
-		if natsMaxReconnect == "" {
-			natsMaxReconnect = "3"
-		}
-		natsMaxReconnectInt, err := strconv.Atoi(natsMaxReconnect)
-		if err != nil {
-			log.Fatalf("invalid NATS max reconnect value %s: %v", natsMaxReconnect, err)
-		}
+	natsReconnectWait := os.Getenv("NATS_RECONNECT_WAIT")
+	if natsReconnectWait == "" {
+		natsReconnectWait = "2s"
+	}
+	natsReconnectWaitDuration, err := time.ParseDuration(natsReconnectWait)
+	if err != nil {
+		log.Fatalf("invalid NATS reconnect wait duration %s : %v", natsReconnectWait, err)
+	}
 
-		natsReconnectWait := os.Getenv("NATS_RECONNECT_WAIT")
-		if natsReconnectWait == "" {
-			natsReconnectWait = "2s"
-		}
-		natsReconnectWaitDuration, err := time.ParseDuration(natsReconnectWait)
-		if err != nil {
-			log.Fatalf("invalid NATS reconnect wait duration %s : %v", natsReconnectWait, err)
+	compressionThreshold := compression.DefaultThreshold
+	if value := os.Getenv(constants.NATSCompressionThresholdEnvKey); value != "" {
+		compressionThreshold, err = strconv.Atoi(value)
+		if err != nil || compressionThreshold < 0 {
+			log.Fatalf("invalid %s value %s", constants.NATSCompressionThresholdEnvKey, value)
 		}
+	}
 
-		config := nats.Config{
-			URL:           natsURL,
-			Timeout:       natsTimeoutDuration,
-			MaxReconnect:  natsMaxReconnectInt,
-			ReconnectWait: natsReconnectWaitDuration,
+	workers := defaultNATSWorkers
+	if value := os.Getenv(constants.NATSWorkersEnvKey); value != "" {
+		workers, err = strconv.Atoi(value)
+		if err != nil || workers < 0 {
+			log.Fatalf("invalid %s value %s", constants.NATSWorkersEnvKey, value)
 		}
+	}
+
+	return nats.Config{
+		URL:                  natsURL,
+		Timeout:              natsTimeoutDuration,
+		MaxReconnect:         natsMaxReconnectInt,
+		ReconnectWait:        natsReconnectWaitDuration,
+		CompressionThreshold: compressionThreshold,
+		Workers:              workers,
+		BatchWorkerShare:     envFraction(constants.NATSBatchWorkerShareEnvKey, defaultNATSBatchWorkerShare),
+	}
+}
+
+func natsInit(ctx context.Context) {
 
-		client, errNewClient := nats.NewClient(ctx, config)
+	natsDoOnce.Do(func() {
+		client, errNewClient := nats.NewClient(ctx, natsConfig())
 		if errNewClient != nil {
 			log.Fatalf("failed to create NATS client: %v", errNewClient)
 		}
@@ -80,120 +132,321 @@ func natsInit(ctx context.Context) {
 	})
 }
 
-// newUserReaderWriter creates a UserReaderWriter implementation based on the environment variable.
-// Set USER_REPOSITORY_TYPE to "mock" to explicitly use mock, or "auth0" to use Auth0.
-func newUserReaderWriter(ctx context.Context) port.UserReaderWriter {
+// auth0DomainFromEnv is AUTH0_DOMAIN, or <AUTH0_TENANT>.auth0.com when unset
+func auth0DomainFromEnv() string {
+	if domain := os.Getenv(constants.Auth0DomainEnvKey); domain != "" {
+		return domain
+	}
+	return fmt.Sprintf("%s.auth0.com", os.Getenv(constants.Auth0TenantEnvKey))
+}
+
+// autheliaUserInfoURL is AUTHELIA_OIDC_USERINFO_URL, or the local development endpoint when unset
+func autheliaUserInfoURL() string {
+	if url := os.Getenv(constants.AutheliaOIDCUserInfoURLEnvKey); url != "" {
+		return url
+	}
+	return "https://auth.k8s.orb.local/api/oidc/userinfo"
+}
+
+func init() {
+	provider.Register(constants.UserRepositoryTypeMock, newMockUserReaderWriter)
+	provider.Register(constants.UserRepositoryTypeAuth0, newAuth0UserReaderWriter)
+	provider.Register(constants.UserRepositoryTypeAuthelia, newAutheliaUserReaderWriter)
+}
 
+// newUserReaderWriter builds the provider registered under
+// USER_REPOSITORY_TYPE, the mock repository when unset
+func newUserReaderWriter(ctx context.Context) port.UserReaderWriter {
 	userRepositoryType := os.Getenv(constants.UserRepositoryTypeEnvKey)
 	if userRepositoryType == "" {
 		userRepositoryType = constants.UserRepositoryTypeMock // default to mock when not set
 	}
 
-	switch userRepositoryType {
-	case constants.UserRepositoryTypeMock:
-		slog.DebugContext(ctx, "using mock user repository implementation")
-		return mock.NewUserReaderWriter(ctx)
-	case constants.UserRepositoryTypeAuth0:
+	userReaderWriter, err := provider.New(ctx, userRepositoryType)
+	if err != nil {
+		log.Fatalf("failed to create user repository: %v", err)
+	}
+	return userReaderWriter
+}
 
-		// Load Auth0 configuration from environment variables
-		auth0Tenant := os.Getenv(constants.Auth0TenantEnvKey)
-		auth0Domain := os.Getenv(constants.Auth0DomainEnvKey)
+// newAuth0UserReaderWriter creates the Auth0 repository from the AUTH0_* variables
+func newAuth0UserReaderWriter(ctx context.Context) (port.UserReaderWriter, error) {
+	// Load Auth0 configuration from environment variables
+	auth0Tenant := os.Getenv(constants.Auth0TenantEnvKey)
+	auth0Domain := auth0DomainFromEnv()
 
-		slog.DebugContext(ctx, "using Auth0 user repository implementation",
-			"tenant", auth0Tenant,
-			"domain", auth0Domain,
-		)
+	slog.DebugContext(ctx, "using Auth0 user repository implementation",
+		"tenant", auth0Tenant,
+		"domain", auth0Domain,
+	)
 
-		if auth0Domain == "" {
-			// Default to tenant.auth0.com if domain is not explicitly set
-			auth0Domain = fmt.Sprintf("%s.auth0.com", auth0Tenant)
-		}
+	auth0Config := auth0.Config{
+		Tenant:                  auth0Tenant,
+		Domain:                  auth0Domain,
+		LFXProfileClientID:      os.Getenv(constants.Auth0LFXProfileClientIDEnvKey),
+		LFXProfileClientSecret:  os.Getenv(constants.Auth0LFXProfileClientSecretEnvKey),
+		LFXOneClientID:          os.Getenv(constants.Auth0LFXOneClientIDEnvKey),
+		DatabaseConnection:      os.Getenv(constants.Auth0DatabaseConnectionEnvKey),
+		CanonicalConnections:    envList(constants.Auth0CanonicalConnectionsEnvKey),
+		SocialConnections:       envList(constants.Auth0SocialConnectionsEnvKey),
+		SocialUsernameAttribute: os.Getenv(constants.Auth0SocialUsernameAttributeEnvKey),
+		StaticCacheTTLs: auth0.StaticCacheTTLs{
+			Roles:         envDuration(constants.Auth0StaticCacheRolesTTLEnvKey, defaultAuth0StaticCacheRolesTTL),
+			Connections:   envDuration(constants.Auth0StaticCacheConnectionsTTLEnvKey, defaultAuth0StaticCacheConnectionsTTL),
+			Organizations: envDuration(constants.Auth0StaticCacheOrganizationsTTLEnvKey, defaultAuth0StaticCacheOrganizationsTTL),
+		},
+		TokenRevocations: tokenRevocations(ctx),
+		DPoP:             dpopVerifier(),
+		Timeouts:         providerTimeouts(),
+	}
 
-		auth0Config := auth0.Config{
-			Tenant:                 auth0Tenant,
-			Domain:                 auth0Domain,
-			LFXProfileClientID:     os.Getenv(constants.Auth0LFXProfileClientIDEnvKey),
-			LFXProfileClientSecret: os.Getenv(constants.Auth0LFXProfileClientSecretEnvKey),
-			LFXOneClientID:         os.Getenv(constants.Auth0LFXOneClientIDEnvKey),
-		}
+	slog.DebugContext(ctx, "Auth0 client initialized with M2M token support",
+		"tenant", auth0Tenant,
+		"domain", auth0Domain,
+	)
 
-		slog.DebugContext(ctx, "Auth0 client initialized with M2M token support",
-			"tenant", auth0Tenant,
-			"domain", auth0Domain,
-		)
+	userReaderWriter, err := auth0.NewUserReaderWriter(ctx, outboundHTTPConfig(), auth0Config)
+	if err != nil {
+		return nil, err
+	}
+	return userReaderWriter, nil
+}
 
-		userReaderWriter, err := auth0.NewUserReaderWriter(ctx, httpclient.DefaultConfig(), auth0Config)
-		if err != nil {
-			log.Fatalf("failed to create Auth0 user reader writer: %v", err)
-		}
+// providerTimeouts returns the operation timeouts set by the PROVIDER_TIMEOUT_*
+// variables; the others keep the default of the provider
+func providerTimeouts() deadline.Budget {
+	return deadline.Budget{
+		deadline.OperationSearch: envDuration(constants.ProviderTimeoutSearchEnvKey, 0),
+		deadline.OperationGet:    envDuration(constants.ProviderTimeoutGetEnvKey, 0),
+		deadline.OperationUpdate: envDuration(constants.ProviderTimeoutUpdateEnvKey, 0),
+		deadline.OperationJobs:   envDuration(constants.ProviderTimeoutJobsEnvKey, 0),
+	}
+}
 
-		return userReaderWriter
-	case constants.UserRepositoryTypeAuthelia:
-		// Initialize NATS client first for Authelia NATS storage
-		natsInit(ctx)
+// newAutheliaUserReaderWriter creates the Authelia repository, stored in the
+// NATS KV buckets, Redis or Postgres, from the AUTHELIA_* variables
+func newAutheliaUserReaderWriter(ctx context.Context) (port.UserReaderWriter, error) {
+	// Initialize NATS client first for Authelia NATS storage
+	natsInit(ctx)
 
-		// Load Authelia configuration from environment variables
-		configMapName := os.Getenv(constants.AutheliaConfigMapNameEnvKey)
-		if configMapName == "" {
-			configMapName = "authelia-users"
-		}
-		configMapNamespace := os.Getenv(constants.AutheliaConfigMapNamespaceEnvKey)
-		if configMapNamespace == "" {
-			configMapNamespace = "lfx"
-		}
+	// Load Authelia configuration from environment variables
+	configMapName := os.Getenv(constants.AutheliaConfigMapNameEnvKey)
+	if configMapName == "" {
+		configMapName = "authelia-users"
+	}
+	configMapNamespace := os.Getenv(constants.AutheliaConfigMapNamespaceEnvKey)
+	if configMapNamespace == "" {
+		configMapNamespace = "lfx"
+	}
 
-		daemonSetName := os.Getenv(constants.AutheliaDaemonSetNameEnvKey)
-		if daemonSetName == "" {
-			daemonSetName = "lfx-platform-authelia"
-		}
+	daemonSetName := os.Getenv(constants.AutheliaDaemonSetNameEnvKey)
+	if daemonSetName == "" {
+		daemonSetName = "lfx-platform-authelia"
+	}
 
-		secretName := os.Getenv(constants.AutheliaSecretNameEnvKey)
-		if secretName == "" {
-			secretName = "authelia-users"
-		}
+	secretName := os.Getenv(constants.AutheliaSecretNameEnvKey)
+	if secretName == "" {
+		secretName = "authelia-users"
+	}
 
-		oidcUserInfoURL := os.Getenv(constants.AutheliaOIDCUserInfoURLEnvKey)
-		if oidcUserInfoURL == "" {
-			oidcUserInfoURL = "https://auth.k8s.orb.local/api/oidc/userinfo"
-		}
+	config := map[string]string{
+		"configmap-name":    configMapName,
+		"namespace":         configMapNamespace,
+		"daemon-set-name":   daemonSetName,
+		"secret-name":       secretName,
+		"oidc-userinfo-url": autheliaUserInfoURL(),
+	}
 
-		config := map[string]string{
-			"configmap-name":    configMapName,
-			"namespace":         configMapNamespace,
-			"daemon-set-name":   daemonSetName,
-			"secret-name":       secretName,
-			"oidc-userinfo-url": oidcUserInfoURL,
+	opts := []authelia.Option{
+		authelia.WithHTTPClientConfig(autheliaHTTPClientConfig()),
+		authelia.WithOperationTimeouts(providerTimeouts()),
+		authelia.WithUserInfoCache(
+			envDuration(constants.AutheliaUserInfoCacheTTLEnvKey, defaultAutheliaUserInfoCacheTTL),
+			envDuration(constants.AutheliaUserInfoNegativeCacheTTLEnvKey, defaultAutheliaUserInfoNegativeCacheTTL),
+			envPositiveInt(constants.AutheliaUserInfoCacheMaxEntriesEnvKey, defaultAutheliaUserInfoCacheMaxEntries),
+		),
+	}
+	if raw := os.Getenv(constants.AutheliaGroupPermissionsEnvKey); raw != "" {
+		var groupPermissions map[string][]string
+		if err := json.Unmarshal([]byte(raw), &groupPermissions); err != nil {
+			return nil, fmt.Errorf("invalid %s value: %w", constants.AutheliaGroupPermissionsEnvKey, err)
 		}
-
-		// Create Authelia user repository with NATS client for storage
-		userWriter, err := authelia.NewUserReaderWriter(ctx, config, natsClient)
-		if err != nil {
-			log.Fatalf("failed to create Authelia user repository: %v", err)
+		opts = append(opts, authelia.WithGroupPermissions(groupPermissions))
+	}
+	if issuer := os.Getenv(constants.AutheliaOIDCIssuerURLEnvKey); issuer != "" {
+		opts = append(opts, authelia.WithOIDCDiscovery(issuer,
+			envDuration(constants.AutheliaOIDCDiscoveryRefreshIntervalEnvKey, defaultAutheliaOIDCDiscoveryRefreshInterval),
+		))
+	}
+	envelope := newKVEnvelope(ctx)
+	if envelope != nil {
+		opts = append(opts, authelia.WithValueEncryption(envelope))
+	}
+	switch store := os.Getenv(constants.AutheliaUserStoreEnvKey); store {
+	case "", constants.AutheliaUserStoreNATS:
+	case constants.AutheliaUserStoreRedis:
+		redisURL := os.Getenv(constants.AutheliaRedisURLEnvKey)
+		if redisURL == "" {
+			return nil, fmt.Errorf("%s is required with the %s user store", constants.AutheliaRedisURLEnvKey, store)
+		}
+		keyPrefix, ok := os.LookupEnv(constants.AutheliaRedisKeyPrefixEnvKey)
+		if !ok {
+			// tenants sharing a server get their own keys, as they get their own buckets
+			keyPrefix = tenant.Bucket(tenant.FromContext(ctx), "authelia") + ":"
+		}
+		opts = append(opts, authelia.WithRedisUserStore(authelia.RedisStoreConfig{
+			URL:       redisURL,
+			KeyPrefix: keyPrefix,
+			OTPTTL:    envDuration(constants.AutheliaRedisOTPTTLEnvKey, 0),
+		}))
+	case constants.AutheliaUserStorePostgres:
+		postgresURL := os.Getenv(constants.AutheliaPostgresURLEnvKey)
+		if postgresURL == "" {
+			return nil, fmt.Errorf("%s is required with the %s user store", constants.AutheliaPostgresURLEnvKey, store)
 		}
-		return userWriter
+		opts = append(opts, authelia.WithPostgresUserStore(authelia.PostgresStoreConfig{
+			URL:             postgresURL,
+			MaxConns:        int32(envPositiveInt(constants.AutheliaPostgresMaxConnsEnvKey, defaultAutheliaPostgresMaxConns)),
+			MinConns:        int32(envNonNegativeInt(constants.AutheliaPostgresMinConnsEnvKey, 0)),
+			MaxConnLifetime: envDuration(constants.AutheliaPostgresMaxConnLifetimeEnvKey, defaultAutheliaPostgresMaxConnLifetime),
+			MaxConnIdleTime: envDuration(constants.AutheliaPostgresMaxConnIdleTimeEnvKey, defaultAutheliaPostgresMaxConnIdleTime),
+			OTPTTL:          envDuration(constants.AutheliaPostgresOTPTTLEnvKey, 0),
+		}))
 	default:
-		log.Fatalf("unsupported user repository type: %s", userRepositoryType)
-		return nil // This will never be reached due to log.Fatalf, but satisfies the linter
+		return nil, fmt.Errorf("invalid %s value %q, expected %s, %s or %s", constants.AutheliaUserStoreEnvKey, store,
+			constants.AutheliaUserStoreNATS, constants.AutheliaUserStoreRedis, constants.AutheliaUserStorePostgres)
+	}
+	switch collisions := os.Getenv(constants.AutheliaLookupCollisionsEnvKey); collisions {
+	case "", constants.AutheliaLookupCollisionsFlag:
+	case constants.AutheliaLookupCollisionsReject:
+		opts = append(opts, authelia.WithRejectedLookupCollisions())
+	default:
+		return nil, fmt.Errorf("invalid %s value %q, expected %s or %s", constants.AutheliaLookupCollisionsEnvKey, collisions,
+			constants.AutheliaLookupCollisionsFlag, constants.AutheliaLookupCollisionsReject)
+	}
+	if envDuration(constants.AutheliaArchiveInactiveAfterEnvKey, 0) > 0 {
+		opts = append(opts, authelia.WithUserArchive())
+	}
+
+	// Create Authelia user repository with the configured storage
+	userWriter, err := authelia.NewUserReaderWriter(ctx, config, natsClient, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if envelope != nil {
+		startKVReencryptionJob(ctx, userWriter)
 	}
+	return userWriter, nil
 }
 
-// QueueSubscriptions starts all NATS subscriptions with the provided dependencies
-func QueueSubscriptions(ctx context.Context) error {
+// QueueSubscriptions wires the dependencies and registers the NATS service
+// endpoints, once per tenant when TENANTS is set
+func QueueSubscriptions(ctx context.Context, version string) error {
 	slog.DebugContext(ctx, "starting NATS subscriptions")
 
 	// Initialize NATS client first
 	natsInit(ctx)
 
+	// Get the NATS client - we need to access it directly
+	sharedClient := getNATSClient()
+	if sharedClient == nil {
+		return fmt.Errorf("NATS client not initialized")
+	}
+	restoreMaintenanceMode(ctx, sharedClient)
+
+	tenants := tenantNames()
+	if len(tenants) == 0 {
+		return subscribeTenant(ctx, version, sharedClient, true, nil)
+	}
+
+	// Tenants share the connection; each one's providers are built from its
+	// own settings, with a client namespacing its subjects and KV buckets
+	// standing in for the shared one while they are.
+	defer func() { natsClient = sharedClient }()
+	router := service.NewTenantRouter()
+	for i, name := range tenants {
+		tenantCtx := logging.AppendCtx(tenant.WithContext(ctx, name), slog.String("tenant", name))
+		err := withTenantEnv(name, func() error {
+			tenantClient, err := sharedClient.ForTenant(tenantCtx, name)
+			if err != nil {
+				return fmt.Errorf("tenant %s: %w", name, err)
+			}
+			natsClient = tenantClient
+			// process-wide listeners, like the Auth0 log stream webhook, feed the first tenant
+			return subscribeTenant(tenantCtx, version, tenantClient, i == 0, router)
+		})
+		if err != nil {
+			return err
+		}
+		slog.InfoContext(tenantCtx, "tenant subscriptions started")
+	}
+
+	// The un-namespaced subjects serve callers that don't know the tenant,
+	// routing each request by the issuer of its token
+	if _, err := sharedClient.AddService(ctx, nats.ServiceConfig{
+		Name:        constants.ServiceName,
+		Version:     version,
+		Description: serviceDescription,
+		QueueGroup:  constants.AuthServiceQueue,
+	}, buildServiceEndpoints(router.Route)); err != nil {
+		return fmt.Errorf("failed to register the tenant routing service: %w", err)
+	}
+	return nil
+}
+
+// subscribeTenant wires the dependencies of one tenant and registers its
+// endpoints on natsClient. primary marks the tenant process-wide listeners
+// report to; in multi-tenant deployments the tenant is registered with
+// router, which guards its endpoints against other tenants' tokens.
+func subscribeTenant(ctx context.Context, version string, natsClient *nats.NATSClient, primary bool, router *service.TenantRouter) error {
 	userReaderWriter := newUserReaderWriter(ctx)
+	eventPublisher := newEventPublisher(ctx)
+	if primary {
+		initLoginEventIngester(eventPublisher)
+	}
+	startDormantAccountsJob(ctx, userReaderWriter, eventPublisher)
+	startDuplicateAccountsJob(ctx, userReaderWriter, eventPublisher)
+	startCanaryProber(ctx, userReaderWriter)
+	startUserArchivalJob(ctx, userReaderWriter)
+
+	// The cache, shadow and hedging wrappers only implement the core repository ports, so
+	// optional capabilities (like the dormant scan above) are resolved on the raw repository.
+	// Hedging sits behind the cache, so only cache misses reach the provider twice, and
+	// behind shadowing, so a hedged read is replayed on the secondary once.
+	userRepository := newUserCache(ctx, natsClient, newShadowReads(ctx, newHedgedReads(ctx, userReaderWriter)))
+	startWarmUp(ctx, natsClient, userReaderWriter, userRepository)
+	stats := newInstanceStats(version, natsClient, userReaderWriter, userRepository)
+	flags := newFeatureFlags(ctx, natsClient)
 
 	opts := []service.MessageHandlerOrchestratorOption{
-		service.WithUserWriterForMessageHandler(userReaderWriter),
-		service.WithUserReaderForMessageHandler(userReaderWriter),
-		service.WithEmailHandlerForMessageHandler(userReaderWriter),
-		service.WithIdentityLinkerForMessageHandler(userReaderWriter),
-		service.WithIdentityUnlinkerForMessageHandler(userReaderWriter),
-		service.WithPasswordHandlerForMessageHandler(userReaderWriter),
-		service.WithEventPublisherForMessageHandler(natsClient),
+		service.WithUserWriterForMessageHandler(userRepository),
+		service.WithUserReaderForMessageHandler(userRepository),
+		service.WithEmailHandlerForMessageHandler(userRepository),
+		service.WithIdentityLinkerForMessageHandler(userRepository),
+		service.WithIdentityUnlinkerForMessageHandler(userRepository),
+		service.WithPasswordHandlerForMessageHandler(userRepository),
+		service.WithEventPublisherForMessageHandler(newOutboxPublisher(ctx, natsClient, eventPublisher)),
+		service.WithInstanceStatsForMessageHandler(stats),
+		service.WithMaintenanceModeForMessageHandler(maintenanceMode()),
+		service.WithVerifiedEmailLookupsForMessageHandler(envBool(constants.EmailLookupRequireVerifiedEnvKey, false)),
+		service.WithFeatureFlagsForMessageHandler(flags),
+		service.WithMetadataSizeLimitsForMessageHandler(model.MetadataSizeLimits{
+			MaxBytes:      envNonNegativeInt(constants.MetadataMaxBytesEnvKey, constants.DefaultMetadataMaxBytes),
+			MaxFieldBytes: envNonNegativeInt(constants.MetadataMaxFieldBytesEnvKey, constants.DefaultMetadataMaxFieldBytes),
+		}),
+		service.WithRuntimeConfigForMessageHandler(newRuntimeConfig(ctx, version, userReaderWriter, flags)),
+		service.WithEmailBatchLimitsForMessageHandler(
+			envPositiveInt(constants.EmailLookupBatchMaxSizeEnvKey, 0),
+			envPositiveInt(constants.EmailLookupBatchConcurrencyEnvKey, 0),
+		),
+		service.WithIdentifierCacheForMessageHandler(
+			envDuration(constants.IdentifierCacheTTLEnvKey, defaultIdentifierCacheTTL),
+			envPositiveInt(constants.IdentifierCacheMaxEntriesEnvKey, defaultIdentifierCacheMaxEntries),
+		),
+	}
+	lookupSnapshot := newLookupSnapshot(ctx, natsClient)
+	if lookupSnapshot != nil {
+		opts = append(opts, service.WithLookupSnapshotForMessageHandler(lookupSnapshot))
 	}
 
 	// Only wire the alias manager for backends that meaningfully support
@@ -202,16 +455,82 @@ func QueueSubscriptions(ctx context.Context) error {
 	// "alias_service_unavailable" guard instead.
 	userRepoType := os.Getenv(constants.UserRepositoryTypeEnvKey)
 	if userRepoType == constants.UserRepositoryTypeAuth0 || userRepoType == constants.UserRepositoryTypeMock || userRepoType == "" {
-		opts = append(opts, service.WithAliasManagerForMessageHandler(userReaderWriter))
+		opts = append(opts, service.WithAliasManagerForMessageHandler(userRepository))
 	}
 
-	if userRepoType == constants.UserRepositoryTypeAuth0 {
-		auth0Domain := os.Getenv(constants.Auth0DomainEnvKey)
-		if auth0Domain == "" {
-			auth0Domain = fmt.Sprintf("%s.auth0.com", os.Getenv(constants.Auth0TenantEnvKey))
+	if userSearcher, ok := userReaderWriter.(port.UserSearcher); ok {
+		opts = append(opts, service.WithUserSearcherForMessageHandler(userSearcher))
+	}
+	if permissionReader, ok := userReaderWriter.(port.PermissionReader); ok {
+		opts = append(opts,
+			service.WithPermissionReaderForMessageHandler(permissionReader),
+			service.WithPermissionCacheForMessageHandler(
+				envDuration(constants.PermissionCacheTTLEnvKey, defaultPermissionCacheTTL),
+				envPositiveInt(constants.PermissionCacheMaxEntriesEnvKey, defaultPermissionCacheMaxEntries),
+			),
+		)
+	}
+	if serviceAccountManager, ok := userReaderWriter.(port.ServiceAccountManager); ok {
+		opts = append(opts,
+			service.WithServiceAccountManagerForMessageHandler(serviceAccountManager),
+			service.WithServiceAccountAudiencesForMessageHandler(envList(constants.ServiceAccountAllowedAudiencesEnvKey)),
+		)
+	}
+	opts = append(opts, personalAccessTokenOptions(ctx, natsClient)...)
+	opts = append(opts, emailOrganizationOptions(ctx, natsClient)...)
+	opts = append(opts, consentOptions(ctx, natsClient)...)
+	opts = append(opts, attestationOptions(ctx, natsClient)...)
+	opts = append(opts, affiliationOptions(ctx, natsClient)...)
+	if revocations := tokenRevocations(ctx); revocations != nil {
+		opts = append(opts, service.WithTokenRevocationListForMessageHandler(revocations, envDuration(constants.TokenRevocationTTLEnvKey, 0)))
+	}
+	if rules := envList(constants.MetadataFieldPolicyEnvKey); len(rules) > 0 {
+		policy, err := service.ParseMetadataFieldPolicy(rules)
+		if err != nil {
+			log.Fatalf("invalid %s: %v", constants.MetadataFieldPolicyEnvKey, err)
+		}
+		opts = append(opts, service.WithMetadataFieldPolicyForMessageHandler(policy))
+	}
+	if rules := envList(constants.CallerProfilesEnvKey); len(rules) > 0 {
+		profiles, err := service.ParseCallerProfiles(rules, envList(constants.CallerProfileDefaultFieldsEnvKey))
+		if err != nil {
+			log.Fatalf("invalid %s: %v", constants.CallerProfilesEnvKey, err)
 		}
+		opts = append(opts, service.WithCallerProfilesForMessageHandler(profiles))
+	}
+	opts = append(opts, service.WithStepUpPolicyForMessageHandler(
+		envDuration(constants.StepUpMaxAgeEnvKey, 0),
+		envList(constants.StepUpAcceptedFactorsEnvKey),
+	))
+	if userImporter, ok := userReaderWriter.(port.UserImporter); ok {
+		opts = append(opts, service.WithUserImporterForMessageHandler(userImporter))
+	}
+	if userReportExporter, ok := userReaderWriter.(port.UserReportExporter); ok {
+		opts = append(opts, userExportOptions(ctx, natsClient, userReportExporter)...)
+	}
+	if userMerger, ok := userReaderWriter.(port.UserMerger); ok {
+		opts = append(opts, userMergeOptions(ctx, natsClient, userMerger)...)
+	}
+	if sessionManager, ok := userReaderWriter.(port.SessionManager); ok {
+		opts = append(opts, service.WithSessionManagerForMessageHandler(sessionManager))
+	}
+	selfTesters := []port.SelfTester{natsClient}
+	if selfTester, ok := userReaderWriter.(port.SelfTester); ok {
+		selfTesters = append(selfTesters, selfTester)
+	}
+	if staticResources, ok := userReaderWriter.(port.StaticResourceCache); ok {
+		opts = append(opts, service.WithStaticResourceCacheForMessageHandler(staticResources))
+	}
+	if reindexer, ok := userReaderWriter.(port.LookupReindexer); ok {
+		opts = append(opts, service.WithLookupReindexerForMessageHandler(reindexer))
+	}
+	opts = append(opts, service.WithSelfTestForMessageHandler(os.Getenv(constants.SelfTestCanaryUserEnvKey), selfTesters...))
+	if typeahead := startTypeaheadIndex(ctx, userReaderWriter); typeahead != nil {
+		opts = append(opts, service.WithTypeaheadSearcherForMessageHandler(typeahead))
+	}
 
-		impersonationFlow, err := auth0.NewImpersonationFlow(ctx, auth0Domain)
+	if userRepoType == constants.UserRepositoryTypeAuth0 {
+		impersonationFlow, err := auth0.NewImpersonationFlow(ctx, auth0DomainFromEnv(), outboundHTTPConfig())
 		if err != nil {
 			slog.WarnContext(ctx, "impersonation flow unavailable", "error", err)
 		} else {
@@ -222,48 +541,53 @@ func QueueSubscriptions(ctx context.Context) error {
 	messageHandlerService := NewMessageHandlerService(
 		service.NewMessageHandlerOrchestrator(opts...),
 	)
-
-	// Get the NATS client - we need to access it directly
-	natsClient := getNATSClient()
-	if natsClient == nil {
-		return fmt.Errorf("NATS client not initialized")
+	startLookupSnapshot(ctx, lookupSnapshot)
+	messageHandlerService.faults = newFaultInjector(ctx)
+
+	name := tenant.FromContext(ctx)
+	handler := service.Chain(port.HandlerFunc(messageHandlerService.HandleMessage),
+		requestMiddleware(router, name, newResponseEnvelope(userRepoType), newRequestLogSampler(serviceEndpoints), newSecurityDetection(ctx, eventPublisher),
+			newEnumerationThrottle(ctx, eventPublisher), newProviderOverride(), newPayloadLimit(serviceEndpoints),
+			newPanicQuarantine(ctx, natsClient), newReadOnlyGuard(serviceEndpoints), newIdempotency(ctx, natsClient, serviceEndpoints))...)
+	if router != nil {
+		// routed requests resolve to this tenant, so they pass its guard
+		var issuer string
+		if tokenIssuer, ok := userReaderWriter.(port.TokenIssuer); ok {
+			issuer = tokenIssuer.TokenIssuer()
+		}
+		router.Register(name, issuer, handler.Handle)
 	}
 
-	// Start subscriptions for each subject
-	subjects := map[string]func(context.Context, port.TransportMessenger){
-		constants.UserMetadataUpdateSubject:           messageHandlerService.HandleMessage,
-		constants.UserEmailToUserSubject:              messageHandlerService.HandleMessage,
-		constants.UserEmailToSubSubject:               messageHandlerService.HandleMessage,
-		constants.UserUsernameToSubSubject:            messageHandlerService.HandleMessage,
-		constants.UserMetadataReadSubject:             messageHandlerService.HandleMessage,
-		constants.UserEmailReadSubject:                messageHandlerService.HandleMessage,
-		constants.UserEmailSetPrimarySubject:          messageHandlerService.HandleMessage,
-		constants.EmailLinkingSendVerificationSubject: messageHandlerService.HandleMessage,
-		constants.EmailLinkingVerifySubject:           messageHandlerService.HandleMessage,
-		constants.UserIdentityLinkSubject:             messageHandlerService.HandleMessage,
-		constants.UserIdentityUnlinkSubject:           messageHandlerService.HandleMessage,
-		constants.UserIdentityListSubject:             messageHandlerService.HandleMessage,
-		constants.UserAddAliasSubject:                 messageHandlerService.HandleMessage,
-		constants.PasswordUpdateSubject:               messageHandlerService.HandleMessage,
-		constants.PasswordResetLinkSubject:            messageHandlerService.HandleMessage,
-		constants.ImpersonationTokenExchangeSubject:   messageHandlerService.HandleMessage,
-	}
-
-	for subject, handler := range subjects {
-		slog.DebugContext(ctx, "subscribing to NATS subject", "subject", subject)
-		if _, err := natsClient.SubscribeWithTransportMessenger(ctx, subject, constants.AuthServiceQueue, handler); err != nil {
-			slog.ErrorContext(ctx, "failed to subscribe to NATS subject",
-				"error", err,
-				"subject", subject,
-			)
-			return fmt.Errorf("failed to subscribe to subject %s: %w", subject, err)
-		}
+	// Register every subject as an endpoint of a NATS micro service so
+	// `nats micro ls/info/stats` can discover and monitor them. Endpoints keep
+	// the existing queue group so replicas load-balance with older deployments;
+	// only per-instance admin endpoints opt out of it.
+	svc, err := natsClient.AddService(ctx, nats.ServiceConfig{
+		Name:        constants.ServiceName,
+		Version:     version,
+		Description: serviceDescription,
+		QueueGroup:  constants.AuthServiceQueue,
+	}, buildServiceEndpoints(handler.Handle))
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to register NATS service", "error", err)
+		return fmt.Errorf("failed to register NATS service: %w", err)
 	}
+	instanceID := svc.Info().ID
+	stats.instanceID.Store(&instanceID)
 
 	slog.DebugContext(ctx, "NATS subscriptions started successfully")
 	return nil
 }
 
+// StopRequestWorkers refuses the NATS requests delivered from now on and
+// waits for the queued and in-flight ones to be replied. It is called on
+// shutdown, after the subscriptions are cancelled.
+func StopRequestWorkers() {
+	if client := getNATSClient(); client != nil {
+		client.StopWorkers()
+	}
+}
+
 // getNATSClient returns the initialized NATS client
 // This is a helper function to access the client for subscription management
 func getNATSClient() *nats.NATSClient {
@@ -0,0 +1,36 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"log"
+	"log/slog"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/nats"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/service"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+)
+
+// defaultPanicQuarantineThreshold tolerates a couple of panics of the same
+// payload, e.g. racing a provider outage, before quarantining it
+const defaultPanicQuarantineThreshold = 3
+
+// newPanicQuarantine returns the quarantine of payloads repeatedly panicking
+// their handler when enabled, and nil otherwise. The NATS client opens the
+// bucket on connect, so a missing bucket is a deployment error.
+func newPanicQuarantine(ctx context.Context, natsClient *nats.NATSClient) *service.PanicQuarantine {
+	if !envBool(constants.PanicQuarantineEnabledEnvKey, false) {
+		return nil
+	}
+
+	kv, ok := natsClient.GetKVStore(constants.KVBucketNameQuarantine)
+	if !ok {
+		log.Fatalf("panic quarantine enabled but the %s KV bucket is not available", constants.KVBucketNameQuarantine)
+	}
+	threshold := envPositiveInt(constants.PanicQuarantineThresholdEnvKey, defaultPanicQuarantineThreshold)
+
+	slog.InfoContext(ctx, "panic quarantine enabled", "threshold", threshold)
+	return service.NewPanicQuarantine(nats.NewPayloadQuarantine(kv), threshold)
+}
@@ -0,0 +1,35 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+)
+
+// ReindexLookups rebuilds the lookup keys of the configured user repository
+// with the current normalization. It backs the -reindex-lookups command, run
+// once after upgrading from a release without normalization or after
+// changing NORMALIZE_GMAIL_CANONICAL.
+func ReindexLookups(ctx context.Context) (model.ReindexResult, error) {
+	reindexer, ok := newUserReaderWriter(ctx).(port.LookupReindexer)
+	if !ok {
+		return model.ReindexResult{}, errs.NewValidation("user repository does not keep lookup keys")
+	}
+	return reindexer.ReindexLookups(ctx, nil)
+}
+
+// RepairLookups points the Authelia lookup keys claimed by a single user back
+// at it and reports those claimed by several. It backs the -repair-lookups
+// command.
+func RepairLookups(ctx context.Context) (model.LookupRepairResult, error) {
+	repairer, ok := newUserReaderWriter(ctx).(port.LookupRepairer)
+	if !ok {
+		return model.LookupRepairResult{}, errs.NewValidation("user repository does not keep lookup keys")
+	}
+	return repairer.RepairLookups(ctx)
+}
@@ -0,0 +1,127 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/service"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	logging "github.com/linuxfoundation/lfx-v2-auth-service/pkg/log"
+)
+
+// defaultReadLogSampleRate logs 1% of successful reads
+const defaultReadLogSampleRate = 0.01
+
+// recordingMessenger captures the response sent through a TransportMessenger
+type recordingMessenger struct {
+	port.TransportMessenger
+	response []byte
+}
+
+// Respond records the response and forwards it
+func (r *recordingMessenger) Respond(data []byte) error {
+	r.response = data
+	return r.TransportMessenger.Respond(data)
+}
+
+// Unwrap returns the wrapped messenger
+func (r *recordingMessenger) Unwrap() port.TransportMessenger {
+	return r.TransportMessenger
+}
+
+// responseError extracts the error from a handler response, if any. Handlers
+// reply with {"success":false,"error":...} or {"error":...} on failure;
+// non-JSON replies (plain lookups) are successes.
+func responseError(response []byte) (string, bool) {
+	var envelope struct {
+		Success *bool  `json:"success"`
+		Error   string `json:"error"`
+	}
+	if err := json.Unmarshal(response, &envelope); err != nil {
+		return "", false
+	}
+	if envelope.Error != "" {
+		return envelope.Error, true
+	}
+	if envelope.Success != nil && !*envelope.Success {
+		return "", true
+	}
+	return "", false
+}
+
+// newRequestLogSampler builds the sampler for successful requests: writes
+// default to 100%, reads to REQUEST_LOG_SAMPLE_RATE, and
+// REQUEST_LOG_SAMPLE_RATES overrides individual subjects.
+func newRequestLogSampler(endpoints []endpointSpec) *logging.Sampler {
+	readRate := defaultReadLogSampleRate
+	if raw := os.Getenv(constants.RequestLogSampleRateEnvKey); raw != "" {
+		parsed, err := logging.ParseSampleRate(raw)
+		if err != nil {
+			log.Fatalf("invalid %s value %s: %v", constants.RequestLogSampleRateEnvKey, raw, err)
+		}
+		readRate = parsed
+	}
+
+	rates := make(map[string]float64, len(endpoints))
+	for _, spec := range endpoints {
+		if spec.write {
+			rates[spec.subject] = 1
+		}
+	}
+
+	overrides, err := logging.ParseSampleRates(os.Getenv(constants.RequestLogSampleRatesEnvKey))
+	if err != nil {
+		log.Fatalf("invalid %s value: %v", constants.RequestLogSampleRatesEnvKey, err)
+	}
+	for subject, rate := range overrides {
+		rates[subject] = rate
+	}
+
+	return logging.NewSampler(readRate, rates)
+}
+
+// requestLogging returns the middleware writing a one-line structured log per
+// request. Failed requests are always logged; successful ones are sampled per
+// subject. Payloads are never logged.
+func requestLogging(sampler *logging.Sampler) service.Middleware {
+	return func(next port.Handler) port.Handler {
+		return port.HandlerFunc(func(ctx context.Context, msg port.TransportMessenger) {
+			started := time.Now()
+			recorder := &recordingMessenger{TransportMessenger: msg}
+
+			next.Handle(ctx, recorder)
+
+			subject := msg.Subject()
+			errMsg, failed := responseError(recorder.response)
+			if !failed && !sampler.Sample(subject) {
+				return
+			}
+
+			outcome := "success"
+			if failed {
+				outcome = "error"
+			}
+			attrs := []any{
+				"subject", subject,
+				"outcome", outcome,
+				"duration_ms", time.Since(started).Milliseconds(),
+				"request_bytes", len(msg.Data()),
+				"response_bytes", len(recorder.response),
+			}
+			if failed {
+				attrs = append(attrs, "error", errMsg)
+			} else {
+				attrs = append(attrs, "sample_rate", sampler.Rate(subject))
+			}
+			slog.InfoContext(ctx, "nats request", attrs...)
+		})
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/featureflag"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/redaction"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/tenant"
+)
+
+// runtimeSettingPrefixes start the environment variables the service reads,
+// so new settings are reported without being listed here. TENANT_ overrides
+// are left out: a tenant reports its settings with them applied.
+var runtimeSettingPrefixes = []string{
+	"AFFILIATIONS_", "ALLOWED_ALIAS_", "ATTESTATION_", "AUTH0_", "AUTHELIA_", "CANARY_PROBE_", "CONSENT_", "DORMANT_ACCOUNTS_", "DPOP_", "DUPLICATE_ACCOUNTS_",
+	"EMAIL_", "ERROR_LOCALIZATION_", "EVENT_SINKS", "FAULT_INJECTION_", "FEATURE_FLAGS_", "HEDGED_READS_", "HTTP_", "IDEMPOTENCY_",
+	"IDENTIFIER_CACHE_", "JOB_LOCK_", "KAFKA_", "KMS_", "KV_ENCRYPTION_", "METADATA_FIELD_POLICY", "METADATA_MAX_", "MOCK_", "NATS_",
+	"NORMALIZE_", "OUTBOX_", "PANIC_QUARANTINE_", "PERMISSION_CACHE_", "PERSONAL_ACCESS_TOKEN", "REDACTION_",
+	"REQUEST_LOG_", "REQUEST_MAX_", "REQUEST_SCHEMA_", "SELFTEST_", "SERVICE_ACCOUNT_", "SHADOW", "STARTUP_",
+	"STEP_UP_", constants.TenantsEnvKey, "TOKEN_REVOCATION_", "TYPEAHEAD_INDEX_",
+	"USER_", "WARMUP_",
+}
+
+// runtimeSettingDefaults are the values of the settings operators most often
+// need to check when they aren't set
+var runtimeSettingDefaults = map[string]string{
+	constants.UserRepositoryTypeEnvKey:             constants.UserRepositoryTypeMock,
+	"NATS_URL":                                     "nats://localhost:4222",
+	"NATS_TIMEOUT":                                 "10s",
+	constants.NATSWorkersEnvKey:                    strconv.Itoa(defaultNATSWorkers),
+	constants.NATSBatchWorkerShareEnvKey:           strconv.FormatFloat(defaultNATSBatchWorkerShare, 'g', -1, 64),
+	constants.UserCacheTTLEnvKey:                   "0s",
+	constants.UserCacheMaxEntriesEnvKey:            strconv.Itoa(defaultUserCacheMaxEntries),
+	constants.IdentifierCacheTTLEnvKey:             defaultIdentifierCacheTTL.String(),
+	constants.IdentifierCacheMaxEntriesEnvKey:      strconv.Itoa(defaultIdentifierCacheMaxEntries),
+	constants.PermissionCacheTTLEnvKey:             defaultPermissionCacheTTL.String(),
+	constants.PermissionCacheMaxEntriesEnvKey:      strconv.Itoa(defaultPermissionCacheMaxEntries),
+	constants.AutheliaUserInfoCacheTTLEnvKey:       defaultAutheliaUserInfoCacheTTL.String(),
+	constants.HedgedReadsEnabledEnvKey:             "false",
+	constants.HedgedReadsMaxPerSecondEnvKey:        strconv.Itoa(defaultHedgedReadsMaxPerSecond),
+	constants.EmailLookupRequireVerifiedEnvKey:     "false",
+	constants.RequestLogSampleRateEnvKey:           strconv.FormatFloat(defaultReadLogSampleRate, 'g', -1, 64),
+	constants.TokenRevocationEnabledEnvKey:         "false",
+	constants.PersonalAccessTokensEnabledEnvKey:    "false",
+	constants.PersonalAccessTokenMaxLifetimeEnvKey: constants.DefaultPersonalAccessTokenMaxLifetime.String(),
+}
+
+// runtimeConfig reports the configuration a tenant was built with. The
+// settings are captured while its environment overrides are applied; the
+// feature flags are read on every request, as the KV bucket may change them.
+type runtimeConfig struct {
+	snapshot model.RuntimeConfig
+	flags    *featureflag.Set
+}
+
+// RuntimeConfig returns the effective configuration of the replica
+func (c *runtimeConfig) RuntimeConfig(ctx context.Context) model.RuntimeConfig {
+	config := c.snapshot
+	config.Settings = maps.Clone(c.snapshot.Settings)
+	config.FeatureFlags = c.flags.Names()
+	return config
+}
+
+// newRuntimeConfig captures the effective configuration of the tenant being
+// built and logs it as the startup banner
+func newRuntimeConfig(ctx context.Context, version string, userReaderWriter port.UserReaderWriter, flags *featureflag.Set) *runtimeConfig {
+	hostname, _ := os.Hostname()
+	snapshot := model.RuntimeConfig{
+		Version:        version,
+		Hostname:       hostname,
+		StartedAt:      processStartedAt,
+		Tenant:         tenant.FromContext(ctx),
+		Provider:       os.Getenv(constants.UserRepositoryTypeEnvKey),
+		ShadowProvider: os.Getenv(constants.ShadowEnvPrefix + constants.UserRepositoryTypeEnvKey),
+		Settings:       runtimeSettings(),
+	}
+	if snapshot.Provider == "" {
+		snapshot.Provider = constants.UserRepositoryTypeMock
+	}
+	if issuer, ok := userReaderWriter.(port.TokenIssuer); ok {
+		snapshot.Issuer = issuer.TokenIssuer()
+		snapshot.Audience = issuer.TokenAudience()
+	}
+	config := &runtimeConfig{snapshot: snapshot, flags: flags}
+
+	banner := config.RuntimeConfig(ctx)
+	slog.InfoContext(ctx, "auth service configuration",
+		"version", banner.Version,
+		"provider", banner.Provider,
+		"shadow_provider", banner.ShadowProvider,
+		"issuer", banner.Issuer,
+		"audience", banner.Audience,
+		"feature_flags", banner.FeatureFlags,
+		"settings", banner.Settings,
+	)
+	return config
+}
+
+// runtimeSettings returns the service settings of the environment, masked
+// for reporting, over the defaults of the unset ones
+func runtimeSettings() map[string]string {
+	settings := maps.Clone(runtimeSettingDefaults)
+	for _, entry := range os.Environ() {
+		key, value, _ := strings.Cut(entry, "=")
+		if strings.HasPrefix(key, constants.TenantEnvPrefix) || !hasRuntimeSettingPrefix(key) {
+			continue
+		}
+		settings[key] = redaction.Setting(key, value)
+	}
+	return settings
+}
+
+func hasRuntimeSettingPrefix(key string) bool {
+	for _, prefix := range runtimeSettingPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
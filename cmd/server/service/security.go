@@ -0,0 +1,83 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/service"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+)
+
+// Default thresholds of the detection rules, within defaultSecurityWindow.
+// Clients legitimately resolve emails in bulk, e.g. a mailing list sync, so
+// enumeration tolerates far more requests than the token rules.
+const (
+	defaultSecurityWindow                    = 5 * time.Minute
+	defaultSecurityInvalidTokenThreshold     = 10
+	defaultSecurityScopeEscalationThreshold  = 3
+	defaultSecurityEmailEnumerationThreshold = 500
+)
+
+// newSecurityDetection returns the middleware raising security events on the
+// requests matching the detection rules when enabled, nil otherwise
+func newSecurityDetection(ctx context.Context, eventPublisher port.EventPublisher) service.Middleware {
+	if !envBool(constants.SecurityDetectionEnabledEnvKey, true) {
+		return nil
+	}
+
+	config := service.SecurityDetectionConfig{
+		Window:                    envDuration(constants.SecurityDetectionWindowEnvKey, defaultSecurityWindow),
+		InvalidTokenThreshold:     envNonNegativeInt(constants.SecurityInvalidTokenThresholdEnvKey, defaultSecurityInvalidTokenThreshold),
+		ScopeEscalationThreshold:  envNonNegativeInt(constants.SecurityScopeEscalationThresholdEnvKey, defaultSecurityScopeEscalationThreshold),
+		EmailEnumerationThreshold: envNonNegativeInt(constants.SecurityEmailEnumerationThresholdEnvKey, defaultSecurityEmailEnumerationThreshold),
+	}
+	slog.InfoContext(ctx, "security event detection enabled",
+		"window", config.Window,
+		"invalid_token_threshold", config.InvalidTokenThreshold,
+		"scope_escalation_threshold", config.ScopeEscalationThreshold,
+		"email_enumeration_threshold", config.EmailEnumerationThreshold,
+	)
+	return service.DetectSecurityEvents(service.NewSecurityDetector(eventPublisher, config))
+}
+
+// Defaults of the enumeration throttle: a caller missing a few accounts, e.g.
+// users who left, isn't slowed down, one probing dozens is blocked
+const (
+	defaultEnumerationWindow        = 10 * time.Minute
+	defaultEnumerationDelayAfter    = 10
+	defaultEnumerationBaseDelay     = 100 * time.Millisecond
+	defaultEnumerationMaxDelay      = 5 * time.Second
+	defaultEnumerationBlockAfter    = 50
+	defaultEnumerationBlockDuration = 15 * time.Minute
+)
+
+// newEnumerationThrottle returns the middleware throttling the account
+// lookups of callers enumerating accounts when enabled, nil otherwise
+func newEnumerationThrottle(ctx context.Context, eventPublisher port.EventPublisher) service.Middleware {
+	if !envBool(constants.EnumerationThrottleEnabledEnvKey, true) {
+		return nil
+	}
+
+	config := service.EnumerationThrottleConfig{
+		Window:        envDuration(constants.EnumerationThrottleWindowEnvKey, defaultEnumerationWindow),
+		DelayAfter:    envNonNegativeInt(constants.EnumerationThrottleDelayAfterEnvKey, defaultEnumerationDelayAfter),
+		BaseDelay:     defaultEnumerationBaseDelay,
+		MaxDelay:      envDuration(constants.EnumerationThrottleMaxDelayEnvKey, defaultEnumerationMaxDelay),
+		BlockAfter:    envNonNegativeInt(constants.EnumerationThrottleBlockAfterEnvKey, defaultEnumerationBlockAfter),
+		BlockDuration: envDuration(constants.EnumerationThrottleBlockDurationEnvKey, defaultEnumerationBlockDuration),
+	}
+	config.BaseDelay = min(config.BaseDelay, config.MaxDelay)
+	slog.InfoContext(ctx, "enumeration throttle enabled",
+		"window", config.Window,
+		"delay_after", config.DelayAfter,
+		"max_delay", config.MaxDelay,
+		"block_after", config.BlockAfter,
+		"block_duration", config.BlockDuration,
+	)
+	return service.ThrottleEnumeration(service.NewEnumerationThrottle(eventPublisher, config))
+}
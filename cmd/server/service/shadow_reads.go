@@ -0,0 +1,74 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/service"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+)
+
+const (
+	defaultShadowReadsSampleRate  = 1.0
+	defaultShadowReadsMaxInFlight = 16
+	defaultShadowReadsTimeout     = 5 * time.Second
+)
+
+// newShadowReads wraps userReaderWriter so its reads are replayed against a
+// secondary provider when SHADOW__USER_REPOSITORY_TYPE is set, and returns it
+// unchanged otherwise. The secondary is built like the primary, with the
+// SHADOW__<KEY> variables applied over the environment.
+func newShadowReads(ctx context.Context, userReaderWriter port.UserReaderWriter) port.UserReaderWriter {
+	secondaryType := os.Getenv(constants.ShadowEnvPrefix + constants.UserRepositoryTypeEnvKey)
+	if secondaryType == "" {
+		return userReaderWriter
+	}
+
+	var secondary port.UserReaderWriter
+	_ = withEnvOverrides(constants.ShadowEnvPrefix, func() error {
+		secondary = newUserReaderWriter(ctx)
+		return nil
+	})
+
+	config := service.ShadowConfig{
+		SampleRate:  envFraction(constants.ShadowReadsSampleRateEnvKey, defaultShadowReadsSampleRate),
+		MaxInFlight: envPositiveInt(constants.ShadowReadsMaxInFlightEnvKey, defaultShadowReadsMaxInFlight),
+		Timeout:     envDuration(constants.ShadowReadsTimeoutEnvKey, defaultShadowReadsTimeout),
+		Primary:     primaryProviderName(),
+		Secondary:   secondaryType,
+	}
+
+	slog.InfoContext(ctx, "shadow reads enabled",
+		"secondary", secondaryType,
+		"sample_rate", config.SampleRate,
+		"max_in_flight", config.MaxInFlight,
+		"timeout", config.Timeout,
+	)
+
+	return service.NewShadowUserReaderWriter(userReaderWriter, secondary, config)
+}
+
+// primaryProviderName returns the name of the provider USER_REPOSITORY_TYPE
+// selects
+func primaryProviderName() string {
+	return cmp.Or(os.Getenv(constants.UserRepositoryTypeEnvKey), constants.UserRepositoryTypeMock)
+}
+
+// newProviderOverride returns the middleware pinning the requests of the
+// PROVIDER_OVERRIDE_CALLERS to the provider they hint, the primary or, with
+// shadow reads enabled, the secondary one. Without callers every hint is
+// refused.
+func newProviderOverride() service.Middleware {
+	providers := []string{primaryProviderName()}
+	if secondaryType := os.Getenv(constants.ShadowEnvPrefix + constants.UserRepositoryTypeEnvKey); secondaryType != "" {
+		providers = append(providers, secondaryType)
+	}
+	return service.OverrideProviders(service.NewProviderOverridePolicy(providers, envList(constants.ProviderOverrideCallersEnvKey)))
+}
@@ -0,0 +1,35 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/service"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+)
+
+// APISpecHandler returns the HTTP handler of the AsyncAPI document of the
+// service endpoints and the OpenAPI document of routes, or nil when
+// API_SPEC_ENABLED is false
+func APISpecHandler(version string, routes []service.HTTPRoute) http.Handler {
+	if !envBool(constants.APISpecEnabledEnvKey, true) {
+		return nil
+	}
+
+	operations := make([]service.SpecOperation, 0, len(serviceEndpoints))
+	for _, spec := range serviceEndpoints {
+		operations = append(operations, service.SpecOperation{
+			Subject:     spec.subject,
+			Description: spec.description,
+			Docs:        spec.docs,
+		})
+	}
+	handler, err := service.SpecHandler(version, operations, routes)
+	if err != nil {
+		log.Fatalf("failed to generate the API documents: %v", err)
+	}
+	return handler
+}
@@ -0,0 +1,78 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"log"
+	"os"
+	"strings"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/tenant"
+)
+
+// tenantNames returns the tenants listed in TENANTS, exiting on invalid or
+// repeated names. It is empty for single-tenant deployments.
+func tenantNames() []string {
+	names := envList(constants.TenantsEnvKey)
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if err := tenant.Validate(name); err != nil {
+			log.Fatalf("invalid %s value: %v", constants.TenantsEnvKey, err)
+		}
+		if seen[name] {
+			log.Fatalf("invalid %s value: tenant %q is listed twice", constants.TenantsEnvKey, name)
+		}
+		seen[name] = true
+	}
+	return names
+}
+
+// tenantEnvPrefix starts the variables overriding settings for tenant name,
+// e.g. TENANT_LF_STAGING__ for lf-staging
+func tenantEnvPrefix(name string) string {
+	return constants.TenantEnvPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "__"
+}
+
+// withTenantEnv runs build with the TENANT_<NAME>__<KEY> variables of tenant
+// name applied over the process environment, and restores it afterwards.
+// Settings are read when providers are built, so the tenant's providers,
+// credentials and caches are configured by its overrides, falling back to the
+// shared values. Tenants must be built one at a time.
+func withTenantEnv(name string, build func() error) error {
+	return withEnvOverrides(tenantEnvPrefix(name), build)
+}
+
+// withEnvOverrides runs build with the variables starting with prefix
+// applied, without it, over the process environment, and restores the
+// environment afterwards
+func withEnvOverrides(prefix string, build func() error) error {
+	type previous struct {
+		value string
+		set   bool
+	}
+	restore := map[string]previous{}
+	for _, entry := range os.Environ() {
+		key, value, _ := strings.Cut(entry, "=")
+		if !strings.HasPrefix(key, prefix) || key == prefix {
+			continue
+		}
+		setting := strings.TrimPrefix(key, prefix)
+		old, set := os.LookupEnv(setting)
+		restore[setting] = previous{value: old, set: set}
+		if err := os.Setenv(setting, value); err != nil {
+			log.Fatalf("failed to apply %s: %v", key, err)
+		}
+	}
+	defer func() {
+		for setting, old := range restore {
+			if old.set {
+				_ = os.Setenv(setting, old.value)
+			} else {
+				_ = os.Unsetenv(setting)
+			}
+		}
+	}()
+	return build()
+}
@@ -0,0 +1,32 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"log"
+	"os"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/httpclient"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/tlsconfig"
+)
+
+// autheliaHTTPClientConfig returns the HTTP client configuration for calls to
+// Authelia: the outbound settings plus the CA bundle and client certificate
+// from AUTHELIA_TLS_*.
+func autheliaHTTPClientConfig() httpclient.Config {
+	config := outboundHTTPConfig()
+
+	tlsConfig, err := tlsconfig.Client(tlsconfig.ClientConfig{
+		CAFile:     os.Getenv(constants.AutheliaTLSCAFileEnvKey),
+		CertFile:   os.Getenv(constants.AutheliaTLSCertFileEnvKey),
+		KeyFile:    os.Getenv(constants.AutheliaTLSKeyFileEnvKey),
+		ServerName: os.Getenv(constants.AutheliaTLSServerNameEnvKey),
+	})
+	if err != nil {
+		log.Fatalf("invalid Authelia TLS configuration: %v", err)
+	}
+	config.TLSClientConfig = tlsConfig
+	return config
+}
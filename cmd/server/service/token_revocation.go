@@ -0,0 +1,51 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/nats"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+)
+
+var (
+	// tokenRevocationLists holds the list of each tenant, by its NATS client
+	tokenRevocationLists   = map[*nats.NATSClient]port.TokenRevocationList{}
+	tokenRevocationListsMu sync.Mutex
+)
+
+// tokenRevocations returns the token revocation list when enabled, or nil.
+// The Auth0 repository and the message handlers share one list, so it is
+// loaded once per tenant, on first use.
+func tokenRevocations(ctx context.Context) port.TokenRevocationList {
+	if !envBool(constants.TokenRevocationEnabledEnvKey, false) {
+		return nil
+	}
+
+	// the NATS client opens the bucket on connect when revocation is enabled
+	natsInit(ctx)
+	client := getNATSClient()
+
+	tokenRevocationListsMu.Lock()
+	defer tokenRevocationListsMu.Unlock()
+	if list, ok := tokenRevocationLists[client]; ok {
+		return list
+	}
+
+	kv, ok := client.GetKVStore(constants.KVBucketNameTokenRevocations)
+	if !ok {
+		log.Fatalf("token revocation enabled but the %s KV bucket is not available", constants.KVBucketNameTokenRevocations)
+	}
+
+	list, err := nats.NewTokenRevocationList(ctx, kv)
+	if err != nil {
+		log.Fatalf("failed to load token revocations: %v", err)
+	}
+	tokenRevocationLists[client] = list
+	return list
+}
@@ -0,0 +1,50 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/service"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/scheduler"
+)
+
+// defaultTypeaheadRefreshInterval leaves room for Auth0 users export jobs,
+// which take minutes on large tenants
+const defaultTypeaheadRefreshInterval = 15 * time.Minute
+
+// startTypeaheadIndex schedules the typeahead index rebuild when enabled and
+// supported by the configured user repository. It returns nil otherwise. The
+// first build starts immediately and runs in the background, so typeahead
+// searches are rejected as not ready until it completes.
+func startTypeaheadIndex(ctx context.Context, userReaderWriter port.UserReaderWriter) *service.TypeaheadIndex {
+	if !envBool(constants.TypeaheadIndexEnabledEnvKey, false) {
+		return nil
+	}
+
+	exporter, ok := userReaderWriter.(port.UserExporter)
+	if !ok {
+		slog.WarnContext(ctx, "typeahead index enabled but the user repository can't export users",
+			"repository_type", os.Getenv(constants.UserRepositoryTypeEnvKey),
+		)
+		return nil
+	}
+
+	interval := envDuration(constants.TypeaheadIndexRefreshIntervalEnvKey, defaultTypeaheadRefreshInterval)
+	if interval == 0 {
+		log.Fatalf("invalid %s value: must be a positive duration", constants.TypeaheadIndexRefreshIntervalEnvKey)
+	}
+
+	slog.InfoContext(ctx, "scheduling typeahead index refresh", "interval", interval)
+
+	index := service.NewTypeaheadIndex(exporter)
+	scheduler.Every(ctx, "typeahead-index", interval, 0, index.Run)
+	return index
+}
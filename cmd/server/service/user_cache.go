@@ -0,0 +1,103 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/memory"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/nats"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/service"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/scheduler"
+)
+
+const (
+	defaultUserCacheMaxEntries        = 10_000
+	defaultUserCacheReconcileInterval = 10 * time.Minute
+	defaultUserCacheSampleRate        = 0.1
+	defaultUserCacheMaxSamples        = 100
+
+	defaultIdentifierCacheTTL        = 5 * time.Minute
+	defaultIdentifierCacheMaxEntries = 10_000
+
+	defaultPermissionCacheTTL        = time.Minute
+	defaultPermissionCacheMaxEntries = 10_000
+
+	defaultAutheliaUserInfoCacheTTL         = 30 * time.Second
+	defaultAutheliaUserInfoNegativeCacheTTL = 5 * time.Second
+	defaultAutheliaUserInfoCacheMaxEntries  = 10_000
+)
+
+// newUserCache wraps userReaderWriter with the read-through user cache when
+// USER_CACHE_TTL is set, keeps it in step with the other replicas' caches over
+// natsClient, and schedules its reconciliation against the provider.
+// It returns userReaderWriter unchanged when caching is disabled.
+func newUserCache(ctx context.Context, natsClient *nats.NATSClient, userReaderWriter port.UserReaderWriter) port.UserReaderWriter {
+	ttl := envDuration(constants.UserCacheTTLEnvKey, 0)
+	if ttl == 0 {
+		return userReaderWriter
+	}
+
+	var opts []memory.UserCacheOption
+	if staleTTL := envDuration(constants.UserCacheStaleTTLEnvKey, 0); staleTTL > 0 {
+		slog.InfoContext(ctx, "user cache serving stale entries while revalidating", "stale_ttl", staleTTL)
+		opts = append(opts, memory.WithStaleWhileRevalidate(staleTTL))
+	}
+	broadcast := envBool(constants.UserCacheBroadcastInvalidationsEnvKey, true)
+	var broadcaster *service.CacheInvalidationBroadcaster
+	if broadcast {
+		opts = append(opts, memory.WithInvalidationBroadcast(func(ctx context.Context, userID string) {
+			broadcaster.Publish(ctx, userID)
+		}))
+	}
+	userCache := memory.NewUserCache(userReaderWriter, ttl, envPositiveInt(constants.UserCacheMaxEntriesEnvKey, defaultUserCacheMaxEntries), opts...)
+	if broadcast {
+		broadcaster = service.NewCacheInvalidationBroadcaster(userCache, natsClient, uuid.NewString())
+		if _, err := natsClient.SubscribeBroadcast(ctx, constants.UserCacheInvalidateSubject, broadcaster.Handle); err != nil {
+			log.Fatalf("failed to subscribe to user cache invalidations: %v", err)
+		}
+	}
+
+	interval := envDuration(constants.UserCacheReconcileIntervalEnvKey, defaultUserCacheReconcileInterval)
+	if interval == 0 {
+		slog.InfoContext(ctx, "user cache enabled without reconciliation", "ttl", ttl)
+		return userCache
+	}
+
+	sampleRate := defaultUserCacheSampleRate
+	if raw := os.Getenv(constants.UserCacheReconcileSampleRateEnvKey); raw != "" {
+		parsed, err := strconv.ParseFloat(raw, 64)
+		if err != nil || parsed <= 0 || parsed > 1 {
+			log.Fatalf("invalid %s value %s: must be in (0, 1]", constants.UserCacheReconcileSampleRateEnvKey, raw)
+		}
+		sampleRate = parsed
+	}
+
+	config := service.CacheReconcilerConfig{
+		SampleRate: sampleRate,
+		MaxSamples: envPositiveInt(constants.UserCacheReconcileMaxSamplesEnvKey, defaultUserCacheMaxSamples),
+	}
+
+	slog.InfoContext(ctx, "user cache enabled",
+		"ttl", ttl,
+		"broadcast_invalidations", broadcast,
+		"reconcile_interval", interval,
+		"sample_rate", config.SampleRate,
+		"max_samples", config.MaxSamples,
+	)
+
+	reconciler := service.NewCacheReconciler(userCache, userReaderWriter, config)
+	scheduler.Every(ctx, "user-cache-reconcile", interval, interval, reconciler.Run)
+
+	return userCache
+}
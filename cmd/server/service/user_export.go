@@ -0,0 +1,32 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"log"
+	"log/slog"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/nats"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/service"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+)
+
+// userExportOptions wires bulk user exports, and the object store they can be
+// kept in when enabled. The NATS client opens the object store on connect,
+// so a missing one is a deployment error.
+func userExportOptions(ctx context.Context, natsClient *nats.NATSClient, exporter port.UserReportExporter) []service.MessageHandlerOrchestratorOption {
+	opts := []service.MessageHandlerOrchestratorOption{service.WithUserReportExporterForMessageHandler(exporter)}
+	if !envBool(constants.UserExportObjectStoreEnabledEnvKey, false) {
+		return opts
+	}
+
+	objects, ok := natsClient.GetObjectStore(constants.ObjectStoreNameUserExports)
+	if !ok {
+		log.Fatalf("user export object store enabled but the %s object store is not available", constants.ObjectStoreNameUserExports)
+	}
+	slog.InfoContext(ctx, "user exports can be stored in the object store", "bucket", constants.ObjectStoreNameUserExports)
+	return append(opts, service.WithUserExportStoreForMessageHandler(nats.NewUserExportStore(objects)))
+}
@@ -0,0 +1,32 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"log"
+	"log/slog"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/nats"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/service"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+)
+
+// userMergeOptions wires user merges, and their audit history when enabled.
+// The NATS client opens the bucket on connect, so a missing one is a
+// deployment error.
+func userMergeOptions(ctx context.Context, natsClient *nats.NATSClient, merger port.UserMerger) []service.MessageHandlerOrchestratorOption {
+	opts := []service.MessageHandlerOrchestratorOption{service.WithUserMergerForMessageHandler(merger)}
+	if !envBool(constants.UserMergeHistoryEnabledEnvKey, false) {
+		return opts
+	}
+
+	kv, ok := natsClient.GetKVStore(constants.KVBucketNameUserMerges)
+	if !ok {
+		log.Fatalf("user merge history enabled but the %s KV bucket is not available", constants.KVBucketNameUserMerges)
+	}
+	slog.InfoContext(ctx, "user merges are recorded", "bucket", constants.KVBucketNameUserMerges)
+	return append(opts, service.WithUserMergeHistoryForMessageHandler(nats.NewUserMergeHistory(kv)))
+}
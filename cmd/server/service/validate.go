@@ -0,0 +1,76 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/auth0"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/authelia"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/nats"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/provider"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+)
+
+// ValidateConfig checks the configuration against the services it points
+// at — NATS and its KV buckets, and the identity provider — and reports
+// every problem found. It backs the -validate-config command and runs at
+// startup, so a misconfiguration shows up before the first request.
+func ValidateConfig(ctx context.Context) *model.ConfigReport {
+	repositoryType := os.Getenv(constants.UserRepositoryTypeEnvKey)
+	if repositoryType == "" {
+		repositoryType = constants.UserRepositoryTypeMock
+	}
+
+	checks := []model.ConfigCheck{
+		model.RunConfigCheck("repository", func() (string, string) {
+			if provider.Registered(repositoryType) {
+				return model.ConfigCheckOK, repositoryType
+			}
+			return model.ConfigCheckFail, "unknown " + constants.UserRepositoryTypeEnvKey + " " + repositoryType +
+				" (registered: " + strings.Join(provider.Names(), ", ") + ")"
+		}),
+	}
+	checks = append(checks, nats.CheckConfig(ctx, natsConfig())...)
+
+	switch repositoryType {
+	case constants.UserRepositoryTypeAuth0:
+		checks = append(checks, auth0.CheckConfig(ctx, outboundHTTPConfig(), auth0.Config{
+			Tenant: os.Getenv(constants.Auth0TenantEnvKey),
+			Domain: auth0DomainFromEnv(),
+		})...)
+	case constants.UserRepositoryTypeAuthelia:
+		checks = append(checks, authelia.CheckConfig(ctx, autheliaHTTPClientConfig(),
+			autheliaUserInfoURL(),
+			os.Getenv(constants.AutheliaOIDCIssuerURLEnvKey),
+		)...)
+	}
+	return model.NewConfigReport(checks)
+}
+
+// ValidateConfigOnStartup runs ValidateConfig unless STARTUP_CONFIG_VALIDATION
+// is false, logging every check, and reports whether the service can start
+func ValidateConfigOnStartup(ctx context.Context) bool {
+	if !envBool(constants.StartupConfigValidationEnvKey, true) {
+		return true
+	}
+
+	report := ValidateConfig(ctx)
+	for _, check := range report.Checks {
+		attrs := []any{"check", check.Name, "detail", check.Detail, "duration_ms", check.DurationMS}
+		switch check.Status {
+		case model.ConfigCheckFail:
+			slog.ErrorContext(ctx, "configuration check failed", attrs...)
+		case model.ConfigCheckWarn:
+			slog.WarnContext(ctx, "configuration check warning", attrs...)
+		default:
+			slog.DebugContext(ctx, "configuration check "+check.Status, attrs...)
+		}
+	}
+	return report.OK
+}
@@ -0,0 +1,101 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/nats"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/service"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/scheduler"
+)
+
+const (
+	defaultWarmUpTimeout              = 30 * time.Second
+	defaultWarmUpHotUsersSaveInterval = 5 * time.Minute
+)
+
+// warmUps are the warm-ups of the tenants of the process, which is ready
+// once all of them ran
+var warmUps struct {
+	mu   sync.Mutex
+	runs []*service.WarmUp
+}
+
+// warmedUp reports whether the warm-ups of all tenants ran
+func warmedUp() bool {
+	warmUps.mu.Lock()
+	defer warmUps.mu.Unlock()
+	for _, run := range warmUps.runs {
+		if !run.Done() {
+			return false
+		}
+	}
+	return true
+}
+
+// startWarmUp runs the warm-up of a tenant in the background when enabled:
+// the provider, when userReaderWriter can warm up, then the hot users of
+// the cache warm-up bucket into userRepository, when WARMUP_HOT_USERS is set
+// and userRepository caches users. The hot user list of the cache is saved
+// every WARMUP_HOT_USERS_SAVE_INTERVAL after. The replica reports not ready
+// until the warm-up ran, so it gets traffic warm.
+func startWarmUp(ctx context.Context, natsClient *nats.NATSClient, userReaderWriter, userRepository port.UserReaderWriter) {
+	if !envBool(constants.WarmUpEnabledEnvKey, true) {
+		return
+	}
+
+	var warmers []port.Warmer
+	if warmer, ok := userReaderWriter.(port.Warmer); ok {
+		warmers = append(warmers, warmer)
+	}
+	config := service.WarmUpConfig{
+		HotUsers: envNonNegativeInt(constants.WarmUpHotUsersEnvKey, 0),
+		Timeout:  envDuration(constants.WarmUpTimeoutEnvKey, defaultWarmUpTimeout),
+	}
+
+	var (
+		cache port.HotUserCache
+		store port.HotUserStore
+	)
+	if config.HotUsers > 0 {
+		var ok bool
+		if cache, ok = userRepository.(port.HotUserCache); !ok {
+			slog.WarnContext(ctx, "hot users set but the user cache is disabled, they aren't preloaded",
+				"hot_users", config.HotUsers,
+			)
+			config.HotUsers = 0
+		} else {
+			kv, ok := natsClient.GetKVStore(constants.KVBucketNameCacheWarmup)
+			if !ok {
+				log.Fatalf("hot users set but the %s KV bucket is not available", constants.KVBucketNameCacheWarmup)
+			}
+			store = nats.NewHotUserStore(kv)
+		}
+	}
+	if len(warmers) == 0 && config.HotUsers == 0 {
+		return
+	}
+
+	warmUp := service.NewWarmUp(cache, store, config, warmers...)
+	warmUps.mu.Lock()
+	warmUps.runs = append(warmUps.runs, warmUp)
+	warmUps.mu.Unlock()
+
+	slog.InfoContext(ctx, "warming up", "warmers", len(warmers), "hot_users", config.HotUsers, "timeout", config.Timeout)
+	go warmUp.Run(ctx)
+	if config.HotUsers > 0 {
+		interval := envDuration(constants.WarmUpHotUsersSaveIntervalEnvKey, defaultWarmUpHotUsersSaveInterval)
+		if interval == 0 {
+			log.Fatalf("invalid %s value: must be a positive duration", constants.WarmUpHotUsersSaveIntervalEnvKey)
+		}
+		scheduler.Every(ctx, "hot-users-save", interval, interval, warmUp.SaveHotUsers)
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/tlsconfig"
+)
+
+// clientAuthRequireExceptProbes verifies client certificates during the
+// handshake when presented and rejects requests without one, except for the
+// health probes, which kubelet sends without a certificate.
+const clientAuthRequireExceptProbes = "require_except_probes"
+
+// serverTLSConfig returns the TLS configuration for the HTTP listener, or nil
+// to serve plain HTTP. exceptProbes is true when client certificates are
+// enforced per request rather than during the handshake.
+func serverTLSConfig() (config *tls.Config, exceptProbes bool, err error) {
+	certFile := os.Getenv(constants.HTTPTLSCertFileEnvKey)
+	keyFile := os.Getenv(constants.HTTPTLSKeyFileEnvKey)
+	if certFile == "" && keyFile == "" {
+		return nil, false, nil
+	}
+
+	clientAuth := tlsconfig.ClientAuth(os.Getenv(constants.HTTPTLSClientAuthEnvKey))
+	if clientAuth == clientAuthRequireExceptProbes {
+		clientAuth, exceptProbes = tlsconfig.ClientAuthVerifyIfGiven, true
+	}
+
+	config, err = tlsconfig.Server(tlsconfig.ServerConfig{
+		CertFile:     certFile,
+		KeyFile:      keyFile,
+		ClientCAFile: os.Getenv(constants.HTTPTLSClientCAFileEnvKey),
+		ClientAuth:   clientAuth,
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid HTTP TLS configuration: %w", err)
+	}
+	return config, exceptProbes, nil
+}
+
+// requireClientCert rejects requests that did not present a verified client
+// certificate, except for the exempt paths
+func requireClientCert(next http.Handler, exempt ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if slices.Contains(exempt, r.URL.Path) || (r.TLS != nil && len(r.TLS.VerifiedChains) > 0) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		http.Error(w, "client certificate required", http.StatusUnauthorized)
+	})
+}
@@ -0,0 +1,50 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import "time"
+
+// Login event types published on the login events subject. They are provider
+// agnostic; adapters map their native log codes onto these.
+const (
+	LoginEventTypeSuccess     = "login.success"
+	LoginEventTypeFailure     = "login.failure"
+	LoginEventTypeMFASuccess  = "mfa.success"
+	LoginEventTypeMFAFailure  = "mfa.failure"
+	LoginEventTypeBlocked     = "login.blocked"
+	LoginEventTypeUnsupported = ""
+)
+
+// LoginEvent is a normalized authentication event received from the identity
+// provider's log stream.
+type LoginEvent struct {
+	// EventID is the provider's log identifier, stable across redeliveries
+	EventID string `json:"event_id"`
+	// Type is one of the LoginEventType* constants
+	Type string `json:"type"`
+	// ProviderType is the provider's native event code (e.g. Auth0 "s", "fp")
+	ProviderType string `json:"provider_type"`
+	// UserID is the canonical user identifier, when the provider resolved one
+	UserID string `json:"user_id,omitempty"`
+	// Username is the login name the user presented
+	Username string `json:"username,omitempty"`
+	// Connection is the connection used to authenticate
+	Connection string `json:"connection,omitempty"`
+	// ClientID is the application the user authenticated against
+	ClientID string `json:"client_id,omitempty"`
+	// IP is the truncated source IP address
+	IP string `json:"ip,omitempty"`
+	// Description is the provider's human readable description
+	Description string `json:"description,omitempty"`
+	// Timestamp is when the provider recorded the event
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// UserActivity holds login activity for a user, as reported by the identity
+// provider. LastIP is always truncated to its network prefix.
+type UserActivity struct {
+	LastLogin   *time.Time `json:"last_login,omitempty" yaml:"last_login,omitempty"`
+	LoginsCount *int       `json:"logins_count,omitempty" yaml:"logins_count,omitempty"`
+	LastIP      string     `json:"last_ip,omitempty" yaml:"last_ip,omitempty"`
+}
@@ -0,0 +1,226 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import (
+	_ "embed"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+)
+
+// iso3166List holds the ISO 3166-1 countries and ISO 3166-2 subdivisions
+//
+//go:embed iso3166.txt
+var iso3166List string
+
+// maxAddressSuggestions bounds the suggestions of an address error
+const maxAddressSuggestions = 3
+
+// isoRegion is a country or a subdivision with the names it's known by
+type isoRegion struct {
+	code  string
+	name  string
+	folds []string
+}
+
+// isoRegions indexes the countries and, per country, their subdivisions
+type isoRegions struct {
+	countries    []isoRegion
+	subdivisions map[string][]isoRegion
+}
+
+var regions = parseISO3166(iso3166List)
+
+func parseISO3166(list string) isoRegions {
+	parsed := isoRegions{subdivisions: make(map[string][]isoRegion)}
+	for _, line := range strings.Split(list, "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		code := fields[0]
+		if country, _, isSubdivision := strings.Cut(code, "-"); isSubdivision {
+			// "CA" is matched as well as "US-CA"
+			folds := []string{foldRegion(code), foldRegion(code[len(country)+1:]), foldRegion(fields[1])}
+			parsed.subdivisions[country] = append(parsed.subdivisions[country], isoRegion{code: code, name: fields[1], folds: folds})
+			continue
+		}
+		region := isoRegion{code: code, name: fields[2]}
+		for _, name := range fields {
+			region.folds = append(region.folds, foldRegion(name))
+		}
+		parsed.countries = append(parsed.countries, region)
+	}
+	return parsed
+}
+
+// foldRegion lowercases a region name or code and strips its diacritics and
+// punctuation, so "Côte d’Ivoire" matches "cote d'ivoire"
+func foldRegion(s string) string {
+	stripped, _, _ := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), s)
+	return strings.Join(strings.FieldsFunc(strings.ToLower(stripped), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// AddressSuggestion is a region close to a value that matched none
+type AddressSuggestion struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+// AddressError is a country or state_province matching no ISO 3166 region,
+// with the regions the value was closest to. It unwraps to a Validation error.
+type AddressError struct {
+	Field       string              `json:"field"`
+	Value       string              `json:"value"`
+	Suggestions []AddressSuggestion `json:"suggestions,omitempty"`
+
+	message string
+}
+
+// Error returns the error message for AddressError.
+func (e *AddressError) Error() string {
+	if len(e.Suggestions) == 0 {
+		return e.message
+	}
+	described := make([]string, len(e.Suggestions))
+	for i, suggestion := range e.Suggestions {
+		described[i] = fmt.Sprintf("%s (%s)", suggestion.Code, suggestion.Name)
+	}
+	return e.message + "; did you mean " + strings.Join(described, " or ") + "?"
+}
+
+// Unwrap lets callers handle AddressError as any other validation error
+func (e *AddressError) Unwrap() error {
+	return errors.NewValidation(e.message)
+}
+
+// NormalizeCountry returns the ISO 3166-1 alpha-2 code of a country given by
+// its alpha-2 or alpha-3 code or by its name, e.g. "US" for "usa" or
+// "United States of America"
+func NormalizeCountry(country string) (string, error) {
+	if region, ok := matchRegion(regions.countries, country); ok {
+		return region.code, nil
+	}
+	return "", &AddressError{
+		Field:       "country",
+		Value:       country,
+		Suggestions: suggestRegions(regions.countries, country),
+		message:     fmt.Sprintf("country %q is not an ISO 3166-1 country", country),
+	}
+}
+
+// NormalizeSubdivision returns the ISO 3166-2 code of a subdivision of the
+// country with the alpha-2 code country, given by its code, with or without
+// the country prefix, or by its name, e.g. "US-CA" for "CA" or "California"
+func NormalizeSubdivision(country, subdivision string) (string, error) {
+	subdivisions := regions.subdivisions[country]
+	if region, ok := matchRegion(subdivisions, subdivision); ok {
+		return region.code, nil
+	}
+	return "", &AddressError{
+		Field:       "state_province",
+		Value:       subdivision,
+		Suggestions: suggestRegions(subdivisions, subdivision),
+		message:     fmt.Sprintf("state_province %q is not an ISO 3166-2 subdivision of %s", subdivision, country),
+	}
+}
+
+func matchRegion(candidates []isoRegion, value string) (isoRegion, bool) {
+	folded := foldRegion(value)
+	i := slices.IndexFunc(candidates, func(region isoRegion) bool { return slices.Contains(region.folds, folded) })
+	if folded == "" || i < 0 {
+		return isoRegion{}, false
+	}
+	return candidates[i], true
+}
+
+// suggestRegions returns the regions with a name or code within a few edits
+// of value, closest first
+func suggestRegions(candidates []isoRegion, value string) []AddressSuggestion {
+	folded := foldRegion(value)
+	// a typo per four characters, and at least one
+	threshold := max(1, len([]rune(folded))/4)
+
+	type scored struct {
+		region   isoRegion
+		distance int
+	}
+	var close []scored
+	for _, region := range candidates {
+		best := threshold + 1
+		for _, fold := range region.folds {
+			best = min(best, editDistance(folded, fold))
+		}
+		if best <= threshold {
+			close = append(close, scored{region, best})
+		}
+	}
+	slices.SortStableFunc(close, func(a, b scored) int { return a.distance - b.distance })
+
+	var suggestions []AddressSuggestion
+	for _, c := range close[:min(len(close), maxAddressSuggestions)] {
+		suggestions = append(suggestions, AddressSuggestion{Code: c.region.code, Name: c.region.name})
+	}
+	return suggestions
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
+
+// normalizeAddress replaces the country and state_province, when set, with
+// their ISO codes. A subdivision is only known within its country, so
+// state_province can't be set without the country in the same update. An
+// empty value is kept, clearing the field.
+func (um *UserMetadata) normalizeAddress() error {
+	country := ""
+	if um.Country != nil && *um.Country != "" {
+		code, err := NormalizeCountry(*um.Country)
+		if err != nil {
+			return err
+		}
+		*um.Country = code
+		country = code
+	}
+	if um.StateProvince == nil || *um.StateProvince == "" {
+		return nil
+	}
+	if country == "" {
+		return errors.NewValidation("country is required to set state_province")
+	}
+	code, err := NormalizeSubdivision(country, *um.StateProvince)
+	if err != nil {
+		return err
+	}
+	*um.StateProvince = code
+	return nil
+}
@@ -0,0 +1,89 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import (
+	stderrors "errors"
+	"testing"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+)
+
+func TestNormalizeCountry(t *testing.T) {
+	for _, tt := range []struct {
+		in, want string
+	}{
+		{"US", "US"},
+		{"usa", "US"},
+		{"United States of America", "US"},
+		{" germany ", "DE"},
+		{"Cote d'Ivoire", "CI"},
+		{"Bolivia", "BO"},
+	} {
+		if got, err := NormalizeCountry(tt.in); err != nil || got != tt.want {
+			t.Errorf("NormalizeCountry(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+
+	_, err := NormalizeCountry("Untied States")
+	var addressErr *AddressError
+	if !stderrors.As(err, &addressErr) {
+		t.Fatalf("NormalizeCountry(near miss) error = %v, want an AddressError", err)
+	}
+	if len(addressErr.Suggestions) == 0 || addressErr.Suggestions[0].Code != "US" {
+		t.Errorf("suggestions = %v, want US first", addressErr.Suggestions)
+	}
+	var validation errors.Validation
+	if !stderrors.As(err, &validation) {
+		t.Errorf("AddressError doesn't unwrap to a Validation error")
+	}
+
+	if _, err := NormalizeCountry("Aetheria"); err == nil || !stderrors.As(err, &addressErr) || len(addressErr.Suggestions) != 0 {
+		t.Errorf("NormalizeCountry(Aetheria) = %v, want an error without suggestions", err)
+	}
+}
+
+func TestNormalizeSubdivision(t *testing.T) {
+	for _, tt := range []struct {
+		country, in, want string
+	}{
+		{"US", "CA", "US-CA"},
+		{"US", "us-ca", "US-CA"},
+		{"US", "California", "US-CA"},
+		{"DE", "Bayern", "DE-BY"},
+		{"FR", "Ile-de-France", "FR-IDF"},
+	} {
+		if got, err := NormalizeSubdivision(tt.country, tt.in); err != nil || got != tt.want {
+			t.Errorf("NormalizeSubdivision(%q, %q) = %q, %v, want %q", tt.country, tt.in, got, err, tt.want)
+		}
+	}
+
+	_, err := NormalizeSubdivision("US", "Califronia")
+	var addressErr *AddressError
+	if !stderrors.As(err, &addressErr) || len(addressErr.Suggestions) == 0 || addressErr.Suggestions[0].Code != "US-CA" {
+		t.Errorf("NormalizeSubdivision(near miss) = %v, want US-CA suggested", err)
+	}
+	// subdivisions of another country don't match
+	if _, err := NormalizeSubdivision("CA", "California"); err == nil {
+		t.Errorf("NormalizeSubdivision(CA, California) succeeded, want an error")
+	}
+}
+
+func TestNormalizeAddress(t *testing.T) {
+	str := func(v string) *string { return &v }
+
+	metadata := &UserMetadata{Country: str("Canada"), StateProvince: str("Quebec")}
+	if err := metadata.normalizeAddress(); err != nil || *metadata.Country != "CA" || *metadata.StateProvince != "CA-QC" {
+		t.Errorf("normalizeAddress = %v, %q %q, want CA CA-QC", err, *metadata.Country, *metadata.StateProvince)
+	}
+
+	if err := (&UserMetadata{StateProvince: str("Quebec")}).normalizeAddress(); err == nil {
+		t.Errorf("normalizeAddress without a country succeeded, want an error")
+	}
+
+	cleared := &UserMetadata{Country: str(""), StateProvince: str("")}
+	if err := cleared.normalizeAddress(); err != nil {
+		t.Errorf("normalizeAddress of empty values = %v, want them kept", err)
+	}
+}
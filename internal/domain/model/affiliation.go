@@ -0,0 +1,108 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+)
+
+// AffiliationDateLayout is the layout of affiliation dates: a calendar day,
+// with no time or zone
+const AffiliationDateLayout = time.DateOnly
+
+// Affiliation is a period during which a user worked for an organization.
+// An affiliation without an end date is current.
+type Affiliation struct {
+	ID           string `json:"id"`
+	Organization string `json:"organization"`
+	Title        string `json:"title,omitempty"`
+	// StartDate and EndDate are inclusive days, in AffiliationDateLayout
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date,omitempty"`
+}
+
+// Current reports whether the affiliation has no end date
+func (a Affiliation) Current() bool {
+	return a.EndDate == ""
+}
+
+// Validate checks the organization and dates of the affiliation
+func (a Affiliation) Validate() error {
+	if strings.TrimSpace(a.Organization) == "" {
+		return errors.NewValidation("organization is required")
+	}
+	start, err := time.Parse(AffiliationDateLayout, a.StartDate)
+	if err != nil {
+		return errors.NewValidation("start_date must be a date such as 2024-01-31")
+	}
+	if a.Current() {
+		return nil
+	}
+	end, err := time.Parse(AffiliationDateLayout, a.EndDate)
+	if err != nil {
+		return errors.NewValidation("end_date must be a date such as 2024-01-31")
+	}
+	if end.Before(start) {
+		return errors.NewValidation("end_date must not be before start_date")
+	}
+	return nil
+}
+
+// Overlaps reports whether the periods of a and b share a day. An open
+// period runs on indefinitely. Dates in AffiliationDateLayout compare as
+// strings.
+func (a Affiliation) Overlaps(b Affiliation) bool {
+	return (b.Current() || a.StartDate <= b.EndDate) && (a.Current() || b.StartDate <= a.EndDate)
+}
+
+// AffiliationHistory is every affiliation of a user, oldest first
+type AffiliationHistory struct {
+	UserID       string        `json:"user_id"`
+	Affiliations []Affiliation `json:"affiliations"`
+
+	// Revision is the store revision the history was read at, so it is only
+	// written back when nobody changed it in between. It is never stored.
+	Revision uint64 `json:"-"`
+}
+
+// Add validates and adds an affiliation that doesn't overlap any other one,
+// keeping the history ordered by start date
+func (h *AffiliationHistory) Add(affiliation Affiliation) error {
+	if err := affiliation.Validate(); err != nil {
+		return err
+	}
+	for _, other := range h.Affiliations {
+		if affiliation.Overlaps(other) {
+			return errors.NewConflict(fmt.Sprintf("affiliation overlaps %s from %s", other.Organization, other.StartDate))
+		}
+	}
+	h.Affiliations = append(h.Affiliations, affiliation)
+	slices.SortStableFunc(h.Affiliations, func(a, b Affiliation) int { return strings.Compare(a.StartDate, b.StartDate) })
+	return nil
+}
+
+// End sets the end date of the current affiliation id and returns it
+func (h *AffiliationHistory) End(id, endDate string) (Affiliation, error) {
+	i := slices.IndexFunc(h.Affiliations, func(a Affiliation) bool { return a.ID == id })
+	if i < 0 {
+		return Affiliation{}, errors.NewNotFound("affiliation not found")
+	}
+	if !h.Affiliations[i].Current() {
+		return Affiliation{}, errors.NewConflict("affiliation already ended on " + h.Affiliations[i].EndDate)
+	}
+
+	ended := h.Affiliations[i]
+	ended.EndDate = endDate
+	if err := ended.Validate(); err != nil {
+		return Affiliation{}, err
+	}
+	// ending a period only shortens it, so it can't start overlapping others
+	h.Affiliations[i] = ended
+	return ended, nil
+}
@@ -0,0 +1,28 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import "testing"
+
+func TestAffiliationOverlaps(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		a, b Affiliation
+		want bool
+	}{
+		{"disjoint", Affiliation{StartDate: "2019-01-01", EndDate: "2019-12-31"}, Affiliation{StartDate: "2020-01-01", EndDate: "2020-12-31"}, false},
+		{"sharing the last day", Affiliation{StartDate: "2019-01-01", EndDate: "2020-01-01"}, Affiliation{StartDate: "2020-01-01", EndDate: "2020-12-31"}, true},
+		{"nested", Affiliation{StartDate: "2019-01-01", EndDate: "2021-12-31"}, Affiliation{StartDate: "2020-01-01", EndDate: "2020-12-31"}, true},
+		{"current after a past one", Affiliation{StartDate: "2021-01-01"}, Affiliation{StartDate: "2019-01-01", EndDate: "2020-12-31"}, false},
+		{"current before a later one", Affiliation{StartDate: "2019-01-01"}, Affiliation{StartDate: "2021-01-01", EndDate: "2021-12-31"}, true},
+		{"two current", Affiliation{StartDate: "2019-01-01"}, Affiliation{StartDate: "2021-01-01"}, true},
+	} {
+		if got := tt.a.Overlaps(tt.b); got != tt.want {
+			t.Errorf("%s: a.Overlaps(b) = %v, want %v", tt.name, got, tt.want)
+		}
+		if got := tt.b.Overlaps(tt.a); got != tt.want {
+			t.Errorf("%s: b.Overlaps(a) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
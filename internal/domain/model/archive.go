@@ -0,0 +1,14 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package model
+
+// ArchiveResult summarizes one archival run over the stored users
+type ArchiveResult struct {
+	// Scanned is the number of users inspected
+	Scanned int `json:"scanned"`
+	// Archived is the number of inactive users moved to the archive
+	Archived int `json:"archived"`
+	// Failed is the number of inactive users that could not be archived
+	Failed int `json:"failed"`
+}
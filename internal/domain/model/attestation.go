@@ -0,0 +1,40 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import "time"
+
+// FieldAttestation records that a verifier checked the value of one profile
+// field of a user, e.g. their legal name against an identity document. It
+// only holds while the field keeps the value that was checked.
+type FieldAttestation struct {
+	// UserID is the user whose field was verified
+	UserID string `json:"user_id"`
+	// Field is the JSON name of the metadata field, e.g. "family_name"
+	Field string `json:"field"`
+	Value string `json:"value"`
+	// Verifier is the sub of the caller who recorded the attestation
+	Verifier   string    `json:"verifier"`
+	Method     string    `json:"method"`
+	VerifiedAt time.Time `json:"verified_at"`
+}
+
+// Holds reports whether the field still has the attested value in metadata
+func (a *FieldAttestation) Holds(metadata *UserMetadata) bool {
+	value, ok := metadata.FieldValue(a.Field)
+	return ok && value != "" && value == a.Value
+}
+
+// AttributeVerification is how a field of a profile read was verified; the
+// value itself is the one read
+type AttributeVerification struct {
+	Verifier   string    `json:"verifier"`
+	Method     string    `json:"method"`
+	VerifiedAt time.Time `json:"verified_at"`
+}
+
+// Verification returns the verification of a, as reported by reads
+func (a *FieldAttestation) Verification() AttributeVerification {
+	return AttributeVerification{Verifier: a.Verifier, Method: a.Method, VerifiedAt: a.VerifiedAt}
+}
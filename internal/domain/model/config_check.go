@@ -0,0 +1,57 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import "time"
+
+// Outcomes of a configuration check
+const (
+	ConfigCheckOK = "ok"
+	// ConfigCheckWarn means the service can start, but some features won't work
+	ConfigCheckWarn = "warn"
+	// ConfigCheckFail means the service can't serve requests as configured
+	ConfigCheckFail = "fail"
+	// ConfigCheckSkip means the check doesn't apply to this configuration
+	ConfigCheckSkip = "skip"
+)
+
+// ConfigCheck is the outcome of one configuration check
+type ConfigCheck struct {
+	// Name identifies the check, e.g. auth0.jwks
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	// Detail explains a warning or failure, or what was verified
+	Detail     string `json:"detail,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// RunConfigCheck runs check and records its outcome and how long it took
+func RunConfigCheck(name string, check func() (status, detail string)) ConfigCheck {
+	started := time.Now()
+	status, detail := check()
+	return ConfigCheck{
+		Name:       name,
+		Status:     status,
+		Detail:     detail,
+		DurationMS: time.Since(started).Milliseconds(),
+	}
+}
+
+// ConfigReport is the outcome of every configuration check
+type ConfigReport struct {
+	Checks []ConfigCheck `json:"checks"`
+	// OK is false when any check failed; warnings don't fail the report
+	OK bool `json:"ok"`
+}
+
+// NewConfigReport summarizes checks into a report
+func NewConfigReport(checks []ConfigCheck) *ConfigReport {
+	report := &ConfigReport{Checks: checks, OK: true}
+	for _, check := range checks {
+		if check.Status == ConfigCheckFail {
+			report.OK = false
+		}
+	}
+	return report
+}
@@ -0,0 +1,35 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import "time"
+
+// PolicyAcceptance records that a user accepted a version of a policy, such
+// as the terms of service. It is never changed once stored.
+type PolicyAcceptance struct {
+	// UserID is the sub of the user who accepted the policy
+	UserID     string    `json:"user_id"`
+	Policy     string    `json:"policy"`
+	Version    string    `json:"version"`
+	AcceptedAt time.Time `json:"accepted_at"`
+}
+
+// PolicyConsent is where a user stands with one policy. Current is set when
+// the user accepted the policy's current version.
+type PolicyConsent struct {
+	Policy         string `json:"policy"`
+	CurrentVersion string `json:"current_version"`
+	// AcceptedVersion is the latest version the user accepted, if any
+	AcceptedVersion string     `json:"accepted_version,omitempty"`
+	AcceptedAt      *time.Time `json:"accepted_at,omitempty"`
+	Current         bool       `json:"current"`
+}
+
+// ConsentStatus is where a user stands with the policies asked about.
+// AllCurrent is set when every one of them is accepted in its current version.
+type ConsentStatus struct {
+	UserID     string          `json:"user_id"`
+	Policies   []PolicyConsent `json:"policies"`
+	AllCurrent bool            `json:"all_current"`
+}
@@ -0,0 +1,93 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+)
+
+const (
+	// maxPronounsLength bounds the pronouns, e.g. "they/them" or "she/they"
+	maxPronounsLength = 32
+	// maxPreferredNameLength bounds the preferred name in characters
+	maxPreferredNameLength = 64
+)
+
+// pronounsPattern is one to four sets of letters separated by slashes
+var pronounsPattern = regexp.MustCompile(`^\p{L}+(/\p{L}+){0,3}$`)
+
+// NormalizePronouns returns pronouns in the form "she/her": lowercase, with
+// no space around the slashes
+func NormalizePronouns(pronouns string) (string, error) {
+	parts := strings.Split(pronouns, "/")
+	for i, part := range parts {
+		parts[i] = strings.ToLower(strings.TrimSpace(part))
+	}
+	normalized := strings.Join(parts, "/")
+	if utf8.RuneCountInString(normalized) > maxPronounsLength || !pronounsPattern.MatchString(normalized) {
+		return "", errors.NewValidation(fmt.Sprintf("pronouns %q must be words separated by slashes, such as they/them", pronouns))
+	}
+	return normalized, nil
+}
+
+// NormalizePreferredName collapses the whitespace of a preferred name and
+// rejects names too long or holding control characters
+func NormalizePreferredName(name string) (string, error) {
+	normalized := strings.Join(strings.Fields(name), " ")
+	if utf8.RuneCountInString(normalized) > maxPreferredNameLength {
+		return "", errors.NewValidation(fmt.Sprintf("preferred_name must be at most %d characters", maxPreferredNameLength))
+	}
+	if strings.ContainsFunc(normalized, unicode.IsControl) {
+		return "", errors.NewValidation("preferred_name must not contain control characters")
+	}
+	return normalized, nil
+}
+
+// normalizeNaming replaces the pronouns and preferred name, when set, with
+// their normalized forms. An empty value is kept, clearing the field.
+func (um *UserMetadata) normalizeNaming() error {
+	for _, field := range []struct {
+		value     *string
+		normalize func(string) (string, error)
+	}{
+		{um.Pronouns, NormalizePronouns},
+		{um.PreferredName, NormalizePreferredName},
+	} {
+		if field.value == nil || *field.value == "" {
+			continue
+		}
+		normalized, err := field.normalize(*field.value)
+		if err != nil {
+			return err
+		}
+		*field.value = normalized
+	}
+	return nil
+}
+
+// DisplayName is the name every UI renders for the user: the preferred name,
+// else the given and family names, else the username
+func (u User) DisplayName() string {
+	if m := u.UserMetadata; m != nil {
+		if m.PreferredName != nil && strings.TrimSpace(*m.PreferredName) != "" {
+			return strings.TrimSpace(*m.PreferredName)
+		}
+		var parts []string
+		for _, part := range []*string{m.GivenName, m.FamilyName} {
+			if part != nil && strings.TrimSpace(*part) != "" {
+				parts = append(parts, strings.TrimSpace(*part))
+			}
+		}
+		if len(parts) > 0 {
+			return strings.Join(parts, " ")
+		}
+	}
+	return u.Username
+}
@@ -0,0 +1,58 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeNaming(t *testing.T) {
+	for _, tt := range []struct {
+		in, want string
+	}{
+		{"they/them", "they/them"},
+		{"She / Her", "she/her"},
+		{"he/they", "he/they"},
+		{"ze/hir/hirs", "ze/hir/hirs"},
+		{"elle", "elle"},
+	} {
+		if got, err := NormalizePronouns(tt.in); err != nil || got != tt.want {
+			t.Errorf("NormalizePronouns(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+	for _, in := range []string{"", "she//her", "they/them/", "a/b/c/d/e", "<b>he</b>", "she/her1"} {
+		if got, err := NormalizePronouns(in); err == nil {
+			t.Errorf("NormalizePronouns(%q) = %q, want an error", in, got)
+		}
+	}
+
+	if got, err := NormalizePreferredName("  Jo   Smith "); err != nil || got != "Jo Smith" {
+		t.Errorf("NormalizePreferredName = %q, %v, want %q", got, err, "Jo Smith")
+	}
+	for _, in := range []string{"Jo\u0000Smith", strings.Repeat("a", 70)} {
+		if _, err := NormalizePreferredName(in); err == nil {
+			t.Errorf("NormalizePreferredName(%q) succeeded, want an error", in)
+		}
+	}
+}
+
+func TestUserDisplayName(t *testing.T) {
+	str := func(v string) *string { return &v }
+	for _, tt := range []struct {
+		name string
+		user User
+		want string
+	}{
+		{"preferred name first", User{Username: "jdoe", UserMetadata: &UserMetadata{PreferredName: str("Johnny"), GivenName: str("John"), FamilyName: str("Doe")}}, "Johnny"},
+		{"given and family names", User{Username: "jdoe", UserMetadata: &UserMetadata{PreferredName: str(""), GivenName: str("John"), FamilyName: str("Doe")}}, "John Doe"},
+		{"given name alone", User{Username: "jdoe", UserMetadata: &UserMetadata{GivenName: str("John")}}, "John"},
+		{"username otherwise", User{Username: "jdoe", UserMetadata: &UserMetadata{Name: str("John Doe")}}, "jdoe"},
+		{"no metadata", User{Username: "jdoe"}, "jdoe"},
+	} {
+		if got := tt.user.DisplayName(); got != tt.want {
+			t.Errorf("%s: DisplayName() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
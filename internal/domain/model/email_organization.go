@@ -0,0 +1,16 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import "time"
+
+// EmailOrganization maps an email domain, and its subdomains without a
+// mapping of their own, to the organization its users work for
+type EmailOrganization struct {
+	Domain           string    `json:"domain"`
+	OrganizationID   string    `json:"organization_id,omitempty"`
+	OrganizationName string    `json:"organization_name"`
+	UpdatedBy        string    `json:"updated_by,omitempty"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
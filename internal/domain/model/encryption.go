@@ -0,0 +1,17 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package model
+
+// ReencryptResult summarizes a storage re-encryption pass
+type ReencryptResult struct {
+	// Scanned is the number of records inspected
+	Scanned int `json:"scanned"`
+	// Reencrypted is the number of records rewritten with the active key
+	Reencrypted int `json:"reencrypted"`
+	// Conflicts is the number of records modified concurrently; they were
+	// written with the active key by the other writer and need no retry
+	Conflicts int `json:"conflicts"`
+	// Failed is the number of records that could not be read or rewritten
+	Failed int `json:"failed"`
+}
@@ -0,0 +1,20 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import "time"
+
+// IdempotentRequest is a mutation sent with an idempotency key: pending
+// while its first attempt is handled, then holding the reply that attempt
+// got, which retries with the same key are given instead
+type IdempotentRequest struct {
+	Key     string `json:"key"`
+	Subject string `json:"subject"`
+	// RequestHash is the SHA-256 of the subject and payload, so a key reused
+	// for a different request is told apart from a retry
+	RequestHash string    `json:"request_hash"`
+	Pending     bool      `json:"pending"`
+	Response    []byte    `json:"response,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -0,0 +1,82 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/language"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+)
+
+// timezoneList holds the IANA time zone names, one per line, followed by the
+// links (deprecated or alternate names) with the zone they resolve to
+//
+//go:embed timezones.txt
+var timezoneList string
+
+// timezones maps the case folded zone and link names to their canonical zone
+var timezones = parseTimezones(timezoneList)
+
+func parseTimezones(list string) map[string]string {
+	zones := make(map[string]string)
+	for _, line := range strings.Split(list, "\n") {
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0 || strings.HasPrefix(fields[0], "#"):
+		case len(fields) == 1:
+			zones[strings.ToLower(fields[0])] = fields[0]
+		default:
+			zones[strings.ToLower(fields[0])] = fields[1]
+		}
+	}
+	return zones
+}
+
+// NormalizeTimezone returns the canonical IANA name of the time zone tz,
+// matched case insensitively; a link such as "US/Pacific" resolves to the
+// zone it names ("America/Los_Angeles")
+func NormalizeTimezone(tz string) (string, error) {
+	canonical, ok := timezones[strings.ToLower(strings.TrimSpace(tz))]
+	if !ok {
+		return "", errors.NewValidation(fmt.Sprintf("timezone %q is not an IANA time zone", tz))
+	}
+	return canonical, nil
+}
+
+// NormalizeLocale returns the canonical BCP 47 form of the language tag
+// locale, e.g. "en-US" for "en_us". Tags that are ill-formed or use subtags
+// missing from the IANA registry are rejected.
+func NormalizeLocale(locale string) (string, error) {
+	tag, err := language.Parse(strings.TrimSpace(locale))
+	if err != nil || tag == language.Und {
+		return "", errors.NewValidation(fmt.Sprintf("locale %q is not a BCP 47 language tag", locale))
+	}
+	return tag.String(), nil
+}
+
+// normalizeLocalization replaces the locale and timezone, when set, with
+// their canonical forms. An empty value is kept, clearing the field.
+func (um *UserMetadata) normalizeLocalization() error {
+	for _, field := range []struct {
+		value     *string
+		normalize func(string) (string, error)
+	}{
+		{um.Locale, NormalizeLocale},
+		{um.Timezone, NormalizeTimezone},
+	} {
+		if field.value == nil || *field.value == "" {
+			continue
+		}
+		normalized, err := field.normalize(*field.value)
+		if err != nil {
+			return err
+		}
+		*field.value = normalized
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import "testing"
+
+func TestNormalizeLocalization(t *testing.T) {
+	for _, tt := range []struct {
+		in, want string
+	}{
+		{"en-US", "en-US"},
+		{"en_us", "en-US"},
+		{" pt-br ", "pt-BR"},
+		{"zh-hant-TW", "zh-Hant-TW"},
+	} {
+		if got, err := NormalizeLocale(tt.in); err != nil || got != tt.want {
+			t.Errorf("NormalizeLocale(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+	for _, in := range []string{"", "und", "english", "en-US-x", "xx-ZZ"} {
+		if got, err := NormalizeLocale(in); err == nil {
+			t.Errorf("NormalizeLocale(%q) = %q, want an error", in, got)
+		}
+	}
+
+	for _, tt := range []struct {
+		in, want string
+	}{
+		{"America/New_York", "America/New_York"},
+		{"america/new_york", "America/New_York"},
+		{"US/Pacific", "America/Los_Angeles"},
+		{"Asia/Calcutta", "Asia/Kolkata"},
+		{"UTC", "Etc/UTC"},
+	} {
+		if got, err := NormalizeTimezone(tt.in); err != nil || got != tt.want {
+			t.Errorf("NormalizeTimezone(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+	for _, in := range []string{"", "Aetheria/Skylands", "GMT+2", "America"} {
+		if got, err := NormalizeTimezone(in); err == nil {
+			t.Errorf("NormalizeTimezone(%q) = %q, want an error", in, got)
+		}
+	}
+
+	locale, tz, empty := "fr_fr", "europe/paris", ""
+	user := &User{Token: "token", UserMetadata: &UserMetadata{Locale: &locale, Timezone: &tz, Zoneinfo: &empty}}
+	if err := user.Validate(); err != nil {
+		t.Fatalf("Validate() = %v", err)
+	}
+	if *user.UserMetadata.Locale != "fr-FR" || *user.UserMetadata.Timezone != "Europe/Paris" {
+		t.Errorf("Validate() normalized to %q, %q", *user.UserMetadata.Locale, *user.UserMetadata.Timezone)
+	}
+
+	invalid := "Mars/Olympus"
+	user.UserMetadata.Timezone = &invalid
+	if err := user.Validate(); err == nil || err.Error() != `timezone "Mars/Olympus" is not an IANA time zone` {
+		t.Errorf("Validate() = %v", err)
+	}
+	user.UserMetadata.Timezone = &empty
+	if err := user.Validate(); err != nil {
+		t.Errorf("Validate() of an empty timezone = %v", err)
+	}
+}
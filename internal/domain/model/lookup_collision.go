@@ -0,0 +1,29 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package model
+
+// LookupCollision is a lookup key claimed by more than one user: their
+// emails or subs normalize to the same index key, so lookups by it can only
+// resolve to one of them
+type LookupCollision struct {
+	// Key is the lookup key
+	Key string `json:"key"`
+	// Owner is the username the key resolves to now
+	Owner string `json:"owner,omitempty"`
+	// Claimants are the usernames whose emails or subs build the key
+	Claimants []string `json:"claimants"`
+}
+
+// LookupRepairResult summarizes a scan of the lookup keys
+type LookupRepairResult struct {
+	// Scanned is the number of users inspected
+	Scanned int `json:"scanned"`
+	// Repaired is the number of keys pointed back at the one user claiming them
+	Repaired int `json:"repaired"`
+	// Failed is the number of keys that could not be read or repaired
+	Failed int `json:"failed"`
+	// Conflicts are the keys several users claim, left for an operator
+	// to resolve by editing or merging the accounts
+	Conflicts []LookupCollision `json:"conflicts"`
+}
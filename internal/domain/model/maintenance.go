@@ -0,0 +1,14 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import "time"
+
+// MaintenanceState is whether a replica refuses writes, and why
+type MaintenanceState struct {
+	ReadOnly bool   `json:"read_only"`
+	Reason   string `json:"reason,omitempty"`
+	// Since is when read-only mode was switched on
+	Since *time.Time `json:"since,omitempty"`
+}
@@ -0,0 +1,61 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// MetadataSizeLimits bound the user metadata written to the provider, in
+// bytes. A limit of zero or less doesn't apply.
+type MetadataSizeLimits struct {
+	// MaxBytes bounds the JSON encoding of the whole metadata
+	MaxBytes int
+	// MaxFieldBytes bounds each string field
+	MaxFieldBytes int
+}
+
+// MetadataSizeError is metadata larger than its limits allow. Field is the
+// string field over MaxFieldBytes, or empty when the whole metadata is over
+// MaxBytes.
+type MetadataSizeError struct {
+	Field string `json:"field,omitempty"`
+	Size  int    `json:"size"`
+	Limit int    `json:"limit"`
+}
+
+// Error returns the error message for MetadataSizeError.
+func (e *MetadataSizeError) Error() string {
+	if e.Field != "" {
+		return fmt.Sprintf("%s is %d bytes, more than the %d allowed", e.Field, e.Size, e.Limit)
+	}
+	return fmt.Sprintf("user_metadata is %d bytes, more than the %d allowed", e.Size, e.Limit)
+}
+
+// CheckSize returns a *MetadataSizeError when a string field of um, or um as
+// a whole, is larger than limits allow. Fields are checked first, in their
+// declaration order, so the error names the field to shorten.
+func (um *UserMetadata) CheckSize(limits MetadataSizeLimits) error {
+	if um == nil {
+		return nil
+	}
+	if limits.MaxFieldBytes > 0 {
+		for _, field := range um.SetFields() {
+			if value, ok := um.FieldValue(field); ok && len(value) > limits.MaxFieldBytes {
+				return &MetadataSizeError{Field: field, Size: len(value), Limit: limits.MaxFieldBytes}
+			}
+		}
+	}
+	if limits.MaxBytes > 0 {
+		encoded, err := json.Marshal(um)
+		if err != nil {
+			return err
+		}
+		if len(encoded) > limits.MaxBytes {
+			return &MetadataSizeError{Size: len(encoded), Limit: limits.MaxBytes}
+		}
+	}
+	return nil
+}
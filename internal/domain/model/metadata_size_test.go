@@ -0,0 +1,35 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestUserMetadataCheckSize(t *testing.T) {
+	str := func(s string) *string { return &s }
+	limits := MetadataSizeLimits{MaxBytes: 200, MaxFieldBytes: 50}
+
+	if err := (&UserMetadata{Name: str("Jo Smith")}).CheckSize(limits); err != nil {
+		t.Errorf("small metadata: unexpected error %v", err)
+	}
+
+	var sizeErr *MetadataSizeError
+	err := (&UserMetadata{Name: str("Jo"), Address: str(strings.Repeat("a", 60))}).CheckSize(limits)
+	if !errors.As(err, &sizeErr) || sizeErr.Field != "address" || sizeErr.Size != 60 || sizeErr.Limit != 50 {
+		t.Errorf("long field: got %v, want address over 50", err)
+	}
+
+	long := strings.Repeat("a", 45)
+	err = (&UserMetadata{Name: str(long), Address: str(long), JobTitle: str(long), Organization: str(long)}).CheckSize(limits)
+	if !errors.As(err, &sizeErr) || sizeErr.Field != "" || sizeErr.Limit != 200 {
+		t.Errorf("large metadata: got %v, want the total over 200", err)
+	}
+
+	if err := (&UserMetadata{Address: str(strings.Repeat("a", 60))}).CheckSize(MetadataSizeLimits{}); err != nil {
+		t.Errorf("no limits: unexpected error %v", err)
+	}
+}
@@ -0,0 +1,27 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import "time"
+
+// OutboxEvent is a domain event persisted before it is published, so an event
+// whose publication fails, or is interrupted by the process stopping, is
+// published later instead of being lost
+type OutboxEvent struct {
+	ID        string    `json:"id"`
+	Subject   string    `json:"subject"`
+	Data      []byte    `json:"data"`
+	CreatedAt time.Time `json:"created_at"`
+	// Attempts counts the publications tried, and LastError holds the error
+	// of the latest failed one
+	Attempts  int    `json:"attempts,omitempty"`
+	LastError string `json:"last_error,omitempty"`
+	// ClaimedAt is set while a replica publishes the event, so the others
+	// leave it alone until the claim is released or expires
+	ClaimedAt *time.Time `json:"claimed_at,omitempty"`
+
+	// Revision is the store revision the event was read at, for updates
+	// that must not overwrite another replica's
+	Revision uint64 `json:"-"`
+}
@@ -0,0 +1,36 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import "slices"
+
+// Permission is a named permission held by a user
+type Permission struct {
+	Name string `json:"name"`
+	// Audience is the API the permission is defined on, when the provider scopes
+	// permissions to APIs (Auth0 resource servers)
+	Audience string `json:"audience,omitempty"`
+}
+
+// UserPermissions are the roles a user holds and the permissions they grant
+type UserPermissions struct {
+	Roles       []string     `json:"roles"`
+	Permissions []Permission `json:"permissions"`
+}
+
+// HasRole reports whether the user holds role
+func (p *UserPermissions) HasRole(role string) bool {
+	return p != nil && role != "" && slices.Contains(p.Roles, role)
+}
+
+// HasPermission reports whether the user holds the permission name. An empty
+// audience matches the permission on any API.
+func (p *UserPermissions) HasPermission(name, audience string) bool {
+	if p == nil || name == "" {
+		return false
+	}
+	return slices.ContainsFunc(p.Permissions, func(permission Permission) bool {
+		return permission.Name == name && (audience == "" || permission.Audience == audience)
+	})
+}
@@ -0,0 +1,32 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import "time"
+
+// PersonalAccessToken is a long-lived token a user mints for scripts and
+// integrations. Only a salted hash of its secret is stored.
+type PersonalAccessToken struct {
+	ID string `json:"id"`
+	// UserID is the sub of the user the token acts as
+	UserID    string     `json:"user_id"`
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	// Salt and Hash verify the secret; they are never returned to callers
+	Salt []byte `json:"-"`
+	Hash []byte `json:"-"`
+}
+
+// Expired reports whether the token is past its expiry at now
+func (t *PersonalAccessToken) Expired(now time.Time) bool {
+	return !now.Before(t.ExpiresAt)
+}
+
+// Active reports whether the token can still be used at now
+func (t *PersonalAccessToken) Active(now time.Time) bool {
+	return t.RevokedAt == nil && !t.Expired(now)
+}
@@ -0,0 +1,136 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+)
+
+// Notification frequencies: each notification as it happens, or a digest
+const (
+	NotificationFrequencyImmediate = "immediate"
+	NotificationFrequencyDaily     = "daily"
+	NotificationFrequencyWeekly    = "weekly"
+)
+
+var notificationFrequencies = []string{NotificationFrequencyImmediate, NotificationFrequencyDaily, NotificationFrequencyWeekly}
+
+// UserPreferences is the preferences namespace of the user metadata. Unlike
+// the other metadata fields it is managed: written only through the
+// user_preferences subjects, which validate it and leave the rest of the
+// metadata alone.
+type UserPreferences struct {
+	Notifications *NotificationPreferences `json:"notifications,omitempty" yaml:"notifications,omitempty"`
+}
+
+// NotificationPreferences are the preferences of each notification channel
+type NotificationPreferences struct {
+	Email *ChannelPreferences `json:"email,omitempty" yaml:"email,omitempty"`
+	InApp *ChannelPreferences `json:"in_app,omitempty" yaml:"in_app,omitempty"`
+	SMS   *ChannelPreferences `json:"sms,omitempty" yaml:"sms,omitempty"`
+}
+
+// ChannelPreferences are whether a channel delivers notifications, and how often
+type ChannelPreferences struct {
+	Enabled   *bool   `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	Frequency *string `json:"frequency,omitempty" yaml:"frequency,omitempty"`
+}
+
+// notificationChannels are the channels of the notification preferences, by JSON name
+var notificationChannels = []struct {
+	name  string
+	field func(n *NotificationPreferences) **ChannelPreferences
+}{
+	{"email", func(n *NotificationPreferences) **ChannelPreferences { return &n.Email }},
+	{"in_app", func(n *NotificationPreferences) **ChannelPreferences { return &n.InApp }},
+	{"sms", func(n *NotificationPreferences) **ChannelPreferences { return &n.SMS }},
+}
+
+// DefaultUserPreferences are the preferences of a user who never set any:
+// email and in-app notifications as they happen, no SMS
+func DefaultUserPreferences() *UserPreferences {
+	channel := func(enabled bool) *ChannelPreferences {
+		frequency := NotificationFrequencyImmediate
+		return &ChannelPreferences{Enabled: &enabled, Frequency: &frequency}
+	}
+	return &UserPreferences{Notifications: &NotificationPreferences{
+		Email: channel(true),
+		InApp: channel(true),
+		SMS:   channel(false),
+	}}
+}
+
+// Validate checks the values of the preferences that are set
+func (p *UserPreferences) Validate() error {
+	if p == nil || p.Notifications == nil {
+		return nil
+	}
+	for _, channel := range notificationChannels {
+		preferences := *channel.field(p.Notifications)
+		if preferences == nil || preferences.Frequency == nil {
+			continue
+		}
+		if !slices.Contains(notificationFrequencies, *preferences.Frequency) {
+			return errors.NewValidation(fmt.Sprintf("notifications.%s.frequency must be immediate, daily or weekly", channel.name))
+		}
+	}
+	return nil
+}
+
+// Merge returns the preferences with the fields set in update replacing
+// their own. Neither is modified.
+func (p *UserPreferences) Merge(update *UserPreferences) *UserPreferences {
+	merged := &UserPreferences{}
+	var base, over *NotificationPreferences
+	if p != nil {
+		base = p.Notifications
+	}
+	if update != nil {
+		over = update.Notifications
+	}
+	if base == nil && over == nil {
+		return merged
+	}
+
+	merged.Notifications = &NotificationPreferences{}
+	for _, channel := range notificationChannels {
+		var from, to *ChannelPreferences
+		if base != nil {
+			from = *channel.field(base)
+		}
+		if over != nil {
+			to = *channel.field(over)
+		}
+		*channel.field(merged.Notifications) = mergeChannelPreferences(from, to)
+	}
+	return merged
+}
+
+// WithDefaults returns the preferences with the defaults filling the fields
+// that aren't set
+func (p *UserPreferences) WithDefaults() *UserPreferences {
+	return DefaultUserPreferences().Merge(p)
+}
+
+func mergeChannelPreferences(base, update *ChannelPreferences) *ChannelPreferences {
+	if base == nil && update == nil {
+		return nil
+	}
+	merged := &ChannelPreferences{}
+	if base != nil {
+		*merged = *base
+	}
+	if update != nil {
+		if update.Enabled != nil {
+			merged.Enabled = update.Enabled
+		}
+		if update.Frequency != nil {
+			merged.Frequency = update.Frequency
+		}
+	}
+	return merged
+}
@@ -0,0 +1,49 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestUserPreferences(t *testing.T) {
+	b := func(v bool) *bool { return &v }
+	s := func(v string) *string { return &v }
+
+	stored := &UserPreferences{Notifications: &NotificationPreferences{
+		Email: &ChannelPreferences{Enabled: b(false)},
+	}}
+	update := &UserPreferences{Notifications: &NotificationPreferences{
+		Email: &ChannelPreferences{Frequency: s(NotificationFrequencyWeekly)},
+		SMS:   &ChannelPreferences{Enabled: b(true)},
+	}}
+
+	merged := stored.Merge(update)
+	got, _ := json.Marshal(merged)
+	if want := `{"notifications":{"email":{"enabled":false,"frequency":"weekly"},"sms":{"enabled":true}}}`; string(got) != want {
+		t.Errorf("Merge() = %s, want %s", got, want)
+	}
+	if stored.Notifications.Email.Frequency != nil {
+		t.Error("Merge() modified the stored preferences")
+	}
+
+	got, _ = json.Marshal(merged.WithDefaults())
+	if want := `{"notifications":{"email":{"enabled":false,"frequency":"weekly"},"in_app":{"enabled":true,"frequency":"immediate"},"sms":{"enabled":true,"frequency":"immediate"}}}`; string(got) != want {
+		t.Errorf("WithDefaults() = %s, want %s", got, want)
+	}
+
+	var none *UserPreferences
+	if got, _ := json.Marshal(none.WithDefaults()); string(got) != `{"notifications":{"email":{"enabled":true,"frequency":"immediate"},"in_app":{"enabled":true,"frequency":"immediate"},"sms":{"enabled":false,"frequency":"immediate"}}}` {
+		t.Errorf("WithDefaults() of no preferences = %s", got)
+	}
+
+	if err := update.Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+	invalid := &UserPreferences{Notifications: &NotificationPreferences{InApp: &ChannelPreferences{Frequency: s("hourly")}}}
+	if err := invalid.Validate(); err == nil || err.Error() != "notifications.in_app.frequency must be immediate, daily or weekly" {
+		t.Errorf("Validate() = %v", err)
+	}
+}
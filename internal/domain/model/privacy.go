@@ -0,0 +1,45 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package model
+
+// ProfileFieldUsername is the field of a profile every reader gets
+const ProfileFieldUsername = "username"
+
+// UserPrivacy is the privacy namespace of the user metadata, where users say
+// what other services may see of them
+type UserPrivacy struct {
+	// ShareProfile set to false opts the user out of profile sharing: reads
+	// by other services only get the username. Unset shares the profile.
+	ShareProfile *bool `json:"share_profile,omitempty" yaml:"share_profile,omitempty"`
+}
+
+// SharesProfile reports whether the user shares their profile with other
+// services, which they do unless they opted out
+func (u *User) SharesProfile() bool {
+	if u.UserMetadata == nil || u.UserMetadata.Privacy == nil || u.UserMetadata.Privacy.ShareProfile == nil {
+		return true
+	}
+	return *u.UserMetadata.Privacy.ShareProfile
+}
+
+// MinimizedFor reports whether a reader gets the minimized profile of the
+// user: readers other than the user, self false, of a user who opted out of
+// profile sharing
+func (u *User) MinimizedFor(self bool) bool {
+	return !self && !u.SharesProfile()
+}
+
+// ProfileFor returns the user as a reader is allowed to see them: the user as
+// it is, or when MinimizedFor the reader, a copy holding only the
+// identifiers, so a profile read replies the username only
+func (u *User) ProfileFor(self bool) *User {
+	if !u.MinimizedFor(self) {
+		return u
+	}
+	return &User{
+		UserID:   u.UserID,
+		Username: u.Username,
+		Stale:    u.Stale,
+	}
+}
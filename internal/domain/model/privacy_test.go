@@ -0,0 +1,55 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import "testing"
+
+func TestUserProfileFor(t *testing.T) {
+	b := func(v bool) *bool { return &v }
+	name := "Jane Doe"
+
+	tests := []struct {
+		name      string
+		privacy   *UserPrivacy
+		self      bool
+		minimized bool
+	}{
+		{name: "no privacy settings", minimized: false},
+		{name: "unset flag", privacy: &UserPrivacy{}, minimized: false},
+		{name: "shares the profile", privacy: &UserPrivacy{ShareProfile: b(true)}, minimized: false},
+		{name: "opted out, other reader", privacy: &UserPrivacy{ShareProfile: b(false)}, minimized: true},
+		{name: "opted out, own read", privacy: &UserPrivacy{ShareProfile: b(false)}, self: true, minimized: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := &User{
+				UserID:       "auth0|1",
+				Username:     "jdoe",
+				PrimaryEmail: "jane@example.com",
+				UserMetadata: &UserMetadata{Name: &name, Privacy: tt.privacy},
+			}
+			if got := user.MinimizedFor(tt.self); got != tt.minimized {
+				t.Errorf("MinimizedFor() = %v, want %v", got, tt.minimized)
+			}
+
+			profile := user.ProfileFor(tt.self)
+			if !tt.minimized {
+				if profile != user {
+					t.Error("ProfileFor() should return the user as it is")
+				}
+				return
+			}
+			if profile.UserID != "auth0|1" || profile.Username != "jdoe" {
+				t.Errorf("ProfileFor() = %+v, want the identifiers kept", profile)
+			}
+			if profile.PrimaryEmail != "" || profile.UserMetadata != nil {
+				t.Errorf("ProfileFor() = %+v, want the profile left out", profile)
+			}
+			if user.UserMetadata == nil || user.PrimaryEmail == "" {
+				t.Error("ProfileFor() modified the user")
+			}
+		})
+	}
+}
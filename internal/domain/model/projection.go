@@ -0,0 +1,108 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import (
+	"fmt"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+)
+
+// ProfileFieldActivity is the projected field holding the login activity
+const ProfileFieldActivity = "activity"
+
+// ProfileFieldDisplayName is the computed name UIs render, see User.DisplayName
+const ProfileFieldDisplayName = "display_name"
+
+// profileFields maps the fields a read can select to their value on a user.
+// Each returns nil when the user has no value, so it's left out of the projection.
+var profileFields = map[string]func(u *User) any{
+	"username": func(u *User) any {
+		if u.Username == "" {
+			return nil
+		}
+		return u.Username
+	},
+	"picture":        metadataField(func(m *UserMetadata) *string { return m.Picture }),
+	"zoneinfo":       metadataField(func(m *UserMetadata) *string { return m.Zoneinfo }),
+	"name":           metadataField(func(m *UserMetadata) *string { return m.Name }),
+	"given_name":     metadataField(func(m *UserMetadata) *string { return m.GivenName }),
+	"family_name":    metadataField(func(m *UserMetadata) *string { return m.FamilyName }),
+	"job_title":      metadataField(func(m *UserMetadata) *string { return m.JobTitle }),
+	"organization":   metadataField(func(m *UserMetadata) *string { return m.Organization }),
+	"country":        metadataField(func(m *UserMetadata) *string { return m.Country }),
+	"state_province": metadataField(func(m *UserMetadata) *string { return m.StateProvince }),
+	"city":           metadataField(func(m *UserMetadata) *string { return m.City }),
+	"address":        metadataField(func(m *UserMetadata) *string { return m.Address }),
+	"postal_code":    metadataField(func(m *UserMetadata) *string { return m.PostalCode }),
+	"phone_number":   metadataField(func(m *UserMetadata) *string { return m.PhoneNumber }),
+	"t_shirt_size":   metadataField(func(m *UserMetadata) *string { return m.TShirtSize }),
+	"locale":         metadataField(func(m *UserMetadata) *string { return m.Locale }),
+	"timezone":       metadataField(func(m *UserMetadata) *string { return m.Timezone }),
+	"pronouns":       metadataField(func(m *UserMetadata) *string { return m.Pronouns }),
+	"preferred_name": metadataField(func(m *UserMetadata) *string { return m.PreferredName }),
+	ProfileFieldDisplayName: func(u *User) any {
+		if name := u.DisplayName(); name != "" {
+			return name
+		}
+		return nil
+	},
+	"preferences": func(u *User) any {
+		if u.UserMetadata == nil || u.UserMetadata.Preferences == nil {
+			return nil
+		}
+		return u.UserMetadata.Preferences
+	},
+	"privacy": func(u *User) any {
+		if u.UserMetadata == nil || u.UserMetadata.Privacy == nil {
+			return nil
+		}
+		return u.UserMetadata.Privacy
+	},
+	ProfileFieldActivity: func(u *User) any {
+		if u.Activity == nil {
+			return nil
+		}
+		return u.Activity
+	},
+}
+
+func metadataField(get func(m *UserMetadata) *string) func(u *User) any {
+	return func(u *User) any {
+		if u.UserMetadata == nil {
+			return nil
+		}
+		if value := get(u.UserMetadata); value != nil {
+			return *value
+		}
+		return nil
+	}
+}
+
+// IsProfileField reports whether field can be selected by a read
+func IsProfileField(field string) bool {
+	_, ok := profileFields[field]
+	return ok
+}
+
+// ProjectProfile returns only the selected fields of the user, keyed by their
+// JSON names, so callers that need a couple of fields don't receive the whole
+// metadata blob. Fields the user has no value for are left out. activity can
+// only be selected when includeActivity is set, as it's a privileged field.
+func (u *User) ProjectProfile(fields []string, includeActivity bool) (map[string]any, error) {
+	projection := make(map[string]any, len(fields))
+	for _, field := range fields {
+		get, ok := profileFields[field]
+		if !ok {
+			return nil, errors.NewValidation(fmt.Sprintf("unknown field %q", field))
+		}
+		if field == ProfileFieldActivity && !includeActivity {
+			return nil, errors.NewForbidden("insufficient_scope for field activity")
+		}
+		if value := get(u); value != nil {
+			projection[field] = value
+		}
+	}
+	return projection, nil
+}
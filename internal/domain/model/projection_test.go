@@ -0,0 +1,101 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+)
+
+func TestUser_ProjectProfile(t *testing.T) {
+	name := "John Doe"
+	picture := "https://example.com/avatar.jpg"
+	lastLogin := time.Date(2025, 2, 3, 4, 5, 6, 0, time.UTC)
+	user := &User{
+		Username:     "john.doe",
+		UserMetadata: &UserMetadata{Name: &name, Picture: &picture},
+		Activity:     &UserActivity{LastLogin: &lastLogin},
+	}
+
+	tests := []struct {
+		name            string
+		user            *User
+		fields          []string
+		includeActivity bool
+		want            map[string]any
+		wantErr         error
+	}{
+		{
+			name:   "selected fields only",
+			user:   user,
+			fields: []string{"username", "picture"},
+			want:   map[string]any{"username": "john.doe", "picture": picture},
+		},
+		{
+			name:   "fields without a value are left out",
+			user:   user,
+			fields: []string{"name", "job_title"},
+			want:   map[string]any{"name": name},
+		},
+		{
+			name:   "user without metadata",
+			user:   &User{Username: "john.doe"},
+			fields: []string{"username", "city"},
+			want:   map[string]any{"username": "john.doe"},
+		},
+		{
+			name:            "activity with scope",
+			user:            user,
+			fields:          []string{"activity"},
+			includeActivity: true,
+			want:            map[string]any{"activity": user.Activity},
+		},
+		{
+			name:    "activity without scope",
+			user:    user,
+			fields:  []string{"activity"},
+			wantErr: errs.Forbidden{},
+		},
+		{
+			name:    "unknown field",
+			user:    user,
+			fields:  []string{"username", "primary_email"},
+			wantErr: errs.Validation{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.user.ProjectProfile(tt.fields, tt.includeActivity)
+			if tt.wantErr != nil {
+				if err == nil || reflect.TypeOf(err) != reflect.TypeOf(tt.wantErr) {
+					t.Fatalf("expected %T, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestProfileFields_CoverUserMetadata keeps the selectable fields in step
+// with UserMetadata, so a new metadata field can't be silently unselectable
+func TestProfileFields_CoverUserMetadata(t *testing.T) {
+	metadata := reflect.TypeFor[UserMetadata]()
+	for i := range metadata.NumField() {
+		tag, _, _ := strings.Cut(metadata.Field(i).Tag.Get("json"), ",")
+		if _, ok := profileFields[tag]; !ok {
+			t.Errorf("user metadata field %q is not selectable", tag)
+		}
+	}
+}
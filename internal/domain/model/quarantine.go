@@ -0,0 +1,22 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import "time"
+
+// QuarantinedPayload is a request payload that repeatedly panicked its
+// handler, kept for inspection. Secrets and tokens in the payload are masked.
+type QuarantinedPayload struct {
+	// Signature identifies the payload: the SHA-256 of its subject and data
+	Signature string `json:"signature"`
+	Subject   string `json:"subject"`
+	Payload   string `json:"payload"`
+	// Truncated reports the payload was cut to the stored size
+	Truncated bool `json:"truncated,omitempty"`
+	// Panic is the value the handler last panicked with
+	Panic         string    `json:"panic"`
+	Panics        int       `json:"panics"`
+	FirstPanicAt  time.Time `json:"first_panic_at"`
+	QuarantinedAt time.Time `json:"quarantined_at"`
+}
@@ -0,0 +1,26 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package model
+
+// ReindexResult summarizes a lookup key re-index pass
+type ReindexResult struct {
+	// Scanned is the number of users inspected
+	Scanned int `json:"scanned"`
+	// Reindexed is the number of users whose lookup keys were rewritten
+	Reindexed int `json:"reindexed"`
+	// Removed is the number of stale lookup keys deleted
+	Removed int `json:"removed"`
+	// Orphaned is the number of lookup keys deleted because no user claims them
+	Orphaned int `json:"orphaned"`
+	// Failed is the number of users whose lookup keys could not be rewritten
+	Failed int `json:"failed"`
+}
+
+// ReindexProgress is reported while a re-index runs: the counts so far and,
+// after a failure, the user it was for and why
+type ReindexProgress struct {
+	ReindexResult
+	Username string `json:"username,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
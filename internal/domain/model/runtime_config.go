@@ -0,0 +1,28 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import "time"
+
+// RuntimeConfig is the effective configuration of one service replica, with
+// secrets masked
+type RuntimeConfig struct {
+	Version   string    `json:"version"`
+	Hostname  string    `json:"hostname"`
+	StartedAt time.Time `json:"started_at"`
+	// Tenant is set in multi-tenant deployments
+	Tenant string `json:"tenant,omitempty"`
+	// Provider is the identity provider type, e.g. auth0
+	Provider string `json:"provider"`
+	// ShadowProvider is the secondary provider reads are shadowed on, if any
+	ShadowProvider string `json:"shadow_provider,omitempty"`
+	// Issuer and Audience are what the access tokens are verified against
+	Issuer   string `json:"issuer,omitempty"`
+	Audience string `json:"audience,omitempty"`
+	// FeatureFlags are the names of the flags in effect
+	FeatureFlags []string `json:"feature_flags,omitempty"`
+	// Settings are the effective values of the settings, by environment
+	// variable, defaults included
+	Settings map[string]string `json:"settings"`
+}
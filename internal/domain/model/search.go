@@ -0,0 +1,52 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import (
+	"strings"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/normalize"
+)
+
+// UserSearchHit is a candidate returned by the admin user search
+type UserSearchHit struct {
+	UserID       string `json:"user_id"`
+	Username     string `json:"username,omitempty"`
+	Name         string `json:"name,omitempty"`
+	PrimaryEmail string `json:"primary_email,omitempty"`
+	// Score ranks the hit against the query; higher is a better match
+	Score int `json:"score"`
+}
+
+// UserSearchResult is a page of ranked search hits
+type UserSearchResult struct {
+	Users []UserSearchHit `json:"users"`
+	// Total is the number of hits across all pages
+	Total   int `json:"total"`
+	Page    int `json:"page"`
+	PerPage int `json:"per_page"`
+}
+
+// MatchesSearch reports whether the normalized query appears anywhere in the
+// user's username, primary email or name. Repositories without a search engine
+// use it to pick candidates for the admin search.
+func (u User) MatchesSearch(query string) bool {
+	if query == "" {
+		return false
+	}
+	for _, value := range []string{normalize.Username(u.Username), normalize.Email(u.PrimaryEmail), normalize.Email(u.SearchName())} {
+		if strings.Contains(value, query) {
+			return true
+		}
+	}
+	return false
+}
+
+// SearchName returns the display name the admin search matches against
+func (u User) SearchName() string {
+	if u.UserMetadata != nil && u.UserMetadata.Name != nil {
+		return *u.UserMetadata.Name
+	}
+	return ""
+}
@@ -0,0 +1,45 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import "time"
+
+// Detection rules raising security events
+const (
+	// SecurityRuleInvalidToken is a caller sending tokens that can't be
+	// verified, e.g. forged, expired or revoked ones
+	SecurityRuleInvalidToken = "invalid_token"
+	// SecurityRuleScopeEscalation is a caller with a valid token asking for
+	// operations its scopes don't grant
+	SecurityRuleScopeEscalation = "scope_escalation"
+	// SecurityRuleEmailEnumeration is a caller resolving emails to accounts
+	// in bulk
+	SecurityRuleEmailEnumeration = "email_enumeration"
+	// SecurityRuleEnumerationLockout is a caller blocked from looking up
+	// accounts after looking up too many that don't exist
+	SecurityRuleEnumerationLockout = "enumeration_lockout"
+)
+
+// SecurityEvent reports a caller whose requests matched a detection rule
+// often enough within a window to be suspicious
+type SecurityEvent struct {
+	EventID string `json:"event_id"`
+	Rule    string `json:"rule"`
+	// Caller identifies the client the requests came from, see the
+	// detector for how it is resolved
+	Caller string `json:"caller"`
+	// UserID is the redacted subject of the token of the last request, when
+	// it had one; for invalid tokens it is the subject the token claims
+	UserID string `json:"user_id,omitempty"`
+	// Subject is the subject of the last request
+	Subject     string    `json:"subject"`
+	Count       int       `json:"count"`
+	Threshold   int       `json:"threshold"`
+	Window      string    `json:"window"`
+	FirstSeenAt time.Time `json:"first_seen_at"`
+	DetectedAt  time.Time `json:"detected_at"`
+	// BlockedUntil is when the caller may send requests again, for the rules
+	// blocking it
+	BlockedUntil *time.Time `json:"blocked_until,omitempty"`
+}
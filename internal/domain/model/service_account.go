@@ -0,0 +1,37 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import "time"
+
+// ServiceAccount is a non-human identity that authenticates with the client
+// credentials grant, such as an Auth0 machine-to-machine application
+type ServiceAccount struct {
+	ClientID    string `json:"client_id"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Owner is the team or service accountable for the account
+	Owner  string                `json:"owner"`
+	Grants []ServiceAccountGrant `json:"grants"`
+	// CreatedBy and RotatedBy are the subs of the callers that created the
+	// account and last rotated its secret
+	CreatedBy string     `json:"created_by,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	RotatedBy string     `json:"rotated_by,omitempty"`
+	RotatedAt *time.Time `json:"rotated_at,omitempty"`
+}
+
+// ServiceAccountGrant allows a service account to request tokens for an API
+// with a set of scopes
+type ServiceAccountGrant struct {
+	Audience string   `json:"audience"`
+	Scopes   []string `json:"scopes"`
+}
+
+// ServiceAccountCredentials is a service account with its client secret.
+// Providers only reveal the secret when the account is created or rotated.
+type ServiceAccountCredentials struct {
+	ServiceAccount
+	ClientSecret string `json:"client_secret"`
+}
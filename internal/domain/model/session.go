@@ -0,0 +1,33 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import "time"
+
+// Kinds of UserSession
+const (
+	// SessionTypeLogin is a login session of the identity provider
+	SessionTypeLogin = "session"
+	// SessionTypeRefreshToken is a refresh token an application holds
+	SessionTypeRefreshToken = "refresh_token"
+)
+
+// UserSession is a way a user stays signed in: a login session or a refresh
+// token. Revoking it forces the user to sign in again on that device.
+type UserSession struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	// SessionID is the login session a refresh token was issued in, if any
+	SessionID string `json:"session_id,omitempty"`
+	// Clients are the applications the session is signed in to
+	Clients      []string   `json:"clients,omitempty"`
+	CreatedAt    *time.Time `json:"created_at,omitempty"`
+	LastActiveAt *time.Time `json:"last_active_at,omitempty"`
+	// ExpiresAt is the absolute expiry; IdleExpiresAt moves with activity
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	IdleExpiresAt *time.Time `json:"idle_expires_at,omitempty"`
+	UserAgent     string     `json:"user_agent,omitempty"`
+	// IP is the truncated address the session was last used from
+	IP string `json:"ip,omitempty"`
+}
@@ -0,0 +1,57 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import "time"
+
+// InstanceStats is a point-in-time snapshot of one service replica, used for
+// capacity planning. Counters are cumulative since the replica started.
+type InstanceStats struct {
+	InstanceID    string           `json:"instance_id"`
+	Hostname      string           `json:"hostname"`
+	Version       string           `json:"version"`
+	StartedAt     time.Time        `json:"started_at"`
+	UptimeSeconds int64            `json:"uptime_seconds"`
+	Requests      RequestStats     `json:"requests"`
+	Cache         *CacheStats      `json:"cache,omitempty"`
+	Provider      *ProviderStats   `json:"provider,omitempty"`
+	Maintenance   MaintenanceState `json:"maintenance"`
+}
+
+// RequestStats counts NATS requests handled by the replica
+type RequestStats struct {
+	InFlight int64 `json:"in_flight"`
+	Total    int64 `json:"total"`
+}
+
+// CacheStats describes the user cache of the replica
+type CacheStats struct {
+	Entries int     `json:"entries"`
+	Hits    int64   `json:"hits"`
+	Misses  int64   `json:"misses"`
+	HitRate float64 `json:"hit_rate"`
+	// StaleHits are the hits served from expired entries in
+	// stale-while-revalidate mode, included in Hits
+	StaleHits int64 `json:"stale_hits,omitempty"`
+}
+
+// ProviderStats describes the replica's calls to the identity provider
+type ProviderStats struct {
+	Type      string  `json:"type"`
+	Requests  int64   `json:"requests"`
+	Errors    int64   `json:"errors"`
+	ErrorRate float64 `json:"error_rate"`
+	// TokenRefreshedAt is when the M2M token was last fetched, if the provider uses one
+	TokenRefreshedAt *time.Time `json:"token_refreshed_at,omitempty"`
+	// TokenAgeSeconds is the age of the current M2M token
+	TokenAgeSeconds *int64 `json:"token_age_seconds,omitempty"`
+}
+
+// Ratio returns part/total, or 0 when total is 0
+func Ratio(part, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) / float64(total)
+}
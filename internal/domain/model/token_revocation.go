@@ -0,0 +1,30 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import "time"
+
+// TokenRevocation revokes a single access token by its jti, or every token of
+// a user issued up to RevokedAt when JTI is empty
+type TokenRevocation struct {
+	JTI     string `json:"jti,omitempty"`
+	Subject string `json:"sub"`
+	Reason  string `json:"reason,omitempty"`
+	// RevokedBy is the sub of the caller that revoked the token(s)
+	RevokedBy string    `json:"revoked_by"`
+	RevokedAt time.Time `json:"revoked_at"`
+	// ExpiresAt is when the revocation can be forgotten because every token
+	// it covers has expired
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Covers reports whether the revocation applies to a token with the given
+// jti, sub and issue time. Tokens of a revoked user without an iat claim are
+// treated as revoked.
+func (r *TokenRevocation) Covers(jti, sub string, issuedAt *time.Time) bool {
+	if r.JTI != "" {
+		return r.JTI == jti
+	}
+	return r.Subject == sub && (issuedAt == nil || !issuedAt.After(r.RevokedAt))
+}
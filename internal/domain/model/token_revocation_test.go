@@ -0,0 +1,40 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenRevocationCovers(t *testing.T) {
+	revokedAt := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	before, after := revokedAt.Add(-time.Minute), revokedAt.Add(time.Minute)
+
+	byJTI := &TokenRevocation{JTI: "jti-1", Subject: "auth0|alice", RevokedAt: revokedAt}
+	bySub := &TokenRevocation{Subject: "auth0|alice", RevokedAt: revokedAt}
+
+	tests := []struct {
+		name       string
+		revocation *TokenRevocation
+		jti, sub   string
+		issuedAt   *time.Time
+		want       bool
+	}{
+		{name: "same jti", revocation: byJTI, jti: "jti-1", sub: "auth0|alice", issuedAt: &before, want: true},
+		{name: "other jti of the same user", revocation: byJTI, jti: "jti-2", sub: "auth0|alice", issuedAt: &before, want: false},
+		{name: "user token issued before", revocation: bySub, jti: "jti-2", sub: "auth0|alice", issuedAt: &before, want: true},
+		{name: "user token issued at revocation", revocation: bySub, sub: "auth0|alice", issuedAt: &revokedAt, want: true},
+		{name: "user token issued after", revocation: bySub, sub: "auth0|alice", issuedAt: &after, want: false},
+		{name: "user token without iat", revocation: bySub, sub: "auth0|alice", want: true},
+		{name: "other user", revocation: bySub, sub: "auth0|bob", issuedAt: &before, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.revocation.Covers(tt.jti, tt.sub, tt.issuedAt); got != tt.want {
+				t.Errorf("Covers() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
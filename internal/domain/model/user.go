@@ -9,22 +9,34 @@ import (
 	"encoding/hex"
 	"fmt"
 	"log/slog"
+	"reflect"
+	"slices"
 	"strings"
 
 	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/normalize"
 	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/redaction"
 )
 
-// User represents a user in the system
+// User represents a user in the system.
+// SecondaryEmails are addresses on the account that aren't linked alternate
+// emails, e.g. the email a social or enterprise identity asserted.
 type User struct {
 	Token           string        `json:"token" yaml:"token"`
 	UserID          string        `json:"user_id" yaml:"user_id"`
 	Sub             string        `json:"sub,omitempty" yaml:"sub,omitempty"`
 	Username        string        `json:"username" yaml:"username"`
 	PrimaryEmail    string        `json:"primary_email" yaml:"primary_email"`
+	EmailVerified   bool          `json:"email_verified,omitempty" yaml:"email_verified,omitempty"`
 	AlternateEmails []Email       `json:"alternate_emails,omitempty" yaml:"alternate_emails,omitempty"`
+	SecondaryEmails []Email       `json:"secondary_emails,omitempty" yaml:"secondary_emails,omitempty"`
 	Identities      []Identity    `json:"identities,omitempty" yaml:"identities,omitempty"`
 	UserMetadata    *UserMetadata `json:"user_metadata,omitempty" yaml:"user_metadata,omitempty"`
+	Activity        *UserActivity `json:"activity,omitempty" yaml:"activity,omitempty"`
+
+	// Stale is set on a user served from an expired cache entry while the
+	// entry is refreshed in the background. It is never stored.
+	Stale bool `json:"-" yaml:"-"`
 }
 
 // UserMetadata represents the metadata of a user
@@ -43,6 +55,18 @@ type UserMetadata struct {
 	PostalCode    *string `json:"postal_code,omitempty" yaml:"postal_code,omitempty"`
 	PhoneNumber   *string `json:"phone_number,omitempty" yaml:"phone_number,omitempty"`
 	TShirtSize    *string `json:"t_shirt_size,omitempty" yaml:"t_shirt_size,omitempty"`
+	// Locale is a BCP 47 language tag and Timezone an IANA time zone, both
+	// stored in their canonical form
+	Locale   *string `json:"locale,omitempty" yaml:"locale,omitempty"`
+	Timezone *string `json:"timezone,omitempty" yaml:"timezone,omitempty"`
+	// Pronouns are written like "they/them". PreferredName, when set, is the
+	// name shown instead of the given and family names.
+	Pronouns      *string `json:"pronouns,omitempty" yaml:"pronouns,omitempty"`
+	PreferredName *string `json:"preferred_name,omitempty" yaml:"preferred_name,omitempty"`
+	// Preferences is managed through the user_preferences subjects
+	Preferences *UserPreferences `json:"preferences,omitempty" yaml:"preferences,omitempty"`
+	// Privacy holds the user's profile sharing choice, see UserPrivacy
+	Privacy *UserPrivacy `json:"privacy,omitempty" yaml:"privacy,omitempty"`
 }
 
 // Validate validates the user data and returns an error if validation fails
@@ -60,6 +84,18 @@ func (u *User) Validate() error {
 		return errors.NewValidation(errRequiredMsg("user_metadata"))
 	}
 
+	// canonical values are what lets consumers compare and schedule with them
+	if err := u.UserMetadata.normalizeLocalization(); err != nil {
+		return err
+	}
+	if err := u.UserMetadata.normalizeNaming(); err != nil {
+		return err
+	}
+	// downstream shipping and tax reporting need ISO codes, not free text
+	if err := u.UserMetadata.normalizeAddress(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -95,9 +131,35 @@ func (u User) buildIndexKey(ctx context.Context, kind, data string) string {
 	return key
 }
 
+// HasVerifiedEmail reports whether email is a verified address of the user:
+// the primary email, a linked alternate email, an identity email or a
+// secondary email. Addresses are compared by their normalized key.
+func (u User) HasVerifiedEmail(email string) bool {
+	email = normalize.EmailKey(email)
+	if email == "" {
+		return false
+	}
+	if u.EmailVerified && normalize.EmailKey(u.PrimaryEmail) == email {
+		return true
+	}
+	for _, list := range [][]Email{u.AlternateEmails, u.SecondaryEmails} {
+		for _, e := range list {
+			if e.Verified && normalize.EmailKey(e.Email) == email {
+				return true
+			}
+		}
+	}
+	for _, identity := range u.Identities {
+		if identity.EmailVerified && normalize.EmailKey(identity.Email) == email {
+			return true
+		}
+	}
+	return false
+}
+
 // BuildEmailIndexKey builds the index key for the email
 func (u User) BuildEmailIndexKey(ctx context.Context) string {
-	data := strings.TrimSpace(strings.ToLower(u.PrimaryEmail))
+	data := normalize.EmailKey(u.PrimaryEmail)
 	if data == "" {
 		return ""
 	}
@@ -106,13 +168,22 @@ func (u User) BuildEmailIndexKey(ctx context.Context) string {
 
 // BuildAlternateEmailIndexKey builds the index key for the alternate email
 func (u User) BuildAlternateEmailIndexKey(ctx context.Context, alternateEmail string) string {
-	data := strings.TrimSpace(strings.ToLower(alternateEmail))
+	data := normalize.EmailKey(alternateEmail)
 	if data == "" {
 		return ""
 	}
 	return u.buildIndexKey(ctx, "alternate-email", data)
 }
 
+// BuildSecondaryEmailIndexKey builds the index key for a secondary email
+func (u User) BuildSecondaryEmailIndexKey(ctx context.Context, secondaryEmail string) string {
+	data := normalize.EmailKey(secondaryEmail)
+	if data == "" {
+		return ""
+	}
+	return u.buildIndexKey(ctx, "secondary-email", data)
+}
+
 // BuildSubIndexKey builds the index key for the sub
 func (u User) BuildSubIndexKey(ctx context.Context) string {
 	data := strings.TrimSpace(strings.ToLower(u.Sub))
@@ -166,6 +237,63 @@ func (um *UserMetadata) userMetadataSanitize() {
 	if um.Zoneinfo != nil {
 		*um.Zoneinfo = strings.TrimSpace(*um.Zoneinfo)
 	}
+	if um.Locale != nil {
+		*um.Locale = strings.TrimSpace(*um.Locale)
+	}
+	if um.Timezone != nil {
+		*um.Timezone = strings.TrimSpace(*um.Timezone)
+	}
+	if um.Pronouns != nil {
+		*um.Pronouns = strings.TrimSpace(*um.Pronouns)
+	}
+	if um.PreferredName != nil {
+		*um.PreferredName = strings.TrimSpace(*um.PreferredName)
+	}
+}
+
+// metadataFields are the JSON names of the UserMetadata fields, by field index
+var metadataFields = func() []string {
+	t := reflect.TypeFor[UserMetadata]()
+	names := make([]string, t.NumField())
+	for i := range names {
+		names[i], _, _ = strings.Cut(t.Field(i).Tag.Get("json"), ",")
+	}
+	return names
+}()
+
+// MetadataFieldNames returns the JSON names of every metadata field
+func MetadataFieldNames() []string {
+	return slices.Clone(metadataFields)
+}
+
+// SetFields returns the JSON names of the fields of um that are set, which
+// for an update are the fields it writes
+func (um *UserMetadata) SetFields() []string {
+	if um == nil {
+		return nil
+	}
+	v := reflect.ValueOf(um).Elem()
+	var set []string
+	for i, name := range metadataFields {
+		if !v.Field(i).IsNil() {
+			set = append(set, name)
+		}
+	}
+	return set
+}
+
+// FieldValue returns the value of the string field named name, and whether
+// it is set
+func (um *UserMetadata) FieldValue(name string) (string, bool) {
+	i := slices.Index(metadataFields, name)
+	if um == nil || i < 0 {
+		return "", false
+	}
+	value, ok := reflect.ValueOf(um).Elem().Field(i).Interface().(*string)
+	if !ok || value == nil {
+		return "", false
+	}
+	return *value, true
 }
 
 // Patch updates the UserMetadata with the update values only if the update values are not nil
@@ -246,5 +374,36 @@ func (a *UserMetadata) Patch(update *UserMetadata) bool {
 		updated = true
 	}
 
+	if update.Locale != nil {
+		a.Locale = update.Locale
+		updated = true
+	}
+
+	if update.Timezone != nil {
+		a.Timezone = update.Timezone
+		updated = true
+	}
+
+	if update.Pronouns != nil {
+		a.Pronouns = update.Pronouns
+		updated = true
+	}
+
+	if update.PreferredName != nil {
+		a.PreferredName = update.PreferredName
+		updated = true
+	}
+
+	// the namespace is written whole, already merged and validated
+	if update.Preferences != nil {
+		a.Preferences = update.Preferences
+		updated = true
+	}
+
+	if update.Privacy != nil {
+		a.Privacy = update.Privacy
+		updated = true
+	}
+
 	return updated
 }
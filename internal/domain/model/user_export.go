@@ -0,0 +1,41 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package model
+
+// User export formats
+const (
+	UserExportFormatCSV  = "csv"
+	UserExportFormatJSON = "json"
+)
+
+// UserExport selects the users and attributes of a bulk export
+type UserExport struct {
+	// ConnectionID limits the export to the users of one connection
+	ConnectionID string `json:"connection_id,omitempty"`
+	// Format is csv (the default) or json, the latter as JSON lines
+	Format string            `json:"format,omitempty"`
+	Fields []UserExportField `json:"fields"`
+}
+
+// UserExportField is an attribute of the exported users, e.g. email or
+// user_metadata.organization, exported under ExportAs when it is set
+type UserExportField struct {
+	Name     string `json:"name"`
+	ExportAs string `json:"export_as,omitempty"`
+}
+
+// UserExportFile is a finished export, decompressed
+type UserExportFile struct {
+	JobID   string
+	Format  string
+	Content []byte
+}
+
+// UserExportObject is an export stored in an object store
+type UserExportObject struct {
+	Bucket string `json:"bucket"`
+	Name   string `json:"name"`
+	Size   uint64 `json:"size"`
+	Digest string `json:"digest,omitempty"`
+}
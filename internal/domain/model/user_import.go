@@ -0,0 +1,68 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import "encoding/json"
+
+// UserImport is a manifest of users to create, or update with Upsert, in a
+// connection of the identity provider. Users are provider records, passed
+// through as they are.
+type UserImport struct {
+	ConnectionID string            `json:"connection_id"`
+	Users        []json.RawMessage `json:"users"`
+	Upsert       bool              `json:"upsert,omitempty"`
+	// ExternalID tags the job, e.g. with the environment being seeded
+	ExternalID string `json:"external_id,omitempty"`
+	// SendCompletionEmail emails the tenant administrators when the job ends
+	SendCompletionEmail bool `json:"send_completion_email,omitempty"`
+}
+
+// User import job statuses
+const (
+	UserImportStatusPending   = "pending"
+	UserImportStatusCompleted = "completed"
+	UserImportStatusFailed    = "failed"
+)
+
+// UserImportJob is the progress, and once done the outcome, of a user import
+type UserImportJob struct {
+	ID           string `json:"id"`
+	Status       string `json:"status"`
+	ConnectionID string `json:"connection_id,omitempty"`
+	ExternalID   string `json:"external_id,omitempty"`
+	CreatedAt    string `json:"created_at,omitempty"`
+	// PercentageDone is reported while the job is pending
+	PercentageDone int                `json:"percentage_done,omitempty"`
+	Summary        *UserImportSummary `json:"summary,omitempty"`
+	// Errors are the records the job rejected, reported once it is done
+	Errors []UserImportError `json:"errors,omitempty"`
+}
+
+// Done reports whether the job has finished, successfully or not
+func (j *UserImportJob) Done() bool {
+	return j.Status == UserImportStatusCompleted || j.Status == UserImportStatusFailed
+}
+
+// UserImportSummary counts the records of a finished import
+type UserImportSummary struct {
+	Total    int `json:"total"`
+	Inserted int `json:"inserted"`
+	Updated  int `json:"updated"`
+	Failed   int `json:"failed"`
+}
+
+// UserImportError is a record an import rejected, identified by its email
+// and user_id when it has them; the rest of the record isn't reported back
+type UserImportError struct {
+	Email  string                  `json:"email,omitempty"`
+	UserID string                  `json:"user_id,omitempty"`
+	Errors []UserImportErrorDetail `json:"errors"`
+}
+
+// UserImportErrorDetail is one reason a record was rejected
+type UserImportErrorDetail struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Path    string `json:"path,omitempty"`
+}
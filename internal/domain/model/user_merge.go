@@ -0,0 +1,108 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import "time"
+
+// Conflict-resolution policies of a user merge, deciding which account's
+// value a metadata field keeps when both accounts have one
+const (
+	// UserMergePolicyFillMissing keeps the primary's values and fills the
+	// fields it has no value for from the secondary
+	UserMergePolicyFillMissing = "fill_missing"
+	// UserMergePolicyPrimaryWins keeps only the primary's metadata
+	UserMergePolicyPrimaryWins = "primary_wins"
+	// UserMergePolicySecondaryWins takes every value the secondary has
+	UserMergePolicySecondaryWins = "secondary_wins"
+)
+
+// UserMerge folds the secondary account into the primary one: the
+// secondary's identities and emails move to the primary, which keeps the
+// merged metadata, and the secondary no longer exists on its own
+type UserMerge struct {
+	Primary   *User
+	Secondary *User
+	// Metadata is the metadata the primary keeps after the merge
+	Metadata *UserMetadata
+}
+
+// UserMergeRecord is the audit history entry of a merge, kept under the sub
+// of the account that was merged away
+type UserMergeRecord struct {
+	OldSub      string    `json:"old_sub"`
+	NewSub      string    `json:"new_sub"`
+	OldUsername string    `json:"old_username,omitempty"`
+	NewUsername string    `json:"new_username,omitempty"`
+	Policy      string    `json:"policy"`
+	Conflicts   []string  `json:"conflicts,omitempty"`
+	MergedBy    string    `json:"merged_by"`
+	MergedAt    time.Time `json:"merged_at"`
+}
+
+// mergeableMetadataFields are the metadata fields a merge resolves, by JSON name
+var mergeableMetadataFields = []struct {
+	name  string
+	field func(m *UserMetadata) **string
+}{
+	{"picture", func(m *UserMetadata) **string { return &m.Picture }},
+	{"zoneinfo", func(m *UserMetadata) **string { return &m.Zoneinfo }},
+	{"name", func(m *UserMetadata) **string { return &m.Name }},
+	{"given_name", func(m *UserMetadata) **string { return &m.GivenName }},
+	{"family_name", func(m *UserMetadata) **string { return &m.FamilyName }},
+	{"job_title", func(m *UserMetadata) **string { return &m.JobTitle }},
+	{"organization", func(m *UserMetadata) **string { return &m.Organization }},
+	{"country", func(m *UserMetadata) **string { return &m.Country }},
+	{"state_province", func(m *UserMetadata) **string { return &m.StateProvince }},
+	{"city", func(m *UserMetadata) **string { return &m.City }},
+	{"address", func(m *UserMetadata) **string { return &m.Address }},
+	{"postal_code", func(m *UserMetadata) **string { return &m.PostalCode }},
+	{"phone_number", func(m *UserMetadata) **string { return &m.PhoneNumber }},
+	{"t_shirt_size", func(m *UserMetadata) **string { return &m.TShirtSize }},
+	{"locale", func(m *UserMetadata) **string { return &m.Locale }},
+	{"timezone", func(m *UserMetadata) **string { return &m.Timezone }},
+	{"pronouns", func(m *UserMetadata) **string { return &m.Pronouns }},
+	{"preferred_name", func(m *UserMetadata) **string { return &m.PreferredName }},
+}
+
+// MergeUserMetadata merges the metadata of two accounts with policy and
+// returns the result with the fields both had different values for. Empty
+// values count as missing. Neither input is modified.
+func MergeUserMetadata(primary, secondary *UserMetadata, policy string) (*UserMetadata, []string) {
+	merged := &UserMetadata{}
+	if primary != nil {
+		*merged = *primary
+	}
+	if secondary == nil {
+		return merged, nil
+	}
+
+	var conflicts []string
+	for _, f := range mergeableMetadataFields {
+		kept := f.field(merged)
+		other := *f.field(secondary)
+		if other == nil || *other == "" {
+			continue
+		}
+		if *kept == nil || **kept == "" {
+			if policy != UserMergePolicyPrimaryWins {
+				value := *other
+				*kept = &value
+			}
+			continue
+		}
+		if **kept == *other {
+			continue
+		}
+		conflicts = append(conflicts, f.name)
+		if policy == UserMergePolicySecondaryWins {
+			value := *other
+			*kept = &value
+		}
+	}
+	// preferences are kept whole, from the secondary only when the primary has none
+	if merged.Preferences == nil && policy != UserMergePolicyPrimaryWins {
+		merged.Preferences = secondary.Preferences
+	}
+	return merged, conflicts
+}
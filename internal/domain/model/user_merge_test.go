@@ -0,0 +1,70 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeUserMetadata(t *testing.T) {
+	s := func(v string) *string { return &v }
+	primary := &UserMetadata{Name: s("Jane Doe"), JobTitle: s("Engineer"), City: s("")}
+	secondary := &UserMetadata{Name: s("Jane D."), JobTitle: s("Engineer"), City: s("Lisbon"), Country: s("PT")}
+
+	tests := []struct {
+		name          string
+		policy        string
+		want          *UserMetadata
+		wantConflicts []string
+	}{
+		{
+			name:          "fill missing keeps the primary's values",
+			policy:        UserMergePolicyFillMissing,
+			want:          &UserMetadata{Name: s("Jane Doe"), JobTitle: s("Engineer"), City: s("Lisbon"), Country: s("PT")},
+			wantConflicts: []string{"name"},
+		},
+		{
+			name:          "primary wins ignores the secondary",
+			policy:        UserMergePolicyPrimaryWins,
+			want:          &UserMetadata{Name: s("Jane Doe"), JobTitle: s("Engineer"), City: s("")},
+			wantConflicts: []string{"name"},
+		},
+		{
+			name:          "secondary wins takes every value it has",
+			policy:        UserMergePolicySecondaryWins,
+			want:          &UserMetadata{Name: s("Jane D."), JobTitle: s("Engineer"), City: s("Lisbon"), Country: s("PT")},
+			wantConflicts: []string{"name"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, conflicts := MergeUserMetadata(primary, secondary, tt.policy)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MergeUserMetadata() = %+v, want %+v", got, tt.want)
+			}
+			if !reflect.DeepEqual(conflicts, tt.wantConflicts) {
+				t.Errorf("MergeUserMetadata() conflicts = %v, want %v", conflicts, tt.wantConflicts)
+			}
+		})
+	}
+
+	if *primary.Name != "Jane Doe" || *primary.City != "" || primary.Country != nil {
+		t.Errorf("MergeUserMetadata() modified the primary metadata: %+v", primary)
+	}
+}
+
+func TestMergeUserMetadata_NilInputs(t *testing.T) {
+	name := "Jane Doe"
+	got, conflicts := MergeUserMetadata(nil, &UserMetadata{Name: &name}, UserMergePolicyFillMissing)
+	if got.Name == nil || *got.Name != name || conflicts != nil {
+		t.Errorf("MergeUserMetadata(nil, secondary) = %+v, %v", got, conflicts)
+	}
+
+	got, conflicts = MergeUserMetadata(&UserMetadata{Name: &name}, nil, UserMergePolicySecondaryWins)
+	if got.Name == nil || *got.Name != name || conflicts != nil {
+		t.Errorf("MergeUserMetadata(primary, nil) = %+v, %v", got, conflicts)
+	}
+}
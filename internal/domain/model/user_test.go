@@ -1178,3 +1178,37 @@ func TestUserMetadata_Patch_Idempotency(t *testing.T) {
 		t.Errorf("Organization fields don't match after multiple patches")
 	}
 }
+
+func TestUser_HasVerifiedEmail(t *testing.T) {
+	user := User{
+		PrimaryEmail:    "primary@example.com",
+		EmailVerified:   true,
+		AlternateEmails: []Email{{Email: "alt@example.com", Verified: true}, {Email: "pending@example.com"}},
+		SecondaryEmails: []Email{{Email: "secondary@example.com", Verified: true}},
+		Identities:      []Identity{{Connection: "github", Email: "gh@example.com", EmailVerified: true}},
+	}
+
+	tests := []struct {
+		name  string
+		user  User
+		email string
+		want  bool
+	}{
+		{name: "verified primary", user: user, email: "PRIMARY@example.com", want: true},
+		{name: "verified alternate", user: user, email: "alt@example.com", want: true},
+		{name: "unverified alternate", user: user, email: "pending@example.com", want: false},
+		{name: "verified secondary", user: user, email: "secondary@example.com", want: true},
+		{name: "verified identity email", user: user, email: " gh@example.com ", want: true},
+		{name: "unknown email", user: user, email: "other@example.com", want: false},
+		{name: "empty email", user: user, email: "", want: false},
+		{name: "unverified primary", user: User{PrimaryEmail: "primary@example.com"}, email: "primary@example.com", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.user.HasVerifiedEmail(tt.email); got != tt.want {
+				t.Errorf("HasVerifiedEmail(%q) = %v, want %v", tt.email, got, tt.want)
+			}
+		})
+	}
+}
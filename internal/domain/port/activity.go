@@ -0,0 +1,21 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package port
+
+import (
+	"context"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+)
+
+// UserActivityStore keeps the most recent login activity observed for users.
+type UserActivityStore interface {
+	RecordLogin(ctx context.Context, event model.LoginEvent) error
+	GetActivity(ctx context.Context, userID string) (*model.UserActivity, bool)
+}
+
+// LoginEventIngester accepts normalized login events from a provider log stream.
+type LoginEventIngester interface {
+	IngestLoginEvents(ctx context.Context, events []model.LoginEvent) error
+}
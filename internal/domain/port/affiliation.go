@@ -0,0 +1,20 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package port
+
+import (
+	"context"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+)
+
+// AffiliationStore keeps the employment history of users
+type AffiliationStore interface {
+	// GetAffiliations returns the history of the user, empty with a zero
+	// revision when the user has none
+	GetAffiliations(ctx context.Context, userID string) (*model.AffiliationHistory, error)
+	// SaveAffiliations writes the history if it is still at its revision,
+	// returning an errors.Conflict when it changed since it was read
+	SaveAffiliations(ctx context.Context, history *model.AffiliationHistory) error
+}
@@ -0,0 +1,21 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package port
+
+import (
+	"context"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+)
+
+// InactiveUserArchiver is implemented by user repositories that can move
+// users nobody has used in a while out of their primary storage. An archived
+// user is restored the next time it is read, so archival is invisible to
+// callers apart from the latency of that first read.
+type InactiveUserArchiver interface {
+	// ArchiveInactiveUsers archives up to limit users last created, updated
+	// or read before cutoff
+	ArchiveInactiveUsers(ctx context.Context, cutoff time.Time, limit int) (model.ArchiveResult, error)
+}
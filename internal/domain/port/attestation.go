@@ -0,0 +1,22 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package port
+
+import (
+	"context"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+)
+
+// AttestationStore keeps the verified profile fields of users, one
+// attestation per user and field
+type AttestationStore interface {
+	// PutAttestation stores attestation, replacing the one of the same field
+	PutAttestation(ctx context.Context, attestation *model.FieldAttestation) error
+	// DeleteAttestation removes the attestation of a field; it is not an
+	// error when there is none
+	DeleteAttestation(ctx context.Context, userID, field string) error
+	// ListAttestations returns every attestation of the user
+	ListAttestations(ctx context.Context, userID string) ([]*model.FieldAttestation, error)
+}
@@ -0,0 +1,25 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package port
+
+import (
+	"context"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+)
+
+// UserCache is the inspection side of the read-through user cache, used to
+// detect and evict entries that have drifted from the identity provider.
+type UserCache interface {
+	CachedUserIDs() []string
+	CachedUser(userID string) (*model.User, bool)
+	InvalidateUser(userID string)
+}
+
+// HotUserCache is the warm-up side of the read-through user cache: the
+// users read the most, and loading a user before it is read
+type HotUserCache interface {
+	HotUserIDs(n int) []string
+	Preload(ctx context.Context, userID string) error
+}
@@ -0,0 +1,21 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package port
+
+import (
+	"context"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+)
+
+// ConsentStore keeps the policy versions users accepted. Acceptances are
+// write-once: recording a version the user already accepted keeps the
+// original record.
+type ConsentStore interface {
+	// RecordAcceptance stores acceptance unless the user already accepted that
+	// version, and returns the stored record either way
+	RecordAcceptance(ctx context.Context, acceptance *model.PolicyAcceptance) (*model.PolicyAcceptance, error)
+	// ListAcceptances returns every acceptance of the user, of any policy
+	ListAcceptances(ctx context.Context, userID string) ([]*model.PolicyAcceptance, error)
+}
@@ -0,0 +1,21 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package port
+
+import (
+	"context"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+)
+
+// DormantUserScanner is implemented by user repositories that can search users
+// by last login time. Returned users carry at least UserID and Activity.
+type DormantUserScanner interface {
+	// ListDormantUsers returns up to limit users whose last login is before cutoff.
+	ListDormantUsers(ctx context.Context, cutoff time.Time, limit int) ([]*model.User, error)
+	// TagDormantUser marks the user as dormant in provider-side metadata so
+	// downstream deactivation workflows can pick it up.
+	TagDormantUser(ctx context.Context, userID string, since time.Time) error
+}
@@ -0,0 +1,21 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package port
+
+import (
+	"context"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+)
+
+// EmailOrganizationStore persists the email domain to organization mappings
+type EmailOrganizationStore interface {
+	// Get returns a NotFound error for domains without a mapping
+	Get(ctx context.Context, domain string) (*model.EmailOrganization, error)
+	Put(ctx context.Context, mapping *model.EmailOrganization) error
+	// Delete returns the deleted mapping, or a NotFound error for domains
+	// without one
+	Delete(ctx context.Context, domain string) (*model.EmailOrganization, error)
+	List(ctx context.Context) ([]*model.EmailOrganization, error)
+}
@@ -0,0 +1,23 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package port
+
+import (
+	"context"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+)
+
+// StorageReencrypter is implemented by user repositories that encrypt records
+// at rest. ReencryptStorage rewrites plaintext records and records sealed with
+// a retired key using the active key.
+type StorageReencrypter interface {
+	ReencryptStorage(ctx context.Context) (model.ReencryptResult, error)
+}
+
+// SecretDecrypter decrypts secrets that were encrypted out of band with a key
+// management service, e.g. client secrets supplied as KMS ciphertext.
+type SecretDecrypter interface {
+	DecryptSecret(ctx context.Context, ciphertext []byte) ([]byte, error)
+}
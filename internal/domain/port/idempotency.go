@@ -0,0 +1,22 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package port
+
+import (
+	"context"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+)
+
+// IdempotencyStore keeps the requests sent with an idempotency key for a
+// short while, shared by every replica
+type IdempotencyStore interface {
+	// Reserve stores request, pending, unless its subject and key are taken,
+	// in which case the request stored under them is returned instead
+	Reserve(ctx context.Context, request *model.IdempotentRequest) (*model.IdempotentRequest, error)
+	// Complete stores the reply of a reserved request
+	Complete(ctx context.Context, request *model.IdempotentRequest) error
+	// Release deletes a request, so its key can be used again
+	Release(ctx context.Context, request *model.IdempotentRequest) error
+}
@@ -0,0 +1,13 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package port
+
+// TokenIssuer is implemented by user repositories that verify JWT access
+// tokens of a single issuer. TokenIssuer returns its iss value and
+// TokenAudience the aud tokens must carry, both empty when token
+// verification isn't configured.
+type TokenIssuer interface {
+	TokenIssuer() string
+	TokenAudience() string
+}
@@ -0,0 +1,17 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package port
+
+import (
+	"context"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+)
+
+// LookupRepairer is implemented by user repositories that keep their own
+// lookup keys. RepairLookups points every key claimed by a single user at
+// that user and reports the keys claimed by several.
+type LookupRepairer interface {
+	RepairLookups(ctx context.Context) (model.LookupRepairResult, error)
+}
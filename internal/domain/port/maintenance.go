@@ -0,0 +1,18 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package port
+
+import (
+	"context"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+)
+
+// MaintenanceStore keeps the maintenance mode shared by every replica, so a
+// replica started after it was switched comes up in it
+type MaintenanceStore interface {
+	// LoadMaintenance returns the stored mode, nil when it was never switched
+	LoadMaintenance(ctx context.Context) (*model.MaintenanceState, error)
+	SaveMaintenance(ctx context.Context, state model.MaintenanceState) error
+}
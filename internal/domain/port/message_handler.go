@@ -9,6 +9,90 @@ import "context"
 type MessageHandler interface {
 	UserHandler
 	ImpersonationMessageHandler
+	AdminMessageHandler
+	TokenMessageHandler
+	ServiceAccountMessageHandler
+	PersonalAccessTokenMessageHandler
+	SessionMessageHandler
+	SchemaMessageHandler
+	EmailOrganizationMessageHandler
+	ConsentMessageHandler
+	AttestationMessageHandler
+	AffiliationMessageHandler
+}
+
+// AffiliationMessageHandler defines the behavior of the employment history handlers
+type AffiliationMessageHandler interface {
+	AddAffiliation(ctx context.Context, msg TransportMessenger) ([]byte, error)
+	EndAffiliation(ctx context.Context, msg TransportMessenger) ([]byte, error)
+	ListAffiliations(ctx context.Context, msg TransportMessenger) ([]byte, error)
+}
+
+// AttestationMessageHandler defines the behavior of the verified profile field handlers
+type AttestationMessageHandler interface {
+	RecordAttestation(ctx context.Context, msg TransportMessenger) ([]byte, error)
+	RevokeAttestation(ctx context.Context, msg TransportMessenger) ([]byte, error)
+}
+
+// ConsentMessageHandler defines the behavior of the policy acceptance handlers
+type ConsentMessageHandler interface {
+	RecordConsent(ctx context.Context, msg TransportMessenger) ([]byte, error)
+	ConsentStatus(ctx context.Context, msg TransportMessenger) ([]byte, error)
+}
+
+// EmailOrganizationMessageHandler defines the behavior of the email domain to organization handlers
+type EmailOrganizationMessageHandler interface {
+	EmailOrganization(ctx context.Context, msg TransportMessenger) ([]byte, error)
+	SetEmailOrganization(ctx context.Context, msg TransportMessenger) ([]byte, error)
+	DeleteEmailOrganization(ctx context.Context, msg TransportMessenger) ([]byte, error)
+	ListEmailOrganizations(ctx context.Context, msg TransportMessenger) ([]byte, error)
+}
+
+// SchemaMessageHandler defines the behavior of the schema discovery handler
+type SchemaMessageHandler interface {
+	Schemas(ctx context.Context, msg TransportMessenger) ([]byte, error)
+}
+
+// SessionMessageHandler defines the behavior of the session listing and revocation handlers
+type SessionMessageHandler interface {
+	ListSessions(ctx context.Context, msg TransportMessenger) ([]byte, error)
+	RevokeSession(ctx context.Context, msg TransportMessenger) ([]byte, error)
+}
+
+// PersonalAccessTokenMessageHandler defines the behavior of the personal access token handlers
+type PersonalAccessTokenMessageHandler interface {
+	CreatePersonalAccessToken(ctx context.Context, msg TransportMessenger) ([]byte, error)
+	ListPersonalAccessTokens(ctx context.Context, msg TransportMessenger) ([]byte, error)
+	RevokePersonalAccessToken(ctx context.Context, msg TransportMessenger) ([]byte, error)
+	ValidatePersonalAccessToken(ctx context.Context, msg TransportMessenger) ([]byte, error)
+}
+
+// ServiceAccountMessageHandler defines the behavior of the service account management handlers
+type ServiceAccountMessageHandler interface {
+	CreateServiceAccount(ctx context.Context, msg TransportMessenger) ([]byte, error)
+	ListServiceAccounts(ctx context.Context, msg TransportMessenger) ([]byte, error)
+	RotateServiceAccount(ctx context.Context, msg TransportMessenger) ([]byte, error)
+}
+
+// AdminMessageHandler defines the behavior of the operational (admin) handlers
+type AdminMessageHandler interface {
+	AdminStats(ctx context.Context, msg TransportMessenger) ([]byte, error)
+	AdminSelfTest(ctx context.Context, msg TransportMessenger) ([]byte, error)
+	RevokeToken(ctx context.Context, msg TransportMessenger) ([]byte, error)
+	AdminConfig(ctx context.Context, msg TransportMessenger) ([]byte, error)
+	ImportUsers(ctx context.Context, msg TransportMessenger) ([]byte, error)
+	UserImportStatus(ctx context.Context, msg TransportMessenger) ([]byte, error)
+	ExportUsers(ctx context.Context, msg TransportMessenger) ([]byte, error)
+	MergeUsers(ctx context.Context, msg TransportMessenger) ([]byte, error)
+	AdminStaticCacheFlush(ctx context.Context, msg TransportMessenger) ([]byte, error)
+	AdminReindex(ctx context.Context, msg TransportMessenger) ([]byte, error)
+	AdminMaintenance(ctx context.Context, msg TransportMessenger) ([]byte, error)
+}
+
+// TokenMessageHandler defines the behavior of the access token handlers
+type TokenMessageHandler interface {
+	CheckTokenScope(ctx context.Context, msg TransportMessenger) ([]byte, error)
+	CheckStepUp(ctx context.Context, msg TransportMessenger) ([]byte, error)
 }
 
 // UserHandler defines the behavior of the user domain handlers
@@ -19,6 +103,13 @@ type UserHandler interface {
 	UserLinkHandler
 	PasswordManagementHandler
 	AliasMessageHandler
+	PermissionMessageHandler
+}
+
+// PermissionMessageHandler defines the behavior of the permission check domain handlers
+type PermissionMessageHandler interface {
+	HasPermission(ctx context.Context, msg TransportMessenger) ([]byte, error)
+	HasPermissionBatch(ctx context.Context, msg TransportMessenger) ([]byte, error)
 }
 
 // AliasMessageHandler defines the behavior of the alias management domain handlers.
@@ -29,6 +120,7 @@ type AliasMessageHandler interface {
 // UserReadHandler defines the behavior of the user read/lookup domain handlers
 type UserReaderHandler interface {
 	GetUserMetadata(ctx context.Context, msg TransportMessenger) ([]byte, error)
+	GetUserPreferences(ctx context.Context, msg TransportMessenger) ([]byte, error)
 	GetUserEmails(ctx context.Context, msg TransportMessenger) ([]byte, error)
 	ListIdentities(ctx context.Context, msg TransportMessenger) ([]byte, error)
 }
@@ -36,13 +128,17 @@ type UserReaderHandler interface {
 // UserLookupHandler defines the behavior of the user lookup domain handlers
 type UserLookupHandler interface {
 	EmailToUsername(ctx context.Context, msg TransportMessenger) ([]byte, error)
+	EmailToUsernameBatch(ctx context.Context, msg TransportMessenger) ([]byte, error)
 	EmailToSub(ctx context.Context, msg TransportMessenger) ([]byte, error)
 	UsernameToSub(ctx context.Context, msg TransportMessenger) ([]byte, error)
+	SubToUsername(ctx context.Context, msg TransportMessenger) ([]byte, error)
+	SearchUsers(ctx context.Context, msg TransportMessenger) ([]byte, error)
 }
 
 // UserWriteHandler defines the behavior of the user write domain handlers
 type UserWriteHandler interface {
 	UpdateUser(ctx context.Context, msg TransportMessenger) ([]byte, error)
+	UpdateUserPreferences(ctx context.Context, msg TransportMessenger) ([]byte, error)
 	SetPrimaryEmail(ctx context.Context, msg TransportMessenger) ([]byte, error)
 }
 
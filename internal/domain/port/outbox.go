@@ -0,0 +1,24 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package port
+
+import (
+	"context"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+)
+
+// Outbox persists the events waiting to be published, shared by every
+// replica
+type Outbox interface {
+	// Add stores a new event, setting its revision
+	Add(ctx context.Context, event *model.OutboxEvent) error
+	// Pending returns up to limit stored events, oldest first
+	Pending(ctx context.Context, limit int) ([]*model.OutboxEvent, error)
+	// Update replaces an event unless it changed since it was read at its
+	// revision, reporting whether it did and setting the new revision
+	Update(ctx context.Context, event *model.OutboxEvent) (bool, error)
+	// Delete removes a published event
+	Delete(ctx context.Context, event *model.OutboxEvent) error
+}
@@ -0,0 +1,16 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package port
+
+import (
+	"context"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+)
+
+// PermissionReader is implemented by user repositories that can report the
+// roles and permissions assigned to a user
+type PermissionReader interface {
+	UserPermissions(ctx context.Context, userID string) (*model.UserPermissions, error)
+}
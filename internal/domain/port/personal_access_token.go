@@ -0,0 +1,20 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package port
+
+import (
+	"context"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+)
+
+// PersonalAccessTokenStore persists personal access tokens and indexes them by user
+type PersonalAccessTokenStore interface {
+	CreateToken(ctx context.Context, token *model.PersonalAccessToken) error
+	// GetToken returns a NotFound error for unknown ids
+	GetToken(ctx context.Context, id string) (*model.PersonalAccessToken, error)
+	ListTokens(ctx context.Context, userID string) ([]*model.PersonalAccessToken, error)
+	UpdateToken(ctx context.Context, token *model.PersonalAccessToken) error
+	DeleteToken(ctx context.Context, token *model.PersonalAccessToken) error
+}
@@ -0,0 +1,16 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package port
+
+import (
+	"context"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+)
+
+// PayloadQuarantine stores the request payloads that repeatedly panic their
+// handler, for inspection
+type PayloadQuarantine interface {
+	Quarantine(ctx context.Context, payload *model.QuarantinedPayload) error
+}
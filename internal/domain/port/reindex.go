@@ -0,0 +1,19 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package port
+
+import (
+	"context"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+)
+
+// LookupReindexer is implemented by user repositories that keep their own
+// email and sub lookup keys. ReindexLookups rewrites them with the current
+// normalization and removes keys built by earlier versions or left without a
+// user. progress, when not nil, is called every few users and after each
+// failure.
+type LookupReindexer interface {
+	ReindexLookups(ctx context.Context, progress func(model.ReindexProgress)) (model.ReindexResult, error)
+}
@@ -0,0 +1,24 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package port
+
+import (
+	"context"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+)
+
+// UserSearcher is implemented by user repositories that can find users by a
+// fragment of their username, email or name. SearchUserCandidates returns up
+// to limit users that may match query; ranking is left to the caller.
+type UserSearcher interface {
+	SearchUserCandidates(ctx context.Context, query string, limit int) ([]*model.User, error)
+}
+
+// UserExporter is implemented by user repositories that can list every user
+// in bulk, e.g. to build an in-memory index. ExportUsers may be slow and is
+// meant for background jobs, not request paths.
+type UserExporter interface {
+	ExportUsers(ctx context.Context) ([]*model.User, error)
+}
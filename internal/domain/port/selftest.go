@@ -0,0 +1,16 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package port
+
+import (
+	"context"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+)
+
+// SelfTester is implemented by components that can check their own health
+// at runtime for admin.selftest
+type SelfTester interface {
+	SelfTest(ctx context.Context) []model.ConfigCheck
+}
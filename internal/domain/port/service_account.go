@@ -0,0 +1,18 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package port
+
+import (
+	"context"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+)
+
+// ServiceAccountManager is implemented by user repositories that can manage
+// service accounts. Only accounts created through it are listed or rotated.
+type ServiceAccountManager interface {
+	CreateServiceAccount(ctx context.Context, account *model.ServiceAccount) (*model.ServiceAccountCredentials, error)
+	ListServiceAccounts(ctx context.Context) ([]*model.ServiceAccount, error)
+	RotateServiceAccountSecret(ctx context.Context, clientID, rotatedBy string) (*model.ServiceAccountCredentials, error)
+}
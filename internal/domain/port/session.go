@@ -0,0 +1,21 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package port
+
+import (
+	"context"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+)
+
+// SessionManager lists and revokes the sessions and refresh tokens of a user.
+// It is implemented by providers that expose their session storage.
+type SessionManager interface {
+	ListSessions(ctx context.Context, userID string) ([]*model.UserSession, error)
+	// RevokeSession revokes one session or refresh token of the user. It
+	// returns NotFound when sessionID doesn't belong to the user.
+	RevokeSession(ctx context.Context, userID, sessionType, sessionID string) error
+	// RevokeAllSessions revokes every session and refresh token of the user
+	RevokeAllSessions(ctx context.Context, userID string) error
+}
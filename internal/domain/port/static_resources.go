@@ -0,0 +1,14 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package port
+
+import "context"
+
+// StaticResourceCache is implemented by providers caching the resources of
+// their tenant that rarely change, such as Auth0 roles and connections
+type StaticResourceCache interface {
+	// FlushStaticResources drops the cached resources of kinds, every kind
+	// when none is named, and returns the kinds that had cached data
+	FlushStaticResources(ctx context.Context, kinds ...string) ([]string, error)
+}
@@ -0,0 +1,31 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package port
+
+import (
+	"context"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+)
+
+// InstanceStatsProvider returns the stats of the running replica
+type InstanceStatsProvider interface {
+	InstanceStats(ctx context.Context) model.InstanceStats
+}
+
+// RuntimeConfigProvider returns the effective configuration of the running replica
+type RuntimeConfigProvider interface {
+	RuntimeConfig(ctx context.Context) model.RuntimeConfig
+}
+
+// ProviderStatsReporter is implemented by user repositories that track their
+// calls to the upstream identity provider
+type ProviderStatsReporter interface {
+	ProviderStats() model.ProviderStats
+}
+
+// CacheStatsReporter is implemented by caches that track their hit rate
+type CacheStatsReporter interface {
+	CacheStats() model.CacheStats
+}
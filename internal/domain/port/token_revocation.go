@@ -0,0 +1,23 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package port
+
+import (
+	"context"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+)
+
+// TokenRevocationChecker reports whether an access token has been revoked.
+// It is called on every verification, so implementations answer from memory.
+type TokenRevocationChecker interface {
+	IsRevoked(jti, sub string, issuedAt *time.Time) bool
+}
+
+// TokenRevocationList records revocations and shares them across replicas
+type TokenRevocationList interface {
+	TokenRevocationChecker
+	Revoke(ctx context.Context, revocation *model.TokenRevocation) error
+}
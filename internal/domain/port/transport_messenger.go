@@ -3,9 +3,49 @@
 
 package port
 
+import "context"
+
 // TransportMessenger represents the behavior of a message that can be sent to the committee API.
 type TransportMessenger interface {
 	Subject() string
 	Data() []byte
 	Respond(data []byte) error
 }
+
+// CallerIdentifier is implemented by the messengers of transports that can
+// tell the requests of one client apart from those of others
+type CallerIdentifier interface {
+	// Caller returns an identifier shared by the requests of the client that
+	// sent the message, empty when the transport can't tell
+	Caller() string
+}
+
+// Handler handles a request received over the transport, replying through msg
+type Handler interface {
+	Handle(ctx context.Context, msg TransportMessenger)
+}
+
+// HandlerFunc adapts a function to a Handler
+type HandlerFunc func(ctx context.Context, msg TransportMessenger)
+
+// Handle calls f
+func (f HandlerFunc) Handle(ctx context.Context, msg TransportMessenger) {
+	f(ctx, msg)
+}
+
+// overloadedKey is the context key of requests refused for lack of capacity
+type overloadedKey struct{}
+
+// WithOverloaded marks ctx as the context of a request the transport has no
+// capacity left for. The handler replies a refusal instead of handling it,
+// so the refusal is shaped like every other reply.
+func WithOverloaded(ctx context.Context) context.Context {
+	return context.WithValue(ctx, overloadedKey{}, true)
+}
+
+// Overloaded reports whether the request of ctx is to be refused for lack of
+// capacity
+func Overloaded(ctx context.Context) bool {
+	overloaded, _ := ctx.Value(overloadedKey{}).(bool)
+	return overloaded
+}
@@ -0,0 +1,25 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package port
+
+import (
+	"context"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+)
+
+// UserReportExporter is implemented by user repositories that can export
+// chosen attributes of every user in bulk, for reporting. Unlike
+// UserExporter, the attributes are the caller's and the export is returned
+// as the file the identity provider produced.
+type UserReportExporter interface {
+	// ExportUserReport runs an export job and returns its file once done
+	ExportUserReport(ctx context.Context, export *model.UserExport) (*model.UserExportFile, error)
+}
+
+// UserExportStore keeps finished exports for reporting tools to download,
+// so they don't need credentials of the identity provider
+type UserExportStore interface {
+	Put(ctx context.Context, name string, file *model.UserExportFile) (*model.UserExportObject, error)
+}
@@ -0,0 +1,20 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package port
+
+import (
+	"context"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+)
+
+// UserImporter is implemented by user repositories that can import users in
+// bulk, as asynchronous jobs of the identity provider
+type UserImporter interface {
+	// ImportUsers starts a job importing the users of the manifest
+	ImportUsers(ctx context.Context, manifest *model.UserImport) (*model.UserImportJob, error)
+	// UserImportJob returns the progress of a job, with the records it
+	// rejected once it is done
+	UserImportJob(ctx context.Context, jobID string) (*model.UserImportJob, error)
+}
@@ -0,0 +1,24 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package port
+
+import (
+	"context"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+)
+
+// UserMerger is implemented by user repositories that can fold a duplicate
+// account into another one, so the person signs in to a single account with
+// the identities and emails of both
+type UserMerger interface {
+	MergeUsers(ctx context.Context, merge *model.UserMerge) error
+}
+
+// UserMergeHistory records the merges that were run, by the sub of the
+// account merged away
+type UserMergeHistory interface {
+	Record(ctx context.Context, record *model.UserMergeRecord) error
+	Get(ctx context.Context, oldSub string) (*model.UserMergeRecord, error)
+}
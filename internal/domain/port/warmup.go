@@ -0,0 +1,26 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package port
+
+import "context"
+
+// Warmer is implemented by components that load resources lazily, on the
+// first request needing them, and can load them ahead of traffic instead
+type Warmer interface {
+	WarmUp(ctx context.Context) error
+}
+
+// HotUserStore keeps the list of the users read the most across restarts,
+// so a new replica preloads them into its cache
+type HotUserStore interface {
+	LoadHotUsers(ctx context.Context) ([]string, error)
+	SaveHotUsers(ctx context.Context, userIDs []string) error
+}
+
+// SnapshotStore keeps the latest snapshot of an in-memory cache across
+// restarts. LoadSnapshot returns nil before the first save.
+type SnapshotStore interface {
+	LoadSnapshot(ctx context.Context) ([]byte, error)
+	SaveSnapshot(ctx context.Context, snapshot []byte) error
+}
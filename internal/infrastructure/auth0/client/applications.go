@@ -0,0 +1,157 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// AppTypeNonInteractive is the application type of machine-to-machine applications
+const AppTypeNonInteractive = "non_interactive"
+
+// applicationListFields are the fields requested when listing applications,
+// leaving out the client secret
+const applicationListFields = "client_id,name,description,app_type,client_metadata"
+
+// Application is an Auth0 application (a "client" in the Management API)
+type Application struct {
+	ClientID     string   `json:"client_id,omitempty"`
+	ClientSecret string   `json:"client_secret,omitempty"`
+	Name         string   `json:"name,omitempty"`
+	Description  string   `json:"description,omitempty"`
+	AppType      string   `json:"app_type,omitempty"`
+	GrantTypes   []string `json:"grant_types,omitempty"`
+	// ClientMetadata holds string values only, at most 10 keys
+	ClientMetadata map[string]string `json:"client_metadata,omitempty"`
+}
+
+// ClientGrant allows an application to request tokens for an API
+type ClientGrant struct {
+	ID       string   `json:"id,omitempty"`
+	ClientID string   `json:"client_id"`
+	Audience string   `json:"audience"`
+	Scope    []string `json:"scope"`
+}
+
+type applicationMetadataBody struct {
+	ClientMetadata map[string]string `json:"client_metadata"`
+}
+
+// CreateApplication creates an application and returns it with its client secret
+func (c *Client) CreateApplication(ctx context.Context, token string, application Application) (*Application, error) {
+	var created Application
+	err := c.Do(ctx, Request{
+		Method:      http.MethodPost,
+		Path:        managementPath("clients"),
+		Token:       token,
+		Body:        application,
+		Description: "create application",
+	}, &created)
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// GetApplication returns the application with clientID, without its secret
+func (c *Client) GetApplication(ctx context.Context, token, clientID string) (*Application, error) {
+	var application Application
+	err := c.Do(ctx, Request{
+		Method:      http.MethodGet,
+		Path:        managementPath("clients", clientID),
+		Query:       url.Values{"fields": {applicationListFields}, "include_fields": {"true"}},
+		Token:       token,
+		Description: "get application",
+	}, &application)
+	if err != nil {
+		return nil, err
+	}
+	return &application, nil
+}
+
+// ListApplications returns one page of the applications of appType, without their secrets
+func (c *Client) ListApplications(ctx context.Context, token, appType string, page Page) ([]Application, error) {
+	var applications []Application
+	err := c.Do(ctx, Request{
+		Method: http.MethodGet,
+		Path:   managementPath("clients"),
+		Query: page.apply(url.Values{
+			"app_type":       {appType},
+			"fields":         {applicationListFields},
+			"include_fields": {"true"},
+		}),
+		Token:       token,
+		Description: "list applications",
+	}, &applications)
+	return applications, err
+}
+
+// UpdateApplicationMetadata replaces the client metadata of the application with clientID
+func (c *Client) UpdateApplicationMetadata(ctx context.Context, token, clientID string, metadata map[string]string) error {
+	return c.Do(ctx, Request{
+		Method:      http.MethodPatch,
+		Path:        managementPath("clients", clientID),
+		Token:       token,
+		Body:        applicationMetadataBody{ClientMetadata: metadata},
+		Description: "update application metadata",
+		Idempotent:  true,
+	}, nil)
+}
+
+// RotateApplicationSecret replaces the client secret of the application with
+// clientID and returns the application with the new secret
+func (c *Client) RotateApplicationSecret(ctx context.Context, token, clientID string) (*Application, error) {
+	var application Application
+	err := c.Do(ctx, Request{
+		Method:      http.MethodPost,
+		Path:        managementPath("clients", clientID, "rotate-secret"),
+		Token:       token,
+		Description: "rotate application secret",
+	}, &application)
+	if err != nil {
+		return nil, err
+	}
+	return &application, nil
+}
+
+// DeleteApplication deletes the application with clientID
+func (c *Client) DeleteApplication(ctx context.Context, token, clientID string) error {
+	return c.Do(ctx, Request{
+		Method:      http.MethodDelete,
+		Path:        managementPath("clients", clientID),
+		Token:       token,
+		Description: "delete application",
+	}, nil)
+}
+
+// CreateClientGrant grants an application access to an API
+func (c *Client) CreateClientGrant(ctx context.Context, token string, grant ClientGrant) error {
+	return c.Do(ctx, Request{
+		Method:      http.MethodPost,
+		Path:        managementPath("client-grants"),
+		Token:       token,
+		Body:        grant,
+		Description: "create client grant",
+	}, nil)
+}
+
+// ListClientGrants returns one page of the client grants of the application
+// with clientID, or of every application when clientID is empty
+func (c *Client) ListClientGrants(ctx context.Context, token, clientID string, page Page) ([]ClientGrant, error) {
+	query := url.Values{}
+	if clientID != "" {
+		query.Set("client_id", clientID)
+	}
+	var grants []ClientGrant
+	err := c.Do(ctx, Request{
+		Method:      http.MethodGet,
+		Path:        managementPath("client-grants"),
+		Query:       page.apply(query),
+		Token:       token,
+		Description: "list client grants",
+	}, &grants)
+	return grants, err
+}
@@ -0,0 +1,250 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package client is a typed client for the Auth0 Management API. It owns URL
+// building, authentication headers, pagination, rate-limit handling and error
+// decoding, so the adapter only deals in endpoints and payloads.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/httpclient"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/redaction"
+)
+
+// ManagementAPIPath is the Management API v2 base path
+const ManagementAPIPath = "/api/v2/"
+
+const (
+	defaultMaxRateLimitWait = 5 * time.Second
+	defaultRateLimitRetries = 1
+)
+
+// Client calls the Auth0 Management API of one tenant
+type Client struct {
+	baseURL    *url.URL
+	httpClient *httpclient.Client
+
+	// maxRateLimitWait is the longest reset time a rate-limited call waits
+	// for before retrying; longer resets fail fast with the 429
+	maxRateLimitWait time.Duration
+	rateLimitRetries int
+	sleep            func(ctx context.Context, d time.Duration) error
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithMaxRateLimitWait sets the longest rate-limit reset a call waits out
+// before retrying. Zero disables waiting.
+func WithMaxRateLimitWait(d time.Duration) Option {
+	return func(c *Client) {
+		c.maxRateLimitWait = d
+	}
+}
+
+// WithBaseURL overrides the https://<domain> base URL, e.g. for a custom domain proxy
+func WithBaseURL(base *url.URL) Option {
+	return func(c *Client) {
+		c.baseURL = base
+	}
+}
+
+// Request describes one API call
+type Request struct {
+	Method string
+	// Path is resolved against the tenant URL and may carry a query string
+	Path  string
+	Query url.Values
+	// Token is sent as a bearer token; empty sends no Authorization header
+	Token string
+	Body  any
+	// RawBody is sent as is, with the ContentType header, instead of a
+	// JSON-encoded Body, e.g. for multipart uploads
+	RawBody     []byte
+	ContentType string
+	// SensitiveBody replaces the request body with [REDACTED] in debug logs
+	SensitiveBody bool
+	// Idempotent marks a POST or PATCH that is safe to retry after a server
+	// error, as sending it again leaves the tenant as sending it once would
+	Idempotent bool
+	// Description names the call in logs
+	Description string
+}
+
+// Do performs req and decodes a JSON response into out, when out is non-nil.
+// Non-2xx responses are returned as *Error; a rate-limited call whose reset
+// is within the configured wait is retried once the limit resets.
+func (c *Client) Do(ctx context.Context, req Request, out any) error {
+	for attempt := 0; ; attempt++ {
+		err := c.do(ctx, req, out)
+
+		apiErr, ok := err.(*Error)
+		if !ok || !apiErr.RateLimited() || attempt >= c.rateLimitRetries ||
+			apiErr.RetryAfter <= 0 || apiErr.RetryAfter > c.maxRateLimitWait {
+			return err
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < apiErr.RetryAfter {
+			// the limit resets after the caller stops waiting
+			return err
+		}
+
+		slog.WarnContext(ctx, "Auth0 rate limit reached, waiting for reset",
+			"description", req.Description,
+			"retry_after", apiErr.RetryAfter,
+		)
+		if errSleep := c.sleep(ctx, apiErr.RetryAfter); errSleep != nil {
+			return err
+		}
+	}
+}
+
+func (c *Client) do(ctx context.Context, req Request, out any) error {
+	target, err := c.resolve(req)
+	if err != nil {
+		return errs.NewValidation("invalid Auth0 API path", err)
+	}
+
+	var (
+		requestBody []byte
+		bodyReader  io.Reader
+	)
+	headers := map[string]string{"Accept": "application/json"}
+	switch {
+	case req.RawBody != nil:
+		requestBody = req.RawBody
+		bodyReader = bytes.NewReader(requestBody)
+		headers["Content-Type"] = req.ContentType
+	case req.Body != nil:
+		var release func()
+		requestBody, release, err = httpclient.EncodeJSON(req.Body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request body: %w", err)
+		}
+		defer release()
+		bodyReader = bytes.NewReader(requestBody)
+		headers["Content-Type"] = "application/json"
+	}
+	if token := strings.TrimSpace(req.Token); token != "" {
+		if !strings.HasPrefix(strings.ToLower(token), "bearer ") {
+			token = "Bearer " + token
+		}
+		headers["Authorization"] = token
+	}
+
+	if slog.Default().Enabled(ctx, slog.LevelDebug) {
+		loggedBody := redaction.RedactJWTs(string(requestBody))
+		if req.SensitiveBody {
+			loggedBody = "[REDACTED]"
+		}
+		slog.DebugContext(ctx, "calling Auth0 API",
+			"method", req.Method,
+			"url", target,
+			"request_body", loggedBody,
+			"description", req.Description,
+		)
+	}
+
+	if req.Idempotent {
+		ctx = httpclient.WithIdempotency(ctx, true)
+	}
+	response, err := c.httpClient.Request(ctx, req.Method, target, bodyReader, headers)
+	if err != nil {
+		if re, ok := err.(*httpclient.RetryableError); ok {
+			apiErr := decodeError(re.StatusCode, re.Headers, []byte(re.Message))
+			slog.ErrorContext(ctx, "Auth0 API returned error",
+				"status_code", apiErr.StatusCode,
+				"error_code", apiErr.Code,
+				"message", apiErr.Message,
+				"method", req.Method,
+				"description", req.Description,
+			)
+			return apiErr
+		}
+		slog.ErrorContext(ctx, "Auth0 API request failed",
+			"error", err,
+			"method", req.Method,
+			"description", req.Description,
+		)
+		return errs.NewUnexpected("Auth0 API request failed", err)
+	}
+
+	if out == nil || len(response.Body) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(response.Body, out); err != nil {
+		slog.ErrorContext(ctx, "failed to parse Auth0 API response",
+			"error", err,
+			"description", req.Description,
+		)
+		return errs.NewUnexpected("failed to parse Auth0 API response", err)
+	}
+	return nil
+}
+
+// resolve builds the absolute URL for req
+func (c *Client) resolve(req Request) (string, error) {
+	ref, err := url.Parse(req.Path)
+	if err != nil {
+		return "", err
+	}
+	if ref.IsAbs() || ref.Host != "" {
+		return "", fmt.Errorf("path %q must be relative to the tenant", req.Path)
+	}
+
+	target := c.baseURL.ResolveReference(ref)
+	if len(req.Query) > 0 {
+		query := target.Query()
+		for key, values := range req.Query {
+			query[key] = values
+		}
+		target.RawQuery = query.Encode()
+	}
+	return target.String(), nil
+}
+
+// managementPath joins escaped path segments under the Management API base
+func managementPath(segments ...string) string {
+	escaped := make([]string, len(segments))
+	for i, segment := range segments {
+		escaped[i] = url.PathEscape(segment)
+	}
+	return ManagementAPIPath + strings.Join(escaped, "/")
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// New creates a client for the tenant at domain, sending requests through
+// httpClient (which already retries transport errors and 5xx responses)
+func New(domain string, httpClient *httpclient.Client, opts ...Option) *Client {
+	c := &Client{
+		baseURL:          &url.URL{Scheme: "https", Host: domain, Path: "/"},
+		httpClient:       httpClient,
+		maxRateLimitWait: defaultMaxRateLimitWait,
+		rateLimitRetries: defaultRateLimitRetries,
+		sleep:            sleepContext,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
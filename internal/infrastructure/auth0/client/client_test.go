@@ -0,0 +1,301 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/httpclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// cannedResponse is one reply of a scriptedTransport
+type cannedResponse struct {
+	status  int
+	body    string
+	headers http.Header
+}
+
+// scriptedTransport replies with responses in order and records requests
+type scriptedTransport struct {
+	responses []cannedResponse
+	requests  []*http.Request
+}
+
+func (s *scriptedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	s.requests = append(s.requests, req)
+	reply := cannedResponse{status: http.StatusOK, body: "{}"}
+	if len(s.responses) > 0 {
+		reply, s.responses = s.responses[0], s.responses[1:]
+	}
+	headers := reply.headers
+	if headers == nil {
+		headers = http.Header{}
+	}
+	return &http.Response{
+		StatusCode: reply.status,
+		Body:       io.NopCloser(strings.NewReader(reply.body)),
+		Header:     headers,
+		Request:    req,
+	}, nil
+}
+
+func newTestClient(transport http.RoundTripper, opts ...Option) (*Client, *[]time.Duration) {
+	var slept []time.Duration
+	c := New("tenant.auth0.com", httpclient.NewClient(httpclient.Config{Transport: transport}), opts...)
+	c.sleep = func(_ context.Context, d time.Duration) error {
+		slept = append(slept, d)
+		return nil
+	}
+	return c, &slept
+}
+
+func TestClient_GetUser(t *testing.T) {
+	transport := &scriptedTransport{responses: []cannedResponse{
+		{status: http.StatusOK, body: `{"user_id":"auth0|abc/def"}`},
+	}}
+	c, _ := newTestClient(transport)
+
+	var user struct {
+		UserID string `json:"user_id"`
+	}
+	require.NoError(t, c.GetUser(context.Background(), "m2m-token", "auth0|abc/def", &user))
+
+	assert.Equal(t, "auth0|abc/def", user.UserID)
+	require.Len(t, transport.requests, 1)
+	req := transport.requests[0]
+	assert.Equal(t, "https://tenant.auth0.com/api/v2/users/auth0%7Cabc%2Fdef", req.URL.String())
+	assert.Equal(t, "Bearer m2m-token", req.Header.Get("Authorization"))
+}
+
+func TestClient_SearchUsers(t *testing.T) {
+	transport := &scriptedTransport{}
+	c, _ := newTestClient(transport)
+
+	err := c.SearchUsers(context.Background(), "token", SearchParams{
+		Query:  `email:"a@example.com"`,
+		Fields: []string{"user_id", "email"},
+		Page:   Page{Page: 2, PerPage: 50},
+	}, nil)
+	require.NoError(t, err)
+
+	query := transport.requests[0].URL.Query()
+	assert.Equal(t, "/api/v2/users", transport.requests[0].URL.Path)
+	assert.Equal(t, `email:"a@example.com"`, query.Get("q"))
+	assert.Equal(t, "v3", query.Get("search_engine"))
+	assert.Equal(t, "user_id,email", query.Get("fields"))
+	assert.Equal(t, "true", query.Get("include_fields"))
+	assert.Equal(t, "2", query.Get("page"))
+	assert.Equal(t, "50", query.Get("per_page"))
+}
+
+func TestClient_ImportUsers(t *testing.T) {
+	transport := &scriptedTransport{responses: []cannedResponse{
+		{status: http.StatusCreated, body: `{"id":"job_1","type":"users_import","status":"pending","connection_id":"con_db"}`},
+	}}
+	c, _ := newTestClient(transport)
+
+	job, err := c.ImportUsers(context.Background(), "token", UsersImportRequest{
+		ConnectionID: "con_db",
+		Users:        []byte(`[{"email":"zephyr@example.com"}]`),
+		Upsert:       true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "job_1", job.ID)
+
+	req := transport.requests[0]
+	assert.Equal(t, "/api/v2/jobs/users-imports", req.URL.Path)
+	require.NoError(t, req.ParseMultipartForm(1<<20))
+	assert.Equal(t, "con_db", req.FormValue("connection_id"))
+	assert.Equal(t, "true", req.FormValue("upsert"))
+	file, _, err := req.FormFile("users")
+	require.NoError(t, err)
+	users, err := io.ReadAll(file)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"email":"zephyr@example.com"}]`, string(users))
+}
+
+func TestClient_Do_RelativePathWithQuery(t *testing.T) {
+	transport := &scriptedTransport{}
+	c, _ := newTestClient(transport)
+
+	err := c.Do(context.Background(), Request{
+		Method: http.MethodGet,
+		Path:   ManagementAPIPath + "users-by-email?email=a%40example.com",
+	}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "a@example.com", transport.requests[0].URL.Query().Get("email"))
+	assert.Empty(t, transport.requests[0].Header.Get("Authorization"))
+
+	err = c.Do(context.Background(), Request{Method: http.MethodGet, Path: "https://evil.example.com/api/v2/users"}, nil)
+	assert.True(t, errors.As(err, &errs.Validation{}))
+	assert.Len(t, transport.requests, 1)
+}
+
+func TestClient_Do_ErrorDecoding(t *testing.T) {
+	tests := []struct {
+		name        string
+		response    cannedResponse
+		wantStatus  int
+		wantCode    string
+		wantMessage string
+	}{
+		{
+			name:        "auth0 json error",
+			response:    cannedResponse{status: http.StatusNotFound, body: `{"statusCode":404,"error":"Not Found","message":"The user does not exist.","errorCode":"inexistent_user"}`},
+			wantStatus:  http.StatusNotFound,
+			wantCode:    "inexistent_user",
+			wantMessage: "The user does not exist.",
+		},
+		{
+			name:        "plain text error",
+			response:    cannedResponse{status: http.StatusBadGateway, body: "upstream unavailable\n"},
+			wantStatus:  http.StatusBadGateway,
+			wantMessage: "upstream unavailable",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := newTestClient(&scriptedTransport{responses: []cannedResponse{tt.response}})
+
+			err := c.DeleteUser(context.Background(), "token", "auth0|abc")
+			var apiErr *Error
+			require.True(t, errors.As(err, &apiErr))
+			assert.Equal(t, tt.wantStatus, StatusCode(err))
+			assert.Equal(t, tt.wantCode, apiErr.Code)
+			assert.Equal(t, tt.wantMessage, Message(err))
+		})
+	}
+
+	t.Run("non-api error", func(t *testing.T) {
+		assert.Equal(t, -1, StatusCode(errors.New("dial tcp: timeout")))
+	})
+}
+
+func TestClient_Do_RateLimit(t *testing.T) {
+	rateLimited := cannedResponse{
+		status:  http.StatusTooManyRequests,
+		body:    `{"statusCode":429,"error":"Too Many Requests","message":"Global limit has been reached"}`,
+		headers: http.Header{"Retry-After": {"2"}},
+	}
+
+	t.Run("waits for reset and retries", func(t *testing.T) {
+		transport := &scriptedTransport{responses: []cannedResponse{rateLimited, {status: http.StatusNoContent}}}
+		c, slept := newTestClient(transport)
+
+		require.NoError(t, c.DeleteUser(context.Background(), "token", "auth0|abc"))
+		assert.Len(t, transport.requests, 2)
+		assert.Equal(t, []time.Duration{2 * time.Second}, *slept)
+	})
+
+	t.Run("fails fast when reset exceeds max wait", func(t *testing.T) {
+		transport := &scriptedTransport{responses: []cannedResponse{rateLimited}}
+		c, slept := newTestClient(transport, WithMaxRateLimitWait(time.Second))
+
+		err := c.DeleteUser(context.Background(), "token", "auth0|abc")
+		var apiErr *Error
+		require.True(t, errors.As(err, &apiErr))
+		assert.True(t, apiErr.RateLimited())
+		assert.Equal(t, 2*time.Second, apiErr.RetryAfter)
+		assert.Len(t, transport.requests, 1)
+		assert.Empty(t, *slept)
+	})
+
+	t.Run("gives up after one retry", func(t *testing.T) {
+		transport := &scriptedTransport{responses: []cannedResponse{rateLimited, rateLimited, rateLimited}}
+		c, _ := newTestClient(transport)
+
+		err := c.DeleteUser(context.Background(), "token", "auth0|abc")
+		assert.Equal(t, http.StatusTooManyRequests, StatusCode(err))
+		assert.Len(t, transport.requests, 2)
+	})
+}
+
+func TestClient_Do_RetriesIdempotentWrites(t *testing.T) {
+	serverError := cannedResponse{status: http.StatusInternalServerError, body: `{"statusCode":500}`}
+	newRetryingClient := func(transport http.RoundTripper) *Client {
+		return New("tenant.auth0.com", httpclient.NewClient(httpclient.Config{Transport: transport, MaxRetries: 1, RetryDelay: time.Millisecond}))
+	}
+
+	t.Run("a profile update is sent again", func(t *testing.T) {
+		transport := &scriptedTransport{responses: []cannedResponse{serverError, {status: http.StatusOK}}}
+		require.NoError(t, newRetryingClient(transport).UpdateUser(context.Background(), "token", "auth0|abc", map[string]string{"name": "Jane"}, nil))
+		assert.Len(t, transport.requests, 2)
+	})
+
+	t.Run("a user is not created twice", func(t *testing.T) {
+		transport := &scriptedTransport{responses: []cannedResponse{serverError, {status: http.StatusCreated}}}
+		err := newRetryingClient(transport).CreateUser(context.Background(), "token", map[string]string{"email": "jdoe@example.com"}, nil)
+		assert.Equal(t, http.StatusInternalServerError, StatusCode(err))
+		assert.Len(t, transport.requests, 1)
+	})
+}
+
+func TestRateLimitReset(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+
+	tests := []struct {
+		name    string
+		headers http.Header
+		want    time.Duration
+	}{
+		{name: "retry-after", headers: http.Header{"Retry-After": {"3"}}, want: 3 * time.Second},
+		{name: "x-ratelimit-reset", headers: http.Header{"X-Ratelimit-Reset": {strconv.FormatInt(now.Unix()+4, 10)}}, want: 4 * time.Second},
+		{name: "reset in the past", headers: http.Header{"X-Ratelimit-Reset": {strconv.FormatInt(now.Unix()-4, 10)}}, want: 0},
+		{name: "no headers", headers: http.Header{}, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, rateLimitReset(tt.headers, now))
+		})
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	pages := [][]int{{1, 2, 3}, {4, 5, 6}, {7}}
+	fetch := func(calls *[]Page) func(context.Context, Page) ([]int, error) {
+		return func(_ context.Context, page Page) ([]int, error) {
+			*calls = append(*calls, page)
+			if page.Page >= len(pages) {
+				return nil, nil
+			}
+			return pages[page.Page], nil
+		}
+	}
+
+	t.Run("stops at short page", func(t *testing.T) {
+		var calls []Page
+		items, err := Paginate(context.Background(), 3, 0, fetch(&calls))
+		require.NoError(t, err)
+		assert.Equal(t, []int{1, 2, 3, 4, 5, 6, 7}, items)
+		assert.Len(t, calls, 3)
+		assert.Equal(t, 3, calls[0].PerPage)
+	})
+
+	t.Run("stops at limit", func(t *testing.T) {
+		var calls []Page
+		items, err := Paginate(context.Background(), 3, 4, fetch(&calls))
+		require.NoError(t, err)
+		assert.Equal(t, []int{1, 2, 3, 4}, items)
+		assert.Len(t, calls, 2)
+	})
+
+	t.Run("propagates errors", func(t *testing.T) {
+		_, err := Paginate(context.Background(), 3, 0, func(context.Context, Page) ([]int, error) {
+			return nil, &Error{StatusCode: http.StatusForbidden}
+		})
+		assert.Equal(t, http.StatusForbidden, StatusCode(err))
+	})
+}
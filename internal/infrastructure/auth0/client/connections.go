@@ -0,0 +1,32 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// Connection is an Auth0 connection users sign in through
+type Connection struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name,omitempty"`
+	// Strategy is the identity provider of the connection, e.g. auth0 for
+	// a database connection, github or samlp
+	Strategy string `json:"strategy"`
+}
+
+// ListConnections returns one page of the connections of the tenant
+func (c *Client) ListConnections(ctx context.Context, token string, page Page) ([]Connection, error) {
+	var connections []Connection
+	err := c.Do(ctx, Request{
+		Method:      http.MethodGet,
+		Path:        managementPath("connections"),
+		Query:       page.apply(nil),
+		Token:       token,
+		Description: "list connections",
+	}, &connections)
+	return connections, err
+}
@@ -0,0 +1,116 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Error is a non-2xx response from Auth0, decoded from its JSON error body
+type Error struct {
+	StatusCode int    `json:"statusCode"`
+	Name       string `json:"error"`
+	Message    string `json:"message"`
+	// Code is Auth0's machine-readable error code, e.g. "inexistent_user"
+	Code string `json:"errorCode"`
+	// Description is the message of Authentication API errors, e.g. of /oauth/token
+	Description string `json:"error_description"`
+	// RetryAfter is how long until the rate limit resets, for 429 responses
+	RetryAfter time.Duration `json:"-"`
+}
+
+func (e *Error) Error() string {
+	message := e.Message
+	if message == "" {
+		message = e.Description
+	}
+	if message == "" {
+		message = e.Name
+	}
+	if message == "" {
+		message = http.StatusText(e.StatusCode)
+	}
+	return fmt.Sprintf("auth0 API error %d: %s", e.StatusCode, message)
+}
+
+// RateLimited reports whether the call was rejected by Auth0's rate limiter
+func (e *Error) RateLimited() bool {
+	return e.StatusCode == http.StatusTooManyRequests
+}
+
+// Blocked reports whether Auth0 refused the call because the user is
+// blocked, by an administrator or after too many failed logins
+func (e *Error) Blocked() bool {
+	if e.StatusCode != http.StatusUnauthorized && e.StatusCode != http.StatusForbidden {
+		return false
+	}
+	return IsBlockedMessage(e.Name, e.Description+" "+e.Message)
+}
+
+// IsBlockedMessage reports whether an Auth0 error name and message say the
+// user is blocked: "unauthorized" with "user is blocked", or the brute-force
+// protection's "too_many_attempts"
+func IsBlockedMessage(name, message string) bool {
+	return name == "too_many_attempts" ||
+		(name == "unauthorized" && strings.Contains(strings.ToLower(message), "blocked"))
+}
+
+// StatusCode returns the HTTP status of an API error, or -1 when err did not
+// come from an Auth0 response (e.g. a transport failure)
+func StatusCode(err error) int {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode
+	}
+	return -1
+}
+
+// Message returns Auth0's error message for API errors, and err's text otherwise
+func Message(err error) string {
+	var apiErr *Error
+	if errors.As(err, &apiErr) && apiErr.Message != "" {
+		return apiErr.Message
+	}
+	if apiErr != nil && apiErr.Description != "" {
+		return apiErr.Description
+	}
+	return err.Error()
+}
+
+// decodeError builds an *Error from a failed response. Bodies that aren't
+// Auth0 JSON errors (proxies, plain-text endpoints) are kept as the message.
+func decodeError(statusCode int, headers http.Header, body []byte) *Error {
+	apiErr := &Error{}
+	if err := json.Unmarshal(body, apiErr); err != nil {
+		apiErr = &Error{Message: strings.TrimSpace(string(body))}
+	}
+	apiErr.StatusCode = statusCode
+	if statusCode == http.StatusTooManyRequests {
+		apiErr.RetryAfter = rateLimitReset(headers, time.Now())
+	}
+	return apiErr
+}
+
+// rateLimitReset reads the wait until the rate limit resets from Retry-After
+// (seconds) or Auth0's X-RateLimit-Reset (unix time). Zero means unknown.
+func rateLimitReset(headers http.Header, now time.Time) time.Duration {
+	if headers == nil {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(headers.Get("Retry-After")); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if reset, err := strconv.ParseInt(headers.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		if wait := time.Unix(reset, 0).Sub(now); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
@@ -0,0 +1,188 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+)
+
+// Job statuses reported by Auth0
+const (
+	JobStatusPending   = "pending"
+	JobStatusCompleted = "completed"
+	JobStatusFailed    = "failed"
+)
+
+// Job is an asynchronous Management API job (exports, imports, verification emails)
+type Job struct {
+	ID        string `json:"id"`
+	Type      string `json:"type"`
+	Status    string `json:"status"`
+	CreatedAt string `json:"created_at,omitempty"`
+	// Location is the download URL of a completed users export
+	Location string `json:"location,omitempty"`
+	// PercentageDone is reported by long-running jobs while pending
+	PercentageDone int `json:"percentage_done,omitempty"`
+	// ConnectionID, ExternalID and Summary are reported by users imports
+	ConnectionID string         `json:"connection_id,omitempty"`
+	ExternalID   string         `json:"external_id,omitempty"`
+	Summary      *ImportSummary `json:"summary,omitempty"`
+}
+
+// ImportSummary counts the records of a completed users import
+type ImportSummary struct {
+	Total    int `json:"total"`
+	Inserted int `json:"inserted"`
+	Updated  int `json:"updated"`
+	Failed   int `json:"failed"`
+}
+
+// Done reports whether the job has finished, successfully or not
+func (j *Job) Done() bool {
+	return j.Status == JobStatusCompleted || j.Status == JobStatusFailed
+}
+
+// VerificationEmailRequest is the body of a verification email job
+type VerificationEmailRequest struct {
+	UserID   string `json:"user_id"`
+	ClientID string `json:"client_id,omitempty"`
+}
+
+// UsersExportRequest is the body of a users export job
+type UsersExportRequest struct {
+	ConnectionID string             `json:"connection_id,omitempty"`
+	Format       string             `json:"format,omitempty"`
+	Fields       []UsersExportField `json:"fields,omitempty"`
+	Limit        int                `json:"limit,omitempty"`
+}
+
+// UsersExportField selects one attribute of a users export
+type UsersExportField struct {
+	Name     string `json:"name"`
+	ExportAs string `json:"export_as,omitempty"`
+}
+
+// UsersImportRequest is the form of a users import job. Users is the JSON
+// array of the users to import.
+type UsersImportRequest struct {
+	ConnectionID        string
+	Users               []byte
+	Upsert              bool
+	ExternalID          string
+	SendCompletionEmail bool
+}
+
+// JobError is a record a users import rejected, with the reasons
+type JobError struct {
+	// User is the record as it was sent
+	User   map[string]any   `json:"user"`
+	Errors []JobErrorDetail `json:"errors"`
+}
+
+// JobErrorDetail is one reason a record was rejected
+type JobErrorDetail struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Path    string `json:"path,omitempty"`
+}
+
+// GetJob returns the job with jobID
+func (c *Client) GetJob(ctx context.Context, token, jobID string) (*Job, error) {
+	var job Job
+	if err := c.Do(ctx, Request{
+		Method:      http.MethodGet,
+		Path:        managementPath("jobs", jobID),
+		Token:       token,
+		Description: "get job",
+	}, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// SendVerificationEmail starts a job that emails the user a verification link
+func (c *Client) SendVerificationEmail(ctx context.Context, token string, request VerificationEmailRequest) (*Job, error) {
+	return c.createJob(ctx, token, "verification-email", request, "send verification email")
+}
+
+// ExportUsers starts a users export job
+func (c *Client) ExportUsers(ctx context.Context, token string, request UsersExportRequest) (*Job, error) {
+	return c.createJob(ctx, token, "users-exports", request, "export users")
+}
+
+// ImportUsers starts a users import job, uploading the users as a file
+func (c *Client) ImportUsers(ctx context.Context, token string, request UsersImportRequest) (*Job, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	fields := [][2]string{
+		{"connection_id", request.ConnectionID},
+		{"upsert", strconv.FormatBool(request.Upsert)},
+		{"send_completion_email", strconv.FormatBool(request.SendCompletionEmail)},
+	}
+	if request.ExternalID != "" {
+		fields = append(fields, [2]string{"external_id", request.ExternalID})
+	}
+	for _, field := range fields {
+		if err := form.WriteField(field[0], field[1]); err != nil {
+			return nil, err
+		}
+	}
+	file, err := form.CreateFormFile("users", "users.json")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := file.Write(request.Users); err != nil {
+		return nil, err
+	}
+	if err := form.Close(); err != nil {
+		return nil, err
+	}
+
+	var job Job
+	if err := c.Do(ctx, Request{
+		Method:        http.MethodPost,
+		Path:          managementPath("jobs", "users-imports"),
+		Token:         token,
+		RawBody:       body.Bytes(),
+		ContentType:   form.FormDataContentType(),
+		SensitiveBody: true,
+		Description:   "import users",
+	}, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// JobErrors returns the records a completed users import rejected; none
+// when every record was imported
+func (c *Client) JobErrors(ctx context.Context, token, jobID string) ([]JobError, error) {
+	var jobErrors []JobError
+	if err := c.Do(ctx, Request{
+		Method:      http.MethodGet,
+		Path:        managementPath("jobs", jobID, "errors"),
+		Token:       token,
+		Description: "get job errors",
+	}, &jobErrors); err != nil {
+		return nil, err
+	}
+	return jobErrors, nil
+}
+
+func (c *Client) createJob(ctx context.Context, token, kind string, body any, description string) (*Job, error) {
+	var job Job
+	if err := c.Do(ctx, Request{
+		Method:      http.MethodPost,
+		Path:        managementPath("jobs", kind),
+		Token:       token,
+		Body:        body,
+		Description: description,
+	}, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
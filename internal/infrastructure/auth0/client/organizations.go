@@ -0,0 +1,79 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// Organization is an Auth0 organization
+type Organization struct {
+	ID          string         `json:"id"`
+	Name        string         `json:"name"`
+	DisplayName string         `json:"display_name,omitempty"`
+	Metadata    map[string]any `json:"metadata,omitempty"`
+}
+
+// OrganizationMember is a user's membership in an organization
+type OrganizationMember struct {
+	UserID string `json:"user_id"`
+	Email  string `json:"email,omitempty"`
+	Name   string `json:"name,omitempty"`
+}
+
+type membersBody struct {
+	Members []string `json:"members"`
+}
+
+// ListOrganizations returns one page of the organizations of the tenant
+func (c *Client) ListOrganizations(ctx context.Context, token string, page Page) ([]Organization, error) {
+	var organizations []Organization
+	err := c.Do(ctx, Request{
+		Method:      http.MethodGet,
+		Path:        managementPath("organizations"),
+		Query:       page.apply(nil),
+		Token:       token,
+		Description: "list organizations",
+	}, &organizations)
+	return organizations, err
+}
+
+// UserOrganizations returns one page of the organizations the user with userID belongs to
+func (c *Client) UserOrganizations(ctx context.Context, token, userID string, page Page) ([]Organization, error) {
+	var organizations []Organization
+	err := c.Do(ctx, Request{
+		Method:      http.MethodGet,
+		Path:        managementPath("users", userID, "organizations"),
+		Query:       page.apply(nil),
+		Token:       token,
+		Description: "list user organizations",
+	}, &organizations)
+	return organizations, err
+}
+
+// OrganizationMembers returns one page of the members of the organization with orgID
+func (c *Client) OrganizationMembers(ctx context.Context, token, orgID string, page Page) ([]OrganizationMember, error) {
+	var members []OrganizationMember
+	err := c.Do(ctx, Request{
+		Method:      http.MethodGet,
+		Path:        managementPath("organizations", orgID, "members"),
+		Query:       page.apply(nil),
+		Token:       token,
+		Description: "list organization members",
+	}, &members)
+	return members, err
+}
+
+// AddOrganizationMembers adds the users with userIDs to the organization with orgID
+func (c *Client) AddOrganizationMembers(ctx context.Context, token, orgID string, userIDs []string) error {
+	return c.Do(ctx, Request{
+		Method:      http.MethodPost,
+		Path:        managementPath("organizations", orgID, "members"),
+		Token:       token,
+		Body:        membersBody{Members: userIDs},
+		Description: "add organization members",
+		Idempotent:  true,
+	}, nil)
+}
@@ -0,0 +1,63 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package client
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+)
+
+// MaxPerPage is the largest page size the Management API accepts
+const MaxPerPage = 100
+
+// Page selects one page of a list endpoint. Pages are zero-based.
+type Page struct {
+	Page    int
+	PerPage int
+}
+
+// apply sets the page and per_page query parameters
+func (p Page) apply(query url.Values) url.Values {
+	if query == nil {
+		query = url.Values{}
+	}
+	perPage := p.PerPage
+	if perPage <= 0 || perPage > MaxPerPage {
+		perPage = MaxPerPage
+	}
+	query.Set("page", strconv.Itoa(p.Page))
+	query.Set("per_page", strconv.Itoa(perPage))
+	return query
+}
+
+// Paginate calls fetch for successive pages of perPage items until a short
+// page is returned or limit items are collected. limit <= 0 collects every page.
+func Paginate[T any](ctx context.Context, perPage, limit int, fetch func(ctx context.Context, page Page) ([]T, error)) ([]T, error) {
+	if perPage <= 0 || perPage > MaxPerPage {
+		perPage = MaxPerPage
+	}
+
+	var items []T
+	for page := 0; limit <= 0 || len(items) < limit; page++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		results, err := fetch(ctx, Page{Page: page, PerPage: perPage})
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range results {
+			if limit > 0 && len(items) == limit {
+				break
+			}
+			items = append(items, item)
+		}
+		if len(results) < perPage {
+			break
+		}
+	}
+	return items, nil
+}
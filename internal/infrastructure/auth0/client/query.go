@@ -0,0 +1,113 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package client
+
+import (
+	"strings"
+)
+
+// luceneSpecial lists the characters the v3 search engine treats as syntax
+// outside of a quoted phrase. See
+// https://auth0.com/docs/manage-users/user-search/user-search-query-syntax
+const luceneSpecial = `+-&|!(){}[]^"~*?:\/ `
+
+// Query is a Lucene query for the v3 user search. Values passed to the
+// constructors are always escaped, so user input can't change the shape of
+// the query; field names are not and must come from code.
+//
+// The zero Query is empty and is dropped when combined with And or Or.
+type Query struct {
+	expr     string
+	compound bool
+}
+
+// Term matches field against value exactly, e.g. identities.user_id:"jdoe"
+func Term(field, value string) Query {
+	return Query{expr: field + `:"` + escapePhrase(value) + `"`}
+}
+
+// Prefix matches values of field starting with value, e.g. nickname:jdo*
+func Prefix(field, value string) Query {
+	return Query{expr: field + ":" + Escape(value) + "*"}
+}
+
+// Exists matches users that have any value for field
+func Exists(field string) Query {
+	return Query{expr: "_exists_:" + field}
+}
+
+// And matches users matching every non-empty query
+func And(queries ...Query) Query {
+	return join(" AND ", queries)
+}
+
+// Or matches users matching any non-empty query
+func Or(queries ...Query) Query {
+	return join(" OR ", queries)
+}
+
+// Not negates q. Not of an empty query is empty.
+func Not(q Query) Query {
+	if q.IsZero() {
+		return q
+	}
+	return Query{expr: "NOT " + q.group()}
+}
+
+// IsZero reports whether q is empty
+func (q Query) IsZero() bool {
+	return q.expr == ""
+}
+
+// String renders q for SearchParams.Query
+func (q Query) String() string {
+	return q.expr
+}
+
+// group parenthesises compound queries so they keep their meaning when nested
+func (q Query) group() string {
+	if q.compound {
+		return "(" + q.expr + ")"
+	}
+	return q.expr
+}
+
+func join(operator string, queries []Query) Query {
+	parts := make([]string, 0, len(queries))
+	var single Query
+	for _, q := range queries {
+		if q.IsZero() {
+			continue
+		}
+		single = q
+		parts = append(parts, q.group())
+	}
+	switch len(parts) {
+	case 0:
+		return Query{}
+	case 1:
+		return single
+	}
+	return Query{expr: strings.Join(parts, operator), compound: true}
+}
+
+// Escape backslash-escapes every Lucene special character in value for use
+// as an unquoted term
+func Escape(value string) string {
+	var b strings.Builder
+	b.Grow(len(value))
+	for _, r := range value {
+		if strings.ContainsRune(luceneSpecial, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// escapePhrase escapes value for use inside double quotes, where only the
+// quote and the backslash are significant
+func escapePhrase(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value)
+}
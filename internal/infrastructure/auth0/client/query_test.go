@@ -0,0 +1,85 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuery_String(t *testing.T) {
+	tests := []struct {
+		name  string
+		query Query
+		want  string
+	}{
+		{
+			name:  "term",
+			query: Term("identities.user_id", "jdoe"),
+			want:  `identities.user_id:"jdoe"`,
+		},
+		{
+			name:  "term escapes quotes and backslashes",
+			query: Term("name", `Jane "JD" Doe\`),
+			want:  `name:"Jane \"JD\" Doe\\"`,
+		},
+		{
+			name:  "term keeps operators inside the phrase",
+			query: Term("identities.user_id", `jdoe" OR email:*`),
+			want:  `identities.user_id:"jdoe\" OR email:*"`,
+		},
+		{
+			name:  "prefix escapes special characters",
+			query: Prefix("nickname", "j(d)o e*"),
+			want:  `nickname:j\(d\)o\ e\**`,
+		},
+		{
+			name:  "exists",
+			query: Exists("user_metadata.name"),
+			want:  `_exists_:user_metadata.name`,
+		},
+		{
+			name: "and",
+			query: And(
+				Term("identities.user_id", "jdoe"),
+				Term("identities.connection", "Username-Password-Authentication"),
+			),
+			want: `identities.user_id:"jdoe" AND identities.connection:"Username-Password-Authentication"`,
+		},
+		{
+			name:  "nested clauses are grouped",
+			query: And(Or(Term("name", "a"), Term("nickname", "a")), Not(Term("blocked", "true"))),
+			want:  `(name:"a" OR nickname:"a") AND NOT blocked:"true"`,
+		},
+		{
+			name:  "not groups compound queries",
+			query: Not(Or(Term("a", "1"), Term("b", "2"))),
+			want:  `NOT (a:"1" OR b:"2")`,
+		},
+		{
+			name:  "empty queries are dropped",
+			query: And(Query{}, Term("email", "a@example.com"), Or()),
+			want:  `email:"a@example.com"`,
+		},
+		{
+			name:  "all empty",
+			query: Or(Query{}, Not(Query{})),
+			want:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.query.String())
+		})
+	}
+}
+
+func TestEscape(t *testing.T) {
+	assert.Equal(t, "jdoe", Escape("jdoe"))
+	assert.Equal(t, `a\&\&b\|\|c`, Escape("a&&b||c"))
+	assert.Equal(t, `\+\-\!\{\}\[\]\^\"\~\?\:\\\/`, Escape(`+-!{}[]^"~?:\/`))
+	assert.Equal(t, "jöe@example.com", Escape("jöe@example.com"))
+}
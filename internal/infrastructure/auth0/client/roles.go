@@ -0,0 +1,91 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// Role is an Auth0 RBAC role
+type Role struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// Permission is an Auth0 RBAC permission held by a user, directly or through a role
+type Permission struct {
+	PermissionName           string `json:"permission_name"`
+	ResourceServerIdentifier string `json:"resource_server_identifier"`
+	ResourceServerName       string `json:"resource_server_name,omitempty"`
+	Description              string `json:"description,omitempty"`
+}
+
+type rolesBody struct {
+	Roles []string `json:"roles"`
+}
+
+// ListRoles returns one page of the roles defined in the tenant
+func (c *Client) ListRoles(ctx context.Context, token string, page Page) ([]Role, error) {
+	var roles []Role
+	err := c.Do(ctx, Request{
+		Method:      http.MethodGet,
+		Path:        managementPath("roles"),
+		Query:       page.apply(nil),
+		Token:       token,
+		Description: "list roles",
+	}, &roles)
+	return roles, err
+}
+
+// UserRoles returns one page of the roles assigned to the user with userID
+func (c *Client) UserRoles(ctx context.Context, token, userID string, page Page) ([]Role, error) {
+	var roles []Role
+	err := c.Do(ctx, Request{
+		Method:      http.MethodGet,
+		Path:        managementPath("users", userID, "roles"),
+		Query:       page.apply(nil),
+		Token:       token,
+		Description: "list user roles",
+	}, &roles)
+	return roles, err
+}
+
+// UserPermissions returns one page of the permissions held by the user with
+// userID, including those granted through roles
+func (c *Client) UserPermissions(ctx context.Context, token, userID string, page Page) ([]Permission, error) {
+	var permissions []Permission
+	err := c.Do(ctx, Request{
+		Method:      http.MethodGet,
+		Path:        managementPath("users", userID, "permissions"),
+		Query:       page.apply(nil),
+		Token:       token,
+		Description: "list user permissions",
+	}, &permissions)
+	return permissions, err
+}
+
+// AssignRoles assigns the roles with roleIDs to the user with userID
+func (c *Client) AssignRoles(ctx context.Context, token, userID string, roleIDs []string) error {
+	return c.Do(ctx, Request{
+		Method:      http.MethodPost,
+		Path:        managementPath("users", userID, "roles"),
+		Token:       token,
+		Body:        rolesBody{Roles: roleIDs},
+		Description: "assign user roles",
+		Idempotent:  true,
+	}, nil)
+}
+
+// RemoveRoles removes the roles with roleIDs from the user with userID
+func (c *Client) RemoveRoles(ctx context.Context, token, userID string, roleIDs []string) error {
+	return c.Do(ctx, Request{
+		Method:      http.MethodDelete,
+		Path:        managementPath("users", userID, "roles"),
+		Token:       token,
+		Body:        rolesBody{Roles: roleIDs},
+		Description: "remove user roles",
+	}, nil)
+}
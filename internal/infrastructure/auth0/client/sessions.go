@@ -0,0 +1,172 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// SessionDevice describes the device a session or refresh token was created
+// and last used from
+type SessionDevice struct {
+	InitialUserAgent string `json:"initial_user_agent,omitempty"`
+	InitialIP        string `json:"initial_ip,omitempty"`
+	LastUserAgent    string `json:"last_user_agent,omitempty"`
+	LastIP           string `json:"last_ip,omitempty"`
+}
+
+// SessionClient is an application a session signed in to
+type SessionClient struct {
+	ClientID string `json:"client_id"`
+}
+
+// Session is an Auth0 login session
+type Session struct {
+	ID               string          `json:"id"`
+	UserID           string          `json:"user_id"`
+	CreatedAt        *time.Time      `json:"created_at,omitempty"`
+	AuthenticatedAt  *time.Time      `json:"authenticated_at,omitempty"`
+	LastInteractedAt *time.Time      `json:"last_interacted_at,omitempty"`
+	IdleExpiresAt    *time.Time      `json:"idle_expires_at,omitempty"`
+	ExpiresAt        *time.Time      `json:"expires_at,omitempty"`
+	Device           SessionDevice   `json:"device"`
+	Clients          []SessionClient `json:"clients,omitempty"`
+}
+
+// RefreshToken is the metadata of a refresh token; the token itself is never returned
+type RefreshToken struct {
+	ID              string        `json:"id"`
+	UserID          string        `json:"user_id"`
+	ClientID        string        `json:"client_id"`
+	SessionID       string        `json:"session_id,omitempty"`
+	CreatedAt       *time.Time    `json:"created_at,omitempty"`
+	LastExchangedAt *time.Time    `json:"last_exchanged_at,omitempty"`
+	IdleExpiresAt   *time.Time    `json:"idle_expires_at,omitempty"`
+	ExpiresAt       *time.Time    `json:"expires_at,omitempty"`
+	Device          SessionDevice `json:"device"`
+}
+
+// sessionPage is a page of a checkpoint-paginated list of sessions
+type sessionPage struct {
+	Sessions []Session `json:"sessions"`
+	Next     string    `json:"next,omitempty"`
+}
+
+// refreshTokenPage is a page of a checkpoint-paginated list of refresh tokens
+type refreshTokenPage struct {
+	Tokens []RefreshToken `json:"tokens"`
+	Next   string         `json:"next,omitempty"`
+}
+
+// checkpointQuery selects a page of a checkpoint-paginated endpoint; from is
+// the next cursor of the previous page, empty for the first one
+func checkpointQuery(from string) url.Values {
+	query := url.Values{"take": {strconv.Itoa(MaxPerPage)}}
+	if from != "" {
+		query.Set("from", from)
+	}
+	return query
+}
+
+// ListUserSessions returns one page of the sessions of the user with userID
+// and the cursor of the next page, empty on the last one
+func (c *Client) ListUserSessions(ctx context.Context, token, userID, from string) ([]Session, string, error) {
+	var page sessionPage
+	err := c.Do(ctx, Request{
+		Method:      http.MethodGet,
+		Path:        managementPath("users", userID, "sessions"),
+		Query:       checkpointQuery(from),
+		Token:       token,
+		Description: "list user sessions",
+	}, &page)
+	return page.Sessions, page.Next, err
+}
+
+// GetSession returns the session with id
+func (c *Client) GetSession(ctx context.Context, token, id string) (*Session, error) {
+	var session Session
+	err := c.Do(ctx, Request{
+		Method:      http.MethodGet,
+		Path:        managementPath("sessions", id),
+		Token:       token,
+		Description: "get session",
+	}, &session)
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// DeleteSession revokes the session with id and the refresh tokens issued in it
+func (c *Client) DeleteSession(ctx context.Context, token, id string) error {
+	return c.Do(ctx, Request{
+		Method:      http.MethodDelete,
+		Path:        managementPath("sessions", id),
+		Token:       token,
+		Description: "delete session",
+	}, nil)
+}
+
+// DeleteUserSessions revokes every session of the user with userID
+func (c *Client) DeleteUserSessions(ctx context.Context, token, userID string) error {
+	return c.Do(ctx, Request{
+		Method:      http.MethodDelete,
+		Path:        managementPath("users", userID, "sessions"),
+		Token:       token,
+		Description: "delete user sessions",
+	}, nil)
+}
+
+// ListUserRefreshTokens returns one page of the refresh tokens of the user
+// with userID and the cursor of the next page, empty on the last one
+func (c *Client) ListUserRefreshTokens(ctx context.Context, token, userID, from string) ([]RefreshToken, string, error) {
+	var page refreshTokenPage
+	err := c.Do(ctx, Request{
+		Method:      http.MethodGet,
+		Path:        managementPath("users", userID, "refresh-tokens"),
+		Query:       checkpointQuery(from),
+		Token:       token,
+		Description: "list user refresh tokens",
+	}, &page)
+	return page.Tokens, page.Next, err
+}
+
+// GetRefreshToken returns the metadata of the refresh token with id
+func (c *Client) GetRefreshToken(ctx context.Context, token, id string) (*RefreshToken, error) {
+	var refreshToken RefreshToken
+	err := c.Do(ctx, Request{
+		Method:      http.MethodGet,
+		Path:        managementPath("refresh-tokens", id),
+		Token:       token,
+		Description: "get refresh token",
+	}, &refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	return &refreshToken, nil
+}
+
+// DeleteRefreshToken revokes the refresh token with id
+func (c *Client) DeleteRefreshToken(ctx context.Context, token, id string) error {
+	return c.Do(ctx, Request{
+		Method:      http.MethodDelete,
+		Path:        managementPath("refresh-tokens", id),
+		Token:       token,
+		Description: "delete refresh token",
+	}, nil)
+}
+
+// DeleteUserRefreshTokens revokes every refresh token of the user with userID
+func (c *Client) DeleteUserRefreshTokens(ctx context.Context, token, userID string) error {
+	return c.Do(ctx, Request{
+		Method:      http.MethodDelete,
+		Path:        managementPath("users", userID, "refresh-tokens"),
+		Token:       token,
+		Description: "delete user refresh tokens",
+	}, nil)
+}
@@ -0,0 +1,138 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// SearchParams is a v3 user search. See
+// https://auth0.com/docs/manage-users/user-search/user-search-query-syntax
+type SearchParams struct {
+	// Query is a Lucene query, e.g. identities.user_id:"jdoe"
+	Query string
+	// Fields restricts the returned user attributes
+	Fields []string
+	Page   Page
+}
+
+func (p SearchParams) values() url.Values {
+	query := url.Values{}
+	if p.Query != "" {
+		query.Set("q", p.Query)
+	}
+	query.Set("search_engine", "v3")
+	if len(p.Fields) > 0 {
+		query.Set("fields", strings.Join(p.Fields, ","))
+		query.Set("include_fields", "true")
+	}
+	return p.Page.apply(query)
+}
+
+// GetUser fetches the user with userID into out
+func (c *Client) GetUser(ctx context.Context, token, userID string, out any) error {
+	return c.Do(ctx, Request{
+		Method:      http.MethodGet,
+		Path:        managementPath("users", userID),
+		Token:       token,
+		Description: "get user",
+	}, out)
+}
+
+// SearchUsers runs one page of a user search into out
+func (c *Client) SearchUsers(ctx context.Context, token string, params SearchParams, out any) error {
+	return c.Do(ctx, Request{
+		Method:      http.MethodGet,
+		Path:        managementPath("users"),
+		Query:       params.values(),
+		Token:       token,
+		Description: "search users",
+	}, out)
+}
+
+// UsersByEmail fetches every user whose email matches exactly (case-insensitive) into out
+func (c *Client) UsersByEmail(ctx context.Context, token, email string, out any) error {
+	return c.Do(ctx, Request{
+		Method:      http.MethodGet,
+		Path:        managementPath("users-by-email"),
+		Query:       url.Values{"email": {email}},
+		Token:       token,
+		Description: "get users by email",
+	}, out)
+}
+
+// CreateUser creates a user from body and decodes the created user into out
+func (c *Client) CreateUser(ctx context.Context, token string, body, out any) error {
+	return c.Do(ctx, Request{
+		Method:      http.MethodPost,
+		Path:        managementPath("users"),
+		Token:       token,
+		Body:        body,
+		Description: "create user",
+	}, out)
+}
+
+// UpdateUser patches the user with body and decodes the updated user into out
+func (c *Client) UpdateUser(ctx context.Context, token, userID string, body, out any) error {
+	return c.Do(ctx, Request{
+		Method:      http.MethodPatch,
+		Path:        managementPath("users", userID),
+		Token:       token,
+		Body:        body,
+		Description: "update user",
+		Idempotent:  true,
+	}, out)
+}
+
+// SetPassword sets the password of the user with userID on a database connection.
+// The request body is redacted from logs.
+func (c *Client) SetPassword(ctx context.Context, token, userID, connection, password string) error {
+	return c.Do(ctx, Request{
+		Method: http.MethodPatch,
+		Path:   managementPath("users", userID),
+		Token:  token,
+		Body: struct {
+			Password   string `json:"password"`
+			Connection string `json:"connection"`
+		}{Password: password, Connection: connection},
+		SensitiveBody: true,
+		Description:   "set user password",
+		Idempotent:    true,
+	}, nil)
+}
+
+// DeleteUser deletes the user with userID
+func (c *Client) DeleteUser(ctx context.Context, token, userID string) error {
+	return c.Do(ctx, Request{
+		Method:      http.MethodDelete,
+		Path:        managementPath("users", userID),
+		Token:       token,
+		Description: "delete user",
+	}, nil)
+}
+
+// LinkIdentity links the identity described by body to the user with userID.
+// body is either {"link_with": <id token>} or {"provider", "user_id"}.
+func (c *Client) LinkIdentity(ctx context.Context, token, userID string, body any) error {
+	return c.Do(ctx, Request{
+		Method:      http.MethodPost,
+		Path:        managementPath("users", userID, "identities"),
+		Token:       token,
+		Body:        body,
+		Description: "link identity",
+	}, nil)
+}
+
+// UnlinkIdentity removes the provider/secondaryUserID identity from the user with userID
+func (c *Client) UnlinkIdentity(ctx context.Context, token, userID, provider, secondaryUserID string) error {
+	return c.Do(ctx, Request{
+		Method:      http.MethodDelete,
+		Path:        managementPath("users", userID, "identities", provider, secondaryUserID),
+		Token:       token,
+		Description: "unlink identity",
+	}, nil)
+}
@@ -0,0 +1,122 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package auth0
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/httpclient"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/jwt"
+)
+
+// coreManagementScopes are the Management API scopes every user lookup and
+// update needs; without them the service can't serve requests at all
+var coreManagementScopes = []string{"read:users", "update:users"}
+
+// featureManagementScopes are the Management API scopes of optional
+// features; without them only those features fail
+var featureManagementScopes = []struct {
+	feature string
+	scopes  []string
+}{
+	{"permission checks", []string{"read:roles"}},
+	{"sessions", []string{"read:sessions", "delete:sessions", "read:refresh_tokens", "delete:refresh_tokens"}},
+	{"service accounts", []string{"create:clients", "read:clients", "update:clients", "delete:clients", "update:client_keys", "create:client_grants", "read:client_grants"}},
+}
+
+// CheckConfig verifies the Auth0 configuration end to end: the tenant is
+// reachable, its signing keys can be fetched, the M2M credentials yield a
+// token, and the token carries the Management API scopes the service uses.
+// Unlike NewUserReaderWriter it reports every problem instead of the first.
+func CheckConfig(ctx context.Context, httpConfig httpclient.Config, config Config) []model.ConfigCheck {
+	httpClient := httpclient.NewClient(httpConfig)
+	checks := []model.ConfigCheck{
+		model.RunConfigCheck("auth0.domain", func() (string, string) {
+			apiRequest := httpclient.NewAPIRequest(
+				httpClient,
+				httpclient.WithMethod(http.MethodGet),
+				httpclient.WithURL(fmt.Sprintf("https://%s/.well-known/openid-configuration", config.Domain)),
+				httpclient.WithDescription("check Auth0 domain"),
+			)
+			var discovery map[string]any
+			if _, err := apiRequest.Call(ctx, &discovery); err != nil {
+				return model.ConfigCheckFail, fmt.Sprintf("%s is not reachable: %v", config.Domain, err)
+			}
+			return model.ConfigCheckOK, config.Domain
+		}),
+		model.RunConfigCheck("auth0.jwks", func() (string, string) {
+			jwtConfig, err := NewJWTVerificationConfig(ctx, config.Domain, httpClient)
+			if err != nil {
+				return model.ConfigCheckFail, err.Error()
+			}
+			return model.ConfigCheckOK, jwtConfig.JWKSURL
+		}),
+	}
+
+	var (
+		haveToken bool
+		scope     string
+	)
+	checks = append(checks, model.RunConfigCheck("auth0.m2m_token", func() (string, string) {
+		tokenManager, err := NewM2MTokenManager(ctx, config, httpclient.NewStandardClient(httpConfig))
+		if err != nil {
+			return model.ConfigCheckFail, err.Error()
+		}
+		info, err := tokenManager.GetTokenInfo()
+		if err != nil {
+			return model.ConfigCheckFail, fmt.Sprintf("M2M credentials were rejected: %v", err)
+		}
+		haveToken, scope = true, info.Scope
+		if scope == "" {
+			// the token response omits scope when it equals the grant
+			if claims, err := jwt.ParseUnverified(ctx, info.AccessToken, jwt.DefaultParseOptions()); err == nil {
+				scope = claims.Scope
+			}
+		}
+		return model.ConfigCheckOK, "token expires at " + info.ExpiresAt.UTC().Format("2006-01-02T15:04:05Z")
+	}))
+
+	checks = append(checks, model.RunConfigCheck("auth0.m2m_scopes", func() (string, string) {
+		if !haveToken {
+			return model.ConfigCheckSkip, "no M2M token"
+		}
+		return checkManagementScopes(scope)
+	}))
+	return checks
+}
+
+// checkManagementScopes compares the scopes granted to the M2M token with
+// the ones the service uses
+func checkManagementScopes(scope string) (string, string) {
+	granted := strings.Fields(scope)
+	if missing := missingScopes(granted, coreManagementScopes); len(missing) > 0 {
+		return model.ConfigCheckFail, "missing " + strings.Join(missing, ", ")
+	}
+
+	var degraded []string
+	for _, feature := range featureManagementScopes {
+		if missing := missingScopes(granted, feature.scopes); len(missing) > 0 {
+			degraded = append(degraded, fmt.Sprintf("%s (missing %s)", feature.feature, strings.Join(missing, ", ")))
+		}
+	}
+	if len(degraded) > 0 {
+		return model.ConfigCheckWarn, "unavailable: " + strings.Join(degraded, "; ")
+	}
+	return model.ConfigCheckOK, fmt.Sprintf("%d scopes granted", len(granted))
+}
+
+func missingScopes(granted, required []string) []string {
+	var missing []string
+	for _, scope := range required {
+		if !slices.Contains(granted, scope) {
+			missing = append(missing, scope)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,39 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package auth0
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckManagementScopes(t *testing.T) {
+	var all []string
+	all = append(all, coreManagementScopes...)
+	for _, feature := range featureManagementScopes {
+		all = append(all, feature.scopes...)
+	}
+
+	tests := []struct {
+		name       string
+		scope      string
+		wantStatus string
+		wantDetail string
+	}{
+		{name: "every scope", scope: strings.Join(all, " "), wantStatus: model.ConfigCheckOK},
+		{name: "no scopes", scope: "", wantStatus: model.ConfigCheckFail, wantDetail: "missing read:users, update:users"},
+		{name: "core scopes only", scope: "read:users update:users", wantStatus: model.ConfigCheckWarn, wantDetail: "sessions (missing read:sessions"},
+		{name: "missing one feature scope", scope: strings.ReplaceAll(strings.Join(all, " "), "read:roles", ""), wantStatus: model.ConfigCheckWarn, wantDetail: "permission checks (missing read:roles)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, detail := checkManagementScopes(tt.scope)
+			assert.Equal(t, tt.wantStatus, status)
+			assert.Contains(t, detail, tt.wantDetail)
+		})
+	}
+}
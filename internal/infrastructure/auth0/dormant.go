@@ -0,0 +1,106 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package auth0
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/auth0/client"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/httpclient"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/redaction"
+)
+
+const (
+	// dormantSearchPageSize is the largest page Auth0 user search allows
+	dormantSearchPageSize = 100
+	// dormantSearchMaxResults is Auth0's hard cap on results per search query
+	dormantSearchMaxResults = 1000
+)
+
+// dormantTagRequest is the PATCH body that marks a user dormant. Auth0 merges
+// top-level app_metadata keys, so existing app_metadata is preserved.
+type dormantTagRequest struct {
+	AppMetadata map[string]any `json:"app_metadata"`
+}
+
+// ListDormantUsers searches Auth0 for users whose last login is before cutoff.
+// Auth0 caps a single search at 1000 results, so larger tenants are reported
+// in slices across runs (tagged users stay dormant until they log in again).
+func (u *userReaderWriter) ListDormantUsers(ctx context.Context, cutoff time.Time, limit int) ([]*model.User, error) {
+	if limit <= 0 || limit > dormantSearchMaxResults {
+		limit = dormantSearchMaxResults
+	}
+
+	m2mToken, errToken := u.config.M2MTokenManager.GetToken(ctx)
+	if errToken != nil {
+		return nil, errors.NewUnexpected("failed to get M2M token for dormant user search", errToken)
+	}
+
+	api := u.api()
+	search := client.SearchParams{
+		Query:  fmt.Sprintf("last_login:[* TO %s]", cutoff.UTC().Format(time.RFC3339)),
+		Fields: []string{"user_id", "last_login", "logins_count", "last_ip"},
+	}
+
+	results, errSearch := client.Paginate(ctx, dormantSearchPageSize, limit, func(ctx context.Context, page client.Page) ([]Auth0User, error) {
+		search.Page = page
+		var results []Auth0User
+		if errCall := api.SearchUsers(ctx, m2mToken, search, &results); errCall != nil {
+			slog.ErrorContext(ctx, "failed to search dormant users",
+				"error", errCall,
+				"status_code", client.StatusCode(errCall),
+				"page", page.Page,
+			)
+			return nil, errCall
+		}
+		return results, nil
+	})
+	if errSearch != nil {
+		return nil, errors.NewUnexpected("failed to search dormant users", errSearch)
+	}
+
+	users := make([]*model.User, 0, len(results))
+	for i := range results {
+		users = append(users, results[i].ToUser())
+	}
+	return users, nil
+}
+
+// TagDormantUser sets app_metadata.dormant and app_metadata.dormant_since on the user.
+func (u *userReaderWriter) TagDormantUser(ctx context.Context, userID string, since time.Time) error {
+	if strings.TrimSpace(userID) == "" {
+		return errors.NewValidation("user_id is required")
+	}
+
+	m2mToken, errToken := u.config.M2MTokenManager.GetToken(ctx)
+	if errToken != nil {
+		return errors.NewUnexpected("failed to get M2M token for dormant tagging", errToken)
+	}
+
+	errCall := u.api().UpdateUser(ctx, m2mToken, userID, dormantTagRequest{
+		AppMetadata: map[string]any{
+			"dormant":       true,
+			"dormant_since": since.UTC().Format(time.RFC3339),
+		},
+	}, nil)
+	if errCall != nil {
+		statusCode := client.StatusCode(errCall)
+		slog.ErrorContext(ctx, "failed to tag dormant user",
+			"error", errCall,
+			"status_code", statusCode,
+			"user_id", redaction.Redact(userID),
+		)
+		return httpclient.ErrorFromStatusCode(statusCode, "failed to tag dormant user")
+	}
+	return nil
+}
+
+var _ port.DormantUserScanner = (*userReaderWriter)(nil)
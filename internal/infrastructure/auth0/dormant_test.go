@@ -0,0 +1,120 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package auth0
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dormantTransport serves paged user search results and records PATCH bodies
+type dormantTransport struct {
+	pages       [][]string // user_ids per page
+	patchStatus int
+	queries     []string
+	patches     map[string]string
+}
+
+func (d *dormantTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	status := http.StatusOK
+	body := "{}"
+
+	switch {
+	case req.Method == http.MethodGet && req.URL.Path == "/api/v2/users":
+		d.queries = append(d.queries, req.URL.Query().Get("q"))
+		page := 0
+		_, _ = fmt.Sscan(req.URL.Query().Get("page"), &page)
+		users := []map[string]any{}
+		if page < len(d.pages) {
+			for _, id := range d.pages[page] {
+				users = append(users, map[string]any{"user_id": id, "last_login": "2023-01-01T00:00:00Z", "logins_count": 3})
+			}
+		}
+		raw, _ := json.Marshal(users)
+		body = string(raw)
+	case req.Method == http.MethodPatch && strings.HasPrefix(req.URL.Path, "/api/v2/users/"):
+		raw, _ := io.ReadAll(req.Body)
+		if d.patches == nil {
+			d.patches = map[string]string{}
+		}
+		d.patches[strings.TrimPrefix(req.URL.Path, "/api/v2/users/")] = string(raw)
+		status = d.patchStatus
+	default:
+		status = http.StatusNotFound
+	}
+
+	return &http.Response{
+		StatusCode: status,
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Request:    req,
+	}, nil
+}
+
+func fullPage(prefix string) []string {
+	ids := make([]string, dormantSearchPageSize)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("%s|%d", prefix, i)
+	}
+	return ids
+}
+
+func TestUserReaderWriter_ListDormantUsers(t *testing.T) {
+	cutoff := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("pages until a short page", func(t *testing.T) {
+		transport := &dormantTransport{pages: [][]string{fullPage("auth0"), {"auth0|last"}}}
+		users, err := newTestReaderWriter(transport).ListDormantUsers(context.Background(), cutoff, 500)
+		require.NoError(t, err)
+
+		assert.Len(t, users, dormantSearchPageSize+1)
+		assert.Len(t, transport.queries, 2)
+		assert.Equal(t, "last_login:[* TO 2024-06-01T00:00:00Z]", transport.queries[0])
+		require.NotNil(t, users[0].Activity)
+		assert.Equal(t, 3, *users[0].Activity.LoginsCount)
+	})
+
+	t.Run("stops at the limit", func(t *testing.T) {
+		transport := &dormantTransport{pages: [][]string{fullPage("auth0"), fullPage("auth0")}}
+		users, err := newTestReaderWriter(transport).ListDormantUsers(context.Background(), cutoff, 10)
+		require.NoError(t, err)
+
+		assert.Len(t, users, 10)
+		assert.Len(t, transport.queries, 1)
+	})
+}
+
+func TestUserReaderWriter_TagDormantUser(t *testing.T) {
+	since := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("patches app_metadata", func(t *testing.T) {
+		transport := &dormantTransport{patchStatus: http.StatusOK}
+		err := newTestReaderWriter(transport).TagDormantUser(context.Background(), "auth0|abc", since)
+		require.NoError(t, err)
+
+		body, ok := transport.patches["auth0|abc"]
+		require.True(t, ok)
+		assert.JSONEq(t, `{"app_metadata":{"dormant":true,"dormant_since":"2025-06-01T00:00:00Z"}}`, body)
+	})
+
+	t.Run("maps provider errors", func(t *testing.T) {
+		transport := &dormantTransport{patchStatus: http.StatusNotFound}
+		err := newTestReaderWriter(transport).TagDormantUser(context.Background(), "auth0|missing", since)
+		assert.Error(t, err)
+	})
+
+	t.Run("requires user id", func(t *testing.T) {
+		err := newTestReaderWriter(&dormantTransport{}).TagDormantUser(context.Background(), " ", since)
+		assert.Error(t, err)
+	})
+}
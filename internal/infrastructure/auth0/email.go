@@ -62,7 +62,7 @@ func (e *emailLinkingFlow) StartPasswordlessFlow(ctx context.Context, email stri
 		slog.ErrorContext(ctx, "failed to send passwordless email",
 			"error", err,
 			"email", redaction.Redact(email))
-		return errors.NewUnexpected("failed to start passwordless flow", err)
+		return authenticationError("failed to start passwordless flow", err)
 	}
 
 	slog.DebugContext(ctx, "passwordless flow started successfully",
@@ -95,7 +95,7 @@ func (e *emailLinkingFlow) ExchangeOTPForToken(ctx context.Context, email, otp s
 		slog.ErrorContext(ctx, "failed to exchange OTP for token",
 			"error", err,
 			"email", redaction.Redact(email))
-		return nil, errors.NewUnexpected("failed to exchange OTP for token", err)
+		return nil, authenticationError("failed to exchange OTP for token", err)
 	}
 
 	slog.DebugContext(ctx, "OTP exchange successful",
@@ -0,0 +1,67 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package auth0
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"github.com/auth0/go-auth0/authentication"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/auth0/client"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/httpclient"
+)
+
+// classify maps a failed Management API call to the error taxonomy: rate
+// limits, blocked users and, for a call on one user, which answers 404 when
+// the user doesn't exist, missing users. False leaves err to the caller.
+func classify(err error, onUser bool) (error, bool) {
+	var apiErr *client.Error
+	if !stderrors.As(err, &apiErr) {
+		return nil, false
+	}
+	switch {
+	case apiErr.RateLimited():
+		return errors.NewRateLimited(client.Message(err)), true
+	case apiErr.Blocked():
+		return errors.NewUserBlocked(client.Message(err)), true
+	case onUser && apiErr.StatusCode == http.StatusNotFound:
+		return errors.NewUserNotFound(client.Message(err)), true
+	}
+	return nil, false
+}
+
+// apiError is the error of a failed Management API call: its taxonomy code
+// if it has one, the error of its status otherwise
+func apiError(err error) error {
+	if classified, ok := classify(err, false); ok {
+		return classified
+	}
+	return httpclient.ErrorFromStatusCode(client.StatusCode(err), client.Message(err))
+}
+
+// userAPIError is apiError for a call on one user
+func userAPIError(err error) error {
+	if classified, ok := classify(err, true); ok {
+		return classified
+	}
+	return httpclient.ErrorFromStatusCode(client.StatusCode(err), client.Message(err))
+}
+
+// authenticationError maps a failed Authentication API call of the SDK to
+// the error taxonomy, or to an unexpected error described by message
+func authenticationError(message string, err error) error {
+	var authErr *authentication.Error
+	if !stderrors.As(err, &authErr) {
+		return errors.NewUnexpected(message, err)
+	}
+	switch {
+	case authErr.StatusCode == http.StatusTooManyRequests:
+		return errors.NewRateLimited(authErr.Message)
+	case client.IsBlockedMessage(authErr.Err, authErr.Message):
+		return errors.NewUserBlocked(authErr.Message)
+	}
+	return errors.NewUnexpected(message, err)
+}
@@ -0,0 +1,65 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package auth0
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/auth0/go-auth0/authentication"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/auth0/client"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+)
+
+func TestAPIErrorTaxonomy(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		onUser   bool
+		wantCode errors.Code
+		wantKind any
+	}{
+		{name: "rate limited", err: &client.Error{StatusCode: http.StatusTooManyRequests, Message: "Global limit has been reached"},
+			wantCode: errors.CodeRateLimited, wantKind: &errors.ServiceUnavailable{}},
+		{name: "blocked user", err: &client.Error{StatusCode: http.StatusUnauthorized, Name: "unauthorized", Description: "user is blocked"},
+			wantCode: errors.CodeUserBlocked, wantKind: &errors.Forbidden{}},
+		{name: "brute-force protection", err: &client.Error{StatusCode: http.StatusUnauthorized, Name: "too_many_attempts"},
+			wantCode: errors.CodeUserBlocked, wantKind: &errors.Forbidden{}},
+		{name: "missing user", err: &client.Error{StatusCode: http.StatusNotFound, Message: "The user does not exist."}, onUser: true,
+			wantCode: errors.CodeUserNotFound, wantKind: &errors.NotFound{}},
+		{name: "missing resource other than a user", err: &client.Error{StatusCode: http.StatusNotFound, Message: "The job does not exist."},
+			wantKind: &errors.NotFound{}},
+		{name: "wrong password", err: &client.Error{StatusCode: http.StatusForbidden, Name: "invalid_grant", Description: "Wrong email or password."}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := apiError(tt.err)
+			if tt.onUser {
+				err = userAPIError(tt.err)
+			}
+			code, classified := errors.CodeOf(err)
+			assert.Equal(t, tt.wantCode != "", classified)
+			assert.Equal(t, tt.wantCode, code)
+			if classified {
+				assert.Equal(t, errors.Message(tt.wantCode), err.Error(), "the reply doesn't depend on Auth0's wording")
+			}
+			if tt.wantKind != nil {
+				assert.ErrorAs(t, err, tt.wantKind)
+			}
+		})
+	}
+}
+
+func TestAuthenticationErrorTaxonomy(t *testing.T) {
+	blocked := &authentication.Error{StatusCode: http.StatusUnauthorized, Err: "unauthorized", Message: "user is blocked"}
+	code, _ := errors.CodeOf(authenticationError("failed to exchange OTP for token", fmt.Errorf("login: %w", blocked)))
+	assert.Equal(t, errors.CodeUserBlocked, code)
+
+	_, classified := errors.CodeOf(authenticationError("failed to exchange OTP for token", &authentication.Error{StatusCode: http.StatusForbidden, Err: "invalid_grant"}))
+	assert.False(t, classified)
+}
@@ -0,0 +1,229 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package auth0
+
+import (
+	"bytes"
+	"cmp"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/auth0/client"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/deadline"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+)
+
+// exportPollInterval is how often a running users export job is polled
+var exportPollInterval = 5 * time.Second
+
+// usersExportFields are the attributes exported for the in-memory indexes
+// and the duplicate account scan. Nested fields are exported under flat
+// names so the JSON lines don't depend on how Auth0 renders dotted paths.
+var usersExportFields = []client.UsersExportField{
+	{Name: "user_id"},
+	{Name: "username"},
+	{Name: "email"},
+	{Name: "email_verified"},
+	{Name: "name"},
+	{Name: "user_metadata.name", ExportAs: "metadata_name"},
+	{Name: "user_metadata.organization", ExportAs: "metadata_organization"},
+	{Name: "user_metadata.job_title", ExportAs: "metadata_job_title"},
+	{Name: "user_metadata.country", ExportAs: "metadata_country"},
+	{Name: "user_metadata.city", ExportAs: "metadata_city"},
+}
+
+// exportedUser is one line of a JSON users export
+type exportedUser struct {
+	UserID               string `json:"user_id"`
+	Username             string `json:"username"`
+	Email                string `json:"email"`
+	EmailVerified        bool   `json:"email_verified"`
+	Name                 string `json:"name"`
+	MetadataName         string `json:"metadata_name"`
+	MetadataOrganization string `json:"metadata_organization"`
+	MetadataJobTitle     string `json:"metadata_job_title"`
+	MetadataCountry      string `json:"metadata_country"`
+	MetadataCity         string `json:"metadata_city"`
+}
+
+func (e exportedUser) toAuth0User() *Auth0User {
+	auth0User := &Auth0User{
+		UserID:        e.UserID,
+		Username:      e.Username,
+		Email:         e.Email,
+		EmailVerified: e.EmailVerified,
+		Name:          e.Name,
+	}
+
+	metadata := &Auth0UserMetadata{}
+	set := false
+	for _, field := range []struct {
+		value  string
+		target **string
+	}{
+		{e.MetadataName, &metadata.Name},
+		{e.MetadataOrganization, &metadata.Organization},
+		{e.MetadataJobTitle, &metadata.JobTitle},
+		{e.MetadataCountry, &metadata.Country},
+		{e.MetadataCity, &metadata.City},
+	} {
+		if field.value != "" {
+			value := field.value
+			*field.target = &value
+			set = true
+		}
+	}
+	if set {
+		auth0User.UserMetadata = metadata
+	}
+	return auth0User
+}
+
+// ExportUsers runs a users export job and returns the exported users. Only
+// the identifiers, names, email verification and a few metadata fields are
+// exported, and identities are not, so users whose username comes from a
+// canonical or social identity are returned without one. It implements
+// port.UserExporter.
+func (u *userReaderWriter) ExportUsers(ctx context.Context) ([]*model.User, error) {
+	job, content, err := u.runUsersExport(ctx, client.UsersExportRequest{
+		Format: "json",
+		Fields: usersExportFields,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	users, errDecode := decodeUsersExport(content)
+	if errDecode != nil {
+		return nil, errors.NewUnexpected("failed to decode users export", errDecode)
+	}
+
+	exported := make([]*model.User, 0, len(users))
+	for _, user := range users {
+		exported = append(exported, u.toUser(user.toAuth0User()))
+	}
+	slog.DebugContext(ctx, "users export downloaded",
+		"job_id", job.ID,
+		"count", len(exported),
+	)
+	return exported, nil
+}
+
+// ExportUserReport runs a users export job of the attributes asked for and
+// returns the file it produced. It implements port.UserReportExporter.
+func (u *userReaderWriter) ExportUserReport(ctx context.Context, export *model.UserExport) (*model.UserExportFile, error) {
+	request := client.UsersExportRequest{
+		ConnectionID: export.ConnectionID,
+		Format:       cmp.Or(export.Format, model.UserExportFormatCSV),
+	}
+	for _, field := range export.Fields {
+		request.Fields = append(request.Fields, client.UsersExportField{Name: field.Name, ExportAs: field.ExportAs})
+	}
+
+	job, content, err := u.runUsersExport(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return &model.UserExportFile{JobID: job.ID, Format: request.Format, Content: content}, nil
+}
+
+// runUsersExport starts a users export job, polls it until it is done and
+// returns it with the file it produced, decompressed
+func (u *userReaderWriter) runUsersExport(ctx context.Context, request client.UsersExportRequest) (*client.Job, []byte, error) {
+	ctx, cancel := u.config.Timeouts.Bound(ctx, deadline.OperationJobs)
+	defer cancel()
+
+	m2mToken, errToken := u.config.M2MTokenManager.GetToken(ctx)
+	if errToken != nil {
+		return nil, nil, errors.NewUnexpected("failed to get M2M token for users export", errToken)
+	}
+
+	api := u.api()
+	job, errJob := api.ExportUsers(ctx, m2mToken, request)
+	if errJob != nil {
+		return nil, nil, errors.NewUnexpected("failed to start users export", errJob)
+	}
+
+	for !job.Done() {
+		timer := time.NewTimer(exportPollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		jobID := job.ID
+		job, errJob = api.GetJob(ctx, m2mToken, jobID)
+		if errJob != nil {
+			return nil, nil, errors.NewUnexpected("failed to poll users export", errJob)
+		}
+		slog.DebugContext(ctx, "users export in progress",
+			"job_id", job.ID,
+			"status", job.Status,
+			"percentage_done", job.PercentageDone,
+		)
+	}
+
+	if job.Status != client.JobStatusCompleted || job.Location == "" {
+		return nil, nil, errors.NewUnexpected("users export job " + job.ID + " " + job.Status)
+	}
+
+	// the location is a pre-signed download URL, so no token is sent
+	response, errDownload := u.jobsHTTPClient.Request(ctx, http.MethodGet, job.Location, nil, nil)
+	if errDownload != nil {
+		return nil, nil, errors.NewUnexpected("failed to download users export", errDownload)
+	}
+
+	content, errGunzip := gunzipExport(response.Body)
+	if errGunzip != nil {
+		return nil, nil, errors.NewUnexpected("failed to decompress users export", errGunzip)
+	}
+	return job, content, nil
+}
+
+// gunzipExport decompresses an export Auth0 delivered gzipped, and returns
+// any other as it is
+func gunzipExport(body []byte) ([]byte, error) {
+	if len(body) < 2 || body[0] != 0x1f || body[1] != 0x8b {
+		return body, nil
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	return io.ReadAll(gz)
+}
+
+// decodeUsersExport decodes a JSON-lines export
+func decodeUsersExport(content []byte) ([]exportedUser, error) {
+	var users []exportedUser
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	for {
+		var user exportedUser
+		err := decoder.Decode(&user)
+		if err == io.EOF {
+			return users, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if user.UserID != "" {
+			users = append(users, user)
+		}
+	}
+}
+
+var (
+	_ port.UserExporter       = (*userReaderWriter)(nil)
+	_ port.UserReportExporter = (*userReaderWriter)(nil)
+)
@@ -0,0 +1,146 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package auth0
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/auth0/client"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// exportTransport serves a users export job that completes on the first poll
+type exportTransport struct {
+	finalStatus string
+	download    []byte
+
+	request       client.UsersExportRequest
+	downloadAuth  string
+	downloadCalls int
+}
+
+func (e *exportTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	status, body := http.StatusOK, []byte(`{}`)
+	switch {
+	case req.Method == http.MethodPost && req.URL.Path == "/api/v2/jobs/users-exports":
+		_ = json.NewDecoder(req.Body).Decode(&e.request)
+		status, body = http.StatusCreated, []byte(`{"id":"job_1","type":"users_export","status":"pending"}`)
+	case req.URL.Path == "/api/v2/jobs/job_1":
+		body = []byte(`{"id":"job_1","type":"users_export","status":"` + e.finalStatus +
+			`","location":"https://exports.example.com/job_1.json.gz"}`)
+	case req.URL.Host == "exports.example.com":
+		e.downloadCalls++
+		e.downloadAuth = req.Header.Get("Authorization")
+		body = e.download
+	default:
+		status, body = http.StatusNotFound, []byte(`{"statusCode":404}`)
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func gzipLines(t *testing.T, lines string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write([]byte(lines))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func TestUserReaderWriter_ExportUsers(t *testing.T) {
+	ctx := context.Background()
+	previous := exportPollInterval
+	exportPollInterval = time.Millisecond
+	t.Cleanup(func() { exportPollInterval = previous })
+
+	lines := `{"user_id":"auth0|jdoe","username":"jdoe","email":"jdoe@example.com","email_verified":true,"name":"jdoe@example.com","metadata_name":"Jane Doe","metadata_organization":"Example Org"}
+{"user_id":"auth0|asmith","username":"asmith","email":"asmith@example.com"}
+`
+
+	t.Run("completed export", func(t *testing.T) {
+		transport := &exportTransport{finalStatus: client.JobStatusCompleted, download: gzipLines(t, lines)}
+		users, err := newTestReaderWriter(transport).ExportUsers(ctx)
+		require.NoError(t, err)
+
+		require.Len(t, users, 2)
+		assert.Equal(t, "jdoe", users[0].Username)
+		assert.Equal(t, "jdoe@example.com", users[0].PrimaryEmail)
+		assert.Equal(t, "Jane Doe", users[0].SearchName())
+		assert.True(t, users[0].EmailVerified)
+		assert.Equal(t, "Example Org", *users[0].UserMetadata.Organization)
+		assert.Equal(t, "asmith", users[1].Username)
+		assert.Nil(t, users[1].UserMetadata)
+
+		assert.Equal(t, "json", transport.request.Format)
+		assert.Contains(t, transport.request.Fields, client.UsersExportField{Name: "user_metadata.name", ExportAs: "metadata_name"})
+		assert.Equal(t, 1, transport.downloadCalls)
+		assert.Empty(t, transport.downloadAuth, "the pre-signed download must not carry the M2M token")
+	})
+
+	t.Run("uncompressed export", func(t *testing.T) {
+		transport := &exportTransport{finalStatus: client.JobStatusCompleted, download: []byte(lines)}
+		users, err := newTestReaderWriter(transport).ExportUsers(ctx)
+		require.NoError(t, err)
+		assert.Len(t, users, 2)
+	})
+
+	t.Run("failed job", func(t *testing.T) {
+		transport := &exportTransport{finalStatus: client.JobStatusFailed}
+		_, err := newTestReaderWriter(transport).ExportUsers(ctx)
+		assert.IsType(t, errors.Unexpected{}, err)
+		assert.Zero(t, transport.downloadCalls)
+	})
+
+	t.Run("cancelled while polling", func(t *testing.T) {
+		exportPollInterval = time.Hour
+		t.Cleanup(func() { exportPollInterval = time.Millisecond })
+
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err := newTestReaderWriter(&exportTransport{finalStatus: client.JobStatusCompleted}).ExportUsers(cancelled)
+		assert.Error(t, err)
+	})
+}
+
+func TestUserReaderWriter_ExportUserReport(t *testing.T) {
+	previous := exportPollInterval
+	exportPollInterval = time.Millisecond
+	t.Cleanup(func() { exportPollInterval = previous })
+
+	csv := "email,organization\nzephyr@example.com,Example Org\n"
+	transport := &exportTransport{finalStatus: client.JobStatusCompleted, download: gzipLines(t, csv)}
+	file, err := newTestReaderWriter(transport).ExportUserReport(context.Background(), &model.UserExport{
+		ConnectionID: "con_db",
+		Fields: []model.UserExportField{
+			{Name: "email"},
+			{Name: "user_metadata.organization", ExportAs: "organization"},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "job_1", file.JobID)
+	assert.Equal(t, model.UserExportFormatCSV, file.Format)
+	assert.Equal(t, csv, string(file.Content), "the file is decompressed")
+	assert.Equal(t, client.UsersExportRequest{
+		ConnectionID: "con_db",
+		Format:       "csv",
+		Fields:       []client.UsersExportField{{Name: "email"}, {Name: "user_metadata.organization", ExportAs: "organization"}},
+	}, transport.request)
+}
@@ -7,107 +7,186 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
-	"net/url"
 	"strings"
 
 	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/auth0/client"
 	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
 	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/normalize"
 	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/redaction"
 )
 
 const (
-	usernamePasswordAuthenticationFilter = constants.Auth0UsernamePasswordConnection
-	emailAuthenticationFilter            = constants.EmailConnection
+	emailAuthenticationFilter = constants.EmailConnection
 )
 
-var (
-	// criteriaEndpointMapping is a map of criteria types and their corresponding API endpoints
-	criteriaEndpointMapping = map[string]string{
-		constants.CriteriaTypeEmail:          "users-by-email?email=%s",
-		constants.CriteriaTypeUsername:       `users?q=identities.user_id:%s&search_engine=v3`,
-		constants.CriteriaTypeAlternateEmail: `users?q=identities.profileData.email:%s&search_engine=v3`,
+// canonicalConnections are the connections whose identity user_id is the
+// LFX username, in priority order. Empty means the default database connection.
+type canonicalConnections []string
+
+func (c canonicalConnections) list() []string {
+	if len(c) == 0 {
+		return []string{constants.Auth0UsernamePasswordConnection}
 	}
-)
+	return c
+}
+
+// query matches users with an identity on any canonical connection
+func (c canonicalConnections) query() client.Query {
+	connections := c.list()
+	terms := make([]client.Query, 0, len(connections))
+	for _, connection := range connections {
+		terms = append(terms, client.Term("identities.connection", connection))
+	}
+	return client.Or(terms...)
+}
+
+// identities returns the user's canonical identities, ordered by connection priority
+func (c canonicalConnections) identities(auth0User *Auth0User) []Auth0Identity {
+	var matched []Auth0Identity
+	for _, connection := range c.list() {
+		for _, identity := range auth0User.Identities {
+			if identity.Connection == connection {
+				matched = append(matched, identity)
+			}
+		}
+	}
+	return matched
+}
 
 type userFilterer interface {
-	Endpoint(ctx context.Context) string
-	Args(ctx context.Context) []any
+	// Lookup fetches the candidate users for the criteria; Filter then picks
+	// the one whose identity actually matches
+	Lookup(ctx context.Context, api *client.Client, token string) ([]Auth0User, error)
 	Filter(ctx context.Context, auth0User *Auth0User) (bool, error)
 }
 
+// searchUsers runs a v3 user search for query
+func searchUsers(ctx context.Context, api *client.Client, token string, query client.Query) ([]Auth0User, error) {
+	var users []Auth0User
+	if err := api.SearchUsers(ctx, token, client.SearchParams{Query: query.String()}, &users); err != nil {
+		return nil, searchFailed(ctx, err)
+	}
+	return users, nil
+}
+
+func searchFailed(ctx context.Context, err error) error {
+	slog.ErrorContext(ctx, "failed to search user",
+		"error", err,
+		"status_code", client.StatusCode(err),
+	)
+	return errors.NewUnexpected("failed to search user", err)
+}
+
 type usernameFilter struct {
-	user *model.User
+	user        *model.User
+	connections canonicalConnections
+	social      socialPolicy
 }
 
-func (u *usernameFilter) Endpoint(ctx context.Context) string {
-	return criteriaEndpointMapping[constants.CriteriaTypeUsername]
+// Query matches the username on a canonical connection or, when social
+// usernames are enabled, on a social identity. Auth0 evaluates the clauses
+// per user rather than per identity, so Filter still checks that they belong
+// to the same identity.
+func (u *usernameFilter) Query(ctx context.Context) client.Query {
+	if u.user.Username == "" {
+		return client.Query{}
+	}
+	return client.Or(
+		client.And(
+			client.Term("identities.user_id", u.user.Username),
+			u.connections.query(),
+		),
+		u.social.query(u.user.Username),
+	)
 }
 
-func (u *usernameFilter) Args(ctx context.Context) []any {
-	return []any{url.QueryEscape(u.user.Username)}
+func (u *usernameFilter) Lookup(ctx context.Context, api *client.Client, token string) ([]Auth0User, error) {
+	query := u.Query(ctx)
+	if query.IsZero() {
+		return nil, errors.NewValidation("username is required")
+	}
+	return searchUsers(ctx, api, token, query)
 }
 
 func (u *usernameFilter) Filter(ctx context.Context, auth0User *Auth0User) (bool, error) {
-	for _, identity := range auth0User.Identities {
-		if identity.Connection == usernamePasswordAuthenticationFilter {
-			// if the search is by username, we need to check if the identity is the one we are looking for
-			//
-			// At this point, we know that the user is found, but the validation is to
-			// make sure the username is from the Username-Password-Authentication connection
-			userID, ok := identity.UserID.(string)
-			if !ok {
-				slog.DebugContext(ctx, "user found, but it's not the correct identity",
-					"filter", usernamePasswordAuthenticationFilter,
-					"user_id", redaction.Redact(fmt.Sprintf("%v", identity.UserID)),
-				)
-				return false, nil
-			}
-
-			if userID != u.user.Username {
-				slog.DebugContext(ctx, "user found, but it's not the correct identity",
-					"filter", usernamePasswordAuthenticationFilter,
-					"user_id", redaction.Redact(userID),
-				)
-				// if the connection is Password-Authentication and the user is not the one we are looking for,
-				// we need to return an error
-				return false, errors.NewNotFound("user not found")
-			}
+	mismatched := false
+	for _, identity := range u.connections.identities(auth0User) {
+		// if the search is by username, we need to check if the identity is the one we are looking for
+		//
+		// At this point, we know that the user is found, but the validation is to
+		// make sure the username is from one of the canonical connections
+		userID, ok := identity.UserID.(string)
+		if !ok {
+			slog.DebugContext(ctx, "user found, but it's not the correct identity",
+				"filter", identity.Connection,
+				"user_id", redaction.Redact(fmt.Sprintf("%v", identity.UserID)),
+			)
+			continue
+		}
+		if normalize.Username(userID) == normalize.Username(u.user.Username) {
 			u.user.Username = userID
 			return true, nil
 		}
+		slog.DebugContext(ctx, "user found, but it's not the correct identity",
+			"filter", identity.Connection,
+			"user_id", redaction.Redact(userID),
+		)
+		mismatched = true
+	}
+	if !mismatched && u.social.enabled() && normalize.Username(u.social.username(auth0User)) == normalize.Username(u.user.Username) {
+		slog.DebugContext(ctx, "user found by social identity",
+			"user_id", redaction.Redact(auth0User.UserID),
+		)
+		return true, nil
+	}
+	if mismatched && !u.social.enabled() {
+		// the user has a canonical identity but none of them is the one we are looking for,
+		// we need to return an error. With social usernames the search also matches on
+		// social identities, so a mismatch only rules out this result.
+		return false, errors.NewUserNotFound("user not found")
 	}
 	return false, nil
 }
 
 type emailFilter struct {
-	user *model.User
-}
-
-func (e *emailFilter) Endpoint(ctx context.Context) string {
-	return criteriaEndpointMapping[constants.CriteriaTypeEmail]
+	user        *model.User
+	connections canonicalConnections
+	social      socialPolicy
 }
 
-func (e *emailFilter) Args(ctx context.Context) []any {
-	return []any{url.QueryEscape(e.user.PrimaryEmail)}
+// Lookup uses users-by-email rather than a search: it is an exact,
+// case-insensitive match and isn't subject to search indexing delays
+func (e *emailFilter) Lookup(ctx context.Context, api *client.Client, token string) ([]Auth0User, error) {
+	if e.user.PrimaryEmail == "" {
+		return nil, errors.NewValidation("email is required")
+	}
+	var users []Auth0User
+	if err := api.UsersByEmail(ctx, token, e.user.PrimaryEmail, &users); err != nil {
+		return nil, searchFailed(ctx, err)
+	}
+	return users, nil
 }
 
 func (e *emailFilter) Filter(ctx context.Context, auth0User *Auth0User) (bool, error) {
-	for _, identity := range auth0User.Identities {
-		if identity.Connection == usernamePasswordAuthenticationFilter {
-			// At this point, we know that the user is found, but the validation is to
-			// make sure the username is from the Username-Password-Authentication connection
-			userID, ok := identity.UserID.(string)
-			if !ok {
-				slog.DebugContext(ctx, "user found, but it's not the correct identity",
-					"filter", usernamePasswordAuthenticationFilter,
-					"user_id", redaction.Redact(fmt.Sprintf("%v", identity.UserID)),
-				)
-				return false, nil
-			}
-			e.user.PrimaryEmail = userID
-			return true, nil
+	for _, identity := range e.connections.identities(auth0User) {
+		// At this point, we know that the user is found, but the validation is to
+		// make sure the username is from one of the canonical connections
+		userID, ok := identity.UserID.(string)
+		if !ok {
+			slog.DebugContext(ctx, "user found, but it's not the correct identity",
+				"filter", identity.Connection,
+				"user_id", redaction.Redact(fmt.Sprintf("%v", identity.UserID)),
+			)
+			continue
 		}
+		e.user.PrimaryEmail = userID
+		return true, nil
+	}
+	// social-only users are resolved when the policy can give them a username
+	if e.social.username(auth0User) != "" {
+		return true, nil
 	}
 	return false, nil
 }
@@ -116,15 +195,20 @@ type alternateEmailFilter struct {
 	user *model.User
 }
 
-func (a *alternateEmailFilter) Endpoint(ctx context.Context) string {
-	return criteriaEndpointMapping[constants.CriteriaTypeAlternateEmail]
+// Query matches the first alternate email against linked identities
+func (a *alternateEmailFilter) Query(ctx context.Context) client.Query {
+	if len(a.user.AlternateEmails) == 0 || a.user.AlternateEmails[0].Email == "" {
+		return client.Query{}
+	}
+	return client.Term("identities.profileData.email", a.user.AlternateEmails[0].Email)
 }
 
-func (a *alternateEmailFilter) Args(ctx context.Context) []any {
-	if len(a.user.AlternateEmails) == 0 {
-		return []any{}
+func (a *alternateEmailFilter) Lookup(ctx context.Context, api *client.Client, token string) ([]Auth0User, error) {
+	query := a.Query(ctx)
+	if query.IsZero() {
+		return nil, errors.NewValidation("alternate email is required")
 	}
-	return []any{url.QueryEscape(a.user.AlternateEmails[0].Email)}
+	return searchUsers(ctx, api, token, query)
 }
 
 func (a *alternateEmailFilter) Filter(ctx context.Context, auth0User *Auth0User) (bool, error) {
@@ -150,18 +234,103 @@ func (a *alternateEmailFilter) Filter(ctx context.Context, auth0User *Auth0User)
 	return false, nil
 }
 
+type secondaryEmailFilter struct {
+	user *model.User
+}
+
+// Query matches the email of any identity, whatever its connection
+func (s *secondaryEmailFilter) Query(ctx context.Context) client.Query {
+	if len(s.user.SecondaryEmails) == 0 || s.user.SecondaryEmails[0].Email == "" {
+		return client.Query{}
+	}
+	return client.Term("identities.profileData.email", s.user.SecondaryEmails[0].Email)
+}
+
+func (s *secondaryEmailFilter) Lookup(ctx context.Context, api *client.Client, token string) ([]Auth0User, error) {
+	query := s.Query(ctx)
+	if query.IsZero() {
+		return nil, errors.NewValidation("secondary email is required")
+	}
+	return searchUsers(ctx, api, token, query)
+}
+
+// Filter only accepts addresses the identity provider verified, so an
+// unverified social email can't be used to resolve someone else's account
+func (s *secondaryEmailFilter) Filter(ctx context.Context, auth0User *Auth0User) (bool, error) {
+	email := s.user.SecondaryEmails[0].Email
+	for _, identity := range auth0User.Identities {
+		if identity.ProfileData == nil || !strings.EqualFold(identity.ProfileData.Email, email) {
+			continue
+		}
+		if !identity.ProfileData.EmailVerified {
+			slog.DebugContext(ctx, "user found, but the identity email is not verified",
+				"filter", identity.Connection,
+				"identity_email", redaction.RedactEmail(identity.ProfileData.Email),
+			)
+			continue
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+type nameFilter struct {
+	user *model.User
+}
+
+// Query matches the display name the user set in their profile or, failing
+// that, the one the identity provider supplied
+func (n *nameFilter) Query(ctx context.Context) client.Query {
+	name := n.name()
+	if name == "" {
+		return client.Query{}
+	}
+	return client.Or(
+		client.Term("user_metadata.name", name),
+		client.Term("name", name),
+	)
+}
+
+func (n *nameFilter) Lookup(ctx context.Context, api *client.Client, token string) ([]Auth0User, error) {
+	query := n.Query(ctx)
+	if query.IsZero() {
+		return nil, errors.NewValidation("name is required")
+	}
+	return searchUsers(ctx, api, token, query)
+}
+
+func (n *nameFilter) Filter(ctx context.Context, auth0User *Auth0User) (bool, error) {
+	name := n.name()
+	if auth0User.UserMetadata != nil && auth0User.UserMetadata.Name != nil &&
+		strings.EqualFold(*auth0User.UserMetadata.Name, name) {
+		return true, nil
+	}
+	return strings.EqualFold(auth0User.Name, name), nil
+}
+
+func (n *nameFilter) name() string {
+	if n.user.UserMetadata == nil || n.user.UserMetadata.Name == nil {
+		return ""
+	}
+	return strings.TrimSpace(*n.user.UserMetadata.Name)
+}
+
 // newUserFilterer creates a new user filterer based on the criteria type
-// each filter might have a different way to filter the user, so we need to return the arguments and the filter function
-func newUserFilterer(criteriaType string, user *model.User) userFilterer {
+// each filter might have a different way to look up and filter the user
+func newUserFilterer(criteriaType string, user *model.User, connections []string, social socialPolicy) userFilterer {
 
 	switch criteriaType {
 
 	case constants.CriteriaTypeEmail:
-		return &emailFilter{user: user}
+		return &emailFilter{user: user, connections: connections, social: social}
 	case constants.CriteriaTypeUsername:
-		return &usernameFilter{user: user}
+		return &usernameFilter{user: user, connections: connections, social: social}
 	case constants.CriteriaTypeAlternateEmail:
 		return &alternateEmailFilter{user: user}
+	case constants.CriteriaTypeSecondaryEmail:
+		return &secondaryEmailFilter{user: user}
+	case constants.CriteriaTypeName:
+		return &nameFilter{user: user}
 	}
 	return nil
 }
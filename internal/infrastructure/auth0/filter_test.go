@@ -5,11 +5,15 @@ package auth0
 
 import (
 	"context"
+	"io"
+	"net/http"
 	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
 	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -43,6 +47,16 @@ func Test_newUserFilterer(t *testing.T) {
 			criteriaType: constants.CriteriaTypeAlternateEmail,
 			want:         &alternateEmailFilter{user: user},
 		},
+		{
+			name:         "creates secondary email filter",
+			criteriaType: constants.CriteriaTypeSecondaryEmail,
+			want:         &secondaryEmailFilter{user: user},
+		},
+		{
+			name:         "creates name filter",
+			criteriaType: constants.CriteriaTypeName,
+			want:         &nameFilter{user: user},
+		},
 		{
 			name:         "returns nil for unknown criteria type",
 			criteriaType: "unknown",
@@ -52,25 +66,13 @@ func Test_newUserFilterer(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := newUserFilterer(tt.criteriaType, user)
+			got := newUserFilterer(tt.criteriaType, user, nil, socialPolicy{})
 			assert.IsType(t, tt.want, got)
 		})
 	}
 }
 
-func Test_usernameFilter_Endpoint(t *testing.T) {
-	ctx := context.Background()
-	user := &model.User{Username: "testuser"}
-	filter := &usernameFilter{user: user}
-
-	endpoint := filter.Endpoint(ctx)
-	expectedEndpoint := criteriaEndpointMapping[constants.CriteriaTypeUsername]
-
-	assert.Equal(t, expectedEndpoint, endpoint)
-	assert.Contains(t, endpoint, "users?q=identities.user_id:")
-}
-
-func Test_usernameFilter_Args(t *testing.T) {
+func Test_usernameFilter_Query(t *testing.T) {
 	ctx := context.Background()
 
 	tests := []struct {
@@ -79,34 +81,70 @@ func Test_usernameFilter_Args(t *testing.T) {
 		want     string
 	}{
 		{
-			name:     "returns escaped username",
+			name:     "matches user_id on the database connection",
 			username: "testuser",
-			want:     "testuser",
+			want:     `identities.user_id:"testuser" AND identities.connection:"Username-Password-Authentication"`,
 		},
 		{
-			name:     "escapes special characters",
+			name:     "quotes special characters",
 			username: "test@user+name",
-			want:     url.QueryEscape("test@user+name"),
+			want:     `identities.user_id:"test@user+name" AND identities.connection:"Username-Password-Authentication"`,
+		},
+		{
+			name:     "escapes quotes so the value can't add clauses",
+			username: `x" OR identities.user_id:*`,
+			want:     `identities.user_id:"x\" OR identities.user_id:*" AND identities.connection:"Username-Password-Authentication"`,
 		},
 		{
-			name:     "escapes spaces",
-			username: "test user",
-			want:     "test+user",
+			name:     "empty username",
+			username: "",
+			want:     "",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			user := &model.User{Username: tt.username}
-			filter := &usernameFilter{user: user}
-
-			args := filter.Args(ctx)
-			require.Len(t, args, 1)
-			assert.Equal(t, tt.want, args[0])
+			filter := &usernameFilter{user: &model.User{Username: tt.username}}
+			assert.Equal(t, tt.want, filter.Query(ctx).String())
 		})
 	}
 }
 
+func Test_usernameFilter_Lookup(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("sends the escaped query to the v3 search", func(t *testing.T) {
+		transport := &searchTransport{body: `[{"user_id":"auth0|1"}]`}
+		filter := &usernameFilter{user: &model.User{Username: "a b"}}
+
+		users, err := filter.Lookup(ctx, newTestReaderWriter(transport).api(), "token")
+		require.NoError(t, err)
+		require.Len(t, users, 1)
+		assert.Equal(t, "/api/v2/users", transport.path)
+		assert.Equal(t, `identities.user_id:"a b" AND identities.connection:"Username-Password-Authentication"`, transport.query.Get("q"))
+		assert.Equal(t, "v3", transport.query.Get("search_engine"))
+	})
+
+	t.Run("rejects an empty username without calling Auth0", func(t *testing.T) {
+		transport := &searchTransport{}
+		filter := &usernameFilter{user: &model.User{}}
+
+		_, err := filter.Lookup(ctx, newTestReaderWriter(transport).api(), "token")
+		require.Error(t, err)
+		assert.IsType(t, errors.Validation{}, err)
+		assert.Empty(t, transport.path)
+	})
+
+	t.Run("wraps API failures", func(t *testing.T) {
+		transport := &searchTransport{status: http.StatusBadRequest, body: `{"message":"bad query"}`}
+		filter := &usernameFilter{user: &model.User{Username: "testuser"}}
+
+		_, err := filter.Lookup(ctx, newTestReaderWriter(transport).api(), "token")
+		require.Error(t, err)
+		assert.IsType(t, errors.Unexpected{}, err)
+	})
+}
+
 func Test_usernameFilter_Filter(t *testing.T) {
 	ctx := context.Background()
 
@@ -124,7 +162,7 @@ func Test_usernameFilter_Filter(t *testing.T) {
 			auth0User: &Auth0User{
 				Identities: []Auth0Identity{
 					{
-						Connection: usernamePasswordAuthenticationFilter,
+						Connection: constants.Auth0UsernamePasswordConnection,
 						UserID:     "testuser",
 					},
 				},
@@ -138,7 +176,7 @@ func Test_usernameFilter_Filter(t *testing.T) {
 			auth0User: &Auth0User{
 				Identities: []Auth0Identity{
 					{
-						Connection: usernamePasswordAuthenticationFilter,
+						Connection: constants.Auth0UsernamePasswordConnection,
 						UserID:     "differentuser",
 					},
 				},
@@ -167,7 +205,7 @@ func Test_usernameFilter_Filter(t *testing.T) {
 			auth0User: &Auth0User{
 				Identities: []Auth0Identity{
 					{
-						Connection: usernamePasswordAuthenticationFilter,
+						Connection: constants.Auth0UsernamePasswordConnection,
 						UserID:     12345, // not a string
 					},
 				},
@@ -185,7 +223,7 @@ func Test_usernameFilter_Filter(t *testing.T) {
 						UserID:     "testuser",
 					},
 					{
-						Connection: usernamePasswordAuthenticationFilter,
+						Connection: constants.Auth0UsernamePasswordConnection,
 						UserID:     "testuser",
 					},
 				},
@@ -228,53 +266,66 @@ func Test_usernameFilter_Filter(t *testing.T) {
 	}
 }
 
-func Test_emailFilter_Endpoint(t *testing.T) {
+func Test_canonicalConnections(t *testing.T) {
 	ctx := context.Background()
-	user := &model.User{PrimaryEmail: "test@example.com"}
-	filter := &emailFilter{user: user}
+	connections := canonicalConnections{"Corp-Database", "corp-saml"}
+
+	auth0User := &Auth0User{
+		Identities: []Auth0Identity{
+			{Connection: constants.Auth0UsernamePasswordConnection, UserID: "legacy"},
+			{Connection: "corp-saml", UserID: "jdoe@corp.example"},
+			{Connection: "Corp-Database", UserID: "jdoe"},
+		},
+	}
+
+	t.Run("defaults to the database connection", func(t *testing.T) {
+		assert.Equal(t, `identities.connection:"Username-Password-Authentication"`, canonicalConnections(nil).query().String())
+	})
+
+	t.Run("query matches any configured connection", func(t *testing.T) {
+		filter := &usernameFilter{user: &model.User{Username: "jdoe"}, connections: connections}
+		assert.Equal(t,
+			`identities.user_id:"jdoe" AND (identities.connection:"Corp-Database" OR identities.connection:"corp-saml")`,
+			filter.Query(ctx).String())
+	})
+
+	t.Run("username matches an enterprise identity", func(t *testing.T) {
+		user := &model.User{Username: "jdoe@corp.example"}
+		found, err := (&usernameFilter{user: user, connections: connections}).Filter(ctx, auth0User)
+		require.NoError(t, err)
+		assert.True(t, found)
+	})
 
-	endpoint := filter.Endpoint(ctx)
-	expectedEndpoint := criteriaEndpointMapping[constants.CriteriaTypeEmail]
+	t.Run("username on an unlisted connection is not found", func(t *testing.T) {
+		user := &model.User{Username: "legacy"}
+		found, err := (&usernameFilter{user: user, connections: connections}).Filter(ctx, auth0User)
+		require.Error(t, err)
+		assert.False(t, found)
+	})
 
-	assert.Equal(t, expectedEndpoint, endpoint)
-	assert.Contains(t, endpoint, "users-by-email?email=")
+	t.Run("email resolves to the highest priority connection", func(t *testing.T) {
+		user := &model.User{PrimaryEmail: "jdoe@corp.example"}
+		found, err := (&emailFilter{user: user, connections: connections}).Filter(ctx, auth0User)
+		require.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, "jdoe", user.PrimaryEmail)
+	})
 }
 
-func Test_emailFilter_Args(t *testing.T) {
+func Test_emailFilter_Lookup(t *testing.T) {
 	ctx := context.Background()
 
-	tests := []struct {
-		name  string
-		email string
-		want  string
-	}{
-		{
-			name:  "returns escaped email",
-			email: "test@example.com",
-			want:  "test%40example.com",
-		},
-		{
-			name:  "escapes email with plus",
-			email: "test+tag@example.com",
-			want:  url.QueryEscape("test+tag@example.com"),
-		},
-		{
-			name:  "escapes email with special characters",
-			email: "test.user@example.com",
-			want:  "test.user%40example.com",
-		},
-	}
+	transport := &searchTransport{body: `[]`}
+	filter := &emailFilter{user: &model.User{PrimaryEmail: "test+tag@example.com"}}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			user := &model.User{PrimaryEmail: tt.email}
-			filter := &emailFilter{user: user}
+	users, err := filter.Lookup(ctx, newTestReaderWriter(transport).api(), "token")
+	require.NoError(t, err)
+	assert.Empty(t, users)
+	assert.Equal(t, "/api/v2/users-by-email", transport.path)
+	assert.Equal(t, "test+tag@example.com", transport.query.Get("email"))
 
-			args := filter.Args(ctx)
-			require.Len(t, args, 1)
-			assert.Equal(t, tt.want, args[0])
-		})
-	}
+	_, err = (&emailFilter{user: &model.User{}}).Lookup(ctx, newTestReaderWriter(transport).api(), "token")
+	assert.IsType(t, errors.Validation{}, err)
 }
 
 func Test_emailFilter_Filter(t *testing.T) {
@@ -293,7 +344,7 @@ func Test_emailFilter_Filter(t *testing.T) {
 			auth0User: &Auth0User{
 				Identities: []Auth0Identity{
 					{
-						Connection: usernamePasswordAuthenticationFilter,
+						Connection: constants.Auth0UsernamePasswordConnection,
 						UserID:     "test@example.com",
 					},
 				},
@@ -321,7 +372,7 @@ func Test_emailFilter_Filter(t *testing.T) {
 			auth0User: &Auth0User{
 				Identities: []Auth0Identity{
 					{
-						Connection: usernamePasswordAuthenticationFilter,
+						Connection: constants.Auth0UsernamePasswordConnection,
 						UserID:     12345,
 					},
 				},
@@ -339,7 +390,7 @@ func Test_emailFilter_Filter(t *testing.T) {
 						UserID:     "other@example.com",
 					},
 					{
-						Connection: usernamePasswordAuthenticationFilter,
+						Connection: constants.Auth0UsernamePasswordConnection,
 						UserID:     "test@example.com",
 					},
 				},
@@ -362,7 +413,7 @@ func Test_emailFilter_Filter(t *testing.T) {
 			auth0User: &Auth0User{
 				Identities: []Auth0Identity{
 					{
-						Connection: usernamePasswordAuthenticationFilter,
+						Connection: constants.Auth0UsernamePasswordConnection,
 						UserID:     "newemail@example.com",
 					},
 				},
@@ -393,71 +444,45 @@ func Test_emailFilter_Filter(t *testing.T) {
 	}
 }
 
-func Test_alternateEmailFilter_Endpoint(t *testing.T) {
-	ctx := context.Background()
-	user := &model.User{
-		AlternateEmails: []model.Email{
-			{Email: "alt@example.com"},
-		},
-	}
-	filter := &alternateEmailFilter{user: user}
-
-	endpoint := filter.Endpoint(ctx)
-	expectedEndpoint := criteriaEndpointMapping[constants.CriteriaTypeAlternateEmail]
-
-	assert.Equal(t, expectedEndpoint, endpoint)
-	assert.Contains(t, endpoint, "users?q=identities.profileData.email:")
-}
-
-func Test_alternateEmailFilter_Args(t *testing.T) {
+func Test_alternateEmailFilter_Query(t *testing.T) {
 	ctx := context.Background()
 
 	tests := []struct {
 		name            string
 		alternateEmails []model.Email
-		wantLen         int
-		wantFirst       string
+		want            string
 	}{
 		{
-			name: "returns escaped alternate email",
+			name: "matches linked identity email",
 			alternateEmails: []model.Email{
 				{Email: "alt@example.com", Verified: true},
 			},
-			wantLen:   1,
-			wantFirst: "alt%40example.com",
+			want: `identities.profileData.email:"alt@example.com"`,
 		},
 		{
-			name: "returns first email when multiple exist",
+			name: "uses first email when multiple exist",
 			alternateEmails: []model.Email{
 				{Email: "first@example.com", Verified: true},
 				{Email: "second@example.com", Verified: false},
 			},
-			wantLen:   1,
-			wantFirst: "first%40example.com",
+			want: `identities.profileData.email:"first@example.com"`,
 		},
 		{
-			name:            "returns empty array when no alternate emails",
+			name:            "empty when no alternate emails",
 			alternateEmails: []model.Email{},
-			wantLen:         0,
+			want:            "",
 		},
 		{
-			name:            "returns empty array when alternate email is nil",
+			name:            "empty when alternate email is nil",
 			alternateEmails: nil,
-			wantLen:         0,
+			want:            "",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			user := &model.User{AlternateEmails: tt.alternateEmails}
-			filter := &alternateEmailFilter{user: user}
-
-			args := filter.Args(ctx)
-			assert.Len(t, args, tt.wantLen)
-
-			if tt.wantLen > 0 {
-				assert.Equal(t, tt.wantFirst, args[0])
-			}
+			filter := &alternateEmailFilter{user: &model.User{AlternateEmails: tt.alternateEmails}}
+			assert.Equal(t, tt.want, filter.Query(ctx).String())
 		})
 	}
 }
@@ -663,37 +688,96 @@ func Test_alternateEmailFilter_Filter(t *testing.T) {
 	}
 }
 
-func Test_criteriaEndpointMapping(t *testing.T) {
-	// Test that all expected criteria types have endpoints defined
-	expectedCriteria := []string{
-		constants.CriteriaTypeEmail,
-		constants.CriteriaTypeUsername,
-		constants.CriteriaTypeAlternateEmail,
+func Test_secondaryEmailFilter(t *testing.T) {
+	ctx := context.Background()
+	user := &model.User{SecondaryEmails: []model.Email{{Email: "Octo@users.example.com"}}}
+	filter := &secondaryEmailFilter{user: user}
+
+	assert.Equal(t, `identities.profileData.email:"Octo@users.example.com"`, filter.Query(ctx).String())
+
+	verified := &Auth0User{Identities: []Auth0Identity{
+		{Connection: "github", ProfileData: &Auth0ProfileData{Email: "octo@users.example.com", EmailVerified: true}},
+	}}
+	found, err := filter.Filter(ctx, verified)
+	require.NoError(t, err)
+	assert.True(t, found)
+
+	unverified := &Auth0User{Identities: []Auth0Identity{
+		{Connection: "github", ProfileData: &Auth0ProfileData{Email: "octo@users.example.com"}},
+	}}
+	found, err = filter.Filter(ctx, unverified)
+	require.NoError(t, err)
+	assert.False(t, found, "unverified identity emails must not resolve the account")
+
+	_, err = (&secondaryEmailFilter{user: &model.User{}}).Lookup(ctx, newTestReaderWriter(&searchTransport{}).api(), "token")
+	assert.IsType(t, errors.Validation{}, err)
+}
+
+func Test_nameFilter(t *testing.T) {
+	ctx := context.Background()
+	name := "Jane (JD) Doe"
+	filter := &nameFilter{user: &model.User{UserMetadata: &model.UserMetadata{Name: &name}}}
+
+	assert.Equal(t, `user_metadata.name:"Jane (JD) Doe" OR name:"Jane (JD) Doe"`, filter.Query(ctx).String())
+
+	metadataName := "jane (jd) doe"
+	tests := []struct {
+		name      string
+		auth0User *Auth0User
+		wantMatch bool
+	}{
+		{
+			name:      "matches profile name case-insensitively",
+			auth0User: &Auth0User{UserMetadata: &Auth0UserMetadata{Name: &metadataName}},
+			wantMatch: true,
+		},
+		{
+			name:      "matches identity provider name",
+			auth0User: &Auth0User{Name: "Jane (JD) Doe"},
+			wantMatch: true,
+		},
+		{
+			name:      "no match on partial name",
+			auth0User: &Auth0User{Name: "Jane Doe"},
+			wantMatch: false,
+		},
 	}
 
-	for _, criteria := range expectedCriteria {
-		t.Run("has endpoint for "+criteria, func(t *testing.T) {
-			endpoint, exists := criteriaEndpointMapping[criteria]
-			assert.True(t, exists, "endpoint should exist for criteria: %s", criteria)
-			assert.NotEmpty(t, endpoint, "endpoint should not be empty for criteria: %s", criteria)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := filter.Filter(ctx, tt.auth0User)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantMatch, got)
 		})
 	}
 
-	// Test endpoint formats
-	t.Run("email endpoint has correct format", func(t *testing.T) {
-		endpoint := criteriaEndpointMapping[constants.CriteriaTypeEmail]
-		assert.Contains(t, endpoint, "users-by-email?email=%s")
+	t.Run("requires a name", func(t *testing.T) {
+		empty := &nameFilter{user: &model.User{}}
+		assert.True(t, empty.Query(ctx).IsZero())
+		_, err := empty.Lookup(ctx, newTestReaderWriter(&searchTransport{}).api(), "token")
+		assert.IsType(t, errors.Validation{}, err)
 	})
+}
 
-	t.Run("username endpoint has correct format", func(t *testing.T) {
-		endpoint := criteriaEndpointMapping[constants.CriteriaTypeUsername]
-		assert.Contains(t, endpoint, "users?q=identities.user_id:")
-		assert.Contains(t, endpoint, "search_engine=v3")
-	})
+// searchTransport records the last request and answers with a canned body
+type searchTransport struct {
+	status int
+	body   string
+	path   string
+	query  url.Values
+}
 
-	t.Run("alternate email endpoint has correct format", func(t *testing.T) {
-		endpoint := criteriaEndpointMapping[constants.CriteriaTypeAlternateEmail]
-		assert.Contains(t, endpoint, "users?q=identities.profileData.email:")
-		assert.Contains(t, endpoint, "search_engine=v3")
-	})
+func (s *searchTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	s.path = req.URL.Path
+	s.query = req.URL.Query()
+	status := s.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(s.body)),
+		Request:    req,
+	}, nil
 }
@@ -0,0 +1,46 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package auth0
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/golden"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGetUser_GoldenFixtures replays the recorded Management API users of
+// testdata/users through GetUser and compares the resulting users with their
+// golden files. Refresh the fixtures with cmd/fixturegen.
+func TestGetUser_GoldenFixtures(t *testing.T) {
+	fixtures, err := filepath.Glob(filepath.Join("testdata", "users", "*.json"))
+	require.NoError(t, err)
+	require.NotEmpty(t, fixtures)
+
+	for _, fixture := range fixtures {
+		if strings.HasSuffix(fixture, ".golden.json") {
+			continue
+		}
+		name := strings.TrimSuffix(filepath.Base(fixture), ".json")
+		t.Run(name, func(t *testing.T) {
+			var recorded struct {
+				UserID string `json:"user_id"`
+			}
+			require.NoError(t, json.Unmarshal(golden.Read(t, fixture), &recorded))
+			require.NotEmpty(t, recorded.UserID, "fixture has no user_id")
+
+			rw := newTestReaderWriter(golden.Transport{"/users/" + recorded.UserID: fixture})
+			user, err := rw.GetUser(context.Background(), &model.User{UserID: recorded.UserID})
+			require.NoError(t, err)
+
+			user.Token = ""
+			golden.Assert(t, strings.TrimSuffix(fixture, ".json")+".golden.json", user)
+		})
+	}
+}
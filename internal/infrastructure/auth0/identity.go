@@ -5,12 +5,10 @@ package auth0
 
 import (
 	"context"
-	"fmt"
 	"log/slog"
-	"net/http"
-	"net/url"
 	"strings"
 
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/auth0/client"
 	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
 	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/httpclient"
 	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/redaction"
@@ -52,24 +50,11 @@ func (ilf *identityLinkingFlow) LinkIdentityToUser(ctx context.Context, userID,
 	// Call Auth0 Management API to link the identity
 	// IMPORTANT: Using the user's management API token (with update:current_user_identities scope)
 	// NOT the service's M2M credentials
-	url := fmt.Sprintf("https://%s/api/v2/users/%s/identities", ilf.domain, url.PathEscape(userID))
-
-	apiRequest := httpclient.NewAPIRequest(
-		ilf.httpClient,
-		httpclient.WithMethod(http.MethodPost),
-		httpclient.WithURL(url),
-		httpclient.WithToken(userToken),
-		httpclient.WithDescription("link identity to user"),
-		httpclient.WithBody(payload),
-	)
-
-	// The response is an array of linked identities
-	var linkedIdentities []any
-	statusCode, errCall := apiRequest.Call(ctx, &linkedIdentities)
+	errCall := ilf.api().LinkIdentity(ctx, userToken, userID, payload)
 	if errCall != nil {
 		slog.ErrorContext(ctx, "failed to link identity to user",
 			"error", errCall,
-			"status_code", statusCode,
+			"status_code", client.StatusCode(errCall),
 			"user_id", redaction.Redact(userID),
 		)
 		return errors.NewUnexpected("failed to link identity to user", errCall)
@@ -77,7 +62,6 @@ func (ilf *identityLinkingFlow) LinkIdentityToUser(ctx context.Context, userID,
 
 	slog.DebugContext(ctx, "identity linked successfully",
 		"user_id", redaction.Redact(userID),
-		"status_code", statusCode,
 	)
 
 	return nil
@@ -115,28 +99,11 @@ func (ilf *identityLinkingFlow) UnlinkIdentityFromUser(ctx context.Context, prim
 	// Call Auth0 Management API to unlink the identity
 	// IMPORTANT: Using the user's management API token (with update:current_user_identities scope)
 	// NOT the service's M2M credentials
-	url := fmt.Sprintf("https://%s/api/v2/users/%s/identities/%s/%s",
-		ilf.domain,
-		url.PathEscape(primaryUserID),
-		url.PathEscape(provider),
-		url.PathEscape(secondaryUserID),
-	)
-
-	apiRequest := httpclient.NewAPIRequest(
-		ilf.httpClient,
-		httpclient.WithMethod(http.MethodDelete),
-		httpclient.WithURL(url),
-		httpclient.WithToken(userToken),
-		httpclient.WithDescription("unlink identity from user"),
-	)
-
-	// The response is an array of remaining identities
-	var remainingIdentities []any
-	statusCode, errCall := apiRequest.Call(ctx, &remainingIdentities)
+	errCall := ilf.api().UnlinkIdentity(ctx, userToken, primaryUserID, provider, secondaryUserID)
 	if errCall != nil {
 		slog.ErrorContext(ctx, "failed to unlink identity from user",
 			"error", errCall,
-			"status_code", statusCode,
+			"status_code", client.StatusCode(errCall),
 			"user_id", redaction.Redact(primaryUserID),
 		)
 		return errors.NewUnexpected("failed to unlink identity from user", errCall)
@@ -144,12 +111,16 @@ func (ilf *identityLinkingFlow) UnlinkIdentityFromUser(ctx context.Context, prim
 
 	slog.DebugContext(ctx, "identity unlinked successfully",
 		"user_id", redaction.Redact(primaryUserID),
-		"status_code", statusCode,
 	)
 
 	return nil
 }
 
+// api returns the typed Management API client for the tenant
+func (ilf *identityLinkingFlow) api() *client.Client {
+	return client.New(ilf.domain, ilf.httpClient)
+}
+
 // newIdentityLinkingFlow creates a new IdentityLinkingFlow with the provided configuration
 func newIdentityLinkingFlow(domain string, httpClient *httpclient.Client) *identityLinkingFlow {
 	return &identityLinkingFlow{
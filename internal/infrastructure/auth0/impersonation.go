@@ -48,7 +48,9 @@ type cteResponse struct {
 // NewImpersonationFlow creates an impersonation flow loaded from environment variables.
 // It reuses AUTH0_M2M_CLIENT_ID and AUTH0_M2M_PRIVATE_BASE64_KEY, plus the new
 // AUTH0_LFX_V2_API_AUDIENCE for the CTE subject_token_type / audience.
-func NewImpersonationFlow(ctx context.Context, domain string) (port.Impersonator, error) {
+// Only httpConfig's transport settings are used: token exchanges keep a 10s
+// timeout and are never retried.
+func NewImpersonationFlow(ctx context.Context, domain string, httpConfig httpclient.Config) (port.Impersonator, error) {
 	clientID := os.Getenv(constants.Auth0M2MClientIDEnvKey)
 	if clientID == "" {
 		return nil, errors.NewUnexpected(constants.Auth0M2MClientIDEnvKey + " is required")
@@ -74,6 +76,9 @@ func NewImpersonationFlow(ctx context.Context, domain string) (port.Impersonator
 		return nil, errors.NewUnexpected("failed to parse private key", err)
 	}
 
+	httpConfig.Timeout = 10 * time.Second
+	httpConfig.MaxRetries = 0
+
 	slog.DebugContext(ctx, "impersonation flow initialized",
 		"client_id", clientID,
 		"domain", domain,
@@ -85,7 +90,7 @@ func NewImpersonationFlow(ctx context.Context, domain string) (port.Impersonator
 		privateKey:    rsaKey,
 		domain:        domain,
 		lfxV2Audience: lfxV2Audience,
-		httpClient:    httpclient.NewClient(httpclient.Config{Timeout: 10 * time.Second}),
+		httpClient:    httpclient.NewClient(httpConfig),
 	}, nil
 }
 
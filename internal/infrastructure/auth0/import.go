@@ -0,0 +1,126 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package auth0
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/auth0/client"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/deadline"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+)
+
+// ImportUsers uploads the manifest users as a users import job of the
+// connection. It implements port.UserImporter.
+func (u *userReaderWriter) ImportUsers(ctx context.Context, manifest *model.UserImport) (*model.UserImportJob, error) {
+	ctx, cancel := u.config.Timeouts.Bound(ctx, deadline.OperationJobs)
+	defer cancel()
+
+	users, err := json.Marshal(manifest.Users)
+	if err != nil {
+		return nil, errors.NewValidation("invalid users", err)
+	}
+
+	m2mToken, errToken := u.config.M2MTokenManager.GetToken(ctx)
+	if errToken != nil {
+		return nil, errors.NewUnexpected("failed to get M2M token for users import", errToken)
+	}
+
+	job, err := u.api().ImportUsers(ctx, m2mToken, client.UsersImportRequest{
+		ConnectionID:        manifest.ConnectionID,
+		Users:               users,
+		Upsert:              manifest.Upsert,
+		ExternalID:          manifest.ExternalID,
+		SendCompletionEmail: manifest.SendCompletionEmail,
+	})
+	if err != nil {
+		return nil, importError(ctx, "failed to start users import", err)
+	}
+	return toUserImportJob(job), nil
+}
+
+// UserImportJob returns a users import job, with the records it rejected
+// once it is done. It implements port.UserImporter.
+func (u *userReaderWriter) UserImportJob(ctx context.Context, jobID string) (*model.UserImportJob, error) {
+	ctx, cancel := u.config.Timeouts.Bound(ctx, deadline.OperationGet)
+	defer cancel()
+
+	m2mToken, errToken := u.config.M2MTokenManager.GetToken(ctx)
+	if errToken != nil {
+		return nil, errors.NewUnexpected("failed to get M2M token for users import", errToken)
+	}
+
+	api := u.api()
+	job, err := api.GetJob(ctx, m2mToken, jobID)
+	if err != nil {
+		return nil, importError(ctx, "failed to get users import", err)
+	}
+	if job.Type != "" && job.Type != "users_import" {
+		return nil, errors.NewNotFound("users import not found")
+	}
+
+	imported := toUserImportJob(job)
+	if !imported.Done() {
+		return imported, nil
+	}
+	jobErrors, err := api.JobErrors(ctx, m2mToken, jobID)
+	if err != nil {
+		return nil, importError(ctx, "failed to get users import errors", err)
+	}
+	for _, jobError := range jobErrors {
+		imported.Errors = append(imported.Errors, toUserImportError(jobError))
+	}
+	return imported, nil
+}
+
+func toUserImportJob(job *client.Job) *model.UserImportJob {
+	imported := &model.UserImportJob{
+		ID:             job.ID,
+		Status:         job.Status,
+		ConnectionID:   job.ConnectionID,
+		ExternalID:     job.ExternalID,
+		CreatedAt:      job.CreatedAt,
+		PercentageDone: job.PercentageDone,
+	}
+	if job.Summary != nil {
+		imported.Summary = &model.UserImportSummary{
+			Total:    job.Summary.Total,
+			Inserted: job.Summary.Inserted,
+			Updated:  job.Summary.Updated,
+			Failed:   job.Summary.Failed,
+		}
+	}
+	return imported
+}
+
+// toUserImportError keeps the identifiers of a rejected record; its other
+// attributes, password hashes included, aren't sent back to the caller
+func toUserImportError(jobError client.JobError) model.UserImportError {
+	rejected := model.UserImportError{Errors: make([]model.UserImportErrorDetail, 0, len(jobError.Errors))}
+	rejected.Email, _ = jobError.User["email"].(string)
+	rejected.UserID, _ = jobError.User["user_id"].(string)
+	for _, detail := range jobError.Errors {
+		rejected.Errors = append(rejected.Errors, model.UserImportErrorDetail{
+			Code:    detail.Code,
+			Message: detail.Message,
+			Path:    detail.Path,
+		})
+	}
+	return rejected
+}
+
+func importError(ctx context.Context, message string, err error) error {
+	statusCode := client.StatusCode(err)
+	slog.ErrorContext(ctx, message,
+		"error", err,
+		"status_code", statusCode,
+	)
+	return apiError(err)
+}
+
+var _ port.UserImporter = (*userReaderWriter)(nil)
@@ -6,13 +6,15 @@ package auth0
 import (
 	"context"
 	"crypto/rsa"
-	"encoding/json"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
 	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
 	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
 	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/httpclient"
@@ -30,6 +32,17 @@ type JWTVerificationConfig struct {
 	ExpectedAudience string
 	// JWKSURL is the URL to fetch JSON Web Key Set (optional, alternative to PublicKey)
 	JWKSURL string
+	// KeyID is the kid of PublicKey in the JWKS, when it was loaded from there
+	KeyID string
+	// LoadedAt is when PublicKey was loaded from the JWKS
+	LoadedAt time.Time
+	// Revocations rejects tokens revoked before they expire (optional)
+	Revocations port.TokenRevocationChecker
+	// DPoP rejects DPoP-bound tokens sent without a valid proof (optional)
+	DPoP *jwtparser.DPoPVerifier
+
+	verifierOnce sync.Once
+	verifier     *jwtparser.Verifier
 }
 
 // JWTVerify verifies a JWT token with the specified required scope
@@ -40,28 +53,34 @@ func (j *JWTVerificationConfig) JWTVerify(ctx context.Context, token string, req
 		return nil, errors.NewValidation("JWT verification configuration is required")
 	}
 
-	// Configure JWT parsing options with signature verification
-	opts := &jwtparser.ParseOptions{
-		RequireExpiration: true,
-		AllowBearerPrefix: true,
-		RequireSubject:    true,
-		VerifySignature:   true,
-		SigningKey:        j.PublicKey,
-		ExpectedIssuer:    j.ExpectedIssuer,
-		ExpectedAudience:  j.ExpectedAudience,
-	}
-
-	if len(requiredScope) > 0 {
-		opts.RequiredScopes = requiredScope
-	}
+	j.prepareVerifier()
 
 	// Parse and validate the JWT token with signature verification
-	claims, err := jwtparser.ParseVerified(ctx, token, opts)
+	claims, err := j.verifier.Parse(ctx, token, requiredScope...)
 	if err != nil {
 		slog.ErrorContext(ctx, "JWT signature verification failed",
 			"error", err,
 			"required_scope", requiredScope)
-		return nil, err
+		if err.Error() == jwtparser.ErrMissingRequiredScope {
+			return nil, err
+		}
+		return nil, errors.NewTokenInvalid(err.Error())
+	}
+
+	if j.Revocations != nil && j.Revocations.IsRevoked(claims.ID, claims.Subject, claims.IssuedAt) {
+		slog.WarnContext(ctx, "revoked JWT rejected",
+			"user_id", redaction.Redact(claims.Subject),
+			"jti", claims.ID)
+		return nil, errors.NewUnauthorized("token has been revoked")
+	}
+
+	if j.DPoP != nil {
+		if err := j.DPoP.Verify(ctx, token, claims); err != nil {
+			slog.WarnContext(ctx, "DPoP proof rejected",
+				"user_id", redaction.Redact(claims.Subject),
+				"error", err)
+			return nil, err
+		}
 	}
 
 	slog.DebugContext(ctx, "JWT signature verification successful",
@@ -75,12 +94,37 @@ func (j *JWTVerificationConfig) JWTVerify(ctx context.Context, token string, req
 	return claims, nil
 }
 
-// NewJWTVerificationConfig creates a JWT verification configuration
-func NewJWTVerificationConfig(ctx context.Context, domain string, httpClient *httpclient.Client) (*JWTVerificationConfig, error) {
-	// Try to load from JWKS URL first (recommended for Auth0)
-	jwksURL := fmt.Sprintf("https://%s/.well-known/jwks.json", domain)
+// prepareVerifier builds the verifier, on first use or at warm-up, once the
+// key and the expected claims are set; it is reused for every token after
+func (j *JWTVerificationConfig) prepareVerifier() {
+	j.verifierOnce.Do(func() {
+		j.verifier = jwtparser.NewVerifier(&jwtparser.ParseOptions{
+			RequireExpiration: true,
+			AllowBearerPrefix: true,
+			RequireSubject:    true,
+			VerifySignature:   true,
+			SigningKey:        j.PublicKey,
+			ExpectedIssuer:    j.ExpectedIssuer,
+			ExpectedAudience:  j.ExpectedAudience,
+			ExpectedKeyID:     j.KeyID,
+		})
+	})
+}
 
-	// Fetch JWKS from Auth0 using the existing httpclient
+// jwksDocument is the subset of a JSON Web Key Set used to load RSA keys
+type jwksDocument struct {
+	Keys []struct {
+		Kty string `json:"kty"`
+		Use string `json:"use,omitempty"`
+		Kid string `json:"kid,omitempty"`
+		Alg string `json:"alg,omitempty"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+	} `json:"keys"`
+}
+
+// fetchJWKS fetches the JSON Web Key Set at jwksURL
+func fetchJWKS(ctx context.Context, httpClient *httpclient.Client, jwksURL string) (*jwksDocument, error) {
 	apiRequest := httpclient.NewAPIRequest(
 		httpClient,
 		httpclient.WithMethod(http.MethodGet),
@@ -88,18 +132,7 @@ func NewJWTVerificationConfig(ctx context.Context, domain string, httpClient *ht
 		httpclient.WithDescription("fetch Auth0 JWKS"),
 	)
 
-	// Parse JWKS and extract the first RSA key
-	var jwks struct {
-		Keys []struct {
-			Kty string `json:"kty"`
-			Use string `json:"use,omitempty"`
-			Kid string `json:"kid,omitempty"`
-			Alg string `json:"alg,omitempty"`
-			N   string `json:"n"`
-			E   string `json:"e"`
-		} `json:"keys"`
-	}
-
+	var jwks jwksDocument
 	statusCode, err := apiRequest.Call(ctx, &jwks)
 	if err != nil {
 		return nil, errors.NewUnexpected("failed to fetch JWKS", err)
@@ -108,17 +141,26 @@ func NewJWTVerificationConfig(ctx context.Context, domain string, httpClient *ht
 	if statusCode != http.StatusOK {
 		return nil, errors.NewUnexpected(fmt.Sprintf("JWKS endpoint returned status %d", statusCode))
 	}
+	return &jwks, nil
+}
+
+// NewJWTVerificationConfig creates a JWT verification configuration
+func NewJWTVerificationConfig(ctx context.Context, domain string, httpClient *httpclient.Client) (*JWTVerificationConfig, error) {
+	// Try to load from JWKS URL first (recommended for Auth0)
+	jwksURL := fmt.Sprintf("https://%s/.well-known/jwks.json", domain)
+
+	// Fetch JWKS from Auth0 using the existing httpclient
+	jwks, err := fetchJWKS(ctx, httpClient, jwksURL)
+	if err != nil {
+		return nil, err
+	}
 
 	// Find the first RSA key suitable for signature verification
 	for _, key := range jwks.Keys {
 		if key.Kty == "RSA" && (key.Use == "sig" || key.Use == "") {
-			// Convert JWK to RSA public key
-			jwkData, err := json.Marshal(key)
-			if err != nil {
-				continue
-			}
-
-			publicKey, err := jwtparser.LoadRSAPublicKeyFromJWK(jwkData)
+			// The key is built from the decoded modulus and exponent, rather
+			// than marshaling it back to JSON for a JWK parser
+			publicKey, err := jwtparser.RSAPublicKeyFromComponents(key.N, key.E)
 			if err != nil {
 				return nil, errors.NewUnexpected("failed to load RSA public key from JWK", err)
 			}
@@ -139,6 +181,8 @@ func NewJWTVerificationConfig(ctx context.Context, domain string, httpClient *ht
 				ExpectedIssuer:   expectedIssuer,
 				ExpectedAudience: expectedAudience,
 				JWKSURL:          jwksURL,
+				KeyID:            key.Kid,
+				LoadedAt:         time.Now(),
 			}, nil
 		}
 	}
@@ -4,16 +4,23 @@
 package auth0
 
 import (
+	"bytes"
 	"context"
 	"crypto/rand"
 	"crypto/rsa"
+	"io"
+	"net/http"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
 	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/golden"
 	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/httpclient"
+	jwtparser "github.com/linuxfoundation/lfx-v2-auth-service/pkg/jwt"
 )
 
 func TestJWTVerification(t *testing.T) {
@@ -120,9 +127,8 @@ func TestMetadataLookupWithJWTVerification(t *testing.T) {
 	// Create user reader writer
 	httpConfig := httpclient.Config{}
 	userRW := &userReaderWriter{
-		config:        config,
-		httpClient:    httpclient.NewClient(httpConfig),
-		errorResponse: NewErrorResponse(),
+		config:     config,
+		httpClient: httpclient.NewClient(httpConfig),
 	}
 
 	tests := []struct {
@@ -191,7 +197,7 @@ func createValidJWT(t *testing.T, privateKey *rsa.PrivateKey) string {
 	return tokenString
 }
 
-func createValidMetadataJWT(t *testing.T, privateKey *rsa.PrivateKey) string {
+func createValidMetadataJWT(t testing.TB, privateKey *rsa.PrivateKey) string {
 	now := time.Now()
 	claims := jwt.MapClaims{
 		"sub":   "test-user-123",
@@ -347,9 +353,8 @@ func TestMetadataLookupWithoutJWTVerificationConfig(t *testing.T) {
 	// Create user reader writer
 	httpConfig := httpclient.Config{}
 	userRW := &userReaderWriter{
-		config:        config,
-		httpClient:    httpclient.NewClient(httpConfig),
-		errorResponse: NewErrorResponse(),
+		config:     config,
+		httpClient: httpclient.NewClient(httpConfig),
 	}
 
 	tests := []struct {
@@ -385,3 +390,101 @@ func TestMetadataLookupWithoutJWTVerificationConfig(t *testing.T) {
 		})
 	}
 }
+
+// revokedSubjects revokes every token of its subjects
+type revokedSubjects map[string]bool
+
+func (r revokedSubjects) IsRevoked(jti, sub string, issuedAt *time.Time) bool {
+	return r[sub]
+}
+
+func TestJWTVerificationRejectsRevokedTokens(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	jwtVerify := &JWTVerificationConfig{
+		PublicKey:        &privateKey.PublicKey,
+		ExpectedIssuer:   "https://test.auth0.com/",
+		ExpectedAudience: "https://test.auth0.com/api/v2/",
+		Revocations:      revokedSubjects{"test-user-123": true},
+	}
+
+	_, err = jwtVerify.JWTVerify(context.Background(), createValidJWT(t, privateKey))
+	if _, ok := err.(errors.Unauthorized); !ok {
+		t.Errorf("Expected an unauthorized error for a revoked token, got %v", err)
+	}
+
+	jwtVerify.Revocations = revokedSubjects{"other-user": true}
+	if _, err := jwtVerify.JWTVerify(context.Background(), createValidJWT(t, privateKey)); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestJWTVerificationRequiresDPoPWhenConfigured(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	jwtVerify := &JWTVerificationConfig{
+		PublicKey:        &privateKey.PublicKey,
+		ExpectedIssuer:   "https://test.auth0.com/",
+		ExpectedAudience: "https://test.auth0.com/api/v2/",
+		DPoP:             jwtparser.NewDPoPVerifier(0, 10, true),
+	}
+
+	_, err = jwtVerify.JWTVerify(context.Background(), createValidJWT(t, privateKey))
+	if _, ok := err.(errors.Unauthorized); !ok {
+		t.Errorf("Expected an unauthorized error for a bearer token, got %v", err)
+	}
+
+	jwtVerify.DPoP = jwtparser.NewDPoPVerifier(0, 10, false)
+	if _, err := jwtVerify.JWTVerify(context.Background(), createValidJWT(t, privateKey)); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+// staticTransport answers every request with body, without touching the
+// network or the disk
+type staticTransport struct {
+	body []byte
+}
+
+func (s staticTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader(s.body)),
+		Request:    req,
+	}, nil
+}
+
+// BenchmarkMetadataLookupAndGetUser measures the hot read path: verifying the
+// caller's token, then fetching their user from the Management API
+func BenchmarkMetadataLookupAndGetUser(b *testing.B) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		b.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	token := createValidMetadataJWT(b, privateKey)
+
+	userJSON := golden.Read(b, filepath.Join("testdata", "users", "database_user.json"))
+	rw := newTestReaderWriter(staticTransport{body: userJSON})
+	rw.config.JWTVerificationConfig = &JWTVerificationConfig{
+		PublicKey:        &privateKey.PublicKey,
+		ExpectedIssuer:   "https://test.auth0.com/",
+		ExpectedAudience: "https://test.auth0.com/api/v2/",
+	}
+	ctx := context.Background()
+	b.ReportAllocs()
+
+	for b.Loop() {
+		user, err := rw.MetadataLookup(ctx, token, "read:current_user")
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := rw.GetUser(ctx, user); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package auth0
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/redaction"
+)
+
+// maxLogStreamBodyBytes bounds a single webhook delivery. Auth0 batches log
+// events, but a batch stays well below this limit.
+const maxLogStreamBodyBytes = 5 << 20
+
+// logEventTypes maps Auth0 log event codes to normalized login event types.
+// Codes not listed here are acknowledged and dropped.
+//
+// See https://auth0.com/docs/deploy-monitor/logs/log-event-type-codes
+var logEventTypes = map[string]string{
+	"s":                model.LoginEventTypeSuccess,
+	"f":                model.LoginEventTypeFailure,
+	"fp":               model.LoginEventTypeFailure,
+	"fu":               model.LoginEventTypeFailure,
+	"gd_auth_succeed":  model.LoginEventTypeMFASuccess,
+	"gd_auth_failed":   model.LoginEventTypeMFAFailure,
+	"gd_auth_rejected": model.LoginEventTypeMFAFailure,
+	"limit_wc":         model.LoginEventTypeBlocked,
+	"limit_mu":         model.LoginEventTypeBlocked,
+	"limit_sul":        model.LoginEventTypeBlocked,
+}
+
+// Auth0LogEvent is a single entry of an Auth0 Log Streaming webhook delivery
+type Auth0LogEvent struct {
+	LogID string          `json:"log_id"`
+	Data  Auth0LogDetails `json:"data"`
+}
+
+// Auth0LogDetails is the log record carried by an Auth0LogEvent
+type Auth0LogDetails struct {
+	Date        time.Time `json:"date"`
+	Type        string    `json:"type"`
+	Description string    `json:"description"`
+	Connection  string    `json:"connection"`
+	ClientID    string    `json:"client_id"`
+	IP          string    `json:"ip"`
+	UserID      string    `json:"user_id"`
+	UserName    string    `json:"user_name"`
+}
+
+// ToLoginEvent converts the log entry to a normalized login event. The second
+// return value is false when the event type is not login related.
+func (e *Auth0LogEvent) ToLoginEvent() (model.LoginEvent, bool) {
+	eventType, ok := logEventTypes[e.Data.Type]
+	if !ok {
+		return model.LoginEvent{}, false
+	}
+	return model.LoginEvent{
+		EventID:      e.LogID,
+		Type:         eventType,
+		ProviderType: e.Data.Type,
+		UserID:       e.Data.UserID,
+		Username:     e.Data.UserName,
+		Connection:   e.Data.Connection,
+		ClientID:     e.Data.ClientID,
+		IP:           redaction.TruncateIP(e.Data.IP),
+		Description:  e.Data.Description,
+		Timestamp:    e.Data.Date.UTC(),
+	}, true
+}
+
+// ParseLogStreamBatch decodes a webhook delivery and returns the login
+// related events it contains.
+func ParseLogStreamBatch(body []byte) ([]model.LoginEvent, error) {
+	var batch []Auth0LogEvent
+	if err := json.Unmarshal(body, &batch); err != nil {
+		return nil, errors.NewValidation("invalid log stream payload", err)
+	}
+
+	events := make([]model.LoginEvent, 0, len(batch))
+	for i := range batch {
+		if event, ok := batch[i].ToLoginEvent(); ok {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// logStreamHandler receives Auth0 Log Streaming (custom webhook) deliveries
+type logStreamHandler struct {
+	token    string
+	ingester func() port.LoginEventIngester
+}
+
+func (h *logStreamHandler) authorized(r *http.Request) bool {
+	got := strings.TrimSpace(r.Header.Get("Authorization"))
+	got = strings.TrimPrefix(got, "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(got), []byte(h.token)) == 1
+}
+
+func (h *logStreamHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if !h.authorized(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxLogStreamBodyBytes))
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	events, err := ParseLogStreamBatch(body)
+	if err != nil {
+		slog.WarnContext(ctx, "rejected auth0 log stream delivery", "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if len(events) > 0 {
+		ingester := h.ingester()
+		if ingester == nil {
+			// Auth0 retries non-2xx deliveries, so ask it to come back once
+			// the service has finished starting.
+			http.Error(w, "service unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		if err := ingester.IngestLoginEvents(ctx, events); err != nil {
+			slog.ErrorContext(ctx, "failed to ingest auth0 login events",
+				"error", err,
+				"events", len(events),
+			)
+			http.Error(w, "failed to ingest events", http.StatusServiceUnavailable)
+			return
+		}
+	}
+
+	slog.DebugContext(ctx, "auth0 log stream delivery processed", "login_events", len(events))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// NewLogStreamHandler returns an HTTP handler for Auth0 Log Streaming
+// webhooks. Requests must present token in the Authorization header (with or
+// without a "Bearer " prefix, matching the value configured in Auth0). The
+// ingester is resolved per request so the handler can be mounted before the
+// rest of the service is wired.
+func NewLogStreamHandler(ctx context.Context, token string, ingester func() port.LoginEventIngester) (http.Handler, error) {
+	if token == "" {
+		return nil, errors.NewValidation("log stream token is required")
+	}
+	slog.DebugContext(ctx, "auth0 log stream webhook enabled")
+	return &logStreamHandler{token: token, ingester: ingester}, nil
+}
@@ -0,0 +1,146 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package auth0
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+)
+
+const logStreamBatch = `[
+  {"log_id":"900","data":{"date":"2025-04-01T10:00:00.000Z","type":"s","connection":"Username-Password-Authentication","client_id":"abc","ip":"203.0.113.42","user_id":"auth0|zephyr001","user_name":"zephyr@example.com"}},
+  {"log_id":"901","data":{"date":"2025-04-01T10:01:00.000Z","type":"fp","ip":"203.0.113.42","user_id":"auth0|zephyr001"}},
+  {"log_id":"902","data":{"date":"2025-04-01T10:02:00.000Z","type":"gd_auth_succeed","user_id":"auth0|zephyr001"}},
+  {"log_id":"903","data":{"date":"2025-04-01T10:03:00.000Z","type":"limit_wc","ip":"198.51.100.9"}},
+  {"log_id":"904","data":{"date":"2025-04-01T10:04:00.000Z","type":"sapi","description":"Update user"}}
+]`
+
+type fakeIngester struct {
+	events []model.LoginEvent
+	err    error
+}
+
+func (f *fakeIngester) IngestLoginEvents(ctx context.Context, events []model.LoginEvent) error {
+	f.events = append(f.events, events...)
+	return f.err
+}
+
+func TestParseLogStreamBatch(t *testing.T) {
+	events, err := ParseLogStreamBatch([]byte(logStreamBatch))
+	require.NoError(t, err)
+	require.Len(t, events, 4, "management API event should be dropped")
+
+	assert.Equal(t, model.LoginEvent{
+		EventID:      "900",
+		Type:         model.LoginEventTypeSuccess,
+		ProviderType: "s",
+		UserID:       "auth0|zephyr001",
+		Username:     "zephyr@example.com",
+		Connection:   "Username-Password-Authentication",
+		ClientID:     "abc",
+		IP:           "203.0.113.0",
+		Timestamp:    time.Date(2025, 4, 1, 10, 0, 0, 0, time.UTC),
+	}, events[0])
+	assert.Equal(t, model.LoginEventTypeFailure, events[1].Type)
+	assert.Equal(t, model.LoginEventTypeMFASuccess, events[2].Type)
+	assert.Equal(t, model.LoginEventTypeBlocked, events[3].Type)
+	assert.Equal(t, "198.51.100.0", events[3].IP)
+
+	_, err = ParseLogStreamBatch([]byte(`{"not":"an array"}`))
+	assert.Error(t, err)
+}
+
+func TestLogStreamHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		authHeader string
+		body       string
+		ingester   *fakeIngester
+		wantStatus int
+		wantEvents int
+	}{
+		{
+			name:       "valid delivery with bearer prefix",
+			authHeader: "Bearer s3cret",
+			body:       logStreamBatch,
+			ingester:   &fakeIngester{},
+			wantStatus: http.StatusNoContent,
+			wantEvents: 4,
+		},
+		{
+			name:       "valid delivery with raw token",
+			authHeader: "s3cret",
+			body:       logStreamBatch,
+			ingester:   &fakeIngester{},
+			wantStatus: http.StatusNoContent,
+			wantEvents: 4,
+		},
+		{
+			name:       "wrong token",
+			authHeader: "Bearer nope",
+			body:       logStreamBatch,
+			ingester:   &fakeIngester{},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "malformed payload",
+			authHeader: "Bearer s3cret",
+			body:       "{",
+			ingester:   &fakeIngester{},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "ingester not ready",
+			authHeader: "Bearer s3cret",
+			body:       logStreamBatch,
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:       "ingest failure asks for redelivery",
+			authHeader: "Bearer s3cret",
+			body:       logStreamBatch,
+			ingester:   &fakeIngester{err: errors.New("publish failed")},
+			wantStatus: http.StatusServiceUnavailable,
+			wantEvents: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, err := NewLogStreamHandler(context.Background(), "s3cret", func() port.LoginEventIngester {
+				if tt.ingester == nil {
+					return nil
+				}
+				return tt.ingester
+			})
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodPost, "/webhooks/auth0/logs", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", tt.authHeader)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			if tt.ingester != nil {
+				assert.Len(t, tt.ingester.events, tt.wantEvents)
+			}
+		})
+	}
+}
+
+func TestNewLogStreamHandler_RequiresToken(t *testing.T) {
+	_, err := NewLogStreamHandler(context.Background(), "", nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,67 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package auth0
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/auth0/client"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/redaction"
+)
+
+// MergeUsers stores the merged metadata on the primary user, then links the
+// secondary user to it as an identity. Auth0 deletes the secondary's own
+// profile when it is linked, so the metadata goes first: a merge that fails
+// to link can be run again. It implements port.UserMerger.
+func (u *userReaderWriter) MergeUsers(ctx context.Context, merge *model.UserMerge) error {
+	if merge == nil || merge.Primary == nil || merge.Secondary == nil {
+		return errors.NewValidation("primary and secondary users are required")
+	}
+	primaryID, secondaryID := merge.Primary.UserID, merge.Secondary.UserID
+	provider, localID, ok := strings.Cut(secondaryID, "|")
+	if primaryID == "" || !ok || provider == "" || localID == "" {
+		return errors.NewValidation("invalid user_id of the users to merge")
+	}
+
+	m2mToken, errToken := u.config.M2MTokenManager.GetToken(ctx)
+	if errToken != nil {
+		return errors.NewUnexpected("failed to get M2M token for user merge", errToken)
+	}
+
+	if merge.Metadata != nil {
+		errUpdate := u.api().UpdateUser(ctx, m2mToken, primaryID, userUpdateRequest{UserMetadata: merge.Metadata}, nil)
+		if errUpdate != nil {
+			slog.ErrorContext(ctx, "failed to store merged metadata on the primary user",
+				"error", errUpdate,
+				"status_code", client.StatusCode(errUpdate),
+				"primary_user_id", redaction.Redact(primaryID),
+			)
+			return errors.NewUnexpected("failed to update the primary user", errUpdate)
+		}
+	}
+
+	errLink := u.api().LinkIdentity(ctx, m2mToken, primaryID, linkSubIdentityPayload{Provider: provider, UserID: localID})
+	if errLink != nil {
+		slog.ErrorContext(ctx, "failed to link the secondary user to the primary user",
+			"error", errLink,
+			"status_code", client.StatusCode(errLink),
+			"primary_user_id", redaction.Redact(primaryID),
+			"secondary_user_id", redaction.Redact(secondaryID),
+		)
+		return errors.NewUnexpected("failed to link the secondary user", errLink)
+	}
+
+	slog.DebugContext(ctx, "users merged",
+		"primary_user_id", redaction.Redact(primaryID),
+		"secondary_user_id", redaction.Redact(secondaryID),
+	)
+	return nil
+}
+
+var _ port.UserMerger = (*userReaderWriter)(nil)
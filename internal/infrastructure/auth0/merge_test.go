@@ -0,0 +1,66 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package auth0
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserReaderWriter_MergeUsers(t *testing.T) {
+	ctx := context.Background()
+	name := "Jane Doe"
+	merge := func() *model.UserMerge {
+		return &model.UserMerge{
+			Primary:   &model.User{UserID: testPrimaryUserID},
+			Secondary: &model.User{UserID: "google-oauth2|1234"},
+			Metadata:  &model.UserMetadata{Name: &name},
+		}
+	}
+
+	t.Run("stores the metadata then links the secondary", func(t *testing.T) {
+		ft := newFakeAuth0(testPrimaryUserID, `{}`)
+		require.NoError(t, newTestReaderWriter(ft).MergeUsers(ctx, merge()))
+
+		require.Len(t, ft.calls, 2)
+		assert.Equal(t, http.MethodPatch, ft.calls[0].method)
+		assert.JSONEq(t, `{"user_metadata":{"name":"Jane Doe"}}`, ft.calls[0].body)
+		assert.Equal(t, "/api/v2/users/"+testPrimaryUserID+"/identities", ft.calls[1].path)
+		assert.JSONEq(t, `{"provider":"google-oauth2","user_id":"1234"}`, ft.calls[1].body)
+	})
+
+	t.Run("failed metadata update doesn't link", func(t *testing.T) {
+		ft := newFakeAuth0(testPrimaryUserID, `{}`)
+		ft.patchStatus = http.StatusBadRequest
+		err := newTestReaderWriter(ft).MergeUsers(ctx, merge())
+
+		var unexpected errors.Unexpected
+		assert.ErrorAs(t, err, &unexpected)
+		assert.Len(t, ft.calls, 1)
+	})
+
+	t.Run("failed link", func(t *testing.T) {
+		ft := newFakeAuth0(testPrimaryUserID, `{}`)
+		ft.linkStatus = http.StatusConflict
+		err := newTestReaderWriter(ft).MergeUsers(ctx, merge())
+		assert.ErrorContains(t, err, "failed to link the secondary user")
+	})
+
+	t.Run("secondary user_id without a provider", func(t *testing.T) {
+		ft := newFakeAuth0(testPrimaryUserID, `{}`)
+		invalid := merge()
+		invalid.Secondary.UserID = "1234"
+		err := newTestReaderWriter(ft).MergeUsers(ctx, invalid)
+
+		var validation errors.Validation
+		assert.ErrorAs(t, err, &validation)
+		assert.Empty(t, ft.calls)
+	})
+}
@@ -4,12 +4,14 @@
 package auth0
 
 import (
-	"encoding/json"
 	"fmt"
-	"log/slog"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/redaction"
 )
 
 // Auth0User represents a user in Auth0
@@ -17,6 +19,7 @@ type Auth0User struct {
 	UserID         string             `json:"user_id"`
 	Username       string             `json:"username"`
 	Email          string             `json:"email"`
+	Name           string             `json:"name,omitempty"`
 	EmailVerified  bool               `json:"email_verified"`
 	FamilyName     string             `json:"family_name"`
 	GivenName      string             `json:"given_name"`
@@ -24,6 +27,9 @@ type Auth0User struct {
 	AlternateEmail []Auth0ProfileData `json:"alternate_email,omitempty"`
 	UserMetadata   *Auth0UserMetadata `json:"user_metadata"`
 	AppMetadata    *Auth0AppMetadata  `json:"app_metadata,omitempty"`
+	LastLogin      *time.Time         `json:"last_login,omitempty"`
+	LoginsCount    *int               `json:"logins_count,omitempty"`
+	LastIP         string             `json:"last_ip,omitempty"`
 }
 
 // Auth0AppMetadata represents the application-level metadata Auth0 stores on a user.
@@ -88,6 +94,13 @@ type Auth0UserMetadata struct {
 	PhoneNumber   *string `json:"phone_number"`
 	TShirtSize    *string `json:"t_shirt_size"`
 	Zoneinfo      *string `json:"zoneinfo"`
+	Locale        *string `json:"locale"`
+	Timezone      *string `json:"timezone"`
+	Pronouns      *string `json:"pronouns"`
+	PreferredName *string `json:"preferred_name"`
+
+	Preferences *model.UserPreferences `json:"preferences,omitempty"`
+	Privacy     *model.UserPrivacy     `json:"privacy,omitempty"`
 }
 
 // ToUser converts an Auth0User to a User
@@ -109,24 +122,23 @@ func (u *Auth0User) ToUser() *model.User {
 			PhoneNumber:   u.UserMetadata.PhoneNumber,
 			TShirtSize:    u.UserMetadata.TShirtSize,
 			Zoneinfo:      u.UserMetadata.Zoneinfo,
+			Locale:        u.UserMetadata.Locale,
+			Timezone:      u.UserMetadata.Timezone,
+			Pronouns:      u.UserMetadata.Pronouns,
+			PreferredName: u.UserMetadata.PreferredName,
+			Preferences:   u.UserMetadata.Preferences,
+			Privacy:       u.UserMetadata.Privacy,
 		}
 	}
 
-	var identities []model.Identity
+	var (
+		identities      []model.Identity
+		secondaryEmails []model.Email
+	)
 	for _, auth0Id := range u.Identities {
-		var identityID string
-		switch v := auth0Id.UserID.(type) {
-		case float64:
-			identityID = strconv.FormatFloat(v, 'f', -1, 64)
-		case string:
-			identityID = v
-		default:
-			identityID = fmt.Sprintf("%v", v)
-		}
-
 		identity := model.Identity{
 			Provider:   auth0Id.Provider,
-			IdentityID: identityID,
+			IdentityID: identityUserID(auth0Id.UserID),
 			Connection: auth0Id.Connection,
 			IsSocial:   auth0Id.IsSocial,
 		}
@@ -135,47 +147,63 @@ func (u *Auth0User) ToUser() *model.User {
 			identity.EmailVerified = auth0Id.ProfileData.EmailVerified
 			identity.Nickname = auth0Id.ProfileData.Nickname
 			identity.Name = auth0Id.ProfileData.Name
+			secondaryEmails = u.appendSecondaryEmail(secondaryEmails, auth0Id)
 		}
 		identities = append(identities, identity)
 	}
 
 	return &model.User{
-		UserID:       u.UserID,
-		Username:     u.Username,
-		PrimaryEmail: u.Email,
-		Identities:   identities,
-		UserMetadata: meta,
+		UserID:          u.UserID,
+		Username:        u.Username,
+		PrimaryEmail:    u.Email,
+		EmailVerified:   u.EmailVerified,
+		Identities:      identities,
+		SecondaryEmails: secondaryEmails,
+		UserMetadata:    meta,
+		Activity:        u.activity(),
 	}
 }
 
-// ErrorResponse represents an error response from Auth0
-type ErrorResponse struct {
-	StatusCode int    `json:"statusCode"`
-	Error      string `json:"error"`
-	Message    string `json:"message"`
-	Attributes struct {
-		Error string `json:"error"`
-	} `json:"attributes"`
+// appendSecondaryEmail adds the identity's email unless it is the primary
+// email, a linked alternate email (email connection) or already listed
+func (u *Auth0User) appendSecondaryEmail(emails []model.Email, identity Auth0Identity) []model.Email {
+	email := identity.ProfileData.Email
+	if email == "" || identity.Connection == constants.EmailConnection || strings.EqualFold(email, u.Email) {
+		return emails
+	}
+	for i, existing := range emails {
+		if strings.EqualFold(existing.Email, email) {
+			emails[i].Verified = existing.Verified || identity.ProfileData.EmailVerified
+			return emails
+		}
+	}
+	return append(emails, model.Email{Email: email, Verified: identity.ProfileData.EmailVerified})
 }
 
-// Message returns the error message from the attributes
-func (e *ErrorResponse) ErrorMessage(errorMessage string) string {
-	var parsed ErrorResponse
-	// parse the error message from the attributes
-	err := json.Unmarshal([]byte(errorMessage), &parsed)
-	if err != nil {
-		slog.Error("failed to parse error message from attributes", "error", err)
-		return errorMessage
-	}
-	if parsed.Message != "" {
-		return parsed.Message
+// identityUserID formats an identity user_id, which Auth0 returns as a number
+// for some social providers (e.g. GitHub)
+func identityUserID(userID any) string {
+	switch v := userID.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		return v
+	default:
+		return fmt.Sprintf("%v", v)
 	}
-	return errorMessage
 }
 
-// NewErrorResponse creates a new ErrorResponse
-func NewErrorResponse() *ErrorResponse {
-	return &ErrorResponse{}
+// activity maps the Auth0 login statistics, truncating the last IP so the
+// full address never leaves the adapter. Returns nil when Auth0 reported none.
+func (u *Auth0User) activity() *model.UserActivity {
+	if u.LastLogin == nil && u.LoginsCount == nil && u.LastIP == "" {
+		return nil
+	}
+	return &model.UserActivity{
+		LastLogin:   u.LastLogin,
+		LoginsCount: u.LoginsCount,
+		LastIP:      redaction.TruncateIP(u.LastIP),
+	}
 }
 
 // PasswordlessStartResponse represents the response from Auth0 passwordless start endpoint
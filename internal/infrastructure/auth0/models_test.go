@@ -5,6 +5,7 @@ package auth0
 
 import (
 	"testing"
+	"time"
 
 	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
 	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/converters"
@@ -13,6 +14,9 @@ import (
 )
 
 func TestAuth0User_ToUser(t *testing.T) {
+	lastLogin := time.Date(2025, 2, 3, 4, 5, 6, 0, time.UTC)
+	loginsCount := 17
+
 	tests := []struct {
 		name      string
 		auth0User Auth0User
@@ -42,6 +46,7 @@ func TestAuth0User_ToUser(t *testing.T) {
 				assert.Equal(t, "auth0|abc123", user.UserID)
 				assert.Equal(t, "johndoe", user.Username)
 				assert.Equal(t, "john@example.com", user.PrimaryEmail)
+				assert.True(t, user.EmailVerified)
 
 				require.Len(t, user.Identities, 1)
 				id := user.Identities[0]
@@ -52,6 +57,22 @@ func TestAuth0User_ToUser(t *testing.T) {
 				assert.True(t, id.EmailVerified)
 			},
 		},
+		{
+			name: "identity emails become secondary emails",
+			auth0User: Auth0User{
+				UserID: "auth0|abc123",
+				Email:  "john@example.com",
+				Identities: []Auth0Identity{
+					{Connection: "Username-Password-Authentication", ProfileData: &Auth0ProfileData{Email: "JOHN@example.com"}},
+					{Connection: "email", ProfileData: &Auth0ProfileData{Email: "alt@example.com", EmailVerified: true}},
+					{Connection: "github", ProfileData: &Auth0ProfileData{Email: "john@users.example.com"}},
+					{Connection: "google-oauth2", ProfileData: &Auth0ProfileData{Email: "john@users.example.com", EmailVerified: true}},
+				},
+			},
+			validate: func(t *testing.T, user *model.User) {
+				assert.Equal(t, []model.Email{{Email: "john@users.example.com", Verified: true}}, user.SecondaryEmails)
+			},
+		},
 		{
 			name: "identity with nil ProfileData",
 			auth0User: Auth0User{
@@ -208,6 +229,30 @@ func TestAuth0User_ToUser(t *testing.T) {
 				assert.Nil(t, user.UserMetadata.Picture)
 			},
 		},
+		{
+			name: "login activity is mapped with truncated last IP",
+			auth0User: Auth0User{
+				UserID:      "auth0|abc123",
+				LastLogin:   &lastLogin,
+				LoginsCount: &loginsCount,
+				LastIP:      "203.0.113.42",
+			},
+			validate: func(t *testing.T, user *model.User) {
+				require.NotNil(t, user.Activity)
+				assert.Equal(t, lastLogin, *user.Activity.LastLogin)
+				assert.Equal(t, 17, *user.Activity.LoginsCount)
+				assert.Equal(t, "203.0.113.0", user.Activity.LastIP)
+			},
+		},
+		{
+			name: "no login activity",
+			auth0User: Auth0User{
+				UserID: "auth0|abc123",
+			},
+			validate: func(t *testing.T, user *model.User) {
+				assert.Nil(t, user.Activity)
+			},
+		},
 		{
 			name: "Connection field is populated on identities",
 			auth0User: Auth0User{
@@ -5,16 +5,13 @@ package auth0
 
 import (
 	"context"
-	"fmt"
 	"log/slog"
 	"net/http"
-	"net/url"
 	"strings"
 
 	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
-	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/auth0/client"
 	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
-	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/httpclient"
 	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/redaction"
 )
 
@@ -29,12 +26,6 @@ type passwordCheckRequest struct {
 	Realm        string `json:"realm"`
 }
 
-// passwordUpdateRequest represents the request body for updating a user's password in Auth0
-type passwordUpdateRequest struct {
-	Password   string `json:"password"`
-	Connection string `json:"connection"`
-}
-
 // resetPasswordRequest represents the request body for Auth0's change password endpoint
 type resetPasswordRequest struct {
 	ClientID   string `json:"client_id"`
@@ -85,27 +76,11 @@ func (u *userReaderWriter) ChangePassword(ctx context.Context, user *model.User,
 		return errors.NewUnexpected("failed to get M2M token for password update", errGetToken)
 	}
 
-	updatePayload := passwordUpdateRequest{
-		Password:   newPassword,
-		Connection: constants.Auth0UsernamePasswordConnection,
-	}
-
-	apiRequest := httpclient.NewAPIRequest(
-		u.httpClient,
-		httpclient.WithMethod(http.MethodPatch),
-		httpclient.WithURL(fmt.Sprintf("https://%s/api/v2/users/%s", u.config.Domain, url.PathEscape(user.UserID))),
-		httpclient.WithToken(m2mToken),
-		httpclient.WithDescription("update user password"),
-		httpclient.WithBody(updatePayload),
-		httpclient.WithSensitiveBody(),
-	)
-
-	var patchResponse map[string]any
-	statusCode, errCall := apiRequest.Call(ctx, &patchResponse)
+	errCall := u.api().SetPassword(ctx, m2mToken, user.UserID, u.config.databaseConnection(), newPassword)
 	if errCall != nil {
 		slog.ErrorContext(ctx, "failed to update password in Auth0",
 			"error", errCall,
-			"status_code", statusCode,
+			"status_code", client.StatusCode(errCall),
 			"user_id", redaction.Redact(user.UserID),
 		)
 		return errors.NewUnexpected("failed to update password", errCall)
@@ -135,29 +110,28 @@ func (u *userReaderWriter) validateCurrentPassword(ctx context.Context, username
 		Password:     password,
 		ClientID:     clientID,
 		ClientSecret: clientSecret,
-		Realm:        constants.Auth0UsernamePasswordConnection,
+		Realm:        u.config.databaseConnection(),
 	}
 
-	url := fmt.Sprintf("https://%s/oauth/token", u.config.Domain)
-
-	apiRequest := httpclient.NewAPIRequest(
-		u.httpClient,
-		httpclient.WithMethod(http.MethodPost),
-		httpclient.WithURL(url),
-		httpclient.WithDescription("validate current password"),
-		httpclient.WithBody(payload),
-		httpclient.WithSensitiveBody(),
-	)
-
 	var tokenResponse map[string]any
-	statusCode, errCall := apiRequest.Call(ctx, &tokenResponse)
+	errCall := u.api().Do(ctx, client.Request{
+		Method:        http.MethodPost,
+		Path:          "/oauth/token",
+		Body:          payload,
+		SensitiveBody: true,
+		Description:   "validate current password",
+	}, &tokenResponse)
 	if errCall != nil {
+		statusCode := client.StatusCode(errCall)
 		slog.ErrorContext(ctx, "current password validation failed",
 			"error", errCall,
 			"status_code", statusCode,
 			"username", redaction.Redact(username),
 		)
 
+		if classified, ok := classify(errCall, false); ok {
+			return classified
+		}
 		if statusCode == http.StatusForbidden || statusCode == http.StatusUnauthorized {
 			return errors.NewUnauthorized("current password is incorrect")
 		}
@@ -208,25 +182,20 @@ func (u *userReaderWriter) SendResetPasswordLink(ctx context.Context, user *mode
 	payload := resetPasswordRequest{
 		ClientID:   clientID,
 		Email:      fullUser.PrimaryEmail,
-		Connection: constants.Auth0UsernamePasswordConnection,
+		Connection: u.config.databaseConnection(),
 	}
 
-	url := fmt.Sprintf("https://%s/dbconnections/change_password", u.config.Domain)
-
-	apiRequest := httpclient.NewAPIRequest(
-		u.httpClient,
-		httpclient.WithMethod(http.MethodPost),
-		httpclient.WithURL(url),
-		httpclient.WithDescription("send reset password link"),
-		httpclient.WithBody(payload),
-	)
-
 	// Auth0 returns plain text (not JSON) for this endpoint; pass nil since we only need the status code.
-	statusCode, errCall := apiRequest.Call(ctx, nil)
+	errCall := u.api().Do(ctx, client.Request{
+		Method:      http.MethodPost,
+		Path:        "/dbconnections/change_password",
+		Body:        payload,
+		Description: "send reset password link",
+	}, nil)
 	if errCall != nil {
 		slog.ErrorContext(ctx, "failed to send reset password link",
 			"error", errCall,
-			"status_code", statusCode,
+			"status_code", client.StatusCode(errCall),
 			"user_id", redaction.Redact(user.UserID),
 		)
 		return errors.NewUnexpected("failed to send reset password link", errCall)
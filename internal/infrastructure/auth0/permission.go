@@ -0,0 +1,79 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package auth0
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/auth0/client"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/concurrent"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/redaction"
+)
+
+// UserPermissions returns the RBAC roles assigned to the user and every
+// permission they hold, directly or through a role. Permissions carry the
+// identifier of the API they are defined on as their audience. It implements
+// port.PermissionReader.
+func (u *userReaderWriter) UserPermissions(ctx context.Context, userID string) (*model.UserPermissions, error) {
+	if userID == "" {
+		return nil, errors.NewValidation("user_id is required")
+	}
+
+	m2mToken, errToken := u.config.M2MTokenManager.GetToken(ctx)
+	if errToken != nil {
+		return nil, errors.NewUnexpected("failed to get M2M token for permission lookup", errToken)
+	}
+
+	api := u.api()
+	var (
+		roles       []client.Role
+		permissions []client.Permission
+	)
+	errFetch := concurrent.NewWorkerPool(2).Run(ctx,
+		func() error {
+			var err error
+			roles, err = client.Paginate(ctx, client.MaxPerPage, 0, func(ctx context.Context, page client.Page) ([]client.Role, error) {
+				return api.UserRoles(ctx, m2mToken, userID, page)
+			})
+			return err
+		},
+		func() error {
+			var err error
+			permissions, err = client.Paginate(ctx, client.MaxPerPage, 0, func(ctx context.Context, page client.Page) ([]client.Permission, error) {
+				return api.UserPermissions(ctx, m2mToken, userID, page)
+			})
+			return err
+		},
+	)
+	if errFetch != nil {
+		statusCode := client.StatusCode(errFetch)
+		slog.ErrorContext(ctx, "failed to fetch user permissions",
+			"error", errFetch,
+			"status_code", statusCode,
+			"user_id", redaction.Redact(userID),
+		)
+		return nil, userAPIError(errFetch)
+	}
+
+	result := &model.UserPermissions{
+		Roles:       make([]string, 0, len(roles)),
+		Permissions: make([]model.Permission, 0, len(permissions)),
+	}
+	for _, role := range roles {
+		result.Roles = append(result.Roles, role.Name)
+	}
+	for _, permission := range permissions {
+		result.Permissions = append(result.Permissions, model.Permission{
+			Name:     permission.PermissionName,
+			Audience: permission.ResourceServerIdentifier,
+		})
+	}
+	return result, nil
+}
+
+var _ port.PermissionReader = (*userReaderWriter)(nil)
@@ -0,0 +1,70 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package auth0
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// permissionsTransport serves the roles and permissions of auth0|jdoe
+type permissionsTransport struct {
+	mu    sync.Mutex
+	paths []string
+}
+
+func (p *permissionsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	p.mu.Lock()
+	p.paths = append(p.paths, req.URL.Path)
+	p.mu.Unlock()
+
+	status, body := http.StatusOK, "[]"
+	switch req.URL.Path {
+	case "/api/v2/users/auth0|jdoe/roles":
+		body = `[{"id":"rol_1","name":"Admin"}]`
+	case "/api/v2/users/auth0|jdoe/permissions":
+		body = `[{"permission_name":"search:users","resource_server_identifier":"https://api.example.com/","resource_server_name":"LFX API"}]`
+	default:
+		status, body = http.StatusNotFound, `{"statusCode":404,"message":"The user does not exist."}`
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func TestUserReaderWriter_UserPermissions(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("roles and permissions", func(t *testing.T) {
+		transport := &permissionsTransport{}
+		permissions, err := newTestReaderWriter(transport).UserPermissions(ctx, "auth0|jdoe")
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"Admin"}, permissions.Roles)
+		assert.Equal(t, []model.Permission{{Name: "search:users", Audience: "https://api.example.com/"}}, permissions.Permissions)
+		assert.ElementsMatch(t, []string{"/api/v2/users/auth0|jdoe/roles", "/api/v2/users/auth0|jdoe/permissions"}, transport.paths)
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		_, err := newTestReaderWriter(&permissionsTransport{}).UserPermissions(ctx, "auth0|ghost")
+		assert.ErrorAs(t, err, &errors.NotFound{})
+	})
+
+	t.Run("user id required", func(t *testing.T) {
+		_, err := newTestReaderWriter(&permissionsTransport{}).UserPermissions(ctx, "")
+		assert.IsType(t, errors.Validation{}, err)
+	})
+}
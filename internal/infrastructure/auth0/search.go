@@ -0,0 +1,74 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package auth0
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/auth0/client"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/deadline"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+)
+
+// candidateQuery matches users with a username, email or name starting with
+// query. Auth0 only supports trailing wildcards, so fragments from the middle
+// of a value are not found.
+func candidateQuery(query string) client.Query {
+	return client.Or(
+		client.Prefix("username", query),
+		client.Prefix("email", query),
+		client.Prefix("name", query),
+		client.Prefix("user_metadata.name", query),
+		client.Prefix("identities.profileData.email", query),
+	)
+}
+
+// SearchUserCandidates runs a v3 prefix search over usernames, emails and
+// names. It implements port.UserSearcher.
+func (u *userReaderWriter) SearchUserCandidates(ctx context.Context, query string, limit int) ([]*model.User, error) {
+	ctx, cancel := u.config.Timeouts.Bound(ctx, deadline.OperationSearch)
+	defer cancel()
+
+	if query == "" {
+		return nil, errors.NewValidation("query is required")
+	}
+	if limit <= 0 || limit > dormantSearchMaxResults {
+		limit = dormantSearchMaxResults
+	}
+
+	m2mToken, errToken := u.config.M2MTokenManager.GetToken(ctx)
+	if errToken != nil {
+		return nil, errors.NewUnexpected("failed to get M2M token for user search", errToken)
+	}
+
+	api := u.api()
+	search := client.SearchParams{Query: candidateQuery(query).String()}
+	results, errSearch := client.Paginate(ctx, min(limit, dormantSearchPageSize), limit, func(ctx context.Context, page client.Page) ([]Auth0User, error) {
+		search.Page = page
+		var results []Auth0User
+		if errCall := api.SearchUsers(ctx, m2mToken, search, &results); errCall != nil {
+			slog.ErrorContext(ctx, "failed to search user candidates",
+				"error", errCall,
+				"status_code", client.StatusCode(errCall),
+				"page", page.Page,
+			)
+			return nil, errCall
+		}
+		return results, nil
+	})
+	if errSearch != nil {
+		return nil, errors.NewUnexpected("failed to search users", errSearch)
+	}
+
+	users := make([]*model.User, 0, len(results))
+	for i := range results {
+		users = append(users, u.toUser(&results[i]))
+	}
+	return users, nil
+}
+
+var _ port.UserSearcher = (*userReaderWriter)(nil)
@@ -0,0 +1,44 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package auth0
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserReaderWriter_SearchUserCandidates(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("prefix search", func(t *testing.T) {
+		transport := &searchTransport{body: `[{"user_id":"auth0|jdoe","username":"jdoe","email":"jdoe@example.com","name":"Jane Doe",` +
+			`"identities":[{"connection":"Username-Password-Authentication","provider":"auth0","user_id":"jdoe"}]}]`}
+		users, err := newTestReaderWriter(transport).SearchUserCandidates(ctx, "jane d", 50)
+		require.NoError(t, err)
+
+		require.Len(t, users, 1)
+		assert.Equal(t, "jdoe", users[0].Username)
+		assert.Equal(t, "/api/v2/users", transport.path)
+		assert.Equal(t,
+			`username:jane\ d* OR email:jane\ d* OR name:jane\ d* OR user_metadata.name:jane\ d* OR identities.profileData.email:jane\ d*`,
+			transport.query.Get("q"))
+		assert.Equal(t, "50", transport.query.Get("per_page"))
+	})
+
+	t.Run("empty query", func(t *testing.T) {
+		_, err := newTestReaderWriter(&searchTransport{}).SearchUserCandidates(ctx, "", 10)
+		assert.IsType(t, errors.Validation{}, err)
+	})
+
+	t.Run("search failure", func(t *testing.T) {
+		transport := &searchTransport{status: http.StatusTooManyRequests, body: `{"statusCode":429,"message":"Too Many Requests"}`}
+		_, err := newTestReaderWriter(transport).SearchUserCandidates(ctx, "jdoe", 10)
+		assert.IsType(t, errors.Unexpected{}, err)
+	})
+}
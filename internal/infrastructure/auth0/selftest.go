@@ -0,0 +1,76 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package auth0
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/auth0/client"
+)
+
+// SelfTest checks that the signing key loaded at startup is still published
+// in the tenant's JWKS. The key isn't reloaded, so once Auth0 rotates it away
+// every new token fails verification until the replica restarts. It also
+// loads the static resources of the tenant, checking the configured
+// connections exist.
+func (u *userReaderWriter) SelfTest(ctx context.Context) []model.ConfigCheck {
+	return []model.ConfigCheck{
+		model.RunConfigCheck("auth0.jwks_freshness", func() (string, string) {
+			jwtConfig := u.config.JWTVerificationConfig
+			if jwtConfig == nil || jwtConfig.JWKSURL == "" {
+				return model.ConfigCheckSkip, "signing key not loaded from a JWKS"
+			}
+			jwks, err := fetchJWKS(ctx, u.httpClient, jwtConfig.JWKSURL)
+			if err != nil {
+				return model.ConfigCheckFail, err.Error()
+			}
+			if jwtConfig.KeyID == "" {
+				return model.ConfigCheckWarn, "signing key has no kid, so its rotation can't be detected"
+			}
+			for _, key := range jwks.Keys {
+				if key.Kid == jwtConfig.KeyID {
+					return model.ConfigCheckOK, fmt.Sprintf("key %s loaded %s ago is still published",
+						jwtConfig.KeyID, time.Since(jwtConfig.LoadedAt).Round(time.Second))
+				}
+			}
+			return model.ConfigCheckFail, fmt.Sprintf("key %s is no longer published; restart to load the current key", jwtConfig.KeyID)
+		}),
+		model.RunConfigCheck("auth0.static_resources", func() (string, string) {
+			if u.config.M2MTokenManager == nil {
+				return model.ConfigCheckSkip, "no M2M credentials"
+			}
+			roles, err := u.tenantRoles(ctx)
+			if err != nil {
+				return model.ConfigCheckFail, err.Error()
+			}
+			connections, err := u.tenantConnections(ctx)
+			if err != nil {
+				return model.ConfigCheckFail, err.Error()
+			}
+			organizations, err := u.tenantOrganizations(ctx)
+			if err != nil {
+				return model.ConfigCheckFail, err.Error()
+			}
+
+			var missing []string
+			for _, name := range slices.Concat(u.config.canonicalConnections(), u.config.SocialConnections) {
+				if !slices.ContainsFunc(connections, func(c client.Connection) bool { return c.Name == name }) {
+					missing = append(missing, name)
+				}
+			}
+			if len(missing) > 0 {
+				return model.ConfigCheckWarn, "configured connections not in the tenant: " + strings.Join(missing, ", ")
+			}
+			return model.ConfigCheckOK, fmt.Sprintf("%d roles, %d connections, %d organizations", len(roles), len(connections), len(organizations))
+		}),
+	}
+}
+
+var _ port.SelfTester = (*userReaderWriter)(nil)
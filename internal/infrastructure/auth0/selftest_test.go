@@ -0,0 +1,51 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package auth0
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/httpclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelfTestJWKSFreshness(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"keys":[{"kty":"RSA","use":"sig","kid":"current","n":"AQAB","e":"AQAB"}]}`))
+	}))
+	defer server.Close()
+
+	httpConfig := httpclient.DefaultConfig()
+	httpConfig.MaxRetries = 0
+
+	tests := []struct {
+		name       string
+		jwtConfig  *JWTVerificationConfig
+		wantStatus string
+	}{
+		{name: "key still published", jwtConfig: &JWTVerificationConfig{JWKSURL: server.URL, KeyID: "current", LoadedAt: time.Now()}, wantStatus: model.ConfigCheckOK},
+		{name: "key rotated away", jwtConfig: &JWTVerificationConfig{JWKSURL: server.URL, KeyID: "previous"}, wantStatus: model.ConfigCheckFail},
+		{name: "key without kid", jwtConfig: &JWTVerificationConfig{JWKSURL: server.URL}, wantStatus: model.ConfigCheckWarn},
+		{name: "static key", jwtConfig: &JWTVerificationConfig{}, wantStatus: model.ConfigCheckSkip},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := &userReaderWriter{
+				config:     Config{JWTVerificationConfig: tt.jwtConfig},
+				httpClient: httpclient.NewClient(httpConfig),
+			}
+			checks := u.SelfTest(context.Background())
+			require.Len(t, checks, 2)
+			assert.Equal(t, tt.wantStatus, checks[0].Status, checks[0].Detail)
+			assert.Equal(t, model.ConfigCheckSkip, checks[1].Status, "no M2M credentials")
+		})
+	}
+}
@@ -0,0 +1,221 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package auth0
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/auth0/client"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+)
+
+// Service accounts are M2M applications whose client metadata records who
+// owns them. managed_by marks the applications created by this service, so
+// list and rotate never touch applications managed elsewhere.
+const (
+	serviceAccountManagedByKey = "managed_by"
+	serviceAccountOwnerKey     = "owner"
+	serviceAccountCreatedByKey = "created_by"
+	serviceAccountCreatedAtKey = "created_at"
+	serviceAccountRotatedByKey = "rotated_by"
+	serviceAccountRotatedAtKey = "rotated_at"
+)
+
+// CreateServiceAccount creates an M2M application with a client grant per
+// API. If a grant can't be created the application is deleted again, so a
+// failed request doesn't leave a half-configured account behind.
+func (u *userReaderWriter) CreateServiceAccount(ctx context.Context, account *model.ServiceAccount) (*model.ServiceAccountCredentials, error) {
+	if account == nil || account.Name == "" {
+		return nil, errors.NewValidation("service account name is required")
+	}
+
+	m2mToken, errToken := u.config.M2MTokenManager.GetToken(ctx)
+	if errToken != nil {
+		return nil, errors.NewUnexpected("failed to get M2M token for service account creation", errToken)
+	}
+
+	now := time.Now().UTC()
+	metadata := map[string]string{
+		serviceAccountManagedByKey: constants.ServiceName,
+		serviceAccountOwnerKey:     account.Owner,
+		serviceAccountCreatedByKey: account.CreatedBy,
+		serviceAccountCreatedAtKey: now.Format(time.RFC3339),
+	}
+
+	api := u.api()
+	application, errCreate := api.CreateApplication(ctx, m2mToken, client.Application{
+		Name:           account.Name,
+		Description:    account.Description,
+		AppType:        client.AppTypeNonInteractive,
+		GrantTypes:     []string{"client_credentials"},
+		ClientMetadata: metadata,
+	})
+	if errCreate != nil {
+		return nil, serviceAccountError(ctx, "failed to create service account", errCreate)
+	}
+
+	grants := make([]client.ClientGrant, 0, len(account.Grants))
+	for _, grant := range account.Grants {
+		clientGrant := client.ClientGrant{ClientID: application.ClientID, Audience: grant.Audience, Scope: grant.Scopes}
+		if errGrant := api.CreateClientGrant(ctx, m2mToken, clientGrant); errGrant != nil {
+			if errDelete := api.DeleteApplication(ctx, m2mToken, application.ClientID); errDelete != nil {
+				slog.ErrorContext(ctx, "failed to delete service account after grant failure",
+					"error", errDelete,
+					"client_id", application.ClientID,
+				)
+			}
+			return nil, serviceAccountError(ctx, "failed to grant service account access", errGrant)
+		}
+		grants = append(grants, clientGrant)
+	}
+
+	return &model.ServiceAccountCredentials{
+		ServiceAccount: toServiceAccount(application, grants),
+		ClientSecret:   application.ClientSecret,
+	}, nil
+}
+
+// ListServiceAccounts returns the M2M applications created by this service
+// with their client grants
+func (u *userReaderWriter) ListServiceAccounts(ctx context.Context) ([]*model.ServiceAccount, error) {
+	m2mToken, errToken := u.config.M2MTokenManager.GetToken(ctx)
+	if errToken != nil {
+		return nil, errors.NewUnexpected("failed to get M2M token for service account listing", errToken)
+	}
+
+	api := u.api()
+	applications, errList := client.Paginate(ctx, client.MaxPerPage, 0, func(ctx context.Context, page client.Page) ([]client.Application, error) {
+		return api.ListApplications(ctx, m2mToken, client.AppTypeNonInteractive, page)
+	})
+	if errList != nil {
+		return nil, serviceAccountError(ctx, "failed to list service accounts", errList)
+	}
+	grants, errGrants := client.Paginate(ctx, client.MaxPerPage, 0, func(ctx context.Context, page client.Page) ([]client.ClientGrant, error) {
+		return api.ListClientGrants(ctx, m2mToken, "", page)
+	})
+	if errGrants != nil {
+		return nil, serviceAccountError(ctx, "failed to list service account grants", errGrants)
+	}
+
+	grantsByClient := make(map[string][]client.ClientGrant)
+	for _, grant := range grants {
+		grantsByClient[grant.ClientID] = append(grantsByClient[grant.ClientID], grant)
+	}
+
+	accounts := make([]*model.ServiceAccount, 0, len(applications))
+	for i := range applications {
+		if !managedServiceAccount(&applications[i]) {
+			continue
+		}
+		account := toServiceAccount(&applications[i], grantsByClient[applications[i].ClientID])
+		accounts = append(accounts, &account)
+	}
+	return accounts, nil
+}
+
+// RotateServiceAccountSecret replaces the client secret of a service account
+// and records who rotated it
+func (u *userReaderWriter) RotateServiceAccountSecret(ctx context.Context, clientID, rotatedBy string) (*model.ServiceAccountCredentials, error) {
+	if clientID == "" {
+		return nil, errors.NewValidation("client_id is required")
+	}
+
+	m2mToken, errToken := u.config.M2MTokenManager.GetToken(ctx)
+	if errToken != nil {
+		return nil, errors.NewUnexpected("failed to get M2M token for service account rotation", errToken)
+	}
+
+	api := u.api()
+	application, errGet := api.GetApplication(ctx, m2mToken, clientID)
+	if errGet != nil {
+		return nil, serviceAccountError(ctx, "failed to get service account", errGet)
+	}
+	if !managedServiceAccount(application) {
+		return nil, errors.NewNotFound("service account not found")
+	}
+
+	rotated, errRotate := api.RotateApplicationSecret(ctx, m2mToken, clientID)
+	if errRotate != nil {
+		return nil, serviceAccountError(ctx, "failed to rotate service account secret", errRotate)
+	}
+
+	// The secret is already replaced, so a failure to record the rotation is
+	// logged rather than returned; the caller must still receive the secret.
+	metadata := maps.Clone(application.ClientMetadata)
+	metadata[serviceAccountRotatedByKey] = rotatedBy
+	metadata[serviceAccountRotatedAtKey] = time.Now().UTC().Format(time.RFC3339)
+	if errUpdate := api.UpdateApplicationMetadata(ctx, m2mToken, clientID, metadata); errUpdate != nil {
+		slog.WarnContext(ctx, "failed to record service account rotation",
+			"error", errUpdate,
+			"client_id", clientID,
+		)
+	} else {
+		application.ClientMetadata = metadata
+	}
+
+	grants, errGrants := client.Paginate(ctx, client.MaxPerPage, 0, func(ctx context.Context, page client.Page) ([]client.ClientGrant, error) {
+		return api.ListClientGrants(ctx, m2mToken, clientID, page)
+	})
+	if errGrants != nil {
+		slog.WarnContext(ctx, "failed to list service account grants after rotation",
+			"error", errGrants,
+			"client_id", clientID,
+		)
+	}
+
+	return &model.ServiceAccountCredentials{
+		ServiceAccount: toServiceAccount(application, grants),
+		ClientSecret:   rotated.ClientSecret,
+	}, nil
+}
+
+// managedServiceAccount reports whether application was created by this service
+func managedServiceAccount(application *client.Application) bool {
+	return application.ClientMetadata[serviceAccountManagedByKey] == constants.ServiceName
+}
+
+func toServiceAccount(application *client.Application, grants []client.ClientGrant) model.ServiceAccount {
+	metadata := application.ClientMetadata
+	account := model.ServiceAccount{
+		ClientID:    application.ClientID,
+		Name:        application.Name,
+		Description: application.Description,
+		Owner:       metadata[serviceAccountOwnerKey],
+		Grants:      make([]model.ServiceAccountGrant, 0, len(grants)),
+		CreatedBy:   metadata[serviceAccountCreatedByKey],
+		CreatedAt:   metadataTime(metadata[serviceAccountCreatedAtKey]),
+		RotatedBy:   metadata[serviceAccountRotatedByKey],
+		RotatedAt:   metadataTime(metadata[serviceAccountRotatedAtKey]),
+	}
+	for _, grant := range grants {
+		account.Grants = append(account.Grants, model.ServiceAccountGrant{Audience: grant.Audience, Scopes: grant.Scope})
+	}
+	return account
+}
+
+// metadataTime parses an RFC 3339 timestamp stored in client metadata
+func metadataTime(value string) *time.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+func serviceAccountError(ctx context.Context, message string, err error) error {
+	statusCode := client.StatusCode(err)
+	slog.ErrorContext(ctx, message,
+		"error", err,
+		"status_code", statusCode,
+	)
+	return apiError(err)
+}
+
+var _ port.ServiceAccountManager = (*userReaderWriter)(nil)
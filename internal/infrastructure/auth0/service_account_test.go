@@ -0,0 +1,171 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package auth0
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/auth0/client"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const managedMetadata = `{"managed_by":"lfx-v2-auth-service","owner":"team-search","created_by":"auth0|admin","created_at":"2026-10-01T12:00:00Z"}`
+
+// applicationsTransport serves a tenant with one managed M2M application
+// (cid_managed) and one created elsewhere (cid_other)
+type applicationsTransport struct {
+	mu    sync.Mutex
+	calls []string
+	// created is the body of the last application created
+	created client.Application
+	// metadata is the client metadata of the last PATCH
+	metadata map[string]string
+	// failGrants makes every client grant creation fail
+	failGrants bool
+}
+
+func (a *applicationsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	call := req.Method + " " + req.URL.Path
+	a.calls = append(a.calls, call)
+
+	status, body := http.StatusOK, "{}"
+	switch call {
+	case "POST /api/v2/clients":
+		_ = json.NewDecoder(req.Body).Decode(&a.created)
+		created := a.created
+		created.ClientID, created.ClientSecret = "cid_new", "s3cret"
+		raw, _ := json.Marshal(created)
+		status, body = http.StatusCreated, string(raw)
+	case "POST /api/v2/client-grants":
+		status = http.StatusCreated
+		if a.failGrants {
+			status, body = http.StatusNotFound, `{"statusCode":404,"message":"No resource server found by specified identifier"}`
+		}
+	case "DELETE /api/v2/clients/cid_new":
+		status, body = http.StatusNoContent, ""
+	case "GET /api/v2/clients":
+		body = `[{"client_id":"cid_managed","name":"indexer","app_type":"non_interactive","client_metadata":` + managedMetadata + `},` +
+			`{"client_id":"cid_other","name":"legacy","app_type":"non_interactive"}]`
+	case "GET /api/v2/client-grants":
+		managed := `{"id":"cgr_1","client_id":"cid_managed","audience":"https://api.example.com/","scope":["read:projects"]}`
+		other := `{"id":"cgr_2","client_id":"cid_other","audience":"https://api.example.com/","scope":["write:projects"]}`
+		switch req.URL.Query().Get("client_id") {
+		case "":
+			body = "[" + managed + "," + other + "]"
+		case "cid_managed":
+			body = "[" + managed + "]"
+		default:
+			body = "[" + other + "]"
+		}
+	case "GET /api/v2/clients/cid_managed":
+		body = `{"client_id":"cid_managed","name":"indexer","client_metadata":` + managedMetadata + `}`
+	case "GET /api/v2/clients/cid_other":
+		body = `{"client_id":"cid_other","name":"legacy"}`
+	case "POST /api/v2/clients/cid_managed/rotate-secret":
+		body = `{"client_id":"cid_managed","client_secret":"n3w"}`
+	case "PATCH /api/v2/clients/cid_managed":
+		var patch applicationMetadataPatch
+		_ = json.NewDecoder(req.Body).Decode(&patch)
+		a.metadata = patch.ClientMetadata
+	default:
+		status, body = http.StatusNotFound, `{"statusCode":404,"message":"Not found"}`
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+type applicationMetadataPatch struct {
+	ClientMetadata map[string]string `json:"client_metadata"`
+}
+
+func TestUserReaderWriter_CreateServiceAccount(t *testing.T) {
+	ctx := context.Background()
+	account := &model.ServiceAccount{
+		Name:      "indexer",
+		Owner:     "team-search",
+		CreatedBy: "auth0|admin",
+		Grants:    []model.ServiceAccountGrant{{Audience: "https://api.example.com/", Scopes: []string{"read:projects"}}},
+	}
+
+	t.Run("creates the application and its grants", func(t *testing.T) {
+		transport := &applicationsTransport{}
+		credentials, err := newTestReaderWriter(transport).CreateServiceAccount(ctx, account)
+		require.NoError(t, err)
+
+		assert.Equal(t, "cid_new", credentials.ClientID)
+		assert.Equal(t, "s3cret", credentials.ClientSecret)
+		assert.Equal(t, "team-search", credentials.Owner)
+		assert.Equal(t, "auth0|admin", credentials.CreatedBy)
+		assert.NotNil(t, credentials.CreatedAt)
+		assert.Equal(t, account.Grants, credentials.Grants)
+
+		assert.Equal(t, client.AppTypeNonInteractive, transport.created.AppType)
+		assert.Equal(t, []string{"client_credentials"}, transport.created.GrantTypes)
+		assert.Equal(t, "lfx-v2-auth-service", transport.created.ClientMetadata["managed_by"])
+		assert.Equal(t, []string{"POST /api/v2/clients", "POST /api/v2/client-grants"}, transport.calls)
+	})
+
+	t.Run("failed grant deletes the application", func(t *testing.T) {
+		transport := &applicationsTransport{failGrants: true}
+		_, err := newTestReaderWriter(transport).CreateServiceAccount(ctx, account)
+		assert.IsType(t, errors.NotFound{}, err)
+		assert.Equal(t, []string{"POST /api/v2/clients", "POST /api/v2/client-grants", "DELETE /api/v2/clients/cid_new"}, transport.calls)
+	})
+}
+
+func TestUserReaderWriter_ListServiceAccounts(t *testing.T) {
+	accounts, err := newTestReaderWriter(&applicationsTransport{}).ListServiceAccounts(context.Background())
+	require.NoError(t, err)
+
+	require.Len(t, accounts, 1, "applications managed elsewhere are skipped")
+	assert.Equal(t, "cid_managed", accounts[0].ClientID)
+	assert.Equal(t, "team-search", accounts[0].Owner)
+	assert.Equal(t, "2026-10-01T12:00:00Z", accounts[0].CreatedAt.Format(time.RFC3339))
+	assert.Equal(t, []model.ServiceAccountGrant{{Audience: "https://api.example.com/", Scopes: []string{"read:projects"}}}, accounts[0].Grants)
+}
+
+func TestUserReaderWriter_RotateServiceAccountSecret(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("rotates and records the rotation", func(t *testing.T) {
+		transport := &applicationsTransport{}
+		credentials, err := newTestReaderWriter(transport).RotateServiceAccountSecret(ctx, "cid_managed", "auth0|ops")
+		require.NoError(t, err)
+
+		assert.Equal(t, "n3w", credentials.ClientSecret)
+		assert.Equal(t, "auth0|ops", credentials.RotatedBy)
+		assert.NotNil(t, credentials.RotatedAt)
+		assert.Equal(t, "team-search", transport.metadata["owner"], "existing metadata is kept")
+		assert.Equal(t, "auth0|ops", transport.metadata["rotated_by"])
+		assert.Len(t, credentials.Grants, 1)
+	})
+
+	t.Run("applications managed elsewhere are not rotated", func(t *testing.T) {
+		transport := &applicationsTransport{}
+		_, err := newTestReaderWriter(transport).RotateServiceAccountSecret(ctx, "cid_other", "auth0|ops")
+		assert.IsType(t, errors.NotFound{}, err)
+		assert.Equal(t, []string{"GET /api/v2/clients/cid_other"}, transport.calls)
+	})
+
+	t.Run("client id required", func(t *testing.T) {
+		_, err := newTestReaderWriter(&applicationsTransport{}).RotateServiceAccountSecret(ctx, "", "auth0|ops")
+		assert.IsType(t, errors.Validation{}, err)
+	})
+}
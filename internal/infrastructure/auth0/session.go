@@ -0,0 +1,196 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package auth0
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/auth0/client"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/redaction"
+)
+
+// sessionMaxPages bounds the pages read per list, so a user with runaway
+// sessions can't make a single request page through the tenant indefinitely
+const sessionMaxPages = 10
+
+// ListSessions returns the login sessions and refresh tokens of the user.
+// Needs the read:sessions and read:refresh_tokens Management API scopes.
+func (u *userReaderWriter) ListSessions(ctx context.Context, userID string) ([]*model.UserSession, error) {
+	if userID == "" {
+		return nil, errors.NewValidation("user_id is required")
+	}
+
+	m2mToken, errToken := u.config.M2MTokenManager.GetToken(ctx)
+	if errToken != nil {
+		return nil, errors.NewUnexpected("failed to get M2M token for session listing", errToken)
+	}
+	api := u.api()
+
+	var sessions []*model.UserSession
+	from := ""
+	for range sessionMaxPages {
+		page, next, err := api.ListUserSessions(ctx, m2mToken, userID, from)
+		if err != nil {
+			return nil, sessionError(ctx, "failed to list user sessions", err)
+		}
+		for _, session := range page {
+			sessions = append(sessions, sessionFromAuth0(session))
+		}
+		if from = next; from == "" {
+			break
+		}
+	}
+
+	from = ""
+	for range sessionMaxPages {
+		page, next, err := api.ListUserRefreshTokens(ctx, m2mToken, userID, from)
+		if err != nil {
+			return nil, sessionError(ctx, "failed to list user refresh tokens", err)
+		}
+		for _, token := range page {
+			sessions = append(sessions, refreshTokenFromAuth0(token))
+		}
+		if from = next; from == "" {
+			break
+		}
+	}
+	return sessions, nil
+}
+
+// RevokeSession revokes one login session or refresh token of the user.
+// Revoking a login session also revokes the refresh tokens issued in it.
+func (u *userReaderWriter) RevokeSession(ctx context.Context, userID, sessionType, sessionID string) error {
+	if userID == "" || sessionID == "" {
+		return errors.NewValidation("user_id and session id are required")
+	}
+
+	m2mToken, errToken := u.config.M2MTokenManager.GetToken(ctx)
+	if errToken != nil {
+		return errors.NewUnexpected("failed to get M2M token for session revocation", errToken)
+	}
+	api := u.api()
+
+	// the Management API deletes by id alone, so ownership is checked first
+	var owner string
+	switch sessionType {
+	case model.SessionTypeLogin:
+		session, err := api.GetSession(ctx, m2mToken, sessionID)
+		if err != nil {
+			return sessionError(ctx, "failed to get session", err)
+		}
+		owner = session.UserID
+	case model.SessionTypeRefreshToken:
+		token, err := api.GetRefreshToken(ctx, m2mToken, sessionID)
+		if err != nil {
+			return sessionError(ctx, "failed to get refresh token", err)
+		}
+		owner = token.UserID
+	default:
+		return errors.NewValidation("type must be session or refresh_token")
+	}
+	if owner != userID {
+		slog.WarnContext(ctx, "session revocation for another user rejected",
+			"user_id", redaction.Redact(userID),
+			"type", sessionType,
+		)
+		return errors.NewNotFound("session not found")
+	}
+
+	if sessionType == model.SessionTypeLogin {
+		if err := api.DeleteSession(ctx, m2mToken, sessionID); err != nil {
+			return sessionError(ctx, "failed to revoke session", err)
+		}
+		return nil
+	}
+	if err := api.DeleteRefreshToken(ctx, m2mToken, sessionID); err != nil {
+		return sessionError(ctx, "failed to revoke refresh token", err)
+	}
+	return nil
+}
+
+// RevokeAllSessions revokes every login session and refresh token of the user
+func (u *userReaderWriter) RevokeAllSessions(ctx context.Context, userID string) error {
+	if userID == "" {
+		return errors.NewValidation("user_id is required")
+	}
+
+	m2mToken, errToken := u.config.M2MTokenManager.GetToken(ctx)
+	if errToken != nil {
+		return errors.NewUnexpected("failed to get M2M token for session revocation", errToken)
+	}
+	api := u.api()
+
+	if err := api.DeleteUserSessions(ctx, m2mToken, userID); err != nil {
+		return sessionError(ctx, "failed to revoke user sessions", err)
+	}
+	if err := api.DeleteUserRefreshTokens(ctx, m2mToken, userID); err != nil {
+		return sessionError(ctx, "failed to revoke user refresh tokens", err)
+	}
+	return nil
+}
+
+func sessionFromAuth0(session client.Session) *model.UserSession {
+	clients := make([]string, 0, len(session.Clients))
+	for _, c := range session.Clients {
+		clients = append(clients, c.ClientID)
+	}
+	lastActive := session.LastInteractedAt
+	if lastActive == nil {
+		lastActive = session.AuthenticatedAt
+	}
+	return &model.UserSession{
+		ID:            session.ID,
+		Type:          model.SessionTypeLogin,
+		Clients:       clients,
+		CreatedAt:     session.CreatedAt,
+		LastActiveAt:  lastActive,
+		ExpiresAt:     session.ExpiresAt,
+		IdleExpiresAt: session.IdleExpiresAt,
+		UserAgent:     lastNonEmpty(session.Device.InitialUserAgent, session.Device.LastUserAgent),
+		IP:            redaction.TruncateIP(lastNonEmpty(session.Device.InitialIP, session.Device.LastIP)),
+	}
+}
+
+func refreshTokenFromAuth0(token client.RefreshToken) *model.UserSession {
+	session := &model.UserSession{
+		ID:            token.ID,
+		Type:          model.SessionTypeRefreshToken,
+		SessionID:     token.SessionID,
+		CreatedAt:     token.CreatedAt,
+		LastActiveAt:  token.LastExchangedAt,
+		ExpiresAt:     token.ExpiresAt,
+		IdleExpiresAt: token.IdleExpiresAt,
+		UserAgent:     lastNonEmpty(token.Device.InitialUserAgent, token.Device.LastUserAgent),
+		IP:            redaction.TruncateIP(lastNonEmpty(token.Device.InitialIP, token.Device.LastIP)),
+	}
+	if token.ClientID != "" {
+		session.Clients = []string{token.ClientID}
+	}
+	return session
+}
+
+// lastNonEmpty returns the last of values that isn't empty
+func lastNonEmpty(values ...string) string {
+	for i := len(values) - 1; i >= 0; i-- {
+		if values[i] != "" {
+			return values[i]
+		}
+	}
+	return ""
+}
+
+func sessionError(ctx context.Context, message string, err error) error {
+	statusCode := client.StatusCode(err)
+	slog.ErrorContext(ctx, message,
+		"error", err,
+		"status_code", statusCode,
+	)
+	return apiError(err)
+}
+
+var _ port.SessionManager = (*userReaderWriter)(nil)
@@ -0,0 +1,107 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package auth0
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sessionsTransport serves two pages of sessions and one refresh token for
+// auth0|alice, and a session (ses_bob) that belongs to auth0|bob
+type sessionsTransport struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (s *sessionsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	call := req.Method + " " + req.URL.Path
+	s.calls = append(s.calls, call)
+
+	status, body := http.StatusOK, "{}"
+	switch call {
+	case "GET /api/v2/users/auth0|alice/sessions":
+		if req.URL.Query().Get("from") == "" {
+			body = `{"sessions":[{"id":"ses_1","user_id":"auth0|alice","created_at":"2026-10-01T12:00:00Z",` +
+				`"device":{"initial_user_agent":"Firefox","initial_ip":"203.0.113.42","last_ip":"198.51.100.7"},"clients":[{"client_id":"cid_profile"}]}],"next":"cursor-2"}`
+		} else {
+			body = `{"sessions":[{"id":"ses_2","user_id":"auth0|alice","authenticated_at":"2026-10-02T12:00:00Z"}]}`
+		}
+	case "GET /api/v2/users/auth0|alice/refresh-tokens":
+		body = `{"tokens":[{"id":"rt_1","user_id":"auth0|alice","client_id":"cid_cli","session_id":"ses_1"}]}`
+	case "GET /api/v2/sessions/ses_1":
+		body = `{"id":"ses_1","user_id":"auth0|alice"}`
+	case "GET /api/v2/sessions/ses_bob":
+		body = `{"id":"ses_bob","user_id":"auth0|bob"}`
+	case "GET /api/v2/refresh-tokens/rt_1":
+		body = `{"id":"rt_1","user_id":"auth0|alice"}`
+	case "DELETE /api/v2/sessions/ses_1", "DELETE /api/v2/refresh-tokens/rt_1",
+		"DELETE /api/v2/users/auth0|alice/sessions", "DELETE /api/v2/users/auth0|alice/refresh-tokens":
+		status, body = http.StatusNoContent, ""
+	default:
+		status, body = http.StatusNotFound, `{"statusCode":404,"message":"Not found"}`
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func TestUserReaderWriter_ListSessions(t *testing.T) {
+	transport := &sessionsTransport{}
+	sessions, err := newTestReaderWriter(transport).ListSessions(context.Background(), "auth0|alice")
+	require.NoError(t, err)
+
+	require.Len(t, sessions, 3, "both session pages and the refresh token are listed")
+	assert.Equal(t, "ses_1", sessions[0].ID)
+	assert.Equal(t, model.SessionTypeLogin, sessions[0].Type)
+	assert.Equal(t, []string{"cid_profile"}, sessions[0].Clients)
+	assert.Equal(t, "198.51.100.0", sessions[0].IP, "the last IP is reported, truncated")
+	assert.Equal(t, "Firefox", sessions[0].UserAgent)
+	assert.NotNil(t, sessions[1].LastActiveAt, "authenticated_at stands in for missing activity")
+	assert.Equal(t, model.SessionTypeRefreshToken, sessions[2].Type)
+	assert.Equal(t, "ses_1", sessions[2].SessionID)
+}
+
+func TestUserReaderWriter_RevokeSession(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("revokes a session of the user", func(t *testing.T) {
+		transport := &sessionsTransport{}
+		require.NoError(t, newTestReaderWriter(transport).RevokeSession(ctx, "auth0|alice", model.SessionTypeLogin, "ses_1"))
+		assert.Equal(t, []string{"GET /api/v2/sessions/ses_1", "DELETE /api/v2/sessions/ses_1"}, transport.calls)
+	})
+
+	t.Run("revokes a refresh token of the user", func(t *testing.T) {
+		transport := &sessionsTransport{}
+		require.NoError(t, newTestReaderWriter(transport).RevokeSession(ctx, "auth0|alice", model.SessionTypeRefreshToken, "rt_1"))
+		assert.Equal(t, []string{"GET /api/v2/refresh-tokens/rt_1", "DELETE /api/v2/refresh-tokens/rt_1"}, transport.calls)
+	})
+
+	t.Run("sessions of other users are not found", func(t *testing.T) {
+		transport := &sessionsTransport{}
+		err := newTestReaderWriter(transport).RevokeSession(ctx, "auth0|alice", model.SessionTypeLogin, "ses_bob")
+		assert.IsType(t, errors.NotFound{}, err)
+		assert.Equal(t, []string{"GET /api/v2/sessions/ses_bob"}, transport.calls)
+	})
+
+	t.Run("revokes every session and refresh token", func(t *testing.T) {
+		transport := &sessionsTransport{}
+		require.NoError(t, newTestReaderWriter(transport).RevokeAllSessions(ctx, "auth0|alice"))
+		assert.Equal(t, []string{"DELETE /api/v2/users/auth0|alice/sessions", "DELETE /api/v2/users/auth0|alice/refresh-tokens"}, transport.calls)
+	})
+}
@@ -0,0 +1,122 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package auth0
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/auth0/client"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+)
+
+// Social username attributes select what a social identity contributes as
+// the username of a user that has no canonical (database or enterprise) identity.
+const (
+	// SocialUsernameSub uses the provider-prefixed identity, e.g. github|1234567
+	SocialUsernameSub = "sub"
+	// SocialUsernameNickname uses the provider's handle, e.g. the GitHub login
+	SocialUsernameNickname = "nickname"
+	// SocialUsernameEmail uses the email the provider asserted
+	SocialUsernameEmail = "email"
+)
+
+// socialPolicy decides whether and how users with only social identities
+// resolve to a username. The zero value disables social usernames.
+type socialPolicy struct {
+	// connections are the social connections that may supply a username, in priority order
+	connections []string
+	attribute   string
+}
+
+// validate rejects unknown username attributes
+func (p socialPolicy) validate() error {
+	switch p.attribute {
+	case "", SocialUsernameSub, SocialUsernameNickname, SocialUsernameEmail:
+		return nil
+	}
+	return errors.NewValidation(fmt.Sprintf("invalid social username attribute: %s", p.attribute))
+}
+
+func (p socialPolicy) enabled() bool {
+	return len(p.connections) > 0
+}
+
+// identity returns the highest priority social identity of the user
+func (p socialPolicy) identity(auth0User *Auth0User) (Auth0Identity, bool) {
+	for _, connection := range p.connections {
+		for _, identity := range auth0User.Identities {
+			if identity.Connection == connection {
+				return identity, true
+			}
+		}
+	}
+	return Auth0Identity{}, false
+}
+
+// username returns the username the policy assigns to the user's social
+// identity, or "" when the policy is disabled or the user has none
+func (p socialPolicy) username(auth0User *Auth0User) string {
+	identity, ok := p.identity(auth0User)
+	if !ok {
+		return ""
+	}
+	switch p.attribute {
+	case SocialUsernameNickname:
+		if identity.ProfileData != nil {
+			return identity.ProfileData.Nickname
+		}
+		return ""
+	case SocialUsernameEmail:
+		if identity.ProfileData != nil {
+			return identity.ProfileData.Email
+		}
+		return ""
+	default:
+		return identity.Provider + "|" + identityUserID(identity.UserID)
+	}
+}
+
+// query matches users whose social identity would resolve to username
+func (p socialPolicy) query(username string) client.Query {
+	if !p.enabled() || username == "" {
+		return client.Query{}
+	}
+
+	var attribute client.Query
+	switch p.attribute {
+	case SocialUsernameNickname:
+		attribute = client.Term("identities.profileData.nickname", username)
+	case SocialUsernameEmail:
+		attribute = client.Term("identities.profileData.email", username)
+	default:
+		provider, userID, ok := strings.Cut(username, "|")
+		if !ok || !slices.Contains(p.connections, provider) {
+			return client.Query{}
+		}
+		return subQuery(provider, userID)
+	}
+
+	return client.And(attribute, canonicalConnections(p.connections).query())
+}
+
+// subQuery matches the user holding the identity provider|userID, whether it
+// is the primary identity or linked to another account
+func subQuery(provider, userID string) client.Query {
+	return client.And(
+		client.Term("identities.provider", provider),
+		client.Term("identities.user_id", userID),
+	)
+}
+
+// hasIdentity reports whether the user holds the identity provider|userID
+func hasIdentity(auth0User *Auth0User, provider, userID string) bool {
+	for _, identity := range auth0User.Identities {
+		if identity.Provider == provider && identityUserID(identity.UserID) == userID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,205 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package auth0
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func socialOnlyUser() *Auth0User {
+	return &Auth0User{
+		UserID: "github|1234567",
+		Identities: []Auth0Identity{
+			{
+				Connection:  "github",
+				Provider:    "github",
+				UserID:      float64(1234567),
+				IsSocial:    true,
+				ProfileData: &Auth0ProfileData{Nickname: "octocat", Email: "octocat@example.com"},
+			},
+			{
+				Connection:  constants.GoogleOAuth2Connection,
+				Provider:    constants.GoogleOAuth2Connection,
+				UserID:      "1098",
+				IsSocial:    true,
+				ProfileData: &Auth0ProfileData{Nickname: "octo", Email: "octo@gmail.example"},
+			},
+		},
+	}
+}
+
+func TestSocialPolicy_Username(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy socialPolicy
+		want   string
+	}{
+		{
+			name:   "disabled",
+			policy: socialPolicy{},
+			want:   "",
+		},
+		{
+			name:   "sub by default",
+			policy: socialPolicy{connections: []string{"github"}},
+			want:   "github|1234567",
+		},
+		{
+			name:   "first connection in priority order",
+			policy: socialPolicy{connections: []string{constants.GoogleOAuth2Connection, "github"}, attribute: SocialUsernameSub},
+			want:   "google-oauth2|1098",
+		},
+		{
+			name:   "nickname",
+			policy: socialPolicy{connections: []string{"github"}, attribute: SocialUsernameNickname},
+			want:   "octocat",
+		},
+		{
+			name:   "email",
+			policy: socialPolicy{connections: []string{"github"}, attribute: SocialUsernameEmail},
+			want:   "octocat@example.com",
+		},
+		{
+			name:   "no identity on the configured connections",
+			policy: socialPolicy{connections: []string{"linkedin"}},
+			want:   "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.policy.username(socialOnlyUser()))
+		})
+	}
+}
+
+func TestSocialPolicy_Query(t *testing.T) {
+	github := []string{"github"}
+
+	assert.True(t, socialPolicy{}.query("octocat").IsZero())
+	assert.Equal(t,
+		`identities.provider:"github" AND identities.user_id:"1234567"`,
+		socialPolicy{connections: github}.query("github|1234567").String())
+	assert.True(t, socialPolicy{connections: github}.query("linkedin|1").IsZero(), "provider must be configured")
+	assert.Equal(t,
+		`identities.profileData.nickname:"octocat" AND identities.connection:"github"`,
+		socialPolicy{connections: github, attribute: SocialUsernameNickname}.query("octocat").String())
+
+	assert.NoError(t, socialPolicy{attribute: SocialUsernameEmail}.validate())
+	assert.IsType(t, errors.Validation{}, socialPolicy{attribute: "login"}.validate())
+}
+
+func TestUsernameFilter_Social(t *testing.T) {
+	ctx := context.Background()
+	policy := socialPolicy{connections: []string{"github"}, attribute: SocialUsernameNickname}
+
+	t.Run("query also matches the social identity", func(t *testing.T) {
+		filter := &usernameFilter{user: &model.User{Username: "octocat"}, social: policy}
+		assert.Equal(t,
+			`(identities.user_id:"octocat" AND identities.connection:"Username-Password-Authentication") OR `+
+				`(identities.profileData.nickname:"octocat" AND identities.connection:"github")`,
+			filter.Query(ctx).String())
+	})
+
+	t.Run("social-only user matches", func(t *testing.T) {
+		filter := &usernameFilter{user: &model.User{Username: "octocat"}, social: policy}
+		found, err := filter.Filter(ctx, socialOnlyUser())
+		require.NoError(t, err)
+		assert.True(t, found)
+	})
+
+	t.Run("canonical identity takes precedence over the social one", func(t *testing.T) {
+		auth0User := socialOnlyUser()
+		auth0User.Identities = append(auth0User.Identities, Auth0Identity{
+			Connection: constants.Auth0UsernamePasswordConnection,
+			UserID:     "jdoe",
+		})
+		filter := &usernameFilter{user: &model.User{Username: "octocat"}, social: policy}
+		found, err := filter.Filter(ctx, auth0User)
+		require.NoError(t, err)
+		assert.False(t, found)
+	})
+
+	t.Run("email lookup accepts social-only users", func(t *testing.T) {
+		filter := &emailFilter{user: &model.User{PrimaryEmail: "octocat@example.com"}, social: policy}
+		found, err := filter.Filter(ctx, socialOnlyUser())
+		require.NoError(t, err)
+		assert.True(t, found)
+
+		found, err = (&emailFilter{user: &model.User{}}).Filter(ctx, socialOnlyUser())
+		require.NoError(t, err)
+		assert.False(t, found, "disabled policy keeps social-only users unresolved")
+	})
+}
+
+func TestUserReaderWriter_ToUser_Username(t *testing.T) {
+	rw := newTestReaderWriter(nil)
+	rw.config.CanonicalConnections = []string{"corp-saml"}
+	rw.config.SocialConnections = []string{"github"}
+
+	saml := &Auth0User{UserID: "samlp|corp-saml|jdoe@corp.example", Identities: []Auth0Identity{
+		{Connection: "corp-saml", Provider: "samlp", UserID: "jdoe@corp.example"},
+	}}
+	assert.Equal(t, "jdoe@corp.example", rw.toUser(saml).Username)
+	assert.Equal(t, "github|1234567", rw.toUser(socialOnlyUser()).Username)
+
+	database := &Auth0User{Username: "jdoe"}
+	assert.Equal(t, "jdoe", rw.toUser(database).Username)
+}
+
+// linkedSubTransport 404s on GET /users/{sub} and serves a search result
+type linkedSubTransport struct {
+	searchQuery string
+}
+
+func (l *linkedSubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	status, body := http.StatusNotFound, `{"statusCode":404,"message":"The user does not exist."}`
+	if req.URL.Path == "/api/v2/users" {
+		l.searchQuery = req.URL.Query().Get("q")
+		status = http.StatusOK
+		body = `[{"user_id":"auth0|primary","username":"jdoe","identities":[` +
+			`{"connection":"Username-Password-Authentication","provider":"auth0","user_id":"jdoe"},` +
+			`{"connection":"github","provider":"github","user_id":1234567}]}]`
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func TestUserReaderWriter_GetUser_LinkedSocialSub(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("resolves a linked identity", func(t *testing.T) {
+		transport := &linkedSubTransport{}
+		rw := newTestReaderWriter(transport)
+		rw.config.SocialConnections = []string{"github"}
+
+		user, err := rw.GetUser(ctx, &model.User{UserID: "github|1234567", Token: "token"})
+		require.NoError(t, err)
+		assert.Equal(t, "auth0|primary", user.UserID)
+		assert.Equal(t, "jdoe", user.Username)
+		assert.Equal(t, `identities.provider:"github" AND identities.user_id:"1234567"`, transport.searchQuery)
+	})
+
+	t.Run("not found without a social policy", func(t *testing.T) {
+		transport := &linkedSubTransport{}
+		_, err := newTestReaderWriter(transport).GetUser(ctx, &model.User{UserID: "github|1234567", Token: "token"})
+		require.Error(t, err)
+		assert.ErrorAs(t, err, &errors.NotFound{})
+		assert.Empty(t, transport.searchQuery)
+	})
+}
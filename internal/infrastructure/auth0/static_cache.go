@@ -0,0 +1,171 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package auth0
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/auth0/client"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/cache"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+)
+
+// Kinds of static resources, as named to FlushStaticResources
+const (
+	StaticResourceRoles         = "roles"
+	StaticResourceConnections   = "connections"
+	StaticResourceOrganizations = "organizations"
+)
+
+// staticResourceKinds are the kinds flushed when none is named
+var staticResourceKinds = []string{StaticResourceRoles, StaticResourceConnections, StaticResourceOrganizations}
+
+// StaticCacheTTLs are how long the roles, connections and organizations of
+// the tenant are cached. A TTL of 0 fetches the resource on every use.
+type StaticCacheTTLs struct {
+	Roles         time.Duration
+	Connections   time.Duration
+	Organizations time.Duration
+}
+
+// staticResource caches the whole list of one kind of Management API
+// resource. A nil staticResource caches nothing.
+type staticResource[T any] struct {
+	items    *cache.Cache[string, []T]
+	inflight singleflight.Group
+}
+
+func newStaticResource[T any](ttl time.Duration) *staticResource[T] {
+	if ttl <= 0 {
+		return nil
+	}
+	return &staticResource[T]{items: cache.New[string, []T](ttl, 1)}
+}
+
+// get returns the cached list, calling fetch on a miss. Concurrent misses
+// share one fetch.
+func (r *staticResource[T]) get(ctx context.Context, fetch func(context.Context) ([]T, error)) ([]T, error) {
+	if r == nil {
+		return fetch(ctx)
+	}
+	if items, ok := r.items.Get(""); ok {
+		return items, nil
+	}
+	value, err, _ := r.inflight.Do("", func() (any, error) {
+		items, err := fetch(ctx)
+		if err == nil {
+			r.items.Set("", items)
+		}
+		return items, err
+	})
+	items, _ := value.([]T)
+	return items, err
+}
+
+// flush drops the cached list and reports whether there was one
+func (r *staticResource[T]) flush() bool {
+	if r == nil {
+		return false
+	}
+	cached := r.items.Len() > 0
+	r.items.Delete("")
+	return cached
+}
+
+// staticResources are the cached resources of the tenant that rarely
+// change, which enriching users would otherwise fetch again and again
+type staticResources struct {
+	roles         *staticResource[client.Role]
+	connections   *staticResource[client.Connection]
+	organizations *staticResource[client.Organization]
+}
+
+func newStaticResources(ttls StaticCacheTTLs) staticResources {
+	return staticResources{
+		roles:         newStaticResource[client.Role](ttls.Roles),
+		connections:   newStaticResource[client.Connection](ttls.Connections),
+		organizations: newStaticResource[client.Organization](ttls.Organizations),
+	}
+}
+
+// listAll collects every page of a Management API list with the M2M token
+func listAll[T any](ctx context.Context, u *userReaderWriter, description string, list func(context.Context, string, client.Page) ([]T, error)) ([]T, error) {
+	m2mToken, err := u.config.M2MTokenManager.GetToken(ctx)
+	if err != nil {
+		return nil, errors.NewUnexpected("failed to get M2M token to "+description, err)
+	}
+	items, err := client.Paginate(ctx, client.MaxPerPage, 0, func(ctx context.Context, page client.Page) ([]T, error) {
+		return list(ctx, m2mToken, page)
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to "+description,
+			"error", err,
+			"status_code", client.StatusCode(err),
+		)
+		return nil, errors.NewUnexpected("failed to "+description, err)
+	}
+	return items, nil
+}
+
+// tenantRoles returns every role defined in the tenant
+func (u *userReaderWriter) tenantRoles(ctx context.Context) ([]client.Role, error) {
+	return u.static.roles.get(ctx, func(ctx context.Context) ([]client.Role, error) {
+		return listAll(ctx, u, "list roles", u.api().ListRoles)
+	})
+}
+
+// tenantConnections returns every connection of the tenant
+func (u *userReaderWriter) tenantConnections(ctx context.Context) ([]client.Connection, error) {
+	return u.static.connections.get(ctx, func(ctx context.Context) ([]client.Connection, error) {
+		return listAll(ctx, u, "list connections", u.api().ListConnections)
+	})
+}
+
+// tenantOrganizations returns every organization of the tenant
+func (u *userReaderWriter) tenantOrganizations(ctx context.Context) ([]client.Organization, error) {
+	return u.static.organizations.get(ctx, func(ctx context.Context) ([]client.Organization, error) {
+		return listAll(ctx, u, "list organizations", u.api().ListOrganizations)
+	})
+}
+
+// FlushStaticResources drops the cached resources of kinds, every kind when
+// none is named, so a change made in the Auth0 dashboard shows before the
+// TTL runs out. It returns the kinds that had cached data. It implements
+// port.StaticResourceCache.
+func (u *userReaderWriter) FlushStaticResources(_ context.Context, kinds ...string) ([]string, error) {
+	if len(kinds) == 0 {
+		kinds = staticResourceKinds
+	}
+	for _, kind := range kinds {
+		if !slices.Contains(staticResourceKinds, kind) {
+			return nil, errors.NewValidation(fmt.Sprintf("unknown static resource %q, expected roles, connections or organizations", kind))
+		}
+	}
+
+	flushed := []string{}
+	for _, kind := range kinds {
+		var cached bool
+		switch kind {
+		case StaticResourceRoles:
+			cached = u.static.roles.flush()
+		case StaticResourceConnections:
+			cached = u.static.connections.flush()
+		case StaticResourceOrganizations:
+			cached = u.static.organizations.flush()
+		}
+		if cached {
+			flushed = append(flushed, kind)
+		}
+	}
+	return flushed, nil
+}
+
+var _ port.StaticResourceCache = (*userReaderWriter)(nil)
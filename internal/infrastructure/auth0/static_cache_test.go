@@ -0,0 +1,89 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package auth0
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+)
+
+// staticResourcesTransport serves the roles, connections and organizations
+// of the tenant, counting the requests per path
+type staticResourcesTransport struct {
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (s *staticResourcesTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	s.mu.Lock()
+	s.calls[req.URL.Path]++
+	s.mu.Unlock()
+
+	status, body := http.StatusOK, "[]"
+	switch req.URL.Path {
+	case "/api/v2/roles":
+		body = `[{"id":"rol_1","name":"lf-staff"}]`
+	case "/api/v2/connections":
+		body = `[{"id":"con_1","name":"Username-Password-Authentication","strategy":"auth0"},{"id":"con_2","name":"github","strategy":"github"}]`
+	case "/api/v2/organizations":
+	default:
+		status, body = http.StatusNotFound, `{"statusCode":404,"message":"Not found"}`
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func TestUserReaderWriter_StaticResources(t *testing.T) {
+	ctx := context.Background()
+	transport := &staticResourcesTransport{calls: map[string]int{}}
+	u := newTestReaderWriter(transport)
+	u.static = newStaticResources(StaticCacheTTLs{Roles: time.Hour, Connections: time.Hour})
+
+	t.Run("cached resources are fetched once", func(t *testing.T) {
+		for range 3 {
+			roles, err := u.tenantRoles(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, "lf-staff", roles[0].Name)
+			_, err = u.tenantOrganizations(ctx)
+			require.NoError(t, err)
+		}
+		assert.Equal(t, 1, transport.calls["/api/v2/roles"])
+		assert.Equal(t, 3, transport.calls["/api/v2/organizations"], "organizations have no TTL, so they aren't cached")
+	})
+
+	t.Run("flush drops the cached kinds", func(t *testing.T) {
+		flushed, err := u.FlushStaticResources(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{StaticResourceRoles}, flushed)
+
+		_, err = u.tenantRoles(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 2, transport.calls["/api/v2/roles"])
+
+		_, err = u.FlushStaticResources(ctx, "rules")
+		assert.Error(t, err)
+	})
+
+	t.Run("self-test reports configured connections missing from the tenant", func(t *testing.T) {
+		u.config.SocialConnections = []string{"github", "google-oauth2"}
+		checks := u.SelfTest(ctx)
+		require.Len(t, checks, 2)
+		assert.Equal(t, model.ConfigCheckWarn, checks[1].Status)
+		assert.Contains(t, checks[1].Detail, "google-oauth2")
+	})
+}
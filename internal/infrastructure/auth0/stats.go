@@ -0,0 +1,34 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package auth0
+
+import (
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+)
+
+// ProviderStats reports the Management API call counters and the M2M token age
+func (u *userReaderWriter) ProviderStats() model.ProviderStats {
+	httpStats := u.httpClient.Stats()
+	stats := model.ProviderStats{
+		Type:      constants.UserRepositoryTypeAuth0,
+		Requests:  httpStats.Requests,
+		Errors:    httpStats.Errors,
+		ErrorRate: model.Ratio(httpStats.Errors, httpStats.Requests),
+	}
+
+	if u.config.M2MTokenManager != nil {
+		if refreshedAt := u.config.M2MTokenManager.LastRefresh(); !refreshedAt.IsZero() {
+			age := int64(time.Since(refreshedAt).Seconds())
+			stats.TokenRefreshedAt = &refreshedAt
+			stats.TokenAgeSeconds = &age
+		}
+	}
+	return stats
+}
+
+var _ port.ProviderStatsReporter = (*userReaderWriter)(nil)
@@ -0,0 +1,31 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package auth0
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserReaderWriter_ProviderStats(t *testing.T) {
+	now := time.Now()
+	transport := &dormantTransport{patchStatus: http.StatusServiceUnavailable}
+	u := newTestReaderWriter(transport)
+
+	_, err := u.ListDormantUsers(context.Background(), now, 10)
+	require.NoError(t, err)
+	assert.Error(t, u.TagDormantUser(context.Background(), "auth0|abc", now))
+
+	stats := u.ProviderStats()
+	assert.Equal(t, "auth0", stats.Type)
+	assert.Equal(t, int64(2), stats.Requests)
+	assert.Equal(t, int64(1), stats.Errors)
+	assert.InDelta(t, 0.5, stats.ErrorRate, 0.001)
+	assert.Nil(t, stats.TokenRefreshedAt, "test token source never refreshes through Auth0")
+}
@@ -11,6 +11,7 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/auth0/go-auth0/authentication"
@@ -29,6 +30,7 @@ type TokenManager struct {
 	tokenSource oauth2.TokenSource
 	config      m2mConfig
 	authConfig  *authentication.Authentication
+	source      *auth0TokenSource
 }
 
 // m2mConfig holds the configuration for Auth0 M2M authentication
@@ -47,6 +49,8 @@ type auth0TokenSource struct {
 	audience        string
 	organization    string
 	extraParameters map[string]string
+	// refreshedAt is the unix time of the last successful token fetch
+	refreshedAt atomic.Int64
 }
 
 // Token implements the oauth2.TokenSource interface
@@ -76,6 +80,8 @@ func (a *auth0TokenSource) Token() (*oauth2.Token, error) {
 		Expiry:       time.Now().Add(time.Duration(tokenSet.ExpiresIn)*time.Second - leeway),
 	}
 
+	a.refreshedAt.Store(time.Now().Unix())
+
 	// Add extra fields
 	token = token.WithExtra(map[string]any{
 		"scope": tokenSet.Scope,
@@ -103,6 +109,19 @@ func (tm *TokenManager) GetToken(ctx context.Context) (string, error) {
 	return token.AccessToken, nil
 }
 
+// LastRefresh returns when the M2M token was last fetched from Auth0, or the
+// zero time if it hasn't been fetched yet
+func (tm *TokenManager) LastRefresh() time.Time {
+	if tm.source == nil {
+		return time.Time{}
+	}
+	refreshedAt := tm.source.refreshedAt.Load()
+	if refreshedAt == 0 {
+		return time.Time{}
+	}
+	return time.Unix(refreshedAt, 0)
+}
+
 // IsTokenExpired checks if the current token is expired
 func (tm *TokenManager) IsTokenExpired() bool {
 	token, err := tm.tokenSource.Token()
@@ -175,8 +194,9 @@ func loadM2MConfigFromEnv(ctx context.Context, config Config) (m2mConfig, error)
 	}, nil
 }
 
-// NewM2MTokenManager creates a new M2M token manager using Auth0 SDK
-func NewM2MTokenManager(ctx context.Context, config Config) (*TokenManager, error) {
+// NewM2MTokenManager creates a new M2M token manager using Auth0 SDK.
+// httpClient carries the outbound CA, proxy and TLS settings; nil uses the SDK default.
+func NewM2MTokenManager(ctx context.Context, config Config, httpClient *http.Client) (*TokenManager, error) {
 	m2mConfig, err := loadM2MConfigFromEnv(ctx, config)
 	if err != nil {
 		return nil, errors.NewUnexpected("failed to load M2M configuration", err)
@@ -186,8 +206,10 @@ func NewM2MTokenManager(ctx context.Context, config Config) (*TokenManager, erro
 	authConfig, err := authentication.New(
 		ctx,
 		config.Domain,
-		authentication.WithClientID(m2mConfig.ClientID),
-		authentication.WithClientAssertion(m2mConfig.PrivateKey, "RS256"),
+		authenticationOptions(httpClient,
+			authentication.WithClientID(m2mConfig.ClientID),
+			authentication.WithClientAssertion(m2mConfig.PrivateKey, "RS256"),
+		)...,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Auth0 client: %w", err)
@@ -204,20 +226,25 @@ func NewM2MTokenManager(ctx context.Context, config Config) (*TokenManager, erro
 	// Wrap with oauth2.ReuseTokenSource for automatic caching and renewal
 	reuseTokenSource := oauth2.ReuseTokenSource(nil, tokenSource)
 
-	// Create HTTP client that automatically handles token management
-	httpClient := oauth2.NewClient(ctx, reuseTokenSource)
+	// Create HTTP client that automatically handles token management,
+	// layered over the configured outbound client
+	if httpClient != nil {
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, httpClient)
+	}
+	oauthClient := oauth2.NewClient(ctx, reuseTokenSource)
 
 	return &TokenManager{
-		httpClient:  httpClient,
+		httpClient:  oauthClient,
 		tokenSource: reuseTokenSource,
 		config:      m2mConfig,
 		authConfig:  authConfig,
+		source:      tokenSource,
 	}, nil
 }
 
 // NewProfileClientAuthConfig creates an Auth0 authentication client for LFX Profile
 // using client ID and client secret for passwordless flows
-func NewProfileClientAuthConfig(ctx context.Context, domain string) (*authentication.Authentication, error) {
+func NewProfileClientAuthConfig(ctx context.Context, domain string, httpClient *http.Client) (*authentication.Authentication, error) {
 	clientID := os.Getenv(constants.Auth0LFXProfileClientIDEnvKey)
 	if clientID == "" {
 		return nil, errors.NewUnexpected(constants.Auth0LFXProfileClientIDEnvKey + " is required for email linking flow")
@@ -232,8 +259,10 @@ func NewProfileClientAuthConfig(ctx context.Context, domain string) (*authentica
 	authConfig, err := authentication.New(
 		ctx,
 		domain,
-		authentication.WithClientID(clientID),
-		authentication.WithClientSecret(clientSecret),
+		authenticationOptions(httpClient,
+			authentication.WithClientID(clientID),
+			authentication.WithClientSecret(clientSecret),
+		)...,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Auth0 LFX Profile client: %w", err)
@@ -246,6 +275,15 @@ func NewProfileClientAuthConfig(ctx context.Context, domain string) (*authentica
 	return authConfig, nil
 }
 
+// authenticationOptions appends the outbound HTTP client, when set, to the
+// Auth0 authentication client options
+func authenticationOptions(httpClient *http.Client, opts ...authentication.Option) []authentication.Option {
+	if httpClient != nil {
+		opts = append(opts, authentication.WithClient(httpClient))
+	}
+	return opts
+}
+
 // decodePrivateKey accepts a private key value that may be base64-encoded or raw PEM.
 // If the value is already a PEM key (starts with "-----BEGIN"), it is returned as-is.
 // Otherwise it is base64-decoded.
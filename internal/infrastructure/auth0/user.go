@@ -8,12 +8,15 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
-	"net/url"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
 	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/auth0/client"
 	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/deadline"
 	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
 	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/httpclient"
 	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/jwt"
@@ -30,6 +33,10 @@ type Config struct {
 	M2MTokenManager *TokenManager
 	// JWTVerificationConfig for JWT signature verification
 	JWTVerificationConfig *JWTVerificationConfig
+	// TokenRevocations rejects revoked access tokens during verification (optional)
+	TokenRevocations port.TokenRevocationChecker
+	// DPoP checks the proofs of DPoP-bound access tokens during verification (optional)
+	DPoP *jwt.DPoPVerifier
 	// LFXProfileClientID is the Auth0 client ID for the LFX Profile app,
 	// used to validate current passwords via Resource Owner Password Grant.
 	LFXProfileClientID string
@@ -38,6 +45,56 @@ type Config struct {
 	// LFXOneClientID is the Auth0 client ID for the LFX One app,
 	// used as the audience when sending password reset links.
 	LFXOneClientID string
+	// DatabaseConnection is the database connection passwords live on.
+	// Defaults to Username-Password-Authentication.
+	DatabaseConnection string
+	// CanonicalConnections are the connections whose identity user_id is the
+	// LFX username, e.g. the database connection plus an enterprise (SAML/ADFS)
+	// connection. Defaults to DatabaseConnection.
+	CanonicalConnections []string
+	// SocialConnections are the social connections (e.g. github, google-oauth2)
+	// that supply the username of users without a canonical identity, in
+	// priority order. Empty disables social usernames.
+	SocialConnections []string
+	// SocialUsernameAttribute selects what the social identity supplies:
+	// SocialUsernameSub (default), SocialUsernameNickname or SocialUsernameEmail.
+	SocialUsernameAttribute string
+	// StaticCacheTTLs are how long the roles, connections and organizations
+	// of the tenant are cached
+	StaticCacheTTLs StaticCacheTTLs
+	// Timeouts override DefaultTimeouts per operation
+	Timeouts deadline.Budget
+}
+
+// DefaultTimeouts are the operation timeouts of the Management API. Searches
+// and writes may be retried after a rate limit, and jobs are polled until
+// Auth0 has processed every user.
+var DefaultTimeouts = deadline.Budget{
+	deadline.OperationSearch: 10 * time.Second,
+	deadline.OperationGet:    5 * time.Second,
+	deadline.OperationUpdate: 10 * time.Second,
+	deadline.OperationJobs:   10 * time.Minute,
+}
+
+// databaseConnection returns the configured database connection or the Auth0 default
+func (c Config) databaseConnection() string {
+	if c.DatabaseConnection != "" {
+		return c.DatabaseConnection
+	}
+	return constants.Auth0UsernamePasswordConnection
+}
+
+// social returns the configured social username policy
+func (c Config) social() socialPolicy {
+	return socialPolicy{connections: c.SocialConnections, attribute: c.SocialUsernameAttribute}
+}
+
+// canonicalConnections returns the configured canonical connections or the database connection
+func (c Config) canonicalConnections() []string {
+	if len(c.CanonicalConnections) > 0 {
+		return c.CanonicalConnections
+	}
+	return []string{c.databaseConnection()}
 }
 
 // userUpdateRequest represents the request body for updating a user in Auth0
@@ -50,20 +107,43 @@ type userReaderWriter struct {
 	identityLinkingFlow *identityLinkingFlow
 	emailLinkingFlow    *emailLinkingFlow
 	httpClient          *httpclient.Client
-	errorResponse       *ErrorResponse
+	// jobsHTTPClient downloads job results, which may take longer than the
+	// timeout of one API call
+	jobsHTTPClient *httpclient.Client
+	static         staticResources
+}
+
+// TokenIssuer returns the issuer of the access tokens the repository verifies
+func (u *userReaderWriter) TokenIssuer() string {
+	if u.config.JWTVerificationConfig == nil {
+		return ""
+	}
+	return u.config.JWTVerificationConfig.ExpectedIssuer
+}
+
+// TokenAudience returns the audience of the access tokens the repository verifies
+func (u *userReaderWriter) TokenAudience() string {
+	if u.config.JWTVerificationConfig == nil {
+		return ""
+	}
+	return u.config.JWTVerificationConfig.ExpectedAudience
+}
+
+// api returns the typed Management API client for the configured tenant
+func (u *userReaderWriter) api() *client.Client {
+	return client.New(u.config.Domain, u.httpClient)
 }
 
-// SearchUser searches Auth0 for a user matching the given criteria (email, username, or user_id).
+// SearchUser searches Auth0 for a user matching the given criteria (email, username, alternate email or name).
 func (u *userReaderWriter) SearchUser(ctx context.Context, user *model.User, criteria string) (*model.User, error) {
+	ctx, cancel := u.config.Timeouts.Bound(ctx, deadline.OperationSearch)
+	defer cancel()
 
-	filterer := newUserFilterer(criteria, user)
+	filterer := newUserFilterer(criteria, user, u.config.canonicalConnections(), u.config.social())
 	if filterer == nil {
 		return nil, errors.NewValidation(fmt.Sprintf("invalid criteria type: %s", criteria))
 	}
 
-	endpoint := filterer.Endpoint(ctx)
-	args := filterer.Args(ctx)
-
 	if user.Token == "" {
 		slog.DebugContext(ctx, "getting M2M token",
 			"criteria", criteria,
@@ -76,30 +156,13 @@ func (u *userReaderWriter) SearchUser(ctx context.Context, user *model.User, cri
 		user.Token = m2mToken
 	}
 
-	endpointWithParam := fmt.Sprintf(endpoint, args...)
-	url := fmt.Sprintf("https://%s/api/v2/%s", u.config.Domain, endpointWithParam)
-
-	apiRequest := httpclient.NewAPIRequest(
-		u.httpClient,
-		httpclient.WithMethod(http.MethodGet),
-		httpclient.WithURL(url),
-		httpclient.WithToken(user.Token),
-		httpclient.WithDescription("search user"),
-	)
-
-	var users []Auth0User
-
-	statusCode, errCall := apiRequest.Call(ctx, &users)
-	if errCall != nil {
-		slog.ErrorContext(ctx, "failed to search user",
-			"error", errCall,
-			"status_code", statusCode,
-		)
-		return nil, errors.NewUnexpected("failed to search user", errCall)
+	users, errLookup := filterer.Lookup(ctx, u.api(), user.Token)
+	if errLookup != nil {
+		return nil, errLookup
 	}
 
 	if len(users) == 0 {
-		return nil, errors.NewNotFound("user not found")
+		return nil, errors.NewUserNotFound("user not found")
 	}
 
 	slog.DebugContext(ctx, "users found, checking if the user is the one with the correct identity",
@@ -107,7 +170,7 @@ func (u *userReaderWriter) SearchUser(ctx context.Context, user *model.User, cri
 	)
 
 	for _, userResult := range users {
-		// identities.user_id:{{username}} AND identities.connection:Username-Password-Authentication (and other connections)
+		// identities.user_id:{{username}} AND identities.connection:{{canonical connection}} (and other connections)
 		// It doesn't work like an AND, it works like an IN clause
 		// (check if it contains the username and the connection, but they might not be in  the same identity)
 		// So it's necessary to check if the identity is the one we are looking for
@@ -118,13 +181,32 @@ func (u *userReaderWriter) SearchUser(ctx context.Context, user *model.User, cri
 		if !found {
 			continue
 		}
-		return userResult.ToUser(), nil
+		return u.toUser(&userResult), nil
 	}
-	return nil, errors.NewNotFound("user not found")
+	return nil, errors.NewUserNotFound("user not found")
+}
+
+// toUser converts auth0User, filling in the username from the canonical
+// identity or, for social-only users, from the social username policy
+func (u *userReaderWriter) toUser(auth0User *Auth0User) *model.User {
+	user := auth0User.ToUser()
+	if user.Username != "" {
+		return user
+	}
+	for _, identity := range canonicalConnections(u.config.canonicalConnections()).identities(auth0User) {
+		if userID, ok := identity.UserID.(string); ok && userID != "" {
+			user.Username = userID
+			return user
+		}
+	}
+	user.Username = u.config.social().username(auth0User)
+	return user
 }
 
 // GetUser fetches the full Auth0 user record by user_id.
 func (u *userReaderWriter) GetUser(ctx context.Context, user *model.User) (*model.User, error) {
+	ctx, cancel := u.config.Timeouts.Bound(ctx, deadline.OperationGet)
+	defer cancel()
 
 	slog.DebugContext(ctx, "getting user", "user_id", user.UserID)
 
@@ -150,43 +232,67 @@ func (u *userReaderWriter) GetUser(ctx context.Context, user *model.User) (*mode
 		return nil, errors.NewValidation("Auth0 domain configuration is missing")
 	}
 
-	apiRequest := httpclient.NewAPIRequest(
-		u.httpClient,
-		httpclient.WithMethod(http.MethodGet),
-		httpclient.WithURL(fmt.Sprintf("https://%s/api/v2/users/%s", u.config.Domain, user.UserID)),
-		httpclient.WithToken(user.Token),
-		httpclient.WithDescription("get user details"),
-	)
-
 	// Parse the response to update the user object
 	var auth0User *Auth0User
-	statusCode, errCall := apiRequest.Call(ctx, &auth0User)
+	errCall := u.api().GetUser(ctx, user.Token, user.UserID, &auth0User)
 	if errCall != nil {
+		statusCode := client.StatusCode(errCall)
 		slog.ErrorContext(ctx, "failed to get user from Auth0",
 			"error", errCall,
 			"status_code", statusCode,
 			"user_id", user.UserID,
 		)
-		msg := u.errorResponse.ErrorMessage(errCall.Error())
-		return nil, httpclient.ErrorFromStatusCode(statusCode, msg)
+		if statusCode == http.StatusNotFound {
+			if linked, found := u.findByLinkedSub(ctx, user); found {
+				return linked, nil
+			}
+		}
+		return nil, userAPIError(errCall)
 	}
 
 	if auth0User == nil {
 		slog.ErrorContext(ctx, "failed to get user from Auth0",
-			"status_code", statusCode,
 			"user_id", user.UserID,
 		)
-		return nil, errors.NewNotFound("user not found")
+		return nil, errors.NewUserNotFound("user not found")
 	}
 
 	slog.DebugContext(ctx, "user retrieved successfully", "user_id", user.UserID)
 
-	return auth0User.ToUser(), nil
+	return u.toUser(auth0User), nil
+}
+
+// findByLinkedSub resolves a social sub (e.g. github|1234567) that isn't a
+// primary user_id because the identity was linked into another account
+func (u *userReaderWriter) findByLinkedSub(ctx context.Context, user *model.User) (*model.User, bool) {
+	policy := u.config.social()
+	provider, userID, ok := strings.Cut(user.UserID, "|")
+	if !ok || !slices.Contains(policy.connections, provider) {
+		return nil, false
+	}
+
+	users, err := searchUsers(ctx, u.api(), user.Token, subQuery(provider, userID))
+	if err != nil {
+		return nil, false
+	}
+	for _, candidate := range users {
+		if hasIdentity(&candidate, provider, userID) {
+			slog.DebugContext(ctx, "resolved linked social identity",
+				"sub", redaction.Redact(user.UserID),
+				"user_id", candidate.UserID,
+			)
+			return u.toUser(&candidate), true
+		}
+	}
+	return nil, false
 }
 
 // MetadataLookup prepares the user for metadata lookup based on the input
 // Accepts JWT token, username, or sub
 func (u *userReaderWriter) MetadataLookup(ctx context.Context, input string, requiredScopes ...string) (*model.User, error) {
+	ctx, cancel := u.config.Timeouts.Bound(ctx, deadline.OperationGet)
+	defer cancel()
+
 	// Validate input
 	input = strings.TrimSpace(input)
 	if input == "" {
@@ -246,6 +352,8 @@ func (u *userReaderWriter) MetadataLookup(ctx context.Context, input string, req
 
 // UpdateUser applies the provided changes to the Auth0 user via PATCH.
 func (u *userReaderWriter) UpdateUser(ctx context.Context, user *model.User) (*model.User, error) {
+	ctx, cancel := u.config.Timeouts.Bound(ctx, deadline.OperationUpdate)
+	defer cancel()
 
 	if u.config.JWTVerificationConfig == nil {
 		return nil, errors.NewValidation("JWT verification configuration is required")
@@ -270,27 +378,21 @@ func (u *userReaderWriter) UpdateUser(ctx context.Context, user *model.User) (*m
 	}
 	updateRequest := userUpdateRequest{UserMetadata: user.UserMetadata}
 
-	// Call Auth0 Management API to update the user
-	apiRequest := httpclient.NewAPIRequest(
-		u.httpClient,
-		httpclient.WithMethod(http.MethodPatch),
-		httpclient.WithURL(fmt.Sprintf("https://%s/api/v2/users/%s", u.config.Domain, user.UserID)),
-		httpclient.WithToken(user.Token),
-		httpclient.WithDescription("update user metadata"),
-		httpclient.WithBody(updateRequest),
-	)
-
 	var auth0Response struct {
 		UserMetadata *model.UserMetadata `json:"user_metadata,omitempty"`
 	}
 
-	statusCode, errCall := apiRequest.Call(ctx, &auth0Response)
+	// Call Auth0 Management API to update the user
+	errCall := u.api().UpdateUser(ctx, user.Token, user.UserID, updateRequest, &auth0Response)
 	if errCall != nil {
 		slog.ErrorContext(ctx, "failed to update user in Auth0",
 			"error", errCall,
-			"status_code", statusCode,
+			"status_code", client.StatusCode(errCall),
 			"user_id", user.UserID,
 		)
+		if classified, ok := classify(errCall, true); ok {
+			return nil, classified
+		}
 		return nil, errors.NewUnexpected("failed to update user in Auth0", errCall)
 	}
 
@@ -378,6 +480,8 @@ func (u *userReaderWriter) ValidateLinkRequest(ctx context.Context, request *mod
 
 // LinkIdentity links the secondary identity in the request onto the primary Auth0 user.
 func (u *userReaderWriter) LinkIdentity(ctx context.Context, request *model.LinkIdentity) error {
+	ctx, cancel := u.config.Timeouts.Bound(ctx, deadline.OperationUpdate)
+	defer cancel()
 
 	if u.identityLinkingFlow == nil {
 		return errors.NewUnexpected("email linking flow not configured")
@@ -422,6 +526,8 @@ func (u *userReaderWriter) LinkIdentity(ctx context.Context, request *model.Link
 
 // UnlinkIdentity unlinks the identity in the request from the primary Auth0 user, refusing system-managed identities.
 func (u *userReaderWriter) UnlinkIdentity(ctx context.Context, request *model.UnlinkIdentity) error {
+	ctx, cancel := u.config.Timeouts.Bound(ctx, deadline.OperationUpdate)
+	defer cancel()
 
 	if u.identityLinkingFlow == nil {
 		return errors.NewUnexpected("identity linking flow not configured")
@@ -475,16 +581,9 @@ func (u *userReaderWriter) UnlinkIdentity(ctx context.Context, request *model.Un
 	if errToken != nil {
 		return errors.NewUnexpected("failed to get M2M token for unlink guard", errToken)
 	}
-	apiRequest := httpclient.NewAPIRequest(
-		u.httpClient,
-		httpclient.WithMethod(http.MethodGet),
-		httpclient.WithURL(fmt.Sprintf("https://%s/api/v2/users/%s", u.config.Domain, url.PathEscape(stubUserID))),
-		httpclient.WithToken(m2mToken),
-		httpclient.WithDescription("get stub user for unlink guard"),
-	)
 	var rawStub Auth0User
-	if statusCode, callErr := apiRequest.Call(ctx, &rawStub); callErr != nil {
-		if statusCode != http.StatusNotFound {
+	if callErr := u.api().GetUser(ctx, m2mToken, stubUserID, &rawStub); callErr != nil {
+		if statusCode := client.StatusCode(callErr); statusCode != http.StatusNotFound {
 			slog.ErrorContext(ctx, "failed to evaluate system-managed unlink guard",
 				"stub_user_id", redaction.Redact(stubUserID),
 				"status_code", statusCode,
@@ -520,6 +619,9 @@ func (u *userReaderWriter) UnlinkIdentity(ctx context.Context, request *model.Un
 // Returns the stub user_id. On link failure a best-effort cleanup of the stub
 // is attempted before the error is returned.
 func (u *userReaderWriter) AddSystemManagedEmail(ctx context.Context, primaryUserID, email string) (string, error) {
+	ctx, cancel := u.config.Timeouts.Bound(ctx, deadline.OperationUpdate)
+	defer cancel()
+
 	if strings.TrimSpace(primaryUserID) == "" {
 		return "", errors.NewValidation("primary_user_id is required")
 	}
@@ -560,18 +662,10 @@ func (u *userReaderWriter) createAndLinkEmailIdentity(ctx context.Context, prima
 		AppMetadata:   appMetadata,
 	}
 
-	apiCreate := httpclient.NewAPIRequest(
-		u.httpClient,
-		httpclient.WithMethod(http.MethodPost),
-		httpclient.WithURL(fmt.Sprintf("https://%s/api/v2/users", u.config.Domain)),
-		httpclient.WithToken(m2mToken),
-		httpclient.WithDescription("create email stub user"),
-		httpclient.WithBody(createPayload),
-	)
-
 	var stubUser Auth0User
-	statusCode, errCreate := apiCreate.Call(ctx, &stubUser)
+	errCreate := u.api().CreateUser(ctx, m2mToken, createPayload, &stubUser)
 	if errCreate != nil {
+		statusCode := client.StatusCode(errCreate)
 		slog.ErrorContext(ctx, "failed to create email stub user",
 			"error", errCreate,
 			"status_code", statusCode,
@@ -606,21 +700,11 @@ func (u *userReaderWriter) createAndLinkEmailIdentity(ctx context.Context, prima
 		UserID:   localID,
 	}
 
-	apiLink := httpclient.NewAPIRequest(
-		u.httpClient,
-		httpclient.WithMethod(http.MethodPost),
-		httpclient.WithURL(fmt.Sprintf("https://%s/api/v2/users/%s/identities", u.config.Domain, url.PathEscape(primaryUserID))),
-		httpclient.WithToken(m2mToken),
-		httpclient.WithDescription("link email stub to primary user"),
-		httpclient.WithBody(linkPayload),
-	)
-
-	var linkedIdentities []any
-	statusCode, errLink := apiLink.Call(ctx, &linkedIdentities)
+	errLink := u.api().LinkIdentity(ctx, m2mToken, primaryUserID, linkPayload)
 	if errLink != nil {
 		slog.ErrorContext(ctx, "failed to link email stub to primary user; attempting rollback",
 			"error", errLink,
-			"status_code", statusCode,
+			"status_code", client.StatusCode(errLink),
 			"stub_user_id", redaction.Redact(stubUser.UserID),
 			"primary_user_id", redaction.Redact(primaryUserID),
 		)
@@ -658,8 +742,15 @@ func (u *userReaderWriter) rollbackEmailStub(ctx context.Context, userID, m2mTok
 // NewUserReaderWriter  creates a new UserReaderWriter with the provided configuration
 func NewUserReaderWriter(ctx context.Context, httpConfig httpclient.Config, auth0Config Config) (port.UserReaderWriter, error) {
 
+	if err := auth0Config.social().validate(); err != nil {
+		return nil, err
+	}
+
+	// SDK clients share the CA, proxy and TLS settings of the API client
+	standardClient := httpclient.NewStandardClient(httpConfig)
+
 	// Add M2M token manager to config
-	m2mTokenManager, err := NewM2MTokenManager(ctx, auth0Config)
+	m2mTokenManager, err := NewM2MTokenManager(ctx, auth0Config, standardClient)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create M2M token manager: %w", err)
 	}
@@ -668,6 +759,9 @@ func NewUserReaderWriter(ctx context.Context, httpConfig httpclient.Config, auth
 
 	// Create httpClient first
 	httpClient := httpclient.NewClient(httpConfig)
+	auth0Config.Timeouts = DefaultTimeouts.With(auth0Config.Timeouts)
+	jobsHTTPConfig := httpConfig
+	jobsHTTPConfig.Timeout = max(httpConfig.Timeout, auth0Config.Timeouts[deadline.OperationJobs])
 
 	// JWT verification config is required
 	if auth0Config.JWTVerificationConfig == nil {
@@ -680,9 +774,15 @@ func NewUserReaderWriter(ctx context.Context, httpConfig httpclient.Config, auth
 		}
 		auth0Config.JWTVerificationConfig = jwtConfig
 	}
+	if auth0Config.TokenRevocations != nil {
+		auth0Config.JWTVerificationConfig.Revocations = auth0Config.TokenRevocations
+	}
+	if auth0Config.DPoP != nil {
+		auth0Config.JWTVerificationConfig.DPoP = auth0Config.DPoP
+	}
 
 	// Create profile client auth config for email linking flow (passwordless)
-	profileClientAuthConfig, err := NewProfileClientAuthConfig(ctx, auth0Config.Domain)
+	profileClientAuthConfig, err := NewProfileClientAuthConfig(ctx, auth0Config.Domain, standardClient)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create profile client auth config: %w", err)
 	}
@@ -698,7 +798,8 @@ func NewUserReaderWriter(ctx context.Context, httpConfig httpclient.Config, auth
 		identityLinkingFlow: identityLinkingFlow,
 		emailLinkingFlow:    emailLinkingFlow,
 		httpClient:          httpClient,
-		errorResponse:       NewErrorResponse(),
+		jobsHTTPClient:      httpclient.NewClient(jobsHTTPConfig),
+		static:              newStaticResources(auth0Config.StaticCacheTTLs),
 	}, nil
 }
 
@@ -741,6 +842,8 @@ func hasSufficientPrimaryEmailIdentity(user *model.User, email string) bool {
 // SetPrimaryEmail updates the user's primary email address via the Auth0 Management API.
 // The email must already be a verified linked identity on the user's account.
 func (u *userReaderWriter) SetPrimaryEmail(ctx context.Context, userID string, email string) error {
+	ctx, cancel := u.config.Timeouts.Bound(ctx, deadline.OperationUpdate)
+	defer cancel()
 
 	if strings.TrimSpace(userID) == "" {
 		return errors.NewValidation("user ID is required")
@@ -807,23 +910,16 @@ func (u *userReaderWriter) SetPrimaryEmail(ctx context.Context, userID string, e
 		EmailVerified: true,
 	}
 
-	apiRequest := httpclient.NewAPIRequest(
-		u.httpClient,
-		httpclient.WithMethod(http.MethodPatch),
-		httpclient.WithURL(fmt.Sprintf("https://%s/api/v2/users/%s", u.config.Domain, url.PathEscape(userID))),
-		httpclient.WithToken(m2mToken),
-		httpclient.WithDescription("set primary email"),
-		httpclient.WithBody(payload),
-	)
-
-	var patchResponse map[string]any
-	statusCode, errCall := apiRequest.Call(ctx, &patchResponse)
+	errCall := u.api().UpdateUser(ctx, m2mToken, userID, payload, nil)
 	if errCall != nil {
 		slog.ErrorContext(ctx, "failed to set primary email in Auth0",
 			"error", errCall,
-			"status_code", statusCode,
+			"status_code", client.StatusCode(errCall),
 			"user_id", redaction.Redact(userID),
 		)
+		if classified, ok := classify(errCall, true); ok {
+			return classified
+		}
 		return errors.NewUnexpected("failed to set primary email", errCall)
 	}
 
@@ -844,15 +940,9 @@ func (u *userReaderWriter) deleteSystemManagedUser(ctx context.Context, userID,
 		return errors.NewValidation("user_id is required")
 	}
 
-	apiGet := httpclient.NewAPIRequest(
-		u.httpClient,
-		httpclient.WithMethod(http.MethodGet),
-		httpclient.WithURL(fmt.Sprintf("https://%s/api/v2/users/%s", u.config.Domain, url.PathEscape(userID))),
-		httpclient.WithToken(m2mToken),
-		httpclient.WithDescription("verify system-managed before delete"),
-	)
 	var target Auth0User
-	if statusCode, errGet := apiGet.Call(ctx, &target); errGet != nil {
+	if errGet := u.api().GetUser(ctx, m2mToken, userID, &target); errGet != nil {
+		statusCode := client.StatusCode(errGet)
 		if statusCode == http.StatusNotFound {
 			// Already gone — nothing to clean up.
 			return nil
@@ -868,14 +958,7 @@ func (u *userReaderWriter) deleteSystemManagedUser(ctx context.Context, userID,
 		return errors.NewForbidden("refusing to delete user without app_metadata.system_managed=true")
 	}
 
-	apiDelete := httpclient.NewAPIRequest(
-		u.httpClient,
-		httpclient.WithMethod(http.MethodDelete),
-		httpclient.WithURL(fmt.Sprintf("https://%s/api/v2/users/%s", u.config.Domain, url.PathEscape(userID))),
-		httpclient.WithToken(m2mToken),
-		httpclient.WithDescription("delete system-managed user"),
-	)
-	if _, errDel := apiDelete.Call(ctx, nil); errDel != nil {
+	if errDel := u.api().DeleteUser(ctx, m2mToken, userID); errDel != nil {
 		return errors.NewUnexpected("failed to delete system-managed user", errDel)
 	}
 	return nil
@@ -893,15 +976,9 @@ func (u *userReaderWriter) deleteEmailConnectionStub(ctx context.Context, userID
 		return errors.NewValidation("user_id is required")
 	}
 
-	apiGet := httpclient.NewAPIRequest(
-		u.httpClient,
-		httpclient.WithMethod(http.MethodGet),
-		httpclient.WithURL(fmt.Sprintf("https://%s/api/v2/users/%s", u.config.Domain, url.PathEscape(userID))),
-		httpclient.WithToken(m2mToken),
-		httpclient.WithDescription("verify email-connection stub before delete"),
-	)
 	var target Auth0User
-	if statusCode, errGet := apiGet.Call(ctx, &target); errGet != nil {
+	if errGet := u.api().GetUser(ctx, m2mToken, userID, &target); errGet != nil {
+		statusCode := client.StatusCode(errGet)
 		if statusCode == http.StatusNotFound {
 			// Already gone — nothing to clean up.
 			return nil
@@ -922,14 +999,7 @@ func (u *userReaderWriter) deleteEmailConnectionStub(ctx context.Context, userID
 		}
 	}
 
-	apiDelete := httpclient.NewAPIRequest(
-		u.httpClient,
-		httpclient.WithMethod(http.MethodDelete),
-		httpclient.WithURL(fmt.Sprintf("https://%s/api/v2/users/%s", u.config.Domain, url.PathEscape(userID))),
-		httpclient.WithToken(m2mToken),
-		httpclient.WithDescription("delete email-connection stub user"),
-	)
-	if _, errDel := apiDelete.Call(ctx, nil); errDel != nil {
+	if errDel := u.api().DeleteUser(ctx, m2mToken, userID); errDel != nil {
 		return errors.NewUnexpected("failed to delete email-connection stub user", errDel)
 	}
 	return nil
@@ -18,6 +18,7 @@ import (
 	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
 	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
 	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/converters"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/deadline"
 	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/httpclient"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -558,7 +559,7 @@ func TestUserReaderWriter_UpdateUser_JWTValidationIntegration(t *testing.T) {
 					},
 				}
 			},
-			expectedErr: "failed to parse jws",
+			expectedErr: "auth_token could not be verified",
 		},
 		{
 			name: "expired jwt token",
@@ -580,7 +581,7 @@ func TestUserReaderWriter_UpdateUser_JWTValidationIntegration(t *testing.T) {
 					},
 				}
 			},
-			expectedErr: "exp",
+			expectedErr: "auth_token could not be verified",
 		},
 		{
 			name: "missing required scope",
@@ -1012,8 +1013,10 @@ func (f *fakeAuth0Transport) countFor(method, pathSuffix string) int {
 // newTestReaderWriter builds a userReaderWriter wired to the given transport and
 // a token manager that returns a fixed valid M2M token.
 func newTestReaderWriter(transport http.RoundTripper) *userReaderWriter {
+	httpClient := httpclient.NewClient(httpclient.Config{Transport: transport, MaxRetries: 0})
 	return &userReaderWriter{
-		httpClient: httpclient.NewClient(httpclient.Config{Transport: transport, MaxRetries: 0}),
+		httpClient:     httpClient,
+		jobsHTTPClient: httpClient,
 		config: Config{
 			Domain:          "test-tenant.auth0.com",
 			M2MTokenManager: &TokenManager{tokenSource: fakeTokenSource{token: "test-m2m-token"}},
@@ -1361,3 +1364,32 @@ func TestUserReaderWriter_AddSystemManagedEmail_HTTPFlow(t *testing.T) {
 		assert.Contains(t, err.Error(), "email already linked")
 	})
 }
+
+// deadlineTransport records the deadline of each request it serves
+type deadlineTransport struct {
+	deadlines []time.Time
+}
+
+func (d *deadlineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	deadline, _ := req.Context().Deadline()
+	d.deadlines = append(d.deadlines, deadline)
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(`{"user_id":"auth0|test123"}`)),
+		Request:    req,
+	}, nil
+}
+
+func TestUserReaderWriter_OperationTimeouts(t *testing.T) {
+	transport := &deadlineTransport{}
+	rw := newTestReaderWriter(transport)
+	rw.config.Timeouts = DefaultTimeouts.With(deadline.Budget{deadline.OperationGet: time.Minute})
+
+	before := time.Now()
+	_, err := rw.GetUser(context.Background(), &model.User{UserID: testPrimaryUserID})
+	require.NoError(t, err)
+
+	require.Len(t, transport.deadlines, 1)
+	assert.WithinRange(t, transport.deadlines[0], before.Add(time.Minute), time.Now().Add(time.Minute))
+}
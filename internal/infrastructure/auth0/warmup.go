@@ -0,0 +1,39 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package auth0
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// WarmUp loads what the first requests would otherwise wait for: the M2M
+// token, the verifier of the signing key loaded from the JWKS when the
+// provider was built, and the roles, connections and organizations of the
+// tenant, which need the token. It reports what failed to load; nothing is
+// required, as each is loaded again on first use.
+func (u *userReaderWriter) WarmUp(ctx context.Context) error {
+	if u.config.JWTVerificationConfig != nil {
+		u.config.JWTVerificationConfig.prepareVerifier()
+	}
+	if u.config.M2MTokenManager == nil {
+		return nil
+	}
+
+	var errs []error
+	if _, err := u.config.M2MTokenManager.GetToken(ctx); err != nil {
+		return fmt.Errorf("M2M token: %w", err)
+	}
+	if _, err := u.tenantRoles(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("roles: %w", err))
+	}
+	if _, err := u.tenantConnections(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("connections: %w", err))
+	}
+	if _, err := u.tenantOrganizations(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("organizations: %w", err))
+	}
+	return errors.Join(errs...)
+}
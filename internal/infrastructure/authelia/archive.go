@@ -0,0 +1,238 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package authelia
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/redaction"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/tenant"
+)
+
+// accessTouchInterval is how stale a user's AccessedAt may get before a read
+// writes it again, so only the first read of the day costs a write
+const accessTouchInterval = 24 * time.Hour
+
+var (
+	archivedUsers, _ = meter.Int64Counter("auth_service.authelia.users.archived",
+		metric.WithDescription("Inactive Authelia users moved to the archive"))
+	restoredUsers, _ = meter.Int64Counter("auth_service.authelia.users.restored",
+		metric.WithDescription("Archived Authelia users restored on access"))
+)
+
+// WithUserArchive enables archival: ArchiveInactiveUsers moves the records of
+// inactive users to an archive bucket next to the users bucket, and a read of
+// an archived user moves it back. Lookup keys stay in the users bucket, so an
+// archived user is still found by email or sub. Only the KV user stores
+// (NATS and Redis) support it.
+func WithUserArchive() Option {
+	return func(u *userReaderWriter) {
+		u.archive = true
+	}
+}
+
+// lastActive is the last time the user was created, written or read
+func lastActive(user *AutheliaUser) time.Time {
+	last := user.CreatedAt
+	for _, t := range []time.Time{user.UpdatedAt, user.AccessedAt} {
+		if t.After(last) {
+			last = t
+		}
+	}
+	return last
+}
+
+// touchUser records that user was read and returns the record's revision
+// afterwards. The write is best effort: it only feeds archival, so a read
+// doesn't fail, or retry, for it.
+func (n *kvUserStorage) touchUser(ctx context.Context, user *AutheliaUser, revision uint64) uint64 {
+	now := time.Now()
+	if now.Sub(user.AccessedAt) < accessTouchInterval {
+		return revision
+	}
+	user.AccessedAt = now
+
+	data, err := json.Marshal(user.ToStorage())
+	if err == nil {
+		data, err = sealValue(ctx, n.envelope, user.Username, data)
+	}
+	if err == nil {
+		var touched uint64
+		if touched, err = n.users.Update(ctx, user.Username, data, revision); err == nil {
+			return touched
+		}
+	}
+	slog.DebugContext(ctx, "failed to record user access",
+		"username", redaction.Redact(user.Username),
+		"error", err,
+	)
+	return revision
+}
+
+// restoreUser moves an archived user back to the users bucket and returns it
+// with its new revision
+func (n *kvUserStorage) restoreUser(ctx context.Context, username string) (*AutheliaUser, uint64, error) {
+	sealed, archivedRevision, err := n.archive.Get(ctx, username)
+	if err != nil {
+		if errors.Is(err, errKeyNotFound) {
+			return nil, 0, errs.NewUserNotFound("user not found")
+		}
+		return nil, 0, errs.NewUnexpected("failed to get user from the "+n.backend+" archive", err)
+	}
+	user, err := n.decodeUser(ctx, username, sealed)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	revision, err := n.users.Create(ctx, username, sealed)
+	if errors.Is(err, errRevisionMismatch) {
+		// another replica restored it first
+		return n.readUser(ctx, username)
+	}
+	if err != nil {
+		return nil, 0, errs.NewUnexpected("failed to restore user in "+n.backend, err)
+	}
+
+	if err := n.setLookupKeys(ctx, user, nil); err != nil {
+		slog.WarnContext(ctx, "failed to rewrite lookup keys of restored user",
+			"username", redaction.Redact(username),
+			"error", err,
+		)
+	}
+	if err := n.archive.Delete(ctx, username, archivedRevision); err != nil && !errors.Is(err, errKeyNotFound) {
+		slog.WarnContext(ctx, "failed to delete restored user from the archive",
+			"username", redaction.Redact(username),
+			"error", err,
+		)
+	}
+
+	restoredUsers.Add(ctx, 1, tenant.Attributes(ctx))
+	slog.InfoContext(ctx, "restored archived user", "username", redaction.Redact(username))
+	return user, revision, nil
+}
+
+// archiveUser moves the record read at revision to the archive. It reports
+// false when the user was written since, and is therefore kept.
+func (n *kvUserStorage) archiveUser(ctx context.Context, username string, sealed []byte, revision uint64) (bool, error) {
+	archivedRevision, err := n.archive.Put(ctx, username, sealed)
+	if err != nil {
+		return false, err
+	}
+	if err := n.users.Delete(ctx, username, revision); err != nil {
+		if errors.Is(err, errRevisionMismatch) || errors.Is(err, errKeyNotFound) {
+			_ = n.archive.Delete(ctx, username, archivedRevision)
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// isArchived reports whether username is in the archive
+func (n *kvUserStorage) isArchived(ctx context.Context, username string) (bool, error) {
+	if n.archive == nil {
+		return false, nil
+	}
+	_, _, err := n.archive.Get(ctx, username)
+	if errors.Is(err, errKeyNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// ArchiveInactiveUsers moves up to limit users last active before cutoff to
+// the archive; limit <= 0 means no limit
+func (n *kvUserStorage) ArchiveInactiveUsers(ctx context.Context, cutoff time.Time, limit int) (model.ArchiveResult, error) {
+	var result model.ArchiveResult
+	if n.archive == nil {
+		return result, errs.NewValidation("user archival is not enabled")
+	}
+
+	keys, err := n.users.Keys(ctx)
+	if err != nil {
+		return result, errs.NewUnexpected("failed to list keys from "+n.backend, err)
+	}
+
+	for _, key := range keys {
+		if limit > 0 && result.Archived >= limit {
+			break
+		}
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+		if strings.HasPrefix(key, kvLookupPrefix) {
+			continue
+		}
+
+		sealed, revision, err := n.users.Get(ctx, key)
+		if errors.Is(err, errKeyNotFound) {
+			continue
+		}
+		var user *AutheliaUser
+		if err == nil {
+			user, err = n.decodeUser(ctx, key, sealed)
+		}
+		if err != nil {
+			result.Failed++
+			slog.WarnContext(ctx, "failed to read user for archival", "username", redaction.Redact(key), "error", err)
+			continue
+		}
+		result.Scanned++
+
+		if !lastActive(user).Before(cutoff) {
+			continue
+		}
+		archived, err := n.archiveUser(ctx, key, sealed, revision)
+		if err != nil {
+			result.Failed++
+			slog.WarnContext(ctx, "failed to archive inactive user", "username", redaction.Redact(key), "error", err)
+			continue
+		}
+		if archived {
+			result.Archived++
+			archivedUsers.Add(ctx, 1, tenant.Attributes(ctx))
+		}
+	}
+
+	slog.InfoContext(ctx, "inactive user archival completed",
+		"cutoff", cutoff,
+		"scanned", result.Scanned,
+		"archived", result.Archived,
+		"failed", result.Failed,
+	)
+	return result, nil
+}
+
+// ArchiveInactiveUsers moves inactive users to the archive. It implements
+// port.InactiveUserArchiver.
+func (a *userReaderWriter) ArchiveInactiveUsers(ctx context.Context, cutoff time.Time, limit int) (model.ArchiveResult, error) {
+	archiver, ok := a.storage.(port.InactiveUserArchiver)
+	if !ok {
+		return model.ArchiveResult{}, errs.NewValidation("user storage does not support archival")
+	}
+	return archiver.ArchiveInactiveUsers(ctx, cutoff, limit)
+}
+
+// archivedInStorage reports whether storage holds username in its archive
+func archivedInStorage(ctx context.Context, storage UserStore, username string) (bool, error) {
+	archive, ok := storage.(interface {
+		isArchived(ctx context.Context, username string) (bool, error)
+	})
+	if !ok {
+		return false, nil
+	}
+	return archive.isArchived(ctx, username)
+}
+
+var _ port.InactiveUserArchiver = (*userReaderWriter)(nil)
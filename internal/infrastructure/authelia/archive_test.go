@@ -0,0 +1,87 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package authelia
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+)
+
+func TestArchiveInactiveUsers(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+	storage, err := newRedisUserStorage(ctx, RedisStoreConfig{URL: "redis://" + server.Addr(), KeyPrefix: "authelia:"}, nil, true)
+	require.NoError(t, err)
+	store := storage.(*kvUserStorage)
+
+	user := &AutheliaUser{
+		User:  &model.User{Username: "jdoe", Sub: "sub-jdoe", PrimaryEmail: "jdoe@example.com"},
+		Email: "jdoe@example.com",
+	}
+	_, err = store.SetUser(ctx, user)
+	require.NoError(t, err)
+
+	t.Run("recently active users are kept", func(t *testing.T) {
+		result, err := store.ArchiveInactiveUsers(ctx, time.Now().Add(-time.Hour), 0)
+		require.NoError(t, err)
+		assert.Equal(t, model.ArchiveResult{Scanned: 1}, result)
+	})
+
+	t.Run("inactive users move to the archive", func(t *testing.T) {
+		result, err := store.ArchiveInactiveUsers(ctx, time.Now().Add(time.Hour), 0)
+		require.NoError(t, err)
+		assert.Equal(t, model.ArchiveResult{Scanned: 1, Archived: 1}, result)
+		assert.False(t, server.Exists("authelia:users/jdoe"))
+		assert.True(t, server.Exists("authelia:archive/jdoe"))
+
+		users, err := store.ListUsers(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, users, "scans don't restore archived users")
+
+		archived, err := archivedInStorage(ctx, store, "jdoe")
+		require.NoError(t, err)
+		assert.True(t, archived)
+	})
+
+	t.Run("a lookup restores the user", func(t *testing.T) {
+		restored, revision, err := store.GetUserWithRevision(ctx, store.BuildLookupKey(ctx, "email", user.BuildEmailIndexKey(ctx)))
+		require.NoError(t, err)
+		assert.Equal(t, "sub-jdoe", restored.Sub)
+		assert.WithinDuration(t, time.Now(), restored.AccessedAt, time.Minute, "the read counts as access")
+		assert.True(t, server.Exists("authelia:users/jdoe"))
+		assert.False(t, server.Exists("authelia:archive/jdoe"))
+
+		// the returned revision is the one after the access was recorded
+		require.NoError(t, store.UpdateUserWithRevision(ctx, restored, revision))
+	})
+
+	t.Run("unknown users are not found", func(t *testing.T) {
+		_, err := store.GetUser(ctx, "nobody")
+		var notFound errs.NotFound
+		assert.ErrorAs(t, err, &notFound)
+	})
+
+	t.Run("stores without an archive", func(t *testing.T) {
+		_, err := (&kvUserStorage{}).ArchiveInactiveUsers(ctx, time.Now(), 0)
+		var validation errs.Validation
+		assert.ErrorAs(t, err, &validation)
+	})
+}
+
+func TestLastActive(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	user := &AutheliaUser{CreatedAt: created, UpdatedAt: created.Add(time.Hour)}
+	assert.Equal(t, created.Add(time.Hour), lastActive(user))
+
+	user.AccessedAt = created.Add(48 * time.Hour)
+	assert.Equal(t, user.AccessedAt, lastActive(user))
+}
@@ -0,0 +1,52 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package authelia
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/httpclient"
+)
+
+// CheckConfig verifies that the OIDC endpoints of Authelia can be reached:
+// the discovery document when issuer is set, and the userinfo endpoint
+// every opaque token lookup calls
+func CheckConfig(ctx context.Context, httpConfig httpclient.Config, userInfoURL, issuer string) []model.ConfigCheck {
+	httpClient := httpclient.NewClient(httpConfig)
+
+	var checks []model.ConfigCheck
+	if issuer != "" {
+		u := &userReaderWriter{}
+		WithOIDCDiscovery(issuer, 0)(u)
+		checks = append(checks, model.RunConfigCheck("authelia.oidc_discovery", func() (string, string) {
+			if err := u.discovery.refresh(ctx, httpClient); err != nil {
+				return model.ConfigCheckFail, err.Error()
+			}
+			userInfoURL = u.userInfoURL()
+			return model.ConfigCheckOK, u.discovery.issuer + oidcDiscoveryPath
+		}))
+	}
+
+	checks = append(checks, model.RunConfigCheck("authelia.userinfo", func() (string, string) {
+		if userInfoURL == "" {
+			return model.ConfigCheckFail, "no userinfo endpoint configured"
+		}
+		apiRequest := httpclient.NewAPIRequest(
+			httpClient,
+			httpclient.WithMethod(http.MethodGet),
+			httpclient.WithURL(userInfoURL),
+			httpclient.WithDescription("check OIDC userinfo endpoint"),
+		)
+		// without a token Authelia answers 401, which shows it is reachable
+		statusCode, err := apiRequest.Call(ctx, nil)
+		if err != nil && statusCode != http.StatusUnauthorized {
+			return model.ConfigCheckFail, fmt.Sprintf("%s is not reachable: %v", userInfoURL, err)
+		}
+		return model.ConfigCheckOK, userInfoURL
+	}))
+	return checks
+}
@@ -0,0 +1,58 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package authelia
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/httpclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckConfig(t *testing.T) {
+	ctx := context.Background()
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case oidcDiscoveryPath:
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(OIDCDiscovery{Issuer: server.URL, UserInfoEndpoint: server.URL + "/userinfo"})
+		case "/userinfo":
+			w.WriteHeader(http.StatusUnauthorized)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	config := httpclient.DefaultConfig()
+	config.MaxRetries = 0
+
+	t.Run("discovered endpoints", func(t *testing.T) {
+		checks := CheckConfig(ctx, config, "", server.URL)
+		require.Len(t, checks, 2)
+		assert.Equal(t, model.ConfigCheckOK, checks[0].Status)
+		assert.Equal(t, model.ConfigCheckOK, checks[1].Status)
+		assert.Equal(t, server.URL+"/userinfo", checks[1].Detail)
+	})
+
+	t.Run("configured userinfo endpoint", func(t *testing.T) {
+		checks := CheckConfig(ctx, config, server.URL+"/userinfo", "")
+		require.Len(t, checks, 1)
+		assert.Equal(t, model.ConfigCheckOK, checks[0].Status)
+	})
+
+	t.Run("unreachable userinfo endpoint", func(t *testing.T) {
+		checks := CheckConfig(ctx, config, server.URL+"/missing", "")
+		require.Len(t, checks, 1)
+		assert.Equal(t, model.ConfigCheckFail, checks[0].Status)
+	})
+}
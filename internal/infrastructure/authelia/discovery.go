@@ -0,0 +1,149 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package authelia
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/httpclient"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/scheduler"
+)
+
+const (
+	// oidcDiscoveryPath is where OpenID Connect providers publish their metadata
+	oidcDiscoveryPath = "/.well-known/openid-configuration"
+
+	// oidcDiscoveryAttempts and oidcDiscoveryRetryDelay give Authelia time to
+	// come up when both start together; the delay doubles between attempts
+	oidcDiscoveryAttempts   = 5
+	oidcDiscoveryRetryDelay = 2 * time.Second
+)
+
+// OIDCDiscovery is the part of the OpenID Connect discovery document the
+// service uses
+type OIDCDiscovery struct {
+	Issuer                string `json:"issuer"`
+	UserInfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+	TokenEndpoint         string `json:"token_endpoint,omitempty"`
+	IntrospectionEndpoint string `json:"introspection_endpoint,omitempty"`
+}
+
+// oidcDiscoverer fetches the discovery document of an issuer and keeps the
+// last good one, so a failed refresh doesn't take the endpoints away
+type oidcDiscoverer struct {
+	issuer          string
+	refreshInterval time.Duration
+	attempts        int
+	retryDelay      time.Duration
+
+	current atomic.Pointer[OIDCDiscovery]
+}
+
+// WithOIDCDiscovery derives the OIDC endpoints from the discovery document of
+// issuer instead of configuring them one by one. The document is fetched at
+// startup, where failing to fetch it is fatal, and every refreshInterval
+// after; 0 fetches it only at startup.
+func WithOIDCDiscovery(issuer string, refreshInterval time.Duration) Option {
+	return func(u *userReaderWriter) {
+		u.discovery = &oidcDiscoverer{
+			issuer:          strings.TrimSuffix(strings.TrimSpace(issuer), "/"),
+			refreshInterval: refreshInterval,
+			attempts:        oidcDiscoveryAttempts,
+			retryDelay:      oidcDiscoveryRetryDelay,
+		}
+	}
+}
+
+// start fetches the discovery document, retrying while Authelia is
+// unreachable, and schedules its refresh
+func (d *oidcDiscoverer) start(ctx context.Context, httpClient *httpclient.Client) error {
+	delay := d.retryDelay
+	var err error
+	for attempt := 1; attempt <= d.attempts; attempt++ {
+		if err = d.refresh(ctx, httpClient); err == nil {
+			break
+		}
+		if attempt == d.attempts {
+			return errs.NewServiceUnavailable(fmt.Sprintf("OIDC discovery at %s failed after %d attempts", d.issuer+oidcDiscoveryPath, d.attempts), err)
+		}
+		slog.WarnContext(ctx, "OIDC discovery failed, retrying",
+			"issuer", d.issuer,
+			"attempt", attempt,
+			"retry_in", delay,
+			"error", err,
+		)
+		select {
+		case <-ctx.Done():
+			return errs.NewServiceUnavailable("OIDC discovery cancelled", ctx.Err())
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+
+	discovery := d.current.Load()
+	slog.InfoContext(ctx, "OIDC discovery loaded",
+		"issuer", discovery.Issuer,
+		"userinfo_endpoint", discovery.UserInfoEndpoint,
+		"jwks_uri", discovery.JWKSURI,
+	)
+	if d.refreshInterval > 0 {
+		scheduler.Every(ctx, "authelia-oidc-discovery", d.refreshInterval, d.refreshInterval, func(ctx context.Context) error {
+			return d.refresh(ctx, httpClient)
+		})
+	}
+	return nil
+}
+
+// refresh fetches and validates the discovery document, replacing the
+// current one only when the new one is usable
+func (d *oidcDiscoverer) refresh(ctx context.Context, httpClient *httpclient.Client) error {
+	apiRequest := httpclient.NewAPIRequest(
+		httpClient,
+		httpclient.WithMethod(http.MethodGet),
+		httpclient.WithURL(d.issuer+oidcDiscoveryPath),
+		httpclient.WithDescription("fetch OIDC discovery document"),
+	)
+
+	var discovery OIDCDiscovery
+	statusCode, err := apiRequest.Call(ctx, &discovery)
+	if err != nil {
+		return httpclient.ErrorFromStatusCode(statusCode, fmt.Sprintf("failed to fetch OIDC discovery document: %v", err))
+	}
+
+	// OpenID Connect Discovery 1.0 §4.3: the issuer must be the one the
+	// document was fetched for, or the endpoints can't be trusted
+	if strings.TrimSuffix(discovery.Issuer, "/") != d.issuer {
+		return errs.NewValidation(fmt.Sprintf("OIDC discovery issuer %q does not match the configured issuer %q", discovery.Issuer, d.issuer))
+	}
+	if discovery.UserInfoEndpoint == "" {
+		return errs.NewValidation("OIDC discovery document has no userinfo_endpoint")
+	}
+
+	if previous := d.current.Swap(&discovery); previous != nil && *previous != discovery {
+		slog.InfoContext(ctx, "OIDC discovery document changed",
+			"userinfo_endpoint", discovery.UserInfoEndpoint,
+			"jwks_uri", discovery.JWKSURI,
+		)
+	}
+	return nil
+}
+
+// userInfoURL is the userinfo endpoint from discovery when enabled, or the
+// configured one
+func (a *userReaderWriter) userInfoURL() string {
+	if a.discovery != nil {
+		if discovery := a.discovery.current.Load(); discovery != nil {
+			return discovery.UserInfoEndpoint
+		}
+	}
+	return a.oidcUserInfoURL
+}
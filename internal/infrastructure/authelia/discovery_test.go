@@ -0,0 +1,107 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package authelia
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/httpclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOIDCDiscovery(t *testing.T) {
+	ctx := context.Background()
+
+	var (
+		failures atomic.Int32
+		issuer   atomic.Value
+		userinfo atomic.Value
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != oidcDiscoveryPath {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if failures.Add(-1) >= 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(OIDCDiscovery{
+			Issuer:           issuer.Load().(string),
+			UserInfoEndpoint: userinfo.Load().(string),
+			JWKSURI:          issuer.Load().(string) + "/jwks.json",
+		})
+	}))
+	defer server.Close()
+
+	config := httpclient.DefaultConfig()
+	config.MaxRetries = 0
+	httpClient := httpclient.NewClient(config)
+
+	newRepository := func() *userReaderWriter {
+		u := &userReaderWriter{oidcUserInfoURL: "https://static.example.com/userinfo"}
+		WithOIDCDiscovery(server.URL+"/", 0)(u)
+		u.discovery.retryDelay = time.Millisecond
+		return u
+	}
+	reset := func(fail int32) {
+		failures.Store(fail)
+		issuer.Store(server.URL)
+		userinfo.Store(server.URL + "/api/oidc/userinfo")
+	}
+
+	t.Run("derives the userinfo endpoint", func(t *testing.T) {
+		reset(0)
+		u := newRepository()
+		require.NoError(t, u.discovery.start(ctx, httpClient))
+		assert.Equal(t, server.URL+"/api/oidc/userinfo", u.userInfoURL())
+		assert.Equal(t, server.URL+"/jwks.json", u.discovery.current.Load().JWKSURI)
+	})
+
+	t.Run("retries while the issuer is unavailable", func(t *testing.T) {
+		reset(2)
+		u := newRepository()
+		require.NoError(t, u.discovery.start(ctx, httpClient))
+		assert.Equal(t, server.URL+"/api/oidc/userinfo", u.userInfoURL())
+	})
+
+	t.Run("fails after the last attempt", func(t *testing.T) {
+		reset(oidcDiscoveryAttempts)
+		err := newRepository().discovery.start(ctx, httpClient)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed after 5 attempts")
+	})
+
+	t.Run("rejects a document for another issuer", func(t *testing.T) {
+		reset(0)
+		issuer.Store("https://other.example.com")
+		u := newRepository()
+		u.discovery.attempts = 1
+		err := u.discovery.start(ctx, httpClient)
+		require.Error(t, err)
+		assert.Equal(t, "https://static.example.com/userinfo", u.userInfoURL())
+	})
+
+	t.Run("a failed refresh keeps the last document", func(t *testing.T) {
+		reset(0)
+		u := newRepository()
+		require.NoError(t, u.discovery.start(ctx, httpClient))
+
+		userinfo.Store("")
+		assert.Error(t, u.discovery.refresh(ctx, httpClient))
+		assert.Equal(t, server.URL+"/api/oidc/userinfo", u.userInfoURL())
+
+		userinfo.Store(server.URL + "/v2/userinfo")
+		require.NoError(t, u.discovery.refresh(ctx, httpClient))
+		assert.Equal(t, server.URL+"/v2/userinfo", u.userInfoURL())
+	})
+}
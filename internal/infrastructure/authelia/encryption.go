@@ -0,0 +1,151 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package authelia
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/encryption"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/redaction"
+)
+
+// WithValueEncryption encrypts user records and lookup values in the users
+// bucket. Existing plaintext values remain readable and are rewritten by
+// ReencryptStorage.
+func WithValueEncryption(envelope *encryption.Envelope) Option {
+	return func(u *userReaderWriter) {
+		u.envelope = envelope
+	}
+}
+
+// sealValue encrypts a value stored under key in the users bucket. The key is
+// used as associated data so a sealed value can't be moved to another key.
+func sealValue(ctx context.Context, envelope *encryption.Envelope, key string, value []byte) ([]byte, error) {
+	if envelope == nil {
+		return value, nil
+	}
+	sealed, err := envelope.Seal(ctx, value, []byte(key))
+	if err != nil {
+		return nil, errs.NewUnexpected("failed to encrypt KV value", err)
+	}
+	return sealed, nil
+}
+
+// openValue reverses sealValue. Plaintext values written before encryption
+// was enabled are returned unchanged.
+func openValue(ctx context.Context, envelope *encryption.Envelope, key string, value []byte) ([]byte, error) {
+	if !encryption.IsSealed(value) {
+		return value, nil
+	}
+	if envelope == nil {
+		return nil, errs.NewUnexpected("KV value is encrypted but no encryption key is configured")
+	}
+	plaintext, err := envelope.Open(ctx, value, []byte(key))
+	if err != nil {
+		return nil, errs.NewUnexpected("failed to decrypt KV value", err)
+	}
+	return plaintext, nil
+}
+
+// needsReencryption reports whether a stored value is plaintext or sealed
+// with a key other than the active one
+func needsReencryption(envelope *encryption.Envelope, value []byte) bool {
+	if !encryption.IsSealed(value) {
+		return true
+	}
+	keyID, err := encryption.KeyID(value)
+	return err != nil || keyID != envelope.ActiveKeyID()
+}
+
+// ReencryptStorage rewrites every user record and lookup value that is not
+// sealed with the active key. Writes use the entry revision, so a record
+// updated concurrently is left to the other writer.
+func (n *kvUserStorage) ReencryptStorage(ctx context.Context) (model.ReencryptResult, error) {
+	var result model.ReencryptResult
+	if n.envelope == nil {
+		return result, errs.NewValidation("KV encryption is not enabled")
+	}
+	// Pick up KMS key rotations so values wrapped by the previous key version are rewritten
+	if err := n.envelope.RefreshActiveKey(ctx); err != nil {
+		return result, err
+	}
+
+	// archived users are sealed too, and still have to open once the
+	// retired key is gone
+	buckets := []kvBucket{n.users}
+	if n.archive != nil {
+		buckets = append(buckets, n.archive)
+	}
+	for _, bucket := range buckets {
+		keys, err := bucket.Keys(ctx)
+		if err != nil {
+			return result, errs.NewUnexpected("failed to list keys from "+n.backend, err)
+		}
+
+		for _, key := range keys {
+			if ctx.Err() != nil {
+				return result, ctx.Err()
+			}
+			result.Scanned++
+
+			rewritten, err := n.reencryptEntry(ctx, bucket, key)
+			switch {
+			case errors.Is(err, errRevisionMismatch):
+				result.Conflicts++
+			case err != nil:
+				result.Failed++
+				slog.WarnContext(ctx, "failed to re-encrypt KV entry", "key", redaction.Redact(key), "error", err)
+			case rewritten:
+				result.Reencrypted++
+			}
+		}
+	}
+
+	slog.InfoContext(ctx, "KV re-encryption completed",
+		"active_key_id", n.envelope.ActiveKeyID(),
+		"scanned", result.Scanned,
+		"reencrypted", result.Reencrypted,
+		"conflicts", result.Conflicts,
+		"failed", result.Failed,
+	)
+	return result, nil
+}
+
+// reencryptEntry rewrites a single entry of bucket with the active key. Entries deleted
+// since the key listing are skipped.
+func (n *kvUserStorage) reencryptEntry(ctx context.Context, bucket kvBucket, key string) (bool, error) {
+	value, revision, err := bucket.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, errKeyNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	if !needsReencryption(n.envelope, value) {
+		return false, nil
+	}
+
+	plaintext, err := openValue(ctx, n.envelope, key, value)
+	if err != nil {
+		return false, err
+	}
+	sealed, err := sealValue(ctx, n.envelope, key, plaintext)
+	if err != nil {
+		return false, err
+	}
+	if _, err := bucket.Update(ctx, key, sealed, revision); err != nil {
+		if errors.Is(err, errKeyNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+var _ port.StorageReencrypter = (*userReaderWriter)(nil)
@@ -0,0 +1,65 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package authelia
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/encryption"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValueEncryption(t *testing.T) {
+	ctx := context.Background()
+	ring, err := encryption.NewStaticKeyring("k1", map[string][]byte{"k1": bytes.Repeat([]byte{7}, 32)})
+	require.NoError(t, err)
+	envelope := encryption.NewEnvelope(ring)
+
+	value := []byte(`{"username":"jdoe"}`)
+
+	t.Run("disabled stores plaintext", func(t *testing.T) {
+		stored, err := sealValue(ctx, nil, "jdoe", value)
+		require.NoError(t, err)
+		assert.Equal(t, value, stored)
+
+		read, err := openValue(ctx, nil, "jdoe", stored)
+		require.NoError(t, err)
+		assert.Equal(t, value, read)
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		stored, err := sealValue(ctx, envelope, "jdoe", value)
+		require.NoError(t, err)
+		assert.True(t, encryption.IsSealed(stored))
+		assert.False(t, needsReencryption(envelope, stored))
+
+		read, err := openValue(ctx, envelope, "jdoe", stored)
+		require.NoError(t, err)
+		assert.Equal(t, value, read)
+
+		_, err = openValue(ctx, envelope, "lookup/authelia-users/email/abc", stored)
+		assert.Error(t, err, "sealed values are bound to their key")
+
+		_, err = openValue(ctx, nil, "jdoe", stored)
+		assert.Error(t, err, "encrypted values need a key to be read")
+	})
+
+	t.Run("legacy plaintext is readable and flagged for re-encryption", func(t *testing.T) {
+		read, err := openValue(ctx, envelope, "jdoe", value)
+		require.NoError(t, err)
+		assert.Equal(t, value, read)
+		assert.True(t, needsReencryption(envelope, value))
+	})
+
+	t.Run("retired key is flagged for re-encryption", func(t *testing.T) {
+		oldRing, err := encryption.NewStaticKeyring("k0", map[string][]byte{"k0": bytes.Repeat([]byte{1}, 32)})
+		require.NoError(t, err)
+		stored, err := sealValue(ctx, encryption.NewEnvelope(oldRing), "jdoe", value)
+		require.NoError(t, err)
+		assert.True(t, needsReencryption(envelope, stored))
+	})
+}
@@ -0,0 +1,25 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package authelia
+
+import (
+	"net/http"
+
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/httpclient"
+)
+
+// userInfoError maps a failed userinfo call to the error taxonomy. Authelia
+// answers 401 or 403 for a token it didn't issue, or that expired or was
+// revoked. Missing users are classified by the user stores; blocked users
+// don't reach the userinfo endpoint, as Authelia issues them no token.
+func userInfoError(statusCode int, message string) error {
+	switch statusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return errs.NewTokenInvalid(message)
+	case http.StatusTooManyRequests:
+		return errs.NewRateLimited(message)
+	}
+	return httpclient.ErrorFromStatusCode(statusCode, message)
+}
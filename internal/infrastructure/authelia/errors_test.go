@@ -0,0 +1,29 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package authelia
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+)
+
+func TestUserInfoErrorTaxonomy(t *testing.T) {
+	for status, want := range map[int]errs.Code{
+		http.StatusUnauthorized:    errs.CodeTokenInvalid,
+		http.StatusForbidden:       errs.CodeTokenInvalid,
+		http.StatusTooManyRequests: errs.CodeRateLimited,
+	} {
+		err := userInfoError(status, "failed to fetch OIDC userinfo")
+		code, _ := errs.CodeOf(err)
+		assert.Equal(t, want, code, "status %d", status)
+		assert.Equal(t, errs.Message(want), err.Error())
+	}
+
+	_, classified := errs.CodeOf(userInfoError(http.StatusBadGateway, "failed to fetch OIDC userinfo"))
+	assert.False(t, classified)
+}
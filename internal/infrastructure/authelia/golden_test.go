@@ -0,0 +1,53 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package authelia
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/golden"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/httpclient"
+)
+
+// TestMetadataLookup_GoldenFixtures replays the recorded userinfo documents of
+// testdata/userinfo through MetadataLookup and compares the resulting users
+// with their golden files. Refresh the fixtures with cmd/fixturegen.
+func TestMetadataLookup_GoldenFixtures(t *testing.T) {
+	fixtures, err := filepath.Glob(filepath.Join("testdata", "userinfo", "*.json"))
+	if err != nil || len(fixtures) == 0 {
+		t.Fatalf("no userinfo fixtures found: %v", err)
+	}
+
+	for _, fixture := range fixtures {
+		if strings.HasSuffix(fixture, ".golden.json") {
+			continue
+		}
+		name := strings.TrimSuffix(filepath.Base(fixture), ".json")
+		t.Run(name, func(t *testing.T) {
+			document := golden.Read(t, fixture)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write(document)
+			}))
+			defer server.Close()
+
+			config := httpclient.DefaultConfig()
+			config.MaxRetries = 0
+			u := &userReaderWriter{oidcUserInfoURL: server.URL, httpClient: httpclient.NewClient(config)}
+
+			user, err := u.MetadataLookup(context.Background(), "authelia_fixture_token")
+			if err != nil {
+				t.Fatalf("MetadataLookup() error = %v", err)
+			}
+
+			user.Token = ""
+			golden.Assert(t, strings.TrimSuffix(fixture, ".json")+".golden.json", user)
+		})
+	}
+}
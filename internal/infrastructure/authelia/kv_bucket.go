@@ -0,0 +1,86 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package authelia
+
+import (
+	"context"
+	"errors"
+
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+var (
+	// errKeyNotFound is returned by a kvBucket for a key it doesn't hold
+	errKeyNotFound = errors.New("key not found")
+	// errRevisionMismatch is returned by a kvBucket when the key was written
+	// since the revision an update or delete was based on
+	errRevisionMismatch = errors.New("key has been modified since the given revision")
+)
+
+// kvBucket is the revisioned key-value store the KV user store keeps its
+// records and lookup keys in. Every write of a key increases its revision,
+// so an update based on a stale read fails instead of overwriting.
+type kvBucket interface {
+	Get(ctx context.Context, key string) ([]byte, uint64, error)
+	Put(ctx context.Context, key string, value []byte) (uint64, error)
+	// Create writes key only if it doesn't exist, failing with
+	// errRevisionMismatch otherwise
+	Create(ctx context.Context, key string, value []byte) (uint64, error)
+	// Update writes key only if its revision is still revision
+	Update(ctx context.Context, key string, value []byte, revision uint64) (uint64, error)
+	// Delete removes key only if its revision is still revision
+	Delete(ctx context.Context, key string, revision uint64) error
+	Keys(ctx context.Context) ([]string, error)
+}
+
+// natsBucket is a kvBucket over a NATS JetStream KV bucket
+type natsBucket struct {
+	kv jetstream.KeyValue
+}
+
+func (b natsBucket) Get(ctx context.Context, key string) ([]byte, uint64, error) {
+	entry, err := b.kv.Get(ctx, key)
+	if err != nil {
+		return nil, 0, natsBucketError(err)
+	}
+	return entry.Value(), entry.Revision(), nil
+}
+
+func (b natsBucket) Put(ctx context.Context, key string, value []byte) (uint64, error) {
+	return b.kv.Put(ctx, key, value)
+}
+
+func (b natsBucket) Create(ctx context.Context, key string, value []byte) (uint64, error) {
+	revision, err := b.kv.Create(ctx, key, value)
+	return revision, natsBucketError(err)
+}
+
+func (b natsBucket) Update(ctx context.Context, key string, value []byte, revision uint64) (uint64, error) {
+	revision, err := b.kv.Update(ctx, key, value, revision)
+	return revision, natsBucketError(err)
+}
+
+func (b natsBucket) Delete(ctx context.Context, key string, revision uint64) error {
+	return natsBucketError(b.kv.Delete(ctx, key, jetstream.LastRevision(revision)))
+}
+
+func (b natsBucket) Keys(ctx context.Context) ([]string, error) {
+	keys, err := b.kv.Keys(ctx)
+	if errors.Is(err, jetstream.ErrNoKeysFound) {
+		return nil, nil
+	}
+	return keys, err
+}
+
+// natsBucketError maps the JetStream errors callers handle to the kvBucket
+// ones. A wrong last sequence matches jetstream.ErrKeyExists.
+func natsBucketError(err error) error {
+	switch {
+	case errors.Is(err, jetstream.ErrKeyNotFound):
+		return errKeyNotFound
+	case errors.Is(err, jetstream.ErrKeyExists):
+		return errors.Join(errRevisionMismatch, err)
+	}
+	return err
+}
@@ -0,0 +1,233 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package authelia
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"sort"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/redaction"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/tenant"
+)
+
+var lookupCollisions, _ = meter.Int64Counter("auth_service.authelia.lookup_collisions",
+	metric.WithDescription("User writes whose lookup keys belong to another user"))
+
+// WithRejectedLookupCollisions refuses, with a conflict, a write whose email
+// or sub lookup keys belong to another user that still claims them. Without
+// it the write succeeds, the keys keep resolving to their owner and the
+// collision is logged and counted. Only the KV user stores check collisions.
+func WithRejectedLookupCollisions() Option {
+	return func(u *userReaderWriter) {
+		u.rejectLookupCollisions = true
+	}
+}
+
+// userLookupKeys returns the lookup keys resolving to user
+func (n *kvUserStorage) userLookupKeys(ctx context.Context, user *AutheliaUser) []string {
+	emails, subs := userIndexKeys(ctx, user)
+	keys := make([]string, 0, len(emails)+len(subs))
+	for _, email := range emails {
+		keys = append(keys, n.BuildLookupKey(ctx, "email", email))
+	}
+	for _, sub := range subs {
+		keys = append(keys, n.BuildLookupKey(ctx, "sub", sub))
+	}
+	return keys
+}
+
+// ownerRecord reads the record of the user a lookup key resolves to,
+// wherever it is kept; nil means the user is gone
+func (n *kvUserStorage) ownerRecord(ctx context.Context, username string) (*AutheliaUser, error) {
+	owner, _, err := n.readUser(ctx, username)
+	var notFound errs.NotFound
+	if !errors.As(err, &notFound) {
+		return owner, err
+	}
+	if n.archive == nil {
+		return nil, nil
+	}
+	sealed, _, err := n.archive.Get(ctx, username)
+	if errors.Is(err, errKeyNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return n.decodeUser(ctx, username, sealed)
+}
+
+// lookupCollisions maps the lookup keys of user that resolve to another
+// user still claiming them to that user. A key left behind by a user who no
+// longer has the email is not a collision, and neither is a key of a user
+// that user absorbed in a merge.
+func (n *kvUserStorage) lookupCollisions(ctx context.Context, user *AutheliaUser) (map[string]string, error) {
+	collisions := make(map[string]string)
+	owners := make(map[string]*AutheliaUser)
+	for _, key := range n.userLookupKeys(ctx, user) {
+		sealed, _, err := n.users.Get(ctx, key)
+		if errors.Is(err, errKeyNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		value, err := openValue(ctx, n.envelope, key, sealed)
+		if err != nil {
+			return nil, err
+		}
+		username := string(value)
+		if username == user.Username {
+			continue
+		}
+
+		owner, seen := owners[username]
+		if !seen {
+			if owner, err = n.ownerRecord(ctx, username); err != nil {
+				return nil, err
+			}
+			owners[username] = owner
+		}
+		if owner == nil || (owner.Sub != "" && slices.Contains(user.MergedSubs, owner.Sub)) {
+			continue
+		}
+		if slices.Contains(n.userLookupKeys(ctx, owner), key) {
+			collisions[key] = username
+		}
+	}
+	return collisions, nil
+}
+
+// checkLookupCollisions returns the lookup keys user must not take over, or
+// a conflict when collisions are rejected
+func (n *kvUserStorage) checkLookupCollisions(ctx context.Context, user *AutheliaUser) (map[string]string, error) {
+	collisions, err := n.lookupCollisions(ctx, user)
+	if err != nil {
+		return nil, errs.NewUnexpected("failed to check lookup keys in "+n.backend, err)
+	}
+	if len(collisions) == 0 {
+		return nil, nil
+	}
+
+	action := "flagged"
+	if n.rejectCollisions {
+		action = "rejected"
+	}
+	for key, owner := range collisions {
+		slog.WarnContext(ctx, "lookup key belongs to another user",
+			"username", redaction.Redact(user.Username),
+			"owner", redaction.Redact(owner),
+			"key", key,
+			"action", action,
+		)
+	}
+	lookupCollisions.Add(ctx, int64(len(collisions)), tenant.Attributes(ctx, attribute.String("action", action)))
+
+	if n.rejectCollisions {
+		return nil, errs.NewConflict("an email or sub of the user already belongs to another user")
+	}
+	return collisions, nil
+}
+
+// RepairLookups scans the lookup keys every stored user claims. A key
+// claimed by one user is pointed back at it if it resolves elsewhere; a key
+// claimed by several is reported, since only an operator can tell whose it
+// is. Keys nobody claims are left alone.
+func (n *kvUserStorage) RepairLookups(ctx context.Context) (model.LookupRepairResult, error) {
+	result := model.LookupRepairResult{Conflicts: []model.LookupCollision{}}
+
+	users, err := n.ListUsers(ctx)
+	if err != nil {
+		return result, err
+	}
+
+	claimants := make(map[string][]string)
+	for username, user := range users {
+		result.Scanned++
+		user.SetUsername(username)
+		for _, key := range n.userLookupKeys(ctx, user) {
+			if !slices.Contains(claimants[key], username) {
+				claimants[key] = append(claimants[key], username)
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(claimants))
+	for key := range claimants {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+		claimedBy := claimants[key]
+
+		owner, err := n.lookupUser(ctx, key)
+		var notFound errs.NotFound
+		if err != nil && !errors.As(err, &notFound) {
+			result.Failed++
+			slog.WarnContext(ctx, "failed to read lookup key", "key", key, "error", err)
+			continue
+		}
+
+		if owner != "" && !slices.Contains(claimedBy, owner) {
+			// an archived owner isn't listed, but still claims its keys
+			record, err := n.ownerRecord(ctx, owner)
+			if err != nil {
+				result.Failed++
+				slog.WarnContext(ctx, "failed to read the owner of a lookup key", "key", key, "error", err)
+				continue
+			}
+			if record != nil && slices.Contains(n.userLookupKeys(ctx, record), key) {
+				claimedBy = append(claimedBy, owner)
+			}
+		}
+
+		if len(claimedBy) > 1 {
+			sort.Strings(claimedBy)
+			result.Conflicts = append(result.Conflicts, model.LookupCollision{Key: key, Owner: owner, Claimants: claimedBy})
+			continue
+		}
+		if owner == claimedBy[0] {
+			continue
+		}
+		if err := n.putLookupKey(ctx, key, claimedBy[0]); err != nil {
+			result.Failed++
+			slog.WarnContext(ctx, "failed to repair lookup key", "key", key, "error", err)
+			continue
+		}
+		result.Repaired++
+	}
+
+	slog.InfoContext(ctx, "lookup key repair completed",
+		"scanned", result.Scanned,
+		"repaired", result.Repaired,
+		"conflicts", len(result.Conflicts),
+		"failed", result.Failed,
+	)
+	return result, nil
+}
+
+// RepairLookups repairs the lookup keys of stored users. It implements
+// port.LookupRepairer.
+func (a *userReaderWriter) RepairLookups(ctx context.Context) (model.LookupRepairResult, error) {
+	repairer, ok := a.storage.(port.LookupRepairer)
+	if !ok {
+		return model.LookupRepairResult{}, errs.NewValidation("user storage does not support lookup key repair")
+	}
+	return repairer.RepairLookups(ctx)
+}
+
+var _ port.LookupRepairer = (*userReaderWriter)(nil)
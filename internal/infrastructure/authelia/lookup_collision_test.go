@@ -0,0 +1,118 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package authelia
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+)
+
+func TestLookupCollisions(t *testing.T) {
+	ctx := context.Background()
+
+	newStore := func(t *testing.T) *kvUserStorage {
+		server := miniredis.RunT(t)
+		storage, err := newRedisUserStorage(ctx, RedisStoreConfig{URL: "redis://" + server.Addr(), KeyPrefix: "authelia:"}, nil, false)
+		require.NoError(t, err)
+		return storage.(*kvUserStorage)
+	}
+	newUser := func(username, sub, email string) *AutheliaUser {
+		return &AutheliaUser{User: &model.User{Username: username, Sub: sub, PrimaryEmail: email}, Email: email}
+	}
+	owner := func(t *testing.T, store *kvUserStorage, email string) string {
+		user := newUser("", "", email)
+		found, err := store.GetUser(ctx, store.BuildLookupKey(ctx, "email", user.BuildEmailIndexKey(ctx)))
+		require.NoError(t, err)
+		return found.Username
+	}
+
+	t.Run("flagged writes keep the key with its owner", func(t *testing.T) {
+		store := newStore(t)
+		_, err := store.SetUser(ctx, newUser("jdoe", "sub-jdoe", "jdoe@example.com"))
+		require.NoError(t, err)
+
+		_, err = store.SetUser(ctx, newUser("jdoe2", "sub-jdoe2", " JDoe@Example.com"))
+		require.NoError(t, err)
+		assert.Equal(t, "jdoe", owner(t, store, "jdoe@example.com"))
+
+		_, err = store.GetUser(ctx, "jdoe2")
+		assert.NoError(t, err, "the record itself is written")
+	})
+
+	t.Run("rejected writes leave nothing behind", func(t *testing.T) {
+		store := newStore(t)
+		store.rejectCollisions = true
+		_, err := store.SetUser(ctx, newUser("jdoe", "sub-jdoe", "jdoe@example.com"))
+		require.NoError(t, err)
+
+		_, err = store.SetUser(ctx, newUser("jdoe2", "sub-jdoe2", "JDoe@example.com"))
+		var conflict errs.Conflict
+		assert.ErrorAs(t, err, &conflict)
+
+		_, err = store.GetUser(ctx, "jdoe2")
+		var notFound errs.NotFound
+		assert.ErrorAs(t, err, &notFound)
+	})
+
+	t.Run("keys of a user who dropped the email move", func(t *testing.T) {
+		store := newStore(t)
+		store.rejectCollisions = true
+		_, err := store.SetUser(ctx, newUser("jdoe", "sub-jdoe", "jdoe@example.com"))
+		require.NoError(t, err)
+		_, err = store.SetUser(ctx, newUser("jdoe", "sub-jdoe", "john@example.com"))
+		require.NoError(t, err)
+
+		_, err = store.SetUser(ctx, newUser("jdoe2", "sub-jdoe2", "jdoe@example.com"))
+		require.NoError(t, err)
+		assert.Equal(t, "jdoe2", owner(t, store, "jdoe@example.com"))
+	})
+
+	t.Run("a merge takes over the absorbed user's keys", func(t *testing.T) {
+		store := newStore(t)
+		store.rejectCollisions = true
+		_, err := store.SetUser(ctx, newUser("jdoe", "sub-jdoe", "jdoe@example.com"))
+		require.NoError(t, err)
+
+		primary := newUser("john", "sub-john", "john@example.com")
+		primary.SecondaryEmails = []model.Email{{Email: "jdoe@example.com", Verified: true}}
+		primary.MergedSubs = []string{"sub-jdoe"}
+		_, err = store.SetUser(ctx, primary)
+		require.NoError(t, err)
+		assert.Equal(t, "john", owner(t, store, "jdoe@example.com"))
+	})
+
+	t.Run("repair points keys back and reports conflicts", func(t *testing.T) {
+		store := newStore(t)
+		_, err := store.SetUser(ctx, newUser("jdoe", "sub-jdoe", "jdoe@example.com"))
+		require.NoError(t, err)
+		_, err = store.SetUser(ctx, newUser("jdoe2", "sub-jdoe2", "JDoe@example.com"))
+		require.NoError(t, err)
+		_, err = store.SetUser(ctx, newUser("jane", "sub-jane", "jane@example.com"))
+		require.NoError(t, err)
+
+		jane := newUser("jane", "sub-jane", "jane@example.com")
+		janeKey := store.BuildLookupKey(ctx, "email", jane.BuildEmailIndexKey(ctx))
+		require.NoError(t, store.putLookupKey(ctx, janeKey, "jdoe"))
+
+		result, err := store.RepairLookups(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 3, result.Scanned)
+		assert.Equal(t, 1, result.Repaired)
+		assert.Equal(t, "jane", owner(t, store, "jane@example.com"))
+
+		jdoe := newUser("jdoe", "", "jdoe@example.com")
+		assert.Equal(t, []model.LookupCollision{{
+			Key:       store.BuildLookupKey(ctx, "email", jdoe.BuildEmailIndexKey(ctx)),
+			Owner:     "jdoe",
+			Claimants: []string{"jdoe", "jdoe2"},
+		}}, result.Conflicts)
+	})
+}
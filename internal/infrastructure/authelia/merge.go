@@ -0,0 +1,111 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package authelia
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/redaction"
+)
+
+// absorbUser moves the emails, identities and sub of secondary to primary,
+// which keeps metadata. Addresses primary already has are skipped.
+func absorbUser(primary, secondary *AutheliaUser, metadata *model.UserMetadata) {
+	known := func(email string) bool {
+		if strings.EqualFold(primary.Email, email) {
+			return true
+		}
+		return slices.ContainsFunc(primary.AlternateEmails, func(e model.Email) bool { return strings.EqualFold(e.Email, email) }) ||
+			slices.ContainsFunc(primary.SecondaryEmails, func(e model.Email) bool { return strings.EqualFold(e.Email, email) })
+	}
+
+	// the primary email of a stored user is verified by the operator
+	if secondary.Email != "" && !known(secondary.Email) {
+		primary.AlternateEmails = append(primary.AlternateEmails, model.Email{Email: secondary.Email, Verified: true})
+	}
+	for _, email := range secondary.AlternateEmails {
+		if !known(email.Email) {
+			primary.AlternateEmails = append(primary.AlternateEmails, email)
+		}
+	}
+	for _, email := range secondary.SecondaryEmails {
+		if !known(email.Email) {
+			primary.SecondaryEmails = append(primary.SecondaryEmails, email)
+		}
+	}
+	for _, identity := range secondary.Identities {
+		if !slices.ContainsFunc(primary.Identities, func(i model.Identity) bool {
+			return i.Provider == identity.Provider && i.IdentityID == identity.IdentityID
+		}) {
+			primary.Identities = append(primary.Identities, identity)
+		}
+	}
+
+	for _, sub := range append([]string{secondary.Sub}, secondary.MergedSubs...) {
+		if sub != "" && !slices.Contains(primary.MergedSubs, sub) {
+			primary.MergedSubs = append(primary.MergedSubs, sub)
+		}
+	}
+	if metadata != nil {
+		primary.UserMetadata = metadata
+	}
+}
+
+// MergeUsers folds the secondary user into the primary one: the primary
+// gains the secondary's emails, identities and sub, so their lookup keys
+// resolve to it, and keeps the merged metadata. The secondary's record is
+// kept, marked as merged, since the users database operators manage would
+// otherwise recreate it on the next sync. It implements port.UserMerger.
+func (a *userReaderWriter) MergeUsers(ctx context.Context, merge *model.UserMerge) error {
+	if merge == nil || merge.Primary == nil || merge.Secondary == nil {
+		return errs.NewValidation("primary and secondary users are required")
+	}
+
+	primary, primaryRevision, err := a.storage.GetUserWithRevision(ctx, a.storage.BuildLookupKey(ctx, "sub", merge.Primary.BuildSubIndexKey(ctx)))
+	if err != nil {
+		return err
+	}
+	secondary, secondaryRevision, err := a.storage.GetUserWithRevision(ctx, a.storage.BuildLookupKey(ctx, "sub", merge.Secondary.BuildSubIndexKey(ctx)))
+	if err != nil {
+		return err
+	}
+	if primary.Username == secondary.Username {
+		return errs.NewValidation("the primary and secondary users are the same account")
+	}
+	if secondary.MergedInto != "" {
+		return errs.NewValidation("the secondary user was already merged into another account")
+	}
+
+	absorbUser(primary, secondary, merge.Metadata)
+	if err := a.storage.UpdateUserWithRevision(ctx, primary, primaryRevision); err != nil {
+		slog.ErrorContext(ctx, "failed to update the primary user of a merge",
+			"username", redaction.Redact(primary.Username),
+			"error", err,
+		)
+		return err
+	}
+
+	secondary.MergedInto = primary.Username
+	if err := a.storage.UpdateUserWithRevision(ctx, secondary, secondaryRevision); err != nil {
+		slog.ErrorContext(ctx, "failed to mark the secondary user of a merge",
+			"username", redaction.Redact(secondary.Username),
+			"error", err,
+		)
+		return err
+	}
+
+	slog.InfoContext(ctx, "users merged",
+		"primary_username", redaction.Redact(primary.Username),
+		"secondary_username", redaction.Redact(secondary.Username),
+	)
+	return nil
+}
+
+var _ port.UserMerger = (*userReaderWriter)(nil)
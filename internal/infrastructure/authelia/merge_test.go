@@ -0,0 +1,87 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package authelia
+
+import (
+	"context"
+	"testing"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserReaderWriter_MergeUsers(t *testing.T) {
+	ctx := context.Background()
+
+	newStorage := func() *mockStorageReaderWriter {
+		primary := &AutheliaUser{
+			User: &model.User{
+				Username:        "jdoe",
+				Sub:             "sub-primary",
+				AlternateEmails: []model.Email{{Email: "jane@work.example", Verified: true}},
+			},
+			Email: "jdoe@example.com",
+		}
+		secondary := &AutheliaUser{
+			User: &model.User{
+				Username:        "jane",
+				Sub:             "sub-secondary",
+				AlternateEmails: []model.Email{{Email: "JANE@work.example", Verified: true}},
+				Identities:      []model.Identity{{Provider: "github", IdentityID: "42"}},
+			},
+			Email: "jane@example.com",
+		}
+		storage := &mockStorageReaderWriter{users: map[string]*AutheliaUser{}}
+		for _, user := range []*AutheliaUser{primary, secondary} {
+			storage.users[storage.BuildLookupKey(ctx, "sub", user.BuildSubIndexKey(ctx))] = user
+		}
+		return storage
+	}
+	merge := func() *model.UserMerge {
+		name := "Jane Doe"
+		return &model.UserMerge{
+			Primary:   &model.User{Sub: "sub-primary"},
+			Secondary: &model.User{Sub: "sub-secondary"},
+			Metadata:  &model.UserMetadata{Name: &name},
+		}
+	}
+
+	t.Run("primary absorbs the secondary", func(t *testing.T) {
+		storage := newStorage()
+		rw := &userReaderWriter{storage: storage}
+		require.NoError(t, rw.MergeUsers(ctx, merge()))
+
+		primary := storage.users["jdoe"]
+		assert.Equal(t, []model.Email{
+			{Email: "jane@work.example", Verified: true},
+			{Email: "jane@example.com", Verified: true},
+		}, primary.AlternateEmails, "addresses the primary has aren't added twice")
+		assert.Equal(t, []model.Identity{{Provider: "github", IdentityID: "42"}}, primary.Identities)
+		assert.Equal(t, []string{"sub-secondary"}, primary.MergedSubs)
+		assert.Equal(t, "Jane Doe", *primary.UserMetadata.Name)
+
+		assert.Equal(t, "jdoe", storage.users["jane"].MergedInto)
+	})
+
+	t.Run("secondary already merged", func(t *testing.T) {
+		storage := newStorage()
+		rw := &userReaderWriter{storage: storage}
+		require.NoError(t, rw.MergeUsers(ctx, merge()))
+
+		// the mock keeps the marked record under its sub key too
+		err := rw.MergeUsers(ctx, merge())
+		var validation errors.Validation
+		assert.ErrorAs(t, err, &validation)
+	})
+
+	t.Run("same account", func(t *testing.T) {
+		rw := &userReaderWriter{storage: newStorage()}
+		same := merge()
+		same.Secondary = &model.User{Sub: "sub-primary"}
+		err := rw.MergeUsers(ctx, same)
+		assert.ErrorContains(t, err, "same account")
+	})
+}
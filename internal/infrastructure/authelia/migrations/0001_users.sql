@@ -0,0 +1,37 @@
+-- Authelia user records, with the emails and subs resolving to them indexed in
+-- their own tables instead of lookup keys. Emails and subs are stored as the
+-- SHA-256 index keys the KV store uses, so they are no more exposed than there.
+
+CREATE SEQUENCE authelia_user_revisions;
+
+CREATE TABLE authelia_users (
+    username    TEXT PRIMARY KEY,
+    -- the JSON storage record, sealed with the KV encryption key when enabled
+    record      BYTEA NOT NULL,
+    revision    BIGINT NOT NULL,
+    merged_into TEXT,
+    created_at  TIMESTAMPTZ NOT NULL,
+    updated_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE authelia_user_emails (
+    email_key TEXT PRIMARY KEY,
+    username  TEXT NOT NULL REFERENCES authelia_users (username) ON DELETE CASCADE
+);
+
+CREATE INDEX authelia_user_emails_username ON authelia_user_emails (username);
+
+CREATE TABLE authelia_user_subs (
+    sub_key  TEXT PRIMARY KEY,
+    username TEXT NOT NULL REFERENCES authelia_users (username) ON DELETE CASCADE
+);
+
+CREATE INDEX authelia_user_subs_username ON authelia_user_subs (username);
+
+CREATE TABLE authelia_email_otps (
+    email      TEXT PRIMARY KEY,
+    otp        TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX authelia_email_otps_expires_at ON authelia_email_otps (expires_at);
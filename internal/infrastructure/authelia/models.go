@@ -28,8 +28,18 @@ type AutheliaUser struct {
 	Email       string    `json:"email"`       // email for Authelia
 	Password    string    `json:"password"`    // bcrypt hash for Authelia
 	DisplayName string    `json:"displayname"` // display name for Authelia
+	Groups      []string  `json:"groups"`      // Authelia groups, managed in the users database
 	CreatedAt   time.Time `json:"created_at"`  // creation timestamp
 	UpdatedAt   time.Time `json:"updated_at"`  // update timestamp
+	// AccessedAt is when the user was last read, kept to the day while
+	// archival is enabled
+	AccessedAt time.Time `json:"accessed_at,omitzero"`
+	// MergedInto is the username of the account this one was merged into;
+	// its lookup keys belong to that account
+	MergedInto string `json:"merged_into,omitempty"`
+	// MergedSubs are the subs of the accounts merged into this one, which
+	// still resolve to it
+	MergedSubs []string `json:"merged_subs,omitempty"`
 
 	// not part of the user model, but used to track if the user is missing from the orchestrator
 	// or if the password needs to be updated
@@ -45,9 +55,14 @@ type AutheliaUserStorage struct {
 	DisplayName    string              `json:"displayname"`               // display name for Authelia
 	UserMetadata   *model.UserMetadata `json:"user_metadata,omitempty"`   // user metadata from domain model
 	AlternateEmail []model.Email       `json:"alternate_email,omitempty"` // alternate email for Authelia
+	SecondaryEmail []model.Email       `json:"secondary_email,omitempty"` // other addresses on the account
 	Identities     []model.Identity    `json:"identities,omitempty"`      // linked social identities
+	Groups         []string            `json:"groups,omitempty"`          // Authelia groups
 	CreatedAt      time.Time           `json:"created_at"`                // creation timestamp
 	UpdatedAt      time.Time           `json:"updated_at"`                // update timestamp
+	AccessedAt     time.Time           `json:"accessed_at,omitzero"`      // last read, while archival is enabled
+	MergedInto     string              `json:"merged_into,omitempty"`     // account this one was merged into
+	MergedSubs     []string            `json:"merged_subs,omitempty"`     // subs of the accounts merged into this one
 }
 
 // SetUsername sets the username for the user
@@ -64,6 +79,7 @@ func (a *AutheliaUser) ToStorage() *AutheliaUserStorage {
 		username       string
 		userMetadata   *model.UserMetadata
 		alternateEmail []model.Email
+		secondaryEmail []model.Email
 		identities     []model.Identity
 	)
 
@@ -71,6 +87,7 @@ func (a *AutheliaUser) ToStorage() *AutheliaUserStorage {
 		username = a.Username
 		userMetadata = a.UserMetadata
 		alternateEmail = a.AlternateEmails
+		secondaryEmail = a.SecondaryEmails
 		identities = a.Identities
 	}
 
@@ -81,9 +98,14 @@ func (a *AutheliaUser) ToStorage() *AutheliaUserStorage {
 		DisplayName:    a.DisplayName,
 		UserMetadata:   userMetadata,
 		AlternateEmail: alternateEmail,
+		SecondaryEmail: secondaryEmail,
 		Identities:     identities,
+		Groups:         a.Groups,
 		CreatedAt:      a.CreatedAt,
 		UpdatedAt:      a.UpdatedAt,
+		AccessedAt:     a.AccessedAt,
+		MergedInto:     a.MergedInto,
+		MergedSubs:     a.MergedSubs,
 	}
 }
 
@@ -95,7 +117,11 @@ func (a *AutheliaUser) FromStorage(storage *AutheliaUserStorage) {
 	a.Username = storage.Username
 	a.UserMetadata = storage.UserMetadata
 	a.PrimaryEmail = storage.Email
+	// the primary email is provisioned by the operator in the users database,
+	// so it is trusted as verified
+	a.EmailVerified = storage.Email != ""
 	a.AlternateEmails = storage.AlternateEmail
+	a.SecondaryEmails = storage.SecondaryEmail
 	a.Identities = storage.Identities
 	// for consistency in naming across implementations,
 	// we use the unique identifier as the user_id
@@ -103,15 +129,20 @@ func (a *AutheliaUser) FromStorage(storage *AutheliaUserStorage) {
 	a.Sub = storage.Sub
 	a.Email = storage.Email
 	a.DisplayName = storage.DisplayName
+	a.Groups = storage.Groups
 	a.CreatedAt = storage.CreatedAt
 	a.UpdatedAt = storage.UpdatedAt
+	a.AccessedAt = storage.AccessedAt
+	a.MergedInto = storage.MergedInto
+	a.MergedSubs = storage.MergedSubs
 }
 
 // AutheliaUserYAML represents the YAML structure for Authelia users_database.yml
 type AutheliaUserYAML struct {
-	DisplayName string `yaml:"displayname"`
-	Password    string `yaml:"password"`
-	Email       string `yaml:"email"`
+	DisplayName string   `yaml:"displayname"`
+	Password    string   `yaml:"password"`
+	Email       string   `yaml:"email"`
+	Groups      []string `yaml:"groups,omitempty"`
 }
 
 // ToAutheliaYAML converts AutheliaUser to the format expected by Authelia
@@ -120,6 +151,7 @@ func (a *AutheliaUser) ToAutheliaYAML() AutheliaUserYAML {
 		DisplayName: a.DisplayName,
 		Password:    a.Password,
 		Email:       a.Email,
+		Groups:      a.Groups,
 	}
 }
 
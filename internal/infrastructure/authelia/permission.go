@@ -0,0 +1,56 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package authelia
+
+import (
+	"context"
+	"slices"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+)
+
+// WithGroupPermissions maps Authelia groups to the permissions they grant.
+// Authelia has no permissions of its own, so without a mapping users hold
+// roles (their groups) but no permissions.
+func WithGroupPermissions(groupPermissions map[string][]string) Option {
+	return func(u *userReaderWriter) {
+		u.groupPermissions = groupPermissions
+	}
+}
+
+// UserPermissions returns the user's groups as roles, and the permissions
+// those groups are mapped to. It implements port.PermissionReader.
+func (a *userReaderWriter) UserPermissions(ctx context.Context, userID string) (*model.UserPermissions, error) {
+	if userID == "" {
+		return nil, errs.NewValidation("user_id is required")
+	}
+
+	subKey := a.storage.BuildLookupKey(ctx, "sub", model.User{Sub: userID}.BuildSubIndexKey(ctx))
+	user, err := a.storage.GetUser(ctx, subKey)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &model.UserPermissions{
+		Roles:       slices.Clone(user.Groups),
+		Permissions: []model.Permission{},
+	}
+	if result.Roles == nil {
+		result.Roles = []string{}
+	}
+
+	var names []string
+	for _, group := range user.Groups {
+		names = append(names, a.groupPermissions[group]...)
+	}
+	slices.Sort(names)
+	for _, name := range slices.Compact(names) {
+		result.Permissions = append(result.Permissions, model.Permission{Name: name})
+	}
+	return result, nil
+}
+
+var _ port.PermissionReader = (*userReaderWriter)(nil)
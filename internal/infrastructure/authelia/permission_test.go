@@ -0,0 +1,49 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package authelia
+
+import (
+	"context"
+	"testing"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserReaderWriter_UserPermissions(t *testing.T) {
+	ctx := context.Background()
+	sub := "550e8400-e29b-41d4-a716-446655440000"
+
+	storage := &mockStorageReaderWriter{users: map[string]*AutheliaUser{}}
+	storage.users[storage.BuildLookupKey(ctx, "sub", model.User{Sub: sub}.BuildSubIndexKey(ctx))] = &AutheliaUser{
+		User:   &model.User{Username: "jdoe", Sub: sub},
+		Groups: []string{"dev", "admins"},
+	}
+
+	rw := &userReaderWriter{storage: storage}
+	WithGroupPermissions(map[string][]string{
+		"admins": {"search:users", "read:projects"},
+		"dev":    {"read:projects"},
+	})(rw)
+
+	permissions, err := rw.UserPermissions(ctx, sub)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dev", "admins"}, permissions.Roles)
+	assert.Equal(t, []model.Permission{{Name: "read:projects"}, {Name: "search:users"}}, permissions.Permissions)
+	assert.True(t, permissions.HasPermission("search:users", ""))
+	assert.True(t, permissions.HasRole("dev"))
+
+	t.Run("without a mapping groups grant no permissions", func(t *testing.T) {
+		permissions, err := (&userReaderWriter{storage: storage}).UserPermissions(ctx, sub)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"dev", "admins"}, permissions.Roles)
+		assert.Empty(t, permissions.Permissions)
+	})
+
+	t.Run("unknown sub", func(t *testing.T) {
+		_, err := rw.UserPermissions(ctx, "unknown")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,577 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package authelia
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/encryption"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/redaction"
+)
+
+// postgresMigrations are applied in the order of their numeric prefix
+//
+//go:embed migrations/*.sql
+var postgresMigrations embed.FS
+
+// postgresMigrationLock serializes the migrations of replicas starting at once
+const postgresMigrationLock = 0x61757468656c6961 // "authelia"
+
+// PostgresStoreConfig configures the Postgres user store
+type PostgresStoreConfig struct {
+	// URL is the postgres:// URL or key/value connection string of the database
+	URL string
+	// MaxConns bounds the connection pool; <= 0 keeps the pgx default
+	MaxConns int32
+	// MinConns is the number of connections kept open when idle
+	MinConns int32
+	// MaxConnLifetime closes connections older than it; <= 0 keeps the pgx default
+	MaxConnLifetime time.Duration
+	// MaxConnIdleTime closes connections idle for longer; <= 0 keeps the pgx default
+	MaxConnIdleTime time.Duration
+	// OTPTTL is how long a verification code is kept; <= 0 means 5 minutes
+	OTPTTL time.Duration
+}
+
+// WithPostgresUserStore keeps the user records and verification codes in
+// Postgres, with the emails and subs resolving to a user in indexed tables
+func WithPostgresUserStore(config PostgresStoreConfig) Option {
+	return func(u *userReaderWriter) {
+		u.postgresStore = &config
+	}
+}
+
+// postgresDB is the part of a pgx pool the Postgres user store uses
+type postgresDB interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// inTransaction runs fn in a transaction, committed when fn succeeds and
+// rolled back otherwise; the error of fn is returned as it is
+func inTransaction(ctx context.Context, db postgresDB, fn func(tx pgx.Tx) error) error {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		if errRollback := tx.Rollback(ctx); errRollback != nil {
+			slog.WarnContext(ctx, "failed to roll back Postgres transaction", "error", errRollback)
+		}
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// postgresUserStorage implements UserStore over the tables of the migrations.
+// A record and the rows resolving to it are written in one transaction.
+type postgresUserStorage struct {
+	db     postgresDB
+	otpTTL time.Duration
+	// envelope encrypts the records; nil stores plaintext
+	envelope *encryption.Envelope
+}
+
+// BuildLookupKey builds the lookup key callers resolve emails and subs with;
+// GetUser maps it back to the index tables
+func (p *postgresUserStorage) BuildLookupKey(_ context.Context, lookupKey, key string) string {
+	return buildLookupKey(lookupKey, key)
+}
+
+func (p *postgresUserStorage) GetUser(ctx context.Context, key string) (*AutheliaUser, error) {
+	user, _, err := p.GetUserWithRevision(ctx, key)
+	return user, err
+}
+
+func (p *postgresUserStorage) GetUserWithRevision(ctx context.Context, key string) (*AutheliaUser, uint64, error) {
+	if key == "" {
+		return nil, 0, errs.NewUnexpected("key is required")
+	}
+
+	query := `SELECT username, record, revision FROM authelia_users WHERE username = $1`
+	if strings.HasPrefix(key, kvLookupPrefix) {
+		if email, ok := strings.CutPrefix(key, buildLookupKey("email", "")); ok {
+			query, key = `SELECT u.username, u.record, u.revision FROM authelia_user_emails e
+				JOIN authelia_users u ON u.username = e.username WHERE e.email_key = $1`, email
+		} else if sub, ok := strings.CutPrefix(key, buildLookupKey("sub", "")); ok {
+			query, key = `SELECT u.username, u.record, u.revision FROM authelia_user_subs s
+				JOIN authelia_users u ON u.username = s.username WHERE s.sub_key = $1`, sub
+		} else {
+			return nil, 0, errs.NewUserNotFound("user not found")
+		}
+	}
+
+	var username string
+	var record []byte
+	var revision int64
+	if err := p.db.QueryRow(ctx, query, key).Scan(&username, &record, &revision); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, 0, errs.NewUserNotFound("user not found")
+		}
+		return nil, 0, errs.NewUnexpected("failed to get user from Postgres", err)
+	}
+	user, err := p.decodeUser(ctx, username, record)
+	if err != nil {
+		return nil, 0, err
+	}
+	return user, uint64(revision), nil
+}
+
+func (p *postgresUserStorage) decodeUser(ctx context.Context, username string, record []byte) (*AutheliaUser, error) {
+	value, err := openValue(ctx, p.envelope, username, record)
+	if err != nil {
+		return nil, err
+	}
+	var storageUser AutheliaUserStorage
+	if err := json.Unmarshal(value, &storageUser); err != nil {
+		return nil, errs.NewUnexpected("failed to unmarshal user data", err)
+	}
+	var autheliaUser AutheliaUser
+	autheliaUser.FromStorage(&storageUser)
+	return &autheliaUser, nil
+}
+
+func (p *postgresUserStorage) ListUsers(ctx context.Context) (map[string]*AutheliaUser, error) {
+	rows, err := p.db.Query(ctx, `SELECT username, record FROM authelia_users`)
+	if err != nil {
+		return nil, errs.NewUnexpected("failed to list users from Postgres", err)
+	}
+	defer rows.Close()
+
+	users := make(map[string]*AutheliaUser)
+	for rows.Next() {
+		var username string
+		var record []byte
+		if err := rows.Scan(&username, &record); err != nil {
+			return nil, errs.NewUnexpected("failed to list users from Postgres", err)
+		}
+		user, err := p.decodeUser(ctx, username, record)
+		if err != nil {
+			slog.WarnContext(ctx, "failed to get user during list operation",
+				"username", username, "error", err)
+			continue
+		}
+		users[username] = user
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errs.NewUnexpected("failed to list users from Postgres", err)
+	}
+	return users, nil
+}
+
+// encodeUser returns the sealed storage record of user
+func (p *postgresUserStorage) encodeUser(ctx context.Context, user *AutheliaUser) ([]byte, error) {
+	data, err := json.Marshal(user.ToStorage())
+	if err != nil {
+		return nil, errs.NewUnexpected("failed to marshal user data", err)
+	}
+	return sealValue(ctx, p.envelope, user.Username, data)
+}
+
+func (p *postgresUserStorage) SetUser(ctx context.Context, user *AutheliaUser) (any, error) {
+	user.UpdatedAt = time.Now()
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = time.Now()
+	}
+	record, err := p.encodeUser(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	err = inTransaction(ctx, p.db, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `INSERT INTO authelia_users (username, record, revision, merged_into, created_at, updated_at)
+			VALUES ($1, $2, nextval('authelia_user_revisions'), NULLIF($3, ''), $4, $5)
+			ON CONFLICT (username) DO UPDATE SET record = EXCLUDED.record, revision = EXCLUDED.revision,
+				merged_into = EXCLUDED.merged_into, updated_at = EXCLUDED.updated_at`,
+			user.Username, record, user.MergedInto, user.CreatedAt, user.UpdatedAt); err != nil {
+			return err
+		}
+		_, err := replaceIndexKeys(ctx, tx, user)
+		return err
+	})
+	if err != nil {
+		return nil, errs.NewUnexpected("failed to set user in Postgres", err)
+	}
+	return user, nil
+}
+
+func (p *postgresUserStorage) UpdateUserWithRevision(ctx context.Context, user *AutheliaUser, revision uint64) error {
+	user.UpdatedAt = time.Now()
+	record, err := p.encodeUser(ctx, user)
+	if err != nil {
+		return err
+	}
+
+	return inTransaction(ctx, p.db, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `UPDATE authelia_users
+			SET record = $2, revision = nextval('authelia_user_revisions'), merged_into = NULLIF($3, ''), updated_at = $4
+			WHERE username = $1 AND revision = $5`,
+			user.Username, record, user.MergedInto, user.UpdatedAt, int64(revision))
+		if err != nil {
+			return errs.NewUnexpected("failed to update user in Postgres", err)
+		}
+		if tag.RowsAffected() == 0 {
+			var exists bool
+			if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM authelia_users WHERE username = $1)`, user.Username).Scan(&exists); err != nil {
+				return errs.NewUnexpected("failed to update user in Postgres", err)
+			}
+			if !exists {
+				return errs.NewUserNotFound("user not found for update")
+			}
+			return errs.NewConflict("user has been modified by another process, please retry")
+		}
+		if _, err := replaceIndexKeys(ctx, tx, user); err != nil {
+			return errs.NewUnexpected("failed to set lookup keys in Postgres", err)
+		}
+		return nil
+	})
+}
+
+// replaceIndexKeys points the emails and subs of user at it and removes the
+// rows of those it no longer has, returning how many were removed. A key
+// another user held moves to this one, as a lookup key does when rewritten.
+func replaceIndexKeys(ctx context.Context, tx pgx.Tx, user *AutheliaUser) (int64, error) {
+	if user.MergedInto != "" {
+		// the rows resolve to the account it was merged into
+		return 0, nil
+	}
+	emails, subs := userIndexKeys(ctx, user)
+
+	var removed int64
+	for _, index := range []struct {
+		table, column string
+		keys          []string
+	}{
+		{"authelia_user_emails", "email_key", emails},
+		{"authelia_user_subs", "sub_key", subs},
+	} {
+		keys := index.keys
+		if keys == nil {
+			keys = []string{}
+		}
+		tag, err := tx.Exec(ctx, fmt.Sprintf(`DELETE FROM %s WHERE username = $1 AND NOT (%s = ANY($2))`, index.table, index.column),
+			user.Username, keys)
+		if err != nil {
+			return removed, err
+		}
+		removed += tag.RowsAffected()
+		if len(keys) == 0 {
+			continue
+		}
+		if _, err := tx.Exec(ctx, fmt.Sprintf(`INSERT INTO %s (%s, username) SELECT DISTINCT unnest($1::text[]), $2
+			ON CONFLICT (%s) DO UPDATE SET username = EXCLUDED.username`, index.table, index.column, index.column),
+			keys, user.Username); err != nil {
+			return removed, err
+		}
+	}
+	return removed, nil
+}
+
+// CreateVerificationCode stores the code for email until the OTP TTL passes,
+// purging the expired ones
+func (p *postgresUserStorage) CreateVerificationCode(ctx context.Context, email, otp string) error {
+	if email == "" {
+		return errs.NewUnexpected("email is required")
+	}
+	if otp == "" {
+		return errs.NewUnexpected("otp is required")
+	}
+
+	if _, err := p.db.Exec(ctx, `DELETE FROM authelia_email_otps WHERE expires_at <= now()`); err != nil {
+		slog.WarnContext(ctx, "failed to purge expired verification codes", "error", err)
+	}
+	if _, err := p.db.Exec(ctx, `INSERT INTO authelia_email_otps (email, otp, expires_at)
+		VALUES ($1, $2, now() + make_interval(secs => $3))
+		ON CONFLICT (email) DO UPDATE SET otp = EXCLUDED.otp, expires_at = EXCLUDED.expires_at`,
+		email, otp, p.otpTTL.Seconds()); err != nil {
+		return errs.NewUnexpected("failed to store verification code in Postgres", err)
+	}
+
+	slog.InfoContext(ctx, "verification code stored successfully",
+		"email", email,
+	)
+	return nil
+}
+
+// GetVerificationCode returns the code stored for email if it hasn't expired
+func (p *postgresUserStorage) GetVerificationCode(ctx context.Context, email string) (string, error) {
+	if email == "" {
+		return "", errs.NewUnexpected("email is required")
+	}
+
+	var otp string
+	err := p.db.QueryRow(ctx, `SELECT otp FROM authelia_email_otps WHERE email = $1 AND expires_at > now()`, email).Scan(&otp)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", errs.NewNotFound("verification code not found or expired")
+		}
+		return "", errs.NewUnexpected("failed to get verification code from Postgres", err)
+	}
+
+	slog.InfoContext(ctx, "verification code retrieved successfully",
+		"email", email,
+	)
+	return otp, nil
+}
+
+// ReencryptStorage rewrites the records not sealed with the active key. The
+// revision is kept, so a pending update based on it still applies; a record
+// updated since it was read is counted as a conflict.
+func (p *postgresUserStorage) ReencryptStorage(ctx context.Context) (model.ReencryptResult, error) {
+	var result model.ReencryptResult
+	if p.envelope == nil {
+		return result, errs.NewValidation("KV encryption is not enabled")
+	}
+	if err := p.envelope.RefreshActiveKey(ctx); err != nil {
+		return result, err
+	}
+
+	rows, err := p.db.Query(ctx, `SELECT username, record, revision FROM authelia_users`)
+	if err != nil {
+		return result, errs.NewUnexpected("failed to list users from Postgres", err)
+	}
+	type stored struct {
+		username string
+		record   []byte
+		revision int64
+	}
+	var records []stored
+	for rows.Next() {
+		var record stored
+		if err := rows.Scan(&record.username, &record.record, &record.revision); err != nil {
+			rows.Close()
+			return result, errs.NewUnexpected("failed to list users from Postgres", err)
+		}
+		records = append(records, record)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return result, errs.NewUnexpected("failed to list users from Postgres", err)
+	}
+
+	for _, record := range records {
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+		result.Scanned++
+		if !needsReencryption(p.envelope, record.record) {
+			continue
+		}
+
+		plaintext, err := openValue(ctx, p.envelope, record.username, record.record)
+		if err == nil {
+			record.record, err = sealValue(ctx, p.envelope, record.username, plaintext)
+		}
+		var tag pgconn.CommandTag
+		if err == nil {
+			tag, err = p.db.Exec(ctx, `UPDATE authelia_users SET record = $2 WHERE username = $1 AND revision = $3`,
+				record.username, record.record, record.revision)
+		}
+		switch {
+		case err != nil:
+			result.Failed++
+			slog.WarnContext(ctx, "failed to re-encrypt user record", "username", redaction.Redact(record.username), "error", err)
+		case tag.RowsAffected() == 0:
+			result.Conflicts++
+		default:
+			result.Reencrypted++
+		}
+	}
+
+	slog.InfoContext(ctx, "Postgres re-encryption completed",
+		"active_key_id", p.envelope.ActiveKeyID(),
+		"scanned", result.Scanned,
+		"reencrypted", result.Reencrypted,
+		"conflicts", result.Conflicts,
+		"failed", result.Failed,
+	)
+	return result, nil
+}
+
+// ReindexLookups rewrites the email and sub rows of every user with the
+// current normalization, removing the rows of keys built before it. The rows
+// are deleted with their user, so none are ever orphaned.
+func (p *postgresUserStorage) ReindexLookups(ctx context.Context, progress func(model.ReindexProgress)) (model.ReindexResult, error) {
+	var result model.ReindexResult
+
+	users, err := p.ListUsers(ctx)
+	if err != nil {
+		return result, err
+	}
+	for username, user := range users {
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+		result.Scanned++
+
+		var removed int64
+		err := inTransaction(ctx, p.db, func(tx pgx.Tx) (errIndex error) {
+			removed, errIndex = replaceIndexKeys(ctx, tx, user)
+			return errIndex
+		})
+		if err != nil {
+			result.Failed++
+			slog.WarnContext(ctx, "failed to re-index lookup keys", "username", redaction.Redact(username), "error", err)
+			reportReindex(progress, result, username, err)
+			continue
+		}
+		result.Reindexed++
+		result.Removed += int(removed)
+		if result.Scanned%reindexProgressEvery == 0 {
+			reportReindex(progress, result, "", nil)
+		}
+	}
+
+	slog.InfoContext(ctx, "lookup key re-index completed",
+		"scanned", result.Scanned,
+		"reindexed", result.Reindexed,
+		"removed", result.Removed,
+		"failed", result.Failed,
+	)
+	return result, nil
+}
+
+// migratePostgres applies the migrations the database doesn't have yet, in one
+// transaction holding an advisory lock, and returns the versions it applied
+func migratePostgres(ctx context.Context, db postgresDB) ([]int, error) {
+	migrations, err := postgresMigrationFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []int
+	err = inTransaction(ctx, db, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, int64(postgresMigrationLock)); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, `CREATE TABLE IF NOT EXISTS authelia_schema_migrations (
+			version    INT PRIMARY KEY,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)`); err != nil {
+			return err
+		}
+		var current int
+		if err := tx.QueryRow(ctx, `SELECT COALESCE(MAX(version), 0) FROM authelia_schema_migrations`).Scan(&current); err != nil {
+			return err
+		}
+
+		for _, migration := range migrations {
+			if migration.version <= current {
+				continue
+			}
+			if _, err := tx.Exec(ctx, migration.sql); err != nil {
+				return fmt.Errorf("migration %d: %w", migration.version, err)
+			}
+			if _, err := tx.Exec(ctx, `INSERT INTO authelia_schema_migrations (version) VALUES ($1)`, migration.version); err != nil {
+				return err
+			}
+			applied = append(applied, migration.version)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errs.NewUnexpected("failed to migrate the Postgres user store", err)
+	}
+	return applied, nil
+}
+
+type postgresMigration struct {
+	version int
+	sql     string
+}
+
+// postgresMigrationFiles returns the embedded migrations by version; a file is
+// named <version>_<description>.sql
+func postgresMigrationFiles() ([]postgresMigration, error) {
+	files, err := postgresMigrations.ReadDir("migrations")
+	if err != nil {
+		return nil, err
+	}
+	migrations := make([]postgresMigration, 0, len(files))
+	for _, file := range files {
+		prefix, _, _ := strings.Cut(file.Name(), "_")
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s has no version prefix", file.Name())
+		}
+		data, err := postgresMigrations.ReadFile(path.Join("migrations", file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, postgresMigration{version: version, sql: string(data)})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+	return migrations, nil
+}
+
+// newPostgresUserStorage connects the pool and migrates the schema
+func newPostgresUserStorage(ctx context.Context, config PostgresStoreConfig, envelope *encryption.Envelope) (UserStore, error) {
+	poolConfig, err := pgxpool.ParseConfig(config.URL)
+	if err != nil {
+		return nil, errs.NewValidation("invalid Postgres URL: " + err.Error())
+	}
+	if config.MaxConns > 0 {
+		poolConfig.MaxConns = config.MaxConns
+	}
+	if config.MinConns > 0 {
+		poolConfig.MinConns = min(config.MinConns, poolConfig.MaxConns)
+	}
+	if config.MaxConnLifetime > 0 {
+		poolConfig.MaxConnLifetime = config.MaxConnLifetime
+	}
+	if config.MaxConnIdleTime > 0 {
+		poolConfig.MaxConnIdleTime = config.MaxConnIdleTime
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, errs.NewServiceUnavailable("failed to create the Postgres pool", err)
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, errs.NewServiceUnavailable("failed to connect to Postgres", err)
+	}
+	applied, err := migratePostgres(ctx, pool)
+	if err != nil {
+		pool.Close()
+		return nil, err
+	}
+	if config.OTPTTL <= 0 {
+		config.OTPTTL = defaultOTPTTL
+	}
+	slog.InfoContext(ctx, "created Postgres user storage",
+		"postgres_host", poolConfig.ConnConfig.Host,
+		"database", poolConfig.ConnConfig.Database,
+		"max_conns", poolConfig.MaxConns,
+		"migrations_applied", applied,
+		"encrypted", envelope != nil,
+	)
+
+	return &postgresUserStorage{db: pool, otpTTL: config.OTPTTL, envelope: envelope}, nil
+}
+
+var (
+	_ UserStore               = (*postgresUserStorage)(nil)
+	_ port.StorageReencrypter = (*postgresUserStorage)(nil)
+	_ port.LookupReindexer    = (*postgresUserStorage)(nil)
+)
@@ -0,0 +1,153 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package authelia
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+)
+
+func TestMigratePostgres(t *testing.T) {
+	ctx := context.Background()
+	migrations, err := postgresMigrationFiles()
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+	latest := migrations[len(migrations)-1].version
+
+	expectMigration := func(mock pgxmock.PgxPoolIface, current int) {
+		mock.ExpectBegin()
+		mock.ExpectExec(`SELECT pg_advisory_xact_lock`).WithArgs(int64(postgresMigrationLock)).
+			WillReturnResult(pgxmock.NewResult("SELECT", 1))
+		mock.ExpectExec(`CREATE TABLE IF NOT EXISTS authelia_schema_migrations`).
+			WillReturnResult(pgxmock.NewResult("CREATE TABLE", 0))
+		mock.ExpectQuery(`SELECT COALESCE\(MAX\(version\), 0\) FROM authelia_schema_migrations`).
+			WillReturnRows(pgxmock.NewRows([]string{"max"}).AddRow(current))
+	}
+
+	t.Run("applies pending migrations in order", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		expectMigration(mock, 0)
+		for _, migration := range migrations {
+			mock.ExpectExec(`CREATE`).WillReturnResult(pgxmock.NewResult("CREATE TABLE", 0))
+			mock.ExpectExec(`INSERT INTO authelia_schema_migrations`).WithArgs(migration.version).
+				WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		}
+		mock.ExpectCommit()
+
+		applied, err := migratePostgres(ctx, mock)
+		require.NoError(t, err)
+		assert.Equal(t, latest, applied[len(applied)-1])
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("up to date schema", func(t *testing.T) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mock.Close()
+
+		expectMigration(mock, latest)
+		mock.ExpectCommit()
+
+		applied, err := migratePostgres(ctx, mock)
+		require.NoError(t, err)
+		assert.Empty(t, applied)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPostgresUserStorage(t *testing.T) {
+	ctx := context.Background()
+	user := &AutheliaUser{
+		User:  &model.User{Username: "jdoe", Sub: "sub-jdoe", PrimaryEmail: "jdoe@example.com"},
+		Email: "jdoe@example.com",
+	}
+	record, err := json.Marshal(user.ToStorage())
+	require.NoError(t, err)
+
+	newStore := func(t *testing.T) (*postgresUserStorage, pgxmock.PgxPoolIface) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		t.Cleanup(mock.Close)
+		return &postgresUserStorage{db: mock, otpTTL: defaultOTPTTL}, mock
+	}
+
+	t.Run("resolves an email through its index", func(t *testing.T) {
+		store, mock := newStore(t)
+		mock.ExpectQuery(`FROM authelia_user_emails e`).WithArgs(user.BuildEmailIndexKey(ctx)).
+			WillReturnRows(pgxmock.NewRows([]string{"username", "record", "revision"}).AddRow("jdoe", record, int64(7)))
+
+		found, revision, err := store.GetUserWithRevision(ctx, store.BuildLookupKey(ctx, "email", user.BuildEmailIndexKey(ctx)))
+		require.NoError(t, err)
+		assert.Equal(t, "jdoe", found.Username)
+		assert.Equal(t, uint64(7), revision)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		store, mock := newStore(t)
+		mock.ExpectQuery(`FROM authelia_users WHERE username = \$1`).WithArgs("nobody").WillReturnError(pgx.ErrNoRows)
+
+		_, err := store.GetUser(ctx, "nobody")
+		var notFound errs.NotFound
+		assert.ErrorAs(t, err, &notFound)
+	})
+
+	t.Run("writes the record and its index rows in one transaction", func(t *testing.T) {
+		store, mock := newStore(t)
+		mock.ExpectBegin()
+		mock.ExpectExec(`INSERT INTO authelia_users`).
+			WithArgs("jdoe", pgxmock.AnyArg(), "", pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectExec(`DELETE FROM authelia_user_emails`).WithArgs("jdoe", []string{user.BuildEmailIndexKey(ctx)}).
+			WillReturnResult(pgxmock.NewResult("DELETE", 1))
+		mock.ExpectExec(`INSERT INTO authelia_user_emails`).WithArgs([]string{user.BuildEmailIndexKey(ctx)}, "jdoe").
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectExec(`DELETE FROM authelia_user_subs`).WithArgs("jdoe", []string{user.BuildSubIndexKey(ctx)}).
+			WillReturnResult(pgxmock.NewResult("DELETE", 0))
+		mock.ExpectExec(`INSERT INTO authelia_user_subs`).WithArgs([]string{user.BuildSubIndexKey(ctx)}, "jdoe").
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectCommit()
+
+		_, err := store.SetUser(ctx, user)
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("stale revision conflicts and rolls back", func(t *testing.T) {
+		store, mock := newStore(t)
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE authelia_users`).
+			WithArgs("jdoe", pgxmock.AnyArg(), "", pgxmock.AnyArg(), int64(3)).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+		mock.ExpectQuery(`SELECT EXISTS`).WithArgs("jdoe").WillReturnRows(pgxmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectRollback()
+
+		err := store.UpdateUserWithRevision(ctx, user, 3)
+		var conflict errs.Conflict
+		assert.ErrorAs(t, err, &conflict)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("expired verification codes are not returned", func(t *testing.T) {
+		store, mock := newStore(t)
+		mock.ExpectQuery(`SELECT otp FROM authelia_email_otps WHERE email = \$1 AND expires_at > now\(\)`).
+			WithArgs("jdoe@example.com").WillReturnError(pgx.ErrNoRows)
+
+		_, err := store.GetVerificationCode(ctx, "jdoe@example.com")
+		var notFound errs.NotFound
+		assert.ErrorAs(t, err, &notFound)
+	})
+}
@@ -0,0 +1,196 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package authelia
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/encryption"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+)
+
+// RedisStoreConfig configures the Redis user store
+type RedisStoreConfig struct {
+	// URL is the redis:// or rediss:// URL of the server
+	URL string
+	// KeyPrefix namespaces the keys of the store, e.g. "authelia:"
+	KeyPrefix string
+	// OTPTTL is how long a verification code is kept; <= 0 means 5 minutes
+	OTPTTL time.Duration
+}
+
+// WithRedisUserStore keeps the user records, lookup keys and verification
+// codes in Redis instead of the NATS KV buckets
+func WithRedisUserStore(config RedisStoreConfig) Option {
+	return func(u *userReaderWriter) {
+		u.redisStore = &config
+	}
+}
+
+// Keys of a redisBucket are hashes holding the value and the revision it was
+// written at. Revisions come from one counter, as in a JetStream stream, so a
+// key deleted and written again doesn't get back a revision it had.
+var (
+	redisPutScript = redis.NewScript(`
+local revision = redis.call('INCR', KEYS[2])
+redis.call('HSET', KEYS[1], 'value', ARGV[1], 'revision', revision)
+if tonumber(ARGV[2]) > 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return revision`)
+
+	// returns -2 when the key exists
+	redisCreateScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	return -2
+end
+local revision = redis.call('INCR', KEYS[2])
+redis.call('HSET', KEYS[1], 'value', ARGV[1], 'revision', revision)
+return revision`)
+
+	// returns -1 when the key doesn't exist and -2 when its revision changed
+	redisUpdateScript = redis.NewScript(`
+local current = redis.call('HGET', KEYS[1], 'revision')
+if not current then
+	return -1
+end
+if current ~= ARGV[2] then
+	return -2
+end
+local revision = redis.call('INCR', KEYS[2])
+redis.call('HSET', KEYS[1], 'value', ARGV[1], 'revision', revision)
+return revision`)
+
+	redisDeleteScript = redis.NewScript(`
+local current = redis.call('HGET', KEYS[1], 'revision')
+if not current then
+	return -1
+end
+if current ~= ARGV[1] then
+	return -2
+end
+redis.call('DEL', KEYS[1])
+return 0`)
+)
+
+// redisBucket is a kvBucket over the Redis keys starting with prefix
+type redisBucket struct {
+	client *redis.Client
+	prefix string
+	// revisions is the key of the revision counter
+	revisions string
+	// ttl expires every key written; 0 keeps them
+	ttl time.Duration
+}
+
+func (b redisBucket) Get(ctx context.Context, key string) ([]byte, uint64, error) {
+	fields, err := b.client.HMGet(ctx, b.prefix+key, "value", "revision").Result()
+	if err != nil {
+		return nil, 0, err
+	}
+	value, ok := fields[0].(string)
+	if !ok {
+		return nil, 0, errKeyNotFound
+	}
+	revision, err := strconv.ParseUint(fields[1].(string), 10, 64)
+	if err != nil {
+		return nil, 0, err
+	}
+	return []byte(value), revision, nil
+}
+
+func (b redisBucket) Put(ctx context.Context, key string, value []byte) (uint64, error) {
+	revision, err := redisPutScript.Run(ctx, b.client, []string{b.prefix + key, b.revisions}, value, b.ttl.Milliseconds()).Uint64()
+	return revision, err
+}
+
+func (b redisBucket) Create(ctx context.Context, key string, value []byte) (uint64, error) {
+	created, err := redisCreateScript.Run(ctx, b.client, []string{b.prefix + key, b.revisions}, value).Int64()
+	if err != nil {
+		return 0, err
+	}
+	return uint64(created), redisScriptError(created)
+}
+
+func (b redisBucket) Update(ctx context.Context, key string, value []byte, revision uint64) (uint64, error) {
+	updated, err := redisUpdateScript.Run(ctx, b.client, []string{b.prefix + key, b.revisions}, value, revision).Int64()
+	if err != nil {
+		return 0, err
+	}
+	return uint64(updated), redisScriptError(updated)
+}
+
+func (b redisBucket) Delete(ctx context.Context, key string, revision uint64) error {
+	deleted, err := redisDeleteScript.Run(ctx, b.client, []string{b.prefix + key}, revision).Int64()
+	if err != nil {
+		return err
+	}
+	return redisScriptError(deleted)
+}
+
+func (b redisBucket) Keys(ctx context.Context) ([]string, error) {
+	var keys []string
+	iter := b.client.Scan(ctx, 0, redisGlobEscaper.Replace(b.prefix)+"*", 1000).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, strings.TrimPrefix(iter.Val(), b.prefix))
+	}
+	return keys, iter.Err()
+}
+
+// redisGlobEscaper escapes the characters SCAN patterns treat specially
+var redisGlobEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`)
+
+func redisScriptError(result int64) error {
+	switch result {
+	case -1:
+		return errKeyNotFound
+	case -2:
+		return errRevisionMismatch
+	}
+	return nil
+}
+
+// newRedisUserStorage connects to the Redis user store. Users and
+// verification codes are kept under "<prefix>users/" and "<prefix>otp/", and
+// with archive set, archived users under "<prefix>archive/".
+func newRedisUserStorage(ctx context.Context, config RedisStoreConfig, envelope *encryption.Envelope, archive bool) (UserStore, error) {
+	options, err := redis.ParseURL(config.URL)
+	if err != nil {
+		return nil, errs.NewValidation("invalid Redis URL: " + err.Error())
+	}
+	client := redis.NewClient(options)
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		return nil, errs.NewServiceUnavailable("failed to connect to Redis", err)
+	}
+	if config.OTPTTL <= 0 {
+		config.OTPTTL = defaultOTPTTL
+	}
+	slog.DebugContext(ctx, "created Redis user storage",
+		"redis_addr", options.Addr,
+		"key_prefix", config.KeyPrefix,
+		"encrypted", envelope != nil,
+		"archive", archive,
+	)
+
+	revisions := config.KeyPrefix + "revision"
+	storage := &kvUserStorage{
+		users:    redisBucket{client: client, prefix: config.KeyPrefix + "users/", revisions: revisions},
+		otps:     redisBucket{client: client, prefix: config.KeyPrefix + "otp/", revisions: revisions, ttl: config.OTPTTL},
+		backend:  "Redis",
+		envelope: envelope,
+	}
+	if archive {
+		storage.archive = redisBucket{client: client, prefix: config.KeyPrefix + "archive/", revisions: revisions}
+	}
+	return storage, nil
+}
+
+var _ kvBucket = redisBucket{}
@@ -0,0 +1,96 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package authelia
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+)
+
+func TestRedisUserStorage(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+	store, err := newRedisUserStorage(ctx, RedisStoreConfig{URL: "redis://" + server.Addr(), KeyPrefix: "acme-authelia:"}, nil, false)
+	require.NoError(t, err)
+
+	user := &AutheliaUser{
+		User:  &model.User{Username: "jdoe", Sub: "sub-jdoe", PrimaryEmail: "jdoe@example.com"},
+		Email: "jdoe@example.com",
+	}
+	_, err = store.SetUser(ctx, user)
+	require.NoError(t, err)
+
+	t.Run("resolves lookup keys", func(t *testing.T) {
+		byEmail, err := store.GetUser(ctx, store.BuildLookupKey(ctx, "email", user.BuildEmailIndexKey(ctx)))
+		require.NoError(t, err)
+		assert.Equal(t, "jdoe", byEmail.Username)
+
+		bySub, err := store.GetUser(ctx, store.BuildLookupKey(ctx, "sub", user.BuildSubIndexKey(ctx)))
+		require.NoError(t, err)
+		assert.Equal(t, "jdoe@example.com", bySub.Email)
+
+		_, err = store.GetUser(ctx, store.BuildLookupKey(ctx, "sub", "unknown"))
+		var notFound errs.NotFound
+		assert.ErrorAs(t, err, &notFound)
+	})
+
+	t.Run("lists users without lookup keys", func(t *testing.T) {
+		users, err := store.ListUsers(ctx)
+		require.NoError(t, err)
+		assert.Len(t, users, 1)
+		assert.Contains(t, users, "jdoe")
+		assert.True(t, server.Exists("acme-authelia:users/jdoe"))
+	})
+
+	t.Run("updates only the revision read", func(t *testing.T) {
+		stored, revision, err := store.GetUserWithRevision(ctx, "jdoe")
+		require.NoError(t, err)
+
+		stored.DisplayName = "J. Doe"
+		require.NoError(t, store.UpdateUserWithRevision(ctx, stored, revision))
+
+		stored.DisplayName = "Stale"
+		err = store.UpdateUserWithRevision(ctx, stored, revision)
+		var conflict errs.Conflict
+		assert.ErrorAs(t, err, &conflict)
+
+		current, err := store.GetUser(ctx, "jdoe")
+		require.NoError(t, err)
+		assert.Equal(t, "J. Doe", current.DisplayName)
+
+		err = store.UpdateUserWithRevision(ctx, &AutheliaUser{User: &model.User{Username: "nobody"}}, revision)
+		var notFound errs.NotFound
+		assert.ErrorAs(t, err, &notFound)
+	})
+
+	t.Run("verification codes expire", func(t *testing.T) {
+		require.NoError(t, store.CreateVerificationCode(ctx, "jdoe@example.com", "123456"))
+		otp, err := store.GetVerificationCode(ctx, "jdoe@example.com")
+		require.NoError(t, err)
+		assert.Equal(t, "123456", otp)
+
+		server.FastForward(defaultOTPTTL + time.Second)
+		_, err = store.GetVerificationCode(ctx, "jdoe@example.com")
+		var notFound errs.NotFound
+		assert.ErrorAs(t, err, &notFound)
+	})
+}
+
+func TestRedisUserStorage_Unreachable(t *testing.T) {
+	server := miniredis.RunT(t)
+	addr := server.Addr()
+	server.Close()
+
+	_, err := newRedisUserStorage(context.Background(), RedisStoreConfig{URL: "redis://" + addr}, nil, false)
+	var unavailable errs.ServiceUnavailable
+	assert.ErrorAs(t, err, &unavailable)
+}
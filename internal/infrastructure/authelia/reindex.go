@@ -0,0 +1,232 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package authelia
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/redaction"
+)
+
+// legacyEmailIndexKey is the index key email lookups used before
+// normalization: a hash of the trimmed, lowercased address
+func legacyEmailIndexKey(email string) string {
+	data := strings.TrimSpace(strings.ToLower(email))
+	if data == "" {
+		return ""
+	}
+	hash := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(hash[:])
+}
+
+// staleLookupKeys returns the pre-normalization email lookup keys of user
+// that differ from the keys setLookupKeys writes now
+func (n *kvUserStorage) staleLookupKeys(ctx context.Context, user *AutheliaUser) []string {
+	type pair struct{ legacy, current string }
+	var pairs []pair
+	if user.Email != "" {
+		pairs = append(pairs, pair{legacyEmailIndexKey(user.PrimaryEmail), user.BuildEmailIndexKey(ctx)})
+	}
+	for _, alternateEmail := range user.AlternateEmails {
+		pairs = append(pairs, pair{legacyEmailIndexKey(alternateEmail.Email), user.BuildAlternateEmailIndexKey(ctx, alternateEmail.Email)})
+	}
+	for _, secondaryEmail := range user.SecondaryEmails {
+		if secondaryEmail.Verified {
+			pairs = append(pairs, pair{legacyEmailIndexKey(secondaryEmail.Email), user.BuildSecondaryEmailIndexKey(ctx, secondaryEmail.Email)})
+		}
+	}
+
+	current := make(map[string]bool, len(pairs))
+	for _, p := range pairs {
+		current[p.current] = true
+	}
+
+	var stale []string
+	seen := make(map[string]bool, len(pairs))
+	for _, p := range pairs {
+		if p.legacy == "" || current[p.legacy] || seen[p.legacy] {
+			continue
+		}
+		seen[p.legacy] = true
+		stale = append(stale, n.BuildLookupKey(ctx, "email", p.legacy))
+	}
+	return stale
+}
+
+// removeLookupKey deletes a lookup key if it still points at username, so a
+// key another user has since claimed is left alone
+func (n *kvUserStorage) removeLookupKey(ctx context.Context, key, username string) (bool, error) {
+	sealed, revision, err := n.users.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, errKeyNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	value, err := openValue(ctx, n.envelope, key, sealed)
+	if err != nil {
+		return false, err
+	}
+	if string(value) != username {
+		return false, nil
+	}
+	if err := n.users.Delete(ctx, key, revision); err != nil {
+		if errors.Is(err, errKeyNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// reindexProgressEvery is how many users a re-index goes through between
+// progress reports
+const reindexProgressEvery = 100
+
+// reportReindex passes the counts so far, and the failure of username if
+// any, to progress
+func reportReindex(progress func(model.ReindexProgress), result model.ReindexResult, username string, err error) {
+	if progress == nil {
+		return
+	}
+	update := model.ReindexProgress{ReindexResult: result, Username: username}
+	if err != nil {
+		update.Error = err.Error()
+	}
+	progress(update)
+}
+
+// ReindexLookups rewrites the lookup keys of every stored user with the
+// current normalization, deletes the keys built before it was introduced,
+// then deletes the keys no user claims any more. Keys another user still
+// claims are left with it, as RepairLookups reports them. It is safe to run
+// more than once.
+func (n *kvUserStorage) ReindexLookups(ctx context.Context, progress func(model.ReindexProgress)) (model.ReindexResult, error) {
+	var result model.ReindexResult
+
+	users, err := n.ListUsers(ctx)
+	if err != nil {
+		return result, err
+	}
+
+	for username, user := range users {
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+		result.Scanned++
+
+		user.SetUsername(username)
+		keep, err := n.lookupCollisions(ctx, user)
+		if err == nil {
+			err = n.setLookupKeys(ctx, user, keep)
+		}
+		if err != nil {
+			result.Failed++
+			slog.WarnContext(ctx, "failed to re-index lookup keys", "username", redaction.Redact(username), "error", err)
+			reportReindex(progress, result, username, err)
+			continue
+		}
+		result.Reindexed++
+
+		for _, key := range n.staleLookupKeys(ctx, user) {
+			removed, err := n.removeLookupKey(ctx, key, username)
+			if err != nil {
+				slog.WarnContext(ctx, "failed to remove stale lookup key", "username", redaction.Redact(username), "error", err)
+				continue
+			}
+			if removed {
+				result.Removed++
+			}
+		}
+		if result.Scanned%reindexProgressEvery == 0 {
+			reportReindex(progress, result, "", nil)
+		}
+	}
+
+	if err := n.removeOrphanedLookupKeys(ctx, users, &result); err != nil {
+		return result, err
+	}
+
+	slog.InfoContext(ctx, "lookup key re-index completed",
+		"scanned", result.Scanned,
+		"reindexed", result.Reindexed,
+		"removed", result.Removed,
+		"orphaned", result.Orphaned,
+		"failed", result.Failed,
+	)
+	return result, nil
+}
+
+// removeOrphanedLookupKeys deletes the lookup keys whose user, as listed in
+// users or, for one written or archived since, as stored now, doesn't claim
+// them
+func (n *kvUserStorage) removeOrphanedLookupKeys(ctx context.Context, users map[string]*AutheliaUser, result *model.ReindexResult) error {
+	keys, err := n.users.Keys(ctx)
+	if err != nil {
+		return errs.NewUnexpected("failed to list keys from "+n.backend, err)
+	}
+
+	claimed := make(map[[2]string]bool)
+	for _, user := range users {
+		for _, key := range n.userLookupKeys(ctx, user) {
+			claimed[[2]string{key, user.Username}] = true
+		}
+	}
+
+	for _, key := range keys {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if !strings.HasPrefix(key, kvLookupPrefix) {
+			continue
+		}
+		username, err := n.lookupUser(ctx, key)
+		var notFound errs.NotFound
+		if errors.As(err, &notFound) || claimed[[2]string{key, username}] {
+			continue
+		}
+		var owner *AutheliaUser
+		if err == nil {
+			owner, err = n.ownerRecord(ctx, username)
+		}
+		if err != nil {
+			slog.WarnContext(ctx, "failed to check lookup key", "key", key, "error", err)
+			continue
+		}
+		if owner != nil && slices.Contains(n.userLookupKeys(ctx, owner), key) {
+			continue
+		}
+
+		removed, err := n.removeLookupKey(ctx, key, username)
+		if err != nil {
+			slog.WarnContext(ctx, "failed to remove orphaned lookup key", "key", key, "error", err)
+			continue
+		}
+		if removed {
+			result.Orphaned++
+		}
+	}
+	return nil
+}
+
+// ReindexLookups rewrites the email and sub lookup keys of stored users. It
+// implements port.LookupReindexer.
+func (a *userReaderWriter) ReindexLookups(ctx context.Context, progress func(model.ReindexProgress)) (model.ReindexResult, error) {
+	reindexer, ok := a.storage.(port.LookupReindexer)
+	if !ok {
+		return model.ReindexResult{}, errs.NewValidation("user storage does not support re-indexing")
+	}
+	return reindexer.ReindexLookups(ctx, progress)
+}
+
+var _ port.LookupReindexer = (*userReaderWriter)(nil)
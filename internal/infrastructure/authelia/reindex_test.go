@@ -0,0 +1,86 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package authelia
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/normalize"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaleLookupKeys(t *testing.T) {
+	ctx := context.Background()
+	storage := &kvUserStorage{}
+
+	newUser := func(email string, alternates ...string) *AutheliaUser {
+		user := &AutheliaUser{User: &model.User{Username: "jdoe", PrimaryEmail: email}, Email: email}
+		for _, alternate := range alternates {
+			user.AlternateEmails = append(user.AlternateEmails, model.Email{Email: alternate, Verified: true})
+		}
+		return user
+	}
+
+	t.Run("keys already normalized", func(t *testing.T) {
+		assert.Empty(t, storage.staleLookupKeys(ctx, newUser("JDoe@Example.com", "jdoe@lfx.example")))
+	})
+
+	t.Run("compatibility characters", func(t *testing.T) {
+		user := newUser("ｊdoe@example.com")
+		stale := storage.staleLookupKeys(ctx, user)
+		assert.Equal(t, []string{storage.BuildLookupKey(ctx, "email", legacyEmailIndexKey("ｊdoe@example.com"))}, stale)
+		assert.NotContains(t, stale, storage.BuildLookupKey(ctx, "email", user.BuildEmailIndexKey(ctx)))
+	})
+
+	t.Run("gmail canonicalization", func(t *testing.T) {
+		previous := normalize.CurrentOptions()
+		normalize.SetOptions(normalize.Options{GmailCanonical: true})
+		t.Cleanup(func() { normalize.SetOptions(previous) })
+
+		user := newUser("j.doe@gmail.com", "jdoe@gmail.com", "j.doe+lfx@gmail.com")
+		assert.ElementsMatch(t, []string{
+			storage.BuildLookupKey(ctx, "email", legacyEmailIndexKey("j.doe@gmail.com")),
+			storage.BuildLookupKey(ctx, "email", legacyEmailIndexKey("j.doe+lfx@gmail.com")),
+		}, storage.staleLookupKeys(ctx, user), "the legacy key of jdoe@gmail.com is also its current key")
+	})
+}
+
+func TestReindexLookups(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+	storage, err := newRedisUserStorage(ctx, RedisStoreConfig{URL: "redis://" + server.Addr(), KeyPrefix: "authelia:"}, nil, false)
+	require.NoError(t, err)
+	store := storage.(*kvUserStorage)
+
+	user := &AutheliaUser{
+		User:  &model.User{Username: "jdoe", Sub: "sub-jdoe", PrimaryEmail: "jdoe@example.com"},
+		Email: "jdoe@example.com",
+	}
+	_, err = store.SetUser(ctx, user)
+	require.NoError(t, err)
+	emailKey := store.BuildLookupKey(ctx, "email", user.BuildEmailIndexKey(ctx))
+	subKey := store.BuildLookupKey(ctx, "sub", user.BuildSubIndexKey(ctx))
+
+	// a key of a user since deleted, one of an email jdoe dropped and a
+	// missing sub key
+	require.NoError(t, store.putLookupKey(ctx, store.BuildLookupKey(ctx, "email", "gone"), "nobody"))
+	require.NoError(t, store.putLookupKey(ctx, store.BuildLookupKey(ctx, "email", "dropped"), "jdoe"))
+	require.True(t, server.Del("authelia:users/"+subKey))
+
+	var reports []model.ReindexProgress
+	result, err := store.ReindexLookups(ctx, func(progress model.ReindexProgress) {
+		reports = append(reports, progress)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, model.ReindexResult{Scanned: 1, Reindexed: 1, Orphaned: 2}, result)
+	assert.Empty(t, reports, "progress is reported every few users and on failures")
+
+	keys, err := store.users.Keys(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"jdoe", emailKey, subKey}, keys)
+}
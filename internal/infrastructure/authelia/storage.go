@@ -12,17 +12,26 @@ import (
 	"strings"
 	"time"
 
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
 	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/nats"
 	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/encryption"
 	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
-	"github.com/nats-io/nats.go/jetstream"
 )
 
 const (
 	kvLookupPrefix = "lookup/"
 )
 
-type internalStorageReaderWriter interface {
+// defaultOTPTTL is how long the stores other than NATS keep a verification
+// code, the TTL of the NATS email OTP bucket
+const defaultOTPTTL = 5 * time.Minute
+
+// UserStore keeps the Authelia user records, the lookup keys resolving emails
+// and subs to them, and the email verification codes. The NATS KV buckets are
+// the default store; WithRedisUserStore and WithPostgresUserStore keep them
+// in Redis or Postgres instead, for deployments without JetStream.
+type UserStore interface {
 	internalStorageReader
 	internalStorageWriter
 	emailHandler
@@ -45,29 +54,45 @@ type emailHandler interface {
 	GetVerificationCode(ctx context.Context, email string) (string, error)
 }
 
-// natsUserStorage implements UserStorage using NATS KV store
-type natsUserStorage struct {
-	natsClient *nats.NATSClient
-	kvStore    map[string]jetstream.KeyValue
+// kvUserStorage implements UserStore over key-value buckets: a user record
+// is stored under its username, and every lookup key holds the username it
+// resolves to
+type kvUserStorage struct {
+	users kvBucket
+	// otps expires verification codes on its own
+	otps kvBucket
+	// backend names the store in errors, e.g. "NATS KV"
+	backend string
+	// envelope encrypts values in the users bucket; nil stores plaintext
+	envelope *encryption.Envelope
+	// archive keeps the records of inactive users; nil disables archival
+	archive kvBucket
+	// rejectCollisions refuses writes whose lookup keys belong to another
+	// user; otherwise the keys stay with their owner
+	rejectCollisions bool
 }
 
-func (n *natsUserStorage) lookupUser(ctx context.Context, key string) (string, error) {
+func (n *kvUserStorage) lookupUser(ctx context.Context, key string) (string, error) {
 
 	if !strings.HasPrefix(key, kvLookupPrefix) {
 		return key, nil
 	}
 
-	entry, err := n.kvStore[constants.KVBucketNameAutheliaUsers].Get(ctx, key)
+	value, _, err := n.users.Get(ctx, key)
 	if err != nil {
-		if errors.Is(err, jetstream.ErrKeyNotFound) {
-			return "", errs.NewNotFound("user not found")
+		if errors.Is(err, errKeyNotFound) {
+			return "", errs.NewUserNotFound("user not found")
 		}
-		return "", errs.NewUnexpected("failed to get user from NATS KV", err)
+		return "", errs.NewUnexpected("failed to get user from "+n.backend, err)
+	}
+	username, err := openValue(ctx, n.envelope, key, value)
+	if err != nil {
+		return "", err
 	}
-	return string(entry.Value()), nil
+	return string(username), nil
 }
 
-func (n *natsUserStorage) GetUser(ctx context.Context, key string) (*AutheliaUser, error) {
+func (n *kvUserStorage) GetUser(ctx context.Context, key string) (*AutheliaUser, error) {
 	user, _, err := n.GetUserWithRevision(ctx, key)
 	if err != nil {
 		return nil, err
@@ -75,7 +100,7 @@ func (n *natsUserStorage) GetUser(ctx context.Context, key string) (*AutheliaUse
 	return user, nil
 }
 
-func (n *natsUserStorage) GetUserWithRevision(ctx context.Context, key string) (*AutheliaUser, uint64, error) {
+func (n *kvUserStorage) GetUserWithRevision(ctx context.Context, key string) (*AutheliaUser, uint64, error) {
 
 	if key == "" {
 		return nil, 0, errs.NewUnexpected("key is required")
@@ -86,33 +111,65 @@ func (n *natsUserStorage) GetUserWithRevision(ctx context.Context, key string) (
 		return nil, 0, errLookupUser
 	}
 
-	entry, err := n.kvStore[constants.KVBucketNameAutheliaUsers].Get(ctx, username)
+	user, revision, err := n.readUser(ctx, username)
+	if n.archive == nil {
+		return user, revision, err
+	}
+
+	// the lookup keys of an archived user still resolve to its username
+	var notFound errs.NotFound
+	if errors.As(err, &notFound) {
+		user, revision, err = n.restoreUser(ctx, username)
+	}
 	if err != nil {
-		if errors.Is(err, jetstream.ErrKeyNotFound) {
-			return nil, 0, errs.NewNotFound("user not found")
+		return nil, 0, err
+	}
+	return user, n.touchUser(ctx, user, revision), nil
+}
+
+// readUser reads the record stored under username
+func (n *kvUserStorage) readUser(ctx context.Context, username string) (*AutheliaUser, uint64, error) {
+	sealed, revision, err := n.users.Get(ctx, username)
+	if err != nil {
+		if errors.Is(err, errKeyNotFound) {
+			return nil, 0, errs.NewUserNotFound("user not found")
 		}
-		return nil, 0, errs.NewUnexpected("failed to get user from NATS KV", err)
+		return nil, 0, errs.NewUnexpected("failed to get user from "+n.backend, err)
+	}
+
+	user, err := n.decodeUser(ctx, username, sealed)
+	if err != nil {
+		return nil, 0, err
+	}
+	return user, revision, nil
+}
+
+// decodeUser opens and unmarshals a record sealed under username
+func (n *kvUserStorage) decodeUser(ctx context.Context, username string, sealed []byte) (*AutheliaUser, error) {
+	value, err := openValue(ctx, n.envelope, username, sealed)
+	if err != nil {
+		return nil, err
 	}
 
 	var storageUser AutheliaUserStorage
-	if err := json.Unmarshal(entry.Value(), &storageUser); err != nil {
-		return nil, 0, errs.NewUnexpected("failed to unmarshal user data", err)
+	if err := json.Unmarshal(value, &storageUser); err != nil {
+		return nil, errs.NewUnexpected("failed to unmarshal user data", err)
 	}
 
 	// Convert storage format back to AutheliaUser
 	var autheliaUser AutheliaUser
 	autheliaUser.FromStorage(&storageUser)
 
-	return &autheliaUser, entry.Revision(), nil
+	return &autheliaUser, nil
 }
 
-func (n *natsUserStorage) ListUsers(ctx context.Context) (map[string]*AutheliaUser, error) {
+func (n *kvUserStorage) ListUsers(ctx context.Context) (map[string]*AutheliaUser, error) {
 	users := make(map[string]*AutheliaUser)
 
 	// Get all keys from the KV store
-	keys, err := n.kvStore[constants.KVBucketNameAutheliaUsers].Keys(ctx)
-	if err != nil && !strings.Contains(err.Error(), "no keys found") {
-		return nil, errs.NewUnexpected("failed to list keys from NATS KV", err)
+	keys, err := n.users.Keys(ctx)
+	if err != nil {
+		return nil, errs.NewUnexpected("failed to list keys from "+n.backend, err)
 	}
 
 	// Retrieve each user
@@ -123,7 +180,8 @@ func (n *natsUserStorage) ListUsers(ctx context.Context) (map[string]*AutheliaUs
 			continue
 		}
 
-		user, err := n.GetUser(ctx, key)
+		// read without restoring or touching, so scans don't count as use
+		user, _, err := n.readUser(ctx, key)
 		if err != nil {
 			slog.WarnContext(ctx, "failed to get user during list operation",
 				"username", key, "error", err)
@@ -135,33 +193,62 @@ func (n *natsUserStorage) ListUsers(ctx context.Context) (map[string]*AutheliaUs
 	return users, nil
 }
 
-func (n *natsUserStorage) setLookupKeys(ctx context.Context, user *AutheliaUser) error {
-	if user.Email != "" {
-		_, errPutLookup := n.kvStore[constants.KVBucketNameAutheliaUsers].Put(ctx, n.BuildLookupKey(ctx, "email", user.BuildEmailIndexKey(ctx)), []byte(user.Username))
-		if errPutLookup != nil {
-			return errs.NewUnexpected("failed to set lookup key in NATS KV", errPutLookup)
-		}
+func (n *kvUserStorage) putLookupKey(ctx context.Context, key, username string) error {
+	value, err := sealValue(ctx, n.envelope, key, []byte(username))
+	if err != nil {
+		return err
+	}
+	_, err = n.users.Put(ctx, key, value)
+	return err
+}
+
+// userIndexKeys returns the index keys of the emails and subs resolving to
+// user: its primary, alternate and verified secondary emails, its sub and the
+// subs merged into it. A merged account has none, its keys now resolving to
+// the account it was merged into.
+func userIndexKeys(ctx context.Context, user *AutheliaUser) (emails, subs []string) {
+	if user.MergedInto != "" {
+		return nil, nil
 	}
 
-	if len(user.AlternateEmails) > 0 {
-		for _, alternateEmail := range user.AlternateEmails {
-			_, errPutLookup := n.kvStore[constants.KVBucketNameAutheliaUsers].Put(ctx, n.BuildLookupKey(ctx, "email", user.BuildAlternateEmailIndexKey(ctx, alternateEmail.Email)), []byte(user.Username))
-			if errPutLookup != nil {
-				return errs.NewUnexpected("failed to set alternate email lookup key in NATS KV", errPutLookup)
-			}
+	if user.Email != "" {
+		emails = append(emails, user.BuildEmailIndexKey(ctx))
+	}
+	for _, alternateEmail := range user.AlternateEmails {
+		emails = append(emails, user.BuildAlternateEmailIndexKey(ctx, alternateEmail.Email))
+	}
+	// only verified secondary emails resolve to the account
+	for _, secondaryEmail := range user.SecondaryEmails {
+		if secondaryEmail.Verified {
+			emails = append(emails, user.BuildSecondaryEmailIndexKey(ctx, secondaryEmail.Email))
 		}
 	}
 
 	if user.Sub != "" {
-		_, errPutLookup := n.kvStore[constants.KVBucketNameAutheliaUsers].Put(ctx, n.BuildLookupKey(ctx, "sub", user.BuildSubIndexKey(ctx)), []byte(user.Username))
-		if errPutLookup != nil {
-			return errs.NewUnexpected("failed to set sub lookup key in NATS KV", errPutLookup)
+		subs = append(subs, user.BuildSubIndexKey(ctx))
+	}
+	for _, mergedSub := range user.MergedSubs {
+		merged := model.User{Sub: mergedSub}
+		subs = append(subs, merged.BuildSubIndexKey(ctx))
+	}
+	return emails, subs
+}
+
+// setLookupKeys points the lookup keys of user at it, except those in keep,
+// which stay with the user they resolve to
+func (n *kvUserStorage) setLookupKeys(ctx context.Context, user *AutheliaUser, keep map[string]string) error {
+	for _, key := range n.userLookupKeys(ctx, user) {
+		if _, ok := keep[key]; ok {
+			continue
+		}
+		if errPutLookup := n.putLookupKey(ctx, key, user.Username); errPutLookup != nil {
+			return errs.NewUnexpected("failed to set lookup key in "+n.backend, errPutLookup)
 		}
 	}
 	return nil
 }
 
-func (n *natsUserStorage) SetUser(ctx context.Context, user *AutheliaUser) (any, error) {
+func (n *kvUserStorage) SetUser(ctx context.Context, user *AutheliaUser) (any, error) {
 
 	// Update timestamp
 	user.UpdatedAt = time.Now()
@@ -171,6 +258,12 @@ func (n *natsUserStorage) SetUser(ctx context.Context, user *AutheliaUser) (any,
 		user.CreatedAt = time.Now()
 	}
 
+	// checked before anything is written, so a rejected write leaves no trace
+	collisions, err := n.checkLookupCollisions(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
 	// Convert to storage format (excludes sensitive fields)
 	storageUser := user.ToStorage()
 
@@ -178,27 +271,36 @@ func (n *natsUserStorage) SetUser(ctx context.Context, user *AutheliaUser) (any,
 	if err != nil {
 		return nil, errs.NewUnexpected("failed to marshal user data", err)
 	}
+	data, err = sealValue(ctx, n.envelope, user.Username, data)
+	if err != nil {
+		return nil, err
+	}
 
 	// user main data
-	_, errPut := n.kvStore[constants.KVBucketNameAutheliaUsers].Put(ctx, user.Username, data)
+	_, errPut := n.users.Put(ctx, user.Username, data)
 	if errPut != nil {
-		return nil, errs.NewUnexpected("failed to set user in NATS KV", errPut)
+		return nil, errs.NewUnexpected("failed to set user in "+n.backend, errPut)
 	}
 
 	// lookup keys
-	errSetLookupKeys := n.setLookupKeys(ctx, user)
+	errSetLookupKeys := n.setLookupKeys(ctx, user, collisions)
 	if errSetLookupKeys != nil {
-		return nil, errs.NewUnexpected("failed to set lookup keys in NATS KV", errSetLookupKeys)
+		return nil, errs.NewUnexpected("failed to set lookup keys in "+n.backend, errSetLookupKeys)
 	}
 
 	return user, nil
 }
 
-func (n *natsUserStorage) UpdateUserWithRevision(ctx context.Context, user *AutheliaUser, revision uint64) error {
+func (n *kvUserStorage) UpdateUserWithRevision(ctx context.Context, user *AutheliaUser, revision uint64) error {
 
 	// Update timestamp
 	user.UpdatedAt = time.Now()
 
+	collisions, err := n.checkLookupCollisions(ctx, user)
+	if err != nil {
+		return err
+	}
+
 	// Convert to storage format (excludes sensitive fields)
 	storageUser := user.ToStorage()
 
@@ -206,22 +308,26 @@ func (n *natsUserStorage) UpdateUserWithRevision(ctx context.Context, user *Auth
 	if err != nil {
 		return errs.NewUnexpected("failed to marshal user data", err)
 	}
+	data, err = sealValue(ctx, n.envelope, user.Username, data)
+	if err != nil {
+		return err
+	}
 
 	// Use Update instead of Put to ensure optimistic locking with revision
-	_, errUpdate := n.kvStore[constants.KVBucketNameAutheliaUsers].Update(ctx, user.Username, data, revision)
+	_, errUpdate := n.users.Update(ctx, user.Username, data, revision)
 	if errUpdate != nil {
-		if errors.Is(errUpdate, jetstream.ErrKeyNotFound) {
-			return errs.NewNotFound("user not found for update")
+		if errors.Is(errUpdate, errKeyNotFound) {
+			return errs.NewUserNotFound("user not found for update")
 		}
-		// NATS returns an error if the revision doesn't match (concurrent modification)
+		// the bucket returns an error if the revision doesn't match (concurrent modification)
 		return errs.NewConflict("user has been modified by another process, please retry", errUpdate)
 	}
 
 	// lookup keys - these are not subject to revision control as they're separate keys
 	// lookup keys
-	errSetLookupKeys := n.setLookupKeys(ctx, user)
+	errSetLookupKeys := n.setLookupKeys(ctx, user, collisions)
 	if errSetLookupKeys != nil {
-		return errs.NewUnexpected("failed to set lookup keys in NATS KV", errSetLookupKeys)
+		return errs.NewUnexpected("failed to set lookup keys in "+n.backend, errSetLookupKeys)
 	}
 
 	return nil
@@ -229,7 +335,7 @@ func (n *natsUserStorage) UpdateUserWithRevision(ctx context.Context, user *Auth
 
 // CreateVerificationCode stores a verification code (OTP) for an email address in the email OTP bucket
 // The key is the email address and the value is the OTP code as a string
-func (n *natsUserStorage) CreateVerificationCode(ctx context.Context, email, otp string) error {
+func (n *kvUserStorage) CreateVerificationCode(ctx context.Context, email, otp string) error {
 	if email == "" {
 		return errs.NewUnexpected("email is required")
 	}
@@ -239,9 +345,9 @@ func (n *natsUserStorage) CreateVerificationCode(ctx context.Context, email, otp
 
 	// Store the OTP as a simple string value
 	// The TTL is configured in the bucket itself (5 minutes by default)
-	_, errPut := n.kvStore[constants.KVBucketNameAutheliaEmailOTP].Put(ctx, email, []byte(otp))
+	_, errPut := n.otps.Put(ctx, email, []byte(otp))
 	if errPut != nil {
-		return errs.NewUnexpected("failed to store verification code in NATS KV", errPut)
+		return errs.NewUnexpected("failed to store verification code in "+n.backend, errPut)
 	}
 
 	slog.InfoContext(ctx, "verification code stored successfully",
@@ -253,20 +359,20 @@ func (n *natsUserStorage) CreateVerificationCode(ctx context.Context, email, otp
 
 // GetVerificationCode retrieves a verification code (OTP) for an email address from the email OTP bucket
 // Returns the OTP as a string
-func (n *natsUserStorage) GetVerificationCode(ctx context.Context, email string) (string, error) {
+func (n *kvUserStorage) GetVerificationCode(ctx context.Context, email string) (string, error) {
 	if email == "" {
 		return "", errs.NewUnexpected("email is required")
 	}
 
-	entry, err := n.kvStore[constants.KVBucketNameAutheliaEmailOTP].Get(ctx, email)
+	value, _, err := n.otps.Get(ctx, email)
 	if err != nil {
-		if errors.Is(err, jetstream.ErrKeyNotFound) {
+		if errors.Is(err, errKeyNotFound) {
 			return "", errs.NewNotFound("verification code not found or expired")
 		}
-		return "", errs.NewUnexpected("failed to get verification code from NATS KV", err)
+		return "", errs.NewUnexpected("failed to get verification code from "+n.backend, err)
 	}
 
-	otp := string(entry.Value())
+	otp := string(value)
 
 	slog.InfoContext(ctx, "verification code retrieved successfully",
 		"email", email,
@@ -276,26 +382,38 @@ func (n *natsUserStorage) GetVerificationCode(ctx context.Context, email string)
 }
 
 // BuildLookupKey builds the lookup key for the given lookup key and key
-func (n *natsUserStorage) BuildLookupKey(ctx context.Context, lookupKey, key string) string {
+func (n *kvUserStorage) BuildLookupKey(ctx context.Context, lookupKey, key string) string {
+	return buildLookupKey(lookupKey, key)
+}
+
+func buildLookupKey(lookupKey, key string) string {
 	prefix := fmt.Sprintf(constants.KVLookupPrefixAuthelia, lookupKey)
 	return fmt.Sprintf("%s/%s", prefix, key)
 }
 
-// newNATSUserStorage creates a new NATS-based user storage
-func newNATSUserStorage(ctx context.Context, natsClient *nats.NATSClient) (internalStorageReaderWriter, error) {
+// newNATSUserStorage creates a new NATS-based user storage. With archive set,
+// inactive users are moved to the archive bucket.
+func newNATSUserStorage(ctx context.Context, natsClient *nats.NATSClient, envelope *encryption.Envelope, archive bool) (UserStore, error) {
 	// Get the KV store for authelia users
-	kvStores := make(map[string]jetstream.KeyValue)
-	for _, bucketName := range []string{constants.KVBucketNameAutheliaUsers, constants.KVBucketNameAutheliaEmailOTP} {
+	bucketNames := []string{constants.KVBucketNameAutheliaUsers, constants.KVBucketNameAutheliaEmailOTP}
+	if archive {
+		bucketNames = append(bucketNames, constants.KVBucketNameAutheliaUsersArchive)
+	}
+	buckets := make(map[string]kvBucket)
+	for _, bucketName := range bucketNames {
 		kvStore, exists := natsClient.GetKVStore(bucketName)
 		if !exists {
 			return nil, errs.NewUnexpected("KV bucket not found in NATS client")
 		}
-		kvStores[bucketName] = kvStore
+		buckets[bucketName] = natsBucket{kv: kvStore}
 	}
-	slog.DebugContext(ctx, "created NATS user storage", "kvStores", kvStores)
-
-	return &natsUserStorage{
-		natsClient: natsClient,
-		kvStore:    kvStores,
+	slog.DebugContext(ctx, "created NATS user storage", "encrypted", envelope != nil, "archive", archive)
+
+	return &kvUserStorage{
+		users:    buckets[constants.KVBucketNameAutheliaUsers],
+		otps:     buckets[constants.KVBucketNameAutheliaEmailOTP],
+		archive:  buckets[constants.KVBucketNameAutheliaUsersArchive],
+		backend:  "NATS KV",
+		envelope: envelope,
 	}, nil
 }
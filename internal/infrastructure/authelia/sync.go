@@ -7,6 +7,7 @@ import (
 	"context"
 	"encoding/json"
 	"log/slog"
+	"slices"
 	"strings"
 
 	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/concurrent"
@@ -79,7 +80,7 @@ func (s *sync) compareUsers(storage, orchestrator map[string]*AutheliaUser) map[
 
 }
 
-func (s *sync) loadUsers(ctx context.Context, storage internalStorageReaderWriter, orchestrator internalOrchestrator) error {
+func (s *sync) loadUsers(ctx context.Context, storage UserStore, orchestrator internalOrchestrator) error {
 
 	functions := []func() error{
 		// get users from NATS KV
@@ -139,7 +140,28 @@ func (s *sync) loadUsers(ctx context.Context, storage internalStorageReaderWrite
 	return concurrent.NewWorkerPool(len(functions)).Run(ctx, functions...)
 }
 
-func (s *sync) syncUsers(ctx context.Context, storage internalStorageReaderWriter, orchestrator internalOrchestrator) error {
+// syncGroups copies group changes made in the users database to the stored
+// records. Groups are managed by operators in the ConfigMap, unlike the other
+// attributes, for which the storage is the source of truth.
+func (s *sync) syncGroups(ctx context.Context, storage UserStore) error {
+	for username, orchestratorUser := range s.userOrchestratorMap {
+		storageUser, exists := s.usersStorageMap[username]
+		if !exists || slices.Equal(storageUser.Groups, orchestratorUser.Groups) {
+			continue
+		}
+
+		slog.DebugContext(ctx, "updating user groups from ConfigMap", "username", username)
+		storageUser.SetUsername(username)
+		storageUser.Groups = orchestratorUser.Groups
+		if _, errUpdate := storage.SetUser(ctx, storageUser); errUpdate != nil {
+			slog.ErrorContext(ctx, "failed to update user groups in storage", "error", errUpdate)
+			return errors.NewUnexpected("failed to update user groups in storage", errUpdate)
+		}
+	}
+	return nil
+}
+
+func (s *sync) syncUsers(ctx context.Context, storage UserStore, orchestrator internalOrchestrator) error {
 
 	errLoadUsers := s.loadUsers(ctx, storage, orchestrator)
 	if errLoadUsers != nil {
@@ -147,6 +169,10 @@ func (s *sync) syncUsers(ctx context.Context, storage internalStorageReaderWrite
 		return errLoadUsers
 	}
 
+	if errGroups := s.syncGroups(ctx, storage); errGroups != nil {
+		return errGroups
+	}
+
 	updateOrchestratorOrigin := false
 	changedSecretsEntries := make(map[string][]byte)
 
@@ -159,6 +185,16 @@ func (s *sync) syncUsers(ctx context.Context, storage internalStorageReaderWrite
 
 		switch user.actionNeeded {
 		case actionNeededStorageCreation:
+			// an archived user is restored with its full record when it is
+			// next read, not recreated from the few fields of the ConfigMap
+			archived, errArchived := archivedInStorage(ctx, storage, username)
+			if errArchived != nil {
+				slog.WarnContext(ctx, "failed to check the user archive, skipping user", "error", errArchived)
+				continue
+			}
+			if archived {
+				continue
+			}
 			_, errUpdate := storage.SetUser(ctx, user)
 			if errUpdate != nil {
 				slog.ErrorContext(ctx, "failed to update user in storage", "error", errUpdate)
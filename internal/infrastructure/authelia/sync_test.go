@@ -6,6 +6,7 @@ package authelia
 import (
 	"context"
 	"errors"
+	"slices"
 	"testing"
 
 	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
@@ -636,3 +637,65 @@ func TestSync_SyncUsers_StorageCreation(t *testing.T) {
 		t.Error("syncUsers() should not update orchestrator for storage creation")
 	}
 }
+
+func TestSync_SyncUsers_Groups(t *testing.T) {
+	ctx := context.Background()
+
+	storageUsers := map[string]*AutheliaUser{
+		"user1": {
+			User:   &model.User{Username: "user1", PrimaryEmail: "user1@example.com"},
+			Email:  "user1@example.com",
+			Groups: []string{"dev"},
+		},
+		"user2": {
+			User:   &model.User{Username: "user2", PrimaryEmail: "user2@example.com"},
+			Email:  "user2@example.com",
+			Groups: []string{"ops"},
+		},
+	}
+	orchestratorUsers := map[string]any{
+		"users": map[string]any{
+			"user1": map[string]any{
+				"password": "hash1",
+				"email":    "user1@example.com",
+				"groups":   []any{"dev", "admins"},
+			},
+			"user2": map[string]any{
+				"password": "hash2",
+				"email":    "user2@example.com",
+				"groups":   []any{"ops"},
+			},
+		},
+	}
+
+	s := &sync{}
+	mockStorage := &mockStorageReaderWriter{users: storageUsers}
+	mockOrch := &mockOrchestrator{users: orchestratorUsers}
+
+	if err := s.syncUsers(ctx, mockStorage, mockOrch); err != nil {
+		t.Fatalf("syncUsers() failed: %v", err)
+	}
+
+	if got := mockStorage.users["user1"].Groups; !slices.Equal(got, []string{"dev", "admins"}) {
+		t.Errorf("user1 groups = %v, want the ConfigMap groups", got)
+	}
+	if mockStorage.users["user1"].PrimaryEmail != "user1@example.com" {
+		t.Error("syncUsers() should keep the stored record when copying groups")
+	}
+	if got := mockStorage.users["user2"].Groups; !slices.Equal(got, []string{"ops"}) {
+		t.Errorf("user2 groups = %v, want unchanged", got)
+	}
+	if mockOrch.updateOriginCalled {
+		t.Error("syncUsers() should not rewrite the ConfigMap for group changes")
+	}
+}
+
+func TestAutheliaUser_ToAutheliaYAML_Groups(t *testing.T) {
+	user := &AutheliaUser{User: &model.User{Username: "user1"}, Email: "user1@example.com", Groups: []string{"admins"}}
+	if got := user.ToAutheliaYAML().Groups; !slices.Equal(got, []string{"admins"}) {
+		t.Errorf("ToAutheliaYAML() groups = %v, want [admins]", got)
+	}
+	if got := user.ToStorage().Groups; !slices.Equal(got, []string{"admins"}) {
+		t.Errorf("ToStorage() groups = %v, want [admins]", got)
+	}
+}
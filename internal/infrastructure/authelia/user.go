@@ -17,6 +17,8 @@ import (
 	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/nats"
 	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/collections"
 	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/deadline"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/encryption"
 	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
 	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/httpclient"
 	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/jwt"
@@ -27,27 +29,63 @@ import (
 type userReaderWriter struct {
 	oidcUserInfoURL  string
 	sync             *sync
-	storage          internalStorageReaderWriter
+	storage          UserStore
 	orchestrator     internalOrchestrator
 	emailLinkingFlow passwordlessFlow
 	httpClient       *httpclient.Client
+	envelope         *encryption.Envelope
+	// groupPermissions maps groups to the permissions they grant
+	groupPermissions map[string][]string
+	// userInfo caches userinfo responses by token; nil disables caching
+	userInfo *userInfoCache
+	// discovery derives the OIDC endpoints from the issuer; nil uses oidcUserInfoURL
+	discovery *oidcDiscoverer
+	// redisStore keeps the storage in Redis; nil uses the NATS KV buckets
+	redisStore *RedisStoreConfig
+	// postgresStore keeps the storage in Postgres
+	postgresStore *PostgresStoreConfig
+	// archive moves inactive users out of the KV storage
+	archive bool
+	// rejectLookupCollisions refuses writes taking another user's lookup keys
+	rejectLookupCollisions bool
+	// timeouts bound the operations on the user store
+	timeouts deadline.Budget
 }
 
-// fetchOIDCUserInfo fetches user information from the OIDC userinfo endpoint
+// DefaultTimeouts are the operation timeouts of the user store. Updates also
+// sync the users to the orchestrator, and jobs scan every user record.
+var DefaultTimeouts = deadline.Budget{
+	deadline.OperationSearch: 5 * time.Second,
+	deadline.OperationGet:    5 * time.Second,
+	deadline.OperationUpdate: 15 * time.Second,
+	deadline.OperationJobs:   5 * time.Minute,
+}
+
+// fetchOIDCUserInfo fetches user information from the OIDC userinfo endpoint,
+// or from the userinfo cache when enabled
 func (a *userReaderWriter) fetchOIDCUserInfo(ctx context.Context, token string) (*OIDCUserInfo, error) {
 	if strings.TrimSpace(token) == "" {
 		return nil, errs.NewValidation("token is required")
 	}
 
-	if strings.TrimSpace(a.oidcUserInfoURL) == "" {
+	if strings.TrimSpace(a.userInfoURL()) == "" {
 		return nil, errs.NewValidation("OIDC userinfo URL is not configured")
 	}
 
+	if a.userInfo != nil {
+		return a.userInfo.get(ctx, token, a.timeouts[deadline.OperationGet], a.requestOIDCUserInfo)
+	}
+	return a.requestOIDCUserInfo(ctx, token)
+}
+
+// requestOIDCUserInfo calls the OIDC userinfo endpoint
+func (a *userReaderWriter) requestOIDCUserInfo(ctx context.Context, token string) (*OIDCUserInfo, error) {
+
 	// Create API request using the standard pattern
 	apiRequest := httpclient.NewAPIRequest(
 		a.httpClient,
 		httpclient.WithMethod(http.MethodGet),
-		httpclient.WithURL(a.oidcUserInfoURL),
+		httpclient.WithURL(a.userInfoURL()),
 		httpclient.WithToken(token),
 		httpclient.WithDescription("fetch OIDC userinfo"),
 	)
@@ -58,9 +96,9 @@ func (a *userReaderWriter) fetchOIDCUserInfo(ctx context.Context, token string)
 		slog.ErrorContext(ctx, "failed to fetch OIDC userinfo",
 			"error", err,
 			"status_code", statusCode,
-			"url", a.oidcUserInfoURL,
+			"url", a.userInfoURL(),
 		)
-		return nil, httpclient.ErrorFromStatusCode(statusCode, fmt.Sprintf("failed to fetch OIDC userinfo: %v", err))
+		return nil, userInfoError(statusCode, fmt.Sprintf("failed to fetch OIDC userinfo: %v", err))
 	}
 
 	return &userInfo, nil
@@ -68,6 +106,8 @@ func (a *userReaderWriter) fetchOIDCUserInfo(ctx context.Context, token string)
 
 // SearchUser searches for a user in storage
 func (a *userReaderWriter) SearchUser(ctx context.Context, user *model.User, criteria string) (*model.User, error) {
+	ctx, cancel := a.timeouts.Bound(ctx, deadline.OperationSearch)
+	defer cancel()
 
 	if user == nil {
 		return nil, errs.NewValidation("user is required")
@@ -94,6 +134,15 @@ func (a *userReaderWriter) SearchUser(ctx context.Context, user *model.User, cri
 				return a.storage.BuildLookupKey(ctx, "email", user.BuildAlternateEmailIndexKey(ctx, alternateEmail.Email))
 			}
 			return ""
+		case constants.CriteriaTypeSecondaryEmail:
+			for _, secondaryEmail := range user.SecondaryEmails {
+				slog.DebugContext(ctx, "searching user",
+					"criteria", criteria,
+					"secondary_email", redaction.RedactEmail(secondaryEmail.Email),
+				)
+				return a.storage.BuildLookupKey(ctx, "email", user.BuildSecondaryEmailIndexKey(ctx, secondaryEmail.Email))
+			}
+			return ""
 		case constants.CriteriaTypeUsername:
 			slog.DebugContext(ctx, "searching user",
 				"criteria", criteria,
@@ -123,6 +172,8 @@ func (a *userReaderWriter) SearchUser(ctx context.Context, user *model.User, cri
 
 // GetUser retrieves a user from storage
 func (a *userReaderWriter) GetUser(ctx context.Context, user *model.User) (*model.User, error) {
+	ctx, cancel := a.timeouts.Bound(ctx, deadline.OperationGet)
+	defer cancel()
 
 	if user == nil {
 		return nil, errs.NewValidation("user is required")
@@ -195,6 +246,9 @@ func (u *userReaderWriter) MetadataLookup(ctx context.Context, input string, req
 
 // UpdateUser updates a user only in storage with patch-like behavior, updating only changed fields
 func (a *userReaderWriter) UpdateUser(ctx context.Context, user *model.User) (*model.User, error) {
+	ctx, cancel := a.timeouts.Bound(ctx, deadline.OperationUpdate)
+	defer cancel()
+
 	if user == nil {
 		return nil, errs.NewValidation("user is required")
 	}
@@ -360,6 +414,9 @@ func (a *userReaderWriter) ValidateLinkRequest(ctx context.Context, _ *model.Lin
 
 // LinkIdentity links a secondary identity onto an Authelia-backed primary user.
 func (a *userReaderWriter) LinkIdentity(ctx context.Context, request *model.LinkIdentity) error {
+	ctx, cancel := a.timeouts.Bound(ctx, deadline.OperationUpdate)
+	defer cancel()
+
 	if request == nil {
 		return errs.NewValidation("request is required")
 	}
@@ -496,6 +553,9 @@ func (a *userReaderWriter) linkSocialIdentity(ctx context.Context, request *mode
 
 // UnlinkIdentity unlinks an identity from an Authelia-backed primary user.
 func (a *userReaderWriter) UnlinkIdentity(ctx context.Context, request *model.UnlinkIdentity) error {
+	ctx, cancel := a.timeouts.Bound(ctx, deadline.OperationUpdate)
+	defer cancel()
+
 	if request == nil {
 		return errs.NewValidation("request is required")
 	}
@@ -567,8 +627,94 @@ func (a *userReaderWriter) AddSystemManagedEmail(_ context.Context, _, _ string)
 	return "", errs.NewValidation("add system managed email is not supported for Authelia users")
 }
 
+// ReencryptStorage rewrites stored records that are not sealed with the active
+// encryption key. It implements port.StorageReencrypter.
+func (a *userReaderWriter) ReencryptStorage(ctx context.Context) (model.ReencryptResult, error) {
+	reencrypter, ok := a.storage.(port.StorageReencrypter)
+	if !ok {
+		return model.ReencryptResult{}, errs.NewValidation("user storage does not support re-encryption")
+	}
+	return reencrypter.ReencryptStorage(ctx)
+}
+
+// ExportUsers returns every user in the user store. It implements
+// port.UserExporter.
+func (a *userReaderWriter) ExportUsers(ctx context.Context) ([]*model.User, error) {
+	ctx, cancel := a.timeouts.Bound(ctx, deadline.OperationJobs)
+	defer cancel()
+
+	users, err := a.storage.ListUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	exported := make([]*model.User, 0, len(users))
+	for _, user := range users {
+		// a merged account lives on in the one it was merged into
+		if user.User == nil || user.MergedInto != "" {
+			continue
+		}
+		// the display name stands in for a profile name that was never set
+		if user.SearchName() == "" && user.DisplayName != "" {
+			if user.UserMetadata == nil {
+				user.UserMetadata = &model.UserMetadata{}
+			}
+			user.UserMetadata.Name = &user.DisplayName
+		}
+		exported = append(exported, user.User)
+	}
+	return exported, nil
+}
+
+// SearchUserCandidates scans the user records in the user store for users whose
+// username, email or name contains query. It implements port.UserSearcher.
+func (a *userReaderWriter) SearchUserCandidates(ctx context.Context, query string, limit int) ([]*model.User, error) {
+	ctx, cancel := a.timeouts.Bound(ctx, deadline.OperationSearch)
+	defer cancel()
+
+	if query == "" {
+		return nil, errs.NewValidation("query is required")
+	}
+
+	users, err := a.ExportUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []*model.User
+	for _, user := range users {
+		if !user.MatchesSearch(query) {
+			continue
+		}
+		candidates = append(candidates, user)
+		if limit > 0 && len(candidates) == limit {
+			break
+		}
+	}
+	return candidates, nil
+}
+
+// Option configures the Authelia user repository
+type Option func(*userReaderWriter)
+
+// WithHTTPClientConfig sets the HTTP client used for calls to Authelia, e.g.
+// to trust a private CA or present a client certificate.
+func WithHTTPClientConfig(config httpclient.Config) Option {
+	return func(u *userReaderWriter) {
+		u.httpClient = httpclient.NewClient(config)
+	}
+}
+
+// WithOperationTimeouts overrides DefaultTimeouts for the operations of
+// timeouts set to a positive duration
+func WithOperationTimeouts(timeouts deadline.Budget) Option {
+	return func(u *userReaderWriter) {
+		u.timeouts = DefaultTimeouts.With(timeouts)
+	}
+}
+
 // NewUserReaderWriter creates a new Authelia User repository
-func NewUserReaderWriter(ctx context.Context, config map[string]string, natsClient *nats.NATSClient) (port.UserReaderWriter, error) {
+func NewUserReaderWriter(ctx context.Context, config map[string]string, natsClient *nats.NATSClient, opts ...Option) (port.UserReaderWriter, error) {
 	// Set defaults in case of not set
 
 	u := &userReaderWriter{
@@ -576,14 +722,39 @@ func NewUserReaderWriter(ctx context.Context, config map[string]string, natsClie
 		oidcUserInfoURL:  config["oidc-userinfo-url"],
 		emailLinkingFlow: newEmailLinkingFlow(),
 		httpClient:       httpclient.NewClient(httpclient.DefaultConfig()),
+		timeouts:         DefaultTimeouts,
+	}
+	for _, opt := range opts {
+		opt(u)
 	}
 
-	// Initialize storage using NATS KV store
+	if u.discovery != nil {
+		if err := u.discovery.start(ctx, u.httpClient); err != nil {
+			slog.ErrorContext(ctx, "failed to discover OIDC endpoints", "error", err)
+			return nil, err
+		}
+	}
+
+	// Initialize storage using the configured store, the NATS KV store by default
 	if u.storage == nil {
-		storage, errNATSUserStorage := newNATSUserStorage(ctx, natsClient)
-		if errNATSUserStorage != nil {
-			slog.ErrorContext(ctx, "failed to create storage", "error", errNATSUserStorage)
-			return nil, errNATSUserStorage
+		var storage UserStore
+		var errUserStorage error
+		switch {
+		case u.postgresStore != nil && u.archive:
+			errUserStorage = errs.NewValidation("user archival is only supported by the NATS and Redis user stores")
+		case u.postgresStore != nil:
+			storage, errUserStorage = newPostgresUserStorage(ctx, *u.postgresStore, u.envelope)
+		case u.redisStore != nil:
+			storage, errUserStorage = newRedisUserStorage(ctx, *u.redisStore, u.envelope, u.archive)
+		default:
+			storage, errUserStorage = newNATSUserStorage(ctx, natsClient, u.envelope, u.archive)
+		}
+		if errUserStorage != nil {
+			slog.ErrorContext(ctx, "failed to create storage", "error", errUserStorage)
+			return nil, errUserStorage
+		}
+		if kv, ok := storage.(*kvUserStorage); ok {
+			kv.rejectCollisions = u.rejectLookupCollisions
 		}
 		u.storage = storage
 	}
@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
 	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/converters"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -242,3 +243,28 @@ func TestUserReaderWriter_MetadataLookup(t *testing.T) {
 		})
 	}
 }
+
+func TestUserReaderWriter_SearchUser_SecondaryEmail(t *testing.T) {
+	ctx := context.Background()
+
+	stored := &AutheliaUser{User: &model.User{Username: "octocat"}}
+	stored.SecondaryEmails = []model.Email{{Email: "octocat@users.example.com", Verified: true}}
+
+	// round-trips through the storage format
+	roundTripped := &AutheliaUser{}
+	roundTripped.FromStorage(stored.ToStorage())
+	assert.Equal(t, stored.SecondaryEmails, roundTripped.SecondaryEmails)
+
+	lookup := &model.User{SecondaryEmails: []model.Email{{Email: "OctoCat@users.example.com"}}}
+	storage := &mockStorageReaderWriter{users: map[string]*AutheliaUser{
+		"email:" + lookup.BuildSecondaryEmailIndexKey(ctx, "octocat@users.example.com"): roundTripped,
+	}}
+	rw := &userReaderWriter{storage: storage}
+
+	got, err := rw.SearchUser(ctx, lookup, constants.CriteriaTypeSecondaryEmail)
+	require.NoError(t, err)
+	assert.Equal(t, "octocat", got.Username)
+
+	_, err = rw.SearchUser(ctx, &model.User{}, constants.CriteriaTypeSecondaryEmail)
+	assert.Error(t, err)
+}
@@ -0,0 +1,119 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package authelia
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/cache"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/tenant"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"golang.org/x/sync/singleflight"
+)
+
+// meter is safe to initialize at package level — otel.Meter() delegates to
+// whichever MeterProvider is installed when instruments record.
+var meter = otel.Meter("github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/authelia")
+
+// userInfoResult is a cached userinfo response: the user behind a valid
+// token, or the error Authelia rejected an invalid one with
+type userInfoResult struct {
+	userInfo *OIDCUserInfo
+	err      error
+}
+
+// userInfoCache remembers userinfo responses by token hash, so a dashboard
+// polling with the same opaque token costs one Authelia call per TTL.
+// Rejected tokens are remembered too, for a shorter time, so a client stuck
+// on an expired token doesn't hammer Authelia either.
+type userInfoCache struct {
+	results     *cache.Cache[string, userInfoResult]
+	ttl         time.Duration
+	negativeTTL time.Duration
+	inflight    singleflight.Group
+	lookups     metric.Int64Counter
+}
+
+// WithUserInfoCache caches userinfo responses for ttl, and rejected tokens
+// for negativeTTL, keeping at most maxEntries tokens. A ttl of 0 disables
+// the cache; a negativeTTL of 0 only caches valid tokens.
+func WithUserInfoCache(ttl, negativeTTL time.Duration, maxEntries int) Option {
+	return func(u *userReaderWriter) {
+		if ttl <= 0 {
+			u.userInfo = nil
+			return
+		}
+		lookups, _ := meter.Int64Counter("auth_service.authelia.userinfo_cache.lookups",
+			metric.WithDescription("Opaque token lookups, by whether the cached userinfo answered them"))
+		u.userInfo = &userInfoCache{
+			results:     cache.New[string, userInfoResult](ttl, maxEntries),
+			ttl:         ttl,
+			negativeTTL: negativeTTL,
+			lookups:     lookups,
+		}
+	}
+}
+
+// get returns the userinfo of token, calling fetch on a miss. Concurrent
+// misses for the same token share one call, which runs detached from the
+// caller that started it and bounded by timeout instead, so one caller
+// giving up doesn't fail the others.
+func (c *userInfoCache) get(ctx context.Context, token string, timeout time.Duration, fetch func(context.Context, string) (*OIDCUserInfo, error)) (*OIDCUserInfo, error) {
+	key := tokenHash(token)
+	if result, ok := c.results.Get(key); ok {
+		outcome := "hit"
+		if result.err != nil {
+			outcome = "negative_hit"
+		}
+		c.lookups.Add(ctx, 1, tenant.Attributes(ctx, attribute.String("result", outcome)))
+		return result.userInfo, result.err
+	}
+	c.lookups.Add(ctx, 1, tenant.Attributes(ctx, attribute.String("result", "miss")))
+
+	shared := c.inflight.DoChan(key, func() (any, error) {
+		fetchCtx := context.WithoutCancel(ctx)
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			fetchCtx, cancel = context.WithTimeout(fetchCtx, timeout)
+			defer cancel()
+		}
+
+		userInfo, err := fetch(fetchCtx, token)
+		switch {
+		case err == nil:
+			c.results.SetWithTTL(key, userInfoResult{userInfo: userInfo}, c.ttl)
+		case fetchCtx.Err() == nil && isInvalidToken(err) && c.negativeTTL > 0:
+			c.results.SetWithTTL(key, userInfoResult{err: err}, c.negativeTTL)
+		}
+		return userInfo, err
+	})
+	select {
+	case result := <-shared:
+		userInfo, _ := result.Val.(*OIDCUserInfo)
+		return userInfo, result.Err
+	case <-ctx.Done():
+		return nil, errs.NewServiceUnavailable("OIDC userinfo lookup cancelled", ctx.Err())
+	}
+}
+
+// isInvalidToken reports whether Authelia rejected the token itself; other
+// failures, such as Authelia being down or the fetch timing out, aren't
+// cached
+func isInvalidToken(err error) bool {
+	var unauthorized errs.Unauthorized
+	return errors.As(err, &unauthorized)
+}
+
+// tokenHash keys the cache, so tokens aren't kept in memory in the clear
+func tokenHash(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,164 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package authelia
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/httpclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserInfoCache(t *testing.T) {
+	ctx := context.Background()
+
+	var calls atomic.Int32
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		switch {
+		case status != http.StatusOK:
+			w.WriteHeader(status)
+		case r.Header.Get("Authorization") == "Bearer authelia_valid":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"sub":"0b4f3c1e-9d4a-4e53-8f0a-3a6c2f1b7d10","preferred_username":"jdoe"}`))
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	newRepository := func(opts ...Option) *userReaderWriter {
+		config := httpclient.DefaultConfig()
+		config.MaxRetries = 0
+		u := &userReaderWriter{oidcUserInfoURL: server.URL, httpClient: httpclient.NewClient(config)}
+		for _, opt := range opts {
+			opt(u)
+		}
+		return u
+	}
+
+	t.Run("caches valid tokens", func(t *testing.T) {
+		calls.Store(0)
+		u := newRepository(WithUserInfoCache(time.Minute, time.Minute, 10))
+		for range 3 {
+			user, err := u.MetadataLookup(ctx, "authelia_valid")
+			require.NoError(t, err)
+			assert.Equal(t, "jdoe", user.Username)
+		}
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("caches rejected tokens", func(t *testing.T) {
+		calls.Store(0)
+		u := newRepository(WithUserInfoCache(time.Minute, time.Minute, 10))
+		for range 3 {
+			_, err := u.MetadataLookup(ctx, "authelia_expired")
+			assert.ErrorAs(t, err, &errors.Unauthorized{})
+		}
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("negative caching can be disabled", func(t *testing.T) {
+		calls.Store(0)
+		u := newRepository(WithUserInfoCache(time.Minute, 0, 10))
+		for range 2 {
+			_, err := u.MetadataLookup(ctx, "authelia_expired")
+			assert.Error(t, err)
+		}
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("doesn't cache Authelia failures", func(t *testing.T) {
+		calls.Store(0)
+		status = http.StatusServiceUnavailable
+		defer func() { status = http.StatusOK }()
+		u := newRepository(WithUserInfoCache(time.Minute, time.Minute, 10))
+		for range 2 {
+			_, err := u.MetadataLookup(ctx, "authelia_valid")
+			assert.Error(t, err)
+		}
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		calls.Store(0)
+		u := newRepository(WithUserInfoCache(0, time.Minute, 10))
+		for range 2 {
+			_, err := u.MetadataLookup(ctx, "authelia_valid")
+			require.NoError(t, err)
+		}
+		assert.Equal(t, int32(2), calls.Load())
+	})
+}
+
+func TestUserInfoCache_SharedFetch(t *testing.T) {
+	newCache := func() *userInfoCache {
+		u := &userReaderWriter{}
+		WithUserInfoCache(time.Minute, time.Minute, 10)(u)
+		return u.userInfo
+	}
+
+	t.Run("survives the first caller giving up", func(t *testing.T) {
+		c := newCache()
+		release := make(chan struct{})
+		started := make(chan struct{})
+		var calls atomic.Int32
+		fetch := func(ctx context.Context, _ string) (*OIDCUserInfo, error) {
+			if calls.Add(1) == 1 {
+				close(started)
+			}
+			<-release
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			return &OIDCUserInfo{Sub: "jdoe"}, nil
+		}
+
+		first, cancel := context.WithCancel(context.Background())
+		firstErr := make(chan error, 1)
+		go func() {
+			_, err := c.get(first, "authelia_valid", time.Minute, fetch)
+			firstErr <- err
+		}()
+		<-started
+
+		second := make(chan *OIDCUserInfo, 1)
+		go func() {
+			userInfo, _ := c.get(context.Background(), "authelia_valid", time.Minute, fetch)
+			second <- userInfo
+		}()
+
+		cancel()
+		assert.ErrorAs(t, <-firstErr, &errors.ServiceUnavailable{})
+		close(release)
+
+		userInfo := <-second
+		require.NotNil(t, userInfo)
+		assert.Equal(t, "jdoe", userInfo.Sub)
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("doesn't cache a timed out fetch", func(t *testing.T) {
+		c := newCache()
+		var calls atomic.Int32
+		fetch := func(ctx context.Context, _ string) (*OIDCUserInfo, error) {
+			calls.Add(1)
+			<-ctx.Done()
+			return nil, errors.NewUnauthorized("failed to fetch OIDC userinfo: " + ctx.Err().Error())
+		}
+		for range 2 {
+			_, err := c.get(context.Background(), "authelia_slow", 10*time.Millisecond, fetch)
+			assert.Error(t, err)
+		}
+		assert.Equal(t, int32(2), calls.Load())
+	})
+}
@@ -0,0 +1,67 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package disk keeps state on the local disk of a replica, for deployments
+// giving it a volume that outlives the process.
+package disk
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+)
+
+// snapshotStore implements port.SnapshotStore on a file
+type snapshotStore struct {
+	path string
+}
+
+// LoadSnapshot returns the saved snapshot, nil before the first save
+func (s *snapshotStore) LoadSnapshot(context.Context) ([]byte, error) {
+	snapshot, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errs.NewUnexpected("failed to load the cache snapshot", err)
+	}
+	return snapshot, nil
+}
+
+// SaveSnapshot replaces the saved snapshot. It is written next to the file
+// and renamed over it, so a replica stopped mid-save leaves the previous
+// snapshot whole.
+func (s *snapshotStore) SaveSnapshot(_ context.Context, snapshot []byte) error {
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return errs.NewUnexpected("failed to create the cache snapshot directory", err)
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(s.path)+".*")
+	if err != nil {
+		return errs.NewUnexpected("failed to save the cache snapshot", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(snapshot)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), s.path)
+	}
+	if err != nil {
+		return errs.NewUnexpected("failed to save the cache snapshot", err)
+	}
+	return nil
+}
+
+// NewSnapshotStore returns a snapshot store keeping its snapshot in the file
+// at path
+func NewSnapshotStore(path string) port.SnapshotStore {
+	return &snapshotStore{path: path}
+}
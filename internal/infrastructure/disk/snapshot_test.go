@@ -0,0 +1,34 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package disk
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotStore(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "cache")
+	store := NewSnapshotStore(filepath.Join(dir, "identifiers.json"))
+
+	snapshot, err := store.LoadSnapshot(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, snapshot, "nothing is saved yet")
+
+	require.NoError(t, store.SaveSnapshot(ctx, []byte(`{"a":1}`)))
+	require.NoError(t, store.SaveSnapshot(ctx, []byte(`{"b":2}`)))
+	snapshot, err = store.LoadSnapshot(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, `{"b":2}`, string(snapshot))
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, 1, "no temporary file is left behind")
+}
@@ -0,0 +1,45 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package kafka
+
+import (
+	"time"
+)
+
+const (
+	// SASLMechanismPlain selects SASL/PLAIN authentication
+	SASLMechanismPlain = "plain"
+	// SASLMechanismSCRAMSHA256 selects SASL/SCRAM-SHA-256 authentication
+	SASLMechanismSCRAMSHA256 = "scram-sha-256"
+	// SASLMechanismSCRAMSHA512 selects SASL/SCRAM-SHA-512 authentication
+	SASLMechanismSCRAMSHA512 = "scram-sha-512"
+)
+
+// Config represents the Kafka producer configuration
+type Config struct {
+	// Brokers is the list of bootstrap broker addresses (host:port)
+	Brokers []string `json:"brokers"`
+	// Topic is the topic all lifecycle events are written to
+	Topic string `json:"topic"`
+	// SASLMechanism is one of plain, scram-sha-256 or scram-sha-512; empty disables SASL
+	SASLMechanism string `json:"sasl_mechanism"`
+	// SASLUsername is the SASL username
+	SASLUsername string `json:"sasl_username"`
+	// SASLPassword is the SASL password
+	SASLPassword string `json:"-"`
+	// TLSEnabled enables TLS for broker connections
+	TLSEnabled bool `json:"tls_enabled"`
+	// TLSCAFile is an optional PEM bundle used instead of the system roots
+	TLSCAFile string `json:"tls_ca_file"`
+	// WriteTimeout bounds a single produce call
+	WriteTimeout time.Duration `json:"write_timeout"`
+	// BatchTimeout is how long a message waits for others to share its batch;
+	// zero uses DefaultBatchTimeout
+	BatchTimeout time.Duration `json:"batch_timeout"`
+}
+
+// eventKey is the subset of an event payload used to pick the message key.
+type eventKey struct {
+	UserID string `json:"user_id"`
+}
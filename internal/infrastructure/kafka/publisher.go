@@ -0,0 +1,165 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package kafka
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/cloudevents"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/tlsconfig"
+
+	kafkago "github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+)
+
+// DefaultBatchTimeout is the batch timeout of the writer when none is set.
+// Publish waits for its message to be written, so kafka-go's default of one
+// second would be added to every event.
+const DefaultBatchTimeout = 10 * time.Millisecond
+
+// messageWriter is the subset of *kafkago.Writer used by the publisher.
+type messageWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafkago.Message) error
+	Close() error
+}
+
+// Publisher writes domain events to a Kafka topic. It implements
+// port.EventPublisher so it can be used wherever the NATS publisher is.
+type Publisher struct {
+	writer messageWriter
+	config Config
+}
+
+// Publish writes the event to the configured topic. The NATS subject becomes
+// the CloudEvents type so consumers can tell events apart on the shared
+// topic, and the payload's user_id (when present) is used as the message key
+// to keep events for the same user ordered within a partition.
+func (p *Publisher) Publish(ctx context.Context, subject string, data []byte) error {
+	if p.config.WriteTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.config.WriteTimeout)
+		defer cancel()
+	}
+
+	if err := p.writer.WriteMessages(ctx, buildMessage(subject, data)); err != nil {
+		slog.ErrorContext(ctx, "failed to write event to kafka",
+			"error", err,
+			"topic", p.config.Topic,
+			"subject", subject,
+		)
+		return errors.NewUnexpected("failed to publish event to kafka", err)
+	}
+	return nil
+}
+
+// Close flushes pending messages and releases broker connections
+func (p *Publisher) Close() error {
+	return p.writer.Close()
+}
+
+func buildMessage(subject string, data []byte) kafkago.Message {
+	headers := cloudevents.New(subject).Headers(cloudevents.KafkaHeaderPrefix)
+	msg := kafkago.Message{
+		Value:   data,
+		Headers: make([]kafkago.Header, 0, len(headers)),
+	}
+	for key, value := range headers {
+		msg.Headers = append(msg.Headers, kafkago.Header{Key: key, Value: []byte(value)})
+	}
+
+	var key eventKey
+	if err := json.Unmarshal(data, &key); err == nil && key.UserID != "" {
+		msg.Key = []byte(key.UserID)
+	}
+	return msg
+}
+
+func saslMechanism(config Config) (sasl.Mechanism, error) {
+	switch strings.ToLower(config.SASLMechanism) {
+	case "":
+		return nil, nil
+	case SASLMechanismPlain:
+		return plain.Mechanism{Username: config.SASLUsername, Password: config.SASLPassword}, nil
+	case SASLMechanismSCRAMSHA256:
+		return scram.Mechanism(scram.SHA256, config.SASLUsername, config.SASLPassword)
+	case SASLMechanismSCRAMSHA512:
+		return scram.Mechanism(scram.SHA512, config.SASLUsername, config.SASLPassword)
+	default:
+		return nil, errors.NewValidation(fmt.Sprintf("unsupported kafka SASL mechanism: %s", config.SASLMechanism))
+	}
+}
+
+func tlsConfig(config Config) (*tls.Config, error) {
+	if !config.TLSEnabled {
+		return nil, nil
+	}
+
+	tlsCfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if config.TLSCAFile != "" {
+		pool, err := tlsconfig.LoadCertPool(config.TLSCAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsCfg.RootCAs = pool
+	}
+	return tlsCfg, nil
+}
+
+// NewPublisher creates a Kafka event publisher. No connection is made until
+// the first event is written.
+func NewPublisher(ctx context.Context, config Config) (*Publisher, error) {
+	if len(config.Brokers) == 0 {
+		return nil, errors.NewValidation("at least one kafka broker is required")
+	}
+	if config.Topic == "" {
+		return nil, errors.NewValidation("kafka topic is required")
+	}
+
+	mechanism, err := saslMechanism(config)
+	if err != nil {
+		return nil, err
+	}
+	tlsCfg, err := tlsConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	batchTimeout := config.BatchTimeout
+	if batchTimeout <= 0 {
+		batchTimeout = DefaultBatchTimeout
+	}
+
+	writer := &kafkago.Writer{
+		Addr:         kafkago.TCP(config.Brokers...),
+		Topic:        config.Topic,
+		Balancer:     &kafkago.Hash{},
+		RequiredAcks: kafkago.RequireAll,
+		BatchTimeout: batchTimeout,
+		Transport: &kafkago.Transport{
+			SASL: mechanism,
+			TLS:  tlsCfg,
+		},
+	}
+
+	slog.InfoContext(ctx, "kafka event publisher configured",
+		"brokers", config.Brokers,
+		"topic", config.Topic,
+		"sasl", config.SASLMechanism != "",
+		"tls", config.TLSEnabled,
+	)
+
+	return &Publisher{writer: writer, config: config}, nil
+}
+
+var _ port.EventPublisher = (*Publisher)(nil)
@@ -0,0 +1,153 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package kafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/cloudevents"
+)
+
+type fakeWriter struct {
+	messages []kafkago.Message
+	err      error
+}
+
+func (f *fakeWriter) WriteMessages(_ context.Context, msgs ...kafkago.Message) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.messages = append(f.messages, msgs...)
+	return nil
+}
+
+func (f *fakeWriter) Close() error { return nil }
+
+func headerMap(msg kafkago.Message) map[string]string {
+	out := make(map[string]string, len(msg.Headers))
+	for _, h := range msg.Headers {
+		out[h.Key] = string(h.Value)
+	}
+	return out
+}
+
+func TestPublisherPublish(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      []byte
+		writerErr error
+		wantKey   string
+		wantErr   bool
+	}{
+		{
+			name:    "keyed by user id",
+			data:    []byte(`{"user_id":"auth0|123","principal":"auth0|123"}`),
+			wantKey: "auth0|123",
+		},
+		{
+			name: "payload without user id is unkeyed",
+			data: []byte(`{"foo":"bar"}`),
+		},
+		{
+			name:      "writer error is surfaced",
+			data:      []byte(`{}`),
+			writerErr: errors.New("broker down"),
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writer := &fakeWriter{err: tt.writerErr}
+			p := &Publisher{writer: writer, config: Config{Topic: "user-events"}}
+
+			err := p.Publish(context.Background(), "lfx.user_profile.updated", tt.data)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, writer.messages, 1)
+
+			msg := writer.messages[0]
+			assert.Equal(t, tt.data, msg.Value)
+			assert.Equal(t, tt.wantKey, string(msg.Key))
+
+			headers := headerMap(msg)
+			assert.Equal(t, "lfx.user_profile.updated", headers[cloudevents.KafkaHeaderPrefix+"type"])
+			assert.Equal(t, cloudevents.SpecVersion, headers[cloudevents.KafkaHeaderPrefix+"specversion"])
+			assert.NotEmpty(t, headers[cloudevents.KafkaHeaderPrefix+"id"])
+		})
+	}
+}
+
+func TestNewPublisher(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{
+			name:   "minimal config",
+			config: Config{Brokers: []string{"localhost:9092"}, Topic: "user-events"},
+		},
+		{
+			name:   "scram sha-512",
+			config: Config{Brokers: []string{"localhost:9092"}, Topic: "user-events", SASLMechanism: "SCRAM-SHA-512", SASLUsername: "u", SASLPassword: "p"},
+		},
+		{
+			name:   "tls with system roots",
+			config: Config{Brokers: []string{"localhost:9093"}, Topic: "user-events", TLSEnabled: true},
+		},
+		{
+			name:    "missing brokers",
+			config:  Config{Topic: "user-events"},
+			wantErr: true,
+		},
+		{
+			name:    "missing topic",
+			config:  Config{Brokers: []string{"localhost:9092"}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown sasl mechanism",
+			config:  Config{Brokers: []string{"localhost:9092"}, Topic: "user-events", SASLMechanism: "gssapi"},
+			wantErr: true,
+		},
+		{
+			name:    "missing CA file",
+			config:  Config{Brokers: []string{"localhost:9093"}, Topic: "user-events", TLSEnabled: true, TLSCAFile: "/does/not/exist.pem"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewPublisher(context.Background(), tt.config)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.NoError(t, p.Close())
+		})
+	}
+}
+
+func TestNewPublisherBatchTimeout(t *testing.T) {
+	p, err := NewPublisher(context.Background(), Config{Brokers: []string{"localhost:9092"}, Topic: "user-events"})
+	require.NoError(t, err)
+	assert.Equal(t, DefaultBatchTimeout, p.writer.(*kafkago.Writer).BatchTimeout, "publishes don't wait kafka-go's one second default")
+
+	p, err = NewPublisher(context.Background(), Config{Brokers: []string{"localhost:9092"}, Topic: "user-events", BatchTimeout: 50 * time.Millisecond})
+	require.NoError(t, err)
+	assert.Equal(t, 50*time.Millisecond, p.writer.(*kafkago.Writer).BatchTimeout)
+}
@@ -0,0 +1,135 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package kms adapts cloud key management services for envelope encryption
+// of stored values and for decrypting secrets at startup.
+package kms
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/encryption"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+)
+
+const awsKeyIDPrefix = "aws/"
+
+// awsKMSAPI is the subset of the AWS KMS client used by AWSKeyWrapper
+type awsKMSAPI interface {
+	Encrypt(ctx context.Context, params *kms.EncryptInput, optFns ...func(*kms.Options)) (*kms.EncryptOutput, error)
+	Decrypt(ctx context.Context, params *kms.DecryptInput, optFns ...func(*kms.Options)) (*kms.DecryptOutput, error)
+}
+
+// AWSKeyWrapper wraps data keys with an AWS KMS symmetric key. Key IDs are
+// "aws/" followed by the key UUID the key reference currently resolves to, so
+// repointing an alias at a new key is picked up as a rotation.
+type AWSKeyWrapper struct {
+	client awsKMSAPI
+	keyRef string
+	active atomic.Pointer[string]
+}
+
+// NewAWSKeyWrapper creates a wrapper for keyRef (key ID, key ARN, alias name
+// or alias ARN) using the default AWS credential chain. A probe encryption
+// is made so misconfiguration fails at startup.
+func NewAWSKeyWrapper(ctx context.Context, keyRef string) (*AWSKeyWrapper, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, errs.NewUnexpected("failed to load AWS configuration", err)
+	}
+	return newAWSKeyWrapper(ctx, kms.NewFromConfig(cfg), keyRef)
+}
+
+func newAWSKeyWrapper(ctx context.Context, client awsKMSAPI, keyRef string) (*AWSKeyWrapper, error) {
+	if strings.TrimSpace(keyRef) == "" {
+		return nil, errs.NewValidation("AWS KMS key ID is required")
+	}
+	w := &AWSKeyWrapper{client: client, keyRef: keyRef}
+	if err := w.RefreshActiveKey(ctx); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// encryptionContext binds wrapped data keys to this service in CloudTrail and
+// key policies
+func encryptionContext() map[string]string {
+	return map[string]string{"service": constants.ServiceName}
+}
+
+// RefreshActiveKey implements encryption.KeyRefresher
+func (w *AWSKeyWrapper) RefreshActiveKey(ctx context.Context) error {
+	return probeWrap(ctx, w)
+}
+
+// ActiveKeyID implements encryption.KeyWrapper
+func (w *AWSKeyWrapper) ActiveKeyID() string {
+	if id := w.active.Load(); id != nil {
+		return *id
+	}
+	return ""
+}
+
+// Wrap implements encryption.KeyWrapper
+func (w *AWSKeyWrapper) Wrap(ctx context.Context, dataKey []byte) ([]byte, string, error) {
+	out, err := w.client.Encrypt(ctx, &kms.EncryptInput{
+		KeyId:             aws.String(w.keyRef),
+		Plaintext:         dataKey,
+		EncryptionContext: encryptionContext(),
+	})
+	if err != nil {
+		return nil, "", errs.NewServiceUnavailable("AWS KMS encrypt failed", err)
+	}
+
+	keyID := awsKeyIDPrefix + awsKeyUUID(aws.ToString(out.KeyId))
+	w.active.Store(&keyID)
+	return out.CiphertextBlob, keyID, nil
+}
+
+// Unwrap implements encryption.KeyWrapper
+func (w *AWSKeyWrapper) Unwrap(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	if !strings.HasPrefix(keyID, awsKeyIDPrefix) {
+		return nil, errs.NewNotFound("key is not an AWS KMS key")
+	}
+	out, err := w.client.Decrypt(ctx, &kms.DecryptInput{
+		CiphertextBlob:    wrapped,
+		EncryptionContext: encryptionContext(),
+	})
+	if err != nil {
+		return nil, errs.NewServiceUnavailable("AWS KMS decrypt failed", err)
+	}
+	return out.Plaintext, nil
+}
+
+// DecryptSecret implements port.SecretDecrypter for ciphertext produced by
+// `aws kms encrypt` without an encryption context.
+func (w *AWSKeyWrapper) DecryptSecret(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	out, err := w.client.Decrypt(ctx, &kms.DecryptInput{CiphertextBlob: ciphertext})
+	if err != nil {
+		return nil, errs.NewServiceUnavailable("AWS KMS decrypt failed", err)
+	}
+	return out.Plaintext, nil
+}
+
+// awsKeyUUID extracts the key UUID from a key ARN
+// (arn:aws:kms:<region>:<account>:key/<uuid>)
+func awsKeyUUID(arn string) string {
+	if _, uuid, ok := strings.Cut(arn, ":key/"); ok {
+		return uuid
+	}
+	return arn
+}
+
+var (
+	_ encryption.KeyWrapper   = (*AWSKeyWrapper)(nil)
+	_ encryption.KeyRefresher = (*AWSKeyWrapper)(nil)
+	_ port.SecretDecrypter    = (*AWSKeyWrapper)(nil)
+)
@@ -0,0 +1,86 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package kms
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/encryption"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+)
+
+// fakeAWSKMS "encrypts" by prefixing the key ARN, which is enough to check
+// that the right key and encryption context are used
+type fakeAWSKMS struct {
+	keyARN   string
+	failWith error
+}
+
+func (f *fakeAWSKMS) Encrypt(_ context.Context, in *kms.EncryptInput, _ ...func(*kms.Options)) (*kms.EncryptOutput, error) {
+	if f.failWith != nil {
+		return nil, f.failWith
+	}
+	blob := append([]byte(f.keyARN+"|"+in.EncryptionContext["service"]+"|"), in.Plaintext...)
+	return &kms.EncryptOutput{CiphertextBlob: blob, KeyId: aws.String(f.keyARN)}, nil
+}
+
+func (f *fakeAWSKMS) Decrypt(_ context.Context, in *kms.DecryptInput, _ ...func(*kms.Options)) (*kms.DecryptOutput, error) {
+	parts := bytes.SplitN(in.CiphertextBlob, []byte("|"), 3)
+	if len(parts) != 3 || string(parts[1]) != in.EncryptionContext["service"] {
+		return nil, errors.New("InvalidCiphertextException")
+	}
+	return &kms.DecryptOutput{Plaintext: parts[2]}, nil
+}
+
+func TestAWSKeyWrapper(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeAWSKMS{keyARN: "arn:aws:kms:us-east-2:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab"}
+
+	w, err := newAWSKeyWrapper(ctx, fake, "alias/lfx-auth-service")
+	require.NoError(t, err)
+	assert.Equal(t, "aws/1234abcd-12ab-34cd-56ef-1234567890ab", w.ActiveKeyID(), "active key resolved by the startup probe")
+
+	env := encryption.NewEnvelope(w)
+	sealed, err := env.Seal(ctx, []byte("jdoe"), []byte("jdoe"))
+	require.NoError(t, err)
+	opened, err := env.Open(ctx, sealed, []byte("jdoe"))
+	require.NoError(t, err)
+	assert.Equal(t, "jdoe", string(opened))
+
+	t.Run("alias repointed to a new key", func(t *testing.T) {
+		fake.keyARN = "arn:aws:kms:us-east-2:123456789012:key/0987dcba-09fe-87dc-65ba-ab0987654321"
+		require.NoError(t, w.RefreshActiveKey(ctx))
+		assert.Equal(t, "aws/0987dcba-09fe-87dc-65ba-ab0987654321", w.ActiveKeyID())
+
+		keyID, err := encryption.KeyID(sealed)
+		require.NoError(t, err)
+		assert.NotEqual(t, w.ActiveKeyID(), keyID, "old values are flagged for re-encryption")
+	})
+
+	t.Run("foreign key IDs are not found", func(t *testing.T) {
+		_, err := w.Unwrap(ctx, "2025-01", []byte("x"))
+		assert.ErrorAs(t, err, &errs.NotFound{})
+	})
+
+	t.Run("secrets are decrypted without context", func(t *testing.T) {
+		_, err := w.DecryptSecret(ctx, []byte("arn||s3cr3t"))
+		require.NoError(t, err)
+	})
+
+	t.Run("startup fails without key access", func(t *testing.T) {
+		_, err := newAWSKeyWrapper(ctx, &fakeAWSKMS{failWith: errors.New("AccessDeniedException")}, "alias/x")
+		assert.ErrorAs(t, err, &errs.ServiceUnavailable{})
+
+		_, err = newAWSKeyWrapper(ctx, fake, "")
+		assert.ErrorAs(t, err, &errs.Validation{})
+	})
+}
@@ -0,0 +1,185 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package kms
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/encryption"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/httpclient"
+)
+
+const (
+	gcpKeyIDPrefix = "gcp/"
+	gcpKMSEndpoint = "https://cloudkms.googleapis.com/v1/"
+	gcpKMSScope    = "https://www.googleapis.com/auth/cloudkms"
+)
+
+// gcpKeyNamePattern matches a crypto key resource name
+var gcpKeyNamePattern = regexp.MustCompile(`^projects/[^/]+/locations/[^/]+/keyRings/[^/]+/cryptoKeys/[^/]+$`)
+
+// GCPKeyWrapper wraps data keys with a Google Cloud KMS symmetric key through
+// the Cloud KMS REST API. Key IDs are "gcp/" followed by the crypto key
+// version that produced the ciphertext, so a new primary version is picked up
+// as a rotation.
+type GCPKeyWrapper struct {
+	httpClient  *httpclient.Client
+	tokenSource oauth2.TokenSource
+	endpoint    string
+	keyName     string
+	active      atomic.Pointer[string]
+}
+
+type gcpEncryptRequest struct {
+	Plaintext                   string `json:"plaintext"`
+	AdditionalAuthenticatedData string `json:"additionalAuthenticatedData,omitempty"`
+}
+
+type gcpEncryptResponse struct {
+	Name       string `json:"name"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+type gcpDecryptRequest struct {
+	Ciphertext                  string `json:"ciphertext"`
+	AdditionalAuthenticatedData string `json:"additionalAuthenticatedData,omitempty"`
+}
+
+type gcpDecryptResponse struct {
+	Plaintext string `json:"plaintext"`
+}
+
+// NewGCPKeyWrapper creates a wrapper for keyName
+// (projects/*/locations/*/keyRings/*/cryptoKeys/*) using Application Default
+// Credentials. A probe encryption is made so misconfiguration fails at startup.
+func NewGCPKeyWrapper(ctx context.Context, config httpclient.Config, keyName string) (*GCPKeyWrapper, error) {
+	tokenSource, err := google.DefaultTokenSource(ctx, gcpKMSScope)
+	if err != nil {
+		return nil, errs.NewUnexpected("failed to load Google Cloud credentials", err)
+	}
+	return newGCPKeyWrapper(ctx, httpclient.NewClient(config), tokenSource, gcpKMSEndpoint, keyName)
+}
+
+func newGCPKeyWrapper(ctx context.Context, client *httpclient.Client, tokenSource oauth2.TokenSource, endpoint, keyName string) (*GCPKeyWrapper, error) {
+	if !gcpKeyNamePattern.MatchString(keyName) {
+		return nil, errs.NewValidation("GCP KMS key must be of the form projects/*/locations/*/keyRings/*/cryptoKeys/*")
+	}
+	w := &GCPKeyWrapper{
+		httpClient:  client,
+		tokenSource: tokenSource,
+		endpoint:    endpoint,
+		keyName:     keyName,
+	}
+	if err := w.RefreshActiveKey(ctx); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// RefreshActiveKey implements encryption.KeyRefresher
+func (w *GCPKeyWrapper) RefreshActiveKey(ctx context.Context) error {
+	return probeWrap(ctx, w)
+}
+
+// ActiveKeyID implements encryption.KeyWrapper
+func (w *GCPKeyWrapper) ActiveKeyID() string {
+	if id := w.active.Load(); id != nil {
+		return *id
+	}
+	return ""
+}
+
+// Wrap implements encryption.KeyWrapper
+func (w *GCPKeyWrapper) Wrap(ctx context.Context, dataKey []byte) ([]byte, string, error) {
+	var resp gcpEncryptResponse
+	err := w.call(ctx, w.keyName+":encrypt", gcpEncryptRequest{
+		Plaintext:                   base64.StdEncoding.EncodeToString(dataKey),
+		AdditionalAuthenticatedData: gcpDataKeyAAD,
+	}, &resp)
+	if err != nil {
+		return nil, "", err
+	}
+
+	wrapped, err := base64.StdEncoding.DecodeString(resp.Ciphertext)
+	if err != nil {
+		return nil, "", errs.NewUnexpected("invalid ciphertext in GCP KMS response", err)
+	}
+	keyID := gcpKeyIDPrefix + resp.Name
+	w.active.Store(&keyID)
+	return wrapped, keyID, nil
+}
+
+// gcpDataKeyAAD binds wrapped data keys to their purpose, so a wrapped data
+// key can't be passed off as an encrypted secret or the other way around
+var gcpDataKeyAAD = base64.StdEncoding.EncodeToString([]byte("lfx-auth-service/data-key"))
+
+// Unwrap implements encryption.KeyWrapper
+func (w *GCPKeyWrapper) Unwrap(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	version, ok := strings.CutPrefix(keyID, gcpKeyIDPrefix)
+	if !ok {
+		return nil, errs.NewNotFound("key is not a GCP KMS key")
+	}
+	// Decryption targets the crypto key; the version is recorded in the ciphertext
+	keyName, _, _ := strings.Cut(version, "/cryptoKeyVersions/")
+	return w.decrypt(ctx, keyName, wrapped, gcpDataKeyAAD)
+}
+
+// DecryptSecret implements port.SecretDecrypter for ciphertext produced by
+// `gcloud kms encrypt` with the configured key and no additional data.
+func (w *GCPKeyWrapper) DecryptSecret(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	return w.decrypt(ctx, w.keyName, ciphertext, "")
+}
+
+func (w *GCPKeyWrapper) decrypt(ctx context.Context, keyName string, ciphertext []byte, aad string) ([]byte, error) {
+	var resp gcpDecryptResponse
+	err := w.call(ctx, keyName+":decrypt", gcpDecryptRequest{
+		Ciphertext:                  base64.StdEncoding.EncodeToString(ciphertext),
+		AdditionalAuthenticatedData: aad,
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := base64.StdEncoding.DecodeString(resp.Plaintext)
+	if err != nil {
+		return nil, errs.NewUnexpected("invalid plaintext in GCP KMS response", err)
+	}
+	return plaintext, nil
+}
+
+func (w *GCPKeyWrapper) call(ctx context.Context, resource string, body, resp any) error {
+	token, err := w.tokenSource.Token()
+	if err != nil {
+		return errs.NewServiceUnavailable("failed to get Google Cloud access token", err)
+	}
+
+	_, err = httpclient.NewAPIRequest(w.httpClient,
+		httpclient.WithMethod(http.MethodPost),
+		httpclient.WithURL(w.endpoint+resource),
+		httpclient.WithToken(token.AccessToken),
+		httpclient.WithBody(body),
+		httpclient.WithSensitiveBody(),
+		httpclient.WithDescription("GCP KMS "+resource[strings.LastIndex(resource, ":")+1:]),
+	).Call(ctx, resp)
+	if err != nil {
+		return errs.NewServiceUnavailable("GCP KMS request failed", err)
+	}
+	return nil
+}
+
+var (
+	_ encryption.KeyWrapper   = (*GCPKeyWrapper)(nil)
+	_ encryption.KeyRefresher = (*GCPKeyWrapper)(nil)
+	_ port.SecretDecrypter    = (*GCPKeyWrapper)(nil)
+)
@@ -0,0 +1,106 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package kms
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/encryption"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/httpclient"
+)
+
+const testGCPKey = "projects/lfx/locations/global/keyRings/auth/cryptoKeys/kv"
+
+// fakeCloudKMS serves :encrypt and :decrypt, "encrypting" by prefixing the primary version and AAD
+func fakeCloudKMS(t *testing.T, primary *string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer test-token", r.Header.Get("Authorization"))
+		resource, method, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/v1/"), ":")
+		if resource != testGCPKey {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		var body map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+		switch method {
+		case "encrypt":
+			plaintext, _ := base64.StdEncoding.DecodeString(body["plaintext"])
+			ciphertext := append([]byte(body["additionalAuthenticatedData"]+"|"), plaintext...)
+			_ = json.NewEncoder(w).Encode(map[string]string{
+				"name":       testGCPKey + "/cryptoKeyVersions/" + *primary,
+				"ciphertext": base64.StdEncoding.EncodeToString(ciphertext),
+			})
+		case "decrypt":
+			ciphertext, _ := base64.StdEncoding.DecodeString(body["ciphertext"])
+			aad, plaintext, ok := strings.Cut(string(ciphertext), "|")
+			if !ok || aad != body["additionalAuthenticatedData"] {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]string{"plaintext": base64.StdEncoding.EncodeToString([]byte(plaintext))})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestGCPKeyWrapper(t *testing.T) {
+	ctx := context.Background()
+	primary := "1"
+	server := fakeCloudKMS(t, &primary)
+	defer server.Close()
+
+	client := httpclient.NewClient(httpclient.Config{MaxRetries: 0})
+	tokens := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "test-token"})
+
+	w, err := newGCPKeyWrapper(ctx, client, tokens, server.URL+"/v1/", testGCPKey)
+	require.NoError(t, err)
+	assert.Equal(t, "gcp/"+testGCPKey+"/cryptoKeyVersions/1", w.ActiveKeyID())
+
+	env := encryption.NewEnvelope(w)
+	sealed, err := env.Seal(ctx, []byte("jdoe"), nil)
+	require.NoError(t, err)
+
+	t.Run("rotation keeps old versions readable", func(t *testing.T) {
+		primary = "2"
+		require.NoError(t, w.RefreshActiveKey(ctx))
+		assert.Equal(t, "gcp/"+testGCPKey+"/cryptoKeyVersions/2", w.ActiveKeyID())
+
+		opened, err := env.Open(ctx, sealed, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "jdoe", string(opened))
+	})
+
+	t.Run("data keys and secrets use different AAD", func(t *testing.T) {
+		_, err := w.DecryptSecret(ctx, []byte("|s3cr3t"))
+		require.NoError(t, err)
+
+		keyID, wrapped := w.ActiveKeyID(), []byte("|not-a-data-key")
+		_, err = w.Unwrap(ctx, keyID, wrapped)
+		assert.Error(t, err)
+	})
+
+	t.Run("foreign key IDs are not found", func(t *testing.T) {
+		_, err := w.Unwrap(ctx, "aws/1234", []byte("x"))
+		assert.ErrorAs(t, err, &errs.NotFound{})
+	})
+
+	t.Run("invalid key name", func(t *testing.T) {
+		_, err := newGCPKeyWrapper(ctx, client, tokens, server.URL+"/v1/", "projects/lfx/cryptoKeys/kv")
+		assert.ErrorAs(t, err, &errs.Validation{})
+	})
+}
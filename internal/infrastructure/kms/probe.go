@@ -0,0 +1,23 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package kms
+
+import (
+	"context"
+	"crypto/rand"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/encryption"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+)
+
+// probeWrap wraps a throwaway key so the wrapper learns which key version the
+// configured key currently resolves to. It doubles as a permission check.
+func probeWrap(ctx context.Context, w encryption.KeyWrapper) error {
+	probe := make([]byte, 32)
+	if _, err := rand.Read(probe); err != nil {
+		return errs.NewUnexpected("failed to generate probe key", err)
+	}
+	_, _, err := w.Wrap(ctx, probe)
+	return err
+}
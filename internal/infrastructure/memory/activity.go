@@ -0,0 +1,70 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package memory provides in-process implementations of domain ports that
+// only need to live as long as the service instance.
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/cache"
+)
+
+// activityStore caches the last observed login per user
+type activityStore struct {
+	// mu serialises read-modify-write so out-of-order deliveries can't move
+	// last_login backwards
+	mu    sync.Mutex
+	cache *cache.Cache[string, model.UserActivity]
+}
+
+// RecordLogin counts a successful login event and updates the user's last
+// login from it. The count is of the logins seen since the user's entry was
+// created. Other event types and events without a user are ignored.
+func (s *activityStore) RecordLogin(ctx context.Context, event model.LoginEvent) error {
+	if event.Type != model.LoginEventTypeSuccess || event.UserID == "" {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, _ := s.cache.Get(event.UserID)
+	// a new count rather than an increment, since GetActivity hands out the
+	// pointer of the cached one
+	count := 1
+	if current.LoginsCount != nil {
+		count = *current.LoginsCount + 1
+	}
+	current.LoginsCount = &count
+
+	if current.LastLogin == nil || event.Timestamp.After(*current.LastLogin) {
+		lastLogin := event.Timestamp
+		current.LastLogin = &lastLogin
+		current.LastIP = event.IP
+	}
+	s.cache.Set(event.UserID, current)
+	return nil
+}
+
+// GetActivity returns the cached activity for the user
+func (s *activityStore) GetActivity(ctx context.Context, userID string) (*model.UserActivity, bool) {
+	activity, ok := s.cache.Get(userID)
+	if !ok {
+		return nil, false
+	}
+	return &activity, true
+}
+
+// NewActivityStore creates an in-memory activity store. Entries expire after
+// ttl and at most maxEntries users are tracked.
+func NewActivityStore(ttl time.Duration, maxEntries int) port.UserActivityStore {
+	return &activityStore{
+		cache: cache.New[string, model.UserActivity](ttl, maxEntries),
+	}
+}
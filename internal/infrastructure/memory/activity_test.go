@@ -0,0 +1,83 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+)
+
+func TestActivityStoreRecordLogin(t *testing.T) {
+	ctx := context.Background()
+	t0 := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		events     []model.LoginEvent
+		wantFound  bool
+		wantLogin  time.Time
+		wantLastIP string
+		wantLogins int
+	}{
+		{
+			name: "successful login is recorded",
+			events: []model.LoginEvent{
+				{Type: model.LoginEventTypeSuccess, UserID: "auth0|1", IP: "10.0.0.0", Timestamp: t0},
+			},
+			wantFound:  true,
+			wantLogin:  t0,
+			wantLastIP: "10.0.0.0",
+			wantLogins: 1,
+		},
+		{
+			name: "older event does not move last login backwards",
+			events: []model.LoginEvent{
+				{Type: model.LoginEventTypeSuccess, UserID: "auth0|1", IP: "10.0.0.0", Timestamp: t0},
+				{Type: model.LoginEventTypeSuccess, UserID: "auth0|1", IP: "10.9.9.0", Timestamp: t0.Add(-time.Hour)},
+			},
+			wantFound:  true,
+			wantLogin:  t0,
+			wantLastIP: "10.0.0.0",
+			wantLogins: 2,
+		},
+		{
+			name: "failures are not activity",
+			events: []model.LoginEvent{
+				{Type: model.LoginEventTypeFailure, UserID: "auth0|1", Timestamp: t0},
+			},
+		},
+		{
+			name: "events without a user are ignored",
+			events: []model.LoginEvent{
+				{Type: model.LoginEventTypeSuccess, Timestamp: t0},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewActivityStore(time.Hour, 10)
+			for _, e := range tt.events {
+				require.NoError(t, store.RecordLogin(ctx, e))
+			}
+
+			activity, found := store.GetActivity(ctx, "auth0|1")
+			assert.Equal(t, tt.wantFound, found)
+			if !tt.wantFound {
+				return
+			}
+			require.NotNil(t, activity.LastLogin)
+			assert.Equal(t, tt.wantLogin, *activity.LastLogin)
+			assert.Equal(t, tt.wantLastIP, activity.LastIP)
+			require.NotNil(t, activity.LoginsCount)
+			assert.Equal(t, tt.wantLogins, *activity.LoginsCount)
+		})
+	}
+}
@@ -0,0 +1,304 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package memory
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/cache"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/envelope"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+)
+
+// staleRefreshTimeout bounds a background refresh of a stale entry, which
+// outlives the read that triggered it
+const staleRefreshTimeout = 10 * time.Second
+
+// UserCache wraps a user repository and caches GetUser results by user ID.
+// Writes that change a user's record invalidate the entry; every other call
+// goes straight to the wrapped repository.
+type UserCache struct {
+	port.UserReaderWriter
+	users *cache.Cache[string, *model.User]
+
+	// staleTTL is how long past expiry an entry is still served while it is
+	// refreshed; 0 disables stale-while-revalidate
+	staleTTL   time.Duration
+	refreshing singleflight.Group
+
+	// broadcast tells the other replicas about a user changed by a write
+	// through this cache; nil keeps invalidations local
+	broadcast func(ctx context.Context, userID string)
+
+	hits      atomic.Int64
+	staleHits atomic.Int64
+	misses    atomic.Int64
+
+	// reads counts the reads of each user, for the hot user list
+	readsMu sync.Mutex
+	reads   map[string]int64
+}
+
+// UserCacheOption configures a UserCache
+type UserCacheOption func(*UserCache)
+
+// WithStaleWhileRevalidate serves entries up to staleTTL past their expiry,
+// marked stale, while one background read per user refreshes them. Reads
+// then keep their cached latency when the provider slows down.
+func WithStaleWhileRevalidate(staleTTL time.Duration) UserCacheOption {
+	return func(c *UserCache) {
+		c.staleTTL = staleTTL
+	}
+}
+
+// WithInvalidationBroadcast calls broadcast with the user ID of every write
+// made through the cache, after the local entry is dropped, so replicas
+// sharing the provider can drop theirs.
+func WithInvalidationBroadcast(broadcast func(ctx context.Context, userID string)) UserCacheOption {
+	return func(c *UserCache) {
+		c.broadcast = broadcast
+	}
+}
+
+// GetUser returns the cached user when present, otherwise fetches and caches it.
+// The caller's token has already been verified by MetadataLookup at this point,
+// so a hit does not depend on which credentials populated the entry.
+func (c *UserCache) GetUser(ctx context.Context, user *model.User) (*model.User, error) {
+	// a read pinned to a provider must get that provider's answer, which
+	// the others sharing the cache must not get
+	if envelope.ProviderFromContext(ctx) != "" {
+		return c.UserReaderWriter.GetUser(ctx, user)
+	}
+	if user != nil && user.UserID != "" {
+		c.countRead(user.UserID)
+		if cached, stale, ok := c.users.GetStale(user.UserID, c.staleTTL); ok {
+			c.hits.Add(1)
+			served := cloneUser(cached)
+			outcome := envelope.CacheHit
+			if stale {
+				c.staleHits.Add(1)
+				c.refresh(ctx, user)
+				served.Stale = true
+				outcome = envelope.CacheStale
+			}
+			envelope.RecordCache(ctx, outcome)
+			return served, nil
+		}
+		c.misses.Add(1)
+		envelope.RecordCache(ctx, envelope.CacheMiss)
+	}
+
+	fetched, err := c.UserReaderWriter.GetUser(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	if fetched != nil && fetched.UserID != "" {
+		c.users.Set(fetched.UserID, cloneUser(fetched))
+	}
+	return fetched, nil
+}
+
+// countRead counts a read of userID
+func (c *UserCache) countRead(userID string) {
+	c.readsMu.Lock()
+	defer c.readsMu.Unlock()
+	if c.reads == nil {
+		c.reads = make(map[string]int64)
+	}
+	c.reads[userID]++
+}
+
+// HotUserIDs returns the IDs of the n cached users read the most, the most
+// read first. The counts of the users no longer cached are dropped, so they
+// don't grow past the cache size.
+func (c *UserCache) HotUserIDs(n int) []string {
+	cached := c.users.Keys()
+
+	c.readsMu.Lock()
+	counts := make(map[string]int64, len(cached))
+	for _, userID := range cached {
+		if count := c.reads[userID]; count > 0 {
+			counts[userID] = count
+		}
+	}
+	c.reads = maps.Clone(counts)
+	c.readsMu.Unlock()
+
+	hot := slices.Collect(maps.Keys(counts))
+	slices.SortFunc(hot, func(a, b string) int {
+		return cmp.Or(cmp.Compare(counts[b], counts[a]), strings.Compare(a, b))
+	})
+	return hot[:min(n, len(hot))]
+}
+
+// Preload reads userID from the provider into the cache, unless it is
+// already cached. Preloads are not counted as reads.
+func (c *UserCache) Preload(ctx context.Context, userID string) error {
+	if _, ok := c.users.Get(userID); ok {
+		return nil
+	}
+	fetched, err := c.UserReaderWriter.GetUser(ctx, &model.User{UserID: userID})
+	if err != nil {
+		return err
+	}
+	if fetched != nil && fetched.UserID != "" {
+		c.users.Set(fetched.UserID, cloneUser(fetched))
+	}
+	return nil
+}
+
+// refresh re-reads a stale user in the background, unless a refresh of the
+// user is already running. A user the provider no longer has is evicted; on
+// other errors the stale entry is kept until it leaves the stale window.
+func (c *UserCache) refresh(ctx context.Context, user *model.User) {
+	ctx = context.WithoutCancel(ctx)
+	input := cloneUser(user)
+	go func() {
+		_, _, _ = c.refreshing.Do(input.UserID, func() (any, error) {
+			ctx, cancel := context.WithTimeout(ctx, staleRefreshTimeout)
+			defer cancel()
+
+			fetched, err := c.UserReaderWriter.GetUser(ctx, input)
+			var notFound errs.NotFound
+			switch {
+			case errors.As(err, &notFound):
+				c.InvalidateUser(input.UserID)
+			case err != nil:
+				slog.WarnContext(ctx, "failed to refresh stale cache entry", "user_id", input.UserID, "error", err)
+			case fetched != nil && fetched.UserID != "":
+				c.users.Set(fetched.UserID, cloneUser(fetched))
+			}
+			return nil, err
+		})
+	}()
+}
+
+// UpdateUser updates the user and invalidates its cache entry
+func (c *UserCache) UpdateUser(ctx context.Context, user *model.User) (*model.User, error) {
+	updated, err := c.UserReaderWriter.UpdateUser(ctx, user)
+	if user != nil {
+		c.invalidateWritten(ctx, user.UserID)
+	}
+	if updated != nil && (user == nil || updated.UserID != user.UserID) {
+		c.invalidateWritten(ctx, updated.UserID)
+	}
+	return updated, err
+}
+
+// SetPrimaryEmail sets the primary email and invalidates the user's cache entry
+func (c *UserCache) SetPrimaryEmail(ctx context.Context, userID string, email string) error {
+	defer c.invalidateWritten(ctx, userID)
+	return c.UserReaderWriter.SetPrimaryEmail(ctx, userID, email)
+}
+
+// LinkIdentity links the identity and invalidates the user's cache entry
+func (c *UserCache) LinkIdentity(ctx context.Context, request *model.LinkIdentity) error {
+	if request != nil {
+		defer c.invalidateWritten(ctx, request.User.UserID)
+	}
+	return c.UserReaderWriter.LinkIdentity(ctx, request)
+}
+
+// UnlinkIdentity unlinks the identity and invalidates the user's cache entry
+func (c *UserCache) UnlinkIdentity(ctx context.Context, request *model.UnlinkIdentity) error {
+	if request != nil {
+		defer c.invalidateWritten(ctx, request.User.UserID)
+	}
+	return c.UserReaderWriter.UnlinkIdentity(ctx, request)
+}
+
+// AddSystemManagedEmail links the alias and invalidates the primary user's cache entry
+func (c *UserCache) AddSystemManagedEmail(ctx context.Context, primaryUserID, email string) (string, error) {
+	defer c.invalidateWritten(ctx, primaryUserID)
+	return c.UserReaderWriter.AddSystemManagedEmail(ctx, primaryUserID, email)
+}
+
+// invalidateWritten drops the entry of a user changed by a write and
+// broadcasts the change
+func (c *UserCache) invalidateWritten(ctx context.Context, userID string) {
+	if userID == "" {
+		return
+	}
+	c.InvalidateUser(userID)
+	if c.broadcast != nil {
+		c.broadcast(ctx, userID)
+	}
+}
+
+// CachedUserIDs returns the IDs of all unexpired cache entries
+func (c *UserCache) CachedUserIDs() []string {
+	return c.users.Keys()
+}
+
+// CachedUser returns a copy of the cached user, if present
+func (c *UserCache) CachedUser(userID string) (*model.User, bool) {
+	cached, ok := c.users.Get(userID)
+	if !ok {
+		return nil, false
+	}
+	return cloneUser(cached), true
+}
+
+// InvalidateUser drops the user's cache entry
+func (c *UserCache) InvalidateUser(userID string) {
+	if userID != "" {
+		c.users.Delete(userID)
+	}
+}
+
+// CacheStats reports the cache size and GetUser hit rate
+func (c *UserCache) CacheStats() model.CacheStats {
+	hits, misses := c.hits.Load(), c.misses.Load()
+	return model.CacheStats{
+		Entries:   c.users.Len(),
+		Hits:      hits,
+		Misses:    misses,
+		HitRate:   model.Ratio(hits, hits+misses),
+		StaleHits: c.staleHits.Load(),
+	}
+}
+
+// cloneUser deep-copies a user so callers can't mutate cached entries
+func cloneUser(user *model.User) *model.User {
+	raw, err := json.Marshal(user)
+	if err != nil {
+		return user
+	}
+	var clone model.User
+	if err := json.Unmarshal(raw, &clone); err != nil {
+		return user
+	}
+	return &clone
+}
+
+var (
+	_ port.UserCache          = (*UserCache)(nil)
+	_ port.CacheStatsReporter = (*UserCache)(nil)
+)
+
+// NewUserCache wraps next with a read-through user cache
+func NewUserCache(next port.UserReaderWriter, ttl time.Duration, maxEntries int, opts ...UserCacheOption) *UserCache {
+	c := &UserCache{
+		UserReaderWriter: next,
+		users:            cache.New[string, *model.User](ttl, maxEntries),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
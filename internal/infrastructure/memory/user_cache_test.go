@@ -0,0 +1,224 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package memory
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/envelope"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+)
+
+// countingRepo serves users from a map and counts provider reads
+type countingRepo struct {
+	port.UserReaderWriter
+	users map[string]*model.User
+	gets  int
+}
+
+func (r *countingRepo) GetUser(ctx context.Context, user *model.User) (*model.User, error) {
+	r.gets++
+	found, ok := r.users[user.UserID]
+	if !ok {
+		return nil, errors.NewNotFound("user not found")
+	}
+	return cloneUser(found), nil
+}
+
+func (r *countingRepo) UpdateUser(ctx context.Context, user *model.User) (*model.User, error) {
+	r.users[user.UserID] = cloneUser(user)
+	return user, nil
+}
+
+func (r *countingRepo) SetPrimaryEmail(ctx context.Context, userID string, email string) error {
+	r.users[userID].PrimaryEmail = email
+	return nil
+}
+
+func TestUserCacheGetUser(t *testing.T) {
+	ctx := context.Background()
+	repo := &countingRepo{users: map[string]*model.User{
+		"auth0|1": {UserID: "auth0|1", Username: "one", PrimaryEmail: "one@example.com"},
+	}}
+	c := NewUserCache(repo, time.Minute, 10)
+
+	first, err := c.GetUser(ctx, &model.User{UserID: "auth0|1"})
+	require.NoError(t, err)
+	second, err := c.GetUser(ctx, &model.User{UserID: "auth0|1"})
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, repo.gets, "second read should be served from cache")
+	stats := c.CacheStats()
+	assert.Equal(t, int64(1), stats.Hits)
+	assert.Equal(t, int64(1), stats.Misses)
+	assert.InDelta(t, 0.5, stats.HitRate, 0.001)
+	assert.Equal(t, first, second)
+
+	second.Username = "mutated"
+	cached, ok := c.CachedUser("auth0|1")
+	require.True(t, ok)
+	assert.Equal(t, "one", cached.Username, "callers must not be able to mutate cached entries")
+
+	_, err = c.GetUser(ctx, &model.User{UserID: "auth0|missing"})
+	assert.Error(t, err)
+	assert.ElementsMatch(t, []string{"auth0|1"}, c.CachedUserIDs(), "errors are not cached")
+}
+
+func TestUserCacheRecordsOutcome(t *testing.T) {
+	repo := &countingRepo{users: map[string]*model.User{"auth0|1": {UserID: "auth0|1"}}}
+	c := NewUserCache(repo, time.Minute, 10)
+
+	ctx, recorder := envelope.WithRecorder(context.Background())
+	_, err := c.GetUser(ctx, &model.User{UserID: "auth0|1"})
+	require.NoError(t, err)
+	assert.Equal(t, envelope.CacheMiss, recorder.Cache())
+
+	ctx, recorder = envelope.WithRecorder(context.Background())
+	_, err = c.GetUser(ctx, &model.User{UserID: "auth0|1"})
+	require.NoError(t, err)
+	assert.Equal(t, envelope.CacheHit, recorder.Cache())
+}
+
+func TestUserCacheWritesInvalidate(t *testing.T) {
+	ctx := context.Background()
+	repo := &countingRepo{users: map[string]*model.User{
+		"auth0|1": {UserID: "auth0|1", PrimaryEmail: "old@example.com"},
+	}}
+	var broadcast []string
+	c := NewUserCache(repo, time.Minute, 10, WithInvalidationBroadcast(func(ctx context.Context, userID string) {
+		broadcast = append(broadcast, userID)
+	}))
+
+	_, err := c.GetUser(ctx, &model.User{UserID: "auth0|1"})
+	require.NoError(t, err)
+
+	require.NoError(t, c.SetPrimaryEmail(ctx, "auth0|1", "new@example.com"))
+	_, ok := c.CachedUser("auth0|1")
+	assert.False(t, ok)
+
+	user, err := c.GetUser(ctx, &model.User{UserID: "auth0|1"})
+	require.NoError(t, err)
+	assert.Equal(t, "new@example.com", user.PrimaryEmail)
+
+	_, err = c.UpdateUser(ctx, &model.User{UserID: "auth0|1", PrimaryEmail: "new@example.com", Username: "renamed"})
+	require.NoError(t, err)
+	_, ok = c.CachedUser("auth0|1")
+	assert.False(t, ok)
+	assert.Equal(t, []string{"auth0|1", "auth0|1"}, broadcast, "each write is broadcast once")
+}
+
+// lockedRepo serves users from a map under a lock, for background refreshes
+type lockedRepo struct {
+	port.UserReaderWriter
+	mu    sync.Mutex
+	users map[string]*model.User
+	gets  int
+}
+
+func (r *lockedRepo) GetUser(ctx context.Context, user *model.User) (*model.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.gets++
+	found, ok := r.users[user.UserID]
+	if !ok {
+		return nil, errors.NewNotFound("user not found")
+	}
+	return cloneUser(found), nil
+}
+
+func (r *lockedRepo) set(user *model.User) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if user.Username == "" {
+		delete(r.users, user.UserID)
+		return
+	}
+	r.users[user.UserID] = user
+}
+
+func TestUserCacheStaleWhileRevalidate(t *testing.T) {
+	ctx := context.Background()
+	repo := &lockedRepo{users: map[string]*model.User{
+		"auth0|1": {UserID: "auth0|1", Username: "before"},
+	}}
+	const ttl = 20 * time.Millisecond
+	c := NewUserCache(repo, ttl, 10, WithStaleWhileRevalidate(time.Minute))
+
+	fresh, err := c.GetUser(ctx, &model.User{UserID: "auth0|1"})
+	require.NoError(t, err)
+	assert.False(t, fresh.Stale)
+
+	repo.set(&model.User{UserID: "auth0|1", Username: "after"})
+	time.Sleep(2 * ttl)
+
+	stale, err := c.GetUser(ctx, &model.User{UserID: "auth0|1"})
+	require.NoError(t, err)
+	assert.True(t, stale.Stale, "an expired entry is served marked stale")
+	assert.Equal(t, "before", stale.Username)
+	assert.Eventually(t, func() bool {
+		cached, ok := c.CachedUser("auth0|1")
+		return ok && cached.Username == "after"
+	}, time.Second, time.Millisecond, "the stale entry is refreshed in the background")
+	assert.Equal(t, int64(1), c.CacheStats().StaleHits)
+
+	repo.set(&model.User{UserID: "auth0|1"})
+	time.Sleep(2 * ttl)
+	_, err = c.GetUser(ctx, &model.User{UserID: "auth0|1"})
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		_, _, ok := c.users.GetStale("auth0|1", time.Minute)
+		return !ok
+	}, time.Second, time.Millisecond, "a user deleted upstream is evicted on refresh")
+}
+
+func TestUserCacheBypassedByPinnedReads(t *testing.T) {
+	repo := &countingRepo{users: map[string]*model.User{"auth0|1": {UserID: "auth0|1"}}}
+	c := NewUserCache(repo, time.Minute, 10)
+
+	pinned := envelope.WithProvider(context.Background(), "auth0")
+	for range 2 {
+		_, err := c.GetUser(pinned, &model.User{UserID: "auth0|1"})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 2, repo.gets)
+	assert.Empty(t, c.CachedUserIDs(), "pinned answers are not cached")
+}
+
+func TestUserCacheHotUsers(t *testing.T) {
+	ctx := context.Background()
+	repo := &countingRepo{users: map[string]*model.User{
+		"auth0|1": {UserID: "auth0|1"},
+		"auth0|2": {UserID: "auth0|2"},
+		"auth0|3": {UserID: "auth0|3"},
+	}}
+	c := NewUserCache(repo, time.Minute, 10)
+
+	for userID, reads := range map[string]int{"auth0|1": 1, "auth0|2": 3, "auth0|3": 2} {
+		for range reads {
+			_, err := c.GetUser(ctx, &model.User{UserID: userID})
+			require.NoError(t, err)
+		}
+	}
+	assert.Equal(t, []string{"auth0|2", "auth0|3"}, c.HotUserIDs(2))
+
+	c.InvalidateUser("auth0|2")
+	assert.Equal(t, []string{"auth0|3", "auth0|1"}, c.HotUserIDs(5), "users no longer cached are dropped")
+
+	gets := repo.gets
+	require.NoError(t, c.Preload(ctx, "auth0|2"))
+	require.NoError(t, c.Preload(ctx, "auth0|3"))
+	assert.Equal(t, gets+1, repo.gets, "cached users aren't read again")
+	_, ok := c.CachedUser("auth0|2")
+	assert.True(t, ok)
+	assert.Error(t, c.Preload(ctx, "auth0|missing"))
+	assert.Equal(t, []string{"auth0|3", "auth0|1"}, c.HotUserIDs(5), "preloads aren't reads")
+}
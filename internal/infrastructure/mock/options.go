@@ -0,0 +1,70 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package mock
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+)
+
+// Option configures the mock user repository
+type Option func(*userWriter)
+
+// WithUsers replaces the embedded users with a YAML document of the same
+// shape as users.yaml, so a local setup can seed its own fixtures
+func WithUsers(usersYAML []byte) Option {
+	return func(u *userWriter) {
+		u.usersYAML = usersYAML
+	}
+}
+
+// WithLatency delays every provider call by latency plus a random share of
+// jitter, approximating the round-trip to a hosted identity provider
+func WithLatency(latency, jitter time.Duration) Option {
+	return func(u *userWriter) {
+		u.latency = latency
+		u.latencyJitter = jitter
+	}
+}
+
+// WithErrorRate fails the given fraction of provider calls with a
+// service-unavailable error, like a provider outage or rate limiting
+func WithErrorRate(rate float64) Option {
+	return func(u *userWriter) {
+		u.errorRate = rate
+	}
+}
+
+// WithJWTSecret makes MetadataLookup verify the HS256 signature, expiry and
+// scopes of JWTs with secret instead of only reading their sub, so dev
+// tokens behave like provider-issued ones
+func WithJWTSecret(secret []byte) Option {
+	return func(u *userWriter) {
+		u.jwtSecret = secret
+	}
+}
+
+// simulate delays a provider call and fails it at the configured error rate
+func (u *userWriter) simulate(ctx context.Context) error {
+	delay := u.latency
+	if u.latencyJitter > 0 {
+		delay += rand.N(u.latencyJitter)
+	}
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return errors.NewServiceUnavailable("mock: provider call canceled", ctx.Err())
+		case <-timer.C:
+		}
+	}
+	if u.errorRate > 0 && rand.Float64() < u.errorRate {
+		return errors.NewServiceUnavailable("mock: injected provider error")
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package mock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+)
+
+func TestWithErrorRate(t *testing.T) {
+	ctx := context.Background()
+
+	failing := NewUserReaderWriter(ctx, WithErrorRate(1))
+	_, err := failing.GetUser(ctx, &model.User{Username: "zephyr.stormwind"})
+	var unavailable errs.ServiceUnavailable
+	if !errors.As(err, &unavailable) {
+		t.Fatalf("expected an injected ServiceUnavailable error, got %v", err)
+	}
+
+	healthy := NewUserReaderWriter(ctx, WithErrorRate(0))
+	if _, err := healthy.GetUser(ctx, &model.User{Username: "zephyr.stormwind"}); err != nil {
+		t.Fatalf("expected no error without injection, got %v", err)
+	}
+}
+
+func TestWithLatency(t *testing.T) {
+	writer := NewUserReaderWriter(context.Background(), WithLatency(20*time.Millisecond, 0))
+
+	started := time.Now()
+	if _, err := writer.GetUser(context.Background(), &model.User{Username: "zephyr.stormwind"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(started); elapsed < 20*time.Millisecond {
+		t.Errorf("expected the call to take at least 20ms, took %s", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := writer.GetUser(ctx, &model.User{Username: "zephyr.stormwind"}); err == nil {
+		t.Error("expected a canceled call to fail")
+	}
+}
+
+func TestWithUsers(t *testing.T) {
+	ctx := context.Background()
+	writer := NewUserReaderWriter(ctx, WithUsers([]byte(`
+users:
+  - user_id: "user-100"
+    sub: "provider|user-100"
+    username: "fixture.user"
+    primary_email: "fixture.user@example.com"
+`)))
+
+	user, err := writer.GetUser(ctx, &model.User{Username: "fixture.user"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if user.UserID != "user-100" {
+		t.Errorf("expected user-100, got %s", user.UserID)
+	}
+	if _, err := writer.GetUser(ctx, &model.User{Username: "zephyr.stormwind"}); err == nil {
+		t.Error("expected the embedded users to be replaced")
+	}
+}
+
+func TestWithJWTSecret(t *testing.T) {
+	ctx := context.Background()
+	writer := NewUserReaderWriter(ctx, WithJWTSecret([]byte("test-secret")))
+
+	user, err := writer.MetadataLookup(ctx, createTestJWT(t, "auth0|123456789"))
+	if err != nil {
+		t.Fatalf("expected a token signed with the secret to verify, got %v", err)
+	}
+	if user.Sub != "auth0|123456789" {
+		t.Errorf("expected sub auth0|123456789, got %s", user.Sub)
+	}
+
+	other := NewUserReaderWriter(ctx, WithJWTSecret([]byte("another-secret")))
+	_, err = other.MetadataLookup(ctx, createTestJWT(t, "auth0|123456789"))
+	var unauthorized errs.Unauthorized
+	if !errors.As(err, &unauthorized) {
+		t.Errorf("expected Unauthorized for a token signed with another secret, got %v", err)
+	}
+}
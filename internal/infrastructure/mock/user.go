@@ -5,7 +5,9 @@ package mock
 
 import (
 	"context"
+	"crypto/rand"
 	_ "embed"
+	"encoding/hex"
 	"fmt"
 	"log/slog"
 	"strings"
@@ -35,6 +37,21 @@ type userWriter struct {
 	otps map[string]*otpEntry
 	// Mutex for thread-safe OTP operations
 	otpMutex sync.RWMutex
+	// In-memory storage for service accounts (client_id -> account)
+	serviceAccounts     map[string]*model.ServiceAccount
+	serviceAccountMutex sync.Mutex
+	// In-memory storage for sessions (user_id -> sessions), seeded on first listing
+	sessions     map[string][]*model.UserSession
+	sessionMutex sync.Mutex
+
+	// usersYAML is the user fixture document, the embedded one by default
+	usersYAML []byte
+	// latency, latencyJitter and errorRate simulate a remote provider
+	latency       time.Duration
+	latencyJitter time.Duration
+	errorRate     float64
+	// jwtSecret verifies HS256 dev tokens; nil only reads their sub
+	jwtSecret []byte
 }
 
 //go:embed users.yaml
@@ -45,10 +62,10 @@ type UserData struct {
 	Users []model.User `yaml:"users"`
 }
 
-// loadUsersFromYAML loads users from embedded YAML file
-func loadUsersFromYAML(ctx context.Context) ([]*model.User, error) {
+// loadUsersFromYAML loads users from a YAML document
+func loadUsersFromYAML(ctx context.Context, data []byte) ([]*model.User, error) {
 	var userData UserData
-	if err := yaml.Unmarshal(usersYAML, &userData); err != nil {
+	if err := yaml.Unmarshal(data, &userData); err != nil {
 		slog.ErrorContext(ctx, "failed to unmarshal YAML users", "error", err)
 		return nil, fmt.Errorf("failed to unmarshal YAML users: %w", err)
 	}
@@ -58,12 +75,15 @@ func loadUsersFromYAML(ctx context.Context) ([]*model.User, error) {
 		users[i] = &userData.Users[i]
 	}
 
-	slog.InfoContext(ctx, "loaded users from embedded YAML", "count", len(users))
+	slog.InfoContext(ctx, "loaded users from YAML", "count", len(users))
 	return users, nil
 }
 
 // GetUser fetches a user from the in-memory mock store by user_id.
 func (u *userWriter) GetUser(ctx context.Context, user *model.User) (*model.User, error) {
+	if err := u.simulate(ctx); err != nil {
+		return nil, err
+	}
 	slog.InfoContext(ctx, "mock: getting user", "user", user)
 
 	// For mock implementation, we'll use user_id, sub, username, or primary email as key
@@ -90,11 +110,14 @@ func (u *userWriter) GetUser(ctx context.Context, user *model.User) (*model.User
 
 	// If not found, return error (consistent with Auth0 behavior)
 	slog.InfoContext(ctx, "mock: user not found in storage", "key", key)
-	return nil, errors.NewNotFound("user not found")
+	return nil, errors.NewUserNotFound("user not found")
 }
 
 // SearchUser searches the in-memory mock store for a user matching the given criteria.
 func (u *userWriter) SearchUser(ctx context.Context, user *model.User, criteria string) (*model.User, error) {
+	if err := u.simulate(ctx); err != nil {
+		return nil, err
+	}
 	slog.InfoContext(ctx, "mock: searching user", "user", user, "criteria", criteria)
 
 	// For mock implementation, we'll search by the criteria string as a key first
@@ -108,7 +131,7 @@ func (u *userWriter) SearchUser(ctx context.Context, user *model.User, criteria
 	if err != nil {
 		// Return a more specific search error
 		slog.InfoContext(ctx, "mock: user not found by search criteria", "criteria", criteria)
-		return nil, errors.NewNotFound("user not found by criteria")
+		return nil, errors.NewUserNotFound("user not found by criteria")
 	}
 
 	return result, nil
@@ -116,6 +139,9 @@ func (u *userWriter) SearchUser(ctx context.Context, user *model.User, criteria
 
 // UpdateUser applies the provided changes to a mock user record.
 func (u *userWriter) UpdateUser(ctx context.Context, user *model.User) (*model.User, error) {
+	if err := u.simulate(ctx); err != nil {
+		return nil, err
+	}
 	slog.InfoContext(ctx, "mock: updating user", "user", user)
 
 	// For mock implementation, we'll use user_id, sub, username, or primary email as key
@@ -212,6 +238,24 @@ func (u *userWriter) UpdateUser(ctx context.Context, user *model.User) (*model.U
 			if user.UserMetadata.TShirtSize != nil {
 				updatedUser.UserMetadata.TShirtSize = user.UserMetadata.TShirtSize
 			}
+			if user.UserMetadata.Locale != nil {
+				updatedUser.UserMetadata.Locale = user.UserMetadata.Locale
+			}
+			if user.UserMetadata.Timezone != nil {
+				updatedUser.UserMetadata.Timezone = user.UserMetadata.Timezone
+			}
+			if user.UserMetadata.Pronouns != nil {
+				updatedUser.UserMetadata.Pronouns = user.UserMetadata.Pronouns
+			}
+			if user.UserMetadata.PreferredName != nil {
+				updatedUser.UserMetadata.PreferredName = user.UserMetadata.PreferredName
+			}
+			if user.UserMetadata.Preferences != nil {
+				updatedUser.UserMetadata.Preferences = user.UserMetadata.Preferences
+			}
+			if user.UserMetadata.Privacy != nil {
+				updatedUser.UserMetadata.Privacy = user.UserMetadata.Privacy
+			}
 		}
 	}
 
@@ -224,6 +268,9 @@ func (u *userWriter) UpdateUser(ctx context.Context, user *model.User) (*model.U
 
 // SendVerificationAlternateEmail is a no-op in the mock adapter.
 func (u *userWriter) SendVerificationAlternateEmail(ctx context.Context, alternateEmail string) error {
+	if err := u.simulate(ctx); err != nil {
+		return err
+	}
 	slog.DebugContext(ctx, "mock: sending alternate email verification", "alternate_email", redaction.Redact(alternateEmail))
 
 	// Validate email format
@@ -272,6 +319,9 @@ func (u *userWriter) SendVerificationAlternateEmail(ctx context.Context, alterna
 
 // VerifyAlternateEmail simulates verifying an alternate email in the mock store.
 func (u *userWriter) VerifyAlternateEmail(ctx context.Context, email *model.Email) (*model.AuthResponse, error) {
+	if err := u.simulate(ctx); err != nil {
+		return nil, err
+	}
 	slog.DebugContext(ctx, "mock: verifying alternate email", "email", redaction.Redact(email.Email))
 
 	if email.Email == "" || email.OTP == "" {
@@ -351,6 +401,9 @@ func (u *userWriter) ValidateLinkRequest(ctx context.Context, _ *model.LinkIdent
 
 // LinkIdentity links a secondary identity onto a mock primary user.
 func (u *userWriter) LinkIdentity(ctx context.Context, request *model.LinkIdentity) error {
+	if err := u.simulate(ctx); err != nil {
+		return err
+	}
 	slog.DebugContext(ctx, "mock: linking identity")
 
 	if request == nil {
@@ -382,7 +435,7 @@ func (u *userWriter) linkEmailIdentity(ctx context.Context, request *model.LinkI
 
 	user, exists := u.users[request.User.UserID]
 	if !exists {
-		return errors.NewNotFound("user not found")
+		return errors.NewUserNotFound("user not found")
 	}
 
 	if strings.ToLower(user.PrimaryEmail) == normalizedEmail {
@@ -433,7 +486,7 @@ func (u *userWriter) linkSocialIdentity(ctx context.Context, request *model.Link
 
 	user, exists := u.users[request.User.UserID]
 	if !exists {
-		return errors.NewNotFound("user not found")
+		return errors.NewUserNotFound("user not found")
 	}
 
 	for _, id := range user.Identities {
@@ -459,6 +512,9 @@ func (u *userWriter) linkSocialIdentity(ctx context.Context, request *model.Link
 
 // UnlinkIdentity unlinks an identity from a mock primary user.
 func (u *userWriter) UnlinkIdentity(ctx context.Context, request *model.UnlinkIdentity) error {
+	if err := u.simulate(ctx); err != nil {
+		return err
+	}
 	slog.DebugContext(ctx, "mock: unlinking identity")
 
 	if request == nil {
@@ -471,7 +527,7 @@ func (u *userWriter) UnlinkIdentity(ctx context.Context, request *model.UnlinkId
 
 	user, exists := u.users[request.User.UserID]
 	if !exists {
-		return errors.NewNotFound("user not found")
+		return errors.NewUserNotFound("user not found")
 	}
 
 	switch request.Unlink.Provider {
@@ -507,6 +563,9 @@ func (u *userWriter) UnlinkIdentity(ctx context.Context, request *model.UnlinkId
 
 // ChangePassword updates the password for a mock user.
 func (u *userWriter) ChangePassword(ctx context.Context, user *model.User, currentPassword, newPassword string) error {
+	if err := u.simulate(ctx); err != nil {
+		return err
+	}
 	if user == nil {
 		return errors.NewValidation("user is required")
 	}
@@ -518,6 +577,9 @@ func (u *userWriter) ChangePassword(ctx context.Context, user *model.User, curre
 
 // SendResetPasswordLink is a no-op in the mock adapter.
 func (u *userWriter) SendResetPasswordLink(ctx context.Context, user *model.User) error {
+	if err := u.simulate(ctx); err != nil {
+		return err
+	}
 	if user == nil {
 		return errors.NewValidation("user is required")
 	}
@@ -529,13 +591,16 @@ func (u *userWriter) SendResetPasswordLink(ctx context.Context, user *model.User
 
 // SetPrimaryEmail updates the primary email on a mock user.
 func (u *userWriter) SetPrimaryEmail(ctx context.Context, userID string, email string) error {
+	if err := u.simulate(ctx); err != nil {
+		return err
+	}
 	slog.DebugContext(ctx, "mock: setting primary email",
 		"user_id", redaction.Redact(userID),
 	)
 
 	existingUser, exists := u.users[userID]
 	if !exists {
-		return errors.NewNotFound("user not found")
+		return errors.NewUserNotFound("user not found")
 	}
 
 	oldPrimary := existingUser.PrimaryEmail
@@ -573,10 +638,13 @@ func (u *userWriter) SetPrimaryEmail(ctx context.Context, userID string, email s
 // AddSystemManagedEmail is a no-op stub for the mock adapter. It records the
 // email as a verified linked identity on the user so tests can verify it
 // surfaces in GetUserEmails / user_emails.read.
-func (u *userWriter) AddSystemManagedEmail(_ context.Context, primaryUserID, email string) (string, error) {
+func (u *userWriter) AddSystemManagedEmail(ctx context.Context, primaryUserID, email string) (string, error) {
+	if err := u.simulate(ctx); err != nil {
+		return "", err
+	}
 	user, exists := u.users[primaryUserID]
 	if !exists {
-		return "", errors.NewNotFound("user not found")
+		return "", errors.NewUserNotFound("user not found")
 	}
 	stubID := "email|mock-" + email
 	for _, id := range user.Identities {
@@ -594,6 +662,237 @@ func (u *userWriter) AddSystemManagedEmail(_ context.Context, primaryUserID, ema
 	return stubID, nil
 }
 
+// SearchUserCandidates returns mock users whose username, email or name contains query.
+func (u *userWriter) SearchUserCandidates(ctx context.Context, query string, limit int) ([]*model.User, error) {
+	if err := u.simulate(ctx); err != nil {
+		return nil, err
+	}
+	if query == "" {
+		return nil, errors.NewValidation("query is required")
+	}
+	// users are indexed under several keys; report each once
+	seen := make(map[string]bool)
+	var candidates []*model.User
+	for _, user := range u.users {
+		if seen[user.UserID] || !user.MatchesSearch(query) {
+			continue
+		}
+		seen[user.UserID] = true
+		candidates = append(candidates, user)
+		if limit > 0 && len(candidates) == limit {
+			break
+		}
+	}
+	slog.DebugContext(ctx, "mock: user candidates listed", "count", len(candidates))
+	return candidates, nil
+}
+
+// ExportUsers returns every mock user once.
+func (u *userWriter) ExportUsers(ctx context.Context) ([]*model.User, error) {
+	if err := u.simulate(ctx); err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var users []*model.User
+	for _, user := range u.users {
+		if seen[user.UserID] {
+			continue
+		}
+		seen[user.UserID] = true
+		users = append(users, user)
+	}
+	slog.DebugContext(ctx, "mock: users exported", "count", len(users))
+	return users, nil
+}
+
+// CreateServiceAccount stores a service account in memory with a random client ID and secret.
+func (u *userWriter) CreateServiceAccount(ctx context.Context, account *model.ServiceAccount) (*model.ServiceAccountCredentials, error) {
+	if err := u.simulate(ctx); err != nil {
+		return nil, err
+	}
+	if account == nil || account.Name == "" {
+		return nil, errors.NewValidation("service account name is required")
+	}
+	clientID, err := randomHex(16)
+	if err != nil {
+		return nil, errors.NewUnexpected("failed to generate client id", err)
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return nil, errors.NewUnexpected("failed to generate client secret", err)
+	}
+
+	now := time.Now().UTC()
+	stored := *account
+	stored.ClientID = "mock-" + clientID
+	stored.CreatedAt = &now
+
+	u.serviceAccountMutex.Lock()
+	defer u.serviceAccountMutex.Unlock()
+	if u.serviceAccounts == nil {
+		u.serviceAccounts = make(map[string]*model.ServiceAccount)
+	}
+	u.serviceAccounts[stored.ClientID] = &stored
+
+	slog.DebugContext(ctx, "mock: service account created", "client_id", stored.ClientID)
+	return &model.ServiceAccountCredentials{ServiceAccount: stored, ClientSecret: secret}, nil
+}
+
+// ListServiceAccounts returns the service accounts created since startup.
+func (u *userWriter) ListServiceAccounts(ctx context.Context) ([]*model.ServiceAccount, error) {
+	if err := u.simulate(ctx); err != nil {
+		return nil, err
+	}
+	u.serviceAccountMutex.Lock()
+	defer u.serviceAccountMutex.Unlock()
+	accounts := make([]*model.ServiceAccount, 0, len(u.serviceAccounts))
+	for _, account := range u.serviceAccounts {
+		copied := *account
+		accounts = append(accounts, &copied)
+	}
+	slog.DebugContext(ctx, "mock: service accounts listed", "count", len(accounts))
+	return accounts, nil
+}
+
+// RotateServiceAccountSecret issues a new random secret for a stored service account.
+func (u *userWriter) RotateServiceAccountSecret(ctx context.Context, clientID, rotatedBy string) (*model.ServiceAccountCredentials, error) {
+	if err := u.simulate(ctx); err != nil {
+		return nil, err
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return nil, errors.NewUnexpected("failed to generate client secret", err)
+	}
+
+	u.serviceAccountMutex.Lock()
+	defer u.serviceAccountMutex.Unlock()
+	account, exists := u.serviceAccounts[clientID]
+	if !exists {
+		return nil, errors.NewNotFound("service account not found")
+	}
+	now := time.Now().UTC()
+	account.RotatedBy = rotatedBy
+	account.RotatedAt = &now
+
+	slog.DebugContext(ctx, "mock: service account secret rotated", "client_id", clientID)
+	return &model.ServiceAccountCredentials{ServiceAccount: *account, ClientSecret: secret}, nil
+}
+
+// ListSessions returns the sessions of a user. Every user starts with one
+// login session and one refresh token, so the profile UI has something to show.
+func (u *userWriter) ListSessions(ctx context.Context, userID string) ([]*model.UserSession, error) {
+	if err := u.simulate(ctx); err != nil {
+		return nil, err
+	}
+	u.sessionMutex.Lock()
+	defer u.sessionMutex.Unlock()
+	if u.sessions == nil {
+		u.sessions = make(map[string][]*model.UserSession)
+	}
+	sessions, seeded := u.sessions[userID]
+	if !seeded {
+		created := time.Now().UTC().Add(-time.Hour)
+		expires := created.Add(7 * 24 * time.Hour)
+		sessions = []*model.UserSession{
+			{ID: "mock-session-1", Type: model.SessionTypeLogin, Clients: []string{"mock-client"}, CreatedAt: &created, LastActiveAt: &created, ExpiresAt: &expires, UserAgent: "Mozilla/5.0", IP: "127.0.0.0"},
+			{ID: "mock-refresh-token-1", Type: model.SessionTypeRefreshToken, SessionID: "mock-session-1", Clients: []string{"mock-client"}, CreatedAt: &created, ExpiresAt: &expires, UserAgent: "Mozilla/5.0", IP: "127.0.0.0"},
+		}
+		u.sessions[userID] = sessions
+	}
+
+	listed := make([]*model.UserSession, 0, len(sessions))
+	for _, session := range sessions {
+		copied := *session
+		listed = append(listed, &copied)
+	}
+	slog.DebugContext(ctx, "mock: sessions listed", "count", len(listed))
+	return listed, nil
+}
+
+// RevokeSession removes one session of a user; revoking a login session also
+// removes the refresh tokens issued in it.
+func (u *userWriter) RevokeSession(ctx context.Context, userID, sessionType, sessionID string) error {
+	if err := u.simulate(ctx); err != nil {
+		return err
+	}
+	u.sessionMutex.Lock()
+	defer u.sessionMutex.Unlock()
+	sessions := u.sessions[userID]
+	kept := make([]*model.UserSession, 0, len(sessions))
+	found := false
+	for _, session := range sessions {
+		if session.Type == sessionType && session.ID == sessionID {
+			found = true
+			continue
+		}
+		if sessionType == model.SessionTypeLogin && session.SessionID == sessionID {
+			continue
+		}
+		kept = append(kept, session)
+	}
+	if !found {
+		return errors.NewNotFound("session not found")
+	}
+	u.sessions[userID] = kept
+	slog.DebugContext(ctx, "mock: session revoked", "type", sessionType, "session_id", sessionID)
+	return nil
+}
+
+// RevokeAllSessions removes every session of a user
+func (u *userWriter) RevokeAllSessions(ctx context.Context, userID string) error {
+	if err := u.simulate(ctx); err != nil {
+		return err
+	}
+	u.sessionMutex.Lock()
+	defer u.sessionMutex.Unlock()
+	if u.sessions == nil {
+		u.sessions = make(map[string][]*model.UserSession)
+	}
+	u.sessions[userID] = []*model.UserSession{}
+	slog.DebugContext(ctx, "mock: all sessions revoked")
+	return nil
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// ListDormantUsers returns mock users whose activity.last_login is before cutoff.
+// Users without recorded activity are skipped, matching Auth0's range query.
+func (u *userWriter) ListDormantUsers(ctx context.Context, cutoff time.Time, limit int) ([]*model.User, error) {
+	seen := make(map[string]bool)
+	var dormant []*model.User
+	for _, user := range u.users {
+		if seen[user.UserID] {
+			continue
+		}
+		seen[user.UserID] = true
+
+		if user.Activity == nil || user.Activity.LastLogin == nil || !user.Activity.LastLogin.Before(cutoff) {
+			continue
+		}
+		dormant = append(dormant, user)
+		if limit > 0 && len(dormant) == limit {
+			break
+		}
+	}
+	slog.DebugContext(ctx, "mock: dormant users listed", "count", len(dormant))
+	return dormant, nil
+}
+
+// TagDormantUser only logs in the mock adapter; there is no app_metadata to update.
+func (u *userWriter) TagDormantUser(ctx context.Context, userID string, since time.Time) error {
+	if _, exists := u.users[userID]; !exists {
+		return errors.NewUserNotFound("user not found")
+	}
+	slog.InfoContext(ctx, "mock: tagged dormant user", "user_id", redaction.Redact(userID), "since", since)
+	return nil
+}
+
 // MetadataLookup resolves a user via metadata lookup in the mock store.
 func (u *userWriter) MetadataLookup(ctx context.Context, input string, requiredScopes ...string) (*model.User, error) {
 	slog.DebugContext(ctx, "mock: metadata lookup", "input", redaction.Redact(input))
@@ -607,14 +906,29 @@ func (u *userWriter) MetadataLookup(ctx context.Context, input string, requiredS
 	user := &model.User{}
 
 	// First, try to parse as JWT token to extract the sub
-	if cleanToken, isJWT := jwt.LooksLikeJWT(input); isJWT {
+	if cleanToken, isJWT := jwt.LooksLikeJWT(input); isJWT && u.jwtSecret != nil {
+		claims, err := jwt.ParseVerified(ctx, cleanToken, &jwt.ParseOptions{
+			RequireExpiration: true,
+			RequireSubject:    true,
+			VerifySignature:   true,
+			HMACKey:           u.jwtSecret,
+			RequiredScopes:    requiredScopes,
+		})
+		if err != nil {
+			slog.WarnContext(ctx, "mock: JWT verification failed", "error", err)
+			return nil, errors.NewUnauthorized("invalid token", err)
+		}
+		input = claims.Subject
+		user.Token = cleanToken
+	} else if isJWT {
 		sub, err := u.extractSubFromJWT(ctx, cleanToken)
 		if err != nil {
 			slog.WarnContext(ctx, "mock: failed to parse JWT, treating as regular input", "error", err)
 			// If JWT parsing fails, fall back to regular input processing
 		} else {
-			// Successfully extracted sub from JWT
+			// Successfully extracted sub from JWT; the mock treats a parsed token as verified
 			input = sub
+			user.Token = cleanToken
 			slog.InfoContext(ctx, "mock: extracted sub from JWT", "sub", sub)
 		}
 	}
@@ -660,25 +974,32 @@ func (u *userWriter) extractSubFromJWT(ctx context.Context, tokenString string)
 }
 
 // NewUserReaderWriter creates a new mock UserReaderWriter with YAML file as the data source
-func NewUserReaderWriter(ctx context.Context) port.UserReaderWriter {
-	users := make(map[string]*model.User)
-	otps := make(map[string]*otpEntry)
+func NewUserReaderWriter(ctx context.Context, opts ...Option) port.UserReaderWriter {
+	u := &userWriter{
+		users:     make(map[string]*model.User),
+		otps:      make(map[string]*otpEntry),
+		usersYAML: usersYAML,
+	}
+	for _, opt := range opts {
+		opt(u)
+	}
 
-	// Load users from embedded YAML file
-	mockUsers, err := loadUsersFromYAML(ctx)
+	// Load users from the YAML document
+	mockUsers, err := loadUsersFromYAML(ctx, u.usersYAML)
 	if err != nil {
 		slog.ErrorContext(ctx, "failed to load users from YAML file", "error", err)
-		return &userWriter{users: users, otps: otps} // Return empty store if YAML fails
+		return u // Return empty store if YAML fails
 	}
 
 	if len(mockUsers) == 0 {
 		slog.WarnContext(ctx, "no users found in YAML file")
-		return &userWriter{users: users, otps: otps} // Return empty store if no users
+		return u // Return empty store if no users
 	}
 
 	slog.InfoContext(ctx, "successfully loaded users from YAML file", "count", len(mockUsers))
 
 	// Add users to storage with multiple keys for lookup flexibility
+	users := u.users
 	for _, user := range mockUsers {
 		// Add by user_id (primary key)
 		if user.UserID != "" {
@@ -713,9 +1034,5 @@ func NewUserReaderWriter(ctx context.Context) port.UserReaderWriter {
 
 	slog.InfoContext(ctx, "mock: initialized user store", "total_users", len(mockUsers), "total_keys", len(users))
 
-	return &userWriter{
-		users:    users,
-		otps:     otps,
-		otpMutex: sync.RWMutex{},
-	}
+	return u
 }
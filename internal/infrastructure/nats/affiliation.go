@@ -0,0 +1,76 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package nats
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// affiliationStore implements port.AffiliationStore on a NATS KV bucket. The
+// whole history of a user is one value, under the SHA-256 of the sub, so the
+// overlap checks see every affiliation and writes are compare-and-set.
+type affiliationStore struct {
+	kv jetstream.KeyValue
+}
+
+func affiliationKey(userID string) string {
+	hash := sha256.Sum256([]byte(userID))
+	return hex.EncodeToString(hash[:])
+}
+
+// GetAffiliations reads the history of the user and its revision
+func (s *affiliationStore) GetAffiliations(ctx context.Context, userID string) (*model.AffiliationHistory, error) {
+	entry, err := s.kv.Get(ctx, affiliationKey(userID))
+	if err != nil {
+		if errors.Is(err, jetstream.ErrKeyNotFound) {
+			return &model.AffiliationHistory{UserID: userID}, nil
+		}
+		return nil, errs.NewUnexpected("failed to get affiliations", err)
+	}
+	var history model.AffiliationHistory
+	if err := json.Unmarshal(entry.Value(), &history); err != nil {
+		return nil, errs.NewUnexpected("failed to unmarshal affiliations", err)
+	}
+	history.Revision = entry.Revision()
+	return &history, nil
+}
+
+// SaveAffiliations creates the key of a new history and updates the key of
+// an existing one at its revision
+func (s *affiliationStore) SaveAffiliations(ctx context.Context, history *model.AffiliationHistory) error {
+	value, err := json.Marshal(history)
+	if err != nil {
+		return errs.NewUnexpected("failed to marshal affiliations", err)
+	}
+
+	key := affiliationKey(history.UserID)
+	var revision uint64
+	if history.Revision == 0 {
+		revision, err = s.kv.Create(ctx, key, value)
+	} else {
+		revision, err = s.kv.Update(ctx, key, value, history.Revision)
+	}
+	if err != nil {
+		if errors.Is(err, jetstream.ErrKeyExists) {
+			return errs.NewConflict("affiliations changed concurrently, retry", err)
+		}
+		return errs.NewUnexpected("failed to store affiliations", err)
+	}
+	history.Revision = revision
+	return nil
+}
+
+// NewAffiliationStore returns an affiliation store backed by kv
+func NewAffiliationStore(kv jetstream.KeyValue) port.AffiliationStore {
+	return &affiliationStore{kv: kv}
+}
@@ -0,0 +1,82 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package nats
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// attestationStore implements port.AttestationStore on a NATS KV bucket. An
+// attestation is kept under <user>.<field>, the SHA-256 of the sub followed
+// by the metadata field name, which is a valid key token as it is.
+type attestationStore struct {
+	kv jetstream.KeyValue
+}
+
+func attestationUserPrefix(userID string) string {
+	hash := sha256.Sum256([]byte(userID))
+	return hex.EncodeToString(hash[:]) + "."
+}
+
+// PutAttestation stores the attestation under its user and field
+func (s *attestationStore) PutAttestation(ctx context.Context, attestation *model.FieldAttestation) error {
+	value, err := json.Marshal(attestation)
+	if err != nil {
+		return errs.NewUnexpected("failed to marshal attestation", err)
+	}
+	if _, err := s.kv.Put(ctx, attestationUserPrefix(attestation.UserID)+attestation.Field, value); err != nil {
+		return errs.NewUnexpected("failed to store attestation", err)
+	}
+	return nil
+}
+
+// DeleteAttestation deletes the key of the user and field
+func (s *attestationStore) DeleteAttestation(ctx context.Context, userID, field string) error {
+	if err := s.kv.Delete(ctx, attestationUserPrefix(userID)+field); err != nil && !errors.Is(err, jetstream.ErrKeyNotFound) {
+		return errs.NewUnexpected("failed to delete attestation", err)
+	}
+	return nil
+}
+
+// ListAttestations returns the attestations under the user's prefix
+func (s *attestationStore) ListAttestations(ctx context.Context, userID string) ([]*model.FieldAttestation, error) {
+	lister, err := s.kv.ListKeysFiltered(ctx, attestationUserPrefix(userID)+"*")
+	if err != nil {
+		return nil, errs.NewUnexpected("failed to list attestations", err)
+	}
+	defer func() { _ = lister.Stop() }()
+
+	var attestations []*model.FieldAttestation
+	for key := range lister.Keys() {
+		entry, err := s.kv.Get(ctx, key)
+		if err != nil {
+			if !errors.Is(err, jetstream.ErrKeyNotFound) {
+				slog.WarnContext(ctx, "skipping attestation", "error", err, "key", key)
+			}
+			continue
+		}
+		var attestation model.FieldAttestation
+		if err := json.Unmarshal(entry.Value(), &attestation); err != nil {
+			slog.WarnContext(ctx, "skipping attestation", "error", err, "key", key)
+			continue
+		}
+		attestations = append(attestations, &attestation)
+	}
+	return attestations, nil
+}
+
+// NewAttestationStore returns an attestation store backed by kv
+func NewAttestationStore(kv jetstream.KeyValue) port.AttestationStore {
+	return &attestationStore{kv: kv}
+}
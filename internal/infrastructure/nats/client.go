@@ -5,13 +5,23 @@ package nats
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"os"
+	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/cloudevents"
 	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/deadline"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/envelope"
 	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/featureflag"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/idempotency"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/jwt"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/tenant"
 
 	"github.com/nats-io/nats.go"
 	"github.com/nats-io/nats.go/jetstream"
@@ -26,7 +36,15 @@ type NATSClient struct {
 	conn    *nats.Conn
 	config  Config
 	kvStore map[string]jetstream.KeyValue
+	objects map[string]jetstream.ObjectStore
 	timeout time.Duration
+	pools   *workerPools
+	// tenant namespaces the subjects and KV buckets of a tenant's client;
+	// empty for single-tenant deployments
+	tenant string
+
+	inFlight atomic.Int64
+	handled  atomic.Int64
 }
 
 // NATSClientInterface defines the interface for NATS operations
@@ -55,7 +73,21 @@ func (c *NATSClient) IsReady(ctx context.Context) error {
 	return nil
 }
 
+// StopWorkers refuses the requests delivered from now on and waits for the
+// worker pools to finish the queued and in-flight ones. Tenant clients share
+// the pools, so stopping one client stops them all.
+func (c *NATSClient) StopWorkers() {
+	c.pools.stop()
+}
+
+// RequestStats returns the number of requests being processed and handled so far
+func (c *NATSClient) RequestStats() (inFlight, total int64) {
+	return c.inFlight.Load(), c.handled.Load()
+}
+
 // KeyValueStore creates a JetStream client and gets the key-value store for projects.
+// A tenant's client opens the tenant's copy of the bucket, still found by
+// GetKVStore under bucketName.
 func (c *NATSClient) KeyValueStore(ctx context.Context, bucketName string) error {
 	js, err := jetstream.New(c.conn)
 	if err != nil {
@@ -65,12 +97,12 @@ func (c *NATSClient) KeyValueStore(ctx context.Context, bucketName string) error
 		)
 		return err
 	}
-	kvStore, err := js.KeyValue(ctx, bucketName)
+	kvStore, err := js.KeyValue(ctx, tenant.Bucket(c.tenant, bucketName))
 	if err != nil {
 		slog.ErrorContext(ctx, "error getting NATS JetStream key-value store",
 			"error", err,
 			"nats_url", c.conn.ConnectedUrl(),
-			"bucket", bucketName,
+			"bucket", tenant.Bucket(c.tenant, bucketName),
 		)
 		return err
 	}
@@ -91,11 +123,40 @@ func (c *NATSClient) GetKVStore(bucketName string) (jetstream.KeyValue, bool) {
 	return kvStore, exists
 }
 
+// ObjectStore gets the object store bucketName, found by GetObjectStore
+// afterwards. Like KV buckets, a tenant's client opens the tenant's copy.
+func (c *NATSClient) ObjectStore(ctx context.Context, bucketName string) error {
+	js, err := jetstream.New(c.conn)
+	if err != nil {
+		return err
+	}
+	objectStore, err := js.ObjectStore(ctx, tenant.Bucket(c.tenant, bucketName))
+	if err != nil {
+		return err
+	}
+
+	if c.objects == nil {
+		c.objects = make(map[string]jetstream.ObjectStore)
+	}
+	c.objects[bucketName] = objectStore
+	return nil
+}
+
+// GetObjectStore returns the object store for a given bucket name
+func (c *NATSClient) GetObjectStore(bucketName string) (jetstream.ObjectStore, bool) {
+	objectStore, exists := c.objects[bucketName]
+	return objectStore, exists
+}
+
 // Publish publishes a message to a NATS subject with an OTel producer span.
+// CloudEvents attributes are attached as headers (binary content mode), so the
+// body is delivered exactly as given. A tenant's client publishes to the
+// tenant's namespace.
 func (c *NATSClient) Publish(ctx context.Context, subject string, data []byte) error {
 	if err := c.IsReady(ctx); err != nil {
 		return err
 	}
+	subject = tenant.Subject(c.tenant, subject)
 
 	ctx, span := tracer.Start(ctx, "nats.publish",
 		trace.WithSpanKind(trace.SpanKindProducer),
@@ -111,6 +172,9 @@ func (c *NATSClient) Publish(ctx context.Context, subject string, data []byte) e
 	msg := nats.NewMsg(subject)
 	msg.Header = make(nats.Header)
 	msg.Data = data
+	for key, value := range cloudevents.New(subject).Headers(cloudevents.NATSHeaderPrefix) {
+		msg.Header.Set(key, value)
+	}
 	otel.GetTextMapPropagator().Inject(ctx, natsHeaderCarrier(msg.Header))
 
 	if err := c.conn.PublishMsg(msg); err != nil {
@@ -129,37 +193,194 @@ func (c *NATSClient) SubscribeWithTransportMessenger(ctx context.Context, subjec
 	}
 
 	return c.conn.QueueSubscribe(subject, queueName, func(msg *nats.Msg) {
-		// Extract trace context from incoming message headers and start a consumer span.
-		msgCtx := otel.GetTextMapPropagator().Extract(ctx, natsHeaderCarrier(msg.Header))
-		msgCtx, span := tracer.Start(msgCtx, "nats.process",
-			trace.WithSpanKind(trace.SpanKindConsumer),
-			trace.WithAttributes(
-				attribute.String("messaging.system", "nats"),
-				attribute.String("messaging.destination.name", subject),
-				attribute.String("messaging.operation.type", "process"),
-				attribute.String("messaging.consumer.group.name", queueName),
-				attribute.Int("messaging.message.body.size", len(msg.Data)),
-			),
-		)
-		defer span.End()
-
-		transportMsg := NewTransportMessenger(msg)
-
-		defer func() {
-			if r := recover(); r != nil {
-				slog.ErrorContext(msgCtx, "panic in NATS handler",
-					"subject", subject,
-					"queue", queueName,
-					"panic", r,
-				)
-				span.SetStatus(codes.Error, "panic in NATS handler")
+		c.pools.run(ctx, requestPriority(PriorityInteractive, msg.Header), func(ctx context.Context) {
+			if port.Overloaded(ctx) && msg.Reply == "" {
+				// nobody to tell it was refused
+				return
 			}
-		}()
+			c.processMsg(ctx, subject, queueName, msg, handler)
+		})
+	})
+}
 
-		handler(msgCtx, transportMsg)
+// SubscribeBroadcast subscribes to a subject under the client's tenant
+// namespace without a queue group, so every replica gets every message.
+// handler runs on the subscription's goroutine and must not block.
+func (c *NATSClient) SubscribeBroadcast(ctx context.Context, subject string, handler func(context.Context, []byte)) (*nats.Subscription, error) {
+	if err := c.IsReady(ctx); err != nil {
+		return nil, err
+	}
+
+	return c.conn.Subscribe(tenant.Subject(c.tenant, subject), func(msg *nats.Msg) {
+		handler(otel.GetTextMapPropagator().Extract(ctx, natsHeaderCarrier(msg.Header)), msg.Data)
 	})
 }
 
+// processMsg runs handler for one message of a queue subscription
+func (c *NATSClient) processMsg(ctx context.Context, subject, queueName string, msg *nats.Msg, handler func(context.Context, port.TransportMessenger)) {
+	// Extract trace context from incoming message headers and start a consumer span.
+	msgCtx := otel.GetTextMapPropagator().Extract(ctx, natsHeaderCarrier(msg.Header))
+	msgCtx, span := tracer.Start(msgCtx, "nats.process",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "nats"),
+			attribute.String("messaging.destination.name", subject),
+			attribute.String("messaging.operation.type", "process"),
+			attribute.String("messaging.consumer.group.name", queueName),
+			attribute.Int("messaging.message.body.size", len(msg.Data)),
+		),
+	)
+	defer span.End()
+	if proof := msg.Header.Get(jwt.DPoPHeader); proof != "" {
+		msgCtx = jwt.WithDPoPRequest(msgCtx, proof, jwt.DPoPNATSMethod, jwt.NATSDPoPURI(subject))
+	}
+	msgCtx = featureflag.Context(msgCtx, msg.Header)
+	msgCtx = idempotency.Context(msgCtx, msg.Header)
+	msgCtx = envelope.Context(msgCtx, msg.Header)
+	msgCtx, cancel := deadline.Context(msgCtx, msg.Header)
+	defer cancel()
+
+	transportMsg := NewTransportMessenger(msg, c.replyOptions())
+
+	defer func() {
+		if r := recover(); r != nil {
+			slog.ErrorContext(msgCtx, "panic in NATS handler",
+				"subject", subject,
+				"queue", queueName,
+				"panic", r,
+			)
+			span.SetStatus(codes.Error, "panic in NATS handler")
+		}
+	}()
+
+	handler(msgCtx, transportMsg)
+}
+
+// ForTenant returns a client for tenant sharing this client's connection and
+// worker pools. It registers service endpoints and publishes under the
+// tenant's subjects, and opens the tenant's copies of the KV buckets the
+// features enabled in the environment need, which must already exist.
+func (c *NATSClient) ForTenant(ctx context.Context, name string) (*NATSClient, error) {
+	if err := tenant.Validate(name); err != nil {
+		return nil, errors.NewValidation(err.Error())
+	}
+	client := &NATSClient{
+		conn:    c.conn,
+		config:  c.config,
+		timeout: c.timeout,
+		pools:   c.pools,
+		tenant:  name,
+	}
+	if err := client.openBuckets(ctx); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// openBuckets opens the KV buckets and object stores of the features enabled
+// in the environment
+func (c *NATSClient) openBuckets(ctx context.Context) error {
+	for _, bucketName := range requiredBuckets() {
+		if err := c.KeyValueStore(ctx, bucketName); err != nil {
+			slog.ErrorContext(ctx, "failed to initialize NATS key-value store",
+				"error", err,
+				"bucket", tenant.Bucket(c.tenant, bucketName),
+			)
+			return errors.NewServiceUnavailable(fmt.Sprintf("failed to initialize NATS key-value store %s", tenant.Bucket(c.tenant, bucketName)), err)
+		}
+		slog.InfoContext(ctx, "NATS key-value store initialized",
+			"bucket", tenant.Bucket(c.tenant, bucketName),
+		)
+	}
+	for _, bucketName := range requiredObjectStores() {
+		if err := c.ObjectStore(ctx, bucketName); err != nil {
+			slog.ErrorContext(ctx, "failed to initialize NATS object store",
+				"error", err,
+				"bucket", tenant.Bucket(c.tenant, bucketName),
+			)
+			return errors.NewServiceUnavailable(fmt.Sprintf("failed to initialize NATS object store %s", tenant.Bucket(c.tenant, bucketName)), err)
+		}
+		slog.InfoContext(ctx, "NATS object store initialized",
+			"bucket", tenant.Bucket(c.tenant, bucketName),
+		)
+	}
+	return nil
+}
+
+// requiredBuckets are the KV buckets the configured features store data in
+func requiredBuckets() []string {
+	var buckets []string
+	// Check if Authelia is enabled with the NATS user store, as the provider or
+	// as the secondary provider of shadow reads, by checking the environment
+	// variables directly
+	autheliaKV := func(prefix string) bool {
+		store := os.Getenv(prefix + constants.AutheliaUserStoreEnvKey)
+		return os.Getenv(prefix+constants.UserRepositoryTypeEnvKey) == constants.UserRepositoryTypeAuthelia &&
+			(store == "" || store == constants.AutheliaUserStoreNATS)
+	}
+	if autheliaKV("") || autheliaKV(constants.ShadowEnvPrefix) {
+		buckets = append(buckets, constants.KVBucketNameAutheliaUsers)
+		buckets = append(buckets, constants.KVBucketNameAutheliaEmailOTP)
+	}
+	if inactiveAfter, _ := time.ParseDuration(os.Getenv(constants.AutheliaArchiveInactiveAfterEnvKey)); autheliaKV("") && inactiveAfter > 0 {
+		buckets = append(buckets, constants.KVBucketNameAutheliaUsersArchive)
+	}
+	if enabled, _ := strconv.ParseBool(os.Getenv(constants.PersonalAccessTokensEnabledEnvKey)); enabled {
+		buckets = append(buckets, constants.KVBucketNamePersonalAccessTokens)
+	}
+	if enabled, _ := strconv.ParseBool(os.Getenv(constants.TokenRevocationEnabledEnvKey)); enabled {
+		buckets = append(buckets, constants.KVBucketNameTokenRevocations)
+	}
+	if enabled, _ := strconv.ParseBool(os.Getenv(constants.FeatureFlagsKVEnabledEnvKey)); enabled {
+		buckets = append(buckets, constants.KVBucketNameFeatureFlags)
+	}
+	if enabled, _ := strconv.ParseBool(os.Getenv(constants.PanicQuarantineEnabledEnvKey)); enabled {
+		buckets = append(buckets, constants.KVBucketNameQuarantine)
+	}
+	if enabled, _ := strconv.ParseBool(os.Getenv(constants.IdempotencyEnabledEnvKey)); enabled {
+		buckets = append(buckets, constants.KVBucketNameIdempotency)
+	}
+	if enabled, _ := strconv.ParseBool(os.Getenv(constants.JobLockEnabledEnvKey)); enabled {
+		buckets = append(buckets, constants.KVBucketNameJobLocks)
+	}
+	if enabled, _ := strconv.ParseBool(os.Getenv(constants.MaintenanceKVEnabledEnvKey)); enabled {
+		buckets = append(buckets, constants.KVBucketNameMaintenance)
+	}
+	if enabled, _ := strconv.ParseBool(os.Getenv(constants.OutboxEnabledEnvKey)); enabled {
+		buckets = append(buckets, constants.KVBucketNameOutbox)
+	}
+	if enabled, _ := strconv.ParseBool(os.Getenv(constants.EmailOrganizationsEnabledEnvKey)); enabled {
+		buckets = append(buckets, constants.KVBucketNameEmailOrganizations)
+	}
+	if enabled, _ := strconv.ParseBool(os.Getenv(constants.UserMergeHistoryEnabledEnvKey)); enabled {
+		buckets = append(buckets, constants.KVBucketNameUserMerges)
+	}
+	if enabled, _ := strconv.ParseBool(os.Getenv(constants.ConsentEnabledEnvKey)); enabled {
+		buckets = append(buckets, constants.KVBucketNameUserConsents)
+	}
+	if enabled, _ := strconv.ParseBool(os.Getenv(constants.AttestationEnabledEnvKey)); enabled {
+		buckets = append(buckets, constants.KVBucketNameUserAttestations)
+	}
+	if enabled, _ := strconv.ParseBool(os.Getenv(constants.AffiliationsEnabledEnvKey)); enabled {
+		buckets = append(buckets, constants.KVBucketNameUserAffiliations)
+	}
+	hotUsers, _ := strconv.Atoi(os.Getenv(constants.WarmUpHotUsersEnvKey))
+	if hotUsers > 0 || os.Getenv(constants.IdentifierCacheSnapshotEnvKey) == constants.IdentifierCacheSnapshotKV {
+		buckets = append(buckets, constants.KVBucketNameCacheWarmup)
+	}
+	return buckets
+}
+
+// requiredObjectStores are the object stores the configured features store
+// files in
+func requiredObjectStores() []string {
+	var buckets []string
+	if enabled, _ := strconv.ParseBool(os.Getenv(constants.UserExportObjectStoreEnabledEnvKey)); enabled {
+		buckets = append(buckets, constants.ObjectStoreNameUserExports)
+	}
+	return buckets
+}
+
 // NewClient creates a new NATS client with the given configuration
 func NewClient(ctx context.Context, config Config) (*NATSClient, error) {
 	slog.InfoContext(ctx, "creating NATS client",
@@ -206,26 +427,17 @@ func NewClient(ctx context.Context, config Config) (*NATSClient, error) {
 		conn:    conn,
 		config:  config,
 		timeout: config.Timeout,
+		pools:   newWorkerPools(config.Workers, config.BatchWorkerShare),
 	}
-
-	var buckets []string
-	// Check if Authelia is enabled by checking the environment variable directly
-	if os.Getenv(constants.UserRepositoryTypeEnvKey) == constants.UserRepositoryTypeAuthelia {
-		buckets = append(buckets, constants.KVBucketNameAutheliaUsers)
-		buckets = append(buckets, constants.KVBucketNameAutheliaEmailOTP)
+	if client.pools != nil {
+		slog.InfoContext(ctx, "NATS request worker pools",
+			"interactive_workers", client.pools.capacity(PriorityInteractive),
+			"batch_workers", client.pools.capacity(PriorityBatch),
+		)
 	}
 
-	for _, bucketName := range buckets {
-		if err := client.KeyValueStore(ctx, bucketName); err != nil {
-			slog.ErrorContext(ctx, "failed to initialize NATS key-value store",
-				"error", err,
-				"bucket", bucketName,
-			)
-			return nil, errors.NewServiceUnavailable("failed to initialize NATS key-value store", err)
-		}
-		slog.InfoContext(ctx, "NATS key-value store initialized",
-			"bucket", bucketName,
-		)
+	if err := client.openBuckets(ctx); err != nil {
+		return nil, err
 	}
 
 	slog.InfoContext(ctx, "NATS client created successfully",
@@ -0,0 +1,93 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package nats
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// consentStore implements port.ConsentStore on a NATS KV bucket. An
+// acceptance is kept under <user>.<version>, the SHA-256 of the sub and of
+// the policy and version: both may contain characters KV keys don't allow.
+// Keys are only ever created, so a stored acceptance can't be overwritten.
+type consentStore struct {
+	kv jetstream.KeyValue
+}
+
+func consentUserPrefix(userID string) string {
+	hash := sha256.Sum256([]byte(userID))
+	return hex.EncodeToString(hash[:]) + "."
+}
+
+func consentKey(acceptance *model.PolicyAcceptance) string {
+	hash := sha256.Sum256([]byte(acceptance.Policy + "\x00" + acceptance.Version))
+	return consentUserPrefix(acceptance.UserID) + hex.EncodeToString(hash[:])
+}
+
+// RecordAcceptance creates the acceptance key, or returns the acceptance
+// already under it
+func (s *consentStore) RecordAcceptance(ctx context.Context, acceptance *model.PolicyAcceptance) (*model.PolicyAcceptance, error) {
+	value, err := json.Marshal(acceptance)
+	if err != nil {
+		return nil, errs.NewUnexpected("failed to marshal policy acceptance", err)
+	}
+	key := consentKey(acceptance)
+	if _, err := s.kv.Create(ctx, key, value); err != nil {
+		if errors.Is(err, jetstream.ErrKeyExists) {
+			return s.get(ctx, key)
+		}
+		return nil, errs.NewUnexpected("failed to store policy acceptance", err)
+	}
+	return acceptance, nil
+}
+
+func (s *consentStore) get(ctx context.Context, key string) (*model.PolicyAcceptance, error) {
+	entry, err := s.kv.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, jetstream.ErrKeyNotFound) {
+			return nil, errs.NewNotFound("policy acceptance not found")
+		}
+		return nil, errs.NewUnexpected("failed to get policy acceptance", err)
+	}
+	var acceptance model.PolicyAcceptance
+	if err := json.Unmarshal(entry.Value(), &acceptance); err != nil {
+		return nil, errs.NewUnexpected("failed to unmarshal policy acceptance", err)
+	}
+	return &acceptance, nil
+}
+
+// ListAcceptances returns the acceptances under the user's prefix
+func (s *consentStore) ListAcceptances(ctx context.Context, userID string) ([]*model.PolicyAcceptance, error) {
+	lister, err := s.kv.ListKeysFiltered(ctx, consentUserPrefix(userID)+"*")
+	if err != nil {
+		return nil, errs.NewUnexpected("failed to list policy acceptances", err)
+	}
+	defer func() { _ = lister.Stop() }()
+
+	var acceptances []*model.PolicyAcceptance
+	for key := range lister.Keys() {
+		acceptance, err := s.get(ctx, key)
+		if err != nil {
+			slog.WarnContext(ctx, "skipping policy acceptance", "error", err, "key", key)
+			continue
+		}
+		acceptances = append(acceptances, acceptance)
+	}
+	return acceptances, nil
+}
+
+// NewConsentStore returns a consent store backed by kv
+func NewConsentStore(kv jetstream.KeyValue) port.ConsentStore {
+	return &consentStore{kv: kv}
+}
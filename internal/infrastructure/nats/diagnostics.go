@@ -0,0 +1,48 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package nats
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// CheckConfig connects to NATS and opens every KV bucket the configured
+// features need, reporting each missing bucket rather than the first one
+func CheckConfig(ctx context.Context, config Config) []model.ConfigCheck {
+	var conn *nats.Conn
+	checks := []model.ConfigCheck{
+		model.RunConfigCheck("nats.connection", func() (string, string) {
+			var err error
+			conn, err = nats.Connect(config.URL, nats.Name(constants.ServiceName), nats.Timeout(config.Timeout))
+			if err != nil {
+				return model.ConfigCheckFail, fmt.Sprintf("failed to connect to %s: %v", config.URL, err)
+			}
+			return model.ConfigCheckOK, conn.ConnectedUrl()
+		}),
+	}
+	if conn == nil {
+		return checks
+	}
+	defer conn.Close()
+
+	js, err := jetstream.New(conn)
+	for _, bucket := range requiredBuckets() {
+		checks = append(checks, model.RunConfigCheck("nats.kv."+bucket, func() (string, string) {
+			if err != nil {
+				return model.ConfigCheckFail, fmt.Sprintf("JetStream is not available: %v", err)
+			}
+			if _, err := js.KeyValue(ctx, bucket); err != nil {
+				return model.ConfigCheckFail, fmt.Sprintf("bucket %s is not available: %v", bucket, err)
+			}
+			return model.ConfigCheckOK, bucket
+		}))
+	}
+	return checks
+}
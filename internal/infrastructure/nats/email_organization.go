@@ -0,0 +1,93 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// emailOrganizationStore implements port.EmailOrganizationStore on a NATS KV
+// bucket keyed by domain: domains are valid KV keys as they are
+type emailOrganizationStore struct {
+	kv jetstream.KeyValue
+}
+
+// Get returns the mapping of domain
+func (s *emailOrganizationStore) Get(ctx context.Context, domain string) (*model.EmailOrganization, error) {
+	entry, err := s.kv.Get(ctx, domain)
+	if err != nil {
+		if errors.Is(err, jetstream.ErrKeyNotFound) {
+			return nil, errs.NewNotFound("email domain not found")
+		}
+		return nil, errs.NewUnexpected("failed to get email organization", err)
+	}
+	var mapping model.EmailOrganization
+	if err := json.Unmarshal(entry.Value(), &mapping); err != nil {
+		return nil, errs.NewUnexpected("failed to unmarshal email organization", err)
+	}
+	return &mapping, nil
+}
+
+// Put stores the mapping, replacing the domain's current one
+func (s *emailOrganizationStore) Put(ctx context.Context, mapping *model.EmailOrganization) error {
+	value, err := json.Marshal(mapping)
+	if err != nil {
+		return errs.NewUnexpected("failed to marshal email organization", err)
+	}
+	if _, err := s.kv.Put(ctx, mapping.Domain, value); err != nil {
+		return errs.NewUnexpected("failed to store email organization", err)
+	}
+	return nil
+}
+
+// Delete removes the mapping of domain and returns it
+func (s *emailOrganizationStore) Delete(ctx context.Context, domain string) (*model.EmailOrganization, error) {
+	mapping, err := s.Get(ctx, domain)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.kv.Delete(ctx, domain); err != nil && !errors.Is(err, jetstream.ErrKeyNotFound) {
+		return nil, errs.NewUnexpected("failed to delete email organization", err)
+	}
+	return mapping, nil
+}
+
+// List returns every mapping, sorted by domain
+func (s *emailOrganizationStore) List(ctx context.Context) ([]*model.EmailOrganization, error) {
+	lister, err := s.kv.ListKeys(ctx)
+	if err != nil {
+		return nil, errs.NewUnexpected("failed to list email organizations", err)
+	}
+	defer func() { _ = lister.Stop() }()
+
+	var mappings []*model.EmailOrganization
+	for key := range lister.Keys() {
+		mapping, err := s.Get(ctx, key)
+		if err != nil {
+			// deleted since it was listed
+			slog.DebugContext(ctx, "skipping email organization", "error", err, "domain", key)
+			continue
+		}
+		mappings = append(mappings, mapping)
+	}
+	slices.SortFunc(mappings, func(a, b *model.EmailOrganization) int {
+		return strings.Compare(a.Domain, b.Domain)
+	})
+	return mappings, nil
+}
+
+// NewEmailOrganizationStore returns an email organization store backed by kv
+func NewEmailOrganizationStore(kv jetstream.KeyValue) port.EmailOrganizationStore {
+	return &emailOrganizationStore{kv: kv}
+}
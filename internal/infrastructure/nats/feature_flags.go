@@ -0,0 +1,95 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/featureflag"
+)
+
+// featureFlagWatch keeps a flag set in step with the bucket, one key per
+// flag holding its JSON rollout
+type featureFlagWatch struct {
+	kv  jetstream.KeyValue
+	set *featureflag.Set
+}
+
+// apply updates the set from a watch entry
+func (w *featureFlagWatch) apply(ctx context.Context, entry jetstream.KeyValueEntry) {
+	if entry.Operation() != jetstream.KeyValuePut {
+		w.set.Delete(entry.Key())
+		return
+	}
+	var flag featureflag.Flag
+	if err := json.Unmarshal(entry.Value(), &flag); err != nil {
+		slog.WarnContext(ctx, "skipping malformed feature flag", "error", err, "flag", entry.Key())
+		return
+	}
+	w.set.Put(entry.Key(), flag)
+	slog.InfoContext(ctx, "feature flag updated", "flag", entry.Key(),
+		"enabled", flag.Enabled, "percentage", flag.Percentage, "callers", len(flag.Callers))
+}
+
+// watch applies the updates of watcher until ctx is done, restarting it with
+// backoff whenever the channel closes. Flags keep their last value meanwhile.
+func (w *featureFlagWatch) watch(ctx context.Context, watcher jetstream.KeyWatcher) {
+	backoff := time.Second
+	for {
+		for entry := range watcher.Updates() {
+			if entry != nil {
+				w.apply(ctx, entry)
+			}
+		}
+		_ = watcher.Stop()
+		if ctx.Err() != nil {
+			return
+		}
+
+		slog.WarnContext(ctx, "feature flag watch closed, restarting", "backoff", backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		next, err := w.kv.WatchAll(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to restart feature flag watch", "error", err)
+			backoff = min(backoff*2, time.Minute)
+			watcher = &closedWatcher{}
+			continue
+		}
+		backoff = time.Second
+		watcher = next
+	}
+}
+
+// WatchFeatureFlags loads the flags stored in kv into set, over the ones it
+// already holds, and keeps following kv until ctx is done. It returns once
+// the stored flags are loaded, so they apply from the first request.
+func WatchFeatureFlags(ctx context.Context, kv jetstream.KeyValue, set *featureflag.Set) error {
+	w := &featureFlagWatch{kv: kv, set: set}
+
+	watcher, err := kv.WatchAll(ctx)
+	if err != nil {
+		return errs.NewServiceUnavailable("failed to watch feature flags", err)
+	}
+	// the watch delivers every stored value, then a nil entry
+	for entry := range watcher.Updates() {
+		if entry == nil {
+			break
+		}
+		w.apply(ctx, entry)
+	}
+
+	slog.InfoContext(ctx, "feature flags loaded", "flags", set.Names())
+	go w.watch(ctx, watcher)
+	return nil
+}
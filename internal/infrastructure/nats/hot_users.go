@@ -0,0 +1,64 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+)
+
+// hotUsersKey is the key of the hot user list. Replicas share it: the last
+// one saving its list wins, which is as good a sample of the traffic as any.
+const hotUsersKey = "hot-users"
+
+// hotUserList is the stored hot user list
+type hotUserList struct {
+	UserIDs []string  `json:"user_ids"`
+	SavedAt time.Time `json:"saved_at"`
+}
+
+// hotUserStore implements port.HotUserStore on a NATS KV bucket
+type hotUserStore struct {
+	kv jetstream.KeyValue
+}
+
+// LoadHotUsers returns the saved hot user list, empty before the first save
+func (s *hotUserStore) LoadHotUsers(ctx context.Context) ([]string, error) {
+	entry, err := s.kv.Get(ctx, hotUsersKey)
+	if errors.Is(err, jetstream.ErrKeyNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errs.NewUnexpected("failed to load the hot user list", err)
+	}
+	var list hotUserList
+	if err := json.Unmarshal(entry.Value(), &list); err != nil {
+		return nil, errs.NewUnexpected("failed to decode the hot user list", err)
+	}
+	return list.UserIDs, nil
+}
+
+// SaveHotUsers replaces the saved hot user list
+func (s *hotUserStore) SaveHotUsers(ctx context.Context, userIDs []string) error {
+	value, err := json.Marshal(hotUserList{UserIDs: userIDs, SavedAt: time.Now().UTC()})
+	if err != nil {
+		return errs.NewUnexpected("failed to encode the hot user list", err)
+	}
+	if _, err := s.kv.Put(ctx, hotUsersKey, value); err != nil {
+		return errs.NewUnexpected("failed to save the hot user list", err)
+	}
+	return nil
+}
+
+// NewHotUserStore returns a hot user store backed by kv
+func NewHotUserStore(kv jetstream.KeyValue) port.HotUserStore {
+	return &hotUserStore{kv: kv}
+}
@@ -0,0 +1,79 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// idempotencyStore implements port.IdempotencyStore on a NATS KV bucket
+// whose TTL ages the requests out. Keys hash the subject and idempotency
+// key, which callers choose and may contain characters KV keys don't allow.
+type idempotencyStore struct {
+	kv jetstream.KeyValue
+}
+
+func idempotencyKey(request *model.IdempotentRequest) string {
+	return hashKey(request.Subject + "\n" + request.Key)
+}
+
+// Reserve creates the request's key, so only one replica handles its first
+// attempt, and reads the stored request when the key exists
+func (s *idempotencyStore) Reserve(ctx context.Context, request *model.IdempotentRequest) (*model.IdempotentRequest, error) {
+	value, err := json.Marshal(request)
+	if err != nil {
+		return nil, errs.NewUnexpected("failed to marshal idempotent request", err)
+	}
+
+	key := idempotencyKey(request)
+	_, err = s.kv.Create(ctx, key, value)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, jetstream.ErrKeyExists) {
+		return nil, errs.NewUnexpected("failed to reserve idempotency key", err)
+	}
+
+	entry, err := s.kv.Get(ctx, key)
+	if err != nil {
+		return nil, errs.NewUnexpected("failed to read idempotent request", err)
+	}
+	var stored model.IdempotentRequest
+	if err := json.Unmarshal(entry.Value(), &stored); err != nil {
+		return nil, errs.NewUnexpected("failed to unmarshal idempotent request", err)
+	}
+	return &stored, nil
+}
+
+// Complete replaces the pending request with the one holding its reply
+func (s *idempotencyStore) Complete(ctx context.Context, request *model.IdempotentRequest) error {
+	value, err := json.Marshal(request)
+	if err != nil {
+		return errs.NewUnexpected("failed to marshal idempotent request", err)
+	}
+	if _, err := s.kv.Put(ctx, idempotencyKey(request), value); err != nil {
+		return errs.NewUnexpected("failed to store idempotent reply", err)
+	}
+	return nil
+}
+
+// Release deletes the request's key
+func (s *idempotencyStore) Release(ctx context.Context, request *model.IdempotentRequest) error {
+	if err := s.kv.Delete(ctx, idempotencyKey(request)); err != nil && !errors.Is(err, jetstream.ErrKeyNotFound) {
+		return errs.NewUnexpected("failed to release idempotency key", err)
+	}
+	return nil
+}
+
+// NewIdempotencyStore returns an idempotency store backed by kv
+func NewIdempotencyStore(kv jetstream.KeyValue) port.IdempotencyStore {
+	return &idempotencyStore{kv: kv}
+}
@@ -0,0 +1,71 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/scheduler"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// jobLease is the value of a job's key: the replica holding it, and until when
+type jobLease struct {
+	Owner     string    `json:"owner"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// jobLock implements scheduler.Lock on a NATS KV bucket, one key per job.
+// Leases are taken with the key's revision, so two replicas can't both
+// take an expired one.
+type jobLock struct {
+	kv    jetstream.KeyValue
+	owner string
+}
+
+// Acquire creates the job's key, or replaces it when the lease expired or
+// is owned by this replica
+func (l *jobLock) Acquire(ctx context.Context, job string, lease time.Duration) (bool, error) {
+	now := time.Now()
+	value, err := json.Marshal(jobLease{Owner: l.owner, ExpiresAt: now.Add(lease)})
+	if err != nil {
+		return false, errs.NewUnexpected("failed to marshal job lease", err)
+	}
+
+	entry, err := l.kv.Get(ctx, job)
+	if errors.Is(err, jetstream.ErrKeyNotFound) {
+		if _, err := l.kv.Create(ctx, job, value); err != nil {
+			if errors.Is(err, jetstream.ErrKeyExists) {
+				return false, nil
+			}
+			return false, errs.NewUnexpected("failed to create job lease", err)
+		}
+		return true, nil
+	}
+	if err != nil {
+		return false, errs.NewUnexpected("failed to read job lease", err)
+	}
+
+	var held jobLease
+	if err := json.Unmarshal(entry.Value(), &held); err == nil && held.Owner != l.owner && now.Before(held.ExpiresAt) {
+		return false, nil
+	}
+	if _, err := l.kv.Update(ctx, job, value, entry.Revision()); err != nil {
+		if errors.Is(err, jetstream.ErrKeyExists) {
+			return false, nil
+		}
+		return false, errs.NewUnexpected("failed to update job lease", err)
+	}
+	return true, nil
+}
+
+// NewJobLock returns a job lock backed by kv; owner tells this replica apart
+// from the others
+func NewJobLock(kv jetstream.KeyValue, owner string) scheduler.Lock {
+	return &jobLock{kv: kv, owner: owner}
+}
@@ -0,0 +1,133 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// leaseEntry is a KV entry holding a job lease at a revision
+type leaseEntry struct {
+	jetstream.KeyValueEntry
+	value    []byte
+	revision uint64
+}
+
+func (e *leaseEntry) Value() []byte    { return e.value }
+func (e *leaseEntry) Revision() uint64 { return e.revision }
+
+// leaseKV is an in-memory bucket with the Get/Create/Update semantics the
+// job lock relies on; conflict makes the next write lose a revision race
+type leaseKV struct {
+	jetstream.KeyValue
+	entries  map[string]*leaseEntry
+	conflict bool
+}
+
+func newLeaseKV() *leaseKV {
+	return &leaseKV{entries: map[string]*leaseEntry{}}
+}
+
+func (kv *leaseKV) Get(_ context.Context, key string) (jetstream.KeyValueEntry, error) {
+	entry, ok := kv.entries[key]
+	if !ok {
+		return nil, jetstream.ErrKeyNotFound
+	}
+	return entry, nil
+}
+
+func (kv *leaseKV) Create(_ context.Context, key string, value []byte, _ ...jetstream.KVCreateOpt) (uint64, error) {
+	if _, ok := kv.entries[key]; ok || kv.conflict {
+		return 0, jetstream.ErrKeyExists
+	}
+	kv.entries[key] = &leaseEntry{value: value, revision: 1}
+	return 1, nil
+}
+
+func (kv *leaseKV) Update(_ context.Context, key string, value []byte, revision uint64) (uint64, error) {
+	entry, ok := kv.entries[key]
+	if !ok || entry.revision != revision || kv.conflict {
+		return 0, jetstream.ErrKeyExists
+	}
+	kv.entries[key] = &leaseEntry{value: value, revision: revision + 1}
+	return revision + 1, nil
+}
+
+func (kv *leaseKV) hold(t *testing.T, key, owner string, expiresAt time.Time) {
+	t.Helper()
+	value, err := json.Marshal(jobLease{Owner: owner, ExpiresAt: expiresAt})
+	require.NoError(t, err)
+	kv.entries[key] = &leaseEntry{value: value, revision: 7}
+}
+
+func (kv *leaseKV) owner(t *testing.T, key string) string {
+	t.Helper()
+	var lease jobLease
+	require.NoError(t, json.Unmarshal(kv.entries[key].value, &lease))
+	return lease.Owner
+}
+
+func TestJobLock_Acquire(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("creates the lease when nobody holds it", func(t *testing.T) {
+		kv := newLeaseKV()
+		ok, err := NewJobLock(kv, "replica-a").Acquire(ctx, "sweep", time.Minute)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, "replica-a", kv.owner(t, "sweep"))
+	})
+
+	t.Run("owner renews its live lease", func(t *testing.T) {
+		kv := newLeaseKV()
+		kv.hold(t, "sweep", "replica-a", time.Now().Add(time.Minute))
+		ok, err := NewJobLock(kv, "replica-a").Acquire(ctx, "sweep", time.Minute)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, uint64(8), kv.entries["sweep"].revision)
+	})
+
+	t.Run("refused while another owner's lease is live", func(t *testing.T) {
+		kv := newLeaseKV()
+		kv.hold(t, "sweep", "replica-b", time.Now().Add(time.Minute))
+		ok, err := NewJobLock(kv, "replica-a").Acquire(ctx, "sweep", time.Minute)
+		require.NoError(t, err)
+		assert.False(t, ok)
+		assert.Equal(t, "replica-b", kv.owner(t, "sweep"))
+	})
+
+	t.Run("takes over an expired lease", func(t *testing.T) {
+		kv := newLeaseKV()
+		kv.hold(t, "sweep", "replica-b", time.Now().Add(-time.Second))
+		ok, err := NewJobLock(kv, "replica-a").Acquire(ctx, "sweep", time.Minute)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, "replica-a", kv.owner(t, "sweep"))
+	})
+
+	t.Run("loses a revision race on takeover", func(t *testing.T) {
+		kv := newLeaseKV()
+		kv.hold(t, "sweep", "replica-b", time.Now().Add(-time.Second))
+		kv.conflict = true
+		ok, err := NewJobLock(kv, "replica-a").Acquire(ctx, "sweep", time.Minute)
+		require.NoError(t, err)
+		assert.False(t, ok)
+		assert.Equal(t, "replica-b", kv.owner(t, "sweep"))
+	})
+
+	t.Run("loses a race to create the lease", func(t *testing.T) {
+		kv := newLeaseKV()
+		kv.conflict = true
+		ok, err := NewJobLock(kv, "replica-a").Acquire(ctx, "sweep", time.Minute)
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,57 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+)
+
+// maintenanceKey is the key of the maintenance mode in its bucket
+const maintenanceKey = "mode"
+
+// maintenanceStore implements port.MaintenanceStore on a NATS KV bucket
+type maintenanceStore struct {
+	kv jetstream.KeyValue
+}
+
+// LoadMaintenance returns the stored mode, nil before the first switch
+func (s *maintenanceStore) LoadMaintenance(ctx context.Context) (*model.MaintenanceState, error) {
+	entry, err := s.kv.Get(ctx, maintenanceKey)
+	if errors.Is(err, jetstream.ErrKeyNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errs.NewUnexpected("failed to load the maintenance mode", err)
+	}
+	var state model.MaintenanceState
+	if err := json.Unmarshal(entry.Value(), &state); err != nil {
+		return nil, errs.NewUnexpected("failed to decode the maintenance mode", err)
+	}
+	return &state, nil
+}
+
+// SaveMaintenance replaces the stored mode
+func (s *maintenanceStore) SaveMaintenance(ctx context.Context, state model.MaintenanceState) error {
+	value, err := json.Marshal(state)
+	if err != nil {
+		return errs.NewUnexpected("failed to encode the maintenance mode", err)
+	}
+	if _, err := s.kv.Put(ctx, maintenanceKey, value); err != nil {
+		return errs.NewUnexpected("failed to save the maintenance mode", err)
+	}
+	return nil
+}
+
+// NewMaintenanceStore returns a maintenance store backed by kv
+func NewMaintenanceStore(kv jetstream.KeyValue) port.MaintenanceStore {
+	return &maintenanceStore{kv: kv}
+}
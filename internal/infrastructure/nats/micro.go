@@ -0,0 +1,219 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package nats
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/deadline"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/envelope"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/featureflag"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/idempotency"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/jwt"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/tenant"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/micro"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// devServiceVersion is advertised when the build version isn't semver (e.g. "dev")
+const devServiceVersion = "0.0.0-dev"
+
+// semverPattern is the version format NATS micro accepts
+var semverPattern = regexp.MustCompile(`^\d+\.\d+\.\d+(?:-[0-9A-Za-z.-]+)?(?:\+[0-9A-Za-z.-]+)?$`)
+
+// ServiceConfig describes the service registered with the NATS services framework
+type ServiceConfig struct {
+	Name        string
+	Version     string
+	Description string
+	QueueGroup  string
+}
+
+// ServiceEndpoint is a request/reply endpoint exposed through the service
+type ServiceEndpoint struct {
+	// Name is the endpoint name shown by `nats micro info`
+	Name string
+	// Subject is the NATS subject the endpoint listens on, namespaced by a
+	// tenant's client; handlers always see this subject
+	Subject string
+	// Metadata is advertised with the endpoint (description, request format, docs)
+	Metadata map[string]string
+	// Handler processes requests for the endpoint
+	Handler func(context.Context, port.TransportMessenger)
+	// NoQueue makes every replica receive the request instead of one member
+	// of the queue group, for per-instance endpoints
+	NoQueue bool
+	// Priority is the worker pool class of the endpoint's requests; empty is
+	// PriorityInteractive
+	Priority Priority
+}
+
+// microTransportMessenger implements port.TransportMessenger for NATS micro requests
+type microTransportMessenger struct {
+	req     micro.Request
+	subject string
+	reply   ReplyOptions
+}
+
+// Subject returns the endpoint subject, without the tenant namespace the
+// request was sent to
+func (m *microTransportMessenger) Subject() string {
+	return m.subject
+}
+
+// Data returns the request payload
+func (m *microTransportMessenger) Data() []byte {
+	return m.req.Data()
+}
+
+// Respond sends a reply to the request, compressed or chunked when it is
+// large and the caller accepts it
+func (m *microTransportMessenger) Respond(data []byte) error {
+	return m.reply.respond(nats.Header(m.req.Headers()), data, func(msg *nats.Msg) error {
+		return m.req.Respond(msg.Data, micro.WithHeaders(micro.Headers(msg.Header)))
+	})
+}
+
+// AddService registers the service with the NATS services framework so its
+// endpoints show up in `nats micro ls/info/stats`, with per-endpoint request
+// counts and processing times. The service is stopped, draining in-flight
+// requests, when ctx is cancelled.
+func (c *NATSClient) AddService(ctx context.Context, config ServiceConfig, endpoints []ServiceEndpoint) (micro.Service, error) {
+	if err := c.IsReady(ctx); err != nil {
+		return nil, err
+	}
+
+	var metadata map[string]string
+	if c.tenant != "" {
+		metadata = map[string]string{"tenant": c.tenant}
+	}
+	svc, err := micro.AddService(c.conn, micro.Config{
+		Name:        config.Name,
+		Version:     serviceVersion(config.Version),
+		Description: config.Description,
+		QueueGroup:  config.QueueGroup,
+		Metadata:    metadata,
+		ErrorHandler: func(_ micro.Service, natsErr *micro.NATSError) {
+			slog.ErrorContext(ctx, "NATS service error",
+				"subject", natsErr.Subject,
+				"error", natsErr.Description,
+			)
+		},
+	})
+	if err != nil {
+		return nil, errors.NewUnexpected("failed to register NATS service", err)
+	}
+
+	for _, endpoint := range endpoints {
+		slog.DebugContext(ctx, "adding NATS service endpoint",
+			"endpoint", endpoint.Name,
+			"subject", tenant.Subject(c.tenant, endpoint.Subject),
+		)
+		queueGroup := config.QueueGroup
+		opts := []micro.EndpointOpt{
+			micro.WithEndpointSubject(tenant.Subject(c.tenant, endpoint.Subject)),
+			micro.WithEndpointMetadata(endpoint.Metadata),
+		}
+		if endpoint.NoQueue {
+			queueGroup = ""
+			opts = append(opts, micro.WithEndpointQueueGroupDisabled())
+		}
+		errAdd := svc.AddEndpoint(endpoint.Name, c.endpointHandler(ctx, endpoint, queueGroup), opts...)
+		if errAdd != nil {
+			_ = svc.Stop()
+			return nil, errors.NewUnexpected("failed to add NATS service endpoint "+endpoint.Name, errAdd)
+		}
+	}
+
+	go func() {
+		<-ctx.Done()
+		if errStop := svc.Stop(); errStop != nil {
+			slog.Warn("failed to stop NATS service", "error", errStop)
+		}
+	}()
+
+	slog.InfoContext(ctx, "NATS service registered",
+		"name", config.Name,
+		"tenant", c.tenant,
+		"version", serviceVersion(config.Version),
+		"endpoints", len(endpoints),
+	)
+	return svc, nil
+}
+
+// endpointHandler adapts a TransportMessenger handler to micro, keeping the
+// same consumer span and panic recovery as plain queue subscriptions. Requests
+// are processed by the worker pool of their priority class.
+func (c *NATSClient) endpointHandler(ctx context.Context, endpoint ServiceEndpoint, queueName string) micro.Handler {
+	return micro.HandlerFunc(func(req micro.Request) {
+		priority := requestPriority(endpoint.Priority, nats.Header(req.Headers()))
+		c.pools.run(ctx, priority, func(ctx context.Context) {
+			c.processEndpointRequest(ctx, endpoint, queueName, priority, req)
+		})
+	})
+}
+
+// processEndpointRequest runs the endpoint handler for one request
+func (c *NATSClient) processEndpointRequest(ctx context.Context, endpoint ServiceEndpoint, queueName string, priority Priority, req micro.Request) {
+	c.inFlight.Add(1)
+	defer c.inFlight.Add(-1)
+	c.handled.Add(1)
+
+	msgCtx := otel.GetTextMapPropagator().Extract(ctx, natsHeaderCarrier(nats.Header(req.Headers())))
+	msgCtx, span := tracer.Start(msgCtx, "nats.process",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "nats"),
+			attribute.String("messaging.destination.name", req.Subject()),
+			attribute.String("messaging.operation.type", "process"),
+			attribute.String("messaging.consumer.group.name", queueName),
+			attribute.Int("messaging.message.body.size", len(req.Data())),
+			attribute.String("lfx.priority", string(priority)),
+		),
+	)
+	defer span.End()
+	if c.tenant != "" {
+		span.SetAttributes(attribute.String("lfx.tenant", c.tenant))
+	}
+	if proof := req.Headers().Get(jwt.DPoPHeader); proof != "" {
+		msgCtx = jwt.WithDPoPRequest(msgCtx, proof, jwt.DPoPNATSMethod, jwt.NATSDPoPURI(req.Subject()))
+	}
+	msgCtx = featureflag.Context(msgCtx, nats.Header(req.Headers()))
+	msgCtx = idempotency.Context(msgCtx, nats.Header(req.Headers()))
+	msgCtx = envelope.Context(msgCtx, nats.Header(req.Headers()))
+	msgCtx, cancel := deadline.Context(msgCtx, nats.Header(req.Headers()))
+	defer cancel()
+
+	defer func() {
+		if r := recover(); r != nil {
+			slog.ErrorContext(msgCtx, "panic in NATS handler",
+				"subject", endpoint.Subject,
+				"queue", queueName,
+				"panic", r,
+			)
+			span.SetStatus(codes.Error, "panic in NATS handler")
+		}
+	}()
+
+	endpoint.Handler(msgCtx, &microTransportMessenger{req: req, subject: endpoint.Subject, reply: c.replyOptions()})
+}
+
+// serviceVersion normalizes a build version ("v1.2.3", "dev") to the semver micro requires
+func serviceVersion(version string) string {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if semverPattern.MatchString(version) {
+		return version
+	}
+	return devServiceVersion
+}
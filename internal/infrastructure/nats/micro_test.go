@@ -0,0 +1,44 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package nats
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceVersion(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "1.2.3", want: "1.2.3"},
+		{in: "v0.4.0", want: "0.4.0"},
+		{in: "v1.0.0-rc.1", want: "1.0.0-rc.1"},
+		{in: "dev", want: devServiceVersion},
+		{in: "", want: devServiceVersion},
+		{in: "1.2", want: devServiceVersion},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			assert.Equal(t, tt.want, serviceVersion(tt.in))
+		})
+	}
+}
+
+func TestNATSClient_ForTenant(t *testing.T) {
+	shared := &NATSClient{pools: newWorkerPools(4, 0.25)}
+
+	client, err := shared.ForTenant(context.Background(), "acme")
+	require.NoError(t, err)
+	assert.Equal(t, "acme", client.tenant)
+	assert.Same(t, shared.pools, client.pools, "tenants share the worker pools")
+
+	_, err = shared.ForTenant(context.Background(), "Not.Valid")
+	assert.Error(t, err)
+}
@@ -17,6 +17,14 @@ type Config struct {
 	MaxReconnect int `json:"max_reconnect"`
 	// ReconnectWait is the time to wait between reconnection attempts
 	ReconnectWait time.Duration `json:"reconnect_wait"`
+	// CompressionThreshold is the reply size in bytes above which replies are
+	// compressed for callers sending Accept-Encoding; 0 disables compression
+	CompressionThreshold int `json:"compression_threshold"`
+	// Workers is the number of requests processed at once, split between the
+	// priority classes; 0 processes each subject's requests one at a time
+	Workers int `json:"workers"`
+	// BatchWorkerShare is the fraction of Workers reserved for batch requests
+	BatchWorkerShare float64 `json:"batch_worker_share"`
 }
 
 // NATSRequest represents a NATS request for message handling
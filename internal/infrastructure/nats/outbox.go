@@ -0,0 +1,113 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// outboxStore implements port.Outbox on a NATS KV bucket. Keys start with
+// the zero-padded creation time, so sorting them gives the oldest first.
+type outboxStore struct {
+	kv jetstream.KeyValue
+}
+
+func outboxKey(event *model.OutboxEvent) string {
+	return fmt.Sprintf("%020d.%s", event.CreatedAt.UnixNano(), event.ID)
+}
+
+// Add creates the event's key
+func (s *outboxStore) Add(ctx context.Context, event *model.OutboxEvent) error {
+	value, err := json.Marshal(event)
+	if err != nil {
+		return errs.NewUnexpected("failed to marshal outbox event", err)
+	}
+	revision, err := s.kv.Create(ctx, outboxKey(event), value)
+	if err != nil {
+		return errs.NewUnexpected("failed to store outbox event", err)
+	}
+	event.Revision = revision
+	return nil
+}
+
+// Pending reads the events of the oldest limit keys
+func (s *outboxStore) Pending(ctx context.Context, limit int) ([]*model.OutboxEvent, error) {
+	lister, err := s.kv.ListKeys(ctx)
+	if err != nil {
+		if errors.Is(err, jetstream.ErrNoKeysFound) {
+			return nil, nil
+		}
+		return nil, errs.NewUnexpected("failed to list outbox events", err)
+	}
+	defer func() { _ = lister.Stop() }()
+
+	var keys []string
+	for key := range lister.Keys() {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	if len(keys) > limit {
+		keys = keys[:limit]
+	}
+
+	events := make([]*model.OutboxEvent, 0, len(keys))
+	for _, key := range keys {
+		entry, err := s.kv.Get(ctx, key)
+		if errors.Is(err, jetstream.ErrKeyNotFound) {
+			// published by another replica since it was listed
+			continue
+		}
+		if err != nil {
+			return nil, errs.NewUnexpected("failed to read outbox event", err)
+		}
+		var event model.OutboxEvent
+		if err := json.Unmarshal(entry.Value(), &event); err != nil {
+			slog.WarnContext(ctx, "skipping malformed outbox event", "key", key, "error", err)
+			continue
+		}
+		event.Revision = entry.Revision()
+		events = append(events, &event)
+	}
+	return events, nil
+}
+
+// Update writes the event if its key is still at the event's revision
+func (s *outboxStore) Update(ctx context.Context, event *model.OutboxEvent) (bool, error) {
+	value, err := json.Marshal(event)
+	if err != nil {
+		return false, errs.NewUnexpected("failed to marshal outbox event", err)
+	}
+	revision, err := s.kv.Update(ctx, outboxKey(event), value, event.Revision)
+	if err != nil {
+		if errors.Is(err, jetstream.ErrKeyExists) || errors.Is(err, jetstream.ErrKeyNotFound) {
+			return false, nil
+		}
+		return false, errs.NewUnexpected("failed to update outbox event", err)
+	}
+	event.Revision = revision
+	return true, nil
+}
+
+// Delete removes the event's key
+func (s *outboxStore) Delete(ctx context.Context, event *model.OutboxEvent) error {
+	if err := s.kv.Delete(ctx, outboxKey(event)); err != nil && !errors.Is(err, jetstream.ErrKeyNotFound) {
+		return errs.NewUnexpected("failed to delete outbox event", err)
+	}
+	return nil
+}
+
+// NewOutbox returns an outbox backed by kv
+func NewOutbox(kv jetstream.KeyValue) port.Outbox {
+	return &outboxStore{kv: kv}
+}
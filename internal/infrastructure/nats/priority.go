@@ -0,0 +1,174 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package nats
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/tenant"
+)
+
+var meter = otel.Meter("github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/nats")
+
+// Priority is the class of worker pool a request is processed by
+type Priority string
+
+const (
+	// PriorityInteractive is the default class, for requests a user waits on
+	PriorityInteractive Priority = "interactive"
+	// PriorityBatch is the class of bulk traffic, processed by a smaller pool
+	// so it can't take the workers interactive requests need
+	PriorityBatch Priority = "batch"
+
+	// PriorityHeader lets a caller mark a request to an interactive subject as
+	// batch traffic. Requests to batch subjects stay batch whatever it says.
+	PriorityHeader = "Lfx-Priority"
+)
+
+// requestPriority is the class a request to an endpoint of class base is
+// processed in
+func requestPriority(base Priority, header nats.Header) Priority {
+	if base == PriorityBatch || Priority(header.Get(PriorityHeader)) == PriorityBatch {
+		return PriorityBatch
+	}
+	return PriorityInteractive
+}
+
+// workerQueueDepth is how many requests may wait per worker of a class
+// before its queue is full
+const workerQueueDepth = 32
+
+// workerPools bound how many requests of each priority class are processed
+// at once. Each class has its own workers and queue, so a backlog of batch
+// requests waits behind the batch workers only, and never holds up the
+// delivery of the subscription it came from.
+type workerPools struct {
+	queues  map[Priority]chan queuedRequest
+	workers map[Priority]int
+	wait    metric.Float64Histogram
+
+	// mu guards closed against the sends to the queues
+	mu     sync.RWMutex
+	closed bool
+	// pending counts the queued and in-flight requests
+	pending sync.WaitGroup
+}
+
+// queuedRequest is a request waiting for a worker of its class
+type queuedRequest struct {
+	ctx      context.Context
+	queuedAt time.Time
+	process  func(ctx context.Context)
+}
+
+// newWorkerPools splits workers between the classes, giving batchShare of
+// them, and at least one, to batch requests, and starts them. It returns nil
+// when workers <= 0, which processes requests inline on the subscription
+// goroutine.
+func newWorkerPools(workers int, batchShare float64) *workerPools {
+	if workers <= 0 {
+		return nil
+	}
+	batch := max(int(math.Round(float64(workers)*batchShare)), 1)
+	interactive := max(workers-batch, 1)
+
+	wait, _ := meter.Float64Histogram("auth_service.nats.queue_wait",
+		metric.WithDescription("Time requests waited for a worker of their priority class"),
+		metric.WithUnit("ms"))
+	p := &workerPools{
+		queues:  make(map[Priority]chan queuedRequest, 2),
+		workers: map[Priority]int{PriorityInteractive: interactive, PriorityBatch: batch},
+		wait:    wait,
+	}
+	for priority, n := range p.workers {
+		queue := make(chan queuedRequest, n*workerQueueDepth)
+		p.queues[priority] = queue
+		for range n {
+			go p.work(priority, queue)
+		}
+	}
+	return p
+}
+
+// work processes the requests of queue, one at a time, until it is closed
+// and drained. A request whose context ended while it was queued is refused
+// rather than processed.
+func (p *workerPools) work(priority Priority, queue <-chan queuedRequest) {
+	for request := range queue {
+		p.wait.Record(request.ctx, float64(time.Since(request.queuedAt).Microseconds())/1000,
+			tenant.Attributes(request.ctx, attribute.String("priority", string(priority))))
+		ctx := request.ctx
+		if ctx.Err() != nil {
+			ctx = port.WithOverloaded(ctx)
+		}
+		request.process(ctx)
+		p.pending.Done()
+	}
+}
+
+// run queues a request of class priority for a worker of its pool and
+// returns at once, so the subscription it came from keeps delivering the
+// requests of the other class. When the queue of its class is full, or the
+// pools are stopped, the request is processed at once with a context marked
+// port.WithOverloaded, which refuses it, and run reports false.
+func (p *workerPools) run(ctx context.Context, priority Priority, process func(ctx context.Context)) bool {
+	if p == nil {
+		process(ctx)
+		return true
+	}
+
+	p.mu.RLock()
+	queued := false
+	if !p.closed {
+		p.pending.Add(1)
+		select {
+		case p.queues[priority] <- queuedRequest{ctx: ctx, queuedAt: time.Now(), process: process}:
+			queued = true
+		default:
+			p.pending.Done()
+		}
+	}
+	p.mu.RUnlock()
+
+	if !queued {
+		slog.WarnContext(ctx, "request refused, no worker capacity", "priority", string(priority))
+		process(port.WithOverloaded(ctx))
+	}
+	return queued
+}
+
+// stop refuses the requests delivered from now on and waits for the queued
+// and in-flight ones; the workers exit once their queue is drained
+func (p *workerPools) stop() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		for _, queue := range p.queues {
+			close(queue)
+		}
+	}
+	p.mu.Unlock()
+	p.pending.Wait()
+}
+
+// capacity returns the number of workers of a class
+func (p *workerPools) capacity(priority Priority) int {
+	if p == nil {
+		return 0
+	}
+	return p.workers[priority]
+}
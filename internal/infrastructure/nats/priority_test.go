@@ -0,0 +1,146 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package nats
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+)
+
+func TestRequestPriority(t *testing.T) {
+	batchHeader := nats.Header{PriorityHeader: {string(PriorityBatch)}}
+	interactiveHeader := nats.Header{PriorityHeader: {string(PriorityInteractive)}}
+
+	assert.Equal(t, PriorityInteractive, requestPriority("", nil))
+	assert.Equal(t, PriorityInteractive, requestPriority(PriorityInteractive, interactiveHeader))
+	assert.Equal(t, PriorityBatch, requestPriority(PriorityInteractive, batchHeader), "callers can mark their own traffic as batch")
+	assert.Equal(t, PriorityBatch, requestPriority(PriorityBatch, interactiveHeader), "batch subjects can't be raised to interactive")
+}
+
+func TestNewWorkerPools(t *testing.T) {
+	assert.Nil(t, newWorkerPools(0, 0.25))
+
+	pools := newWorkerPools(8, 0.25)
+	assert.Equal(t, 6, pools.capacity(PriorityInteractive))
+	assert.Equal(t, 2, pools.capacity(PriorityBatch))
+
+	pools = newWorkerPools(2, 0)
+	assert.Equal(t, 1, pools.capacity(PriorityBatch), "batch keeps at least one worker")
+	assert.Equal(t, 1, pools.capacity(PriorityInteractive))
+}
+
+func TestWorkerPools_BatchBacklogDoesNotBlockInteractive(t *testing.T) {
+	ctx := context.Background()
+	pools := newWorkerPools(4, 0.25)
+
+	release := make(chan struct{})
+	defer close(release)
+	// occupy the only batch worker
+	assert.True(t, pools.run(ctx, PriorityBatch, func(context.Context) { <-release }))
+
+	queued := make(chan struct{})
+	returned := make(chan struct{})
+	go func() {
+		// a request of an interactive subject marked batch, delivered by the
+		// same subscription as the interactive one below
+		pools.run(ctx, PriorityBatch, func(context.Context) { close(queued) })
+		close(returned)
+	}()
+	select {
+	case <-returned:
+	case <-time.After(time.Second):
+		t.Fatal("queuing a batch request blocked its subscription")
+	}
+
+	done := make(chan struct{})
+	pools.run(ctx, PriorityInteractive, func(context.Context) { close(done) })
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("interactive request waited behind batch requests")
+	}
+
+	select {
+	case <-queued:
+		t.Fatal("second batch request should wait for the batch worker")
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestWorkerPools_FullQueueRefuses(t *testing.T) {
+	ctx := context.Background()
+	pools := newWorkerPools(2, 0.5)
+
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+	assert.True(t, pools.run(ctx, PriorityBatch, func(context.Context) { close(started); <-release }))
+	<-started
+	for range workerQueueDepth {
+		assert.True(t, pools.run(ctx, PriorityBatch, func(context.Context) {}))
+	}
+
+	var refused bool
+	assert.False(t, pools.run(ctx, PriorityBatch, func(ctx context.Context) { refused = port.Overloaded(ctx) }), "a full batch queue refuses requests")
+	assert.True(t, refused, "a refused request is processed at once, marked overloaded")
+	assert.True(t, pools.run(ctx, PriorityInteractive, func(context.Context) {}), "the interactive queue is separate")
+}
+
+func TestWorkerPools_Stop(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	pools := newWorkerPools(2, 0.5)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	var inFlightDone atomic.Bool
+	assert.True(t, pools.run(ctx, PriorityBatch, func(context.Context) {
+		close(started)
+		<-release
+		inFlightDone.Store(true)
+	}))
+	<-started
+	var queuedOverloaded atomic.Bool
+	assert.True(t, pools.run(ctx, PriorityBatch, func(ctx context.Context) { queuedOverloaded.Store(port.Overloaded(ctx)) }))
+
+	// shutdown: the subscriptions are cancelled, then the pools stopped
+	cancel()
+	stopped := make(chan struct{})
+	go func() {
+		pools.stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		t.Fatal("stop returned before the in-flight request finished")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("stop didn't return once the requests were done")
+	}
+	assert.True(t, inFlightDone.Load())
+	assert.True(t, queuedOverloaded.Load(), "a request queued past the cancel is refused, not processed")
+
+	var refused bool
+	assert.False(t, pools.run(context.Background(), PriorityInteractive, func(ctx context.Context) { refused = port.Overloaded(ctx) }))
+	assert.True(t, refused, "stopped pools refuse new requests")
+}
+
+func TestWorkerPools_Inline(t *testing.T) {
+	var pools *workerPools
+	ran := false
+	pools.run(context.Background(), PriorityBatch, func(context.Context) { ran = true })
+	assert.True(t, ran, "without pools requests are processed inline")
+	pools.stop()
+}
@@ -0,0 +1,38 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package nats
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// payloadQuarantine implements port.PayloadQuarantine on a NATS KV bucket,
+// keyed by payload signature so replicas quarantining the same payload
+// write the same key
+type payloadQuarantine struct {
+	kv jetstream.KeyValue
+}
+
+// Quarantine stores payload, replacing an earlier entry of its signature
+func (q *payloadQuarantine) Quarantine(ctx context.Context, payload *model.QuarantinedPayload) error {
+	value, err := json.Marshal(payload)
+	if err != nil {
+		return errs.NewUnexpected("failed to marshal quarantined payload", err)
+	}
+	if _, err := q.kv.Put(ctx, payload.Signature, value); err != nil {
+		return errs.NewUnexpected("failed to store quarantined payload", err)
+	}
+	return nil
+}
+
+// NewPayloadQuarantine returns a payload quarantine backed by kv
+func NewPayloadQuarantine(kv jetstream.KeyValue) port.PayloadQuarantine {
+	return &payloadQuarantine{kv: kv}
+}
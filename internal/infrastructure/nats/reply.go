@@ -0,0 +1,50 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package nats
+
+import (
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/compression"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/stream"
+	"github.com/nats-io/nats.go"
+)
+
+// ReplyOptions configure how replies are encoded for callers that ask for it
+type ReplyOptions struct {
+	// CompressionThreshold is the reply size above which replies are
+	// compressed for callers sending Accept-Encoding; 0 disables compression
+	CompressionThreshold int
+	// ChunkSize is the largest reply sent in one message to callers that
+	// accept chunked replies; 0 never chunks
+	ChunkSize int
+}
+
+// respond sends data as the reply to a request with header, compressed and
+// chunked as the request allows, through send
+func (o ReplyOptions) respond(header nats.Header, data []byte, send func(*nats.Msg) error) error {
+	body, encoding := compression.Compress(header.Get(compression.AcceptEncodingHeader), data, o.CompressionThreshold)
+	var replyHeader nats.Header
+	if encoding != "" {
+		replyHeader = nats.Header{compression.ContentEncodingHeader: {encoding}}
+	}
+
+	chunkSize := 0
+	if stream.Accepted(header) {
+		chunkSize = o.ChunkSize
+	}
+	for _, msg := range stream.Messages(body, chunkSize, replyHeader) {
+		if err := send(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replyOptions are the reply options of the requests the client handles.
+// Chunks leave room for their headers within the server's max payload.
+func (c *NATSClient) replyOptions() ReplyOptions {
+	return ReplyOptions{
+		CompressionThreshold: c.config.CompressionThreshold,
+		ChunkSize:            max(int(c.conn.MaxPayload())-stream.HeaderRoom, 0),
+	}
+}
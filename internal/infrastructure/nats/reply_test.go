@@ -0,0 +1,66 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package nats
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/compression"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/stream"
+)
+
+func TestReplyOptions_Respond(t *testing.T) {
+	data := bytes.Repeat([]byte(`{"username":"john.doe"},`), 200)
+	options := ReplyOptions{CompressionThreshold: 1024, ChunkSize: 512}
+
+	tests := []struct {
+		name         string
+		header       nats.Header
+		data         []byte
+		wantMessages int
+		wantEncoding string
+	}{
+		{name: "caller asking for nothing", data: data, wantMessages: 1},
+		{
+			name:         "caller accepting compression",
+			header:       nats.Header{compression.AcceptEncodingHeader: {"gzip"}},
+			data:         data,
+			wantMessages: 1,
+			wantEncoding: compression.Gzip,
+		},
+		{
+			name:         "caller accepting chunks",
+			header:       nats.Header{stream.Header: {stream.Chunked}},
+			data:         data,
+			wantMessages: len(data)/512 + 2,
+		},
+		{
+			name:         "small reply to a caller accepting chunks",
+			header:       nats.Header{stream.Header: {stream.Chunked}},
+			data:         []byte(`{"success":true}`),
+			wantMessages: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent []*nats.Msg
+			err := options.respond(tt.header, tt.data, func(msg *nats.Msg) error {
+				sent = append(sent, msg)
+				return nil
+			})
+			require.NoError(t, err)
+			require.Len(t, sent, tt.wantMessages)
+			assert.Equal(t, tt.wantEncoding, sent[0].Header.Get(compression.ContentEncodingHeader))
+			if tt.wantEncoding == "" && tt.wantMessages == 1 {
+				assert.Equal(t, tt.data, sent[0].Data)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package nats
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+)
+
+// SelfTest checks the connection and reads the status of every KV bucket the
+// client opened. Nothing is written: the buckets hold production data their
+// watchers and scans would see a probe key in.
+func (c *NATSClient) SelfTest(ctx context.Context) []model.ConfigCheck {
+	checks := []model.ConfigCheck{
+		model.RunConfigCheck("nats.connection", func() (string, string) {
+			if err := c.IsReady(ctx); err != nil {
+				return model.ConfigCheckFail, err.Error()
+			}
+			return model.ConfigCheckOK, c.conn.ConnectedUrl()
+		}),
+	}
+
+	for _, bucket := range slices.Sorted(maps.Keys(c.kvStore)) {
+		kv := c.kvStore[bucket]
+		checks = append(checks, model.RunConfigCheck("nats.kv."+bucket, func() (string, string) {
+			status, err := kv.Status(ctx)
+			if err != nil {
+				return model.ConfigCheckFail, fmt.Sprintf("status read failed: %v", err)
+			}
+			return model.ConfigCheckOK, fmt.Sprintf("%s, %d values", status.Bucket(), status.Values())
+		}))
+	}
+	return checks
+}
+
+var _ port.SelfTester = (*NATSClient)(nil)
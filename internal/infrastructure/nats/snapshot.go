@@ -0,0 +1,47 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package nats
+
+import (
+	"context"
+	"errors"
+
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+)
+
+// snapshotStore implements port.SnapshotStore on a key of a NATS KV bucket.
+// Replicas share the key; the last one saving its snapshot wins.
+type snapshotStore struct {
+	kv  jetstream.KeyValue
+	key string
+}
+
+// LoadSnapshot returns the saved snapshot, nil before the first save
+func (s *snapshotStore) LoadSnapshot(ctx context.Context) ([]byte, error) {
+	entry, err := s.kv.Get(ctx, s.key)
+	if errors.Is(err, jetstream.ErrKeyNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errs.NewUnexpected("failed to load the cache snapshot", err)
+	}
+	return entry.Value(), nil
+}
+
+// SaveSnapshot replaces the saved snapshot
+func (s *snapshotStore) SaveSnapshot(ctx context.Context, snapshot []byte) error {
+	if _, err := s.kv.Put(ctx, s.key, snapshot); err != nil {
+		return errs.NewUnexpected("failed to save the cache snapshot", err)
+	}
+	return nil
+}
+
+// NewSnapshotStore returns a snapshot store keeping its snapshot under key
+// in kv
+func NewSnapshotStore(kv jetstream.KeyValue, key string) port.SnapshotStore {
+	return &snapshotStore{kv: kv, key: key}
+}
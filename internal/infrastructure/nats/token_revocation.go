@@ -0,0 +1,193 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package nats
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// Revocations are stored under jti.<hash> or sub.<hash>, hashing the claim
+// because jtis and subs contain characters KV keys don't allow
+const (
+	tokenRevocationJTIPrefix = "jti."
+	tokenRevocationSubPrefix = "sub."
+)
+
+func tokenRevocationKey(revocation *model.TokenRevocation) string {
+	if revocation.JTI != "" {
+		return tokenRevocationJTIPrefix + hashKey(revocation.JTI)
+	}
+	return tokenRevocationSubPrefix + hashKey(revocation.Subject)
+}
+
+func hashKey(value string) string {
+	hash := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(hash[:])
+}
+
+// tokenRevocationList keeps every revocation of the bucket in memory and
+// follows the bucket with a watch, so revocations made on any replica are
+// enforced on all of them without a KV read per verification
+type tokenRevocationList struct {
+	kv jetstream.KeyValue
+
+	mu          sync.RWMutex
+	revocations map[string]*model.TokenRevocation
+}
+
+// IsRevoked reports whether a token is covered by an unexpired revocation
+func (l *tokenRevocationList) IsRevoked(jti, sub string, issuedAt *time.Time) bool {
+	now := time.Now()
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	candidates := []string{tokenRevocationSubPrefix + hashKey(sub)}
+	if jti != "" {
+		candidates = append(candidates, tokenRevocationJTIPrefix+hashKey(jti))
+	}
+	for _, key := range candidates {
+		revocation, ok := l.revocations[key]
+		if ok && now.Before(revocation.ExpiresAt) && revocation.Covers(jti, sub, issuedAt) {
+			return true
+		}
+	}
+	return false
+}
+
+// Revoke stores a revocation. It is enforced on this replica immediately
+// and on the others once the watch delivers it.
+func (l *tokenRevocationList) Revoke(ctx context.Context, revocation *model.TokenRevocation) error {
+	key := tokenRevocationKey(revocation)
+	value, err := json.Marshal(revocation)
+	if err != nil {
+		return errs.NewUnexpected("failed to marshal token revocation", err)
+	}
+	if _, err := l.kv.Put(ctx, key, value); err != nil {
+		return errs.NewUnexpected("failed to store token revocation", err)
+	}
+	l.store(key, revocation)
+	return nil
+}
+
+// store records a revocation, keeping the later one when a user is revoked
+// twice, and forgets the revocations that expired
+func (l *tokenRevocationList) store(key string, revocation *model.TokenRevocation) {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if existing, ok := l.revocations[key]; ok && existing.RevokedAt.After(revocation.RevokedAt) {
+		return
+	}
+	l.revocations[key] = revocation
+	for k, r := range l.revocations {
+		if !now.Before(r.ExpiresAt) {
+			delete(l.revocations, k)
+		}
+	}
+}
+
+func (l *tokenRevocationList) forget(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.revocations, key)
+}
+
+// apply updates the list from a watch entry
+func (l *tokenRevocationList) apply(ctx context.Context, entry jetstream.KeyValueEntry) {
+	if entry.Operation() != jetstream.KeyValuePut {
+		// deleted by an operator or aged out by the bucket TTL
+		l.forget(entry.Key())
+		return
+	}
+	var revocation model.TokenRevocation
+	if err := json.Unmarshal(entry.Value(), &revocation); err != nil {
+		slog.WarnContext(ctx, "skipping malformed token revocation", "error", err, "key", entry.Key())
+		return
+	}
+	l.store(entry.Key(), &revocation)
+}
+
+// watch applies the updates of watcher until ctx is done. Revocations only
+// grow the list, so a missed update fails open until the watch is restarted;
+// it is restarted with backoff whenever the channel closes.
+func (l *tokenRevocationList) watch(ctx context.Context, watcher jetstream.KeyWatcher) {
+	backoff := time.Second
+	for {
+		for entry := range watcher.Updates() {
+			if entry != nil {
+				l.apply(ctx, entry)
+			}
+		}
+		_ = watcher.Stop()
+		if ctx.Err() != nil {
+			return
+		}
+
+		slog.WarnContext(ctx, "token revocation watch closed, restarting", "backoff", backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		next, err := l.kv.WatchAll(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to restart token revocation watch", "error", err)
+			backoff = min(backoff*2, time.Minute)
+			watcher = &closedWatcher{}
+			continue
+		}
+		backoff = time.Second
+		watcher = next
+	}
+}
+
+// closedWatcher stands in for a watch that couldn't be restarted, so the
+// retry loop runs again after the backoff
+type closedWatcher struct{}
+
+func (closedWatcher) Updates() <-chan jetstream.KeyValueEntry {
+	updates := make(chan jetstream.KeyValueEntry)
+	close(updates)
+	return updates
+}
+
+func (closedWatcher) Stop() error { return nil }
+
+// NewTokenRevocationList loads the revocations of kv and keeps following it
+// until ctx is done. It returns once the existing revocations are loaded, so
+// tokens revoked before startup are rejected from the first request.
+func NewTokenRevocationList(ctx context.Context, kv jetstream.KeyValue) (port.TokenRevocationList, error) {
+	list := &tokenRevocationList{
+		kv:          kv,
+		revocations: make(map[string]*model.TokenRevocation),
+	}
+
+	watcher, err := kv.WatchAll(ctx)
+	if err != nil {
+		return nil, errs.NewServiceUnavailable("failed to watch token revocations", err)
+	}
+	// the watch delivers every stored value, then a nil entry
+	for entry := range watcher.Updates() {
+		if entry == nil {
+			break
+		}
+		list.apply(ctx, entry)
+	}
+
+	slog.InfoContext(ctx, "token revocations loaded", "count", len(list.revocations))
+	go list.watch(ctx, watcher)
+	return list, nil
+}
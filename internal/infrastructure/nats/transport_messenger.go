@@ -4,13 +4,16 @@
 package nats
 
 import (
+	"strings"
+
 	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
 	"github.com/nats-io/nats.go"
 )
 
 // natsTransportMessenger implements port.TransportMessenger for NATS messages
 type natsTransportMessenger struct {
-	msg *nats.Msg
+	msg   *nats.Msg
+	reply ReplyOptions
 }
 
 // Subject returns the NATS message subject
@@ -23,14 +26,29 @@ func (n *natsTransportMessenger) Data() []byte {
 	return n.msg.Data
 }
 
-// Respond sends a response to the NATS message
+// Respond sends a response to the NATS message, compressed or chunked when
+// it is large and the caller accepts it
 func (n *natsTransportMessenger) Respond(data []byte) error {
-	return n.msg.Respond(data)
+	return n.reply.respond(n.msg.Header, data, n.msg.RespondMsg)
+}
+
+// Caller returns the reply subject of the message without its last token.
+// Clients receive the replies to their requests below one inbox per
+// connection, as in _INBOX.<connection>.<request>, so it is shared by the
+// requests of a connection. A reply subject of two tokens or less is the
+// inbox of a single request, and identifies no caller.
+func (n *natsTransportMessenger) Caller() string {
+	if strings.Count(n.msg.Reply, ".") < 2 {
+		return ""
+	}
+	return n.msg.Reply[:strings.LastIndexByte(n.msg.Reply, '.')]
 }
 
-// NewTransportMessenger creates a new TransportMessenger from a NATS message
-func NewTransportMessenger(msg *nats.Msg) port.TransportMessenger {
+// NewTransportMessenger creates a new TransportMessenger from a NATS message,
+// encoding its replies with reply
+func NewTransportMessenger(msg *nats.Msg, reply ReplyOptions) port.TransportMessenger {
 	return &natsTransportMessenger{
-		msg: msg,
+		msg:   msg,
+		reply: reply,
 	}
 }
@@ -0,0 +1,53 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package nats
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// exportContentTypes are the Content-Type headers of the stored exports
+var exportContentTypes = map[string]string{
+	model.UserExportFormatCSV:  "text/csv",
+	model.UserExportFormatJSON: "application/x-ndjson",
+}
+
+// userExportStore implements port.UserExportStore on a NATS object store
+type userExportStore struct {
+	objects jetstream.ObjectStore
+}
+
+// Put stores the export under name, replacing an object of the same name
+func (s *userExportStore) Put(ctx context.Context, name string, file *model.UserExportFile) (*model.UserExportObject, error) {
+	meta := jetstream.ObjectMeta{
+		Name:        name,
+		Description: "users export job " + file.JobID,
+	}
+	if contentType, ok := exportContentTypes[file.Format]; ok {
+		meta.Headers = nats.Header{"Content-Type": {contentType}}
+	}
+
+	info, err := s.objects.Put(ctx, meta, bytes.NewReader(file.Content))
+	if err != nil {
+		return nil, errs.NewUnexpected("failed to store users export", err)
+	}
+	return &model.UserExportObject{
+		Bucket: info.Bucket,
+		Name:   info.Name,
+		Size:   info.Size,
+		Digest: info.Digest,
+	}, nil
+}
+
+// NewUserExportStore returns a user export store backed by an object store
+func NewUserExportStore(objects jetstream.ObjectStore) port.UserExportStore {
+	return &userExportStore{objects: objects}
+}
@@ -0,0 +1,62 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package nats
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// userMergeHistory implements port.UserMergeHistory on a NATS KV bucket. A
+// record is kept under the SHA-256 of the old sub, as subs contain
+// characters KV keys don't allow.
+type userMergeHistory struct {
+	kv jetstream.KeyValue
+}
+
+func userMergeKey(oldSub string) string {
+	hash := sha256.Sum256([]byte(oldSub))
+	return hex.EncodeToString(hash[:])
+}
+
+// Record stores the merge record under its old sub
+func (h *userMergeHistory) Record(ctx context.Context, record *model.UserMergeRecord) error {
+	value, err := json.Marshal(record)
+	if err != nil {
+		return errs.NewUnexpected("failed to marshal user merge record", err)
+	}
+	if _, err := h.kv.Put(ctx, userMergeKey(record.OldSub), value); err != nil {
+		return errs.NewUnexpected("failed to store user merge record", err)
+	}
+	return nil
+}
+
+// Get returns the record of the merge that folded oldSub into another account
+func (h *userMergeHistory) Get(ctx context.Context, oldSub string) (*model.UserMergeRecord, error) {
+	entry, err := h.kv.Get(ctx, userMergeKey(oldSub))
+	if err != nil {
+		if errors.Is(err, jetstream.ErrKeyNotFound) {
+			return nil, errs.NewNotFound("user merge not found")
+		}
+		return nil, errs.NewUnexpected("failed to get user merge record", err)
+	}
+	var record model.UserMergeRecord
+	if err := json.Unmarshal(entry.Value(), &record); err != nil {
+		return nil, errs.NewUnexpected("failed to unmarshal user merge record", err)
+	}
+	return &record, nil
+}
+
+// NewUserMergeHistory returns a user merge history backed by kv
+func NewUserMergeHistory(kv jetstream.KeyValue) port.UserMergeHistory {
+	return &userMergeHistory{kv: kv}
+}
@@ -0,0 +1,109 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package provider is the registry of the identity provider adapters. Each
+// adapter registers a factory under a name (e.g. "auth0"), and the service
+// builds the one USER_REPOSITORY_TYPE selects, so adding an adapter doesn't
+// touch the startup code. A factory may build other registered providers and
+// combine them, registering a composite like any other adapter.
+package provider
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+)
+
+// Factory builds a provider, reading its settings from the environment
+type Factory func(ctx context.Context) (port.UserReaderWriter, error)
+
+// Registry maps provider names to their factories
+type Registry struct {
+	mu        sync.RWMutex
+	factories map[string]Factory
+}
+
+// NewRegistry returns an empty registry
+func NewRegistry() *Registry {
+	return &Registry{factories: make(map[string]Factory)}
+}
+
+// Register adds the factory of the provider name. It panics when name is
+// empty, factory is nil or name is already registered, which are
+// programming errors surfacing at startup.
+func (r *Registry) Register(name string, factory Factory) {
+	if name == "" || factory == nil {
+		panic("provider: Register needs a name and a factory")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.factories[name]; exists {
+		panic(fmt.Sprintf("provider: %q registered twice", name))
+	}
+	r.factories[name] = factory
+}
+
+// Registered reports whether a provider is registered under name
+func (r *Registry) Registered(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, exists := r.factories[name]
+	return exists
+}
+
+// Names returns the registered provider names, sorted
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.factories))
+	for name := range r.factories {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// New builds the provider registered under name
+func (r *Registry) New(ctx context.Context, name string) (port.UserReaderWriter, error) {
+	r.mu.RLock()
+	factory, exists := r.factories[name]
+	r.mu.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("unsupported provider %q (registered: %s)", name, strings.Join(r.Names(), ", "))
+	}
+
+	userReaderWriter, err := factory(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s provider: %w", name, err)
+	}
+	return userReaderWriter, nil
+}
+
+// defaultRegistry is the registry the package functions use
+var defaultRegistry = NewRegistry()
+
+// Register adds the factory of the provider name to the default registry,
+// usually from the init function of the adapter wiring
+func Register(name string, factory Factory) {
+	defaultRegistry.Register(name, factory)
+}
+
+// Registered reports whether name is in the default registry
+func Registered(name string) bool {
+	return defaultRegistry.Registered(name)
+}
+
+// Names returns the provider names in the default registry, sorted
+func Names() []string {
+	return defaultRegistry.Names()
+}
+
+// New builds the provider registered under name in the default registry
+func New(ctx context.Context, name string) (port.UserReaderWriter, error) {
+	return defaultRegistry.New(ctx, name)
+}
@@ -0,0 +1,71 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/infrastructure/mock"
+)
+
+func TestRegistry(t *testing.T) {
+	ctx := context.Background()
+	registry := NewRegistry()
+	registry.Register("mock", func(ctx context.Context) (port.UserReaderWriter, error) {
+		return mock.NewUserReaderWriter(ctx), nil
+	})
+	registry.Register("broken", func(context.Context) (port.UserReaderWriter, error) {
+		return nil, errors.New("missing credentials")
+	})
+
+	if got := registry.Names(); !slices.Equal(got, []string{"broken", "mock"}) {
+		t.Fatalf("expected sorted names, got %v", got)
+	}
+	if !registry.Registered("mock") || registry.Registered("okta") {
+		t.Fatal("expected only registered names to be reported")
+	}
+
+	if userReaderWriter, err := registry.New(ctx, "mock"); err != nil || userReaderWriter == nil {
+		t.Fatalf("expected the mock provider, got %v, %v", userReaderWriter, err)
+	}
+	if _, err := registry.New(ctx, "broken"); err == nil || !strings.Contains(err.Error(), "missing credentials") {
+		t.Fatalf("expected the factory error, got %v", err)
+	}
+	if _, err := registry.New(ctx, "okta"); err == nil || !strings.Contains(err.Error(), "broken, mock") {
+		t.Fatalf("expected an error listing the registered providers, got %v", err)
+	}
+}
+
+func TestRegistry_Composite(t *testing.T) {
+	ctx := context.Background()
+	registry := NewRegistry()
+	registry.Register("mock", func(ctx context.Context) (port.UserReaderWriter, error) {
+		return mock.NewUserReaderWriter(ctx), nil
+	})
+	registry.Register("wrapped", func(ctx context.Context) (port.UserReaderWriter, error) {
+		return registry.New(ctx, "mock")
+	})
+
+	if _, err := registry.New(ctx, "wrapped"); err != nil {
+		t.Fatalf("expected a composite built from another provider, got %v", err)
+	}
+}
+
+func TestRegistry_RegisterTwicePanics(t *testing.T) {
+	registry := NewRegistry()
+	factory := func(ctx context.Context) (port.UserReaderWriter, error) { return mock.NewUserReaderWriter(ctx), nil }
+	registry.Register("mock", factory)
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic on a duplicate name")
+		}
+	}()
+	registry.Register("mock", factory)
+}
@@ -0,0 +1,62 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+)
+
+// AdminStats returns the operational stats of the replica that received the request
+func (m *messageHandlerOrchestrator) AdminStats(ctx context.Context, msg port.TransportMessenger) ([]byte, error) {
+	if m.instanceStats == nil {
+		return m.errorResponse("stats_unavailable"), nil
+	}
+
+	response := UserDataResponse{
+		Success: true,
+		Data:    m.instanceStats.InstanceStats(ctx),
+	}
+
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		return m.errorResponse("failed to marshal response"), nil
+	}
+	return responseJSON, nil
+}
+
+// WithRuntimeConfigForMessageHandler sets the provider of the runtime
+// configuration reported by admin.config
+func WithRuntimeConfigForMessageHandler(provider port.RuntimeConfigProvider) MessageHandlerOrchestratorOption {
+	return func(m *messageHandlerOrchestrator) {
+		m.runtimeConfig = provider
+	}
+}
+
+// AdminConfig returns the effective configuration of the replica that
+// received the request, to callers whose token carries the read:config scope
+func (m *messageHandlerOrchestrator) AdminConfig(ctx context.Context, msg port.TransportMessenger) ([]byte, error) {
+	if m.runtimeConfig == nil {
+		return m.errorResponse("config_unavailable"), nil
+	}
+
+	var request struct {
+		AuthToken string `json:"auth_token"`
+	}
+	if err := json.Unmarshal(msg.Data(), &request); err != nil {
+		return m.errorResponse("failed_to_unmarshal_request"), nil
+	}
+	if _, err := m.authorizeScope(ctx, request.AuthToken, constants.ConfigReadRequiredScope); err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+
+	responseJSON, err := json.Marshal(UserDataResponse{Success: true, Data: m.runtimeConfig.RuntimeConfig(ctx)})
+	if err != nil {
+		return m.errorResponse("failed to marshal response"), nil
+	}
+	return responseJSON, nil
+}
@@ -0,0 +1,123 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/jwt"
+)
+
+// mockInstanceStats is a mock implementation of port.InstanceStatsProvider for testing
+type mockInstanceStats struct {
+	stats model.InstanceStats
+}
+
+func (m *mockInstanceStats) InstanceStats(ctx context.Context) model.InstanceStats {
+	return m.stats
+}
+
+func TestMessageHandlerOrchestrator_AdminStats(t *testing.T) {
+	t.Run("returns the instance stats", func(t *testing.T) {
+		provider := &mockInstanceStats{stats: model.InstanceStats{
+			InstanceID: "abc",
+			Requests:   model.RequestStats{InFlight: 2, Total: 10},
+			Cache:      &model.CacheStats{Hits: 3, Misses: 1, HitRate: 0.75},
+		}}
+		orchestrator := NewMessageHandlerOrchestrator(WithInstanceStatsForMessageHandler(provider))
+
+		raw, err := orchestrator.AdminStats(context.Background(), &mockTransportMessenger{})
+		require.NoError(t, err)
+
+		var response struct {
+			Success bool                `json:"success"`
+			Data    model.InstanceStats `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(raw, &response))
+		assert.True(t, response.Success)
+		assert.Equal(t, provider.stats, response.Data)
+	})
+
+	t.Run("unavailable without a provider", func(t *testing.T) {
+		raw, err := NewMessageHandlerOrchestrator().AdminStats(context.Background(), &mockTransportMessenger{})
+		require.NoError(t, err)
+
+		var response UserDataResponse
+		require.NoError(t, json.Unmarshal(raw, &response))
+		assert.False(t, response.Success)
+		assert.Equal(t, "stats_unavailable", response.Error)
+	})
+}
+
+// mockRuntimeConfig is a mock implementation of port.RuntimeConfigProvider for testing
+type mockRuntimeConfig struct {
+	config model.RuntimeConfig
+}
+
+func (m *mockRuntimeConfig) RuntimeConfig(ctx context.Context) model.RuntimeConfig {
+	return m.config
+}
+
+func TestMessageHandlerOrchestrator_AdminConfig(t *testing.T) {
+	adminToken, err := jwt.GenerateTestAccessToken("auth0|admin", "https://issuer/", "aud", constants.ConfigReadRequiredScope, time.Hour)
+	require.NoError(t, err)
+	withoutScope, err := jwt.GenerateSimpleTestAccessToken("auth0|admin", time.Hour)
+	require.NoError(t, err)
+
+	reader := &mockUserServiceReader{
+		metadataLookupFunc: func(ctx context.Context, input string) (*model.User, error) {
+			return &model.User{Token: input}, nil
+		},
+	}
+	provider := &mockRuntimeConfig{config: model.RuntimeConfig{
+		Version:  "1.2.3",
+		Provider: "auth0",
+		Issuer:   "https://issuer/",
+		Settings: map[string]string{"USER_CACHE_TTL": "5m"},
+	}}
+	orchestrator := NewMessageHandlerOrchestrator(
+		WithUserReaderForMessageHandler(reader),
+		WithRuntimeConfigForMessageHandler(provider),
+	)
+	call := func(t *testing.T, m port.MessageHandler, authToken string) (bool, string, model.RuntimeConfig) {
+		t.Helper()
+		payload, _ := json.Marshal(map[string]string{"auth_token": authToken})
+		raw, err := m.AdminConfig(context.Background(), &mockTransportMessenger{data: payload})
+		require.NoError(t, err)
+		var response struct {
+			Success bool                `json:"success"`
+			Error   string              `json:"error"`
+			Data    model.RuntimeConfig `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(raw, &response))
+		return response.Success, response.Error, response.Data
+	}
+
+	t.Run("returns the runtime config", func(t *testing.T) {
+		ok, errMsg, config := call(t, orchestrator, adminToken)
+		require.True(t, ok, errMsg)
+		assert.Equal(t, provider.config, config)
+	})
+
+	t.Run("requires the read:config scope", func(t *testing.T) {
+		ok, errMsg, _ := call(t, orchestrator, withoutScope)
+		assert.False(t, ok)
+		assert.Equal(t, "insufficient_scope", errMsg)
+	})
+
+	t.Run("unavailable without a provider", func(t *testing.T) {
+		ok, errMsg, _ := call(t, NewMessageHandlerOrchestrator(WithUserReaderForMessageHandler(reader)), adminToken)
+		assert.False(t, ok)
+		assert.Equal(t, "config_unavailable", errMsg)
+	})
+}
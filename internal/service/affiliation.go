@@ -0,0 +1,232 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/redaction"
+)
+
+// Changes reported by AffiliationChangedEvent
+const (
+	affiliationActionAdded = "added"
+	affiliationActionEnded = "ended"
+)
+
+// affiliationAddRequest is the input of user.affiliations.add. Sub is only
+// set by administrators recording the affiliation of another user.
+type affiliationAddRequest struct {
+	AuthToken    string `json:"auth_token"`
+	Sub          string `json:"sub,omitempty"`
+	Organization string `json:"organization"`
+	Title        string `json:"title,omitempty"`
+	StartDate    string `json:"start_date"`
+	EndDate      string `json:"end_date,omitempty"`
+}
+
+// affiliationEndRequest is the input of user.affiliations.end
+type affiliationEndRequest struct {
+	AuthToken string `json:"auth_token"`
+	Sub       string `json:"sub,omitempty"`
+	ID        string `json:"id"`
+	EndDate   string `json:"end_date"`
+}
+
+// affiliationListRequest is the input of user.affiliations.list. Exactly one
+// of AuthToken and Sub identifies the user.
+type affiliationListRequest struct {
+	AuthToken string `json:"auth_token,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+}
+
+// AffiliationChangedEvent is the payload published on
+// constants.AffiliationChangedSubject, with the history after the change so
+// consumers don't need to read it back
+type AffiliationChangedEvent struct {
+	UserID       string              `json:"user_id"`
+	Action       string              `json:"action"`
+	Affiliation  model.Affiliation   `json:"affiliation"`
+	Affiliations []model.Affiliation `json:"affiliations"`
+	ChangedBy    string              `json:"changed_by"`
+	Timestamp    time.Time           `json:"timestamp"`
+}
+
+// WithAffiliationStoreForMessageHandler sets where the message handler
+// orchestrator keeps the employment history of users
+func WithAffiliationStoreForMessageHandler(store port.AffiliationStore) MessageHandlerOrchestratorOption {
+	return func(m *messageHandlerOrchestrator) {
+		m.affiliations = store
+	}
+}
+
+// AddAffiliation adds an affiliation to the history of the user the token
+// belongs to, or of sub for administrators. It must not overlap the others.
+func (m *messageHandlerOrchestrator) AddAffiliation(ctx context.Context, msg port.TransportMessenger) ([]byte, error) {
+	if m.affiliations == nil || m.userReader == nil {
+		return m.errorResponse("affiliations_unavailable"), nil
+	}
+
+	var request affiliationAddRequest
+	if err := json.Unmarshal(msg.Data(), &request); err != nil {
+		return m.errorResponse("failed_to_unmarshal_request"), nil
+	}
+
+	userID, actor, err := m.affiliationWriter(ctx, request.AuthToken, request.Sub)
+	if err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+
+	history, err := m.affiliations.GetAffiliations(ctx, userID)
+	if err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+	affiliation := model.Affiliation{
+		ID:           rand.Text(),
+		Organization: strings.TrimSpace(request.Organization),
+		Title:        strings.TrimSpace(request.Title),
+		StartDate:    strings.TrimSpace(request.StartDate),
+		EndDate:      strings.TrimSpace(request.EndDate),
+	}
+	if err := history.Add(affiliation); err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+
+	return m.saveAffiliationChange(ctx, history, affiliationActionAdded, affiliation, actor)
+}
+
+// EndAffiliation sets the end date of a current affiliation
+func (m *messageHandlerOrchestrator) EndAffiliation(ctx context.Context, msg port.TransportMessenger) ([]byte, error) {
+	if m.affiliations == nil || m.userReader == nil {
+		return m.errorResponse("affiliations_unavailable"), nil
+	}
+
+	var request affiliationEndRequest
+	if err := json.Unmarshal(msg.Data(), &request); err != nil {
+		return m.errorResponse("failed_to_unmarshal_request"), nil
+	}
+	if strings.TrimSpace(request.ID) == "" {
+		return m.errorResponse("id is required"), nil
+	}
+
+	userID, actor, err := m.affiliationWriter(ctx, request.AuthToken, request.Sub)
+	if err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+
+	history, err := m.affiliations.GetAffiliations(ctx, userID)
+	if err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+	ended, err := history.End(strings.TrimSpace(request.ID), strings.TrimSpace(request.EndDate))
+	if err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+
+	return m.saveAffiliationChange(ctx, history, affiliationActionEnded, ended, actor)
+}
+
+// ListAffiliations returns the employment history of a user, oldest first
+func (m *messageHandlerOrchestrator) ListAffiliations(ctx context.Context, msg port.TransportMessenger) ([]byte, error) {
+	if m.affiliations == nil || m.userReader == nil {
+		return m.errorResponse("affiliations_unavailable"), nil
+	}
+
+	var request affiliationListRequest
+	if err := json.Unmarshal(msg.Data(), &request); err != nil {
+		return m.errorResponse("failed_to_unmarshal_request"), nil
+	}
+
+	// reading an affiliation is no more sensitive than a consent status
+	userID, err := m.consentUser(ctx, consentStatusRequest{AuthToken: request.AuthToken, Sub: request.Sub})
+	if err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+
+	history, err := m.affiliations.GetAffiliations(ctx, userID)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to get affiliations", "error", err)
+		return m.errorResponse(err.Error()), nil
+	}
+	if history.Affiliations == nil {
+		history.Affiliations = []model.Affiliation{}
+	}
+
+	responseJSON, err := json.Marshal(UserDataResponse{Success: true, Data: history})
+	if err != nil {
+		return m.errorResponse("failed to marshal response"), nil
+	}
+	return responseJSON, nil
+}
+
+// affiliationWriter returns whose history a change applies to and who makes
+// it. Users change their own history; changing another user's takes the
+// affiliation management scope.
+func (m *messageHandlerOrchestrator) affiliationWriter(ctx context.Context, authToken, sub string) (string, string, error) {
+	claims, err := m.verifyAccessToken(ctx, authToken)
+	if err != nil {
+		return "", "", err
+	}
+	if claims.Subject == "" {
+		return "", "", errs.NewUnauthorized("auth_token has no subject")
+	}
+
+	sub = strings.TrimSpace(sub)
+	if sub == "" || sub == claims.Subject {
+		return claims.Subject, claims.Subject, nil
+	}
+	if !claims.HasScope(constants.AffiliationManageRequiredScope) {
+		return "", "", errs.NewForbidden("insufficient_scope")
+	}
+	return sub, claims.Subject, nil
+}
+
+// saveAffiliationChange stores the changed history and publishes the change
+func (m *messageHandlerOrchestrator) saveAffiliationChange(ctx context.Context, history *model.AffiliationHistory, action string, affiliation model.Affiliation, actor string) ([]byte, error) {
+	if err := m.affiliations.SaveAffiliations(ctx, history); err != nil {
+		slog.ErrorContext(ctx, "failed to save affiliations", "error", err)
+		return m.errorResponse(err.Error()), nil
+	}
+
+	if m.eventPublisher != nil {
+		event := AffiliationChangedEvent{
+			UserID:       history.UserID,
+			Action:       action,
+			Affiliation:  affiliation,
+			Affiliations: history.Affiliations,
+			ChangedBy:    actor,
+			Timestamp:    time.Now().UTC(),
+		}
+		eventJSON, jsonErr := json.Marshal(event)
+		if jsonErr != nil {
+			slog.WarnContext(ctx, "failed to marshal affiliation changed event", "error", jsonErr)
+		} else if pubErr := m.eventPublisher.Publish(ctx, constants.AffiliationChangedSubject, eventJSON); pubErr != nil {
+			slog.WarnContext(ctx, "failed to publish affiliation changed event",
+				"error", pubErr,
+				"user_id", redaction.Redact(history.UserID),
+			)
+		}
+	}
+
+	slog.InfoContext(ctx, "affiliation "+action,
+		"user_id", redaction.Redact(history.UserID),
+		"affiliation_id", affiliation.ID,
+		"changed_by", redaction.Redact(actor),
+	)
+
+	responseJSON, err := json.Marshal(UserDataResponse{Success: true, Data: affiliation})
+	if err != nil {
+		return m.errorResponse("failed to marshal response"), nil
+	}
+	return responseJSON, nil
+}
@@ -0,0 +1,160 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/jwt"
+)
+
+// memoryAffiliationStore keeps histories with a revision, rejecting stale
+// writes like the KV store
+type memoryAffiliationStore struct {
+	histories map[string]model.AffiliationHistory
+}
+
+func (s *memoryAffiliationStore) GetAffiliations(_ context.Context, userID string) (*model.AffiliationHistory, error) {
+	history, ok := s.histories[userID]
+	if !ok {
+		return &model.AffiliationHistory{UserID: userID}, nil
+	}
+	history.Affiliations = append([]model.Affiliation(nil), history.Affiliations...)
+	return &history, nil
+}
+
+func (s *memoryAffiliationStore) SaveAffiliations(_ context.Context, history *model.AffiliationHistory) error {
+	if s.histories[history.UserID].Revision != history.Revision {
+		return errs.NewConflict("affiliations changed concurrently, retry")
+	}
+	history.Revision++
+	s.histories[history.UserID] = *history
+	return nil
+}
+
+func TestMessageHandlerOrchestrator_Affiliations(t *testing.T) {
+	ctx := context.Background()
+
+	userToken, err := jwt.GenerateSimpleTestAccessToken("auth0|alice", time.Hour)
+	require.NoError(t, err)
+	adminToken, err := jwt.GenerateTestAccessToken("auth0|admin", "https://test.any.com/", "https://test.any.com/api/v2/",
+		constants.AffiliationManageRequiredScope, time.Hour)
+	require.NoError(t, err)
+
+	reader := &mockUserServiceReader{
+		metadataLookupFunc: func(ctx context.Context, input string) (*model.User, error) {
+			return &model.User{Token: input}, nil
+		},
+	}
+	store := &memoryAffiliationStore{histories: map[string]model.AffiliationHistory{}}
+	publisher := &mockEventPublisher{}
+	orchestrator := NewMessageHandlerOrchestrator(
+		WithUserReaderForMessageHandler(reader),
+		WithAffiliationStoreForMessageHandler(store),
+		WithEventPublisherForMessageHandler(publisher),
+	).(*messageHandlerOrchestrator)
+
+	call := func(t *testing.T, handler func(context.Context, *mockTransportMessenger) ([]byte, error), request any) map[string]any {
+		t.Helper()
+		payload, _ := json.Marshal(request)
+		result, err := handler(ctx, &mockTransportMessenger{data: payload})
+		require.NoError(t, err)
+		var response map[string]any
+		require.NoError(t, json.Unmarshal(result, &response))
+		return response
+	}
+	add := func(ctx context.Context, msg *mockTransportMessenger) ([]byte, error) {
+		return orchestrator.AddAffiliation(ctx, msg)
+	}
+	end := func(ctx context.Context, msg *mockTransportMessenger) ([]byte, error) {
+		return orchestrator.EndAffiliation(ctx, msg)
+	}
+	list := func(ctx context.Context, msg *mockTransportMessenger) ([]byte, error) {
+		return orchestrator.ListAffiliations(ctx, msg)
+	}
+
+	var currentID string
+	t.Run("adds a current affiliation and publishes the change", func(t *testing.T) {
+		response := call(t, add, map[string]any{"auth_token": userToken, "organization": "The Linux Foundation", "start_date": "2023-04-01"})
+		require.Equal(t, true, response["success"], response["error"])
+		currentID = response["data"].(map[string]any)["id"].(string)
+		assert.NotEmpty(t, currentID)
+
+		require.Len(t, publisher.calls, 1)
+		assert.Equal(t, constants.AffiliationChangedSubject, publisher.calls[0].Subject)
+		var event AffiliationChangedEvent
+		require.NoError(t, json.Unmarshal(publisher.calls[0].Data, &event))
+		assert.Equal(t, "auth0|alice", event.UserID)
+		assert.Equal(t, affiliationActionAdded, event.Action)
+		assert.Len(t, event.Affiliations, 1)
+	})
+
+	t.Run("rejects overlapping and invalid periods", func(t *testing.T) {
+		response := call(t, add, map[string]any{"auth_token": userToken, "organization": "Acme Corp", "start_date": "2019-02-01", "end_date": "2023-04-01"})
+		assert.Equal(t, "affiliation overlaps The Linux Foundation from 2023-04-01", response["error"])
+		response = call(t, add, map[string]any{"auth_token": userToken, "organization": "Acme Corp", "start_date": "2019-02-01", "end_date": "2018-01-01"})
+		assert.Equal(t, "end_date must not be before start_date", response["error"])
+		response = call(t, add, map[string]any{"auth_token": userToken, "organization": "Acme Corp", "start_date": "02/01/2019"})
+		assert.Equal(t, "start_date must be a date such as 2024-01-31", response["error"])
+	})
+
+	t.Run("past affiliations are kept in start order", func(t *testing.T) {
+		response := call(t, add, map[string]any{"auth_token": userToken, "organization": "Acme Corp", "start_date": "2019-02-01", "end_date": "2023-03-31"})
+		require.Equal(t, true, response["success"], response["error"])
+
+		response = call(t, list, map[string]any{"sub": "auth0|alice"})
+		require.Equal(t, true, response["success"], response["error"])
+		affiliations := response["data"].(map[string]any)["affiliations"].([]any)
+		require.Len(t, affiliations, 2)
+		assert.Equal(t, "Acme Corp", affiliations[0].(map[string]any)["organization"])
+		assert.Equal(t, "The Linux Foundation", affiliations[1].(map[string]any)["organization"])
+	})
+
+	t.Run("ends a current affiliation once", func(t *testing.T) {
+		response := call(t, end, map[string]any{"auth_token": userToken, "id": currentID, "end_date": "2025-06-30"})
+		require.Equal(t, true, response["success"], response["error"])
+		assert.Equal(t, "2025-06-30", response["data"].(map[string]any)["end_date"])
+
+		response = call(t, end, map[string]any{"auth_token": userToken, "id": currentID, "end_date": "2025-07-31"})
+		assert.Equal(t, "affiliation already ended on 2025-06-30", response["error"])
+		response = call(t, end, map[string]any{"auth_token": userToken, "id": "missing", "end_date": "2025-07-31"})
+		assert.Equal(t, "affiliation not found", response["error"])
+	})
+
+	t.Run("changing another user's history needs the management scope", func(t *testing.T) {
+		response := call(t, add, map[string]any{"auth_token": userToken, "sub": "auth0|bob", "organization": "Acme Corp", "start_date": "2020-01-01"})
+		assert.Equal(t, "insufficient_scope", response["error"])
+
+		publisher.calls = nil
+		response = call(t, add, map[string]any{"auth_token": adminToken, "sub": "auth0|bob", "organization": "Acme Corp", "start_date": "2020-01-01"})
+		require.Equal(t, true, response["success"], response["error"])
+		require.Len(t, publisher.calls, 1)
+		var event AffiliationChangedEvent
+		require.NoError(t, json.Unmarshal(publisher.calls[0].Data, &event))
+		assert.Equal(t, "auth0|bob", event.UserID)
+		assert.Equal(t, "auth0|admin", event.ChangedBy)
+	})
+
+	t.Run("empty history lists as an empty list", func(t *testing.T) {
+		response := call(t, list, map[string]any{"sub": "auth0|carol"})
+		require.Equal(t, true, response["success"], response["error"])
+		assert.Equal(t, []any{}, response["data"].(map[string]any)["affiliations"])
+	})
+
+	t.Run("unavailable without a store", func(t *testing.T) {
+		unconfigured := NewMessageHandlerOrchestrator(WithUserReaderForMessageHandler(reader))
+		result, err := unconfigured.ListAffiliations(ctx, &mockTransportMessenger{data: []byte(`{"sub":"auth0|alice"}`)})
+		require.NoError(t, err)
+		assert.Contains(t, string(result), "affiliations_unavailable")
+	})
+}
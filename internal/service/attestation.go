@@ -0,0 +1,245 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/jwt"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/redaction"
+)
+
+// verifiedFieldReadOnly is the error of an update changing a verified field
+const verifiedFieldReadOnly = "verified_field_read_only"
+
+// defaultAttestableFields are the fields that can be verified when none are configured
+var defaultAttestableFields = []string{"name", "given_name", "family_name", "organization", "job_title"}
+
+// attestationRecordRequest is the input of admin.attestation.record. The
+// attested value is the one the field has when the record is made.
+type attestationRecordRequest struct {
+	AuthToken string `json:"auth_token"`
+	Sub       string `json:"sub"`
+	Field     string `json:"field"`
+	// Method is how the value was checked, e.g. "identity_document"
+	Method string `json:"method"`
+}
+
+// attestationRevokeRequest is the input of admin.attestation.revoke
+type attestationRevokeRequest struct {
+	AuthToken string `json:"auth_token"`
+	Sub       string `json:"sub"`
+	Field     string `json:"field"`
+}
+
+// WithAttestationStoreForMessageHandler sets where the message handler
+// orchestrator keeps verified profile fields. fields are the metadata fields
+// that can be verified; empty uses the defaults.
+func WithAttestationStoreForMessageHandler(store port.AttestationStore, fields []string) MessageHandlerOrchestratorOption {
+	return func(m *messageHandlerOrchestrator) {
+		m.attestations = store
+		m.attestableFields = fields
+		if len(fields) == 0 {
+			m.attestableFields = defaultAttestableFields
+		}
+	}
+}
+
+// RecordAttestation marks the current value of a profile field of a user as
+// verified. Only callers with the attestation scope record attestations; the
+// user can no longer change the field through user_metadata.update.
+func (m *messageHandlerOrchestrator) RecordAttestation(ctx context.Context, msg port.TransportMessenger) ([]byte, error) {
+	if m.attestations == nil || m.userReader == nil {
+		return m.errorResponse("attestation_unavailable"), nil
+	}
+
+	var request attestationRecordRequest
+	if err := json.Unmarshal(msg.Data(), &request); err != nil {
+		return m.errorResponse("failed_to_unmarshal_request"), nil
+	}
+	method := strings.TrimSpace(request.Method)
+	if method == "" {
+		return m.errorResponse("method is required"), nil
+	}
+
+	claims, user, field, err := m.attestationTarget(ctx, request.AuthToken, request.Sub, request.Field)
+	if err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+	value, ok := user.UserMetadata.FieldValue(field)
+	if !ok || value == "" {
+		return m.errorResponse("field " + field + " has no value to verify"), nil
+	}
+
+	attestation := &model.FieldAttestation{
+		UserID:     user.UserID,
+		Field:      field,
+		Value:      value,
+		Verifier:   claims.Subject,
+		Method:     method,
+		VerifiedAt: time.Now().UTC(),
+	}
+	if err := m.attestations.PutAttestation(ctx, attestation); err != nil {
+		slog.ErrorContext(ctx, "failed to record attestation", "error", err)
+		return m.errorResponse(err.Error()), nil
+	}
+
+	slog.InfoContext(ctx, "attestation recorded",
+		"user_id", redaction.Redact(attestation.UserID),
+		"field", field,
+		"method", method,
+		"verifier", redaction.Redact(attestation.Verifier),
+	)
+
+	responseJSON, err := json.Marshal(UserDataResponse{Success: true, Data: attestation})
+	if err != nil {
+		return m.errorResponse("failed to marshal response"), nil
+	}
+	return responseJSON, nil
+}
+
+// RevokeAttestation removes the verification of a profile field, making it
+// writable by the user again
+func (m *messageHandlerOrchestrator) RevokeAttestation(ctx context.Context, msg port.TransportMessenger) ([]byte, error) {
+	if m.attestations == nil || m.userReader == nil {
+		return m.errorResponse("attestation_unavailable"), nil
+	}
+
+	var request attestationRevokeRequest
+	if err := json.Unmarshal(msg.Data(), &request); err != nil {
+		return m.errorResponse("failed_to_unmarshal_request"), nil
+	}
+
+	claims, user, field, err := m.attestationTarget(ctx, request.AuthToken, request.Sub, request.Field)
+	if err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+	if err := m.attestations.DeleteAttestation(ctx, user.UserID, field); err != nil {
+		slog.ErrorContext(ctx, "failed to revoke attestation", "error", err)
+		return m.errorResponse(err.Error()), nil
+	}
+
+	slog.InfoContext(ctx, "attestation revoked",
+		"user_id", redaction.Redact(user.UserID),
+		"field", field,
+		"revoked_by", redaction.Redact(claims.Subject),
+	)
+
+	responseJSON, err := json.Marshal(UserDataResponse{Success: true, Message: "attestation revoked"})
+	if err != nil {
+		return m.errorResponse("failed to marshal response"), nil
+	}
+	return responseJSON, nil
+}
+
+// attestationTarget authorizes an attestation request and resolves the user
+// and field it is about
+func (m *messageHandlerOrchestrator) attestationTarget(ctx context.Context, authToken, sub, field string) (*jwt.Claims, *model.User, string, error) {
+	claims, err := m.authorizeScope(ctx, authToken, constants.UserAttestRequiredScope)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	sub, field = strings.TrimSpace(sub), strings.TrimSpace(field)
+	switch {
+	case sub == "" || field == "":
+		return nil, nil, "", errs.NewValidation("sub and field are required")
+	case !slices.Contains(m.attestableFields, field):
+		return nil, nil, "", errs.NewValidation("field " + field + " can't be verified")
+	}
+
+	user, err := m.userReader.GetUser(ctx, &model.User{UserID: sub, Sub: sub})
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return claims, user, field, nil
+}
+
+// verifiedFields returns how the fields of user were verified, leaving out
+// the attestations whose field changed since. A failed lookup is logged and
+// reported as no verified field: reads don't fail over it.
+func (m *messageHandlerOrchestrator) verifiedFields(ctx context.Context, user *model.User) map[string]model.AttributeVerification {
+	if m.attestations == nil || user == nil || user.UserMetadata == nil {
+		return nil
+	}
+	attestations, err := m.attestations.ListAttestations(ctx, user.UserID)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to list attestations", "error", err, "user_id", redaction.Redact(user.UserID))
+		return nil
+	}
+
+	var verified map[string]model.AttributeVerification
+	for _, attestation := range attestations {
+		if !slices.Contains(m.attestableFields, attestation.Field) || !attestation.Holds(user.UserMetadata) {
+			continue
+		}
+		if verified == nil {
+			verified = make(map[string]model.AttributeVerification)
+		}
+		verified[attestation.Field] = attestation.Verification()
+	}
+	return verified
+}
+
+// protectVerifiedFields returns the verified fields the update would change.
+// A field rewritten with its verified value is left alone.
+func (m *messageHandlerOrchestrator) protectVerifiedFields(ctx context.Context, update *model.User) []fieldAuthorizationError {
+	if m.attestations == nil {
+		return nil
+	}
+	var attestable []string
+	for _, field := range update.UserMetadata.SetFields() {
+		if slices.Contains(m.attestableFields, field) {
+			attestable = append(attestable, field)
+		}
+	}
+	if len(attestable) == 0 {
+		return nil
+	}
+
+	deny := func(reason string) []fieldAuthorizationError {
+		denied := make([]fieldAuthorizationError, len(attestable))
+		for i, field := range attestable {
+			denied[i] = fieldAuthorizationError{Field: field, Error: reason}
+		}
+		return denied
+	}
+	// without knowing whose fields these are, none of them can be written
+	owner, err := m.tokenOwner(ctx, update.Token)
+	if err != nil {
+		return deny(err.Error())
+	}
+	attestations, err := m.attestations.ListAttestations(ctx, owner.UserID)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to list attestations", "error", err, "user_id", redaction.Redact(owner.UserID))
+		return deny("failed to check verified fields")
+	}
+
+	var denied []fieldAuthorizationError
+	for _, attestation := range attestations {
+		if !slices.Contains(attestable, attestation.Field) || !attestation.Holds(owner.UserMetadata) {
+			continue
+		}
+		if value, _ := update.UserMetadata.FieldValue(attestation.Field); value != attestation.Value {
+			denied = append(denied, fieldAuthorizationError{Field: attestation.Field, Error: verifiedFieldReadOnly})
+		}
+	}
+	if len(denied) > 0 {
+		slices.SortFunc(denied, func(a, b fieldAuthorizationError) int { return strings.Compare(a.Field, b.Field) })
+		slog.WarnContext(ctx, "update of verified fields denied",
+			"fields", len(denied),
+			"user_id", redaction.Redact(owner.UserID),
+		)
+	}
+	return denied
+}
@@ -0,0 +1,160 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/jwt"
+)
+
+// memoryAttestationStore keeps one attestation per user and field, like the KV store
+type memoryAttestationStore struct {
+	attestations map[string]*model.FieldAttestation
+}
+
+func (s *memoryAttestationStore) PutAttestation(_ context.Context, attestation *model.FieldAttestation) error {
+	s.attestations[attestation.UserID+"/"+attestation.Field] = attestation
+	return nil
+}
+
+func (s *memoryAttestationStore) DeleteAttestation(_ context.Context, userID, field string) error {
+	delete(s.attestations, userID+"/"+field)
+	return nil
+}
+
+func (s *memoryAttestationStore) ListAttestations(_ context.Context, userID string) ([]*model.FieldAttestation, error) {
+	var attestations []*model.FieldAttestation
+	for _, attestation := range s.attestations {
+		if attestation.UserID == userID {
+			attestations = append(attestations, attestation)
+		}
+	}
+	return attestations, nil
+}
+
+func TestMessageHandlerOrchestrator_Attestation(t *testing.T) {
+	ctx := context.Background()
+	str := func(v string) *string { return &v }
+
+	adminToken, err := jwt.GenerateTestAccessToken("auth0|admin", "https://test.any.com/", "https://test.any.com/api/v2/",
+		constants.UserAttestRequiredScope, time.Hour)
+	require.NoError(t, err)
+	userToken, err := jwt.GenerateSimpleTestAccessToken("auth0|alice", time.Hour)
+	require.NoError(t, err)
+
+	alice := &model.User{UserID: "auth0|alice", Username: "alice", UserMetadata: &model.UserMetadata{
+		Name:         str("Alice Liddell"),
+		Organization: str("Example Corp"),
+		City:         str("Oxford"),
+	}}
+	reader := &mockUserServiceReader{
+		metadataLookupFunc: func(ctx context.Context, input string) (*model.User, error) {
+			if input == userToken {
+				return &model.User{Token: input, UserID: alice.UserID}, nil
+			}
+			return &model.User{Token: input, UserID: input}, nil
+		},
+		getUserFunc: func(ctx context.Context, user *model.User) (*model.User, error) {
+			copied := *alice
+			metadata := *alice.UserMetadata
+			copied.UserMetadata = &metadata
+			return &copied, nil
+		},
+	}
+	var updated *model.User
+	writer := &mockUserServiceWriter{updateUserFunc: func(ctx context.Context, user *model.User) (*model.User, error) {
+		updated = user
+		return user, nil
+	}}
+	store := &memoryAttestationStore{attestations: map[string]*model.FieldAttestation{}}
+	orchestrator := NewMessageHandlerOrchestrator(
+		WithUserReaderForMessageHandler(reader),
+		WithUserWriterForMessageHandler(writer),
+		WithAttestationStoreForMessageHandler(store, nil),
+	).(*messageHandlerOrchestrator)
+
+	call := func(t *testing.T, handler func(context.Context, *mockTransportMessenger) ([]byte, error), request any) map[string]any {
+		t.Helper()
+		payload, ok := request.([]byte)
+		if !ok {
+			payload, _ = json.Marshal(request)
+		}
+		result, err := handler(ctx, &mockTransportMessenger{data: payload})
+		require.NoError(t, err)
+		var response map[string]any
+		require.NoError(t, json.Unmarshal(result, &response))
+		return response
+	}
+	record := func(ctx context.Context, msg *mockTransportMessenger) ([]byte, error) {
+		return orchestrator.RecordAttestation(ctx, msg)
+	}
+	revoke := func(ctx context.Context, msg *mockTransportMessenger) ([]byte, error) {
+		return orchestrator.RevokeAttestation(ctx, msg)
+	}
+	read := func(ctx context.Context, msg *mockTransportMessenger) ([]byte, error) {
+		return orchestrator.GetUserMetadata(ctx, msg)
+	}
+	update := func(ctx context.Context, msg *mockTransportMessenger) ([]byte, error) {
+		return orchestrator.UpdateUser(ctx, msg)
+	}
+
+	t.Run("recording needs the attestation scope", func(t *testing.T) {
+		response := call(t, record, map[string]any{"auth_token": userToken, "sub": "auth0|alice", "field": "name", "method": "identity_document"})
+		assert.Equal(t, "insufficient_scope", response["error"])
+	})
+
+	t.Run("only attestable fields with a value can be verified", func(t *testing.T) {
+		response := call(t, record, map[string]any{"auth_token": adminToken, "sub": "auth0|alice", "field": "city", "method": "manual"})
+		assert.Equal(t, "field city can't be verified", response["error"])
+		response = call(t, record, map[string]any{"auth_token": adminToken, "sub": "auth0|alice", "field": "job_title", "method": "manual"})
+		assert.Equal(t, "field job_title has no value to verify", response["error"])
+	})
+
+	t.Run("records the current value", func(t *testing.T) {
+		response := call(t, record, map[string]any{"auth_token": adminToken, "sub": "auth0|alice", "field": "name", "method": "identity_document"})
+		require.Equal(t, true, response["success"], response["error"])
+		data := response["data"].(map[string]any)
+		assert.Equal(t, "Alice Liddell", data["value"])
+		assert.Equal(t, "auth0|admin", data["verifier"])
+	})
+
+	t.Run("reads report verified fields beside the metadata", func(t *testing.T) {
+		response := call(t, read, []byte("auth0|alice"))
+		data := response["data"].(map[string]any)
+		assert.Equal(t, "Alice Liddell", data["name"])
+		verified := data["verified"].(map[string]any)
+		assert.Len(t, verified, 1)
+		assert.Equal(t, "identity_document", verified["name"].(map[string]any)["method"])
+
+		response = call(t, read, map[string]any{"input": "auth0|alice", "fields": []string{"city"}})
+		assert.NotContains(t, response["data"], "verified")
+	})
+
+	t.Run("users can't change verified fields", func(t *testing.T) {
+		updated = nil
+		response := call(t, update, map[string]any{"token": userToken, "user_metadata": map[string]string{"name": "Alice L.", "city": "London"}})
+		assert.Equal(t, verifiedFieldReadOnly, response["data"].(map[string]any)["fields"].([]any)[0].(map[string]any)["error"])
+		assert.Nil(t, updated)
+
+		response = call(t, update, map[string]any{"token": userToken, "user_metadata": map[string]string{"name": "Alice Liddell", "organization": "Other Corp"}})
+		assert.Equal(t, true, response["success"], response["error"])
+		require.NotNil(t, updated)
+	})
+
+	t.Run("revoked fields are writable again", func(t *testing.T) {
+		response := call(t, revoke, map[string]any{"auth_token": adminToken, "sub": "auth0|alice", "field": "name"})
+		require.Equal(t, true, response["success"], response["error"])
+		response = call(t, update, map[string]any{"token": userToken, "user_metadata": map[string]string{"name": "Alice L."}})
+		assert.Equal(t, true, response["success"], response["error"])
+	})
+}
@@ -0,0 +1,91 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/redaction"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/tenant"
+)
+
+// userCacheInvalidation is the message each replica publishes after a write
+// through its user cache
+type userCacheInvalidation struct {
+	UserID string `json:"user_id"`
+	// Origin identifies the replica that made the write, which has already
+	// dropped its own entry
+	Origin string `json:"origin"`
+}
+
+// CacheInvalidationBroadcaster keeps the user caches of the replicas in step:
+// it publishes the users written through the local cache and drops the users
+// other replicas wrote. Without it a replica serves a user another replica
+// changed until the entry expires or the reconciler samples it.
+type CacheInvalidationBroadcaster struct {
+	cache     port.UserCache
+	publisher port.EventPublisher
+	origin    string
+
+	invalidations metric.Int64Counter
+}
+
+// Publish tells the other replicas that userID changed. A failed publish is
+// only logged: the local write succeeded, and the other replicas' entries
+// still expire with the cache TTL.
+func (b *CacheInvalidationBroadcaster) Publish(ctx context.Context, userID string) {
+	data, err := json.Marshal(userCacheInvalidation{UserID: userID, Origin: b.origin})
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to marshal user cache invalidation", "error", err)
+		return
+	}
+	if err := b.publisher.Publish(ctx, constants.UserCacheInvalidateSubject, data); err != nil {
+		slog.WarnContext(ctx, "failed to broadcast user cache invalidation",
+			"error", err,
+			"user_id", redaction.Redact(userID),
+		)
+		return
+	}
+	b.invalidations.Add(ctx, 1, tenant.Attributes(ctx, attribute.String("direction", "sent")))
+}
+
+// Handle drops the cache entry of a user another replica wrote; a replica's
+// own broadcasts are ignored
+func (b *CacheInvalidationBroadcaster) Handle(ctx context.Context, data []byte) {
+	var invalidation userCacheInvalidation
+	if err := json.Unmarshal(data, &invalidation); err != nil {
+		slog.WarnContext(ctx, "ignoring malformed user cache invalidation", "error", err)
+		return
+	}
+	if invalidation.UserID == "" || invalidation.Origin == b.origin {
+		return
+	}
+	slog.DebugContext(ctx, "dropping user cache entry changed by another replica",
+		"user_id", redaction.Redact(invalidation.UserID),
+		"origin", invalidation.Origin,
+	)
+	b.cache.InvalidateUser(invalidation.UserID)
+	b.invalidations.Add(ctx, 1, tenant.Attributes(ctx, attribute.String("direction", "received")))
+}
+
+// NewCacheInvalidationBroadcaster creates a broadcaster for cache. origin must
+// be unique to the replica, so it can tell its own broadcasts apart.
+func NewCacheInvalidationBroadcaster(cache port.UserCache, publisher port.EventPublisher, origin string) *CacheInvalidationBroadcaster {
+	invalidations, _ := meter.Int64Counter("auth_service.user_cache.invalidations",
+		metric.WithDescription("User cache invalidations broadcast to or received from other replicas"))
+
+	return &CacheInvalidationBroadcaster{
+		cache:         cache,
+		publisher:     publisher,
+		origin:        origin,
+		invalidations: invalidations,
+	}
+}
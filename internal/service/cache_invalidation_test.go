@@ -0,0 +1,47 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+)
+
+func TestCacheInvalidationBroadcaster(t *testing.T) {
+	ctx := context.Background()
+
+	// two replicas sharing one bus
+	publisher := &mockEventPublisher{}
+	first := NewCacheInvalidationBroadcaster(&mockUserCache{}, publisher, "replica-1")
+	secondCache := &mockUserCache{}
+	second := NewCacheInvalidationBroadcaster(secondCache, publisher, "replica-2")
+
+	first.Publish(ctx, "auth0|1")
+	require.Len(t, publisher.calls, 1)
+	assert.Equal(t, constants.UserCacheInvalidateSubject, publisher.calls[0].Subject)
+
+	t.Run("other replicas drop the entry", func(t *testing.T) {
+		second.Handle(ctx, publisher.calls[0].Data)
+		assert.Equal(t, []string{"auth0|1"}, secondCache.invalidated)
+	})
+
+	t.Run("own broadcasts are ignored", func(t *testing.T) {
+		firstCache := &mockUserCache{}
+		self := NewCacheInvalidationBroadcaster(firstCache, publisher, "replica-1")
+		self.Handle(ctx, publisher.calls[0].Data)
+		assert.Empty(t, firstCache.invalidated)
+	})
+
+	t.Run("malformed messages are ignored", func(t *testing.T) {
+		cache := &mockUserCache{}
+		NewCacheInvalidationBroadcaster(cache, publisher, "replica-3").Handle(ctx, []byte("{"))
+		NewCacheInvalidationBroadcaster(cache, publisher, "replica-3").Handle(ctx, []byte(`{"origin":"replica-1"}`))
+		assert.Empty(t, cache.invalidated)
+	})
+}
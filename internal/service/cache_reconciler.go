@@ -0,0 +1,177 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/redaction"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/tenant"
+)
+
+// meter is safe to initialize at package level — otel.Meter() delegates to
+// whichever MeterProvider is installed when instruments record.
+var meter = otel.Meter("github.com/linuxfoundation/lfx-v2-auth-service/internal/service")
+
+const (
+	// divergenceReasonChanged means the provider's record differs from the cached one
+	divergenceReasonChanged = "changed"
+	// divergenceReasonDeleted means the provider no longer has the user
+	divergenceReasonDeleted = "deleted"
+)
+
+// CacheReconcilerConfig configures the cache reconciler
+type CacheReconcilerConfig struct {
+	// SampleRate is the fraction of cached entries checked per run, in (0, 1]
+	SampleRate float64
+	// MaxSamples caps the entries checked per run; <= 0 means no cap
+	MaxSamples int
+}
+
+// ReconcileResult summarizes one reconciliation run
+type ReconcileResult struct {
+	Cached   int
+	Sampled  int
+	Diverged int
+	Errors   int
+}
+
+// CacheReconciler re-fetches a sample of cached users from the provider and
+// evicts the entries that no longer match.
+type CacheReconciler struct {
+	cache    port.UserCache
+	provider port.UserReader
+	config   CacheReconcilerConfig
+	shuffle  func(n int, swap func(i, j int))
+
+	sampled  metric.Int64Counter
+	diverged metric.Int64Counter
+	failures metric.Int64Counter
+}
+
+// Run performs one reconciliation pass; it satisfies scheduler.Job
+func (r *CacheReconciler) Run(ctx context.Context) error {
+	_, err := r.Reconcile(ctx)
+	return err
+}
+
+// Reconcile checks a sample of cached users against the provider
+func (r *CacheReconciler) Reconcile(ctx context.Context) (ReconcileResult, error) {
+	ids := r.cache.CachedUserIDs()
+	result := ReconcileResult{Cached: len(ids)}
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	n := int(math.Ceil(float64(len(ids)) * r.config.SampleRate))
+	if r.config.MaxSamples > 0 && n > r.config.MaxSamples {
+		n = r.config.MaxSamples
+	}
+	r.shuffle(len(ids), func(i, j int) { ids[i], ids[j] = ids[j], ids[i] })
+
+	for _, userID := range ids[:n] {
+		cached, ok := r.cache.CachedUser(userID)
+		if !ok {
+			// expired or invalidated since the IDs were listed
+			continue
+		}
+		result.Sampled++
+		r.sampled.Add(ctx, 1, tenant.Attributes(ctx))
+
+		fresh, err := r.provider.GetUser(ctx, &model.User{UserID: userID})
+		if err != nil {
+			var notFound errs.NotFound
+			if errors.As(err, &notFound) {
+				r.evict(ctx, userID, divergenceReasonDeleted)
+				result.Diverged++
+				continue
+			}
+			slog.WarnContext(ctx, "cache reconciliation fetch failed",
+				"error", err,
+				"user_id", redaction.Redact(userID),
+			)
+			r.failures.Add(ctx, 1, tenant.Attributes(ctx))
+			result.Errors++
+			continue
+		}
+
+		if userFingerprint(cached) != userFingerprint(fresh) {
+			r.evict(ctx, userID, divergenceReasonChanged)
+			result.Diverged++
+		}
+	}
+
+	slog.InfoContext(ctx, "cache reconciliation completed",
+		"cached", result.Cached,
+		"sampled", result.Sampled,
+		"diverged", result.Diverged,
+		"errors", result.Errors,
+	)
+
+	if result.Sampled > 0 && result.Errors == result.Sampled {
+		return result, errs.NewServiceUnavailable("cache reconciliation could not reach the user provider")
+	}
+	return result, nil
+}
+
+func (r *CacheReconciler) evict(ctx context.Context, userID, reason string) {
+	slog.DebugContext(ctx, "evicting stale cache entry",
+		"user_id", redaction.Redact(userID),
+		"reason", reason,
+	)
+	r.cache.InvalidateUser(userID)
+	r.diverged.Add(ctx, 1, tenant.Attributes(ctx, attribute.String("reason", reason)))
+}
+
+// userFingerprint hashes the fields a cache entry must agree on with the
+// provider. Login activity changes on every sign-in and is bounded by the
+// cache TTL anyway, so it is not treated as drift.
+func userFingerprint(user *model.User) [sha256.Size]byte {
+	if user == nil {
+		return [sha256.Size]byte{}
+	}
+	comparable := *user
+	comparable.Token = ""
+	comparable.Activity = nil
+	raw, _ := json.Marshal(comparable)
+	return sha256.Sum256(raw)
+}
+
+// NewCacheReconciler creates a reconciler for cache. provider must be the
+// uncached repository, otherwise every entry trivially matches itself.
+func NewCacheReconciler(cache port.UserCache, provider port.UserReader, config CacheReconcilerConfig) *CacheReconciler {
+	if config.SampleRate <= 0 || config.SampleRate > 1 {
+		config.SampleRate = 1
+	}
+
+	sampled, _ := meter.Int64Counter("auth_service.user_cache.reconcile.sampled",
+		metric.WithDescription("Cached users re-fetched from the provider for drift detection"))
+	diverged, _ := meter.Int64Counter("auth_service.user_cache.reconcile.diverged",
+		metric.WithDescription("Cached users found to differ from the provider and evicted"))
+	failures, _ := meter.Int64Counter("auth_service.user_cache.reconcile.errors",
+		metric.WithDescription("Provider fetches that failed during reconciliation"))
+
+	return &CacheReconciler{
+		cache:    cache,
+		provider: provider,
+		config:   config,
+		shuffle:  rand.Shuffle,
+		sampled:  sampled,
+		diverged: diverged,
+		failures: failures,
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+)
+
+// mockUserCache is a map-backed implementation of port.UserCache for testing
+type mockUserCache struct {
+	ids         []string
+	users       map[string]*model.User
+	invalidated []string
+}
+
+func (m *mockUserCache) CachedUserIDs() []string {
+	return append([]string(nil), m.ids...)
+}
+
+func (m *mockUserCache) CachedUser(userID string) (*model.User, bool) {
+	user, ok := m.users[userID]
+	return user, ok
+}
+
+func (m *mockUserCache) InvalidateUser(userID string) {
+	m.invalidated = append(m.invalidated, userID)
+	delete(m.users, userID)
+}
+
+func TestCacheReconciler_Reconcile(t *testing.T) {
+	cached := map[string]*model.User{
+		"auth0|same":    {UserID: "auth0|same", Username: "same"},
+		"auth0|changed": {UserID: "auth0|changed", Username: "before"},
+		"auth0|deleted": {UserID: "auth0|deleted", Username: "gone"},
+		"auth0|flaky":   {UserID: "auth0|flaky", Username: "flaky"},
+	}
+	provider := &mockUserServiceReader{
+		getUserFunc: func(ctx context.Context, user *model.User) (*model.User, error) {
+			switch user.UserID {
+			case "auth0|same":
+				return &model.User{UserID: "auth0|same", Username: "same", Activity: &model.UserActivity{LastIP: "10.0.0.0"}}, nil
+			case "auth0|changed":
+				return &model.User{UserID: "auth0|changed", Username: "after"}, nil
+			case "auth0|deleted":
+				return nil, errs.NewNotFound("user not found")
+			default:
+				return nil, errors.New("timeout")
+			}
+		},
+	}
+
+	newCache := func() *mockUserCache {
+		users := make(map[string]*model.User, len(cached))
+		ids := make([]string, 0, len(cached))
+		for id, u := range cached {
+			users[id] = u
+			ids = append(ids, id)
+		}
+		return &mockUserCache{ids: ids, users: users}
+	}
+
+	t.Run("full sample evicts drifted entries", func(t *testing.T) {
+		cache := newCache()
+		r := NewCacheReconciler(cache, provider, CacheReconcilerConfig{SampleRate: 1})
+
+		result, err := r.Reconcile(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, ReconcileResult{Cached: 4, Sampled: 4, Diverged: 2, Errors: 1}, result)
+		assert.ElementsMatch(t, []string{"auth0|changed", "auth0|deleted"}, cache.invalidated)
+	})
+
+	t.Run("sample rate and cap limit the checks", func(t *testing.T) {
+		cache := newCache()
+		cache.ids = []string{"auth0|same", "auth0|changed", "auth0|deleted"}
+		r := NewCacheReconciler(cache, provider, CacheReconcilerConfig{SampleRate: 0.5, MaxSamples: 1})
+
+		result, err := r.Reconcile(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.Sampled)
+	})
+
+	t.Run("provider outage is reported", func(t *testing.T) {
+		cache := &mockUserCache{ids: []string{"auth0|flaky"}, users: map[string]*model.User{"auth0|flaky": cached["auth0|flaky"]}}
+		r := NewCacheReconciler(cache, provider, CacheReconcilerConfig{SampleRate: 1})
+
+		_, err := r.Reconcile(context.Background())
+		var unavailable errs.ServiceUnavailable
+		assert.ErrorAs(t, err, &unavailable)
+		assert.Empty(t, cache.invalidated, "entries are kept when the provider can't be reached")
+	})
+
+	t.Run("empty cache", func(t *testing.T) {
+		result, err := NewCacheReconciler(&mockUserCache{}, provider, CacheReconcilerConfig{}).Reconcile(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, ReconcileResult{}, result)
+	})
+}
@@ -0,0 +1,123 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+)
+
+// CallerProfiles are the profile fields each caller service may read of
+// other users. A caller is matched by the prefix of its transport caller,
+// which for NATS is the inbox its replies are delivered to: a service
+// connecting with a custom inbox prefix, which its NATS permissions reserve
+// to it, is recognized by it. Callers matching no profile get the fallback
+// fields. The username is part of every profile, so a read always tells
+// which user it returned.
+type CallerProfiles struct {
+	profiles map[string][]string
+	fallback []string
+}
+
+// ParseCallerProfiles parses rules of the form "prefix=field|field", e.g.
+// "_INBOX_meet=zoneinfo|timezone", and the fallback fields of the callers
+// matching none
+func ParseCallerProfiles(rules []string, fallback []string) (*CallerProfiles, error) {
+	profiles := &CallerProfiles{profiles: make(map[string][]string, len(rules))}
+	for _, rule := range rules {
+		prefix, fields, ok := strings.Cut(rule, "=")
+		prefix = strings.TrimSpace(prefix)
+		if !ok || prefix == "" {
+			return nil, fmt.Errorf("rule %q must be prefix=field|field", rule)
+		}
+		if _, duplicate := profiles.profiles[prefix]; duplicate {
+			return nil, fmt.Errorf("rule %q repeats the profile of %s", rule, prefix)
+		}
+		allowed, err := profileFieldList(strings.Split(fields, "|"))
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: %w", rule, err)
+		}
+		profiles.profiles[prefix] = allowed
+	}
+	allowed, err := profileFieldList(fallback)
+	if err != nil {
+		return nil, fmt.Errorf("fallback profile: %w", err)
+	}
+	profiles.fallback = allowed
+	return profiles, nil
+}
+
+// profileFieldList validates the fields of a profile and adds the username
+// to them
+func profileFieldList(fields []string) ([]string, error) {
+	allowed := []string{model.ProfileFieldUsername}
+	for _, field := range fields {
+		field = strings.TrimSpace(field)
+		if field == "" || slices.Contains(allowed, field) {
+			continue
+		}
+		if !model.IsProfileField(field) {
+			return nil, fmt.Errorf("unknown profile field %q", field)
+		}
+		allowed = append(allowed, field)
+	}
+	return allowed, nil
+}
+
+// WithCallerProfilesForMessageHandler restricts the profile fields other
+// services read to those of their caller profile
+func WithCallerProfilesForMessageHandler(profiles *CallerProfiles) MessageHandlerOrchestratorOption {
+	return func(m *messageHandlerOrchestrator) {
+		m.callerProfiles = profiles
+	}
+}
+
+// fieldsFor returns the fields of the profile of caller, that of its longest
+// matching prefix. A prefix matches the caller it equals or the callers below
+// it, so _INBOX_meet doesn't match _INBOX_meetings.
+func (p *CallerProfiles) fieldsFor(caller string) []string {
+	var match string
+	for prefix := range p.profiles {
+		if len(prefix) > len(match) && callerHasPrefix(caller, prefix) {
+			match = prefix
+		}
+	}
+	if match == "" {
+		return p.fallback
+	}
+	return p.profiles[match]
+}
+
+// callerHasPrefix reports whether caller is prefix or below it, at a token
+// boundary
+func callerHasPrefix(caller, prefix string) bool {
+	if !strings.HasPrefix(caller, prefix) {
+		return false
+	}
+	rest := caller[len(prefix):]
+	return rest == "" || rest[0] == '.'
+}
+
+// restrict returns the fields a read of caller selecting requested gets:
+// the whole profile when it selects none, else the selected fields the
+// profile allows, or the username when it allows none of them
+func (p *CallerProfiles) restrict(caller string, requested []string) []string {
+	allowed := p.fieldsFor(caller)
+	if len(requested) == 0 {
+		return slices.Clone(allowed)
+	}
+	var fields []string
+	for _, field := range requested {
+		if slices.Contains(allowed, field) {
+			fields = append(fields, field)
+		}
+	}
+	if len(fields) == 0 {
+		return []string{model.ProfileFieldUsername}
+	}
+	return fields
+}
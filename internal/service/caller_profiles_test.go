@@ -0,0 +1,98 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/converters"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/jwt"
+)
+
+func TestParseCallerProfiles(t *testing.T) {
+	profiles, err := ParseCallerProfiles([]string{
+		"_INBOX_committee=name|given_name|family_name",
+		" _INBOX_meet = zoneinfo|timezone ",
+		"_INBOX_meet.insights=organization",
+	}, []string{"picture"})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"username", "name", "given_name", "family_name"}, profiles.fieldsFor("_INBOX_committee.conn1"))
+	assert.Equal(t, []string{"username", "zoneinfo", "timezone"}, profiles.fieldsFor("_INBOX_meet"))
+	assert.Equal(t, []string{"username", "organization"}, profiles.fieldsFor("_INBOX_meet.insights.conn1"),
+		"the longest prefix wins")
+	assert.Equal(t, []string{"username", "picture"}, profiles.fieldsFor("_INBOX_meetings.conn1"),
+		"a prefix only matches whole tokens")
+	assert.Equal(t, []string{"username", "picture"}, profiles.fieldsFor(""))
+
+	for _, rules := range [][]string{
+		{"name|picture"},
+		{"=name"},
+		{"_INBOX_a=email"},
+		{"_INBOX_a=name", "_INBOX_a=picture"},
+	} {
+		_, err := ParseCallerProfiles(rules, nil)
+		assert.Error(t, err, "rules %v", rules)
+	}
+	_, err = ParseCallerProfiles(nil, []string{"unknown"})
+	assert.Error(t, err)
+}
+
+func TestMessageHandlerOrchestrator_GetUserMetadata_CallerProfiles(t *testing.T) {
+	profiles, err := ParseCallerProfiles([]string{"_INBOX_meet=zoneinfo|timezone"}, nil)
+	require.NoError(t, err)
+
+	reader := &mockUserServiceReader{
+		metadataLookupFunc: func(ctx context.Context, input string) (*model.User, error) {
+			return &model.User{UserID: "auth0|123"}, nil
+		},
+		getUserFunc: func(ctx context.Context, user *model.User) (*model.User, error) {
+			return &model.User{UserID: "auth0|123", Username: "john.doe", UserMetadata: &model.UserMetadata{
+				Name:     converters.StringPtr("John Doe"),
+				Timezone: converters.StringPtr("America/New_York"),
+			}}, nil
+		},
+	}
+	orchestrator := NewMessageHandlerOrchestrator(
+		WithUserReaderForMessageHandler(reader),
+		WithCallerProfilesForMessageHandler(profiles),
+	)
+
+	read := func(t *testing.T, caller, payload string) string {
+		t.Helper()
+		msg := &callerMessenger{
+			repliedMessenger: repliedMessenger{data: []byte(payload)},
+			subject:          constants.UserMetadataReadSubject,
+			caller:           caller,
+		}
+		result, err := orchestrator.GetUserMetadata(context.Background(), msg)
+		require.NoError(t, err)
+		var response struct {
+			Success bool            `json:"success"`
+			Data    json.RawMessage `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(result, &response))
+		require.True(t, response.Success, string(result))
+		return string(response.Data)
+	}
+
+	assert.JSONEq(t, `{"username":"john.doe","timezone":"America/New_York"}`, read(t, "_INBOX_meet.conn1", "auth0|123"))
+	assert.JSONEq(t, `{"timezone":"America/New_York"}`,
+		read(t, "_INBOX_meet.conn1", `{"input":"auth0|123","fields":["name","timezone"]}`))
+	assert.JSONEq(t, `{"username":"john.doe"}`, read(t, "_INBOX_meet.conn1", `{"input":"auth0|123","fields":["name"]}`))
+	assert.JSONEq(t, `{"username":"john.doe"}`, read(t, "_INBOX.conn2", "john.doe"),
+		"callers without a profile get the username only")
+
+	token, err := jwt.GenerateTestAccessToken("auth0|123", "https://issuer/", "aud", "openid", time.Hour)
+	require.NoError(t, err)
+	assert.Contains(t, read(t, "_INBOX_meet.conn1", token), `"name":"John Doe"`, "own reads are not restricted")
+}
@@ -0,0 +1,186 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/redaction"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/tenant"
+)
+
+// Operations of a canary probe, recorded as the operation attribute
+const (
+	canaryOperationRead  = "read"
+	canaryOperationWrite = "write"
+)
+
+// canaryDisplayName is written to a canary user without a name
+const canaryDisplayName = "LFX Canary"
+
+// CanaryProbeConfig configures the canary prober
+type CanaryProbeConfig struct {
+	// User is the username or sub of the synthetic account probed
+	User string
+	// Token returns a current access token of the canary user allowed to
+	// update its metadata; nil probes reads only
+	Token func(ctx context.Context) (string, error)
+	// Timeout bounds each probe; <= 0 means 10 seconds
+	Timeout time.Duration
+}
+
+// CanaryProbeResult is the outcome of one probe. A step that wasn't run
+// leaves its error nil and its duration zero.
+type CanaryProbeResult struct {
+	Read         time.Duration
+	ReadError    error
+	Write        time.Duration
+	WriteError   error
+	WriteSkipped bool
+}
+
+// CanaryProber reads the canary user and writes its metadata back through
+// the provider on a schedule, so provider degradation shows in the probe
+// metrics before real users run into it. It must be given the uncached
+// repository: a cache hit would hide an unreachable provider.
+type CanaryProber struct {
+	reader port.UserReader
+	writer port.UserWriter
+	config CanaryProbeConfig
+
+	probes   metric.Int64Counter
+	duration metric.Float64Histogram
+}
+
+// NewCanaryProber creates a prober of config.User through reader and writer
+func NewCanaryProber(reader port.UserReader, writer port.UserWriter, config CanaryProbeConfig) *CanaryProber {
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+
+	probes, _ := meter.Int64Counter("auth_service.canary.probes",
+		metric.WithDescription("Canary user probes through the provider, by operation and outcome"))
+	duration, _ := meter.Float64Histogram("auth_service.canary.probe.duration",
+		metric.WithDescription("Time the provider took to serve a canary user probe, by operation"),
+		metric.WithUnit("ms"))
+
+	return &CanaryProber{
+		reader:   reader,
+		writer:   writer,
+		config:   config,
+		probes:   probes,
+		duration: duration,
+	}
+}
+
+// Run performs one probe; it satisfies scheduler.Job. A failed probe is an
+// error, so the scheduler logs it.
+func (p *CanaryProber) Run(ctx context.Context) error {
+	result := p.Probe(ctx)
+	return errors.Join(result.ReadError, result.WriteError)
+}
+
+// Probe reads the canary user, then writes its name back unchanged when a
+// token is configured
+func (p *CanaryProber) Probe(ctx context.Context) CanaryProbeResult {
+	ctx, cancel := context.WithTimeout(ctx, p.config.Timeout)
+	defer cancel()
+
+	var result CanaryProbeResult
+	started := time.Now()
+	user, err := p.read(ctx)
+	result.Read, result.ReadError = time.Since(started), err
+	p.record(ctx, canaryOperationRead, result.Read, err)
+
+	switch {
+	case err != nil:
+		// nothing to write back
+	case p.config.Token == nil:
+		result.WriteSkipped = true
+	default:
+		started = time.Now()
+		err = p.write(ctx, user)
+		result.Write, result.WriteError = time.Since(started), err
+		p.record(ctx, canaryOperationWrite, result.Write, err)
+	}
+
+	slog.DebugContext(ctx, "canary probe completed",
+		"canary_user", redaction.Redact(p.config.User),
+		"read_ms", result.Read.Milliseconds(),
+		"write_ms", result.Write.Milliseconds(),
+		"write_skipped", result.WriteSkipped,
+	)
+	return result
+}
+
+// read resolves the canary user the way user_metadata.read resolves its
+// input
+func (p *CanaryProber) read(ctx context.Context) (*model.User, error) {
+	lookup, err := p.reader.MetadataLookup(ctx, p.config.User)
+	if err != nil {
+		return nil, err
+	}
+	var user *model.User
+	if lookup.UserID != "" {
+		user, err = p.reader.GetUser(ctx, lookup)
+	} else {
+		user, err = p.reader.SearchUser(ctx, lookup, constants.CriteriaTypeUsername)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || user.UserID == "" {
+		return nil, errs.NewNotFound("canary user not found")
+	}
+	return user, nil
+}
+
+// write updates the name of the canary user to the one it already has, so
+// the provider's write path is exercised without changing anything
+func (p *CanaryProber) write(ctx context.Context, user *model.User) error {
+	token, err := p.config.Token(ctx)
+	if err != nil {
+		return errs.NewUnexpected("canary token unavailable", err)
+	}
+
+	name := canaryDisplayName
+	if user.UserMetadata != nil && user.UserMetadata.Name != nil && strings.TrimSpace(*user.UserMetadata.Name) != "" {
+		name = *user.UserMetadata.Name
+	}
+	_, err = p.writer.UpdateUser(ctx, &model.User{
+		Token:        token,
+		UserID:       user.UserID,
+		Sub:          user.Sub,
+		Username:     user.Username,
+		UserMetadata: &model.UserMetadata{Name: &name},
+	})
+	return err
+}
+
+func (p *CanaryProber) record(ctx context.Context, operation string, took time.Duration, err error) {
+	outcome := "success"
+	if err != nil {
+		outcome = "failure"
+		slog.WarnContext(ctx, "canary probe failed",
+			"operation", operation,
+			"error", err,
+			"canary_user", redaction.Redact(p.config.User),
+		)
+	}
+	p.probes.Add(ctx, 1, tenant.Attributes(ctx,
+		attribute.String("operation", operation), attribute.String("outcome", outcome)))
+	p.duration.Record(ctx, float64(took.Microseconds())/1000,
+		tenant.Attributes(ctx, attribute.String("operation", operation)))
+}
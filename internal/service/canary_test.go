@@ -0,0 +1,66 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+)
+
+func TestCanaryProber(t *testing.T) {
+	ctx := context.Background()
+
+	name := "Canary Bird"
+	reader := &mockUserServiceReader{
+		metadataLookupFunc: func(ctx context.Context, input string) (*model.User, error) {
+			return &model.User{Username: input}, nil
+		},
+		searchUserFunc: func(ctx context.Context, user *model.User, criteria string) (*model.User, error) {
+			return &model.User{UserID: "auth0|canary", Username: user.Username, UserMetadata: &model.UserMetadata{Name: &name}}, nil
+		},
+	}
+	var written *model.User
+	writer := &mockUserServiceWriter{updateUserFunc: func(ctx context.Context, user *model.User) (*model.User, error) {
+		written = user
+		return user, nil
+	}}
+	token := func(context.Context) (string, error) { return "canary-token", nil }
+
+	t.Run("reads the canary and writes its name back", func(t *testing.T) {
+		result := NewCanaryProber(reader, writer, CanaryProbeConfig{User: "canary", Token: token}).Probe(ctx)
+
+		require.NoError(t, result.ReadError)
+		require.NoError(t, result.WriteError)
+		require.NotNil(t, written)
+		assert.Equal(t, "canary-token", written.Token)
+		assert.Equal(t, "auth0|canary", written.UserID)
+		assert.Equal(t, name, *written.UserMetadata.Name)
+	})
+
+	t.Run("probes reads only without a token", func(t *testing.T) {
+		written = nil
+		result := NewCanaryProber(reader, writer, CanaryProbeConfig{User: "canary"}).Probe(ctx)
+
+		assert.NoError(t, result.ReadError)
+		assert.True(t, result.WriteSkipped)
+		assert.Nil(t, written)
+	})
+
+	t.Run("reports a failing provider", func(t *testing.T) {
+		written = nil
+		failing := &mockUserServiceReader{metadataLookupFunc: func(ctx context.Context, input string) (*model.User, error) {
+			return nil, errs.NewServiceUnavailable("provider unreachable")
+		}}
+		prober := NewCanaryProber(failing, writer, CanaryProbeConfig{User: "canary", Token: token})
+
+		assert.Error(t, prober.Run(ctx))
+		assert.Nil(t, written, "nothing is written when the read fails")
+	})
+}
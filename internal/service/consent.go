@@ -0,0 +1,197 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/redaction"
+)
+
+// consentRecordRequest is the input of user.consent.record
+type consentRecordRequest struct {
+	AuthToken string `json:"auth_token"`
+	Policy    string `json:"policy"`
+	Version   string `json:"version"`
+}
+
+// consentStatusRequest is the input of user.consent.status. Exactly one of
+// AuthToken and Sub identifies the user; Policies narrows the reply to some
+// of the configured policies.
+type consentStatusRequest struct {
+	AuthToken string   `json:"auth_token,omitempty"`
+	Sub       string   `json:"sub,omitempty"`
+	Policies  []string `json:"policies,omitempty"`
+}
+
+// WithConsentStoreForMessageHandler sets where the message handler
+// orchestrator records policy acceptances. policies maps each policy users
+// accept to its current version.
+func WithConsentStoreForMessageHandler(store port.ConsentStore, policies map[string]string) MessageHandlerOrchestratorOption {
+	return func(m *messageHandlerOrchestrator) {
+		m.consents = store
+		m.consentPolicies = policies
+	}
+}
+
+// RecordConsent records that the user the token belongs to accepted the
+// current version of a policy. Accepting a version again is harmless: the
+// reply carries the original acceptance, which is never changed.
+func (m *messageHandlerOrchestrator) RecordConsent(ctx context.Context, msg port.TransportMessenger) ([]byte, error) {
+	if m.consents == nil || m.userReader == nil {
+		return m.errorResponse("consent_unavailable"), nil
+	}
+
+	var request consentRecordRequest
+	if err := json.Unmarshal(msg.Data(), &request); err != nil {
+		return m.errorResponse("failed_to_unmarshal_request"), nil
+	}
+
+	claims, err := m.verifyAccessToken(ctx, request.AuthToken)
+	if err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+	if claims.Subject == "" {
+		return m.errorResponse("auth_token has no subject"), nil
+	}
+
+	policy, version := strings.TrimSpace(request.Policy), strings.TrimSpace(request.Version)
+	current, known := m.consentPolicies[policy]
+	switch {
+	case policy == "" || version == "":
+		return m.errorResponse("policy and version are required"), nil
+	case !known:
+		return m.errorResponse("unknown policy: " + policy), nil
+	case version != current:
+		// an outdated version can't be accepted any more, nor a future one yet
+		return m.errorResponse("version " + version + " is not the current version of " + policy), nil
+	}
+
+	acceptance, err := m.consents.RecordAcceptance(ctx, &model.PolicyAcceptance{
+		UserID:     claims.Subject,
+		Policy:     policy,
+		Version:    version,
+		AcceptedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to record policy acceptance", "error", err)
+		return m.errorResponse(err.Error()), nil
+	}
+
+	slog.InfoContext(ctx, "policy acceptance recorded",
+		"user_id", redaction.Redact(acceptance.UserID),
+		"policy", acceptance.Policy,
+		"version", acceptance.Version,
+		"accepted_at", acceptance.AcceptedAt,
+	)
+
+	responseJSON, err := json.Marshal(UserDataResponse{Success: true, Data: acceptance})
+	if err != nil {
+		return m.errorResponse("failed to marshal response"), nil
+	}
+	return responseJSON, nil
+}
+
+// ConsentStatus reports, for each configured policy or the ones asked for,
+// whether the user accepted its current version, so services can gate
+// access on it
+func (m *messageHandlerOrchestrator) ConsentStatus(ctx context.Context, msg port.TransportMessenger) ([]byte, error) {
+	if m.consents == nil || m.userReader == nil {
+		return m.errorResponse("consent_unavailable"), nil
+	}
+
+	var request consentStatusRequest
+	if err := json.Unmarshal(msg.Data(), &request); err != nil {
+		return m.errorResponse("failed_to_unmarshal_request"), nil
+	}
+
+	userID, err := m.consentUser(ctx, request)
+	if err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+
+	policies := trimNonEmpty(request.Policies)
+	if len(policies) == 0 {
+		policies = slices.Sorted(maps.Keys(m.consentPolicies))
+	}
+	for _, policy := range policies {
+		if _, known := m.consentPolicies[policy]; !known {
+			return m.errorResponse("unknown policy: " + policy), nil
+		}
+	}
+
+	acceptances, err := m.consents.ListAcceptances(ctx, userID)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to list policy acceptances", "error", err)
+		return m.errorResponse(err.Error()), nil
+	}
+
+	responseJSON, err := json.Marshal(UserDataResponse{Success: true, Data: consentStatus(userID, policies, m.consentPolicies, acceptances)})
+	if err != nil {
+		return m.errorResponse("failed to marshal response"), nil
+	}
+	return responseJSON, nil
+}
+
+// consentUser returns the sub a status request is about
+func (m *messageHandlerOrchestrator) consentUser(ctx context.Context, request consentStatusRequest) (string, error) {
+	sub := strings.TrimSpace(request.Sub)
+	if (request.AuthToken == "") == (sub == "") {
+		return "", errs.NewValidation("exactly one of auth_token or sub is required")
+	}
+	if sub != "" {
+		return sub, nil
+	}
+	claims, err := m.verifyAccessToken(ctx, request.AuthToken)
+	if err != nil {
+		return "", err
+	}
+	if claims.Subject == "" {
+		return "", errs.NewUnauthorized("auth_token has no subject")
+	}
+	return claims.Subject, nil
+}
+
+// consentStatus matches the acceptances of a user against the current
+// versions of policies
+func consentStatus(userID string, policies []string, current map[string]string, acceptances []*model.PolicyAcceptance) model.ConsentStatus {
+	status := model.ConsentStatus{UserID: userID, Policies: make([]model.PolicyConsent, 0, len(policies)), AllCurrent: true}
+	for _, policy := range policies {
+		consent := model.PolicyConsent{Policy: policy, CurrentVersion: current[policy]}
+		var chosen *model.PolicyAcceptance
+		for _, acceptance := range acceptances {
+			if acceptance.Policy == policy && (chosen == nil || preferAcceptance(acceptance, chosen, consent.CurrentVersion)) {
+				chosen = acceptance
+			}
+		}
+		if chosen != nil {
+			acceptedAt := chosen.AcceptedAt
+			consent.AcceptedVersion = chosen.Version
+			consent.AcceptedAt = &acceptedAt
+			consent.Current = chosen.Version == consent.CurrentVersion
+		}
+		status.AllCurrent = status.AllCurrent && consent.Current
+		status.Policies = append(status.Policies, consent)
+	}
+	return status
+}
+
+// preferAcceptance reports whether a tells more than b about the consent to
+// version current: an acceptance of current beats the others, which are
+// ranked latest first
+func preferAcceptance(a, b *model.PolicyAcceptance, current string) bool {
+	if (a.Version == current) != (b.Version == current) {
+		return a.Version == current
+	}
+	return a.AcceptedAt.After(b.AcceptedAt)
+}
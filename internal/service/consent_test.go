@@ -0,0 +1,153 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/jwt"
+)
+
+// memoryConsentStore keeps acceptances write-once, like the KV store
+type memoryConsentStore struct {
+	acceptances []*model.PolicyAcceptance
+}
+
+func (s *memoryConsentStore) RecordAcceptance(_ context.Context, acceptance *model.PolicyAcceptance) (*model.PolicyAcceptance, error) {
+	for _, stored := range s.acceptances {
+		if stored.UserID == acceptance.UserID && stored.Policy == acceptance.Policy && stored.Version == acceptance.Version {
+			return stored, nil
+		}
+	}
+	s.acceptances = append(s.acceptances, acceptance)
+	return acceptance, nil
+}
+
+func (s *memoryConsentStore) ListAcceptances(_ context.Context, userID string) ([]*model.PolicyAcceptance, error) {
+	var acceptances []*model.PolicyAcceptance
+	for _, acceptance := range s.acceptances {
+		if acceptance.UserID == userID {
+			acceptances = append(acceptances, acceptance)
+		}
+	}
+	return acceptances, nil
+}
+
+func TestMessageHandlerOrchestrator_Consent(t *testing.T) {
+	ctx := context.Background()
+	token, err := jwt.GenerateSimpleTestAccessToken("auth0|alice", time.Hour)
+	require.NoError(t, err)
+
+	earlier := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	store := &memoryConsentStore{acceptances: []*model.PolicyAcceptance{
+		{UserID: "auth0|alice", Policy: "tos", Version: "2024-01", AcceptedAt: earlier},
+		{UserID: "auth0|alice", Policy: "privacy", Version: "3", AcceptedAt: earlier},
+		{UserID: "auth0|bob", Policy: "tos", Version: "2025-06", AcceptedAt: earlier},
+	}}
+	reader := &mockUserServiceReader{
+		metadataLookupFunc: func(ctx context.Context, input string) (*model.User, error) {
+			return &model.User{Token: input}, nil
+		},
+	}
+	orchestrator := NewMessageHandlerOrchestrator(
+		WithUserReaderForMessageHandler(reader),
+		WithConsentStoreForMessageHandler(store, map[string]string{"tos": "2025-06", "privacy": "3"}),
+	)
+
+	call := func(t *testing.T, handler func(context.Context, *mockTransportMessenger) ([]byte, error), request map[string]any) UserDataResponse {
+		t.Helper()
+		payload, _ := json.Marshal(request)
+		result, err := handler(ctx, &mockTransportMessenger{data: payload})
+		require.NoError(t, err)
+		var response UserDataResponse
+		require.NoError(t, json.Unmarshal(result, &response))
+		return response
+	}
+	record := func(ctx context.Context, msg *mockTransportMessenger) ([]byte, error) {
+		return orchestrator.RecordConsent(ctx, msg)
+	}
+	status := func(ctx context.Context, msg *mockTransportMessenger) ([]byte, error) {
+		return orchestrator.ConsentStatus(ctx, msg)
+	}
+	statusOf := func(t *testing.T, request map[string]any) model.ConsentStatus {
+		t.Helper()
+		response := call(t, status, request)
+		require.True(t, response.Success, response.Error)
+		var result model.ConsentStatus
+		data, _ := json.Marshal(response.Data)
+		require.NoError(t, json.Unmarshal(data, &result))
+		return result
+	}
+
+	t.Run("outdated acceptance is not current", func(t *testing.T) {
+		result := statusOf(t, map[string]any{"auth_token": token})
+		assert.Equal(t, "auth0|alice", result.UserID)
+		assert.False(t, result.AllCurrent)
+		require.Len(t, result.Policies, 2)
+		assert.Equal(t, "privacy", result.Policies[0].Policy)
+		assert.True(t, result.Policies[0].Current)
+		assert.Equal(t, "tos", result.Policies[1].Policy)
+		assert.Equal(t, "2024-01", result.Policies[1].AcceptedVersion)
+		assert.False(t, result.Policies[1].Current)
+	})
+
+	t.Run("only the current version can be accepted", func(t *testing.T) {
+		response := call(t, record, map[string]any{"auth_token": token, "policy": "tos", "version": "2024-01"})
+		assert.False(t, response.Success)
+		assert.Contains(t, response.Error, "not the current version")
+
+		response = call(t, record, map[string]any{"auth_token": token, "policy": "cookies", "version": "1"})
+		assert.Equal(t, "unknown policy: cookies", response.Error)
+	})
+
+	t.Run("acceptance is recorded once", func(t *testing.T) {
+		first := call(t, record, map[string]any{"auth_token": token, "policy": "tos", "version": "2025-06"})
+		require.True(t, first.Success, first.Error)
+		again := call(t, record, map[string]any{"auth_token": token, "policy": "tos", "version": "2025-06"})
+		require.True(t, again.Success, again.Error)
+		assert.Equal(t, first.Data, again.Data)
+
+		result := statusOf(t, map[string]any{"sub": "auth0|alice", "policies": []string{"tos"}})
+		assert.True(t, result.AllCurrent)
+		require.Len(t, result.Policies, 1)
+		assert.Equal(t, "2025-06", result.Policies[0].AcceptedVersion)
+	})
+
+	t.Run("status needs exactly one user", func(t *testing.T) {
+		response := call(t, status, map[string]any{"auth_token": token, "sub": "auth0|bob"})
+		assert.Equal(t, "exactly one of auth_token or sub is required", response.Error)
+
+		response = call(t, status, map[string]any{"sub": "auth0|bob", "policies": []string{"cookies"}})
+		assert.Equal(t, "unknown policy: cookies", response.Error)
+	})
+
+	t.Run("unavailable without a store", func(t *testing.T) {
+		response := call(t, func(ctx context.Context, msg *mockTransportMessenger) ([]byte, error) {
+			return NewMessageHandlerOrchestrator(WithUserReaderForMessageHandler(reader)).ConsentStatus(ctx, msg)
+		}, map[string]any{"sub": "auth0|bob"})
+		assert.Equal(t, "consent_unavailable", response.Error)
+	})
+}
+
+func TestConsentStatus_PrefersCurrentVersion(t *testing.T) {
+	older := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	newer := older.AddDate(0, 1, 0)
+	acceptances := []*model.PolicyAcceptance{
+		{Policy: "tos", Version: "2", AcceptedAt: older},
+		// accepted later, but no longer the current version
+		{Policy: "tos", Version: "3-draft", AcceptedAt: newer},
+	}
+	status := consentStatus("auth0|a", []string{"tos"}, map[string]string{"tos": "2"}, acceptances)
+	require.Len(t, status.Policies, 1)
+	assert.Equal(t, "2", status.Policies[0].AcceptedVersion)
+	assert.Equal(t, older, *status.Policies[0].AcceptedAt)
+	assert.True(t, status.AllCurrent)
+}
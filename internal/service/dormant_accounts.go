@@ -0,0 +1,128 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/redaction"
+)
+
+// DormantAccountsConfig configures the dormant account scan
+type DormantAccountsConfig struct {
+	// Threshold is how long without a login before an account counts as dormant
+	Threshold time.Duration
+	// MaxUsers caps the users reported (and tagged) per run
+	MaxUsers int
+	// TagUsers marks reported users dormant in the provider's app_metadata
+	TagUsers bool
+}
+
+// DormantAccount is a single entry of the dormant accounts report
+type DormantAccount struct {
+	UserID      string     `json:"user_id"`
+	LastLogin   *time.Time `json:"last_login,omitempty"`
+	LoginsCount *int       `json:"logins_count,omitempty"`
+}
+
+// DormantAccountsReport is published on DormantAccountsReportSubject after each scan
+type DormantAccountsReport struct {
+	GeneratedAt   time.Time        `json:"generated_at"`
+	ThresholdDays int              `json:"threshold_days"`
+	Cutoff        time.Time        `json:"cutoff"`
+	Count         int              `json:"count"`
+	Truncated     bool             `json:"truncated"`
+	Tagged        int              `json:"tagged"`
+	Users         []DormantAccount `json:"users"`
+}
+
+// DormantAccountsJob scans the user repository for inactive accounts
+type DormantAccountsJob struct {
+	scanner        port.DormantUserScanner
+	eventPublisher port.EventPublisher
+	config         DormantAccountsConfig
+	now            func() time.Time
+}
+
+// Run performs one scan, optionally tags the dormant users, and publishes the report
+func (j *DormantAccountsJob) Run(ctx context.Context) error {
+	now := j.now().UTC()
+	cutoff := now.Add(-j.config.Threshold)
+
+	// one more than reported tells a full report from a truncated one
+	limit := j.config.MaxUsers
+	if limit > 0 {
+		limit++
+	}
+	users, err := j.scanner.ListDormantUsers(ctx, cutoff, limit)
+	if err != nil {
+		return err
+	}
+	truncated := j.config.MaxUsers > 0 && len(users) > j.config.MaxUsers
+	if truncated {
+		users = users[:j.config.MaxUsers]
+	}
+
+	report := DormantAccountsReport{
+		GeneratedAt:   now,
+		ThresholdDays: int(j.config.Threshold / (24 * time.Hour)),
+		Cutoff:        cutoff,
+		Count:         len(users),
+		Truncated:     truncated,
+		Users:         make([]DormantAccount, 0, len(users)),
+	}
+
+	for _, user := range users {
+		account := DormantAccount{UserID: user.UserID}
+		if user.Activity != nil {
+			account.LastLogin = user.Activity.LastLogin
+			account.LoginsCount = user.Activity.LoginsCount
+		}
+		report.Users = append(report.Users, account)
+
+		if !j.config.TagUsers {
+			continue
+		}
+		if err := j.scanner.TagDormantUser(ctx, user.UserID, now); err != nil {
+			slog.WarnContext(ctx, "failed to tag dormant user",
+				"error", err,
+				"user_id", redaction.Redact(user.UserID),
+			)
+			continue
+		}
+		report.Tagged++
+	}
+
+	slog.InfoContext(ctx, "dormant account scan completed",
+		"count", report.Count,
+		"tagged", report.Tagged,
+		"truncated", report.Truncated,
+		"threshold_days", report.ThresholdDays,
+	)
+
+	if j.eventPublisher == nil {
+		return nil
+	}
+	reportJSON, err := json.Marshal(report)
+	if err != nil {
+		return errs.NewUnexpected("failed to marshal dormant accounts report", err)
+	}
+	return j.eventPublisher.Publish(ctx, constants.DormantAccountsReportSubject, reportJSON)
+}
+
+// NewDormantAccountsJob creates the dormant account scan job
+func NewDormantAccountsJob(scanner port.DormantUserScanner, eventPublisher port.EventPublisher, config DormantAccountsConfig) *DormantAccountsJob {
+	return &DormantAccountsJob{
+		scanner:        scanner,
+		eventPublisher: eventPublisher,
+		config:         config,
+		now:            time.Now,
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+)
+
+// mockDormantUserScanner is a mock implementation of port.DormantUserScanner for testing
+type mockDormantUserScanner struct {
+	users      []*model.User
+	listErr    error
+	tagErr     map[string]error
+	cutoff     time.Time
+	limit      int
+	taggedUser []string
+}
+
+func (m *mockDormantUserScanner) ListDormantUsers(ctx context.Context, cutoff time.Time, limit int) ([]*model.User, error) {
+	m.cutoff, m.limit = cutoff, limit
+	if limit > 0 && len(m.users) > limit {
+		return m.users[:limit], m.listErr
+	}
+	return m.users, m.listErr
+}
+
+func (m *mockDormantUserScanner) TagDormantUser(ctx context.Context, userID string, since time.Time) error {
+	if err := m.tagErr[userID]; err != nil {
+		return err
+	}
+	m.taggedUser = append(m.taggedUser, userID)
+	return nil
+}
+
+func TestDormantAccountsJob_Run(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	lastLogin := now.AddDate(-2, 0, 0)
+	users := []*model.User{
+		{UserID: "auth0|a", Activity: &model.UserActivity{LastLogin: &lastLogin}},
+		{UserID: "auth0|b"},
+	}
+
+	tests := []struct {
+		name       string
+		config     DormantAccountsConfig
+		scanner    *mockDormantUserScanner
+		wantErr    bool
+		wantTagged []string
+		wantReport func(t *testing.T, report DormantAccountsReport)
+	}{
+		{
+			name:    "report only",
+			config:  DormantAccountsConfig{Threshold: 365 * 24 * time.Hour, MaxUsers: 10},
+			scanner: &mockDormantUserScanner{users: users},
+			wantReport: func(t *testing.T, report DormantAccountsReport) {
+				assert.Equal(t, 2, report.Count)
+				assert.Equal(t, 365, report.ThresholdDays)
+				assert.Equal(t, now.AddDate(0, 0, -365), report.Cutoff)
+				assert.False(t, report.Truncated)
+				assert.Equal(t, 0, report.Tagged)
+				require.Len(t, report.Users, 2)
+				assert.Equal(t, lastLogin, *report.Users[0].LastLogin)
+				assert.Nil(t, report.Users[1].LastLogin)
+			},
+		},
+		{
+			name:       "tagging with a partial failure",
+			config:     DormantAccountsConfig{Threshold: 24 * time.Hour, MaxUsers: 2, TagUsers: true},
+			scanner:    &mockDormantUserScanner{users: users, tagErr: map[string]error{"auth0|b": errors.New("rate limited")}},
+			wantTagged: []string{"auth0|a"},
+			wantReport: func(t *testing.T, report DormantAccountsReport) {
+				assert.Equal(t, 1, report.Tagged)
+				assert.False(t, report.Truncated, "exactly MaxUsers dormant users is a full report")
+			},
+		},
+		{
+			name:    "more dormant users than reported",
+			config:  DormantAccountsConfig{Threshold: 24 * time.Hour, MaxUsers: 1},
+			scanner: &mockDormantUserScanner{users: users},
+			wantReport: func(t *testing.T, report DormantAccountsReport) {
+				assert.True(t, report.Truncated)
+				assert.Equal(t, 1, report.Count)
+				require.Len(t, report.Users, 1)
+				assert.Equal(t, "auth0|a", report.Users[0].UserID)
+			},
+		},
+		{
+			name:    "scan failure",
+			config:  DormantAccountsConfig{Threshold: 24 * time.Hour},
+			scanner: &mockDormantUserScanner{listErr: errors.New("auth0 down")},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publisher := &mockEventPublisher{}
+			job := NewDormantAccountsJob(tt.scanner, publisher, tt.config)
+			job.now = func() time.Time { return now }
+
+			err := job.Run(context.Background())
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Empty(t, publisher.calls)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.config.MaxUsers+1, tt.scanner.limit, "one more than reported is asked for")
+			assert.Equal(t, tt.wantTagged, tt.scanner.taggedUser)
+
+			require.Len(t, publisher.calls, 1)
+			assert.Equal(t, constants.DormantAccountsReportSubject, publisher.calls[0].Subject)
+			var report DormantAccountsReport
+			require.NoError(t, json.Unmarshal(publisher.calls[0].Data, &report))
+			tt.wantReport(t, report)
+		})
+	}
+}
@@ -0,0 +1,357 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/normalize"
+)
+
+// Reasons two accounts are reported as likely duplicates
+const (
+	// DuplicateReasonVerifiedEmail is a verified email address both accounts have
+	DuplicateReasonVerifiedEmail = "verified_email"
+	// DuplicateReasonSimilarName is a near-identical name with matching metadata
+	DuplicateReasonSimilarName = "similar_name"
+)
+
+// duplicateMetadataFields are the metadata fields similar names are matched on
+var duplicateMetadataFields = []struct {
+	name string
+	get  func(m *model.UserMetadata) *string
+}{
+	{"organization", func(m *model.UserMetadata) *string { return m.Organization }},
+	{"job_title", func(m *model.UserMetadata) *string { return m.JobTitle }},
+	{"country", func(m *model.UserMetadata) *string { return m.Country }},
+	{"city", func(m *model.UserMetadata) *string { return m.City }},
+}
+
+// DuplicateAccountsConfig configures the duplicate account scan
+type DuplicateAccountsConfig struct {
+	// NameSimilarity is the similarity, in (0, 1], from which two names count
+	// as near-identical; 0 turns name matching off
+	NameSimilarity float64
+	// MinMatchingFields is how many of the organization, job title, country
+	// and city accounts with similar names must also share
+	MinMatchingFields int
+	// MaxCandidates caps the candidates reported per run
+	MaxCandidates int
+	// AllowList are known false positives: a "sub=sub" pair is never reported,
+	// a lone sub is never reported with any account
+	AllowList []string
+}
+
+// DuplicateAccountCandidate is a pair of accounts likely to be one person
+type DuplicateAccountCandidate struct {
+	UserIDs        []string `json:"user_ids"`
+	Usernames      []string `json:"usernames,omitempty"`
+	Reasons        []string `json:"reasons"`
+	NameSimilarity float64  `json:"name_similarity,omitempty"`
+	MatchingFields []string `json:"matching_fields,omitempty"`
+}
+
+// DuplicateAccountsReport is published on DuplicateAccountsReportSubject after each scan
+type DuplicateAccountsReport struct {
+	GeneratedAt time.Time                   `json:"generated_at"`
+	Scanned     int                         `json:"scanned"`
+	Count       int                         `json:"count"`
+	Truncated   bool                        `json:"truncated"`
+	Candidates  []DuplicateAccountCandidate `json:"candidates"`
+}
+
+// DuplicateAccountsJob scans every user for likely duplicate accounts, to
+// feed the merge workflow
+type DuplicateAccountsJob struct {
+	exporter       port.UserExporter
+	eventPublisher port.EventPublisher
+	config         DuplicateAccountsConfig
+	allowedSubs    map[string]bool
+	allowedPairs   map[[2]string]bool
+	now            func() time.Time
+}
+
+// Run performs one scan and publishes the candidates report
+func (j *DuplicateAccountsJob) Run(ctx context.Context) error {
+	users, err := j.exporter.ExportUsers(ctx)
+	if err != nil {
+		return err
+	}
+
+	candidates := j.findDuplicates(users)
+	report := DuplicateAccountsReport{
+		GeneratedAt: j.now().UTC(),
+		Scanned:     len(users),
+		Count:       len(candidates),
+		Candidates:  candidates,
+	}
+	if j.config.MaxCandidates > 0 && len(candidates) > j.config.MaxCandidates {
+		report.Candidates = candidates[:j.config.MaxCandidates]
+		report.Truncated = true
+	}
+
+	slog.InfoContext(ctx, "duplicate account scan completed",
+		"scanned", report.Scanned,
+		"count", report.Count,
+		"truncated", report.Truncated,
+	)
+
+	if j.eventPublisher == nil {
+		return nil
+	}
+	reportJSON, err := json.Marshal(report)
+	if err != nil {
+		return errs.NewUnexpected("failed to marshal duplicate accounts report", err)
+	}
+	return j.eventPublisher.Publish(ctx, constants.DuplicateAccountsReportSubject, reportJSON)
+}
+
+// findDuplicates returns the candidate pairs of users, sorted by user ids
+func (j *DuplicateAccountsJob) findDuplicates(users []*model.User) []DuplicateAccountCandidate {
+	users = slices.DeleteFunc(slices.Clone(users), func(u *model.User) bool {
+		return u == nil || u.UserID == "" || j.allowedSubs[u.UserID]
+	})
+
+	found := make(map[[2]string]*DuplicateAccountCandidate)
+	candidate := func(a, b *model.User) *DuplicateAccountCandidate {
+		if a.UserID == b.UserID {
+			return nil
+		}
+		if a.UserID > b.UserID {
+			a, b = b, a
+		}
+		key := [2]string{a.UserID, b.UserID}
+		if j.allowedPairs[key] {
+			return nil
+		}
+		if c, ok := found[key]; ok {
+			return c
+		}
+		c := &DuplicateAccountCandidate{UserIDs: key[:]}
+		if a.Username != "" || b.Username != "" {
+			c.Usernames = []string{a.Username, b.Username}
+		}
+		found[key] = c
+		return c
+	}
+
+	// the same verified address on two accounts
+	byEmail := make(map[string][]*model.User)
+	for _, user := range users {
+		for _, email := range verifiedEmails(user) {
+			byEmail[email] = append(byEmail[email], user)
+		}
+	}
+	for _, group := range byEmail {
+		for i := range group {
+			for k := i + 1; k < len(group); k++ {
+				if c := candidate(group[i], group[k]); c != nil && !slices.Contains(c.Reasons, DuplicateReasonVerifiedEmail) {
+					c.Reasons = append(c.Reasons, DuplicateReasonVerifiedEmail)
+				}
+			}
+		}
+	}
+
+	if j.config.NameSimilarity > 0 {
+		j.matchSimilarNames(users, candidate)
+	}
+
+	candidates := make([]DuplicateAccountCandidate, 0, len(found))
+	for _, c := range found {
+		candidates = append(candidates, *c)
+	}
+	slices.SortFunc(candidates, func(a, b DuplicateAccountCandidate) int {
+		return cmp.Or(strings.Compare(a.UserIDs[0], b.UserIDs[0]), strings.Compare(a.UserIDs[1], b.UserIDs[1]))
+	})
+	return candidates
+}
+
+// matchSimilarNames compares the names of users sharing a block: the first
+// word of the name with the initial of the last, and the other way round,
+// so a typo in one of them still puts the pair in a shared block
+func (j *DuplicateAccountsJob) matchSimilarNames(users []*model.User, candidate func(a, b *model.User) *DuplicateAccountCandidate) {
+	names := make(map[*model.User]string, len(users))
+	blocks := make(map[string][]*model.User)
+	for _, user := range users {
+		name := duplicateNameKey(fullName(user))
+		words := strings.Fields(name)
+		if len(words) < 2 {
+			// a single word is too weak a signal
+			continue
+		}
+		names[user] = name
+		first, last := words[0], words[len(words)-1]
+		initial := func(word string) string { _, size := utf8.DecodeRuneInString(word); return word[:size] }
+		for _, key := range []string{"f:" + first + " " + initial(last), "l:" + last + " " + initial(first)} {
+			blocks[key] = append(blocks[key], user)
+		}
+	}
+
+	for _, block := range blocks {
+		for i := range block {
+			for k := i + 1; k < len(block); k++ {
+				a, b := block[i], block[k]
+				fields := matchingMetadataFields(a.UserMetadata, b.UserMetadata)
+				if len(fields) < j.config.MinMatchingFields {
+					continue
+				}
+				similarity := nameSimilarity(names[a], names[b])
+				if similarity < j.config.NameSimilarity {
+					continue
+				}
+				c := candidate(a, b)
+				if c == nil || slices.Contains(c.Reasons, DuplicateReasonSimilarName) {
+					continue
+				}
+				c.Reasons = append(c.Reasons, DuplicateReasonSimilarName)
+				c.NameSimilarity = similarity
+				c.MatchingFields = fields
+			}
+		}
+	}
+}
+
+// verifiedEmails returns the match keys of the verified addresses of user
+func verifiedEmails(user *model.User) []string {
+	var emails []string
+	add := func(email string) {
+		if key := normalize.EmailKey(email); key != "" && !slices.Contains(emails, key) {
+			emails = append(emails, key)
+		}
+	}
+	if user.EmailVerified {
+		add(user.PrimaryEmail)
+	}
+	for _, list := range [][]model.Email{user.AlternateEmails, user.SecondaryEmails} {
+		for _, email := range list {
+			if email.Verified {
+				add(email.Email)
+			}
+		}
+	}
+	return emails
+}
+
+// fullName is the display name of user, or its given and family names
+func fullName(user *model.User) string {
+	if name := user.SearchName(); name != "" {
+		return name
+	}
+	if user.UserMetadata == nil {
+		return ""
+	}
+	var parts []string
+	for _, part := range []*string{user.UserMetadata.GivenName, user.UserMetadata.FamilyName} {
+		if part != nil {
+			parts = append(parts, *part)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// duplicateNameKey folds a name for comparison: lowercased, without accents
+// or punctuation, words separated by single spaces
+func duplicateNameKey(name string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(normalize.Username(name)) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		default:
+			b.WriteRune(' ')
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// matchingMetadataFields returns the duplicate metadata fields a and b have
+// the same non-empty value for
+func matchingMetadataFields(a, b *model.UserMetadata) []string {
+	if a == nil || b == nil {
+		return nil
+	}
+	var fields []string
+	for _, f := range duplicateMetadataFields {
+		va, vb := f.get(a), f.get(b)
+		if va == nil || vb == nil {
+			continue
+		}
+		if x := normalize.Username(*va); x != "" && x == normalize.Username(*vb) {
+			fields = append(fields, f.name)
+		}
+	}
+	return fields
+}
+
+// nameSimilarity is one minus the edit distance of a and b over the length
+// of the longer one, from 0 for unrelated names to 1 for equal ones
+func nameSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 0
+	}
+	return 1 - float64(editDistance(ra, rb))/float64(longest)
+}
+
+// editDistance is the Levenshtein distance of a and b
+func editDistance(a, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for k := range previous {
+		previous[k] = k
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for k := 1; k <= len(b); k++ {
+			cost := 1
+			if a[i-1] == b[k-1] {
+				cost = 0
+			}
+			current[k] = min(previous[k]+1, current[k-1]+1, previous[k-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// NewDuplicateAccountsJob creates the duplicate account scan job
+func NewDuplicateAccountsJob(exporter port.UserExporter, eventPublisher port.EventPublisher, config DuplicateAccountsConfig) *DuplicateAccountsJob {
+	job := &DuplicateAccountsJob{
+		exporter:       exporter,
+		eventPublisher: eventPublisher,
+		config:         config,
+		allowedSubs:    make(map[string]bool),
+		allowedPairs:   make(map[[2]string]bool),
+		now:            time.Now,
+	}
+	for _, entry := range config.AllowList {
+		a, b, pair := strings.Cut(entry, "=")
+		a, b = strings.TrimSpace(a), strings.TrimSpace(b)
+		if !pair {
+			job.allowedSubs[a] = true
+			continue
+		}
+		if a > b {
+			a, b = b, a
+		}
+		job.allowedPairs[[2]string{a, b}] = true
+	}
+	return job
+}
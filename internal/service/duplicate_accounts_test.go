@@ -0,0 +1,136 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+)
+
+func duplicateTestUser(id, email string, verified bool, name, organization, country string) *model.User {
+	user := &model.User{UserID: id, Username: id, PrimaryEmail: email, EmailVerified: verified}
+	if name != "" {
+		user.UserMetadata = &model.UserMetadata{Name: &name}
+		if organization != "" {
+			user.UserMetadata.Organization = &organization
+		}
+		if country != "" {
+			user.UserMetadata.Country = &country
+		}
+	}
+	return user
+}
+
+func TestDuplicateAccountsJob_Run(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	users := []*model.User{
+		duplicateTestUser("auth0|a", "Jane@Example.com", true, "Jane Doe", "Example", "US"),
+		duplicateTestUser("github|b", "jane@example.com", true, "Jané Doe", "example", "FR"),
+		// the shared address isn't verified on this one
+		duplicateTestUser("google|c", "jane@example.com", false, "", "", ""),
+		duplicateTestUser("auth0|d", "jdoe@other.org", true, "Jane Doee", "Example", ""),
+		duplicateTestUser("auth0|e", "john@example.org", true, "John Smith", "Example", ""),
+	}
+	users[4].AlternateEmails = []model.Email{{Email: "jdoe@other.org", Verified: true}}
+
+	tests := []struct {
+		name       string
+		config     DuplicateAccountsConfig
+		exporter   *mockUserExporter
+		wantErr    bool
+		wantReport func(t *testing.T, report DuplicateAccountsReport)
+	}{
+		{
+			name:     "verified emails and similar names",
+			config:   DuplicateAccountsConfig{NameSimilarity: 0.85, MinMatchingFields: 1, MaxCandidates: 10},
+			exporter: &mockUserExporter{users: users},
+			wantReport: func(t *testing.T, report DuplicateAccountsReport) {
+				assert.Equal(t, 5, report.Scanned)
+				assert.Equal(t, now, report.GeneratedAt)
+				require.Len(t, report.Candidates, 4)
+
+				assert.Equal(t, []string{"auth0|a", "auth0|d"}, report.Candidates[0].UserIDs)
+				assert.Equal(t, []string{DuplicateReasonSimilarName}, report.Candidates[0].Reasons)
+				assert.Equal(t, []string{"organization"}, report.Candidates[0].MatchingFields)
+
+				assert.Equal(t, []string{"auth0|a", "github|b"}, report.Candidates[1].UserIDs)
+				assert.Equal(t, []string{DuplicateReasonVerifiedEmail, DuplicateReasonSimilarName}, report.Candidates[1].Reasons)
+				assert.Equal(t, 1.0, report.Candidates[1].NameSimilarity)
+
+				assert.Equal(t, []string{"auth0|d", "auth0|e"}, report.Candidates[2].UserIDs)
+				assert.Equal(t, []string{DuplicateReasonVerifiedEmail}, report.Candidates[2].Reasons)
+
+				assert.Equal(t, []string{"auth0|d", "github|b"}, report.Candidates[3].UserIDs)
+			},
+		},
+		{
+			name: "allow list and stricter names",
+			config: DuplicateAccountsConfig{
+				NameSimilarity:    0.95,
+				MinMatchingFields: 2,
+				AllowList:         []string{"github|b = auth0|a", "auth0|e"},
+			},
+			exporter: &mockUserExporter{users: users},
+			wantReport: func(t *testing.T, report DuplicateAccountsReport) {
+				assert.Equal(t, 0, report.Count)
+				assert.Empty(t, report.Candidates)
+			},
+		},
+		{
+			name:     "name matching off and truncated",
+			config:   DuplicateAccountsConfig{MaxCandidates: 1},
+			exporter: &mockUserExporter{users: users},
+			wantReport: func(t *testing.T, report DuplicateAccountsReport) {
+				assert.Equal(t, 2, report.Count)
+				assert.True(t, report.Truncated)
+				require.Len(t, report.Candidates, 1)
+				assert.Equal(t, []string{"auth0|a", "github|b"}, report.Candidates[0].UserIDs)
+				assert.Equal(t, []string{DuplicateReasonVerifiedEmail}, report.Candidates[0].Reasons)
+			},
+		},
+		{
+			name:     "export failure",
+			exporter: &mockUserExporter{err: errors.New("auth0 down")},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publisher := &mockEventPublisher{}
+			job := NewDuplicateAccountsJob(tt.exporter, publisher, tt.config)
+			job.now = func() time.Time { return now }
+
+			err := job.Run(context.Background())
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Empty(t, publisher.calls)
+				return
+			}
+			require.NoError(t, err)
+
+			require.Len(t, publisher.calls, 1)
+			assert.Equal(t, constants.DuplicateAccountsReportSubject, publisher.calls[0].Subject)
+			var report DuplicateAccountsReport
+			require.NoError(t, json.Unmarshal(publisher.calls[0].Data, &report))
+			tt.wantReport(t, report)
+		})
+	}
+}
+
+func TestNameSimilarity(t *testing.T) {
+	assert.Equal(t, 1.0, nameSimilarity(duplicateNameKey("Jané  Doe"), duplicateNameKey("jane doe")))
+	assert.InDelta(t, 0.9, nameSimilarity("jane doee", "jane doe"), 0.02)
+	assert.Equal(t, 0.0, nameSimilarity("", ""))
+	assert.Equal(t, "mary jo smith", duplicateNameKey("Mary-Jo  Smith."))
+}
@@ -0,0 +1,147 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/concurrent"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/normalize"
+)
+
+const (
+	// defaultEmailBatchMaxSize bounds the emails accepted in a single batch request
+	defaultEmailBatchMaxSize = 100
+	// defaultEmailBatchConcurrency bounds the provider lookups in flight per batch
+	defaultEmailBatchConcurrency = 10
+)
+
+// Per-item outcomes of email_to_username.batch
+const (
+	emailBatchStatusFound    = "found"
+	emailBatchStatusNotFound = "not_found"
+	emailBatchStatusError    = "error"
+)
+
+// emailBatchRequest is the input of email_to_username.batch
+type emailBatchRequest struct {
+	Emails []string `json:"emails"`
+}
+
+// emailBatchResult is the outcome for one requested email, in request order
+type emailBatchResult struct {
+	Email    string `json:"email"`
+	Status   string `json:"status"`
+	Username string `json:"username,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// WithEmailBatchLimitsForMessageHandler sets the maximum number of emails per
+// email_to_username.batch request and how many are resolved concurrently.
+// Values <= 0 keep the defaults.
+func WithEmailBatchLimitsForMessageHandler(maxSize, concurrency int) MessageHandlerOrchestratorOption {
+	return func(m *messageHandlerOrchestrator) {
+		m.emailBatchMaxSize = maxSize
+		m.emailBatchConcurrency = concurrency
+	}
+}
+
+func (m *messageHandlerOrchestrator) emailBatchLimits() (int, int) {
+	maxSize, concurrency := m.emailBatchMaxSize, m.emailBatchConcurrency
+	if maxSize <= 0 {
+		maxSize = defaultEmailBatchMaxSize
+	}
+	if concurrency <= 0 {
+		concurrency = defaultEmailBatchConcurrency
+	}
+	return maxSize, concurrency
+}
+
+// EmailToUsernameBatch resolves many emails to usernames in one request. Each
+// email goes through the same fallback as email_to_username; a failure is
+// reported on its item and doesn't fail the batch.
+func (m *messageHandlerOrchestrator) EmailToUsernameBatch(ctx context.Context, msg port.TransportMessenger) ([]byte, error) {
+	if m.userReader == nil {
+		return m.errorResponse("auth_service_unavailable"), nil
+	}
+
+	var request emailBatchRequest
+	if err := json.Unmarshal(msg.Data(), &request); err != nil {
+		return m.errorResponse("failed_to_unmarshal_request"), nil
+	}
+	if len(request.Emails) == 0 {
+		return m.errorResponse("emails are required"), nil
+	}
+	maxSize, concurrency := m.emailBatchLimits()
+	if len(request.Emails) > maxSize {
+		return m.errorResponse(fmt.Sprintf("too many emails: at most %d per request", maxSize)), nil
+	}
+
+	results := make([]emailBatchResult, len(request.Emails))
+	// duplicates are resolved once and share the result
+	first := make(map[string]int, len(request.Emails))
+	var lookups []func() error
+	for i, raw := range request.Emails {
+		email := normalize.Email(raw)
+		results[i].Email = raw
+		if email == "" || !(&model.Email{Email: email}).IsValidEmail() {
+			results[i].Status = emailBatchStatusError
+			results[i].Error = "invalid email"
+			continue
+		}
+		if _, ok := first[email]; ok {
+			continue
+		}
+		first[email] = i
+		lookups = append(lookups, func() error {
+			results[i] = m.resolveBatchEmail(ctx, raw, email)
+			return nil
+		})
+	}
+
+	if err := concurrent.NewWorkerPool(concurrency).Run(ctx, lookups...); err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+
+	for i, raw := range request.Emails {
+		if j, ok := first[normalize.Email(raw)]; ok && j != i {
+			results[i] = results[j]
+			results[i].Email = raw
+		}
+	}
+
+	slog.DebugContext(ctx, "email batch resolved", "emails", len(request.Emails), "lookups", len(lookups))
+
+	responseJSON, err := json.Marshal(UserDataResponse{Success: true, Data: results})
+	if err != nil {
+		return m.errorResponse("failed to marshal response"), nil
+	}
+	return responseJSON, nil
+}
+
+// resolveBatchEmail resolves a single normalized email of a batch
+func (m *messageHandlerOrchestrator) resolveBatchEmail(ctx context.Context, raw, email string) emailBatchResult {
+	result := emailBatchResult{Email: raw}
+
+	user, err := m.searchByEmailWithFallback(ctx, email)
+	var notFound errs.NotFound
+	switch {
+	case errors.As(err, &notFound):
+		result.Status = emailBatchStatusNotFound
+	case err != nil:
+		result.Status = emailBatchStatusError
+		result.Error = err.Error()
+	default:
+		result.Status = emailBatchStatusFound
+		result.Username = user.Username
+	}
+	return result
+}
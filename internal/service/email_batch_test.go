@@ -0,0 +1,120 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+)
+
+// emailBatchResponse mirrors the reply of EmailToUsernameBatch
+type emailBatchResponse struct {
+	Success bool               `json:"success"`
+	Error   string             `json:"error"`
+	Data    []emailBatchResult `json:"data"`
+}
+
+func decodeEmailBatch(t *testing.T, result []byte) emailBatchResponse {
+	t.Helper()
+	var response emailBatchResponse
+	if err := json.Unmarshal(result, &response); err != nil {
+		t.Fatalf("failed to unmarshal response %s: %v", result, err)
+	}
+	return response
+}
+
+func TestMessageHandlerOrchestrator_EmailToUsernameBatch(t *testing.T) {
+	ctx := context.Background()
+
+	var searches atomic.Int32
+	reader := &mockUserServiceReader{
+		searchUserFunc: func(ctx context.Context, user *model.User, criteria string) (*model.User, error) {
+			searches.Add(1)
+			if criteria != constants.CriteriaTypeEmail {
+				return nil, errors.NewNotFound("user not found")
+			}
+			switch user.PrimaryEmail {
+			case "jdoe@example.com":
+				return &model.User{Username: "jdoe", PrimaryEmail: user.PrimaryEmail}, nil
+			case "broken@example.com":
+				return nil, errors.NewUnexpected("provider unavailable")
+			}
+			return nil, errors.NewNotFound("user not found")
+		},
+	}
+	orchestrator := NewMessageHandlerOrchestrator(WithUserReaderForMessageHandler(reader))
+
+	payload := `{"emails":["JDoe@Example.com","unknown@example.com","broken@example.com","not-an-email","jdoe@example.com"]}`
+	result, err := orchestrator.EmailToUsernameBatch(ctx, &mockTransportMessenger{data: []byte(payload)})
+	if err != nil {
+		t.Fatalf("EmailToUsernameBatch() unexpected error: %v", err)
+	}
+	response := decodeEmailBatch(t, result)
+	if !response.Success {
+		t.Fatalf("EmailToUsernameBatch() success = false, error = %s", response.Error)
+	}
+
+	want := []emailBatchResult{
+		{Email: "JDoe@Example.com", Status: emailBatchStatusFound, Username: "jdoe"},
+		{Email: "unknown@example.com", Status: emailBatchStatusNotFound},
+		{Email: "broken@example.com", Status: emailBatchStatusError, Error: "provider unavailable"},
+		{Email: "not-an-email", Status: emailBatchStatusError, Error: "invalid email"},
+		{Email: "jdoe@example.com", Status: emailBatchStatusFound, Username: "jdoe"},
+	}
+	if len(response.Data) != len(want) {
+		t.Fatalf("EmailToUsernameBatch() returned %d results, want %d", len(response.Data), len(want))
+	}
+	for i := range want {
+		if response.Data[i] != want[i] {
+			t.Errorf("result[%d] = %+v, want %+v", i, response.Data[i], want[i])
+		}
+	}
+
+	// jdoe is found on the first criteria; each not-found email tries all of them
+	if got, limit := searches.Load(), int32(1+2*len(emailFallbackCriteria)); got > limit {
+		t.Errorf("SearchUser() called %d times, want at most %d (duplicates resolved once)", got, limit)
+	}
+}
+
+func TestMessageHandlerOrchestrator_EmailToUsernameBatch_Validation(t *testing.T) {
+	ctx := context.Background()
+	reader := &mockUserServiceReader{}
+
+	tests := []struct {
+		name      string
+		opts      []MessageHandlerOrchestratorOption
+		payload   string
+		wantError string
+	}{
+		{name: "no user reader", payload: `{"emails":["a@example.com"]}`, wantError: "auth_service_unavailable"},
+		{name: "invalid json", opts: []MessageHandlerOrchestratorOption{WithUserReaderForMessageHandler(reader)}, payload: `["a@example.com"]`, wantError: "failed_to_unmarshal_request"},
+		{name: "empty batch", opts: []MessageHandlerOrchestratorOption{WithUserReaderForMessageHandler(reader)}, payload: `{"emails":[]}`, wantError: "emails are required"},
+		{
+			name:      "batch too large",
+			opts:      []MessageHandlerOrchestratorOption{WithUserReaderForMessageHandler(reader), WithEmailBatchLimitsForMessageHandler(2, 1)},
+			payload:   `{"emails":["a@example.com","b@example.com","c@example.com"]}`,
+			wantError: "too many emails: at most 2 per request",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewMessageHandlerOrchestrator(tt.opts...).EmailToUsernameBatch(ctx, &mockTransportMessenger{data: []byte(tt.payload)})
+			if err != nil {
+				t.Fatalf("EmailToUsernameBatch() unexpected error: %v", err)
+			}
+			response := decodeEmailBatch(t, result)
+			if response.Success || !strings.Contains(response.Error, tt.wantError) {
+				t.Errorf("EmailToUsernameBatch() error = %q, want %q", response.Error, tt.wantError)
+			}
+		})
+	}
+}
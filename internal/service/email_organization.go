@@ -0,0 +1,279 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/jwt"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/normalize"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/redaction"
+)
+
+// emailOrganizationMaxNameLength bounds the organization name and id, in bytes
+const emailOrganizationMaxNameLength = 200
+
+// publicEmailDomains are mailbox providers anyone can sign up with, so their
+// domain says nothing about the user's employer
+var publicEmailDomains = []string{
+	"126.com", "163.com", "aol.com", "duck.com", "fastmail.com", "gmail.com", "gmx.com", "gmx.de",
+	"gmx.net", "googlemail.com", "hey.com", "hotmail.co.uk", "hotmail.com", "hotmail.fr", "icloud.com",
+	"live.com", "mac.com", "mail.com", "mail.ru", "me.com", "msn.com", "naver.com", "outlook.com",
+	"pm.me", "proton.me", "protonmail.com", "qq.com", "sina.com", "tutanota.com", "users.noreply.github.com",
+	"web.de", "yahoo.co.jp", "yahoo.co.uk", "yahoo.com", "yandex.com", "yandex.ru", "ymail.com", "zoho.com",
+}
+
+// emailDomainLabel is one label of a domain in its ASCII (punycode) form
+var emailDomainLabel = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// emailOrganizationRequest is the input of the admin.email_organization subjects
+type emailOrganizationRequest struct {
+	AuthToken        string `json:"auth_token"`
+	Domain           string `json:"domain"`
+	OrganizationID   string `json:"organization_id"`
+	OrganizationName string `json:"organization_name"`
+}
+
+// emailOrganizationLookup is the reply of email.organization. Domain is the
+// domain of the input, MatchedDomain the one whose mapping applies: the
+// domain itself or the closest parent with a mapping.
+type emailOrganizationLookup struct {
+	Domain           string `json:"domain"`
+	MatchedDomain    string `json:"matched_domain,omitempty"`
+	OrganizationID   string `json:"organization_id,omitempty"`
+	OrganizationName string `json:"organization_name,omitempty"`
+	// Public marks a public mailbox provider, which implies no organization
+	Public bool `json:"public,omitempty"`
+}
+
+// WithEmailOrganizationsForMessageHandler sets the email domain to organization
+// mappings for the message handler orchestrator. publicDomains are excluded
+// from them on top of the built-in public mailbox providers.
+func WithEmailOrganizationsForMessageHandler(store port.EmailOrganizationStore, publicDomains []string) MessageHandlerOrchestratorOption {
+	return func(m *messageHandlerOrchestrator) {
+		m.emailOrganizations = store
+		m.publicEmailDomains = make(map[string]bool, len(publicEmailDomains)+len(publicDomains))
+		for _, domain := range publicEmailDomains {
+			m.publicEmailDomains[domain] = true
+		}
+		for _, domain := range publicDomains {
+			m.publicEmailDomains[strings.ToLower(strings.TrimSpace(domain))] = true
+		}
+	}
+}
+
+// emailDomain returns the domain of an email address, or of a bare domain,
+// in canonical form
+func emailDomain(input string) (string, error) {
+	domain := normalize.Email(input)
+	if at := strings.LastIndex(domain, "@"); at >= 0 {
+		domain = domain[at+1:]
+	}
+	domain = strings.TrimSuffix(domain, ".")
+	if domain == "" {
+		return "", errs.NewValidation("email or domain is required")
+	}
+
+	labels := strings.Split(domain, ".")
+	if len(labels) < 2 || len(domain) > 253 {
+		return "", errs.NewValidation("invalid email domain")
+	}
+	for _, label := range labels {
+		if !emailDomainLabel.MatchString(label) {
+			return "", errs.NewValidation("invalid email domain")
+		}
+	}
+	return domain, nil
+}
+
+// isPublicEmailDomain reports whether domain, or a parent of it, is a public
+// mailbox provider
+func (m *messageHandlerOrchestrator) isPublicEmailDomain(domain string) bool {
+	for candidate := domain; strings.Contains(candidate, "."); {
+		if m.publicEmailDomains[candidate] {
+			return true
+		}
+		_, candidate, _ = strings.Cut(candidate, ".")
+	}
+	return false
+}
+
+// EmailOrganization returns the organization of an email address, or of a
+// bare domain, from the mapping of its domain or else of the closest parent
+// domain, so eng.example.com users are matched by a mapping of example.com.
+func (m *messageHandlerOrchestrator) EmailOrganization(ctx context.Context, msg port.TransportMessenger) ([]byte, error) {
+	if m.emailOrganizations == nil {
+		return m.errorResponse("email_organizations_unavailable"), nil
+	}
+
+	domain, err := emailDomain(string(msg.Data()))
+	if err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+
+	result := emailOrganizationLookup{Domain: domain}
+	if m.isPublicEmailDomain(domain) {
+		result.Public = true
+	} else {
+		mapping, err := m.closestEmailOrganization(ctx, domain)
+		if err != nil {
+			return m.errorResponse(err.Error()), nil
+		}
+		result.MatchedDomain = mapping.Domain
+		result.OrganizationID = mapping.OrganizationID
+		result.OrganizationName = mapping.OrganizationName
+	}
+
+	responseJSON, err := json.Marshal(UserDataResponse{Success: true, Data: result})
+	if err != nil {
+		return m.errorResponse("failed to marshal response"), nil
+	}
+	return responseJSON, nil
+}
+
+// closestEmailOrganization returns the mapping of domain or of its closest
+// parent that has one, stopping before the top-level domain
+func (m *messageHandlerOrchestrator) closestEmailOrganization(ctx context.Context, domain string) (*model.EmailOrganization, error) {
+	for candidate := domain; strings.Contains(candidate, "."); {
+		mapping, err := m.emailOrganizations.Get(ctx, candidate)
+		if err == nil {
+			return mapping, nil
+		}
+		if !errors.As(err, &errs.NotFound{}) {
+			return nil, err
+		}
+		_, candidate, _ = strings.Cut(candidate, ".")
+	}
+	return nil, errs.NewNotFound("organization not found")
+}
+
+// SetEmailOrganization maps an email domain to an organization, replacing
+// its current mapping. Public mailbox providers can't be mapped.
+func (m *messageHandlerOrchestrator) SetEmailOrganization(ctx context.Context, msg port.TransportMessenger) ([]byte, error) {
+	request, claims, errResponse := m.emailOrganizationAdminRequest(ctx, msg)
+	if errResponse != nil {
+		return errResponse, nil
+	}
+
+	domain, err := emailDomain(request.Domain)
+	if err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+	if m.isPublicEmailDomain(domain) {
+		return m.errorResponse(domain + " is a public email domain"), nil
+	}
+	name := strings.TrimSpace(request.OrganizationName)
+	switch {
+	case name == "":
+		return m.errorResponse("organization_name is required"), nil
+	case len(name) > emailOrganizationMaxNameLength:
+		return m.errorResponse("organization_name is too long"), nil
+	case len(request.OrganizationID) > emailOrganizationMaxNameLength:
+		return m.errorResponse("organization_id is too long"), nil
+	}
+
+	mapping := &model.EmailOrganization{
+		Domain:           domain,
+		OrganizationID:   strings.TrimSpace(request.OrganizationID),
+		OrganizationName: name,
+		UpdatedBy:        claims.Subject,
+		UpdatedAt:        time.Now().UTC(),
+	}
+	if err := m.emailOrganizations.Put(ctx, mapping); err != nil {
+		slog.ErrorContext(ctx, "failed to store email organization", "error", err)
+		return m.errorResponse(err.Error()), nil
+	}
+
+	slog.InfoContext(ctx, "email organization set",
+		"domain", domain,
+		"organization_id", mapping.OrganizationID,
+		"updated_by", redaction.Redact(claims.Subject),
+	)
+
+	responseJSON, err := json.Marshal(UserDataResponse{Success: true, Data: mapping})
+	if err != nil {
+		return m.errorResponse("failed to marshal response"), nil
+	}
+	return responseJSON, nil
+}
+
+// DeleteEmailOrganization removes the mapping of an email domain and returns it
+func (m *messageHandlerOrchestrator) DeleteEmailOrganization(ctx context.Context, msg port.TransportMessenger) ([]byte, error) {
+	request, claims, errResponse := m.emailOrganizationAdminRequest(ctx, msg)
+	if errResponse != nil {
+		return errResponse, nil
+	}
+
+	domain, err := emailDomain(request.Domain)
+	if err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+	mapping, err := m.emailOrganizations.Delete(ctx, domain)
+	if err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+
+	slog.InfoContext(ctx, "email organization deleted",
+		"domain", domain,
+		"deleted_by", redaction.Redact(claims.Subject),
+	)
+
+	responseJSON, err := json.Marshal(UserDataResponse{Success: true, Data: mapping})
+	if err != nil {
+		return m.errorResponse("failed to marshal response"), nil
+	}
+	return responseJSON, nil
+}
+
+// ListEmailOrganizations returns every email domain mapping, sorted by domain
+func (m *messageHandlerOrchestrator) ListEmailOrganizations(ctx context.Context, msg port.TransportMessenger) ([]byte, error) {
+	if _, _, errResponse := m.emailOrganizationAdminRequest(ctx, msg); errResponse != nil {
+		return errResponse, nil
+	}
+
+	mappings, err := m.emailOrganizations.List(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to list email organizations", "error", err)
+		return m.errorResponse(err.Error()), nil
+	}
+	if mappings == nil {
+		mappings = []*model.EmailOrganization{}
+	}
+
+	responseJSON, err := json.Marshal(UserDataResponse{Success: true, Data: mappings})
+	if err != nil {
+		return m.errorResponse("failed to marshal response"), nil
+	}
+	return responseJSON, nil
+}
+
+// emailOrganizationAdminRequest decodes and authorizes a request of the
+// admin.email_organization subjects, returning the reply to send instead
+// when it can't be served
+func (m *messageHandlerOrchestrator) emailOrganizationAdminRequest(ctx context.Context, msg port.TransportMessenger) (*emailOrganizationRequest, *jwt.Claims, []byte) {
+	if m.emailOrganizations == nil {
+		return nil, nil, m.errorResponse("email_organizations_unavailable")
+	}
+
+	var request emailOrganizationRequest
+	if err := json.Unmarshal(msg.Data(), &request); err != nil {
+		return nil, nil, m.errorResponse("failed_to_unmarshal_request")
+	}
+
+	claims, err := m.authorizeScope(ctx, request.AuthToken, constants.EmailOrganizationManageRequiredScope)
+	if err != nil {
+		return nil, nil, m.errorResponse(err.Error())
+	}
+	return &request, claims, nil
+}
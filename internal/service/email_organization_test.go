@@ -0,0 +1,190 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/jwt"
+)
+
+// memoryEmailOrganizationStore keeps the mappings by domain
+type memoryEmailOrganizationStore map[string]*model.EmailOrganization
+
+func (s memoryEmailOrganizationStore) Get(_ context.Context, domain string) (*model.EmailOrganization, error) {
+	mapping, ok := s[domain]
+	if !ok {
+		return nil, errs.NewNotFound("email domain not found")
+	}
+	return mapping, nil
+}
+
+func (s memoryEmailOrganizationStore) Put(_ context.Context, mapping *model.EmailOrganization) error {
+	s[mapping.Domain] = mapping
+	return nil
+}
+
+func (s memoryEmailOrganizationStore) Delete(ctx context.Context, domain string) (*model.EmailOrganization, error) {
+	mapping, err := s.Get(ctx, domain)
+	delete(s, domain)
+	return mapping, err
+}
+
+func (s memoryEmailOrganizationStore) List(_ context.Context) ([]*model.EmailOrganization, error) {
+	var mappings []*model.EmailOrganization
+	for _, mapping := range s {
+		mappings = append(mappings, mapping)
+	}
+	slices.SortFunc(mappings, func(a, b *model.EmailOrganization) int { return strings.Compare(a.Domain, b.Domain) })
+	return mappings, nil
+}
+
+func TestMessageHandlerOrchestrator_EmailOrganization(t *testing.T) {
+	ctx := context.Background()
+	store := memoryEmailOrganizationStore{
+		"example.com":          {Domain: "example.com", OrganizationID: "org_1", OrganizationName: "Example Org"},
+		"research.example.com": {Domain: "research.example.com", OrganizationName: "Example Research"},
+	}
+	orchestrator := NewMessageHandlerOrchestrator(WithEmailOrganizationsForMessageHandler(store, []string{"Mailbox.Example"}))
+
+	type response struct {
+		Success bool                    `json:"success"`
+		Error   string                  `json:"error"`
+		Data    emailOrganizationLookup `json:"data"`
+	}
+	lookup := func(t *testing.T, input string) response {
+		t.Helper()
+		result, err := orchestrator.EmailOrganization(ctx, &mockTransportMessenger{data: []byte(input)})
+		require.NoError(t, err)
+		var r response
+		require.NoError(t, json.Unmarshal(result, &r))
+		return r
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		want  emailOrganizationLookup
+	}{
+		{"email", " Zephyr@Example.COM ", emailOrganizationLookup{Domain: "example.com", MatchedDomain: "example.com", OrganizationID: "org_1", OrganizationName: "Example Org"}},
+		{"bare domain", "example.com", emailOrganizationLookup{Domain: "example.com", MatchedDomain: "example.com", OrganizationID: "org_1", OrganizationName: "Example Org"}},
+		{"subdomain", "zora@eng.example.com", emailOrganizationLookup{Domain: "eng.example.com", MatchedDomain: "example.com", OrganizationID: "org_1", OrganizationName: "Example Org"}},
+		{"subdomain with its own mapping", "zora@lab.research.example.com", emailOrganizationLookup{Domain: "lab.research.example.com", MatchedDomain: "research.example.com", OrganizationName: "Example Research"}},
+		{"public", "zephyr@gmail.com", emailOrganizationLookup{Domain: "gmail.com", Public: true}},
+		{"configured public", "zephyr@mailbox.example", emailOrganizationLookup{Domain: "mailbox.example", Public: true}},
+		{"github noreply", "123+zephyr@users.noreply.github.com", emailOrganizationLookup{Domain: "users.noreply.github.com", Public: true}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := lookup(t, tc.input)
+			require.True(t, r.Success, r.Error)
+			assert.Equal(t, tc.want, r.Data)
+		})
+	}
+
+	errorCases := map[string]string{
+		"zephyr@unmapped.example": "organization not found",
+		"":                        "email or domain is required",
+		"zephyr@localhost":        "invalid email domain",
+		"zephyr@bad_domain.com":   "invalid email domain",
+	}
+	for input, want := range errorCases {
+		t.Run("error "+input, func(t *testing.T) {
+			r := lookup(t, input)
+			assert.False(t, r.Success)
+			assert.Equal(t, want, r.Error)
+		})
+	}
+
+	t.Run("unavailable without a store", func(t *testing.T) {
+		result, err := NewMessageHandlerOrchestrator().EmailOrganization(ctx, &mockTransportMessenger{data: []byte("zephyr@example.com")})
+		require.NoError(t, err)
+		assert.Contains(t, string(result), "email_organizations_unavailable")
+	})
+}
+
+func TestMessageHandlerOrchestrator_ManageEmailOrganizations(t *testing.T) {
+	ctx := context.Background()
+	withScope, err := jwt.GenerateTestAccessToken("auth0|admin", "https://issuer/", "aud", constants.EmailOrganizationManageRequiredScope, time.Hour)
+	require.NoError(t, err)
+	withoutScope, err := jwt.GenerateSimpleTestAccessToken("auth0|admin", time.Hour)
+	require.NoError(t, err)
+
+	reader := &mockUserServiceReader{
+		metadataLookupFunc: func(ctx context.Context, input string) (*model.User, error) {
+			return &model.User{Token: input, UserID: "auth0|admin"}, nil
+		},
+	}
+	store := memoryEmailOrganizationStore{}
+	orchestrator := NewMessageHandlerOrchestrator(WithUserReaderForMessageHandler(reader), WithEmailOrganizationsForMessageHandler(store, nil))
+
+	type response struct {
+		Success bool            `json:"success"`
+		Error   string          `json:"error"`
+		Data    json.RawMessage `json:"data"`
+	}
+	call := func(t *testing.T, handler func(context.Context, port.TransportMessenger) ([]byte, error), request string) response {
+		t.Helper()
+		result, err := handler(ctx, &mockTransportMessenger{data: []byte(request)})
+		require.NoError(t, err)
+		var r response
+		require.NoError(t, json.Unmarshal(result, &r))
+		return r
+	}
+	set, del, list := orchestrator.SetEmailOrganization, orchestrator.DeleteEmailOrganization, orchestrator.ListEmailOrganizations
+
+	r := call(t, set, `{"auth_token":"`+withScope+`","domain":"Example.com","organization_id":"org_1","organization_name":" Example Org "}`)
+	require.True(t, r.Success, r.Error)
+	require.Contains(t, store, "example.com")
+	assert.Equal(t, "Example Org", store["example.com"].OrganizationName)
+	assert.Equal(t, "auth0|admin", store["example.com"].UpdatedBy)
+
+	r = call(t, list, `{"auth_token":"`+withScope+`"}`)
+	require.True(t, r.Success, r.Error)
+	var mappings []model.EmailOrganization
+	require.NoError(t, json.Unmarshal(r.Data, &mappings))
+	require.Len(t, mappings, 1)
+	assert.Equal(t, "example.com", mappings[0].Domain)
+
+	r = call(t, del, `{"auth_token":"`+withScope+`","domain":"example.com"}`)
+	require.True(t, r.Success, r.Error)
+	assert.Empty(t, store)
+	r = call(t, del, `{"auth_token":"`+withScope+`","domain":"example.com"}`)
+	assert.Equal(t, "email domain not found", r.Error)
+
+	r = call(t, list, `{"auth_token":"`+withScope+`"}`)
+	require.True(t, r.Success, r.Error)
+	assert.JSONEq(t, `[]`, string(r.Data))
+
+	errorCases := []struct {
+		name    string
+		request string
+		want    string
+	}{
+		{"missing scope", `{"auth_token":"` + withoutScope + `","domain":"example.com","organization_name":"Example Org"}`, "insufficient_scope"},
+		{"public domain", `{"auth_token":"` + withScope + `","domain":"outlook.com","organization_name":"Microsoft"}`, "outlook.com is a public email domain"},
+		{"name required", `{"auth_token":"` + withScope + `","domain":"example.com"}`, "organization_name is required"},
+		{"invalid domain", `{"auth_token":"` + withScope + `","domain":"example","organization_name":"Example Org"}`, "invalid email domain"},
+	}
+	for _, tc := range errorCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := call(t, set, tc.request)
+			assert.False(t, r.Success)
+			assert.Equal(t, tc.want, r.Error)
+			assert.Empty(t, store)
+		})
+	}
+}
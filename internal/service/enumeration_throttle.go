@@ -0,0 +1,284 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/normalize"
+)
+
+// errLookupsBlocked is the reply to a lookup of a caller blocked for
+// enumerating accounts
+const errLookupsBlocked = "too many lookups of unknown accounts, retry later"
+
+// accountLookupSubjects are the subjects resolving an email or a username to
+// an account, whose misses tell an attacker which accounts don't exist
+var accountLookupSubjects = map[string]bool{
+	constants.UserEmailToUserSubject:      true,
+	constants.UserEmailToSubSubject:       true,
+	constants.UserEmailToUserBatchSubject: true,
+	constants.UserUsernameToSubSubject:    true,
+}
+
+// EnumerationThrottleConfig sets when the lookups of a caller are slowed down
+// and refused, by the number of distinct unknown accounts it looked up within
+// Window. A zero DelayAfter or BlockAfter disables that step.
+type EnumerationThrottleConfig struct {
+	Window time.Duration
+	// DelayAfter is the misses after which each lookup is delayed, by
+	// BaseDelay doubled for every further miss, up to MaxDelay
+	DelayAfter int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+	// BlockAfter is the misses blocking the lookups of the caller for
+	// BlockDuration
+	BlockAfter    int
+	BlockDuration time.Duration
+}
+
+// enumerationCounter holds the distinct misses of a caller in its window
+type enumerationCounter struct {
+	start        time.Time
+	misses       map[string]struct{}
+	blockedUntil time.Time
+}
+
+// EnumerationThrottle deters account enumeration: the distinct emails and
+// usernames a caller looks up without them matching an account are counted
+// in fixed windows, and past the configured thresholds its lookups are
+// delayed, then refused for a while with a security event. Looking up
+// existing accounts, or the same unknown one again, doesn't count. Callers
+// are resolved as for the SecurityDetector.
+type EnumerationThrottle struct {
+	publisher port.EventPublisher
+	config    EnumerationThrottleConfig
+	now       func() time.Time
+	// sleep waits for d or until ctx is done
+	sleep func(ctx context.Context, d time.Duration)
+
+	mu       sync.Mutex
+	counters map[string]*enumerationCounter
+}
+
+// NewEnumerationThrottle returns a throttle publishing the security event of
+// a block with publisher; a nil publisher only logs and counts it
+func NewEnumerationThrottle(publisher port.EventPublisher, config EnumerationThrottleConfig) *EnumerationThrottle {
+	return &EnumerationThrottle{
+		publisher: publisher,
+		config:    config,
+		now:       time.Now,
+		sleep:     sleepContext,
+		counters:  make(map[string]*enumerationCounter),
+	}
+}
+
+// sleepContext waits for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+// ThrottleEnumeration returns the middleware applying throttle to the account
+// lookups; requests to other subjects pass through
+func ThrottleEnumeration(throttle *EnumerationThrottle) Middleware {
+	return func(next port.Handler) port.Handler {
+		return port.HandlerFunc(func(ctx context.Context, msg port.TransportMessenger) {
+			request := securityRequest{subject: msg.Subject(), caller: transportCaller(msg)}
+			key := request.key()
+			if !accountLookupSubjects[request.subject] || key == "" {
+				next.Handle(ctx, msg)
+				return
+			}
+
+			delay, blocked := throttle.admit(key)
+			if blocked {
+				respondError(ctx, msg, errLookupsBlocked)
+				return
+			}
+			if delay > 0 {
+				throttle.sleep(ctx, delay)
+			}
+			next.Handle(ctx, &enumerationMessenger{TransportMessenger: msg, ctx: ctx, throttle: throttle, request: request})
+		})
+	}
+}
+
+// admit returns how long the next lookup of caller key waits, or that it is
+// refused
+func (e *EnumerationThrottle) admit(key string) (time.Duration, bool) {
+	now := e.now()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	counter, ok := e.counters[key]
+	if !ok {
+		return 0, false
+	}
+	if now.Before(counter.blockedUntil) {
+		return 0, true
+	}
+	if now.Sub(counter.start) >= e.config.Window {
+		delete(e.counters, key)
+		return 0, false
+	}
+	return e.delay(len(counter.misses)), false
+}
+
+// delay is the wait of a lookup after misses distinct misses
+func (e *EnumerationThrottle) delay(misses int) time.Duration {
+	if e.config.DelayAfter < 1 || misses < e.config.DelayAfter {
+		return 0
+	}
+	delay := e.config.BaseDelay
+	for range misses - e.config.DelayAfter {
+		if delay >= e.config.MaxDelay {
+			break
+		}
+		delay *= 2
+	}
+	return min(delay, e.config.MaxDelay)
+}
+
+// recordMisses counts the unknown accounts a lookup of request resolved,
+// blocking its caller when they reach the threshold
+func (e *EnumerationThrottle) recordMisses(ctx context.Context, request securityRequest, identifiers []string) {
+	if len(identifiers) == 0 {
+		return
+	}
+	key := request.key()
+	now := e.now()
+
+	e.mu.Lock()
+	counter, ok := e.counters[key]
+	if !ok || (now.Sub(counter.start) >= e.config.Window && !now.Before(counter.blockedUntil)) {
+		if !ok && len(e.counters) >= maxTrackedSecurityCallers {
+			e.sweep(now)
+			if len(e.counters) >= maxTrackedSecurityCallers {
+				e.mu.Unlock()
+				return
+			}
+		}
+		counter = &enumerationCounter{start: now, misses: make(map[string]struct{})}
+		e.counters[key] = counter
+	}
+	blocking := e.config.BlockAfter > 0
+	for _, identifier := range identifiers {
+		// the count stops at the block threshold, so it bounds the memory of a caller
+		if blocking && len(counter.misses) >= e.config.BlockAfter {
+			break
+		}
+		sum := sha256.Sum256([]byte(identifier))
+		counter.misses[hex.EncodeToString(sum[:])[:16]] = struct{}{}
+	}
+	misses, firstSeen := len(counter.misses), counter.start
+	block := blocking && misses >= e.config.BlockAfter && !now.Before(counter.blockedUntil)
+	if block {
+		counter.blockedUntil = now.Add(e.config.BlockDuration)
+		// the caller starts over once the block is over
+		counter.start = counter.blockedUntil
+		counter.misses = make(map[string]struct{})
+	}
+	event := model.SecurityEvent{
+		Rule:        model.SecurityRuleEnumerationLockout,
+		Caller:      key,
+		Subject:     request.subject,
+		Count:       misses,
+		Threshold:   e.config.BlockAfter,
+		Window:      e.config.Window.String(),
+		FirstSeenAt: firstSeen,
+		DetectedAt:  now,
+	}
+	blockedUntil := counter.blockedUntil
+	e.mu.Unlock()
+
+	if block {
+		event.BlockedUntil = &blockedUntil
+		raiseSecurityEvent(ctx, e.publisher, event)
+	}
+}
+
+// sweep drops the counters of callers whose window and block are over; e.mu
+// is held
+func (e *EnumerationThrottle) sweep(now time.Time) {
+	for key, counter := range e.counters {
+		if now.Sub(counter.start) >= e.config.Window && !now.Before(counter.blockedUntil) {
+			delete(e.counters, key)
+		}
+	}
+}
+
+// enumerationMessenger counts the unknown accounts of the lookup reply sent
+// through it
+type enumerationMessenger struct {
+	port.TransportMessenger
+
+	ctx      context.Context
+	throttle *EnumerationThrottle
+	request  securityRequest
+}
+
+// Respond counts the misses of the reply and forwards it
+func (e *enumerationMessenger) Respond(data []byte) error {
+	e.throttle.recordMisses(e.ctx, e.request, lookupMisses(e.request.subject, e.Data(), data))
+	return e.TransportMessenger.Respond(data)
+}
+
+// Unwrap returns the wrapped messenger
+func (e *enumerationMessenger) Unwrap() port.TransportMessenger {
+	return e.TransportMessenger
+}
+
+// lookupMisses returns the looked-up identifiers of a lookup reply that
+// matched no account: the not_found entries of a batch, the email or
+// username of a single lookup replied user not found
+func lookupMisses(subject string, request, reply []byte) []string {
+	if subject == constants.UserEmailToUserBatchSubject {
+		var response struct {
+			Success bool               `json:"success"`
+			Data    []emailBatchResult `json:"data"`
+		}
+		if err := json.Unmarshal(reply, &response); err != nil || !response.Success {
+			return nil
+		}
+		var misses []string
+		for _, result := range response.Data {
+			if result.Status == emailBatchStatusNotFound {
+				misses = append(misses, normalize.Email(result.Email))
+			}
+		}
+		return misses
+	}
+
+	var response struct {
+		Success bool   `json:"success"`
+		Error   string `json:"error"`
+	}
+	if err := json.Unmarshal(reply, &response); err != nil || response.Success ||
+		!strings.HasPrefix(response.Error, errs.Message(errs.CodeUserNotFound)) {
+		return nil
+	}
+	identifier := strings.TrimSpace(string(request))
+	if subject != constants.UserUsernameToSubSubject {
+		identifier = normalize.Email(identifier)
+	}
+	if identifier == "" {
+		return nil
+	}
+	return []string{identifier}
+}
@@ -0,0 +1,144 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+)
+
+func TestThrottleEnumeration(t *testing.T) {
+	// known resolves a@example.com only, like a provider with one user
+	known := port.HandlerFunc(func(_ context.Context, msg port.TransportMessenger) {
+		if strings.TrimSpace(string(msg.Data())) == "a@example.com" {
+			_ = msg.Respond([]byte("jdoe"))
+			return
+		}
+		_ = msg.Respond([]byte(`{"success":false,"error":"user not found"}`))
+	})
+
+	type setup struct {
+		throttle  *EnumerationThrottle
+		handler   port.Handler
+		publisher *mockEventPublisher
+		delays    []time.Duration
+		now       time.Time
+	}
+	newSetup := func(handler port.Handler) *setup {
+		s := &setup{publisher: &mockEventPublisher{}, now: time.Now()}
+		s.throttle = NewEnumerationThrottle(s.publisher, EnumerationThrottleConfig{
+			Window:        time.Minute,
+			DelayAfter:    2,
+			BaseDelay:     100 * time.Millisecond,
+			MaxDelay:      300 * time.Millisecond,
+			BlockAfter:    5,
+			BlockDuration: 10 * time.Minute,
+		})
+		s.throttle.now = func() time.Time { return s.now }
+		s.throttle.sleep = func(_ context.Context, d time.Duration) { s.delays = append(s.delays, d) }
+		s.handler = Chain(handler, ThrottleEnumeration(s.throttle))
+		return s
+	}
+	lookup := func(s *setup, subject, caller, data string) string {
+		msg := &callerMessenger{repliedMessenger: repliedMessenger{data: []byte(data)}, subject: subject, caller: caller}
+		s.handler.Handle(context.Background(), msg)
+		return string(msg.replied)
+	}
+
+	t.Run("delays progressively, then blocks with a security event", func(t *testing.T) {
+		s := newSetup(known)
+		for _, email := range []string{"b@example.com", "c@example.com", "d@example.com", "e@example.com", "f@example.com"} {
+			lookup(s, constants.UserEmailToUserSubject, "_INBOX.conn1", email)
+		}
+		// the 3rd lookup follows 2 misses, the 4th and 5th follow 3 and 4
+		assert.Equal(t, []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond}, s.delays)
+
+		reply := lookup(s, constants.UserEmailToUserSubject, "_INBOX.conn1", "a@example.com")
+		assert.JSONEq(t, `{"success":false,"error":"too many lookups of unknown accounts, retry later"}`, reply)
+		assert.Equal(t, "jdoe", lookup(s, constants.UserEmailToUserSubject, "_INBOX.conn2", "a@example.com"),
+			"other callers are not blocked")
+
+		require.Len(t, s.publisher.calls, 1)
+		assert.Equal(t, constants.SecurityEventsSubject, s.publisher.calls[0].Subject)
+		var event model.SecurityEvent
+		require.NoError(t, json.Unmarshal(s.publisher.calls[0].Data, &event))
+		assert.Equal(t, model.SecurityRuleEnumerationLockout, event.Rule)
+		assert.Equal(t, "_INBOX.conn1", event.Caller)
+		assert.Equal(t, 5, event.Count)
+		require.NotNil(t, event.BlockedUntil)
+		assert.WithinDuration(t, s.now.Add(10*time.Minute), *event.BlockedUntil, time.Second)
+
+		s.now = s.now.Add(11 * time.Minute)
+		assert.Equal(t, "jdoe", lookup(s, constants.UserEmailToUserSubject, "_INBOX.conn1", "a@example.com"),
+			"the block is lifted after its duration")
+	})
+
+	t.Run("counts distinct unknown accounts only", func(t *testing.T) {
+		s := newSetup(known)
+		for range 10 {
+			lookup(s, constants.UserEmailToUserSubject, "_INBOX.conn1", "a@example.com")
+			lookup(s, constants.UserEmailToSubSubject, "_INBOX.conn1", "B@Example.com")
+			lookup(s, constants.UserEmailToSubSubject, "_INBOX.conn1", "b@example.com")
+		}
+		assert.Empty(t, s.delays)
+		assert.Empty(t, s.publisher.calls)
+	})
+
+	t.Run("counts the not found entries of a batch", func(t *testing.T) {
+		s := newSetup(port.HandlerFunc(func(_ context.Context, msg port.TransportMessenger) {
+			_ = msg.Respond([]byte(`{"success":true,"data":[
+				{"email":"a@example.com","status":"found","username":"jdoe"},
+				{"email":"x1@example.com","status":"not_found"},
+				{"email":"x2@example.com","status":"not_found"},
+				{"email":"x3@example.com","status":"not_found"},
+				{"email":"x4@example.com","status":"not_found"},
+				{"email":"x5@example.com","status":"not_found"}]}`))
+		}))
+		lookup(s, constants.UserEmailToUserBatchSubject, "_INBOX.conn1", `{"emails":["a@example.com"]}`)
+		require.Len(t, s.publisher.calls, 1)
+		assert.Contains(t, lookup(s, constants.UserEmailToUserBatchSubject, "_INBOX.conn1", `{}`), errLookupsBlocked)
+	})
+
+	t.Run("starts over in a new window", func(t *testing.T) {
+		s := newSetup(known)
+		for _, email := range []string{"b@example.com", "c@example.com", "d@example.com", "e@example.com"} {
+			lookup(s, constants.UserEmailToUserSubject, "_INBOX.conn1", email)
+		}
+		s.now = s.now.Add(2 * time.Minute)
+		s.delays = nil
+		lookup(s, constants.UserEmailToUserSubject, "_INBOX.conn1", "f@example.com")
+		assert.Empty(t, s.delays)
+		assert.Empty(t, s.publisher.calls)
+	})
+
+	t.Run("sees the caller through the messengers of outer middlewares", func(t *testing.T) {
+		s := newSetup(known)
+		s.handler = Chain(known, ScrubErrors(), DetectSecurityEvents(NewSecurityDetector(nil, SecurityDetectionConfig{})),
+			ThrottleEnumeration(s.throttle))
+		for _, email := range []string{"b@example.com", "c@example.com", "d@example.com", "e@example.com", "f@example.com"} {
+			lookup(s, constants.UserEmailToUserSubject, "_INBOX.conn1", email)
+		}
+		require.Len(t, s.publisher.calls, 1)
+	})
+
+	t.Run("leaves other subjects and unattributed requests alone", func(t *testing.T) {
+		s := newSetup(known)
+		for _, email := range []string{"b@example.com", "c@example.com", "d@example.com", "e@example.com", "f@example.com", "g@example.com"} {
+			lookup(s, constants.UserMetadataReadSubject, "_INBOX.conn1", email)
+			lookup(s, constants.UserEmailToUserSubject, "", email)
+		}
+		assert.Empty(t, s.delays)
+		assert.Empty(t, s.publisher.calls)
+	})
+}
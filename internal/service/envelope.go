@@ -0,0 +1,164 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/envelope"
+)
+
+// legacyEnvelopeMembers are the members of a version 1 reply that have a
+// place in the typed envelope; the others are moved into its data
+var legacyEnvelopeMembers = map[string]bool{
+	"success":        true,
+	"data":           true,
+	"message":        true,
+	"error":          true,
+	"error_code":     true,
+	"error_message":  true,
+	"error_language": true,
+	"stale":          true,
+	"version":        true,
+}
+
+// TypedEnvelope returns the middleware replying in the envelope version the
+// request asks for, defaultVersion when it asks for none. Handlers reply
+// version 1; the replies of requests asking for version 2 are converted,
+// with the meta block of the request: its trace ID, or a new ID without
+// one, provider unless a layer recorded another, the cache outcome of its
+// user reads, and the time it took.
+func TypedEnvelope(provider string, defaultVersion int) Middleware {
+	return func(next port.Handler) port.Handler {
+		return port.HandlerFunc(func(ctx context.Context, msg port.TransportMessenger) {
+			if cmp.Or(envelope.VersionFromContext(ctx), defaultVersion) < envelope.Version {
+				next.Handle(ctx, msg)
+				return
+			}
+			ctx, recorder := envelope.WithRecorder(ctx)
+			next.Handle(ctx, &envelopeMessenger{
+				TransportMessenger: msg,
+				started:            time.Now(),
+				requestID:          requestID(ctx),
+				provider:           provider,
+				recorder:           recorder,
+			})
+		})
+	}
+}
+
+// requestID returns the trace ID of the request of ctx, or a new ID when it
+// isn't traced
+func requestID(ctx context.Context) string {
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.HasTraceID() {
+		return spanContext.TraceID().String()
+	}
+	return uuid.NewString()
+}
+
+// envelopeMessenger converts the reply sent through it to the typed envelope
+type envelopeMessenger struct {
+	port.TransportMessenger
+
+	started   time.Time
+	requestID string
+	provider  string
+	recorder  *envelope.Recorder
+}
+
+// Respond forwards the reply in the typed envelope
+func (e *envelopeMessenger) Respond(data []byte) error {
+	meta := envelope.Meta{
+		RequestID:  e.requestID,
+		Provider:   cmp.Or(e.recorder.Provider(), e.provider),
+		Cache:      e.recorder.Cache(),
+		DurationMS: time.Since(e.started).Milliseconds(),
+	}
+	return e.TransportMessenger.Respond(typedEnvelope(data, meta))
+}
+
+// Unwrap returns the wrapped messenger
+func (e *envelopeMessenger) Unwrap() port.TransportMessenger {
+	return e.TransportMessenger
+}
+
+// typedEnvelope converts a version 1 reply to the typed envelope. A reply
+// that isn't a version 1 envelope is the data of a successful one: the plain
+// text username of an email lookup becomes a JSON string.
+func typedEnvelope(reply []byte, meta envelope.Meta) []byte {
+	typed := envelope.Envelope{Version: envelope.Version, Meta: meta}
+
+	var members map[string]json.RawMessage
+	trimmed := bytes.TrimSpace(reply)
+	switch {
+	case len(trimmed) > 0 && trimmed[0] == '{' && json.Unmarshal(trimmed, &members) == nil && members["success"] != nil:
+		typed = typedFromLegacy(members, meta)
+	case json.Valid(trimmed) && len(trimmed) > 0:
+		typed.Success = true
+		typed.Data = trimmed
+	default:
+		typed.Success = true
+		typed.Data, _ = json.Marshal(string(reply))
+	}
+
+	converted, err := json.Marshal(typed)
+	if err != nil {
+		return reply
+	}
+	return converted
+}
+
+// typedFromLegacy builds the typed envelope of the members of a version 1
+// reply
+func typedFromLegacy(members map[string]json.RawMessage, meta envelope.Meta) envelope.Envelope {
+	str := func(name string) string {
+		var value string
+		_ = json.Unmarshal(members[name], &value)
+		return value
+	}
+	typed := envelope.Envelope{Version: envelope.Version, Meta: meta, Data: members["data"]}
+	_ = json.Unmarshal(members["success"], &typed.Success)
+
+	var stale bool
+	if _ = json.Unmarshal(members["stale"], &stale); stale {
+		typed.Meta.Cache = envelope.CacheStale
+	}
+
+	extra := make(map[string]json.RawMessage)
+	for name, value := range members {
+		if !legacyEnvelopeMembers[name] {
+			extra[name] = value
+		}
+	}
+	if len(typed.Data) == 0 && len(extra) > 0 {
+		typed.Data, _ = json.Marshal(extra)
+	}
+
+	message, errMessage := str("message"), str("error")
+	if typed.Success && errMessage == "" {
+		typed.Message = message
+		return typed
+	}
+	typed.Success = false
+	typed.Error = &envelope.Error{
+		Code:     cmp.Or(str("error_code"), envelope.CodeUnknown),
+		Message:  cmp.Or(errMessage, message, "unsuccessful reply"),
+		Language: str("error_language"),
+	}
+	if localized := str("error_message"); localized != typed.Error.Message {
+		typed.Error.LocalizedMessage = localized
+	}
+	if typed.Error.LocalizedMessage == "" {
+		typed.Error.Language = ""
+	}
+	return typed
+}
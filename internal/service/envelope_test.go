@@ -0,0 +1,100 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/envelope"
+)
+
+func TestTypedEnvelope(t *testing.T) {
+	versioned := func(version string) context.Context {
+		return envelope.Context(context.Background(), nats.Header{envelope.Header: []string{version}})
+	}
+	reply := func(ctx context.Context, defaultVersion int, handler port.HandlerFunc) (string, envelope.Envelope) {
+		msg := &repliedMessenger{}
+		Chain(handler, TypedEnvelope("auth0", defaultVersion)).Handle(ctx, msg)
+		var typed envelope.Envelope
+		_ = json.Unmarshal(msg.replied, &typed)
+		return string(msg.replied), typed
+	}
+	respond := func(data string) port.HandlerFunc {
+		return func(_ context.Context, msg port.TransportMessenger) { _ = msg.Respond([]byte(data)) }
+	}
+
+	t.Run("leaves the replies of version 1 callers alone", func(t *testing.T) {
+		raw, _ := reply(context.Background(), envelope.Legacy, respond(`{"success":true,"data":{"username":"jdoe"}}`))
+		assert.JSONEq(t, `{"success":true,"data":{"username":"jdoe"}}`, raw)
+		raw, _ = reply(versioned("1"), envelope.Version, respond("jdoe"))
+		assert.Equal(t, "jdoe", raw, "the header wins over the default version")
+	})
+
+	t.Run("converts a successful reply", func(t *testing.T) {
+		raw, typed := reply(versioned("2"), envelope.Legacy, respond(`{"success":true,"data":{"username":"jdoe"},"message":"updated"}`))
+		assert.Equal(t, envelope.Version, typed.Version)
+		assert.True(t, typed.Success)
+		assert.JSONEq(t, `{"username":"jdoe"}`, string(typed.Data))
+		assert.Equal(t, "updated", typed.Message)
+		assert.Nil(t, typed.Error)
+		assert.NotEmpty(t, typed.Meta.RequestID)
+		assert.Equal(t, "auth0", typed.Meta.Provider)
+		assert.NotContains(t, raw, `"error"`)
+	})
+
+	t.Run("converts an unsuccessful reply with its code", func(t *testing.T) {
+		_, typed := reply(versioned("2"), envelope.Legacy, respond(
+			`{"success":false,"error":"user not found","error_code":"user_not_found","error_message":"usuario no encontrado","error_language":"es"}`))
+		assert.False(t, typed.Success)
+		assert.Equal(t, &envelope.Error{Code: "user_not_found", Message: "user not found",
+			LocalizedMessage: "usuario no encontrado", Language: "es"}, typed.Error)
+
+		_, typed = reply(versioned("2"), envelope.Legacy, respond(`{"success":false,"error":"boom"}`))
+		assert.Equal(t, &envelope.Error{Code: envelope.CodeUnknown, Message: "boom"}, typed.Error)
+	})
+
+	t.Run("wraps the replies that are not envelopes as data", func(t *testing.T) {
+		_, typed := reply(context.Background(), envelope.Version, respond("jdoe"))
+		assert.True(t, typed.Success)
+		assert.JSONEq(t, `"jdoe"`, string(typed.Data))
+
+		_, typed = reply(context.Background(), envelope.Version, respond(`{"status":"ok"}`))
+		assert.JSONEq(t, `{"status":"ok"}`, string(typed.Data))
+	})
+
+	t.Run("moves the other members of a reply without data into data", func(t *testing.T) {
+		_, typed := reply(versioned("2"), envelope.Legacy, respond(`{"success":true,"valid":true,"scopes":["read"]}`))
+		assert.JSONEq(t, `{"valid":true,"scopes":["read"]}`, string(typed.Data))
+	})
+
+	t.Run("reports the cache outcome and the recorded provider", func(t *testing.T) {
+		_, typed := reply(versioned("2"), envelope.Legacy, func(ctx context.Context, msg port.TransportMessenger) {
+			envelope.RecordCache(ctx, envelope.CacheHit)
+			envelope.RecordProvider(ctx, "authelia")
+			_ = msg.Respond([]byte(`{"success":true,"data":{}}`))
+		})
+		assert.Equal(t, envelope.CacheHit, typed.Meta.Cache)
+		assert.Equal(t, "authelia", typed.Meta.Provider)
+
+		_, typed = reply(versioned("2"), envelope.Legacy, respond(`{"success":true,"data":{},"stale":true}`))
+		assert.Equal(t, envelope.CacheStale, typed.Meta.Cache)
+	})
+
+	t.Run("uses the trace ID as request ID", func(t *testing.T) {
+		traceID := trace.TraceID{1, 2, 3}
+		ctx := trace.ContextWithSpanContext(versioned("2"), trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID: traceID, SpanID: trace.SpanID{1},
+		}))
+		_, typed := reply(ctx, envelope.Legacy, respond(`{"success":true}`))
+		require.Equal(t, traceID.String(), typed.Meta.RequestID)
+	})
+}
@@ -0,0 +1,178 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/jwt"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/redaction"
+)
+
+// Kinds of grant a metadata field rule can require
+const (
+	fieldGrantScope      = "scope"
+	fieldGrantPermission = "permission"
+	fieldGrantRole       = "role"
+)
+
+// fieldUpdateForbidden is the error of an update writing restricted fields
+// its caller isn't allowed to
+const fieldUpdateForbidden = "field_update_forbidden"
+
+// MetadataFieldGrant is a scope or permission the caller's token must carry,
+// or a role the caller must hold
+type MetadataFieldGrant struct {
+	Kind  string
+	Value string
+}
+
+func (g MetadataFieldGrant) String() string {
+	return g.Kind + " " + g.Value
+}
+
+// MetadataFieldPolicy maps restricted metadata fields to the grants that
+// allow writing them; any one of a field's grants is enough. Fields without a
+// rule are writable by every caller allowed to update metadata.
+type MetadataFieldPolicy map[string][]MetadataFieldGrant
+
+// ParseMetadataFieldPolicy parses rules of the form
+// "field=kind:value|kind:value", e.g. "name=role:lf-staff|scope:write:legal_name"
+func ParseMetadataFieldPolicy(rules []string) (MetadataFieldPolicy, error) {
+	known := model.MetadataFieldNames()
+	policy := make(MetadataFieldPolicy, len(rules))
+	for _, rule := range rules {
+		field, grants, ok := strings.Cut(rule, "=")
+		field = strings.TrimSpace(field)
+		if !ok || field == "" {
+			return nil, fmt.Errorf("rule %q must be field=kind:value", rule)
+		}
+		if !slices.Contains(known, field) {
+			return nil, fmt.Errorf("rule %q names an unknown metadata field", rule)
+		}
+		for _, grant := range strings.Split(grants, "|") {
+			kind, value, _ := strings.Cut(strings.TrimSpace(grant), ":")
+			if value == "" || (kind != fieldGrantScope && kind != fieldGrantPermission && kind != fieldGrantRole) {
+				return nil, fmt.Errorf("rule %q: grant %q must be scope:, permission: or role: followed by a name", rule, grant)
+			}
+			policy[field] = append(policy[field], MetadataFieldGrant{Kind: kind, Value: value})
+		}
+	}
+	return policy, nil
+}
+
+// WithMetadataFieldPolicyForMessageHandler restricts who may update some
+// metadata fields. Role grants are looked up with the permission reader.
+func WithMetadataFieldPolicyForMessageHandler(policy MetadataFieldPolicy) MessageHandlerOrchestratorOption {
+	return func(m *messageHandlerOrchestrator) {
+		m.metadataFieldPolicy = policy
+	}
+}
+
+// fieldAuthorizationError is why the update of one field was denied
+type fieldAuthorizationError struct {
+	Field string `json:"field"`
+	Error string `json:"error"`
+}
+
+// fieldAuthorizationErrors is the data of a field_update_forbidden reply
+type fieldAuthorizationErrors struct {
+	Fields []fieldAuthorizationError `json:"fields"`
+}
+
+// authorizeMetadataFields checks the restricted fields the update writes
+// against the policy and returns those the caller may not write. The token
+// is only verified here when a restricted field is written; the provider
+// verifies it for every update anyway.
+func (m *messageHandlerOrchestrator) authorizeMetadataFields(ctx context.Context, user *model.User) []fieldAuthorizationError {
+	var restricted []string
+	for _, field := range user.UserMetadata.SetFields() {
+		if len(m.metadataFieldPolicy[field]) > 0 {
+			restricted = append(restricted, field)
+		}
+	}
+	if len(restricted) == 0 {
+		return nil
+	}
+
+	var denied []fieldAuthorizationError
+	claims, err := m.verifyAccessToken(ctx, user.Token, constants.UserUpdateMetadataRequiredScope)
+	if err != nil {
+		for _, field := range restricted {
+			denied = append(denied, fieldAuthorizationError{Field: field, Error: err.Error()})
+		}
+	} else {
+		roles := m.callerRoles(ctx, claims)
+		for _, field := range restricted {
+			grants := m.metadataFieldPolicy[field]
+			if !slices.ContainsFunc(grants, func(grant MetadataFieldGrant) bool { return grantHeld(grant, claims, roles) }) {
+				denied = append(denied, fieldAuthorizationError{Field: field, Error: "requires " + describeGrants(grants)})
+			}
+		}
+	}
+
+	if len(denied) > 0 {
+		fields := make([]string, 0, len(denied))
+		for _, d := range denied {
+			fields = append(fields, d.Field)
+		}
+		sub := ""
+		if claims != nil {
+			sub = claims.Subject
+		}
+		slog.WarnContext(ctx, "metadata field update denied",
+			"fields", fields,
+			"sub", redaction.Redact(sub),
+		)
+	}
+	return denied
+}
+
+// callerRoles returns a lookup of the roles held by the token's subject. It
+// only reaches the provider when a role is asked for, and holds nothing when
+// roles can't be read.
+func (m *messageHandlerOrchestrator) callerRoles(ctx context.Context, claims *jwt.Claims) func() *model.UserPermissions {
+	var (
+		permissions *model.UserPermissions
+		fetched     bool
+	)
+	return func() *model.UserPermissions {
+		if !fetched && m.permissionReader != nil {
+			// a failed lookup is logged by userPermissions and grants no role
+			permissions, _ = m.userPermissions(ctx, claims.Subject)
+		}
+		fetched = true
+		return permissions
+	}
+}
+
+func grantHeld(grant MetadataFieldGrant, claims *jwt.Claims, roles func() *model.UserPermissions) bool {
+	switch grant.Kind {
+	case fieldGrantScope:
+		return claims.HasScope(grant.Value)
+	case fieldGrantPermission:
+		return claims.HasPermission(grant.Value)
+	case fieldGrantRole:
+		return roles().HasRole(grant.Value)
+	}
+	return false
+}
+
+// describeGrants lists grants as "scope a, role b or role c"
+func describeGrants(grants []MetadataFieldGrant) string {
+	described := make([]string, len(grants))
+	for i, grant := range grants {
+		described[i] = grant.String()
+	}
+	if len(described) == 1 {
+		return described[0]
+	}
+	return strings.Join(described[:len(described)-1], ", ") + " or " + described[len(described)-1]
+}
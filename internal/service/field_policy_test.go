@@ -0,0 +1,116 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/jwt"
+)
+
+func TestParseMetadataFieldPolicy(t *testing.T) {
+	policy, err := ParseMetadataFieldPolicy([]string{"name=role:lf-staff|scope:write:legal_name", "organization=permission:manage:profiles"})
+	require.NoError(t, err)
+	assert.Equal(t, MetadataFieldPolicy{
+		"name":         {{Kind: "role", Value: "lf-staff"}, {Kind: "scope", Value: "write:legal_name"}},
+		"organization": {{Kind: "permission", Value: "manage:profiles"}},
+	}, policy)
+
+	for _, rule := range []string{"name", "nickname=role:staff", "name=group:staff", "name=role:"} {
+		_, err := ParseMetadataFieldPolicy([]string{rule})
+		assert.Error(t, err, rule)
+	}
+}
+
+func TestMessageHandlerOrchestrator_UpdateUser_FieldPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	token := func(t *testing.T, sub, scope string) string {
+		t.Helper()
+		token, err := jwt.GenerateTestAccessToken(sub, "https://test.any.com/", "https://test.any.com/api/v2/", scope, time.Hour)
+		require.NoError(t, err)
+		return token
+	}
+
+	policy, err := ParseMetadataFieldPolicy([]string{"name=role:lf-staff|scope:write:legal_name", "organization=scope:write:organization"})
+	require.NoError(t, err)
+
+	reader := &mockUserServiceReader{
+		metadataLookupFunc: func(ctx context.Context, input string) (*model.User, error) {
+			return &model.User{Token: input}, nil
+		},
+	}
+	permissions := &mockPermissionReader{permissions: map[string]*model.UserPermissions{
+		"auth0|staff": {Roles: []string{"lf-staff"}},
+		"auth0|jdoe":  {},
+	}}
+
+	type response struct {
+		Success bool                     `json:"success"`
+		Error   string                   `json:"error"`
+		Data    fieldAuthorizationErrors `json:"data"`
+	}
+	update := func(t *testing.T, token string, metadata map[string]string) (response, bool) {
+		t.Helper()
+		updated := false
+		writer := &mockUserServiceWriter{updateUserFunc: func(ctx context.Context, user *model.User) (*model.User, error) {
+			updated = true
+			return user, nil
+		}}
+		payload, _ := json.Marshal(map[string]any{"token": token, "user_metadata": metadata})
+		handler := NewMessageHandlerOrchestrator(
+			WithUserReaderForMessageHandler(reader),
+			WithUserWriterForMessageHandler(writer),
+			WithPermissionReaderForMessageHandler(permissions),
+			WithMetadataFieldPolicyForMessageHandler(policy),
+		)
+		result, err := handler.UpdateUser(ctx, &mockTransportMessenger{data: payload})
+		require.NoError(t, err)
+		var r response
+		require.NoError(t, json.Unmarshal(result, &r))
+		return r, updated
+	}
+
+	t.Run("unrestricted fields skip the policy", func(t *testing.T) {
+		r, updated := update(t, "opaque-token", map[string]string{"job_title": "Engineer"})
+		assert.True(t, r.Success)
+		assert.True(t, updated)
+	})
+
+	t.Run("role grants the field", func(t *testing.T) {
+		r, updated := update(t, token(t, "auth0|staff", "update:current_user_metadata"), map[string]string{"name": "Jane Doe"})
+		assert.True(t, r.Success, r.Error)
+		assert.True(t, updated)
+	})
+
+	t.Run("scope grants the field", func(t *testing.T) {
+		r, _ := update(t, token(t, "auth0|jdoe", "update:current_user_metadata write:legal_name"), map[string]string{"name": "Jane Doe"})
+		assert.True(t, r.Success, r.Error)
+	})
+
+	t.Run("denied fields reject the whole update", func(t *testing.T) {
+		r, updated := update(t, token(t, "auth0|jdoe", "update:current_user_metadata"),
+			map[string]string{"name": "Jane Doe", "organization": "Example", "city": "Paris"})
+		assert.False(t, r.Success)
+		assert.False(t, updated)
+		assert.Equal(t, fieldUpdateForbidden, r.Error)
+		assert.Equal(t, []fieldAuthorizationError{
+			{Field: "name", Error: "requires role lf-staff or scope write:legal_name"},
+			{Field: "organization", Error: "requires scope write:organization"},
+		}, r.Data.Fields)
+	})
+
+	t.Run("restricted fields need an access token", func(t *testing.T) {
+		r, updated := update(t, "opaque-token", map[string]string{"organization": "Example"})
+		assert.False(t, updated)
+		assert.Equal(t, []fieldAuthorizationError{{Field: "organization", Error: "auth_token must be an access token"}}, r.Data.Fields)
+	})
+}
@@ -0,0 +1,246 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/tenant"
+)
+
+const (
+	// hedgeWindow is how many recent latencies the hedge delay is derived from
+	hedgeWindow = 256
+	// hedgeMinSamples are needed before the observed percentile replaces the
+	// initial delay
+	hedgeMinSamples = 20
+	// hedgeInitialDelay is the hedge delay until enough latencies are observed
+	hedgeInitialDelay = 200 * time.Millisecond
+)
+
+// Outcomes of a read recorded by the hedging metrics
+const (
+	hedgeOutcomeNotHedged  = "not_hedged"
+	hedgeOutcomePrimaryWon = "primary_won"
+	hedgeOutcomeHedgeWon   = "hedge_won"
+	hedgeOutcomeCapped     = "capped"
+)
+
+// HedgingConfig configures hedged provider reads
+type HedgingConfig struct {
+	// Percentile of recent read latencies after which a read is hedged, in (0, 1)
+	Percentile float64
+	// MinDelay is the shortest wait before hedging, so a fast provider isn't
+	// sent every read twice
+	MinDelay time.Duration
+	// MaxPerSecond caps the hedges sent per second, protecting the provider
+	// rate limits when it slows down for everyone
+	MaxPerSecond float64
+}
+
+// HedgedUserReaderWriter wraps a user repository and hedges its GetUser and
+// SearchUser reads: when the provider hasn't answered within the configured
+// percentile of recent latencies, the same read is sent again and the first
+// answer is used. Every other call goes straight to the wrapped repository.
+type HedgedUserReaderWriter struct {
+	port.UserReaderWriter
+	config HedgingConfig
+
+	getUser    *latencyWindow
+	searchUser *latencyWindow
+	budget     *hedgeBudget
+
+	reads metric.Int64Counter
+}
+
+// GetUser gets the user, hedging the read when the provider is slow
+func (h *HedgedUserReaderWriter) GetUser(ctx context.Context, user *model.User) (*model.User, error) {
+	return hedge(ctx, h, h.getUser, "get_user", user, func(ctx context.Context, user *model.User) (*model.User, error) {
+		return h.UserReaderWriter.GetUser(ctx, user)
+	})
+}
+
+// SearchUser searches the user, hedging the read when the provider is slow
+func (h *HedgedUserReaderWriter) SearchUser(ctx context.Context, user *model.User, criteria string) (*model.User, error) {
+	return hedge(ctx, h, h.searchUser, "search_user", user, func(ctx context.Context, user *model.User) (*model.User, error) {
+		return h.UserReaderWriter.SearchUser(ctx, user, criteria)
+	})
+}
+
+// readResult is the answer of one of the reads of a hedged call
+type readResult struct {
+	user   *model.User
+	err    error
+	hedged bool
+}
+
+// hedge runs read, and runs it again on a copy of user when it hasn't
+// returned within the hedge delay of window, returning the first answer. The
+// other read is cancelled.
+func hedge(ctx context.Context, h *HedgedUserReaderWriter, window *latencyWindow, operation string, user *model.User,
+	read func(context.Context, *model.User) (*model.User, error)) (*model.User, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// buffered so the read that loses doesn't block once nobody listens
+	results := make(chan readResult, 2)
+	start := time.Now()
+	go func() {
+		found, err := read(ctx, user)
+		results <- readResult{user: found, err: err}
+	}()
+
+	delay := max(window.percentile(h.config.Percentile), h.config.MinDelay)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	outcome := hedgeOutcomeNotHedged
+	select {
+	case result := <-results:
+		window.record(time.Since(start))
+		h.recordRead(ctx, operation, outcome)
+		return result.user, result.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-timer.C:
+	}
+
+	// a hedge that can't answer before the caller's deadline only adds load
+	deadline, hasDeadline := ctx.Deadline()
+	if (hasDeadline && time.Until(deadline) < delay) || !h.budget.take(time.Now()) {
+		h.recordRead(ctx, operation, hedgeOutcomeCapped)
+		result := <-results
+		window.record(time.Since(start))
+		return result.user, result.err
+	}
+
+	var hedgeInput *model.User
+	if user != nil {
+		copied := *user
+		hedgeInput = &copied
+	}
+	go func() {
+		found, err := read(ctx, hedgeInput)
+		results <- readResult{user: found, err: err, hedged: true}
+	}()
+
+	result := <-results
+	// the primary's latency is what the percentile tracks; when the hedge
+	// wins it is at least the time taken so far
+	window.record(time.Since(start))
+	outcome = hedgeOutcomePrimaryWon
+	if result.hedged {
+		outcome = hedgeOutcomeHedgeWon
+	}
+	h.recordRead(ctx, operation, outcome)
+	return result.user, result.err
+}
+
+func (h *HedgedUserReaderWriter) recordRead(ctx context.Context, operation, outcome string) {
+	h.reads.Add(context.WithoutCancel(ctx), 1, tenant.Attributes(ctx,
+		attribute.String("operation", operation),
+		attribute.String("outcome", outcome),
+	))
+}
+
+// latencyWindow keeps the latest read latencies and the percentile asked of
+// them, recomputed every few records rather than on every read
+type latencyWindow struct {
+	mu        sync.Mutex
+	latencies [hedgeWindow]time.Duration
+	next      int
+	count     int
+
+	cachedPercentile float64
+	cachedAt         int
+	cached           time.Duration
+}
+
+func (w *latencyWindow) record(latency time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.latencies[w.next] = latency
+	w.next = (w.next + 1) % hedgeWindow
+	w.count++
+}
+
+// percentile returns the p percentile of the recorded latencies, or the
+// initial delay until enough are recorded
+func (w *latencyWindow) percentile(p float64) time.Duration {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.count < hedgeMinSamples {
+		return hedgeInitialDelay
+	}
+	if w.cachedPercentile == p && w.count-w.cachedAt < hedgeWindow/16 {
+		return w.cached
+	}
+
+	n := min(w.count, hedgeWindow)
+	sorted := slices.Clone(w.latencies[:n])
+	slices.Sort(sorted)
+	w.cached = sorted[min(int(float64(n)*p), n-1)]
+	w.cachedPercentile, w.cachedAt = p, w.count
+	return w.cached
+}
+
+// hedgeBudget is a token bucket of hedges, refilled at the configured rate
+type hedgeBudget struct {
+	mu     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func newHedgeBudget(perSecond float64) *hedgeBudget {
+	burst := max(perSecond, 1)
+	return &hedgeBudget{rate: perSecond, tokens: burst}
+}
+
+// take spends a hedge when one is available
+func (b *hedgeBudget) take(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.last.IsZero() {
+		b.tokens = min(b.tokens+now.Sub(b.last).Seconds()*b.rate, max(b.rate, 1))
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// NewHedgedUserReaderWriter wraps next with hedged reads. A Percentile
+// outside (0, 1) defaults to the p95, and a MaxPerSecond <= 0 to 10 hedges
+// per second.
+func NewHedgedUserReaderWriter(next port.UserReaderWriter, config HedgingConfig) *HedgedUserReaderWriter {
+	if config.Percentile <= 0 || config.Percentile >= 1 {
+		config.Percentile = 0.95
+	}
+	if config.MaxPerSecond <= 0 {
+		config.MaxPerSecond = 10
+	}
+
+	reads, _ := meter.Int64Counter("auth_service.provider.hedged_reads",
+		metric.WithDescription("Provider reads eligible for hedging, by whether they were hedged and which read answered"))
+
+	return &HedgedUserReaderWriter{
+		UserReaderWriter: next,
+		config:           config,
+		getUser:          &latencyWindow{},
+		searchUser:       &latencyWindow{},
+		budget:           newHedgeBudget(config.MaxPerSecond),
+		reads:            reads,
+	}
+}
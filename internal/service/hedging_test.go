@@ -0,0 +1,116 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+)
+
+// slowFirstReader answers its first GetUser after firstDelay and the others
+// immediately, counting the calls and cancellations it sees
+type slowFirstReader struct {
+	port.UserReaderWriter
+	firstDelay time.Duration
+	calls      atomic.Int32
+	cancelled  atomic.Int32
+}
+
+func (r *slowFirstReader) GetUser(ctx context.Context, user *model.User) (*model.User, error) {
+	if r.calls.Add(1) == 1 {
+		select {
+		case <-time.After(r.firstDelay):
+			return &model.User{UserID: user.UserID, Username: "primary"}, nil
+		case <-ctx.Done():
+			r.cancelled.Add(1)
+			return nil, ctx.Err()
+		}
+	}
+	return &model.User{UserID: user.UserID, Username: "hedge"}, nil
+}
+
+// warm fills the get_user window with fast latencies
+func warm(h *HedgedUserReaderWriter) {
+	for range hedgeMinSamples {
+		h.getUser.record(time.Millisecond)
+	}
+}
+
+func TestHedgedUserReaderWriter_GetUser(t *testing.T) {
+	t.Run("fast read is not hedged", func(t *testing.T) {
+		reader := &slowFirstReader{}
+		h := NewHedgedUserReaderWriter(reader, HedgingConfig{MinDelay: 50 * time.Millisecond})
+		warm(h)
+
+		user, err := h.GetUser(context.Background(), &model.User{UserID: "auth0|1"})
+		require.NoError(t, err)
+		assert.Equal(t, "primary", user.Username)
+		assert.Equal(t, int32(1), reader.calls.Load())
+	})
+
+	t.Run("slow read is hedged and the loser cancelled", func(t *testing.T) {
+		reader := &slowFirstReader{firstDelay: 5 * time.Second}
+		h := NewHedgedUserReaderWriter(reader, HedgingConfig{MinDelay: 10 * time.Millisecond})
+		warm(h)
+
+		input := &model.User{UserID: "auth0|1"}
+		user, err := h.GetUser(context.Background(), input)
+		require.NoError(t, err)
+		assert.Equal(t, "hedge", user.Username)
+		assert.Equal(t, int32(2), reader.calls.Load())
+		assert.Eventually(t, func() bool { return reader.cancelled.Load() == 1 }, time.Second, time.Millisecond)
+	})
+
+	t.Run("hedges beyond the cap wait for the primary", func(t *testing.T) {
+		reader := &slowFirstReader{firstDelay: 50 * time.Millisecond}
+		h := NewHedgedUserReaderWriter(reader, HedgingConfig{MinDelay: 10 * time.Millisecond, MaxPerSecond: 1})
+		warm(h)
+		require.True(t, h.budget.take(time.Now()))
+
+		user, err := h.GetUser(context.Background(), &model.User{UserID: "auth0|1"})
+		require.NoError(t, err)
+		assert.Equal(t, "primary", user.Username)
+		assert.Equal(t, int32(1), reader.calls.Load())
+	})
+
+	t.Run("no hedge past the caller's deadline", func(t *testing.T) {
+		reader := &slowFirstReader{firstDelay: 5 * time.Second}
+		h := NewHedgedUserReaderWriter(reader, HedgingConfig{MinDelay: 20 * time.Millisecond})
+		warm(h)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+		defer cancel()
+		_, err := h.GetUser(ctx, &model.User{UserID: "auth0|1"})
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Equal(t, int32(1), reader.calls.Load())
+	})
+}
+
+func TestLatencyWindow_Percentile(t *testing.T) {
+	w := &latencyWindow{}
+	assert.Equal(t, hedgeInitialDelay, w.percentile(0.95))
+
+	for i := 1; i <= 100; i++ {
+		w.record(time.Duration(i) * time.Millisecond)
+	}
+	assert.Equal(t, 96*time.Millisecond, w.percentile(0.95))
+	assert.Equal(t, 51*time.Millisecond, w.percentile(0.5))
+}
+
+func TestHedgeBudget_Take(t *testing.T) {
+	b := newHedgeBudget(2)
+	now := time.Now()
+	assert.True(t, b.take(now))
+	assert.True(t, b.take(now))
+	assert.False(t, b.take(now))
+	assert.True(t, b.take(now.Add(500*time.Millisecond)))
+}
@@ -0,0 +1,96 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/i18n"
+)
+
+// requestLocaleMember is the member of a JSON object payload naming the
+// languages the caller wants error messages in
+const requestLocaleMember = "locale"
+
+// LocalizeErrors returns the middleware adding the code of the error and its
+// message in the caller's language to unsuccessful replies. The error member
+// is left as it is, so callers matching on it keep working. The language is
+// negotiated from the locale member of the request, English when it's absent
+// or no catalog matches it; errors the catalog doesn't know are left alone.
+func LocalizeErrors(catalog *i18n.Catalog) Middleware {
+	return func(next port.Handler) port.Handler {
+		return port.HandlerFunc(func(ctx context.Context, msg port.TransportMessenger) {
+			next.Handle(ctx, &localizingMessenger{
+				TransportMessenger: msg,
+				catalog:            catalog,
+				locale:             requestLocale(msg.Data()),
+			})
+		})
+	}
+}
+
+// requestLocale returns the locale member of a JSON object payload
+func requestLocale(data []byte) string {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) == 0 || trimmed[0] != '{' {
+		return ""
+	}
+	var payload map[string]json.RawMessage
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return ""
+	}
+	var locale string
+	if err := json.Unmarshal(payload[requestLocaleMember], &locale); err != nil {
+		return ""
+	}
+	return locale
+}
+
+// localizingMessenger localizes the error of the reply sent through it
+type localizingMessenger struct {
+	port.TransportMessenger
+
+	catalog *i18n.Catalog
+	locale  string
+}
+
+// Respond forwards the reply with its error localized
+func (l *localizingMessenger) Respond(data []byte) error {
+	return l.TransportMessenger.Respond(l.localize(data))
+}
+
+// Unwrap returns the wrapped messenger
+func (l *localizingMessenger) Unwrap() port.TransportMessenger {
+	return l.TransportMessenger
+}
+
+func (l *localizingMessenger) localize(data []byte) []byte {
+	var reply struct {
+		Success bool   `json:"success"`
+		Error   string `json:"error"`
+	}
+	if err := json.Unmarshal(data, &reply); err != nil || reply.Success || reply.Error == "" {
+		return data
+	}
+	localized, ok := l.catalog.Localize(l.locale, reply.Error)
+	if !ok {
+		return data
+	}
+
+	// the other members are kept as they are
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return data
+	}
+	envelope["error_code"], _ = json.Marshal(localized.Code)
+	envelope["error_message"], _ = json.Marshal(localized.Message)
+	envelope["error_language"], _ = json.Marshal(localized.Language.String())
+	localizedData, err := json.Marshal(envelope)
+	if err != nil {
+		return data
+	}
+	return localizedData
+}
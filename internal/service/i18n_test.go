@@ -0,0 +1,60 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/i18n"
+)
+
+func TestLocalizeErrors(t *testing.T) {
+	catalog, err := i18n.Load()
+	require.NoError(t, err)
+
+	handle := func(t *testing.T, request, reply string) map[string]any {
+		t.Helper()
+		msg := &repliedMessenger{data: []byte(request)}
+		handler := port.HandlerFunc(func(_ context.Context, msg port.TransportMessenger) {
+			_ = msg.Respond([]byte(reply))
+		})
+		Chain(handler, LocalizeErrors(catalog)).Handle(context.Background(), msg)
+		var response map[string]any
+		require.NoError(t, json.Unmarshal(msg.replied, &response))
+		return response
+	}
+
+	t.Run("adds the code and message in the requested language", func(t *testing.T) {
+		response := handle(t, `{"auth_token":"token","locale":"de-AT"}`,
+			`{"success":false,"error":"user not found","data":{"id":"1"}}`)
+		assert.Equal(t, "user not found", response["error"])
+		assert.Equal(t, "user_not_found", response["error_code"])
+		assert.Equal(t, "Der Benutzer wurde nicht gefunden.", response["error_message"])
+		assert.Equal(t, "de", response["error_language"])
+		assert.Equal(t, map[string]any{"id": "1"}, response["data"])
+	})
+
+	t.Run("falls back to English for plain payloads", func(t *testing.T) {
+		response := handle(t, `auth0|123`, `{"success":false,"error":"user not found"}`)
+		assert.Equal(t, "The user was not found.", response["error_message"])
+		assert.Equal(t, "en", response["error_language"])
+	})
+
+	t.Run("leaves successful replies and unknown errors alone", func(t *testing.T) {
+		msg := &repliedMessenger{data: []byte(`{"locale":"fr"}`)}
+		for _, reply := range []string{`{"success":true,"message":"ok"}`, `{"success":false,"error":"something new"}`, `not json`} {
+			handler := port.HandlerFunc(func(_ context.Context, msg port.TransportMessenger) {
+				_ = msg.Respond([]byte(reply))
+			})
+			Chain(handler, LocalizeErrors(catalog)).Handle(context.Background(), msg)
+			assert.Equal(t, reply, string(msg.replied))
+		}
+	})
+}
@@ -0,0 +1,227 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/idempotency"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/tenant"
+)
+
+const (
+	// idempotencyPendingTimeout is how long a first attempt may hold its key.
+	// A pending request older than this is taken to belong to a replica that
+	// stopped while handling it, and the retry handles it again.
+	idempotencyPendingTimeout = time.Minute
+	// idempotencyStoreTimeout bounds the store calls made after the handler
+	// replied, when the request context may be done
+	idempotencyStoreTimeout = 5 * time.Second
+)
+
+// Replies to requests the idempotency middleware doesn't hand to the handler
+const (
+	errIdempotencyKeyInvalid  = "invalid idempotency key"
+	errIdempotencyKeyReused   = "idempotency key reused for a different request"
+	errIdempotencyKeyInFlight = "request with this idempotency key in progress"
+)
+
+// Outcomes of a request recorded by the idempotency metrics
+const (
+	idempotencyOutcomeStored      = "stored"
+	idempotencyOutcomeReplayed    = "replayed"
+	idempotencyOutcomeReused      = "reused"
+	idempotencyOutcomeInFlight    = "in_flight"
+	idempotencyOutcomeNotStored   = "not_stored"
+	idempotencyOutcomeUnavailable = "unavailable"
+)
+
+// Idempotency returns the middleware that makes the mutations of the
+// subjects writes reports idempotent. A mutation sent with an idempotency
+// key is handled once: its successful reply is stored, and a retry with the
+// same key and payload gets the stored reply without reaching the handler.
+// Unsuccessful replies aren't stored, so a failed mutation can be retried.
+//
+// A retry arriving while the first attempt is handled is refused rather than
+// handled concurrently, and a key reused with another payload is refused.
+// When the store can't be reached the request is handled as if it had no key.
+func Idempotency(store port.IdempotencyStore, writes func(subject string) bool) Middleware {
+	requests, _ := meter.Int64Counter("auth_service.idempotency.requests",
+		metric.WithDescription("Mutations sent with an idempotency key, by subject and outcome"))
+
+	return func(next port.Handler) port.Handler {
+		return port.HandlerFunc(func(ctx context.Context, msg port.TransportMessenger) {
+			if !writes(msg.Subject()) {
+				next.Handle(ctx, msg)
+				return
+			}
+			key := requestIdempotencyKey(ctx, msg.Data())
+			if key == "" {
+				next.Handle(ctx, msg)
+				return
+			}
+			if len(key) > idempotency.MaxKeyLength {
+				respondError(ctx, msg, errIdempotencyKeyInvalid)
+				return
+			}
+
+			record := func(outcome string) {
+				requests.Add(context.WithoutCancel(ctx), 1, tenant.Attributes(ctx,
+					attribute.String("subject", msg.Subject()),
+					attribute.String("outcome", outcome),
+				))
+			}
+
+			request := &model.IdempotentRequest{
+				Key:         key,
+				Subject:     msg.Subject(),
+				RequestHash: payloadSignature(msg.Subject(), msg.Data()),
+				Pending:     true,
+				CreatedAt:   time.Now().UTC(),
+			}
+			stored, err := reserveIdempotencyKey(ctx, store, request)
+			if err != nil {
+				slog.WarnContext(ctx, "idempotency store unavailable, handling request without it",
+					"subject", msg.Subject(), "error", err)
+				record(idempotencyOutcomeUnavailable)
+				next.Handle(ctx, msg)
+				return
+			}
+			if stored != nil {
+				switch {
+				case stored.RequestHash != request.RequestHash:
+					record(idempotencyOutcomeReused)
+					respondError(ctx, msg, errIdempotencyKeyReused)
+				case stored.Pending:
+					record(idempotencyOutcomeInFlight)
+					respondError(ctx, msg, errIdempotencyKeyInFlight)
+				default:
+					record(idempotencyOutcomeReplayed)
+					if err := msg.Respond(stored.Response); err != nil {
+						slog.ErrorContext(ctx, "failed to respond to request", "error", err)
+					}
+				}
+				return
+			}
+
+			recorder := &replyRecorder{TransportMessenger: msg}
+			// the key is released even when the handler panics, so the retry
+			// isn't refused until the pending timeout
+			completed := false
+			defer func() {
+				if completed {
+					return
+				}
+				storeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), idempotencyStoreTimeout)
+				defer cancel()
+				if err := store.Release(storeCtx, request); err != nil {
+					slog.WarnContext(ctx, "failed to release idempotency key", "subject", msg.Subject(), "error", err)
+				}
+				record(idempotencyOutcomeNotStored)
+			}()
+			next.Handle(ctx, recorder)
+
+			response, ok := recorder.reply()
+			if !ok || !successfulReply(response) {
+				return
+			}
+			request.Pending = false
+			request.Response = response
+			storeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), idempotencyStoreTimeout)
+			defer cancel()
+			if err := store.Complete(storeCtx, request); err != nil {
+				slog.WarnContext(ctx, "failed to store idempotent reply", "subject", msg.Subject(), "error", err)
+				return
+			}
+			completed = true
+			record(idempotencyOutcomeStored)
+		})
+	}
+}
+
+// reserveIdempotencyKey reserves the key of request, taking over the key of
+// a pending request abandoned past the pending timeout
+func reserveIdempotencyKey(ctx context.Context, store port.IdempotencyStore, request *model.IdempotentRequest) (*model.IdempotentRequest, error) {
+	stored, err := store.Reserve(ctx, request)
+	if err != nil || stored == nil || !stored.Pending || stored.RequestHash != request.RequestHash ||
+		time.Since(stored.CreatedAt) < idempotencyPendingTimeout {
+		return stored, err
+	}
+	if err := store.Release(ctx, stored); err != nil {
+		return nil, err
+	}
+	return store.Reserve(ctx, request)
+}
+
+// requestIdempotencyKey returns the idempotency key of a request: the one of
+// its header, else the idempotency_key member of a JSON object payload
+func requestIdempotencyKey(ctx context.Context, data []byte) string {
+	if key := idempotency.KeyFromContext(ctx); key != "" {
+		return key
+	}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) == 0 || trimmed[0] != '{' {
+		return ""
+	}
+	var payload map[string]json.RawMessage
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return ""
+	}
+	var key string
+	if err := json.Unmarshal(payload[idempotency.Member], &key); err != nil {
+		return ""
+	}
+	return key
+}
+
+// successfulReply reports whether a reply is a successful envelope
+func successfulReply(response []byte) bool {
+	var envelope struct {
+		Success bool   `json:"success"`
+		Error   string `json:"error"`
+	}
+	if err := json.Unmarshal(response, &envelope); err != nil {
+		return false
+	}
+	return envelope.Success && envelope.Error == ""
+}
+
+// replyRecorder forwards the reply sent through the messenger and keeps a
+// copy of it
+type replyRecorder struct {
+	port.TransportMessenger
+
+	mu       sync.Mutex
+	response []byte
+	replied  bool
+}
+
+// Respond records the reply and forwards it
+func (r *replyRecorder) Respond(data []byte) error {
+	r.mu.Lock()
+	r.response = bytes.Clone(data)
+	r.replied = true
+	r.mu.Unlock()
+	return r.TransportMessenger.Respond(data)
+}
+
+// Unwrap returns the wrapped messenger
+func (r *replyRecorder) Unwrap() port.TransportMessenger {
+	return r.TransportMessenger
+}
+
+func (r *replyRecorder) reply() ([]byte, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.response, r.replied
+}
@@ -0,0 +1,166 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/idempotency"
+)
+
+// memoryIdempotencyStore is an in-memory port.IdempotencyStore
+type memoryIdempotencyStore struct {
+	mu       sync.Mutex
+	requests map[string]*model.IdempotentRequest
+	err      error
+}
+
+func newMemoryIdempotencyStore() *memoryIdempotencyStore {
+	return &memoryIdempotencyStore{requests: make(map[string]*model.IdempotentRequest)}
+}
+
+func (s *memoryIdempotencyStore) Reserve(_ context.Context, request *model.IdempotentRequest) (*model.IdempotentRequest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return nil, s.err
+	}
+	key := request.Subject + "\n" + request.Key
+	if stored, ok := s.requests[key]; ok {
+		copied := *stored
+		return &copied, nil
+	}
+	copied := *request
+	s.requests[key] = &copied
+	return nil, nil
+}
+
+func (s *memoryIdempotencyStore) Complete(_ context.Context, request *model.IdempotentRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	copied := *request
+	s.requests[request.Subject+"\n"+request.Key] = &copied
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Release(_ context.Context, request *model.IdempotentRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.requests, request.Subject+"\n"+request.Key)
+	return nil
+}
+
+func TestIdempotency(t *testing.T) {
+	const update = constants.UserMetadataUpdateSubject
+	writes := func(subject string) bool { return subject == update }
+
+	// newHandler returns a handler counting its calls and replying reply
+	newHandler := func(store port.IdempotencyStore, reply string) (port.Handler, *int) {
+		calls := 0
+		handler := Chain(port.HandlerFunc(func(_ context.Context, msg port.TransportMessenger) {
+			calls++
+			_ = msg.Respond([]byte(strings.ReplaceAll(reply, "N", string(rune('0'+calls)))))
+		}), Idempotency(store, writes))
+		return handler, &calls
+	}
+	send := func(ctx context.Context, handler port.Handler, subject, payload string) string {
+		msg := &subjectMessenger{repliedMessenger: repliedMessenger{data: []byte(payload)}, subject: subject}
+		handler.Handle(ctx, msg)
+		return string(msg.replied)
+	}
+	ctx := context.Background()
+	payload := `{"token":"t","user_metadata":{"name":"Zephyr"},"idempotency_key":"k-1"}`
+
+	t.Run("retry replays the first reply", func(t *testing.T) {
+		handler, calls := newHandler(newMemoryIdempotencyStore(), `{"success":true,"data":{"attempt":N}}`)
+		first := send(ctx, handler, update, payload)
+		assert.Equal(t, `{"success":true,"data":{"attempt":1}}`, first)
+		assert.Equal(t, first, send(ctx, handler, update, payload))
+		assert.Equal(t, 1, *calls)
+	})
+
+	t.Run("header key", func(t *testing.T) {
+		handler, calls := newHandler(newMemoryIdempotencyStore(), `{"success":true,"data":{"attempt":N}}`)
+		keyed := idempotency.WithKey(ctx, "k-header")
+		plain := `{"token":"t","user_metadata":{"name":"Zephyr"}}`
+		first := send(keyed, handler, update, plain)
+		assert.Equal(t, first, send(keyed, handler, update, plain))
+		assert.Equal(t, 1, *calls)
+	})
+
+	t.Run("requests without a key and reads are handled every time", func(t *testing.T) {
+		handler, calls := newHandler(newMemoryIdempotencyStore(), `{"success":true}`)
+		send(ctx, handler, update, `{"token":"t"}`)
+		send(ctx, handler, update, `{"token":"t"}`)
+		send(ctx, handler, constants.UserMetadataReadSubject, payload)
+		send(ctx, handler, constants.UserMetadataReadSubject, payload)
+		assert.Equal(t, 4, *calls)
+	})
+
+	t.Run("unsuccessful replies are not stored", func(t *testing.T) {
+		store := newMemoryIdempotencyStore()
+		handler, calls := newHandler(store, `{"success":false,"error":"provider unavailable"}`)
+		send(ctx, handler, update, payload)
+		send(ctx, handler, update, payload)
+		assert.Equal(t, 2, *calls)
+		assert.Empty(t, store.requests, "the key is released")
+	})
+
+	t.Run("key reused for another payload", func(t *testing.T) {
+		handler, calls := newHandler(newMemoryIdempotencyStore(), `{"success":true}`)
+		send(ctx, handler, update, payload)
+		reply := send(ctx, handler, update, strings.Replace(payload, "Zephyr", "Zara", 1))
+		assert.JSONEq(t, `{"success":false,"error":"idempotency key reused for a different request"}`, reply)
+		assert.Equal(t, 1, *calls)
+	})
+
+	t.Run("retry of a request in progress", func(t *testing.T) {
+		store := newMemoryIdempotencyStore()
+		_, err := store.Reserve(ctx, &model.IdempotentRequest{Key: "k-1", Subject: update,
+			RequestHash: payloadSignature(update, []byte(payload)), Pending: true, CreatedAt: time.Now()})
+		require.NoError(t, err)
+		handler, calls := newHandler(store, `{"success":true}`)
+		reply := send(ctx, handler, update, payload)
+		assert.JSONEq(t, `{"success":false,"error":"request with this idempotency key in progress"}`, reply)
+		assert.Zero(t, *calls)
+	})
+
+	t.Run("abandoned pending request is taken over", func(t *testing.T) {
+		store := newMemoryIdempotencyStore()
+		_, err := store.Reserve(ctx, &model.IdempotentRequest{Key: "k-1", Subject: update,
+			RequestHash: payloadSignature(update, []byte(payload)), Pending: true,
+			CreatedAt: time.Now().Add(-2 * idempotencyPendingTimeout)})
+		require.NoError(t, err)
+		handler, calls := newHandler(store, `{"success":true}`)
+		assert.JSONEq(t, `{"success":true}`, send(ctx, handler, update, payload))
+		assert.Equal(t, 1, *calls)
+	})
+
+	t.Run("store unavailable", func(t *testing.T) {
+		store := newMemoryIdempotencyStore()
+		store.err = errors.New("kv unavailable")
+		handler, calls := newHandler(store, `{"success":true}`)
+		send(ctx, handler, update, payload)
+		send(ctx, handler, update, payload)
+		assert.Equal(t, 2, *calls)
+	})
+
+	t.Run("key too long", func(t *testing.T) {
+		handler, calls := newHandler(newMemoryIdempotencyStore(), `{"success":true}`)
+		reply := send(idempotency.WithKey(ctx, strings.Repeat("k", idempotency.MaxKeyLength+1)), handler, update, payload)
+		assert.JSONEq(t, `{"success":false,"error":"invalid idempotency key"}`, reply)
+		assert.Zero(t, *calls)
+	})
+}
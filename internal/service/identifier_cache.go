@@ -0,0 +1,47 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/cache"
+)
+
+// identifierCache remembers username <-> sub mappings resolved through the
+// user reader. A resolved pair is stored in both directions, so a lookup one
+// way also answers the reverse lookup. A nil cache caches nothing.
+type identifierCache struct {
+	subs      *cache.Cache[string, string]
+	usernames *cache.Cache[string, string]
+}
+
+func (c *identifierCache) sub(username string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	return c.subs.Get(username)
+}
+
+func (c *identifierCache) username(sub string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	return c.usernames.Get(sub)
+}
+
+func (c *identifierCache) store(username, sub string) {
+	if c == nil || username == "" || sub == "" {
+		return
+	}
+	c.subs.Set(username, sub)
+	c.usernames.Set(sub, username)
+}
+
+func newIdentifierCache(ttl time.Duration, maxEntries int) *identifierCache {
+	return &identifierCache{
+		subs:      cache.New[string, string](ttl, maxEntries),
+		usernames: cache.New[string, string](ttl, maxEntries),
+	}
+}
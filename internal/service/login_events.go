@@ -0,0 +1,71 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/redaction"
+)
+
+// loginEventIngester records login activity and republishes normalized
+// login events for downstream analytics.
+type loginEventIngester struct {
+	activity       port.UserActivityStore
+	eventPublisher port.EventPublisher
+}
+
+// IngestLoginEvents processes a batch of login events. Activity updates are
+// best effort; a publish failure is returned so the provider can redeliver
+// the batch (consumers should de-duplicate on event_id).
+func (l *loginEventIngester) IngestLoginEvents(ctx context.Context, events []model.LoginEvent) error {
+	var publishFailures int
+	for _, event := range events {
+		if l.activity != nil {
+			if err := l.activity.RecordLogin(ctx, event); err != nil {
+				slog.WarnContext(ctx, "failed to record login activity",
+					"error", err,
+					"user_id", redaction.Redact(event.UserID),
+				)
+			}
+		}
+
+		if l.eventPublisher == nil {
+			continue
+		}
+		eventJSON, err := json.Marshal(event)
+		if err != nil {
+			slog.WarnContext(ctx, "failed to marshal login event", "error", err, "event_id", event.EventID)
+			continue
+		}
+		if err := l.eventPublisher.Publish(ctx, constants.LoginEventsSubject, eventJSON); err != nil {
+			slog.WarnContext(ctx, "failed to publish login event",
+				"error", err,
+				"event_id", event.EventID,
+				"type", event.Type,
+			)
+			publishFailures++
+		}
+	}
+
+	if publishFailures > 0 {
+		return errs.NewServiceUnavailable("failed to publish login events")
+	}
+	return nil
+}
+
+// NewLoginEventIngester creates a login event ingester. Either dependency may
+// be nil to disable that side of the processing.
+func NewLoginEventIngester(activity port.UserActivityStore, eventPublisher port.EventPublisher) port.LoginEventIngester {
+	return &loginEventIngester{
+		activity:       activity,
+		eventPublisher: eventPublisher,
+	}
+}
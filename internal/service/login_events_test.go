@@ -0,0 +1,88 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+)
+
+// mockActivityStore is a mock implementation of port.UserActivityStore for testing
+type mockActivityStore struct {
+	recorded []model.LoginEvent
+}
+
+func (m *mockActivityStore) RecordLogin(ctx context.Context, event model.LoginEvent) error {
+	m.recorded = append(m.recorded, event)
+	return nil
+}
+
+func (m *mockActivityStore) GetActivity(ctx context.Context, userID string) (*model.UserActivity, bool) {
+	return nil, false
+}
+
+func TestLoginEventIngester_IngestLoginEvents(t *testing.T) {
+	ctx := context.Background()
+	events := []model.LoginEvent{
+		{EventID: "1", Type: model.LoginEventTypeSuccess, UserID: "auth0|a", Timestamp: time.Now().UTC()},
+		{EventID: "2", Type: model.LoginEventTypeBlocked, Username: "someone", Timestamp: time.Now().UTC()},
+	}
+
+	tests := []struct {
+		name        string
+		publishFunc func(ctx context.Context, subject string, data []byte) error
+		wantErr     bool
+	}{
+		{
+			name: "all events recorded and republished",
+		},
+		{
+			name: "publish failure is returned for redelivery",
+			publishFunc: func(ctx context.Context, subject string, data []byte) error {
+				return errors.New("nats down")
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			activity := &mockActivityStore{}
+			publisher := &mockEventPublisher{publishFunc: tt.publishFunc}
+			ingester := NewLoginEventIngester(activity, publisher)
+
+			err := ingester.IngestLoginEvents(ctx, events)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Len(t, activity.recorded, len(events))
+			require.Len(t, publisher.calls, len(events))
+			for i, call := range publisher.calls {
+				assert.Equal(t, constants.LoginEventsSubject, call.Subject)
+				var got model.LoginEvent
+				require.NoError(t, json.Unmarshal(call.Data, &got))
+				assert.Equal(t, events[i].EventID, got.EventID)
+				assert.Equal(t, events[i].Type, got.Type)
+			}
+		})
+	}
+}
+
+func TestLoginEventIngester_NilDependencies(t *testing.T) {
+	ingester := NewLoginEventIngester(nil, nil)
+	err := ingester.IngestLoginEvents(context.Background(), []model.LoginEvent{{EventID: "1", Type: model.LoginEventTypeSuccess}})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,131 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/cache"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+)
+
+// lookupSnapshotDocument is the saved form of the identifier cache
+type lookupSnapshotDocument struct {
+	SavedAt  time.Time            `json:"saved_at"`
+	Mappings []lookupSnapshotPair `json:"mappings"`
+}
+
+// lookupSnapshotPair is a cached username <-> sub pair and its expiry
+type lookupSnapshotPair struct {
+	Username  string    `json:"username"`
+	Sub       string    `json:"sub"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// LookupSnapshot saves the identifier cache of the message handler to a
+// store and restores it at startup, so a restarted replica answers the
+// lookups its predecessor cached without asking the identity provider again.
+// Restored pairs keep their expiry: a restart never serves a pair longer
+// than the cache TTL allows.
+type LookupSnapshot struct {
+	store      port.SnapshotStore
+	maxEntries int
+	// cache is the identifier cache of the message handler it is given to,
+	// nil when that one caches nothing
+	cache *identifierCache
+}
+
+// Restore loads the saved snapshot into the identifier cache and returns the
+// number of pairs restored
+func (s *LookupSnapshot) Restore(ctx context.Context) (int, error) {
+	if s.cache == nil {
+		return 0, nil
+	}
+	data, err := s.store.LoadSnapshot(ctx)
+	if err != nil || data == nil {
+		return 0, err
+	}
+	var document lookupSnapshotDocument
+	if err := json.Unmarshal(data, &document); err != nil {
+		return 0, errs.NewUnexpected("failed to decode the lookup cache snapshot", err)
+	}
+
+	subs := make([]cache.Entry[string, string], 0, len(document.Mappings))
+	usernames := make([]cache.Entry[string, string], 0, len(document.Mappings))
+	for _, pair := range document.Mappings {
+		if pair.Username == "" || pair.Sub == "" {
+			continue
+		}
+		subs = append(subs, cache.Entry[string, string]{Key: pair.Username, Value: pair.Sub, ExpiresAt: pair.ExpiresAt})
+		usernames = append(usernames, cache.Entry[string, string]{Key: pair.Sub, Value: pair.Username, ExpiresAt: pair.ExpiresAt})
+	}
+	restored := max(s.cache.subs.Restore(subs), s.cache.usernames.Restore(usernames))
+	slog.DebugContext(ctx, "lookup cache snapshot restored",
+		"restored", restored,
+		"saved", len(document.Mappings),
+		"saved_at", document.SavedAt,
+	)
+	return restored, nil
+}
+
+// Save replaces the saved snapshot with the pairs of the identifier cache,
+// those expiring last when there are more than the maximum; it satisfies
+// scheduler.Job. An empty cache doesn't replace the saved snapshot.
+func (s *LookupSnapshot) Save(ctx context.Context) error {
+	if s.cache == nil {
+		return nil
+	}
+	pairs := s.cache.pairs()
+	if len(pairs) == 0 {
+		return nil
+	}
+	slices.SortFunc(pairs, func(a, b lookupSnapshotPair) int {
+		return cmp.Or(b.ExpiresAt.Compare(a.ExpiresAt), cmp.Compare(a.Username, b.Username))
+	})
+	if s.maxEntries > 0 && len(pairs) > s.maxEntries {
+		pairs = pairs[:s.maxEntries]
+	}
+
+	data, err := json.Marshal(lookupSnapshotDocument{SavedAt: time.Now().UTC(), Mappings: pairs})
+	if err != nil {
+		return errs.NewUnexpected("failed to encode the lookup cache snapshot", err)
+	}
+	return s.store.SaveSnapshot(ctx, data)
+}
+
+// pairs returns the cached pairs of both directions, once each, with the
+// later of their expiries
+func (c *identifierCache) pairs() []lookupSnapshotPair {
+	type key struct{ username, sub string }
+	expiries := make(map[key]time.Time)
+	for _, e := range c.subs.Entries() {
+		expiries[key{e.Key, e.Value}] = e.ExpiresAt
+	}
+	for _, e := range c.usernames.Entries() {
+		k := key{e.Value, e.Key}
+		if e.ExpiresAt.After(expiries[k]) {
+			expiries[k] = e.ExpiresAt
+		}
+	}
+
+	pairs := make([]lookupSnapshotPair, 0, len(expiries))
+	for k, expiresAt := range expiries {
+		pairs = append(pairs, lookupSnapshotPair{Username: k.username, Sub: k.sub, ExpiresAt: expiresAt})
+	}
+	return pairs
+}
+
+// NewLookupSnapshot returns a snapshot of the identifier cache kept in
+// store, holding up to maxEntries pairs; maxEntries <= 0 means all of them.
+// It is given to the message handler with
+// WithLookupSnapshotForMessageHandler.
+func NewLookupSnapshot(store port.SnapshotStore, maxEntries int) *LookupSnapshot {
+	return &LookupSnapshot{store: store, maxEntries: maxEntries}
+}
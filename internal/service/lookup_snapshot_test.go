@@ -0,0 +1,112 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memorySnapshotStore keeps a snapshot in memory
+type memorySnapshotStore struct {
+	snapshot []byte
+	saves    int
+}
+
+func (s *memorySnapshotStore) LoadSnapshot(context.Context) ([]byte, error) { return s.snapshot, nil }
+
+func (s *memorySnapshotStore) SaveSnapshot(_ context.Context, snapshot []byte) error {
+	s.snapshot = snapshot
+	s.saves++
+	return nil
+}
+
+func TestLookupSnapshot(t *testing.T) {
+	ctx := context.Background()
+	newOrchestrator := func(snapshot *LookupSnapshot) *messageHandlerOrchestrator {
+		return NewMessageHandlerOrchestrator(
+			WithIdentifierCacheForMessageHandler(time.Minute, 100),
+			WithLookupSnapshotForMessageHandler(snapshot),
+		).(*messageHandlerOrchestrator)
+	}
+
+	t.Run("restores the pairs of the previous replica", func(t *testing.T) {
+		store := &memorySnapshotStore{}
+		before := newOrchestrator(NewLookupSnapshot(store, 0))
+		before.identifiers.store("jdoe", "auth0|1")
+		before.identifiers.store("asmith", "auth0|2")
+		require.NoError(t, before.lookupSnapshot.Save(ctx))
+
+		after := newOrchestrator(NewLookupSnapshot(store, 0))
+		restored, err := after.lookupSnapshot.Restore(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 2, restored)
+		sub, ok := after.identifiers.sub("jdoe")
+		assert.True(t, ok)
+		assert.Equal(t, "auth0|1", sub)
+		username, ok := after.identifiers.username("auth0|2")
+		assert.True(t, ok)
+		assert.Equal(t, "asmith", username)
+	})
+
+	t.Run("skips expired pairs", func(t *testing.T) {
+		store := &memorySnapshotStore{}
+		store.snapshot, _ = json.Marshal(lookupSnapshotDocument{Mappings: []lookupSnapshotPair{
+			{Username: "jdoe", Sub: "auth0|1", ExpiresAt: time.Now().Add(-time.Second)},
+			{Username: "asmith", Sub: "auth0|2", ExpiresAt: time.Now().Add(time.Minute)},
+		}})
+		after := newOrchestrator(NewLookupSnapshot(store, 0))
+		restored, err := after.lookupSnapshot.Restore(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, restored)
+		_, ok := after.identifiers.sub("jdoe")
+		assert.False(t, ok)
+	})
+
+	t.Run("keeps the pairs expiring last", func(t *testing.T) {
+		store := &memorySnapshotStore{}
+		before := newOrchestrator(NewLookupSnapshot(store, 2))
+		for i := range 3 {
+			before.identifiers.store(fmt.Sprintf("user%d", i), fmt.Sprintf("auth0|%d", i))
+			time.Sleep(time.Millisecond)
+		}
+		require.NoError(t, before.lookupSnapshot.Save(ctx))
+
+		var document lookupSnapshotDocument
+		require.NoError(t, json.Unmarshal(store.snapshot, &document))
+		require.Len(t, document.Mappings, 2)
+		assert.Equal(t, "user2", document.Mappings[0].Username)
+		assert.Equal(t, "user1", document.Mappings[1].Username)
+	})
+
+	t.Run("doesn't replace the snapshot with an empty cache", func(t *testing.T) {
+		store := &memorySnapshotStore{snapshot: []byte(`{"mappings":[]}`)}
+		require.NoError(t, newOrchestrator(NewLookupSnapshot(store, 0)).lookupSnapshot.Save(ctx))
+		assert.Zero(t, store.saves)
+	})
+
+	t.Run("does nothing without a cache", func(t *testing.T) {
+		store := &memorySnapshotStore{snapshot: []byte(`not json`)}
+		m := NewMessageHandlerOrchestrator(
+			WithIdentifierCacheForMessageHandler(0, 0),
+			WithLookupSnapshotForMessageHandler(NewLookupSnapshot(store, 0)),
+		).(*messageHandlerOrchestrator)
+		restored, err := m.lookupSnapshot.Restore(ctx)
+		require.NoError(t, err)
+		assert.Zero(t, restored)
+		require.NoError(t, m.lookupSnapshot.Save(ctx))
+	})
+
+	t.Run("reports an unreadable snapshot", func(t *testing.T) {
+		store := &memorySnapshotStore{snapshot: []byte(`not json`)}
+		_, err := newOrchestrator(NewLookupSnapshot(store, 0)).lookupSnapshot.Restore(ctx)
+		assert.Error(t, err)
+	})
+}
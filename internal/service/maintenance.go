@@ -0,0 +1,171 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/redaction"
+)
+
+// errReadOnlyMode is the reply to a write refused in read-only mode
+const errReadOnlyMode = "read_only_mode"
+
+// MaintenanceMode is the read-only switch of a replica, e.g. for the length
+// of a migration: while it is on, writes are refused and reads go on
+type MaintenanceMode struct {
+	mu    sync.RWMutex
+	state model.MaintenanceState
+	store port.MaintenanceStore
+}
+
+// NewMaintenanceMode returns a switch starting in read-only mode when
+// readOnly is set
+func NewMaintenanceMode(readOnly bool, reason string) *MaintenanceMode {
+	m := &MaintenanceMode{}
+	m.Set(readOnly, reason)
+	return m
+}
+
+// State returns the current mode
+func (m *MaintenanceMode) State() model.MaintenanceState {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.state
+}
+
+// ReadOnly reports whether writes are refused
+func (m *MaintenanceMode) ReadOnly() bool {
+	return m.State().ReadOnly
+}
+
+// Set switches read-only mode on or off and returns the new mode. Switching
+// it on again only updates the reason, so Since stays when it started.
+func (m *MaintenanceMode) Set(readOnly bool, reason string) model.MaintenanceState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !readOnly {
+		m.state = model.MaintenanceState{}
+		return m.state
+	}
+	since := m.state.Since
+	if since == nil {
+		now := time.Now().UTC()
+		since = &now
+	}
+	m.state = model.MaintenanceState{ReadOnly: true, Reason: reason, Since: since}
+	return m.state
+}
+
+// Restore switches to the mode kept in store, which wins over the start
+// mode, and keeps store for the next switches. A store that was never
+// written leaves the start mode.
+func (m *MaintenanceMode) Restore(ctx context.Context, store port.MaintenanceStore) error {
+	state, err := store.LoadMaintenance(ctx)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.store = store
+	if state != nil {
+		m.state = *state
+	}
+	return nil
+}
+
+// Switch is Set, also saving the new mode to the store Restore kept, so a
+// replica started later comes up in it. A failed save is logged: the
+// replica handling the request is switched either way.
+func (m *MaintenanceMode) Switch(ctx context.Context, readOnly bool, reason string) model.MaintenanceState {
+	state := m.Set(readOnly, reason)
+	m.mu.RLock()
+	store := m.store
+	m.mu.RUnlock()
+	if store != nil {
+		if err := store.SaveMaintenance(ctx, state); err != nil {
+			slog.ErrorContext(ctx, "failed to save the maintenance mode", "error", err)
+		}
+	}
+	return state
+}
+
+// RefuseWritesWhenReadOnly returns the middleware replying read_only_mode to
+// the requests of the subjects isWrite reports while mode is read-only,
+// without reaching their handler. The reason is sent as the message.
+func RefuseWritesWhenReadOnly(mode *MaintenanceMode, isWrite func(subject string) bool) Middleware {
+	return func(next port.Handler) port.Handler {
+		return port.HandlerFunc(func(ctx context.Context, msg port.TransportMessenger) {
+			state := mode.State()
+			if !state.ReadOnly || !isWrite(msg.Subject()) {
+				next.Handle(ctx, msg)
+				return
+			}
+			response, err := json.Marshal(UserDataResponse{Success: false, Error: errReadOnlyMode, Message: state.Reason})
+			if err != nil {
+				slog.ErrorContext(ctx, "failed to marshal error response", "error", err)
+				return
+			}
+			if err := msg.Respond(response); err != nil {
+				slog.ErrorContext(ctx, "failed to respond to request", "error", err)
+			}
+		})
+	}
+}
+
+// maintenanceRequest is the input of admin.maintenance; no read_only only
+// reads the mode
+type maintenanceRequest struct {
+	AuthToken string `json:"auth_token"`
+	ReadOnly  *bool  `json:"read_only,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// WithMaintenanceModeForMessageHandler sets the read-only switch
+// admin.maintenance reads and flips
+func WithMaintenanceModeForMessageHandler(mode *MaintenanceMode) MessageHandlerOrchestratorOption {
+	return func(m *messageHandlerOrchestrator) {
+		m.maintenance = mode
+	}
+}
+
+// AdminMaintenance switches read-only mode of the replica handling the
+// request on or off, and replies the mode; every replica gets the request
+func (m *messageHandlerOrchestrator) AdminMaintenance(ctx context.Context, msg port.TransportMessenger) ([]byte, error) {
+	if m.maintenance == nil {
+		return m.errorResponse("maintenance_unavailable"), nil
+	}
+
+	var request maintenanceRequest
+	if err := json.Unmarshal(msg.Data(), &request); err != nil {
+		return m.errorResponse("failed_to_unmarshal_request"), nil
+	}
+	claims, err := m.authorizeScope(ctx, request.AuthToken, constants.MaintenanceManageRequiredScope)
+	if err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+
+	state := m.maintenance.State()
+	if request.ReadOnly != nil {
+		state = m.maintenance.Switch(ctx, *request.ReadOnly, request.Reason)
+		slog.WarnContext(ctx, "maintenance mode changed",
+			"read_only", state.ReadOnly,
+			"reason", state.Reason,
+			"changed_by", redaction.Redact(claims.Subject),
+		)
+	}
+
+	responseJSON, err := json.Marshal(UserDataResponse{Success: true, Data: state})
+	if err != nil {
+		return m.errorResponse("failed to marshal response"), nil
+	}
+	return responseJSON, nil
+}
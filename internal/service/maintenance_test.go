@@ -0,0 +1,149 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/jwt"
+)
+
+func TestMaintenanceMode(t *testing.T) {
+	mode := NewMaintenanceMode(false, "ignored")
+	assert.Equal(t, model.MaintenanceState{}, mode.State())
+
+	on := mode.Set(true, "migration")
+	require.NotNil(t, on.Since)
+	again := mode.Set(true, "migration, step 2")
+	assert.Equal(t, on.Since, again.Since, "switching it on again keeps when it started")
+	assert.Equal(t, "migration, step 2", again.Reason)
+
+	assert.Equal(t, model.MaintenanceState{}, mode.Set(false, ""))
+	assert.False(t, mode.ReadOnly())
+}
+
+// fakeMaintenanceStore is a port.MaintenanceStore in memory
+type fakeMaintenanceStore struct {
+	state *model.MaintenanceState
+	saves int
+}
+
+func (s *fakeMaintenanceStore) LoadMaintenance(context.Context) (*model.MaintenanceState, error) {
+	return s.state, nil
+}
+
+func (s *fakeMaintenanceStore) SaveMaintenance(_ context.Context, state model.MaintenanceState) error {
+	s.state = &state
+	s.saves++
+	return nil
+}
+
+func TestMaintenanceModeRestore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("an empty store keeps the start mode", func(t *testing.T) {
+		mode := NewMaintenanceMode(true, "configured")
+		require.NoError(t, mode.Restore(ctx, &fakeMaintenanceStore{}))
+		assert.Equal(t, "configured", mode.State().Reason)
+	})
+
+	t.Run("the stored mode wins and switches are saved", func(t *testing.T) {
+		since := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+		store := &fakeMaintenanceStore{state: &model.MaintenanceState{ReadOnly: true, Reason: "migration", Since: &since}}
+		mode := NewMaintenanceMode(false, "")
+		require.NoError(t, mode.Restore(ctx, store))
+		assert.Equal(t, *store.state, mode.State(), "a replica started later comes up in the switched mode")
+
+		mode.Switch(ctx, false, "")
+		assert.Equal(t, 1, store.saves)
+		assert.Equal(t, model.MaintenanceState{}, *store.state)
+	})
+}
+
+func TestRefuseWritesWhenReadOnly(t *testing.T) {
+	mode := NewMaintenanceMode(false, "")
+	var handled []string
+	handler := Chain(port.HandlerFunc(func(_ context.Context, msg port.TransportMessenger) { handled = append(handled, msg.Subject()) }),
+		RefuseWritesWhenReadOnly(mode, func(subject string) bool { return subject == constants.UserMetadataUpdateSubject }))
+
+	send := func(subject string) *subjectMessenger {
+		msg := &subjectMessenger{repliedMessenger: repliedMessenger{data: []byte(`{}`)}, subject: subject}
+		handler.Handle(context.Background(), msg)
+		return msg
+	}
+
+	send(constants.UserMetadataUpdateSubject)
+	assert.Equal(t, []string{constants.UserMetadataUpdateSubject}, handled)
+
+	mode.Set(true, "database migration")
+	handled = nil
+	send(constants.UserMetadataReadSubject)
+	refused := send(constants.UserMetadataUpdateSubject)
+	assert.Equal(t, []string{constants.UserMetadataReadSubject}, handled, "reads go on")
+
+	var response UserDataResponse
+	require.NoError(t, json.Unmarshal(refused.replied, &response))
+	assert.False(t, response.Success)
+	assert.Equal(t, errReadOnlyMode, response.Error)
+	assert.Equal(t, "database migration", response.Message)
+}
+
+func TestMessageHandlerOrchestrator_AdminMaintenance(t *testing.T) {
+	ctx := context.Background()
+	manageToken, err := jwt.GenerateTestAccessToken("auth0|admin", "https://test.any.com/", "https://test.any.com/api/v2/", constants.MaintenanceManageRequiredScope, time.Hour)
+	require.NoError(t, err)
+	readToken, err := jwt.GenerateTestAccessToken("auth0|reader", "https://test.any.com/", "https://test.any.com/api/v2/", "read:users", time.Hour)
+	require.NoError(t, err)
+
+	reader := &mockUserServiceReader{
+		metadataLookupFunc: func(ctx context.Context, input string) (*model.User, error) {
+			return &model.User{Token: input}, nil
+		},
+	}
+	request := func(m *messageHandlerOrchestrator, payload map[string]any) UserDataResponse {
+		t.Helper()
+		data, _ := json.Marshal(payload)
+		reply, err := m.AdminMaintenance(ctx, &mockTransportMessenger{data: data})
+		require.NoError(t, err)
+		var response UserDataResponse
+		require.NoError(t, json.Unmarshal(reply, &response))
+		return response
+	}
+
+	t.Run("switches read-only mode on and off", func(t *testing.T) {
+		mode := NewMaintenanceMode(false, "")
+		m := NewMessageHandlerOrchestrator(WithUserReaderForMessageHandler(reader), WithMaintenanceModeForMessageHandler(mode)).(*messageHandlerOrchestrator)
+
+		response := request(m, map[string]any{"auth_token": manageToken, "read_only": true, "reason": "migration"})
+		require.True(t, response.Success, response.Error)
+		assert.Equal(t, true, response.Data.(map[string]any)["read_only"])
+		assert.True(t, mode.ReadOnly())
+
+		response = request(m, map[string]any{"auth_token": manageToken})
+		require.True(t, response.Success, response.Error)
+		assert.Equal(t, "migration", response.Data.(map[string]any)["reason"], "no read_only only reads the mode")
+
+		request(m, map[string]any{"auth_token": manageToken, "read_only": false})
+		assert.False(t, mode.ReadOnly())
+	})
+
+	t.Run("requires the maintenance scope", func(t *testing.T) {
+		mode := NewMaintenanceMode(false, "")
+		m := NewMessageHandlerOrchestrator(WithUserReaderForMessageHandler(reader), WithMaintenanceModeForMessageHandler(mode)).(*messageHandlerOrchestrator)
+
+		response := request(m, map[string]any{"auth_token": readToken, "read_only": true})
+		assert.False(t, response.Success)
+		assert.Equal(t, "insufficient_scope", response.Error)
+		assert.False(t, mode.ReadOnly())
+	})
+}
@@ -9,13 +9,19 @@ import (
 	"errors"
 	"log/slog"
 	"os"
+	"slices"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
 	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/cache"
 	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
 	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/featureflag"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/jwt"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/normalize"
 	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/redaction"
 )
 
@@ -35,19 +41,99 @@ type UserDataResponse struct {
 	Message string `json:"message,omitempty"`
 	Data    any    `json:"data,omitempty"`
 	Error   string `json:"error,omitempty"`
+	// Stale marks data served from an expired cache entry while it is refreshed
+	Stale bool `json:"stale,omitempty"`
 }
 
 // messageHandlerOrchestrator orchestrates the message handling process
 type messageHandlerOrchestrator struct {
-	userWriter       port.UserWriter
-	userReader       port.UserReader
-	emailHandler     port.EmailHandler
-	identityLinker   port.IdentityLinker
-	identityUnlinker port.IdentityLinker
-	passwordHandler  port.PasswordHandler
-	impersonator     port.Impersonator
-	eventPublisher   port.EventPublisher
-	aliasManager     port.AliasManager
+	userWriter        port.UserWriter
+	userReader        port.UserReader
+	emailHandler      port.EmailHandler
+	identityLinker    port.IdentityLinker
+	identityUnlinker  port.IdentityLinker
+	passwordHandler   port.PasswordHandler
+	impersonator      port.Impersonator
+	eventPublisher    port.EventPublisher
+	aliasManager      port.AliasManager
+	instanceStats     port.InstanceStatsProvider
+	runtimeConfig     port.RuntimeConfigProvider
+	userSearcher      port.UserSearcher
+	typeaheadSearcher port.UserSearcher
+	permissionReader  port.PermissionReader
+
+	personalAccessTokens port.PersonalAccessTokenStore
+	// personalAccessTokenMaxLifetime caps the expiry of new tokens; 0 uses the default
+	personalAccessTokenMaxLifetime time.Duration
+	// personalAccessTokenScopes are the scopes tokens may carry; empty allows none
+	personalAccessTokenScopes []string
+
+	sessionManager port.SessionManager
+
+	tokenRevocations port.TokenRevocationList
+	// tokenRevocationTTL is how long revocations of every token of a user last; 0 uses the default
+	tokenRevocationTTL time.Duration
+
+	// stepUpMaxAge and stepUpFactors are the step-up policy; zero values use the defaults
+	stepUpMaxAge  time.Duration
+	stepUpFactors []string
+
+	// selfTestCanary is the user admin.selftest looks up; empty skips the round-trip
+	selfTestCanary string
+	selfTesters    []port.SelfTester
+
+	userImporter       port.UserImporter
+	userReportExporter port.UserReportExporter
+	userExportStore    port.UserExportStore
+	userMerger         port.UserMerger
+	userMergeHistory   port.UserMergeHistory
+
+	// metadataFieldPolicy restricts who may update some metadata fields
+	metadataFieldPolicy MetadataFieldPolicy
+	// callerProfiles restricts the fields other services read; nil doesn't
+	callerProfiles *CallerProfiles
+
+	affiliations port.AffiliationStore
+
+	metadataSizeLimits model.MetadataSizeLimits
+
+	staticResources port.StaticResourceCache
+
+	lookupReindexer port.LookupReindexer
+	// reindexing is set while admin.reindex runs on this replica
+	reindexing atomic.Bool
+
+	maintenance *MaintenanceMode
+
+	attestations port.AttestationStore
+	// attestableFields are the metadata fields that can be verified
+	attestableFields []string
+
+	consents port.ConsentStore
+	// consentPolicies maps the policies users accept to their current version
+	consentPolicies map[string]string
+
+	emailOrganizations port.EmailOrganizationStore
+	// publicEmailDomains are never mapped to an organization
+	publicEmailDomains map[string]bool
+
+	serviceAccountManager port.ServiceAccountManager
+	// serviceAccountAudiences are the APIs service accounts may be granted; empty allows any
+	serviceAccountAudiences []string
+
+	// requireVerifiedEmail restricts email lookups to verified addresses
+	requireVerifiedEmail bool
+	// flags gate the behaviors being rolled out; nil has every flag off
+	flags *featureflag.Set
+	// identifiers caches username <-> sub resolutions; nil disables caching
+	identifiers *identifierCache
+	// lookupSnapshot saves and restores identifiers; nil keeps them in memory only
+	lookupSnapshot *LookupSnapshot
+	// permissions caches the roles and permissions of a sub; nil disables caching
+	permissions *cache.Cache[string, *model.UserPermissions]
+	// emailBatchMaxSize and emailBatchConcurrency bound email_to_username.batch; 0 uses the defaults
+	emailBatchMaxSize     int
+	emailBatchConcurrency int
 }
 
 // MessageHandlerOrchestratorOption defines a function type for setting options
@@ -109,6 +195,13 @@ func WithEventPublisherForMessageHandler(eventPublisher port.EventPublisher) Mes
 	}
 }
 
+// WithInstanceStatsForMessageHandler sets the instance stats provider for the message handler orchestrator
+func WithInstanceStatsForMessageHandler(instanceStats port.InstanceStatsProvider) MessageHandlerOrchestratorOption {
+	return func(m *messageHandlerOrchestrator) {
+		m.instanceStats = instanceStats
+	}
+}
+
 // WithAliasManagerForMessageHandler sets the alias manager for the message handler orchestrator
 func WithAliasManagerForMessageHandler(aliasManager port.AliasManager) MessageHandlerOrchestratorOption {
 	return func(m *messageHandlerOrchestrator) {
@@ -116,6 +209,49 @@ func WithAliasManagerForMessageHandler(aliasManager port.AliasManager) MessageHa
 	}
 }
 
+// WithIdentifierCacheForMessageHandler caches username_to_sub and
+// sub_to_username results for ttl. A ttl of 0 disables the cache.
+func WithIdentifierCacheForMessageHandler(ttl time.Duration, maxEntries int) MessageHandlerOrchestratorOption {
+	return func(m *messageHandlerOrchestrator) {
+		if ttl <= 0 {
+			m.identifiers = nil
+			return
+		}
+		m.identifiers = newIdentifierCache(ttl, maxEntries)
+	}
+}
+
+// WithLookupSnapshotForMessageHandler persists the identifier cache in
+// snapshot, which restores and saves it once the orchestrator is built
+func WithLookupSnapshotForMessageHandler(snapshot *LookupSnapshot) MessageHandlerOrchestratorOption {
+	return func(m *messageHandlerOrchestrator) {
+		m.lookupSnapshot = snapshot
+	}
+}
+
+// WithVerifiedEmailLookupsForMessageHandler makes email_to_username and
+// email_to_sub resolve only addresses the identity provider marked verified
+func WithVerifiedEmailLookupsForMessageHandler(required bool) MessageHandlerOrchestratorOption {
+	return func(m *messageHandlerOrchestrator) {
+		m.requireVerifiedEmail = required
+	}
+}
+
+// WithFeatureFlagsForMessageHandler sets the feature flags gating the
+// behaviors being rolled out
+func WithFeatureFlagsForMessageHandler(flags *featureflag.Set) MessageHandlerOrchestratorOption {
+	return func(m *messageHandlerOrchestrator) {
+		m.flags = flags
+	}
+}
+
+// verifiedEmailsOnly reports whether email lookups of the request resolve
+// only verified addresses, for every caller or as the strict email
+// verification rollout reaches it
+func (m *messageHandlerOrchestrator) verifiedEmailsOnly(ctx context.Context) bool {
+	return m.requireVerifiedEmail || m.flags.Enabled(ctx, featureflag.StrictEmailVerification)
+}
+
 func (m *messageHandlerOrchestrator) errorResponse(error string) []byte {
 	response := UserDataResponse{
 		Success: false,
@@ -143,8 +279,11 @@ func (m *messageHandlerOrchestrator) searchByEmail(ctx context.Context, criteria
 	user := &model.User{
 		PrimaryEmail: email,
 	}
-	if criteria == constants.CriteriaTypeAlternateEmail {
+	switch criteria {
+	case constants.CriteriaTypeAlternateEmail:
 		user.AlternateEmails = []model.Email{{Email: email}}
+	case constants.CriteriaTypeSecondaryEmail:
+		user.SecondaryEmails = []model.Email{{Email: email}}
 	}
 
 	user, err := m.userReader.SearchUser(ctx, user, criteria)
@@ -156,25 +295,44 @@ func (m *messageHandlerOrchestrator) searchByEmail(ctx context.Context, criteria
 
 }
 
-// searchByEmailWithFallback tries primary email first; if not found, retries with alternate email.
+// emailFallbackCriteria are tried in order until one of them finds the user
+var emailFallbackCriteria = []string{
+	constants.CriteriaTypeEmail,
+	constants.CriteriaTypeAlternateEmail,
+	constants.CriteriaTypeSecondaryEmail,
+}
+
+// searchByEmailWithFallback tries primary email first; if not found, retries with
+// alternate email and then with any verified secondary email on the account.
+// When verifiedEmailsOnly, a match on an unverified address counts as not found.
 func (m *messageHandlerOrchestrator) searchByEmailWithFallback(ctx context.Context, email string) (*model.User, error) {
-	user, err := m.searchByEmail(ctx, constants.CriteriaTypeEmail, email)
-	if err == nil {
-		return user, nil
-	}
+	var err error
+	for _, criteria := range emailFallbackCriteria {
+		var user *model.User
+		user, err = m.searchByEmail(ctx, criteria, email)
+		if err == nil && m.verifiedEmailsOnly(ctx) && !user.HasVerifiedEmail(email) {
+			slog.DebugContext(ctx, "user found by an unverified email",
+				"criteria", criteria,
+				"email", redaction.RedactEmail(email),
+			)
+			err = errs.NewNotFound("user not found")
+		}
+		if err == nil {
+			return user, nil
+		}
 
-	var notFound errs.NotFound
-	if !errors.As(err, &notFound) {
-		return nil, err
+		var notFound errs.NotFound
+		if !errors.As(err, &notFound) {
+			return nil, err
+		}
 	}
-
-	return m.searchByEmail(ctx, constants.CriteriaTypeAlternateEmail, email)
+	return nil, err
 }
 
 // EmailToUsername converts an email to a username
 func (m *messageHandlerOrchestrator) EmailToUsername(ctx context.Context, msg port.TransportMessenger) ([]byte, error) {
 
-	email := strings.ToLower(strings.TrimSpace(string(msg.Data())))
+	email := normalize.Email(string(msg.Data()))
 	if email == "" {
 		return m.errorResponse("email is required"), nil
 	}
@@ -189,7 +347,7 @@ func (m *messageHandlerOrchestrator) EmailToUsername(ctx context.Context, msg po
 // EmailToSub converts an email to a sub
 func (m *messageHandlerOrchestrator) EmailToSub(ctx context.Context, msg port.TransportMessenger) ([]byte, error) {
 
-	email := strings.ToLower(strings.TrimSpace(string(msg.Data())))
+	email := normalize.Email(string(msg.Data()))
 	if email == "" {
 		return m.errorResponse("email is required"), nil
 	}
@@ -201,14 +359,67 @@ func (m *messageHandlerOrchestrator) EmailToSub(ctx context.Context, msg port.Tr
 	return []byte(user.UserID), nil
 }
 
-// UsernameToSub converts a username to a sub using local mapping logic.
-// This derives the Auth0 sub deterministically without an external call.
-func (m *messageHandlerOrchestrator) UsernameToSub(_ context.Context, msg port.TransportMessenger) ([]byte, error) {
+// UsernameToSub converts a username to a sub. The user is resolved through the
+// user reader, so the answer is right for every provider; without a reader the
+// Auth0 sub is derived locally with mapUsernameToSub.
+func (m *messageHandlerOrchestrator) UsernameToSub(ctx context.Context, msg port.TransportMessenger) ([]byte, error) {
 	username := strings.TrimSpace(string(msg.Data()))
 	if username == "" {
 		return m.errorResponse("username is required"), nil
 	}
-	return []byte(mapUsernameToSub(username)), nil
+	if m.userReader == nil {
+		return []byte(mapUsernameToSub(username)), nil
+	}
+
+	if sub, ok := m.identifiers.sub(username); ok {
+		return []byte(sub), nil
+	}
+
+	user, err := m.userReader.SearchUser(ctx, &model.User{Username: username}, constants.CriteriaTypeUsername)
+	if err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+	if user == nil || user.UserID == "" {
+		return m.errorResponse("user not found"), nil
+	}
+
+	m.identifiers.store(username, user.UserID)
+	return []byte(user.UserID), nil
+}
+
+// SubToUsername converts a sub (an Auth0 user ID or an Authelia UUID) to a username
+func (m *messageHandlerOrchestrator) SubToUsername(ctx context.Context, msg port.TransportMessenger) ([]byte, error) {
+	sub := strings.TrimSpace(string(msg.Data()))
+	if sub == "" {
+		return m.errorResponse("sub is required"), nil
+	}
+	if m.userReader == nil {
+		return m.errorResponse("auth_service_unavailable"), nil
+	}
+
+	username, err := m.usernameForSub(ctx, sub)
+	if err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+	return []byte(username), nil
+}
+
+// usernameForSub resolves the username of sub through the identifier cache
+func (m *messageHandlerOrchestrator) usernameForSub(ctx context.Context, sub string) (string, error) {
+	if username, ok := m.identifiers.username(sub); ok {
+		return username, nil
+	}
+
+	user, err := m.userReader.GetUser(ctx, &model.User{UserID: sub, Sub: sub})
+	if err != nil {
+		return "", err
+	}
+	if user == nil || user.Username == "" {
+		return "", errs.NewNotFound("user not found")
+	}
+
+	m.identifiers.store(user.Username, sub)
+	return user.Username, nil
 }
 
 // resolveUserFromAuthInput resolves a user from an auth token, subject identifier, or LFID username.
@@ -238,10 +449,35 @@ func (m *messageHandlerOrchestrator) resolveUserFromAuthInput(ctx context.Contex
 	return m.userReader.SearchUser(ctx, user, constants.CriteriaTypeUsername)
 }
 
-// getUserByInput resolves a user when the NATS payload is a raw auth input string
-// (no JSON wrapper), as used by user_metadata.read.
-func (m *messageHandlerOrchestrator) getUserByInput(ctx context.Context, msg port.TransportMessenger) (*model.User, error) {
-	input := strings.TrimSpace(string(msg.Data()))
+// userMetadataReadRequest is the JSON form of the user_metadata.read input,
+// used to select the fields of the reply or to read on behalf of another
+// user. A raw auth input string is read as an input with no field selection.
+type userMetadataReadRequest struct {
+	Input  string   `json:"input"`
+	Fields []string `json:"fields"`
+	// OnBehalfOf is the sub or username of the user whose read is returned,
+	// with Input the caller's own access token
+	OnBehalfOf string `json:"on_behalf_of"`
+}
+
+// parseUserMetadataReadRequest reads the user_metadata.read payload, which is
+// either a raw auth input string or a JSON userMetadataReadRequest
+func parseUserMetadataReadRequest(data []byte) (userMetadataReadRequest, error) {
+	payload := strings.TrimSpace(string(data))
+	if !strings.HasPrefix(payload, "{") {
+		return userMetadataReadRequest{Input: payload}, nil
+	}
+	var request userMetadataReadRequest
+	if err := json.Unmarshal([]byte(payload), &request); err != nil {
+		return userMetadataReadRequest{}, errs.NewValidation("failed_to_unmarshal_request")
+	}
+	request.Input = strings.TrimSpace(request.Input)
+	request.OnBehalfOf = strings.TrimSpace(request.OnBehalfOf)
+	return request, nil
+}
+
+// getUserByInput resolves a user from the raw auth input of user_metadata.read
+func (m *messageHandlerOrchestrator) getUserByInput(ctx context.Context, input string) (*model.User, error) {
 	if input == "" {
 		return nil, errs.NewValidation("input is required")
 	}
@@ -262,22 +498,92 @@ func (m *messageHandlerOrchestrator) getUserByInput(ctx context.Context, msg por
 	return user, nil
 }
 
-// GetUserMetadata retrieves user metadata based on the input strategy
+// GetUserMetadata retrieves user metadata based on the input strategy. When
+// the request selects fields, only those are returned.
 func (m *messageHandlerOrchestrator) GetUserMetadata(ctx context.Context, msg port.TransportMessenger) ([]byte, error) {
 
-	userRetrieved, errGetUser := m.getUserByInput(ctx, msg)
+	request, errRequest := parseUserMetadataReadRequest(msg.Data())
+	if errRequest != nil {
+		return m.errorResponse(errRequest.Error()), nil
+	}
+
+	// a read on behalf of a user is the one the user would get, so the
+	// caller's privileged scopes don't add anything to it
+	onBehalf := request.OnBehalfOf != ""
+	getUser := m.getUserByInput
+	input := request.Input
+	if onBehalf {
+		getUser = func(ctx context.Context, target string) (*model.User, error) {
+			return m.getUserOnBehalfOf(ctx, request.Input, target)
+		}
+		input = request.OnBehalfOf
+	}
+	userRetrieved, errGetUser := getUser(ctx, input)
 	if errGetUser != nil {
 		slog.ErrorContext(ctx, "error getting user metadata",
 			"error", errGetUser,
-			"input", redaction.Redact(string(msg.Data())),
+			"input", redaction.Redact(request.Input),
 		)
 		return m.errorResponse(errGetUser.Error()), nil
 	}
 
-	// Return success response with user metadata
+	// other services reading a user who opted out of profile sharing only get
+	// the username, whatever they select
+	self := isSelfRead(request.Input, onBehalf)
+	fields := request.Fields
+	if userRetrieved.MinimizedFor(self) {
+		userRetrieved = userRetrieved.ProfileFor(false)
+		fields = []string{model.ProfileFieldUsername}
+	}
+	// and only the fields of their caller profile
+	if !self && m.callerProfiles != nil {
+		fields = m.callerProfiles.restrict(transportCaller(msg), fields)
+	}
+
+	// Return success response with user metadata and the computed display
+	// name; login activity is only included for callers holding the
+	// privileged activity scope
+	var data any
+	if len(fields) > 0 {
+		allowActivity := slices.Contains(fields, model.ProfileFieldActivity) && !onBehalf && canReadActivity(ctx, request.Input)
+		projection, err := userRetrieved.ProjectProfile(fields, allowActivity)
+		if err != nil {
+			return m.errorResponse(err.Error()), nil
+		}
+		data = projection
+	} else if userRetrieved.UserMetadata != nil || userRetrieved.Activity != nil {
+		read := userMetadataReadData{
+			UserMetadata: userRetrieved.UserMetadata,
+			DisplayName:  userRetrieved.DisplayName(),
+		}
+		if userRetrieved.Activity != nil && !onBehalf && canReadActivity(ctx, request.Input) {
+			read.Activity = userRetrieved.Activity
+		}
+		data = read
+	}
+
+	// verified fields are reported beside the metadata, never mixed into it
+	if verified := m.verifiedFields(ctx, userRetrieved); len(verified) > 0 {
+		switch typed := data.(type) {
+		case map[string]any:
+			for field := range verified {
+				if _, selected := typed[field]; !selected {
+					delete(verified, field)
+				}
+			}
+			if len(verified) > 0 {
+				typed[profileFieldVerified] = verified
+			}
+		case userMetadataReadData:
+			typed.Verified = verified
+			data = typed
+		}
+	}
+
 	response := UserDataResponse{
 		Success: true,
-		Data:    userRetrieved.UserMetadata,
+		Data:    data,
+		Stale:   userRetrieved.Stale,
 	}
 
 	responseJSON, err := json.Marshal(response)
@@ -289,6 +595,44 @@ func (m *messageHandlerOrchestrator) GetUserMetadata(ctx context.Context, msg po
 	return responseJSON, nil
 }
 
+// userMetadataReadData is the user_metadata.read payload of a read selecting
+// no fields; metadata fields stay at the top level and the computed display
+// name, activity and verifications are added beside them.
+type userMetadataReadData struct {
+	*model.UserMetadata
+	DisplayName string                                 `json:"display_name,omitempty"`
+	Activity    *model.UserActivity                    `json:"activity,omitempty"`
+	Verified    map[string]model.AttributeVerification `json:"verified,omitempty"`
+}
+
+// profileFieldVerified holds the verified fields of a projected read
+const profileFieldVerified = "verified"
+
+// isSelfRead reports whether a user_metadata.read is the user's own: read
+// with their access token, or on their behalf, rather than looked up by sub
+// or username by another service. Authelia access tokens are opaque and
+// recognized by their prefix, as the Authelia lookup does.
+func isSelfRead(input string, onBehalf bool) bool {
+	input = strings.TrimSpace(input)
+	_, isJWT := jwt.LooksLikeJWT(input)
+	return isJWT || strings.HasPrefix(input, "authelia") || onBehalf
+}
+
+// canReadActivity reports whether the read input is an access token carrying
+// UserReadActivityRequiredScope. By the time this runs the token has already
+// been verified by MetadataLookup, so it's only parsed here to read the scope.
+func canReadActivity(ctx context.Context, input string) bool {
+	token, isJWT := jwt.LooksLikeJWT(strings.TrimSpace(input))
+	if !isJWT {
+		return false
+	}
+	claims, err := jwt.ParseUnverified(ctx, token, &jwt.ParseOptions{AllowBearerPrefix: true})
+	if err != nil {
+		return false
+	}
+	return claims.HasScope(constants.UserReadActivityRequiredScope)
+}
+
 // userEmailsRequest represents the input for retrieving user emails
 type userEmailsRequest struct {
 	User struct {
@@ -459,13 +803,48 @@ func (m *messageHandlerOrchestrator) UpdateUser(ctx context.Context, msg port.Tr
 
 	// Sanitize user data first
 	user.UserSanitize()
+	// the preferences namespace is only written, validated, by UpdateUserPreferences
+	if user.UserMetadata != nil {
+		user.UserMetadata.Preferences = nil
+	}
 
 	// Validate user data
 	if err := user.Validate(); err != nil {
+		// an unknown country or subdivision is replied with what it was close to
+		var addressErr *model.AddressError
+		if errors.As(err, &addressErr) {
+			responseJSON, errMarshal := json.Marshal(UserDataResponse{Success: false, Error: err.Error(), Data: addressErr})
+			if errMarshal != nil {
+				return m.errorResponse("failed to marshal response"), nil
+			}
+			return responseJSON, nil
+		}
 		responseJSON := m.errorResponse(err.Error())
 		return responseJSON, nil
 	}
 
+	// only the update is known here, not the metadata it's merged into
+	if response := m.oversizeMetadataResponse(ctx, user.UserMetadata, m.metadataSizeLimits); response != nil {
+		return response, nil
+	}
+
+	// the whole update is rejected when any restricted or verified field is denied
+	denied := m.authorizeMetadataFields(ctx, user)
+	if len(denied) == 0 {
+		denied = m.protectVerifiedFields(ctx, user)
+	}
+	if len(denied) > 0 {
+		responseJSON, errMarshal := json.Marshal(UserDataResponse{
+			Success: false,
+			Error:   fieldUpdateForbidden,
+			Data:    fieldAuthorizationErrors{Fields: denied},
+		})
+		if errMarshal != nil {
+			return m.errorResponse("failed to marshal response"), nil
+		}
+		return responseJSON, nil
+	}
+
 	// It's calling another service to update the user because in case of
 	// need to expose the same functionality using another pattern, like http rest,
 	// we can do without changing the user writer orchestrator
@@ -477,7 +856,8 @@ func (m *messageHandlerOrchestrator) UpdateUser(ctx context.Context, msg port.Tr
 
 	// Publish domain event so downstream consumers (e.g. v1-sync-helper) can
 	// react to profile changes. Fire-and-forget: a publish failure must not
-	// block the user-facing response.
+	// block the user-facing response. Behind the outbox, the event is stored
+	// before Publish returns and a failed publish is retried.
 	if m.eventPublisher != nil {
 		event := UserProfileUpdatedEvent{
 			UserID:    user.UserID,
@@ -516,7 +896,7 @@ func (m *messageHandlerOrchestrator) UpdateUser(ctx context.Context, msg port.Tr
 
 func (m *messageHandlerOrchestrator) checkEmailExists(ctx context.Context, email string) error {
 
-	email = strings.ToLower(strings.TrimSpace(email))
+	email = normalize.Email(email)
 
 	var notFound errs.NotFound
 	for _, criteria := range []string{constants.CriteriaTypeAlternateEmail, constants.CriteriaTypeEmail} {
@@ -559,7 +939,7 @@ func (m *messageHandlerOrchestrator) StartEmailLinking(ctx context.Context, msg
 		return m.errorResponse("email service unavailable"), nil
 	}
 
-	alternateEmailInput := strings.ToLower(strings.TrimSpace(string(msg.Data())))
+	alternateEmailInput := normalize.Email(string(msg.Data()))
 	if alternateEmailInput == "" {
 		return m.errorResponse("alternate email is required"), nil
 	}
@@ -840,7 +1220,7 @@ func (m *messageHandlerOrchestrator) SetPrimaryEmail(ctx context.Context, msg po
 	if strings.TrimSpace(request.User.AuthToken) == "" {
 		return m.errorResponse("auth_token is required"), nil
 	}
-	email := strings.ToLower(strings.TrimSpace(request.Email))
+	email := normalize.Email(request.Email)
 	if email == "" {
 		return m.errorResponse("email is required"), nil
 	}
@@ -1100,5 +1480,8 @@ func NewMessageHandlerOrchestrator(opts ...MessageHandlerOrchestratorOption) por
 	for _, opt := range opts {
 		opt(m)
 	}
+	if m.lookupSnapshot != nil {
+		m.lookupSnapshot.cache = m.identifiers
+	}
 	return m
 }
@@ -9,11 +9,15 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
 	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
 	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/converters"
 	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/featureflag"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/jwt"
 )
 
 // mockTransportMessenger is a mock implementation of port.TransportMessenger for testing
@@ -621,6 +625,26 @@ func TestMessageHandlerOrchestrator_EmailToUsername(t *testing.T) {
 			expectError:    false,
 			expectedResult: "testuser",
 		},
+		{
+			name:        "secondary email fallback resolves username",
+			messageData: []byte("octocat@users.example.com"),
+			userReader: &mockUserServiceReader{
+				searchUserFunc: func(ctx context.Context, user *model.User, criteria string) (*model.User, error) {
+					if criteria != constants.CriteriaTypeSecondaryEmail {
+						return nil, errors.NewNotFound("user not found")
+					}
+					if len(user.SecondaryEmails) != 1 || user.SecondaryEmails[0].Email != "octocat@users.example.com" {
+						t.Errorf("Expected secondary email octocat@users.example.com, got %v", user.SecondaryEmails)
+					}
+					return &model.User{
+						UserID:   "auth0|octocat001",
+						Username: "octocat",
+					}, nil
+				},
+			},
+			expectError:    false,
+			expectedResult: "octocat",
+		},
 		{
 			name:        "primary email hit does not trigger fallback",
 			messageData: []byte("testuser@example.com"),
@@ -1259,6 +1283,118 @@ func TestMessageHandlerOrchestrator_UsernameToSub_NoUserReader(t *testing.T) {
 	}
 }
 
+func TestMessageHandlerOrchestrator_UsernameToSub_UserReader(t *testing.T) {
+	ctx := context.Background()
+
+	searches, gets := 0, 0
+	reader := &mockUserServiceReader{
+		searchUserFunc: func(ctx context.Context, user *model.User, criteria string) (*model.User, error) {
+			searches++
+			if criteria != constants.CriteriaTypeUsername {
+				t.Errorf("SearchUser() criteria = %q, want %q", criteria, constants.CriteriaTypeUsername)
+			}
+			if user.Username != "zephyr.stormwind" {
+				return nil, errors.NewNotFound("user not found")
+			}
+			return &model.User{UserID: "auth0|zephyr001", Username: user.Username}, nil
+		},
+		getUserFunc: func(ctx context.Context, user *model.User) (*model.User, error) {
+			gets++
+			return user, nil
+		},
+	}
+	orchestrator := NewMessageHandlerOrchestrator(
+		WithUserReaderForMessageHandler(reader),
+		WithIdentifierCacheForMessageHandler(time.Minute, 10),
+	)
+
+	for range 2 {
+		result, err := orchestrator.UsernameToSub(ctx, &mockTransportMessenger{data: []byte(" zephyr.stormwind ")})
+		if err != nil {
+			t.Fatalf("UsernameToSub() unexpected error: %v", err)
+		}
+		if string(result) != "auth0|zephyr001" {
+			t.Errorf("UsernameToSub() = %q, want %q", result, "auth0|zephyr001")
+		}
+	}
+	if searches != 1 {
+		t.Errorf("SearchUser() called %d times, want 1 (second lookup cached)", searches)
+	}
+
+	// the reverse mapping is answered from the cache too
+	result, _ := orchestrator.SubToUsername(ctx, &mockTransportMessenger{data: []byte("auth0|zephyr001")})
+	if string(result) != "zephyr.stormwind" {
+		t.Errorf("SubToUsername() = %q, want %q", result, "zephyr.stormwind")
+	}
+	if gets != 0 {
+		t.Errorf("GetUser() called %d times, want 0", gets)
+	}
+
+	result, _ = orchestrator.UsernameToSub(ctx, &mockTransportMessenger{data: []byte("unknown")})
+	if !strings.Contains(string(result), "user not found") {
+		t.Errorf("UsernameToSub() = %s, want user not found error", result)
+	}
+}
+
+func TestMessageHandlerOrchestrator_SubToUsername(t *testing.T) {
+	ctx := context.Background()
+
+	reader := &mockUserServiceReader{
+		getUserFunc: func(ctx context.Context, user *model.User) (*model.User, error) {
+			switch user.Sub {
+			case "auth0|zephyr001":
+				return &model.User{UserID: user.UserID, Username: "zephyr.stormwind"}, nil
+			case "auth0|nousername":
+				return &model.User{UserID: user.UserID}, nil
+			}
+			return nil, errors.NewNotFound("user not found")
+		},
+	}
+
+	tests := []struct {
+		name      string
+		reader    port.UserReader
+		data      string
+		want      string
+		wantError string
+	}{
+		{name: "resolves the username", reader: reader, data: "auth0|zephyr001", want: "zephyr.stormwind"},
+		{name: "trims whitespace", reader: reader, data: " auth0|zephyr001\n", want: "zephyr.stormwind"},
+		{name: "unknown sub", reader: reader, data: "auth0|unknown", wantError: "user not found"},
+		{name: "user without username", reader: reader, data: "auth0|nousername", wantError: "user not found"},
+		{name: "empty sub", reader: reader, data: "  ", wantError: "sub is required"},
+		{name: "no user reader", data: "auth0|zephyr001", wantError: "auth_service_unavailable"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []MessageHandlerOrchestratorOption
+			if tt.reader != nil {
+				opts = append(opts, WithUserReaderForMessageHandler(tt.reader))
+			}
+			orchestrator := NewMessageHandlerOrchestrator(opts...)
+
+			result, err := orchestrator.SubToUsername(ctx, &mockTransportMessenger{data: []byte(tt.data)})
+			if err != nil {
+				t.Fatalf("SubToUsername() unexpected error: %v", err)
+			}
+			if tt.wantError == "" {
+				if string(result) != tt.want {
+					t.Errorf("SubToUsername() = %q, want %q", result, tt.want)
+				}
+				return
+			}
+			var response UserDataResponse
+			if err := json.Unmarshal(result, &response); err != nil {
+				t.Fatalf("failed to unmarshal error response: %v", err)
+			}
+			if response.Success || response.Error != tt.wantError {
+				t.Errorf("SubToUsername() error = %q, want %q", response.Error, tt.wantError)
+			}
+		})
+	}
+}
+
 // mockEventPublisher is a mock implementation of port.EventPublisher for testing
 type mockEventPublisher struct {
 	publishFunc func(ctx context.Context, subject string, data []byte) error
@@ -1975,6 +2111,278 @@ func TestMessageHandlerOrchestrator_GetUserMetadata(t *testing.T) {
 	}
 }
 
+func TestMessageHandlerOrchestrator_GetUserMetadata_Activity(t *testing.T) {
+	ctx := context.Background()
+	lastLogin := time.Date(2025, 2, 3, 4, 5, 6, 0, time.UTC)
+
+	withoutScope, err := jwt.GenerateSimpleTestAccessToken("auth0|123", time.Hour)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	withScope, err := jwt.GenerateTestAccessToken("auth0|123", "https://issuer/", "aud", "openid "+constants.UserReadActivityRequiredScope, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		input        string
+		wantActivity bool
+	}{
+		{name: "token with activity scope", input: withScope, wantActivity: true},
+		{name: "token without activity scope", input: withoutScope, wantActivity: false},
+		{name: "subject identifier input", input: "auth0|123", wantActivity: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := &mockUserServiceReader{
+				metadataLookupFunc: func(ctx context.Context, input string) (*model.User, error) {
+					return &model.User{UserID: "auth0|123"}, nil
+				},
+				getUserFunc: func(ctx context.Context, user *model.User) (*model.User, error) {
+					return &model.User{
+						UserID:       "auth0|123",
+						UserMetadata: &model.UserMetadata{Name: converters.StringPtr("John Doe")},
+						Activity:     &model.UserActivity{LastLogin: &lastLogin, LastIP: "203.0.113.0"},
+					}, nil
+				},
+			}
+			orchestrator := NewMessageHandlerOrchestrator(WithUserReaderForMessageHandler(reader))
+
+			result, err := orchestrator.GetUserMetadata(ctx, &mockTransportMessenger{data: []byte(tt.input)})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var response struct {
+				Success bool `json:"success"`
+				Data    struct {
+					Name     string              `json:"name"`
+					Activity *model.UserActivity `json:"activity"`
+				} `json:"data"`
+			}
+			if err := json.Unmarshal(result, &response); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+			if !response.Success {
+				t.Fatalf("expected success, got %s", string(result))
+			}
+			if response.Data.Name != "John Doe" {
+				t.Errorf("expected metadata to stay at the top level, got %s", string(result))
+			}
+			if got := response.Data.Activity != nil; got != tt.wantActivity {
+				t.Errorf("activity present = %v, want %v (%s)", got, tt.wantActivity, string(result))
+			}
+		})
+	}
+}
+
+func TestMessageHandlerOrchestrator_GetUserMetadata_Fields(t *testing.T) {
+	ctx := context.Background()
+	lastLogin := time.Date(2025, 2, 3, 4, 5, 6, 0, time.UTC)
+
+	withScope, err := jwt.GenerateTestAccessToken("auth0|123", "https://issuer/", "aud", "openid "+constants.UserReadActivityRequiredScope, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		payload   string
+		wantData  string
+		wantError string
+	}{
+		{
+			name:     "selected fields",
+			payload:  `{"input":"auth0|123","fields":["username","picture"]}`,
+			wantData: `{"picture":"https://example.com/avatar.jpg","username":"john.doe"}`,
+		},
+		{
+			name:     "no fields returns the full metadata",
+			payload:  `{"input":"auth0|123"}`,
+			wantData: `{"picture":"https://example.com/avatar.jpg","name":"John Doe","display_name":"john.doe"}`,
+		},
+		{
+			name:     "display name can be selected",
+			payload:  `{"input":"auth0|123","fields":["display_name"]}`,
+			wantData: `{"display_name":"john.doe"}`,
+		},
+		{
+			name:     "activity with scope",
+			payload:  `{"input":"` + withScope + `","fields":["activity"]}`,
+			wantData: `{"activity":{"last_login":"2025-02-03T04:05:06Z"}}`,
+		},
+		{
+			name:      "activity without scope",
+			payload:   `{"input":"auth0|123","fields":["activity"]}`,
+			wantError: "insufficient_scope for field activity",
+		},
+		{
+			name:      "unknown field",
+			payload:   `{"input":"auth0|123","fields":["primary_email"]}`,
+			wantError: `unknown field "primary_email"`,
+		},
+		{
+			name:      "malformed request",
+			payload:   `{"input":`,
+			wantError: "failed_to_unmarshal_request",
+		},
+		{
+			name:      "missing input",
+			payload:   `{"fields":["username"]}`,
+			wantError: "input is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := &mockUserServiceReader{
+				metadataLookupFunc: func(ctx context.Context, input string) (*model.User, error) {
+					return &model.User{UserID: "auth0|123"}, nil
+				},
+				getUserFunc: func(ctx context.Context, user *model.User) (*model.User, error) {
+					return &model.User{
+						UserID:   "auth0|123",
+						Username: "john.doe",
+						UserMetadata: &model.UserMetadata{
+							Name:    converters.StringPtr("John Doe"),
+							Picture: converters.StringPtr("https://example.com/avatar.jpg"),
+						},
+						Activity: &model.UserActivity{LastLogin: &lastLogin},
+					}, nil
+				},
+			}
+			orchestrator := NewMessageHandlerOrchestrator(WithUserReaderForMessageHandler(reader))
+
+			result, err := orchestrator.GetUserMetadata(ctx, &mockTransportMessenger{data: []byte(tt.payload)})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var response struct {
+				Success bool            `json:"success"`
+				Error   string          `json:"error"`
+				Data    json.RawMessage `json:"data"`
+			}
+			if err := json.Unmarshal(result, &response); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+			if tt.wantError != "" {
+				if response.Success || response.Error != tt.wantError {
+					t.Fatalf("expected error %q, got %s", tt.wantError, string(result))
+				}
+				return
+			}
+			if !response.Success {
+				t.Fatalf("expected success, got %s", string(result))
+			}
+			if string(response.Data) != tt.wantData {
+				t.Errorf("data = %s, want %s", string(response.Data), tt.wantData)
+			}
+		})
+	}
+}
+
+func TestMessageHandlerOrchestrator_GetUserMetadata_Stale(t *testing.T) {
+	for _, stale := range []bool{false, true} {
+		reader := &mockUserServiceReader{
+			getUserFunc: func(ctx context.Context, user *model.User) (*model.User, error) {
+				return &model.User{UserID: user.UserID, UserMetadata: &model.UserMetadata{}, Stale: stale}, nil
+			},
+		}
+		orchestrator := NewMessageHandlerOrchestrator(WithUserReaderForMessageHandler(reader))
+
+		result, err := orchestrator.GetUserMetadata(context.Background(), &mockTransportMessenger{data: []byte("auth0|123")})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var response UserDataResponse
+		if err := json.Unmarshal(result, &response); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		if !response.Success || response.Stale != stale {
+			t.Errorf("stale user %v: got reply %s", stale, string(result))
+		}
+	}
+}
+
+func TestMessageHandlerOrchestrator_GetUserMetadata_ProfileSharing(t *testing.T) {
+	ctx := context.Background()
+	ownToken, err := jwt.GenerateTestAccessToken("auth0|123", "https://issuer/", "aud", "openid", time.Hour)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	optedOut := false
+
+	tests := []struct {
+		name     string
+		share    *bool
+		payload  string
+		wantData string
+	}{
+		{
+			name:     "other services get the username only of a user who opted out",
+			share:    &optedOut,
+			payload:  `{"input":"auth0|123"}`,
+			wantData: `{"username":"john.doe"}`,
+		},
+		{
+			name:     "selected fields are minimized too",
+			share:    &optedOut,
+			payload:  `{"input":"john.doe","fields":["name","picture"]}`,
+			wantData: `{"username":"john.doe"}`,
+		},
+		{
+			name:     "the user's own token gets the full profile",
+			share:    &optedOut,
+			payload:  ownToken,
+			wantData: `{"name":"John Doe","privacy":{"share_profile":false},"display_name":"john.doe"}`,
+		},
+		{
+			name:     "profiles are shared unless the user opted out",
+			payload:  `{"input":"auth0|123","fields":["name"]}`,
+			wantData: `{"name":"John Doe"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := &mockUserServiceReader{
+				metadataLookupFunc: func(ctx context.Context, input string) (*model.User, error) {
+					return &model.User{UserID: "auth0|123"}, nil
+				},
+				getUserFunc: func(ctx context.Context, user *model.User) (*model.User, error) {
+					metadata := &model.UserMetadata{Name: converters.StringPtr("John Doe")}
+					if tt.share != nil {
+						metadata.Privacy = &model.UserPrivacy{ShareProfile: tt.share}
+					}
+					return &model.User{UserID: "auth0|123", Username: "john.doe", UserMetadata: metadata}, nil
+				},
+			}
+			orchestrator := NewMessageHandlerOrchestrator(WithUserReaderForMessageHandler(reader))
+
+			result, err := orchestrator.GetUserMetadata(ctx, &mockTransportMessenger{data: []byte(tt.payload)})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var response struct {
+				Success bool            `json:"success"`
+				Data    json.RawMessage `json:"data"`
+			}
+			if err := json.Unmarshal(result, &response); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+			if !response.Success {
+				t.Fatalf("expected success, got %s", string(result))
+			}
+			if string(response.Data) != tt.wantData {
+				t.Errorf("data = %s, want %s", string(response.Data), tt.wantData)
+			}
+		})
+	}
+}
+
 func TestMessageHandlerOrchestrator_GetUserMetadata_NoUserReader(t *testing.T) {
 	// Test when userReader is nil
 	orchestrator := &messageHandlerOrchestrator{
@@ -3680,3 +4088,175 @@ func TestMessageHandlerOrchestrator_AddAlias(t *testing.T) {
 		}
 	})
 }
+
+func TestMessageHandlerOrchestrator_EmailToUsername_RequireVerified(t *testing.T) {
+	ctx := context.Background()
+
+	searched := []string{}
+	userReader := &mockUserServiceReader{
+		searchUserFunc: func(ctx context.Context, user *model.User, criteria string) (*model.User, error) {
+			searched = append(searched, criteria)
+			switch criteria {
+			case constants.CriteriaTypeEmail:
+				// matched on an unverified primary email
+				return &model.User{UserID: "auth0|unverified", Username: "squatter", PrimaryEmail: "invitee@example.com"}, nil
+			case constants.CriteriaTypeAlternateEmail:
+				return &model.User{
+					UserID:          "auth0|owner",
+					Username:        "owner",
+					AlternateEmails: []model.Email{{Email: "invitee@example.com", Verified: true}},
+				}, nil
+			}
+			return nil, errors.NewNotFound("user not found")
+		},
+	}
+
+	strict := NewMessageHandlerOrchestrator(
+		WithUserReaderForMessageHandler(userReader),
+		WithVerifiedEmailLookupsForMessageHandler(true),
+	)
+	result, err := strict.EmailToUsername(ctx, &mockTransportMessenger{data: []byte("invitee@example.com")})
+	if err != nil {
+		t.Fatalf("EmailToUsername() unexpected error: %v", err)
+	}
+	if string(result) != "owner" {
+		t.Errorf("EmailToUsername() = %q, want %q", result, "owner")
+	}
+	if len(searched) != 2 {
+		t.Errorf("expected the unverified primary match to fall through to the alternate search, got %v", searched)
+	}
+
+	lenient := NewMessageHandlerOrchestrator(WithUserReaderForMessageHandler(userReader))
+	result, err = lenient.EmailToUsername(ctx, &mockTransportMessenger{data: []byte("invitee@example.com")})
+	if err != nil {
+		t.Fatalf("EmailToUsername() unexpected error: %v", err)
+	}
+	if string(result) != "squatter" {
+		t.Errorf("EmailToUsername() without the gate = %q, want %q", result, "squatter")
+	}
+}
+
+func TestMessageHandlerOrchestrator_EmailToUsername_StrictEmailVerificationFlag(t *testing.T) {
+	userReader := &mockUserServiceReader{
+		searchUserFunc: func(ctx context.Context, user *model.User, criteria string) (*model.User, error) {
+			if criteria == constants.CriteriaTypeEmail {
+				return &model.User{UserID: "auth0|unverified", Username: "squatter", PrimaryEmail: "invitee@example.com"}, nil
+			}
+			return nil, errors.NewNotFound("user not found")
+		},
+	}
+	orchestrator := NewMessageHandlerOrchestrator(
+		WithUserReaderForMessageHandler(userReader),
+		WithFeatureFlagsForMessageHandler(featureflag.NewSet(map[string]featureflag.Flag{
+			featureflag.StrictEmailVerification: {Callers: []string{"project-service"}},
+		})),
+	)
+	msg := &mockTransportMessenger{data: []byte("invitee@example.com")}
+
+	// the rollout reaches project-service only
+	ctx := featureflag.WithCaller(context.Background(), "project-service")
+	result, err := orchestrator.EmailToUsername(ctx, msg)
+	if err != nil {
+		t.Fatalf("EmailToUsername() unexpected error: %v", err)
+	}
+	if !strings.Contains(string(result), "not found") {
+		t.Errorf("EmailToUsername() for a rolled out caller = %q, want a not found error", result)
+	}
+
+	ctx = featureflag.WithCaller(context.Background(), "committee-service")
+	result, err = orchestrator.EmailToUsername(ctx, msg)
+	if err != nil {
+		t.Fatalf("EmailToUsername() unexpected error: %v", err)
+	}
+	if string(result) != "squatter" {
+		t.Errorf("EmailToUsername() for another caller = %q, want %q", result, "squatter")
+	}
+}
+
+func TestMessageHandlerOrchestrator_UpdateUser_Address(t *testing.T) {
+	ctx := context.Background()
+
+	var stored *model.User
+	orchestrator := NewMessageHandlerOrchestrator(
+		WithUserWriterForMessageHandler(&mockUserServiceWriter{updateUserFunc: func(ctx context.Context, user *model.User) (*model.User, error) {
+			stored = user
+			return user, nil
+		}}),
+	)
+	type response struct {
+		Success bool               `json:"success"`
+		Error   string             `json:"error"`
+		Data    model.AddressError `json:"data"`
+	}
+	update := func(t *testing.T, metadata map[string]string) response {
+		t.Helper()
+		payload, _ := json.Marshal(map[string]any{"token": "token", "user_metadata": metadata})
+		result, err := orchestrator.UpdateUser(ctx, &mockTransportMessenger{data: payload})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var r response
+		if err := json.Unmarshal(result, &r); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		return r
+	}
+
+	t.Run("stores ISO codes", func(t *testing.T) {
+		r := update(t, map[string]string{"country": "United States", "state_province": "california"})
+		if !r.Success {
+			t.Fatalf("expected success, got %q", r.Error)
+		}
+		if *stored.UserMetadata.Country != "US" || *stored.UserMetadata.StateProvince != "US-CA" {
+			t.Errorf("stored %q %q, want US US-CA", *stored.UserMetadata.Country, *stored.UserMetadata.StateProvince)
+		}
+	})
+
+	t.Run("near misses are replied with suggestions", func(t *testing.T) {
+		stored = nil
+		r := update(t, map[string]string{"country": "Untied States"})
+		if r.Success || !strings.Contains(r.Error, "did you mean US (United States)") {
+			t.Fatalf("expected a suggestion in the error, got %q", r.Error)
+		}
+		if r.Data.Field != "country" || len(r.Data.Suggestions) == 0 || r.Data.Suggestions[0].Code != "US" {
+			t.Errorf("data = %+v, want country with US suggested", r.Data)
+		}
+		if stored != nil {
+			t.Errorf("user was updated despite the invalid country")
+		}
+	})
+}
+
+func TestMessageHandlerOrchestrator_UpdateUser_MetadataSize(t *testing.T) {
+	updated := false
+	orchestrator := NewMessageHandlerOrchestrator(
+		WithUserWriterForMessageHandler(&mockUserServiceWriter{updateUserFunc: func(ctx context.Context, user *model.User) (*model.User, error) {
+			updated = true
+			return user, nil
+		}}),
+		WithMetadataSizeLimitsForMessageHandler(model.MetadataSizeLimits{MaxBytes: 16384, MaxFieldBytes: 32}),
+	)
+
+	payload, _ := json.Marshal(map[string]any{"token": "token", "user_metadata": map[string]string{"job_title": strings.Repeat("a", 40)}})
+	result, err := orchestrator.UpdateUser(context.Background(), &mockTransportMessenger{data: payload})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var response struct {
+		Success bool                    `json:"success"`
+		Error   string                  `json:"error"`
+		Data    model.MetadataSizeError `json:"data"`
+	}
+	if err := json.Unmarshal(result, &response); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if response.Success || response.Error != errMetadataTooLarge {
+		t.Fatalf("expected %s, got %q", errMetadataTooLarge, response.Error)
+	}
+	if response.Data != (model.MetadataSizeError{Field: "job_title", Size: 40, Limit: 32}) {
+		t.Errorf("data = %+v, want job_title over 32", response.Data)
+	}
+	if updated {
+		t.Errorf("user was updated despite the oversize field")
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+)
+
+// errMetadataTooLarge is the error of an update whose metadata is over the
+// size limits, replied with a model.MetadataSizeError as data
+const errMetadataTooLarge = "metadata_too_large"
+
+// WithMetadataSizeLimitsForMessageHandler bounds the metadata updates write
+// to the provider, so an update over the provider's own cap (16KB for Auth0)
+// is refused with a clear error instead of failing there
+func WithMetadataSizeLimitsForMessageHandler(limits model.MetadataSizeLimits) MessageHandlerOrchestratorOption {
+	return func(m *messageHandlerOrchestrator) {
+		m.metadataSizeLimits = limits
+	}
+}
+
+// oversizeMetadataResponse returns the reply refusing metadata over limits,
+// or nil when it's within them
+func (m *messageHandlerOrchestrator) oversizeMetadataResponse(ctx context.Context, metadata *model.UserMetadata, limits model.MetadataSizeLimits) []byte {
+	err := metadata.CheckSize(limits)
+	if err == nil {
+		return nil
+	}
+	var sizeErr *model.MetadataSizeError
+	if !errors.As(err, &sizeErr) {
+		return m.errorResponse(err.Error())
+	}
+
+	slog.WarnContext(ctx, "metadata update over the size limits", "error", sizeErr)
+	responseJSON, errMarshal := json.Marshal(UserDataResponse{Success: false, Error: errMetadataTooLarge, Data: sizeErr})
+	if errMarshal != nil {
+		return m.errorResponse("failed to marshal response")
+	}
+	return responseJSON
+}
@@ -0,0 +1,177 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/tenant"
+)
+
+const (
+	// errInternal is the reply to a request whose handler panicked
+	errInternal = "internal error"
+	// errQuarantined is the reply to a request whose payload is quarantined
+	errQuarantined = "payload quarantined"
+	// errPayloadTooLarge is the reply to a request whose payload is over the
+	// limit of its subject, with a payloadSize as data
+	errPayloadTooLarge = "payload_too_large"
+)
+
+// Outcomes of a request recorded by the panic metrics
+const (
+	panicOutcomeRecovered   = "recovered"
+	panicOutcomeQuarantined = "quarantined"
+	panicOutcomeRefused     = "refused"
+)
+
+// Middleware wraps a handler with behavior shared by every request, such as
+// logging, authorization or recovery
+type Middleware func(next port.Handler) port.Handler
+
+// Chain wraps handler with middlewares, the first being the outermost: it
+// sees the request first and the reply last
+func Chain(handler port.Handler, middlewares ...Middleware) port.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+// Recover returns the middleware that turns a panic of the next handler into
+// an internal error reply, so the caller doesn't wait for its timeout. The
+// panic is logged with its stack and counted; a handler that replied before
+// panicking isn't replied to again. The reply's message references the
+// payload signature, which quarantined payloads are stored under.
+//
+// With a quarantine, payloads that panicked their handler too often are
+// refused without reaching it; quarantine may be nil.
+func Recover(quarantine *PanicQuarantine) Middleware {
+	panics, _ := meter.Int64Counter("auth_service.handler.panics",
+		metric.WithDescription("Requests whose handler panicked or whose payload was quarantined, by subject and outcome"))
+
+	return func(next port.Handler) port.Handler {
+		return port.HandlerFunc(func(ctx context.Context, msg port.TransportMessenger) {
+			signature := payloadSignature(msg.Subject(), msg.Data())
+			if quarantine != nil && quarantine.Quarantined(signature) {
+				recordPanic(ctx, panics, msg, panicOutcomeRefused)
+				respondPanic(ctx, msg, errQuarantined, signature)
+				return
+			}
+
+			replier := &replyTracker{TransportMessenger: msg}
+			defer func() {
+				r := recover()
+				if r == nil {
+					return
+				}
+				panicked := fmt.Sprint(r)
+				slog.ErrorContext(ctx, "panic in request handler",
+					"subject", msg.Subject(),
+					"signature", signature,
+					"panic", panicked,
+					"stack", string(debug.Stack()),
+				)
+				if !replier.replied.Load() {
+					respondPanic(ctx, msg, errInternal, signature)
+				}
+				outcome := panicOutcomeRecovered
+				if quarantine != nil && quarantine.RecordPanic(ctx, signature, msg, panicked) {
+					outcome = panicOutcomeQuarantined
+				}
+				recordPanic(ctx, panics, msg, outcome)
+			}()
+			next.Handle(ctx, replier)
+		})
+	}
+}
+
+func recordPanic(ctx context.Context, panics metric.Int64Counter, msg port.TransportMessenger, outcome string) {
+	panics.Add(context.WithoutCancel(ctx), 1, tenant.Attributes(ctx,
+		attribute.String("subject", msg.Subject()),
+		attribute.String("outcome", outcome),
+	))
+}
+
+// respondPanic replies to a request that panicked its handler, or whose
+// payload is quarantined, with the payload signature as a reference
+func respondPanic(ctx context.Context, msg port.TransportMessenger, message, signature string) {
+	response, err := json.Marshal(UserDataResponse{Success: false, Error: message, Message: "reference " + signature})
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to marshal error response", "error", err)
+		return
+	}
+	if err := msg.Respond(response); err != nil {
+		slog.ErrorContext(ctx, "failed to respond to request", "error", err)
+	}
+}
+
+// replyTracker records whether a reply was sent through the messenger
+type replyTracker struct {
+	port.TransportMessenger
+	replied atomic.Bool
+}
+
+// Respond marks the request replied and forwards the reply
+func (r *replyTracker) Respond(data []byte) error {
+	r.replied.Store(true)
+	return r.TransportMessenger.Respond(data)
+}
+
+// Unwrap returns the wrapped messenger
+func (r *replyTracker) Unwrap() port.TransportMessenger {
+	return r.TransportMessenger
+}
+
+// respondError replies to msg with an unsuccessful response carrying message
+func respondError(ctx context.Context, msg port.TransportMessenger, message string) {
+	response, err := json.Marshal(UserDataResponse{Success: false, Error: message})
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to marshal error response", "error", err)
+		return
+	}
+	if err := msg.Respond(response); err != nil {
+		slog.ErrorContext(ctx, "failed to respond to request", "error", err)
+	}
+}
+
+// payloadSize is the data of a payload_too_large reply
+type payloadSize struct {
+	Size  int `json:"size"`
+	Limit int `json:"limit"`
+}
+
+// LimitPayloadSize returns the middleware refusing requests whose payload is
+// larger than limit returns for their subject, before anything decodes it. A
+// limit of zero or less lets every payload through.
+func LimitPayloadSize(limit func(subject string) int) Middleware {
+	return func(next port.Handler) port.Handler {
+		return port.HandlerFunc(func(ctx context.Context, msg port.TransportMessenger) {
+			allowed := limit(msg.Subject())
+			if size := len(msg.Data()); allowed > 0 && size > allowed {
+				slog.WarnContext(ctx, "request payload over the size limit",
+					"subject", msg.Subject(), "size", size, "limit", allowed)
+				response, err := json.Marshal(UserDataResponse{Success: false, Error: errPayloadTooLarge, Data: payloadSize{Size: size, Limit: allowed}})
+				if err != nil {
+					slog.ErrorContext(ctx, "failed to marshal error response", "error", err)
+					return
+				}
+				if err := msg.Respond(response); err != nil {
+					slog.ErrorContext(ctx, "failed to respond to request", "error", err)
+				}
+				return
+			}
+			next.Handle(ctx, msg)
+		})
+	}
+}
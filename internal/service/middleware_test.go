@@ -0,0 +1,99 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+)
+
+// tracing returns a middleware appending name to calls around the next handler
+func tracing(name string, calls *[]string) Middleware {
+	return func(next port.Handler) port.Handler {
+		return port.HandlerFunc(func(ctx context.Context, msg port.TransportMessenger) {
+			*calls = append(*calls, name+" in")
+			next.Handle(ctx, msg)
+			*calls = append(*calls, name+" out")
+		})
+	}
+}
+
+func TestChain(t *testing.T) {
+	var calls []string
+	handler := port.HandlerFunc(func(context.Context, port.TransportMessenger) {
+		calls = append(calls, "handler")
+	})
+
+	Chain(handler, tracing("first", &calls), tracing("second", &calls)).Handle(context.Background(), &repliedMessenger{})
+
+	assert.Equal(t, []string{"first in", "second in", "handler", "second out", "first out"}, calls)
+}
+
+func TestRecover(t *testing.T) {
+	t.Run("replies an internal error to a panicking handler", func(t *testing.T) {
+		msg := &repliedMessenger{}
+		handler := port.HandlerFunc(func(context.Context, port.TransportMessenger) {
+			panic("nil map")
+		})
+
+		require.NotPanics(t, func() { Chain(handler, Recover(nil)).Handle(context.Background(), msg) })
+
+		var response UserDataResponse
+		require.NoError(t, json.Unmarshal(msg.replied, &response))
+		assert.False(t, response.Success)
+		assert.Equal(t, errInternal, response.Error)
+	})
+
+	t.Run("keeps the reply sent before the panic", func(t *testing.T) {
+		msg := &repliedMessenger{}
+		handler := port.HandlerFunc(func(_ context.Context, msg port.TransportMessenger) {
+			_ = msg.Respond([]byte(`{"success":true}`))
+			panic("after reply")
+		})
+
+		Chain(handler, Recover(nil)).Handle(context.Background(), msg)
+
+		assert.JSONEq(t, `{"success":true}`, string(msg.replied))
+	})
+
+	t.Run("passes through handlers that don't panic", func(t *testing.T) {
+		msg := &repliedMessenger{}
+		handler := port.HandlerFunc(func(_ context.Context, msg port.TransportMessenger) {
+			_ = msg.Respond([]byte("zephyr.stormwind"))
+		})
+
+		Chain(handler, Recover(nil)).Handle(context.Background(), msg)
+
+		assert.Equal(t, "zephyr.stormwind", string(msg.replied))
+	})
+}
+
+func TestLimitPayloadSize(t *testing.T) {
+	handled := false
+	handler := Chain(port.HandlerFunc(func(context.Context, port.TransportMessenger) { handled = true }),
+		LimitPayloadSize(func(string) int { return 16 }))
+
+	msg := &repliedMessenger{data: []byte(`{"auth_token":"a"}`)}
+	handler.Handle(context.Background(), msg)
+
+	assert.False(t, handled)
+	var response struct {
+		Success bool        `json:"success"`
+		Error   string      `json:"error"`
+		Data    payloadSize `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(msg.replied, &response))
+	assert.False(t, response.Success)
+	assert.Equal(t, errPayloadTooLarge, response.Error)
+	assert.Equal(t, payloadSize{Size: 18, Limit: 16}, response.Data)
+
+	handler.Handle(context.Background(), &repliedMessenger{data: []byte(`{}`)})
+	assert.True(t, handled)
+}
@@ -0,0 +1,78 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/jwt"
+)
+
+func TestMessageHandlerOrchestrator_GetUserMetadata_OnBehalfOf(t *testing.T) {
+	ctx := context.Background()
+
+	staffToken, err := jwt.GenerateTestAccessToken("auth0|staff", "https://test.any.com/", "https://test.any.com/api/v2/",
+		constants.UserReadOnBehalfRequiredScope+" "+constants.UserReadActivityRequiredScope, time.Hour)
+	require.NoError(t, err)
+	userToken, err := jwt.GenerateSimpleTestAccessToken("auth0|alice", time.Hour)
+	require.NoError(t, err)
+
+	name := "Alice Smith"
+	logins := 3
+	reader := &mockUserServiceReader{
+		metadataLookupFunc: func(ctx context.Context, input string) (*model.User, error) {
+			if _, isJWT := jwt.LooksLikeJWT(input); isJWT {
+				return &model.User{Token: input}, nil
+			}
+			return &model.User{UserID: input, Sub: input}, nil
+		},
+		getUserFunc: func(ctx context.Context, user *model.User) (*model.User, error) {
+			return &model.User{
+				UserID:       user.UserID,
+				Username:     "alice",
+				UserMetadata: &model.UserMetadata{Name: &name},
+				Activity:     &model.UserActivity{LoginsCount: &logins},
+			}, nil
+		},
+	}
+	orchestrator := NewMessageHandlerOrchestrator(WithUserReaderForMessageHandler(reader))
+
+	read := func(t *testing.T, request map[string]any) map[string]any {
+		t.Helper()
+		payload, _ := json.Marshal(request)
+		result, err := orchestrator.GetUserMetadata(ctx, &mockTransportMessenger{data: payload})
+		require.NoError(t, err)
+		var response map[string]any
+		require.NoError(t, json.Unmarshal(result, &response))
+		return response
+	}
+
+	t.Run("returns the user's own read", func(t *testing.T) {
+		response := read(t, map[string]any{"input": staffToken, "on_behalf_of": "auth0|alice"})
+		require.Equal(t, true, response["success"], response["error"])
+		data := response["data"].(map[string]any)
+		assert.Equal(t, name, data["name"])
+		assert.NotContains(t, data, "activity", "the staff member's activity scope must not apply")
+	})
+
+	t.Run("requires the on behalf scope", func(t *testing.T) {
+		response := read(t, map[string]any{"input": userToken, "on_behalf_of": "auth0|bob"})
+		assert.Equal(t, false, response["success"])
+		assert.Equal(t, "insufficient_scope", response["error"])
+	})
+
+	t.Run("never takes the user's token", func(t *testing.T) {
+		response := read(t, map[string]any{"input": staffToken, "on_behalf_of": userToken})
+		assert.Equal(t, false, response["success"])
+		assert.Equal(t, "on_behalf_of must be a sub or username, not a token", response["error"])
+	})
+}
@@ -0,0 +1,153 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/tenant"
+)
+
+const (
+	// outboxClaimTimeout is how long a replica may hold an event it is
+	// publishing before another replica takes it over
+	outboxClaimTimeout = 30 * time.Second
+	// outboxBatchSize bounds the events one dispatch publishes
+	outboxBatchSize = 100
+)
+
+// Outcomes of an event recorded by the outbox metrics
+const (
+	outboxOutcomeQueued    = "queued"
+	outboxOutcomeDelivered = "delivered"
+	outboxOutcomeFailed    = "failed"
+	outboxOutcomeBypassed  = "bypassed"
+)
+
+// OutboxPublisher publishes events through an outbox: an event is stored
+// before it is published and deleted once it is, so an event whose
+// publication fails, or is interrupted by the process stopping, is published
+// by a later dispatch instead of being lost. Events are published at least
+// once and, across dispatches, not necessarily in order.
+type OutboxPublisher struct {
+	outbox    port.Outbox
+	publisher port.EventPublisher
+	now       func() time.Time
+
+	events metric.Int64Counter
+}
+
+// NewOutboxPublisher returns a publisher storing events in outbox before
+// publishing them with publisher
+func NewOutboxPublisher(outbox port.Outbox, publisher port.EventPublisher) *OutboxPublisher {
+	events, _ := meter.Int64Counter("auth_service.outbox.events",
+		metric.WithDescription("Events through the outbox, by subject and outcome"))
+
+	return &OutboxPublisher{
+		outbox:    outbox,
+		publisher: publisher,
+		now:       time.Now,
+		events:    events,
+	}
+}
+
+// Publish stores the event and publishes it right away. A failed publication
+// is left to the dispatcher and isn't reported; only an event that could be
+// neither stored nor published returns an error.
+func (o *OutboxPublisher) Publish(ctx context.Context, subject string, data []byte) error {
+	event := &model.OutboxEvent{
+		ID:        rand.Text(),
+		Subject:   subject,
+		Data:      data,
+		CreatedAt: o.now().UTC(),
+	}
+	if err := o.outbox.Add(ctx, event); err != nil {
+		// publishing without the outbox still delivers the event while the
+		// process lives
+		slog.WarnContext(ctx, "failed to store outbox event, publishing it directly",
+			"subject", subject, "error", err)
+		o.record(ctx, subject, outboxOutcomeBypassed)
+		return o.publisher.Publish(ctx, subject, data)
+	}
+	o.record(ctx, subject, outboxOutcomeQueued)
+
+	// the caller's reply shouldn't wait on a retry, and the event outlives a
+	// cancelled request
+	o.deliver(context.WithoutCancel(ctx), event)
+	return nil
+}
+
+// Dispatch publishes the stored events no replica is publishing. It is run
+// periodically, on every replica.
+func (o *OutboxPublisher) Dispatch(ctx context.Context) error {
+	events, err := o.outbox.Pending(ctx, outboxBatchSize)
+	if err != nil {
+		return err
+	}
+	for _, event := range events {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if event.ClaimedAt != nil && o.now().Sub(*event.ClaimedAt) < outboxClaimTimeout {
+			continue
+		}
+		o.deliver(ctx, event)
+	}
+	return nil
+}
+
+// deliver claims and publishes event, deleting it once published and
+// releasing it with the error otherwise. An event claimed by another replica
+// since it was read is left to that replica.
+func (o *OutboxPublisher) deliver(ctx context.Context, event *model.OutboxEvent) {
+	claimedAt := o.now().UTC()
+	event.ClaimedAt = &claimedAt
+	event.Attempts++
+	claimed, err := o.outbox.Update(ctx, event)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to claim outbox event", "subject", event.Subject, "id", event.ID, "error", err)
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	if err := o.publisher.Publish(ctx, event.Subject, event.Data); err != nil {
+		slog.WarnContext(ctx, "failed to publish outbox event, it will be retried",
+			"subject", event.Subject,
+			"id", event.ID,
+			"attempts", event.Attempts,
+			"error", err,
+		)
+		o.record(ctx, event.Subject, outboxOutcomeFailed)
+		event.ClaimedAt = nil
+		event.LastError = err.Error()
+		if _, err := o.outbox.Update(ctx, event); err != nil {
+			// the claim expires, so the event is retried anyway
+			slog.WarnContext(ctx, "failed to release outbox event", "subject", event.Subject, "id", event.ID, "error", err)
+		}
+		return
+	}
+
+	o.record(ctx, event.Subject, outboxOutcomeDelivered)
+	if err := o.outbox.Delete(ctx, event); err != nil {
+		// the claim expires and the event is published again
+		slog.WarnContext(ctx, "failed to delete published outbox event", "subject", event.Subject, "id", event.ID, "error", err)
+	}
+}
+
+func (o *OutboxPublisher) record(ctx context.Context, subject, outcome string) {
+	o.events.Add(context.WithoutCancel(ctx), 1, tenant.Attributes(ctx,
+		attribute.String("subject", subject),
+		attribute.String("outcome", outcome),
+	))
+}
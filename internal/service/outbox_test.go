@@ -0,0 +1,167 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+)
+
+// memoryOutbox is an in-memory port.Outbox
+type memoryOutbox struct {
+	mu       sync.Mutex
+	events   map[string]model.OutboxEvent
+	revision uint64
+	addErr   error
+}
+
+func newMemoryOutbox() *memoryOutbox {
+	return &memoryOutbox{events: make(map[string]model.OutboxEvent)}
+}
+
+func (o *memoryOutbox) Add(_ context.Context, event *model.OutboxEvent) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.addErr != nil {
+		return o.addErr
+	}
+	o.revision++
+	event.Revision = o.revision
+	o.events[event.ID] = *event
+	return nil
+}
+
+func (o *memoryOutbox) Pending(_ context.Context, limit int) ([]*model.OutboxEvent, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var events []*model.OutboxEvent
+	for _, event := range o.events {
+		copied := event
+		events = append(events, &copied)
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].CreatedAt.Before(events[j].CreatedAt) })
+	return events[:min(limit, len(events))], nil
+}
+
+func (o *memoryOutbox) Update(_ context.Context, event *model.OutboxEvent) (bool, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	stored, ok := o.events[event.ID]
+	if !ok || stored.Revision != event.Revision {
+		return false, nil
+	}
+	o.revision++
+	event.Revision = o.revision
+	o.events[event.ID] = *event
+	return true, nil
+}
+
+func (o *memoryOutbox) Delete(_ context.Context, event *model.OutboxEvent) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	delete(o.events, event.ID)
+	return nil
+}
+
+func (o *memoryOutbox) stored() []model.OutboxEvent {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var events []model.OutboxEvent
+	for _, event := range o.events {
+		events = append(events, event)
+	}
+	return events
+}
+
+// flakyPublisher records the events it publishes and fails while err is set
+type flakyPublisher struct {
+	mu        sync.Mutex
+	err       error
+	published []string
+}
+
+func (p *flakyPublisher) Publish(_ context.Context, subject string, data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	p.published = append(p.published, subject+" "+string(data))
+	return nil
+}
+
+func TestOutboxPublisher(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("published and deleted", func(t *testing.T) {
+		outbox, publisher := newMemoryOutbox(), &flakyPublisher{}
+		require.NoError(t, NewOutboxPublisher(outbox, publisher).Publish(ctx, "lfx.user_profile.updated", []byte(`{"user_id":"u1"}`)))
+		assert.Equal(t, []string{`lfx.user_profile.updated {"user_id":"u1"}`}, publisher.published)
+		assert.Empty(t, outbox.stored())
+	})
+
+	t.Run("failed publish is kept and dispatched later", func(t *testing.T) {
+		outbox, publisher := newMemoryOutbox(), &flakyPublisher{err: errors.New("nats: connection closed")}
+		o := NewOutboxPublisher(outbox, publisher)
+		require.NoError(t, o.Publish(ctx, "lfx.user_profile.updated", []byte(`{"user_id":"u1"}`)))
+
+		stored := outbox.stored()
+		require.Len(t, stored, 1)
+		assert.Equal(t, 1, stored[0].Attempts)
+		assert.Equal(t, "nats: connection closed", stored[0].LastError)
+		assert.Nil(t, stored[0].ClaimedAt, "the claim is released")
+
+		publisher.err = nil
+		require.NoError(t, o.Dispatch(ctx))
+		assert.Equal(t, []string{`lfx.user_profile.updated {"user_id":"u1"}`}, publisher.published)
+		assert.Empty(t, outbox.stored())
+	})
+
+	t.Run("events claimed by another replica are skipped until the claim expires", func(t *testing.T) {
+		outbox, publisher := newMemoryOutbox(), &flakyPublisher{}
+		now := time.Now()
+		claimed := now.Add(-time.Second)
+		require.NoError(t, outbox.Add(ctx, &model.OutboxEvent{ID: "e1", Subject: "s", Data: []byte("1"), CreatedAt: now, ClaimedAt: &claimed}))
+
+		o := NewOutboxPublisher(outbox, publisher)
+		require.NoError(t, o.Dispatch(ctx))
+		assert.Empty(t, publisher.published)
+
+		o.now = func() time.Time { return now.Add(outboxClaimTimeout) }
+		require.NoError(t, o.Dispatch(ctx))
+		assert.Equal(t, []string{"s 1"}, publisher.published)
+	})
+
+	t.Run("an event updated since it was read is left alone", func(t *testing.T) {
+		outbox, publisher := newMemoryOutbox(), &flakyPublisher{}
+		event := &model.OutboxEvent{ID: "e1", Subject: "s", Data: []byte("1"), CreatedAt: time.Now()}
+		require.NoError(t, outbox.Add(ctx, event))
+		stale := *event
+		_, err := outbox.Update(ctx, event)
+		require.NoError(t, err)
+
+		NewOutboxPublisher(outbox, publisher).deliver(ctx, &stale)
+		assert.Empty(t, publisher.published)
+		assert.Len(t, outbox.stored(), 1)
+	})
+
+	t.Run("published directly when the outbox is unavailable", func(t *testing.T) {
+		outbox, publisher := newMemoryOutbox(), &flakyPublisher{}
+		outbox.addErr = errors.New("kv unavailable")
+		require.NoError(t, NewOutboxPublisher(outbox, publisher).Publish(ctx, "s", []byte("1")))
+		assert.Equal(t, []string{"s 1"}, publisher.published)
+
+		publisher.err = errors.New("nats: connection closed")
+		assert.Error(t, NewOutboxPublisher(outbox, publisher).Publish(ctx, "s", []byte("2")))
+	})
+}
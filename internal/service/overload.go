@@ -0,0 +1,39 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+)
+
+// errServiceBusy is the reply to a request the transport had no worker
+// capacity for; it is safe to retry
+const errServiceBusy = "service busy, retry later"
+
+// RefuseWhenOverloaded returns the middleware replying service busy to the
+// requests the transport marked port.WithOverloaded, without reaching their
+// handler. Placed after the envelope and localization, the refusal carries
+// their error code and typed envelope like other replies.
+func RefuseWhenOverloaded() Middleware {
+	return func(next port.Handler) port.Handler {
+		return port.HandlerFunc(func(ctx context.Context, msg port.TransportMessenger) {
+			if !port.Overloaded(ctx) {
+				next.Handle(ctx, msg)
+				return
+			}
+			response, err := json.Marshal(UserDataResponse{Success: false, Error: errServiceBusy})
+			if err != nil {
+				slog.ErrorContext(ctx, "failed to marshal error response", "error", err)
+				return
+			}
+			if err := msg.Respond(response); err != nil {
+				slog.ErrorContext(ctx, "failed to respond to request", "error", err)
+			}
+		})
+	}
+}
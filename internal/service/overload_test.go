@@ -0,0 +1,40 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/i18n"
+)
+
+func TestRefuseWhenOverloaded(t *testing.T) {
+	catalog, err := i18n.Load()
+	require.NoError(t, err)
+
+	handled := 0
+	handler := Chain(port.HandlerFunc(func(_ context.Context, msg port.TransportMessenger) {
+		handled++
+		_ = msg.Respond([]byte(`{"success":true}`))
+	}), LocalizeErrors(catalog), RefuseWhenOverloaded())
+
+	handler.Handle(context.Background(), &repliedMessenger{data: []byte(`{}`)})
+	assert.Equal(t, 1, handled)
+
+	msg := &repliedMessenger{data: []byte(`{}`)}
+	handler.Handle(port.WithOverloaded(context.Background()), msg)
+	assert.Equal(t, 1, handled, "an overloaded request doesn't reach its handler")
+
+	var response map[string]any
+	require.NoError(t, json.Unmarshal(msg.replied, &response))
+	assert.Equal(t, false, response["success"])
+	assert.Equal(t, errServiceBusy, response["error"])
+	assert.Equal(t, "service_busy", response["error_code"], "the refusal carries an error code like other replies")
+}
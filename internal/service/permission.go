@@ -0,0 +1,221 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/cache"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/concurrent"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/redaction"
+)
+
+const (
+	// permissionBatchMaxSize bounds the checks accepted in a single batch request
+	permissionBatchMaxSize = 100
+	// permissionBatchConcurrency bounds the provider lookups in flight per batch
+	permissionBatchConcurrency = 10
+)
+
+// permissionCheck is the input of has_permission and one item of its batch
+// variant. Exactly one of Permission and Role is set.
+type permissionCheck struct {
+	Sub        string `json:"sub"`
+	Permission string `json:"permission,omitempty"`
+	Role       string `json:"role,omitempty"`
+	// Audience restricts Permission to the permissions of one API
+	Audience string `json:"audience,omitempty"`
+}
+
+func (c *permissionCheck) normalize() {
+	c.Sub = strings.TrimSpace(c.Sub)
+	c.Permission = strings.TrimSpace(c.Permission)
+	c.Role = strings.TrimSpace(c.Role)
+	c.Audience = strings.TrimSpace(c.Audience)
+}
+
+func (c permissionCheck) validate() error {
+	switch {
+	case c.Sub == "":
+		return errs.NewValidation("sub is required")
+	case (c.Permission == "") == (c.Role == ""):
+		return errs.NewValidation("exactly one of permission or role is required")
+	case c.Role != "" && c.Audience != "":
+		return errs.NewValidation("audience only applies to permissions")
+	}
+	return nil
+}
+
+// evaluate reports whether permissions satisfy the check
+func (c permissionCheck) evaluate(permissions *model.UserPermissions) bool {
+	if c.Role != "" {
+		return permissions.HasRole(c.Role)
+	}
+	return permissions.HasPermission(c.Permission, c.Audience)
+}
+
+// permissionCheckResult is the outcome of one check
+type permissionCheckResult struct {
+	permissionCheck
+	Allowed bool   `json:"allowed"`
+	Error   string `json:"error,omitempty"`
+}
+
+// permissionBatchRequest is the input of has_permission.batch
+type permissionBatchRequest struct {
+	Checks []permissionCheck `json:"checks"`
+}
+
+// WithPermissionReaderForMessageHandler sets the permission reader for the message handler orchestrator
+func WithPermissionReaderForMessageHandler(permissionReader port.PermissionReader) MessageHandlerOrchestratorOption {
+	return func(m *messageHandlerOrchestrator) {
+		m.permissionReader = permissionReader
+	}
+}
+
+// WithPermissionCacheForMessageHandler caches a user's roles and permissions
+// for ttl, so repeated checks for the same sub don't reach the provider. A ttl
+// of 0 disables the cache.
+func WithPermissionCacheForMessageHandler(ttl time.Duration, maxEntries int) MessageHandlerOrchestratorOption {
+	return func(m *messageHandlerOrchestrator) {
+		if ttl <= 0 {
+			m.permissions = nil
+			return
+		}
+		m.permissions = cache.New[string, *model.UserPermissions](ttl, maxEntries)
+	}
+}
+
+// HasPermission reports whether a sub holds a named permission or role
+func (m *messageHandlerOrchestrator) HasPermission(ctx context.Context, msg port.TransportMessenger) ([]byte, error) {
+	if m.permissionReader == nil {
+		return m.errorResponse("permission_check_unavailable"), nil
+	}
+
+	var check permissionCheck
+	if err := json.Unmarshal(msg.Data(), &check); err != nil {
+		return m.errorResponse("failed_to_unmarshal_request"), nil
+	}
+	check.normalize()
+	if err := check.validate(); err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+
+	permissions, err := m.userPermissions(ctx, check.Sub)
+	if err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+
+	responseJSON, err := json.Marshal(UserDataResponse{
+		Success: true,
+		Data:    permissionCheckResult{permissionCheck: check, Allowed: check.evaluate(permissions)},
+	})
+	if err != nil {
+		return m.errorResponse("failed to marshal response"), nil
+	}
+	return responseJSON, nil
+}
+
+// HasPermissionBatch evaluates many checks in one request. The permissions of
+// each sub are fetched once; a failed check is reported on its item and
+// doesn't fail the batch.
+func (m *messageHandlerOrchestrator) HasPermissionBatch(ctx context.Context, msg port.TransportMessenger) ([]byte, error) {
+	if m.permissionReader == nil {
+		return m.errorResponse("permission_check_unavailable"), nil
+	}
+
+	var request permissionBatchRequest
+	if err := json.Unmarshal(msg.Data(), &request); err != nil {
+		return m.errorResponse("failed_to_unmarshal_request"), nil
+	}
+	if len(request.Checks) == 0 {
+		return m.errorResponse("checks are required"), nil
+	}
+	if len(request.Checks) > permissionBatchMaxSize {
+		return m.errorResponse(fmt.Sprintf("too many checks: at most %d per request", permissionBatchMaxSize)), nil
+	}
+
+	results := make([]permissionCheckResult, len(request.Checks))
+	fetched := make(map[string]*model.UserPermissions)
+	failed := make(map[string]error)
+	var (
+		mu      sync.Mutex
+		lookups []func() error
+	)
+	for i := range request.Checks {
+		check := request.Checks[i]
+		check.normalize()
+		results[i].permissionCheck = check
+		if err := check.validate(); err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		if _, ok := fetched[check.Sub]; ok {
+			continue
+		}
+		fetched[check.Sub] = nil
+		lookups = append(lookups, func() error {
+			permissions, err := m.userPermissions(ctx, check.Sub)
+			mu.Lock()
+			defer mu.Unlock()
+			fetched[check.Sub], failed[check.Sub] = permissions, err
+			return nil
+		})
+	}
+
+	if err := concurrent.NewWorkerPool(permissionBatchConcurrency).Run(ctx, lookups...); err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+
+	for i := range results {
+		if results[i].Error != "" {
+			continue
+		}
+		if err := failed[results[i].Sub]; err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		results[i].Allowed = results[i].evaluate(fetched[results[i].Sub])
+	}
+
+	slog.DebugContext(ctx, "permission batch evaluated", "checks", len(request.Checks), "lookups", len(lookups))
+
+	responseJSON, err := json.Marshal(UserDataResponse{Success: true, Data: results})
+	if err != nil {
+		return m.errorResponse("failed to marshal response"), nil
+	}
+	return responseJSON, nil
+}
+
+// userPermissions returns the roles and permissions of sub, from the cache
+// when possible
+func (m *messageHandlerOrchestrator) userPermissions(ctx context.Context, sub string) (*model.UserPermissions, error) {
+	if m.permissions != nil {
+		if permissions, ok := m.permissions.Get(sub); ok {
+			return permissions, nil
+		}
+	}
+
+	permissions, err := m.permissionReader.UserPermissions(ctx, sub)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to get user permissions",
+			"error", err,
+			"sub", redaction.Redact(sub),
+		)
+		return nil, err
+	}
+	if m.permissions != nil {
+		m.permissions.Set(sub, permissions)
+	}
+	return permissions, nil
+}
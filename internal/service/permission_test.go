@@ -0,0 +1,184 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+)
+
+// mockPermissionReader serves fixed permissions per sub and counts lookups
+type mockPermissionReader struct {
+	permissions map[string]*model.UserPermissions
+	calls       atomic.Int32
+}
+
+func (m *mockPermissionReader) UserPermissions(ctx context.Context, userID string) (*model.UserPermissions, error) {
+	m.calls.Add(1)
+	permissions, ok := m.permissions[userID]
+	if !ok {
+		return nil, errors.NewNotFound("user not found")
+	}
+	return permissions, nil
+}
+
+func newMockPermissionReader() *mockPermissionReader {
+	return &mockPermissionReader{permissions: map[string]*model.UserPermissions{
+		"auth0|admin": {
+			Roles: []string{"Admin"},
+			Permissions: []model.Permission{
+				{Name: "search:users", Audience: "https://api.example.com/"},
+				{Name: "read:projects", Audience: "https://projects.example.com/"},
+			},
+		},
+		"auth0|jdoe": {Roles: []string{}, Permissions: []model.Permission{}},
+	}}
+}
+
+type permissionResponse[T any] struct {
+	Success bool   `json:"success"`
+	Error   string `json:"error"`
+	Data    T      `json:"data"`
+}
+
+func decodePermissionResponse[T any](t *testing.T, result []byte) permissionResponse[T] {
+	t.Helper()
+	var response permissionResponse[T]
+	if err := json.Unmarshal(result, &response); err != nil {
+		t.Fatalf("failed to unmarshal response %s: %v", result, err)
+	}
+	return response
+}
+
+func TestMessageHandlerOrchestrator_HasPermission(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name        string
+		request     string
+		wantAllowed bool
+		wantError   string
+	}{
+		{name: "permission on any api", request: `{"sub":"auth0|admin","permission":"search:users"}`, wantAllowed: true},
+		{name: "permission on its api", request: `{"sub":"auth0|admin","permission":"search:users","audience":"https://api.example.com/"}`, wantAllowed: true},
+		{name: "permission on another api", request: `{"sub":"auth0|admin","permission":"search:users","audience":"https://projects.example.com/"}`},
+		{name: "role", request: `{"sub":" auth0|admin ","role":"Admin"}`, wantAllowed: true},
+		{name: "role is case sensitive", request: `{"sub":"auth0|admin","role":"admin"}`},
+		{name: "user without permissions", request: `{"sub":"auth0|jdoe","permission":"search:users"}`},
+		{name: "unknown user", request: `{"sub":"auth0|ghost","permission":"search:users"}`, wantError: "user not found"},
+		{name: "missing sub", request: `{"permission":"search:users"}`, wantError: "sub is required"},
+		{name: "permission and role", request: `{"sub":"auth0|admin","permission":"search:users","role":"Admin"}`, wantError: "exactly one of permission or role is required"},
+		{name: "audience on a role", request: `{"sub":"auth0|admin","role":"Admin","audience":"https://api.example.com/"}`, wantError: "audience only applies to permissions"},
+		{name: "invalid json", request: `{`, wantError: "failed_to_unmarshal_request"},
+	}
+
+	orchestrator := NewMessageHandlerOrchestrator(WithPermissionReaderForMessageHandler(newMockPermissionReader()))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := orchestrator.HasPermission(ctx, &mockTransportMessenger{data: []byte(tt.request)})
+			if err != nil {
+				t.Fatalf("HasPermission() unexpected error: %v", err)
+			}
+			response := decodePermissionResponse[permissionCheckResult](t, result)
+			if tt.wantError != "" {
+				if response.Success || response.Error != tt.wantError {
+					t.Errorf("HasPermission() error = %q, want %q", response.Error, tt.wantError)
+				}
+				return
+			}
+			if !response.Success || response.Data.Allowed != tt.wantAllowed {
+				t.Errorf("HasPermission() = %+v, want allowed %v", response, tt.wantAllowed)
+			}
+		})
+	}
+
+	t.Run("no permission reader", func(t *testing.T) {
+		result, _ := NewMessageHandlerOrchestrator().HasPermission(ctx, &mockTransportMessenger{data: []byte(`{"sub":"auth0|admin","role":"Admin"}`)})
+		if response := decodePermissionResponse[permissionCheckResult](t, result); response.Error != "permission_check_unavailable" {
+			t.Errorf("HasPermission() error = %q, want permission_check_unavailable", response.Error)
+		}
+	})
+
+	t.Run("cached", func(t *testing.T) {
+		reader := newMockPermissionReader()
+		cached := NewMessageHandlerOrchestrator(
+			WithPermissionReaderForMessageHandler(reader),
+			WithPermissionCacheForMessageHandler(time.Minute, 10),
+		)
+		for range 3 {
+			if _, err := cached.HasPermission(ctx, &mockTransportMessenger{data: []byte(`{"sub":"auth0|admin","role":"Admin"}`)}); err != nil {
+				t.Fatalf("HasPermission() unexpected error: %v", err)
+			}
+		}
+		if calls := reader.calls.Load(); calls != 1 {
+			t.Errorf("UserPermissions() calls = %d, want 1", calls)
+		}
+	})
+}
+
+func TestMessageHandlerOrchestrator_HasPermissionBatch(t *testing.T) {
+	ctx := context.Background()
+	reader := newMockPermissionReader()
+	orchestrator := NewMessageHandlerOrchestrator(WithPermissionReaderForMessageHandler(reader))
+
+	request := `{"checks":[
+		{"sub":"auth0|admin","permission":"search:users"},
+		{"sub":"auth0|admin","role":"Viewer"},
+		{"sub":"auth0|jdoe","permission":"search:users"},
+		{"sub":"auth0|ghost","role":"Admin"},
+		{"sub":"auth0|jdoe"}
+	]}`
+	result, err := orchestrator.HasPermissionBatch(ctx, &mockTransportMessenger{data: []byte(request)})
+	if err != nil {
+		t.Fatalf("HasPermissionBatch() unexpected error: %v", err)
+	}
+	response := decodePermissionResponse[[]permissionCheckResult](t, result)
+	if !response.Success || len(response.Data) != 5 {
+		t.Fatalf("HasPermissionBatch() = %+v, want 5 results", response)
+	}
+
+	want := []struct {
+		allowed bool
+		err     string
+	}{
+		{allowed: true},
+		{},
+		{},
+		{err: "user not found"},
+		{err: "exactly one of permission or role is required"},
+	}
+	for i, w := range want {
+		got := response.Data[i]
+		if got.Allowed != w.allowed || got.Error != w.err {
+			t.Errorf("result[%d] = %+v, want allowed %v, error %q", i, got, w.allowed, w.err)
+		}
+	}
+	if response.Data[1].Role != "Viewer" || response.Data[0].Sub != "auth0|admin" {
+		t.Errorf("results don't echo their checks: %+v", response.Data[:2])
+	}
+	// admin, jdoe and ghost are each looked up once
+	if calls := reader.calls.Load(); calls != 3 {
+		t.Errorf("UserPermissions() calls = %d, want 3", calls)
+	}
+
+	t.Run("limits", func(t *testing.T) {
+		result, _ := orchestrator.HasPermissionBatch(ctx, &mockTransportMessenger{data: []byte(`{"checks":[]}`)})
+		if r := decodePermissionResponse[[]permissionCheckResult](t, result); r.Error != "checks are required" {
+			t.Errorf("HasPermissionBatch() error = %q, want checks are required", r.Error)
+		}
+
+		checks := make([]permissionCheck, permissionBatchMaxSize+1)
+		payload, _ := json.Marshal(permissionBatchRequest{Checks: checks})
+		result, _ = orchestrator.HasPermissionBatch(ctx, &mockTransportMessenger{data: payload})
+		if r := decodePermissionResponse[[]permissionCheckResult](t, result); r.Error != "too many checks: at most 100 per request" {
+			t.Errorf("HasPermissionBatch() error = %q, want the size limit", r.Error)
+		}
+	})
+}
@@ -0,0 +1,446 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/jwt"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/redaction"
+)
+
+const (
+	// personalAccessTokenPrefix marks personal access tokens so they are
+	// recognizable in logs and by secret scanners
+	personalAccessTokenPrefix = "lfxpat_"
+	// defaultPersonalAccessTokenLifetime applies when a request sets no expiry
+	defaultPersonalAccessTokenLifetime = 90 * 24 * time.Hour
+	// personalAccessTokenMaxActive bounds the active tokens of a user
+	personalAccessTokenMaxActive = 20
+	// personalAccessTokenRetention is how long revoked and expired tokens stay
+	// listed before they are deleted
+	personalAccessTokenRetention = 30 * 24 * time.Hour
+	// personalAccessTokenMaxNameLength bounds the token name, in characters
+	personalAccessTokenMaxNameLength = 100
+)
+
+// errInvalidPersonalAccessToken is returned for every token that doesn't
+// match a stored one, so validation doesn't reveal which part was wrong
+var errInvalidPersonalAccessToken = errs.NewUnauthorized("invalid_token")
+
+// personalAccessTokenCreateRequest is the input of personal_access_token.create
+type personalAccessTokenCreateRequest struct {
+	AuthToken     string   `json:"auth_token"`
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days"`
+}
+
+// personalAccessTokenRequest is the input of personal_access_token.list and .revoke
+type personalAccessTokenRequest struct {
+	AuthToken string `json:"auth_token"`
+	TokenID   string `json:"token_id"`
+}
+
+// personalAccessTokenValidateRequest is the input of personal_access_token.validate
+type personalAccessTokenValidateRequest struct {
+	Token string `json:"token"`
+}
+
+// personalAccessTokenCreated is the reply of personal_access_token.create. It
+// is the only reply that carries the token itself.
+type personalAccessTokenCreated struct {
+	*model.PersonalAccessToken
+	Token string `json:"token"`
+}
+
+// personalAccessTokenIdentity is the reply of personal_access_token.validate
+type personalAccessTokenIdentity struct {
+	UserID    string    `json:"user_id"`
+	Username  string    `json:"username"`
+	TokenID   string    `json:"token_id"`
+	Scopes    []string  `json:"scopes"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// WithPersonalAccessTokenStoreForMessageHandler sets the personal access token store for the message handler orchestrator
+func WithPersonalAccessTokenStoreForMessageHandler(store port.PersonalAccessTokenStore) MessageHandlerOrchestratorOption {
+	return func(m *messageHandlerOrchestrator) {
+		m.personalAccessTokens = store
+	}
+}
+
+// WithPersonalAccessTokenPolicyForMessageHandler caps the lifetime of new
+// personal access tokens and lists the scopes they may carry. A maxLifetime
+// of 0 keeps the default of a year; without scopes no token can be created.
+func WithPersonalAccessTokenPolicyForMessageHandler(maxLifetime time.Duration, scopes []string) MessageHandlerOrchestratorOption {
+	return func(m *messageHandlerOrchestrator) {
+		m.personalAccessTokenMaxLifetime = maxLifetime
+		m.personalAccessTokenScopes = scopes
+	}
+}
+
+// CreatePersonalAccessToken mints a personal access token for the user of
+// auth_token. The token is only returned in this reply.
+func (m *messageHandlerOrchestrator) CreatePersonalAccessToken(ctx context.Context, msg port.TransportMessenger) ([]byte, error) {
+	if m.personalAccessTokens == nil {
+		return m.errorResponse("personal_access_tokens_unavailable"), nil
+	}
+
+	var request personalAccessTokenCreateRequest
+	if err := json.Unmarshal(msg.Data(), &request); err != nil {
+		return m.errorResponse("failed_to_unmarshal_request"), nil
+	}
+
+	claims, err := m.personalAccessTokenOwner(ctx, request.AuthToken)
+	if err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+	userID := claims.Subject
+
+	now := time.Now().UTC()
+	token, err := m.newPersonalAccessToken(request, claims, now)
+	if err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+
+	existing, err := m.personalAccessTokens.ListTokens(ctx, userID)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to list personal access tokens", "error", err)
+		return m.errorResponse(err.Error()), nil
+	}
+	if active := m.prunePersonalAccessTokens(ctx, existing, now); active >= personalAccessTokenMaxActive {
+		return m.errorResponse(fmt.Sprintf("too many active personal access tokens: at most %d per user", personalAccessTokenMaxActive)), nil
+	}
+
+	secret, err := randomToken(32)
+	if err != nil {
+		return m.errorResponse("failed to generate personal access token"), nil
+	}
+	token.Hash = hashPersonalAccessTokenSecret(token.Salt, secret)
+
+	if err := m.personalAccessTokens.CreateToken(ctx, token); err != nil {
+		slog.ErrorContext(ctx, "failed to store personal access token", "error", err)
+		return m.errorResponse(err.Error()), nil
+	}
+
+	slog.InfoContext(ctx, "personal access token created",
+		"token_id", token.ID,
+		"user_id", redaction.Redact(userID),
+		"scopes", token.Scopes,
+		"expires_at", token.ExpiresAt,
+	)
+
+	responseJSON, err := json.Marshal(UserDataResponse{
+		Success: true,
+		Data:    personalAccessTokenCreated{PersonalAccessToken: token, Token: personalAccessTokenPrefix + token.ID + "_" + secret},
+	})
+	if err != nil {
+		return m.errorResponse("failed to marshal response"), nil
+	}
+	return responseJSON, nil
+}
+
+// ListPersonalAccessTokens lists the personal access tokens of the user of
+// auth_token, newest first. Revoked and expired tokens stay listed for 30 days.
+func (m *messageHandlerOrchestrator) ListPersonalAccessTokens(ctx context.Context, msg port.TransportMessenger) ([]byte, error) {
+	if m.personalAccessTokens == nil {
+		return m.errorResponse("personal_access_tokens_unavailable"), nil
+	}
+
+	var request personalAccessTokenRequest
+	if err := json.Unmarshal(msg.Data(), &request); err != nil {
+		return m.errorResponse("failed_to_unmarshal_request"), nil
+	}
+
+	claims, err := m.personalAccessTokenOwner(ctx, request.AuthToken)
+	if err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+	userID := claims.Subject
+
+	tokens, err := m.personalAccessTokens.ListTokens(ctx, userID)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to list personal access tokens", "error", err)
+		return m.errorResponse(err.Error()), nil
+	}
+	slices.SortFunc(tokens, func(a, b *model.PersonalAccessToken) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+
+	responseJSON, err := json.Marshal(UserDataResponse{Success: true, Data: tokens})
+	if err != nil {
+		return m.errorResponse("failed to marshal response"), nil
+	}
+	return responseJSON, nil
+}
+
+// RevokePersonalAccessToken revokes one of the tokens of the user of
+// auth_token. Revoking a revoked token succeeds without changing it.
+func (m *messageHandlerOrchestrator) RevokePersonalAccessToken(ctx context.Context, msg port.TransportMessenger) ([]byte, error) {
+	if m.personalAccessTokens == nil {
+		return m.errorResponse("personal_access_tokens_unavailable"), nil
+	}
+
+	var request personalAccessTokenRequest
+	if err := json.Unmarshal(msg.Data(), &request); err != nil {
+		return m.errorResponse("failed_to_unmarshal_request"), nil
+	}
+
+	claims, err := m.personalAccessTokenOwner(ctx, request.AuthToken)
+	if err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+	userID := claims.Subject
+
+	tokenID := strings.TrimSpace(request.TokenID)
+	if tokenID == "" {
+		return m.errorResponse("token_id is required"), nil
+	}
+
+	token, err := m.personalAccessTokens.GetToken(ctx, tokenID)
+	if err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+	// tokens of other users are reported as missing rather than forbidden
+	if token.UserID != userID {
+		return m.errorResponse("personal access token not found"), nil
+	}
+
+	if token.RevokedAt == nil {
+		now := time.Now().UTC()
+		token.RevokedAt = &now
+		if err := m.personalAccessTokens.UpdateToken(ctx, token); err != nil {
+			slog.ErrorContext(ctx, "failed to revoke personal access token", "error", err, "token_id", tokenID)
+			return m.errorResponse(err.Error()), nil
+		}
+		slog.InfoContext(ctx, "personal access token revoked",
+			"token_id", tokenID,
+			"user_id", redaction.Redact(userID),
+		)
+	}
+
+	responseJSON, err := json.Marshal(UserDataResponse{Success: true, Data: token})
+	if err != nil {
+		return m.errorResponse("failed to marshal response"), nil
+	}
+	return responseJSON, nil
+}
+
+// ValidatePersonalAccessToken exchanges a personal access token for the
+// identity of its user and the scopes it grants. Other LFX services call it
+// to authenticate requests that carry a token instead of a JWT.
+func (m *messageHandlerOrchestrator) ValidatePersonalAccessToken(ctx context.Context, msg port.TransportMessenger) ([]byte, error) {
+	if m.personalAccessTokens == nil {
+		return m.errorResponse("personal_access_tokens_unavailable"), nil
+	}
+
+	var request personalAccessTokenValidateRequest
+	if err := json.Unmarshal(msg.Data(), &request); err != nil {
+		return m.errorResponse("failed_to_unmarshal_request"), nil
+	}
+
+	token, err := m.verifyPersonalAccessToken(ctx, request.Token, time.Now())
+	if err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+
+	// resolving the username also rejects tokens of users that were deleted
+	username, err := m.usernameForSub(ctx, token.UserID)
+	if err != nil {
+		slog.WarnContext(ctx, "personal access token owner could not be resolved",
+			"error", err,
+			"token_id", token.ID,
+		)
+		return m.errorResponse(err.Error()), nil
+	}
+
+	responseJSON, err := json.Marshal(UserDataResponse{
+		Success: true,
+		Data: personalAccessTokenIdentity{
+			UserID:    token.UserID,
+			Username:  username,
+			TokenID:   token.ID,
+			Scopes:    token.Scopes,
+			ExpiresAt: token.ExpiresAt,
+		},
+	})
+	if err != nil {
+		return m.errorResponse("failed to marshal response"), nil
+	}
+	return responseJSON, nil
+}
+
+// personalAccessTokenOwner returns the claims of authToken, whose subject is
+// the user owning the tokens. Personal access tokens can't be used to manage
+// personal access tokens.
+func (m *messageHandlerOrchestrator) personalAccessTokenOwner(ctx context.Context, authToken string) (*jwt.Claims, error) {
+	claims, err := m.verifyAccessToken(ctx, authToken)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Subject == "" {
+		return nil, errs.NewUnauthorized("auth_token has no subject")
+	}
+	return claims, nil
+}
+
+// newPersonalAccessToken validates a create request of the owner of claims
+// into a token with a fresh id and salt. A token only carries allowed scopes
+// the owner's access token holds, so it never grants more than the session
+// it was minted from. The hash is set once the secret is generated.
+func (m *messageHandlerOrchestrator) newPersonalAccessToken(request personalAccessTokenCreateRequest, claims *jwt.Claims, now time.Time) (*model.PersonalAccessToken, error) {
+	name := strings.TrimSpace(request.Name)
+	scopes := trimNonEmpty(request.Scopes)
+	switch {
+	case name == "":
+		return nil, errs.NewValidation("name is required")
+	case utf8.RuneCountInString(name) > personalAccessTokenMaxNameLength:
+		return nil, errs.NewValidation(fmt.Sprintf("name must be at most %d characters", personalAccessTokenMaxNameLength))
+	case len(scopes) == 0:
+		return nil, errs.NewValidation("scopes are required")
+	case request.ExpiresInDays < 0:
+		return nil, errs.NewValidation("expires_in_days must not be negative")
+	}
+	if len(m.personalAccessTokenScopes) == 0 {
+		return nil, errs.NewForbidden("no scopes are allowed for personal access tokens")
+	}
+	held := strings.Fields(claims.Scope)
+	for _, scope := range scopes {
+		if !slices.Contains(m.personalAccessTokenScopes, scope) {
+			return nil, errs.NewForbidden(fmt.Sprintf("scope %s is not allowed for personal access tokens", scope))
+		}
+		if !slices.Contains(held, scope) {
+			return nil, errs.NewForbidden(fmt.Sprintf("scope %s is not held by auth_token", scope))
+		}
+	}
+
+	lifetime := defaultPersonalAccessTokenLifetime
+	if request.ExpiresInDays > 0 {
+		lifetime = time.Duration(request.ExpiresInDays) * 24 * time.Hour
+	}
+	maxLifetime := cmp.Or(m.personalAccessTokenMaxLifetime, constants.DefaultPersonalAccessTokenMaxLifetime)
+	if lifetime > maxLifetime {
+		return nil, errs.NewValidation(fmt.Sprintf("expires_in_days must be at most %d", int(maxLifetime/(24*time.Hour))))
+	}
+
+	id, err := randomHex(16)
+	if err != nil {
+		return nil, errs.NewUnexpected("failed to generate personal access token id", err)
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, errs.NewUnexpected("failed to generate personal access token salt", err)
+	}
+
+	return &model.PersonalAccessToken{
+		ID:        id,
+		UserID:    claims.Subject,
+		Name:      name,
+		Scopes:    scopes,
+		CreatedAt: now,
+		ExpiresAt: now.Add(lifetime),
+		Salt:      salt,
+	}, nil
+}
+
+// prunePersonalAccessTokens deletes tokens that have been inactive for longer
+// than the retention period and returns the number of active ones
+func (m *messageHandlerOrchestrator) prunePersonalAccessTokens(ctx context.Context, tokens []*model.PersonalAccessToken, now time.Time) int {
+	active := 0
+	for _, token := range tokens {
+		if token.Active(now) {
+			active++
+			continue
+		}
+		inactiveSince := token.ExpiresAt
+		if token.RevokedAt != nil && token.RevokedAt.Before(inactiveSince) {
+			inactiveSince = *token.RevokedAt
+		}
+		if now.Sub(inactiveSince) < personalAccessTokenRetention {
+			continue
+		}
+		if err := m.personalAccessTokens.DeleteToken(ctx, token); err != nil {
+			slog.WarnContext(ctx, "failed to delete inactive personal access token", "error", err, "token_id", token.ID)
+		}
+	}
+	return active
+}
+
+// verifyPersonalAccessToken returns the stored token matching raw when it is
+// active at now
+func (m *messageHandlerOrchestrator) verifyPersonalAccessToken(ctx context.Context, raw string, now time.Time) (*model.PersonalAccessToken, error) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(raw), personalAccessTokenPrefix)
+	if !ok {
+		return nil, errInvalidPersonalAccessToken
+	}
+	id, secret, ok := strings.Cut(rest, "_")
+	if !ok || id == "" || secret == "" {
+		return nil, errInvalidPersonalAccessToken
+	}
+
+	token, err := m.personalAccessTokens.GetToken(ctx, id)
+	if err != nil {
+		var notFound errs.NotFound
+		if errors.As(err, &notFound) {
+			return nil, errInvalidPersonalAccessToken
+		}
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare(hashPersonalAccessTokenSecret(token.Salt, secret), token.Hash) != 1 {
+		slog.WarnContext(ctx, "personal access token secret mismatch", "token_id", id)
+		return nil, errInvalidPersonalAccessToken
+	}
+	if token.RevokedAt != nil {
+		return nil, errs.NewUnauthorized("token has been revoked")
+	}
+	if token.Expired(now) {
+		return nil, errs.NewUnauthorized("token has expired")
+	}
+	return token, nil
+}
+
+// hashPersonalAccessTokenSecret hashes secret with salt. Secrets are 256 bits
+// of randomness, so a fast hash is enough to make a leaked bucket useless.
+func hashPersonalAccessTokenSecret(salt []byte, secret string) []byte {
+	hash := sha256.New()
+	hash.Write(salt)
+	hash.Write([]byte(secret))
+	return hash.Sum(nil)
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// randomToken returns n random bytes, base64url-encoded without padding
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,327 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/jwt"
+)
+
+// mockPersonalAccessTokenStore keeps tokens in memory
+type mockPersonalAccessTokenStore struct {
+	tokens map[string]*model.PersonalAccessToken
+}
+
+func (s *mockPersonalAccessTokenStore) CreateToken(ctx context.Context, token *model.PersonalAccessToken) error {
+	if _, exists := s.tokens[token.ID]; exists {
+		return errors.NewConflict("personal access token already exists")
+	}
+	stored := *token
+	s.tokens[token.ID] = &stored
+	return nil
+}
+
+func (s *mockPersonalAccessTokenStore) GetToken(ctx context.Context, id string) (*model.PersonalAccessToken, error) {
+	token, ok := s.tokens[id]
+	if !ok {
+		return nil, errors.NewNotFound("personal access token not found")
+	}
+	copied := *token
+	return &copied, nil
+}
+
+func (s *mockPersonalAccessTokenStore) ListTokens(ctx context.Context, userID string) ([]*model.PersonalAccessToken, error) {
+	var tokens []*model.PersonalAccessToken
+	for _, token := range s.tokens {
+		if token.UserID == userID {
+			copied := *token
+			tokens = append(tokens, &copied)
+		}
+	}
+	return tokens, nil
+}
+
+func (s *mockPersonalAccessTokenStore) UpdateToken(ctx context.Context, token *model.PersonalAccessToken) error {
+	stored := *token
+	s.tokens[token.ID] = &stored
+	return nil
+}
+
+func (s *mockPersonalAccessTokenStore) DeleteToken(ctx context.Context, token *model.PersonalAccessToken) error {
+	delete(s.tokens, token.ID)
+	return nil
+}
+
+func TestMessageHandlerOrchestrator_PersonalAccessTokens(t *testing.T) {
+	ctx := context.Background()
+
+	// the personal access tokens of alice and bob may carry read:projects,
+	// which their access tokens hold
+	aliceToken, err := jwt.GenerateTestAccessToken("auth0|alice", "https://test.any.com/", "https://test.any.com/api/v2/", "read:current_user read:projects", time.Hour)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	bobToken, err := jwt.GenerateTestAccessToken("auth0|bob", "https://test.any.com/", "https://test.any.com/api/v2/", "read:current_user read:projects", time.Hour)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+
+	reader := &mockUserServiceReader{
+		metadataLookupFunc: func(ctx context.Context, input string) (*model.User, error) {
+			return &model.User{Token: input}, nil
+		},
+		getUserFunc: func(ctx context.Context, user *model.User) (*model.User, error) {
+			usernames := map[string]string{"auth0|alice": "alice", "auth0|bob": "bob"}
+			if username, ok := usernames[user.UserID]; ok {
+				return &model.User{UserID: user.UserID, Username: username}, nil
+			}
+			return nil, errors.NewNotFound("user not found")
+		},
+	}
+	newOrchestrator := func(store *mockPersonalAccessTokenStore, opts ...MessageHandlerOrchestratorOption) port.MessageHandler {
+		opts = append([]MessageHandlerOrchestratorOption{
+			WithUserReaderForMessageHandler(reader),
+			WithPersonalAccessTokenStoreForMessageHandler(store),
+			WithPersonalAccessTokenPolicyForMessageHandler(0, []string{"read:projects", "write:projects"}),
+		}, opts...)
+		return NewMessageHandlerOrchestrator(opts...)
+	}
+	newStore := func() *mockPersonalAccessTokenStore {
+		return &mockPersonalAccessTokenStore{tokens: map[string]*model.PersonalAccessToken{}}
+	}
+
+	type response struct {
+		Success bool            `json:"success"`
+		Error   string          `json:"error"`
+		Data    json.RawMessage `json:"data"`
+	}
+	call := func(t *testing.T, handler func(context.Context, port.TransportMessenger) ([]byte, error), request map[string]any) response {
+		t.Helper()
+		payload, _ := json.Marshal(request)
+		result, err := handler(ctx, &mockTransportMessenger{data: payload})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var r response
+		if err := json.Unmarshal(result, &r); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		return r
+	}
+	create := func(t *testing.T, m port.MessageHandler, authToken string) (id, token string) {
+		t.Helper()
+		r := call(t, m.CreatePersonalAccessToken, map[string]any{"auth_token": authToken, "name": "ci", "scopes": []string{"read:projects"}})
+		if !r.Success {
+			t.Fatalf("expected success, got error %q", r.Error)
+		}
+		var created struct {
+			ID    string `json:"id"`
+			Token string `json:"token"`
+		}
+		if err := json.Unmarshal(r.Data, &created); err != nil {
+			t.Fatalf("failed to unmarshal token: %v", err)
+		}
+		return created.ID, created.Token
+	}
+
+	t.Run("created token validates to the user", func(t *testing.T) {
+		store := newStore()
+		m := newOrchestrator(store)
+		id, token := create(t, m, aliceToken)
+
+		if !strings.HasPrefix(token, "lfxpat_"+id+"_") {
+			t.Errorf("token = %q, want lfxpat_%s_ prefix", token, id)
+		}
+		stored := store.tokens[id]
+		if stored == nil || len(stored.Hash) == 0 || strings.Contains(string(stored.Hash), token) {
+			t.Fatalf("stored = %+v, want a hashed secret", stored)
+		}
+		if got := stored.ExpiresAt.Sub(stored.CreatedAt); got != defaultPersonalAccessTokenLifetime {
+			t.Errorf("lifetime = %v, want %v", got, defaultPersonalAccessTokenLifetime)
+		}
+
+		r := call(t, m.ValidatePersonalAccessToken, map[string]any{"token": token})
+		if !r.Success {
+			t.Fatalf("expected success, got error %q", r.Error)
+		}
+		var identity personalAccessTokenIdentity
+		if err := json.Unmarshal(r.Data, &identity); err != nil {
+			t.Fatalf("failed to unmarshal identity: %v", err)
+		}
+		if identity.UserID != "auth0|alice" || identity.Username != "alice" || identity.TokenID != id {
+			t.Errorf("identity = %+v, want alice's token %s", identity, id)
+		}
+		if len(identity.Scopes) != 1 || identity.Scopes[0] != "read:projects" {
+			t.Errorf("scopes = %v, want [read:projects]", identity.Scopes)
+		}
+	})
+
+	t.Run("invalid tokens are rejected alike", func(t *testing.T) {
+		store := newStore()
+		m := newOrchestrator(store)
+		id, token := create(t, m, aliceToken)
+
+		for _, invalid := range []string{"", "lfxpat_", "nope_" + id + "_x", "lfxpat_" + id + "_wrong", "lfxpat_missing_" + token[len(token)-8:]} {
+			r := call(t, m.ValidatePersonalAccessToken, map[string]any{"token": invalid})
+			if r.Success || r.Error != "invalid_token" {
+				t.Errorf("validate(%q) = %+v, want invalid_token", invalid, r)
+			}
+		}
+	})
+
+	t.Run("revoked and expired tokens are rejected", func(t *testing.T) {
+		store := newStore()
+		m := newOrchestrator(store)
+		revokedID, revoked := create(t, m, aliceToken)
+		expiredID, expired := create(t, m, aliceToken)
+
+		if r := call(t, m.RevokePersonalAccessToken, map[string]any{"auth_token": aliceToken, "token_id": revokedID}); !r.Success {
+			t.Fatalf("revoke failed: %q", r.Error)
+		}
+		store.tokens[expiredID].ExpiresAt = time.Now().Add(-time.Minute)
+
+		if r := call(t, m.ValidatePersonalAccessToken, map[string]any{"token": revoked}); r.Success || r.Error != "token has been revoked" {
+			t.Errorf("validate revoked = %+v, want token has been revoked", r)
+		}
+		if r := call(t, m.ValidatePersonalAccessToken, map[string]any{"token": expired}); r.Success || r.Error != "token has expired" {
+			t.Errorf("validate expired = %+v, want token has expired", r)
+		}
+	})
+
+	t.Run("tokens of other users can't be revoked", func(t *testing.T) {
+		store := newStore()
+		m := newOrchestrator(store)
+		id, _ := create(t, m, aliceToken)
+
+		r := call(t, m.RevokePersonalAccessToken, map[string]any{"auth_token": bobToken, "token_id": id})
+		if r.Success || r.Error != "personal access token not found" {
+			t.Errorf("response = %+v, want personal access token not found", r)
+		}
+		if store.tokens[id].RevokedAt != nil {
+			t.Error("token must not be revoked by another user")
+		}
+	})
+
+	t.Run("list returns the caller's tokens without secrets", func(t *testing.T) {
+		store := newStore()
+		m := newOrchestrator(store)
+		create(t, m, aliceToken)
+		create(t, m, aliceToken)
+		create(t, m, bobToken)
+
+		r := call(t, m.ListPersonalAccessTokens, map[string]any{"auth_token": aliceToken})
+		if !r.Success {
+			t.Fatalf("expected success, got error %q", r.Error)
+		}
+		if strings.Contains(string(r.Data), "hash") || strings.Contains(string(r.Data), "lfxpat_") {
+			t.Errorf("list leaks secrets: %s", r.Data)
+		}
+		var tokens []model.PersonalAccessToken
+		if err := json.Unmarshal(r.Data, &tokens); err != nil {
+			t.Fatalf("failed to unmarshal tokens: %v", err)
+		}
+		if len(tokens) != 2 {
+			t.Errorf("tokens = %+v, want alice's two tokens", tokens)
+		}
+	})
+
+	createErrors := []struct {
+		name    string
+		opts    []MessageHandlerOrchestratorOption
+		request map[string]any
+		want    string
+	}{
+		{
+			name:    "name required",
+			request: map[string]any{"auth_token": aliceToken, "scopes": []string{"read:projects"}},
+			want:    "name is required",
+		},
+		{
+			name:    "scopes required",
+			request: map[string]any{"auth_token": aliceToken, "name": "ci", "scopes": []string{" "}},
+			want:    "scopes are required",
+		},
+		{
+			name:    "lifetime above the maximum",
+			opts:    []MessageHandlerOrchestratorOption{WithPersonalAccessTokenPolicyForMessageHandler(30*24*time.Hour, []string{"read:projects"})},
+			request: map[string]any{"auth_token": aliceToken, "name": "ci", "scopes": []string{"read:projects"}, "expires_in_days": 31},
+			want:    "expires_in_days must be at most 30",
+		},
+		{
+			name:    "scope not allowed",
+			opts:    []MessageHandlerOrchestratorOption{WithPersonalAccessTokenPolicyForMessageHandler(0, []string{"read:projects"})},
+			request: map[string]any{"auth_token": aliceToken, "name": "ci", "scopes": []string{"write:projects"}},
+			want:    "is not allowed for personal access tokens",
+		},
+		{
+			name:    "scope not held by the caller",
+			request: map[string]any{"auth_token": aliceToken, "name": "ci", "scopes": []string{"read:projects", "write:projects"}},
+			want:    "scope write:projects is not held by auth_token",
+		},
+		{
+			name:    "no scope allowed without an allowlist",
+			opts:    []MessageHandlerOrchestratorOption{WithPersonalAccessTokenPolicyForMessageHandler(0, nil)},
+			request: map[string]any{"auth_token": aliceToken, "name": "ci", "scopes": []string{"read:projects"}},
+			want:    "no scopes are allowed for personal access tokens",
+		},
+		{
+			name:    "personal access tokens can't mint tokens",
+			request: map[string]any{"auth_token": "lfxpat_abc_def", "name": "ci", "scopes": []string{"read:projects"}},
+			want:    "auth_token",
+		},
+	}
+	for _, tt := range createErrors {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newStore()
+			r := call(t, newOrchestrator(store, tt.opts...).CreatePersonalAccessToken, tt.request)
+			if r.Success || !strings.Contains(r.Error, tt.want) {
+				t.Errorf("response = %+v, want error containing %q", r, tt.want)
+			}
+			if len(store.tokens) != 0 {
+				t.Error("rejected request must not store a token")
+			}
+		})
+	}
+
+	t.Run("active tokens are limited and old inactive ones pruned", func(t *testing.T) {
+		store := newStore()
+		m := newOrchestrator(store)
+		for range personalAccessTokenMaxActive {
+			create(t, m, aliceToken)
+		}
+		r := call(t, m.CreatePersonalAccessToken, map[string]any{"auth_token": aliceToken, "name": "ci", "scopes": []string{"read:projects"}})
+		if r.Success || !strings.Contains(r.Error, "too many active personal access tokens") {
+			t.Errorf("response = %+v, want too many active personal access tokens", r)
+		}
+
+		old := time.Now().Add(-2 * personalAccessTokenRetention)
+		for _, token := range store.tokens {
+			token.RevokedAt = &old
+			break
+		}
+		create(t, m, aliceToken)
+		if len(store.tokens) != personalAccessTokenMaxActive {
+			t.Errorf("stored %d tokens, want the old revoked one replaced", len(store.tokens))
+		}
+	})
+
+	t.Run("unavailable without a store", func(t *testing.T) {
+		m := NewMessageHandlerOrchestrator(WithUserReaderForMessageHandler(reader))
+		result, err := m.ValidatePersonalAccessToken(ctx, &mockTransportMessenger{data: []byte(`{}`)})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !strings.Contains(string(result), "personal_access_tokens_unavailable") {
+			t.Errorf("response = %s, want personal_access_tokens_unavailable", result)
+		}
+	})
+}
@@ -0,0 +1,70 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/envelope"
+)
+
+// errProviderOverrideDenied is the reply to a provider hint of a caller not
+// allowed to send one
+const errProviderOverrideDenied = "provider override not allowed"
+
+// ProviderOverridePolicy is who may pin their requests to one of the
+// providers the service runs. Callers are matched by the prefix of their
+// transport caller, the reply inbox for NATS, as caller profiles are.
+type ProviderOverridePolicy struct {
+	providers []string
+	callers   []string
+}
+
+// NewProviderOverridePolicy returns the policy letting the callers below
+// callers pin their requests to one of providers
+func NewProviderOverridePolicy(providers, callers []string) *ProviderOverridePolicy {
+	return &ProviderOverridePolicy{providers: providers, callers: callers}
+}
+
+// allowed reports whether caller may pin its requests
+func (p *ProviderOverridePolicy) allowed(caller string) bool {
+	return caller != "" && slices.ContainsFunc(p.callers, func(prefix string) bool {
+		return callerHasPrefix(caller, prefix)
+	})
+}
+
+// OverrideProviders returns the middleware pinning the requests hinting a
+// provider to it, for the callers policy allows; the composite repository
+// then serves their reads from that provider. Hints of other callers, and
+// of providers the service doesn't run, are refused rather than ignored, so
+// a caller never mistakes another provider's answer for the one it asked.
+func OverrideProviders(policy *ProviderOverridePolicy) Middleware {
+	return func(next port.Handler) port.Handler {
+		return port.HandlerFunc(func(ctx context.Context, msg port.TransportMessenger) {
+			provider := envelope.RequestedProviderFromContext(ctx)
+			if provider == "" {
+				next.Handle(ctx, msg)
+				return
+			}
+			if caller := transportCaller(msg); !policy.allowed(caller) {
+				slog.WarnContext(ctx, "provider override refused",
+					"subject", msg.Subject(),
+					"caller", caller,
+					"provider", provider,
+				)
+				respondError(ctx, msg, errProviderOverrideDenied)
+				return
+			}
+			if !slices.Contains(policy.providers, provider) {
+				respondError(ctx, msg, fmt.Sprintf("provider %q is not served", provider))
+				return
+			}
+			next.Handle(envelope.WithProvider(ctx, provider), msg)
+		})
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/envelope"
+)
+
+func TestOverrideProviders(t *testing.T) {
+	policy := NewProviderOverridePolicy([]string{"authelia", "auth0"}, []string{"_INBOX_migration"})
+	request := func(caller, hint string) (pinned, reply string) {
+		handler := Chain(port.HandlerFunc(func(ctx context.Context, msg port.TransportMessenger) {
+			pinned = envelope.ProviderFromContext(ctx)
+			_ = msg.Respond([]byte(`{"success":true}`))
+		}), OverrideProviders(policy))
+
+		ctx := envelope.Context(context.Background(), nats.Header{envelope.ProviderHeader: []string{hint}})
+		msg := &callerMessenger{subject: constants.UserMetadataReadSubject, caller: caller}
+		handler.Handle(ctx, msg)
+		return pinned, string(msg.replied)
+	}
+
+	t.Run("pins the requests of allowed callers", func(t *testing.T) {
+		pinned, reply := request("_INBOX_migration.abc", "auth0")
+		assert.Equal(t, "auth0", pinned)
+		assert.JSONEq(t, `{"success":true}`, reply)
+	})
+
+	t.Run("leaves requests without a hint alone", func(t *testing.T) {
+		pinned, reply := request("_INBOX.abc", "")
+		assert.Empty(t, pinned)
+		assert.JSONEq(t, `{"success":true}`, reply)
+	})
+
+	t.Run("refuses the hints of other callers", func(t *testing.T) {
+		for _, caller := range []string{"_INBOX.abc", "_INBOX_migrations.abc", ""} {
+			_, reply := request(caller, "auth0")
+			assert.JSONEq(t, `{"success":false,"error":"provider override not allowed"}`, reply, "caller %q", caller)
+		}
+	})
+
+	t.Run("refuses providers the service doesn't run", func(t *testing.T) {
+		_, reply := request("_INBOX_migration.abc", "okta")
+		assert.JSONEq(t, `{"success":false,"error":"provider \"okta\" is not served"}`, reply)
+	})
+}
@@ -0,0 +1,153 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/redaction"
+)
+
+const (
+	// maxTrackedPanicSignatures bounds the payloads whose panics are counted
+	// and the quarantined ones remembered, so a flood of distinct panicking
+	// payloads can't grow them without limit
+	maxTrackedPanicSignatures = 1024
+	// maxQuarantinedPayloadBytes is how much of a payload is stored
+	maxQuarantinedPayloadBytes = 4096
+	// quarantineStoreTimeout bounds the write of a quarantined payload
+	quarantineStoreTimeout = 5 * time.Second
+)
+
+// PanicQuarantine counts the panics of each request payload, identified by a
+// signature of its subject and data. A payload reaching the threshold is
+// quarantined: stored for inspection and refused without reaching its
+// handler again, on this replica, until restart.
+type PanicQuarantine struct {
+	store     port.PayloadQuarantine
+	threshold int
+	now       func() time.Time
+
+	mu          sync.Mutex
+	panics      map[string]*model.QuarantinedPayload
+	quarantined map[string]struct{}
+}
+
+// NewPanicQuarantine returns a quarantine storing payloads in store after
+// threshold panics; a threshold < 1 quarantines on the first one
+func NewPanicQuarantine(store port.PayloadQuarantine, threshold int) *PanicQuarantine {
+	return &PanicQuarantine{
+		store:       store,
+		threshold:   max(threshold, 1),
+		now:         time.Now,
+		panics:      make(map[string]*model.QuarantinedPayload),
+		quarantined: make(map[string]struct{}),
+	}
+}
+
+// Quarantined reports whether the payload of signature is quarantined
+func (q *PanicQuarantine) Quarantined(signature string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	_, quarantined := q.quarantined[signature]
+	return quarantined
+}
+
+// RecordPanic counts a panic of the payload of signature, quarantining it
+// when it reaches the threshold, and reports whether it did
+func (q *PanicQuarantine) RecordPanic(ctx context.Context, signature string, msg port.TransportMessenger, panicked string) bool {
+	now := q.now()
+
+	q.mu.Lock()
+	entry, tracked := q.panics[signature]
+	if !tracked {
+		if len(q.panics) >= maxTrackedPanicSignatures {
+			q.mu.Unlock()
+			return false
+		}
+		entry = &model.QuarantinedPayload{Signature: signature, Subject: msg.Subject(), FirstPanicAt: now}
+		q.panics[signature] = entry
+	}
+	entry.Panics++
+	entry.Panic = panicked
+	if entry.Panics < q.threshold || len(q.quarantined) >= maxTrackedPanicSignatures {
+		q.mu.Unlock()
+		return false
+	}
+	delete(q.panics, signature)
+	q.quarantined[signature] = struct{}{}
+	q.mu.Unlock()
+
+	entry.Payload, entry.Truncated = maskPayload(msg.Data())
+	entry.QuarantinedAt = now
+	storeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), quarantineStoreTimeout)
+	defer cancel()
+	if err := q.store.Quarantine(storeCtx, entry); err != nil {
+		slog.ErrorContext(ctx, "failed to store quarantined payload",
+			"signature", signature,
+			"error", err,
+		)
+	}
+	slog.WarnContext(ctx, "payload quarantined after repeated panics",
+		"subject", entry.Subject,
+		"signature", signature,
+		"panics", entry.Panics,
+	)
+	return true
+}
+
+// payloadSignature identifies a request payload by its subject and data
+func payloadSignature(subject string, data []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(subject))
+	hash.Write([]byte{0})
+	hash.Write(data)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// maskPayload returns the payload as stored in the quarantine: the values of
+// JSON members named like secrets (password, auth_token, ...) are replaced,
+// JWTs anywhere are redacted, and the result is cut to the stored size
+func maskPayload(data []byte) (string, bool) {
+	var decoded any
+	if err := json.Unmarshal(data, &decoded); err == nil {
+		if masked, err := json.Marshal(maskSecretMembers(decoded)); err == nil {
+			data = masked
+		}
+	}
+
+	masked := redaction.RedactJWTs(string(data))
+	if len(masked) <= maxQuarantinedPayloadBytes {
+		return masked, false
+	}
+	return masked[:maxQuarantinedPayloadBytes], true
+}
+
+// maskSecretMembers replaces the values of the secret members of a decoded
+// JSON value, recursively
+func maskSecretMembers(value any) any {
+	switch typed := value.(type) {
+	case map[string]any:
+		for key, member := range typed {
+			if redaction.Setting(key, "x") == redaction.SecretPlaceholder || key == "token" || key == "otp" {
+				typed[key] = redaction.SecretPlaceholder
+				continue
+			}
+			typed[key] = maskSecretMembers(member)
+		}
+	case []any:
+		for i, element := range typed {
+			typed[i] = maskSecretMembers(element)
+		}
+	}
+	return value
+}
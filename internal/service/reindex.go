@@ -0,0 +1,127 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/redaction"
+)
+
+// reindexMaxDuration bounds a re-index, which outlives the request that
+// started it
+const reindexMaxDuration = time.Hour
+
+// reindexRequest is the input of admin.reindex
+type reindexRequest struct {
+	AuthToken string `json:"auth_token"`
+}
+
+// reindexStarted is the reply of admin.reindex
+type reindexStarted struct {
+	JobID           string `json:"job_id"`
+	ProgressSubject string `json:"progress_subject"`
+}
+
+// ReindexProgressEvent is published on ReindexProgressSubject while a
+// re-index runs; the one marked done carries the outcome, with the error
+// that stopped the job if any
+type ReindexProgressEvent struct {
+	JobID string `json:"job_id"`
+	model.ReindexProgress
+	Done      bool      `json:"done"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// WithLookupReindexerForMessageHandler sets the user store admin.reindex
+// rebuilds the lookup keys of
+func WithLookupReindexerForMessageHandler(reindexer port.LookupReindexer) MessageHandlerOrchestratorOption {
+	return func(m *messageHandlerOrchestrator) {
+		m.lookupReindexer = reindexer
+	}
+}
+
+// AdminReindex starts rebuilding the lookup keys of every user on the
+// replica handling the request and replies the job ID; the progress, the
+// users that failed and the outcome are published on ReindexProgressSubject.
+// A replica runs one re-index at a time.
+func (m *messageHandlerOrchestrator) AdminReindex(ctx context.Context, msg port.TransportMessenger) ([]byte, error) {
+	if m.lookupReindexer == nil {
+		return m.errorResponse("reindex_unavailable"), nil
+	}
+
+	var request reindexRequest
+	if err := json.Unmarshal(msg.Data(), &request); err != nil {
+		return m.errorResponse("failed_to_unmarshal_request"), nil
+	}
+	claims, err := m.authorizeScope(ctx, request.AuthToken, constants.LookupReindexRequiredScope)
+	if err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+
+	if !m.reindexing.CompareAndSwap(false, true) {
+		return m.errorResponse("reindex already running"), nil
+	}
+	jobID := uuid.NewString()
+	slog.InfoContext(ctx, "lookup key re-index started",
+		"job_id", jobID,
+		"started_by", redaction.Redact(claims.Subject),
+	)
+
+	// the job keeps the tenant of the request, not its deadline
+	jobCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), reindexMaxDuration)
+	go func() {
+		defer cancel()
+		defer m.reindexing.Store(false)
+		m.runReindex(jobCtx, jobID)
+	}()
+
+	responseJSON, err := json.Marshal(UserDataResponse{Success: true, Data: reindexStarted{
+		JobID:           jobID,
+		ProgressSubject: constants.ReindexProgressSubject,
+	}})
+	if err != nil {
+		return m.errorResponse("failed to marshal response"), nil
+	}
+	return responseJSON, nil
+}
+
+// runReindex re-indexes the lookup keys, publishing its progress as it goes
+func (m *messageHandlerOrchestrator) runReindex(ctx context.Context, jobID string) {
+	result, err := m.lookupReindexer.ReindexLookups(ctx, func(progress model.ReindexProgress) {
+		m.publishReindexProgress(ctx, ReindexProgressEvent{JobID: jobID, ReindexProgress: progress})
+	})
+
+	done := ReindexProgressEvent{JobID: jobID, ReindexProgress: model.ReindexProgress{ReindexResult: result}, Done: true}
+	if err != nil {
+		done.Error = err.Error()
+		slog.ErrorContext(ctx, "lookup key re-index failed", "job_id", jobID, "error", err)
+	}
+	m.publishReindexProgress(ctx, done)
+}
+
+// publishReindexProgress publishes event; a lost event is only logged, as
+// the job goes on regardless
+func (m *messageHandlerOrchestrator) publishReindexProgress(ctx context.Context, event ReindexProgressEvent) {
+	if m.eventPublisher == nil {
+		return
+	}
+	event.Timestamp = time.Now().UTC()
+	eventJSON, err := json.Marshal(event)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to marshal reindex progress event", "error", err)
+		return
+	}
+	if err := m.eventPublisher.Publish(ctx, constants.ReindexProgressSubject, eventJSON); err != nil {
+		slog.WarnContext(ctx, "failed to publish reindex progress event", "job_id", event.JobID, "error", err)
+	}
+}
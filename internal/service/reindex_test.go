@@ -0,0 +1,144 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/jwt"
+)
+
+type fakeLookupReindexer struct {
+	release chan struct{}
+	err     error
+}
+
+func (f *fakeLookupReindexer) ReindexLookups(_ context.Context, progress func(model.ReindexProgress)) (model.ReindexResult, error) {
+	if f.release != nil {
+		<-f.release
+	}
+	progress(model.ReindexProgress{ReindexResult: model.ReindexResult{Scanned: 1, Failed: 1}, Username: "jdoe", Error: "boom"})
+	return model.ReindexResult{Scanned: 2, Reindexed: 1, Orphaned: 3, Failed: 1}, f.err
+}
+
+func TestMessageHandlerOrchestrator_AdminReindex(t *testing.T) {
+	ctx := context.Background()
+	reindexToken, err := jwt.GenerateTestAccessToken("auth0|admin", "https://test.any.com/", "https://test.any.com/api/v2/", constants.LookupReindexRequiredScope, time.Hour)
+	require.NoError(t, err)
+	readToken, err := jwt.GenerateTestAccessToken("auth0|reader", "https://test.any.com/", "https://test.any.com/api/v2/", "read:users", time.Hour)
+	require.NoError(t, err)
+
+	reader := &mockUserServiceReader{
+		metadataLookupFunc: func(ctx context.Context, input string) (*model.User, error) {
+			return &model.User{Token: input}, nil
+		},
+	}
+	newHandler := func(reindexer *fakeLookupReindexer) (*messageHandlerOrchestrator, chan ReindexProgressEvent) {
+		events := make(chan ReindexProgressEvent, 10)
+		publisher := &mockEventPublisher{publishFunc: func(_ context.Context, subject string, data []byte) error {
+			assert.Equal(t, constants.ReindexProgressSubject, subject)
+			var event ReindexProgressEvent
+			assert.NoError(t, json.Unmarshal(data, &event))
+			events <- event
+			return nil
+		}}
+		m := NewMessageHandlerOrchestrator(
+			WithUserReaderForMessageHandler(reader),
+			WithEventPublisherForMessageHandler(publisher),
+			WithLookupReindexerForMessageHandler(reindexer),
+		).(*messageHandlerOrchestrator)
+		return m, events
+	}
+	reindex := func(m *messageHandlerOrchestrator, token string) UserDataResponse {
+		t.Helper()
+		data, _ := json.Marshal(map[string]any{"auth_token": token})
+		reply, err := m.AdminReindex(ctx, &mockTransportMessenger{data: data})
+		require.NoError(t, err)
+		var response UserDataResponse
+		require.NoError(t, json.Unmarshal(reply, &response))
+		return response
+	}
+	next := func(events chan ReindexProgressEvent) ReindexProgressEvent {
+		t.Helper()
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatal("no reindex progress event")
+			return ReindexProgressEvent{}
+		}
+	}
+
+	t.Run("reports progress, then the outcome", func(t *testing.T) {
+		m, events := newHandler(&fakeLookupReindexer{})
+
+		response := reindex(m, reindexToken)
+		require.True(t, response.Success, response.Error)
+		jobID := response.Data.(map[string]any)["job_id"]
+		assert.NotEmpty(t, jobID)
+		assert.Equal(t, constants.ReindexProgressSubject, response.Data.(map[string]any)["progress_subject"])
+
+		failure := next(events)
+		assert.Equal(t, jobID, failure.JobID)
+		assert.Equal(t, "jdoe", failure.Username)
+		assert.Equal(t, "boom", failure.Error)
+		assert.False(t, failure.Done)
+
+		done := next(events)
+		assert.True(t, done.Done)
+		assert.Equal(t, model.ReindexResult{Scanned: 2, Reindexed: 1, Orphaned: 3, Failed: 1}, done.ReindexResult)
+		assert.Empty(t, done.Error)
+	})
+
+	t.Run("the outcome carries the error that stopped the job", func(t *testing.T) {
+		m, events := newHandler(&fakeLookupReindexer{err: errors.New("store unavailable")})
+
+		require.True(t, reindex(m, reindexToken).Success)
+		next(events)
+		done := next(events)
+		assert.True(t, done.Done)
+		assert.Equal(t, "store unavailable", done.Error)
+	})
+
+	t.Run("one re-index at a time", func(t *testing.T) {
+		reindexer := &fakeLookupReindexer{release: make(chan struct{})}
+		m, events := newHandler(reindexer)
+
+		require.True(t, reindex(m, reindexToken).Success)
+		response := reindex(m, reindexToken)
+		assert.False(t, response.Success)
+		assert.Equal(t, "reindex already running", response.Error)
+
+		close(reindexer.release)
+		next(events)
+		assert.True(t, next(events).Done)
+		assert.Eventually(t, func() bool { return !m.reindexing.Load() }, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("requires the reindex scope", func(t *testing.T) {
+		m, _ := newHandler(&fakeLookupReindexer{})
+
+		response := reindex(m, readToken)
+		assert.False(t, response.Success)
+		assert.Equal(t, "insufficient_scope", response.Error)
+		assert.False(t, m.reindexing.Load())
+	})
+
+	t.Run("unavailable without a store keeping lookup keys", func(t *testing.T) {
+		m := NewMessageHandlerOrchestrator(WithUserReaderForMessageHandler(reader)).(*messageHandlerOrchestrator)
+
+		response := reindex(m, reindexToken)
+		assert.False(t, response.Success)
+		assert.Equal(t, "reindex_unavailable", response.Error)
+	})
+}
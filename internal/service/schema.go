@@ -0,0 +1,125 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path"
+	"strings"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/jsonschema"
+)
+
+// errInvalidRequest prefixes the reply to a request not matching its schema
+const errInvalidRequest = "invalid request"
+
+// schemaFiles hold one document per subject: the subject and the JSON
+// Schemas (draft 2020-12) of its request and reply
+//
+//go:embed schemas/*.json
+var schemaFiles embed.FS
+
+// SubjectSchema is the JSON Schemas of the request and reply of a subject
+type SubjectSchema struct {
+	Request  json.RawMessage `json:"request"`
+	Response json.RawMessage `json:"response"`
+
+	request *jsonschema.Schema
+}
+
+// subjectSchemas are the schemas of every subject, by subject
+var subjectSchemas = mustLoadSubjectSchemas()
+
+func mustLoadSubjectSchemas() map[string]*SubjectSchema {
+	schemas, err := loadSubjectSchemas()
+	if err != nil {
+		panic(err)
+	}
+	return schemas
+}
+
+func loadSubjectSchemas() (map[string]*SubjectSchema, error) {
+	files, err := schemaFiles.ReadDir("schemas")
+	if err != nil {
+		return nil, err
+	}
+
+	schemas := make(map[string]*SubjectSchema, len(files))
+	for _, file := range files {
+		data, err := schemaFiles.ReadFile(path.Join("schemas", file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var document struct {
+			Subject string `json:"subject"`
+			SubjectSchema
+		}
+		if err := json.Unmarshal(data, &document); err != nil {
+			return nil, fmt.Errorf("schema %s: %w", file.Name(), err)
+		}
+		if document.request, err = jsonschema.Parse(document.Request); err != nil {
+			return nil, fmt.Errorf("schema %s: %w", file.Name(), err)
+		}
+		schemas[document.Subject] = &document.SubjectSchema
+	}
+	return schemas, nil
+}
+
+// validateRequest validates a request payload against the request schema
+// of subject. Payloads starting like a JSON object or array are decoded as
+// JSON; any other payload is the plain text value of a lookup, a string.
+// Subjects without a schema aren't validated.
+func validateRequest(subject string, data []byte) error {
+	schema, ok := subjectSchemas[subject]
+	if !ok {
+		return nil
+	}
+	trimmed := strings.TrimSpace(string(data))
+	if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		return schema.request.ValidateJSON(data)
+	}
+	return schema.request.Validate(trimmed)
+}
+
+// ValidateRequests returns the middleware rejecting the requests whose
+// payload doesn't match the schema of their subject before they reach the
+// handler. The reply names every mismatch with its path, e.g.
+// "invalid request: /user/auth_token: expected string, got integer".
+func ValidateRequests() Middleware {
+	return func(next port.Handler) port.Handler {
+		return port.HandlerFunc(func(ctx context.Context, msg port.TransportMessenger) {
+			if err := validateRequest(msg.Subject(), msg.Data()); err != nil {
+				slog.DebugContext(ctx, "request does not match its schema", "error", err)
+				respondError(ctx, msg, errInvalidRequest+": "+err.Error())
+				return
+			}
+			next.Handle(ctx, msg)
+		})
+	}
+}
+
+// Schemas returns the request and reply schemas of the subject named in the
+// request, or of every subject when it is empty
+func (m *messageHandlerOrchestrator) Schemas(ctx context.Context, msg port.TransportMessenger) ([]byte, error) {
+	subject := strings.TrimSpace(string(msg.Data()))
+	data := subjectSchemas
+	if subject != "" {
+		schema, ok := subjectSchemas[subject]
+		if !ok {
+			return m.errorResponse("unknown subject"), nil
+		}
+		data = map[string]*SubjectSchema{subject: schema}
+	}
+
+	responseJSON, err := json.Marshal(UserDataResponse{Success: true, Data: data})
+	if err != nil {
+		return m.errorResponse("failed to marshal response"), nil
+	}
+	return responseJSON, nil
+}
@@ -0,0 +1,145 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+)
+
+// subjectMessenger is a repliedMessenger received on subject
+type subjectMessenger struct {
+	repliedMessenger
+	subject string
+}
+
+func (m *subjectMessenger) Subject() string { return m.subject }
+
+func TestSubjectSchemas(t *testing.T) {
+	subjects := []string{
+		constants.UserEmailToUserSubject, constants.UserEmailToUserBatchSubject, constants.UserEmailToSubSubject,
+		constants.UserUsernameToSubSubject, constants.UserSubToUsernameSubject, constants.UserSearchSubject,
+		constants.UserHasPermissionSubject, constants.UserHasPermissionBatchSubject,
+		constants.TokenCheckScopeSubject, constants.TokenCheckStepUpSubject,
+		constants.ServiceAccountCreateSubject, constants.ServiceAccountListSubject, constants.ServiceAccountRotateSubject,
+		constants.PersonalAccessTokenCreateSubject, constants.PersonalAccessTokenListSubject,
+		constants.PersonalAccessTokenRevokeSubject, constants.PersonalAccessTokenValidateSubject,
+		constants.UserMetadataUpdateSubject, constants.UserMetadataReadSubject,
+		constants.UserEmailReadSubject, constants.UserEmailSetPrimarySubject, constants.UserAddAliasSubject,
+		constants.UserSessionsListSubject, constants.UserSessionsRevokeSubject,
+		constants.ImpersonationTokenExchangeSubject,
+		constants.EmailLinkingSendVerificationSubject, constants.EmailLinkingVerifySubject,
+		constants.UserIdentityLinkSubject, constants.UserIdentityUnlinkSubject, constants.UserIdentityListSubject,
+		constants.PasswordUpdateSubject, constants.PasswordResetLinkSubject,
+		constants.AdminStatsSubject, constants.AdminSelfTestSubject, constants.AdminRevokeTokenSubject,
+		constants.AdminConfigSubject, constants.AdminUsersImportSubject, constants.AdminUsersImportStatusSubject,
+		constants.AdminUsersExportSubject, constants.EmailOrganizationSubject, constants.AdminEmailOrganizationSetSubject,
+		constants.AdminEmailOrganizationDeleteSubject, constants.AdminEmailOrganizationListSubject, constants.AdminUsersMergeSubject,
+		constants.UserConsentRecordSubject, constants.UserConsentStatusSubject,
+		constants.UserPreferencesReadSubject, constants.UserPreferencesUpdateSubject,
+		constants.AdminAttestationRecordSubject, constants.AdminAttestationRevokeSubject,
+		constants.UserAffiliationsAddSubject, constants.UserAffiliationsEndSubject, constants.UserAffiliationsListSubject,
+		constants.AdminStaticCacheFlushSubject, constants.AdminReindexSubject, constants.AdminMaintenanceSubject,
+		constants.SchemaSubject,
+	}
+	for _, subject := range subjects {
+		schema, ok := subjectSchemas[subject]
+		if assert.True(t, ok, "no schema for %s", subject) {
+			assert.NotEmpty(t, schema.Response, "no response schema for %s", subject)
+		}
+	}
+	assert.Len(t, subjectSchemas, len(subjects), "schemas of unknown subjects")
+}
+
+func TestValidateRequests(t *testing.T) {
+	handled := 0
+	handler := Chain(port.HandlerFunc(func(context.Context, port.TransportMessenger) { handled++ }), ValidateRequests())
+
+	reply := func(subject, payload string) *UserDataResponse {
+		msg := &subjectMessenger{repliedMessenger: repliedMessenger{data: []byte(payload)}, subject: subject}
+		handler.Handle(context.Background(), msg)
+		if msg.replied == nil {
+			return nil
+		}
+		var response UserDataResponse
+		require.NoError(t, json.Unmarshal(msg.replied, &response))
+		return &response
+	}
+
+	tests := []struct {
+		name    string
+		subject string
+		payload string
+		want    string
+	}{
+		{"valid JSON", constants.UserEmailReadSubject, `{"user":{"auth_token":"t"}}`, ""},
+		{"wrong type", constants.UserEmailReadSubject, `{"user":{"auth_token":42}}`, "invalid request: /user/auth_token: expected string, got integer"},
+		{"missing member", constants.PasswordResetLinkSubject, `{}`, "invalid request: /token: is required"},
+		{"malformed JSON", constants.TokenCheckScopeSubject, `{"auth_token":`, "invalid request: /: invalid JSON: unexpected EOF"},
+		{"plain text lookup", constants.UserEmailToUserSubject, "zephyr@example.com", ""},
+		{"empty lookup", constants.UserEmailToUserSubject, "  ", "invalid request: /: must not be empty"},
+		{"text or JSON", constants.UserMetadataReadSubject, `{"input":"zephyr.stormwind","fields":["username"]}`, ""},
+		{"empty admin payload", constants.AdminStatsSubject, "", ""},
+		{"subject without a schema", "lfx.auth-service.unknown", `{"anything":1}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := handled
+			response := reply(tt.subject, tt.payload)
+			if tt.want == "" {
+				assert.Nil(t, response)
+				assert.Equal(t, before+1, handled)
+				return
+			}
+			require.NotNil(t, response)
+			assert.False(t, response.Success)
+			assert.Equal(t, tt.want, response.Error)
+			assert.Equal(t, before, handled, "an invalid request doesn't reach the handler")
+		})
+	}
+}
+
+func TestMessageHandlerOrchestrator_Schemas(t *testing.T) {
+	orchestrator := NewMessageHandlerOrchestrator()
+
+	decode := func(t *testing.T, payload string) (bool, map[string]SubjectSchema, string) {
+		t.Helper()
+		raw, err := orchestrator.Schemas(context.Background(), &mockTransportMessenger{data: []byte(payload)})
+		require.NoError(t, err)
+		var response struct {
+			Success bool                     `json:"success"`
+			Data    map[string]SubjectSchema `json:"data"`
+			Error   string                   `json:"error"`
+		}
+		require.NoError(t, json.Unmarshal(raw, &response))
+		return response.Success, response.Data, response.Error
+	}
+
+	t.Run("every subject", func(t *testing.T) {
+		ok, data, _ := decode(t, "")
+		assert.True(t, ok)
+		assert.Len(t, data, len(subjectSchemas))
+	})
+
+	t.Run("one subject", func(t *testing.T) {
+		ok, data, _ := decode(t, constants.TokenCheckScopeSubject)
+		assert.True(t, ok)
+		require.Contains(t, data, constants.TokenCheckScopeSubject)
+		assert.Len(t, data, 1)
+		assert.Contains(t, string(data[constants.TokenCheckScopeSubject].Request), `"auth_token"`)
+	})
+
+	t.Run("unknown subject", func(t *testing.T) {
+		ok, _, errMsg := decode(t, "lfx.auth-service.unknown")
+		assert.False(t, ok)
+		assert.Equal(t, "unknown subject", errMsg)
+	})
+}
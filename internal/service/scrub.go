@@ -0,0 +1,74 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/redaction"
+)
+
+// scrubbedReplyMembers are the members of an unsuccessful reply describing
+// its error. They are free text built from provider errors, which may quote
+// the request of the failed call.
+var scrubbedReplyMembers = []string{"error", "message", "error_message"}
+
+// ScrubErrors returns the middleware removing credentials and email
+// addresses from the error of unsuccessful replies, see redaction.Scrub.
+// Successful replies are left alone: their data is what the caller asked for.
+func ScrubErrors() Middleware {
+	return func(next port.Handler) port.Handler {
+		return port.HandlerFunc(func(ctx context.Context, msg port.TransportMessenger) {
+			next.Handle(ctx, &scrubbingMessenger{TransportMessenger: msg})
+		})
+	}
+}
+
+// scrubbingMessenger scrubs the error of the reply sent through it
+type scrubbingMessenger struct {
+	port.TransportMessenger
+}
+
+// Respond forwards the reply with its error scrubbed
+func (s *scrubbingMessenger) Respond(data []byte) error {
+	return s.TransportMessenger.Respond(scrubReply(data))
+}
+
+// Unwrap returns the wrapped messenger
+func (s *scrubbingMessenger) Unwrap() port.TransportMessenger {
+	return s.TransportMessenger
+}
+
+func scrubReply(data []byte) []byte {
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return data
+	}
+	var success bool
+	if err := json.Unmarshal(envelope["success"], &success); err != nil || success {
+		return data
+	}
+
+	changed := false
+	for _, member := range scrubbedReplyMembers {
+		var text string
+		if err := json.Unmarshal(envelope[member], &text); err != nil {
+			continue
+		}
+		if scrubbed := redaction.Scrub(text); scrubbed != text {
+			envelope[member], _ = json.Marshal(scrubbed)
+			changed = true
+		}
+	}
+	if !changed {
+		return data
+	}
+	scrubbedData, err := json.Marshal(envelope)
+	if err != nil {
+		return data
+	}
+	return scrubbedData
+}
@@ -0,0 +1,301 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/jwt"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/redaction"
+)
+
+const (
+	// maxTrackedSecurityCallers bounds the callers counted by each rule, so a
+	// flood of distinct callers can't grow the counters without limit
+	maxTrackedSecurityCallers = 10000
+	// securityPublishTimeout bounds the publish of a security event
+	securityPublishTimeout = 5 * time.Second
+)
+
+// invalidTokenErrors are the replies to a request whose token could not be used
+var invalidTokenErrors = map[string]bool{
+	errs.Message(errs.CodeTokenInvalid): true,
+	"token has been revoked":            true,
+}
+
+// emailLookupSubjects are the subjects resolving an email to an account
+var emailLookupSubjects = map[string]bool{
+	constants.UserEmailToUserSubject:      true,
+	constants.UserEmailToSubSubject:       true,
+	constants.UserEmailToUserBatchSubject: true,
+}
+
+// SecurityDetectionConfig sets the thresholds of the detection rules: the
+// number of matching requests of one caller within Window raising an event.
+// A threshold < 1 disables its rule.
+type SecurityDetectionConfig struct {
+	Window                    time.Duration
+	InvalidTokenThreshold     int
+	ScopeEscalationThreshold  int
+	EmailEnumerationThreshold int
+}
+
+// securityCounter counts the matching requests of a caller in the current
+// window of a rule
+type securityCounter struct {
+	start   time.Time
+	count   int
+	alerted bool
+}
+
+// SecurityDetector counts the requests of each caller matching the detection
+// rules in fixed windows. A caller reaching the threshold of a rule raises one
+// security event for the window: it is published on the security events
+// subject, logged and counted on an alertable metric.
+//
+// The caller of a request is the one its transport reports, such as the NATS
+// connection of the client; when the transport can't tell, the requests of a
+// token subject are counted together, and requests without either are not
+// counted at all.
+type SecurityDetector struct {
+	publisher  port.EventPublisher
+	window     time.Duration
+	thresholds map[string]int
+	now        func() time.Time
+
+	mu       sync.Mutex
+	counters map[string]map[string]*securityCounter
+}
+
+// NewSecurityDetector returns a detector publishing its events with
+// publisher; a nil publisher only logs and counts them
+func NewSecurityDetector(publisher port.EventPublisher, config SecurityDetectionConfig) *SecurityDetector {
+	return &SecurityDetector{
+		publisher: publisher,
+		window:    config.Window,
+		thresholds: map[string]int{
+			model.SecurityRuleInvalidToken:     config.InvalidTokenThreshold,
+			model.SecurityRuleScopeEscalation:  config.ScopeEscalationThreshold,
+			model.SecurityRuleEmailEnumeration: config.EmailEnumerationThreshold,
+		},
+		now:      time.Now,
+		counters: make(map[string]map[string]*securityCounter),
+	}
+}
+
+// DetectSecurityEvents returns the middleware feeding detector with every
+// request and its reply
+func DetectSecurityEvents(detector *SecurityDetector) Middleware {
+	return func(next port.Handler) port.Handler {
+		return port.HandlerFunc(func(ctx context.Context, msg port.TransportMessenger) {
+			request := securityRequest{subject: msg.Subject(), caller: transportCaller(msg)}
+			if token, ok := requestToken(msg.Data()); ok {
+				if peeked, ok := jwt.Peek(token); ok {
+					request.sub = peeked.Subject
+				}
+			}
+			if emailLookupSubjects[request.subject] {
+				detector.observe(ctx, model.SecurityRuleEmailEnumeration, request, lookedUpEmails(msg.Data()))
+			}
+			next.Handle(ctx, &securityMessenger{TransportMessenger: msg, ctx: ctx, detector: detector, request: request})
+		})
+	}
+}
+
+// securityRequest is what the detector knows of the request of a reply
+type securityRequest struct {
+	subject string
+	caller  string
+	// sub is the unverified subject of the request token
+	sub string
+}
+
+// key returns the identifier the requests of the caller are counted under,
+// empty when the request can't be attributed
+func (r securityRequest) key() string {
+	if r.caller != "" {
+		return r.caller
+	}
+	if r.sub != "" {
+		sum := sha256.Sum256([]byte(r.sub))
+		return "sub:" + hex.EncodeToString(sum[:])[:16]
+	}
+	return ""
+}
+
+// messengerWrapper is implemented by the messengers the middlewares wrap the
+// transport messenger in
+type messengerWrapper interface {
+	Unwrap() port.TransportMessenger
+}
+
+// transportCaller returns the caller the transport of msg reports, looking
+// through the messengers wrapping it
+func transportCaller(msg port.TransportMessenger) string {
+	for msg != nil {
+		if identifier, ok := msg.(port.CallerIdentifier); ok {
+			return identifier.Caller()
+		}
+		wrapper, ok := msg.(messengerWrapper)
+		if !ok {
+			break
+		}
+		msg = wrapper.Unwrap()
+	}
+	return ""
+}
+
+// lookedUpEmails returns the number of emails a lookup request resolves: the
+// entries of a batch, one for a single lookup
+func lookedUpEmails(data []byte) int {
+	var batch emailBatchRequest
+	if err := json.Unmarshal(data, &batch); err == nil {
+		return len(batch.Emails)
+	}
+	return 1
+}
+
+// securityMessenger feeds the detector with the reply sent through it
+type securityMessenger struct {
+	port.TransportMessenger
+
+	ctx      context.Context
+	detector *SecurityDetector
+	request  securityRequest
+}
+
+// Respond inspects the reply and forwards it
+func (s *securityMessenger) Respond(data []byte) error {
+	var reply struct {
+		Success bool   `json:"success"`
+		Error   string `json:"error"`
+	}
+	if err := json.Unmarshal(data, &reply); err == nil && !reply.Success {
+		switch {
+		case invalidTokenErrors[reply.Error]:
+			s.detector.observe(s.ctx, model.SecurityRuleInvalidToken, s.request, 1)
+		case reply.Error == jwt.ErrMissingRequiredScope:
+			s.detector.observe(s.ctx, model.SecurityRuleScopeEscalation, s.request, 1)
+		}
+	}
+	return s.TransportMessenger.Respond(data)
+}
+
+// Unwrap returns the wrapped messenger
+func (s *securityMessenger) Unwrap() port.TransportMessenger {
+	return s.TransportMessenger
+}
+
+// observe counts n matching requests of the caller of request for rule,
+// raising the event of the window when they reach its threshold
+func (d *SecurityDetector) observe(ctx context.Context, rule string, request securityRequest, n int) {
+	threshold := d.thresholds[rule]
+	key := request.key()
+	if threshold < 1 || key == "" || n < 1 {
+		return
+	}
+	now := d.now()
+
+	d.mu.Lock()
+	callers, ok := d.counters[rule]
+	if !ok {
+		callers = make(map[string]*securityCounter)
+		d.counters[rule] = callers
+	}
+	counter, tracked := callers[key]
+	if !tracked || now.Sub(counter.start) >= d.window {
+		if !tracked && len(callers) >= maxTrackedSecurityCallers {
+			d.sweep(callers, now)
+			if len(callers) >= maxTrackedSecurityCallers {
+				d.mu.Unlock()
+				return
+			}
+		}
+		counter = &securityCounter{start: now}
+		callers[key] = counter
+	}
+	counter.count += n
+	raise := counter.count >= threshold && !counter.alerted
+	if raise {
+		counter.alerted = true
+	}
+	event := model.SecurityEvent{
+		Rule:        rule,
+		Caller:      key,
+		UserID:      redaction.Redact(request.sub),
+		Subject:     request.subject,
+		Count:       counter.count,
+		Threshold:   threshold,
+		Window:      d.window.String(),
+		FirstSeenAt: counter.start,
+		DetectedAt:  now,
+	}
+	d.mu.Unlock()
+
+	if raise {
+		raiseSecurityEvent(ctx, d.publisher, event)
+	}
+}
+
+// sweep drops the counters of callers whose window is over; d.mu is held
+func (d *SecurityDetector) sweep(callers map[string]*securityCounter, now time.Time) {
+	for key, counter := range callers {
+		if now.Sub(counter.start) >= d.window {
+			delete(callers, key)
+		}
+	}
+}
+
+// securityEvents counts the security events raised, to alert on
+var securityEvents, _ = meter.Int64Counter("auth_service.security.events",
+	metric.WithDescription("Security events raised by the detection rules, by rule"))
+
+// raiseSecurityEvent logs, counts and publishes event with publisher, when
+// it is not nil
+func raiseSecurityEvent(ctx context.Context, publisher port.EventPublisher, event model.SecurityEvent) {
+	ctx = context.WithoutCancel(ctx)
+	event.EventID = uuid.NewString()
+
+	slog.WarnContext(ctx, "security event detected",
+		"rule", event.Rule,
+		"caller", event.Caller,
+		"user_id", event.UserID,
+		"subject", event.Subject,
+		"count", event.Count,
+		"window", event.Window,
+	)
+	securityEvents.Add(ctx, 1, metric.WithAttributes(attribute.String("rule", event.Rule)))
+
+	if publisher == nil {
+		return
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to marshal security event", "error", err, "rule", event.Rule)
+		return
+	}
+	publishCtx, cancel := context.WithTimeout(ctx, securityPublishTimeout)
+	defer cancel()
+	if err := publisher.Publish(publishCtx, constants.SecurityEventsSubject, data); err != nil {
+		slog.ErrorContext(ctx, "failed to publish security event",
+			"error", err,
+			"event_id", event.EventID,
+			"rule", event.Rule,
+		)
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/jwt"
+)
+
+// callerMessenger is a messenger whose transport identifies its caller
+type callerMessenger struct {
+	repliedMessenger
+	subject string
+	caller  string
+}
+
+func (m *callerMessenger) Subject() string { return m.subject }
+func (m *callerMessenger) Caller() string  { return m.caller }
+
+func TestDetectSecurityEvents(t *testing.T) {
+	newDetector := func(publisher port.EventPublisher) *SecurityDetector {
+		return NewSecurityDetector(publisher, SecurityDetectionConfig{
+			Window:                    time.Minute,
+			InvalidTokenThreshold:     3,
+			ScopeEscalationThreshold:  2,
+			EmailEnumerationThreshold: 5,
+		})
+	}
+	replying := func(reply string) port.Handler {
+		return port.HandlerFunc(func(_ context.Context, msg port.TransportMessenger) {
+			_ = msg.Respond([]byte(reply))
+		})
+	}
+	send := func(handler port.Handler, subject, caller, data string) {
+		msg := &callerMessenger{repliedMessenger: repliedMessenger{data: []byte(data)}, subject: subject, caller: caller}
+		handler.Handle(context.Background(), msg)
+	}
+	events := func(t *testing.T, publisher *mockEventPublisher) []model.SecurityEvent {
+		t.Helper()
+		var out []model.SecurityEvent
+		for _, call := range publisher.calls {
+			require.Equal(t, constants.SecurityEventsSubject, call.Subject)
+			var event model.SecurityEvent
+			require.NoError(t, json.Unmarshal(call.Data, &event))
+			out = append(out, event)
+		}
+		return out
+	}
+
+	t.Run("repeated invalid tokens from one caller raise one event", func(t *testing.T) {
+		publisher := &mockEventPublisher{}
+		handler := Chain(replying(`{"success":false,"error":"auth_token could not be verified"}`),
+			DetectSecurityEvents(newDetector(publisher)))
+
+		token, err := jwt.GenerateTestAccessToken("auth0|attacker", "https://test.any.com/", "https://test.any.com/api/v2/", "", time.Hour)
+		require.NoError(t, err)
+		for range 5 {
+			send(handler, constants.TokenCheckScopeSubject, "_INBOX.conn1", `{"auth_token":"`+token+`"}`)
+		}
+		send(handler, constants.TokenCheckScopeSubject, "_INBOX.conn2", `{"auth_token":"`+token+`"}`)
+
+		got := events(t, publisher)
+		require.Len(t, got, 1)
+		assert.Equal(t, model.SecurityRuleInvalidToken, got[0].Rule)
+		assert.Equal(t, "_INBOX.conn1", got[0].Caller)
+		assert.Equal(t, "auth0|att****", got[0].UserID)
+		assert.Equal(t, 3, got[0].Count)
+		assert.NotEmpty(t, got[0].EventID)
+	})
+
+	t.Run("scope escalation attempts raise an event", func(t *testing.T) {
+		publisher := &mockEventPublisher{}
+		handler := Chain(replying(`{"success":false,"error":"`+jwt.ErrMissingRequiredScope+`"}`),
+			DetectSecurityEvents(newDetector(publisher)))
+
+		send(handler, constants.UserMetadataUpdateSubject, "_INBOX.conn1", `{}`)
+		send(handler, constants.UserMetadataUpdateSubject, "_INBOX.conn1", `{}`)
+
+		got := events(t, publisher)
+		require.Len(t, got, 1)
+		assert.Equal(t, model.SecurityRuleScopeEscalation, got[0].Rule)
+		assert.Equal(t, constants.UserMetadataUpdateSubject, got[0].Subject)
+	})
+
+	t.Run("bulk email lookups raise an event, batch entries included", func(t *testing.T) {
+		publisher := &mockEventPublisher{}
+		handler := Chain(replying(`jdoe`), DetectSecurityEvents(newDetector(publisher)))
+
+		send(handler, constants.UserEmailToUserSubject, "_INBOX.conn1", `a@example.com`)
+		send(handler, constants.UserEmailToUserBatchSubject, "_INBOX.conn1", `{"emails":["b@example.com","c@example.com"]}`)
+		assert.Empty(t, publisher.calls)
+		send(handler, constants.UserEmailToSubSubject, "_INBOX.conn1", `d@example.com`)
+		send(handler, constants.UserEmailToSubSubject, "_INBOX.conn1", `e@example.com`)
+
+		got := events(t, publisher)
+		require.Len(t, got, 1)
+		assert.Equal(t, model.SecurityRuleEmailEnumeration, got[0].Rule)
+		assert.Equal(t, 5, got[0].Count)
+	})
+
+	t.Run("a new window counts again", func(t *testing.T) {
+		publisher := &mockEventPublisher{}
+		detector := newDetector(publisher)
+		now := time.Now()
+		detector.now = func() time.Time { return now }
+		handler := Chain(replying(`{"success":false,"error":"token has been revoked"}`), DetectSecurityEvents(detector))
+
+		for range 3 {
+			send(handler, constants.TokenCheckScopeSubject, "_INBOX.conn1", `{}`)
+		}
+		now = now.Add(2 * time.Minute)
+		for range 3 {
+			send(handler, constants.TokenCheckScopeSubject, "_INBOX.conn1", `{}`)
+		}
+		assert.Len(t, events(t, publisher), 2)
+	})
+
+	t.Run("ignores successful replies and unattributed requests", func(t *testing.T) {
+		publisher := &mockEventPublisher{}
+		detector := newDetector(publisher)
+		for range 5 {
+			send(Chain(replying(`{"success":true}`), DetectSecurityEvents(detector)), constants.TokenCheckScopeSubject, "_INBOX.conn1", `{}`)
+			send(Chain(replying(`{"success":false,"error":"auth_token could not be verified"}`), DetectSecurityEvents(detector)),
+				constants.TokenCheckScopeSubject, "", `{}`)
+		}
+		assert.Empty(t, publisher.calls)
+	})
+}
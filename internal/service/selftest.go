@@ -0,0 +1,116 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+)
+
+// selfTestTimeout bounds a whole admin.selftest run, so a hung dependency
+// fails its check instead of the monitor's request timing out
+const selfTestTimeout = 10 * time.Second
+
+// WithSelfTestForMessageHandler sets what admin.selftest checks: canary is
+// the username or sub of a dedicated test user looked up through the
+// provider, empty skips the round-trip; testers add their own checks.
+func WithSelfTestForMessageHandler(canary string, testers ...port.SelfTester) MessageHandlerOrchestratorOption {
+	return func(m *messageHandlerOrchestrator) {
+		m.selfTestCanary = canary
+		m.selfTesters = testers
+	}
+}
+
+// AdminSelfTest runs the internal checks of the replica that received the
+// request and replies with a pass/fail report. Only the canary user is read,
+// and none of its data is returned; the token in auth_token must carry the
+// self-test scope.
+func (m *messageHandlerOrchestrator) AdminSelfTest(ctx context.Context, msg port.TransportMessenger) ([]byte, error) {
+	if m.userReader == nil {
+		return m.errorResponse("auth_service_unavailable"), nil
+	}
+
+	var request struct {
+		AuthToken string `json:"auth_token"`
+	}
+	if err := json.Unmarshal(msg.Data(), &request); err != nil {
+		return m.errorResponse("failed_to_unmarshal_request"), nil
+	}
+	if _, err := m.authorizeScope(ctx, request.AuthToken, constants.SelfTestRunRequiredScope); err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, selfTestTimeout)
+	defer cancel()
+
+	checks := m.selfTestProvider(ctx)
+	for _, tester := range m.selfTesters {
+		checks = append(checks, tester.SelfTest(ctx)...)
+	}
+	report := model.NewConfigReport(checks)
+	if !report.OK {
+		slog.WarnContext(ctx, "self-test failed", "checks", checks)
+	}
+
+	responseJSON, err := json.Marshal(UserDataResponse{Success: true, Data: report})
+	if err != nil {
+		return m.errorResponse("failed to marshal response"), nil
+	}
+	return responseJSON, nil
+}
+
+// selfTestProvider looks the canary user up through the provider, then
+// checks the user cache serves it
+func (m *messageHandlerOrchestrator) selfTestProvider(ctx context.Context) []model.ConfigCheck {
+	if m.selfTestCanary == "" {
+		return []model.ConfigCheck{
+			{Name: "provider.canary", Status: model.ConfigCheckSkip, Detail: "no canary user configured"},
+			{Name: "cache.user", Status: model.ConfigCheckSkip, Detail: "no canary user configured"},
+		}
+	}
+
+	var userID string
+	checks := []model.ConfigCheck{
+		model.RunConfigCheck("provider.canary", func() (string, string) {
+			user, err := m.resolveUserFromAuthInput(ctx, m.selfTestCanary)
+			if err != nil {
+				return model.ConfigCheckFail, fmt.Sprintf("canary lookup failed: %v", err)
+			}
+			if user == nil || user.UserID == "" {
+				return model.ConfigCheckFail, "canary lookup returned no user"
+			}
+			userID = user.UserID
+			return model.ConfigCheckOK, "canary user resolved"
+		}),
+	}
+
+	checks = append(checks, model.RunConfigCheck("cache.user", func() (string, string) {
+		cache, ok := m.userReader.(port.UserCache)
+		switch {
+		case !ok:
+			return model.ConfigCheckSkip, "user cache disabled"
+		case userID == "":
+			return model.ConfigCheckSkip, "canary user not resolved"
+		}
+		if _, err := m.userReader.GetUser(ctx, &model.User{UserID: userID}); err != nil {
+			return model.ConfigCheckFail, fmt.Sprintf("canary read failed: %v", err)
+		}
+		if _, cached := cache.CachedUser(userID); !cached {
+			return model.ConfigCheckFail, "canary user was read but not cached"
+		}
+		if reporter, ok := m.userReader.(port.CacheStatsReporter); ok {
+			stats := reporter.CacheStats()
+			return model.ConfigCheckOK, fmt.Sprintf("%d entries, hit rate %.2f", stats.Entries, stats.HitRate)
+		}
+		return model.ConfigCheckOK, "canary user cached"
+	}))
+	return checks
+}
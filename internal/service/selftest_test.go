@@ -0,0 +1,142 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/jwt"
+)
+
+// mockSelfTester is a mock implementation of port.SelfTester for testing
+type mockSelfTester struct {
+	checks []model.ConfigCheck
+}
+
+func (m *mockSelfTester) SelfTest(ctx context.Context) []model.ConfigCheck {
+	return m.checks
+}
+
+// verifyingMetadataLookup accepts every access token, like a provider
+// verifying it would
+func verifyingMetadataLookup(ctx context.Context, input string) (*model.User, error) {
+	return &model.User{Token: input}, nil
+}
+
+// mockCachingUserReader is a user reader wrapped by a cache, like memory.UserCache
+type mockCachingUserReader struct {
+	*mockUserServiceReader
+	*mockUserCache
+}
+
+// selfTestRequest is an admin.selftest payload carrying a token with scope
+func selfTestRequest(t *testing.T, scope string) []byte {
+	t.Helper()
+	token, err := jwt.GenerateTestAccessToken("auth0|monitor", "https://test.any.com/", "https://test.any.com/api/v2/", scope, time.Hour)
+	require.NoError(t, err)
+	data, err := json.Marshal(map[string]string{"auth_token": token})
+	require.NoError(t, err)
+	return data
+}
+
+func runSelfTest(t *testing.T, opts ...MessageHandlerOrchestratorOption) (model.ConfigReport, map[string]model.ConfigCheck) {
+	t.Helper()
+	msg := &mockTransportMessenger{data: selfTestRequest(t, constants.SelfTestRunRequiredScope)}
+	raw, err := NewMessageHandlerOrchestrator(opts...).AdminSelfTest(context.Background(), msg)
+	require.NoError(t, err)
+
+	var response struct {
+		Success bool               `json:"success"`
+		Data    model.ConfigReport `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(raw, &response))
+	require.True(t, response.Success)
+
+	checks := make(map[string]model.ConfigCheck)
+	for _, check := range response.Data.Checks {
+		checks[check.Name] = check
+	}
+	return response.Data, checks
+}
+
+func TestMessageHandlerOrchestrator_AdminSelfTest(t *testing.T) {
+	canary := &model.User{UserID: "auth0|canary", Username: "canary"}
+
+	t.Run("passes with a cached canary", func(t *testing.T) {
+		cache := &mockUserCache{users: map[string]*model.User{}}
+		reader := &mockCachingUserReader{
+			mockUserServiceReader: &mockUserServiceReader{
+				metadataLookupFunc: verifyingMetadataLookup,
+				searchUserFunc: func(ctx context.Context, user *model.User, criteria string) (*model.User, error) {
+					return canary, nil
+				},
+				getUserFunc: func(ctx context.Context, user *model.User) (*model.User, error) {
+					cache.users[user.UserID] = canary
+					return canary, nil
+				},
+			},
+			mockUserCache: cache,
+		}
+		tester := &mockSelfTester{checks: []model.ConfigCheck{{Name: "nats.connection", Status: model.ConfigCheckOK}}}
+
+		report, checks := runSelfTest(t,
+			WithUserReaderForMessageHandler(reader),
+			WithSelfTestForMessageHandler("canary", tester),
+		)
+		assert.True(t, report.OK)
+		assert.Equal(t, model.ConfigCheckOK, checks["provider.canary"].Status)
+		assert.Equal(t, model.ConfigCheckOK, checks["cache.user"].Status)
+		assert.Equal(t, model.ConfigCheckOK, checks["nats.connection"].Status)
+	})
+
+	t.Run("fails when the canary lookup fails", func(t *testing.T) {
+		reader := &mockUserServiceReader{
+			metadataLookupFunc: verifyingMetadataLookup,
+			searchUserFunc: func(ctx context.Context, user *model.User, criteria string) (*model.User, error) {
+				return nil, errs.NewNotFound("user not found")
+			},
+		}
+
+		report, checks := runSelfTest(t,
+			WithUserReaderForMessageHandler(reader),
+			WithSelfTestForMessageHandler("canary"),
+		)
+		assert.False(t, report.OK)
+		assert.Equal(t, model.ConfigCheckFail, checks["provider.canary"].Status)
+		assert.Equal(t, model.ConfigCheckSkip, checks["cache.user"].Status)
+	})
+
+	t.Run("fails when a tester fails", func(t *testing.T) {
+		tester := &mockSelfTester{checks: []model.ConfigCheck{{Name: "nats.kv.tokens", Status: model.ConfigCheckFail}}}
+
+		reader := &mockUserServiceReader{metadataLookupFunc: verifyingMetadataLookup}
+
+		report, checks := runSelfTest(t, WithUserReaderForMessageHandler(reader), WithSelfTestForMessageHandler("", tester))
+		assert.False(t, report.OK)
+		assert.Equal(t, model.ConfigCheckSkip, checks["provider.canary"].Status)
+		assert.Equal(t, model.ConfigCheckFail, checks["nats.kv.tokens"].Status)
+	})
+	t.Run("requires the self-test scope", func(t *testing.T) {
+		tester := &mockSelfTester{checks: []model.ConfigCheck{{Name: "nats.connection", Status: model.ConfigCheckOK}}}
+		reader := &mockUserServiceReader{metadataLookupFunc: verifyingMetadataLookup}
+		m := NewMessageHandlerOrchestrator(WithUserReaderForMessageHandler(reader), WithSelfTestForMessageHandler("", tester))
+
+		raw, err := m.AdminSelfTest(context.Background(), &mockTransportMessenger{data: selfTestRequest(t, "read:users")})
+		require.NoError(t, err)
+		var response UserDataResponse
+		require.NoError(t, json.Unmarshal(raw, &response))
+		assert.False(t, response.Success)
+		assert.Equal(t, "insufficient_scope", response.Error)
+		assert.Nil(t, response.Data, "no check runs")
+	})
+}
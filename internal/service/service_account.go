@@ -0,0 +1,236 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/redaction"
+)
+
+// serviceAccountMaxGrants bounds the APIs a service account can be granted on creation
+const serviceAccountMaxGrants = 10
+
+// serviceAccountCreateRequest is the input of service_account.create
+type serviceAccountCreateRequest struct {
+	AuthToken   string                      `json:"auth_token"`
+	Name        string                      `json:"name"`
+	Description string                      `json:"description"`
+	Owner       string                      `json:"owner"`
+	Grants      []model.ServiceAccountGrant `json:"grants"`
+}
+
+// serviceAccountListRequest is the input of service_account.list
+type serviceAccountListRequest struct {
+	AuthToken string `json:"auth_token"`
+	// Owner optionally restricts the list to the accounts of one owner
+	Owner string `json:"owner"`
+}
+
+// serviceAccountRotateRequest is the input of service_account.rotate
+type serviceAccountRotateRequest struct {
+	AuthToken string `json:"auth_token"`
+	ClientID  string `json:"client_id"`
+}
+
+// WithServiceAccountManagerForMessageHandler sets the service account manager for the message handler orchestrator
+func WithServiceAccountManagerForMessageHandler(manager port.ServiceAccountManager) MessageHandlerOrchestratorOption {
+	return func(m *messageHandlerOrchestrator) {
+		m.serviceAccountManager = manager
+	}
+}
+
+// WithServiceAccountAudiencesForMessageHandler lists the APIs service
+// accounts can be granted access to. An empty list allows none.
+func WithServiceAccountAudiencesForMessageHandler(audiences []string) MessageHandlerOrchestratorOption {
+	return func(m *messageHandlerOrchestrator) {
+		m.serviceAccountAudiences = audiences
+	}
+}
+
+// validateServiceAccount normalizes a create request into the account to create
+func (m *messageHandlerOrchestrator) validateServiceAccount(request serviceAccountCreateRequest) (*model.ServiceAccount, error) {
+	account := &model.ServiceAccount{
+		Name:        strings.TrimSpace(request.Name),
+		Description: strings.TrimSpace(request.Description),
+		Owner:       strings.TrimSpace(request.Owner),
+		Grants:      make([]model.ServiceAccountGrant, 0, len(request.Grants)),
+	}
+	switch {
+	case account.Name == "":
+		return nil, errs.NewValidation("name is required")
+	case account.Owner == "":
+		return nil, errs.NewValidation("owner is required")
+	case len(request.Grants) == 0:
+		return nil, errs.NewValidation("grants are required")
+	case len(request.Grants) > serviceAccountMaxGrants:
+		return nil, errs.NewValidation(fmt.Sprintf("too many grants: at most %d per service account", serviceAccountMaxGrants))
+	}
+
+	for _, grant := range request.Grants {
+		audience := strings.TrimSpace(grant.Audience)
+		scopes := trimNonEmpty(grant.Scopes)
+		switch {
+		case audience == "":
+			return nil, errs.NewValidation("grant audience is required")
+		case len(scopes) == 0:
+			return nil, errs.NewValidation(fmt.Sprintf("grant for %s requires scopes", audience))
+		case isManagementAPIAudience(audience) || !slices.Contains(m.serviceAccountAudiences, audience):
+			return nil, errs.NewForbidden(fmt.Sprintf("audience %s is not allowed for service accounts", audience))
+		}
+		for _, existing := range account.Grants {
+			if existing.Audience == audience {
+				return nil, errs.NewValidation(fmt.Sprintf("duplicate grant for %s", audience))
+			}
+		}
+		account.Grants = append(account.Grants, model.ServiceAccountGrant{Audience: audience, Scopes: scopes})
+	}
+	return account, nil
+}
+
+// isManagementAPIAudience reports whether audience is the Auth0 Management
+// API of a tenant, https://<tenant>/api/v2/. Its grants administer the
+// tenant, so a service account never gets one, even when it is allowlisted.
+func isManagementAPIAudience(audience string) bool {
+	parsed, err := url.Parse(audience)
+	if err != nil {
+		return false
+	}
+	return strings.TrimSuffix(parsed.Path, "/") == "/api/v2"
+}
+
+// CreateServiceAccount creates a service account with scoped grants and
+// returns its client secret. The secret is only returned here and on rotation.
+func (m *messageHandlerOrchestrator) CreateServiceAccount(ctx context.Context, msg port.TransportMessenger) ([]byte, error) {
+	if m.serviceAccountManager == nil {
+		return m.errorResponse("service_account_management_unavailable"), nil
+	}
+
+	var request serviceAccountCreateRequest
+	if err := json.Unmarshal(msg.Data(), &request); err != nil {
+		return m.errorResponse("failed_to_unmarshal_request"), nil
+	}
+
+	claims, err := m.authorizeScope(ctx, request.AuthToken, constants.ServiceAccountManageRequiredScope)
+	if err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+
+	account, err := m.validateServiceAccount(request)
+	if err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+	account.CreatedBy = claims.Subject
+
+	credentials, err := m.serviceAccountManager.CreateServiceAccount(ctx, account)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to create service account", "error", err)
+		return m.errorResponse(err.Error()), nil
+	}
+
+	slog.InfoContext(ctx, "service account created",
+		"client_id", credentials.ClientID,
+		"owner", credentials.Owner,
+		"created_by", redaction.Redact(claims.Subject),
+		"grants", len(credentials.Grants),
+	)
+
+	responseJSON, err := json.Marshal(UserDataResponse{Success: true, Data: credentials})
+	if err != nil {
+		return m.errorResponse("failed to marshal response"), nil
+	}
+	return responseJSON, nil
+}
+
+// ListServiceAccounts lists the service accounts managed by the service,
+// optionally for a single owner. Secrets are never included.
+func (m *messageHandlerOrchestrator) ListServiceAccounts(ctx context.Context, msg port.TransportMessenger) ([]byte, error) {
+	if m.serviceAccountManager == nil {
+		return m.errorResponse("service_account_management_unavailable"), nil
+	}
+
+	var request serviceAccountListRequest
+	if err := json.Unmarshal(msg.Data(), &request); err != nil {
+		return m.errorResponse("failed_to_unmarshal_request"), nil
+	}
+
+	if _, err := m.authorizeScope(ctx, request.AuthToken, constants.ServiceAccountManageRequiredScope); err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+
+	accounts, err := m.serviceAccountManager.ListServiceAccounts(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to list service accounts", "error", err)
+		return m.errorResponse(err.Error()), nil
+	}
+
+	owner := strings.TrimSpace(request.Owner)
+	listed := make([]*model.ServiceAccount, 0, len(accounts))
+	for _, account := range accounts {
+		if owner == "" || account.Owner == owner {
+			listed = append(listed, account)
+		}
+	}
+	slices.SortFunc(listed, func(a, b *model.ServiceAccount) int {
+		return cmp.Or(strings.Compare(a.Name, b.Name), strings.Compare(a.ClientID, b.ClientID))
+	})
+
+	responseJSON, err := json.Marshal(UserDataResponse{Success: true, Data: listed})
+	if err != nil {
+		return m.errorResponse("failed to marshal response"), nil
+	}
+	return responseJSON, nil
+}
+
+// RotateServiceAccount replaces the client secret of a service account and
+// returns the new one. The previous secret stops working immediately.
+func (m *messageHandlerOrchestrator) RotateServiceAccount(ctx context.Context, msg port.TransportMessenger) ([]byte, error) {
+	if m.serviceAccountManager == nil {
+		return m.errorResponse("service_account_management_unavailable"), nil
+	}
+
+	var request serviceAccountRotateRequest
+	if err := json.Unmarshal(msg.Data(), &request); err != nil {
+		return m.errorResponse("failed_to_unmarshal_request"), nil
+	}
+
+	claims, err := m.authorizeScope(ctx, request.AuthToken, constants.ServiceAccountManageRequiredScope)
+	if err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+
+	clientID := strings.TrimSpace(request.ClientID)
+	if clientID == "" {
+		return m.errorResponse("client_id is required"), nil
+	}
+
+	credentials, err := m.serviceAccountManager.RotateServiceAccountSecret(ctx, clientID, claims.Subject)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to rotate service account secret", "error", err, "client_id", clientID)
+		return m.errorResponse(err.Error()), nil
+	}
+
+	slog.InfoContext(ctx, "service account secret rotated",
+		"client_id", clientID,
+		"owner", credentials.Owner,
+		"rotated_by", redaction.Redact(claims.Subject),
+	)
+
+	responseJSON, err := json.Marshal(UserDataResponse{Success: true, Data: credentials})
+	if err != nil {
+		return m.errorResponse("failed to marshal response"), nil
+	}
+	return responseJSON, nil
+}
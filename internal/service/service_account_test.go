@@ -0,0 +1,235 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/jwt"
+)
+
+// mockServiceAccountManager records the accounts it is asked to create and rotate
+type mockServiceAccountManager struct {
+	created   *model.ServiceAccount
+	rotatedBy string
+	accounts  []*model.ServiceAccount
+}
+
+func (m *mockServiceAccountManager) CreateServiceAccount(ctx context.Context, account *model.ServiceAccount) (*model.ServiceAccountCredentials, error) {
+	m.created = account
+	created := *account
+	created.ClientID = "cid_new"
+	return &model.ServiceAccountCredentials{ServiceAccount: created, ClientSecret: "s3cret"}, nil
+}
+
+func (m *mockServiceAccountManager) ListServiceAccounts(ctx context.Context) ([]*model.ServiceAccount, error) {
+	return m.accounts, nil
+}
+
+func (m *mockServiceAccountManager) RotateServiceAccountSecret(ctx context.Context, clientID, rotatedBy string) (*model.ServiceAccountCredentials, error) {
+	for _, account := range m.accounts {
+		if account.ClientID == clientID {
+			m.rotatedBy = rotatedBy
+			return &model.ServiceAccountCredentials{ServiceAccount: *account, ClientSecret: "n3w"}, nil
+		}
+	}
+	return nil, errors.NewNotFound("service account not found")
+}
+
+func TestMessageHandlerOrchestrator_ServiceAccounts(t *testing.T) {
+	ctx := context.Background()
+
+	withScope, err := jwt.GenerateTestAccessToken("auth0|admin", "https://issuer/", "aud", constants.ServiceAccountManageRequiredScope, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	withoutScope, err := jwt.GenerateSimpleTestAccessToken("auth0|admin", time.Hour)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+
+	reader := &mockUserServiceReader{
+		metadataLookupFunc: func(ctx context.Context, input string) (*model.User, error) {
+			return &model.User{Token: input, UserID: "auth0|admin"}, nil
+		},
+	}
+	newOrchestrator := func(manager *mockServiceAccountManager, opts ...MessageHandlerOrchestratorOption) port.MessageHandler {
+		opts = append([]MessageHandlerOrchestratorOption{WithServiceAccountAudiencesForMessageHandler([]string{"https://api.example.com/"})}, opts...)
+		opts = append(opts, WithUserReaderForMessageHandler(reader), WithServiceAccountManagerForMessageHandler(manager))
+		return NewMessageHandlerOrchestrator(opts...)
+	}
+
+	type response struct {
+		Success bool            `json:"success"`
+		Error   string          `json:"error"`
+		Data    json.RawMessage `json:"data"`
+	}
+	call := func(t *testing.T, handler func(context.Context, port.TransportMessenger) ([]byte, error), request map[string]any) response {
+		t.Helper()
+		payload, _ := json.Marshal(request)
+		result, err := handler(ctx, &mockTransportMessenger{data: payload})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var r response
+		if err := json.Unmarshal(result, &r); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		return r
+	}
+	validCreate := map[string]any{
+		"auth_token": withScope,
+		"name":       " indexer ",
+		"owner":      "team-search",
+		"grants":     []map[string]any{{"audience": "https://api.example.com/", "scopes": []string{"read:projects", " "}}},
+	}
+
+	t.Run("create returns the secret and records the creator", func(t *testing.T) {
+		manager := &mockServiceAccountManager{}
+		r := call(t, newOrchestrator(manager).CreateServiceAccount, validCreate)
+		if !r.Success {
+			t.Fatalf("expected success, got error %q", r.Error)
+		}
+		var credentials model.ServiceAccountCredentials
+		if err := json.Unmarshal(r.Data, &credentials); err != nil {
+			t.Fatalf("failed to unmarshal credentials: %v", err)
+		}
+		if credentials.ClientSecret != "s3cret" || credentials.ClientID != "cid_new" {
+			t.Errorf("credentials = %+v, want cid_new with its secret", credentials)
+		}
+		if manager.created.Name != "indexer" || manager.created.CreatedBy != "auth0|admin" {
+			t.Errorf("created = %+v, want trimmed name and the caller as creator", manager.created)
+		}
+		if scopes := manager.created.Grants[0].Scopes; len(scopes) != 1 || scopes[0] != "read:projects" {
+			t.Errorf("grant scopes = %v, want [read:projects]", scopes)
+		}
+	})
+
+	createErrors := []struct {
+		name    string
+		opts    []MessageHandlerOrchestratorOption
+		request map[string]any
+		want    string
+	}{
+		{
+			name:    "missing scope",
+			request: map[string]any{"auth_token": withoutScope, "name": "indexer", "owner": "team-search"},
+			want:    "insufficient_scope",
+		},
+		{
+			name:    "owner required",
+			request: map[string]any{"auth_token": withScope, "name": "indexer"},
+			want:    "owner is required",
+		},
+		{
+			name:    "grants required",
+			request: map[string]any{"auth_token": withScope, "name": "indexer", "owner": "team-search"},
+			want:    "grants are required",
+		},
+		{
+			name: "grant without scopes",
+			request: map[string]any{"auth_token": withScope, "name": "indexer", "owner": "team-search",
+				"grants": []map[string]any{{"audience": "https://api.example.com/"}}},
+			want: "requires scopes",
+		},
+		{
+			name: "duplicate audience",
+			request: map[string]any{"auth_token": withScope, "name": "indexer", "owner": "team-search",
+				"grants": []map[string]any{
+					{"audience": "https://api.example.com/", "scopes": []string{"a"}},
+					{"audience": "https://api.example.com/", "scopes": []string{"b"}},
+				}},
+			want: "duplicate grant",
+		},
+		{
+			name:    "audience not allowed",
+			opts:    []MessageHandlerOrchestratorOption{WithServiceAccountAudiencesForMessageHandler([]string{"https://other.example.com/"})},
+			request: validCreate,
+			want:    "is not allowed for service accounts",
+		},
+		{
+			name:    "no audience allowed without an allowlist",
+			opts:    []MessageHandlerOrchestratorOption{WithServiceAccountAudiencesForMessageHandler(nil)},
+			request: validCreate,
+			want:    "audience https://api.example.com/ is not allowed for service accounts",
+		},
+		{
+			name: "management API never allowed",
+			opts: []MessageHandlerOrchestratorOption{WithServiceAccountAudiencesForMessageHandler([]string{"https://tenant.auth0.com/api/v2/"})},
+			request: map[string]any{"auth_token": withScope, "name": "indexer", "owner": "team-search",
+				"grants": []map[string]any{{"audience": "https://tenant.auth0.com/api/v2/", "scopes": []string{"read:users"}}}},
+			want: "is not allowed for service accounts",
+		},
+	}
+	for _, tt := range createErrors {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := &mockServiceAccountManager{}
+			r := call(t, newOrchestrator(manager, tt.opts...).CreateServiceAccount, tt.request)
+			if r.Success || !strings.Contains(r.Error, tt.want) {
+				t.Errorf("response = %+v, want error containing %q", r, tt.want)
+			}
+			if manager.created != nil {
+				t.Error("rejected request must not create an account")
+			}
+		})
+	}
+
+	t.Run("list filters by owner and sorts by name", func(t *testing.T) {
+		manager := &mockServiceAccountManager{accounts: []*model.ServiceAccount{
+			{ClientID: "c3", Name: "zeta", Owner: "team-search"},
+			{ClientID: "c2", Name: "other", Owner: "team-web"},
+			{ClientID: "c1", Name: "alpha", Owner: "team-search"},
+		}}
+		r := call(t, newOrchestrator(manager).ListServiceAccounts, map[string]any{"auth_token": withScope, "owner": "team-search"})
+		if !r.Success {
+			t.Fatalf("expected success, got error %q", r.Error)
+		}
+		var accounts []model.ServiceAccount
+		if err := json.Unmarshal(r.Data, &accounts); err != nil {
+			t.Fatalf("failed to unmarshal accounts: %v", err)
+		}
+		if len(accounts) != 2 || accounts[0].ClientID != "c1" || accounts[1].ClientID != "c3" {
+			t.Errorf("accounts = %+v, want [c1 c3]", accounts)
+		}
+	})
+
+	t.Run("rotate records the caller", func(t *testing.T) {
+		manager := &mockServiceAccountManager{accounts: []*model.ServiceAccount{{ClientID: "c1", Name: "alpha"}}}
+		rotate := newOrchestrator(manager).RotateServiceAccount
+
+		r := call(t, rotate, map[string]any{"auth_token": withScope, "client_id": "c1"})
+		if !r.Success || manager.rotatedBy != "auth0|admin" {
+			t.Errorf("response = %+v, rotatedBy = %q; want success rotated by auth0|admin", r, manager.rotatedBy)
+		}
+
+		r = call(t, rotate, map[string]any{"auth_token": withScope, "client_id": "missing"})
+		if r.Success || r.Error != "service account not found" {
+			t.Errorf("response = %+v, want service account not found", r)
+		}
+
+		r = call(t, rotate, map[string]any{"auth_token": withScope})
+		if r.Success || r.Error != "client_id is required" {
+			t.Errorf("response = %+v, want client_id is required", r)
+		}
+	})
+
+	t.Run("unavailable without a manager", func(t *testing.T) {
+		m := NewMessageHandlerOrchestrator(WithUserReaderForMessageHandler(reader))
+		result, err := m.CreateServiceAccount(ctx, &mockTransportMessenger{data: []byte(`{}`)})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !strings.Contains(string(result), "service_account_management_unavailable") {
+			t.Errorf("response = %s, want service_account_management_unavailable", result)
+		}
+	})
+}
@@ -0,0 +1,200 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/redaction"
+)
+
+// sessionListRequest is the input of user.sessions.list
+type sessionListRequest struct {
+	AuthToken string `json:"auth_token"`
+	// Sub selects another user; it needs the manage:sessions scope
+	Sub string `json:"sub"`
+}
+
+// sessionRevokeRequest is the input of user.sessions.revoke. Either All is
+// set, or ID and Type select one session.
+type sessionRevokeRequest struct {
+	AuthToken string `json:"auth_token"`
+	Sub       string `json:"sub"`
+	ID        string `json:"id"`
+	Type      string `json:"type"`
+	All       bool   `json:"all"`
+}
+
+// sessionRevokeResult is the reply of user.sessions.revoke
+type sessionRevokeResult struct {
+	ID   string `json:"id,omitempty"`
+	Type string `json:"type,omitempty"`
+	All  bool   `json:"all"`
+	// AccessTokensRevoked reports whether the access tokens the user already
+	// holds were revoked too, which needs the token revocation list
+	AccessTokensRevoked bool `json:"access_tokens_revoked"`
+}
+
+// WithSessionManagerForMessageHandler sets the session manager for the message handler orchestrator
+func WithSessionManagerForMessageHandler(manager port.SessionManager) MessageHandlerOrchestratorOption {
+	return func(m *messageHandlerOrchestrator) {
+		m.sessionManager = manager
+	}
+}
+
+// sessionOwner verifies authToken and returns the sub of its caller and of
+// the user whose sessions they act on: their own, or sub when the token
+// carries the manage:sessions scope
+func (m *messageHandlerOrchestrator) sessionOwner(ctx context.Context, authToken, sub string) (caller, owner string, err error) {
+	claims, err := m.verifyAccessToken(ctx, authToken)
+	if err != nil {
+		return "", "", err
+	}
+	if claims.Subject == "" {
+		return "", "", errs.NewUnauthorized("auth_token has no subject")
+	}
+	sub = strings.TrimSpace(sub)
+	if sub == "" || sub == claims.Subject {
+		return claims.Subject, claims.Subject, nil
+	}
+	if !claims.HasScope(constants.SessionManageRequiredScope) {
+		return "", "", errs.NewForbidden("insufficient_scope")
+	}
+	return claims.Subject, sub, nil
+}
+
+// ListSessions lists the login sessions and refresh tokens of the caller, or
+// of another user for admins, most recently active first
+func (m *messageHandlerOrchestrator) ListSessions(ctx context.Context, msg port.TransportMessenger) ([]byte, error) {
+	if m.sessionManager == nil {
+		return m.errorResponse("session_management_unavailable"), nil
+	}
+
+	var request sessionListRequest
+	if err := json.Unmarshal(msg.Data(), &request); err != nil {
+		return m.errorResponse("failed_to_unmarshal_request"), nil
+	}
+
+	_, userID, err := m.sessionOwner(ctx, request.AuthToken, request.Sub)
+	if err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+
+	sessions, err := m.sessionManager.ListSessions(ctx, userID)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to list sessions", "error", err)
+		return m.errorResponse(err.Error()), nil
+	}
+	slices.SortFunc(sessions, func(a, b *model.UserSession) int {
+		return cmp.Or(compareTimesDesc(a.LastActiveAt, b.LastActiveAt), compareTimesDesc(a.CreatedAt, b.CreatedAt), strings.Compare(a.ID, b.ID))
+	})
+
+	responseJSON, err := json.Marshal(UserDataResponse{Success: true, Data: sessions})
+	if err != nil {
+		return m.errorResponse("failed to marshal response"), nil
+	}
+	return responseJSON, nil
+}
+
+// RevokeSession revokes one session of the caller, or all of them, forcing a
+// new sign-in on the affected devices. Admins can revoke another user's.
+func (m *messageHandlerOrchestrator) RevokeSession(ctx context.Context, msg port.TransportMessenger) ([]byte, error) {
+	if m.sessionManager == nil {
+		return m.errorResponse("session_management_unavailable"), nil
+	}
+
+	var request sessionRevokeRequest
+	if err := json.Unmarshal(msg.Data(), &request); err != nil {
+		return m.errorResponse("failed_to_unmarshal_request"), nil
+	}
+
+	caller, userID, err := m.sessionOwner(ctx, request.AuthToken, request.Sub)
+	if err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+
+	result := sessionRevokeResult{All: request.All}
+	if request.All {
+		if request.ID != "" {
+			return m.errorResponse("id must not be set with all"), nil
+		}
+		if err := m.sessionManager.RevokeAllSessions(ctx, userID); err != nil {
+			slog.ErrorContext(ctx, "failed to revoke sessions", "error", err)
+			return m.errorResponse(err.Error()), nil
+		}
+		result.AccessTokensRevoked = m.revokeAccessTokensOf(ctx, userID, caller)
+	} else {
+		result.ID = strings.TrimSpace(request.ID)
+		result.Type = cmp.Or(strings.TrimSpace(request.Type), model.SessionTypeLogin)
+		if result.ID == "" {
+			return m.errorResponse("id is required unless all is set"), nil
+		}
+		if result.Type != model.SessionTypeLogin && result.Type != model.SessionTypeRefreshToken {
+			return m.errorResponse("type must be session or refresh_token"), nil
+		}
+		if err := m.sessionManager.RevokeSession(ctx, userID, result.Type, result.ID); err != nil {
+			slog.ErrorContext(ctx, "failed to revoke session", "error", err, "type", result.Type)
+			return m.errorResponse(err.Error()), nil
+		}
+	}
+
+	slog.InfoContext(ctx, "sessions revoked",
+		"user_id", redaction.Redact(userID),
+		"revoked_by", redaction.Redact(caller),
+		"all", result.All,
+		"type", result.Type,
+		"access_tokens_revoked", result.AccessTokensRevoked,
+	)
+
+	responseJSON, err := json.Marshal(UserDataResponse{Success: true, Data: result})
+	if err != nil {
+		return m.errorResponse("failed to marshal response"), nil
+	}
+	return responseJSON, nil
+}
+
+// revokeAccessTokensOf revokes the access tokens userID already holds when
+// the revocation list is enabled. Revoking sessions only stops new tokens
+// from being issued, so without it a forced logout lasts until they expire.
+func (m *messageHandlerOrchestrator) revokeAccessTokensOf(ctx context.Context, userID, revokedBy string) bool {
+	if m.tokenRevocations == nil {
+		return false
+	}
+	now := time.Now().UTC()
+	revocation := &model.TokenRevocation{
+		Subject:   userID,
+		Reason:    "sessions revoked",
+		RevokedBy: revokedBy,
+		RevokedAt: now,
+		ExpiresAt: now.Add(cmp.Or(m.tokenRevocationTTL, defaultTokenRevocationTTL)),
+	}
+	if err := m.tokenRevocations.Revoke(ctx, revocation); err != nil {
+		slog.WarnContext(ctx, "failed to revoke access tokens after session revocation", "error", err)
+		return false
+	}
+	return true
+}
+
+// compareTimesDesc orders later times first and missing times last
+func compareTimesDesc(a, b *time.Time) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+	return b.Compare(*a)
+}
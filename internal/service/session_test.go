@@ -0,0 +1,187 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/jwt"
+)
+
+// mockSessionManager records the sessions it is asked to revoke
+type mockSessionManager struct {
+	sessions   map[string][]*model.UserSession
+	revoked    []string
+	revokedAll []string
+}
+
+func (m *mockSessionManager) ListSessions(ctx context.Context, userID string) ([]*model.UserSession, error) {
+	return m.sessions[userID], nil
+}
+
+func (m *mockSessionManager) RevokeSession(ctx context.Context, userID, sessionType, sessionID string) error {
+	for _, session := range m.sessions[userID] {
+		if session.ID == sessionID && session.Type == sessionType {
+			m.revoked = append(m.revoked, sessionID)
+			return nil
+		}
+	}
+	return errors.NewNotFound("session not found")
+}
+
+func (m *mockSessionManager) RevokeAllSessions(ctx context.Context, userID string) error {
+	m.revokedAll = append(m.revokedAll, userID)
+	return nil
+}
+
+func TestMessageHandlerOrchestrator_Sessions(t *testing.T) {
+	ctx := context.Background()
+
+	aliceToken, err := jwt.GenerateSimpleTestAccessToken("auth0|alice", time.Hour)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	adminToken, err := jwt.GenerateTestAccessToken("auth0|admin", "https://issuer/", "aud", constants.SessionManageRequiredScope, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+
+	older, newer := time.Now().Add(-2*time.Hour), time.Now().Add(-time.Hour)
+	newManager := func() *mockSessionManager {
+		return &mockSessionManager{sessions: map[string][]*model.UserSession{
+			"auth0|alice": {
+				{ID: "ses_idle", Type: model.SessionTypeLogin},
+				{ID: "ses_old", Type: model.SessionTypeLogin, LastActiveAt: &older},
+				{ID: "rt_new", Type: model.SessionTypeRefreshToken, LastActiveAt: &newer},
+			},
+			"auth0|bob": {{ID: "ses_bob", Type: model.SessionTypeLogin}},
+		}}
+	}
+	reader := &mockUserServiceReader{
+		metadataLookupFunc: func(ctx context.Context, input string) (*model.User, error) {
+			return &model.User{Token: input}, nil
+		},
+	}
+	newOrchestrator := func(manager *mockSessionManager, opts ...MessageHandlerOrchestratorOption) port.MessageHandler {
+		opts = append(opts, WithUserReaderForMessageHandler(reader), WithSessionManagerForMessageHandler(manager))
+		return NewMessageHandlerOrchestrator(opts...)
+	}
+
+	type response struct {
+		Success bool            `json:"success"`
+		Error   string          `json:"error"`
+		Data    json.RawMessage `json:"data"`
+	}
+	call := func(t *testing.T, handler func(context.Context, port.TransportMessenger) ([]byte, error), request map[string]any) response {
+		t.Helper()
+		payload, _ := json.Marshal(request)
+		result, err := handler(ctx, &mockTransportMessenger{data: payload})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var r response
+		if err := json.Unmarshal(result, &r); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		return r
+	}
+
+	t.Run("list returns the caller's sessions, most recent first", func(t *testing.T) {
+		r := call(t, newOrchestrator(newManager()).ListSessions, map[string]any{"auth_token": aliceToken})
+		if !r.Success {
+			t.Fatalf("expected success, got error %q", r.Error)
+		}
+		var sessions []model.UserSession
+		if err := json.Unmarshal(r.Data, &sessions); err != nil {
+			t.Fatalf("failed to unmarshal sessions: %v", err)
+		}
+		if len(sessions) != 3 || sessions[0].ID != "rt_new" || sessions[1].ID != "ses_old" || sessions[2].ID != "ses_idle" {
+			t.Errorf("sessions = %+v, want [rt_new ses_old ses_idle]", sessions)
+		}
+	})
+
+	t.Run("other users need the manage scope", func(t *testing.T) {
+		m := newOrchestrator(newManager())
+		if r := call(t, m.ListSessions, map[string]any{"auth_token": aliceToken, "sub": "auth0|bob"}); r.Success || r.Error != "insufficient_scope" {
+			t.Errorf("response = %+v, want insufficient_scope", r)
+		}
+		if r := call(t, m.ListSessions, map[string]any{"auth_token": adminToken, "sub": "auth0|bob"}); !r.Success {
+			t.Errorf("expected admin success, got error %q", r.Error)
+		}
+	})
+
+	t.Run("revoke one session", func(t *testing.T) {
+		manager := newManager()
+		m := newOrchestrator(manager)
+		if r := call(t, m.RevokeSession, map[string]any{"auth_token": aliceToken, "id": "rt_new", "type": "refresh_token"}); !r.Success {
+			t.Fatalf("expected success, got error %q", r.Error)
+		}
+		if len(manager.revoked) != 1 || manager.revoked[0] != "rt_new" {
+			t.Errorf("revoked = %v, want [rt_new]", manager.revoked)
+		}
+		if r := call(t, m.RevokeSession, map[string]any{"auth_token": aliceToken, "id": "ses_bob"}); r.Success || r.Error != "session not found" {
+			t.Errorf("response = %+v, want session not found", r)
+		}
+	})
+
+	t.Run("revoke all also revokes access tokens when the list is enabled", func(t *testing.T) {
+		manager := newManager()
+		revocations := &mockTokenRevocationList{}
+		r := call(t, newOrchestrator(manager, WithTokenRevocationListForMessageHandler(revocations, 0)).RevokeSession,
+			map[string]any{"auth_token": adminToken, "sub": "auth0|alice", "all": true})
+		if !r.Success {
+			t.Fatalf("expected success, got error %q", r.Error)
+		}
+		var result sessionRevokeResult
+		if err := json.Unmarshal(r.Data, &result); err != nil {
+			t.Fatalf("failed to unmarshal result: %v", err)
+		}
+		if !result.All || !result.AccessTokensRevoked {
+			t.Errorf("result = %+v, want all sessions and access tokens revoked", result)
+		}
+		if len(manager.revokedAll) != 1 || manager.revokedAll[0] != "auth0|alice" {
+			t.Errorf("revokedAll = %v, want [auth0|alice]", manager.revokedAll)
+		}
+		if len(revocations.revocations) != 1 || revocations.revocations[0].Subject != "auth0|alice" || revocations.revocations[0].RevokedBy != "auth0|admin" {
+			t.Errorf("revocations = %+v, want alice's tokens revoked by auth0|admin", revocations.revocations)
+		}
+	})
+
+	revokeErrors := []struct {
+		name    string
+		request map[string]any
+		want    string
+	}{
+		{name: "id required", request: map[string]any{"auth_token": aliceToken}, want: "id is required unless all is set"},
+		{name: "unknown type", request: map[string]any{"auth_token": aliceToken, "id": "x", "type": "cookie"}, want: "type must be session or refresh_token"},
+		{name: "id with all", request: map[string]any{"auth_token": aliceToken, "id": "x", "all": true}, want: "id must not be set with all"},
+	}
+	for _, tt := range revokeErrors {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := newManager()
+			r := call(t, newOrchestrator(manager).RevokeSession, tt.request)
+			if r.Success || r.Error != tt.want {
+				t.Errorf("response = %+v, want %q", r, tt.want)
+			}
+			if len(manager.revoked)+len(manager.revokedAll) != 0 {
+				t.Error("rejected request must not revoke")
+			}
+		})
+	}
+
+	t.Run("unavailable without a manager", func(t *testing.T) {
+		m := NewMessageHandlerOrchestrator(WithUserReaderForMessageHandler(reader))
+		r := call(t, m.ListSessions, map[string]any{"auth_token": aliceToken})
+		if r.Success || r.Error != "session_management_unavailable" {
+			t.Errorf("response = %+v, want session_management_unavailable", r)
+		}
+	})
+}
@@ -0,0 +1,287 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"reflect"
+	"sort"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/envelope"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/redaction"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/tenant"
+)
+
+// Outcomes of a shadow read recorded by the shadow metrics
+const (
+	shadowOutcomeMatch    = "match"
+	shadowOutcomeDiverged = "diverged"
+	// shadowOutcomeError means the secondary failed where the primary didn't
+	shadowOutcomeError = "secondary_error"
+	// shadowOutcomeDropped means the replay was skipped because too many
+	// were in flight
+	shadowOutcomeDropped = "dropped"
+)
+
+// shadowIgnoredFields differ between providers by design, or on every
+// sign-in, so they are not reported as divergence
+var shadowIgnoredFields = map[string]bool{
+	"token":    true,
+	"activity": true,
+}
+
+// ShadowConfig configures shadow reads
+type ShadowConfig struct {
+	// SampleRate is the fraction of reads replayed against the secondary, in (0, 1]
+	SampleRate float64
+	// MaxInFlight caps the replays running at once; reads beyond it aren't replayed
+	MaxInFlight int
+	// Timeout bounds each replay
+	Timeout time.Duration
+	// Primary and Secondary are the names of the providers, e.g. auth0, the
+	// requests pinned to one of them are served by
+	Primary   string
+	Secondary string
+}
+
+// ShadowUserReaderWriter serves reads from the primary user repository and
+// replays a sample of them against a secondary in the background, comparing
+// the answers, so a provider switch can be validated on production traffic.
+// Callers always get the primary's answer and never wait on the secondary,
+// unless their request is pinned to one of them; writes only reach the
+// primary.
+type ShadowUserReaderWriter struct {
+	port.UserReaderWriter
+	secondary port.UserReader
+	config    ShadowConfig
+	slots     chan struct{}
+	sample    func() float64
+
+	reads     metric.Int64Counter
+	divergent metric.Int64Counter
+}
+
+// GetUser gets the user from the primary, replaying the read on the secondary
+func (s *ShadowUserReaderWriter) GetUser(ctx context.Context, user *model.User) (*model.User, error) {
+	if reader := s.pinned(ctx); reader != nil {
+		return reader.GetUser(ctx, user)
+	}
+	found, err := s.UserReaderWriter.GetUser(ctx, user)
+	s.shadow(ctx, "get_user", user, found, err, func(ctx context.Context, user *model.User) (*model.User, error) {
+		return s.secondary.GetUser(ctx, user)
+	})
+	return found, err
+}
+
+// SearchUser searches the user on the primary, replaying the search on the secondary
+func (s *ShadowUserReaderWriter) SearchUser(ctx context.Context, user *model.User, criteria string) (*model.User, error) {
+	if reader := s.pinned(ctx); reader != nil {
+		return reader.SearchUser(ctx, user, criteria)
+	}
+	found, err := s.UserReaderWriter.SearchUser(ctx, user, criteria)
+	s.shadow(ctx, "search_user", user, found, err, func(ctx context.Context, user *model.User) (*model.User, error) {
+		return s.secondary.SearchUser(ctx, user, criteria)
+	})
+	return found, err
+}
+
+// pinned returns the provider the request of ctx is pinned to, recording it
+// for the reply, nil when it isn't pinned. Pinned reads aren't replayed:
+// their caller is comparing the providers itself.
+func (s *ShadowUserReaderWriter) pinned(ctx context.Context) port.UserReader {
+	provider := envelope.ProviderFromContext(ctx)
+	var reader port.UserReader
+	switch {
+	case provider == "":
+		return nil
+	case provider == s.config.Secondary:
+		reader = s.secondary
+	case provider == s.config.Primary:
+		reader = s.UserReaderWriter
+	default:
+		return nil
+	}
+	envelope.RecordProvider(ctx, provider)
+	return reader
+}
+
+// shadow replays a read in the background when it is sampled and a slot is
+// free. Reads the primary failed for other reasons than not found aren't
+// replayed: there is no answer to compare with.
+func (s *ShadowUserReaderWriter) shadow(ctx context.Context, operation string, input, primary *model.User, primaryErr error,
+	replay func(context.Context, *model.User) (*model.User, error)) {
+	if primaryErr != nil && !isNotFound(primaryErr) {
+		return
+	}
+	if s.sample() >= s.config.SampleRate {
+		return
+	}
+	select {
+	case s.slots <- struct{}{}:
+	default:
+		s.record(ctx, operation, shadowOutcomeDropped)
+		return
+	}
+
+	// the replay outlives the request, keeping its values for logs and metrics
+	replayCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.config.Timeout)
+	var replayInput *model.User
+	if input != nil {
+		copied := *input
+		replayInput = &copied
+	}
+	go func() {
+		defer func() { <-s.slots }()
+		defer cancel()
+		secondary, err := replay(replayCtx, replayInput)
+		s.compare(replayCtx, operation, input, primary, primaryErr, secondary, err)
+	}()
+}
+
+// compare records whether the secondary agreed with the primary, logging the
+// names of the fields they disagree on but not their values
+func (s *ShadowUserReaderWriter) compare(ctx context.Context, operation string, input, primary *model.User, primaryErr error, secondary *model.User, secondaryErr error) {
+	userID := ""
+	if input != nil {
+		userID = input.UserID
+	}
+
+	switch {
+	case secondaryErr != nil && !isNotFound(secondaryErr):
+		slog.WarnContext(ctx, "shadow read failed on the secondary provider",
+			"operation", operation,
+			"user_id", redaction.Redact(userID),
+			"error", secondaryErr,
+		)
+		s.record(ctx, operation, shadowOutcomeError)
+		return
+	case primaryErr != nil || secondaryErr != nil:
+		// at least one of them didn't find the user
+		if primaryErr != nil && secondaryErr != nil {
+			s.record(ctx, operation, shadowOutcomeMatch)
+			return
+		}
+		s.diverged(ctx, operation, userID, []string{"found"})
+		return
+	}
+
+	if fields := divergentFields(primary, secondary); len(fields) > 0 {
+		s.diverged(ctx, operation, userID, fields)
+		return
+	}
+	s.record(ctx, operation, shadowOutcomeMatch)
+}
+
+func (s *ShadowUserReaderWriter) diverged(ctx context.Context, operation, userID string, fields []string) {
+	slog.InfoContext(ctx, "shadow read diverged from the primary provider",
+		"operation", operation,
+		"user_id", redaction.Redact(userID),
+		"fields", fields,
+	)
+	s.record(ctx, operation, shadowOutcomeDiverged)
+	for _, field := range fields {
+		s.divergent.Add(ctx, 1, tenant.Attributes(ctx,
+			attribute.String("operation", operation),
+			attribute.String("field", field),
+		))
+	}
+}
+
+func (s *ShadowUserReaderWriter) record(ctx context.Context, operation, outcome string) {
+	s.reads.Add(context.WithoutCancel(ctx), 1, tenant.Attributes(ctx,
+		attribute.String("operation", operation),
+		attribute.String("outcome", outcome),
+	))
+}
+
+func isNotFound(err error) bool {
+	var notFound errs.NotFound
+	return errors.As(err, &notFound)
+}
+
+// divergentFields returns the JSON names of the user fields a and b
+// disagree on, sorted. Nested objects such as user_metadata are compared
+// member by member, e.g. user_metadata.city.
+func divergentFields(a, b *model.User) []string {
+	var fields []string
+	diffMembers("", userFields(a), userFields(b), &fields)
+	sort.Strings(fields)
+	return fields
+}
+
+func diffMembers(prefix string, left, right map[string]any, fields *[]string) {
+	names := map[string]bool{}
+	for name := range left {
+		names[name] = true
+	}
+	for name := range right {
+		names[name] = true
+	}
+	for name := range names {
+		if prefix == "" && shadowIgnoredFields[name] {
+			continue
+		}
+		l, r := left[name], right[name]
+		lm, lok := l.(map[string]any)
+		rm, rok := r.(map[string]any)
+		switch {
+		case lok && rok:
+			diffMembers(prefix+name+".", lm, rm, fields)
+		case !reflect.DeepEqual(l, r):
+			*fields = append(*fields, prefix+name)
+		}
+	}
+}
+
+func userFields(user *model.User) map[string]any {
+	fields := map[string]any{}
+	if user == nil {
+		return fields
+	}
+	raw, _ := json.Marshal(user)
+	_ = json.Unmarshal(raw, &fields)
+	return fields
+}
+
+// NewShadowUserReaderWriter serves reads and writes from primary and shadows
+// the reads on secondary. A SampleRate outside (0, 1] replays every read, a
+// MaxInFlight <= 0 allows 16 replays at once and a Timeout <= 0 gives each
+// 5 seconds.
+func NewShadowUserReaderWriter(primary port.UserReaderWriter, secondary port.UserReader, config ShadowConfig) *ShadowUserReaderWriter {
+	if config.SampleRate <= 0 || config.SampleRate > 1 {
+		config.SampleRate = 1
+	}
+	if config.MaxInFlight <= 0 {
+		config.MaxInFlight = 16
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 5 * time.Second
+	}
+
+	reads, _ := meter.Int64Counter("auth_service.provider.shadow_reads",
+		metric.WithDescription("Reads replayed against the secondary provider, by whether its answer matched the primary's"))
+	divergent, _ := meter.Int64Counter("auth_service.provider.shadow_divergent_fields",
+		metric.WithDescription("User fields the secondary provider answered differently from the primary, by field"))
+
+	return &ShadowUserReaderWriter{
+		UserReaderWriter: primary,
+		secondary:        secondary,
+		config:           config,
+		slots:            make(chan struct{}, config.MaxInFlight),
+		sample:           rand.Float64,
+		reads:            reads,
+		divergent:        divergent,
+	}
+}
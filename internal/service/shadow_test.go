@@ -0,0 +1,156 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/envelope"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+)
+
+// fixedReader answers every GetUser with user or err
+type fixedReader struct {
+	port.UserReaderWriter
+	user *model.User
+	err  error
+}
+
+func (r *fixedReader) GetUser(ctx context.Context, user *model.User) (*model.User, error) {
+	return r.user, r.err
+}
+
+// replayedReader reports the inputs of the reads replayed on it
+type replayedReader struct {
+	port.UserReaderWriter
+	inputs  chan *model.User
+	release chan struct{}
+}
+
+func (r *replayedReader) GetUser(ctx context.Context, user *model.User) (*model.User, error) {
+	r.inputs <- user
+	if r.release != nil {
+		<-r.release
+	}
+	return &model.User{UserID: user.UserID, Username: "secondary"}, nil
+}
+
+func TestShadowUserReaderWriter_GetUser(t *testing.T) {
+	primaryUser := &model.User{UserID: "auth0|123", Username: "primary"}
+
+	t.Run("answers from the primary and replays a copy of the read", func(t *testing.T) {
+		secondary := &replayedReader{inputs: make(chan *model.User, 1)}
+		shadow := NewShadowUserReaderWriter(&fixedReader{user: primaryUser}, secondary, ShadowConfig{})
+
+		input := &model.User{UserID: "auth0|123"}
+		found, err := shadow.GetUser(context.Background(), input)
+		require.NoError(t, err)
+		assert.Equal(t, primaryUser, found)
+
+		select {
+		case replayed := <-secondary.inputs:
+			assert.Equal(t, "auth0|123", replayed.UserID)
+			assert.NotSame(t, input, replayed)
+		case <-time.After(time.Second):
+			t.Fatal("the read was not replayed on the secondary")
+		}
+	})
+
+	t.Run("doesn't replay reads the primary failed", func(t *testing.T) {
+		secondary := &replayedReader{inputs: make(chan *model.User, 1)}
+		shadow := NewShadowUserReaderWriter(&fixedReader{err: errs.NewServiceUnavailable("down")}, secondary, ShadowConfig{})
+
+		_, err := shadow.GetUser(context.Background(), &model.User{UserID: "auth0|123"})
+		require.Error(t, err)
+		select {
+		case <-secondary.inputs:
+			t.Fatal("a failed read was replayed")
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+
+	t.Run("drops replays beyond the in-flight cap", func(t *testing.T) {
+		secondary := &replayedReader{inputs: make(chan *model.User, 2), release: make(chan struct{})}
+		defer close(secondary.release)
+		shadow := NewShadowUserReaderWriter(&fixedReader{user: primaryUser}, secondary, ShadowConfig{MaxInFlight: 1})
+
+		for range 2 {
+			_, err := shadow.GetUser(context.Background(), &model.User{UserID: "auth0|123"})
+			require.NoError(t, err)
+		}
+		<-secondary.inputs
+		select {
+		case <-secondary.inputs:
+			t.Fatal("a replay ran beyond the in-flight cap")
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+
+	t.Run("samples replays", func(t *testing.T) {
+		secondary := &replayedReader{inputs: make(chan *model.User, 1)}
+		shadow := NewShadowUserReaderWriter(&fixedReader{user: primaryUser}, secondary, ShadowConfig{SampleRate: 0.5})
+		shadow.sample = func() float64 { return 0.7 }
+
+		_, err := shadow.GetUser(context.Background(), &model.User{UserID: "auth0|123"})
+		require.NoError(t, err)
+		select {
+		case <-secondary.inputs:
+			t.Fatal("an unsampled read was replayed")
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+
+	t.Run("serves pinned reads from their provider without replaying them", func(t *testing.T) {
+		secondary := &replayedReader{inputs: make(chan *model.User, 1)}
+		shadow := NewShadowUserReaderWriter(&fixedReader{user: primaryUser}, secondary,
+			ShadowConfig{Primary: "authelia", Secondary: "auth0"})
+
+		ctx, recorder := envelope.WithRecorder(envelope.WithProvider(context.Background(), "auth0"))
+		found, err := shadow.GetUser(ctx, &model.User{UserID: "auth0|123"})
+		require.NoError(t, err)
+		assert.Equal(t, "secondary", found.Username)
+		assert.Equal(t, "auth0", recorder.Provider())
+		<-secondary.inputs
+
+		found, err = shadow.GetUser(envelope.WithProvider(context.Background(), "authelia"), &model.User{UserID: "auth0|123"})
+		require.NoError(t, err)
+		assert.Equal(t, primaryUser, found)
+		select {
+		case <-secondary.inputs:
+			t.Fatal("a pinned read was replayed")
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+}
+
+func TestDivergentFields(t *testing.T) {
+	city, otherCity := "Portland", "Seattle"
+	a := &model.User{
+		Token:        "token-a",
+		UserID:       "auth0|123",
+		Username:     "jdoe",
+		PrimaryEmail: "jdoe@example.com",
+		UserMetadata: &model.UserMetadata{City: &city},
+		Activity:     &model.UserActivity{},
+	}
+	b := &model.User{
+		UserID:       "auth0|123",
+		Username:     "jdoe",
+		PrimaryEmail: "john@example.com",
+		UserMetadata: &model.UserMetadata{City: &otherCity},
+	}
+
+	assert.Equal(t, []string{"primary_email", "user_metadata.city"}, divergentFields(a, b))
+	assert.Empty(t, divergentFields(a, a))
+
+	b.UserMetadata = nil
+	assert.Equal(t, []string{"primary_email", "user_metadata"}, divergentFields(a, b))
+}
@@ -0,0 +1,199 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/jsonschema"
+)
+
+// specTitle is the title of the API documents
+const specTitle = "LFX v2 Auth Service"
+
+// SpecOperation is a request/reply subject the service answers on
+type SpecOperation struct {
+	Subject     string
+	Description string
+	// Docs is the path of the document describing the subject in the repository
+	Docs string
+}
+
+// HTTPRoute is a route of the HTTP gateway
+type HTTPRoute struct {
+	Method      string
+	Path        string
+	OperationID string
+	Summary     string
+	// ContentType is the media type of the successful response
+	ContentType string
+}
+
+// publishedEvent is a subject the service publishes events on, with the type
+// of their payload
+type publishedEvent struct {
+	subject     string
+	description string
+	payload     any
+}
+
+// publishedEvents are the event subjects of consumers. The cache invalidation
+// subject is left out, as only the replicas of the service use it.
+var publishedEvents = []publishedEvent{
+	{constants.UserProfileUpdatedSubject, "A user's metadata was updated", UserProfileUpdatedEvent{}},
+	{constants.LoginEventsSubject, "A login, MFA or blocked-login event of the identity provider", model.LoginEvent{}},
+	{constants.DormantAccountsReportSubject, "The users who haven't logged in within the threshold", DormantAccountsReport{}},
+	{constants.DuplicateAccountsReportSubject, "The pairs of accounts likely to belong to one person", DuplicateAccountsReport{}},
+	{constants.UserMergedSubject, "An account was merged into another one", UserMergedEvent{}},
+	{constants.AffiliationChangedSubject, "An affiliation of a user was added or ended", AffiliationChangedEvent{}},
+	{constants.ReindexProgressSubject, "The progress of a lookup re-index", ReindexProgressEvent{}},
+	{constants.SecurityEventsSubject, "The requests of a caller matched a detection rule", model.SecurityEvent{}},
+}
+
+// channelID derives the id of the channel of subject in the AsyncAPI
+// document, e.g. lfx.auth-service.user_metadata.read -> user_metadata_read
+func channelID(subject string) string {
+	return strings.NewReplacer(".", "_", "-", "_").Replace(strings.TrimPrefix(subject, "lfx.auth-service."))
+}
+
+// AsyncAPIDocument returns the AsyncAPI 3.0 document of the NATS subjects:
+// the request and reply payloads of operations, from their schemas, and the
+// payloads of the published events, from their Go types. A subject without
+// a schema is documented with the reply envelope.
+func AsyncAPIDocument(version string, operations []SpecOperation) ([]byte, error) {
+	channels := make(map[string]any, len(operations)+len(publishedEvents))
+	documented := make(map[string]any, len(operations)+len(publishedEvents))
+
+	envelope := jsonschema.Reflect(UserDataResponse{})
+	for _, operation := range operations {
+		id := channelID(operation.Subject)
+		var request, reply any = map[string]any{}, envelope
+		if schema, ok := subjectSchemas[operation.Subject]; ok {
+			request, reply = schema.Request, schema.Response
+		}
+		channels[id] = map[string]any{
+			"address":     operation.Subject,
+			"description": operation.Description,
+			"messages": map[string]any{
+				"request": map[string]any{"payload": request},
+				"reply":   map[string]any{"payload": reply},
+			},
+		}
+		entry := map[string]any{
+			"action":   "receive",
+			"channel":  map[string]any{"$ref": "#/channels/" + id},
+			"summary":  operation.Description,
+			"messages": []any{map[string]any{"$ref": "#/channels/" + id + "/messages/request"}},
+			"reply": map[string]any{
+				"address": map[string]any{
+					"location":    "$message.header#/replyTo",
+					"description": "the reply inbox of the request",
+				},
+				"messages": []any{map[string]any{"$ref": "#/channels/" + id + "/messages/reply"}},
+			},
+		}
+		if operation.Docs != "" {
+			entry["externalDocs"] = map[string]any{"url": operation.Docs}
+		}
+		documented[id] = entry
+	}
+
+	for _, event := range publishedEvents {
+		id := channelID(event.subject)
+		channels[id] = map[string]any{
+			"address":     event.subject,
+			"description": event.description,
+			"messages": map[string]any{
+				"event": map[string]any{"payload": jsonschema.Reflect(event.payload)},
+			},
+		}
+		documented[id] = map[string]any{
+			"action":   "send",
+			"channel":  map[string]any{"$ref": "#/channels/" + id},
+			"summary":  event.description,
+			"messages": []any{map[string]any{"$ref": "#/channels/" + id + "/messages/event"}},
+		}
+	}
+
+	return json.Marshal(map[string]any{
+		"asyncapi":           "3.0.0",
+		"info":               map[string]any{"title": specTitle, "version": version},
+		"defaultContentType": "application/json",
+		"channels":           channels,
+		"operations":         documented,
+	})
+}
+
+// OpenAPIDocument returns the OpenAPI 3.0 document of the routes of the HTTP
+// gateway
+func OpenAPIDocument(version string, routes []HTTPRoute) ([]byte, error) {
+	paths := make(map[string]map[string]any, len(routes))
+	for _, route := range routes {
+		contentType := route.ContentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		operation := map[string]any{
+			"operationId": route.OperationID,
+			"responses": map[string]any{
+				"200": map[string]any{
+					"description": "OK",
+					"content":     map[string]any{contentType: map[string]any{}},
+				},
+			},
+		}
+		if route.Summary != "" {
+			operation["summary"] = route.Summary
+		}
+		if paths[route.Path] == nil {
+			paths[route.Path] = map[string]any{}
+		}
+		paths[route.Path][strings.ToLower(route.Method)] = operation
+	}
+
+	return json.Marshal(map[string]any{
+		"openapi": "3.0.3",
+		"info":    map[string]any{"title": specTitle, "version": version},
+		"paths":   paths,
+	})
+}
+
+// SpecHandler returns the HTTP handler serving the AsyncAPI and OpenAPI
+// documents, generated once: both under their names, or only one of them
+// with ?format=asyncapi or ?format=openapi.
+func SpecHandler(version string, operations []SpecOperation, routes []HTTPRoute) (http.Handler, error) {
+	asyncAPI, err := AsyncAPIDocument(version, operations)
+	if err != nil {
+		return nil, err
+	}
+	openAPI, err := OpenAPIDocument(version, routes)
+	if err != nil {
+		return nil, err
+	}
+	both, err := json.Marshal(map[string]json.RawMessage{"asyncapi": asyncAPI, "openapi": openAPI})
+	if err != nil {
+		return nil, err
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var document []byte
+		switch r.URL.Query().Get("format") {
+		case "":
+			document = both
+		case "asyncapi":
+			document = asyncAPI
+		case "openapi":
+			document = openAPI
+		default:
+			http.Error(w, "format must be asyncapi or openapi", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(document)
+	}), nil
+}
@@ -0,0 +1,88 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+)
+
+func TestAsyncAPIDocument(t *testing.T) {
+	data, err := AsyncAPIDocument("1.2.3", []SpecOperation{
+		{Subject: constants.UserMetadataReadSubject, Description: "Read user metadata", Docs: "docs/subjects/user_metadata.md"},
+		{Subject: "lfx.auth-service.undocumented", Description: "No schema"},
+	})
+	require.NoError(t, err)
+
+	var document struct {
+		AsyncAPI string `json:"asyncapi"`
+		Info     struct {
+			Version string `json:"version"`
+		} `json:"info"`
+		Channels map[string]struct {
+			Address  string `json:"address"`
+			Messages map[string]struct {
+				Payload json.RawMessage `json:"payload"`
+			} `json:"messages"`
+		} `json:"channels"`
+		Operations map[string]struct {
+			Action string `json:"action"`
+		} `json:"operations"`
+	}
+	require.NoError(t, json.Unmarshal(data, &document))
+	assert.Equal(t, "3.0.0", document.AsyncAPI)
+	assert.Equal(t, "1.2.3", document.Info.Version)
+
+	read := document.Channels["user_metadata_read"]
+	assert.Equal(t, constants.UserMetadataReadSubject, read.Address)
+	assert.JSONEq(t, string(subjectSchemas[constants.UserMetadataReadSubject].Request), string(read.Messages["request"].Payload))
+	assert.JSONEq(t, string(subjectSchemas[constants.UserMetadataReadSubject].Response), string(read.Messages["reply"].Payload))
+	assert.Equal(t, "receive", document.Operations["user_metadata_read"].Action)
+
+	assert.Contains(t, string(document.Channels["undocumented"].Messages["reply"].Payload), `"success"`,
+		"a subject without a schema is documented with the reply envelope")
+
+	security := document.Channels["events_security"]
+	assert.Equal(t, constants.SecurityEventsSubject, security.Address)
+	assert.Contains(t, string(security.Messages["event"].Payload), `"blocked_until"`)
+	assert.Equal(t, "send", document.Operations["events_security"].Action)
+}
+
+func TestSpecHandler(t *testing.T) {
+	handler, err := SpecHandler("1.2.3",
+		[]SpecOperation{{Subject: constants.UserMetadataReadSubject, Description: "Read user metadata"}},
+		[]HTTPRoute{{Method: http.MethodGet, Path: "/livez", OperationID: "Livez", ContentType: "text/plain"}},
+	)
+	require.NoError(t, err)
+
+	get := func(url string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, url, nil))
+		return recorder
+	}
+
+	var both map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(get("/spec").Body.Bytes(), &both))
+	assert.Contains(t, both, "asyncapi")
+	assert.Contains(t, both, "openapi")
+
+	var openAPI struct {
+		OpenAPI string                                `json:"openapi"`
+		Paths   map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	recorder := get("/spec?format=openapi")
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &openAPI))
+	assert.Equal(t, "3.0.3", openAPI.OpenAPI)
+	assert.Contains(t, openAPI.Paths["/livez"], "get")
+
+	assert.Equal(t, http.StatusBadRequest, get("/spec?format=yaml").Code)
+}
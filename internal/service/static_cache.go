@@ -0,0 +1,61 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/redaction"
+)
+
+// staticCacheFlushRequest is the input of admin.static_cache.flush; no
+// kinds flushes every kind
+type staticCacheFlushRequest struct {
+	AuthToken string   `json:"auth_token"`
+	Kinds     []string `json:"kinds,omitempty"`
+}
+
+// WithStaticResourceCacheForMessageHandler sets the provider cache of static
+// resources admin.static_cache.flush empties
+func WithStaticResourceCacheForMessageHandler(cache port.StaticResourceCache) MessageHandlerOrchestratorOption {
+	return func(m *messageHandlerOrchestrator) {
+		m.staticResources = cache
+	}
+}
+
+// AdminStaticCacheFlush empties the static resource cache of the provider on
+// the replica handling the request; every replica gets the request
+func (m *messageHandlerOrchestrator) AdminStaticCacheFlush(ctx context.Context, msg port.TransportMessenger) ([]byte, error) {
+	if m.staticResources == nil {
+		return m.errorResponse("static_cache_unavailable"), nil
+	}
+
+	var request staticCacheFlushRequest
+	if err := json.Unmarshal(msg.Data(), &request); err != nil {
+		return m.errorResponse("failed_to_unmarshal_request"), nil
+	}
+	claims, err := m.authorizeScope(ctx, request.AuthToken, constants.StaticCacheFlushRequiredScope)
+	if err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+
+	flushed, err := m.staticResources.FlushStaticResources(ctx, trimNonEmpty(request.Kinds)...)
+	if err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+	slog.InfoContext(ctx, "static resource cache flushed",
+		"flushed", flushed,
+		"flushed_by", redaction.Redact(claims.Subject),
+	)
+
+	responseJSON, err := json.Marshal(UserDataResponse{Success: true, Data: map[string]any{"flushed": flushed}})
+	if err != nil {
+		return m.errorResponse("failed to marshal response"), nil
+	}
+	return responseJSON, nil
+}
@@ -0,0 +1,78 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/jwt"
+)
+
+type fakeStaticResourceCache struct {
+	kinds [][]string
+}
+
+func (f *fakeStaticResourceCache) FlushStaticResources(_ context.Context, kinds ...string) ([]string, error) {
+	f.kinds = append(f.kinds, kinds)
+	return []string{"roles"}, nil
+}
+
+func TestMessageHandlerOrchestrator_AdminStaticCacheFlush(t *testing.T) {
+	ctx := context.Background()
+	flushToken, err := jwt.GenerateTestAccessToken("auth0|admin", "https://test.any.com/", "https://test.any.com/api/v2/", constants.StaticCacheFlushRequiredScope, time.Hour)
+	require.NoError(t, err)
+	readToken, err := jwt.GenerateTestAccessToken("auth0|reader", "https://test.any.com/", "https://test.any.com/api/v2/", "read:users", time.Hour)
+	require.NoError(t, err)
+
+	reader := &mockUserServiceReader{
+		metadataLookupFunc: func(ctx context.Context, input string) (*model.User, error) {
+			return &model.User{Token: input}, nil
+		},
+	}
+	flush := func(m *messageHandlerOrchestrator, payload map[string]any) UserDataResponse {
+		t.Helper()
+		data, _ := json.Marshal(payload)
+		reply, err := m.AdminStaticCacheFlush(ctx, &mockTransportMessenger{data: data})
+		require.NoError(t, err)
+		var response UserDataResponse
+		require.NoError(t, json.Unmarshal(reply, &response))
+		return response
+	}
+
+	t.Run("flushes the requested kinds", func(t *testing.T) {
+		cache := &fakeStaticResourceCache{}
+		m := NewMessageHandlerOrchestrator(WithUserReaderForMessageHandler(reader), WithStaticResourceCacheForMessageHandler(cache)).(*messageHandlerOrchestrator)
+
+		response := flush(m, map[string]any{"auth_token": flushToken, "kinds": []string{"roles", " "}})
+		require.True(t, response.Success, response.Error)
+		assert.Equal(t, map[string]any{"flushed": []any{"roles"}}, response.Data)
+		assert.Equal(t, [][]string{{"roles"}}, cache.kinds)
+	})
+
+	t.Run("requires the flush scope", func(t *testing.T) {
+		cache := &fakeStaticResourceCache{}
+		m := NewMessageHandlerOrchestrator(WithUserReaderForMessageHandler(reader), WithStaticResourceCacheForMessageHandler(cache)).(*messageHandlerOrchestrator)
+
+		response := flush(m, map[string]any{"auth_token": readToken})
+		assert.False(t, response.Success)
+		assert.Equal(t, "insufficient_scope", response.Error)
+		assert.Empty(t, cache.kinds)
+	})
+
+	t.Run("unavailable without a static cache", func(t *testing.T) {
+		m := NewMessageHandlerOrchestrator(WithUserReaderForMessageHandler(reader)).(*messageHandlerOrchestrator)
+
+		response := flush(m, map[string]any{"auth_token": flushToken})
+		assert.False(t, response.Success)
+		assert.Equal(t, "static_cache_unavailable", response.Error)
+	})
+}
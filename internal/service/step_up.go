@@ -0,0 +1,124 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+)
+
+const (
+	// defaultStepUpMaxAge is how recent the last interactive sign-in must be
+	defaultStepUpMaxAge = 15 * time.Minute
+
+	// stepUpReasonAuthTimeMissing means the token doesn't say when the user signed in
+	stepUpReasonAuthTimeMissing = "auth_time_missing"
+	// stepUpReasonAuthenticationTooOld means the user signed in longer ago than the policy allows
+	stepUpReasonAuthenticationTooOld = "authentication_too_old"
+	// stepUpReasonFactorMissing means the user didn't sign in with an accepted factor
+	stepUpReasonFactorMissing = "factor_missing"
+)
+
+// defaultStepUpFactors are the RFC 8176 methods that count as a second
+// factor: any multi-factor sign-in, a one-time password or a hardware key
+var defaultStepUpFactors = []string{"mfa", "otp", "hwk"}
+
+// stepUpCheckRequest is the input of token.check_step_up
+type stepUpCheckRequest struct {
+	AuthToken string `json:"auth_token"`
+	// MaxAgeSeconds lets a caller demand a more recent sign-in than the
+	// policy for an especially sensitive operation; it can't relax it
+	MaxAgeSeconds int `json:"max_age_seconds"`
+}
+
+// stepUpCheckResult is the reply of token.check_step_up
+type stepUpCheckResult struct {
+	Sub            string     `json:"sub"`
+	StepUpRequired bool       `json:"step_up_required"`
+	Reasons        []string   `json:"reasons"`
+	AuthTime       *time.Time `json:"auth_time,omitempty"`
+	AMR            []string   `json:"amr"`
+	// MaxAgeSeconds and AcceptedFactors are the policy the token was checked against
+	MaxAgeSeconds   int      `json:"max_age_seconds"`
+	AcceptedFactors []string `json:"accepted_factors"`
+}
+
+// WithStepUpPolicyForMessageHandler sets how recent, and with which factors,
+// a sign-in must be for sensitive operations. 0 and empty keep the defaults.
+func WithStepUpPolicyForMessageHandler(maxAge time.Duration, factors []string) MessageHandlerOrchestratorOption {
+	return func(m *messageHandlerOrchestrator) {
+		m.stepUpMaxAge = maxAge
+		m.stepUpFactors = factors
+	}
+}
+
+// CheckStepUp verifies an access token and reports whether its user must
+// authenticate again before a sensitive operation, judged by the auth_time
+// and amr claims. The services guarding those operations share this policy
+// instead of each reading the claims their own way.
+func (m *messageHandlerOrchestrator) CheckStepUp(ctx context.Context, msg port.TransportMessenger) ([]byte, error) {
+	var request stepUpCheckRequest
+	if err := json.Unmarshal(msg.Data(), &request); err != nil {
+		return m.errorResponse("failed_to_unmarshal_request"), nil
+	}
+	if request.MaxAgeSeconds < 0 {
+		return m.errorResponse("max_age_seconds must not be negative"), nil
+	}
+
+	claims, err := m.verifyAccessToken(ctx, request.AuthToken)
+	if err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+
+	maxAge := cmp.Or(m.stepUpMaxAge, defaultStepUpMaxAge)
+	if requested := time.Duration(request.MaxAgeSeconds) * time.Second; requested > 0 && requested < maxAge {
+		maxAge = requested
+	}
+	factors := m.stepUpFactors
+	if len(factors) == 0 {
+		factors = defaultStepUpFactors
+	}
+
+	result := stepUpCheckResult{
+		Sub:             claims.Subject,
+		Reasons:         []string{},
+		AMR:             claims.AuthenticationMethods(),
+		MaxAgeSeconds:   int(maxAge / time.Second),
+		AcceptedFactors: factors,
+	}
+	if result.AMR == nil {
+		result.AMR = []string{}
+	}
+
+	if authTime, ok := claims.AuthTime(); !ok {
+		result.Reasons = append(result.Reasons, stepUpReasonAuthTimeMissing)
+	} else {
+		authTime = authTime.UTC()
+		result.AuthTime = &authTime
+		if time.Since(authTime) > maxAge {
+			result.Reasons = append(result.Reasons, stepUpReasonAuthenticationTooOld)
+		}
+	}
+	if !slices.ContainsFunc(result.AMR, func(method string) bool { return slices.Contains(factors, method) }) {
+		result.Reasons = append(result.Reasons, stepUpReasonFactorMissing)
+	}
+	result.StepUpRequired = len(result.Reasons) > 0
+
+	slog.DebugContext(ctx, "step-up checked",
+		"step_up_required", result.StepUpRequired,
+		"reasons", result.Reasons,
+	)
+
+	responseJSON, err := json.Marshal(UserDataResponse{Success: true, Data: result})
+	if err != nil {
+		return m.errorResponse("failed to marshal response"), nil
+	}
+	return responseJSON, nil
+}
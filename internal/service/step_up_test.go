@@ -0,0 +1,160 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/jwt"
+)
+
+func TestMessageHandlerOrchestrator_CheckStepUp(t *testing.T) {
+	ctx := context.Background()
+
+	tokenWith := func(t *testing.T, claims map[string]any) string {
+		t.Helper()
+		opts := jwt.AccessTokenOptions("auth0|jdoe", nil)
+		opts.CustomClaims = claims
+		opts.SigningMethod, opts.SigningKey = jwa.HS256, []byte("secret")
+		token, err := jwt.Generate(opts)
+		if err != nil {
+			t.Fatalf("failed to generate token: %v", err)
+		}
+		return token
+	}
+
+	reader := &mockUserServiceReader{
+		metadataLookupFunc: func(ctx context.Context, input string) (*model.User, error) {
+			return &model.User{Token: input, UserID: "auth0|jdoe"}, nil
+		},
+	}
+
+	type response struct {
+		Success bool              `json:"success"`
+		Error   string            `json:"error"`
+		Data    stepUpCheckResult `json:"data"`
+	}
+	check := func(t *testing.T, request map[string]any, opts ...MessageHandlerOrchestratorOption) response {
+		t.Helper()
+		payload, _ := json.Marshal(request)
+		opts = append(opts, WithUserReaderForMessageHandler(reader))
+		result, err := NewMessageHandlerOrchestrator(opts...).
+			CheckStepUp(ctx, &mockTransportMessenger{data: payload})
+		if err != nil {
+			t.Fatalf("CheckStepUp() unexpected error: %v", err)
+		}
+		var r response
+		if err := json.Unmarshal(result, &r); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		return r
+	}
+
+	recent := time.Now().Add(-5 * time.Minute).Unix()
+	stale := time.Now().Add(-time.Hour).Unix()
+
+	tests := []struct {
+		name         string
+		claims       map[string]any
+		request      map[string]any
+		opts         []MessageHandlerOrchestratorOption
+		wantRequired bool
+		wantReasons  []string
+	}{
+		{
+			name:        "recent mfa sign-in",
+			claims:      map[string]any{"auth_time": recent, "amr": []string{"pwd", "mfa"}},
+			wantReasons: []string{},
+		},
+		{
+			name:         "stale sign-in",
+			claims:       map[string]any{"auth_time": stale, "amr": []string{"mfa"}},
+			wantRequired: true,
+			wantReasons:  []string{stepUpReasonAuthenticationTooOld},
+		},
+		{
+			name:         "password only",
+			claims:       map[string]any{"auth_time": recent, "amr": []string{"pwd"}},
+			wantRequired: true,
+			wantReasons:  []string{stepUpReasonFactorMissing},
+		},
+		{
+			name:         "no claims",
+			wantRequired: true,
+			wantReasons:  []string{stepUpReasonAuthTimeMissing, stepUpReasonFactorMissing},
+		},
+		{
+			name:         "request tightens the max age",
+			claims:       map[string]any{"auth_time": recent, "amr": []string{"mfa"}},
+			request:      map[string]any{"max_age_seconds": 60},
+			wantRequired: true,
+			wantReasons:  []string{stepUpReasonAuthenticationTooOld},
+		},
+		{
+			name:         "request can't relax the max age",
+			claims:       map[string]any{"auth_time": stale, "amr": []string{"mfa"}},
+			request:      map[string]any{"max_age_seconds": 7200},
+			wantRequired: true,
+			wantReasons:  []string{stepUpReasonAuthenticationTooOld},
+		},
+		{
+			name:        "configured policy",
+			claims:      map[string]any{"auth_time": stale, "amr": []string{"fpt"}},
+			opts:        []MessageHandlerOrchestratorOption{WithStepUpPolicyForMessageHandler(2*time.Hour, []string{"fpt"})},
+			wantReasons: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := map[string]any{"auth_token": tokenWith(t, tt.claims)}
+			for k, v := range tt.request {
+				request[k] = v
+			}
+			r := check(t, request, tt.opts...)
+			if !r.Success {
+				t.Fatalf("expected success, got error %q", r.Error)
+			}
+			if r.Data.StepUpRequired != tt.wantRequired {
+				t.Errorf("step_up_required = %v, want %v", r.Data.StepUpRequired, tt.wantRequired)
+			}
+			if !slices.Equal(r.Data.Reasons, tt.wantReasons) {
+				t.Errorf("reasons = %v, want %v", r.Data.Reasons, tt.wantReasons)
+			}
+			if r.Data.Sub != "auth0|jdoe" {
+				t.Errorf("sub = %q, want auth0|jdoe", r.Data.Sub)
+			}
+		})
+	}
+
+	t.Run("reports the policy", func(t *testing.T) {
+		r := check(t, map[string]any{"auth_token": tokenWith(t, nil)})
+		if r.Data.MaxAgeSeconds != int(defaultStepUpMaxAge/time.Second) {
+			t.Errorf("max_age_seconds = %d, want %d", r.Data.MaxAgeSeconds, int(defaultStepUpMaxAge/time.Second))
+		}
+		if !slices.Equal(r.Data.AcceptedFactors, defaultStepUpFactors) {
+			t.Errorf("accepted_factors = %v, want %v", r.Data.AcceptedFactors, defaultStepUpFactors)
+		}
+	})
+
+	t.Run("rejects a negative max age", func(t *testing.T) {
+		r := check(t, map[string]any{"auth_token": tokenWith(t, nil), "max_age_seconds": -1})
+		if r.Success {
+			t.Error("expected failure for a negative max_age_seconds")
+		}
+	})
+
+	t.Run("rejects tokens that aren't JWTs", func(t *testing.T) {
+		r := check(t, map[string]any{"auth_token": "opaque"})
+		if r.Success {
+			t.Error("expected failure for an opaque token")
+		}
+	})
+}
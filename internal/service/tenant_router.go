@@ -0,0 +1,154 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/jwt"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/tenant"
+)
+
+// Errors replied by the tenant router
+const (
+	errCrossTenantToken = "token was issued for another tenant"
+	errTenantUnresolved = "tenant could not be resolved from the request token; send it to the tenant's subjects"
+)
+
+// tokenMembers are the request members carrying the caller's access token,
+// in the order they are looked up
+var tokenMembers = []string{"auth_token", "token", "input"}
+
+// TenantRouter routes requests by the issuer of their access token in
+// deployments serving several tenants. Each tenant's providers verify tokens
+// against its own JWKS and audience; the router sends a token to the tenant
+// that issued it, and keeps a tenant's subjects from acting on the tokens of
+// another one.
+type TenantRouter struct {
+	mu       sync.RWMutex
+	issuers  map[string]string
+	handlers map[string]func(context.Context, port.TransportMessenger)
+
+	routed metric.Int64Counter
+}
+
+// NewTenantRouter returns a router without tenants
+func NewTenantRouter() *TenantRouter {
+	routed, _ := meter.Int64Counter("auth_service.tenant.routed_requests",
+		metric.WithDescription("Requests routed by the issuer of their token, by tenant and outcome"))
+	return &TenantRouter{
+		issuers:  map[string]string{},
+		handlers: map[string]func(context.Context, port.TransportMessenger){},
+		routed:   routed,
+	}
+}
+
+// Register adds tenant name, whose tokens are issued by issuer and whose
+// requests are handled by handler. An empty issuer registers a tenant that
+// doesn't verify JWTs, e.g. one backed by opaque tokens; its requests can
+// only reach it on its own subjects.
+func (r *TenantRouter) Register(name, issuer string, handler func(context.Context, port.TransportMessenger)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if issuer != "" {
+		if other, ok := r.issuers[issuer]; ok && other != name {
+			slog.Warn("tenants share a token issuer, routing it to the first one",
+				"issuer", issuer, "tenant", name, "routed_to", other)
+		} else {
+			r.issuers[issuer] = name
+		}
+	}
+	r.handlers[name] = handler
+}
+
+// tenantOf returns the tenant that issued the token of the request, if it
+// carries a JWT from a registered issuer
+func (r *TenantRouter) tenantOf(data []byte) (string, func(context.Context, port.TransportMessenger), bool) {
+	token, ok := requestToken(data)
+	if !ok {
+		return "", nil, false
+	}
+	peeked, ok := jwt.Peek(token)
+	if !ok || peeked.Issuer == "" {
+		return "", nil, false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	name, ok := r.issuers[peeked.Issuer]
+	if !ok {
+		return "", nil, false
+	}
+	return name, r.handlers[name], true
+}
+
+// Route handles a request sent to the subjects shared by every tenant,
+// passing it to the tenant that issued its token. Requests without a token of
+// a registered issuer are rejected, as they can't be attributed to a tenant.
+func (r *TenantRouter) Route(ctx context.Context, msg port.TransportMessenger) {
+	name, handler, ok := r.tenantOf(msg.Data())
+	if !ok {
+		r.record(ctx, "", "unresolved")
+		respondError(ctx, msg, errTenantUnresolved)
+		return
+	}
+	ctx = tenant.WithContext(ctx, name)
+	r.record(ctx, name, "routed")
+	handler(ctx, msg)
+}
+
+// Guard wraps the handler of tenant name so requests carrying a token issued
+// for another registered tenant are rejected before they reach its
+// providers. Tokens of unknown issuers are left to the tenant's own
+// verification.
+func (r *TenantRouter) Guard(name string, handler func(context.Context, port.TransportMessenger)) func(context.Context, port.TransportMessenger) {
+	return func(ctx context.Context, msg port.TransportMessenger) {
+		if issuedFor, _, ok := r.tenantOf(msg.Data()); ok && issuedFor != name {
+			slog.WarnContext(ctx, "rejected a token issued for another tenant",
+				"tenant", name, "token_tenant", issuedFor)
+			r.record(ctx, name, "cross_tenant")
+			respondError(ctx, msg, errCrossTenantToken)
+			return
+		}
+		handler(ctx, msg)
+	}
+}
+
+func (r *TenantRouter) record(ctx context.Context, name, outcome string) {
+	r.routed.Add(context.WithoutCancel(ctx), 1, metric.WithAttributes(
+		attribute.String("tenant", name),
+		attribute.String("outcome", outcome),
+	))
+}
+
+// requestToken returns the access token of a request: the auth_token, token
+// or input member of a JSON payload, or a plain text payload that looks like
+// a JWT
+func requestToken(data []byte) (string, bool) {
+	trimmed := strings.TrimSpace(string(data))
+	if strings.HasPrefix(trimmed, "{") {
+		var members map[string]json.RawMessage
+		if err := json.Unmarshal(data, &members); err != nil {
+			return "", false
+		}
+		for _, member := range tokenMembers {
+			var value string
+			if raw, ok := members[member]; !ok || json.Unmarshal(raw, &value) != nil {
+				continue
+			}
+			if token, ok := jwt.LooksLikeJWT(value); ok {
+				return token, true
+			}
+		}
+		return "", false
+	}
+	return jwt.LooksLikeJWT(trimmed)
+}
@@ -0,0 +1,122 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/tenant"
+)
+
+// repliedMessenger records the reply sent to a request
+type repliedMessenger struct {
+	data    []byte
+	replied []byte
+}
+
+func (m *repliedMessenger) Subject() string        { return "lfx.auth-service.token.check_scope" }
+func (m *repliedMessenger) Data() []byte           { return m.data }
+func (m *repliedMessenger) Respond(b []byte) error { m.replied = b; return nil }
+
+// unsignedToken builds a compact JWT issued by issuer; the router only peeks
+// at it, verification is left to the tenant
+func unsignedToken(issuer string) string {
+	segment := func(v any) string {
+		b, _ := json.Marshal(v)
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+	return segment(map[string]string{"alg": "RS256", "typ": "JWT"}) + "." +
+		segment(map[string]string{"iss": issuer, "sub": "auth0|123"}) + ".c2lnbmF0dXJl"
+}
+
+// handledBy returns a handler recording the tenant of the requests it gets
+func handledBy(got *string) func(context.Context, port.TransportMessenger) {
+	return func(ctx context.Context, msg port.TransportMessenger) {
+		*got = tenant.FromContext(ctx)
+		if *got == "" {
+			*got = "untagged"
+		}
+	}
+}
+
+func TestTenantRouter(t *testing.T) {
+	var handled string
+	router := NewTenantRouter()
+	router.Register("lf", "https://lf.auth0.com/", handledBy(&handled))
+	router.Register("openmainframe", "https://omp.auth0.com/", handledBy(&handled))
+	router.Register("internal", "", handledBy(&handled))
+
+	replyError := func(t *testing.T, msg *repliedMessenger) string {
+		t.Helper()
+		var response UserDataResponse
+		require.NoError(t, json.Unmarshal(msg.replied, &response))
+		assert.False(t, response.Success)
+		return response.Error
+	}
+
+	t.Run("routes a token to the tenant of its issuer", func(t *testing.T) {
+		tests := []struct {
+			name string
+			data string
+		}{
+			{"auth_token member", `{"auth_token": "` + unsignedToken("https://omp.auth0.com/") + `", "scopes": ["read"]}`},
+			{"token member", `{"token": "Bearer ` + unsignedToken("https://omp.auth0.com/") + `"}`},
+			{"input member", `{"input": "` + unsignedToken("https://omp.auth0.com/") + `"}`},
+			{"plain text", unsignedToken("https://omp.auth0.com/")},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				handled = ""
+				msg := &repliedMessenger{data: []byte(tt.data)}
+				router.Route(context.Background(), msg)
+				assert.Equal(t, "openmainframe", handled)
+				assert.Nil(t, msg.replied)
+			})
+		}
+	})
+
+	t.Run("rejects requests it can't attribute", func(t *testing.T) {
+		for _, data := range []string{
+			"jdoe",
+			`{"email": "jdoe@example.com"}`,
+			unsignedToken("https://unknown.example.com/"),
+			"not{json",
+		} {
+			handled = ""
+			msg := &repliedMessenger{data: []byte(data)}
+			router.Route(context.Background(), msg)
+			assert.Empty(t, handled, data)
+			assert.Equal(t, errTenantUnresolved, replyError(t, msg), data)
+		}
+	})
+
+	t.Run("guard rejects tokens of another tenant", func(t *testing.T) {
+		handled = ""
+		msg := &repliedMessenger{data: []byte(unsignedToken("https://lf.auth0.com/"))}
+		router.Guard("openmainframe", handledBy(&handled))(context.Background(), msg)
+		assert.Empty(t, handled)
+		assert.Equal(t, errCrossTenantToken, replyError(t, msg))
+	})
+
+	t.Run("guard passes the tenant's own and unattributed requests", func(t *testing.T) {
+		for _, data := range []string{
+			unsignedToken("https://omp.auth0.com/"),
+			unsignedToken("https://unknown.example.com/"),
+			"jdoe",
+		} {
+			handled = ""
+			msg := &repliedMessenger{data: []byte(data)}
+			router.Guard("openmainframe", handledBy(&handled))(context.Background(), msg)
+			assert.Equal(t, "untagged", handled, data)
+			assert.Nil(t, msg.replied, data)
+		}
+	})
+}
@@ -0,0 +1,148 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/jwt"
+)
+
+// tokenCheckMaxEntries bounds the scopes and permissions evaluated per request
+const tokenCheckMaxEntries = 50
+
+// tokenCheckScopeRequest is the input of token.check_scope
+type tokenCheckScopeRequest struct {
+	AuthToken   string   `json:"auth_token"`
+	Scopes      []string `json:"scopes"`
+	Permissions []string `json:"permissions"`
+}
+
+// scopeGrant reports whether the token carries one requested scope
+type scopeGrant struct {
+	Scope   string `json:"scope"`
+	Granted bool   `json:"granted"`
+}
+
+// permissionGrant reports whether the token carries one requested permission
+type permissionGrant struct {
+	Permission string `json:"permission"`
+	Granted    bool   `json:"granted"`
+}
+
+// tokenCheckScopeResult is the reply of token.check_scope
+type tokenCheckScopeResult struct {
+	Sub         string            `json:"sub"`
+	Scopes      []scopeGrant      `json:"scopes"`
+	Permissions []permissionGrant `json:"permissions"`
+	// AllGranted is true when every requested scope and permission is granted
+	AllGranted bool `json:"all_granted"`
+}
+
+// CheckTokenScope verifies an access token and reports, for every requested
+// scope and permission, whether the token grants it. A token that fails
+// verification is an error; a verified token missing a scope is not.
+func (m *messageHandlerOrchestrator) CheckTokenScope(ctx context.Context, msg port.TransportMessenger) ([]byte, error) {
+	var request tokenCheckScopeRequest
+	if err := json.Unmarshal(msg.Data(), &request); err != nil {
+		return m.errorResponse("failed_to_unmarshal_request"), nil
+	}
+
+	scopes := trimNonEmpty(request.Scopes)
+	permissions := trimNonEmpty(request.Permissions)
+	if len(scopes) == 0 && len(permissions) == 0 {
+		return m.errorResponse("scopes or permissions are required"), nil
+	}
+	if len(scopes)+len(permissions) > tokenCheckMaxEntries {
+		return m.errorResponse(fmt.Sprintf("too many scopes and permissions: at most %d per request", tokenCheckMaxEntries)), nil
+	}
+
+	claims, err := m.verifyAccessToken(ctx, request.AuthToken)
+	if err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+
+	result := tokenCheckScopeResult{
+		Sub:         claims.Subject,
+		Scopes:      make([]scopeGrant, 0, len(scopes)),
+		Permissions: make([]permissionGrant, 0, len(permissions)),
+		AllGranted:  true,
+	}
+	for _, scope := range scopes {
+		granted := claims.HasScope(scope)
+		result.Scopes = append(result.Scopes, scopeGrant{Scope: scope, Granted: granted})
+		result.AllGranted = result.AllGranted && granted
+	}
+	for _, permission := range permissions {
+		granted := claims.HasPermission(permission)
+		result.Permissions = append(result.Permissions, permissionGrant{Permission: permission, Granted: granted})
+		result.AllGranted = result.AllGranted && granted
+	}
+
+	slog.DebugContext(ctx, "token scopes checked",
+		"scopes", len(scopes),
+		"permissions", len(permissions),
+		"all_granted", result.AllGranted,
+	)
+
+	responseJSON, err := json.Marshal(UserDataResponse{Success: true, Data: result})
+	if err != nil {
+		return m.errorResponse("failed to marshal response"), nil
+	}
+	return responseJSON, nil
+}
+
+// verifyAccessToken verifies that authToken is an access token the provider
+// accepts and returns its claims. requiredScopes are passed on to the
+// provider, which rejects tokens missing any of them.
+func (m *messageHandlerOrchestrator) verifyAccessToken(ctx context.Context, authToken string, requiredScopes ...string) (*jwt.Claims, error) {
+	token, isJWT := jwt.LooksLikeJWT(strings.TrimSpace(authToken))
+	if !isJWT {
+		return nil, errs.NewUnauthorized("auth_token must be an access token")
+	}
+
+	// MetadataLookup verifies the signature, expiry, issuer and audience. A
+	// provider that can't verify the token doesn't return it on the user.
+	user, err := m.userReader.MetadataLookup(ctx, token, requiredScopes...)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || user.Token == "" {
+		return nil, errs.NewUnauthorized("auth_token could not be verified")
+	}
+
+	claims, err := jwt.ParseUnverified(ctx, token, &jwt.ParseOptions{AllowBearerPrefix: true})
+	if err != nil {
+		return nil, errs.NewUnauthorized("auth_token could not be parsed")
+	}
+	// providers other than Auth0 don't consult the revocation list themselves
+	if m.tokenRevocations != nil && m.tokenRevocations.IsRevoked(claims.ID, claims.Subject, claims.IssuedAt) {
+		return nil, errs.NewUnauthorized("token has been revoked")
+	}
+	return claims, nil
+}
+
+// trimNonEmpty trims values and drops the empty and repeated ones, keeping order
+func trimNonEmpty(values []string) []string {
+	seen := make(map[string]struct{}, len(values))
+	trimmed := make([]string, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if _, ok := seen[value]; ok {
+			continue
+		}
+		seen[value] = struct{}{}
+		trimmed = append(trimmed, value)
+	}
+	return trimmed
+}
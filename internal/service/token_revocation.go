@@ -0,0 +1,143 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/jwt"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/redaction"
+)
+
+// defaultTokenRevocationTTL outlives the access tokens the identity providers
+// issue, which last 24 hours at most
+const defaultTokenRevocationTTL = 24 * time.Hour
+
+// tokenRevokeRequest is the input of admin.revoke_token. Exactly one of
+// Token, JTI and Sub selects what is revoked.
+type tokenRevokeRequest struct {
+	AuthToken string `json:"auth_token"`
+	// Token is the access token to revoke
+	Token string `json:"token"`
+	// JTI is the id of the access token to revoke
+	JTI string `json:"jti"`
+	// Sub revokes every access token of the user issued until now
+	Sub    string `json:"sub"`
+	Reason string `json:"reason"`
+}
+
+// WithTokenRevocationListForMessageHandler sets the token revocation list for
+// the message handler orchestrator. ttl is how long a revocation lasts when
+// the expiry of the revoked tokens is unknown; 0 keeps the default of a day.
+func WithTokenRevocationListForMessageHandler(list port.TokenRevocationList, ttl time.Duration) MessageHandlerOrchestratorOption {
+	return func(m *messageHandlerOrchestrator) {
+		m.tokenRevocations = list
+		m.tokenRevocationTTL = ttl
+	}
+}
+
+// RevokeToken revokes an access token, or every access token of a user,
+// before it expires. Every replica rejects revoked tokens once the
+// revocation reaches it through the revocation bucket.
+func (m *messageHandlerOrchestrator) RevokeToken(ctx context.Context, msg port.TransportMessenger) ([]byte, error) {
+	if m.tokenRevocations == nil {
+		return m.errorResponse("token_revocation_unavailable"), nil
+	}
+
+	var request tokenRevokeRequest
+	if err := json.Unmarshal(msg.Data(), &request); err != nil {
+		return m.errorResponse("failed_to_unmarshal_request"), nil
+	}
+
+	claims, err := m.authorizeScope(ctx, request.AuthToken, constants.TokenRevokeRequiredScope)
+	if err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+
+	now := time.Now().UTC()
+	revocation, err := m.newTokenRevocation(ctx, request, now)
+	if err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+	revocation.RevokedBy = claims.Subject
+
+	if err := m.tokenRevocations.Revoke(ctx, revocation); err != nil {
+		slog.ErrorContext(ctx, "failed to revoke token", "error", err)
+		return m.errorResponse(err.Error()), nil
+	}
+
+	slog.InfoContext(ctx, "access token revoked",
+		"jti", revocation.JTI,
+		"sub", redaction.Redact(revocation.Subject),
+		"all_tokens", revocation.JTI == "",
+		"revoked_by", redaction.Redact(claims.Subject),
+		"reason", revocation.Reason,
+	)
+
+	responseJSON, err := json.Marshal(UserDataResponse{Success: true, Data: revocation})
+	if err != nil {
+		return m.errorResponse("failed to marshal response"), nil
+	}
+	return responseJSON, nil
+}
+
+// newTokenRevocation turns a revoke request into the revocation to store
+func (m *messageHandlerOrchestrator) newTokenRevocation(ctx context.Context, request tokenRevokeRequest, now time.Time) (*model.TokenRevocation, error) {
+	token := strings.TrimSpace(request.Token)
+	jti := strings.TrimSpace(request.JTI)
+	sub := strings.TrimSpace(request.Sub)
+
+	selectors := 0
+	for _, value := range []string{token, jti, sub} {
+		if value != "" {
+			selectors++
+		}
+	}
+	if selectors != 1 {
+		return nil, errs.NewValidation("exactly one of token, jti or sub is required")
+	}
+
+	revocation := &model.TokenRevocation{
+		JTI:       jti,
+		Subject:   sub,
+		Reason:    strings.TrimSpace(request.Reason),
+		RevokedAt: now,
+		ExpiresAt: now.Add(cmp.Or(m.tokenRevocationTTL, defaultTokenRevocationTTL)),
+	}
+	if token == "" {
+		return revocation, nil
+	}
+
+	// the token is revoked because it can't be trusted, so its signature
+	// isn't checked: only its jti is recorded
+	raw, isJWT := jwt.LooksLikeJWT(token)
+	if !isJWT {
+		return nil, errs.NewValidation("token must be a JWT")
+	}
+	claims, err := jwt.ParseUnverified(ctx, raw, &jwt.ParseOptions{AllowBearerPrefix: true})
+	if err != nil {
+		return nil, errs.NewValidation("token could not be parsed")
+	}
+	if claims.ID == "" {
+		return nil, errs.NewValidation("token has no jti; revoke every token of its sub instead")
+	}
+	if claims.ExpiresAt != nil {
+		if !now.Before(*claims.ExpiresAt) {
+			return nil, errs.NewValidation("token has already expired")
+		}
+		revocation.ExpiresAt = claims.ExpiresAt.UTC()
+	}
+	revocation.JTI = claims.ID
+	revocation.Subject = claims.Subject
+	return revocation, nil
+}
@@ -0,0 +1,147 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/jwt"
+)
+
+// mockTokenRevocationList keeps revocations in memory
+type mockTokenRevocationList struct {
+	revocations []*model.TokenRevocation
+}
+
+func (l *mockTokenRevocationList) IsRevoked(jti, sub string, issuedAt *time.Time) bool {
+	for _, revocation := range l.revocations {
+		if revocation.Covers(jti, sub, issuedAt) {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *mockTokenRevocationList) Revoke(ctx context.Context, revocation *model.TokenRevocation) error {
+	l.revocations = append(l.revocations, revocation)
+	return nil
+}
+
+func TestMessageHandlerOrchestrator_RevokeToken(t *testing.T) {
+	ctx := context.Background()
+
+	adminToken, err := jwt.GenerateTestAccessToken("auth0|admin", "https://issuer/", "aud", constants.TokenRevokeRequiredScope, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	withoutScope, err := jwt.GenerateSimpleTestAccessToken("auth0|admin", time.Hour)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	compromised := func(t *testing.T, jti string) string {
+		t.Helper()
+		opts := jwt.AccessTokenOptions("auth0|alice", nil)
+		opts.SigningMethod = "HS256"
+		opts.SigningKey = []byte("test-secret")
+		if jti != "" {
+			opts.CustomClaims = map[string]any{"jti": jti}
+		}
+		token, err := jwt.Generate(opts)
+		if err != nil {
+			t.Fatalf("failed to generate token: %v", err)
+		}
+		return token
+	}
+
+	reader := &mockUserServiceReader{
+		metadataLookupFunc: func(ctx context.Context, input string) (*model.User, error) {
+			return &model.User{Token: input}, nil
+		},
+	}
+
+	call := func(t *testing.T, list *mockTokenRevocationList, request map[string]any) (bool, string, *model.TokenRevocation) {
+		t.Helper()
+		m := NewMessageHandlerOrchestrator(
+			WithUserReaderForMessageHandler(reader),
+			WithTokenRevocationListForMessageHandler(list, 0),
+		)
+		payload, _ := json.Marshal(request)
+		result, err := m.RevokeToken(ctx, &mockTransportMessenger{data: payload})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var response struct {
+			Success bool                   `json:"success"`
+			Error   string                 `json:"error"`
+			Data    *model.TokenRevocation `json:"data"`
+		}
+		if err := json.Unmarshal(result, &response); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		return response.Success, response.Error, response.Data
+	}
+
+	t.Run("revokes a token by its jti", func(t *testing.T) {
+		list := &mockTokenRevocationList{}
+		ok, errMsg, revocation := call(t, list, map[string]any{"auth_token": adminToken, "token": compromised(t, "jti-1"), "reason": "leaked in CI logs"})
+		if !ok {
+			t.Fatalf("expected success, got error %q", errMsg)
+		}
+		if revocation.JTI != "jti-1" || revocation.Subject != "auth0|alice" || revocation.RevokedBy != "auth0|admin" {
+			t.Errorf("revocation = %+v, want jti-1 of auth0|alice revoked by auth0|admin", revocation)
+		}
+		if until := time.Until(revocation.ExpiresAt); until > time.Hour || until <= 0 {
+			t.Errorf("revocation expires in %v, want the token expiry", until)
+		}
+	})
+
+	t.Run("revokes every token of a user", func(t *testing.T) {
+		list := &mockTokenRevocationList{}
+		ok, errMsg, revocation := call(t, list, map[string]any{"auth_token": adminToken, "sub": "auth0|alice"})
+		if !ok {
+			t.Fatalf("expected success, got error %q", errMsg)
+		}
+		if revocation.JTI != "" || time.Until(revocation.ExpiresAt) < 23*time.Hour {
+			t.Errorf("revocation = %+v, want a sub revocation lasting the default TTL", revocation)
+		}
+	})
+
+	errorCases := []struct {
+		name    string
+		request map[string]any
+		want    string
+	}{
+		{name: "missing scope", request: map[string]any{"auth_token": withoutScope, "sub": "auth0|alice"}, want: "insufficient_scope"},
+		{name: "no selector", request: map[string]any{"auth_token": adminToken}, want: "exactly one of token, jti or sub is required"},
+		{name: "two selectors", request: map[string]any{"auth_token": adminToken, "jti": "jti-1", "sub": "auth0|alice"}, want: "exactly one of token, jti or sub is required"},
+		{name: "token without jti", request: map[string]any{"auth_token": adminToken, "token": compromised(t, "")}, want: "token has no jti"},
+		{name: "not a token", request: map[string]any{"auth_token": adminToken, "token": "opaque"}, want: "token must be a JWT"},
+	}
+	for _, tt := range errorCases {
+		t.Run(tt.name, func(t *testing.T) {
+			list := &mockTokenRevocationList{}
+			ok, errMsg, _ := call(t, list, tt.request)
+			if ok || !strings.Contains(errMsg, tt.want) {
+				t.Errorf("success = %v, error = %q; want error containing %q", ok, errMsg, tt.want)
+			}
+			if len(list.revocations) != 0 {
+				t.Error("rejected request must not revoke")
+			}
+		})
+	}
+
+	t.Run("revoked tokens are rejected by verification", func(t *testing.T) {
+		list := &mockTokenRevocationList{revocations: []*model.TokenRevocation{{Subject: "auth0|admin", RevokedAt: time.Now().Add(time.Minute)}}}
+		ok, errMsg, _ := call(t, list, map[string]any{"auth_token": adminToken, "sub": "auth0|alice"})
+		if ok || errMsg != "token has been revoked" {
+			t.Errorf("success = %v, error = %q; want token has been revoked", ok, errMsg)
+		}
+	})
+}
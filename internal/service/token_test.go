@@ -0,0 +1,165 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/jwt"
+)
+
+func TestMessageHandlerOrchestrator_CheckTokenScope(t *testing.T) {
+	ctx := context.Background()
+
+	// ParseUnverified doesn't check the signature, so an HMAC key will do
+	opts := jwt.AccessTokenOptions("auth0|jdoe", nil)
+	opts.Scope = "openid read:projects"
+	opts.CustomClaims = map[string]any{"permissions": []string{"write:projects"}}
+	opts.SigningMethod, opts.SigningKey = jwa.HS256, []byte("secret")
+	token, err := jwt.Generate(opts)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+
+	reader := &mockUserServiceReader{
+		metadataLookupFunc: func(ctx context.Context, input string) (*model.User, error) {
+			return &model.User{Token: input, UserID: "auth0|jdoe"}, nil
+		},
+	}
+	rejecting := &mockUserServiceReader{
+		metadataLookupFunc: func(ctx context.Context, input string) (*model.User, error) {
+			return nil, errors.NewUnauthorized("token has expired")
+		},
+	}
+	unverified := &mockUserServiceReader{
+		metadataLookupFunc: func(ctx context.Context, input string) (*model.User, error) {
+			return &model.User{Username: input}, nil
+		},
+	}
+
+	type response struct {
+		Success bool                  `json:"success"`
+		Error   string                `json:"error"`
+		Data    tokenCheckScopeResult `json:"data"`
+	}
+	check := func(t *testing.T, reader *mockUserServiceReader, request map[string]any) response {
+		t.Helper()
+		payload, _ := json.Marshal(request)
+		result, err := NewMessageHandlerOrchestrator(WithUserReaderForMessageHandler(reader)).
+			CheckTokenScope(ctx, &mockTransportMessenger{data: payload})
+		if err != nil {
+			t.Fatalf("CheckTokenScope() unexpected error: %v", err)
+		}
+		var r response
+		if err := json.Unmarshal(result, &r); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		return r
+	}
+
+	t.Run("reports each scope and permission", func(t *testing.T) {
+		r := check(t, reader, map[string]any{
+			"auth_token":  "Bearer " + token,
+			"scopes":      []string{"read:projects", " admin ", "read:projects", ""},
+			"permissions": []string{"write:projects", "read:projects"},
+		})
+		if !r.Success {
+			t.Fatalf("expected success, got error %q", r.Error)
+		}
+		if r.Data.Sub != "auth0|jdoe" {
+			t.Errorf("sub = %q, want auth0|jdoe", r.Data.Sub)
+		}
+		wantScopes := []scopeGrant{{Scope: "read:projects", Granted: true}, {Scope: "admin", Granted: false}}
+		if len(r.Data.Scopes) != len(wantScopes) {
+			t.Fatalf("scopes = %+v, want %+v", r.Data.Scopes, wantScopes)
+		}
+		for i, want := range wantScopes {
+			if r.Data.Scopes[i] != want {
+				t.Errorf("scopes[%d] = %+v, want %+v", i, r.Data.Scopes[i], want)
+			}
+		}
+		wantPermissions := []permissionGrant{{Permission: "write:projects", Granted: true}, {Permission: "read:projects", Granted: false}}
+		if len(r.Data.Permissions) != len(wantPermissions) {
+			t.Fatalf("permissions = %+v, want %+v", r.Data.Permissions, wantPermissions)
+		}
+		for i, want := range wantPermissions {
+			if r.Data.Permissions[i] != want {
+				t.Errorf("permissions[%d] = %+v, want %+v", i, r.Data.Permissions[i], want)
+			}
+		}
+		if r.Data.AllGranted {
+			t.Error("all_granted = true, want false")
+		}
+	})
+
+	t.Run("all granted", func(t *testing.T) {
+		r := check(t, reader, map[string]any{"auth_token": token, "scopes": []string{"openid", "read:projects"}})
+		if !r.Success || !r.Data.AllGranted {
+			t.Errorf("expected all scopes granted, got %+v (error %q)", r.Data, r.Error)
+		}
+	})
+
+	tests := []struct {
+		name    string
+		reader  *mockUserServiceReader
+		request map[string]any
+		want    string
+	}{
+		{
+			name:    "nothing to check",
+			reader:  reader,
+			request: map[string]any{"auth_token": token, "scopes": []string{" "}},
+			want:    "scopes or permissions are required",
+		},
+		{
+			name:    "too many entries",
+			reader:  reader,
+			request: map[string]any{"auth_token": token, "scopes": manyScopes(tokenCheckMaxEntries + 1)},
+			want:    "too many scopes and permissions",
+		},
+		{
+			name:    "not a jwt",
+			reader:  reader,
+			request: map[string]any{"auth_token": "opaque", "scopes": []string{"openid"}},
+			want:    "auth_token must be an access token",
+		},
+		{
+			name:    "rejected by the provider",
+			reader:  rejecting,
+			request: map[string]any{"auth_token": token, "scopes": []string{"openid"}},
+			want:    "token has expired",
+		},
+		{
+			name:    "provider can't verify the token",
+			reader:  unverified,
+			request: map[string]any{"auth_token": token, "scopes": []string{"openid"}},
+			want:    "auth_token could not be verified",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := check(t, tt.reader, tt.request)
+			if r.Success {
+				t.Fatal("expected an error response")
+			}
+			if !strings.Contains(r.Error, tt.want) {
+				t.Errorf("error = %q, want it to contain %q", r.Error, tt.want)
+			}
+		})
+	}
+}
+
+func manyScopes(n int) []string {
+	scopes := make([]string, n)
+	for i := range scopes {
+		scopes[i] = "scope:" + strings.Repeat("x", i+1)
+	}
+	return scopes
+}
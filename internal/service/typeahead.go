@@ -0,0 +1,148 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/normalize"
+)
+
+// typeaheadEntry maps one indexed key to the user it was taken from
+type typeaheadEntry struct {
+	key  string
+	user int
+}
+
+// typeaheadSnapshot is an immutable build of the index. Entries are sorted by
+// key, so the keys sharing a prefix form one contiguous run.
+type typeaheadSnapshot struct {
+	users   []*model.User
+	entries []typeaheadEntry
+	builtAt time.Time
+}
+
+// TypeaheadIndex is an in-memory prefix index over usernames, emails and
+// names, rebuilt in the background from a bulk export of the user repository.
+// Lookups only read the current snapshot and never call the provider.
+type TypeaheadIndex struct {
+	source   port.UserExporter
+	snapshot atomic.Pointer[typeaheadSnapshot]
+	now      func() time.Time
+}
+
+// Run rebuilds the index; it satisfies scheduler.Job
+func (t *TypeaheadIndex) Run(ctx context.Context) error {
+	return t.Refresh(ctx)
+}
+
+// Refresh exports every user and swaps in a new snapshot. The previous
+// snapshot keeps serving until the export succeeds.
+func (t *TypeaheadIndex) Refresh(ctx context.Context) error {
+	started := t.now()
+	users, err := t.source.ExportUsers(ctx)
+	if err != nil {
+		return err
+	}
+
+	snapshot := buildTypeaheadSnapshot(users)
+	snapshot.builtAt = t.now()
+	t.snapshot.Store(snapshot)
+
+	slog.InfoContext(ctx, "typeahead index refreshed",
+		"users", len(snapshot.users),
+		"keys", len(snapshot.entries),
+		"duration", snapshot.builtAt.Sub(started),
+	)
+	return nil
+}
+
+// Ready reports whether a snapshot has been built
+func (t *TypeaheadIndex) Ready() bool {
+	return t.snapshot.Load() != nil
+}
+
+// SearchUserCandidates returns up to limit users with a username, email or
+// name word starting with query. It implements port.UserSearcher.
+func (t *TypeaheadIndex) SearchUserCandidates(_ context.Context, query string, limit int) ([]*model.User, error) {
+	snapshot := t.snapshot.Load()
+	if snapshot == nil {
+		return nil, errs.NewServiceUnavailable("typeahead index is not ready")
+	}
+	query = normalize.Email(query)
+	if query == "" {
+		return nil, errs.NewValidation("query is required")
+	}
+
+	start, _ := slices.BinarySearchFunc(snapshot.entries, query, func(entry typeaheadEntry, query string) int {
+		return strings.Compare(entry.key, query)
+	})
+
+	seen := make(map[int]bool)
+	var candidates []*model.User
+	for _, entry := range snapshot.entries[start:] {
+		if !strings.HasPrefix(entry.key, query) {
+			break
+		}
+		if seen[entry.user] {
+			continue
+		}
+		seen[entry.user] = true
+		candidates = append(candidates, snapshot.users[entry.user])
+		if limit > 0 && len(candidates) == limit {
+			break
+		}
+	}
+	return candidates, nil
+}
+
+// buildTypeaheadSnapshot indexes each user under its normalized username,
+// email and name, and under every word of the email and name
+func buildTypeaheadSnapshot(users []*model.User) *typeaheadSnapshot {
+	snapshot := &typeaheadSnapshot{users: make([]*model.User, 0, len(users))}
+	for _, user := range users {
+		if user == nil || user.UserID == "" {
+			continue
+		}
+		idx := len(snapshot.users)
+		snapshot.users = append(snapshot.users, user)
+
+		keys := []string{normalize.Username(user.Username)}
+		for _, value := range []string{normalize.Email(user.PrimaryEmail), normalize.Email(user.SearchName())} {
+			keys = append(keys, value)
+			keys = append(keys, searchWords(value)...)
+		}
+		slices.Sort(keys)
+		for _, key := range slices.Compact(keys) {
+			if key != "" {
+				snapshot.entries = append(snapshot.entries, typeaheadEntry{key: key, user: idx})
+			}
+		}
+	}
+
+	slices.SortFunc(snapshot.entries, func(a, b typeaheadEntry) int {
+		return cmp.Or(strings.Compare(a.key, b.key), cmp.Compare(a.user, b.user))
+	})
+	return snapshot
+}
+
+// NewTypeaheadIndex creates an empty index fed by source. It serves no
+// results until the first Refresh succeeds.
+func NewTypeaheadIndex(source port.UserExporter) *TypeaheadIndex {
+	return &TypeaheadIndex{
+		source: source,
+		now:    time.Now,
+	}
+}
+
+var _ port.UserSearcher = (*TypeaheadIndex)(nil)
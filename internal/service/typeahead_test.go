@@ -0,0 +1,108 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+)
+
+// mockUserExporter returns a fixed user list, or err
+type mockUserExporter struct {
+	users []*model.User
+	err   error
+	calls int
+}
+
+func (m *mockUserExporter) ExportUsers(ctx context.Context) ([]*model.User, error) {
+	m.calls++
+	return m.users, m.err
+}
+
+func candidateUsernames(users []*model.User) []string {
+	var names []string
+	for _, user := range users {
+		names = append(names, user.Username)
+	}
+	return names
+}
+
+func TestTypeaheadIndex_SearchUserCandidates(t *testing.T) {
+	ctx := context.Background()
+	index := NewTypeaheadIndex(&mockUserExporter{users: searchTestUsers()})
+
+	if _, err := index.SearchUserCandidates(ctx, "jane", 10); err == nil {
+		t.Fatal("SearchUserCandidates() before the first refresh: expected an error")
+	} else if _, ok := err.(errors.ServiceUnavailable); !ok {
+		t.Errorf("SearchUserCandidates() error = %T, want errors.ServiceUnavailable", err)
+	}
+	if index.Ready() {
+		t.Error("Ready() = true before the first refresh")
+	}
+
+	if err := index.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh() unexpected error: %v", err)
+	}
+	if !index.Ready() {
+		t.Error("Ready() = false after a refresh")
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		limit int
+		want  []string
+	}{
+		// candidates come in key order, then export order: jdoe and jane both
+		// hold the key "jane", and mjaneway doesn't match since only word
+		// prefixes are indexed
+		{name: "prefix of usernames, emails and names", query: "JANE", want: []string{"jdoe", "jane", "janet"}},
+		{name: "email word", query: "doe", want: []string{"jdoe"}},
+		{name: "limit", query: "ja", limit: 1, want: []string{"jdoe"}},
+		{name: "no match", query: "zed", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users, err := index.SearchUserCandidates(ctx, tt.query, tt.limit)
+			if err != nil {
+				t.Fatalf("SearchUserCandidates() unexpected error: %v", err)
+			}
+			got := candidateUsernames(users)
+			if len(got) != len(tt.want) {
+				t.Fatalf("SearchUserCandidates(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("SearchUserCandidates(%q) = %v, want %v", tt.query, got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestTypeaheadIndex_RefreshKeepsSnapshotOnError(t *testing.T) {
+	ctx := context.Background()
+	exporter := &mockUserExporter{users: searchTestUsers()}
+	index := NewTypeaheadIndex(exporter)
+	if err := index.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh() unexpected error: %v", err)
+	}
+
+	exporter.err = errors.NewServiceUnavailable("export failed")
+	if err := index.Run(ctx); err == nil {
+		t.Error("Run() expected the export error")
+	}
+
+	users, err := index.SearchUserCandidates(ctx, "bob", 10)
+	if err != nil || len(users) != 1 {
+		t.Errorf("SearchUserCandidates() after a failed refresh = %v, %v; want the previous snapshot", candidateUsernames(users), err)
+	}
+	if exporter.calls != 2 {
+		t.Errorf("ExportUsers() calls = %d, want 2", exporter.calls)
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/redaction"
+)
+
+// Destinations of a users export
+const (
+	userExportDestinationReply       = "reply"
+	userExportDestinationObjectStore = "object_store"
+)
+
+// userExportMaxWait bounds how long an export is waited on when the request
+// carries no deadline
+const userExportMaxWait = 10 * time.Minute
+
+// userExportObjectName restricts the names exports are stored under
+var userExportObjectName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._/-]{0,254}$`)
+
+// userExportRequest is the input of admin.users_export
+type userExportRequest struct {
+	AuthToken string `json:"auth_token"`
+	model.UserExport
+	// Destination is reply (the default) or object_store
+	Destination string `json:"destination"`
+	// Name is the object the export is stored as, by default
+	// users-exports/<job id>.csv (or .jsonl)
+	Name string `json:"name"`
+}
+
+// userExportResult is the reply of admin.users_export: the export itself,
+// or where it was stored
+type userExportResult struct {
+	JobID   string                  `json:"job_id"`
+	Format  string                  `json:"format"`
+	Content string                  `json:"content,omitempty"`
+	Object  *model.UserExportObject `json:"object,omitempty"`
+}
+
+// WithUserReportExporterForMessageHandler sets the bulk user exporter for the message handler orchestrator
+func WithUserReportExporterForMessageHandler(exporter port.UserReportExporter) MessageHandlerOrchestratorOption {
+	return func(m *messageHandlerOrchestrator) {
+		m.userReportExporter = exporter
+	}
+}
+
+// WithUserExportStoreForMessageHandler sets where exports sent to the object_store destination are kept
+func WithUserExportStoreForMessageHandler(store port.UserExportStore) MessageHandlerOrchestratorOption {
+	return func(m *messageHandlerOrchestrator) {
+		m.userExportStore = store
+	}
+}
+
+// validateUserExport checks the request before the job is started, as a
+// mistake is otherwise only reported once the export has run
+func (m *messageHandlerOrchestrator) validateUserExport(request *userExportRequest) error {
+	request.Format = strings.ToLower(strings.TrimSpace(request.Format))
+	switch request.Format {
+	case "":
+		request.Format = model.UserExportFormatCSV
+	case model.UserExportFormatCSV, model.UserExportFormatJSON:
+	default:
+		return errs.NewValidation("format must be csv or json")
+	}
+
+	if len(request.Fields) == 0 {
+		return errs.NewValidation("fields are required")
+	}
+	for i, field := range request.Fields {
+		if strings.TrimSpace(field.Name) == "" {
+			return errs.NewValidation(fmt.Sprintf("fields[%d]: name is required", i))
+		}
+	}
+
+	switch request.Destination {
+	case "", userExportDestinationReply:
+		request.Destination = userExportDestinationReply
+		if request.Name != "" {
+			return errs.NewValidation("name is only used with the object_store destination")
+		}
+	case userExportDestinationObjectStore:
+		if m.userExportStore == nil {
+			return errs.NewValidation("the object_store destination is not enabled")
+		}
+		if request.Name != "" && !userExportObjectName.MatchString(request.Name) {
+			return errs.NewValidation("name may only contain letters, digits, '.', '_', '-' and '/'")
+		}
+	default:
+		return errs.NewValidation("destination must be reply or object_store")
+	}
+	return nil
+}
+
+// ExportUsers exports the attributes asked for of every user, or of the
+// users of a connection, through a job of the identity provider. It waits
+// for the job to finish, then replies the export, chunked when it is large,
+// or stores it for reporting tools to download from the object store.
+func (m *messageHandlerOrchestrator) ExportUsers(ctx context.Context, msg port.TransportMessenger) ([]byte, error) {
+	if m.userReportExporter == nil {
+		return m.errorResponse("user_export_unavailable"), nil
+	}
+
+	var request userExportRequest
+	if err := json.Unmarshal(msg.Data(), &request); err != nil {
+		return m.errorResponse("failed_to_unmarshal_request"), nil
+	}
+
+	claims, err := m.authorizeScope(ctx, request.AuthToken, constants.UserExportRequiredScope)
+	if err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+
+	if err := m.validateUserExport(&request); err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, userExportMaxWait)
+		defer cancel()
+	}
+
+	file, err := m.userReportExporter.ExportUserReport(ctx, &request.UserExport)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to export users", "error", err)
+		return m.errorResponse(err.Error()), nil
+	}
+
+	result := userExportResult{JobID: file.JobID, Format: file.Format}
+	if request.Destination == userExportDestinationObjectStore {
+		name := request.Name
+		if name == "" {
+			extension := file.Format
+			if extension == model.UserExportFormatJSON {
+				extension = "jsonl"
+			}
+			name = "users-exports/" + file.JobID + "." + extension
+		}
+		if result.Object, err = m.userExportStore.Put(ctx, name, file); err != nil {
+			slog.ErrorContext(ctx, "failed to store users export", "job_id", file.JobID, "error", err)
+			return m.errorResponse(err.Error()), nil
+		}
+	} else {
+		result.Content = string(file.Content)
+	}
+
+	slog.InfoContext(ctx, "users exported",
+		"job_id", file.JobID,
+		"connection_id", request.ConnectionID,
+		"fields", len(request.Fields),
+		"destination", request.Destination,
+		"bytes", len(file.Content),
+		"exported_by", redaction.Redact(claims.Subject),
+	)
+
+	responseJSON, err := json.Marshal(UserDataResponse{Success: true, Data: result})
+	if err != nil {
+		return m.errorResponse("failed to marshal response"), nil
+	}
+	return responseJSON, nil
+}
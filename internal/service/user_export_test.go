@@ -0,0 +1,141 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/jwt"
+)
+
+// mockUserReportExporter records the export asked for and returns a CSV file
+type mockUserReportExporter struct {
+	export      *model.UserExport
+	hasDeadline bool
+}
+
+func (m *mockUserReportExporter) ExportUserReport(ctx context.Context, export *model.UserExport) (*model.UserExportFile, error) {
+	m.export = export
+	_, m.hasDeadline = ctx.Deadline()
+	return &model.UserExportFile{JobID: "job_1", Format: export.Format, Content: []byte("email\nzephyr@example.com\n")}, nil
+}
+
+// memoryUserExportStore keeps the stored exports by name
+type memoryUserExportStore map[string]*model.UserExportFile
+
+func (s memoryUserExportStore) Put(_ context.Context, name string, file *model.UserExportFile) (*model.UserExportObject, error) {
+	s[name] = file
+	return &model.UserExportObject{Bucket: constants.ObjectStoreNameUserExports, Name: name, Size: uint64(len(file.Content))}, nil
+}
+
+func TestMessageHandlerOrchestrator_ExportUsers(t *testing.T) {
+	ctx := context.Background()
+
+	withScope, err := jwt.GenerateTestAccessToken("auth0|admin", "https://issuer/", "aud", constants.UserExportRequiredScope, time.Hour)
+	require.NoError(t, err)
+	withoutScope, err := jwt.GenerateSimpleTestAccessToken("auth0|admin", time.Hour)
+	require.NoError(t, err)
+
+	reader := &mockUserServiceReader{
+		metadataLookupFunc: func(ctx context.Context, input string) (*model.User, error) {
+			return &model.User{Token: input, UserID: "auth0|admin"}, nil
+		},
+	}
+
+	type response struct {
+		Success bool             `json:"success"`
+		Error   string           `json:"error"`
+		Data    userExportResult `json:"data"`
+	}
+	call := func(t *testing.T, opts []MessageHandlerOrchestratorOption, request string) response {
+		t.Helper()
+		orchestrator := NewMessageHandlerOrchestrator(append(opts, WithUserReaderForMessageHandler(reader))...)
+		result, err := orchestrator.ExportUsers(ctx, &mockTransportMessenger{data: []byte(request)})
+		require.NoError(t, err)
+		var r response
+		require.NoError(t, json.Unmarshal(result, &r))
+		return r
+	}
+	fields := `"fields":[{"name":"email"},{"name":"user_metadata.organization","export_as":"organization"}]`
+
+	t.Run("replies the export", func(t *testing.T) {
+		exporter := &mockUserReportExporter{}
+		r := call(t, []MessageHandlerOrchestratorOption{WithUserReportExporterForMessageHandler(exporter)},
+			`{"auth_token":"`+withScope+`","connection_id":"con_db",`+fields+`}`)
+		require.True(t, r.Success, r.Error)
+		assert.Equal(t, "job_1", r.Data.JobID)
+		assert.Equal(t, model.UserExportFormatCSV, r.Data.Format)
+		assert.Equal(t, "email\nzephyr@example.com\n", r.Data.Content)
+		assert.Nil(t, r.Data.Object)
+
+		assert.Equal(t, "con_db", exporter.export.ConnectionID)
+		assert.Equal(t, model.UserExportField{Name: "user_metadata.organization", ExportAs: "organization"}, exporter.export.Fields[1])
+		assert.True(t, exporter.hasDeadline, "the wait is bounded without a request deadline")
+	})
+
+	t.Run("stores the export", func(t *testing.T) {
+		store := memoryUserExportStore{}
+		opts := []MessageHandlerOrchestratorOption{
+			WithUserReportExporterForMessageHandler(&mockUserReportExporter{}),
+			WithUserExportStoreForMessageHandler(store),
+		}
+		r := call(t, opts, `{"auth_token":"`+withScope+`","format":"JSON",`+fields+`,"destination":"object_store"}`)
+		require.True(t, r.Success, r.Error)
+		assert.Empty(t, r.Data.Content)
+		require.NotNil(t, r.Data.Object)
+		assert.Equal(t, "users-exports/job_1.jsonl", r.Data.Object.Name)
+		assert.Contains(t, store, "users-exports/job_1.jsonl")
+
+		r = call(t, opts, `{"auth_token":"`+withScope+`",`+fields+`,"destination":"object_store","name":"reports/2026-10.csv"}`)
+		require.True(t, r.Success, r.Error)
+		assert.Contains(t, store, "reports/2026-10.csv")
+	})
+
+	errorCases := []struct {
+		name    string
+		request string
+		want    string
+	}{
+		{"missing scope", `{"auth_token":"` + withoutScope + `",` + fields + `}`, "insufficient_scope"},
+		{"fields required", `{"auth_token":"` + withScope + `","fields":[]}`, "fields are required"},
+		{"field name required", `{"auth_token":"` + withScope + `","fields":[{"export_as":"email"}]}`, "fields[0]: name is required"},
+		{"unknown format", `{"auth_token":"` + withScope + `","format":"xlsx",` + fields + `}`, "format must be csv or json"},
+		{"unknown destination", `{"auth_token":"` + withScope + `",` + fields + `,"destination":"s3"}`, "destination must be reply or object_store"},
+		{"object store not enabled", `{"auth_token":"` + withScope + `",` + fields + `,"destination":"object_store"}`,
+			"the object_store destination is not enabled"},
+		{"name without object store", `{"auth_token":"` + withScope + `",` + fields + `,"name":"report.csv"}`,
+			"name is only used with the object_store destination"},
+	}
+	for _, tc := range errorCases {
+		t.Run(tc.name, func(t *testing.T) {
+			exporter := &mockUserReportExporter{}
+			r := call(t, []MessageHandlerOrchestratorOption{WithUserReportExporterForMessageHandler(exporter)}, tc.request)
+			assert.False(t, r.Success)
+			assert.Equal(t, tc.want, r.Error)
+			assert.Nil(t, exporter.export, "no job is started")
+		})
+	}
+
+	t.Run("invalid object name", func(t *testing.T) {
+		opts := []MessageHandlerOrchestratorOption{
+			WithUserReportExporterForMessageHandler(&mockUserReportExporter{}),
+			WithUserExportStoreForMessageHandler(memoryUserExportStore{}),
+		}
+		r := call(t, opts, `{"auth_token":"`+withScope+`",`+fields+`,"destination":"object_store","name":"../report csv"}`)
+		assert.Equal(t, "name may only contain letters, digits, '.', '_', '-' and '/'", r.Error)
+	})
+
+	t.Run("unavailable without an exporter", func(t *testing.T) {
+		r := call(t, nil, `{}`)
+		assert.Equal(t, "user_export_unavailable", r.Error)
+	})
+}
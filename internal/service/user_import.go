@@ -0,0 +1,200 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/redaction"
+)
+
+const (
+	// userImportMaxBytes is the largest users file Auth0 accepts in one job
+	userImportMaxBytes = 500 * 1024
+	// userImportMaxWait bounds how long an import waited on is polled when
+	// the request carries no deadline
+	userImportMaxWait = 25 * time.Second
+)
+
+// userImportPollInterval is how often an import waited on is polled
+var userImportPollInterval = 2 * time.Second
+
+// userImportRequest is the input of admin.users_import
+type userImportRequest struct {
+	AuthToken string `json:"auth_token"`
+	model.UserImport
+	// Wait polls the job until it is done, or the request deadline nears,
+	// and replies its outcome instead of the job just started
+	Wait bool `json:"wait"`
+}
+
+// userImportStatusRequest is the input of admin.users_import_status
+type userImportStatusRequest struct {
+	AuthToken string `json:"auth_token"`
+	JobID     string `json:"job_id"`
+}
+
+// WithUserImporterForMessageHandler sets the bulk user importer for the message handler orchestrator
+func WithUserImporterForMessageHandler(importer port.UserImporter) MessageHandlerOrchestratorOption {
+	return func(m *messageHandlerOrchestrator) {
+		m.userImporter = importer
+	}
+}
+
+// validateUserImport checks the manifest before it is uploaded, so a typo
+// fails the request rather than every record of the job
+func validateUserImport(manifest *model.UserImport) error {
+	manifest.ConnectionID = strings.TrimSpace(manifest.ConnectionID)
+	switch {
+	case manifest.ConnectionID == "":
+		return errs.NewValidation("connection_id is required")
+	case len(manifest.Users) == 0:
+		return errs.NewValidation("users are required")
+	}
+
+	size := 0
+	emails := make(map[string]int, len(manifest.Users))
+	for i, raw := range manifest.Users {
+		size += len(raw)
+		var user struct {
+			Email string `json:"email"`
+		}
+		if err := json.Unmarshal(raw, &user); err != nil {
+			return errs.NewValidation(fmt.Sprintf("users[%d] must be an object", i))
+		}
+		email := strings.ToLower(strings.TrimSpace(user.Email))
+		if email == "" {
+			return errs.NewValidation(fmt.Sprintf("users[%d]: email is required", i))
+		}
+		if first, ok := emails[email]; ok {
+			return errs.NewValidation(fmt.Sprintf("users[%d]: duplicate email of users[%d]", i, first))
+		}
+		emails[email] = i
+	}
+	if size > userImportMaxBytes {
+		return errs.NewValidation(fmt.Sprintf("users exceed %d KB: split the manifest into several imports", userImportMaxBytes/1024))
+	}
+	return nil
+}
+
+// ImportUsers starts a bulk import of the users of a manifest into a
+// connection of the identity provider, and with wait polls it to its end.
+// The records the import rejects are reported by admin.users_import_status.
+func (m *messageHandlerOrchestrator) ImportUsers(ctx context.Context, msg port.TransportMessenger) ([]byte, error) {
+	if m.userImporter == nil {
+		return m.errorResponse("user_import_unavailable"), nil
+	}
+
+	var request userImportRequest
+	if err := json.Unmarshal(msg.Data(), &request); err != nil {
+		return m.errorResponse("failed_to_unmarshal_request"), nil
+	}
+
+	claims, err := m.authorizeScope(ctx, request.AuthToken, constants.UserImportRequiredScope)
+	if err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+
+	manifest := &request.UserImport
+	if err := validateUserImport(manifest); err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+
+	job, err := m.userImporter.ImportUsers(ctx, manifest)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to start users import", "error", err)
+		return m.errorResponse(err.Error()), nil
+	}
+
+	slog.InfoContext(ctx, "users import started",
+		"job_id", job.ID,
+		"connection_id", manifest.ConnectionID,
+		"users", len(manifest.Users),
+		"upsert", manifest.Upsert,
+		"imported_by", redaction.Redact(claims.Subject),
+	)
+
+	if request.Wait {
+		job = m.waitUserImport(ctx, job)
+	}
+
+	responseJSON, err := json.Marshal(UserDataResponse{Success: true, Data: job})
+	if err != nil {
+		return m.errorResponse("failed to marshal response"), nil
+	}
+	return responseJSON, nil
+}
+
+// waitUserImport polls job until it is done or the time to wait is up, and
+// returns its latest state; a failed poll returns the previous one
+func (m *messageHandlerOrchestrator) waitUserImport(ctx context.Context, job *model.UserImportJob) *model.UserImportJob {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(userImportMaxWait)
+	}
+
+	for !job.Done() {
+		// leave time for the final poll and the reply
+		if time.Until(deadline) < 2*userImportPollInterval {
+			return job
+		}
+		timer := time.NewTimer(userImportPollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return job
+		case <-timer.C:
+		}
+
+		polled, err := m.userImporter.UserImportJob(ctx, job.ID)
+		if err != nil {
+			slog.WarnContext(ctx, "failed to poll users import", "job_id", job.ID, "error", err)
+			return job
+		}
+		job = polled
+	}
+	return job
+}
+
+// UserImportStatus returns the progress of a bulk user import and, once it
+// is done, its summary and the records it rejected with the reasons
+func (m *messageHandlerOrchestrator) UserImportStatus(ctx context.Context, msg port.TransportMessenger) ([]byte, error) {
+	if m.userImporter == nil {
+		return m.errorResponse("user_import_unavailable"), nil
+	}
+
+	var request userImportStatusRequest
+	if err := json.Unmarshal(msg.Data(), &request); err != nil {
+		return m.errorResponse("failed_to_unmarshal_request"), nil
+	}
+
+	if _, err := m.authorizeScope(ctx, request.AuthToken, constants.UserImportRequiredScope); err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+
+	jobID := strings.TrimSpace(request.JobID)
+	if jobID == "" {
+		return m.errorResponse("job_id is required"), nil
+	}
+
+	job, err := m.userImporter.UserImportJob(ctx, jobID)
+	if err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+
+	responseJSON, err := json.Marshal(UserDataResponse{Success: true, Data: job})
+	if err != nil {
+		return m.errorResponse("failed to marshal response"), nil
+	}
+	return responseJSON, nil
+}
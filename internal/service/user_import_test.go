@@ -0,0 +1,147 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/jwt"
+)
+
+// mockUserImporter starts jobs that complete after polls polls
+type mockUserImporter struct {
+	imported *model.UserImport
+	polls    int
+}
+
+func (m *mockUserImporter) ImportUsers(_ context.Context, manifest *model.UserImport) (*model.UserImportJob, error) {
+	m.imported = manifest
+	return &model.UserImportJob{ID: "job_1", Status: model.UserImportStatusPending, ConnectionID: manifest.ConnectionID}, nil
+}
+
+func (m *mockUserImporter) UserImportJob(_ context.Context, jobID string) (*model.UserImportJob, error) {
+	if jobID != "job_1" {
+		return nil, errors.NewNotFound("users import not found")
+	}
+	if m.polls--; m.polls > 0 {
+		return &model.UserImportJob{ID: jobID, Status: model.UserImportStatusPending, PercentageDone: 50}, nil
+	}
+	return &model.UserImportJob{
+		ID:      jobID,
+		Status:  model.UserImportStatusCompleted,
+		Summary: &model.UserImportSummary{Total: 2, Inserted: 1, Failed: 1},
+		Errors: []model.UserImportError{{
+			Email:  "zora@example.com",
+			Errors: []model.UserImportErrorDetail{{Code: "INVALID_FORMAT", Message: "Invalid email", Path: "email"}},
+		}},
+	}, nil
+}
+
+func TestMessageHandlerOrchestrator_ImportUsers(t *testing.T) {
+	ctx := context.Background()
+	defer func(interval time.Duration) { userImportPollInterval = interval }(userImportPollInterval)
+	userImportPollInterval = time.Millisecond
+
+	withScope, err := jwt.GenerateTestAccessToken("auth0|admin", "https://issuer/", "aud", constants.UserImportRequiredScope, time.Hour)
+	require.NoError(t, err)
+	withoutScope, err := jwt.GenerateSimpleTestAccessToken("auth0|admin", time.Hour)
+	require.NoError(t, err)
+
+	reader := &mockUserServiceReader{
+		metadataLookupFunc: func(ctx context.Context, input string) (*model.User, error) {
+			return &model.User{Token: input, UserID: "auth0|admin"}, nil
+		},
+	}
+	newOrchestrator := func(importer *mockUserImporter) port.MessageHandler {
+		return NewMessageHandlerOrchestrator(WithUserReaderForMessageHandler(reader), WithUserImporterForMessageHandler(importer))
+	}
+
+	type response struct {
+		Success bool                `json:"success"`
+		Error   string              `json:"error"`
+		Data    model.UserImportJob `json:"data"`
+	}
+	call := func(t *testing.T, handler func(context.Context, port.TransportMessenger) ([]byte, error), request string) response {
+		t.Helper()
+		result, err := handler(ctx, &mockTransportMessenger{data: []byte(request)})
+		require.NoError(t, err)
+		var r response
+		require.NoError(t, json.Unmarshal(result, &r))
+		return r
+	}
+	manifest := `"connection_id":" con_db ","users":[{"email":"zephyr@example.com","name":"Zephyr"},{"email":"zora@example.com"}]`
+
+	t.Run("starts the import", func(t *testing.T) {
+		importer := &mockUserImporter{}
+		r := call(t, newOrchestrator(importer).ImportUsers, `{"auth_token":"`+withScope+`",`+manifest+`,"upsert":true}`)
+		require.True(t, r.Success, r.Error)
+		assert.Equal(t, "job_1", r.Data.ID)
+		assert.Equal(t, model.UserImportStatusPending, r.Data.Status)
+		assert.Equal(t, "con_db", importer.imported.ConnectionID)
+		assert.True(t, importer.imported.Upsert)
+		assert.JSONEq(t, `{"email":"zephyr@example.com","name":"Zephyr"}`, string(importer.imported.Users[0]), "records are passed through")
+	})
+
+	t.Run("waits for the outcome", func(t *testing.T) {
+		importer := &mockUserImporter{polls: 2}
+		r := call(t, newOrchestrator(importer).ImportUsers, `{"auth_token":"`+withScope+`",`+manifest+`,"wait":true}`)
+		require.True(t, r.Success, r.Error)
+		assert.Equal(t, model.UserImportStatusCompleted, r.Data.Status)
+		assert.Equal(t, 1, r.Data.Summary.Failed)
+		require.Len(t, r.Data.Errors, 1)
+		assert.Equal(t, "zora@example.com", r.Data.Errors[0].Email)
+	})
+
+	t.Run("status reports rejected records", func(t *testing.T) {
+		handler := newOrchestrator(&mockUserImporter{}).UserImportStatus
+		r := call(t, handler, `{"auth_token":"`+withScope+`","job_id":"job_1"}`)
+		require.True(t, r.Success, r.Error)
+		assert.Equal(t, "INVALID_FORMAT", r.Data.Errors[0].Errors[0].Code)
+
+		r = call(t, handler, `{"auth_token":"`+withScope+`","job_id":"job_2"}`)
+		assert.Equal(t, "users import not found", r.Error)
+	})
+
+	largeName := strings.Repeat("z", userImportMaxBytes)
+	errorCases := []struct {
+		name    string
+		request string
+		want    string
+	}{
+		{"missing scope", `{"auth_token":"` + withoutScope + `",` + manifest + `}`, "insufficient_scope"},
+		{"connection required", `{"auth_token":"` + withScope + `","users":[{"email":"zephyr@example.com"}]}`, "connection_id is required"},
+		{"users required", `{"auth_token":"` + withScope + `","connection_id":"con_db","users":[]}`, "users are required"},
+		{"record not an object", `{"auth_token":"` + withScope + `","connection_id":"con_db","users":["zephyr"]}`, "users[0] must be an object"},
+		{"email required", `{"auth_token":"` + withScope + `","connection_id":"con_db","users":[{"name":"Zephyr"}]}`, "users[0]: email is required"},
+		{"duplicate email", `{"auth_token":"` + withScope + `","connection_id":"con_db","users":[{"email":"zephyr@example.com"},{"email":"Zephyr@example.com"}]}`,
+			"users[1]: duplicate email of users[0]"},
+		{"manifest too large", `{"auth_token":"` + withScope + `","connection_id":"con_db","users":[{"email":"zephyr@example.com","name":"` + largeName + `"}]}`,
+			"users exceed 500 KB: split the manifest into several imports"},
+	}
+	for _, tc := range errorCases {
+		t.Run(tc.name, func(t *testing.T) {
+			importer := &mockUserImporter{}
+			r := call(t, newOrchestrator(importer).ImportUsers, tc.request)
+			assert.False(t, r.Success)
+			assert.Equal(t, tc.want, r.Error)
+			assert.Nil(t, importer.imported, "nothing is uploaded")
+		})
+	}
+
+	t.Run("unavailable without an importer", func(t *testing.T) {
+		r := call(t, NewMessageHandlerOrchestrator().ImportUsers, `{}`)
+		assert.Equal(t, "user_import_unavailable", r.Error)
+	})
+}
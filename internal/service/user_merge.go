@@ -0,0 +1,191 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/redaction"
+)
+
+// userMergeRequest is the input of admin.users_merge
+type userMergeRequest struct {
+	AuthToken string `json:"auth_token"`
+	// PrimarySub is the account that is kept
+	PrimarySub string `json:"primary_sub"`
+	// SecondarySub is the duplicate merged into it
+	SecondarySub string `json:"secondary_sub"`
+	// Policy resolves the metadata fields both accounts have; empty is fill_missing
+	Policy string `json:"policy"`
+}
+
+// userMergeResult is the reply of admin.users_merge
+type userMergeResult struct {
+	model.UserMergeRecord
+	UserMetadata *model.UserMetadata `json:"user_metadata,omitempty"`
+}
+
+// UserMergedEvent is the payload published on constants.UserMergedSubject
+type UserMergedEvent struct {
+	OldSub    string    `json:"old_sub"`
+	NewSub    string    `json:"new_sub"`
+	MergedBy  string    `json:"merged_by"`
+	Conflicts []string  `json:"conflicts,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// WithUserMergerForMessageHandler sets the user merger for the message handler orchestrator
+func WithUserMergerForMessageHandler(merger port.UserMerger) MessageHandlerOrchestratorOption {
+	return func(m *messageHandlerOrchestrator) {
+		m.userMerger = merger
+	}
+}
+
+// WithUserMergeHistoryForMessageHandler sets where the message handler orchestrator records user merges
+func WithUserMergeHistoryForMessageHandler(history port.UserMergeHistory) MessageHandlerOrchestratorOption {
+	return func(m *messageHandlerOrchestrator) {
+		m.userMergeHistory = history
+	}
+}
+
+// validateUserMerge checks the request and refuses a secondary account the
+// history says was already merged away
+func (m *messageHandlerOrchestrator) validateUserMerge(ctx context.Context, request *userMergeRequest) error {
+	request.PrimarySub = strings.TrimSpace(request.PrimarySub)
+	request.SecondarySub = strings.TrimSpace(request.SecondarySub)
+	switch {
+	case request.PrimarySub == "" || request.SecondarySub == "":
+		return errs.NewValidation("primary_sub and secondary_sub are required")
+	case strings.EqualFold(request.PrimarySub, request.SecondarySub):
+		return errs.NewValidation("primary_sub and secondary_sub must be different accounts")
+	}
+
+	switch request.Policy {
+	case "":
+		request.Policy = model.UserMergePolicyFillMissing
+	case model.UserMergePolicyFillMissing, model.UserMergePolicyPrimaryWins, model.UserMergePolicySecondaryWins:
+	default:
+		return errs.NewValidation("policy must be fill_missing, primary_wins or secondary_wins")
+	}
+
+	if m.userMergeHistory == nil {
+		return nil
+	}
+	if record, err := m.userMergeHistory.Get(ctx, request.SecondarySub); err == nil {
+		return errs.NewValidation("secondary_sub was already merged into " + record.NewSub)
+	} else if !errors.As(err, &errs.NotFound{}) {
+		return err
+	}
+	return nil
+}
+
+// MergeUsers folds a duplicate account into the one that is kept: the
+// identity provider links the duplicate's identities and emails to it, the
+// metadata of both is merged with the request's policy, and the old sub
+// keeps resolving to the kept account. The merge is recorded in the merge
+// history and announced on the user merged event.
+func (m *messageHandlerOrchestrator) MergeUsers(ctx context.Context, msg port.TransportMessenger) ([]byte, error) {
+	if m.userMerger == nil || m.userReader == nil {
+		return m.errorResponse("user_merge_unavailable"), nil
+	}
+
+	var request userMergeRequest
+	if err := json.Unmarshal(msg.Data(), &request); err != nil {
+		return m.errorResponse("failed_to_unmarshal_request"), nil
+	}
+
+	claims, err := m.authorizeScope(ctx, request.AuthToken, constants.UserMergeRequiredScope)
+	if err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+
+	if err := m.validateUserMerge(ctx, &request); err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+
+	primary, err := m.userReader.GetUser(ctx, &model.User{UserID: request.PrimarySub, Sub: request.PrimarySub})
+	if err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+	secondary, err := m.userReader.GetUser(ctx, &model.User{UserID: request.SecondarySub, Sub: request.SecondarySub})
+	if err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+	// a sub already linked to the primary resolves to it
+	if primary.UserID == secondary.UserID {
+		return m.errorResponse("the users are already the same account"), nil
+	}
+
+	metadata, conflicts := model.MergeUserMetadata(primary.UserMetadata, secondary.UserMetadata, request.Policy)
+	if err := m.userMerger.MergeUsers(ctx, &model.UserMerge{Primary: primary, Secondary: secondary, Metadata: metadata}); err != nil {
+		slog.ErrorContext(ctx, "failed to merge users",
+			"primary_sub", redaction.Redact(request.PrimarySub),
+			"secondary_sub", redaction.Redact(request.SecondarySub),
+			"error", err,
+		)
+		return m.errorResponse(err.Error()), nil
+	}
+
+	record := model.UserMergeRecord{
+		OldSub:      request.SecondarySub,
+		NewSub:      request.PrimarySub,
+		OldUsername: secondary.Username,
+		NewUsername: primary.Username,
+		Policy:      request.Policy,
+		Conflicts:   conflicts,
+		MergedBy:    claims.Subject,
+		MergedAt:    time.Now().UTC(),
+	}
+	// the accounts are merged at this point, so a lost record is only logged
+	if m.userMergeHistory != nil {
+		if err := m.userMergeHistory.Record(ctx, &record); err != nil {
+			slog.WarnContext(ctx, "failed to record user merge",
+				"old_sub", redaction.Redact(record.OldSub),
+				"error", err,
+			)
+		}
+	}
+
+	if m.eventPublisher != nil {
+		event := UserMergedEvent{
+			OldSub:    record.OldSub,
+			NewSub:    record.NewSub,
+			MergedBy:  record.MergedBy,
+			Conflicts: record.Conflicts,
+			Timestamp: record.MergedAt,
+		}
+		eventJSON, jsonErr := json.Marshal(event)
+		if jsonErr != nil {
+			slog.WarnContext(ctx, "failed to marshal user merged event", "error", jsonErr)
+		} else if pubErr := m.eventPublisher.Publish(ctx, constants.UserMergedSubject, eventJSON); pubErr != nil {
+			slog.WarnContext(ctx, "failed to publish user merged event",
+				"error", pubErr,
+				"old_sub", redaction.Redact(record.OldSub),
+			)
+		}
+	}
+
+	slog.InfoContext(ctx, "users merged",
+		"old_sub", redaction.Redact(record.OldSub),
+		"new_sub", redaction.Redact(record.NewSub),
+		"policy", record.Policy,
+		"conflicts", len(conflicts),
+		"merged_by", redaction.Redact(claims.Subject),
+	)
+
+	responseJSON, err := json.Marshal(UserDataResponse{Success: true, Data: userMergeResult{UserMergeRecord: record, UserMetadata: metadata}})
+	if err != nil {
+		return m.errorResponse("failed to marshal response"), nil
+	}
+	return responseJSON, nil
+}
@@ -0,0 +1,180 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/jwt"
+)
+
+// mockUserMerger records the merge asked for
+type mockUserMerger struct {
+	merge *model.UserMerge
+	err   error
+}
+
+func (m *mockUserMerger) MergeUsers(_ context.Context, merge *model.UserMerge) error {
+	m.merge = merge
+	return m.err
+}
+
+// memoryUserMergeHistory keeps the merge records by old sub
+type memoryUserMergeHistory map[string]*model.UserMergeRecord
+
+func (h memoryUserMergeHistory) Record(_ context.Context, record *model.UserMergeRecord) error {
+	h[record.OldSub] = record
+	return nil
+}
+
+func (h memoryUserMergeHistory) Get(_ context.Context, oldSub string) (*model.UserMergeRecord, error) {
+	record, ok := h[oldSub]
+	if !ok {
+		return nil, errs.NewNotFound("user merge not found")
+	}
+	return record, nil
+}
+
+func TestMessageHandlerOrchestrator_MergeUsers(t *testing.T) {
+	ctx := context.Background()
+
+	withScope, err := jwt.GenerateTestAccessToken("auth0|admin", "https://issuer/", "aud", constants.UserMergeRequiredScope, time.Hour)
+	require.NoError(t, err)
+	withoutScope, err := jwt.GenerateSimpleTestAccessToken("auth0|admin", time.Hour)
+	require.NoError(t, err)
+
+	s := func(v string) *string { return &v }
+	users := map[string]*model.User{
+		"auth0|jdoe":        {UserID: "auth0|jdoe", Username: "jdoe", UserMetadata: &model.UserMetadata{Name: s("Jane Doe")}},
+		"google-oauth2|123": {UserID: "google-oauth2|123", Username: "jane", UserMetadata: &model.UserMetadata{Name: s("Jane D."), City: s("Lisbon")}},
+		// a sub already linked to the primary resolves to it
+		"github|42": {UserID: "auth0|jdoe", Username: "jdoe"},
+	}
+	reader := &mockUserServiceReader{
+		metadataLookupFunc: func(ctx context.Context, input string) (*model.User, error) {
+			return &model.User{Token: input, UserID: "auth0|admin"}, nil
+		},
+		getUserFunc: func(ctx context.Context, user *model.User) (*model.User, error) {
+			found, ok := users[user.UserID]
+			if !ok {
+				return nil, errs.NewNotFound("user not found")
+			}
+			return found, nil
+		},
+	}
+
+	type response struct {
+		Success bool            `json:"success"`
+		Error   string          `json:"error"`
+		Data    userMergeResult `json:"data"`
+	}
+	call := func(t *testing.T, opts []MessageHandlerOrchestratorOption, request string) response {
+		t.Helper()
+		orchestrator := NewMessageHandlerOrchestrator(append(opts, WithUserReaderForMessageHandler(reader))...)
+		result, err := orchestrator.MergeUsers(ctx, &mockTransportMessenger{data: []byte(request)})
+		require.NoError(t, err)
+		var r response
+		require.NoError(t, json.Unmarshal(result, &r))
+		return r
+	}
+
+	t.Run("merges, records and announces", func(t *testing.T) {
+		merger := &mockUserMerger{}
+		history := memoryUserMergeHistory{}
+		publisher := &mockEventPublisher{}
+		r := call(t, []MessageHandlerOrchestratorOption{
+			WithUserMergerForMessageHandler(merger),
+			WithUserMergeHistoryForMessageHandler(history),
+			WithEventPublisherForMessageHandler(publisher),
+		}, `{"auth_token":"`+withScope+`","primary_sub":"auth0|jdoe","secondary_sub":"google-oauth2|123"}`)
+		require.True(t, r.Success, r.Error)
+
+		assert.Equal(t, "google-oauth2|123", r.Data.OldSub)
+		assert.Equal(t, "auth0|jdoe", r.Data.NewSub)
+		assert.Equal(t, model.UserMergePolicyFillMissing, r.Data.Policy)
+		assert.Equal(t, []string{"name"}, r.Data.Conflicts)
+		assert.Equal(t, "auth0|admin", r.Data.MergedBy)
+		assert.Equal(t, "Jane Doe", *r.Data.UserMetadata.Name)
+		assert.Equal(t, "Lisbon", *r.Data.UserMetadata.City)
+
+		require.NotNil(t, merger.merge)
+		assert.Equal(t, "jane", merger.merge.Secondary.Username)
+		assert.Equal(t, "Lisbon", *merger.merge.Metadata.City)
+		assert.Equal(t, "jdoe", history["google-oauth2|123"].NewUsername)
+
+		require.Len(t, publisher.calls, 1)
+		assert.Equal(t, constants.UserMergedSubject, publisher.calls[0].Subject)
+		var event UserMergedEvent
+		require.NoError(t, json.Unmarshal(publisher.calls[0].Data, &event))
+		assert.Equal(t, "google-oauth2|123", event.OldSub)
+		assert.Equal(t, "auth0|jdoe", event.NewSub)
+	})
+
+	t.Run("policy decides conflicts", func(t *testing.T) {
+		merger := &mockUserMerger{}
+		r := call(t, []MessageHandlerOrchestratorOption{WithUserMergerForMessageHandler(merger)},
+			`{"auth_token":"`+withScope+`","primary_sub":"auth0|jdoe","secondary_sub":"google-oauth2|123","policy":"secondary_wins"}`)
+		require.True(t, r.Success, r.Error)
+		assert.Equal(t, "Jane D.", *merger.merge.Metadata.Name)
+	})
+
+	t.Run("secondary already merged", func(t *testing.T) {
+		merger := &mockUserMerger{}
+		history := memoryUserMergeHistory{"google-oauth2|123": {OldSub: "google-oauth2|123", NewSub: "auth0|other"}}
+		r := call(t, []MessageHandlerOrchestratorOption{WithUserMergerForMessageHandler(merger), WithUserMergeHistoryForMessageHandler(history)},
+			`{"auth_token":"`+withScope+`","primary_sub":"auth0|jdoe","secondary_sub":"google-oauth2|123"}`)
+		assert.False(t, r.Success)
+		assert.Equal(t, "secondary_sub was already merged into auth0|other", r.Error)
+		assert.Nil(t, merger.merge)
+	})
+
+	t.Run("failed merge isn't recorded", func(t *testing.T) {
+		merger := &mockUserMerger{err: errs.NewUnexpected("failed to link the secondary user")}
+		history := memoryUserMergeHistory{}
+		publisher := &mockEventPublisher{}
+		r := call(t, []MessageHandlerOrchestratorOption{
+			WithUserMergerForMessageHandler(merger),
+			WithUserMergeHistoryForMessageHandler(history),
+			WithEventPublisherForMessageHandler(publisher),
+		}, `{"auth_token":"`+withScope+`","primary_sub":"auth0|jdoe","secondary_sub":"google-oauth2|123"}`)
+		assert.False(t, r.Success)
+		assert.Empty(t, history)
+		assert.Empty(t, publisher.calls)
+	})
+
+	tests := []struct {
+		name    string
+		request string
+		want    string
+	}{
+		{"missing scope", `{"auth_token":"` + withoutScope + `","primary_sub":"auth0|jdoe","secondary_sub":"google-oauth2|123"}`, "insufficient_scope"},
+		{"missing sub", `{"auth_token":"` + withScope + `","primary_sub":"auth0|jdoe"}`, "primary_sub and secondary_sub are required"},
+		{"same sub", `{"auth_token":"` + withScope + `","primary_sub":"auth0|jdoe","secondary_sub":"AUTH0|jdoe"}`, "primary_sub and secondary_sub must be different accounts"},
+		{"unknown policy", `{"auth_token":"` + withScope + `","primary_sub":"auth0|jdoe","secondary_sub":"google-oauth2|123","policy":"newest"}`, "policy must be fill_missing, primary_wins or secondary_wins"},
+		{"already linked", `{"auth_token":"` + withScope + `","primary_sub":"auth0|jdoe","secondary_sub":"github|42"}`, "the users are already the same account"},
+		{"unknown user", `{"auth_token":"` + withScope + `","primary_sub":"auth0|jdoe","secondary_sub":"github|7"}`, "user not found"},
+		{"malformed", `{`, "failed_to_unmarshal_request"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := call(t, []MessageHandlerOrchestratorOption{WithUserMergerForMessageHandler(&mockUserMerger{})}, tt.request)
+			assert.False(t, r.Success)
+			assert.Equal(t, tt.want, r.Error)
+		})
+	}
+
+	t.Run("unavailable", func(t *testing.T) {
+		r := call(t, nil, `{}`)
+		assert.Equal(t, "user_merge_unavailable", r.Error)
+	})
+}
@@ -0,0 +1,150 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/redaction"
+)
+
+// userPreferencesUpdateRequest is the input of user_preferences.update
+type userPreferencesUpdateRequest struct {
+	Token       string          `json:"token"`
+	Preferences json.RawMessage `json:"preferences"`
+}
+
+// decodeUserPreferences reads the preferences of an update strictly, so a
+// misspelled field is refused rather than silently dropped
+func decodeUserPreferences(raw json.RawMessage) (*model.UserPreferences, error) {
+	if len(bytes.TrimSpace(raw)) == 0 || bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+		return nil, errs.NewValidation("preferences is required")
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	var preferences model.UserPreferences
+	if err := decoder.Decode(&preferences); err != nil {
+		return nil, errs.NewValidation("invalid preferences: " + err.Error())
+	}
+	if err := preferences.Validate(); err != nil {
+		return nil, err
+	}
+	return &preferences, nil
+}
+
+// tokenOwner resolves the user a token belongs to. Unlike a read, an
+// update only accepts a token the provider verified, not a username or sub.
+func (m *messageHandlerOrchestrator) tokenOwner(ctx context.Context, token string) (*model.User, error) {
+	if m.userReader == nil {
+		return nil, errs.NewUnexpected("auth_service_unavailable")
+	}
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return nil, errs.NewValidation("token is required")
+	}
+
+	lookup, err := m.userReader.MetadataLookup(ctx, token, constants.UserUpdateMetadataRequiredScope)
+	if err != nil {
+		return nil, err
+	}
+	if lookup == nil || lookup.Token == "" {
+		return nil, errs.NewUnauthorized("token could not be verified")
+	}
+	if lookup.UserID != "" {
+		return m.userReader.GetUser(ctx, lookup)
+	}
+	return m.userReader.SearchUser(ctx, lookup, constants.CriteriaTypeUsername)
+}
+
+// GetUserPreferences returns the preferences of a user, with the defaults
+// for the ones never set. The input is that of user_metadata.read: an access
+// token, a username or a sub.
+func (m *messageHandlerOrchestrator) GetUserPreferences(ctx context.Context, msg port.TransportMessenger) ([]byte, error) {
+	input := string(msg.Data())
+	user, err := m.getUserByInput(ctx, input)
+	if err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+
+	var stored *model.UserPreferences
+	if user.UserMetadata != nil {
+		stored = user.UserMetadata.Preferences
+	}
+
+	responseJSON, err := json.Marshal(UserDataResponse{Success: true, Data: stored.WithDefaults(), Stale: user.Stale})
+	if err != nil {
+		return m.errorResponse("failed to marshal response"), nil
+	}
+	return responseJSON, nil
+}
+
+// UpdateUserPreferences sets the preferences given in the request, keeps the
+// others as stored and writes the preferences namespace alone, so the other
+// metadata fields can't be changed or cleared through it
+func (m *messageHandlerOrchestrator) UpdateUserPreferences(ctx context.Context, msg port.TransportMessenger) ([]byte, error) {
+	if m.userWriter == nil {
+		return m.errorResponse("auth_service_unavailable"), nil
+	}
+
+	var request userPreferencesUpdateRequest
+	if err := json.Unmarshal(msg.Data(), &request); err != nil {
+		return m.errorResponse("failed_to_unmarshal_request"), nil
+	}
+	update, err := decodeUserPreferences(request.Preferences)
+	if err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+
+	user, err := m.tokenOwner(ctx, request.Token)
+	if err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+	var stored *model.UserPreferences
+	if user.UserMetadata != nil {
+		stored = user.UserMetadata.Preferences
+	}
+	merged := stored.Merge(update)
+
+	// the provider stores the namespace with the other fields, so they're
+	// counted too; the fields are the user's own, so only the total applies
+	var metadata model.UserMetadata
+	if user.UserMetadata != nil {
+		metadata = *user.UserMetadata
+	}
+	metadata.Preferences = merged
+	if response := m.oversizeMetadataResponse(ctx, &metadata, model.MetadataSizeLimits{MaxBytes: m.metadataSizeLimits.MaxBytes}); response != nil {
+		return response, nil
+	}
+
+	updated, err := m.userWriter.UpdateUser(ctx, &model.User{
+		Token:        strings.TrimSpace(request.Token),
+		UserID:       user.UserID,
+		Username:     user.Username,
+		UserMetadata: &model.UserMetadata{Preferences: merged},
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to update user preferences",
+			"error", err,
+			"user_id", redaction.Redact(user.UserID),
+		)
+		return m.errorResponse(err.Error()), nil
+	}
+	if updated != nil && updated.UserMetadata != nil && updated.UserMetadata.Preferences != nil {
+		merged = updated.UserMetadata.Preferences
+	}
+
+	responseJSON, err := json.Marshal(UserDataResponse{Success: true, Data: merged.WithDefaults()})
+	if err != nil {
+		return m.errorResponse("failed to marshal response"), nil
+	}
+	return responseJSON, nil
+}
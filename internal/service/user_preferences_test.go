@@ -0,0 +1,106 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+)
+
+func TestMessageHandlerOrchestrator_UserPreferences(t *testing.T) {
+	ctx := context.Background()
+	disabled := false
+	name := "Jane Doe"
+	stored := &model.User{UserID: "auth0|jane", Username: "jane", UserMetadata: &model.UserMetadata{
+		Name:        &name,
+		Preferences: &model.UserPreferences{Notifications: &model.NotificationPreferences{Email: &model.ChannelPreferences{Enabled: &disabled}}},
+	}}
+
+	reader := &mockUserServiceReader{
+		metadataLookupFunc: func(ctx context.Context, input string) (*model.User, error) {
+			switch input {
+			case "valid-token":
+				return &model.User{Token: input, UserID: "auth0|jane"}, nil
+			case "jane":
+				return &model.User{Username: "jane"}, nil
+			}
+			return &model.User{UserID: input}, nil
+		},
+		getUserFunc: func(ctx context.Context, user *model.User) (*model.User, error) { return stored, nil },
+		searchUserFunc: func(ctx context.Context, user *model.User, criteria string) (*model.User, error) {
+			return stored, nil
+		},
+	}
+	var written *model.User
+	writer := &mockUserServiceWriter{updateUserFunc: func(ctx context.Context, user *model.User) (*model.User, error) {
+		written = user
+		return &model.User{UserMetadata: &model.UserMetadata{Name: &name, Preferences: user.UserMetadata.Preferences}}, nil
+	}}
+	orchestrator := NewMessageHandlerOrchestrator(WithUserReaderForMessageHandler(reader), WithUserWriterForMessageHandler(writer))
+
+	preferencesOf := func(t *testing.T, result []byte) *model.UserPreferences {
+		t.Helper()
+		var response struct {
+			Success     bool                   `json:"success"`
+			Error       string                 `json:"error"`
+			Preferences *model.UserPreferences `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(result, &response))
+		require.True(t, response.Success, response.Error)
+		return response.Preferences
+	}
+
+	t.Run("read applies the defaults", func(t *testing.T) {
+		result, err := orchestrator.GetUserPreferences(ctx, &mockTransportMessenger{data: []byte("jane")})
+		require.NoError(t, err)
+		preferences := preferencesOf(t, result)
+		assert.False(t, *preferences.Notifications.Email.Enabled)
+		assert.Equal(t, model.NotificationFrequencyImmediate, *preferences.Notifications.Email.Frequency)
+		assert.True(t, *preferences.Notifications.InApp.Enabled)
+		assert.False(t, *preferences.Notifications.SMS.Enabled)
+	})
+
+	t.Run("update writes the preferences namespace alone", func(t *testing.T) {
+		payload := `{"token":"valid-token","preferences":{"notifications":{"email":{"frequency":"daily"}}}}`
+		result, err := orchestrator.UpdateUserPreferences(ctx, &mockTransportMessenger{data: []byte(payload)})
+		require.NoError(t, err)
+		preferences := preferencesOf(t, result)
+		assert.Equal(t, model.NotificationFrequencyDaily, *preferences.Notifications.Email.Frequency)
+		assert.False(t, *preferences.Notifications.Email.Enabled, "the stored value is kept")
+
+		require.NotNil(t, written)
+		assert.Equal(t, "valid-token", written.Token)
+		assert.Equal(t, &model.UserMetadata{Preferences: written.UserMetadata.Preferences}, written.UserMetadata)
+		assert.Nil(t, written.UserMetadata.Preferences.Notifications.InApp, "defaults aren't stored")
+	})
+
+	tests := []struct {
+		name    string
+		payload string
+		wantErr string
+	}{
+		{"unknown field", `{"token":"valid-token","preferences":{"notifications":{"emails":{}}}}`, `invalid preferences: json: unknown field "emails"`},
+		{"invalid frequency", `{"token":"valid-token","preferences":{"notifications":{"sms":{"frequency":"hourly"}}}}`, "notifications.sms.frequency must be immediate, daily or weekly"},
+		{"missing preferences", `{"token":"valid-token"}`, "preferences is required"},
+		{"username instead of a token", `{"token":"jane","preferences":{}}`, "token could not be verified"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			written = nil
+			result, err := orchestrator.UpdateUserPreferences(ctx, &mockTransportMessenger{data: []byte(tt.payload)})
+			require.NoError(t, err)
+			var response UserDataResponse
+			require.NoError(t, json.Unmarshal(result, &response))
+			assert.False(t, response.Success)
+			assert.Equal(t, tt.wantErr, response.Error)
+			assert.Nil(t, written)
+		})
+	}
+}
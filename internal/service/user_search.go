@@ -0,0 +1,231 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/jwt"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/normalize"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/redaction"
+)
+
+const (
+	// userSearchMinQueryLength matches the shortest prefix Auth0 accepts for a wildcard search
+	userSearchMinQueryLength = 3
+	// typeaheadMinQueryLength is lower since the in-memory index has no such limit
+	typeaheadMinQueryLength = 2
+	// userSearchCandidateLimit bounds the users fetched from the provider and ranked per request
+	userSearchCandidateLimit = 200
+	defaultUserSearchPerPage = 20
+	maxUserSearchPerPage     = 100
+)
+
+// Match scores, from best to weakest. A field bonus breaks ties in favour of
+// the username, then the email, then the name.
+const (
+	scoreExact      = 100
+	scorePrefix     = 75
+	scoreWordPrefix = 50
+	scoreContains   = 25
+)
+
+// userSearchRequest is the input of users.search
+type userSearchRequest struct {
+	AuthToken string `json:"auth_token"`
+	Query     string `json:"query"`
+	Page      int    `json:"page"`
+	PerPage   int    `json:"per_page"`
+	// Mode is "" for a provider search or UserSearchModeTypeahead
+	Mode string `json:"mode"`
+}
+
+// UserSearchModeTypeahead answers users.search from the in-memory typeahead
+// index instead of the provider
+const UserSearchModeTypeahead = "typeahead"
+
+// WithTypeaheadSearcherForMessageHandler sets the searcher used for
+// typeahead-mode user searches, normally a TypeaheadIndex
+func WithTypeaheadSearcherForMessageHandler(searcher port.UserSearcher) MessageHandlerOrchestratorOption {
+	return func(m *messageHandlerOrchestrator) {
+		m.typeaheadSearcher = searcher
+	}
+}
+
+// WithUserSearcherForMessageHandler sets the user searcher for the message handler orchestrator
+func WithUserSearcherForMessageHandler(userSearcher port.UserSearcher) MessageHandlerOrchestratorOption {
+	return func(m *messageHandlerOrchestrator) {
+		m.userSearcher = userSearcher
+	}
+}
+
+// SearchUsers returns a ranked page of users whose username, email or name
+// matches a free-text query. Callers must present an access token carrying
+// constants.UserSearchRequiredScope. In typeahead mode candidates come from the
+// in-memory index, so the provider is only used to verify the token.
+func (m *messageHandlerOrchestrator) SearchUsers(ctx context.Context, msg port.TransportMessenger) ([]byte, error) {
+	if m.userReader == nil {
+		return m.errorResponse("user_search_unavailable"), nil
+	}
+
+	var request userSearchRequest
+	if err := json.Unmarshal(msg.Data(), &request); err != nil {
+		return m.errorResponse("failed_to_unmarshal_request"), nil
+	}
+
+	searcher, minQueryLength := m.userSearcher, userSearchMinQueryLength
+	switch request.Mode {
+	case "":
+	case UserSearchModeTypeahead:
+		searcher, minQueryLength = m.typeaheadSearcher, typeaheadMinQueryLength
+	default:
+		return m.errorResponse("mode must be empty or typeahead"), nil
+	}
+	if searcher == nil {
+		return m.errorResponse("user_search_unavailable"), nil
+	}
+
+	if _, err := m.authorizeScope(ctx, request.AuthToken, constants.UserSearchRequiredScope); err != nil {
+		return m.errorResponse(err.Error()), nil
+	}
+
+	query := normalize.Email(request.Query)
+	if utf8.RuneCountInString(query) < minQueryLength {
+		return m.errorResponse(fmt.Sprintf("query must be at least %d characters", minQueryLength)), nil
+	}
+	if request.Page < 0 {
+		return m.errorResponse("page must not be negative"), nil
+	}
+	perPage := request.PerPage
+	if perPage <= 0 {
+		perPage = defaultUserSearchPerPage
+	}
+	perPage = min(perPage, maxUserSearchPerPage)
+
+	candidates, err := searcher.SearchUserCandidates(ctx, query, userSearchCandidateLimit)
+	if err != nil {
+		slog.ErrorContext(ctx, "user search failed", "error", err)
+		return m.errorResponse(err.Error()), nil
+	}
+
+	hits := rankUsers(query, candidates)
+	result := model.UserSearchResult{
+		Users:   []model.UserSearchHit{},
+		Total:   len(hits),
+		Page:    request.Page,
+		PerPage: perPage,
+	}
+	if start := request.Page * perPage; start < len(hits) {
+		result.Users = hits[start:min(start+perPage, len(hits))]
+	}
+
+	slog.DebugContext(ctx, "user search completed",
+		"query", redaction.Redact(query),
+		"mode", request.Mode,
+		"candidates", len(candidates),
+		"hits", len(hits),
+	)
+
+	responseJSON, err := json.Marshal(UserDataResponse{Success: true, Data: result})
+	if err != nil {
+		return m.errorResponse("failed to marshal response"), nil
+	}
+	return responseJSON, nil
+}
+
+// authorizeScope verifies that authToken is an access token the provider
+// accepts and that it carries scope, and returns its claims
+func (m *messageHandlerOrchestrator) authorizeScope(ctx context.Context, authToken, scope string) (*jwt.Claims, error) {
+	claims, err := m.verifyAccessToken(ctx, authToken, scope)
+	if err != nil {
+		return nil, err
+	}
+	if !claims.HasScope(scope) {
+		return nil, errs.NewForbidden("insufficient_scope")
+	}
+	return claims, nil
+}
+
+// rankUsers scores every candidate against query and returns the matches,
+// best first. Candidates come from a provider-side search that may be broader
+// than the ranking, so non-matching users are dropped here.
+func rankUsers(query string, candidates []*model.User) []model.UserSearchHit {
+	hits := make([]model.UserSearchHit, 0, len(candidates))
+	seen := make(map[string]bool, len(candidates))
+	for _, user := range candidates {
+		if user == nil || seen[user.UserID] {
+			continue
+		}
+		seen[user.UserID] = true
+
+		// searchers are other services: users who opted out of profile
+		// sharing are only found, and returned, by their username
+		user = user.ProfileFor(false)
+		name := user.SearchName()
+		score := max(
+			matchScore(query, normalize.Username(user.Username))+3,
+			matchScore(query, normalize.Email(user.PrimaryEmail))+2,
+			matchScore(query, normalize.Email(name))+1,
+		)
+		// the bonus alone isn't a match
+		if score <= 3 {
+			continue
+		}
+		hits = append(hits, model.UserSearchHit{
+			UserID:       user.UserID,
+			Username:     user.Username,
+			Name:         name,
+			PrimaryEmail: user.PrimaryEmail,
+			Score:        score,
+		})
+	}
+
+	slices.SortStableFunc(hits, func(a, b model.UserSearchHit) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Username, b.Username)
+	})
+	return hits
+}
+
+// matchScore scores a single normalized field value against query
+func matchScore(query, value string) int {
+	switch {
+	case value == "":
+		return 0
+	case value == query:
+		return scoreExact
+	case strings.HasPrefix(value, query):
+		return scorePrefix
+	}
+	for _, word := range searchWords(value) {
+		if strings.HasPrefix(word, query) {
+			return scoreWordPrefix
+		}
+	}
+	if strings.Contains(value, query) {
+		return scoreContains
+	}
+	return 0
+}
+
+// searchWords splits a normalized value into the words a query may start
+// with, e.g. "jane.doe@example.com" into jane, doe, example and com
+func searchWords(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ' ' || r == '.' || r == '-' || r == '_' || r == '@'
+	})
+}
@@ -0,0 +1,219 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/model"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/converters"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/jwt"
+)
+
+// mockUserSearcher returns every user matching the query
+type mockUserSearcher struct {
+	users []*model.User
+	query string
+}
+
+func (m *mockUserSearcher) SearchUserCandidates(ctx context.Context, query string, limit int) ([]*model.User, error) {
+	m.query = query
+	var candidates []*model.User
+	for _, user := range m.users {
+		if user.MatchesSearch(query) {
+			candidates = append(candidates, user)
+		}
+	}
+	return candidates, nil
+}
+
+func searchTestUsers() []*model.User {
+	return []*model.User{
+		{UserID: "auth0|1", Username: "janet", PrimaryEmail: "janet@example.com"},
+		{UserID: "auth0|2", Username: "jdoe", PrimaryEmail: "jane.doe@example.com", UserMetadata: &model.UserMetadata{Name: converters.StringPtr("Jane Doe")}},
+		{UserID: "auth0|3", Username: "jane", PrimaryEmail: "j@example.com"},
+		{UserID: "auth0|4", Username: "mjaneway", PrimaryEmail: "captain@example.com"},
+		{UserID: "auth0|5", Username: "bob", PrimaryEmail: "bob@example.com"},
+	}
+}
+
+func TestRankUsers(t *testing.T) {
+	hits := rankUsers("jane", searchTestUsers())
+
+	var got []string
+	for _, hit := range hits {
+		got = append(got, hit.Username)
+	}
+	// exact username, username prefix, email prefix, substring
+	want := []string{"jane", "janet", "jdoe", "mjaneway"}
+	if len(got) != len(want) {
+		t.Fatalf("rankUsers() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("rankUsers() = %v, want %v", got, want)
+			break
+		}
+	}
+	if hits[2].Name != "Jane Doe" {
+		t.Errorf("rankUsers() name = %q, want %q", hits[2].Name, "Jane Doe")
+	}
+}
+
+func TestRankUsers_ProfileSharing(t *testing.T) {
+	optedOut := false
+	users := searchTestUsers()
+	users[1].UserMetadata.Privacy = &model.UserPrivacy{ShareProfile: &optedOut}
+
+	hits := rankUsers("jane doe", users)
+	if len(hits) != 0 {
+		t.Errorf("rankUsers() = %v, want the name of an opted-out user not searchable", hits)
+	}
+	hits = rankUsers("jdoe", users)
+	if len(hits) != 1 || hits[0].Username != "jdoe" || hits[0].Name != "" || hits[0].PrimaryEmail != "" {
+		t.Errorf("rankUsers() = %+v, want the username only", hits)
+	}
+}
+
+func TestMatchScore(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int
+	}{
+		{value: "doe", want: scoreExact},
+		{value: "doemark", want: scorePrefix},
+		{value: "jane doe", want: scoreWordPrefix},
+		{value: "jane.doe@example.com", want: scoreWordPrefix},
+		{value: "jdoe", want: scoreContains},
+		{value: "smith", want: 0},
+		{value: "", want: 0},
+	}
+	for _, tt := range tests {
+		if got := matchScore("doe", tt.value); got != tt.want {
+			t.Errorf("matchScore(%q) = %d, want %d", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestMessageHandlerOrchestrator_SearchUsers(t *testing.T) {
+	ctx := context.Background()
+
+	withScope, err := jwt.GenerateTestAccessToken("auth0|admin", "https://issuer/", "aud", "openid "+constants.UserSearchRequiredScope, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	withoutScope, err := jwt.GenerateSimpleTestAccessToken("auth0|admin", time.Hour)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+
+	// the reader accepts every JWT, like a provider that verified it
+	reader := &mockUserServiceReader{
+		metadataLookupFunc: func(ctx context.Context, input string) (*model.User, error) {
+			return &model.User{Token: input, UserID: "auth0|admin"}, nil
+		},
+	}
+	// a reader that can't verify JWTs returns no token
+	unverified := &mockUserServiceReader{
+		metadataLookupFunc: func(ctx context.Context, input string) (*model.User, error) {
+			return &model.User{Username: input}, nil
+		},
+	}
+
+	type response struct {
+		Success bool                   `json:"success"`
+		Error   string                 `json:"error"`
+		Data    model.UserSearchResult `json:"data"`
+	}
+	search := func(t *testing.T, opts []MessageHandlerOrchestratorOption, request map[string]any) response {
+		t.Helper()
+		payload, _ := json.Marshal(request)
+		result, err := NewMessageHandlerOrchestrator(opts...).SearchUsers(ctx, &mockTransportMessenger{data: payload})
+		if err != nil {
+			t.Fatalf("SearchUsers() unexpected error: %v", err)
+		}
+		var r response
+		if err := json.Unmarshal(result, &r); err != nil {
+			t.Fatalf("failed to unmarshal response %s: %v", result, err)
+		}
+		return r
+	}
+
+	searcher := &mockUserSearcher{users: searchTestUsers()}
+	opts := []MessageHandlerOrchestratorOption{WithUserReaderForMessageHandler(reader), WithUserSearcherForMessageHandler(searcher)}
+
+	t.Run("ranked and paginated", func(t *testing.T) {
+		r := search(t, opts, map[string]any{"auth_token": withScope, "query": "  JANE ", "page": 1, "per_page": 2})
+		if !r.Success {
+			t.Fatalf("SearchUsers() error = %s", r.Error)
+		}
+		if searcher.query != "jane" {
+			t.Errorf("candidate query = %q, want normalized %q", searcher.query, "jane")
+		}
+		if r.Data.Total != 4 || r.Data.Page != 1 || r.Data.PerPage != 2 {
+			t.Errorf("SearchUsers() total/page/per_page = %d/%d/%d, want 4/1/2", r.Data.Total, r.Data.Page, r.Data.PerPage)
+		}
+		if len(r.Data.Users) != 2 || r.Data.Users[0].Username != "jdoe" || r.Data.Users[1].Username != "mjaneway" {
+			t.Errorf("SearchUsers() page = %+v, want jdoe, mjaneway", r.Data.Users)
+		}
+	})
+
+	t.Run("page past the end", func(t *testing.T) {
+		r := search(t, opts, map[string]any{"auth_token": withScope, "query": "jane", "page": 5})
+		if !r.Success || len(r.Data.Users) != 0 || r.Data.Total != 4 {
+			t.Errorf("SearchUsers() = %+v, want an empty page of 4 hits", r)
+		}
+	})
+
+	typeahead := NewTypeaheadIndex(&mockUserExporter{users: searchTestUsers()})
+	if err := typeahead.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh() unexpected error: %v", err)
+	}
+	typeaheadOpts := append([]MessageHandlerOrchestratorOption{WithTypeaheadSearcherForMessageHandler(typeahead)}, opts...)
+
+	t.Run("typeahead mode", func(t *testing.T) {
+		providerSearcher := &mockUserSearcher{users: searchTestUsers()}
+		r := search(t, append(typeaheadOpts, WithUserSearcherForMessageHandler(providerSearcher)),
+			map[string]any{"auth_token": withScope, "query": "ja", "mode": UserSearchModeTypeahead})
+		if !r.Success {
+			t.Fatalf("SearchUsers() error = %s", r.Error)
+		}
+		if providerSearcher.query != "" {
+			t.Errorf("typeahead search called the provider with %q", providerSearcher.query)
+		}
+		if r.Data.Total != 3 || r.Data.Users[0].Username != "jane" {
+			t.Errorf("SearchUsers() = %+v, want 3 hits led by jane", r.Data)
+		}
+	})
+
+	errorTests := []struct {
+		name    string
+		opts    []MessageHandlerOrchestratorOption
+		request map[string]any
+		want    string
+	}{
+		{name: "no searcher", opts: []MessageHandlerOrchestratorOption{WithUserReaderForMessageHandler(reader)}, request: map[string]any{"auth_token": withScope, "query": "jane"}, want: "user_search_unavailable"},
+		{name: "missing scope", opts: opts, request: map[string]any{"auth_token": withoutScope, "query": "jane"}, want: "insufficient_scope"},
+		{name: "not a jwt", opts: opts, request: map[string]any{"auth_token": "jdoe", "query": "jane"}, want: "auth_token must be an access token"},
+		{name: "unverified token", opts: []MessageHandlerOrchestratorOption{WithUserReaderForMessageHandler(unverified), WithUserSearcherForMessageHandler(searcher)}, request: map[string]any{"auth_token": withScope, "query": "jane"}, want: "auth_token could not be verified"},
+		{name: "short query", opts: opts, request: map[string]any{"auth_token": withScope, "query": "ja"}, want: "query must be at least 3 characters"},
+		{name: "unknown mode", opts: opts, request: map[string]any{"auth_token": withScope, "query": "jane", "mode": "fuzzy"}, want: "mode must be empty or typeahead"},
+		{name: "typeahead without an index", opts: opts, request: map[string]any{"auth_token": withScope, "query": "jane", "mode": UserSearchModeTypeahead}, want: "user_search_unavailable"},
+		{name: "typeahead index not ready", opts: append([]MessageHandlerOrchestratorOption{WithTypeaheadSearcherForMessageHandler(NewTypeaheadIndex(&mockUserExporter{}))}, opts...), request: map[string]any{"auth_token": withScope, "query": "jane", "mode": UserSearchModeTypeahead}, want: "typeahead index is not ready"},
+		{name: "short typeahead query", opts: typeaheadOpts, request: map[string]any{"auth_token": withScope, "query": "j", "mode": UserSearchModeTypeahead}, want: "query must be at least 2 characters"},
+		{name: "negative page", opts: opts, request: map[string]any{"auth_token": withScope, "query": "jane", "page": -1}, want: "page must not be negative"},
+	}
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := search(t, tt.opts, tt.request)
+			if r.Success || r.Error != tt.want {
+				t.Errorf("SearchUsers() error = %q, want %q", r.Error, tt.want)
+			}
+		})
+	}
+}
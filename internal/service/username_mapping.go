@@ -9,7 +9,8 @@ package service
 // and handles conversion of LDAP usernames to the "sub" claim format expected by v2 services.
 //
 // NOTE: This mapping is not forward-safe for a future Auth0 native-DB migration.
-// When that migration occurs, this will be replaced with a call to Auth0 with a cache layer.
+// UsernameToSub resolves through the user reader when one is configured and only
+// falls back to this mapping without one.
 
 import (
 	"crypto/sha512"
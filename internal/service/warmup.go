@@ -0,0 +1,141 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/internal/domain/port"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/concurrent"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/redaction"
+)
+
+// WarmUpConfig configures the startup warm-up
+type WarmUpConfig struct {
+	// HotUsers is the number of the users read the most saved, and preloaded
+	// at the next start; 0 disables the hot user list
+	HotUsers int
+	// Concurrency caps the users preloaded at once
+	Concurrency int
+	// Timeout bounds the warm-up; what isn't loaded by then is loaded on
+	// first use
+	Timeout time.Duration
+}
+
+// WarmUpResult summarizes a warm-up
+type WarmUpResult struct {
+	Warmed    int
+	Failed    int
+	Preloaded int
+	Duration  time.Duration
+}
+
+// WarmUp loads, at startup, what the first requests would otherwise wait
+// for: the resources of the warmers, such as signing keys and provider
+// tokens, then the users of the saved hot user list into the user cache.
+// It also saves the hot user list of the cache for the next start.
+type WarmUp struct {
+	warmers []port.Warmer
+	cache   port.HotUserCache
+	store   port.HotUserStore
+	config  WarmUpConfig
+	done    atomic.Bool
+}
+
+// NewWarmUp returns the warm-up of warmers, and of cache with the hot user
+// list of store when both are set
+func NewWarmUp(cache port.HotUserCache, store port.HotUserStore, config WarmUpConfig, warmers ...port.Warmer) *WarmUp {
+	if config.Concurrency <= 0 {
+		config.Concurrency = 8
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 30 * time.Second
+	}
+	return &WarmUp{warmers: warmers, cache: cache, store: store, config: config}
+}
+
+// hotUsers reports whether the hot user list is kept
+func (w *WarmUp) hotUsers() bool {
+	return w.cache != nil && w.store != nil && w.config.HotUsers > 0
+}
+
+// Run warms up, once, within the timeout. Failures are logged and leave
+// the resource to be loaded on first use.
+func (w *WarmUp) Run(ctx context.Context) WarmUpResult {
+	defer w.done.Store(true)
+	started := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, w.config.Timeout)
+	defer cancel()
+
+	var result WarmUpResult
+	for _, warmer := range w.warmers {
+		if err := warmer.WarmUp(ctx); err != nil {
+			slog.WarnContext(ctx, "warm-up failed, loading on first use", "error", err)
+			result.Failed++
+			continue
+		}
+		result.Warmed++
+	}
+	if w.hotUsers() {
+		result.Preloaded = w.preload(ctx)
+	}
+
+	result.Duration = time.Since(started)
+	slog.InfoContext(ctx, "warm-up done",
+		"warmed", result.Warmed,
+		"failed", result.Failed,
+		"preloaded_users", result.Preloaded,
+		"duration", result.Duration,
+	)
+	return result
+}
+
+// preload loads the saved hot users into the cache, returning how many were
+func (w *WarmUp) preload(ctx context.Context) int {
+	userIDs, err := w.store.LoadHotUsers(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to load the hot user list", "error", err)
+		return 0
+	}
+	userIDs = userIDs[:min(len(userIDs), w.config.HotUsers)]
+
+	var preloaded atomic.Int64
+	preloads := make([]func() error, 0, len(userIDs))
+	for _, userID := range userIDs {
+		preloads = append(preloads, func() error {
+			if err := w.cache.Preload(ctx, userID); err != nil {
+				// a user deleted since the list was saved, or a slow provider
+				slog.DebugContext(ctx, "failed to preload a hot user", "user_id", redaction.Redact(userID), "error", err)
+				return nil
+			}
+			preloaded.Add(1)
+			return nil
+		})
+	}
+	_ = concurrent.NewWorkerPool(w.config.Concurrency).Run(ctx, preloads...)
+	return int(preloaded.Load())
+}
+
+// Done reports whether the warm-up has run
+func (w *WarmUp) Done() bool {
+	return w.done.Load()
+}
+
+// SaveHotUsers saves the hot user list of the cache; it satisfies
+// scheduler.Job. It does nothing when the list isn't kept, or before the
+// warm-up ran, so a replica doesn't replace the list it is preloading with
+// its empty one.
+func (w *WarmUp) SaveHotUsers(ctx context.Context) error {
+	if !w.hotUsers() || !w.Done() {
+		return nil
+	}
+	userIDs := w.cache.HotUserIDs(w.config.HotUsers)
+	if len(userIDs) == 0 {
+		return nil
+	}
+	return w.store.SaveHotUsers(ctx, userIDs)
+}
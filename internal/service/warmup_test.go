@@ -0,0 +1,101 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// warmerFunc adapts a function to port.Warmer
+type warmerFunc func(ctx context.Context) error
+
+func (f warmerFunc) WarmUp(ctx context.Context) error { return f(ctx) }
+
+// fakeHotUserCache records the users preloaded into it
+type fakeHotUserCache struct {
+	mu        sync.Mutex
+	hot       []string
+	preloaded []string
+	missing   map[string]bool
+}
+
+func (c *fakeHotUserCache) HotUserIDs(n int) []string { return c.hot[:min(n, len(c.hot))] }
+
+func (c *fakeHotUserCache) Preload(_ context.Context, userID string) error {
+	if c.missing[userID] {
+		return errors.New("user not found")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.preloaded = append(c.preloaded, userID)
+	return nil
+}
+
+// fakeHotUserStore keeps the hot user list in memory
+type fakeHotUserStore struct {
+	userIDs []string
+	saves   int
+}
+
+func (s *fakeHotUserStore) LoadHotUsers(context.Context) ([]string, error) { return s.userIDs, nil }
+
+func (s *fakeHotUserStore) SaveHotUsers(_ context.Context, userIDs []string) error {
+	s.userIDs = userIDs
+	s.saves++
+	return nil
+}
+
+func TestWarmUp(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("runs the warmers and preloads the saved hot users", func(t *testing.T) {
+		var warmed int
+		cache := &fakeHotUserCache{missing: map[string]bool{"auth0|3": true}}
+		store := &fakeHotUserStore{userIDs: []string{"auth0|1", "auth0|2", "auth0|3", "auth0|4"}}
+		warmUp := NewWarmUp(cache, store, WarmUpConfig{HotUsers: 3},
+			warmerFunc(func(context.Context) error { warmed++; return nil }),
+			warmerFunc(func(context.Context) error { return errors.New("token endpoint down") }),
+		)
+		assert.False(t, warmUp.Done())
+
+		result := warmUp.Run(ctx)
+		assert.True(t, warmUp.Done())
+		assert.Equal(t, 1, warmed)
+		assert.Equal(t, 1, result.Warmed)
+		assert.Equal(t, 1, result.Failed)
+		assert.Equal(t, 2, result.Preloaded, "a deleted user is skipped")
+		slices.Sort(cache.preloaded)
+		assert.Equal(t, []string{"auth0|1", "auth0|2"}, cache.preloaded, "at most HotUsers are preloaded")
+	})
+
+	t.Run("saves the hot users once warmed up", func(t *testing.T) {
+		cache := &fakeHotUserCache{hot: []string{"auth0|9", "auth0|8"}}
+		store := &fakeHotUserStore{userIDs: []string{"auth0|1"}}
+		warmUp := NewWarmUp(cache, store, WarmUpConfig{HotUsers: 1})
+
+		require.NoError(t, warmUp.SaveHotUsers(ctx))
+		assert.Zero(t, store.saves, "the list being preloaded is kept")
+
+		warmUp.Run(ctx)
+		require.NoError(t, warmUp.SaveHotUsers(ctx))
+		assert.Equal(t, []string{"auth0|9"}, store.userIDs)
+
+		cache.hot = nil
+		require.NoError(t, warmUp.SaveHotUsers(ctx))
+		assert.Equal(t, 1, store.saves, "an empty list doesn't replace the saved one")
+	})
+
+	t.Run("keeps no list without a cache", func(t *testing.T) {
+		warmUp := NewWarmUp(nil, nil, WarmUpConfig{HotUsers: 10})
+		assert.Zero(t, warmUp.Run(ctx).Preloaded)
+		require.NoError(t, warmUp.SaveHotUsers(ctx))
+	})
+}
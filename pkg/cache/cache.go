@@ -0,0 +1,184 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package cache provides a small, concurrency-safe, in-memory TTL cache used
+// by the service to avoid repeated identity provider round trips.
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// entry is a cached value and its expiry time
+type entry[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+// Cache is a generic in-memory cache with a fixed time-to-live per entry and
+// an optional upper bound on the number of entries. When the bound is reached
+// expired entries are evicted first; if none are expired, the entry closest to
+// expiry is dropped.
+type Cache[K comparable, V any] struct {
+	mu         sync.RWMutex
+	entries    map[K]entry[V]
+	ttl        time.Duration
+	maxEntries int
+	now        func() time.Time
+}
+
+// Get returns the value for key if present and not expired
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	e, ok := c.entries[key]
+	if !ok || !c.now().Before(e.expiresAt) {
+		var zero V
+		return zero, false
+	}
+	return e.value, true
+}
+
+// GetStale returns the value for key like Get, and also an entry that expired
+// less than staleFor ago, reporting it as stale. Expired entries are kept
+// until evicted to make room or overwritten, so they may be gone earlier.
+func (c *Cache[K, V]) GetStale(key K, staleFor time.Duration) (value V, stale bool, ok bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	e, found := c.entries[key]
+	if !found {
+		return value, false, false
+	}
+	now := c.now()
+	if now.Before(e.expiresAt) {
+		return e.value, false, true
+	}
+	if now.Before(e.expiresAt.Add(staleFor)) {
+		return e.value, true, true
+	}
+	return value, false, false
+}
+
+// Set stores value under key using the cache TTL
+func (c *Cache[K, V]) Set(key K, value V) {
+	c.SetWithTTL(key, value, c.ttl)
+}
+
+// SetWithTTL stores value under key with an explicit TTL
+func (c *Cache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.entries[key]; !exists && c.maxEntries > 0 && len(c.entries) >= c.maxEntries {
+		c.evictLocked()
+	}
+	c.entries[key] = entry[V]{value: value, expiresAt: c.now().Add(ttl)}
+}
+
+// Delete removes key from the cache
+func (c *Cache[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
+
+// Len returns the number of entries, including expired ones not yet evicted
+func (c *Cache[K, V]) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.entries)
+}
+
+// Keys returns the keys of all unexpired entries, in no particular order
+func (c *Cache[K, V]) Keys() []K {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	now := c.now()
+	keys := make([]K, 0, len(c.entries))
+	for k, e := range c.entries {
+		if now.Before(e.expiresAt) {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// Entry is an exported cache entry, for snapshots
+type Entry[K comparable, V any] struct {
+	Key       K
+	Value     V
+	ExpiresAt time.Time
+}
+
+// Entries returns the unexpired entries, in no particular order
+func (c *Cache[K, V]) Entries() []Entry[K, V] {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	now := c.now()
+	entries := make([]Entry[K, V], 0, len(c.entries))
+	for k, e := range c.entries {
+		if now.Before(e.expiresAt) {
+			entries = append(entries, Entry[K, V]{Key: k, Value: e.value, ExpiresAt: e.expiresAt})
+		}
+	}
+	return entries
+}
+
+// Restore stores entries with the expiry times they carry, so a restored
+// entry expires when it would have anyway, and returns how many were
+// stored. Expired entries and keys already cached are skipped.
+func (c *Cache[K, V]) Restore(entries []Entry[K, V]) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	restored := 0
+	for _, e := range entries {
+		if _, exists := c.entries[e.Key]; exists || !now.Before(e.ExpiresAt) {
+			continue
+		}
+		if c.maxEntries > 0 && len(c.entries) >= c.maxEntries {
+			c.evictLocked()
+		}
+		c.entries[e.Key] = entry[V]{value: e.Value, expiresAt: e.ExpiresAt}
+		restored++
+	}
+	return restored
+}
+
+func (c *Cache[K, V]) evictLocked() {
+	now := c.now()
+
+	var (
+		oldestKey K
+		oldestAt  time.Time
+		found     bool
+	)
+	for k, e := range c.entries {
+		if !now.Before(e.expiresAt) {
+			delete(c.entries, k)
+			continue
+		}
+		if !found || e.expiresAt.Before(oldestAt) {
+			oldestKey, oldestAt, found = k, e.expiresAt, true
+		}
+	}
+	if found && len(c.entries) >= c.maxEntries {
+		delete(c.entries, oldestKey)
+	}
+}
+
+// New creates a cache with the given default TTL. maxEntries <= 0 means unbounded.
+func New[K comparable, V any](ttl time.Duration, maxEntries int) *Cache[K, V] {
+	return &Cache[K, V]{
+		entries:    make(map[K]entry[V]),
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		now:        time.Now,
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeClock struct{ t time.Time }
+
+func (f *fakeClock) now() time.Time { return f.t }
+
+func newTestCache(ttl time.Duration, maxEntries int) (*Cache[string, int], *fakeClock) {
+	clock := &fakeClock{t: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	c := New[string, int](ttl, maxEntries)
+	c.now = clock.now
+	return c, clock
+}
+
+func TestCacheGetSet(t *testing.T) {
+	c, clock := newTestCache(time.Minute, 0)
+
+	_, ok := c.Get("missing")
+	assert.False(t, ok)
+
+	c.Set("a", 1)
+	v, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+
+	clock.t = clock.t.Add(time.Minute)
+	_, ok = c.Get("a")
+	assert.False(t, ok, "entry should expire at its TTL")
+}
+
+func TestCacheGetStale(t *testing.T) {
+	c, clock := newTestCache(time.Minute, 0)
+	c.Set("a", 1)
+
+	v, stale, ok := c.GetStale("a", time.Minute)
+	assert.True(t, ok)
+	assert.False(t, stale)
+	assert.Equal(t, 1, v)
+
+	clock.t = clock.t.Add(90 * time.Second)
+	v, stale, ok = c.GetStale("a", time.Minute)
+	assert.True(t, ok)
+	assert.True(t, stale, "an expired entry within the stale window is stale")
+	assert.Equal(t, 1, v)
+	_, ok = c.Get("a")
+	assert.False(t, ok, "Get never returns stale entries")
+
+	clock.t = clock.t.Add(30 * time.Second)
+	_, _, ok = c.GetStale("a", time.Minute)
+	assert.False(t, ok, "entry past the stale window")
+
+	_, _, ok = c.GetStale("missing", time.Minute)
+	assert.False(t, ok)
+}
+
+func TestCacheSetWithTTL(t *testing.T) {
+	c, clock := newTestCache(time.Minute, 0)
+
+	c.SetWithTTL("short", 1, time.Second)
+	c.Set("long", 2)
+
+	clock.t = clock.t.Add(2 * time.Second)
+	_, ok := c.Get("short")
+	assert.False(t, ok)
+	_, ok = c.Get("long")
+	assert.True(t, ok)
+}
+
+func TestCacheDelete(t *testing.T) {
+	c, _ := newTestCache(time.Minute, 0)
+
+	c.Set("a", 1)
+	c.Delete("a")
+	_, ok := c.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestCacheEviction(t *testing.T) {
+	tests := []struct {
+		name        string
+		setup       func(c *Cache[string, int], clock *fakeClock)
+		wantPresent []string
+		wantAbsent  []string
+	}{
+		{
+			name: "expired entries are evicted first",
+			setup: func(c *Cache[string, int], clock *fakeClock) {
+				c.SetWithTTL("expired", 1, time.Second)
+				c.Set("fresh", 2)
+				clock.t = clock.t.Add(2 * time.Second)
+				c.Set("new", 3)
+			},
+			wantPresent: []string{"fresh", "new"},
+			wantAbsent:  []string{"expired"},
+		},
+		{
+			name: "entry closest to expiry is dropped when none expired",
+			setup: func(c *Cache[string, int], clock *fakeClock) {
+				c.SetWithTTL("soon", 1, 10*time.Second)
+				c.Set("later", 2)
+				c.Set("new", 3)
+			},
+			wantPresent: []string{"later", "new"},
+			wantAbsent:  []string{"soon"},
+		},
+		{
+			name: "overwriting an existing key does not evict",
+			setup: func(c *Cache[string, int], clock *fakeClock) {
+				c.Set("a", 1)
+				c.Set("b", 2)
+				c.Set("a", 3)
+			},
+			wantPresent: []string{"a", "b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, clock := newTestCache(time.Minute, 2)
+			tt.setup(c, clock)
+			for _, k := range tt.wantPresent {
+				_, ok := c.Get(k)
+				assert.True(t, ok, "expected %q to be present", k)
+			}
+			for _, k := range tt.wantAbsent {
+				_, ok := c.Get(k)
+				assert.False(t, ok, "expected %q to be evicted", k)
+			}
+			assert.LessOrEqual(t, c.Len(), 2)
+		})
+	}
+}
+
+func TestCacheKeys(t *testing.T) {
+	c, clock := newTestCache(time.Minute, 0)
+
+	c.Set("a", 1)
+	c.SetWithTTL("b", 2, time.Second)
+	assert.ElementsMatch(t, []string{"a", "b"}, c.Keys())
+
+	clock.t = clock.t.Add(2 * time.Second)
+	assert.Equal(t, []string{"a"}, c.Keys(), "expired entries should not be listed")
+}
+
+func TestCacheEntriesRestore(t *testing.T) {
+	c, clock := newTestCache(time.Minute, 0)
+	c.Set("a", 1)
+	c.SetWithTTL("b", 2, time.Second)
+	c.SetWithTTL("c", 3, 10*time.Second)
+	clock.t = clock.t.Add(2 * time.Second)
+
+	entries := c.Entries()
+	assert.ElementsMatch(t, []Entry[string, int]{
+		{Key: "a", Value: 1, ExpiresAt: clock.t.Add(58 * time.Second)},
+		{Key: "c", Value: 3, ExpiresAt: clock.t.Add(8 * time.Second)},
+	}, entries, "expired entries should not be exported")
+
+	restored, restoredClock := newTestCache(time.Minute, 0)
+	restoredClock.t = clock.t.Add(5 * time.Second)
+	restored.Set("a", 10)
+	assert.Equal(t, 1, restored.Restore(entries), "cached keys should be kept")
+	v, _ := restored.Get("a")
+	assert.Equal(t, 10, v)
+
+	restoredClock.t = restoredClock.t.Add(4 * time.Second)
+	_, ok := restored.Get("c")
+	assert.False(t, ok, "restored entries should keep their expiry")
+
+	expired := []Entry[string, int]{{Key: "d", Value: 4, ExpiresAt: restoredClock.t}}
+	assert.Zero(t, restored.Restore(expired))
+}
@@ -0,0 +1,359 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package client is the Go client of the auth service NATS API. It wraps the
+// request/reply subjects in typed methods, so consuming services don't build
+// payloads, pick subjects or decode reply envelopes themselves. Requests ask
+// for compressed and chunked replies, which are decoded transparently.
+package client
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/compression"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/deadline"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/idempotency"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/stream"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/tenant"
+)
+
+const (
+	// EnvelopeVersion is the newest reply envelope version the client
+	// decodes, and the one it asks for. Replies without a version are
+	// version 1.
+	EnvelopeVersion = 2
+
+	defaultTimeout     = 5 * time.Second
+	defaultMaxAttempts = 3
+	defaultBackoff     = 100 * time.Millisecond
+
+	// priorityHeader and priorityBatch mark batch traffic; the client doesn't
+	// import the service internals that define them
+	priorityHeader = "Lfx-Priority"
+	priorityBatch  = "batch"
+	// callerHeader names the calling service, for feature flag rollouts
+	callerHeader = "Lfx-Caller"
+	// envelopeHeader asks for the reply envelope version of the client
+	envelopeHeader = "Lfx-Envelope"
+	// providerHeader hints the identity provider serving the reads
+	providerHeader = "Lfx-Provider"
+)
+
+// requestFunc sends one request and returns the decoded reply body
+type requestFunc func(ctx context.Context, subject string, data []byte, header nats.Header) ([]byte, error)
+
+// Client calls the auth service over a NATS connection owned by the caller
+type Client struct {
+	request     requestFunc
+	header      nats.Header
+	timeout     time.Duration
+	maxAttempts int
+	backoff     time.Duration
+	tenant      string
+	// idempotencyKeys sends updates with a key, so they are retried like reads
+	idempotencyKeys bool
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithTimeout sets how long one attempt of a request may take (default 5s)
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = timeout
+	}
+}
+
+// WithRetries sets how many times a request is attempted in total and the
+// backoff before the first retry, doubled on each one (default 3 and 100ms).
+// Reads are retried on timeouts and when no instance is listening; updates
+// only in the latter case, as a timed out update may have been applied.
+func WithRetries(maxAttempts int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxAttempts = maxAttempts
+		c.backoff = backoff
+	}
+}
+
+// WithBatchPriority marks the client's requests as batch traffic, processed
+// by the service's batch worker pool so they don't delay interactive reads.
+// Use it for backfills, syncs and other bulk jobs.
+func WithBatchPriority() Option {
+	return func(c *Client) {
+		c.header.Set(priorityHeader, priorityBatch)
+	}
+}
+
+// WithCaller names the calling service (e.g. "project-service") on every
+// request, so behaviors rolled out to some callers first reach it as
+// configured
+func WithCaller(name string) Option {
+	return func(c *Client) {
+		c.header.Set(callerHeader, name)
+	}
+}
+
+// WithProvider pins the client's reads to the identity provider name (e.g.
+// "auth0") of a service running two, such as during a migration. The
+// service refuses the requests of clients it doesn't allow to pin.
+func WithProvider(name string) Option {
+	return func(c *Client) {
+		c.header.Set(providerHeader, name)
+	}
+}
+
+// WithTenant sends the requests to the subjects of tenant, for deployments
+// serving several tenants from one process
+func WithTenant(name string) Option {
+	return func(c *Client) {
+		c.tenant = name
+	}
+}
+
+// WithIdempotencyKeys sends every update with a new idempotency key, reused
+// by its retries, so updates are retried on timeouts too: the service
+// replays the reply of an attempt that was applied instead of applying it
+// again. The service must run with IDEMPOTENCY_ENABLED, or a retried update
+// may be applied twice.
+func WithIdempotencyKeys() Option {
+	return func(c *Client) {
+		c.idempotencyKeys = true
+	}
+}
+
+// New returns a client sending its requests over conn
+func New(conn *nats.Conn, opts ...Option) *Client {
+	c := &Client{
+		request: func(ctx context.Context, subject string, data []byte, header nats.Header) ([]byte, error) {
+			return stream.Request(ctx, conn, subject, data, header)
+		},
+		header: nats.Header{
+			compression.AcceptEncodingHeader: {compression.Zstd + ", " + compression.Gzip},
+			envelopeHeader:                   {strconv.Itoa(EnvelopeVersion)},
+		},
+		timeout:     defaultTimeout,
+		maxAttempts: defaultMaxAttempts,
+		backoff:     defaultBackoff,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.maxAttempts = max(c.maxAttempts, 1)
+	return c
+}
+
+// MetadataRead returns the profile of the user identified by input: an
+// access token, a subject identifier or a username. With fields, only those
+// are returned (e.g. "username", "picture").
+func (c *Client) MetadataRead(ctx context.Context, input string, fields ...string) (*Profile, error) {
+	payload := []byte(input)
+	if len(fields) > 0 {
+		var err error
+		if payload, err = json.Marshal(metadataReadRequest{Input: input, Fields: fields}); err != nil {
+			return nil, err
+		}
+	}
+
+	var profile Profile
+	if err := c.call(ctx, constants.UserMetadataReadSubject, payload, true, &profile); err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// MetadataUpdate updates the metadata of the user the token belongs to and
+// returns the stored metadata
+func (c *Client) MetadataUpdate(ctx context.Context, token string, metadata *UserMetadata) (*UserMetadata, error) {
+	payload, err := json.Marshal(metadataUpdateRequest{Token: token, UserMetadata: metadata})
+	if err != nil {
+		return nil, err
+	}
+
+	var updated UserMetadata
+	if err := c.call(ctx, constants.UserMetadataUpdateSubject, payload, false, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// EmailToUsername returns the username of the user with email
+func (c *Client) EmailToUsername(ctx context.Context, email string) (string, error) {
+	reply, err := c.send(ctx, constants.UserEmailToUserSubject, []byte(email), true)
+	if err != nil {
+		return "", err
+	}
+	// the username is a plain text reply in version 1 envelopes, the data of
+	// version 2 ones; errors are JSON envelopes
+	if trimmed := bytes.TrimSpace(reply); len(trimmed) > 0 && trimmed[0] == '{' {
+		var username string
+		err := decodeEnvelope(constants.UserEmailToUserSubject, reply, &username)
+		return username, err
+	}
+	return string(reply), nil
+}
+
+// Introspect verifies an access token and reports which of the scopes and
+// permissions it grants. At least one scope or permission is required.
+func (c *Client) Introspect(ctx context.Context, token string, scopes, permissions []string) (*Introspection, error) {
+	payload, err := json.Marshal(checkScopeRequest{AuthToken: token, Scopes: scopes, Permissions: permissions})
+	if err != nil {
+		return nil, err
+	}
+
+	var introspection Introspection
+	if err := c.call(ctx, constants.TokenCheckScopeSubject, payload, true, &introspection); err != nil {
+		return nil, err
+	}
+	return &introspection, nil
+}
+
+// call sends a request and decodes the data of its reply envelope into out
+func (c *Client) call(ctx context.Context, subject string, payload []byte, idempotent bool, out any) error {
+	reply, err := c.send(ctx, subject, payload, idempotent)
+	if err != nil {
+		return err
+	}
+	return decodeEnvelope(subject, reply, out)
+}
+
+// send sends a request, retrying the failures it is safe to retry
+func (c *Client) send(ctx context.Context, subject string, payload []byte, idempotent bool) ([]byte, error) {
+	requestHeader := c.header
+	if !idempotent && c.idempotencyKeys {
+		requestHeader = maps.Clone(c.header)
+		requestHeader.Set(idempotency.Header, rand.Text())
+		idempotent = true
+	}
+
+	backoff := c.backoff
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, c.timeout)
+		// the service stops working on the request when this attempt gives up
+		header := maps.Clone(requestHeader)
+		deadline.Set(attemptCtx, header)
+		reply, err := c.request(attemptCtx, tenant.Subject(c.tenant, subject), payload, header)
+		cancel()
+		if err == nil {
+			return reply, nil
+		}
+		if attempt >= c.maxAttempts || ctx.Err() != nil || !retryable(err, idempotent) {
+			return nil, fmt.Errorf("%s: %w", subject, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%s: %w", subject, ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// retryable reports whether a failed request can be sent again. Nobody
+// received it when no instance was listening; a timed out request may have
+// been processed, so it's only sent again when that is harmless.
+func retryable(err error, idempotent bool) bool {
+	if errors.Is(err, nats.ErrNoResponders) {
+		return true
+	}
+	return idempotent && (errors.Is(err, context.DeadlineExceeded) || errors.Is(err, nats.ErrTimeout))
+}
+
+// envelope is the JSON wrapper of the service replies. Its error is a string
+// in version 1, an object with a code in version 2.
+type envelope struct {
+	Version int             `json:"version,omitempty"`
+	Success bool            `json:"success"`
+	Message string          `json:"message,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+	Error   json.RawMessage `json:"error,omitempty"`
+	// ErrorCode is the code of a version 1 error, when the service has one
+	ErrorCode string `json:"error_code,omitempty"`
+}
+
+// envelopeError is the error of a version 2 envelope
+type envelopeError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// replyError returns the code and the message of the error of env, empty
+// when it has none
+func (env *envelope) replyError() (code, message string) {
+	if len(env.Error) == 0 || string(env.Error) == "null" {
+		return "", ""
+	}
+	if err := json.Unmarshal(env.Error, &message); err == nil {
+		return env.ErrorCode, message
+	}
+	var typed envelopeError
+	_ = json.Unmarshal(env.Error, &typed)
+	return typed.Code, typed.Message
+}
+
+// decodeEnvelope decodes a reply envelope, returning a *ReplyError for an
+// unsuccessful reply and decoding its data into out otherwise
+func decodeEnvelope(subject string, reply []byte, out any) error {
+	var env envelope
+	if err := json.Unmarshal(reply, &env); err != nil {
+		return fmt.Errorf("%s: malformed reply: %w", subject, err)
+	}
+	if env.Version > EnvelopeVersion {
+		return fmt.Errorf("%s: unsupported reply envelope version %d", subject, env.Version)
+	}
+	if code, message := env.replyError(); !env.Success || message != "" {
+		return &ReplyError{Subject: subject, Code: code, Message: cmp.Or(message, env.Message, "unsuccessful reply")}
+	}
+	if out == nil || len(env.Data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(env.Data, out); err != nil {
+		return fmt.Errorf("%s: malformed reply data: %w", subject, err)
+	}
+	return nil
+}
+
+var (
+	// ErrNotFound matches, with errors.Is, the replies for users that don't exist
+	ErrNotFound = errors.New("not found")
+	// ErrDeadlineExceeded matches, with errors.Is, the replies of requests the
+	// service abandoned because their deadline passed
+	ErrDeadlineExceeded = errors.New(deadline.ExceededError)
+)
+
+// ReplyError is an unsuccessful reply of the service
+type ReplyError struct {
+	Subject string
+	// Code is the stable code of the error, e.g. user_not_found, empty when
+	// the service has none
+	Code    string
+	Message string
+}
+
+func (e *ReplyError) Error() string {
+	return e.Subject + ": " + e.Message
+}
+
+// Is makes errors.Is match ErrNotFound and ErrDeadlineExceeded replies
+func (e *ReplyError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return strings.HasSuffix(e.Message, "not found")
+	case ErrDeadlineExceeded:
+		return e.Message == deadline.ExceededError
+	}
+	return false
+}
@@ -0,0 +1,251 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/compression"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/deadline"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/idempotency"
+)
+
+// recorded is a request the fake transport received
+type recorded struct {
+	subject string
+	data    string
+	header  nats.Header
+}
+
+// newTestClient returns a client whose requests are answered by replies in
+// turn, recording what was sent
+func newTestClient(t *testing.T, replies ...func() ([]byte, error)) (*Client, *[]recorded) {
+	t.Helper()
+	var sent []recorded
+	c := New(nil, WithRetries(3, time.Millisecond))
+	c.request = func(ctx context.Context, subject string, data []byte, header nats.Header) ([]byte, error) {
+		sent = append(sent, recorded{subject: subject, data: string(data), header: header})
+		require.NotEmpty(t, replies, "unexpected request to %s", subject)
+		reply := replies[0]
+		replies = replies[1:]
+		return reply()
+	}
+	return c, &sent
+}
+
+func replyWith(body string) func() ([]byte, error) {
+	return func() ([]byte, error) { return []byte(body), nil }
+}
+
+func failWith(err error) func() ([]byte, error) {
+	return func() ([]byte, error) { return nil, err }
+}
+
+func TestClient_MetadataRead(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("raw input", func(t *testing.T) {
+		c, sent := newTestClient(t, replyWith(`{"success":true,"data":{"name":"John Doe","activity":{"last_ip":"203.0.113.0"}}}`))
+		profile, err := c.MetadataRead(ctx, "john.doe")
+		require.NoError(t, err)
+		assert.Equal(t, "John Doe", *profile.Name)
+		assert.Equal(t, "203.0.113.0", profile.Activity.LastIP)
+
+		require.Len(t, *sent, 1)
+		assert.Equal(t, constants.UserMetadataReadSubject, (*sent)[0].subject)
+		assert.Equal(t, "john.doe", (*sent)[0].data)
+		assert.Contains(t, (*sent)[0].header.Get(compression.AcceptEncodingHeader), compression.Zstd)
+	})
+
+	t.Run("selected fields", func(t *testing.T) {
+		c, sent := newTestClient(t, replyWith(`{"success":true,"data":{"username":"john.doe"}}`))
+		profile, err := c.MetadataRead(ctx, "auth0|123", "username")
+		require.NoError(t, err)
+		assert.Equal(t, "john.doe", profile.Username)
+		assert.JSONEq(t, `{"input":"auth0|123","fields":["username"]}`, (*sent)[0].data)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		c, _ := newTestClient(t, replyWith(`{"success":false,"error":"user not found"}`))
+		_, err := c.MetadataRead(ctx, "nobody")
+		assert.ErrorIs(t, err, ErrNotFound)
+		var replyErr *ReplyError
+		require.ErrorAs(t, err, &replyErr)
+		assert.Equal(t, "user not found", replyErr.Message)
+	})
+
+	t.Run("retried on timeout", func(t *testing.T) {
+		c, sent := newTestClient(t, failWith(context.DeadlineExceeded), replyWith(`{"success":true,"data":{}}`))
+		_, err := c.MetadataRead(ctx, "john.doe")
+		require.NoError(t, err)
+		assert.Len(t, *sent, 2)
+	})
+
+	t.Run("gives up after the last attempt", func(t *testing.T) {
+		c, sent := newTestClient(t, failWith(nats.ErrNoResponders), failWith(nats.ErrNoResponders), failWith(nats.ErrNoResponders))
+		_, err := c.MetadataRead(ctx, "john.doe")
+		assert.ErrorIs(t, err, nats.ErrNoResponders)
+		assert.Len(t, *sent, 3)
+	})
+
+	t.Run("sends the attempt deadline", func(t *testing.T) {
+		c, sent := newTestClient(t, replyWith(`{"error":"deadline_exceeded"}`))
+		_, err := c.MetadataRead(ctx, "john.doe")
+		assert.ErrorIs(t, err, ErrDeadlineExceeded)
+		assert.NotEmpty(t, (*sent)[0].header.Get(deadline.TimeoutHeader))
+		assert.Empty(t, c.header.Get(deadline.TimeoutHeader), "the shared header must not be modified")
+	})
+
+	t.Run("typed envelope", func(t *testing.T) {
+		c, sent := newTestClient(t,
+			replyWith(`{"version":2,"success":true,"data":{"username":"john.doe"},"meta":{"request_id":"1","duration_ms":3}}`),
+			replyWith(`{"version":2,"success":false,"error":{"code":"user_not_found","message":"user not found"},"meta":{"request_id":"2"}}`))
+		profile, err := c.MetadataRead(ctx, "john.doe")
+		require.NoError(t, err)
+		assert.Equal(t, "john.doe", profile.Username)
+		assert.Equal(t, "2", (*sent)[0].header.Get(envelopeHeader))
+
+		_, err = c.MetadataRead(ctx, "nobody")
+		assert.ErrorIs(t, err, ErrNotFound)
+		var replyErr *ReplyError
+		require.ErrorAs(t, err, &replyErr)
+		assert.Equal(t, "user_not_found", replyErr.Code)
+	})
+
+	t.Run("newer envelope version", func(t *testing.T) {
+		c, _ := newTestClient(t, replyWith(`{"version":3,"success":true,"data":{}}`))
+		_, err := c.MetadataRead(ctx, "john.doe")
+		assert.ErrorContains(t, err, "unsupported reply envelope version 3")
+	})
+}
+
+func TestClient_MetadataUpdate(t *testing.T) {
+	ctx := context.Background()
+	name := "Jane Doe"
+
+	t.Run("updated", func(t *testing.T) {
+		c, sent := newTestClient(t, replyWith(`{"success":true,"data":{"name":"Jane Doe"}}`))
+		updated, err := c.MetadataUpdate(ctx, "token", &UserMetadata{Name: &name})
+		require.NoError(t, err)
+		assert.Equal(t, name, *updated.Name)
+		assert.Equal(t, constants.UserMetadataUpdateSubject, (*sent)[0].subject)
+		assert.JSONEq(t, `{"token":"token","user_metadata":{"name":"Jane Doe"}}`, (*sent)[0].data)
+	})
+
+	t.Run("not retried on timeout", func(t *testing.T) {
+		c, sent := newTestClient(t, failWith(nats.ErrTimeout))
+		_, err := c.MetadataUpdate(ctx, "token", &UserMetadata{Name: &name})
+		assert.ErrorIs(t, err, nats.ErrTimeout)
+		assert.Len(t, *sent, 1)
+	})
+
+	t.Run("retried without responders", func(t *testing.T) {
+		c, sent := newTestClient(t, failWith(nats.ErrNoResponders), replyWith(`{"success":true,"data":{}}`))
+		_, err := c.MetadataUpdate(ctx, "token", &UserMetadata{Name: &name})
+		require.NoError(t, err)
+		assert.Len(t, *sent, 2)
+	})
+
+	t.Run("retried on timeout with an idempotency key", func(t *testing.T) {
+		c, sent := newTestClient(t, failWith(nats.ErrTimeout), replyWith(`{"success":true,"data":{}}`))
+		WithIdempotencyKeys()(c)
+		_, err := c.MetadataUpdate(ctx, "token", &UserMetadata{Name: &name})
+		require.NoError(t, err)
+		require.Len(t, *sent, 2)
+		key := (*sent)[0].header.Get(idempotency.Header)
+		assert.NotEmpty(t, key)
+		assert.Equal(t, key, (*sent)[1].header.Get(idempotency.Header), "retries reuse the key")
+
+		c, sent = newTestClient(t, replyWith(`{"success":true,"data":{}}`))
+		WithIdempotencyKeys()(c)
+		_, err = c.MetadataUpdate(ctx, "token", &UserMetadata{Name: &name})
+		require.NoError(t, err)
+		assert.NotEqual(t, key, (*sent)[0].header.Get(idempotency.Header), "each update has its own key")
+	})
+
+	t.Run("reads carry no idempotency key", func(t *testing.T) {
+		c, sent := newTestClient(t, replyWith("john.doe"))
+		WithIdempotencyKeys()(c)
+		_, err := c.EmailToUsername(ctx, "john@example.com")
+		require.NoError(t, err)
+		assert.Empty(t, (*sent)[0].header.Get(idempotency.Header))
+	})
+}
+
+func TestClient_EmailToUsername(t *testing.T) {
+	ctx := context.Background()
+
+	c, sent := newTestClient(t, replyWith("john.doe"), replyWith(`{"success":false,"error":"user not found"}`),
+		replyWith(`{"version":2,"success":true,"data":"jane.doe","meta":{"request_id":"1"}}`))
+	username, err := c.EmailToUsername(ctx, "john@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "john.doe", username)
+	assert.Equal(t, constants.UserEmailToUserSubject, (*sent)[0].subject)
+
+	_, err = c.EmailToUsername(ctx, "nobody@example.com")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	username, err = c.EmailToUsername(ctx, "jane@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "jane.doe", username)
+}
+
+func TestClient_Introspect(t *testing.T) {
+	ctx := context.Background()
+
+	c, sent := newTestClient(t, replyWith(`{"success":true,"data":{"sub":"auth0|123","scopes":[{"scope":"search:users","granted":true}],"all_granted":true}}`))
+	introspection, err := c.Introspect(ctx, "token", []string{"search:users"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "auth0|123", introspection.Sub)
+	assert.True(t, introspection.AllGranted)
+	assert.Equal(t, []ScopeGrant{{Scope: "search:users", Granted: true}}, introspection.Scopes)
+
+	var request map[string]any
+	require.NoError(t, json.Unmarshal([]byte((*sent)[0].data), &request))
+	assert.Equal(t, constants.TokenCheckScopeSubject, (*sent)[0].subject)
+	assert.Equal(t, "token", request["auth_token"])
+}
+
+func TestWithBatchPriority(t *testing.T) {
+	c := New(nil, WithBatchPriority())
+	assert.Equal(t, priorityBatch, c.header.Get(priorityHeader))
+	assert.Empty(t, New(nil).header.Get(priorityHeader))
+}
+
+func TestWithCaller(t *testing.T) {
+	c := New(nil, WithCaller("project-service"))
+	assert.Equal(t, "project-service", c.header.Get(callerHeader))
+}
+
+func TestWithProvider(t *testing.T) {
+	c := New(nil, WithProvider("auth0"))
+	assert.Equal(t, "auth0", c.header.Get(providerHeader))
+	assert.Empty(t, New(nil).header.Get(providerHeader))
+}
+
+func TestWithTenant(t *testing.T) {
+	c, sent := newTestClient(t, replyWith("john.doe"))
+	WithTenant("acme")(c)
+
+	_, err := c.EmailToUsername(context.Background(), "john@example.com")
+	require.NoError(t, err)
+	require.Len(t, *sent, 1)
+	assert.Equal(t, "lfx.acme.auth-service.email_to_username", (*sent)[0].subject)
+}
+
+func TestRetryable(t *testing.T) {
+	assert.True(t, retryable(nats.ErrNoResponders, false))
+	assert.True(t, retryable(context.DeadlineExceeded, true))
+	assert.False(t, retryable(context.DeadlineExceeded, false))
+	assert.False(t, retryable(errors.New("connection closed"), true))
+}
@@ -0,0 +1,95 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package client
+
+import "time"
+
+// UserMetadata is the profile metadata of a user
+type UserMetadata struct {
+	Picture       *string  `json:"picture,omitempty"`
+	Zoneinfo      *string  `json:"zoneinfo,omitempty"`
+	Name          *string  `json:"name,omitempty"`
+	GivenName     *string  `json:"given_name,omitempty"`
+	FamilyName    *string  `json:"family_name,omitempty"`
+	JobTitle      *string  `json:"job_title,omitempty"`
+	Organization  *string  `json:"organization,omitempty"`
+	Country       *string  `json:"country,omitempty"`
+	StateProvince *string  `json:"state_province,omitempty"`
+	City          *string  `json:"city,omitempty"`
+	Address       *string  `json:"address,omitempty"`
+	PostalCode    *string  `json:"postal_code,omitempty"`
+	PhoneNumber   *string  `json:"phone_number,omitempty"`
+	TShirtSize    *string  `json:"t_shirt_size,omitempty"`
+	Locale        *string  `json:"locale,omitempty"`
+	Timezone      *string  `json:"timezone,omitempty"`
+	Pronouns      *string  `json:"pronouns,omitempty"`
+	PreferredName *string  `json:"preferred_name,omitempty"`
+	Privacy       *Privacy `json:"privacy,omitempty"`
+}
+
+// Privacy holds what a user lets other services see of them
+type Privacy struct {
+	// ShareProfile false opts the user out of profile sharing: reads by other
+	// services only get the username
+	ShareProfile *bool `json:"share_profile,omitempty"`
+}
+
+// UserActivity is the login activity of a user, only returned to callers
+// whose token carries the read:user_activity scope
+type UserActivity struct {
+	LastLogin   *time.Time `json:"last_login,omitempty"`
+	LoginsCount *int       `json:"logins_count,omitempty"`
+	LastIP      string     `json:"last_ip,omitempty"`
+}
+
+// Profile is the reply of MetadataRead. Username is only set when it was
+// selected with the fields of the read, or when the user opted out of profile
+// sharing, when it is all other services get.
+type Profile struct {
+	UserMetadata
+	Username string `json:"username,omitempty"`
+	// DisplayName is computed by the service from the preferred, given and
+	// family names and the username
+	DisplayName string        `json:"display_name,omitempty"`
+	Activity    *UserActivity `json:"activity,omitempty"`
+}
+
+// ScopeGrant reports whether a token carries a requested scope
+type ScopeGrant struct {
+	Scope   string `json:"scope"`
+	Granted bool   `json:"granted"`
+}
+
+// PermissionGrant reports whether a token carries a requested permission
+type PermissionGrant struct {
+	Permission string `json:"permission"`
+	Granted    bool   `json:"granted"`
+}
+
+// Introspection is the reply of Introspect
+type Introspection struct {
+	Sub         string            `json:"sub"`
+	Scopes      []ScopeGrant      `json:"scopes,omitempty"`
+	Permissions []PermissionGrant `json:"permissions,omitempty"`
+	AllGranted  bool              `json:"all_granted"`
+}
+
+// metadataReadRequest is the JSON form of the user_metadata.read input
+type metadataReadRequest struct {
+	Input  string   `json:"input"`
+	Fields []string `json:"fields,omitempty"`
+}
+
+// metadataUpdateRequest is the input of user_metadata.update
+type metadataUpdateRequest struct {
+	Token        string        `json:"token"`
+	UserMetadata *UserMetadata `json:"user_metadata"`
+}
+
+// checkScopeRequest is the input of token.check_scope
+type checkScopeRequest struct {
+	AuthToken   string   `json:"auth_token"`
+	Scopes      []string `json:"scopes,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+}
@@ -0,0 +1,68 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package cloudevents builds CloudEvents v1.0 attributes for domain events
+// published by the service. Events are emitted in binary content mode: the
+// payload is sent untouched as the message body and the context attributes
+// travel as transport headers, so existing consumers that only read the body
+// keep working regardless of the sink.
+package cloudevents
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+)
+
+const (
+	// SpecVersion is the CloudEvents specification version implemented here.
+	SpecVersion = "1.0"
+
+	// ContentTypeJSON is the data content type of every event payload.
+	ContentTypeJSON = "application/json"
+
+	// NATSHeaderPrefix is the attribute header prefix defined by the NATS protocol binding.
+	NATSHeaderPrefix = "ce-"
+
+	// KafkaHeaderPrefix is the attribute header prefix defined by the Kafka protocol binding.
+	KafkaHeaderPrefix = "ce_"
+)
+
+// Event holds the CloudEvents context attributes for a single event.
+type Event struct {
+	ID              string
+	Source          string
+	Type            string
+	Time            time.Time
+	DataContentType string
+}
+
+// New returns the attributes for an event of the given type. The type is the
+// subject the event is published on (e.g. lfx.user_profile.updated), which
+// keeps the NATS subject, the Kafka ce_type header and the CloudEvents type
+// aligned across sinks.
+func New(eventType string) Event {
+	return Event{
+		ID:              uuid.NewString(),
+		Source:          constants.ServiceName,
+		Type:            eventType,
+		Time:            time.Now().UTC(),
+		DataContentType: ContentTypeJSON,
+	}
+}
+
+// Headers returns the binary content mode headers for the event, each key
+// prefixed with the transport specific prefix. The content type is mapped to
+// the plain content-type header as required by both bindings.
+func (e Event) Headers(prefix string) map[string]string {
+	return map[string]string{
+		prefix + "specversion": SpecVersion,
+		prefix + "id":          e.ID,
+		prefix + "source":      e.Source,
+		prefix + "type":        e.Type,
+		prefix + "time":        e.Time.Format(time.RFC3339Nano),
+		"content-type":         e.DataContentType,
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package cloudevents
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+)
+
+func TestNew(t *testing.T) {
+	before := time.Now().UTC()
+	event := New("lfx.user_profile.updated")
+
+	assert.NotEmpty(t, event.ID)
+	assert.Equal(t, constants.ServiceName, event.Source)
+	assert.Equal(t, "lfx.user_profile.updated", event.Type)
+	assert.Equal(t, ContentTypeJSON, event.DataContentType)
+	assert.False(t, event.Time.Before(before))
+
+	assert.NotEqual(t, event.ID, New("lfx.user_profile.updated").ID, "every event should get a unique id")
+}
+
+func TestEventHeaders(t *testing.T) {
+	event := Event{
+		ID:              "abc",
+		Source:          "lfx-v2-auth-service",
+		Type:            "lfx.user_profile.updated",
+		Time:            time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		DataContentType: ContentTypeJSON,
+	}
+
+	tests := []struct {
+		name   string
+		prefix string
+	}{
+		{name: "nats binding", prefix: NATSHeaderPrefix},
+		{name: "kafka binding", prefix: KafkaHeaderPrefix},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := event.Headers(tt.prefix)
+			require.Len(t, headers, 6)
+			assert.Equal(t, SpecVersion, headers[tt.prefix+"specversion"])
+			assert.Equal(t, "abc", headers[tt.prefix+"id"])
+			assert.Equal(t, "lfx-v2-auth-service", headers[tt.prefix+"source"])
+			assert.Equal(t, "lfx.user_profile.updated", headers[tt.prefix+"type"])
+			assert.Equal(t, "2025-01-02T03:04:05Z", headers[tt.prefix+"time"])
+			assert.Equal(t, ContentTypeJSON, headers["content-type"])
+		})
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package compression compresses large NATS reply bodies for callers that ask
+// for it. A caller lists the encodings it can decode in the Accept-Encoding
+// request header; replies above the threshold are compressed with one of them
+// and carry a Content-Encoding header. Callers that send no Accept-Encoding
+// keep receiving plain bodies, so older clients are unaffected.
+package compression
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+)
+
+const (
+	// AcceptEncodingHeader lists the encodings a caller can decode, e.g. "zstd, gzip"
+	AcceptEncodingHeader = "Accept-Encoding"
+	// ContentEncodingHeader names the encoding of a compressed reply body
+	ContentEncodingHeader = "Content-Encoding"
+
+	// Gzip is the gzip encoding
+	Gzip = "gzip"
+	// Zstd is the Zstandard encoding, preferred when a caller accepts both
+	Zstd = "zstd"
+
+	// DefaultThreshold is the reply size above which bodies are compressed
+	DefaultThreshold = 32 * 1024
+
+	// MaxDecodedSize bounds the size of a decoded body, so a small compressed
+	// body can't expand without limit
+	MaxDecodedSize = 64 * 1024 * 1024
+)
+
+// preference orders the supported encodings, most preferred first
+var preference = []string{Zstd, Gzip}
+
+var (
+	zstdEncoder, _ = zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault))
+	zstdDecoder, _ = zstd.NewReader(nil, zstd.WithDecoderMaxMemory(MaxDecodedSize), zstd.WithDecoderConcurrency(0))
+)
+
+// Negotiate returns the supported encoding to use for a caller sending the
+// Accept-Encoding value accept, or "" when it accepts none of them. Encodings
+// with a higher q value win; ties go to the server preference.
+func Negotiate(accept string) string {
+	best, bestQ := "", 0.0
+	for _, encoding := range preference {
+		q := acceptedQuality(accept, encoding)
+		if q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
+
+// acceptedQuality returns the q value accept gives encoding, 0 when it isn't listed
+func acceptedQuality(accept, encoding string) float64 {
+	for item := range strings.SplitSeq(accept, ",") {
+		name, params, _ := strings.Cut(item, ";")
+		if !strings.EqualFold(strings.TrimSpace(name), encoding) {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return 0
+			}
+			q = parsed
+		}
+		return q
+	}
+	return 0
+}
+
+// Encode compresses data with encoding
+func Encode(encoding string, data []byte) ([]byte, error) {
+	switch encoding {
+	case Zstd:
+		return zstdEncoder.EncodeAll(data, make([]byte, 0, len(data)/2)), nil
+	case Gzip:
+		var buf bytes.Buffer
+		buf.Grow(len(data) / 2)
+		writer := gzip.NewWriter(&buf)
+		if _, err := writer.Write(data); err != nil {
+			return nil, errors.NewUnexpected("failed to gzip body", err)
+		}
+		if err := writer.Close(); err != nil {
+			return nil, errors.NewUnexpected("failed to gzip body", err)
+		}
+		return buf.Bytes(), nil
+	}
+	return nil, errors.NewValidation(fmt.Sprintf("unsupported encoding %q", encoding))
+}
+
+// Decode decompresses a body sent with the Content-Encoding value encoding.
+// Bodies without an encoding are returned as they are.
+func Decode(encoding string, data []byte) ([]byte, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "", "identity":
+		return data, nil
+	case Zstd:
+		decoded, err := zstdDecoder.DecodeAll(data, nil)
+		if err != nil {
+			return nil, errors.NewValidation("failed to decode zstd body", err)
+		}
+		return decoded, nil
+	case Gzip:
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, errors.NewValidation("failed to decode gzip body", err)
+		}
+		defer reader.Close()
+		decoded, err := io.ReadAll(io.LimitReader(reader, MaxDecodedSize+1))
+		if err != nil {
+			return nil, errors.NewValidation("failed to decode gzip body", err)
+		}
+		if len(decoded) > MaxDecodedSize {
+			return nil, errors.NewValidation("decoded body is too large")
+		}
+		return decoded, nil
+	}
+	return nil, errors.NewValidation(fmt.Sprintf("unsupported encoding %q", encoding))
+}
+
+// NewReader returns a reader of the decoded body read from r, sent with the
+// Content-Encoding value encoding. Bodies without an encoding are read as they are.
+func NewReader(encoding string, r io.Reader) (io.ReadCloser, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "", "identity":
+		return io.NopCloser(r), nil
+	case Zstd:
+		decoder, err := zstd.NewReader(r, zstd.WithDecoderMaxMemory(MaxDecodedSize), zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, errors.NewValidation("failed to decode zstd body", err)
+		}
+		return decoder.IOReadCloser(), nil
+	case Gzip:
+		reader, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, errors.NewValidation("failed to decode gzip body", err)
+		}
+		return reader, nil
+	}
+	return nil, errors.NewValidation(fmt.Sprintf("unsupported encoding %q", encoding))
+}
+
+// Compress compresses a reply body for a caller sending the Accept-Encoding
+// value accept. It returns the body to send and its encoding, or data and ""
+// when the body is below threshold, the caller accepts no supported encoding,
+// or compressing doesn't make it smaller. A threshold of 0 disables it.
+func Compress(accept string, data []byte, threshold int) ([]byte, string) {
+	if threshold <= 0 || len(data) < threshold || accept == "" {
+		return data, ""
+	}
+	encoding := Negotiate(accept)
+	if encoding == "" {
+		return data, ""
+	}
+	compressed, err := Encode(encoding, data)
+	if err != nil || len(compressed) >= len(data) {
+		return data, ""
+	}
+	return compressed, encoding
+}
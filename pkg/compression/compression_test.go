@@ -0,0 +1,105 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package compression
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{accept: "", want: ""},
+		{accept: "gzip", want: Gzip},
+		{accept: "zstd", want: Zstd},
+		{accept: "gzip, zstd", want: Zstd},
+		{accept: "GZIP", want: Gzip},
+		{accept: "zstd;q=0.5, gzip", want: Gzip},
+		{accept: "zstd;q=0, gzip;q=0", want: ""},
+		{accept: "br, deflate", want: ""},
+		{accept: "zstd;q=bogus", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			assert.Equal(t, tt.want, Negotiate(tt.accept))
+		})
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	body := bytes.Repeat([]byte(`{"username":"john.doe","name":"John Doe"},`), 1000)
+
+	for _, encoding := range []string{Gzip, Zstd} {
+		t.Run(encoding, func(t *testing.T) {
+			encoded, err := Encode(encoding, body)
+			require.NoError(t, err)
+			assert.Less(t, len(encoded), len(body))
+
+			decoded, err := Decode(encoding, encoded)
+			require.NoError(t, err)
+			assert.Equal(t, body, decoded)
+		})
+	}
+
+	t.Run("no encoding", func(t *testing.T) {
+		decoded, err := Decode("", body)
+		require.NoError(t, err)
+		assert.Equal(t, body, decoded)
+	})
+
+	t.Run("unsupported encoding", func(t *testing.T) {
+		_, err := Decode("br", body)
+		assert.Error(t, err)
+		_, err = Encode("br", body)
+		assert.Error(t, err)
+	})
+
+	t.Run("corrupt body", func(t *testing.T) {
+		_, err := Decode(Gzip, body)
+		assert.Error(t, err)
+		_, err = Decode(Zstd, body)
+		assert.Error(t, err)
+	})
+}
+
+func TestCompress(t *testing.T) {
+	large := bytes.Repeat([]byte("a"), 4096)
+
+	tests := []struct {
+		name         string
+		accept       string
+		data         []byte
+		threshold    int
+		wantEncoding string
+	}{
+		{name: "large body for a caller accepting zstd", accept: "zstd, gzip", data: large, threshold: 1024, wantEncoding: Zstd},
+		{name: "large body for a caller accepting gzip", accept: "gzip", data: large, threshold: 1024, wantEncoding: Gzip},
+		{name: "body below the threshold", accept: "zstd", data: large, threshold: 8192},
+		{name: "caller without Accept-Encoding", data: large, threshold: 1024},
+		{name: "caller accepting no supported encoding", accept: "br", data: large, threshold: 1024},
+		{name: "compression disabled", accept: "zstd", data: large, threshold: 0},
+		{name: "body compression doesn't shrink", accept: "gzip", data: []byte("ab"), threshold: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, encoding := Compress(tt.accept, tt.data, tt.threshold)
+			assert.Equal(t, tt.wantEncoding, encoding)
+			if encoding == "" {
+				assert.Equal(t, tt.data, body)
+				return
+			}
+			decoded, err := Decode(encoding, body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.data, decoded)
+		})
+	}
+}
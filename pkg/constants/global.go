@@ -3,6 +3,8 @@
 
 package constants
 
+import "time"
+
 const (
 
 	// ServiceName is the name of the auth service
@@ -21,6 +23,36 @@ const (
 	UserRepositoryTypeAuth0 = "auth0"
 )
 
+const (
+	// Mock repository configuration
+	// MockUsersFileEnvKey is the environment variable key for a YAML file of users that
+	// replaces the embedded fixtures
+	MockUsersFileEnvKey = "MOCK_USERS_FILE"
+
+	// MockLatencyEnvKey is the environment variable key for the delay added to every
+	// provider call
+	MockLatencyEnvKey = "MOCK_LATENCY"
+
+	// MockLatencyJitterEnvKey is the environment variable key for the random delay added
+	// on top of MOCK_LATENCY
+	MockLatencyJitterEnvKey = "MOCK_LATENCY_JITTER"
+
+	// MockErrorRateEnvKey is the environment variable key for the fraction of provider
+	// calls that fail
+	MockErrorRateEnvKey = "MOCK_ERROR_RATE"
+
+	// MockJWTSecretEnvKey is the environment variable key for the HS256 secret dev JWTs
+	// must be signed with
+	MockJWTSecretEnvKey = "MOCK_JWT_SECRET"
+)
+
+const (
+	// Fault injection configuration
+	// FaultInjectionRulesEnvKey is the environment variable key for the JSON array of fault
+	// rules; only accepted by builds with the fault_injection tag
+	FaultInjectionRulesEnvKey = "FAULT_INJECTION_RULES"
+)
+
 const (
 	// Authelia configuration
 	// AutheliaConfigMapNameEnvKey is the environment variable key for the ConfigMap name
@@ -37,6 +69,112 @@ const (
 
 	// AutheliaOIDCUserInfoURLEnvKey is the environment variable key for the OIDC userinfo URL
 	AutheliaOIDCUserInfoURLEnvKey = "AUTHELIA_OIDC_USERINFO_URL"
+
+	// AutheliaOIDCIssuerURLEnvKey is the environment variable key for the Authelia issuer URL. When set,
+	// the OIDC endpoints are discovered from its .well-known/openid-configuration document.
+	AutheliaOIDCIssuerURLEnvKey = "AUTHELIA_OIDC_ISSUER_URL"
+
+	// AutheliaOIDCDiscoveryRefreshIntervalEnvKey is the environment variable key for how often the
+	// discovery document is re-fetched; 0 fetches it only at startup
+	AutheliaOIDCDiscoveryRefreshIntervalEnvKey = "AUTHELIA_OIDC_DISCOVERY_REFRESH_INTERVAL"
+
+	// AutheliaTLSCAFileEnvKey is the environment variable key for a PEM CA bundle used to verify Authelia
+	AutheliaTLSCAFileEnvKey = "AUTHELIA_TLS_CA_FILE"
+
+	// AutheliaTLSCertFileEnvKey is the environment variable key for the client certificate presented to Authelia
+	AutheliaTLSCertFileEnvKey = "AUTHELIA_TLS_CERT_FILE"
+
+	// AutheliaTLSKeyFileEnvKey is the environment variable key for the client certificate's private key
+	AutheliaTLSKeyFileEnvKey = "AUTHELIA_TLS_KEY_FILE"
+
+	// AutheliaTLSServerNameEnvKey is the environment variable key overriding the name Authelia's certificate is verified against
+	AutheliaTLSServerNameEnvKey = "AUTHELIA_TLS_SERVER_NAME"
+
+	// AutheliaGroupPermissionsEnvKey is the environment variable key for a JSON object mapping
+	// Authelia groups to the permissions they grant, e.g. {"admins":["search:users"]}
+	AutheliaGroupPermissionsEnvKey = "AUTHELIA_GROUP_PERMISSIONS"
+
+	// AutheliaUserInfoCacheTTLEnvKey is the environment variable key for how long userinfo
+	// responses are cached per opaque token; 0 disables the cache
+	AutheliaUserInfoCacheTTLEnvKey = "AUTHELIA_USERINFO_CACHE_TTL"
+
+	// AutheliaUserInfoNegativeCacheTTLEnvKey is the environment variable key for how long
+	// rejected opaque tokens are remembered; 0 only caches valid tokens
+	AutheliaUserInfoNegativeCacheTTLEnvKey = "AUTHELIA_USERINFO_NEGATIVE_CACHE_TTL"
+
+	// AutheliaUserInfoCacheMaxEntriesEnvKey is the environment variable key for the maximum
+	// number of cached opaque tokens
+	AutheliaUserInfoCacheMaxEntriesEnvKey = "AUTHELIA_USERINFO_CACHE_MAX_ENTRIES"
+
+	// AutheliaUserStoreEnvKey is the environment variable key for where Authelia user records are kept
+	AutheliaUserStoreEnvKey = "AUTHELIA_USER_STORE"
+
+	// AutheliaUserStoreNATS is the value keeping Authelia user records in the NATS KV buckets
+	AutheliaUserStoreNATS = "nats"
+
+	// AutheliaUserStoreRedis is the value keeping Authelia user records in Redis
+	AutheliaUserStoreRedis = "redis"
+
+	// AutheliaUserStorePostgres is the value keeping Authelia user records in PostgreSQL
+	AutheliaUserStorePostgres = "postgres"
+
+	// AutheliaRedisURLEnvKey is the environment variable key for the redis:// or rediss:// URL of the
+	// Redis user store
+	AutheliaRedisURLEnvKey = "AUTHELIA_REDIS_URL"
+
+	// AutheliaRedisKeyPrefixEnvKey is the environment variable key for the prefix of the keys of the
+	// Redis user store
+	AutheliaRedisKeyPrefixEnvKey = "AUTHELIA_REDIS_KEY_PREFIX"
+
+	// AutheliaRedisOTPTTLEnvKey is the environment variable key for how long the Redis user store keeps
+	// an email verification code
+	AutheliaRedisOTPTTLEnvKey = "AUTHELIA_REDIS_OTP_TTL"
+
+	// AutheliaPostgresURLEnvKey is the environment variable key for the connection URL of the Postgres
+	// user store
+	AutheliaPostgresURLEnvKey = "AUTHELIA_POSTGRES_URL"
+
+	// AutheliaPostgresMaxConnsEnvKey is the environment variable key for the size of the Postgres
+	// connection pool
+	AutheliaPostgresMaxConnsEnvKey = "AUTHELIA_POSTGRES_MAX_CONNS"
+
+	// AutheliaPostgresMinConnsEnvKey is the environment variable key for the Postgres connections kept
+	// open when idle
+	AutheliaPostgresMinConnsEnvKey = "AUTHELIA_POSTGRES_MIN_CONNS"
+
+	// AutheliaPostgresMaxConnLifetimeEnvKey is the environment variable key for how long a Postgres
+	// connection is used before it is replaced
+	AutheliaPostgresMaxConnLifetimeEnvKey = "AUTHELIA_POSTGRES_MAX_CONN_LIFETIME"
+
+	// AutheliaPostgresMaxConnIdleTimeEnvKey is the environment variable key for how long an idle
+	// Postgres connection is kept open
+	AutheliaPostgresMaxConnIdleTimeEnvKey = "AUTHELIA_POSTGRES_MAX_CONN_IDLE_TIME"
+
+	// AutheliaPostgresOTPTTLEnvKey is the environment variable key for how long the Postgres user
+	// store keeps an email verification code
+	AutheliaPostgresOTPTTLEnvKey = "AUTHELIA_POSTGRES_OTP_TTL"
+
+	// AutheliaLookupCollisionsEnvKey is the environment variable key for what happens to a write whose
+	// lookup keys belong to another user: flagged (the default) or rejected
+	AutheliaLookupCollisionsEnvKey = "AUTHELIA_LOOKUP_COLLISIONS"
+
+	// AutheliaLookupCollisionsFlag keeps the keys with their owner and logs the collision
+	AutheliaLookupCollisionsFlag = "flag"
+
+	// AutheliaLookupCollisionsReject refuses the write with a conflict
+	AutheliaLookupCollisionsReject = "reject"
+
+	// AutheliaArchiveInactiveAfterEnvKey is the environment variable key for how long an Authelia
+	// user must go unused before it is moved to the archive. Unset or 0 disables archival.
+	AutheliaArchiveInactiveAfterEnvKey = "AUTHELIA_ARCHIVE_INACTIVE_AFTER"
+
+	// AutheliaArchiveIntervalEnvKey is the environment variable key for the interval between
+	// archival runs
+	AutheliaArchiveIntervalEnvKey = "AUTHELIA_ARCHIVE_INTERVAL"
+
+	// AutheliaArchiveMaxUsersEnvKey is the environment variable key for the maximum number of
+	// users archived per run
+	AutheliaArchiveMaxUsersEnvKey = "AUTHELIA_ARCHIVE_MAX_USERS"
 )
 
 const (
@@ -76,6 +214,35 @@ const (
 	// Auth0LFXOneClientIDEnvKey is the environment variable key for the LFX One Auth0 client ID
 	Auth0LFXOneClientIDEnvKey = "AUTH0_LFX_ONE_CLIENT_ID"
 
+	// Auth0 connection configuration
+	// Auth0DatabaseConnectionEnvKey is the environment variable key for the name of the database
+	// connection passwords are validated and changed on, for tenants that renamed it
+	Auth0DatabaseConnectionEnvKey = "AUTH0_DATABASE_CONNECTION"
+
+	// Auth0CanonicalConnectionsEnvKey is the environment variable key for the comma-separated connections
+	// whose identities are treated as a user's canonical username (e.g. the database plus a SAML connection)
+	Auth0CanonicalConnectionsEnvKey = "AUTH0_CANONICAL_CONNECTIONS"
+
+	// Auth0SocialConnectionsEnvKey is the environment variable key for the comma-separated social connections
+	// that supply the username of users without a canonical identity, in priority order
+	Auth0SocialConnectionsEnvKey = "AUTH0_SOCIAL_CONNECTIONS"
+
+	// Auth0SocialUsernameAttributeEnvKey is the environment variable key for what a social identity supplies
+	// as the username: sub, nickname or email
+	Auth0SocialUsernameAttributeEnvKey = "AUTH0_SOCIAL_USERNAME_ATTRIBUTE"
+
+	// Auth0StaticCacheRolesTTLEnvKey is the environment variable key for how long the roles of the
+	// tenant are cached; 0 disables caching them
+	Auth0StaticCacheRolesTTLEnvKey = "AUTH0_STATIC_CACHE_ROLES_TTL"
+
+	// Auth0StaticCacheConnectionsTTLEnvKey is the environment variable key for how long the
+	// connections of the tenant are cached; 0 disables caching them
+	Auth0StaticCacheConnectionsTTLEnvKey = "AUTH0_STATIC_CACHE_CONNECTIONS_TTL"
+
+	// Auth0StaticCacheOrganizationsTTLEnvKey is the environment variable key for how long the
+	// organizations of the tenant are cached; 0 disables caching them
+	Auth0StaticCacheOrganizationsTTLEnvKey = "AUTH0_STATIC_CACHE_ORGANIZATIONS_TTL"
+
 	// AliasReservedExtraEnvKey is a comma-separated list of additional reserved
 	// alias local parts that should be rejected by add_alias on top of the
 	// built-in list. Useful for ops to lock down branding-sensitive names without
@@ -91,6 +258,28 @@ const (
 	AllowedAliasDomainsEnvKey = "ALLOWED_ALIAS_DOMAINS"
 )
 
+const (
+	// EmailLookupRequireVerifiedEnvKey restricts email_to_username and email_to_sub
+	// to verified addresses; an account matched only by an unverified email is
+	// reported as not found.
+	EmailLookupRequireVerifiedEnvKey = "EMAIL_LOOKUP_REQUIRE_VERIFIED"
+
+	// EmailLookupBatchMaxSizeEnvKey is the maximum number of emails accepted by
+	// a single email_to_username.batch request
+	EmailLookupBatchMaxSizeEnvKey = "EMAIL_LOOKUP_BATCH_MAX_SIZE"
+
+	// EmailLookupBatchConcurrencyEnvKey is the number of emails of a batch
+	// resolved concurrently
+	EmailLookupBatchConcurrencyEnvKey = "EMAIL_LOOKUP_BATCH_CONCURRENCY"
+)
+
+const (
+	// NormalizeGmailCanonicalEnvKey folds dots and +tags in the local part of
+	// gmail.com and googlemail.com addresses when building email index keys.
+	// Changing it requires re-indexing existing lookup keys (-reindex-lookups).
+	NormalizeGmailCanonicalEnvKey = "NORMALIZE_GMAIL_CANONICAL"
+)
+
 const (
 	// Email/SMTP configuration (generic for any SMTP provider: Mailpit, SendGrid, AWS SES, etc.)
 	// EmailSMTPHostEnvKey is the environment variable key for the SMTP server host
@@ -111,3 +300,754 @@ const (
 	// EmailSMTPPasswordEnvKey is the environment variable key for SMTP password
 	EmailSMTPPasswordEnvKey = "EMAIL_SMTP_PASSWORD"
 )
+
+const (
+	// Event sink configuration
+	// EventSinksEnvKey is a comma-separated list of sinks user lifecycle events
+	// are published to. Supported values are "nats" and "kafka"; defaults to "nats".
+	EventSinksEnvKey = "EVENT_SINKS"
+
+	// EventSinkNATS is the value for the NATS event sink
+	EventSinkNATS = "nats"
+
+	// EventSinkKafka is the value for the Kafka event sink
+	EventSinkKafka = "kafka"
+
+	// KafkaBrokersEnvKey is the environment variable key for the comma-separated Kafka bootstrap brokers
+	KafkaBrokersEnvKey = "KAFKA_BROKERS"
+
+	// KafkaTopicEnvKey is the environment variable key for the Kafka topic lifecycle events are written to
+	KafkaTopicEnvKey = "KAFKA_TOPIC"
+
+	// KafkaSASLMechanismEnvKey is the environment variable key for the Kafka SASL mechanism
+	// (plain, scram-sha-256 or scram-sha-512). Empty disables SASL.
+	KafkaSASLMechanismEnvKey = "KAFKA_SASL_MECHANISM"
+
+	// KafkaSASLUsernameEnvKey is the environment variable key for the Kafka SASL username
+	KafkaSASLUsernameEnvKey = "KAFKA_SASL_USERNAME"
+
+	// KafkaSASLPasswordEnvKey is the environment variable key for the Kafka SASL password
+	KafkaSASLPasswordEnvKey = "KAFKA_SASL_PASSWORD"
+
+	// KafkaTLSEnabledEnvKey is the environment variable key to enable TLS for Kafka connections
+	KafkaTLSEnabledEnvKey = "KAFKA_TLS_ENABLED"
+
+	// KafkaTLSCAFileEnvKey is the environment variable key for an optional PEM CA bundle for Kafka TLS
+	KafkaTLSCAFileEnvKey = "KAFKA_TLS_CA_FILE"
+
+	// KafkaBatchTimeoutEnvKey is the environment variable key for how long an event waits for others
+	// to share its Kafka batch
+	KafkaBatchTimeoutEnvKey = "KAFKA_BATCH_TIMEOUT"
+)
+
+const (
+	// Auth0 Log Streaming configuration
+	// Auth0LogStreamTokenEnvKey is the environment variable key for the shared secret Auth0 sends in the
+	// Authorization header of Log Streaming webhook deliveries. Setting it enables the webhook endpoint.
+	Auth0LogStreamTokenEnvKey = "AUTH0_LOG_STREAM_TOKEN"
+
+	// Auth0LogStreamPath is the HTTP path the Log Streaming webhook is served on
+	Auth0LogStreamPath = "/webhooks/auth0/logs"
+)
+
+const (
+	// API document configuration
+	// APISpecEnabledEnvKey is the environment variable key to serve the AsyncAPI and OpenAPI documents
+	APISpecEnabledEnvKey = "API_SPEC_ENABLED"
+
+	// APISpecPath is the HTTP path the API documents are served on
+	APISpecPath = "/spec"
+)
+
+const (
+	// Job lock configuration
+	// JobLockEnabledEnvKey is the environment variable key for running the scheduled dormant account,
+	// duplicate account, canary probe and user archival jobs on one replica at a time, leased in the
+	// job lock KV bucket
+	JobLockEnabledEnvKey = "JOB_LOCK_ENABLED"
+)
+
+const (
+	// Dormant account job configuration
+	// DormantAccountsJobEnabledEnvKey is the environment variable key to enable the dormant account scan
+	DormantAccountsJobEnabledEnvKey = "DORMANT_ACCOUNTS_JOB_ENABLED"
+
+	// DormantAccountsThresholdDaysEnvKey is the environment variable key for the inactivity threshold, in days
+	DormantAccountsThresholdDaysEnvKey = "DORMANT_ACCOUNTS_THRESHOLD_DAYS"
+
+	// DormantAccountsScanIntervalEnvKey is the environment variable key for the interval between scans
+	DormantAccountsScanIntervalEnvKey = "DORMANT_ACCOUNTS_SCAN_INTERVAL"
+
+	// DormantAccountsMaxUsersEnvKey is the environment variable key for the maximum users reported per scan
+	DormantAccountsMaxUsersEnvKey = "DORMANT_ACCOUNTS_MAX_USERS"
+
+	// DormantAccountsTagUsersEnvKey is the environment variable key to tag dormant users' app_metadata
+	DormantAccountsTagUsersEnvKey = "DORMANT_ACCOUNTS_TAG_USERS"
+)
+
+const (
+	// User cache configuration
+	// UserCacheTTLEnvKey is the environment variable key for the user cache TTL. Unset or 0 disables the cache.
+	UserCacheTTLEnvKey = "USER_CACHE_TTL"
+
+	// UserCacheMaxEntriesEnvKey is the environment variable key for the maximum number of cached users
+	UserCacheMaxEntriesEnvKey = "USER_CACHE_MAX_ENTRIES"
+
+	// UserCacheStaleTTLEnvKey is the environment variable key for how long past expiry a cached
+	// user is still served, marked stale, while it is refreshed. Unset or 0 disables it.
+	UserCacheStaleTTLEnvKey = "USER_CACHE_STALE_TTL"
+
+	// UserCacheReconcileIntervalEnvKey is the environment variable key for the interval between
+	// cache reconciliation runs. 0 disables reconciliation.
+	UserCacheReconcileIntervalEnvKey = "USER_CACHE_RECONCILE_INTERVAL"
+
+	// UserCacheReconcileSampleRateEnvKey is the environment variable key for the fraction of
+	// cached users re-fetched from the provider on each reconciliation run
+	UserCacheReconcileSampleRateEnvKey = "USER_CACHE_RECONCILE_SAMPLE_RATE"
+
+	// UserCacheReconcileMaxSamplesEnvKey is the environment variable key for the maximum number
+	// of users re-fetched per reconciliation run
+	UserCacheReconcileMaxSamplesEnvKey = "USER_CACHE_RECONCILE_MAX_SAMPLES"
+
+	// UserCacheBroadcastInvalidationsEnvKey is the environment variable key for broadcasting
+	// the users written through the cache to the other replicas (default: true)
+	UserCacheBroadcastInvalidationsEnvKey = "USER_CACHE_BROADCAST_INVALIDATIONS"
+)
+
+const (
+	// Warm-up configuration
+	// WarmUpEnabledEnvKey is the environment variable key for loading the provider token, signing
+	// keys and hot users at startup instead of on first use (default: true)
+	WarmUpEnabledEnvKey = "WARMUP_ENABLED"
+
+	// WarmUpTimeoutEnvKey is the environment variable key for how long the warm-up may take
+	WarmUpTimeoutEnvKey = "WARMUP_TIMEOUT"
+
+	// WarmUpHotUsersEnvKey is the environment variable key for the number of the users read the
+	// most saved to the cache warm-up KV bucket and preloaded at startup. 0 disables it.
+	WarmUpHotUsersEnvKey = "WARMUP_HOT_USERS"
+
+	// WarmUpHotUsersSaveIntervalEnvKey is the environment variable key for the interval between
+	// saves of the hot user list
+	WarmUpHotUsersSaveIntervalEnvKey = "WARMUP_HOT_USERS_SAVE_INTERVAL"
+)
+
+const (
+	// Hedged reads configuration
+	// HedgedReadsEnabledEnvKey is the environment variable key for enabling hedged provider reads
+	HedgedReadsEnabledEnvKey = "HEDGED_READS_ENABLED"
+
+	// HedgedReadsPercentileEnvKey is the environment variable key for the percentile of recent
+	// read latencies after which a read is hedged
+	HedgedReadsPercentileEnvKey = "HEDGED_READS_PERCENTILE"
+
+	// HedgedReadsMinDelayEnvKey is the environment variable key for the shortest wait before a read is hedged
+	HedgedReadsMinDelayEnvKey = "HEDGED_READS_MIN_DELAY"
+
+	// HedgedReadsMaxPerSecondEnvKey is the environment variable key for the maximum hedges sent per second
+	HedgedReadsMaxPerSecondEnvKey = "HEDGED_READS_MAX_PER_SECOND"
+)
+
+const (
+	// Identifier cache configuration
+	// IdentifierCacheTTLEnvKey is the environment variable key for how long username_to_sub and
+	// sub_to_username results are cached. 0 disables the cache.
+	IdentifierCacheTTLEnvKey = "IDENTIFIER_CACHE_TTL"
+
+	// IdentifierCacheMaxEntriesEnvKey is the environment variable key for the maximum number of
+	// cached mappings per direction
+	IdentifierCacheMaxEntriesEnvKey = "IDENTIFIER_CACHE_MAX_ENTRIES"
+
+	// IdentifierCacheSnapshotEnvKey is the environment variable key for where the identifier cache
+	// is snapshotted to be restored at startup: kv, file, or unset to keep it in memory only
+	IdentifierCacheSnapshotEnvKey = "IDENTIFIER_CACHE_SNAPSHOT"
+
+	// IdentifierCacheSnapshotKV is the value snapshotting the identifier cache to the cache warm-up
+	// KV bucket
+	IdentifierCacheSnapshotKV = "kv"
+
+	// IdentifierCacheSnapshotFile is the value snapshotting the identifier cache to a local file
+	IdentifierCacheSnapshotFile = "file"
+
+	// IdentifierCacheSnapshotPathEnvKey is the environment variable key for the file of the
+	// identifier cache snapshot
+	IdentifierCacheSnapshotPathEnvKey = "IDENTIFIER_CACHE_SNAPSHOT_PATH"
+
+	// IdentifierCacheSnapshotIntervalEnvKey is the environment variable key for the interval between
+	// snapshots of the identifier cache
+	IdentifierCacheSnapshotIntervalEnvKey = "IDENTIFIER_CACHE_SNAPSHOT_INTERVAL"
+
+	// IdentifierCacheSnapshotMaxEntriesEnvKey is the environment variable key for the maximum number
+	// of mappings in a snapshot of the identifier cache
+	IdentifierCacheSnapshotMaxEntriesEnvKey = "IDENTIFIER_CACHE_SNAPSHOT_MAX_ENTRIES"
+)
+
+const (
+	// Typeahead index configuration
+	// TypeaheadIndexEnabledEnvKey is the environment variable key to build the in-memory
+	// index behind users.search in typeahead mode
+	TypeaheadIndexEnabledEnvKey = "TYPEAHEAD_INDEX_ENABLED"
+
+	// TypeaheadIndexRefreshIntervalEnvKey is the environment variable key for the interval
+	// between rebuilds of the typeahead index
+	TypeaheadIndexRefreshIntervalEnvKey = "TYPEAHEAD_INDEX_REFRESH_INTERVAL"
+)
+
+const (
+	// Permission cache configuration
+	// PermissionCacheTTLEnvKey is the environment variable key for how long a user's roles and
+	// permissions are cached for has_permission checks. 0 disables the cache.
+	PermissionCacheTTLEnvKey = "PERMISSION_CACHE_TTL"
+
+	// PermissionCacheMaxEntriesEnvKey is the environment variable key for the maximum number of
+	// users whose permissions are cached
+	PermissionCacheMaxEntriesEnvKey = "PERMISSION_CACHE_MAX_ENTRIES"
+)
+
+const (
+	// Service account configuration
+	// ServiceAccountAllowedAudiencesEnvKey is the environment variable key for a comma-separated
+	// list of the APIs service accounts may be granted access to. Empty allows none; the Auth0
+	// Management API is never allowed.
+	ServiceAccountAllowedAudiencesEnvKey = "SERVICE_ACCOUNT_ALLOWED_AUDIENCES"
+)
+
+const (
+	// Personal access token configuration
+	// PersonalAccessTokensEnabledEnvKey is the environment variable key for enabling personal
+	// access tokens. They need the personal access token KV bucket.
+	PersonalAccessTokensEnabledEnvKey = "PERSONAL_ACCESS_TOKENS_ENABLED"
+
+	// PersonalAccessTokenMaxLifetimeEnvKey is the environment variable key for the longest
+	// lifetime a personal access token can be created with
+	PersonalAccessTokenMaxLifetimeEnvKey = "PERSONAL_ACCESS_TOKEN_MAX_LIFETIME"
+
+	// PersonalAccessTokenAllowedScopesEnvKey is the environment variable key for a comma-separated
+	// list of the scopes personal access tokens may carry, of those the caller's token holds.
+	// Empty allows none, so no token can be created.
+	PersonalAccessTokenAllowedScopesEnvKey = "PERSONAL_ACCESS_TOKEN_ALLOWED_SCOPES"
+
+	// DefaultPersonalAccessTokenMaxLifetime is the default longest lifetime of a personal access token
+	DefaultPersonalAccessTokenMaxLifetime = 365 * 24 * time.Hour
+)
+
+const (
+	// Token revocation configuration
+	// TokenRevocationEnabledEnvKey is the environment variable key for enabling the access token
+	// revocation list. It needs the token revocation KV bucket.
+	TokenRevocationEnabledEnvKey = "TOKEN_REVOCATION_ENABLED"
+
+	// TokenRevocationTTLEnvKey is the environment variable key for how long revocations last when
+	// the expiry of the revoked tokens is unknown. It must outlive the access tokens.
+	TokenRevocationTTLEnvKey = "TOKEN_REVOCATION_TTL"
+)
+
+const (
+	// Shadow reads configuration
+	// ShadowEnvPrefix starts the variables configuring the secondary provider of shadow reads,
+	// e.g. SHADOW__USER_REPOSITORY_TYPE. Shadow reads are enabled when that one is set.
+	ShadowEnvPrefix = "SHADOW__"
+
+	// ShadowReadsSampleRateEnvKey is the environment variable key for the fraction of reads
+	// replayed against the secondary provider
+	ShadowReadsSampleRateEnvKey = "SHADOW_READS_SAMPLE_RATE"
+
+	// ShadowReadsMaxInFlightEnvKey is the environment variable key for the maximum replays
+	// running at once; reads beyond it are not replayed
+	ShadowReadsMaxInFlightEnvKey = "SHADOW_READS_MAX_IN_FLIGHT"
+
+	// ShadowReadsTimeoutEnvKey is the environment variable key for how long a replay may take
+	ShadowReadsTimeoutEnvKey = "SHADOW_READS_TIMEOUT"
+
+	// ProviderOverrideCallersEnvKey is the environment variable key for the comma-separated caller
+	// prefixes allowed to pin their reads to a provider with the Lfx-Provider header
+	ProviderOverrideCallersEnvKey = "PROVIDER_OVERRIDE_CALLERS"
+)
+
+const (
+	// Feature flag configuration
+	// FeatureFlagsFileEnvKey is the environment variable key for a YAML or JSON file of feature
+	// flags, read at startup
+	FeatureFlagsFileEnvKey = "FEATURE_FLAGS_FILE"
+
+	// FeatureFlagsKVEnabledEnvKey is the environment variable key for following the feature flag
+	// KV bucket, whose flags override the file's. It needs the feature flag KV bucket.
+	FeatureFlagsKVEnabledEnvKey = "FEATURE_FLAGS_KV_ENABLED"
+)
+
+const (
+	// NATS reply compression configuration
+	// NATSCompressionThresholdEnvKey is the environment variable key for the reply size in bytes
+	// above which replies are compressed for callers sending Accept-Encoding; 0 disables it
+	NATSCompressionThresholdEnvKey = "NATS_COMPRESSION_THRESHOLD"
+)
+
+const (
+	// Multi-tenancy configuration
+	// TenantsEnvKey is the environment variable key for the comma-separated tenants served by the
+	// process. Unset serves a single tenant on the un-namespaced subjects.
+	TenantsEnvKey = "TENANTS"
+
+	// TenantEnvPrefix starts the environment variables overriding a setting for one tenant, as
+	// TENANT_<NAME>__<KEY>, with the tenant name upper-cased and dashes replaced by underscores
+	TenantEnvPrefix = "TENANT_"
+)
+
+const (
+	// NATS request processing configuration
+	// NATSWorkersEnvKey is the environment variable key for the number of requests processed at
+	// once, split between the priority classes; 0 processes each subject's requests one at a time
+	NATSWorkersEnvKey = "NATS_WORKERS"
+
+	// NATSBatchWorkerShareEnvKey is the environment variable key for the fraction of the workers
+	// reserved for batch requests
+	NATSBatchWorkerShareEnvKey = "NATS_BATCH_WORKER_SHARE"
+)
+
+const (
+	// Startup configuration
+	// StartupConfigValidationEnvKey is the environment variable key for checking the configuration
+	// against NATS and the identity provider before serving; the service exits when a check fails
+	StartupConfigValidationEnvKey = "STARTUP_CONFIG_VALIDATION"
+)
+
+const (
+	// DPoP configuration
+	// DPoPEnabledEnvKey is the environment variable key for checking the DPoP proofs of
+	// sender-constrained access tokens
+	DPoPEnabledEnvKey = "DPOP_ENABLED"
+
+	// DPoPRequiredEnvKey is the environment variable key for rejecting bearer tokens, accepting
+	// DPoP-bound access tokens only
+	DPoPRequiredEnvKey = "DPOP_REQUIRED"
+
+	// DPoPProofWindowEnvKey is the environment variable key for how far the iat of a proof may
+	// be from now; proof ids are remembered for this long on either side
+	DPoPProofWindowEnvKey = "DPOP_PROOF_WINDOW"
+
+	// DPoPReplayCacheMaxEntriesEnvKey is the environment variable key for the maximum number of
+	// proof ids remembered to reject replays
+	DPoPReplayCacheMaxEntriesEnvKey = "DPOP_REPLAY_CACHE_MAX_ENTRIES"
+)
+
+const (
+	// Step-up authentication configuration
+	// StepUpMaxAgeEnvKey is the environment variable key for how recent a sign-in must be
+	// for sensitive operations
+	StepUpMaxAgeEnvKey = "STEP_UP_MAX_AGE"
+
+	// StepUpAcceptedFactorsEnvKey is the environment variable key for the comma-separated
+	// amr values that count as a second factor
+	StepUpAcceptedFactorsEnvKey = "STEP_UP_ACCEPTED_FACTORS"
+)
+
+const (
+	// Self-test configuration
+	// SelfTestCanaryUserEnvKey is the environment variable key for the username or sub of the
+	// dedicated test user admin.selftest looks up
+	SelfTestCanaryUserEnvKey = "SELFTEST_CANARY_USER"
+)
+
+const (
+	// Canary probe configuration
+	// CanaryProbeEnabledEnvKey is the environment variable key for scheduling the canary user probe
+	CanaryProbeEnabledEnvKey = "CANARY_PROBE_ENABLED"
+
+	// CanaryProbeUserEnvKey is the environment variable key for the username or sub of the synthetic
+	// account probed. It defaults to the self-test canary user.
+	CanaryProbeUserEnvKey = "CANARY_PROBE_USER"
+
+	// CanaryProbeTokenFileEnvKey is the environment variable key for a file holding an access token
+	// of the canary user, allowed to update its metadata. Without it only reads are probed.
+	CanaryProbeTokenFileEnvKey = "CANARY_PROBE_TOKEN_FILE"
+
+	// CanaryProbeIntervalEnvKey is the environment variable key for the interval between probes
+	CanaryProbeIntervalEnvKey = "CANARY_PROBE_INTERVAL"
+
+	// CanaryProbeTimeoutEnvKey is the environment variable key bounding each probe
+	CanaryProbeTimeoutEnvKey = "CANARY_PROBE_TIMEOUT"
+)
+
+const (
+	// Request logging configuration
+	// RequestLogSampleRateEnvKey is the environment variable key for the fraction of successful
+	// read requests that are logged. Errors and writes are always logged.
+	RequestLogSampleRateEnvKey = "REQUEST_LOG_SAMPLE_RATE"
+
+	// RequestLogSampleRatesEnvKey is the environment variable key for per-subject overrides,
+	// as comma-separated subject=rate pairs. Overrides apply to successful requests only.
+	RequestLogSampleRatesEnvKey = "REQUEST_LOG_SAMPLE_RATES"
+)
+
+const (
+	// Redaction configuration
+	// RedactionPolicyEnvKey is the environment variable key for per-field redaction modes, as
+	// comma-separated field=mode pairs (fields: identifier, email, token; modes: mask, full, hash, none)
+	RedactionPolicyEnvKey = "REDACTION_POLICY"
+
+	// RedactionHashSaltEnvKey is the environment variable key for the salt used by the hash redaction mode
+	RedactionHashSaltEnvKey = "REDACTION_HASH_SALT"
+
+	// RedactionRelaxedEnvKey is the environment variable key to disable redaction entirely.
+	// Only honored by binaries built with the redaction_dev tag.
+	RedactionRelaxedEnvKey = "REDACTION_RELAXED"
+)
+
+const (
+	// KV encryption configuration (Authelia backend)
+	// KVEncryptionKeysEnvKey is the environment variable key for the key-encryption keys, as
+	// comma-separated id:base64key pairs of 32-byte AES keys. Unset stores KV values in plaintext.
+	KVEncryptionKeysEnvKey = "KV_ENCRYPTION_KEYS"
+
+	// KVEncryptionActiveKeyIDEnvKey is the environment variable key for the ID of the key new values are encrypted with
+	KVEncryptionActiveKeyIDEnvKey = "KV_ENCRYPTION_ACTIVE_KEY_ID"
+
+	// KVEncryptionKeySourceEnvKey is the environment variable key for where key-encryption keys come from:
+	// "static" (KV_ENCRYPTION_KEYS, the default when set) or "kms" (KMS_PROVIDER)
+	KVEncryptionKeySourceEnvKey = "KV_ENCRYPTION_KEY_SOURCE"
+
+	// KVEncryptionKeySourceStatic is the value for keys supplied in KV_ENCRYPTION_KEYS
+	KVEncryptionKeySourceStatic = "static"
+
+	// KVEncryptionKeySourceKMS is the value for data keys wrapped by the configured KMS key
+	KVEncryptionKeySourceKMS = "kms"
+
+	// KVEncryptionDataKeyTTLEnvKey is the environment variable key for how long a KMS-wrapped data key is reused
+	KVEncryptionDataKeyTTLEnvKey = "KV_ENCRYPTION_DATA_KEY_TTL"
+
+	// KVEncryptionDataKeyMaxUsesEnvKey is the environment variable key for how many values a KMS-wrapped data key encrypts
+	KVEncryptionDataKeyMaxUsesEnvKey = "KV_ENCRYPTION_DATA_KEY_MAX_USES"
+
+	// KVEncryptionReencryptIntervalEnvKey is the environment variable key for the interval between
+	// re-encryption passes over the users bucket. 0 disables the background job.
+	KVEncryptionReencryptIntervalEnvKey = "KV_ENCRYPTION_REENCRYPT_INTERVAL"
+)
+
+const (
+	// Key management configuration
+	// KMSProviderEnvKey is the environment variable key for the cloud KMS provider ("aws" or "gcp").
+	// Unset means no KMS: KV encryption can only use static keys and secrets must be plaintext.
+	KMSProviderEnvKey = "KMS_PROVIDER"
+
+	// KMSProviderAWS is the value for AWS KMS
+	KMSProviderAWS = "aws"
+
+	// KMSProviderGCP is the value for Google Cloud KMS
+	KMSProviderGCP = "gcp"
+
+	// KMSKeyIDEnvKey is the environment variable key for the KMS key: an AWS key ID, ARN or alias,
+	// or a GCP crypto key resource name
+	KMSKeyIDEnvKey = "KMS_KEY_ID"
+)
+
+const (
+	// HTTP listener TLS configuration
+	// HTTPTLSCertFileEnvKey is the environment variable key for the HTTP server certificate. Setting it
+	// (with HTTP_TLS_KEY_FILE) serves the health, metrics and webhook endpoints over TLS.
+	HTTPTLSCertFileEnvKey = "HTTP_TLS_CERT_FILE"
+
+	// HTTPTLSKeyFileEnvKey is the environment variable key for the HTTP server certificate's private key
+	HTTPTLSKeyFileEnvKey = "HTTP_TLS_KEY_FILE"
+
+	// HTTPTLSClientCAFileEnvKey is the environment variable key for the PEM CA bundle client certificates
+	// must chain to. Setting it enables mTLS.
+	HTTPTLSClientCAFileEnvKey = "HTTP_TLS_CLIENT_CA_FILE"
+
+	// HTTPTLSClientAuthEnvKey is the environment variable key for the client certificate policy:
+	// "require" (default), "require_except_probes" or "none"
+	HTTPTLSClientAuthEnvKey = "HTTP_TLS_CLIENT_AUTH"
+)
+
+const (
+	// Outbound HTTP client configuration (Auth0, Authelia and KMS calls)
+	// HTTPClientCAFilesEnvKey is the environment variable key for comma-separated PEM CA bundles
+	// trusted in addition to the system roots, e.g. for a TLS-intercepting egress proxy.
+	HTTPClientCAFilesEnvKey = "HTTP_CLIENT_CA_FILES"
+
+	// HTTPClientInsecureSkipVerifyEnvKey is the environment variable key for disabling server
+	// certificate verification on outbound calls. Development only.
+	HTTPClientInsecureSkipVerifyEnvKey = "HTTP_CLIENT_INSECURE_SKIP_VERIFY"
+
+	// HTTPClientProxyURLEnvKey is the environment variable key for an explicit outbound proxy.
+	// Unset honors HTTP_PROXY, HTTPS_PROXY and NO_PROXY; "direct" disables proxying.
+	HTTPClientProxyURLEnvKey = "HTTP_CLIENT_PROXY_URL"
+
+	// HTTPClientHostOverridesEnvKey is the environment variable key for per-host overrides, as a JSON
+	// object keyed by host name with ca_files, insecure_skip_verify, proxy_url and server_name fields
+	HTTPClientHostOverridesEnvKey = "HTTP_CLIENT_HOST_OVERRIDES"
+
+	// HTTPClientMaxIdleConnsPerHostEnvKey is the environment variable key for the idle keep-alive
+	// connections kept per host (default 32)
+	HTTPClientMaxIdleConnsPerHostEnvKey = "HTTP_CLIENT_MAX_IDLE_CONNS_PER_HOST"
+
+	// HTTPClientMaxConnsPerHostEnvKey is the environment variable key for the cap on connections per host.
+	// Unset is unlimited.
+	HTTPClientMaxConnsPerHostEnvKey = "HTTP_CLIENT_MAX_CONNS_PER_HOST"
+
+	// HTTPClientIdleConnTimeoutEnvKey is the environment variable key for how long an idle
+	// keep-alive connection is kept open (default 90s)
+	HTTPClientIdleConnTimeoutEnvKey = "HTTP_CLIENT_IDLE_CONN_TIMEOUT"
+
+	// HTTPClientDisableHTTP2EnvKey is the environment variable key for forcing HTTP/1.1 on outbound calls
+	HTTPClientDisableHTTP2EnvKey = "HTTP_CLIENT_DISABLE_HTTP2"
+)
+
+const (
+	// Provider operation timeouts; unset keeps the default of the provider
+	// ProviderTimeoutSearchEnvKey is the environment variable key for how long a user search may take
+	ProviderTimeoutSearchEnvKey = "PROVIDER_TIMEOUT_SEARCH"
+
+	// ProviderTimeoutGetEnvKey is the environment variable key for how long reading a user may take
+	ProviderTimeoutGetEnvKey = "PROVIDER_TIMEOUT_GET"
+
+	// ProviderTimeoutUpdateEnvKey is the environment variable key for how long a write, e.g. a
+	// profile update or an identity link, may take
+	ProviderTimeoutUpdateEnvKey = "PROVIDER_TIMEOUT_UPDATE"
+
+	// ProviderTimeoutJobsEnvKey is the environment variable key for how long a bulk job, e.g. a
+	// users export or import, may take
+	ProviderTimeoutJobsEnvKey = "PROVIDER_TIMEOUT_JOBS"
+)
+
+const (
+	// Panic quarantine configuration
+	// PanicQuarantineEnabledEnvKey is the environment variable key for quarantining payloads that
+	// repeatedly panic their handler. It needs the quarantine KV bucket.
+	PanicQuarantineEnabledEnvKey = "PANIC_QUARANTINE_ENABLED"
+
+	// PanicQuarantineThresholdEnvKey is the environment variable key for how many panics of the
+	// same payload quarantine it (default 3)
+	PanicQuarantineThresholdEnvKey = "PANIC_QUARANTINE_THRESHOLD"
+)
+
+const (
+	// Security event detection configuration
+	// SecurityDetectionEnabledEnvKey is the environment variable key for counting the requests of
+	// each caller matching the detection rules and raising security events (default true)
+	SecurityDetectionEnabledEnvKey = "SECURITY_DETECTION_ENABLED"
+
+	// SecurityDetectionWindowEnvKey is the environment variable key for the window the matching
+	// requests of a caller are counted in (default 5m)
+	SecurityDetectionWindowEnvKey = "SECURITY_DETECTION_WINDOW"
+
+	// SecurityInvalidTokenThresholdEnvKey is the environment variable key for how many requests
+	// with a token that can't be verified raise an event (default 10, 0 disables the rule)
+	SecurityInvalidTokenThresholdEnvKey = "SECURITY_INVALID_TOKEN_THRESHOLD"
+
+	// SecurityScopeEscalationThresholdEnvKey is the environment variable key for how many requests
+	// missing a required scope raise an event (default 3, 0 disables the rule)
+	SecurityScopeEscalationThresholdEnvKey = "SECURITY_SCOPE_ESCALATION_THRESHOLD"
+
+	// SecurityEmailEnumerationThresholdEnvKey is the environment variable key for how many emails
+	// resolved to accounts raise an event (default 500, 0 disables the rule)
+	SecurityEmailEnumerationThresholdEnvKey = "SECURITY_EMAIL_ENUMERATION_THRESHOLD"
+)
+
+const (
+	// Enumeration throttle configuration
+	// EnumerationThrottleEnabledEnvKey is the environment variable key for delaying, then blocking,
+	// the email and username lookups of callers looking up many unknown accounts (default true)
+	EnumerationThrottleEnabledEnvKey = "ENUMERATION_THROTTLE_ENABLED"
+
+	// EnumerationThrottleWindowEnvKey is the environment variable key for the window the unknown
+	// accounts looked up by a caller are counted in (default 10m)
+	EnumerationThrottleWindowEnvKey = "ENUMERATION_THROTTLE_WINDOW"
+
+	// EnumerationThrottleDelayAfterEnvKey is the environment variable key for how many distinct
+	// unknown accounts delay the further lookups of a caller (default 10, 0 never delays)
+	EnumerationThrottleDelayAfterEnvKey = "ENUMERATION_THROTTLE_DELAY_AFTER"
+
+	// EnumerationThrottleMaxDelayEnvKey is the environment variable key for the longest delay of a
+	// lookup; delays start at 100ms and double with every further unknown account (default 5s)
+	EnumerationThrottleMaxDelayEnvKey = "ENUMERATION_THROTTLE_MAX_DELAY"
+
+	// EnumerationThrottleBlockAfterEnvKey is the environment variable key for how many distinct
+	// unknown accounts block the lookups of a caller (default 50, 0 never blocks)
+	EnumerationThrottleBlockAfterEnvKey = "ENUMERATION_THROTTLE_BLOCK_AFTER"
+
+	// EnumerationThrottleBlockDurationEnvKey is the environment variable key for how long the
+	// lookups of a blocked caller are refused (default 15m)
+	EnumerationThrottleBlockDurationEnvKey = "ENUMERATION_THROTTLE_BLOCK_DURATION"
+)
+
+const (
+	// Request schema configuration
+	// RequestSchemaValidationEnabledEnvKey is the environment variable key for validating request
+	// payloads against the schemas of their subjects before they are handled (default true)
+	RequestSchemaValidationEnabledEnvKey = "REQUEST_SCHEMA_VALIDATION_ENABLED"
+)
+
+const (
+	// Idempotency configuration
+	// IdempotencyEnabledEnvKey is the environment variable key for storing the replies of mutations
+	// sent with an idempotency key and replaying them to retries. It needs the idempotency KV bucket.
+	IdempotencyEnabledEnvKey = "IDEMPOTENCY_ENABLED"
+)
+
+const (
+	// MaintenanceReadOnlyEnvKey is the environment variable key for starting in read-only mode,
+	// refusing writes with read_only_mode while reads keep working
+	MaintenanceReadOnlyEnvKey = "MAINTENANCE_READ_ONLY"
+
+	// MaintenanceReasonEnvKey is the environment variable key for the reason reported while in
+	// read-only mode, e.g. a migration window
+	MaintenanceReasonEnvKey = "MAINTENANCE_REASON"
+
+	// MaintenanceKVEnabledEnvKey is the environment variable key for keeping the maintenance mode in
+	// the maintenance KV bucket, so replicas started after a switch come up in it
+	MaintenanceKVEnabledEnvKey = "MAINTENANCE_KV_ENABLED"
+)
+
+const (
+	// Event outbox configuration
+	// OutboxEnabledEnvKey is the environment variable key for storing user events in the outbox
+	// bucket before publishing them, so they survive a failed publish. It needs the outbox KV bucket.
+	OutboxEnabledEnvKey = "OUTBOX_ENABLED"
+
+	// OutboxDispatchIntervalEnvKey is the environment variable key for how often stored events
+	// that weren't published are retried (default 5s)
+	OutboxDispatchIntervalEnvKey = "OUTBOX_DISPATCH_INTERVAL"
+)
+
+const (
+	// User export configuration
+	// UserExportObjectStoreEnabledEnvKey is the environment variable key for letting bulk user
+	// exports be stored in the user exports object store instead of replied. It needs the bucket.
+	UserExportObjectStoreEnabledEnvKey = "USER_EXPORT_OBJECT_STORE_ENABLED"
+)
+
+const (
+	// Email organization configuration
+	// EmailOrganizationsEnabledEnvKey is the environment variable key for enabling the email domain
+	// to organization mappings. It needs the email organizations KV bucket.
+	EmailOrganizationsEnabledEnvKey = "EMAIL_ORGANIZATIONS_ENABLED"
+
+	// EmailOrganizationsPublicDomainsEnvKey is the environment variable key for comma-separated
+	// public email domains excluded from the mappings on top of the built-in ones
+	EmailOrganizationsPublicDomainsEnvKey = "EMAIL_ORGANIZATIONS_PUBLIC_DOMAINS"
+)
+
+const (
+	// User merge configuration
+	// UserMergeHistoryEnabledEnvKey is the environment variable key for recording user merges in
+	// the user merges KV bucket. It needs the bucket.
+	UserMergeHistoryEnabledEnvKey = "USER_MERGE_HISTORY_ENABLED"
+)
+
+const (
+	// Duplicate account job configuration
+	// DuplicateAccountsJobEnabledEnvKey is the environment variable key to enable the duplicate account scan
+	DuplicateAccountsJobEnabledEnvKey = "DUPLICATE_ACCOUNTS_JOB_ENABLED"
+
+	// DuplicateAccountsScanIntervalEnvKey is the environment variable key for the interval between scans
+	DuplicateAccountsScanIntervalEnvKey = "DUPLICATE_ACCOUNTS_SCAN_INTERVAL"
+
+	// DuplicateAccountsMaxCandidatesEnvKey is the environment variable key for the maximum candidate
+	// pairs reported per scan
+	DuplicateAccountsMaxCandidatesEnvKey = "DUPLICATE_ACCOUNTS_MAX_CANDIDATES"
+
+	// DuplicateAccountsNameSimilarityEnvKey is the environment variable key for the similarity, in [0, 1],
+	// from which two names count as near-identical; 0 turns name matching off
+	DuplicateAccountsNameSimilarityEnvKey = "DUPLICATE_ACCOUNTS_NAME_SIMILARITY"
+
+	// DuplicateAccountsMinMatchingFieldsEnvKey is the environment variable key for how many of the
+	// organization, job title, country and city accounts with similar names must share
+	DuplicateAccountsMinMatchingFieldsEnvKey = "DUPLICATE_ACCOUNTS_MIN_MATCHING_FIELDS"
+
+	// DuplicateAccountsAllowListEnvKey is the environment variable key for comma-separated known false
+	// positives: "sub=sub" pairs never reported together, or subs never reported at all
+	DuplicateAccountsAllowListEnvKey = "DUPLICATE_ACCOUNTS_ALLOW_LIST"
+)
+
+const (
+	// Consent configuration
+	// ConsentEnabledEnvKey is the environment variable key for enabling the policy acceptance
+	// subjects. It needs the user consents KV bucket.
+	ConsentEnabledEnvKey = "CONSENT_ENABLED"
+
+	// ConsentPoliciesEnvKey is the environment variable key for comma-separated policy=version pairs
+	// naming the policies users accept and their current version (e.g. "tos=2025-06,privacy=3")
+	ConsentPoliciesEnvKey = "CONSENT_POLICIES"
+)
+
+const (
+	// Metadata field policy configuration
+	// MetadataFieldPolicyEnvKey is the environment variable key for comma-separated rules restricting
+	// who may update metadata fields, each "field=kind:value|kind:value" with kind scope, permission
+	// or role (e.g. "name=role:lf-staff|scope:write:legal_name")
+	MetadataFieldPolicyEnvKey = "METADATA_FIELD_POLICY"
+)
+
+const (
+	// Caller profile configuration
+	// CallerProfilesEnvKey is the environment variable key for comma-separated rules mapping the inbox
+	// prefix of a caller service to the profile fields it reads of other users, each "prefix=field|field"
+	// (e.g. "_INBOX_meet=zoneinfo|timezone")
+	CallerProfilesEnvKey = "CALLER_PROFILES"
+	// CallerProfileDefaultFieldsEnvKey is the environment variable key for the comma-separated profile
+	// fields of the callers matching no caller profile
+	CallerProfileDefaultFieldsEnvKey = "CALLER_PROFILE_DEFAULT_FIELDS"
+)
+
+const (
+	// Attestation configuration
+	// AttestationEnabledEnvKey is the environment variable key for enabling verified profile fields
+	// and the admin.attestation subjects. It needs the user attestations KV bucket.
+	AttestationEnabledEnvKey = "ATTESTATION_ENABLED"
+
+	// AttestationFieldsEnvKey is the environment variable key for the comma-separated metadata fields
+	// that can be verified
+	AttestationFieldsEnvKey = "ATTESTATION_FIELDS"
+)
+
+const (
+	// Affiliation configuration
+	// AffiliationsEnabledEnvKey is the environment variable key for enabling the employment history
+	// subjects. It needs the user affiliations KV bucket.
+	AffiliationsEnabledEnvKey = "AFFILIATIONS_ENABLED"
+)
+
+const (
+	// Error localization configuration
+	// ErrorLocalizationEnabledEnvKey is the environment variable key for adding the code and the
+	// localized message of the error to unsuccessful replies
+	ErrorLocalizationEnabledEnvKey = "ERROR_LOCALIZATION_ENABLED"
+)
+
+const (
+	// Response envelope configuration
+	// ResponseEnvelopeDefaultVersionEnvKey is the environment variable key for the envelope version
+	// of the replies to requests without the Lfx-Envelope header, 1 or 2
+	ResponseEnvelopeDefaultVersionEnvKey = "RESPONSE_ENVELOPE_DEFAULT_VERSION"
+)
+
+const (
+	// Size limit configuration
+	// RequestMaxPayloadBytesEnvKey is the environment variable key for the largest request payload
+	// accepted, in bytes, 0 for no limit
+	RequestMaxPayloadBytesEnvKey = "REQUEST_MAX_PAYLOAD_BYTES"
+
+	// RequestMaxBatchPayloadBytesEnvKey is the environment variable key for the largest payload of
+	// batch subjects, in bytes, 0 for no limit
+	RequestMaxBatchPayloadBytesEnvKey = "REQUEST_MAX_BATCH_PAYLOAD_BYTES"
+
+	// MetadataMaxBytesEnvKey is the environment variable key for the largest user metadata written
+	// to the provider, in bytes of JSON, 0 for no limit
+	MetadataMaxBytesEnvKey = "METADATA_MAX_BYTES"
+
+	// MetadataMaxFieldBytesEnvKey is the environment variable key for the largest string field of
+	// a user metadata update, in bytes, 0 for no limit
+	MetadataMaxFieldBytesEnvKey = "METADATA_MAX_FIELD_BYTES"
+
+	// DefaultRequestMaxPayloadBytes is the default largest request payload
+	DefaultRequestMaxPayloadBytes = 64 * 1024
+
+	// DefaultRequestMaxBatchPayloadBytes is the default largest payload of batch subjects
+	DefaultRequestMaxBatchPayloadBytes = 1024 * 1024
+
+	// DefaultMetadataMaxBytes is the default largest user metadata, Auth0's cap
+	DefaultMetadataMaxBytes = 16 * 1024
+
+	// DefaultMetadataMaxFieldBytes is the default largest string field of user metadata
+	DefaultMetadataMaxFieldBytes = 1024
+)
@@ -11,6 +11,54 @@ const (
 	// KVBucketNameAutheliaEmailOTP is the name of the KV bucket for authelia email OTPs.
 	KVBucketNameAutheliaEmailOTP = "authelia-email-otp"
 
+	// KVBucketNameAutheliaUsersArchive is the name of the KV bucket inactive authelia users are archived to.
+	KVBucketNameAutheliaUsersArchive = "authelia-users-archive"
+
+	// KVBucketNamePersonalAccessTokens is the name of the KV bucket for personal access tokens.
+	KVBucketNamePersonalAccessTokens = "auth-personal-access-tokens"
+
+	// KVBucketNameTokenRevocations is the name of the KV bucket for revoked access tokens.
+	KVBucketNameTokenRevocations = "auth-token-revocations"
+
+	// KVBucketNameFeatureFlags is the name of the KV bucket for feature flags.
+	KVBucketNameFeatureFlags = "auth-feature-flags"
+
+	// KVBucketNameQuarantine is the name of the KV bucket for quarantined request payloads.
+	KVBucketNameQuarantine = "auth-quarantine"
+
+	// KVBucketNameIdempotency is the name of the KV bucket for the replies of idempotent requests.
+	KVBucketNameIdempotency = "auth-idempotency"
+
+	// KVBucketNameOutbox is the name of the KV bucket for events waiting to be published.
+	KVBucketNameOutbox = "auth-outbox"
+
+	// KVBucketNameEmailOrganizations is the name of the KV bucket for email domain to organization mappings.
+	KVBucketNameEmailOrganizations = "auth-email-organizations"
+
+	// KVBucketNameUserMerges is the name of the KV bucket for the audit history of user merges.
+	KVBucketNameUserMerges = "auth-user-merges"
+
+	// KVBucketNameUserConsents is the name of the KV bucket for the policy versions users accepted.
+	KVBucketNameUserConsents = "auth-user-consents"
+
+	// KVBucketNameUserAttestations is the name of the KV bucket for the verified profile fields of users.
+	KVBucketNameUserAttestations = "auth-user-attestations"
+
+	// KVBucketNameUserAffiliations is the name of the KV bucket for the employment history of users.
+	KVBucketNameUserAffiliations = "auth-user-affiliations"
+
+	// KVBucketNameCacheWarmup is the name of the KV bucket for what replicas preload into their caches at startup.
+	KVBucketNameCacheWarmup = "auth-cache-warmup"
+
+	// KVBucketNameJobLocks is the name of the KV bucket for the leases of the scheduled jobs.
+	KVBucketNameJobLocks = "auth-job-locks"
+
+	// KVBucketNameMaintenance is the name of the KV bucket for the maintenance mode of the replicas.
+	KVBucketNameMaintenance = "auth-maintenance"
+
+	// ObjectStoreNameUserExports is the name of the object store bucket for user exports.
+	ObjectStoreNameUserExports = "auth-user-exports"
+
 	// KVLookupPrefixAuthelia is the prefix for lookup keys in the KV store.
 	KVLookupPrefixAuthelia = "lookup/authelia-users/%s"
 )
@@ -7,6 +7,9 @@ const (
 	// AuthServiceQueue is the queue for the auth service.
 	// The queue is of the form: lfx.auth-service.queue
 	AuthServiceQueue = "lfx.auth-service.queue"
+
+	// SubjectPrefix is the prefix shared by the service's own subjects
+	SubjectPrefix = "lfx.auth-service."
 )
 
 const (
@@ -17,6 +20,10 @@ const (
 	// The subject is of the form: lfx.auth-service.email_to_username
 	UserEmailToUserSubject = "lfx.auth-service.email_to_username"
 
+	// UserEmailToUserBatchSubject is the subject for resolving many emails to usernames at once.
+	// The subject is of the form: lfx.auth-service.email_to_username.batch
+	UserEmailToUserBatchSubject = "lfx.auth-service.email_to_username.batch"
+
 	// UserEmailToSubSubject is the subject for the user email to sub event.
 	// The subject is of the form: lfx.auth-service.email_to_sub
 	UserEmailToSubSubject = "lfx.auth-service.email_to_sub"
@@ -24,6 +31,108 @@ const (
 	// UserUsernameToSubSubject is the subject for the username to sub event.
 	// The subject is of the form: lfx.auth-service.username_to_sub
 	UserUsernameToSubSubject = "lfx.auth-service.username_to_sub"
+
+	// UserSubToUsernameSubject is the subject for the sub to username event.
+	// The subject is of the form: lfx.auth-service.sub_to_username
+	UserSubToUsernameSubject = "lfx.auth-service.sub_to_username"
+
+	// UserSearchSubject is the subject for the privileged free-text user search.
+	// The subject is of the form: lfx.auth-service.users.search
+	UserSearchSubject = "lfx.auth-service.users.search"
+
+	// EmailOrganizationSubject is the subject for the organization an email address, or its
+	// domain, belongs to.
+	// The subject is of the form: lfx.auth-service.email.organization
+	EmailOrganizationSubject = "lfx.auth-service.email.organization"
+)
+
+const (
+
+	// Authorization subjects
+
+	// UserHasPermissionSubject is the subject for checking whether a sub holds a permission or role.
+	// The subject is of the form: lfx.auth-service.user.has_permission
+	UserHasPermissionSubject = "lfx.auth-service.user.has_permission"
+
+	// UserHasPermissionBatchSubject is the subject for evaluating many permission checks at once.
+	// The subject is of the form: lfx.auth-service.user.has_permission.batch
+	UserHasPermissionBatchSubject = "lfx.auth-service.user.has_permission.batch"
+
+	// TokenCheckScopeSubject is the subject for verifying an access token and reporting the scopes it grants.
+	// The subject is of the form: lfx.auth-service.token.check_scope
+	TokenCheckScopeSubject = "lfx.auth-service.token.check_scope"
+
+	// TokenCheckStepUpSubject is the subject for checking whether a token's sign-in is recent and strong enough.
+	// The subject is of the form: lfx.auth-service.token.check_step_up
+	TokenCheckStepUpSubject = "lfx.auth-service.token.check_step_up"
+)
+
+const (
+
+	// Service account subjects
+
+	// ServiceAccountCreateSubject is the subject for creating a service account.
+	// The subject is of the form: lfx.auth-service.service_account.create
+	ServiceAccountCreateSubject = "lfx.auth-service.service_account.create"
+
+	// ServiceAccountListSubject is the subject for listing the service accounts managed by the service.
+	// The subject is of the form: lfx.auth-service.service_account.list
+	ServiceAccountListSubject = "lfx.auth-service.service_account.list"
+
+	// ServiceAccountRotateSubject is the subject for rotating the client secret of a service account.
+	// The subject is of the form: lfx.auth-service.service_account.rotate
+	ServiceAccountRotateSubject = "lfx.auth-service.service_account.rotate"
+)
+
+const (
+
+	// Personal access token subjects
+
+	// PersonalAccessTokenCreateSubject is the subject for minting a personal access token.
+	// The subject is of the form: lfx.auth-service.personal_access_token.create
+	PersonalAccessTokenCreateSubject = "lfx.auth-service.personal_access_token.create"
+
+	// PersonalAccessTokenListSubject is the subject for listing the personal access tokens of a user.
+	// The subject is of the form: lfx.auth-service.personal_access_token.list
+	PersonalAccessTokenListSubject = "lfx.auth-service.personal_access_token.list"
+
+	// PersonalAccessTokenRevokeSubject is the subject for revoking a personal access token.
+	// The subject is of the form: lfx.auth-service.personal_access_token.revoke
+	PersonalAccessTokenRevokeSubject = "lfx.auth-service.personal_access_token.revoke"
+
+	// PersonalAccessTokenValidateSubject is the subject for exchanging a personal access token for the identity of its user.
+	// The subject is of the form: lfx.auth-service.personal_access_token.validate
+	PersonalAccessTokenValidateSubject = "lfx.auth-service.personal_access_token.validate"
+)
+
+const (
+
+	// Consent subjects
+
+	// UserConsentRecordSubject is the subject for recording that a user accepted the current version of a policy.
+	// The subject is of the form: lfx.auth-service.user.consent.record
+	UserConsentRecordSubject = "lfx.auth-service.user.consent.record"
+
+	// UserConsentStatusSubject is the subject for checking whether a user accepted the current versions of the policies.
+	// The subject is of the form: lfx.auth-service.user.consent.status
+	UserConsentStatusSubject = "lfx.auth-service.user.consent.status"
+)
+
+const (
+
+	// Affiliation subjects
+
+	// UserAffiliationsAddSubject is the subject for adding an employer affiliation to the history of a user.
+	// The subject is of the form: lfx.auth-service.user.affiliations.add
+	UserAffiliationsAddSubject = "lfx.auth-service.user.affiliations.add"
+
+	// UserAffiliationsEndSubject is the subject for setting the end date of a current affiliation.
+	// The subject is of the form: lfx.auth-service.user.affiliations.end
+	UserAffiliationsEndSubject = "lfx.auth-service.user.affiliations.end"
+
+	// UserAffiliationsListSubject is the subject for listing the current and past affiliations of a user.
+	// The subject is of the form: lfx.auth-service.user.affiliations.list
+	UserAffiliationsListSubject = "lfx.auth-service.user.affiliations.list"
 )
 
 const (
@@ -33,6 +142,48 @@ const (
 	// UserProfileUpdatedSubject is published after a successful user_metadata update.
 	// Consumers use this to sync profile changes to other systems (e.g. v1 platform DB).
 	UserProfileUpdatedSubject = "lfx.user_profile.updated"
+
+	// LoginEventsSubject is published for every login, MFA and blocked-login
+	// event received from the identity provider's log stream.
+	// The subject is of the form: lfx.auth-service.events.login
+	LoginEventsSubject = "lfx.auth-service.events.login"
+
+	// DormantAccountsReportSubject is published after each dormant account scan
+	// with the users that haven't logged in within the configured threshold.
+	// The subject is of the form: lfx.auth-service.events.dormant_accounts
+	DormantAccountsReportSubject = "lfx.auth-service.events.dormant_accounts"
+
+	// DuplicateAccountsReportSubject is published after each duplicate account
+	// scan with the pairs of accounts likely to belong to one person.
+	// The subject is of the form: lfx.auth-service.events.duplicate_accounts
+	DuplicateAccountsReportSubject = "lfx.auth-service.events.duplicate_accounts"
+
+	// UserMergedSubject is published after a duplicate account was merged into
+	// another one, with the subs of both, so consumers re-point their references.
+	// The subject is of the form: lfx.auth-service.events.user_merged
+	UserMergedSubject = "lfx.auth-service.events.user_merged"
+
+	// AffiliationChangedSubject is published after an affiliation was added or
+	// ended, with the user's whole history, for the insights pipeline.
+	// The subject is of the form: lfx.auth-service.events.affiliation_changed
+	AffiliationChangedSubject = "lfx.auth-service.events.affiliation_changed"
+
+	// UserCacheInvalidateSubject is published by a replica after a write through
+	// its user cache, with the user ID, so every other replica drops its entry.
+	// Replicas subscribe without a queue group, so each one gets every message.
+	// The subject is of the form: lfx.auth-service.cache.user_invalidate
+	UserCacheInvalidateSubject = "lfx.auth-service.cache.user_invalidate"
+
+	// ReindexProgressSubject is published while a re-index started by
+	// admin.reindex runs, every few users and after each failure, then once
+	// with the outcome.
+	// The subject is of the form: lfx.auth-service.events.reindex_progress
+	ReindexProgressSubject = "lfx.auth-service.events.reindex_progress"
+
+	// SecurityEventsSubject is published when the requests of one caller match
+	// a detection rule, such as repeated invalid tokens, often enough to alert.
+	// The subject is of the form: lfx.auth-service.events.security
+	SecurityEventsSubject = "lfx.auth-service.events.security"
 )
 
 const (
@@ -47,6 +198,14 @@ const (
 	// The subject is of the form: lfx.auth-service.user_metadata.read
 	UserMetadataReadSubject = "lfx.auth-service.user_metadata.read"
 
+	// UserPreferencesReadSubject is the subject for reading the preferences namespace of the user metadata.
+	// The subject is of the form: lfx.auth-service.user_preferences.read
+	UserPreferencesReadSubject = "lfx.auth-service.user_preferences.read"
+
+	// UserPreferencesUpdateSubject is the subject for updating the preferences namespace of the user metadata.
+	// The subject is of the form: lfx.auth-service.user_preferences.update
+	UserPreferencesUpdateSubject = "lfx.auth-service.user_preferences.update"
+
 	// UserEmailReadSubject is the subject for the user email read event.
 	// The subject is of the form: lfx.auth-service.user_emails.read
 	UserEmailReadSubject = "lfx.auth-service.user_emails.read"
@@ -62,6 +221,19 @@ const (
 	UserAddAliasSubject = "lfx.auth-service.add_alias"
 )
 
+const (
+
+	// Session subjects
+
+	// UserSessionsListSubject is the subject for listing the login sessions and refresh tokens of a user.
+	// The subject is of the form: lfx.auth-service.user.sessions.list
+	UserSessionsListSubject = "lfx.auth-service.user.sessions.list"
+
+	// UserSessionsRevokeSubject is the subject for revoking one or every session of a user.
+	// The subject is of the form: lfx.auth-service.user.sessions.revoke
+	UserSessionsRevokeSubject = "lfx.auth-service.user.sessions.revoke"
+)
+
 const (
 
 	// Impersonation subjects
@@ -108,3 +280,95 @@ const (
 	// The subject is of the form: lfx.auth-service.password.reset_link
 	PasswordResetLinkSubject = "lfx.auth-service.password.reset_link"
 )
+
+const (
+
+	// Admin subjects
+
+	// AdminStatsSubject is the subject for per-instance operational stats.
+	// It is not load-balanced: every replica answers, so callers should
+	// collect multiple replies.
+	// The subject is of the form: lfx.auth-service.admin.stats
+	AdminStatsSubject = "lfx.auth-service.admin.stats"
+
+	// AdminSelfTestSubject is the subject for running the internal checks of a
+	// replica, for synthetic monitoring.
+	// The subject is of the form: lfx.auth-service.admin.selftest
+	AdminSelfTestSubject = "lfx.auth-service.admin.selftest"
+
+	// AdminRevokeTokenSubject is the subject for revoking an access token, or every
+	// access token of a user, before it expires.
+	// The subject is of the form: lfx.auth-service.admin.revoke_token
+	AdminRevokeTokenSubject = "lfx.auth-service.admin.revoke_token"
+
+	// AdminConfigSubject is the subject for the effective runtime configuration of
+	// a replica, with secrets masked. Every replica answers.
+	// The subject is of the form: lfx.auth-service.admin.config
+	AdminConfigSubject = "lfx.auth-service.admin.config"
+
+	// AdminUsersImportSubject is the subject for starting a bulk import of the users
+	// of a manifest into a connection of the identity provider.
+	// The subject is of the form: lfx.auth-service.admin.users_import
+	AdminUsersImportSubject = "lfx.auth-service.admin.users_import"
+
+	// AdminUsersImportStatusSubject is the subject for the progress of a bulk user
+	// import and, once done, the records it rejected.
+	// The subject is of the form: lfx.auth-service.admin.users_import_status
+	AdminUsersImportStatusSubject = "lfx.auth-service.admin.users_import_status"
+
+	// AdminUsersExportSubject is the subject for exporting chosen attributes of every
+	// user, replied to the caller or stored in the user exports object store.
+	// The subject is of the form: lfx.auth-service.admin.users_export
+	AdminUsersExportSubject = "lfx.auth-service.admin.users_export"
+
+	// AdminEmailOrganizationSetSubject is the subject for mapping an email domain to an
+	// organization, replacing its current mapping.
+	// The subject is of the form: lfx.auth-service.admin.email_organization.set
+	AdminEmailOrganizationSetSubject = "lfx.auth-service.admin.email_organization.set"
+
+	// AdminEmailOrganizationDeleteSubject is the subject for removing the mapping of an email domain.
+	// The subject is of the form: lfx.auth-service.admin.email_organization.delete
+	AdminEmailOrganizationDeleteSubject = "lfx.auth-service.admin.email_organization.delete"
+
+	// AdminEmailOrganizationListSubject is the subject for listing the email domain mappings.
+	// The subject is of the form: lfx.auth-service.admin.email_organization.list
+	AdminEmailOrganizationListSubject = "lfx.auth-service.admin.email_organization.list"
+
+	// AdminUsersMergeSubject is the subject for merging a duplicate account into another one.
+	// The subject is of the form: lfx.auth-service.admin.users_merge
+	AdminUsersMergeSubject = "lfx.auth-service.admin.users_merge"
+
+	// AdminAttestationRecordSubject is the subject for marking the current value of a profile field
+	// of a user as verified.
+	// The subject is of the form: lfx.auth-service.admin.attestation.record
+	AdminAttestationRecordSubject = "lfx.auth-service.admin.attestation.record"
+
+	// AdminAttestationRevokeSubject is the subject for removing the verification of a profile field.
+	// The subject is of the form: lfx.auth-service.admin.attestation.revoke
+	AdminAttestationRevokeSubject = "lfx.auth-service.admin.attestation.revoke"
+
+	// AdminStaticCacheFlushSubject is the subject for flushing the cached static resources of the
+	// provider on every replica.
+	// The subject is of the form: lfx.auth-service.admin.static_cache.flush
+	AdminStaticCacheFlushSubject = "lfx.auth-service.admin.static_cache.flush"
+
+	// AdminReindexSubject is the subject for rebuilding the lookup keys of the users
+	// of the Authelia store, reporting its progress on ReindexProgressSubject.
+	// The subject is of the form: lfx.auth-service.admin.reindex
+	AdminReindexSubject = "lfx.auth-service.admin.reindex"
+
+	// AdminMaintenanceSubject is the subject for switching a replica in or out of
+	// read-only mode, or reading its mode. Every replica answers.
+	// The subject is of the form: lfx.auth-service.admin.maintenance
+	AdminMaintenanceSubject = "lfx.auth-service.admin.maintenance"
+)
+
+const (
+
+	// Discovery subjects
+
+	// SchemaSubject is the subject for the JSON Schemas of the requests and replies
+	// of every subject, or of the one named in the request.
+	// The subject is of the form: lfx.auth-service.schema
+	SchemaSubject = "lfx.auth-service.schema"
+)
@@ -10,6 +10,11 @@ const (
 	CriteriaTypeUsername = "username"
 	// CriteriaTypeAlternateEmail is the type of criteria for alternate email
 	CriteriaTypeAlternateEmail = "alternate_email"
+	// CriteriaTypeSecondaryEmail is the type of criteria for a verified secondary email,
+	// such as an address asserted by a social or enterprise identity
+	CriteriaTypeSecondaryEmail = "secondary_email"
+	// CriteriaTypeName is the type of criteria for the user's display name
+	CriteriaTypeName = "name"
 )
 
 const (
@@ -21,6 +26,55 @@ const (
 	// Intentionally shares the same value as UserUpdateMetadataRequiredScope — Auth0 does not have a
 	// dedicated password-change scope, so the metadata update scope is used as the access gate.
 	UserChangePasswordRequiredScope = "update:current_user_metadata"
+	// UserReadActivityRequiredScope is the privileged scope a token must carry for user_metadata.read
+	// to include the login activity section (last login, login count, truncated last IP).
+	UserReadActivityRequiredScope = "read:user_activity"
+	// UserSearchRequiredScope is the privileged scope a token must carry for users.search.
+	UserSearchRequiredScope = "search:users"
+	// ServiceAccountManageRequiredScope is the privileged scope a token must carry to create, list
+	// or rotate service accounts.
+	ServiceAccountManageRequiredScope = "manage:service_accounts"
+	// TokenRevokeRequiredScope is the privileged scope a token must carry to revoke access tokens.
+	TokenRevokeRequiredScope = "revoke:tokens"
+	// ConfigReadRequiredScope is the privileged scope a token must carry to read the runtime
+	// configuration of a replica.
+	ConfigReadRequiredScope = "read:config"
+	// SessionManageRequiredScope is the privileged scope a token must carry to list or revoke the
+	// sessions of another user. Users can always manage their own.
+	SessionManageRequiredScope = "manage:sessions"
+	// UserImportRequiredScope is the privileged scope a token must carry to import users in bulk
+	// or read the progress of an import.
+	UserImportRequiredScope = "import:users"
+	// UserExportRequiredScope is the privileged scope a token must carry to export users in bulk
+	// for reporting.
+	UserExportRequiredScope = "export:users"
+	// EmailOrganizationManageRequiredScope is the privileged scope a token must carry to change
+	// or list the email domain to organization mappings.
+	EmailOrganizationManageRequiredScope = "manage:email_organizations"
+	// UserMergeRequiredScope is the privileged scope a token must carry to merge a duplicate
+	// account into another one.
+	UserMergeRequiredScope = "merge:users"
+	// UserAttestRequiredScope is the privileged scope a token must carry to mark profile fields
+	// of a user as verified, or revoke their verification.
+	UserAttestRequiredScope = "attest:users"
+	// AffiliationManageRequiredScope is the privileged scope a token must carry to add or end
+	// affiliations of another user than its own.
+	AffiliationManageRequiredScope = "manage:affiliations"
+	// UserReadOnBehalfRequiredScope is the privileged scope a token must carry for user_metadata.read
+	// to return what another user's own read would (on_behalf_of).
+	UserReadOnBehalfRequiredScope = "read:users_on_behalf"
+	// StaticCacheFlushRequiredScope is the privileged scope a token must carry to flush the cached
+	// static resources of the provider, such as Auth0 roles and connections.
+	StaticCacheFlushRequiredScope = "flush:static_cache"
+	// LookupReindexRequiredScope is the privileged scope a token must carry to rebuild the lookup
+	// keys of the user store.
+	LookupReindexRequiredScope = "reindex:users"
+	// MaintenanceManageRequiredScope is the privileged scope a token must carry to switch
+	// read-only mode on or off.
+	MaintenanceManageRequiredScope = "manage:maintenance"
+	// SelfTestRunRequiredScope is the privileged scope a token must carry to run the internal
+	// checks of a replica.
+	SelfTestRunRequiredScope = "run:selftest"
 )
 
 const (
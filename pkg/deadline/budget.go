@@ -0,0 +1,52 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package deadline
+
+import (
+	"context"
+	"time"
+)
+
+// Operation is a kind of identity provider call with a timeout of its own
+type Operation string
+
+// Operations with a timeout budget
+const (
+	// OperationSearch is a lookup by email, username or free text
+	OperationSearch Operation = "search"
+	// OperationGet is a read of one known user, e.g. its profile or userinfo
+	OperationGet Operation = "get"
+	// OperationUpdate is a write to a user
+	OperationUpdate Operation = "update"
+	// OperationJobs is a bulk import or export, waited on to its end
+	OperationJobs Operation = "jobs"
+)
+
+// Budget maps operations to how long they may take, retries and polling
+// included. An operation without a timeout is only bounded by its caller.
+type Budget map[Operation]time.Duration
+
+// Bound returns ctx bounded by the timeout of op. The caller's deadline wins
+// when it is earlier.
+func (b Budget) Bound(ctx context.Context, op Operation) (context.Context, context.CancelFunc) {
+	timeout := b[op]
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// With returns a copy of b with the positive timeouts of overrides
+func (b Budget) With(overrides Budget) Budget {
+	merged := make(Budget, len(b)+len(overrides))
+	for op, timeout := range b {
+		merged[op] = timeout
+	}
+	for op, timeout := range overrides {
+		if timeout > 0 {
+			merged[op] = timeout
+		}
+	}
+	return merged
+}
@@ -0,0 +1,50 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package deadline
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBudgetBound(t *testing.T) {
+	budget := Budget{OperationGet: time.Second, OperationJobs: time.Hour}
+
+	t.Run("operation timeout", func(t *testing.T) {
+		ctx, cancel := budget.Bound(context.Background(), OperationGet)
+		defer cancel()
+		deadline, ok := ctx.Deadline()
+		assert.True(t, ok)
+		assert.WithinDuration(t, time.Now().Add(time.Second), deadline, 100*time.Millisecond)
+	})
+
+	t.Run("an earlier caller deadline wins", func(t *testing.T) {
+		parent, cancelParent := context.WithTimeout(context.Background(), time.Minute)
+		defer cancelParent()
+		want, _ := parent.Deadline()
+
+		ctx, cancel := budget.Bound(parent, OperationJobs)
+		defer cancel()
+		deadline, _ := ctx.Deadline()
+		assert.Equal(t, want, deadline)
+	})
+
+	t.Run("operations without a timeout", func(t *testing.T) {
+		ctx, cancel := budget.Bound(context.Background(), OperationSearch)
+		defer cancel()
+		_, ok := ctx.Deadline()
+		assert.False(t, ok)
+	})
+}
+
+func TestBudgetWith(t *testing.T) {
+	defaults := Budget{OperationGet: time.Second, OperationUpdate: 2 * time.Second}
+	merged := defaults.With(Budget{OperationGet: 5 * time.Second, OperationUpdate: 0, OperationJobs: time.Minute})
+
+	assert.Equal(t, Budget{OperationGet: 5 * time.Second, OperationUpdate: 2 * time.Second, OperationJobs: time.Minute}, merged)
+	assert.Equal(t, time.Second, defaults[OperationGet], "the defaults are not changed")
+}
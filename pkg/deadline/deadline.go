@@ -0,0 +1,71 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package deadline carries a caller's deadline in NATS request headers, so
+// the service stops working on a request, including its identity provider
+// calls, once the caller has given up on it.
+//
+// Callers send either Lfx-Timeout, the time they will wait as a Go duration
+// (e.g. "1500ms"), or Lfx-Deadline, an RFC 3339 instant. Lfx-Timeout is
+// preferred because it doesn't depend on the clocks of both hosts agreeing.
+package deadline
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	// TimeoutHeader is the request header holding how long the caller waits
+	TimeoutHeader = "Lfx-Timeout"
+	// Header is the request header holding the caller's deadline, in RFC 3339
+	Header = "Lfx-Deadline"
+
+	// ExceededError is the reply error of a request whose deadline passed
+	ExceededError = "deadline_exceeded"
+)
+
+// Parse returns the deadline a request with header was sent with, relative
+// to received for Lfx-Timeout. Lfx-Timeout wins when both are set; missing,
+// malformed and non-positive values give no deadline.
+func Parse(header nats.Header, received time.Time) (time.Time, bool) {
+	if raw := header.Get(TimeoutHeader); raw != "" {
+		timeout, err := time.ParseDuration(raw)
+		if err != nil || timeout <= 0 {
+			return time.Time{}, false
+		}
+		return received.Add(timeout), true
+	}
+	if raw := header.Get(Header); raw != "" {
+		deadline, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return deadline, true
+	}
+	return time.Time{}, false
+}
+
+// Context returns ctx bounded by the deadline of a request with header, and
+// ctx itself when the request has none
+func Context(ctx context.Context, header nats.Header) (context.Context, context.CancelFunc) {
+	deadline, ok := Parse(header, time.Now())
+	if !ok {
+		return ctx, func() {}
+	}
+	return context.WithDeadline(ctx, deadline)
+}
+
+// Set adds the deadline of ctx to header as Lfx-Timeout, leaving header
+// unchanged when ctx has no deadline
+func Set(ctx context.Context, header nats.Header) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return
+	}
+	remaining := max(time.Until(deadline).Milliseconds(), 1)
+	header.Set(TimeoutHeader, strconv.FormatInt(remaining, 10)+"ms")
+}
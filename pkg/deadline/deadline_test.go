@@ -0,0 +1,82 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package deadline
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	received := time.Date(2025, 3, 4, 5, 6, 7, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		header nats.Header
+		want   time.Time
+		wantOK bool
+	}{
+		{name: "no header"},
+		{name: "timeout", header: nats.Header{TimeoutHeader: {"1500ms"}}, want: received.Add(1500 * time.Millisecond), wantOK: true},
+		{name: "deadline", header: nats.Header{Header: {"2025-03-04T05:06:09Z"}}, want: received.Add(2 * time.Second), wantOK: true},
+		{
+			name:   "timeout wins over deadline",
+			header: nats.Header{TimeoutHeader: {"1s"}, Header: {"2025-03-04T05:06:09Z"}},
+			want:   received.Add(time.Second),
+			wantOK: true,
+		},
+		{name: "malformed timeout", header: nats.Header{TimeoutHeader: {"soon"}}},
+		{name: "non-positive timeout", header: nats.Header{TimeoutHeader: {"0s"}}},
+		{name: "malformed deadline", header: nats.Header{Header: {"tomorrow"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Parse(tt.header, received)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.True(t, tt.want.Equal(got), "got %v, want %v", got, tt.want)
+		})
+	}
+}
+
+func TestContext(t *testing.T) {
+	t.Run("bounded by the caller's timeout", func(t *testing.T) {
+		ctx, cancel := Context(context.Background(), nats.Header{TimeoutHeader: {"1h"}})
+		defer cancel()
+		deadline, ok := ctx.Deadline()
+		require.True(t, ok)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), deadline, time.Minute)
+	})
+
+	t.Run("past deadline", func(t *testing.T) {
+		ctx, cancel := Context(context.Background(), nats.Header{Header: {"2000-01-01T00:00:00Z"}})
+		defer cancel()
+		assert.ErrorIs(t, ctx.Err(), context.DeadlineExceeded)
+	})
+
+	t.Run("no deadline", func(t *testing.T) {
+		ctx, cancel := Context(context.Background(), nil)
+		defer cancel()
+		_, ok := ctx.Deadline()
+		assert.False(t, ok)
+	})
+}
+
+func TestSet(t *testing.T) {
+	header := nats.Header{}
+	Set(context.Background(), header)
+	assert.Empty(t, header.Get(TimeoutHeader))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	Set(ctx, header)
+	deadline, ok := Parse(header, time.Now())
+	require.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(2*time.Second), deadline, 100*time.Millisecond)
+}
@@ -0,0 +1,102 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package encryption
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/cache"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+)
+
+// defaultUnwrappedKeyEntries bounds the number of unwrapped data keys kept in memory
+const defaultUnwrappedKeyEntries = 1024
+
+// EnvelopeOption configures an Envelope
+type EnvelopeOption func(*Envelope)
+
+// WithDataKeyCache reuses a wrapped data key for up to maxAge or maxUses
+// seals, and keeps unwrapped data keys for maxAge. With a remote KeyWrapper
+// (a cloud KMS) this turns one KMS call per value into one per period.
+// A new data key is also generated as soon as the wrapper's active key changes.
+func WithDataKeyCache(maxAge time.Duration, maxUses int) EnvelopeOption {
+	return func(e *Envelope) {
+		e.sealKeys = &dataKeyCache{maxAge: maxAge, maxUses: maxUses, now: time.Now}
+		e.openKeys = cache.New[[sha256.Size]byte, []byte](maxAge, defaultUnwrappedKeyEntries)
+	}
+}
+
+// dataKey is a plaintext data key with its wrapped form
+type dataKey struct {
+	plaintext []byte
+	wrapped   []byte
+	keyID     string
+}
+
+// dataKeyCache hands out the current data key for sealing until it expires
+type dataKeyCache struct {
+	maxAge  time.Duration
+	maxUses int
+	now     func() time.Time
+
+	mu        sync.Mutex
+	current   *dataKey
+	createdAt time.Time
+	uses      int
+}
+
+func (c *dataKeyCache) get(ctx context.Context, wrapper KeyWrapper) (*dataKey, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.current != nil &&
+		c.now().Sub(c.createdAt) < c.maxAge &&
+		(c.maxUses <= 0 || c.uses < c.maxUses) &&
+		c.current.keyID == wrapper.ActiveKeyID() {
+		c.uses++
+		return c.current, nil
+	}
+
+	key, err := newDataKey(ctx, wrapper)
+	if err != nil {
+		return nil, err
+	}
+	c.current, c.createdAt, c.uses = key, c.now(), 1
+	return key, nil
+}
+
+// newDataKey generates a random data key and wraps it with the active key
+func newDataKey(ctx context.Context, wrapper KeyWrapper) (*dataKey, error) {
+	plaintext := make([]byte, dataKeySize)
+	if _, err := rand.Read(plaintext); err != nil {
+		return nil, errs.NewUnexpected("failed to generate data key", err)
+	}
+	wrapped, keyID, err := wrapper.Wrap(ctx, plaintext)
+	if err != nil {
+		return nil, err
+	}
+	return &dataKey{plaintext: plaintext, wrapped: wrapped, keyID: keyID}, nil
+}
+
+// unwrap returns the plaintext data key, using the cache when enabled
+func (e *Envelope) unwrap(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	if e.openKeys == nil {
+		return e.wrapper.Unwrap(ctx, keyID, wrapped)
+	}
+
+	cacheKey := sha256.Sum256(append([]byte(keyID+":"), wrapped...))
+	if plaintext, ok := e.openKeys.Get(cacheKey); ok {
+		return plaintext, nil
+	}
+	plaintext, err := e.wrapper.Unwrap(ctx, keyID, wrapped)
+	if err != nil {
+		return nil, err
+	}
+	e.openKeys.Set(cacheKey, plaintext)
+	return plaintext, nil
+}
@@ -0,0 +1,122 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package encryption
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingWrapper counts calls to the wrapped keyring, standing in for a remote KMS
+type countingWrapper struct {
+	*StaticKeyring
+	wraps, unwraps int
+}
+
+func (c *countingWrapper) Wrap(ctx context.Context, dataKey []byte) ([]byte, string, error) {
+	c.wraps++
+	return c.StaticKeyring.Wrap(ctx, dataKey)
+}
+
+func (c *countingWrapper) Unwrap(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	c.unwraps++
+	return c.StaticKeyring.Unwrap(ctx, keyID, wrapped)
+}
+
+func TestEnvelope_DataKeyCache(t *testing.T) {
+	ctx := context.Background()
+	newWrapper := func(t *testing.T) *countingWrapper {
+		ring, err := NewStaticKeyring("k1", map[string][]byte{"k1": testKey(1), "k2": testKey(2)})
+		require.NoError(t, err)
+		return &countingWrapper{StaticKeyring: ring}
+	}
+
+	t.Run("reuses data key up to max uses", func(t *testing.T) {
+		wrapper := newWrapper(t)
+		env := NewEnvelope(wrapper, WithDataKeyCache(time.Hour, 3))
+
+		var sealed [][]byte
+		for range 5 {
+			s, err := env.Seal(ctx, []byte("value"), nil)
+			require.NoError(t, err)
+			sealed = append(sealed, s)
+		}
+		assert.Equal(t, 2, wrapper.wraps)
+
+		for _, s := range sealed {
+			_, err := env.Open(ctx, s, nil)
+			require.NoError(t, err)
+		}
+		assert.Equal(t, 2, wrapper.unwraps, "unwrapped data keys are cached")
+	})
+
+	t.Run("expires by age", func(t *testing.T) {
+		wrapper := newWrapper(t)
+		env := NewEnvelope(wrapper, WithDataKeyCache(time.Minute, 0))
+		now := time.Now()
+		env.sealKeys.now = func() time.Time { return now }
+
+		_, err := env.Seal(ctx, []byte("a"), nil)
+		require.NoError(t, err)
+		_, err = env.Seal(ctx, []byte("b"), nil)
+		require.NoError(t, err)
+		assert.Equal(t, 1, wrapper.wraps)
+
+		now = now.Add(2 * time.Minute)
+		_, err = env.Seal(ctx, []byte("c"), nil)
+		require.NoError(t, err)
+		assert.Equal(t, 2, wrapper.wraps)
+	})
+
+	t.Run("active key change replaces the data key", func(t *testing.T) {
+		wrapper := newWrapper(t)
+		env := NewEnvelope(wrapper, WithDataKeyCache(time.Hour, 0))
+
+		_, err := env.Seal(ctx, []byte("a"), nil)
+		require.NoError(t, err)
+
+		wrapper.activeKeyID = "k2"
+		sealed, err := env.Seal(ctx, []byte("b"), nil)
+		require.NoError(t, err)
+		keyID, err := KeyID(sealed)
+		require.NoError(t, err)
+		assert.Equal(t, "k2", keyID)
+		assert.Equal(t, 2, wrapper.wraps)
+	})
+}
+
+func TestChain(t *testing.T) {
+	ctx := context.Background()
+	legacy, err := NewStaticKeyring("old", map[string][]byte{"old": testKey(1)})
+	require.NoError(t, err)
+	current, err := NewStaticKeyring("new", map[string][]byte{"new": testKey(2)})
+	require.NoError(t, err)
+
+	sealedLegacy, err := NewEnvelope(legacy).Seal(ctx, []byte("legacy"), nil)
+	require.NoError(t, err)
+
+	env := NewEnvelope(Chain(current, legacy))
+	assert.Equal(t, "new", env.ActiveKeyID())
+
+	opened, err := env.Open(ctx, sealedLegacy, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "legacy", string(opened))
+
+	sealed, err := env.Seal(ctx, []byte("fresh"), nil)
+	require.NoError(t, err)
+	keyID, err := KeyID(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "new", keyID)
+
+	unknown, err := NewStaticKeyring("other", map[string][]byte{"other": testKey(3)})
+	require.NoError(t, err)
+	sealedUnknown, err := NewEnvelope(unknown).Seal(ctx, []byte("x"), nil)
+	require.NoError(t, err)
+	_, err = env.Open(ctx, sealedUnknown, nil)
+	assert.Error(t, err)
+}
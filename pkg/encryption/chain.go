@@ -0,0 +1,60 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package encryption
+
+import (
+	"context"
+	"errors"
+
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+)
+
+// chain wraps with the primary wrapper and unwraps with whichever wrapper
+// knows the key ID
+type chain struct {
+	primary   KeyWrapper
+	fallbacks []KeyWrapper
+}
+
+// Chain returns a KeyWrapper that wraps new data keys with primary and can
+// still unwrap keys wrapped by any of the fallbacks. It is used to migrate
+// between key sources, e.g. from a static keyring to a cloud KMS. Wrappers
+// must return a NotFound error for key IDs they don't own.
+func Chain(primary KeyWrapper, fallbacks ...KeyWrapper) KeyWrapper {
+	if len(fallbacks) == 0 {
+		return primary
+	}
+	return &chain{primary: primary, fallbacks: fallbacks}
+}
+
+// ActiveKeyID implements KeyWrapper
+func (c *chain) ActiveKeyID() string {
+	return c.primary.ActiveKeyID()
+}
+
+// Wrap implements KeyWrapper
+func (c *chain) Wrap(ctx context.Context, dataKey []byte) ([]byte, string, error) {
+	return c.primary.Wrap(ctx, dataKey)
+}
+
+// Unwrap implements KeyWrapper
+func (c *chain) Unwrap(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	var err error
+	for _, wrapper := range append([]KeyWrapper{c.primary}, c.fallbacks...) {
+		var dataKey []byte
+		dataKey, err = wrapper.Unwrap(ctx, keyID, wrapped)
+		if err == nil || !errors.As(err, &errs.NotFound{}) {
+			return dataKey, err
+		}
+	}
+	return nil, err
+}
+
+// RefreshActiveKey implements KeyRefresher
+func (c *chain) RefreshActiveKey(ctx context.Context) error {
+	if refresher, ok := c.primary.(KeyRefresher); ok {
+		return refresher.RefreshActiveKey(ctx)
+	}
+	return nil
+}
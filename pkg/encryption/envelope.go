@@ -0,0 +1,208 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package encryption provides envelope encryption for values persisted by the
+// service. Every value is sealed with a fresh data key (AES-256-GCM), and the
+// data key is wrapped by a key-encryption key supplied by a KeyWrapper. The
+// wrapping key ID travels with the value so it can be re-encrypted after a
+// key rotation.
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/cache"
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+)
+
+const (
+	dataKeySize = 32
+
+	// envelopePrefix marks sealed values, so plaintext written before
+	// encryption was enabled can still be read and migrated.
+	envelopePrefix = "lfxenc:v1:"
+)
+
+// KeyWrapper wraps and unwraps data keys with a key-encryption key.
+type KeyWrapper interface {
+	// ActiveKeyID returns the ID of the key new data keys are wrapped with.
+	ActiveKeyID() string
+	// Wrap encrypts dataKey with the active key and returns the wrapped key
+	// together with the ID of the key that was used.
+	Wrap(ctx context.Context, dataKey []byte) (wrapped []byte, keyID string, err error)
+	// Unwrap decrypts a data key wrapped by keyID.
+	Unwrap(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+// KeyRefresher is implemented by key wrappers whose active key can change
+// outside the service, such as a KMS key with automatic rotation.
+type KeyRefresher interface {
+	// RefreshActiveKey re-resolves the active key ID
+	RefreshActiveKey(ctx context.Context) error
+}
+
+// Envelope seals and opens values. By default every value gets its own data
+// key; see WithDataKeyCache.
+type Envelope struct {
+	wrapper  KeyWrapper
+	sealKeys *dataKeyCache
+	openKeys *cache.Cache[[sha256.Size]byte, []byte]
+}
+
+// NewEnvelope creates an Envelope backed by the given key wrapper
+func NewEnvelope(wrapper KeyWrapper, opts ...EnvelopeOption) *Envelope {
+	e := &Envelope{wrapper: wrapper}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// ActiveKeyID returns the ID of the key newly sealed values are wrapped with
+func (e *Envelope) ActiveKeyID() string {
+	return e.wrapper.ActiveKeyID()
+}
+
+// RefreshActiveKey re-resolves the wrapper's active key when it supports it.
+// Values sealed before a rotation then report a stale key ID and can be
+// re-encrypted; cached data keys are replaced on the next seal.
+func (e *Envelope) RefreshActiveKey(ctx context.Context) error {
+	if refresher, ok := e.wrapper.(KeyRefresher); ok {
+		return refresher.RefreshActiveKey(ctx)
+	}
+	return nil
+}
+
+// Seal encrypts plaintext. The associated data is authenticated but not
+// stored; callers should bind the value to where it lives (e.g. the KV key)
+// so sealed values can't be swapped between records.
+func (e *Envelope) Seal(ctx context.Context, plaintext, associatedData []byte) ([]byte, error) {
+	var (
+		key *dataKey
+		err error
+	)
+	if e.sealKeys != nil {
+		key, err = e.sealKeys.get(ctx, e.wrapper)
+	} else {
+		key, err = newDataKey(ctx, e.wrapper)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext, err := gcmSeal(key.plaintext, plaintext, associatedData)
+	if err != nil {
+		return nil, err
+	}
+
+	enc := base64.RawURLEncoding
+	var b strings.Builder
+	b.WriteString(envelopePrefix)
+	b.WriteString(key.keyID)
+	b.WriteByte(':')
+	b.WriteString(enc.EncodeToString(key.wrapped))
+	b.WriteByte(':')
+	b.WriteString(enc.EncodeToString(ciphertext))
+	return []byte(b.String()), nil
+}
+
+// Open decrypts a value produced by Seal with the same associated data.
+func (e *Envelope) Open(ctx context.Context, sealed, associatedData []byte) ([]byte, error) {
+	keyID, wrapped, ciphertext, err := parseEnvelope(sealed)
+	if err != nil {
+		return nil, err
+	}
+
+	dataKey, err := e.unwrap(ctx, keyID, wrapped)
+	if err != nil {
+		return nil, err
+	}
+
+	return gcmOpen(dataKey, ciphertext, associatedData)
+}
+
+// IsSealed reports whether data looks like a value produced by Seal
+func IsSealed(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(envelopePrefix))
+}
+
+// KeyID returns the ID of the key that wrapped a sealed value
+func KeyID(sealed []byte) (string, error) {
+	keyID, _, _, err := parseEnvelope(sealed)
+	return keyID, err
+}
+
+func parseEnvelope(sealed []byte) (keyID string, wrapped, ciphertext []byte, err error) {
+	if !IsSealed(sealed) {
+		return "", nil, nil, errs.NewValidation("value is not encrypted")
+	}
+
+	parts := strings.Split(string(sealed[len(envelopePrefix):]), ":")
+	if len(parts) != 3 || parts[0] == "" {
+		return "", nil, nil, errs.NewValidation("malformed encrypted value")
+	}
+
+	enc := base64.RawURLEncoding
+	wrapped, err = enc.DecodeString(parts[1])
+	if err != nil {
+		return "", nil, nil, errs.NewValidation("malformed encrypted value", err)
+	}
+	ciphertext, err = enc.DecodeString(parts[2])
+	if err != nil {
+		return "", nil, nil, errs.NewValidation("malformed encrypted value", err)
+	}
+	return parts[0], wrapped, ciphertext, nil
+}
+
+// gcmSeal encrypts plaintext with AES-GCM and prepends the random nonce
+func gcmSeal(key, plaintext, associatedData []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, errs.NewUnexpected("failed to generate nonce", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, associatedData), nil
+}
+
+// gcmOpen reverses gcmSeal
+func gcmOpen(key, data, associatedData []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < aead.NonceSize() {
+		return nil, errs.NewValidation("encrypted value is too short")
+	}
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+
+	plaintext, err := aead.Open(nil, nonce, ciphertext, associatedData)
+	if err != nil {
+		return nil, errs.NewUnexpected("failed to decrypt value", err)
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errs.NewUnexpected(fmt.Sprintf("invalid AES key of %d bytes", len(key)), err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errs.NewUnexpected("failed to initialize AES-GCM", err)
+	}
+	return aead, nil
+}
@@ -0,0 +1,125 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, dataKeySize)
+}
+
+func TestEnvelope_SealOpen(t *testing.T) {
+	ctx := context.Background()
+	ring, err := NewStaticKeyring("k1", map[string][]byte{"k1": testKey(1)})
+	require.NoError(t, err)
+	env := NewEnvelope(ring)
+
+	plaintext := []byte(`{"username":"jdoe","email":"jdoe@example.com"}`)
+	sealed, err := env.Seal(ctx, plaintext, []byte("jdoe"))
+	require.NoError(t, err)
+
+	assert.True(t, IsSealed(sealed))
+	assert.NotContains(t, string(sealed), "jdoe@example.com")
+	keyID, err := KeyID(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "k1", keyID)
+
+	opened, err := env.Open(ctx, sealed, []byte("jdoe"))
+	require.NoError(t, err)
+	assert.Equal(t, plaintext, opened)
+
+	t.Run("fresh data key per value", func(t *testing.T) {
+		again, err := env.Seal(ctx, plaintext, []byte("jdoe"))
+		require.NoError(t, err)
+		assert.NotEqual(t, sealed, again)
+	})
+
+	t.Run("associated data must match", func(t *testing.T) {
+		_, err := env.Open(ctx, sealed, []byte("someone-else"))
+		assert.Error(t, err)
+	})
+
+	t.Run("tampered ciphertext is rejected", func(t *testing.T) {
+		tampered := bytes.Clone(sealed)
+		tampered[len(tampered)-2] ^= 'A' ^ 'B'
+		_, err := env.Open(ctx, tampered, []byte("jdoe"))
+		assert.Error(t, err)
+	})
+
+	t.Run("plaintext is not sealed", func(t *testing.T) {
+		assert.False(t, IsSealed(plaintext))
+		_, err := env.Open(ctx, plaintext, nil)
+		assert.Error(t, err)
+	})
+}
+
+func TestEnvelope_Rotation(t *testing.T) {
+	ctx := context.Background()
+	oldRing, err := NewStaticKeyring("2024", map[string][]byte{"2024": testKey(1)})
+	require.NoError(t, err)
+	sealed, err := NewEnvelope(oldRing).Seal(ctx, []byte("jdoe"), nil)
+	require.NoError(t, err)
+
+	rotated, err := NewStaticKeyring("2025", map[string][]byte{"2024": testKey(1), "2025": testKey(2)})
+	require.NoError(t, err)
+	env := NewEnvelope(rotated)
+
+	opened, err := env.Open(ctx, sealed, nil)
+	require.NoError(t, err, "values sealed with a retired key stay readable")
+	assert.Equal(t, "jdoe", string(opened))
+
+	resealed, err := env.Seal(ctx, opened, nil)
+	require.NoError(t, err)
+	keyID, err := KeyID(resealed)
+	require.NoError(t, err)
+	assert.Equal(t, "2025", keyID)
+
+	dropped, err := NewStaticKeyring("2025", map[string][]byte{"2025": testKey(2)})
+	require.NoError(t, err)
+	_, err = NewEnvelope(dropped).Open(ctx, sealed, nil)
+	assert.Error(t, err)
+}
+
+func TestParseStaticKeyring(t *testing.T) {
+	k1 := base64.StdEncoding.EncodeToString(testKey(1))
+	k2 := base64.StdEncoding.EncodeToString(testKey(2))
+
+	tests := []struct {
+		name    string
+		active  string
+		raw     string
+		wantErr string
+	}{
+		{name: "single key", active: "a", raw: "a:" + k1},
+		{name: "multiple keys with spaces", active: "b", raw: " a:" + k1 + " , b:" + k2 + ","},
+		{name: "empty", active: "a", raw: "", wantErr: "at least one"},
+		{name: "missing separator", active: "a", raw: k1, wantErr: "id:base64key"},
+		{name: "bad base64", active: "a", raw: "a:not base64!", wantErr: "not valid base64"},
+		{name: "wrong size", active: "a", raw: "a:" + base64.StdEncoding.EncodeToString([]byte("short")), wantErr: "must be 32 bytes"},
+		{name: "duplicate id", active: "a", raw: "a:" + k1 + ",a:" + k2, wantErr: "duplicate"},
+		{name: "active key missing", active: "c", raw: "a:" + k1, wantErr: "not in the keyring"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ring, err := ParseStaticKeyring(tt.active, tt.raw)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.True(t, strings.Contains(err.Error(), tt.wantErr), err.Error())
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.active, ring.ActiveKeyID())
+		})
+	}
+}
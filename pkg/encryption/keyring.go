@@ -0,0 +1,93 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package encryption
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+)
+
+// StaticKeyring is a KeyWrapper holding AES-256 key-encryption keys in
+// memory. Old keys stay in the ring after a rotation so existing values can
+// still be opened until they are re-encrypted. Key IDs can't contain "/",
+// which is reserved for namespaced KMS key IDs.
+type StaticKeyring struct {
+	activeKeyID string
+	keys        map[string][]byte
+}
+
+// NewStaticKeyring creates a keyring from key ID to 32-byte key. The active
+// key must be present in keys.
+func NewStaticKeyring(activeKeyID string, keys map[string][]byte) (*StaticKeyring, error) {
+	if len(keys) == 0 {
+		return nil, errs.NewValidation("at least one encryption key is required")
+	}
+	ring := &StaticKeyring{activeKeyID: activeKeyID, keys: make(map[string][]byte, len(keys))}
+	for id, key := range keys {
+		if id == "" || strings.ContainsAny(id, ":/, ") {
+			return nil, errs.NewValidation(fmt.Sprintf("invalid encryption key ID %q", id))
+		}
+		if len(key) != dataKeySize {
+			return nil, errs.NewValidation(fmt.Sprintf("encryption key %q must be %d bytes, got %d", id, dataKeySize, len(key)))
+		}
+		ring.keys[id] = append([]byte(nil), key...)
+	}
+	if _, ok := ring.keys[activeKeyID]; !ok {
+		return nil, errs.NewValidation(fmt.Sprintf("active encryption key %q is not in the keyring", activeKeyID))
+	}
+	return ring, nil
+}
+
+// ParseStaticKeyring builds a keyring from comma-separated id:base64key
+// pairs, e.g. "2025-01:q83v...,2024-06:Zm9v...".
+func ParseStaticKeyring(activeKeyID, raw string) (*StaticKeyring, error) {
+	keys := make(map[string][]byte)
+	for _, pair := range strings.Split(raw, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(pair, ":")
+		if !ok {
+			return nil, errs.NewValidation(fmt.Sprintf("encryption key entry %q must be of the form id:base64key", pair))
+		}
+		id = strings.TrimSpace(id)
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, errs.NewValidation(fmt.Sprintf("encryption key %q is not valid base64", id), err)
+		}
+		if _, dup := keys[id]; dup {
+			return nil, errs.NewValidation(fmt.Sprintf("duplicate encryption key ID %q", id))
+		}
+		keys[id] = key
+	}
+	return NewStaticKeyring(activeKeyID, keys)
+}
+
+// ActiveKeyID implements KeyWrapper
+func (r *StaticKeyring) ActiveKeyID() string {
+	return r.activeKeyID
+}
+
+// Wrap implements KeyWrapper
+func (r *StaticKeyring) Wrap(_ context.Context, dataKey []byte) ([]byte, string, error) {
+	wrapped, err := gcmSeal(r.keys[r.activeKeyID], dataKey, []byte(r.activeKeyID))
+	if err != nil {
+		return nil, "", err
+	}
+	return wrapped, r.activeKeyID, nil
+}
+
+// Unwrap implements KeyWrapper
+func (r *StaticKeyring) Unwrap(_ context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	key, ok := r.keys[keyID]
+	if !ok {
+		return nil, errs.NewNotFound(fmt.Sprintf("encryption key %q is not in the keyring", keyID))
+	}
+	return gcmOpen(key, wrapped, []byte(keyID))
+}
@@ -0,0 +1,204 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package envelope defines the typed reply envelope of the service, version
+// 2, and carries what it reports about a request through its context.
+//
+// Version 1 replies are the historic ones: success, data, and an error
+// string next to the error_code and error_message members added by the
+// localization. Version 2 replies have a fixed shape, an error object and a
+// meta block:
+//
+//	{"version":2,"success":false,"error":{"code":"user_not_found","message":"user not found"},
+//	 "meta":{"request_id":"4bf9…","provider":"auth0","cache":"miss","duration_ms":12}}
+//
+// Callers ask for a version in the Lfx-Envelope request header; without it
+// they get the default version of the service, 1 unless configured
+// otherwise, so existing consumers keep decoding their replies. They may
+// also hint, in the Lfx-Provider header, the identity provider their reads
+// are served by when the service runs two.
+package envelope
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	// Header is the request header holding the envelope version the caller
+	// decodes
+	Header = "Lfx-Envelope"
+	// ProviderHeader is the request header holding the identity provider the
+	// caller wants its request served by, e.g. auth0
+	ProviderHeader = "Lfx-Provider"
+
+	// Legacy is the version of the historic replies
+	Legacy = 1
+	// Version is the version of the typed envelope
+	Version = 2
+
+	// CodeUnknown is the code of the errors the service doesn't classify
+	CodeUnknown = "unknown"
+)
+
+// Cache outcomes of the reads of a request, from the best to the worst
+const (
+	CacheHit   = "hit"
+	CacheStale = "stale"
+	CacheMiss  = "miss"
+)
+
+// Envelope is a version 2 reply
+type Envelope struct {
+	Version int             `json:"version"`
+	Success bool            `json:"success"`
+	Data    json.RawMessage `json:"data,omitempty"`
+	// Message is the informational message of a successful reply
+	Message string `json:"message,omitempty"`
+	Error   *Error `json:"error,omitempty"`
+	Meta    Meta   `json:"meta"`
+}
+
+// Error is the error of an unsuccessful reply
+type Error struct {
+	// Code is stable across versions and languages, e.g. user_not_found
+	Code string `json:"code"`
+	// Message is the English message, the error member of version 1
+	Message string `json:"message"`
+	// LocalizedMessage is Message in Language, the one the caller asked for
+	LocalizedMessage string `json:"localized_message,omitempty"`
+	Language         string `json:"language,omitempty"`
+}
+
+// Meta is what the service reports about the processing of a request
+type Meta struct {
+	RequestID string `json:"request_id"`
+	// Provider is the identity provider that served the request
+	Provider string `json:"provider,omitempty"`
+	// Cache is the worst cache outcome of the user reads of the request,
+	// empty when it read no user through the cache
+	Cache      string `json:"cache,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+type (
+	versionKey           struct{}
+	requestedProviderKey struct{}
+	providerKey          struct{}
+)
+
+// Context returns ctx carrying the envelope version a request with header
+// asks for, unless it asks for none or one the service doesn't know, and the
+// provider it hints
+func Context(ctx context.Context, header nats.Header) context.Context {
+	if provider := strings.ToLower(strings.TrimSpace(header.Get(ProviderHeader))); provider != "" {
+		ctx = context.WithValue(ctx, requestedProviderKey{}, provider)
+	}
+	version, err := strconv.Atoi(strings.TrimSpace(header.Get(Header)))
+	if err != nil || version < Legacy || version > Version {
+		return ctx
+	}
+	return context.WithValue(ctx, versionKey{}, version)
+}
+
+// VersionFromContext returns the envelope version the request of ctx asks
+// for, 0 when it asks for none
+func VersionFromContext(ctx context.Context) int {
+	version, _ := ctx.Value(versionKey{}).(int)
+	return version
+}
+
+// RequestedProviderFromContext returns the provider the request of ctx hints,
+// empty when it hints none. The hint isn't authorized: the service checks
+// who sent it before pinning the request with WithProvider.
+func RequestedProviderFromContext(ctx context.Context) string {
+	provider, _ := ctx.Value(requestedProviderKey{}).(string)
+	return provider
+}
+
+// WithProvider returns ctx pinning its request to provider
+func WithProvider(ctx context.Context, provider string) context.Context {
+	return context.WithValue(ctx, providerKey{}, provider)
+}
+
+// ProviderFromContext returns the provider the request of ctx is pinned to,
+// empty when it isn't
+func ProviderFromContext(ctx context.Context) string {
+	provider, _ := ctx.Value(providerKey{}).(string)
+	return provider
+}
+
+// Recorder collects what the layers serving a request report for its meta
+// block
+type Recorder struct {
+	mu       sync.Mutex
+	provider string
+	cache    string
+}
+
+type recorderKey struct{}
+
+// WithRecorder returns ctx carrying a new recorder, and the recorder
+func WithRecorder(ctx context.Context) (context.Context, *Recorder) {
+	recorder := &Recorder{}
+	return context.WithValue(ctx, recorderKey{}, recorder), recorder
+}
+
+// RecordCache reports the outcome of a cached read of the request of ctx;
+// the worst outcome of its reads is kept. It does nothing when ctx carries
+// no recorder.
+func RecordCache(ctx context.Context, outcome string) {
+	recorder, ok := ctx.Value(recorderKey{}).(*Recorder)
+	if !ok {
+		return
+	}
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	if cacheRank(outcome) > cacheRank(recorder.cache) {
+		recorder.cache = outcome
+	}
+}
+
+// RecordProvider reports the identity provider serving the request of ctx,
+// when it isn't the default one. It does nothing when ctx carries no
+// recorder.
+func RecordProvider(ctx context.Context, provider string) {
+	recorder, ok := ctx.Value(recorderKey{}).(*Recorder)
+	if !ok {
+		return
+	}
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	recorder.provider = provider
+}
+
+// Cache returns the worst cache outcome recorded
+func (r *Recorder) Cache() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cache
+}
+
+// Provider returns the provider recorded, empty when none was
+func (r *Recorder) Provider() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.provider
+}
+
+func cacheRank(outcome string) int {
+	switch outcome {
+	case CacheHit:
+		return 1
+	case CacheStale:
+		return 2
+	case CacheMiss:
+		return 3
+	}
+	return 0
+}
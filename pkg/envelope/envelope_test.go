@@ -0,0 +1,48 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package envelope
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContext(t *testing.T) {
+	for value, want := range map[string]int{"": 0, "1": 1, " 2 ": 2, "3": 0, "0": 0, "v2": 0} {
+		header := nats.Header{}
+		if value != "" {
+			header.Set(Header, value)
+		}
+		assert.Equal(t, want, VersionFromContext(Context(context.Background(), header)), "header %q", value)
+	}
+}
+
+func TestContextProvider(t *testing.T) {
+	ctx := Context(context.Background(), nats.Header{ProviderHeader: []string{" Auth0 "}})
+	assert.Equal(t, "auth0", RequestedProviderFromContext(ctx))
+	assert.Empty(t, ProviderFromContext(ctx), "a hint doesn't pin the request")
+	assert.Equal(t, "auth0", ProviderFromContext(WithProvider(ctx, "auth0")))
+	assert.Empty(t, RequestedProviderFromContext(Context(context.Background(), nats.Header{})))
+}
+
+func TestRecorder(t *testing.T) {
+	// without a recorder the reports are dropped
+	RecordCache(context.Background(), CacheMiss)
+	RecordProvider(context.Background(), "auth0")
+
+	ctx, recorder := WithRecorder(context.Background())
+	assert.Empty(t, recorder.Cache())
+	RecordCache(ctx, CacheHit)
+	assert.Equal(t, CacheHit, recorder.Cache())
+	RecordCache(ctx, CacheMiss)
+	RecordCache(ctx, CacheStale)
+	assert.Equal(t, CacheMiss, recorder.Cache(), "the worst outcome is kept")
+
+	assert.Empty(t, recorder.Provider())
+	RecordProvider(ctx, "authelia")
+	assert.Equal(t, "authelia", recorder.Provider())
+}
@@ -0,0 +1,95 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package errors
+
+import "errors"
+
+// Code names an error situation the same way whichever identity provider
+// backs the service. Codes are the error codes of the replies, so a caller
+// handles a missing user alike on Auth0 and Authelia.
+type Code string
+
+// The provider error taxonomy
+const (
+	// CodeUserNotFound is a user, or the user of a token, the provider doesn't have
+	CodeUserNotFound Code = "user_not_found"
+	// CodeUserBlocked is a user the provider refuses to authenticate
+	CodeUserBlocked Code = "user_blocked"
+	// CodeRateLimited is a call the provider rejected until its rate limit resets
+	CodeRateLimited Code = "rate_limited"
+	// CodeTokenInvalid is a token the provider could not verify
+	CodeTokenInvalid Code = "token_invalid"
+)
+
+// messages are the replies of each code; the detail the provider gave stays
+// in the wrapped error, for logs
+var messages = map[Code]string{
+	CodeUserNotFound: "user not found",
+	CodeUserBlocked:  "user is blocked",
+	CodeRateLimited:  "rate limited by the identity provider, retry later",
+	CodeTokenInvalid: "auth_token could not be verified",
+}
+
+// Classified is a provider error mapped to the taxonomy. Its message is the
+// one of its code, and it unwraps to the error of its kind (NotFound,
+// Forbidden, ...) carrying the provider's detail.
+type Classified struct {
+	code Code
+	err  error
+}
+
+// Error returns the message of the code
+func (c Classified) Error() string {
+	return messages[c.code]
+}
+
+// Unwrap returns the error of the kind of the code
+func (c Classified) Unwrap() error {
+	return c.err
+}
+
+// Code returns the taxonomy code of the error
+func (c Classified) Code() Code {
+	return c.code
+}
+
+// Detail returns the message of the wrapped error, as the provider gave it
+func (c Classified) Detail() string {
+	return c.err.Error()
+}
+
+// Message returns the reply of code, empty for a code outside the taxonomy
+func Message(code Code) string {
+	return messages[code]
+}
+
+// CodeOf returns the taxonomy code of err, false when err wasn't classified
+func CodeOf(err error) (Code, bool) {
+	var classified Classified
+	if errors.As(err, &classified) {
+		return classified.code, true
+	}
+	return "", false
+}
+
+// NewUserNotFound classifies a missing user: a NotFound error
+func NewUserNotFound(message string, err ...error) Classified {
+	return Classified{code: CodeUserNotFound, err: NewNotFound(message, err...)}
+}
+
+// NewUserBlocked classifies a blocked user: a Forbidden error
+func NewUserBlocked(message string, err ...error) Classified {
+	return Classified{code: CodeUserBlocked, err: NewForbidden(message, err...)}
+}
+
+// NewRateLimited classifies a rate-limited call: a ServiceUnavailable error
+func NewRateLimited(message string, err ...error) Classified {
+	return Classified{code: CodeRateLimited, err: NewServiceUnavailable(message, err...)}
+}
+
+// NewTokenInvalid classifies a token that could not be verified: an
+// Unauthorized error
+func NewTokenInvalid(message string, err ...error) Classified {
+	return Classified{code: CodeTokenInvalid, err: NewUnauthorized(message, err...)}
+}
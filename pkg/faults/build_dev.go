@@ -0,0 +1,10 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+//go:build fault_injection
+
+package faults
+
+// injectionAllowed is true in chaos-testing builds (-tags fault_injection),
+// so fault rules can be configured.
+const injectionAllowed = true
@@ -0,0 +1,9 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+//go:build !fault_injection
+
+package faults
+
+// injectionAllowed is false in production builds: any fault rule is rejected.
+const injectionAllowed = false
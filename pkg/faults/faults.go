@@ -0,0 +1,171 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package faults injects failures into request handling, so consumers can
+// test their timeouts, retries and fallbacks against the service. Rules are
+// only accepted by binaries built with the fault_injection tag.
+package faults
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"strings"
+	"time"
+)
+
+// Kinds of fault
+const (
+	// KindLatency delays the request by Rule.Latency before handling it
+	KindLatency = "latency"
+	// KindProviderError replies with an error as if the identity provider failed
+	KindProviderError = "provider_error"
+	// KindMalformed handles the request, then truncates the reply so it isn't valid JSON
+	KindMalformed = "malformed"
+	// KindTokenExpired replies with the error of an expired access token
+	KindTokenExpired = "token_expired"
+)
+
+// Default error messages, matching those of real failures
+const (
+	defaultProviderErrorMessage = "service unavailable"
+	defaultTokenExpiredMessage  = "token has expired"
+)
+
+// Rule injects one kind of fault into a fraction of the requests on matching subjects
+type Rule struct {
+	// Subject is an exact subject, a prefix ending in ">", or "*" for every subject
+	Subject string `json:"subject"`
+	Kind    string `json:"kind"`
+	// Rate is the fraction of matching requests affected, in (0, 1]
+	Rate float64 `json:"rate"`
+	// Latency is the delay of latency faults, e.g. "2s"
+	Latency string `json:"latency,omitempty"`
+	// Message overrides the error of provider_error and token_expired faults
+	Message string `json:"message,omitempty"`
+
+	latency time.Duration
+}
+
+// matches reports whether the rule applies to subject
+func (r *Rule) matches(subject string) bool {
+	switch {
+	case r.Subject == "*":
+		return true
+	case strings.HasSuffix(r.Subject, ">"):
+		return strings.HasPrefix(subject, strings.TrimSuffix(r.Subject, ">"))
+	}
+	return r.Subject == subject
+}
+
+// validate checks the rule and parses its latency
+func (r *Rule) validate() error {
+	if r.Subject == "" {
+		return fmt.Errorf("fault rule has no subject")
+	}
+	if r.Rate <= 0 || r.Rate > 1 {
+		return fmt.Errorf("fault rule for %s: rate must be in (0, 1]", r.Subject)
+	}
+	switch r.Kind {
+	case KindLatency:
+		latency, err := time.ParseDuration(r.Latency)
+		if err != nil || latency <= 0 {
+			return fmt.Errorf("fault rule for %s: latency must be a positive duration", r.Subject)
+		}
+		r.latency = latency
+	case KindProviderError, KindMalformed, KindTokenExpired:
+	default:
+		return fmt.Errorf("fault rule for %s: unknown kind %q", r.Subject, r.Kind)
+	}
+	return nil
+}
+
+// Injector applies fault rules to requests. A nil Injector injects nothing.
+type Injector struct {
+	rules []Rule
+	// roll returns a number in [0, 1); replaced in tests
+	roll func() float64
+}
+
+// ParseRules parses a JSON array of rules
+func ParseRules(raw string) ([]Rule, error) {
+	var rules []Rule
+	if err := json.Unmarshal([]byte(raw), &rules); err != nil {
+		return nil, fmt.Errorf("fault rules must be a JSON array: %w", err)
+	}
+	return rules, nil
+}
+
+// New validates rules and returns an injector for them. Any rule is rejected
+// unless the binary was built with the fault_injection tag.
+func New(rules []Rule) (*Injector, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	if !injectionAllowed {
+		return nil, fmt.Errorf("fault injection requires a build with the fault_injection tag")
+	}
+	validated := make([]Rule, len(rules))
+	for i, rule := range rules {
+		if err := rule.validate(); err != nil {
+			return nil, err
+		}
+		validated[i] = rule
+	}
+	return &Injector{rules: validated, roll: rand.Float64}, nil
+}
+
+// Apply handles a request on subject with handle, injecting the fault of the
+// first matching rule that fires. Each rule is rolled independently, in order.
+func (f *Injector) Apply(ctx context.Context, subject string, handle func() ([]byte, error)) ([]byte, error) {
+	if f == nil {
+		return handle()
+	}
+	for i := range f.rules {
+		rule := &f.rules[i]
+		if !rule.matches(subject) || f.roll() >= rule.Rate {
+			continue
+		}
+		slog.WarnContext(ctx, "injecting fault", "kind", rule.Kind, "rule_subject", rule.Subject)
+		return rule.inject(ctx, handle)
+	}
+	return handle()
+}
+
+func (r *Rule) inject(ctx context.Context, handle func() ([]byte, error)) ([]byte, error) {
+	switch r.Kind {
+	case KindLatency:
+		timer := time.NewTimer(r.latency)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		return handle()
+	case KindMalformed:
+		response, err := handle()
+		if err != nil {
+			return nil, err
+		}
+		return response[:len(response)/2], nil
+	case KindTokenExpired:
+		return errorReply(r.Message, defaultTokenExpiredMessage), nil
+	default:
+		return errorReply(r.Message, defaultProviderErrorMessage), nil
+	}
+}
+
+// errorReply is the failed reply shape every handler uses
+func errorReply(message, fallback string) []byte {
+	if message == "" {
+		message = fallback
+	}
+	reply, _ := json.Marshal(struct {
+		Success bool   `json:"success"`
+		Error   string `json:"error"`
+	}{Error: message})
+	return reply
+}
@@ -0,0 +1,153 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package faults
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// newTestInjector validates rules regardless of the build tag and fires
+// every matching rule
+func newTestInjector(t *testing.T, rules ...Rule) *Injector {
+	t.Helper()
+	for i := range rules {
+		if err := rules[i].validate(); err != nil {
+			t.Fatalf("validate() error = %v", err)
+		}
+	}
+	return &Injector{rules: rules, roll: func() float64 { return 0 }}
+}
+
+func handled() ([]byte, error) {
+	return []byte(`{"success":true,"data":"zephyr.stormwind"}`), nil
+}
+
+func TestNew(t *testing.T) {
+	injector, err := New(nil)
+	if err != nil || injector != nil {
+		t.Fatalf("New(nil) = %v, %v; want no injector", injector, err)
+	}
+
+	_, err = New([]Rule{{Subject: "*", Kind: KindProviderError, Rate: 1}})
+	if injectionAllowed && err != nil {
+		t.Errorf("New() error = %v", err)
+	}
+	if !injectionAllowed && err == nil {
+		t.Error("New() expected rules to be rejected in a production build")
+	}
+}
+
+func TestRuleValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    Rule
+		wantErr bool
+	}{
+		{name: "provider error", rule: Rule{Subject: "*", Kind: KindProviderError, Rate: 0.5}},
+		{name: "latency", rule: Rule{Subject: "*", Kind: KindLatency, Rate: 1, Latency: "2s"}},
+		{name: "latency without duration", rule: Rule{Subject: "*", Kind: KindLatency, Rate: 1}, wantErr: true},
+		{name: "zero rate", rule: Rule{Subject: "*", Kind: KindMalformed}, wantErr: true},
+		{name: "rate above one", rule: Rule{Subject: "*", Kind: KindMalformed, Rate: 2}, wantErr: true},
+		{name: "unknown kind", rule: Rule{Subject: "*", Kind: "drop", Rate: 1}, wantErr: true},
+		{name: "no subject", rule: Rule{Kind: KindMalformed, Rate: 1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.rule.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	ctx := context.Background()
+	const subject = "lfx.auth-service.user_metadata.read"
+
+	t.Run("nil injector handles the request", func(t *testing.T) {
+		var injector *Injector
+		got, err := injector.Apply(ctx, subject, handled)
+		want, _ := handled()
+		if err != nil || string(got) != string(want) {
+			t.Errorf("Apply() = %s, %v", got, err)
+		}
+	})
+
+	t.Run("provider error", func(t *testing.T) {
+		injector := newTestInjector(t, Rule{Subject: "lfx.auth-service.user_metadata.>", Kind: KindProviderError, Rate: 1})
+		got, _ := injector.Apply(ctx, subject, handled)
+		var reply struct {
+			Success bool   `json:"success"`
+			Error   string `json:"error"`
+		}
+		if err := json.Unmarshal(got, &reply); err != nil {
+			t.Fatalf("reply is not JSON: %v", err)
+		}
+		if reply.Success || reply.Error != defaultProviderErrorMessage {
+			t.Errorf("Apply() = %s, want a provider error", got)
+		}
+	})
+
+	t.Run("token expired with a custom message", func(t *testing.T) {
+		injector := newTestInjector(t, Rule{Subject: subject, Kind: KindTokenExpired, Rate: 1, Message: "expired"})
+		got, _ := injector.Apply(ctx, subject, handled)
+		if string(got) != `{"success":false,"error":"expired"}` {
+			t.Errorf("Apply() = %s", got)
+		}
+	})
+
+	t.Run("malformed reply", func(t *testing.T) {
+		injector := newTestInjector(t, Rule{Subject: "*", Kind: KindMalformed, Rate: 1})
+		got, _ := injector.Apply(ctx, subject, handled)
+		if json.Valid(got) {
+			t.Errorf("Apply() = %s, want invalid JSON", got)
+		}
+	})
+
+	t.Run("latency", func(t *testing.T) {
+		injector := newTestInjector(t, Rule{Subject: "*", Kind: KindLatency, Rate: 1, Latency: "20ms"})
+		started := time.Now()
+		if _, err := injector.Apply(ctx, subject, handled); err != nil {
+			t.Fatalf("Apply() error = %v", err)
+		}
+		if elapsed := time.Since(started); elapsed < 20*time.Millisecond {
+			t.Errorf("Apply() took %s, want at least 20ms", elapsed)
+		}
+	})
+
+	t.Run("other subjects are untouched", func(t *testing.T) {
+		injector := newTestInjector(t, Rule{Subject: "lfx.auth-service.password.>", Kind: KindProviderError, Rate: 1})
+		got, _ := injector.Apply(ctx, subject, handled)
+		want, _ := handled()
+		if string(got) != string(want) {
+			t.Errorf("Apply() = %s, want the handler reply", got)
+		}
+	})
+
+	t.Run("rules that don't fire are skipped", func(t *testing.T) {
+		injector := newTestInjector(t, Rule{Subject: "*", Kind: KindProviderError, Rate: 0.1})
+		injector.roll = func() float64 { return 0.5 }
+		got, _ := injector.Apply(ctx, subject, handled)
+		want, _ := handled()
+		if string(got) != string(want) {
+			t.Errorf("Apply() = %s, want the handler reply", got)
+		}
+	})
+}
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules(`[{"subject":"*","kind":"latency","rate":0.1,"latency":"500ms"}]`)
+	if err != nil {
+		t.Fatalf("ParseRules() error = %v", err)
+	}
+	if len(rules) != 1 || rules[0].Kind != KindLatency || rules[0].Latency != "500ms" {
+		t.Errorf("ParseRules() = %+v", rules)
+	}
+	if _, err := ParseRules("subject=*"); err == nil {
+		t.Error("ParseRules() expected an error for a non-JSON value")
+	}
+}
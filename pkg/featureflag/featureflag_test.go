@@ -0,0 +1,86 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package featureflag
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetEnabled(t *testing.T) {
+	set := NewSet(map[string]Flag{
+		"everyone": {Enabled: true},
+		"listed":   {Callers: []string{"project-service"}},
+		"off":      {},
+	})
+	project := WithCaller(context.Background(), "project-service")
+	other := WithCaller(context.Background(), "committee-service")
+
+	assert.True(t, set.Enabled(context.Background(), "everyone"))
+	assert.True(t, set.Enabled(project, "listed"))
+	assert.False(t, set.Enabled(other, "listed"))
+	assert.False(t, set.Enabled(context.Background(), "listed"))
+	assert.False(t, set.Enabled(project, "off"))
+	assert.False(t, set.Enabled(project, "unknown"))
+
+	var unset *Set
+	assert.False(t, unset.Enabled(project, "everyone"))
+
+	set.Put("off", Flag{Percentage: 100})
+	assert.True(t, set.Enabled(other, "off"))
+	set.Delete("everyone")
+	assert.False(t, set.Enabled(other, "everyone"))
+	assert.Equal(t, []string{"listed", "off"}, set.Names())
+}
+
+func TestSetEnabledPercentage(t *testing.T) {
+	set := NewSet(map[string]Flag{"rollout": {Percentage: 25}})
+
+	on := 0
+	for i := range 1000 {
+		ctx := WithCaller(context.Background(), fmt.Sprintf("service-%d", i))
+		enabled := set.Enabled(ctx, "rollout")
+		// a caller keeps its answer
+		assert.Equal(t, enabled, set.Enabled(ctx, "rollout"))
+		if enabled {
+			on++
+		}
+	}
+	assert.InDelta(t, 250, on, 60)
+}
+
+func TestParse(t *testing.T) {
+	flags, err := Parse([]byte(`
+strict_email_verification:
+  percentage: 10
+  callers: [project-service]
+new_envelope:
+  enabled: true
+`))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Flag{
+		"strict_email_verification": {Percentage: 10, Callers: []string{"project-service"}},
+		"new_envelope":              {Enabled: true},
+	}, flags)
+
+	flags, err = Parse([]byte(`{"new_envelope": {"enabled": true}}`))
+	require.NoError(t, err)
+	assert.True(t, flags["new_envelope"].Enabled)
+
+	_, err = Parse([]byte(`rollout: {percentage: 150}`))
+	assert.Error(t, err)
+	_, err = Parse([]byte(`[not, a, mapping]`))
+	assert.Error(t, err)
+}
+
+func TestContext(t *testing.T) {
+	ctx := Context(context.Background(), nats.Header{CallerHeader: {"project-service"}})
+	assert.Equal(t, "project-service", CallerFromContext(ctx))
+	assert.Empty(t, CallerFromContext(Context(context.Background(), nats.Header{})))
+}
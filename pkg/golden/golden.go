@@ -0,0 +1,84 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package golden
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// UpdateEnvKey rewrites golden files with the current output when set to 1
+const UpdateEnvKey = "UPDATE_GOLDEN"
+
+// Read returns the fixture at path
+func Read(t testing.TB, path string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read fixture %s: %v", path, err)
+	}
+	return data
+}
+
+// Assert compares got, as indented JSON, with the golden file at path. With
+// UPDATE_GOLDEN=1 the golden file is rewritten instead, to accept a change.
+func Assert(t testing.TB, path string, got any) {
+	t.Helper()
+	actual, err := json.MarshalIndent(got, "", "  ")
+	if err != nil {
+		t.Fatalf("failed to marshal output for %s: %v", path, err)
+	}
+	actual = append(actual, '\n')
+
+	if os.Getenv(UpdateEnvKey) == "1" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("failed to create %s: %v", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, actual, 0o600); err != nil {
+			t.Fatalf("failed to update golden file %s: %v", path, err)
+		}
+		return
+	}
+
+	expected := Read(t, path)
+	if !bytes.Equal(expected, actual) {
+		t.Errorf("output differs from golden file %s (run with %s=1 to accept):\n--- want\n%s\n--- got\n%s",
+			path, UpdateEnvKey, expected, actual)
+	}
+}
+
+// Transport replays fixtures over HTTP: a request whose path ends with a key
+// is answered with the content of the fixture file the key maps to, and any
+// other request with 404
+type Transport map[string]string
+
+// RoundTrip serves the fixture matching req
+func (rt Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for suffix, path := range rt {
+		if !strings.HasSuffix(req.URL.Path, suffix) {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return response(req, http.StatusOK, data), nil
+	}
+	return response(req, http.StatusNotFound, []byte(`{"statusCode":404,"message":"no fixture"}`)), nil
+}
+
+func response(req *http.Request, status int, body []byte) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader(body)),
+		Request:    req,
+	}
+}
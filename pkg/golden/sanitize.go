@@ -0,0 +1,176 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package golden keeps identity provider responses as test fixtures. Sanitize
+// strips personal data from responses recorded against a dev tenant, and
+// Assert compares what an adapter makes of a fixture with a golden file, so a
+// change in a provider's payloads shows up as a test diff before deployment.
+package golden
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// fieldClass groups the fields that get the same kind of placeholder
+type fieldClass int
+
+const (
+	classNone fieldClass = iota
+	classEmail
+	classID
+	classIP
+	classURL
+	classText
+	classSecret
+)
+
+// fieldClasses maps the personal fields of Auth0 users and OIDC userinfo
+// documents to their placeholder. Unlisted fields are kept.
+var fieldClasses = map[string]fieldClass{
+	"email":              classEmail,
+	"primary_email":      classEmail,
+	"user_id":            classID,
+	"sub":                classID,
+	"last_ip":            classIP,
+	"initial_ip":         classIP,
+	"ip":                 classIP,
+	"picture":            classURL,
+	"name":               classText,
+	"given_name":         classText,
+	"family_name":        classText,
+	"nickname":           classText,
+	"username":           classText,
+	"preferred_username": classText,
+	"displayname":        classText,
+	"phone_number":       classText,
+	"address":            classText,
+	"city":               classText,
+	"postal_code":        classText,
+	"user_agent":         classText,
+	"initial_user_agent": classText,
+	"last_user_agent":    classText,
+	"access_token":       classSecret,
+	"refresh_token":      classSecret,
+	"id_token":           classSecret,
+}
+
+// sanitizer replaces values consistently: the same value gets the same
+// placeholder everywhere in a document, so links between fields survive
+type sanitizer struct {
+	replacements map[fieldClass]map[string]string
+}
+
+// Sanitize returns the JSON document raw with personal data replaced by
+// placeholders, indented and with sorted keys so fixtures diff cleanly.
+// Placeholders are numbered in document order, so re-recording the same
+// data produces the same fixture.
+func Sanitize(raw []byte) ([]byte, error) {
+	var document any
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&document); err != nil {
+		return nil, fmt.Errorf("fixture is not JSON: %w", err)
+	}
+
+	s := &sanitizer{replacements: make(map[fieldClass]map[string]string)}
+	document = s.walk("", document)
+
+	out, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+func (s *sanitizer) walk(key string, value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			v[k] = s.walk(k, v[k])
+		}
+		return v
+	case []any:
+		for i := range v {
+			v[i] = s.walk(key, v[i])
+		}
+		return v
+	case string:
+		if v == "" {
+			return v
+		}
+		class := fieldClasses[strings.ToLower(key)]
+		if (class == classNone || class == classText) && looksLikeEmail(v) {
+			// emails in unlisted fields, or used as a name, share the
+			// placeholders of the email fields
+			class = classEmail
+		}
+		if class == classNone {
+			return v
+		}
+		return s.replace(class, v)
+	case json.Number:
+		if fieldClasses[strings.ToLower(key)] == classID {
+			// numeric provider ids, e.g. GitHub identities
+			return json.Number(s.replace(classID, v.String()))
+		}
+		return v
+	}
+	return value
+}
+
+func (s *sanitizer) replace(class fieldClass, value string) string {
+	seen := s.replacements[class]
+	if seen == nil {
+		seen = make(map[string]string)
+		s.replacements[class] = seen
+	}
+	if replacement, ok := seen[value]; ok {
+		return replacement
+	}
+
+	n := len(seen) + 1
+	var replacement string
+	switch class {
+	case classEmail:
+		replacement = fmt.Sprintf("user%d@example.com", n)
+	case classID:
+		if provider, id, ok := strings.Cut(value, "|"); ok {
+			// identities carry the part after the provider as their
+			// user_id, so both get the same placeholder
+			replacement = fmt.Sprintf("%s|%s", provider, s.replace(classID, id))
+		} else if _, err := uuid.Parse(value); err == nil {
+			// Authelia subs
+			replacement = fmt.Sprintf("00000000-0000-4000-8000-%012d", n)
+		} else if _, err := json.Number(value).Int64(); err == nil {
+			replacement = fmt.Sprintf("%d", 100000+n)
+		} else {
+			replacement = fmt.Sprintf("id-%06d", n)
+		}
+	case classIP:
+		replacement = fmt.Sprintf("192.0.2.%d", n%255)
+	case classURL:
+		replacement = fmt.Sprintf("https://example.com/picture/%d.png", n)
+	case classSecret:
+		replacement = "[REDACTED]"
+	default:
+		replacement = fmt.Sprintf("redacted-%d", n)
+	}
+	seen[value] = replacement
+	return replacement
+}
+
+func looksLikeEmail(value string) bool {
+	local, domain, ok := strings.Cut(value, "@")
+	return ok && local != "" && strings.Contains(domain, ".") && !strings.ContainsAny(value, " /")
+}
@@ -0,0 +1,70 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package golden
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSanitize(t *testing.T) {
+	raw := []byte(`{
+		"user_id": "auth0|65a1f0c2e4b0a1b2c3d4e5f6",
+		"email": "jane.doe@example.org",
+		"name": "jane.doe@example.org",
+		"given_name": "Jane",
+		"last_ip": "203.0.113.17",
+		"logins_count": 42,
+		"identities": [
+			{"provider": "auth0", "user_id": "65a1f0c2e4b0a1b2c3d4e5f6"},
+			{"provider": "github", "user_id": 1234567, "profileData": {"email": "jane@users.example.com", "name": "Jane"}}
+		],
+		"user_metadata": {"organization": "Example Foundation", "given_name": "Jane"}
+	}`)
+
+	got, err := Sanitize(raw)
+	if err != nil {
+		t.Fatalf("Sanitize() error = %v", err)
+	}
+	out := string(got)
+
+	for _, leaked := range []string{"jane.doe@example.org", "Jane", "203.0.113.17", "65a1f0c2e4b0a1b2c3d4e5f6", "1234567", "jane@users.example.com"} {
+		if strings.Contains(out, leaked) {
+			t.Errorf("Sanitize() kept %q:\n%s", leaked, out)
+		}
+	}
+	for _, kept := range []string{
+		`"organization": "Example Foundation"`,
+		`"logins_count": 42`,
+		// the email used as a name keeps matching the email
+		`"name": "user1@example.com"`,
+		// the identity keeps matching the user_id it is the primary of
+		`"user_id": "auth0|id-000001"`,
+		`"user_id": "id-000001"`,
+		// numeric ids stay numbers
+		`"user_id": 100002`,
+	} {
+		if !strings.Contains(out, kept) {
+			t.Errorf("Sanitize() output lacks %s:\n%s", kept, out)
+		}
+	}
+	if strings.Count(out, `"redacted-1"`) != 3 {
+		t.Errorf("Sanitize() should replace every occurrence of a name with the same placeholder:\n%s", out)
+	}
+
+	again, err := Sanitize(raw)
+	if err != nil {
+		t.Fatalf("Sanitize() error = %v", err)
+	}
+	if !bytes.Equal(got, again) {
+		t.Error("Sanitize() is not deterministic")
+	}
+}
+
+func TestSanitize_InvalidJSON(t *testing.T) {
+	if _, err := Sanitize([]byte("not json")); err == nil {
+		t.Error("Sanitize() should reject invalid JSON")
+	}
+}
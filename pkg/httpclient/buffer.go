@@ -0,0 +1,57 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package httpclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// maxPooledBufferSize keeps the buffers of unusually large bodies, such as
+// user exports, out of the pool so they don't pin memory
+const maxPooledBufferSize = 64 << 10
+
+// bufferPool recycles the buffers request bodies are encoded into and
+// response bodies are read through, the largest allocations of a call
+var bufferPool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+func getBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBufferSize {
+		return
+	}
+	buf.Reset()
+	bufferPool.Put(buf)
+}
+
+// readBody reads r through a pooled buffer and returns a copy of exactly the
+// body's size, where io.ReadAll would grow its result several times
+func readBody(r io.Reader) ([]byte, error) {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if _, err := buf.ReadFrom(r); err != nil {
+		return nil, err
+	}
+	return bytes.Clone(buf.Bytes()), nil
+}
+
+// EncodeJSON encodes v as a request body into a pooled buffer. The body is
+// only valid until release is called, once the request that sends it has
+// returned.
+func EncodeJSON(v any) (body []byte, release func(), err error) {
+	buf := getBuffer()
+	if err := json.NewEncoder(buf).Encode(v); err != nil {
+		putBuffer(buf)
+		return nil, nil, err
+	}
+	// Encode terminates the document with a newline, Marshal doesn't
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), func() { putBuffer(buf) }, nil
+}
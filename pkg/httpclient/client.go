@@ -4,21 +4,40 @@
 package httpclient
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
-	"strings"
+	"net/url"
+	"sync/atomic"
 	"time"
-
-	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
 // Client represents a generic HTTP client with retry logic
 type Client struct {
 	config     Config
 	httpClient *http.Client
+
+	attempts atomic.Int64
+	failures atomic.Int64
+}
+
+// Stats counts the HTTP attempts a client has made, retries included
+type Stats struct {
+	Requests int64
+	// Errors counts attempts that failed on the server side: transport
+	// errors, 5xx and 429 responses. Other 4xx responses are not counted.
+	Errors int64
+}
+
+// Stats returns the cumulative request counters of the client
+func (c *Client) Stats() Stats {
+	return Stats{
+		Requests: c.attempts.Load(),
+		Errors:   c.failures.Load(),
+	}
 }
 
 // Request represents an HTTP request configuration
@@ -40,16 +59,31 @@ type Response struct {
 type RetryableError struct {
 	StatusCode int
 	Message    string
+	// Headers are the response headers, e.g. for rate-limit reset times
+	Headers http.Header
 }
 
 func (e *RetryableError) Error() string {
 	return e.Message
 }
 
-// Do executes an HTTP request with retry logic
+// Do executes an HTTP request, retrying transient failures with backoff.
+// Only idempotent calls are retried after a failure that may have reached
+// the server; see WithIdempotency.
 func (c *Client) Do(ctx context.Context, req Request) (*Response, error) {
 	var lastErr error
 
+	// the body is read again by every attempt
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+	}
+	safe := idempotent(ctx, req.Method)
+
+	retried, reason := 0, ""
 	for attempt := 0; attempt <= c.config.MaxRetries; attempt++ {
 		if attempt > 0 {
 			// Calculate delay with optional exponential backoff
@@ -57,28 +91,41 @@ func (c *Client) Do(ctx context.Context, req Request) (*Response, error) {
 			if c.config.RetryBackoff {
 				delay = time.Duration(int64(delay) * int64(1<<(attempt-1)))
 			}
+			// a retry that can't start before the caller's deadline would only
+			// hold the request open until it passes
+			if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+				break
+			}
 
 			select {
 			case <-ctx.Done():
 				return nil, ctx.Err()
 			case <-time.After(delay):
 			}
+			retried++
+			recordRetry(ctx, requestHost(req.URL), req.Method, reason)
 		}
 
+		if body != nil {
+			req.Body = bytes.NewReader(body)
+		}
 		response, err := c.doRequest(ctx, req)
 		if err == nil {
+			if retried > 0 {
+				slog.InfoContext(ctx, "request succeeded after retries", "method", req.Method, "retries", retried)
+			}
 			return response, nil
 		}
 
 		lastErr = err
 
-		// Don't retry on certain errors
-		if !c.shouldRetry(err) {
+		// Don't retry on certain errors, nor once the context is done
+		if reason = retryReason(err, safe); ctx.Err() != nil || reason == "" {
 			break
 		}
 	}
 
-	slog.ErrorContext(ctx, "request failed", "error", lastErr)
+	slog.ErrorContext(ctx, "request failed", "error", lastErr, "method", req.Method, "retries", retried, "idempotent", safe)
 
 	return nil, lastErr
 }
@@ -98,13 +145,18 @@ func (c *Client) doRequest(ctx context.Context, reqConfig Request) (*Response, e
 		httpReq.Header.Set(key, value)
 	}
 
+	c.attempts.Add(1)
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
+		c.failures.Add(1)
 		return nil, fmt.Errorf("HTTP request failed: %w", err)
 	}
+	if resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests {
+		c.failures.Add(1)
+	}
 	defer func() { _ = resp.Body.Close() }()
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := readBody(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
@@ -120,6 +172,7 @@ func (c *Client) doRequest(ctx context.Context, reqConfig Request) (*Response, e
 		err := &RetryableError{
 			StatusCode: resp.StatusCode,
 			Message:    string(body),
+			Headers:    resp.Header,
 		}
 		return response, err
 	}
@@ -127,23 +180,13 @@ func (c *Client) doRequest(ctx context.Context, reqConfig Request) (*Response, e
 	return response, nil
 }
 
-// shouldRetry determines if a request should be retried based on the error
-func (c *Client) shouldRetry(err error) bool {
-	if err == nil {
-		return false
-	}
-
-	// Check if it's a retryable error
-	if retryableErr, ok := err.(*RetryableError); ok {
-		// Retry on server errors and rate limiting
-		return retryableErr.StatusCode >= http.StatusInternalServerError || retryableErr.StatusCode == http.StatusTooManyRequests
+// requestHost returns the host of rawURL for metrics, empty when it is invalid
+func requestHost(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
 	}
-
-	// Retry on network-related errors
-	errStr := strings.ToLower(err.Error())
-	return strings.Contains(errStr, "timeout") ||
-		strings.Contains(errStr, "connection") ||
-		strings.Contains(errStr, "network")
+	return parsed.Hostname()
 }
 
 // Request performs an HTTP request with the specified verb
@@ -159,16 +202,11 @@ func (c *Client) Request(ctx context.Context, verb, url string, body io.Reader,
 
 // NewClient creates a new HTTP client with the given configuration.
 // The client is instrumented with OpenTelemetry for distributed tracing.
+// An invalid transport configuration makes every request fail; call
+// Config.Validate at startup to surface it early.
 func NewClient(config Config) *Client {
-	base := config.Transport
-	if base == nil {
-		base = http.DefaultTransport
-	}
 	return &Client{
-		config: config,
-		httpClient: &http.Client{
-			Timeout:   config.Timeout,
-			Transport: otelhttp.NewTransport(base),
-		},
+		config:     config,
+		httpClient: NewStandardClient(config),
 	}
 }
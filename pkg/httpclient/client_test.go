@@ -4,6 +4,7 @@
 package httpclient
 
 import (
+	"bytes"
 	"context"
 	"io"
 	"net/http"
@@ -174,6 +175,39 @@ func TestClient_Retry_ServerError(t *testing.T) {
 	}
 }
 
+func TestClient_Retry_StopsAtDeadline(t *testing.T) {
+	callCount := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		callCount++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	config := Config{
+		Timeout:      5 * time.Second,
+		MaxRetries:   3,
+		RetryDelay:   time.Second,
+		RetryBackoff: false,
+	}
+
+	client := NewClient(config)
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.Request(ctx, "GET", server.URL, nil, nil)
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	if callCount != 1 {
+		t.Errorf("Expected no retry that can't start before the deadline, got %d calls", callCount)
+	}
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Errorf("Expected to give up without waiting for the deadline, took %v", elapsed)
+	}
+}
+
 func TestClient_Post(t *testing.T) {
 	// Create a test server
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -230,3 +264,45 @@ func TestDefaultConfig(t *testing.T) {
 		t.Error("Expected default retry backoff to be true")
 	}
 }
+
+// benchmarkTransport answers every request with the same body, so the
+// benchmarks measure the client rather than the network
+type benchmarkTransport struct {
+	body []byte
+}
+
+func (t benchmarkTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		_, _ = io.Copy(io.Discard, req.Body)
+		_ = req.Body.Close()
+	}
+	return &http.Response{
+		StatusCode:    http.StatusOK,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(t.body)),
+		ContentLength: int64(len(t.body)),
+		Request:       req,
+	}, nil
+}
+
+func BenchmarkAPIRequestCall(b *testing.B) {
+	body := []byte(`{"user_id":"auth0|benchmark","email":"user@example.com","email_verified":true,` +
+		`"identities":[{"connection":"Username-Password-Authentication","user_id":"benchmark","provider":"auth0"}],` +
+		`"user_metadata":{"name":"Bench Mark","job_title":"Engineer","organization":"Example","zoneinfo":"UTC"}}`)
+	client := NewClient(Config{Transport: benchmarkTransport{body: body}})
+	ctx := context.Background()
+	b.ReportAllocs()
+
+	for b.Loop() {
+		request := NewAPIRequest(client,
+			WithMethod(http.MethodPatch),
+			WithURL("https://test.auth0.com/api/v2/users/auth0%7Cbenchmark"),
+			WithToken("test-token"),
+			WithBody(map[string]any{"user_metadata": map[string]string{"job_title": "Engineer"}}),
+		)
+		var out map[string]any
+		if _, err := request.Call(ctx, &out); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -4,6 +4,7 @@
 package httpclient
 
 import (
+	"crypto/tls"
 	"net/http"
 	"time"
 )
@@ -27,6 +28,39 @@ type Config struct {
 	// it lets callers intercept requests without a live network or matching
 	// the request scheme/host.
 	Transport http.RoundTripper
+
+	// TLSClientConfig configures TLS for outbound connections, e.g. a client
+	// certificate for mTLS. Ignored when Transport is set, as are the fields below.
+	TLSClientConfig *tls.Config
+
+	// CAFiles are PEM bundles trusted in addition to the system roots (or to
+	// TLSClientConfig.RootCAs when set), for servers behind a private CA
+	CAFiles []string
+
+	// InsecureSkipVerify disables server certificate verification. Development only.
+	InsecureSkipVerify bool
+
+	// ProxyURL routes requests through the given proxy. Empty honors the
+	// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables; ProxyDirect
+	// disables proxying.
+	ProxyURL string
+
+	// HostOverrides replaces CA bundles, verification and proxy settings for
+	// specific hosts, keyed by host name without port
+	HostOverrides map[string]HostConfig
+
+	// MaxIdleConnsPerHost is how many idle keep-alive connections are kept per
+	// host. Lookup bursts need enough to avoid a TLS handshake per request.
+	MaxIdleConnsPerHost int
+
+	// MaxConnsPerHost caps connections per host, idle or in use. Zero is unlimited.
+	MaxConnsPerHost int
+
+	// IdleConnTimeout closes keep-alive connections left idle this long
+	IdleConnTimeout time.Duration
+
+	// DisableHTTP2 forces HTTP/1.1, e.g. for proxies that mishandle h2
+	DisableHTTP2 bool
 }
 
 // DefaultConfig returns a Config with sensible defaults
@@ -36,5 +70,8 @@ func DefaultConfig() Config {
 		MaxRetries:   2,
 		RetryDelay:   1 * time.Second,
 		RetryBackoff: true,
+
+		MaxIdleConnsPerHost: 32,
+		IdleConnTimeout:     90 * time.Second,
 	}
 }
@@ -0,0 +1,77 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package httpclient
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// meter is safe to initialize at package level — otel.Meter() delegates to
+// whichever MeterProvider is installed when instruments record.
+var meter = otel.Meter("github.com/linuxfoundation/lfx-v2-auth-service/pkg/httpclient")
+
+// poolMetrics records how well the connection pool absorbs the request load:
+// a low reuse ratio or a high handshake rate means MaxIdleConnsPerHost is too
+// small for the traffic.
+type poolMetrics struct {
+	next http.RoundTripper
+
+	active     metric.Int64UpDownCounter
+	conns      metric.Int64Counter
+	handshakes metric.Float64Histogram
+}
+
+func newPoolMetrics(next http.RoundTripper) *poolMetrics {
+	active, _ := meter.Int64UpDownCounter("auth_service.http_client.requests.active",
+		metric.WithDescription("Outbound HTTP requests currently holding a connection"))
+	conns, _ := meter.Int64Counter("auth_service.http_client.connections",
+		metric.WithDescription("Connections obtained for outbound HTTP requests, by whether they were reused from the idle pool"))
+	handshakes, _ := meter.Float64Histogram("auth_service.http_client.tls_handshake.duration",
+		metric.WithDescription("TLS handshakes for new outbound connections"),
+		metric.WithUnit("s"))
+
+	return &poolMetrics{
+		next:       next,
+		active:     active,
+		conns:      conns,
+		handshakes: handshakes,
+	}
+}
+
+func (p *poolMetrics) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	host := attribute.String("server.address", req.URL.Hostname())
+
+	var handshakeStart time.Time
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			p.conns.Add(ctx, 1, metric.WithAttributes(host, attribute.Bool("reused", info.Reused)))
+		},
+		TLSHandshakeStart: func() {
+			handshakeStart = time.Now()
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			if handshakeStart.IsZero() {
+				return
+			}
+			p.handshakes.Record(ctx, time.Since(handshakeStart).Seconds(), metric.WithAttributes(
+				host,
+				attribute.Bool("error", err != nil),
+				attribute.String("protocol", state.NegotiatedProtocol),
+			))
+		},
+	}
+
+	p.active.Add(ctx, 1, metric.WithAttributes(host))
+	defer p.active.Add(ctx, -1, metric.WithAttributes(host))
+
+	return p.next.RoundTrip(req.WithContext(httptrace.WithClientTrace(ctx, trace)))
+}
@@ -0,0 +1,161 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestConfig_TunePool(t *testing.T) {
+	tests := []struct {
+		name          string
+		config        Config
+		wantIdle      int
+		wantTotalIdle int
+		wantTimeout   time.Duration
+		wantHTTP2     bool
+	}{
+		{
+			name:          "defaults",
+			config:        DefaultConfig(),
+			wantIdle:      32,
+			wantTotalIdle: 100,
+			wantTimeout:   90 * time.Second,
+			wantHTTP2:     true,
+		},
+		{
+			name:          "per-host above total",
+			config:        Config{MaxIdleConnsPerHost: 200},
+			wantIdle:      200,
+			wantTotalIdle: 200,
+			wantTimeout:   90 * time.Second,
+			wantHTTP2:     true,
+		},
+		{
+			name:          "http2 disabled",
+			config:        Config{DisableHTTP2: true, IdleConnTimeout: time.Minute},
+			wantIdle:      0,
+			wantTotalIdle: 100,
+			wantTimeout:   time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport, err := tt.config.hostTransport(HostConfig{})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if transport.MaxIdleConnsPerHost != tt.wantIdle {
+				t.Errorf("Expected MaxIdleConnsPerHost %d, got %d", tt.wantIdle, transport.MaxIdleConnsPerHost)
+			}
+			if transport.MaxIdleConns != tt.wantTotalIdle {
+				t.Errorf("Expected MaxIdleConns %d, got %d", tt.wantTotalIdle, transport.MaxIdleConns)
+			}
+			if transport.IdleConnTimeout != tt.wantTimeout {
+				t.Errorf("Expected IdleConnTimeout %v, got %v", tt.wantTimeout, transport.IdleConnTimeout)
+			}
+			if http2 := transport.TLSNextProto == nil; http2 != tt.wantHTTP2 {
+				t.Errorf("Expected HTTP/2 enabled %v, got %v", tt.wantHTTP2, http2)
+			}
+		})
+	}
+}
+
+var (
+	metricsReaderOnce sync.Once
+	metricsReader     *sdkmetric.ManualReader
+)
+
+// testMetricsReader installs a global MeterProvider once per test binary: the
+// package meter binds to the first provider it sees, so it can't be swapped
+// per test.
+func testMetricsReader() *sdkmetric.ManualReader {
+	metricsReaderOnce.Do(func() {
+		metricsReader = sdkmetric.NewManualReader()
+		otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(metricsReader)))
+	})
+	return metricsReader
+}
+
+// poolCounts returns the cumulative new/reused connections and TLS handshakes
+// recorded for host
+func poolCounts(t *testing.T, host string) (newConns, reused int64, handshakes uint64) {
+	t.Helper()
+	var metrics metricdata.ResourceMetrics
+	if err := testMetricsReader().Collect(context.Background(), &metrics); err != nil {
+		t.Fatalf("failed to collect metrics: %v", err)
+	}
+
+	forHost := func(attrs attribute.Set) bool {
+		value, _ := attrs.Value("server.address")
+		return value.AsString() == host
+	}
+	for _, scope := range metrics.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				if m.Name != "auth_service.http_client.connections" {
+					continue
+				}
+				for _, point := range data.DataPoints {
+					if !forHost(point.Attributes) {
+						continue
+					}
+					if value, _ := point.Attributes.Value("reused"); value.AsBool() {
+						reused += point.Value
+					} else {
+						newConns += point.Value
+					}
+				}
+			case metricdata.Histogram[float64]:
+				if m.Name != "auth_service.http_client.tls_handshake.duration" {
+					continue
+				}
+				for _, point := range data.DataPoints {
+					if forHost(point.Attributes) {
+						handshakes += point.Count
+					}
+				}
+			}
+		}
+	}
+	return newConns, reused, handshakes
+}
+
+func TestClient_PoolMetrics(t *testing.T) {
+	testMetricsReader()
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	host := "127.0.0.1"
+	beforeNew, beforeReused, beforeHandshakes := poolCounts(t, host)
+
+	client := NewClient(Config{Timeout: 5 * time.Second, InsecureSkipVerify: true, MaxIdleConnsPerHost: 4})
+	for range 3 {
+		if _, err := client.Request(context.Background(), http.MethodGet, server.URL, nil, nil); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	newConns, reused, handshakes := poolCounts(t, host)
+	if newConns-beforeNew != 1 || reused-beforeReused != 2 {
+		t.Errorf("Expected 1 new and 2 reused connections, got %d new and %d reused", newConns-beforeNew, reused-beforeReused)
+	}
+	if handshakes-beforeHandshakes != 1 {
+		t.Errorf("Expected 1 TLS handshake, got %d", handshakes-beforeHandshakes)
+	}
+}
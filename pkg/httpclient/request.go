@@ -95,20 +95,26 @@ func (a *apiRequest) Call(ctx context.Context, resp any) (int, error) {
 
 	// Prepare the request body if provided
 	if a.Body != nil {
-		requestBody, err = json.Marshal(a.Body)
+		var release func()
+		requestBody, release, err = EncodeJSON(a.Body)
 		if err != nil {
 			return -1, fmt.Errorf("failed to marshal request body: %w", err)
 		}
+		defer release()
 	}
 
-	loggedBody := redaction.RedactJWTs(string(requestBody))
-	if a.sensitiveBody {
-		loggedBody = "[REDACTED]"
+	// redacting the body scans it for tokens, so it is skipped unless the
+	// debug log is written
+	if slog.Default().Enabled(ctx, slog.LevelDebug) {
+		loggedBody := redaction.RedactJWTs(string(requestBody))
+		if a.sensitiveBody {
+			loggedBody = "[REDACTED]"
+		}
+		slog.DebugContext(ctx, "calling API",
+			"method", a.Method,
+			"url", a.URL,
+			"request_body", loggedBody)
 	}
-	slog.DebugContext(ctx, "calling API",
-		"method", a.Method,
-		"url", a.URL,
-		"request_body", loggedBody)
 
 	// Prepare headers; only add Authorization when a token is provided
 	headers := map[string]string{
@@ -0,0 +1,91 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Reasons a failed attempt is retried for, as recorded on the retries metric
+const (
+	retryReasonServerError = "server_error"
+	retryReasonRateLimited = "rate_limited"
+	retryReasonConnection  = "connection"
+)
+
+type idempotencyKey struct{}
+
+// WithIdempotency classifies the calls made with ctx as safe to repeat or
+// not, whatever their method, e.g. a PATCH setting fields to given values.
+// Without it the method decides: GET, HEAD, OPTIONS, PUT and DELETE are
+// idempotent, POST and PATCH are not.
+func WithIdempotency(ctx context.Context, idempotent bool) context.Context {
+	return context.WithValue(ctx, idempotencyKey{}, idempotent)
+}
+
+// idempotent reports whether a call with method may be sent again after a
+// failure that may have reached the server
+func idempotent(ctx context.Context, method string) bool {
+	if value, ok := ctx.Value(idempotencyKey{}).(bool); ok {
+		return value
+	}
+	switch strings.ToUpper(method) {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete, "":
+		return true
+	}
+	return false
+}
+
+// retryReason returns why a failed attempt may be retried, or "" when it may
+// not. Rate-limited calls and connections that could not be opened never
+// reached the server, so they are retried whatever the call; server errors,
+// resets and timeouts may have been processed, so only idempotent calls are.
+func retryReason(err error, idempotent bool) string {
+	var retryable *RetryableError
+	if errors.As(err, &retryable) {
+		switch {
+		case retryable.StatusCode == http.StatusTooManyRequests:
+			return retryReasonRateLimited
+		case retryable.StatusCode >= http.StatusInternalServerError && idempotent:
+			return retryReasonServerError
+		}
+		return ""
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return retryReasonConnection
+	}
+	if !idempotent {
+		return ""
+	}
+	var netErr net.Error
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) ||
+		(errors.As(err, &netErr) && netErr.Timeout()) {
+		return retryReasonConnection
+	}
+	return ""
+}
+
+// retries counts the attempts sent again after a transient failure
+var retries, _ = meter.Int64Counter("auth_service.http_client.retries",
+	metric.WithDescription("Outbound HTTP attempts retried after a transient failure, by reason"))
+
+func recordRetry(ctx context.Context, host, method, reason string) {
+	retries.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("server.address", host),
+		attribute.String("http.request.method", method),
+		attribute.String("reason", reason),
+	))
+}
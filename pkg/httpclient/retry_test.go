@@ -0,0 +1,118 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package httpclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestClient_RetriesOnlyIdempotentCalls(t *testing.T) {
+	testMetricsReader()
+
+	tests := []struct {
+		name      string
+		method    string
+		status    int
+		classify  func(context.Context) context.Context
+		wantCalls int
+	}{
+		{name: "GET after a server error", method: http.MethodGet, status: http.StatusBadGateway, wantCalls: 3},
+		{name: "DELETE after a server error", method: http.MethodDelete, status: http.StatusInternalServerError, wantCalls: 3},
+		{name: "POST after a server error", method: http.MethodPost, status: http.StatusInternalServerError, wantCalls: 1},
+		{name: "PATCH classified idempotent", method: http.MethodPatch, status: http.StatusServiceUnavailable, wantCalls: 3,
+			classify: func(ctx context.Context) context.Context { return WithIdempotency(ctx, true) }},
+		{name: "GET classified not idempotent", method: http.MethodGet, status: http.StatusServiceUnavailable, wantCalls: 1,
+			classify: func(ctx context.Context) context.Context { return WithIdempotency(ctx, false) }},
+		{name: "POST after a rate limit", method: http.MethodPost, status: http.StatusTooManyRequests, wantCalls: 3},
+		{name: "GET after a client error", method: http.MethodGet, status: http.StatusNotFound, wantCalls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var bodies []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				bodies = append(bodies, string(body))
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			client := NewClient(Config{Timeout: 5 * time.Second, MaxRetries: 2, RetryDelay: time.Millisecond})
+			host, _ := url.Parse(server.URL)
+			before := retryCount(t, host.Hostname(), tt.method)
+			ctx := context.Background()
+			if tt.classify != nil {
+				ctx = tt.classify(ctx)
+			}
+
+			_, err := client.Request(ctx, tt.method, server.URL, strings.NewReader(`{"name":"jdoe"}`), nil)
+			if err == nil {
+				t.Fatal("Expected an error")
+			}
+			if len(bodies) != tt.wantCalls {
+				t.Fatalf("Expected %d calls, got %d", tt.wantCalls, len(bodies))
+			}
+			for _, body := range bodies {
+				if body != `{"name":"jdoe"}` {
+					t.Errorf("Expected every attempt to send the body, got %q", body)
+				}
+			}
+			if retried := retryCount(t, host.Hostname(), tt.method) - before; retried != int64(tt.wantCalls-1) {
+				t.Errorf("Expected %d retries recorded, got %d", tt.wantCalls-1, retried)
+			}
+		})
+	}
+}
+
+func TestClient_RetriesRefusedConnections(t *testing.T) {
+	// nothing listens on the port of a closed server, so the POST is never sent
+	server := httptest.NewServer(http.NotFoundHandler())
+	target := server.URL
+	server.Close()
+
+	client := NewClient(Config{Timeout: time.Second, MaxRetries: 1, RetryDelay: time.Millisecond})
+	_, err := client.Request(context.Background(), http.MethodPost, target, strings.NewReader(`{}`), nil)
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	if reason := retryReason(err, false); reason != retryReasonConnection {
+		t.Errorf("Expected a refused connection to be retried, got %q", reason)
+	}
+}
+
+// retryCount returns the retries recorded for method calls to host
+func retryCount(t *testing.T, host, method string) int64 {
+	t.Helper()
+	var metrics metricdata.ResourceMetrics
+	if err := testMetricsReader().Collect(context.Background(), &metrics); err != nil {
+		t.Fatalf("failed to collect metrics: %v", err)
+	}
+
+	var count int64
+	for _, scope := range metrics.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			data, ok := m.Data.(metricdata.Sum[int64])
+			if !ok || m.Name != "auth_service.http_client.retries" {
+				continue
+			}
+			for _, point := range data.DataPoints {
+				pointHost, _ := point.Attributes.Value("server.address")
+				pointMethod, _ := point.Attributes.Value("http.request.method")
+				if pointHost.AsString() == host && pointMethod.AsString() == method {
+					count += point.Value
+				}
+			}
+		}
+	}
+	return count
+}
@@ -0,0 +1,195 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// ProxyDirect disables proxying when used as a ProxyURL
+const ProxyDirect = "direct"
+
+// HostConfig overrides the transport settings for requests to one host.
+// Unset fields inherit the client-wide value.
+type HostConfig struct {
+	// CAFiles replaces the client-wide CA bundles for this host
+	CAFiles []string `json:"ca_files,omitempty"`
+	// InsecureSkipVerify disables certificate verification for this host
+	InsecureSkipVerify bool `json:"insecure_skip_verify,omitempty"`
+	// ProxyURL routes this host through a specific proxy, or "direct"
+	ProxyURL string `json:"proxy_url,omitempty"`
+	// ServerName overrides the name the server certificate is verified against
+	ServerName string `json:"server_name,omitempty"`
+}
+
+// errorTransport fails every request with the configuration error that
+// prevented the transport from being built
+type errorTransport struct {
+	err error
+}
+
+func (t errorTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, t.err
+}
+
+// hostRouter sends each request through the transport configured for its host
+type hostRouter struct {
+	fallback http.RoundTripper
+	hosts    map[string]http.RoundTripper
+}
+
+func (r *hostRouter) RoundTrip(req *http.Request) (*http.Response, error) {
+	if transport, ok := r.hosts[strings.ToLower(req.URL.Hostname())]; ok {
+		return transport.RoundTrip(req)
+	}
+	return r.fallback.RoundTrip(req)
+}
+
+// Validate reports configuration errors, such as unreadable CA bundles or
+// malformed proxy URLs, that would otherwise fail every request
+func (c Config) Validate() error {
+	_, err := c.newTransport()
+	return err
+}
+
+// NewStandardClient returns an *http.Client with the same transport settings
+// and tracing as Client, for SDKs that take a standard client.
+func NewStandardClient(config Config) *http.Client {
+	base, err := config.newTransport()
+	if err != nil {
+		slog.Error("invalid HTTP client configuration, requests will fail", "error", err)
+		base = errorTransport{err: err}
+	}
+	return &http.Client{
+		Timeout:   config.Timeout,
+		Transport: otelhttp.NewTransport(newPoolMetrics(base)),
+	}
+}
+
+// newTransport builds the base round tripper for config
+func (c Config) newTransport() (http.RoundTripper, error) {
+	if c.Transport != nil {
+		return c.Transport, nil
+	}
+	fallback, err := c.hostTransport(HostConfig{})
+	if err != nil {
+		return nil, err
+	}
+	if len(c.HostOverrides) == 0 {
+		return fallback, nil
+	}
+
+	router := &hostRouter{fallback: fallback, hosts: make(map[string]http.RoundTripper, len(c.HostOverrides))}
+	for host, override := range c.HostOverrides {
+		transport, err := c.hostTransport(override)
+		if err != nil {
+			return nil, fmt.Errorf("host override %s: %w", host, err)
+		}
+		router.hosts[strings.ToLower(host)] = transport
+	}
+	return router, nil
+}
+
+// hostTransport builds a transport from the client-wide settings with the
+// given override applied
+func (c Config) hostTransport(override HostConfig) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	c.tunePool(transport)
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if c.TLSClientConfig != nil {
+		tlsConfig = c.TLSClientConfig.Clone()
+	}
+
+	caFiles := c.CAFiles
+	if len(override.CAFiles) > 0 {
+		caFiles = override.CAFiles
+	}
+	if len(caFiles) > 0 {
+		pool, err := certPool(tlsConfig.RootCAs, caFiles)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if override.ServerName != "" {
+		tlsConfig.ServerName = override.ServerName
+	}
+	if c.InsecureSkipVerify || override.InsecureSkipVerify {
+		slog.Warn("TLS certificate verification is disabled for outbound HTTP calls; use only in development")
+		tlsConfig.InsecureSkipVerify = true
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	proxyURL := c.ProxyURL
+	if override.ProxyURL != "" {
+		proxyURL = override.ProxyURL
+	}
+	switch proxyURL {
+	case "":
+		// HTTP_PROXY, HTTPS_PROXY and NO_PROXY, as set by http.DefaultTransport
+	case ProxyDirect:
+		transport.Proxy = nil
+	default:
+		parsed, err := url.Parse(proxyURL)
+		if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q", proxyURL)
+		}
+		transport.Proxy = http.ProxyURL(parsed)
+	}
+	return transport, nil
+}
+
+// tunePool applies the connection pool and protocol settings, keeping the
+// http.DefaultTransport values for unset fields
+func (c Config) tunePool(transport *http.Transport) {
+	if c.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = c.MaxIdleConnsPerHost
+		// The total idle limit must not starve the per-host one
+		transport.MaxIdleConns = max(transport.MaxIdleConns, c.MaxIdleConnsPerHost)
+	}
+	if c.MaxConnsPerHost > 0 {
+		transport.MaxConnsPerHost = c.MaxConnsPerHost
+	}
+	if c.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = c.IdleConnTimeout
+	}
+	if c.DisableHTTP2 {
+		transport.ForceAttemptHTTP2 = false
+		// A non-nil empty map is what turns off the bundled HTTP/2 support
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+}
+
+// certPool returns base (or the system roots) with the PEM bundles appended
+func certPool(base *x509.CertPool, files []string) (*x509.CertPool, error) {
+	var pool *x509.CertPool
+	if base != nil {
+		pool = base.Clone()
+	} else if system, err := x509.SystemCertPool(); err == nil {
+		pool = system
+	} else {
+		pool = x509.NewCertPool()
+	}
+
+	for _, file := range files {
+		pem, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle %s: %w", file, err)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("CA bundle %s contains no certificates", file)
+		}
+	}
+	return pool, nil
+}
@@ -0,0 +1,173 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package httpclient
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeServerCA writes the test server's certificate as a PEM bundle
+func writeServerCA(t *testing.T, server *httptest.Server) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	block := &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatalf("failed to write CA bundle: %v", err)
+	}
+	return path
+}
+
+func TestClient_CAFiles(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	caFile := writeServerCA(t, server)
+
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{name: "system roots only", config: Config{}, wantErr: true},
+		{name: "ca bundle", config: Config{CAFiles: []string{caFile}}},
+		{name: "insecure skip verify", config: Config{InsecureSkipVerify: true}},
+		{
+			name: "host override ca bundle",
+			config: Config{HostOverrides: map[string]HostConfig{
+				"127.0.0.1": {CAFiles: []string{caFile}},
+			}},
+		},
+		{
+			name: "override for another host",
+			config: Config{HostOverrides: map[string]HostConfig{
+				"example.com": {CAFiles: []string{caFile}},
+			}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.Timeout = 5 * time.Second
+			client := NewClient(tt.config)
+			_, err := client.Request(context.Background(), http.MethodGet, server.URL, nil, nil)
+			if tt.wantErr && err == nil {
+				t.Error("Expected certificate verification error, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestClient_ProxyURL(t *testing.T) {
+	var proxied int
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer proxy.Close()
+
+	client := NewClient(Config{Timeout: 5 * time.Second, ProxyURL: proxy.URL})
+	if _, err := client.Request(context.Background(), http.MethodGet, "http://upstream.invalid/users", nil, nil); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if proxied != 1 {
+		t.Errorf("Expected request to go through the proxy, got %d proxied requests", proxied)
+	}
+}
+
+func TestConfig_ProxyResolution(t *testing.T) {
+	config := Config{
+		HostOverrides: map[string]HostConfig{
+			"direct.example.com": {ProxyURL: ProxyDirect},
+			"custom.example.com": {ProxyURL: "http://custom-proxy:8080"},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		override  HostConfig
+		wantProxy bool
+		want      string
+	}{
+		// http.ProxyFromEnvironment caches the environment on first use, so
+		// only check that it is kept rather than the proxy it resolves to
+		{name: "environment", override: HostConfig{}, wantProxy: true},
+		{name: "direct", override: config.HostOverrides["direct.example.com"]},
+		{name: "custom", override: config.HostOverrides["custom.example.com"], wantProxy: true, want: "http://custom-proxy:8080"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport, err := config.hostTransport(tt.override)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if (transport.Proxy != nil) != tt.wantProxy {
+				t.Fatalf("Expected proxy function set %v, got %v", tt.wantProxy, transport.Proxy != nil)
+			}
+			if tt.want == "" {
+				return
+			}
+
+			req := &http.Request{URL: &url.URL{Scheme: "https", Host: "api.example.com"}}
+			proxyURL, err := transport.Proxy(req)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if proxyURL == nil || proxyURL.String() != tt.want {
+				t.Errorf("Expected proxy %q, got %v", tt.want, proxyURL)
+			}
+		})
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	emptyBundle := filepath.Join(t.TempDir(), "empty.pem")
+	if err := os.WriteFile(emptyBundle, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatalf("failed to write bundle: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{name: "default", config: DefaultConfig()},
+		{name: "missing ca bundle", config: Config{CAFiles: []string{"/nonexistent/ca.pem"}}, wantErr: true},
+		{name: "bundle without certificates", config: Config{CAFiles: []string{emptyBundle}}, wantErr: true},
+		{name: "invalid proxy", config: Config{ProxyURL: "proxy:3128"}, wantErr: true},
+		{
+			name: "invalid override proxy",
+			config: Config{HostOverrides: map[string]HostConfig{
+				"example.com": {ProxyURL: "::"},
+			}},
+			wantErr: true,
+		},
+		{name: "direct", config: Config{ProxyURL: ProxyDirect}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr && err == nil {
+				t.Error("Expected error, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package i18n translates the error messages of the service for the
+// front-ends showing them to users. Errors are recognized by the codes of
+// codes.json, whose patterns match the English error text and capture its
+// variable parts; messages/<language>.json holds the message of each code in
+// one language, with the captured parts written as {name}.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// Fallback is the language of the messages when none of the requested
+// languages has a catalog
+var Fallback = language.English
+
+//go:embed codes.json messages/*.json
+var catalogFiles embed.FS
+
+// errorCode recognizes the error messages of one code
+type errorCode struct {
+	Code    string `json:"code"`
+	Pattern string `json:"pattern"`
+
+	pattern *regexp.Regexp
+}
+
+// Catalog holds the error codes and their message in every language
+type Catalog struct {
+	codes     []errorCode
+	languages []language.Tag
+	messages  []map[string]string
+	matcher   language.Matcher
+}
+
+// Localized is an error message in the language negotiated for a request
+type Localized struct {
+	Code     string
+	Message  string
+	Language language.Tag
+}
+
+// Load reads the catalogs embedded in the package. Every code must have an
+// English message, and messages can only use the parts their code captures.
+func Load() (*Catalog, error) {
+	data, err := catalogFiles.ReadFile("codes.json")
+	if err != nil {
+		return nil, err
+	}
+	catalog := &Catalog{}
+	if err := json.Unmarshal(data, &catalog.codes); err != nil {
+		return nil, fmt.Errorf("codes.json: %w", err)
+	}
+	captures := make(map[string][]string, len(catalog.codes))
+	for i, code := range catalog.codes {
+		if catalog.codes[i].pattern, err = regexp.Compile(code.Pattern); err != nil {
+			return nil, fmt.Errorf("codes.json: %s: %w", code.Code, err)
+		}
+		captures[code.Code] = catalog.codes[i].pattern.SubexpNames()
+	}
+
+	files, err := catalogFiles.ReadDir("messages")
+	if err != nil {
+		return nil, err
+	}
+	// the fallback comes first, so the matcher picks it when nothing matches
+	catalog.languages = []language.Tag{Fallback}
+	catalog.messages = []map[string]string{nil}
+	for _, file := range files {
+		tag, err := language.Parse(strings.TrimSuffix(file.Name(), ".json"))
+		if err != nil {
+			return nil, fmt.Errorf("messages/%s: %w", file.Name(), err)
+		}
+		data, err := catalogFiles.ReadFile(path.Join("messages", file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var messages map[string]string
+		if err := json.Unmarshal(data, &messages); err != nil {
+			return nil, fmt.Errorf("messages/%s: %w", file.Name(), err)
+		}
+		for code, message := range messages {
+			names, ok := captures[code]
+			if !ok {
+				return nil, fmt.Errorf("messages/%s: unknown code %s", file.Name(), code)
+			}
+			if placeholder := unknownPlaceholder(message, names); placeholder != "" {
+				return nil, fmt.Errorf("messages/%s: %s: %s isn't captured by its pattern", file.Name(), code, placeholder)
+			}
+		}
+		if tag == Fallback {
+			catalog.messages[0] = messages
+			continue
+		}
+		catalog.languages = append(catalog.languages, tag)
+		catalog.messages = append(catalog.messages, messages)
+	}
+	for _, code := range catalog.codes {
+		if catalog.messages[0][code.Code] == "" {
+			return nil, fmt.Errorf("code %s has no %s message", code.Code, Fallback)
+		}
+	}
+	catalog.matcher = language.NewMatcher(catalog.languages)
+	return catalog, nil
+}
+
+// placeholderPattern matches the {name} parts of a message
+var placeholderPattern = regexp.MustCompile(`\{([a-z_]+)\}`)
+
+func unknownPlaceholder(message string, names []string) string {
+	for _, match := range placeholderPattern.FindAllStringSubmatch(message, -1) {
+		known := false
+		for _, name := range names {
+			known = known || (name != "" && name == match[1])
+		}
+		if !known {
+			return match[0]
+		}
+	}
+	return ""
+}
+
+// Languages returns the languages with a catalog, the fallback first
+func (c *Catalog) Languages() []language.Tag {
+	return c.languages
+}
+
+// Localize returns the message of the error in the language best matching
+// locale, a language tag or an Accept-Language list such as "fr-CA,fr;q=0.8".
+// A message missing in that language is given in the fallback language. ok
+// is false when the error matches no code.
+func (c *Catalog) Localize(locale, err string) (localized Localized, ok bool) {
+	for _, code := range c.codes {
+		match := code.pattern.FindStringSubmatch(err)
+		if match == nil {
+			continue
+		}
+
+		index := c.negotiate(locale)
+		message, found := c.messages[index][code.Code]
+		if !found {
+			index, message = 0, c.messages[0][code.Code]
+		}
+		names := code.pattern.SubexpNames()
+		message = placeholderPattern.ReplaceAllStringFunc(message, func(placeholder string) string {
+			for i, name := range names {
+				if i > 0 && "{"+name+"}" == placeholder {
+					return match[i]
+				}
+			}
+			return placeholder
+		})
+		return Localized{Code: code.Code, Message: message, Language: c.languages[index]}, true
+	}
+	return Localized{}, false
+}
+
+// negotiate returns the index of the catalog best matching locale
+func (c *Catalog) negotiate(locale string) int {
+	if strings.TrimSpace(locale) == "" {
+		return 0
+	}
+	tags, _, err := language.ParseAcceptLanguage(locale)
+	if err != nil || len(tags) == 0 {
+		return 0
+	}
+	_, index, confidence := c.matcher.Match(tags...)
+	if confidence == language.No {
+		return 0
+	}
+	return index
+}
@@ -0,0 +1,95 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package i18n
+
+import (
+	"testing"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/language"
+)
+
+func TestLoad(t *testing.T) {
+	catalog, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, Fallback, catalog.Languages()[0])
+	assert.Len(t, catalog.Languages(), 6)
+
+	// every catalog translates every code, so no language silently falls back
+	for i, messages := range catalog.messages {
+		for _, code := range catalog.codes {
+			assert.NotEmpty(t, messages[code.Code], "%s has no %s message", code.Code, catalog.languages[i])
+		}
+	}
+}
+
+func TestCatalog_Localize(t *testing.T) {
+	catalog, err := Load()
+	require.NoError(t, err)
+
+	for _, tt := range []struct {
+		name, locale, err string
+		want              Localized
+	}{
+		{
+			name:   "captured parts are filled in",
+			locale: "fr",
+			err:    `timezone "Europe/Pariss" is not an IANA time zone`,
+			want:   Localized{Code: "timezone_invalid", Message: "« Europe/Pariss » n'est pas un fuseau horaire valide.", Language: language.French},
+		},
+		{
+			name:   "the closest language of an Accept-Language list",
+			locale: "da, es-MX;q=0.9, en;q=0.5",
+			err:    "failed_to_unmarshal_request",
+			want:   Localized{Code: "failed_to_unmarshal_request", Message: "No se pudo leer la solicitud.", Language: language.Spanish},
+		},
+		{
+			name: "English without a locale",
+			err:  `country "Untied States" is not an ISO 3166-1 country; did you mean US (United States)?`,
+			want: Localized{Code: "country_invalid_suggestion", Message: `"Untied States" is not a known country. Did you mean US (United States)?`, Language: language.English},
+		},
+		{
+			name:   "English for a language without a catalog",
+			locale: "ko-KR",
+			err:    "consent_unavailable",
+			want:   Localized{Code: "feature_unavailable", Message: "This feature is currently unavailable.", Language: language.English},
+		},
+		{
+			name:   "English for an invalid locale",
+			locale: "not a;locale",
+			err:    "verified_field_read_only",
+			want:   Localized{Code: "verified_field_read_only", Message: "This field was verified and can no longer be changed.", Language: language.English},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := catalog.Localize(tt.locale, tt.err)
+			require.True(t, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, ok := catalog.Localize("fr", "the users are already the same account")
+	assert.False(t, ok, "errors without a code aren't localized")
+}
+
+func TestUnknownPlaceholder(t *testing.T) {
+	names := []string{"", "value"}
+	assert.Empty(t, unknownPlaceholder("{value} is invalid", names))
+	assert.Equal(t, "{field}", unknownPlaceholder("{field} is {value}", names))
+}
+
+func TestCatalog_ProviderTaxonomy(t *testing.T) {
+	catalog, err := Load()
+	require.NoError(t, err)
+
+	// a classified provider error replies the code of the taxonomy, whichever
+	// provider raised it
+	for _, code := range []errors.Code{errors.CodeUserNotFound, errors.CodeUserBlocked, errors.CodeRateLimited, errors.CodeTokenInvalid} {
+		localized, ok := catalog.Localize("", errors.Message(code))
+		require.True(t, ok, "%s has no catalog code", code)
+		assert.Equal(t, string(code), localized.Code)
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package idempotency carries the idempotency key of a request, so a caller
+// retrying a mutation after a timeout gets the reply of the first attempt
+// instead of applying the mutation twice.
+//
+// Callers send the key in the Lfx-Idempotency-Key request header, or as the
+// idempotency_key member of a JSON payload; the header wins when both are
+// set. A key is only meaningful on the subject it was sent to, and must be
+// unique per mutation, e.g. a random UUID generated before the first attempt.
+package idempotency
+
+import (
+	"context"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	// Header is the request header holding the idempotency key
+	Header = "Lfx-Idempotency-Key"
+	// Member is the JSON payload member holding the idempotency key
+	Member = "idempotency_key"
+
+	// MaxKeyLength is the longest key accepted; a UUID is 36 characters
+	MaxKeyLength = 128
+)
+
+type keyKey struct{}
+
+// WithKey returns ctx carrying the idempotency key of its request
+func WithKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, keyKey{}, key)
+}
+
+// KeyFromContext returns the idempotency key of ctx, empty when the request
+// has none
+func KeyFromContext(ctx context.Context) string {
+	key, _ := ctx.Value(keyKey{}).(string)
+	return key
+}
+
+// Context returns ctx carrying the idempotency key of a request with
+// header, and ctx itself when the request has none
+func Context(ctx context.Context, header nats.Header) context.Context {
+	key := strings.TrimSpace(header.Get(Header))
+	if key == "" {
+		return ctx
+	}
+	return WithKey(ctx, key)
+}
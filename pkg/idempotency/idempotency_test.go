@@ -0,0 +1,30 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package idempotency
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContext(t *testing.T) {
+	tests := []struct {
+		name   string
+		header nats.Header
+		want   string
+	}{
+		{"no header", nats.Header{}, ""},
+		{"key", nats.Header{Header: {"7f9b2c1e-0d4a-4c55-9a51-3f1c2b7e8d90"}}, "7f9b2c1e-0d4a-4c55-9a51-3f1c2b7e8d90"},
+		{"trimmed", nats.Header{Header: {"  retry-1 "}}, "retry-1"},
+		{"blank", nats.Header{Header: {"   "}}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, KeyFromContext(Context(context.Background(), tt.header)))
+		})
+	}
+}
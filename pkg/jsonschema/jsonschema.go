@@ -0,0 +1,324 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package jsonschema validates JSON values against the subset of JSON Schema
+// (draft 2020-12) the service's request schemas use: type, properties,
+// required, additionalProperties, items, enum, anyOf and the length, range
+// and size bounds. Other keywords, such as title, description or format, are
+// accepted and ignored, so the schemas can document more than is checked.
+// Reflect derives the schema of a Go type, for the documents describing the
+// payloads the service publishes.
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Schema is a JSON Schema, or the subset of it that is validated
+type Schema struct {
+	Type                 Types              `json:"type,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+
+	// never is set by the false schema, which no value matches
+	never bool
+}
+
+// UnmarshalJSON accepts the boolean schemas, true matching every value and
+// false none, besides schema objects
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	switch string(bytes.TrimSpace(data)) {
+	case "true":
+		*s = Schema{}
+		return nil
+	case "false":
+		*s = Schema{never: true}
+		return nil
+	}
+	type plain Schema
+	return json.Unmarshal(data, (*plain)(s))
+}
+
+// Types are the JSON types a schema allows, written as a single type name
+// or a list of them
+type Types []string
+
+// UnmarshalJSON accepts "string" as well as ["string", "null"]
+func (t *Types) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = Types{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("type must be a type name or a list of them: %w", err)
+	}
+	*t = list
+	return nil
+}
+
+// allows reports whether a value of JSON type kind is allowed; integers are
+// numbers too
+func (t Types) allows(kind string) bool {
+	if len(t) == 0 || slices.Contains(t, kind) {
+		return true
+	}
+	return kind == "integer" && slices.Contains(t, "number")
+}
+
+// Parse decodes a JSON Schema document
+func Parse(data []byte) (*Schema, error) {
+	var schema Schema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("invalid JSON Schema: %w", err)
+	}
+	return &schema, nil
+}
+
+// Error is a value not matching its schema, at Path: a JSON Pointer to the
+// value, "/" for the root
+type Error struct {
+	Path    string
+	Message string
+}
+
+func (e Error) Error() string {
+	return e.Path + ": " + e.Message
+}
+
+// Errors are every mismatch of a validated value
+type Errors []Error
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// ValidateJSON decodes data and validates it, returning Errors when it is
+// malformed or doesn't match
+func (s *Schema) ValidateJSON(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return Errors{{Path: "/", Message: "invalid JSON: " + err.Error()}}
+	}
+	if decoder.More() {
+		return Errors{{Path: "/", Message: "invalid JSON: unexpected data after the value"}}
+	}
+	return s.Validate(value)
+}
+
+// Validate validates a decoded JSON value: nil, bool, json.Number or
+// float64, string, []any or map[string]any. It returns Errors, or nil when
+// the value matches.
+func (s *Schema) Validate(value any) error {
+	var errs Errors
+	s.validate("", value, &errs)
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func (s *Schema) validate(path string, value any, errs *Errors) {
+	report := func(format string, args ...any) {
+		*errs = append(*errs, Error{Path: pointer(path), Message: fmt.Sprintf(format, args...)})
+	}
+	if s.never {
+		report("is not allowed")
+		return
+	}
+
+	kind := kindOf(value)
+	if !s.Type.allows(kind) {
+		report("expected %s, got %s", strings.Join(s.Type, " or "), kind)
+		return
+	}
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(allowed any) bool { return equal(allowed, value) }) {
+		report("must be one of %s", enumList(s.Enum))
+	}
+	if len(s.AnyOf) > 0 {
+		s.validateAnyOf(path, value, kind, errs)
+	}
+
+	switch typed := value.(type) {
+	case string:
+		length := utf8.RuneCountInString(typed)
+		if s.MinLength != nil && length < *s.MinLength {
+			if *s.MinLength == 1 {
+				report("must not be empty")
+			} else {
+				report("must be at least %d characters", *s.MinLength)
+			}
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			report("must be at most %d characters", *s.MaxLength)
+		}
+	case json.Number, float64:
+		number := toFloat(typed)
+		if s.Minimum != nil && number < *s.Minimum {
+			report("must be at least %s", formatNumber(*s.Minimum))
+		}
+		if s.Maximum != nil && number > *s.Maximum {
+			report("must be at most %s", formatNumber(*s.Maximum))
+		}
+	case []any:
+		if s.MinItems != nil && len(typed) < *s.MinItems {
+			report("must have at least %d items", *s.MinItems)
+		}
+		if s.MaxItems != nil && len(typed) > *s.MaxItems {
+			report("must have at most %d items", *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range typed {
+				s.Items.validate(path+"/"+strconv.Itoa(i), item, errs)
+			}
+		}
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, present := typed[name]; !present {
+				*errs = append(*errs, Error{Path: path + "/" + escape(name), Message: "is required"})
+			}
+		}
+		for _, name := range sortedKeys(typed) {
+			member := path + "/" + escape(name)
+			if property, declared := s.Properties[name]; declared {
+				property.validate(member, typed[name], errs)
+			} else if s.AdditionalProperties != nil {
+				s.AdditionalProperties.validate(member, typed[name], errs)
+			}
+		}
+	}
+}
+
+// validateAnyOf reports the errors of the only alternative allowing the
+// value's type, the clearest explanation of a mismatch, or that no
+// alternative matched
+func (s *Schema) validateAnyOf(path string, value any, kind string, errs *Errors) {
+	var candidates []*Schema
+	var expected []string
+	for _, alternative := range s.AnyOf {
+		if alternative.Validate(value) == nil {
+			return
+		}
+		if alternative.Type.allows(kind) {
+			candidates = append(candidates, alternative)
+		}
+		expected = append(expected, alternative.Type...)
+	}
+
+	switch len(candidates) {
+	case 0:
+		*errs = append(*errs, Error{Path: pointer(path), Message: fmt.Sprintf("expected %s, got %s", strings.Join(expected, " or "), kind)})
+	case 1:
+		candidates[0].validate(path, value, errs)
+	default:
+		*errs = append(*errs, Error{Path: pointer(path), Message: "does not match any of the allowed forms"})
+	}
+}
+
+// kindOf names the JSON type of a decoded value
+func kindOf(value any) string {
+	switch typed := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if _, err := typed.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case float64:
+		if typed == float64(int64(typed)) {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func toFloat(value any) float64 {
+	switch typed := value.(type) {
+	case json.Number:
+		number, _ := typed.Float64()
+		return number
+	case float64:
+		return typed
+	}
+	return 0
+}
+
+// equal compares an enum member with a decoded value
+func equal(allowed, value any) bool {
+	switch typed := value.(type) {
+	case json.Number, float64:
+		if _, isNumber := allowed.(float64); isNumber {
+			return toFloat(typed) == allowed
+		}
+		return false
+	}
+	return allowed == value
+}
+
+func enumList(enum []any) string {
+	members := make([]string, len(enum))
+	for i, member := range enum {
+		encoded, _ := json.Marshal(member)
+		members[i] = string(encoded)
+	}
+	return strings.Join(members, ", ")
+}
+
+func formatNumber(number float64) string {
+	return strconv.FormatFloat(number, 'g', -1, 64)
+}
+
+// escape escapes a member name for a JSON Pointer
+func escape(name string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(name, "~", "~0"), "/", "~1")
+}
+
+// pointer is the JSON Pointer of path, "/" for the root
+func pointer(path string) string {
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+func sortedKeys(members map[string]any) []string {
+	keys := make([]string, 0, len(members))
+	for key := range members {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
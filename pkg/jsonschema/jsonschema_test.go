@@ -0,0 +1,107 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package jsonschema
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const userSchema = `{
+	"type": "object",
+	"required": ["user"],
+	"properties": {
+		"user": {
+			"type": "object",
+			"required": ["auth_token"],
+			"properties": {"auth_token": {"type": "string", "minLength": 1}}
+		},
+		"scopes": {"type": "array", "items": {"type": "string"}, "maxItems": 2},
+		"page": {"type": "integer", "minimum": 1},
+		"mode": {"enum": ["", "typeahead"]},
+		"name": {"type": ["string", "null"], "maxLength": 3}
+	},
+	"additionalProperties": false
+}`
+
+func TestValidateJSON(t *testing.T) {
+	schema, err := Parse([]byte(userSchema))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		payload string
+		want    Errors
+	}{
+		{"valid", `{"user":{"auth_token":"t"},"scopes":["a"],"page":2,"mode":"typeahead","name":null}`, nil},
+		{"missing member", `{"user":{}}`, Errors{{Path: "/user/auth_token", Message: "is required"}}},
+		{"wrong type", `{"user":{"auth_token":42}}`, Errors{{Path: "/user/auth_token", Message: "expected string, got integer"}}},
+		{"empty string", `{"user":{"auth_token":""}}`, Errors{{Path: "/user/auth_token", Message: "must not be empty"}}},
+		{"array item", `{"user":{"auth_token":"t"},"scopes":["a",true]}`, Errors{{Path: "/scopes/1", Message: "expected string, got boolean"}}},
+		{"array size", `{"user":{"auth_token":"t"},"scopes":["a","b","c"]}`, Errors{{Path: "/scopes", Message: "must have at most 2 items"}}},
+		{"integer", `{"user":{"auth_token":"t"},"page":1.5}`, Errors{{Path: "/page", Message: "expected integer, got number"}}},
+		{"minimum", `{"user":{"auth_token":"t"},"page":0}`, Errors{{Path: "/page", Message: "must be at least 1"}}},
+		{"enum", `{"user":{"auth_token":"t"},"mode":"fuzzy"}`, Errors{{Path: "/mode", Message: `must be one of "", "typeahead"`}}},
+		{"max length", `{"user":{"auth_token":"t"},"name":"Zephyr"}`, Errors{{Path: "/name", Message: "must be at most 3 characters"}}},
+		{"unknown member", `{"user":{"auth_token":"t"},"extra":1}`, Errors{{Path: "/extra", Message: "is not allowed"}}},
+		{"root type", `[]`, Errors{{Path: "/", Message: "expected object, got array"}}},
+		{"several errors", `{"user":{"auth_token":1},"page":0}`, Errors{
+			{Path: "/page", Message: "must be at least 1"},
+			{Path: "/user/auth_token", Message: "expected string, got integer"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := schema.ValidateJSON([]byte(tt.payload))
+			if tt.want == nil {
+				assert.NoError(t, err)
+				return
+			}
+			var got Errors
+			require.True(t, errors.As(err, &got), "expected Errors, got %v", err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestValidateJSON_Malformed(t *testing.T) {
+	schema, err := Parse([]byte(`{"type":"object"}`))
+	require.NoError(t, err)
+
+	err = schema.ValidateJSON([]byte(`{"user":`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "/: invalid JSON")
+
+	err = schema.ValidateJSON([]byte(`{} {}`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unexpected data after the value")
+}
+
+func TestValidate_AnyOf(t *testing.T) {
+	schema, err := Parse([]byte(`{"anyOf": [
+		{"type": "string", "minLength": 1},
+		{"type": "object", "required": ["input"], "properties": {"input": {"type": "string"}}}
+	]}`))
+	require.NoError(t, err)
+
+	assert.NoError(t, schema.Validate("zephyr.stormwind"))
+	assert.NoError(t, schema.Validate(map[string]any{"input": "zephyr.stormwind"}))
+	assert.EqualError(t, schema.Validate(""), "/: must not be empty")
+	assert.EqualError(t, schema.Validate(map[string]any{}), "/input: is required")
+	assert.EqualError(t, schema.Validate(true), "/: expected string or object, got boolean")
+}
+
+func TestParse_BooleanSchemas(t *testing.T) {
+	schema, err := Parse([]byte(`{"properties": {"anything": true, "nothing": false}}`))
+	require.NoError(t, err)
+
+	assert.NoError(t, schema.Validate(map[string]any{"anything": 1.0}))
+	assert.EqualError(t, schema.Validate(map[string]any{"nothing": 1.0}), "/nothing: is not allowed")
+
+	_, err = Parse([]byte(`{"type": 1}`))
+	assert.Error(t, err)
+}
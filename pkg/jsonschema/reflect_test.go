@@ -0,0 +1,56 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package jsonschema
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type reflectedBase struct {
+	ID string `json:"id"`
+}
+
+type reflectedNode struct {
+	reflectedBase
+	Name     *string          `json:"name,omitempty"`
+	Count    int              `json:"count"`
+	Created  time.Time        `json:"created_at"`
+	Tags     []string         `json:"tags,omitempty"`
+	Labels   map[string]bool  `json:"labels,omitempty"`
+	Raw      json.RawMessage  `json:"raw,omitempty"`
+	Children []*reflectedNode `json:"children,omitempty"`
+	Skipped  string           `json:"-"`
+}
+
+func TestReflect(t *testing.T) {
+	got, err := json.Marshal(Reflect(reflectedNode{}))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"type": "object",
+		"properties": {
+			"id": {"type": "string"},
+			"name": {"type": ["string", "null"]},
+			"count": {"type": "integer"},
+			"created_at": {"type": "string", "format": "date-time"},
+			"tags": {"type": "array", "items": {"type": "string"}},
+			"labels": {"type": "object", "additionalProperties": {"type": "boolean"}},
+			"raw": {},
+			"children": {"type": "array", "items": {}}
+		},
+		"required": ["id", "count", "created_at"]
+	}`, string(got))
+
+	// the reflected schema validates the values of its type
+	schema, err := Parse(got)
+	require.NoError(t, err)
+	name := "root"
+	value, err := json.Marshal(reflectedNode{reflectedBase: reflectedBase{ID: "1"}, Name: &name, Tags: []string{"a"}})
+	require.NoError(t, err)
+	assert.NoError(t, schema.ValidateJSON(value))
+}
@@ -0,0 +1,260 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/cache"
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+)
+
+const (
+	// DPoPHeader is the header a DPoP proof is sent in, on HTTP and NATS alike
+	DPoPHeader = "DPoP"
+
+	// DPoPProofType is the typ header every DPoP proof carries (RFC 9449)
+	DPoPProofType = "dpop+jwt"
+
+	// DPoPNATSMethod is the htm a proof for a NATS request carries. Its htu is
+	// nats:<subject>, so a proof made for an HTTP request can't be replayed
+	// over NATS, nor the other way around.
+	DPoPNATSMethod = "NATS"
+
+	// DefaultDPoPWindow is how far a proof's iat may be from now
+	DefaultDPoPWindow = 5 * time.Minute
+)
+
+// dpopAlgorithms are the signature algorithms proofs may use: asymmetric
+// only, since the verifier only ever learns the public key
+var dpopAlgorithms = []jwa.SignatureAlgorithm{
+	jwa.RS256, jwa.RS384, jwa.RS512,
+	jwa.PS256, jwa.PS384, jwa.PS512,
+	jwa.ES256, jwa.ES384, jwa.ES512,
+	jwa.EdDSA,
+}
+
+// DPoPRequest is the proof sent with a request and what it must be bound to
+type DPoPRequest struct {
+	// Proof is the DPoP header value; empty when the caller sent none
+	Proof string
+	// Method and URI are the htm and htu the proof must carry
+	Method string
+	URI    string
+
+	mu sync.Mutex
+	// accepted is the hash of the access token the proof was accepted for, so
+	// a request verifying its token twice doesn't trip the replay check
+	accepted string
+}
+
+type dpopContextKey struct{}
+
+// WithDPoPRequest returns a copy of ctx carrying the DPoP proof of the request
+// being handled, for the token verification further down the call chain
+func WithDPoPRequest(ctx context.Context, proof, method, uri string) context.Context {
+	return context.WithValue(ctx, dpopContextKey{}, &DPoPRequest{
+		Proof:  strings.TrimSpace(proof),
+		Method: method,
+		URI:    uri,
+	})
+}
+
+// DPoPRequestFromContext returns the DPoP request stored by WithDPoPRequest
+func DPoPRequestFromContext(ctx context.Context) (*DPoPRequest, bool) {
+	request, ok := ctx.Value(dpopContextKey{}).(*DPoPRequest)
+	return request, ok
+}
+
+// NATSDPoPURI is the htu of a proof for a request on subject
+func NATSDPoPURI(subject string) string {
+	return "nats:" + subject
+}
+
+// JKT returns the JWK thumbprint the token is bound to (the cnf.jkt claim),
+// empty for bearer tokens
+func (c *Claims) JKT() string {
+	value, exists := c.GetClaim("cnf")
+	if !exists {
+		return ""
+	}
+	confirmation, ok := value.(map[string]any)
+	if !ok {
+		return ""
+	}
+	jkt, _ := confirmation["jkt"].(string)
+	return jkt
+}
+
+// DPoPVerifier checks that DPoP-bound access tokens come with a valid proof
+// of possession of their key. Proof ids are remembered for the replay window,
+// so each proof is accepted once per replica.
+type DPoPVerifier struct {
+	// Required rejects bearer tokens, accepting DPoP-bound tokens only
+	Required bool
+
+	window time.Duration
+	now    func() time.Time
+
+	mu   sync.Mutex
+	seen *cache.Cache[string, struct{}]
+}
+
+// NewDPoPVerifier creates a verifier accepting proofs issued within window of
+// now, remembering up to maxProofs proof ids. 0 uses DefaultDPoPWindow.
+func NewDPoPVerifier(window time.Duration, maxProofs int, required bool) *DPoPVerifier {
+	if window <= 0 {
+		window = DefaultDPoPWindow
+	}
+	return &DPoPVerifier{
+		Required: required,
+		window:   window,
+		now:      time.Now,
+		// a proof stays replayable for window on either side of its iat
+		seen: cache.New[string, struct{}](2*window, maxProofs),
+	}
+}
+
+// Verify checks the proof the request carries in ctx against the access token
+// and its verified claims. Bearer tokens pass unless the verifier requires
+// DPoP; bound tokens need a proof signed with the key they are bound to.
+func (v *DPoPVerifier) Verify(ctx context.Context, accessToken string, claims *Claims) error {
+	jkt := claims.JKT()
+	request, _ := DPoPRequestFromContext(ctx)
+	if jkt == "" {
+		if v.Required {
+			return errors.NewUnauthorized("access token must be DPoP-bound")
+		}
+		return nil
+	}
+	if request == nil || request.Proof == "" {
+		return errors.NewUnauthorized("DPoP proof is required for a DPoP-bound token")
+	}
+
+	ath := accessTokenHash(accessToken)
+	request.mu.Lock()
+	defer request.mu.Unlock()
+	if request.accepted == ath {
+		return nil
+	}
+
+	proof, err := v.verifyProof(request, ath, jkt)
+	if err != nil {
+		return err
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	replayKey := jkt + ":" + proof.JwtID()
+	if _, replayed := v.seen.Get(replayKey); replayed {
+		return errors.NewUnauthorized("DPoP proof has already been used")
+	}
+	v.seen.Set(replayKey, struct{}{})
+	request.accepted = ath
+	return nil
+}
+
+// verifyProof checks the proof's header, signature and claims and returns them
+func (v *DPoPVerifier) verifyProof(request *DPoPRequest, ath, jkt string) (jwt.Token, error) {
+	message, err := jws.Parse([]byte(request.Proof))
+	if err != nil || len(message.Signatures()) != 1 {
+		return nil, errors.NewUnauthorized("DPoP proof is malformed")
+	}
+	headers := message.Signatures()[0].ProtectedHeaders()
+	if headers.Type() != DPoPProofType {
+		return nil, errors.NewUnauthorized("DPoP proof must have typ dpop+jwt")
+	}
+	if !slices.Contains(dpopAlgorithms, headers.Algorithm()) {
+		return nil, errors.NewUnauthorized("DPoP proof must be signed with an asymmetric algorithm")
+	}
+	key := headers.JWK()
+	if key == nil {
+		return nil, errors.NewUnauthorized("DPoP proof has no jwk header")
+	}
+	if asymmetric, ok := key.(jwk.AsymmetricKey); !ok || asymmetric.IsPrivate() {
+		return nil, errors.NewUnauthorized("DPoP proof jwk must be a public key")
+	}
+
+	thumbprint, err := key.Thumbprint(crypto.SHA256)
+	if err != nil || base64.RawURLEncoding.EncodeToString(thumbprint) != jkt {
+		return nil, errors.NewUnauthorized("DPoP proof key does not match the token binding")
+	}
+
+	payload, err := jws.Verify([]byte(request.Proof), jws.WithKey(headers.Algorithm(), key))
+	if err != nil {
+		return nil, errors.NewUnauthorized("DPoP proof signature is invalid")
+	}
+	proof, err := jwt.Parse(payload, jwt.WithVerify(false), jwt.WithValidate(false))
+	if err != nil {
+		return nil, errors.NewUnauthorized("DPoP proof claims are malformed")
+	}
+
+	if proof.JwtID() == "" {
+		return nil, errors.NewUnauthorized("DPoP proof has no jti")
+	}
+	if htm, _ := proof.Get("htm"); htm != request.Method {
+		return nil, errors.NewUnauthorized("DPoP proof htm does not match the request")
+	}
+	if htu, _ := proof.Get("htu"); !sameDPoPURI(htu, request.URI) {
+		return nil, errors.NewUnauthorized("DPoP proof htu does not match the request")
+	}
+	if proofAth, _ := proof.Get("ath"); proofAth != ath {
+		return nil, errors.NewUnauthorized("DPoP proof ath does not match the access token")
+	}
+	issuedAt := proof.IssuedAt()
+	if issuedAt.IsZero() {
+		return nil, errors.NewUnauthorized("DPoP proof has no iat")
+	}
+	if now := v.now(); issuedAt.Before(now.Add(-v.window)) || issuedAt.After(now.Add(v.window)) {
+		return nil, errors.NewUnauthorized("DPoP proof iat is outside the accepted window")
+	}
+	return proof, nil
+}
+
+// accessTokenHash is the ath a proof carries: the base64url SHA-256 of the
+// access token, without its authorization scheme
+func accessTokenHash(accessToken string) string {
+	accessToken = strings.TrimSpace(accessToken)
+	if parts := strings.Fields(accessToken); len(parts) == 2 && isAuthScheme(parts[0]) {
+		accessToken = parts[1]
+	}
+	hash := sha256.Sum256([]byte(accessToken))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+// sameDPoPURI compares htu with the request URI ignoring query and fragment,
+// as RFC 9449 requires
+func sameDPoPURI(htu any, uri string) bool {
+	value, ok := htu.(string)
+	if !ok {
+		return false
+	}
+	proof, err := url.Parse(value)
+	if err != nil {
+		return false
+	}
+	want, err := url.Parse(uri)
+	if err != nil {
+		return false
+	}
+	proof.RawQuery, proof.Fragment = "", ""
+	want.RawQuery, want.Fragment = "", ""
+	return strings.EqualFold(proof.Scheme, want.Scheme) &&
+		strings.EqualFold(proof.Host, want.Host) &&
+		proof.Opaque == want.Opaque &&
+		proof.EscapedPath() == want.EscapedPath()
+}
@@ -0,0 +1,148 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDPoPVerifier(t *testing.T) {
+	const accessToken = "header.payload.signature"
+	uri := NATSDPoPURI("lfx.auth-service.user_metadata.read")
+
+	newKey := func(t *testing.T) (jwk.Key, string) {
+		t.Helper()
+		raw, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		key, err := jwk.FromRaw(raw)
+		require.NoError(t, err)
+		public, err := key.PublicKey()
+		require.NoError(t, err)
+		thumbprint, err := public.Thumbprint(crypto.SHA256)
+		require.NoError(t, err)
+		return key, base64.RawURLEncoding.EncodeToString(thumbprint)
+	}
+	key, jkt := newKey(t)
+
+	type proofOptions struct {
+		jti, htm, htu, ath, typ string
+		iat                     time.Time
+		key                     jwk.Key
+	}
+	newProof := func(t *testing.T, opts proofOptions) string {
+		t.Helper()
+		signingKey := key
+		if opts.key != nil {
+			signingKey = opts.key
+		}
+		token := jwt.New()
+		require.NoError(t, token.Set(jwt.JwtIDKey, opts.jti))
+		require.NoError(t, token.Set(jwt.IssuedAtKey, opts.iat))
+		require.NoError(t, token.Set("htm", opts.htm))
+		require.NoError(t, token.Set("htu", opts.htu))
+		require.NoError(t, token.Set("ath", opts.ath))
+		payload, err := jwt.NewSerializer().Serialize(token)
+		require.NoError(t, err)
+
+		public, err := signingKey.PublicKey()
+		require.NoError(t, err)
+		headers := jws.NewHeaders()
+		require.NoError(t, headers.Set(jws.TypeKey, opts.typ))
+		require.NoError(t, headers.Set(jws.JWKKey, public))
+		proof, err := jws.Sign(payload, jws.WithKey(jwa.ES256, signingKey, jws.WithProtectedHeaders(headers)))
+		require.NoError(t, err)
+		return string(proof)
+	}
+	valid := func() proofOptions {
+		return proofOptions{
+			jti: "proof-1",
+			htm: DPoPNATSMethod,
+			htu: uri,
+			ath: accessTokenHash(accessToken),
+			typ: DPoPProofType,
+			iat: time.Now(),
+		}
+	}
+
+	bound := &Claims{Subject: "auth0|jdoe", Raw: map[string]any{"cnf": map[string]any{"jkt": jkt}}}
+	bearer := &Claims{Subject: "auth0|jdoe", Raw: map[string]any{}}
+	requestWith := func(proof string) context.Context {
+		return WithDPoPRequest(context.Background(), proof, DPoPNATSMethod, uri)
+	}
+
+	t.Run("accepts a valid proof once", func(t *testing.T) {
+		verifier := NewDPoPVerifier(0, 100, false)
+		proof := newProof(t, valid())
+
+		assert.NoError(t, verifier.Verify(requestWith(proof), "DPoP "+accessToken, bound))
+		assert.ErrorContains(t, verifier.Verify(requestWith(proof), accessToken, bound), "already been used")
+	})
+
+	t.Run("verifying twice in one request is not a replay", func(t *testing.T) {
+		verifier := NewDPoPVerifier(0, 100, false)
+		ctx := requestWith(newProof(t, valid()))
+
+		require.NoError(t, verifier.Verify(ctx, accessToken, bound))
+		assert.NoError(t, verifier.Verify(ctx, accessToken, bound))
+	})
+
+	t.Run("bearer tokens", func(t *testing.T) {
+		assert.NoError(t, NewDPoPVerifier(0, 100, false).Verify(context.Background(), accessToken, bearer))
+		assert.ErrorContains(t, NewDPoPVerifier(0, 100, true).Verify(context.Background(), accessToken, bearer), "must be DPoP-bound")
+	})
+
+	t.Run("bound token without a proof", func(t *testing.T) {
+		err := NewDPoPVerifier(0, 100, false).Verify(context.Background(), accessToken, bound)
+		assert.ErrorContains(t, err, "proof is required")
+	})
+
+	otherKey, _ := newKey(t)
+	tests := []struct {
+		name    string
+		mutate  func(*proofOptions)
+		wantErr string
+	}{
+		{name: "wrong typ", mutate: func(o *proofOptions) { o.typ = "JWT" }, wantErr: "typ"},
+		{name: "wrong key", mutate: func(o *proofOptions) { o.key = otherKey }, wantErr: "token binding"},
+		{name: "wrong method", mutate: func(o *proofOptions) { o.htm = "POST" }, wantErr: "htm"},
+		{name: "wrong uri", mutate: func(o *proofOptions) { o.htu = NATSDPoPURI("lfx.auth-service.other") }, wantErr: "htu"},
+		{name: "wrong access token", mutate: func(o *proofOptions) { o.ath = accessTokenHash("other") }, wantErr: "ath"},
+		{name: "stale", mutate: func(o *proofOptions) { o.iat = time.Now().Add(-time.Hour) }, wantErr: "window"},
+		{name: "from the future", mutate: func(o *proofOptions) { o.iat = time.Now().Add(time.Hour) }, wantErr: "window"},
+		{name: "no jti", mutate: func(o *proofOptions) { o.jti = "" }, wantErr: "jti"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := valid()
+			tt.mutate(&opts)
+			err := NewDPoPVerifier(0, 100, false).Verify(requestWith(newProof(t, opts)), accessToken, bound)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+
+	t.Run("malformed proof", func(t *testing.T) {
+		err := NewDPoPVerifier(0, 100, false).Verify(requestWith("not-a-proof"), accessToken, bound)
+		assert.ErrorContains(t, err, "malformed")
+	})
+}
+
+func TestClaimsJKT(t *testing.T) {
+	assert.Equal(t, "abc", (&Claims{Raw: map[string]any{"cnf": map[string]any{"jkt": "abc"}}}).JKT())
+	assert.Empty(t, (&Claims{Raw: map[string]any{"cnf": "abc"}}).JKT())
+	assert.Empty(t, (&Claims{}).JKT())
+}
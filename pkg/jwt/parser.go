@@ -6,23 +6,30 @@ package jwt
 import (
 	"context"
 	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"log/slog"
-	"maps"
+	"math/big"
 	"slices"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
 
-	"github.com/lestrrat-go/jwx/v2/jwa"
 	"github.com/lestrrat-go/jwx/v2/jwk"
 	"github.com/lestrrat-go/jwx/v2/jwt"
 )
 
+// ErrMissingRequiredScope is the message of the error of a valid token
+// without a required scope
+const ErrMissingRequiredScope = "missing required scope"
+
 // Claims represents the parsed JWT claims with commonly used fields
 type Claims struct {
 	Subject   string         `json:"sub"`
+	ID        string         `json:"jti,omitempty"`
 	Email     string         `json:"email,omitempty"`
 	ExpiresAt *time.Time     `json:"exp,omitempty"`
 	IssuedAt  *time.Time     `json:"iat,omitempty"`
@@ -47,10 +54,16 @@ type ParseOptions struct {
 	VerifySignature bool
 	// SigningKey is the key used for signature verification (RSA public key)
 	SigningKey *rsa.PublicKey
+	// HMACKey verifies HS256 signatures instead of SigningKey. Shared-secret
+	// tokens are only accepted by the mock repository, for development.
+	HMACKey []byte
 	// ExpectedIssuer validates the 'iss' claim matches this value
 	ExpectedIssuer string
 	// ExpectedAudience validates the 'aud' claim matches this value
 	ExpectedAudience string
+	// ExpectedKeyID rejects tokens whose header names another 'kid' before
+	// their signature is checked. Tokens without a kid are still verified.
+	ExpectedKeyID string
 }
 
 // DefaultParseOptions returns sensible default options
@@ -74,13 +87,10 @@ func ParseUnverified(ctx context.Context, tokenString string, opts *ParseOptions
 		return nil, errors.NewValidation("token is required")
 	}
 
-	// Remove optional Bearer prefix (case-insensitive) and trim
+	// Remove optional Bearer or DPoP prefix (case-insensitive) and trim
 	cleanToken := strings.TrimSpace(tokenString)
 	if opts.AllowBearerPrefix {
-		parts := strings.Fields(tokenString)
-		if len(parts) > 1 && strings.EqualFold(parts[0], "Bearer") {
-			cleanToken = strings.Join(parts[1:], " ")
-		}
+		cleanToken = stripAuthScheme(cleanToken)
 	}
 
 	// Parse the token without verification using jwx
@@ -125,91 +135,26 @@ func ParseUnverified(ctx context.Context, tokenString string, opts *ParseOptions
 
 // ParseVerified parses a JWT token with signature verification and returns the claims.
 // This function validates the token signature using the provided public key.
+// Callers verifying many tokens with the same options should keep a Verifier.
 func ParseVerified(ctx context.Context, tokenString string, opts *ParseOptions) (*Claims, error) {
-	if opts == nil {
-		opts = DefaultParseOptions()
-	}
-
-	if strings.TrimSpace(tokenString) == "" {
-		return nil, errors.NewValidation("token is required")
-	}
-
-	// Remove optional Bearer prefix (case-insensitive) and trim
-	cleanToken := strings.TrimSpace(tokenString)
-	if opts.AllowBearerPrefix {
-		parts := strings.Fields(tokenString)
-		if len(parts) > 1 && strings.EqualFold(parts[0], "Bearer") {
-			cleanToken = strings.Join(parts[1:], " ")
-		}
-	}
-
-	// Parse the token with jwx
-	token, errParse := jwt.Parse([]byte(cleanToken), jwt.WithKey(jwa.RS256, opts.SigningKey))
-	if errParse != nil {
-		return nil, errParse
-	}
-
-	// Extract claims
-	claims, err := extractClaimsFromJWT(token)
-	if err != nil {
-		return nil, err
-	}
-
-	// Validate issuer if specified
-	if opts.ExpectedIssuer != "" {
-		if err := validateIssuer(claims, opts.ExpectedIssuer); err != nil {
-			return nil, err
-		}
-	}
-
-	// Validate audience if specified
-	if opts.ExpectedAudience != "" {
-		if err := validateAudience(claims, opts.ExpectedAudience); err != nil {
-			return nil, err
-		}
-	}
-
-	// Validate expiration if required
-	if opts.RequireExpiration {
-		if err := validateExpiration(claims); err != nil {
-			return nil, err
-		}
-	}
-
-	// Validate subject if required
-	if opts.RequireSubject {
-		if err := validateSubject(claims); err != nil {
-			return nil, err
-		}
-	}
-
-	// Validate required scopes if specified
-	if len(opts.RequiredScopes) > 0 {
-		if err := validateScopes(claims, opts.RequiredScopes); err != nil {
-			return nil, err
-		}
-	}
-
-	slog.DebugContext(ctx, "JWT parsed and verified successfully",
-		"sub", claims.Subject,
-		"issuer", claims.Issuer,
-		"audience", claims.Audience,
-		"expires_at", claims.ExpiresAt,
-		"scope", claims.Scope,
-	)
-
-	return claims, nil
+	return NewVerifier(opts).Parse(ctx, tokenString)
 }
 
 // extractClaimsFromJWT extracts claims from a jwx JWT token
 func extractClaimsFromJWT(token jwt.Token) (*Claims, error) {
+	// the token is discarded once its claims are extracted, so its private
+	// claims map is taken over instead of copied
 	claims := &Claims{
-		Raw: make(map[string]any),
+		Raw: token.PrivateClaims(),
+	}
+	if claims.Raw == nil {
+		claims.Raw = make(map[string]any)
 	}
 
 	// Extract standard claims using jwx methods
 	claims.Subject = token.Subject()
 	claims.Issuer = token.Issuer()
+	claims.ID = token.JwtID()
 
 	// Handle audience (jwx returns []string)
 	audience := token.Audience()
@@ -247,9 +192,6 @@ func extractClaimsFromJWT(token jwt.Token) (*Claims, error) {
 		claims.NotBefore = &nbf
 	}
 
-	// Store all raw claims
-	maps.Copy(claims.Raw, token.PrivateClaims())
-
 	return claims, nil
 }
 
@@ -322,11 +264,9 @@ func validateScopes(claims *Claims, requiredScopes []string) error {
 		return errors.NewValidation("missing 'scope' claim in token")
 	}
 
-	tokenScopes := strings.Fields(claims.Scope) // Split by whitespace
-
 	for _, requiredScope := range requiredScopes {
-		if !slices.Contains(tokenScopes, requiredScope) {
-			return errors.NewValidation("missing required scope")
+		if !hasField(claims.Scope, requiredScope) {
+			return errors.NewValidation(ErrMissingRequiredScope)
 		}
 	}
 
@@ -380,33 +320,118 @@ func (c *Claims) GetStringClaim(key string) (string, bool) {
 
 // HasScope checks if the token has a specific scope
 func (c *Claims) HasScope(scope string) bool {
-	if c.Scope == "" {
+	return hasField(c.Scope, scope)
+}
+
+// hasField reports whether the whitespace-separated list s contains field,
+// without splitting s into a slice
+func hasField(s, field string) bool {
+	for f := range strings.FieldsSeq(s) {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// HasPermission checks if the token lists a permission in its 'permissions'
+// claim, which Auth0 adds to access tokens of APIs with RBAC enabled
+func (c *Claims) HasPermission(permission string) bool {
+	value, exists := c.GetClaim("permissions")
+	if !exists {
 		return false
 	}
-	scopes := strings.Fields(c.Scope)
-	return slices.Contains(scopes, scope)
+	switch permissions := value.(type) {
+	case []string:
+		return slices.Contains(permissions, permission)
+	case []any:
+		for _, p := range permissions {
+			if s, ok := p.(string); ok && s == permission {
+				return true
+			}
+		}
+	}
+	return false
 }
 
-// LooksLikeJWT checks if a string looks like a JWT token by attempting to parse it
-// without verification. Returns the cleaned token and true if the string can be parsed as a valid JWT structure.
-func LooksLikeJWT(tokenStr string) (string, bool) {
-	if strings.TrimSpace(tokenStr) == "" {
-		return "", false
+// AuthTime returns the 'auth_time' claim: when the user last authenticated
+// interactively, which can be long before the token was issued
+func (c *Claims) AuthTime() (time.Time, bool) {
+	value, exists := c.GetClaim("auth_time")
+	if !exists {
+		return time.Time{}, false
+	}
+	switch seconds := value.(type) {
+	case float64:
+		return time.Unix(int64(seconds), 0), true
+	case int64:
+		return time.Unix(seconds, 0), true
+	case int:
+		return time.Unix(int64(seconds), 0), true
+	case json.Number:
+		if n, err := seconds.Int64(); err == nil {
+			return time.Unix(n, 0), true
+		}
 	}
+	return time.Time{}, false
+}
 
-	// Remove optional Bearer prefix (case-insensitive) and trim
-	cleanToken := strings.TrimSpace(tokenStr)
-	parts := strings.Fields(tokenStr)
-	if len(parts) > 1 && strings.EqualFold(parts[0], "Bearer") {
-		cleanToken = strings.Join(parts[1:], " ")
+// AuthenticationMethods returns the 'amr' claim, the methods the user
+// authenticated with (RFC 8176), e.g. "pwd" and "mfa"
+func (c *Claims) AuthenticationMethods() []string {
+	value, exists := c.GetClaim("amr")
+	if !exists {
+		return nil
+	}
+	switch methods := value.(type) {
+	case []string:
+		return methods
+	case []any:
+		amr := make([]string, 0, len(methods))
+		for _, m := range methods {
+			if s, ok := m.(string); ok {
+				amr = append(amr, s)
+			}
+		}
+		return amr
 	}
+	return nil
+}
 
-	// Try to parse the token without verification
-	_, err := jwt.Parse([]byte(cleanToken), jwt.WithVerify(false))
-	if err == jwt.ErrInvalidJWT() {
-		return cleanToken, false
+// stripAuthScheme removes a leading Bearer or DPoP scheme from a trimmed
+// token. It slices instead of splitting, as tokens are stripped on every
+// verification and almost never carry the scheme.
+func stripAuthScheme(token string) string {
+	// the schemes are at most six letters, so only the start is searched
+	i := strings.IndexAny(token[:min(len(token), len("Bearer")+1)], " \t\r\n")
+	if i < 0 || !isAuthScheme(token[:i]) {
+		return token
 	}
-	return cleanToken, true
+	rest := strings.TrimSpace(token[i:])
+	if !strings.ContainsFunc(rest, unicode.IsSpace) {
+		return rest
+	}
+	return strings.Join(strings.Fields(rest), " ")
+}
+
+// isAuthScheme reports whether scheme is an authorization scheme access
+// tokens are sent with: Bearer, or DPoP for sender-constrained tokens
+func isAuthScheme(scheme string) bool {
+	return strings.EqualFold(scheme, "Bearer") || strings.EqualFold(scheme, DPoPHeader)
+}
+
+// LooksLikeJWT checks if a string looks like a JWT token: a compact JWS or JWE
+// whose header decodes to a JSON object naming an algorithm. Returns the
+// cleaned token and true if it does. Only the header is decoded, into a stack
+// buffer, so non-token inputs such as usernames are told apart without a parse.
+func LooksLikeJWT(tokenStr string) (string, bool) {
+	if strings.TrimSpace(tokenStr) == "" {
+		return "", false
+	}
+
+	// Remove optional Bearer or DPoP prefix (case-insensitive) and trim
+	cleanToken := stripAuthScheme(strings.TrimSpace(tokenStr))
+	return cleanToken, looksLikeCompactJWT(cleanToken)
 }
 
 // LoadRSAPublicKeyFromJWK loads an RSA public key from JWK (JSON Web Key) format
@@ -425,3 +450,25 @@ func LoadRSAPublicKeyFromJWK(jwkData []byte) (*rsa.PublicKey, error) {
 
 	return &rsaKey, nil
 }
+
+// RSAPublicKeyFromComponents builds an RSA public key from the base64url
+// encoded modulus and exponent of a JWK, the "n" and "e" members
+func RSAPublicKeyFromComponents(n, e string) (*rsa.PublicKey, error) {
+	modulus, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(n, "="))
+	if err != nil || len(modulus) == 0 {
+		return nil, errors.NewValidation("invalid RSA modulus in JWK")
+	}
+	exponent, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(e, "="))
+	if err != nil || len(exponent) == 0 || len(exponent) > 4 {
+		return nil, errors.NewValidation("invalid RSA exponent in JWK")
+	}
+
+	publicKey := &rsa.PublicKey{N: new(big.Int).SetBytes(modulus)}
+	for _, b := range exponent {
+		publicKey.E = publicKey.E<<8 | int(b)
+	}
+	if publicKey.E < 3 || publicKey.N.BitLen() < 2048 {
+		return nil, errors.NewValidation("RSA key in JWK is too weak")
+	}
+	return publicKey, nil
+}
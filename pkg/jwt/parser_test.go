@@ -314,6 +314,9 @@ func TestClaimsHelpers(t *testing.T) {
 		Raw: jwt.MapClaims{
 			"custom_field": "custom_value",
 			"number_field": 42,
+			"permissions":  []any{"read:projects", 7},
+			"auth_time":    float64(1760000000),
+			"amr":          []any{"pwd", "mfa", 1},
 		},
 	}
 
@@ -344,6 +347,26 @@ func TestClaimsHelpers(t *testing.T) {
 		assert.True(t, claims.HasScope("admin"))
 		assert.False(t, claims.HasScope("delete"))
 	})
+
+	t.Run("HasPermission", func(t *testing.T) {
+		assert.True(t, claims.HasPermission("read:projects"))
+		assert.False(t, claims.HasPermission("read"))
+		assert.False(t, (&Claims{Scope: "read"}).HasPermission("read"))
+	})
+
+	t.Run("AuthTime", func(t *testing.T) {
+		authTime, ok := claims.AuthTime()
+		assert.True(t, ok)
+		assert.Equal(t, int64(1760000000), authTime.Unix())
+
+		_, ok = (&Claims{}).AuthTime()
+		assert.False(t, ok)
+	})
+
+	t.Run("AuthenticationMethods", func(t *testing.T) {
+		assert.Equal(t, []string{"pwd", "mfa"}, claims.AuthenticationMethods())
+		assert.Nil(t, (&Claims{}).AuthenticationMethods())
+	})
 }
 
 func TestParseVerified(t *testing.T) {
@@ -555,6 +578,25 @@ func TestLoadRSAPublicKeyFromJWK(t *testing.T) {
 	}
 }
 
+func TestRSAPublicKeyFromComponents(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	n := encodeBase64URL(privateKey.N.Bytes())
+	e := encodeBase64URL([]byte{1, 0, 1})
+
+	key, err := RSAPublicKeyFromComponents(n, e)
+	require.NoError(t, err)
+	assert.Equal(t, 0, key.N.Cmp(privateKey.N))
+	assert.Equal(t, privateKey.E, key.E)
+
+	_, err = RSAPublicKeyFromComponents("not base64!", e)
+	assert.Error(t, err)
+	_, err = RSAPublicKeyFromComponents(n, "")
+	assert.Error(t, err)
+	_, err = RSAPublicKeyFromComponents(encodeBase64URL([]byte{0xff, 0x01}), e)
+	assert.Error(t, err, "keys shorter than 2048 bits are rejected")
+}
+
 func createExpiredToken(t *testing.T, privateKey *rsa.PrivateKey) string {
 	// Create an expired JWT token
 	claims := jwt.MapClaims{
@@ -659,3 +701,102 @@ func TestLooksLikeJWT(t *testing.T) {
 		})
 	}
 }
+
+// benchmarkToken returns a signed access token like the ones on the lookup
+// path, and the key that verifies it
+func benchmarkToken(b *testing.B) (string, *rsa.PublicKey) {
+	b.Helper()
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(b, err)
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"sub":         "auth0|benchmark",
+		"iss":         "https://test.auth0.com/",
+		"aud":         "https://test.auth0.com/api/v2/",
+		"exp":         now.Add(time.Hour).Unix(),
+		"iat":         now.Unix(),
+		"scope":       "openid profile read:current_user update:current_user_metadata",
+		"permissions": []string{"read:users"},
+	})
+	tokenString, err := token.SignedString(privateKey)
+	require.NoError(b, err)
+	return tokenString, &privateKey.PublicKey
+}
+
+func BenchmarkParseVerified(b *testing.B) {
+	token, publicKey := benchmarkToken(b)
+	ctx := context.Background()
+	opts := &ParseOptions{
+		RequireExpiration: true,
+		AllowBearerPrefix: true,
+		RequireSubject:    true,
+		VerifySignature:   true,
+		SigningKey:        publicKey,
+		ExpectedIssuer:    "https://test.auth0.com/",
+		ExpectedAudience:  "https://test.auth0.com/api/v2/",
+		RequiredScopes:    []string{"read:current_user"},
+	}
+	b.ReportAllocs()
+
+	for b.Loop() {
+		if _, err := ParseVerified(ctx, token, opts); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkVerifierParse(b *testing.B) {
+	token, publicKey := benchmarkToken(b)
+	ctx := context.Background()
+	verifier := NewVerifier(&ParseOptions{
+		RequireExpiration: true,
+		AllowBearerPrefix: true,
+		RequireSubject:    true,
+		VerifySignature:   true,
+		SigningKey:        publicKey,
+		ExpectedIssuer:    "https://test.auth0.com/",
+		ExpectedAudience:  "https://test.auth0.com/api/v2/",
+	})
+	b.ReportAllocs()
+
+	for b.Loop() {
+		if _, err := verifier.Parse(ctx, token, "read:current_user"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseUnverified(b *testing.B) {
+	token, _ := benchmarkToken(b)
+	ctx := context.Background()
+	opts := DefaultParseOptions()
+	b.ReportAllocs()
+
+	for b.Loop() {
+		if _, err := ParseUnverified(ctx, token, opts); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLooksLikeJWT(b *testing.B) {
+	token, _ := benchmarkToken(b)
+	b.ReportAllocs()
+
+	for b.Loop() {
+		if _, ok := LooksLikeJWT(token); !ok {
+			b.Fatal("token not recognized")
+		}
+	}
+}
+
+func BenchmarkPeek(b *testing.B) {
+	token, _ := benchmarkToken(b)
+	b.ReportAllocs()
+
+	for b.Loop() {
+		if _, ok := Peek(token); !ok {
+			b.Fatal("token not recognized")
+		}
+	}
+}
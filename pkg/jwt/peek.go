@@ -0,0 +1,263 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package jwt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+)
+
+// Decoded segments up to these sizes are read into stack buffers; larger
+// ones, which access tokens practically never are, fall back to the heap
+const (
+	peekHeaderBufferSize  = 512
+	peekPayloadBufferSize = 4096
+)
+
+// Peeked holds the fields of a token Peek reads: enough to route it to the
+// issuer and key that verify it, none of them trustworthy until it is
+type Peeked struct {
+	// Algorithm is the alg of the header
+	Algorithm string
+	// KeyID is the kid of the header, empty when absent
+	KeyID string
+	// Issuer is the iss of the payload, empty for encrypted tokens
+	Issuer string
+	// Subject is the sub of the payload, empty for encrypted tokens
+	Subject string
+}
+
+// Peek reads the alg and kid of the header and the iss and sub of the payload
+// of a compact token without verifying it. Unlike ParseUnverified it decodes
+// into stack buffers and scans the JSON for the few members it needs, so it
+// is cheap enough to run before every verification. The payload of an
+// encrypted token (JWE) can't be read, so only its header is.
+func Peek(token string) (Peeked, bool) {
+	var peeked Peeked
+	header, payload, encrypted, ok := splitCompact(token)
+	if !ok {
+		return peeked, false
+	}
+
+	var headerBuffer [peekHeaderBufferSize]byte
+	headerJSON, ok := decodeSegment(headerBuffer[:], header)
+	if !ok {
+		return peeked, false
+	}
+	alg, found, ok := stringMember(headerJSON, "alg")
+	if !ok || !found || len(alg) == 0 {
+		return peeked, false
+	}
+	kid, _, _ := stringMember(headerJSON, "kid")
+	peeked.Algorithm = unquote(alg)
+	peeked.KeyID = unquote(kid)
+	if encrypted {
+		return peeked, true
+	}
+
+	var payloadBuffer [peekPayloadBufferSize]byte
+	payloadJSON, ok := decodeSegment(payloadBuffer[:], payload)
+	if !ok {
+		return peeked, false
+	}
+	iss, _, ok := stringMember(payloadJSON, "iss")
+	if !ok {
+		return peeked, false
+	}
+	sub, _, _ := stringMember(payloadJSON, "sub")
+	peeked.Issuer = unquote(iss)
+	peeked.Subject = unquote(sub)
+	return peeked, true
+}
+
+// looksLikeCompactJWT reports whether token is a compact JWS or JWE whose
+// header is a JSON object naming an algorithm. It doesn't allocate.
+func looksLikeCompactJWT(token string) bool {
+	header, _, _, ok := splitCompact(token)
+	if !ok {
+		return false
+	}
+	var buffer [peekHeaderBufferSize]byte
+	headerJSON, ok := decodeSegment(buffer[:], header)
+	if !ok {
+		return false
+	}
+	alg, found, ok := stringMember(headerJSON, "alg")
+	return ok && found && len(alg) > 0
+}
+
+// splitCompact returns the header and payload segments of a compact token:
+// three segments for a JWS, five for a JWE
+func splitCompact(token string) (header, payload string, encrypted, ok bool) {
+	switch strings.Count(token, ".") {
+	case 2:
+	case 4:
+		encrypted = true
+	default:
+		return "", "", false, false
+	}
+	header, rest, _ := strings.Cut(token, ".")
+	payload, _, _ = strings.Cut(rest, ".")
+	if header == "" {
+		return "", "", false, false
+	}
+	return header, payload, encrypted, true
+}
+
+// decodeSegment decodes a base64url segment into buffer, or into a new slice
+// when it doesn't fit
+func decodeSegment(buffer []byte, segment string) ([]byte, bool) {
+	segment = strings.TrimRight(segment, "=")
+	size := base64.RawURLEncoding.DecodedLen(len(segment))
+	if size > len(buffer) {
+		buffer = make([]byte, size)
+	}
+	n, err := base64.RawURLEncoding.Decode(buffer, []byte(segment))
+	if err != nil {
+		return nil, false
+	}
+	return buffer[:n], true
+}
+
+// stringMember scans the top-level members of the JSON object data for key
+// and returns its raw string value, without the quotes and still escaped.
+// found is false when the key is absent or its value isn't a string, and ok
+// is false when data isn't a well-formed object.
+func stringMember(data []byte, key string) (value []byte, found, ok bool) {
+	i := skipSpace(data, 0)
+	if i >= len(data) || data[i] != '{' {
+		return nil, false, false
+	}
+	i = skipSpace(data, i+1)
+	if i < len(data) && data[i] == '}' {
+		return nil, false, skipSpace(data, i+1) == len(data)
+	}
+
+	for {
+		if i >= len(data) || data[i] != '"' {
+			return nil, false, false
+		}
+		name, end, valid := scanString(data, i)
+		if !valid {
+			return nil, false, false
+		}
+		i = skipSpace(data, end)
+		if i >= len(data) || data[i] != ':' {
+			return nil, false, false
+		}
+		i = skipSpace(data, i+1)
+		if i >= len(data) {
+			return nil, false, false
+		}
+
+		if data[i] == '"' {
+			raw, end, valid := scanString(data, i)
+			if !valid {
+				return nil, false, false
+			}
+			if !found && string(name) == key {
+				value, found = raw, true
+			}
+			i = end
+		} else {
+			end, valid := skipValue(data, i)
+			if !valid {
+				return nil, false, false
+			}
+			i = end
+		}
+
+		i = skipSpace(data, i)
+		if i >= len(data) {
+			return nil, false, false
+		}
+		switch data[i] {
+		case ',':
+			i = skipSpace(data, i+1)
+		case '}':
+			return value, found, skipSpace(data, i+1) == len(data)
+		default:
+			return nil, false, false
+		}
+	}
+}
+
+// scanString returns the content of the string starting at data[start] and
+// the index after its closing quote
+func scanString(data []byte, start int) (content []byte, end int, ok bool) {
+	for i := start + 1; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			i++
+		case '"':
+			return data[start+1 : i], i + 1, true
+		}
+	}
+	return nil, 0, false
+}
+
+// skipValue returns the index after the non-string value starting at
+// data[start]: a number, a literal, or a nested object or array
+func skipValue(data []byte, start int) (int, bool) {
+	depth := 0
+	for i := start; i < len(data); i++ {
+		switch c := data[i]; c {
+		case '"':
+			if depth == 0 {
+				return 0, false
+			}
+			_, end, ok := scanString(data, i)
+			if !ok {
+				return 0, false
+			}
+			i = end - 1
+		case '{', '[':
+			depth++
+		case '}', ']':
+			if depth == 0 {
+				return i, i > start
+			}
+			depth--
+			if depth == 0 {
+				return i + 1, true
+			}
+		case ',':
+			if depth == 0 {
+				return i, i > start
+			}
+		case ' ', '\t', '\n', '\r':
+			if depth == 0 {
+				return i, i > start
+			}
+		}
+	}
+	return len(data), depth == 0 && len(data) > start
+}
+
+func skipSpace(data []byte, i int) int {
+	for i < len(data) && (data[i] == ' ' || data[i] == '\t' || data[i] == '\n' || data[i] == '\r') {
+		i++
+	}
+	return i
+}
+
+// unquote turns a raw JSON string into a Go string, decoding escapes only
+// when there are any
+func unquote(raw []byte) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	if bytes.IndexByte(raw, '\\') < 0 {
+		return string(raw)
+	}
+	var value string
+	quoted := make([]byte, 0, len(raw)+2)
+	quoted = append(append(append(quoted, '"'), raw...), '"')
+	if err := json.Unmarshal(quoted, &value); err != nil {
+		return ""
+	}
+	return value
+}
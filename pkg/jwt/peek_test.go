@@ -0,0 +1,134 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package jwt
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func compactSegment(json string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(json))
+}
+
+func TestPeek(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  string
+		want   Peeked
+		wantOK bool
+	}{
+		{
+			name: "signed token",
+			token: compactSegment(`{"alg":"RS256","typ":"JWT","kid":"key-1"}`) + "." +
+				compactSegment(`{"iss":"https://tenant.auth0.com/","sub":"auth0|123","aud":["a","b"],"exp":1700000000}`) + ".sig",
+			want:   Peeked{Algorithm: "RS256", KeyID: "key-1", Issuer: "https://tenant.auth0.com/", Subject: "auth0|123"},
+			wantOK: true,
+		},
+		{
+			name: "members after nested values and escapes",
+			token: compactSegment(` { "typ" : "JWT", "alg" : "HS256" } `) + "." +
+				compactSegment(`{"ctx":{"iss":"nested","list":[1,{"x":"}"}]},"iss":"https:\/\/issuer\/","sub":"café"}`) + ".",
+			want:   Peeked{Algorithm: "HS256", Issuer: "https://issuer/", Subject: "café"},
+			wantOK: true,
+		},
+		{
+			name:   "encrypted token only has a header",
+			token:  compactSegment(`{"alg":"RSA-OAEP","enc":"A256GCM","kid":"enc-1"}`) + ".key.iv.ciphertext.tag",
+			want:   Peeked{Algorithm: "RSA-OAEP", KeyID: "enc-1"},
+			wantOK: true,
+		},
+		{
+			name:  "header without alg",
+			token: compactSegment(`{"typ":"JWT"}`) + "." + compactSegment(`{"sub":"x"}`) + ".sig",
+		},
+		{
+			name:  "header is not an object",
+			token: compactSegment(`["alg"]`) + "." + compactSegment(`{"sub":"x"}`) + ".sig",
+		},
+		{
+			name:  "truncated payload",
+			token: compactSegment(`{"alg":"RS256"}`) + "." + compactSegment(`{"sub":"x"`) + ".sig",
+		},
+		{
+			name:  "not base64",
+			token: "not json!.payload.sig",
+		},
+		{
+			name:  "username",
+			token: "john.doe",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Peek(tt.token)
+			assert.Equal(t, tt.wantOK, ok)
+			if tt.wantOK {
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLooksLikeJWT_DoesNotAllocate(t *testing.T) {
+	token := compactSegment(`{"alg":"RS256","typ":"JWT"}`) + "." + compactSegment(`{"sub":"auth0|123"}`) + ".sig"
+	allocs := testing.AllocsPerRun(100, func() {
+		if _, ok := LooksLikeJWT(token); !ok {
+			t.Fatal("token not recognized")
+		}
+	})
+	assert.Zero(t, allocs)
+}
+
+func TestVerifier_RejectsBeforeSignatureCheck(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	claims := jwt.MapClaims{
+		"sub": "auth0|123",
+		"iss": "https://tenant.auth0.com/",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	sign := func(kid string) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		signed, err := token.SignedString(privateKey)
+		require.NoError(t, err)
+		return signed
+	}
+	verifier := NewVerifier(&ParseOptions{
+		RequireExpiration: true,
+		SigningKey:        &privateKey.PublicKey,
+		ExpectedIssuer:    "https://tenant.auth0.com/",
+		ExpectedKeyID:     "key-1",
+	})
+	ctx := context.Background()
+
+	_, err = verifier.Parse(ctx, sign("key-1"))
+	assert.NoError(t, err)
+	_, err = verifier.Parse(ctx, sign(""))
+	assert.NoError(t, err, "tokens without a kid are verified against the key")
+
+	_, err = verifier.Parse(ctx, sign("key-2"))
+	assert.ErrorContains(t, err, "unknown key")
+
+	hmac, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("secret"))
+	require.NoError(t, err)
+	_, err = verifier.Parse(ctx, hmac)
+	assert.ErrorContains(t, err, "unexpected signing algorithm 'HS256'")
+
+	claims["iss"] = "https://other.example.com/"
+	_, err = verifier.Parse(ctx, sign("key-1"))
+	assert.ErrorContains(t, err, "invalid issuer")
+}
@@ -0,0 +1,125 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package jwt
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+)
+
+// Verifier verifies tokens against fixed ParseOptions. The jwx options are
+// built once rather than on every call, for paths that verify a token per
+// request.
+type Verifier struct {
+	opts      ParseOptions
+	algorithm jwa.SignatureAlgorithm
+	parse     []jwt.ParseOption
+}
+
+// NewVerifier returns a Verifier for opts; nil selects DefaultParseOptions
+func NewVerifier(opts *ParseOptions) *Verifier {
+	if opts == nil {
+		opts = DefaultParseOptions()
+	}
+	algorithm := jwa.RS256
+	var key any = opts.SigningKey
+	if opts.HMACKey != nil {
+		algorithm, key = jwa.HS256, opts.HMACKey
+	}
+	return &Verifier{
+		opts:      *opts,
+		algorithm: algorithm,
+		parse:     []jwt.ParseOption{jwt.WithKey(algorithm, key)},
+	}
+}
+
+// Parse verifies tokenString like ParseVerified and returns its claims.
+// requiredScopes are checked on top of the RequiredScopes of the options.
+func (v *Verifier) Parse(ctx context.Context, tokenString string, requiredScopes ...string) (*Claims, error) {
+	opts := &v.opts
+
+	// Remove optional Bearer or DPoP prefix (case-insensitive) and trim
+	cleanToken := strings.TrimSpace(tokenString)
+	if cleanToken == "" {
+		return nil, errors.NewValidation("token is required")
+	}
+	if opts.AllowBearerPrefix {
+		cleanToken = stripAuthScheme(cleanToken)
+	}
+
+	// The header and issuer are peeked first, so tokens signed by another
+	// issuer, key or algorithm are turned away without a signature check.
+	// Malformed tokens are left to the parser, which says what is wrong.
+	if peeked, ok := Peek(cleanToken); ok {
+		if peeked.Algorithm != v.algorithm.String() {
+			return nil, errors.NewValidation(fmt.Sprintf("unexpected signing algorithm '%s'", peeked.Algorithm))
+		}
+		if opts.ExpectedIssuer != "" {
+			if err := validateIssuer(&Claims{Issuer: peeked.Issuer}, opts.ExpectedIssuer); err != nil {
+				return nil, err
+			}
+		}
+		if opts.ExpectedKeyID != "" && peeked.KeyID != "" && peeked.KeyID != opts.ExpectedKeyID {
+			return nil, errors.NewValidation("token is signed with an unknown key")
+		}
+	}
+
+	token, errParse := jwt.ParseString(cleanToken, v.parse...)
+	if errParse != nil {
+		return nil, errParse
+	}
+
+	claims, err := extractClaimsFromJWT(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.ExpectedIssuer != "" {
+		if err := validateIssuer(claims, opts.ExpectedIssuer); err != nil {
+			return nil, err
+		}
+	}
+	if opts.ExpectedAudience != "" {
+		if err := validateAudience(claims, opts.ExpectedAudience); err != nil {
+			return nil, err
+		}
+	}
+	if opts.RequireExpiration {
+		if err := validateExpiration(claims); err != nil {
+			return nil, err
+		}
+	}
+	if opts.RequireSubject {
+		if err := validateSubject(claims); err != nil {
+			return nil, err
+		}
+	}
+	for _, scopes := range [][]string{opts.RequiredScopes, requiredScopes} {
+		if len(scopes) > 0 {
+			if err := validateScopes(claims, scopes); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// the attributes are only built when debug logging is on
+	if slog.Default().Enabled(ctx, slog.LevelDebug) {
+		slog.DebugContext(ctx, "JWT parsed and verified successfully",
+			"sub", claims.Subject,
+			"issuer", claims.Issuer,
+			"audience", claims.Audience,
+			"expires_at", claims.ExpiresAt,
+			"scope", claims.Scope,
+		)
+	}
+
+	return claims, nil
+}
@@ -107,8 +107,11 @@ func InitStructureLogConfig() {
 	// Wrap with slog-otel handler to add trace_id and span_id from context
 	otelHandler := slogotel.OtelHandler{Next: h}
 
+	// Scrub credentials and emails from every record, context attributes included
+	scrubbed := scrubHandler{next: otelHandler}
+
 	// Wrap with contextHandler to support context-based attributes
-	logger := contextHandler{otelHandler}
+	logger := contextHandler{scrubbed}
 	slog.SetDefault(slog.New(logger))
 }
 
@@ -0,0 +1,83 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package log
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+)
+
+// Sampler decides, per subject, whether a request log line is emitted
+type Sampler struct {
+	defaultRate float64
+	rates       map[string]float64
+	random      func() float64
+}
+
+// Rate returns the sample rate configured for subject
+func (s *Sampler) Rate(subject string) float64 {
+	if rate, ok := s.rates[subject]; ok {
+		return rate
+	}
+	return s.defaultRate
+}
+
+// Sample reports whether a log line for subject should be emitted
+func (s *Sampler) Sample(subject string) bool {
+	rate := s.Rate(subject)
+	switch {
+	case rate >= 1:
+		return true
+	case rate <= 0:
+		return false
+	default:
+		return s.random() < rate
+	}
+}
+
+// ParseSampleRates parses a comma-separated list of subject=rate pairs,
+// e.g. "lfx.auth-service.user_metadata.read=0.05,lfx.auth-service.email_to_sub=0"
+func ParseSampleRates(raw string) (map[string]float64, error) {
+	rates := make(map[string]float64)
+	for _, pair := range strings.Split(raw, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		subject, value, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(subject) == "" {
+			return nil, fmt.Errorf("invalid sample rate %q: expected subject=rate", pair)
+		}
+		rate, err := ParseSampleRate(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sample rate for %s: %w", subject, err)
+		}
+		rates[strings.TrimSpace(subject)] = rate
+	}
+	return rates, nil
+}
+
+// ParseSampleRate parses a single rate in [0, 1]
+func ParseSampleRate(raw string) (float64, error) {
+	rate, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+	if err != nil {
+		return 0, err
+	}
+	if rate < 0 || rate > 1 {
+		return 0, fmt.Errorf("rate %v must be between 0 and 1", rate)
+	}
+	return rate, nil
+}
+
+// NewSampler creates a sampler using rates per subject and defaultRate for
+// every other subject
+func NewSampler(defaultRate float64, rates map[string]float64) *Sampler {
+	return &Sampler{
+		defaultRate: defaultRate,
+		rates:       rates,
+		random:      rand.Float64,
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package log
+
+import (
+	"testing"
+)
+
+func TestSamplerSample(t *testing.T) {
+	s := NewSampler(0.25, map[string]float64{"always": 1, "never": 0})
+
+	tests := []struct {
+		name    string
+		subject string
+		random  float64
+		want    bool
+	}{
+		{name: "full rate ignores randomness", subject: "always", random: 0.99, want: true},
+		{name: "zero rate never logs", subject: "never", random: 0, want: false},
+		{name: "default rate below threshold", subject: "other", random: 0.1, want: true},
+		{name: "default rate above threshold", subject: "other", random: 0.3, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s.random = func() float64 { return tt.random }
+			if got := s.Sample(tt.subject); got != tt.want {
+				t.Errorf("Sample(%q) = %v, want %v", tt.subject, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSampleRates(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    map[string]float64
+		wantErr bool
+	}{
+		{name: "empty", raw: "", want: map[string]float64{}},
+		{name: "pairs with spaces", raw: " a.read=0.5 , b.read=0 ", want: map[string]float64{"a.read": 0.5, "b.read": 0}},
+		{name: "missing rate", raw: "a.read", wantErr: true},
+		{name: "out of range", raw: "a.read=2", wantErr: true},
+		{name: "not a number", raw: "a.read=half", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSampleRates(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSampleRates() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParseSampleRates() = %v, want %v", got, tt.want)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("rate for %s = %v, want %v", k, got[k], v)
+				}
+			}
+		})
+	}
+}
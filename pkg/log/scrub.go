@@ -0,0 +1,69 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package log
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/redaction"
+)
+
+// scrubHandler removes credentials and email addresses from the message and
+// the string and error attributes of records before the next handler writes
+// them, wherever in the code they were logged from
+type scrubHandler struct {
+	next slog.Handler
+}
+
+// Enabled reports whether the next handler handles level
+func (h scrubHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle forwards a scrubbed copy of the record
+func (h scrubHandler) Handle(ctx context.Context, r slog.Record) error {
+	scrubbed := slog.NewRecord(r.Time, r.Level, redaction.Scrub(r.Message), r.PC)
+	r.Attrs(func(attr slog.Attr) bool {
+		scrubbed.AddAttrs(scrubAttr(attr))
+		return true
+	})
+	return h.next.Handle(ctx, scrubbed)
+}
+
+// WithAttrs returns the handler with the scrubbed attrs
+func (h scrubHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	scrubbed := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		scrubbed[i] = scrubAttr(attr)
+	}
+	return scrubHandler{next: h.next.WithAttrs(scrubbed)}
+}
+
+// WithGroup returns the handler with the group name
+func (h scrubHandler) WithGroup(name string) slog.Handler {
+	return scrubHandler{next: h.next.WithGroup(name)}
+}
+
+// scrubAttr scrubs the value of attr; errors are logged as their scrubbed
+// message
+func scrubAttr(attr slog.Attr) slog.Attr {
+	value := attr.Value.Resolve()
+	switch value.Kind() {
+	case slog.KindString:
+		return slog.String(attr.Key, redaction.Scrub(value.String()))
+	case slog.KindGroup:
+		group := value.Group()
+		scrubbed := make([]any, len(group))
+		for i, member := range group {
+			scrubbed[i] = scrubAttr(member)
+		}
+		return slog.Group(attr.Key, scrubbed...)
+	case slog.KindAny:
+		if err, ok := value.Any().(error); ok && err != nil {
+			return slog.String(attr.Key, redaction.Scrub(err.Error()))
+		}
+	}
+	return slog.Attr{Key: attr.Key, Value: value}
+}
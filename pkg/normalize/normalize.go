@@ -0,0 +1,96 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package normalize canonicalizes usernames and email addresses so the same
+// identifier compares and indexes identically wherever it enters the service.
+package normalize
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+	"golang.org/x/text/unicode/norm"
+)
+
+// gmailDomain is the domain googlemail.com addresses are folded into
+const gmailDomain = "gmail.com"
+
+// Options tunes the normalization applied by EmailKey
+type Options struct {
+	// GmailCanonical drops dots and +tags from the local part of Gmail
+	// addresses, which Gmail itself ignores when delivering mail
+	GmailCanonical bool
+}
+
+// options is the active process-wide configuration
+var options atomic.Pointer[Options]
+
+func init() {
+	options.Store(&Options{})
+}
+
+// SetOptions replaces the active options
+func SetOptions(o Options) {
+	options.Store(&o)
+}
+
+// CurrentOptions returns the active options
+func CurrentOptions() Options {
+	return *options.Load()
+}
+
+// ConfigureFromEnv applies NORMALIZE_GMAIL_CANONICAL
+func ConfigureFromEnv() error {
+	var o Options
+	if raw := os.Getenv(constants.NormalizeGmailCanonicalEnvKey); raw != "" {
+		enabled, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid %s value %s: %w", constants.NormalizeGmailCanonicalEnvKey, raw, err)
+		}
+		o.GmailCanonical = enabled
+	}
+	SetOptions(o)
+	return nil
+}
+
+// fold applies NFKC, trims surrounding whitespace and lowercases s, so
+// compatibility forms such as full-width letters match their ASCII spelling
+func fold(s string) string {
+	return strings.ToLower(strings.TrimSpace(norm.NFKC.String(s)))
+}
+
+// Username returns the canonical form of a username
+func Username(s string) string {
+	return fold(s)
+}
+
+// Email returns the canonical form of an email address. The address is
+// otherwise preserved, so the result is still deliverable.
+func Email(s string) string {
+	return fold(s)
+}
+
+// EmailKey returns the form of an email address used for matching and index
+// keys. On top of Email it applies the optional Gmail canonicalization, so the
+// result identifies a mailbox but is not necessarily the address as entered.
+func EmailKey(s string) string {
+	email := Email(s)
+	if !CurrentOptions().GmailCanonical {
+		return email
+	}
+
+	local, domain, ok := strings.Cut(email, "@")
+	if !ok || (domain != gmailDomain && domain != "googlemail.com") {
+		return email
+	}
+	local, _, _ = strings.Cut(local, "+")
+	local = strings.ReplaceAll(local, ".", "")
+	if local == "" {
+		return email
+	}
+	return local + "@" + gmailDomain
+}
@@ -0,0 +1,89 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package normalize
+
+import (
+	"testing"
+)
+
+// withOptions installs o for the duration of the test
+func withOptions(t *testing.T, o Options) {
+	t.Helper()
+	previous := CurrentOptions()
+	SetOptions(o)
+	t.Cleanup(func() { SetOptions(previous) })
+}
+
+func TestEmail(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "lowercases and trims", input: "  John.Doe@Example.COM \n", want: "john.doe@example.com"},
+		{name: "full-width letters", input: "ｊｏｈｎ@ｅｘａｍｐｌｅ.com", want: "john@example.com"},
+		{name: "decomposed accents are composed", input: "jose\u0301@example.com", want: "jos\u00e9@example.com"},
+		{name: "empty", input: "   ", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Email(tt.input); got != tt.want {
+				t.Errorf("Email(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUsername(t *testing.T) {
+	if got := Username(" JDoeK "); got != "jdoek" {
+		t.Errorf("Username() = %q, want %q", got, "jdoek")
+	}
+}
+
+func TestEmailKey(t *testing.T) {
+	t.Run("gmail canonicalization disabled", func(t *testing.T) {
+		withOptions(t, Options{})
+		if got := EmailKey("J.Doe+lfx@Gmail.com"); got != "j.doe+lfx@gmail.com" {
+			t.Errorf("EmailKey() = %q, want the address unchanged apart from case", got)
+		}
+	})
+
+	t.Run("gmail canonicalization enabled", func(t *testing.T) {
+		withOptions(t, Options{GmailCanonical: true})
+
+		tests := []struct {
+			input string
+			want  string
+		}{
+			{input: "J.Doe+lfx@Gmail.com", want: "jdoe@gmail.com"},
+			{input: "j.doe@googlemail.com", want: "jdoe@gmail.com"},
+			{input: "j.doe+lfx@example.com", want: "j.doe+lfx@example.com"},
+			{input: "+tag@gmail.com", want: "+tag@gmail.com"},
+			{input: "not-an-email", want: "not-an-email"},
+		}
+		for _, tt := range tests {
+			if got := EmailKey(tt.input); got != tt.want {
+				t.Errorf("EmailKey(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		}
+	})
+}
+
+func TestConfigureFromEnv(t *testing.T) {
+	withOptions(t, Options{})
+
+	t.Setenv("NORMALIZE_GMAIL_CANONICAL", "true")
+	if err := ConfigureFromEnv(); err != nil {
+		t.Fatalf("ConfigureFromEnv() error = %v", err)
+	}
+	if !CurrentOptions().GmailCanonical {
+		t.Error("GmailCanonical = false, want true")
+	}
+
+	t.Setenv("NORMALIZE_GMAIL_CANONICAL", "sometimes")
+	if err := ConfigureFromEnv(); err == nil {
+		t.Error("ConfigureFromEnv() error = nil, want invalid value error")
+	}
+}
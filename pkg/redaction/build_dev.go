@@ -0,0 +1,10 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+//go:build redaction_dev
+
+package redaction
+
+// plaintextAllowed is true in local development builds (-tags redaction_dev),
+// so REDACTION_RELAXED and ModeNone can be used.
+const plaintextAllowed = true
@@ -0,0 +1,10 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+//go:build !redaction_dev
+
+package redaction
+
+// plaintextAllowed is false in production builds: ModeNone and relaxed
+// redaction are rejected.
+const plaintextAllowed = false
@@ -0,0 +1,185 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package redaction
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/constants"
+)
+
+// Mode is how a class of sensitive values is rendered
+type Mode string
+
+const (
+	// ModeMask keeps a short prefix and masks the rest (e.g. "joh****")
+	ModeMask Mode = "mask"
+	// ModeFull replaces the value with a fixed placeholder
+	ModeFull Mode = "full"
+	// ModeHash replaces the value with a short salted hash, so the same value
+	// can be correlated across log lines without being revealed
+	ModeHash Mode = "hash"
+	// ModeNone leaves the value as-is. Only honored in builds with the
+	// redaction_dev tag, for local development.
+	ModeNone Mode = "none"
+)
+
+// Field is a class of sensitive values with its own redaction mode
+type Field string
+
+const (
+	// FieldIdentifier covers user IDs, usernames and other identifiers passed to Redact
+	FieldIdentifier Field = "identifier"
+	// FieldEmail covers addresses passed to RedactEmail
+	FieldEmail Field = "email"
+	// FieldToken covers JWTs replaced by RedactJWTs
+	FieldToken Field = "token"
+)
+
+// placeholder is the output of ModeFull
+const placeholder = "[REDACTED]"
+
+// hashLength is the number of hex characters kept from the salted hash
+const hashLength = 12
+
+// Policy maps each field to its redaction mode
+type Policy struct {
+	Modes map[Field]Mode
+	// HashSalt is mixed into ModeHash digests so they can't be reversed with
+	// a dictionary of known emails or usernames
+	HashSalt string
+}
+
+// policy is the active process-wide policy
+var policy atomic.Pointer[Policy]
+
+func init() {
+	p := DefaultPolicy()
+	policy.Store(&p)
+}
+
+// DefaultPolicy returns the strict production policy: identifiers and emails
+// are masked and tokens fully removed.
+func DefaultPolicy() Policy {
+	return Policy{Modes: map[Field]Mode{
+		FieldIdentifier: ModeMask,
+		FieldEmail:      ModeMask,
+		FieldToken:      ModeFull,
+	}}
+}
+
+// mode returns the configured mode for field, falling back to the default policy
+func (p *Policy) mode(field Field) Mode {
+	if mode, ok := p.Modes[field]; ok {
+		return mode
+	}
+	return DefaultPolicy().Modes[field]
+}
+
+// apply renders value for field; mask implements ModeMask for the field
+func (p *Policy) apply(field Field, value string, mask func(string) string) string {
+	if value == "" {
+		return ""
+	}
+	switch p.mode(field) {
+	case ModeFull:
+		return placeholder
+	case ModeHash:
+		return hashValue(p.HashSalt, value)
+	case ModeNone:
+		return value
+	default:
+		return mask(value)
+	}
+}
+
+func hashValue(salt, value string) string {
+	sum := sha256.Sum256([]byte(salt + value))
+	return "h:" + hex.EncodeToString(sum[:])[:hashLength]
+}
+
+// SetPolicy replaces the active policy. ModeNone is rejected unless the
+// binary was built with the redaction_dev tag.
+func SetPolicy(p Policy) error {
+	modes := make(map[Field]Mode, len(p.Modes))
+	for field, mode := range p.Modes {
+		switch field {
+		case FieldIdentifier, FieldEmail, FieldToken:
+		default:
+			return fmt.Errorf("unknown redaction field %q", field)
+		}
+		switch mode {
+		case ModeMask, ModeFull, ModeHash:
+		case ModeNone:
+			if !plaintextAllowed {
+				return fmt.Errorf("redaction mode %q for %s requires a build with the redaction_dev tag", mode, field)
+			}
+		default:
+			return fmt.Errorf("unknown redaction mode %q for %s", mode, field)
+		}
+		modes[field] = mode
+	}
+	p.Modes = modes
+	policy.Store(&p)
+	return nil
+}
+
+// CurrentPolicy returns a copy of the active policy
+func CurrentPolicy() Policy {
+	p := policy.Load()
+	modes := make(map[Field]Mode, len(p.Modes))
+	for field, mode := range p.Modes {
+		modes[field] = mode
+	}
+	return Policy{Modes: modes, HashSalt: p.HashSalt}
+}
+
+// ParsePolicy parses comma-separated field=mode pairs on top of the default
+// policy, e.g. "email=hash,identifier=full"
+func ParsePolicy(raw string) (Policy, error) {
+	p := DefaultPolicy()
+	for _, pair := range strings.Split(raw, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		field, mode, ok := strings.Cut(pair, "=")
+		if !ok {
+			return Policy{}, fmt.Errorf("invalid redaction rule %q: expected field=mode", pair)
+		}
+		p.Modes[Field(strings.TrimSpace(field))] = Mode(strings.ToLower(strings.TrimSpace(mode)))
+	}
+	return p, nil
+}
+
+// ConfigureFromEnv applies REDACTION_POLICY, REDACTION_HASH_SALT and
+// REDACTION_RELAXED. Relaxed mode disables redaction for every field and, like
+// ModeNone, is only available in redaction_dev builds.
+func ConfigureFromEnv() error {
+	p, err := ParsePolicy(os.Getenv(constants.RedactionPolicyEnvKey))
+	if err != nil {
+		return err
+	}
+	p.HashSalt = os.Getenv(constants.RedactionHashSaltEnvKey)
+
+	if raw := os.Getenv(constants.RedactionRelaxedEnvKey); raw != "" {
+		relaxed, errParse := strconv.ParseBool(raw)
+		if errParse != nil {
+			return fmt.Errorf("invalid %s value %s: %w", constants.RedactionRelaxedEnvKey, raw, errParse)
+		}
+		if relaxed {
+			for field := range p.Modes {
+				p.Modes[field] = ModeNone
+			}
+		}
+	}
+
+	return SetPolicy(p)
+}
@@ -7,6 +7,7 @@
 package redaction
 
 import (
+	"net/netip"
 	"regexp"
 	"strings"
 )
@@ -15,8 +16,9 @@ import (
 var jwtPattern = regexp.MustCompile(`[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`)
 
 // Redact redacts sensitive information for logging and output purposes.
-// Shows the first 3 characters when the string has more than 5 characters,
-// otherwise shows asterisks for shorter strings.
+// Under the default policy it shows the first 3 characters when the string has
+// more than 5 characters, otherwise asterisks. The identifier mode of the
+// active Policy can switch it to a placeholder or a salted hash.
 //
 // Examples:
 //   - Redact("") → ""
@@ -25,6 +27,11 @@ var jwtPattern = regexp.MustCompile(`[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}\.[A-
 //   - Redact("johndoe123") → "joh****"
 //   - Redact("auth0|johndoe123") → "auth0|joh****"
 func Redact(sensitive string) string {
+	return policy.Load().apply(FieldIdentifier, sensitive, mask)
+}
+
+// mask implements ModeMask for identifiers
+func mask(sensitive string) string {
 	if len(sensitive) == 0 {
 		return ""
 	}
@@ -32,7 +39,7 @@ func Redact(sensitive string) string {
 	// Handle Auth0 user IDs before length-based checks so that short suffixes
 	// (e.g. "auth0|ab") are redacted as "auth0|**" rather than "aut****".
 	if suffix, ok := strings.CutPrefix(sensitive, "auth0|"); ok {
-		return "auth0|" + Redact(suffix)
+		return "auth0|" + mask(suffix)
 	}
 
 	runes := []rune(sensitive)
@@ -55,12 +62,16 @@ func Redact(sensitive string) string {
 // RedactJWTs replaces any JWT tokens found in the input string with a redacted placeholder.
 // Useful for sanitizing request/response bodies before logging.
 func RedactJWTs(s string) string {
-	return jwtPattern.ReplaceAllString(s, "[REDACTED]")
+	p := policy.Load()
+	return jwtPattern.ReplaceAllStringFunc(s, func(token string) string {
+		return p.apply(FieldToken, token, func(string) string { return placeholder })
+	})
 }
 
 // RedactEmail redacts email addresses for logging and output purposes.
-// Shows the first 3 characters of the local part and keeps the full domain
-// visible for debugging purposes.
+// Under the default policy it shows the first 3 characters of the local part
+// and keeps the full domain visible for debugging purposes. The email mode of
+// the active Policy can switch it to a placeholder or a salted hash.
 //
 // Examples:
 //   - RedactEmail("") → ""
@@ -69,21 +80,55 @@ func RedactJWTs(s string) string {
 //   - RedactEmail("johndoe@company.com") → "joh****@company.com"
 //   - RedactEmail("invalid-email") → "inv****" (falls back to Redact)
 func RedactEmail(email string) string {
-	if email == "" {
-		return ""
-	}
+	return policy.Load().apply(FieldEmail, email, maskEmail)
+}
 
+// maskEmail implements ModeMask for email addresses
+func maskEmail(email string) string {
 	parts := strings.Split(email, "@")
 	if len(parts) != 2 {
 		// Invalid email format, redact the whole thing
-		return Redact(email)
+		return mask(email)
 	}
 
 	localPart := parts[0]
 	domain := parts[1]
 
 	// Redact the local part but keep the domain visible for debugging
-	redactedLocal := Redact(localPart)
+	redactedLocal := mask(localPart)
 
 	return redactedLocal + "@" + domain
 }
+
+// TruncateIP zeroes the host portion of an IP address so it can be stored or
+// published without identifying a single machine. IPv4 addresses keep their
+// /24 network and IPv6 addresses their /48. Values that are not IP addresses
+// are fully redacted. Unlike the other helpers it is a data-minimization step
+// for stored and published values, so it does not follow the redaction Policy.
+//
+// Examples:
+//   - TruncateIP("203.0.113.42") → "203.0.113.0"
+//   - TruncateIP("2001:db8:abcd:12::1") → "2001:db8:abcd::"
+//   - TruncateIP("not-an-ip") → "not****"
+func TruncateIP(ip string) string {
+	if ip == "" {
+		return ""
+	}
+
+	addr, err := netip.ParseAddr(strings.TrimSpace(ip))
+	if err != nil {
+		return mask(ip)
+	}
+
+	bits := 48
+	if addr.Is4() || addr.Is4In6() {
+		addr = addr.Unmap()
+		bits = 24
+	}
+
+	prefix, err := addr.Prefix(bits)
+	if err != nil {
+		return mask(ip)
+	}
+	return prefix.Addr().String()
+}
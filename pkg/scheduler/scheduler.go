@@ -0,0 +1,84 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package scheduler runs periodic background jobs inside the service process.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Job is a unit of periodic work. Errors are logged; the schedule continues.
+type Job func(ctx context.Context) error
+
+// Every starts a goroutine that runs job every interval until ctx is
+// cancelled. The first run happens after initialDelay. Runs never overlap: a
+// run that takes longer than interval delays the next one instead of stacking.
+// A panicking run is recovered and logged so it can't take the service down.
+func Every(ctx context.Context, name string, interval, initialDelay time.Duration, job Job) {
+	go func() {
+		timer := time.NewTimer(initialDelay)
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				slog.DebugContext(ctx, "scheduled job stopped", "job", name)
+				return
+			case <-timer.C:
+			}
+
+			started := time.Now()
+			if err := runSafely(ctx, job); err != nil {
+				slog.ErrorContext(ctx, "scheduled job failed",
+					"job", name,
+					"error", err,
+					"duration", time.Since(started),
+				)
+			} else {
+				slog.DebugContext(ctx, "scheduled job completed",
+					"job", name,
+					"duration", time.Since(started),
+				)
+			}
+			timer.Reset(interval)
+		}
+	}()
+}
+
+// Lock leases a job to one replica at a time, so a job every replica
+// schedules runs on one of them
+type Lock interface {
+	// Acquire takes or renews the lease of job, reporting whether this
+	// replica holds it
+	Acquire(ctx context.Context, job string, lease time.Duration) (bool, error)
+}
+
+// Exclusive returns job running only on the replica holding its lease in
+// lock; the runs of the other replicas are skipped. The lease is taken or
+// renewed at the start of each run.
+func Exclusive(lock Lock, name string, lease time.Duration, job Job) Job {
+	return func(ctx context.Context) error {
+		held, err := lock.Acquire(ctx, name, lease)
+		if err != nil {
+			return fmt.Errorf("failed to acquire the lease of %s: %w", name, err)
+		}
+		if !held {
+			slog.DebugContext(ctx, "scheduled job skipped, leased to another replica", "job", name)
+			return nil
+		}
+		return job(ctx)
+	}
+}
+
+func runSafely(ctx context.Context, job Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return job(ctx)
+}
@@ -0,0 +1,105 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvery(t *testing.T) {
+	tests := []struct {
+		name string
+		job  func(runs *atomic.Int32) Job
+	}{
+		{
+			name: "successful job keeps running",
+			job: func(runs *atomic.Int32) Job {
+				return func(ctx context.Context) error {
+					runs.Add(1)
+					return nil
+				}
+			},
+		},
+		{
+			name: "failing job keeps running",
+			job: func(runs *atomic.Int32) Job {
+				return func(ctx context.Context) error {
+					runs.Add(1)
+					return errors.New("boom")
+				}
+			},
+		},
+		{
+			name: "panicking job keeps running",
+			job: func(runs *atomic.Int32) Job {
+				return func(ctx context.Context) error {
+					runs.Add(1)
+					panic("boom")
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			var runs atomic.Int32
+			Every(ctx, "test", 5*time.Millisecond, 0, tt.job(&runs))
+
+			assert.Eventually(t, func() bool { return runs.Load() >= 3 }, time.Second, time.Millisecond)
+		})
+	}
+}
+
+func TestEvery_StopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var runs atomic.Int32
+	Every(ctx, "test", time.Millisecond, 0, func(ctx context.Context) error {
+		runs.Add(1)
+		return nil
+	})
+	assert.Eventually(t, func() bool { return runs.Load() >= 1 }, time.Second, time.Millisecond)
+
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+	stopped := runs.Load()
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, stopped, runs.Load())
+}
+
+// fakeLock leases every job to the replica named holder
+type fakeLock struct {
+	holder string
+	err    error
+}
+
+func (l fakeLock) Acquire(context.Context, string, time.Duration) (bool, error) {
+	return l.holder == "self", l.err
+}
+
+func TestExclusive(t *testing.T) {
+	var runs int
+	job := func(context.Context) error {
+		runs++
+		return nil
+	}
+
+	assert.NoError(t, Exclusive(fakeLock{holder: "self"}, "test", time.Minute, job)(context.Background()))
+	assert.Equal(t, 1, runs)
+
+	assert.NoError(t, Exclusive(fakeLock{holder: "other"}, "test", time.Minute, job)(context.Background()))
+	assert.Equal(t, 1, runs, "the run is skipped on the replicas not holding the lease")
+
+	assert.Error(t, Exclusive(fakeLock{err: errors.New("kv down")}, "test", time.Minute, job)(context.Background()))
+	assert.Equal(t, 1, runs)
+}
@@ -0,0 +1,151 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package stream
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/compression"
+)
+
+// noRespondersStatus is the status header NATS sets on the reply sent when
+// no one is subscribed to the request subject
+const noRespondersStatus = "503"
+
+// Request sends data to subject accepting a chunked reply and returns the
+// whole reply body, decoded when the reply was compressed. ctx bounds the
+// whole exchange.
+func Request(ctx context.Context, conn *nats.Conn, subject string, data []byte, header nats.Header) ([]byte, error) {
+	body, err := Open(ctx, conn, subject, data, header)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
+
+// Open sends data to subject accepting a chunked reply and returns a reader
+// of the reply body, decoded when the reply was compressed, so large results
+// can be decoded as they arrive. ctx bounds the whole exchange; the reader
+// must be closed to release the reply inbox.
+func Open(ctx context.Context, conn *nats.Conn, subject string, data []byte, header nats.Header) (io.ReadCloser, error) {
+	inbox := conn.NewInbox()
+	sub, err := conn.SubscribeSync(inbox)
+	if err != nil {
+		return nil, err
+	}
+
+	request := nats.NewMsg(subject)
+	request.Reply = inbox
+	request.Data = data
+	for key, values := range header {
+		request.Header[key] = values
+	}
+	request.Header.Set(Header, Chunked)
+	if err := conn.PublishMsg(request); err != nil {
+		_ = sub.Unsubscribe()
+		return nil, err
+	}
+
+	body, err := newReader(func() (*nats.Msg, error) { return sub.NextMsgWithContext(ctx) }, sub.Unsubscribe)
+	if err != nil {
+		_ = sub.Unsubscribe()
+		return nil, err
+	}
+	return body, nil
+}
+
+// newReader reads the reply whose messages next returns, plain or chunked,
+// and decodes it. release is called when the reader is closed.
+func newReader(next func() (*nats.Msg, error), release func() error) (io.ReadCloser, error) {
+	first, err := next()
+	if err != nil {
+		return nil, err
+	}
+	if first.Header.Get("Status") == noRespondersStatus {
+		return nil, nats.ErrNoResponders
+	}
+
+	chunks := &chunkReader{next: next, release: release}
+	if first.Header.Get(SeqHeader) == "" {
+		// a reply that fit in one message
+		chunks.done = true
+	} else if err := chunks.check(first); err != nil {
+		return nil, err
+	}
+	chunks.pending = first.Data
+
+	decoded, err := compression.NewReader(first.Header.Get(compression.ContentEncodingHeader), chunks)
+	if err != nil {
+		return nil, err
+	}
+	return &decodedReader{ReadCloser: decoded, chunks: chunks}, nil
+}
+
+// chunkReader reads the data messages of a chunked reply in order until the
+// terminator
+type chunkReader struct {
+	next    func() (*nats.Msg, error)
+	release func() error
+	pending []byte
+	seq     int
+	done    bool
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		msg, err := r.next()
+		if err != nil {
+			return 0, err
+		}
+		if end := msg.Header.Get(EndHeader); end != "" {
+			if end != strconv.Itoa(r.seq) {
+				return 0, fmt.Errorf("chunked reply ended after %d of %s chunks", r.seq, end)
+			}
+			r.done = true
+			continue
+		}
+		if err := r.check(msg); err != nil {
+			return 0, err
+		}
+		r.pending = msg.Data
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+// check verifies msg is the next data message of the reply
+func (r *chunkReader) check(msg *nats.Msg) error {
+	want := strconv.Itoa(r.seq + 1)
+	if got := msg.Header.Get(SeqHeader); got != want {
+		return fmt.Errorf("chunked reply out of order: got chunk %q, want %s", got, want)
+	}
+	r.seq++
+	return nil
+}
+
+// decodedReader closes the decoder and releases the reply inbox
+type decodedReader struct {
+	io.ReadCloser
+	chunks *chunkReader
+}
+
+func (r *decodedReader) Close() error {
+	errDecoder := r.ReadCloser.Close()
+	if r.chunks.release != nil {
+		if err := r.chunks.release(); err != nil {
+			return err
+		}
+	}
+	return errDecoder
+}
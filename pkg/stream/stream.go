@@ -0,0 +1,71 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package stream implements chunked NATS replies, for results larger than
+// the server's max payload. A caller opts in with the Lfx-Stream request
+// header; a reply that doesn't fit in one message is then sent to the reply
+// inbox as data messages numbered from 1 in Lfx-Stream-Seq, followed by an
+// empty terminator whose Lfx-Stream-End holds the number of data messages.
+// A reply that fits is sent as a single plain message, as it would be to a
+// caller that didn't opt in.
+//
+// The reply headers, such as Content-Encoding, are repeated on every message
+// and apply to the reassembled body.
+package stream
+
+import (
+	"strconv"
+
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	// Header is the request header a caller sets to Chunked to accept a
+	// chunked reply
+	Header = "Lfx-Stream"
+	// Chunked is the value of Header for callers that accept chunked replies
+	Chunked = "chunked"
+
+	// SeqHeader numbers the data messages of a chunked reply, from 1
+	SeqHeader = "Lfx-Stream-Seq"
+	// EndHeader marks the terminator of a chunked reply and holds the number
+	// of data messages sent before it
+	EndHeader = "Lfx-Stream-End"
+
+	// HeaderRoom is left out of the max payload for the headers of a chunk
+	HeaderRoom = 1024
+)
+
+// Accepted reports whether a request with header accepts chunked replies
+func Accepted(header nats.Header) bool {
+	return header.Get(Header) == Chunked
+}
+
+// Messages splits a reply body into the messages of a chunked reply, each
+// carrying a copy of header. A body of at most chunkSize bytes, or a
+// chunkSize <= 0, gives a single plain message.
+func Messages(body []byte, chunkSize int, header nats.Header) []*nats.Msg {
+	if chunkSize <= 0 || len(body) <= chunkSize {
+		return []*nats.Msg{{Data: body, Header: header}}
+	}
+
+	messages := make([]*nats.Msg, 0, len(body)/chunkSize+2)
+	seq := 0
+	for start := 0; start < len(body); start += chunkSize {
+		seq++
+		chunk := &nats.Msg{Data: body[start:min(start+chunkSize, len(body))], Header: copyHeader(header)}
+		chunk.Header.Set(SeqHeader, strconv.Itoa(seq))
+		messages = append(messages, chunk)
+	}
+	terminator := &nats.Msg{Header: copyHeader(header)}
+	terminator.Header.Set(EndHeader, strconv.Itoa(seq))
+	return append(messages, terminator)
+}
+
+func copyHeader(header nats.Header) nats.Header {
+	copied := make(nats.Header, len(header)+1)
+	for key, values := range header {
+		copied[key] = append([]string(nil), values...)
+	}
+	return copied
+}
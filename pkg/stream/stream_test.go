@@ -0,0 +1,120 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package stream
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linuxfoundation/lfx-v2-auth-service/pkg/compression"
+)
+
+// replay returns the messages one at a time, as a reply inbox would
+func replay(messages []*nats.Msg) func() (*nats.Msg, error) {
+	return func() (*nats.Msg, error) {
+		if len(messages) == 0 {
+			return nil, nats.ErrTimeout
+		}
+		msg := messages[0]
+		messages = messages[1:]
+		return msg, nil
+	}
+}
+
+func readAll(t *testing.T, messages []*nats.Msg) ([]byte, error) {
+	t.Helper()
+	released := false
+	body, err := newReader(replay(messages), func() error { released = true; return nil })
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(body)
+	require.NoError(t, body.Close())
+	assert.True(t, released, "the reply inbox should be released on close")
+	return data, err
+}
+
+func TestMessages(t *testing.T) {
+	body := []byte("0123456789")
+
+	t.Run("body that fits is a single plain message", func(t *testing.T) {
+		messages := Messages(body, 10, nil)
+		require.Len(t, messages, 1)
+		assert.Equal(t, body, messages[0].Data)
+		assert.Empty(t, messages[0].Header.Get(SeqHeader))
+	})
+
+	t.Run("chunking disabled", func(t *testing.T) {
+		assert.Len(t, Messages(body, 0, nil), 1)
+	})
+
+	t.Run("large body is chunked with a terminator", func(t *testing.T) {
+		header := nats.Header{compression.ContentEncodingHeader: {compression.Gzip}}
+		messages := Messages(body, 4, header)
+		require.Len(t, messages, 4)
+		for i, want := range []string{"0123", "4567", "89"} {
+			assert.Equal(t, want, string(messages[i].Data))
+			assert.Equal(t, []string{"1", "2", "3"}[i], messages[i].Header.Get(SeqHeader))
+			assert.Equal(t, compression.Gzip, messages[i].Header.Get(compression.ContentEncodingHeader))
+		}
+		assert.Empty(t, messages[3].Data)
+		assert.Equal(t, "3", messages[3].Header.Get(EndHeader))
+		assert.Empty(t, header.Get(SeqHeader), "the given header must not be modified")
+	})
+}
+
+func TestReader(t *testing.T) {
+	body := bytes.Repeat([]byte(`{"email":"user@example.com","status":"found"},`), 500)
+
+	t.Run("plain reply", func(t *testing.T) {
+		got, err := readAll(t, Messages(body, 0, nil))
+		require.NoError(t, err)
+		assert.Equal(t, body, got)
+	})
+
+	t.Run("chunked reply", func(t *testing.T) {
+		got, err := readAll(t, Messages(body, 1000, nil))
+		require.NoError(t, err)
+		assert.Equal(t, body, got)
+	})
+
+	t.Run("compressed chunked reply", func(t *testing.T) {
+		compressed, err := compression.Encode(compression.Zstd, body)
+		require.NoError(t, err)
+		header := nats.Header{compression.ContentEncodingHeader: {compression.Zstd}}
+		got, err := readAll(t, Messages(compressed, 64, header))
+		require.NoError(t, err)
+		assert.Equal(t, body, got)
+	})
+
+	t.Run("chunk out of order", func(t *testing.T) {
+		messages := Messages(body, 1000, nil)
+		messages[1], messages[2] = messages[2], messages[1]
+		_, err := readAll(t, messages)
+		assert.ErrorContains(t, err, "out of order")
+	})
+
+	t.Run("terminator after missing chunks", func(t *testing.T) {
+		messages := Messages(body, 1000, nil)
+		messages = append(messages[:1], messages[len(messages)-1])
+		_, err := readAll(t, messages)
+		assert.ErrorContains(t, err, "chunked reply ended after 1")
+	})
+
+	t.Run("reply that stops before its terminator", func(t *testing.T) {
+		messages := Messages(body, 1000, nil)
+		_, err := readAll(t, messages[:2])
+		assert.ErrorIs(t, err, nats.ErrTimeout)
+	})
+
+	t.Run("no responders", func(t *testing.T) {
+		_, err := newReader(replay([]*nats.Msg{{Header: nats.Header{"Status": {"503"}}}}), nil)
+		assert.ErrorIs(t, err, nats.ErrNoResponders)
+	})
+}
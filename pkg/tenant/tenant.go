@@ -0,0 +1,73 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+// Package tenant namespaces the tenants of a multi-tenant deployment, where
+// one process serves several LFX environments, each with its own identity
+// provider. A tenant's subjects are lfx.{tenant}.auth-service.*, its KV
+// buckets are prefixed with its name, and its metrics carry a tenant label.
+// The empty tenant is a single-tenant deployment and changes nothing.
+package tenant
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// subjectRoot is the first token of every LFX subject; the tenant goes after it
+const subjectRoot = "lfx."
+
+// namePattern keeps tenant names usable as a subject token and in bucket names
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+type contextKey struct{}
+
+// Validate reports whether name can be used as a tenant name: lowercase
+// letters, digits and dashes, starting with a letter or digit
+func Validate(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("invalid tenant name %q: use lowercase letters, digits and dashes", name)
+	}
+	return nil
+}
+
+// Subject namespaces an LFX subject for tenant, e.g.
+// lfx.auth-service.user_metadata.read becomes lfx.acme.auth-service.user_metadata.read
+func Subject(tenant, subject string) string {
+	if tenant == "" || !strings.HasPrefix(subject, subjectRoot) {
+		return subject
+	}
+	return subjectRoot + tenant + "." + strings.TrimPrefix(subject, subjectRoot)
+}
+
+// Bucket is the name of tenant's copy of a KV bucket
+func Bucket(tenant, bucket string) string {
+	if tenant == "" {
+		return bucket
+	}
+	return tenant + "-" + bucket
+}
+
+// WithContext returns a context carrying tenant
+func WithContext(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, contextKey{}, tenant)
+}
+
+// FromContext returns the tenant of ctx, empty for single-tenant deployments
+func FromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(contextKey{}).(string)
+	return tenant
+}
+
+// Attributes adds the tenant label of ctx, when there is one, to the
+// attributes of a metric measurement
+func Attributes(ctx context.Context, attrs ...attribute.KeyValue) metric.MeasurementOption {
+	if tenant := FromContext(ctx); tenant != "" {
+		attrs = append(attrs, attribute.String("tenant", tenant))
+	}
+	return metric.WithAttributes(attrs...)
+}
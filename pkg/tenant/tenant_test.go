@@ -0,0 +1,37 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package tenant
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubject(t *testing.T) {
+	assert.Equal(t, "lfx.auth-service.user_metadata.read", Subject("", "lfx.auth-service.user_metadata.read"))
+	assert.Equal(t, "lfx.acme.auth-service.user_metadata.read", Subject("acme", "lfx.auth-service.user_metadata.read"))
+	assert.Equal(t, "other.subject", Subject("acme", "other.subject"), "only LFX subjects are namespaced")
+}
+
+func TestBucket(t *testing.T) {
+	assert.Equal(t, "authelia-users", Bucket("", "authelia-users"))
+	assert.Equal(t, "acme-authelia-users", Bucket("acme", "authelia-users"))
+}
+
+func TestValidate(t *testing.T) {
+	for _, name := range []string{"acme", "lf-staging", "env2"} {
+		assert.NoError(t, Validate(name), name)
+	}
+	for _, name := range []string{"", "Acme", "a.b", "a*", "-acme", "a b"} {
+		assert.Error(t, Validate(name), name)
+	}
+}
+
+func TestContext(t *testing.T) {
+	ctx := context.Background()
+	assert.Empty(t, FromContext(ctx))
+	assert.Equal(t, "acme", FromContext(WithContext(ctx, "acme")))
+}
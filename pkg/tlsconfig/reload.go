@@ -0,0 +1,96 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	errs "github.com/linuxfoundation/lfx-v2-auth-service/pkg/errors"
+)
+
+// reloadCheckInterval bounds how often the key pair files are stat'ed
+const reloadCheckInterval = 30 * time.Second
+
+// keyPairReloader serves a certificate from disk and reloads it after the
+// files change. A failed reload keeps serving the previous certificate.
+type keyPairReloader struct {
+	certFile, keyFile string
+	now               func() time.Time
+
+	mu          sync.Mutex
+	certificate *tls.Certificate
+	modTime     time.Time
+	checkedAt   time.Time
+}
+
+func newKeyPairReloader(certFile, keyFile string) (*keyPairReloader, error) {
+	r := &keyPairReloader{certFile: certFile, keyFile: keyFile, now: time.Now}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// latestModTime returns the newer modification time of the two files
+func (r *keyPairReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, path := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+func (r *keyPairReloader) load() error {
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return errs.NewValidation(fmt.Sprintf("failed to read TLS key pair %s", r.certFile), err)
+	}
+	certificate, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return errs.NewValidation(fmt.Sprintf("failed to load TLS key pair %s", r.certFile), err)
+	}
+	r.certificate, r.modTime = &certificate, modTime
+	return nil
+}
+
+func (r *keyPairReloader) current() *tls.Certificate {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	if now.Sub(r.checkedAt) < reloadCheckInterval {
+		return r.certificate
+	}
+	r.checkedAt = now
+
+	modTime, err := r.latestModTime()
+	if err != nil || !modTime.After(r.modTime) {
+		return r.certificate
+	}
+	if err := r.load(); err != nil {
+		slog.Warn("failed to reload TLS certificate, keeping the previous one", "cert_file", r.certFile, "error", err)
+		return r.certificate
+	}
+	slog.Info("reloaded TLS certificate", "cert_file", r.certFile)
+	return r.certificate
+}
+
+func (r *keyPairReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.current(), nil
+}
+
+func (r *keyPairReloader) getClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return r.current(), nil
+}
@@ -0,0 +1,235 @@
+// Copyright The Linux Foundation and each contributor to LFX.
+// SPDX-License-Identifier: MIT
+
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	dir  string
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	ca := &testCA{cert: cert, key: key, dir: t.TempDir()}
+	writePEM(t, filepath.Join(ca.dir, "ca.pem"), "CERTIFICATE", der)
+	return ca
+}
+
+// issue writes a leaf certificate and key signed by the CA and returns their paths
+func (ca *testCA) issue(t *testing.T, name string, serial int64, usage x509.ExtKeyUsage) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile, keyFile = filepath.Join(ca.dir, name+".pem"), filepath.Join(ca.dir, name+"-key.pem")
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDER)
+	return certFile, keyFile
+}
+
+func (ca *testCA) file() string {
+	return filepath.Join(ca.dir, "ca.pem")
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600))
+}
+
+// startServer serves over a plain TLS listener; httptest.Server would install
+// its own certificate, which takes precedence over GetCertificate
+func startServer(t *testing.T, config *tls.Config) string {
+	t.Helper()
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", config)
+	require.NoError(t, err)
+	server := &http.Server{
+		ReadHeaderTimeout: time.Second,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if len(r.TLS.PeerCertificates) > 0 {
+				_, _ = w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+			}
+		}),
+	}
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(func() { _ = server.Close() })
+	return "https://" + listener.Addr().String()
+}
+
+func get(t *testing.T, config *tls.Config, url string) (string, error) {
+	t.Helper()
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body := make([]byte, 64)
+	n, _ := resp.Body.Read(body)
+	return string(body[:n]), nil
+}
+
+func TestMutualTLS(t *testing.T) {
+	ca := newTestCA(t)
+	serverCert, serverKey := ca.issue(t, "localhost", 2, x509.ExtKeyUsageServerAuth)
+	clientCert, clientKey := ca.issue(t, "auth-service", 3, x509.ExtKeyUsageClientAuth)
+
+	serverConfig, err := Server(ServerConfig{CertFile: serverCert, KeyFile: serverKey, ClientCAFile: ca.file()})
+	require.NoError(t, err)
+	assert.Equal(t, tls.RequireAndVerifyClientCert, serverConfig.ClientAuth, "client CA defaults to require")
+	server := startServer(t, serverConfig)
+
+	t.Run("client certificate is presented", func(t *testing.T) {
+		config, err := Client(ClientConfig{CAFile: ca.file(), CertFile: clientCert, KeyFile: clientKey})
+		require.NoError(t, err)
+		body, err := get(t, config, server)
+		require.NoError(t, err)
+		assert.Equal(t, "auth-service", body)
+	})
+
+	t.Run("missing client certificate is rejected", func(t *testing.T) {
+		config, err := Client(ClientConfig{CAFile: ca.file()})
+		require.NoError(t, err)
+		_, err = get(t, config, server)
+		assert.Error(t, err)
+	})
+
+	t.Run("untrusted server is rejected", func(t *testing.T) {
+		other := newTestCA(t)
+		config, err := Client(ClientConfig{CAFile: other.file(), CertFile: clientCert, KeyFile: clientKey})
+		require.NoError(t, err)
+		_, err = get(t, config, server)
+		assert.Error(t, err)
+	})
+
+	t.Run("verify if given accepts anonymous clients", func(t *testing.T) {
+		config, err := Server(ServerConfig{CertFile: serverCert, KeyFile: serverKey, ClientCAFile: ca.file(), ClientAuth: ClientAuthVerifyIfGiven})
+		require.NoError(t, err)
+		anonymous := startServer(t, config)
+
+		clientConfig, err := Client(ClientConfig{CAFile: ca.file()})
+		require.NoError(t, err)
+		body, err := get(t, clientConfig, anonymous)
+		require.NoError(t, err)
+		assert.Empty(t, body)
+	})
+}
+
+func TestConfigValidation(t *testing.T) {
+	ca := newTestCA(t)
+	certFile, keyFile := ca.issue(t, "localhost", 2, x509.ExtKeyUsageServerAuth)
+
+	tests := []struct {
+		name string
+		fn   func() error
+	}{
+		{"server without key", func() error { _, err := Server(ServerConfig{CertFile: certFile}); return err }},
+		{"server with missing cert", func() error {
+			_, err := Server(ServerConfig{CertFile: "/does/not/exist.pem", KeyFile: keyFile})
+			return err
+		}},
+		{"client auth without CA", func() error {
+			_, err := Server(ServerConfig{CertFile: certFile, KeyFile: keyFile, ClientAuth: ClientAuthRequire})
+			return err
+		}},
+		{"unknown client auth", func() error {
+			_, err := Server(ServerConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: ca.file(), ClientAuth: "sometimes"})
+			return err
+		}},
+		{"client cert without key", func() error { _, err := Client(ClientConfig{CertFile: certFile}); return err }},
+		{"CA file without certificates", func() error { _, err := Client(ClientConfig{CAFile: keyFile}); return err }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Error(t, tt.fn())
+		})
+	}
+
+	config, err := Client(ClientConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, config, "empty client config keeps Go defaults")
+}
+
+func TestKeyPairReloader(t *testing.T) {
+	ca := newTestCA(t)
+	certFile, keyFile := ca.issue(t, "localhost", 2, x509.ExtKeyUsageServerAuth)
+
+	reloader, err := newKeyPairReloader(certFile, keyFile)
+	require.NoError(t, err)
+	now := time.Now()
+	reloader.now = func() time.Time { return now }
+
+	serial := func() int64 {
+		leaf, err := x509.ParseCertificate(reloader.current().Certificate[0])
+		require.NoError(t, err)
+		return leaf.SerialNumber.Int64()
+	}
+	assert.Equal(t, int64(2), serial())
+
+	// Rotate the files on disk, then move past the check interval
+	ca.issue(t, "localhost", 5, x509.ExtKeyUsageServerAuth)
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, future, future))
+	assert.Equal(t, int64(2), serial(), "files are not re-checked within the interval")
+
+	now = now.Add(reloadCheckInterval)
+	assert.Equal(t, int64(5), serial())
+
+	t.Run("broken files keep the previous certificate", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(keyFile, []byte("garbage"), 0o600))
+		later := future.Add(time.Minute)
+		require.NoError(t, os.Chtimes(keyFile, later, later))
+		now = now.Add(reloadCheckInterval)
+		assert.Equal(t, int64(5), serial())
+	})
+}